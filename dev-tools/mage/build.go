@@ -51,6 +51,11 @@ func DefaultBuildArgs() BuildArgs {
 		args.ExtraFlags = append(args.ExtraFlags, "-buildmode", "pie")
 	}
 
+	if FaultInjection {
+		// Enable the fault injection hooks used by the integration tests.
+		args.ExtraFlags = append(args.ExtraFlags, "-tags", "faultinject")
+	}
+
 	if DevBuild {
 		// Disable optimizations (-N) and inlining (-l) for debugging.
 		args.ExtraFlags = append(args.ExtraFlags, `-gcflags=all=-N -l`)
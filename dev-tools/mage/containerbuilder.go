@@ -0,0 +1,207 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/magefile/mage/sh"
+	"github.com/pkg/errors"
+)
+
+// buildxBuilderName is the name of the buildx builder of the multi-arch images, the default
+// docker driver can neither build for several platforms nor emit attestations.
+const buildxBuilderName = "elastic-agent-builder"
+
+// containerBuilder builds a multi-arch container image from the build directories
+// staged by the docker builders of each platform.
+type containerBuilder struct {
+	// builders are the docker builders of the image, one per architecture.
+	builders map[string]*dockerBuilder
+
+	imageName string
+	buildDir  string
+}
+
+// BuildContainers builds the multi-arch images of the staged docker packages with buildx.
+// The images are exported with their SBOMs and provenance attestations as OCI archives
+// in the distributions directory.
+func BuildContainers(specs []PackageSpec) error {
+	if err := HaveDockerBuildx(); err != nil {
+		return err
+	}
+
+	builders, err := newContainerBuilders(specs)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range builders {
+		fmt.Printf(">> package: Building %v multi-arch image for platforms=%v\n", b.imageName, strings.Join(b.platforms(), ","))
+		if err := b.Build(); err != nil {
+			return errors.Wrapf(err, "failed building %v multi-arch image", b.imageName)
+		}
+	}
+	return nil
+}
+
+// HaveDockerBuildx returns an error if the buildx plugin of docker is not
+// installed, and creates the builder of the multi-arch images if it is missing.
+func HaveDockerBuildx() error {
+	if err := HaveDocker(); err != nil {
+		return err
+	}
+	if err := sh.Run("docker", "buildx", "version"); err != nil {
+		return errors.Wrap(err, "docker buildx is required to build multi-arch images")
+	}
+	if err := sh.Run("docker", "buildx", "inspect", buildxBuilderName); err == nil {
+		return nil
+	}
+	return errors.Wrap(
+		sh.Run("docker", "buildx", "create", "--name", buildxBuilderName, "--driver", "docker-container"),
+		"failed to create buildx builder")
+}
+
+// newContainerBuilders groups the specs of the docker packages by image.
+func newContainerBuilders(specs []PackageSpec) ([]*containerBuilder, error) {
+	images := map[string]*containerBuilder{}
+	var builders []*containerBuilder
+	for _, spec := range specs {
+		db, err := newDockerBuilder(spec)
+		if err != nil {
+			return nil, err
+		}
+		arch, _ := spec.evalContext["GOARCH"].(string)
+		if arch == "" {
+			return nil, errors.Errorf("missing architecture of the %v image", db.imageName)
+		}
+
+		tag := db.tag()
+		b, found := images[tag]
+		if !found {
+			b = &containerBuilder{
+				builders:  map[string]*dockerBuilder{},
+				imageName: db.imageName,
+				buildDir:  filepath.Join(filepath.Dir(spec.packageDir), spec.Name+"-multiarch.docker", "docker-build"),
+			}
+			images[tag] = b
+			builders = append(builders, b)
+		}
+		if _, found := b.builders[arch]; found {
+			return nil, errors.Errorf("duplicated %v architecture for the %v image", arch, db.imageName)
+		}
+		b.builders[arch] = db
+	}
+	return builders, nil
+}
+
+// platforms returns the sorted platforms of the image.
+func (b *containerBuilder) platforms() []string {
+	platforms := make([]string, 0, len(b.builders))
+	for arch := range b.builders {
+		platforms = append(platforms, "linux/"+arch)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// reference returns the docker builder of the image whose Dockerfile is used for all
+// the architectures, its base images must be multi-arch.
+func (b *containerBuilder) reference() *dockerBuilder {
+	if db, found := b.builders["amd64"]; found {
+		return db
+	}
+	platforms := b.platforms()
+	return b.builders[strings.TrimPrefix(platforms[0], "linux/")]
+}
+
+// Build prepares the build context of all the architectures and builds the image.
+func (b *containerBuilder) Build() error {
+	if err := b.prepareBuild(); err != nil {
+		return errors.Wrap(err, "failed to prepare build")
+	}
+
+	outputFile, err := b.outputFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(distributionsDir, 0750); err != nil {
+		return fmt.Errorf("cannot create folder for docker artifacts: %w", err)
+	}
+
+	err = b.buildxBuild(outputFile)
+	tries := 3
+	for err != nil && tries != 0 {
+		fmt.Println(">> Building multi-arch docker images again")
+		err = b.buildxBuild(outputFile)
+		tries--
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to build docker")
+	}
+	return errors.Wrap(CreateSHA512File(outputFile), "failed to create .sha512 file")
+}
+
+// prepareBuild moves the beat directory staged for each architecture into a single
+// build context, the Dockerfile copies the one of the target architecture.
+func (b *containerBuilder) prepareBuild() error {
+	if err := os.RemoveAll(b.buildDir); err != nil {
+		return errors.Wrapf(err, "failed to clean existing build directory %s", b.buildDir)
+	}
+
+	ref := b.reference()
+	entries, err := os.ReadDir(ref.buildDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read build directory %s", ref.buildDir)
+	}
+	for _, entry := range entries {
+		if entry.Name() == filepath.Base(ref.beatDir) {
+			continue
+		}
+		src := filepath.Join(ref.buildDir, entry.Name())
+		target := filepath.Join(b.buildDir, entry.Name())
+		if err := Copy(src, target); err != nil {
+			return errors.Wrapf(err, "failed to copy from %s to %s", src, target)
+		}
+	}
+
+	for arch, db := range b.builders {
+		target := filepath.Join(b.buildDir, filepath.Base(db.beatDir), arch)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(db.beatDir, target); err != nil {
+			return errors.Wrapf(err, "failed to move %s to %s", db.beatDir, target)
+		}
+	}
+	return nil
+}
+
+// outputFile returns the OCI archive of the image, its name has no architecture.
+func (b *containerBuilder) outputFile() (string, error) {
+	outputTar, err := b.reference().Expand(defaultBinaryName+".oci.tar", map[string]interface{}{
+		"Name": b.imageName,
+		"Arch": "",
+	})
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(distributionsDir, outputTar), nil
+}
+
+func (b *containerBuilder) buildxBuild(outputFile string) error {
+	return sh.Run("docker", "buildx", "build",
+		"--builder", buildxBuilderName,
+		"--platform", strings.Join(b.platforms(), ","),
+		"--sbom=true",
+		"--provenance=mode=max",
+		"--tag", b.reference().tag(),
+		"--output", "type=oci,dest="+outputFile,
+		b.buildDir)
+}
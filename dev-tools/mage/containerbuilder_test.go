@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerBuilders(t *testing.T) {
+	dir := t.TempDir()
+	newSpec := func(imageName string, arch string) PackageSpec {
+		return PackageSpec{
+			Name:       "elastic-agent",
+			OS:         "linux",
+			Version:    "8.11.0",
+			Snapshot:   true,
+			ExtraVars:  map[string]string{"image_name": imageName, "repository": "docker.elastic.co/beats"},
+			packageDir: filepath.Join(dir, imageName, "elastic-agent-linux-"+arch+".docker"),
+			evalContext: map[string]interface{}{
+				"GOARCH": arch,
+			},
+		}
+	}
+
+	builders, err := newContainerBuilders([]PackageSpec{
+		newSpec("elastic-agent", "amd64"),
+		newSpec("elastic-agent-wolfi", "arm64"),
+		newSpec("elastic-agent", "arm64"),
+		newSpec("elastic-agent-wolfi", "amd64"),
+	})
+	require.NoError(t, err)
+	require.Len(t, builders, 2)
+	assert.Equal(t, "elastic-agent", builders[0].imageName)
+	assert.Equal(t, []string{"linux/amd64", "linux/arm64"}, builders[0].platforms())
+	assert.Equal(t, "docker.elastic.co/beats/elastic-agent-wolfi:8.11.0-SNAPSHOT", builders[1].reference().tag())
+	assert.Equal(t, filepath.Join(dir, "elastic-agent-wolfi", "elastic-agent-multiarch.docker", "docker-build"), builders[1].buildDir)
+
+	outputFile, err := builders[1].outputFile()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(distributionsDir, "elastic-agent-wolfi-8.11.0-SNAPSHOT-linux.oci.tar"), outputFile)
+
+	_, err = newContainerBuilders([]PackageSpec{
+		newSpec("elastic-agent", "amd64"),
+		newSpec("elastic-agent", "amd64"),
+	})
+	assert.ErrorContains(t, err, "duplicated amd64 architecture for the elastic-agent image")
+}
+
+func TestContainerBuilderPrepareBuild(t *testing.T) {
+	dir := t.TempDir()
+	specs := make([]PackageSpec, 0, 2)
+	for _, arch := range []string{"amd64", "arm64"} {
+		spec := PackageSpec{
+			Name:       "elastic-agent",
+			OS:         "linux",
+			Version:    "8.11.0",
+			packageDir: filepath.Join(dir, "elastic-agent-linux-"+arch+".docker"),
+			evalContext: map[string]interface{}{
+				"GOARCH": arch,
+			},
+		}
+		buildDir := filepath.Join(spec.packageDir, "docker-build")
+		require.NoError(t, os.MkdirAll(filepath.Join(buildDir, "beat"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(buildDir, "Dockerfile"), []byte("FROM "+arch), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(buildDir, "beat", "elastic-agent"), []byte(arch), 0755))
+		specs = append(specs, spec)
+	}
+
+	builders, err := newContainerBuilders(specs)
+	require.NoError(t, err)
+	require.Len(t, builders, 1)
+	require.NoError(t, builders[0].prepareBuild())
+
+	buildDir := builders[0].buildDir
+	dockerfile, err := os.ReadFile(filepath.Join(buildDir, "Dockerfile"))
+	require.NoError(t, err)
+	assert.Equal(t, "FROM amd64", string(dockerfile))
+	for _, arch := range []string{"amd64", "arm64"} {
+		binary, err := os.ReadFile(filepath.Join(buildDir, "beat", arch, "elastic-agent"))
+		require.NoError(t, err)
+		assert.Equal(t, arch, string(binary))
+	}
+}
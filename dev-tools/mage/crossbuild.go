@@ -313,6 +313,7 @@ func (b GolangCrossBuilder) Build() error {
 		"--env", fmt.Sprintf("SNAPSHOT=%v", Snapshot),
 		"--env", fmt.Sprintf("DEV=%v", DevBuild),
 		"--env", fmt.Sprintf("EXTERNAL=%v", ExternalBuild),
+		"--env", fmt.Sprintf("FAULT_INJECTION=%v", FaultInjection),
 		"-v", repoInfo.RootDir+":"+mountPoint,
 		"-w", workDir,
 		image,
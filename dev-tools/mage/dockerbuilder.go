@@ -57,6 +57,11 @@ func (b *dockerBuilder) Build() error {
 		return errors.Wrap(err, "failed to prepare build")
 	}
 
+	if DockerBuildx {
+		// the images are built for all the platforms at once by the containers builder
+		return nil
+	}
+
 	tag, err := b.dockerBuild()
 	tries := 3
 	for err != nil && tries != 0 {
@@ -117,6 +122,7 @@ func (b *dockerBuilder) prepareBuild() error {
 	data := map[string]interface{}{
 		"ExposePorts": b.exposePorts(),
 		"ModulesDirs": b.modulesDirs(),
+		"MultiArch":   DockerBuildx,
 	}
 
 	err = filepath.Walk(templatesDir, func(path string, info os.FileInfo, _ error) error {
@@ -176,7 +182,7 @@ func (b *dockerBuilder) expandDockerfile(templatesDir string, data map[string]in
 	return nil
 }
 
-func (b *dockerBuilder) dockerBuild() (string, error) {
+func (b *dockerBuilder) tag() string {
 	tag := fmt.Sprintf("%s:%s", b.imageName, b.Version)
 	if b.Snapshot {
 		tag = tag + "-SNAPSHOT"
@@ -184,6 +190,11 @@ func (b *dockerBuilder) dockerBuild() (string, error) {
 	if repository, _ := b.ExtraVars["repository"]; repository != "" {
 		tag = fmt.Sprintf("%s/%s", repository, tag)
 	}
+	return tag
+}
+
+func (b *dockerBuilder) dockerBuild() (string, error) {
+	tag := b.tag()
 	return tag, sh.Run("docker", "build", "-t", tag, b.buildDir)
 }
 
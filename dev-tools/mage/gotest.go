@@ -5,16 +5,22 @@
 package mage
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,9 +44,30 @@ type GoTestArgs struct {
 	OutputFile          string            // File to write verbose test output to.
 	JUnitReportFile     string            // File to write a JUnit XML test report to.
 	CoverageProfileFile string            // Test coverage profile file (enables -cover).
+	SummaryFile         string            // File to write a JSON GoTestSummary to.
 	Output              io.Writer         // Write stderr and stdout to Output if set
 }
 
+// GoFuzzArgs are the arguments used for the "goFuzz" targets and they define
+// how "go test -fuzz" is invoked for each of the fuzz targets.
+type GoFuzzArgs struct {
+	LogName     string            // Fuzz run name used in logging.
+	FuzzExpr    string            // Expression selecting the fuzz targets to run, all of them if empty.
+	FuzzTime    string            // Time each fuzz target is run for, passed to the -fuzztime argument of go test.
+	Tags        []string          // Build tags to enable.
+	Packages    []string          // Packages to look for fuzz targets in.
+	Env         map[string]string // Env vars to add to the current env.
+	CorpusDir   string            // Directory of the generated corpus, kept between runs.
+	CrashersDir string            // Directory the failing inputs are collected in.
+	OutputFile  string            // File to write the fuzzing output to.
+}
+
+// fuzzTarget is a fuzz target of a package.
+type fuzzTarget struct {
+	Package string // Import path of the package.
+	Name    string // Name of the fuzz function.
+}
+
 // TestBinaryArgs are the arguments used when building binary for testing.
 type TestBinaryArgs struct {
 	Name       string // Name of the binary to build
@@ -55,6 +82,7 @@ func makeGoTestArgs(name string) GoTestArgs {
 		Packages:        []string{"./..."},
 		OutputFile:      fileName + ".out",
 		JUnitReportFile: fileName + ".xml",
+		SummaryFile:     fileName + "-summary.json",
 		Tags:            testTagsFromEnv(),
 	}
 	if TestCoverage {
@@ -72,6 +100,7 @@ func makeGoTestArgsForModule(name, module string) GoTestArgs {
 		Packages:        []string{fmt.Sprintf("./module/%s/...", module)},
 		OutputFile:      fileName + ".out",
 		JUnitReportFile: fileName + ".xml",
+		SummaryFile:     fileName + "-summary.json",
 		Tags:            testTagsFromEnv(),
 	}
 	if TestCoverage {
@@ -106,6 +135,22 @@ func GoTestIntegrationArgsForModule(module string) GoTestArgs {
 	return args
 }
 
+// DefaultGoFuzzArgs returns a default set of arguments for running all the
+// fuzz targets for FUZZ_TIME each (default 30s). FUZZ_TARGET restricts the
+// fuzz targets run to the ones matching the expression.
+func DefaultGoFuzzArgs() GoFuzzArgs {
+	return GoFuzzArgs{
+		LogName:     "Fuzz",
+		FuzzExpr:    EnvOr("FUZZ_TARGET", ""),
+		FuzzTime:    EnvOr("FUZZ_TIME", "30s"),
+		Tags:        testTagsFromEnv(),
+		Packages:    []string{"./..."},
+		CorpusDir:   "build/fuzz/corpus",
+		CrashersDir: "build/fuzz/crashers",
+		OutputFile:  "build/TEST-go-fuzz.out",
+	}
+}
+
 // DefaultTestBinaryArgs returns the default arguments for building
 // a binary for testing.
 func DefaultTestBinaryArgs() TestBinaryArgs {
@@ -207,9 +252,21 @@ func GoTest(ctx context.Context, params GoTestArgs) error {
 		CreateDir(params.JUnitReportFile)
 		gotestsumArgs = append(gotestsumArgs, "--junitfile", params.JUnitReportFile)
 	}
+	jsonFile := ""
 	if params.OutputFile != "" {
 		CreateDir(params.OutputFile)
-		gotestsumArgs = append(gotestsumArgs, "--jsonfile", params.OutputFile+".json")
+		jsonFile = params.OutputFile + ".json"
+	} else if params.SummaryFile != "" {
+		// the summary is built from the JSON output of go test
+		tmpDir, err := os.MkdirTemp("", "gotest")
+		if err != nil {
+			return errors.Wrap(err, "failed to create go test JSON output directory")
+		}
+		defer os.RemoveAll(tmpDir)
+		jsonFile = filepath.Join(tmpDir, "gotest.json")
+	}
+	if jsonFile != "" {
+		gotestsumArgs = append(gotestsumArgs, "--jsonfile", jsonFile)
 	}
 
 	var testArgs []string
@@ -281,6 +338,12 @@ func GoTest(ctx context.Context, params GoTestArgs) error {
 		goTestErr = exitErr
 	}
 
+	if params.SummaryFile != "" {
+		if err := writeGoTestSummary(params.SummaryFile, jsonFile, params.CoverageProfileFile); err != nil {
+			return errors.Wrap(err, "failed to write go test summary")
+		}
+	}
+
 	if goTestErr != nil {
 		// No packages were tested. Probably the code didn't compile.
 		return errors.Wrap(goTestErr, "go test returned a non-zero value")
@@ -345,6 +408,391 @@ func GoTest(ctx context.Context, params GoTestArgs) error {
 	return nil
 }
 
+// GoFuzz runs each fuzz target of the packages for a limited time, one after
+// the other as "go test" can only fuzz a single target at once. The corpus
+// generated by the fuzzing is kept in CorpusDir between runs, the failing inputs
+// are moved from the testdata directory of the packages to CrashersDir. It
+// returns an error listing the fuzz targets that failed.
+func GoFuzz(ctx context.Context, params GoFuzzArgs) error {
+	fmt.Println(">> go test -fuzz:", params.LogName, "Fuzzing")
+
+	var tagsArgs []string
+	if tags := strings.TrimSpace(strings.Join(params.Tags, " ")); tags != "" {
+		tagsArgs = []string{"-tags", tags}
+	}
+
+	listArgs := append([]string{"test", "-list", "^Fuzz"}, tagsArgs...)
+	listOutput, err := sh.OutputWith(params.Env, "go", append(listArgs, params.Packages...)...)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the fuzz targets")
+	}
+	targets, err := parseFuzzTargets(listOutput, params.FuzzExpr)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		fmt.Println(">> go test -fuzz:", params.LogName, "No fuzz targets")
+		return nil
+	}
+
+	corpusDir, err := filepath.Abs(params.CorpusDir)
+	if err != nil {
+		return err
+	}
+	var output io.Writer = os.Stdout
+	if params.OutputFile != "" {
+		fileOutput, err := os.Create(createDir(params.OutputFile))
+		if err != nil {
+			return errors.Wrap(err, "failed to create go test output file")
+		}
+		defer fileOutput.Close()
+		output = io.MultiWriter(os.Stdout, fileOutput)
+	}
+
+	var failed []string
+	for _, target := range targets {
+		pkgDir, err := sh.OutputWith(params.Env, "go", "list", "-f", "{{.Dir}}", target.Package)
+		if err != nil {
+			return errors.Wrapf(err, "failed to locate package %s", target.Package)
+		}
+		failingInputsDir := filepath.Join(pkgDir, "testdata", "fuzz", target.Name)
+		known, err := listFiles(failingInputsDir)
+		if err != nil {
+			return err
+		}
+
+		args := append([]string{"test"}, tagsArgs...)
+		args = append(args,
+			"-run", "^$",
+			"-fuzz", "^"+target.Name+"$",
+			"-fuzztime", params.FuzzTime,
+			target.Package,
+			// overrides the fuzz cache of go test, the corpus is kept with the other build artifacts
+			"-test.fuzzcachedir="+filepath.Join(corpusDir, filepath.FromSlash(target.Package)),
+		)
+		goFuzz := makeCommand(ctx, params.Env, "go", args...)
+		goFuzz.Stdout = output
+		goFuzz.Stderr = output
+		if err := goFuzz.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return errors.Wrap(err, "failed to execute go")
+			}
+			failed = append(failed, target.Package+"."+target.Name)
+		}
+
+		if err := collectCrashers(failingInputsDir, filepath.Join(params.CrashersDir, filepath.FromSlash(target.Package), target.Name), known); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		fmt.Println(">> go test -fuzz:", params.LogName, "Fuzzing Failed, the failing inputs are in", params.CrashersDir)
+		return fmt.Errorf("failed fuzz targets: %s", strings.Join(failed, ", "))
+	}
+	fmt.Println(">> go test -fuzz:", params.LogName, "Fuzzing Passed")
+	return nil
+}
+
+// parseFuzzTargets parses the output of "go test -list" into the fuzz targets
+// matching the expression. The output lists the names of the fuzz targets of
+// each package before the line reporting the package.
+func parseFuzzTargets(output, expr string) ([]fuzzTarget, error) {
+	var match *regexp.Regexp
+	if expr != "" {
+		var err error
+		if match, err = regexp.Compile(expr); err != nil {
+			return nil, errors.Wrapf(err, "invalid fuzz target expression %q", expr)
+		}
+	}
+
+	var targets []fuzzTarget
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1 && strings.HasPrefix(fields[0], "Fuzz"):
+			names = append(names, fields[0])
+		case len(fields) >= 2 && (fields[0] == "ok" || fields[0] == "?"):
+			for _, name := range names {
+				if match == nil || match.MatchString(name) {
+					targets = append(targets, fuzzTarget{Package: fields[1], Name: name})
+				}
+			}
+			names = nil
+		}
+	}
+	return targets, nil
+}
+
+// listFiles returns the names of the files of the directory, a missing
+// directory has no files.
+func listFiles(dir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	files := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		files[entry.Name()] = true
+	}
+	return files, nil
+}
+
+// collectCrashers moves the failing inputs written by go test in the testdata
+// directory of the package since the run started to the crashers directory.
+func collectCrashers(failingInputsDir, crashersDir string, known map[string]bool) error {
+	current, err := listFiles(failingInputsDir)
+	if err != nil {
+		return err
+	}
+	for name := range current {
+		if known[name] {
+			continue
+		}
+		src := filepath.Join(failingInputsDir, name)
+		dest := filepath.Join(crashersDir, name)
+		if err := Copy(src, dest); err != nil {
+			return errors.Wrapf(err, "failed to collect the failing input %s", src)
+		}
+		if err := os.Remove(src); err != nil {
+			return err
+		}
+		fmt.Println(">> go test -fuzz: failing input collected in", dest)
+	}
+	return nil
+}
+
+// slowestTestsCount is the number of tests listed in GoTestSummary.SlowestTests.
+const slowestTestsCount = 10
+
+// GoTestSummary is the machine-readable summary of a "go test" run, written to
+// GoTestArgs.SummaryFile.
+type GoTestSummary struct {
+	Result       string                `json:"result"`             // pass or fail.
+	Elapsed      float64               `json:"elapsed"`            // Sum of the durations of the packages, in seconds.
+	Packages     []GoTestPackageResult `json:"packages"`           // Results of the tested packages, sorted by name.
+	SlowestTests []GoTestResult        `json:"slowest_tests"`      // The slowest tests, slowest first.
+	Coverage     *GoTestCoverage       `json:"coverage,omitempty"` // Set when the coverage was profiled.
+}
+
+// GoTestPackageResult is the result of the tests of a package.
+type GoTestPackageResult struct {
+	Package string  `json:"package"`
+	Result  string  `json:"result"`  // pass, fail or skip when the package has no tests.
+	Elapsed float64 `json:"elapsed"` // Duration in seconds.
+	Passed  int     `json:"passed"`  // Number of passed tests, subtests included.
+	Failed  int     `json:"failed"`  // Number of failed tests, subtests included.
+	Skipped int     `json:"skipped"` // Number of skipped tests, subtests included.
+}
+
+// GoTestResult is the result of a test.
+type GoTestResult struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test"`
+	Result  string  `json:"result"`  // pass, fail or skip.
+	Elapsed float64 `json:"elapsed"` // Duration in seconds.
+}
+
+// GoTestCoverage are the coverage totals of a coverage profile.
+type GoTestCoverage struct {
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Percent    float64 `json:"percent"`
+}
+
+// goTestEvent is an event of the JSON output of "go test", see "go doc test2json".
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+}
+
+// ReadGoTestSummary reads a summary written by GoTest.
+func ReadGoTestSummary(file string) (*GoTestSummary, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var summary GoTestSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse go test summary %s", file)
+	}
+	return &summary, nil
+}
+
+// NewGoTestSummary builds the summary of the JSON output of "go test" and
+// of the coverage profile, if any.
+func NewGoTestSummary(jsonOutput io.Reader, coverageProfile io.Reader) (*GoTestSummary, error) {
+	packages := map[string]*GoTestPackageResult{}
+	getPackage := func(name string) *GoTestPackageResult {
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &GoTestPackageResult{Package: name}
+			packages[name] = pkg
+		}
+		return pkg
+	}
+
+	var tests []GoTestResult
+	scanner := bufio.NewScanner(jsonOutput)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event goTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// gotestsum keeps the lines go test did not produce as JSON, e.g. build errors
+			continue
+		}
+		if event.Package == "" || (event.Action != "pass" && event.Action != "fail" && event.Action != "skip") {
+			continue
+		}
+		pkg := getPackage(event.Package)
+		if event.Test == "" {
+			pkg.Result = event.Action
+			pkg.Elapsed = event.Elapsed
+			continue
+		}
+		switch event.Action {
+		case "pass":
+			pkg.Passed++
+		case "fail":
+			pkg.Failed++
+		case "skip":
+			pkg.Skipped++
+		}
+		tests = append(tests, GoTestResult{
+			Package: event.Package,
+			Test:    event.Test,
+			Result:  event.Action,
+			Elapsed: event.Elapsed,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	summary := &GoTestSummary{
+		Result:       "pass",
+		Packages:     []GoTestPackageResult{},
+		SlowestTests: []GoTestResult{},
+	}
+	for _, pkg := range packages {
+		if pkg.Result == "" || pkg.Result == "fail" || pkg.Failed > 0 {
+			// a package without a result did not complete, e.g. it timed out
+			summary.Result = "fail"
+			if pkg.Result == "" {
+				pkg.Result = "fail"
+			}
+		}
+		summary.Elapsed += pkg.Elapsed
+		summary.Packages = append(summary.Packages, *pkg)
+	}
+	sort.Slice(summary.Packages, func(i, j int) bool {
+		return summary.Packages[i].Package < summary.Packages[j].Package
+	})
+
+	sort.SliceStable(tests, func(i, j int) bool { return tests[i].Elapsed > tests[j].Elapsed })
+	if len(tests) > slowestTestsCount {
+		tests = tests[:slowestTestsCount]
+	}
+	summary.SlowestTests = append(summary.SlowestTests, tests...)
+
+	if coverageProfile != nil {
+		coverage, err := parseCoverageProfile(coverageProfile)
+		if err != nil {
+			return nil, err
+		}
+		summary.Coverage = coverage
+	}
+	return summary, nil
+}
+
+// parseCoverageProfile computes the coverage totals of a coverage profile. A
+// block profiled by several test binaries is counted once, covered if any of
+// them covered it.
+func parseCoverageProfile(profile io.Reader) (*GoTestCoverage, error) {
+	type block struct {
+		statements int
+		covered    bool
+	}
+	blocks := map[string]*block{}
+
+	scanner := bufio.NewScanner(profile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// name.go:line.column,line.column numberOfStatements count
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid coverage profile line %q", line)
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverage profile line %q: %w", line, err)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid coverage profile line %q: %w", line, err)
+		}
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &block{statements: statements}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var coverage GoTestCoverage
+	for _, b := range blocks {
+		coverage.Statements += b.statements
+		if b.covered {
+			coverage.Covered += b.statements
+		}
+	}
+	if coverage.Statements > 0 {
+		coverage.Percent = math.Round(float64(coverage.Covered)*10000/float64(coverage.Statements)) / 100
+	}
+	return &coverage, nil
+}
+
+// writeGoTestSummary writes the summary of the JSON output of "go test" and of
+// the coverage profile, if any, to summaryFile.
+func writeGoTestSummary(summaryFile, jsonFile, coverageProfileFile string) error {
+	jsonOutput, err := os.Open(jsonFile)
+	if err != nil {
+		return err
+	}
+	defer jsonOutput.Close()
+
+	var coverageProfile io.Reader
+	if coverageProfileFile != "" {
+		f, err := os.Open(coverageProfileFile)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			defer f.Close()
+			coverageProfile = f
+		}
+	}
+
+	summary, err := NewGoTestSummary(jsonOutput, coverageProfile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(createDir(summaryFile), data, 0644)
+}
+
 func makeCommand(ctx context.Context, env map[string]string, cmd string, args ...string) *exec.Cmd {
 	c := exec.CommandContext(ctx, cmd, args...)
 	c.Env = os.Environ()
@@ -367,3 +367,77 @@ var wantTestWithWrongPanic = `(?sm:
 .*
 panic: Fail in goroutine after TestGoTest_Helper_WithWrongPanic/setup_failing_go-routine has completed.*
 )`
+
+func TestParseFuzzTargets(t *testing.T) {
+	output := strings.Join([]string{
+		"FuzzNewConfigFrom",
+		"ok  \tgithub.com/elastic/elastic-agent/internal/pkg/config\t0.015s",
+		"?   \tgithub.com/elastic/elastic-agent/internal/pkg/cli\t[no test files]",
+		"ok  \tgithub.com/elastic/elastic-agent/internal/pkg/id\t0.004s",
+		"FuzzLoadSpec",
+		"FuzzLoadRuntimeSpec",
+		"ok  \tgithub.com/elastic/elastic-agent/pkg/component\t0.021s",
+	}, "\n")
+
+	targets, err := parseFuzzTargets(output, "")
+	require.NoError(t, err)
+	assert.Equal(t, []fuzzTarget{
+		{Package: "github.com/elastic/elastic-agent/internal/pkg/config", Name: "FuzzNewConfigFrom"},
+		{Package: "github.com/elastic/elastic-agent/pkg/component", Name: "FuzzLoadSpec"},
+		{Package: "github.com/elastic/elastic-agent/pkg/component", Name: "FuzzLoadRuntimeSpec"},
+	}, targets)
+
+	targets, err = parseFuzzTargets(output, "Spec$")
+	require.NoError(t, err)
+	assert.Equal(t, []fuzzTarget{
+		{Package: "github.com/elastic/elastic-agent/pkg/component", Name: "FuzzLoadSpec"},
+		{Package: "github.com/elastic/elastic-agent/pkg/component", Name: "FuzzLoadRuntimeSpec"},
+	}, targets)
+
+	_, err = parseFuzzTargets(output, "(")
+	assert.Error(t, err)
+}
+
+func TestNewGoTestSummary(t *testing.T) {
+	events := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestA"}`,
+		`{"Action":"output","Package":"example.com/a","Test":"TestA","Output":"=== RUN   TestA\n"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestA","Elapsed":0.5}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestB/sub","Elapsed":2}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestB","Elapsed":2.1}`,
+		`{"Action":"skip","Package":"example.com/a","Test":"TestC","Elapsed":0}`,
+		`{"Action":"pass","Package":"example.com/a","Elapsed":2.7}`,
+		`{"Action":"fail","Package":"example.com/b","Test":"TestD","Elapsed":1}`,
+		`{"Action":"fail","Package":"example.com/b","Elapsed":1.3}`,
+		`{"Action":"skip","Package":"example.com/c","Elapsed":0}`,
+		`# example.com/d [build failed]`,
+	}, "\n")
+	profile := strings.Join([]string{
+		"mode: atomic",
+		"example.com/a/a.go:3.10,5.2 2 1",
+		"example.com/a/a.go:5.2,7.3 3 0",
+		"example.com/b/b.go:3.10,5.2 5 0",
+		// the same block profiled by another test binary
+		"example.com/a/a.go:5.2,7.3 3 4",
+	}, "\n")
+
+	summary, err := NewGoTestSummary(strings.NewReader(events), strings.NewReader(profile))
+	require.NoError(t, err)
+
+	assert.Equal(t, "fail", summary.Result)
+	assert.InDelta(t, 4.0, summary.Elapsed, 0.0001)
+	assert.Equal(t, []GoTestPackageResult{
+		{Package: "example.com/a", Result: "pass", Elapsed: 2.7, Passed: 3, Skipped: 1},
+		{Package: "example.com/b", Result: "fail", Elapsed: 1.3, Failed: 1},
+		{Package: "example.com/c", Result: "skip"},
+	}, summary.Packages)
+	require.Len(t, summary.SlowestTests, 5)
+	assert.Equal(t, GoTestResult{Package: "example.com/a", Test: "TestB", Result: "pass", Elapsed: 2.1}, summary.SlowestTests[0])
+	assert.Equal(t, "TestB/sub", summary.SlowestTests[1].Test)
+	assert.Equal(t, &GoTestCoverage{Statements: 10, Covered: 5, Percent: 50}, summary.Coverage)
+
+	summary, err = NewGoTestSummary(strings.NewReader(`{"Action":"pass","Package":"example.com/a","Elapsed":0.1}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "pass", summary.Result)
+	assert.Nil(t, summary.Coverage)
+}
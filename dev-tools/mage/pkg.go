@@ -34,6 +34,7 @@ func Package() error {
 	platforms := Platforms
 
 	var tasks []interface{}
+	var containers []PackageSpec
 	for _, target := range platforms {
 		for _, pkg := range Packages {
 			if pkg.OS != target.GOOS() || pkg.Arch != "" && pkg.Arch != target.Arch() {
@@ -46,7 +47,7 @@ func Package() error {
 					continue
 				}
 
-				if target.Name == "linux/arm64" && pkgType == Docker && runtime.GOARCH != "arm64" {
+				if target.Name == "linux/arm64" && pkgType == Docker && runtime.GOARCH != "arm64" && !DockerBuildx {
 					log.Printf("Skipping Docker package type because build host isn't arm")
 					continue
 				}
@@ -94,11 +95,18 @@ func Package() error {
 				spec = spec.Evaluate()
 
 				tasks = append(tasks, packageBuilder{target, spec, pkgType}.Build)
+				if pkgType == Docker && DockerBuildx {
+					containers = append(containers, spec)
+				}
 			}
 		}
 	}
 
 	Parallel(tasks...)
+
+	if len(containers) > 0 {
+		return BuildContainers(containers)
+	}
 	return nil
 }
 
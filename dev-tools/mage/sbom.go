@@ -0,0 +1,177 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mage
+
+import (
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// sbomFileExt is the extension of the CycloneDX SBOMs generated next to the cross-built binaries.
+	sbomFileExt = ".sbom.cdx.json"
+
+	cycloneDXSpecVersion = "1.4"
+)
+
+// CycloneDXBOM is a CycloneDX SBOM, only the fields filled from the build
+// information of a go binary are defined.
+type CycloneDXBOM struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    CycloneDXMetadata    `json:"metadata"`
+	Components  []CycloneDXComponent `json:"components"`
+}
+
+// CycloneDXMetadata describes the binary of the SBOM.
+type CycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp,omitempty"`
+	Tools     []CycloneDXTool    `json:"tools,omitempty"`
+	Component CycloneDXComponent `json:"component"`
+}
+
+// CycloneDXTool is the tool which generated the SBOM.
+type CycloneDXTool struct {
+	Vendor string `json:"vendor"`
+	Name   string `json:"name"`
+}
+
+// CycloneDXComponent is the binary or one of the go modules linked into it.
+type CycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+// CycloneDXProperty is a name/value pair of a component.
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CrossBuildSBOMs generates the SBOM of the binary of each target platform in
+// build/golang-crossbuild, the packages ship them along with the binaries.
+func CrossBuildSBOMs() error {
+	for _, platform := range Platforms {
+		binary := filepath.Join("build", "golang-crossbuild",
+			fmt.Sprintf("%s-%s-%s%s", BeatName, platform.GOOS(), platform.Arch(), binaryExtension(platform.GOOS())))
+		if _, err := os.Stat(binary); errors.Is(err, os.ErrNotExist) {
+			log.Printf("Skipping SBOM of %v because the binary is missing", binary)
+			continue
+		}
+		output := strings.TrimSuffix(binary, binaryExtension(platform.GOOS())) + sbomFileExt
+		if err := GenerateSBOM(binary, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateSBOM writes the CycloneDX SBOM of the go modules linked into the binary.
+func GenerateSBOM(binary string, output string) error {
+	info, err := buildinfo.ReadFile(binary)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the build information of %v", binary)
+	}
+
+	version, err := BeatQualifiedVersion()
+	if err != nil {
+		return err
+	}
+	if Snapshot {
+		version += "-SNAPSHOT"
+	}
+
+	bom := NewCycloneDXBOM(info, version, BuildDate())
+	data, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal SBOM")
+	}
+
+	log.Printf("Writing SBOM of %v to %v", binary, output)
+	return errors.Wrapf(os.WriteFile(createDir(output), data, 0644), "failed to write SBOM to %v", output)
+}
+
+// NewCycloneDXBOM returns the SBOM of the binary with the build information, the
+// binary is the component of the metadata and its dependencies are the components.
+func NewCycloneDXBOM(info *debug.BuildInfo, version string, timestamp string) CycloneDXBOM {
+	app := CycloneDXComponent{
+		Type:    "application",
+		BOMRef:  goPURL(info.Main.Path, version),
+		Name:    info.Main.Path,
+		Version: version,
+		PURL:    goPURL(info.Main.Path, version),
+		Properties: []CycloneDXProperty{
+			{Name: "go.version", Value: info.GoVersion},
+		},
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "GOOS", "GOARCH", "CGO_ENABLED", "vcs.revision", "vcs.time":
+			app.Properties = append(app.Properties, CycloneDXProperty{Name: "go.build." + setting.Key, Value: setting.Value})
+		}
+	}
+
+	components := []CycloneDXComponent{{
+		Type:    "library",
+		BOMRef:  goPURL("stdlib", info.GoVersion),
+		Name:    "stdlib",
+		Version: info.GoVersion,
+		PURL:    goPURL("stdlib", info.GoVersion),
+	}}
+	for _, dep := range info.Deps {
+		module := dep
+		var properties []CycloneDXProperty
+		if dep.Replace != nil {
+			// the code of the replacement is linked, the replaced module is only informative
+			module = dep.Replace
+			properties = append(properties, CycloneDXProperty{Name: "go.module.replaces", Value: dep.Path + "@" + dep.Version})
+		}
+		components = append(components, CycloneDXComponent{
+			Type:       "library",
+			BOMRef:     goPURL(module.Path, module.Version),
+			Name:       module.Path,
+			Version:    module.Version,
+			PURL:       goPURL(module.Path, module.Version),
+			Properties: properties,
+		})
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].BOMRef < components[j].BOMRef
+	})
+
+	return CycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: CycloneDXMetadata{
+			Timestamp: timestamp,
+			Tools:     []CycloneDXTool{{Vendor: BeatVendor, Name: "mage"}},
+			Component: app,
+		},
+		Components: components,
+	}
+}
+
+// goPURL returns the package URL of the go module.
+func goPURL(path string, version string) string {
+	if version == "" {
+		return "pkg:golang/" + path
+	}
+	return fmt.Sprintf("pkg:golang/%s@%s", path, version)
+}
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mage
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCycloneDXBOM(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.20.7",
+		Main:      debug.Module{Path: "github.com/elastic/elastic-agent", Version: "(devel)"},
+		Deps: []*debug.Module{
+			{Path: "gopkg.in/yaml.v2", Version: "v2.4.0"},
+			{
+				Path:    "github.com/dop251/goja",
+				Version: "v0.0.0-20200831102558-9af81ddcf0e1",
+				Replace: &debug.Module{Path: "github.com/andrewkroh/goja", Version: "v0.0.0-20190128172624-dd2ac4456e20"},
+			},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "-ldflags", Value: "-s"},
+			{Key: "GOOS", Value: "linux"},
+			{Key: "GOARCH", Value: "arm64"},
+			{Key: "vcs.revision", Value: "9b61139"},
+		},
+	}
+
+	bom := NewCycloneDXBOM(info, "8.11.0-SNAPSHOT", "2023-08-10T12:00:00Z")
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "2023-08-10T12:00:00Z", bom.Metadata.Timestamp)
+	assert.Equal(t, CycloneDXComponent{
+		Type:    "application",
+		BOMRef:  "pkg:golang/github.com/elastic/elastic-agent@8.11.0-SNAPSHOT",
+		Name:    "github.com/elastic/elastic-agent",
+		Version: "8.11.0-SNAPSHOT",
+		PURL:    "pkg:golang/github.com/elastic/elastic-agent@8.11.0-SNAPSHOT",
+		Properties: []CycloneDXProperty{
+			{Name: "go.version", Value: "go1.20.7"},
+			{Name: "go.build.GOOS", Value: "linux"},
+			{Name: "go.build.GOARCH", Value: "arm64"},
+			{Name: "go.build.vcs.revision", Value: "9b61139"},
+		},
+	}, bom.Metadata.Component)
+	assert.Equal(t, []CycloneDXComponent{
+		{
+			Type:    "library",
+			BOMRef:  "pkg:golang/github.com/andrewkroh/goja@v0.0.0-20190128172624-dd2ac4456e20",
+			Name:    "github.com/andrewkroh/goja",
+			Version: "v0.0.0-20190128172624-dd2ac4456e20",
+			PURL:    "pkg:golang/github.com/andrewkroh/goja@v0.0.0-20190128172624-dd2ac4456e20",
+			Properties: []CycloneDXProperty{
+				{Name: "go.module.replaces", Value: "github.com/dop251/goja@v0.0.0-20200831102558-9af81ddcf0e1"},
+			},
+		},
+		{
+			Type:    "library",
+			BOMRef:  "pkg:golang/gopkg.in/yaml.v2@v2.4.0",
+			Name:    "gopkg.in/yaml.v2",
+			Version: "v2.4.0",
+			PURL:    "pkg:golang/gopkg.in/yaml.v2@v2.4.0",
+		},
+		{
+			Type:    "library",
+			BOMRef:  "pkg:golang/stdlib@go1.20.7",
+			Name:    "stdlib",
+			Version: "go1.20.7",
+			PURL:    "pkg:golang/stdlib@go1.20.7",
+		},
+	}, bom.Components)
+}
@@ -69,9 +69,11 @@ var (
 
 	BeatProjectType ProjectType
 
-	Snapshot      bool
-	DevBuild      bool
-	ExternalBuild bool
+	Snapshot       bool
+	DevBuild       bool
+	ExternalBuild  bool
+	FaultInjection bool
+	DockerBuildx   bool
 
 	versionQualified bool
 	versionQualifier string
@@ -122,6 +124,16 @@ func init() {
 		panic(fmt.Errorf("failed to parse EXTERNAL env value: %w", err))
 	}
 
+	FaultInjection, err = strconv.ParseBool(EnvOr("FAULT_INJECTION", "false"))
+	if err != nil {
+		panic(fmt.Errorf("failed to parse FAULT_INJECTION env value: %w", err))
+	}
+
+	DockerBuildx, err = strconv.ParseBool(EnvOr("DOCKER_BUILDX", "false"))
+	if err != nil {
+		panic(fmt.Errorf("failed to parse DOCKER_BUILDX env value: %w", err))
+	}
+
 	versionQualifier, versionQualified = os.LookupEnv("VERSION_QUALIFIER")
 }
 
@@ -174,6 +186,7 @@ func varMap(args ...map[string]interface{}) map[string]interface{} {
 		"Snapshot":        Snapshot,
 		"DEV":             DevBuild,
 		"EXTERNAL":        ExternalBuild,
+		"FAULT_INJECTION": FaultInjection,
 		"Qualifier":       versionQualifier,
 		"CI":              CI,
 	}
@@ -0,0 +1,121 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/backfill"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// backfillCoordinator is the part of the coordinator used by the Backfill handler.
+type backfillCoordinator interface {
+	State() coordinator.State
+	PerformAction(ctx context.Context, comp component.Component, unit component.Unit, name string, params map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Backfill is the handler of the backfill actions, it has the log inputs re-read a time range or
+// a set of files.
+type Backfill struct {
+	log   *logger.Logger
+	coord backfillCoordinator
+}
+
+// NewBackfill creates a new Backfill handler.
+func NewBackfill(log *logger.Logger, coord backfillCoordinator) *Backfill {
+	return &Backfill{
+		log:   log,
+		coord: coord,
+	}
+}
+
+// Handle starts the backfill in the background and acks the action once every unit is done, the
+// inputs can take a while to reply.
+func (h *Backfill) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker) error {
+	h.log.Debugf("handlerBackfill: action '%+v' received", a)
+	action, ok := a.(*fleetapi.ActionBackfill)
+	if !ok {
+		return fmt.Errorf("invalid type, expected ActionBackfill and received %T", a)
+	}
+	go h.backfill(ctx, action, ack)
+	return nil
+}
+
+func (h *Backfill) backfill(ctx context.Context, action *fleetapi.ActionBackfill, ack acker.Acker) {
+	start := time.Now().UTC()
+	defer func() {
+		action.StartedAt = start.Format(time.RFC3339Nano)
+		action.CompletedAt = time.Now().UTC().Format(time.RFC3339Nano)
+		if err := ack.Ack(ctx, action); err != nil {
+			h.log.Errorw("failed to ack backfill action", "error.message", err, "action", action)
+		}
+		if err := ack.Commit(ctx); err != nil {
+			h.log.Errorw("failed to commit backfill action", "error.message", err, "action", action)
+		}
+	}()
+
+	req, err := backfillRequest(action)
+	if err != nil {
+		action.Err = err
+		return
+	}
+	targets, err := backfill.Targets(h.coord.State().Components, req.Inputs)
+	if err != nil {
+		action.Err = err
+		return
+	}
+
+	results := backfill.Run(ctx, h.log, h.coord.PerformAction, targets, req, nil)
+	units := make([]interface{}, 0, len(results))
+	failed := 0
+	for _, r := range results {
+		unit := map[string]interface{}{
+			"component_id": r.ComponentID,
+			"unit_id":      r.UnitID,
+		}
+		if r.Err != nil {
+			failed++
+			unit["error"] = r.Err.Error()
+		}
+		if len(r.Response) > 0 {
+			unit["response"] = r.Response
+		}
+		units = append(units, unit)
+	}
+	action.Response = map[string]interface{}{
+		backfill.ActionName: map[string]interface{}{
+			"units": units,
+		},
+	}
+	if failed > 0 {
+		action.Err = fmt.Errorf("backfill failed for %d of %d unit(s)", failed, len(results))
+	}
+}
+
+func backfillRequest(action *fleetapi.ActionBackfill) (backfill.Request, error) {
+	req := backfill.Request{
+		Inputs: action.Inputs,
+		Paths:  action.Paths,
+	}
+	var err error
+	if action.From != "" {
+		if req.From, err = time.Parse(time.RFC3339, action.From); err != nil {
+			return req, fmt.Errorf("invalid start of the time range: %w", err)
+		}
+	}
+	if action.To != "" {
+		if req.To, err = time.Parse(time.RFC3339, action.To); err != nil {
+			return req, fmt.Errorf("invalid end of the time range: %w", err)
+		}
+	}
+	return req, req.Validate()
+}
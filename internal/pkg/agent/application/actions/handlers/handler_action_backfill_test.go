@@ -0,0 +1,118 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions/handlers/mocks"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+type fakeBackfillCoordinator struct {
+	params map[string]map[string]interface{}
+}
+
+func (c *fakeBackfillCoordinator) State() coordinator.State {
+	unit := func(id, inputID string) component.Unit {
+		return component.Unit{
+			ID:     id,
+			Type:   client.UnitTypeInput,
+			Config: &proto.UnitExpectedConfig{Id: inputID, Type: "filestream"},
+		}
+	}
+	return coordinator.State{
+		Components: []runtime.ComponentComponentState{
+			{Component: component.Component{
+				ID:    "filestream-default",
+				Units: []component.Unit{unit("filestream-default-logs-1", "logs-1"), unit("filestream-default-logs-2", "logs-2")},
+			}},
+		},
+	}
+}
+
+func (c *fakeBackfillCoordinator) PerformAction(_ context.Context, _ component.Component, unit component.Unit, _ string, params map[string]interface{}) (map[string]interface{}, error) {
+	c.params[unit.ID] = params
+	if unit.ID == "filestream-default-logs-2" {
+		return nil, errors.New("action backfill not registered")
+	}
+	return map[string]interface{}{"files": 2.0}, nil
+}
+
+func TestBackfillHandler(t *testing.T) {
+	log, _ := logger.NewTesting("backfill")
+	coord := &fakeBackfillCoordinator{params: make(map[string]map[string]interface{})}
+	h := NewBackfill(log, coord)
+
+	var acked *fleetapi.ActionBackfill
+	ack := mocks.NewAcker(t)
+	ack.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, a fleetapi.Action) error {
+		acked = a.(*fleetapi.ActionBackfill)
+		return nil
+	})
+	ack.EXPECT().Commit(mock.Anything).Return(nil)
+
+	action := &fleetapi.ActionBackfill{
+		ActionID:   "backfill-1",
+		ActionType: fleetapi.ActionTypeBackfill,
+		From:       "2023-09-10T10:00:00Z",
+		To:         "2023-09-10T12:00:00Z",
+	}
+	h.backfill(context.Background(), action, ack)
+
+	require.NotNil(t, acked)
+	assert.EqualError(t, acked.Err, "backfill failed for 1 of 2 unit(s)")
+	assert.NotEmpty(t, acked.StartedAt)
+	assert.NotEmpty(t, acked.CompletedAt)
+	assert.Equal(t, map[string]interface{}{"from": "2023-09-10T10:00:00Z", "to": "2023-09-10T12:00:00Z"}, coord.params["filestream-default-logs-1"])
+	assert.Equal(t, map[string]interface{}{
+		"backfill": map[string]interface{}{
+			"units": []interface{}{
+				map[string]interface{}{
+					"component_id": "filestream-default",
+					"unit_id":      "filestream-default-logs-1",
+					"response":     map[string]interface{}{"files": 2.0},
+				},
+				map[string]interface{}{
+					"component_id": "filestream-default",
+					"unit_id":      "filestream-default-logs-2",
+					"error":        "action backfill not registered",
+				},
+			},
+		},
+	}, acked.AckEvent().ActionResponse)
+}
+
+func TestBackfillHandlerInvalidAction(t *testing.T) {
+	log, _ := logger.NewTesting("backfill")
+	coord := &fakeBackfillCoordinator{params: make(map[string]map[string]interface{})}
+	h := NewBackfill(log, coord)
+
+	ack := mocks.NewAcker(t)
+	ack.EXPECT().Ack(mock.Anything, mock.Anything).Return(nil)
+	ack.EXPECT().Commit(mock.Anything).Return(nil)
+
+	action := &fleetapi.ActionBackfill{ActionID: "backfill-1", ActionType: fleetapi.ActionTypeBackfill, Inputs: []string{"logs-3"}, From: "1h"}
+	h.backfill(context.Background(), action, ack)
+	assert.EqualError(t, action.Err, `invalid start of the time range: parsing time "1h" as "2006-01-02T15:04:05Z07:00": cannot parse "1h" as "2006"`)
+
+	action = &fleetapi.ActionBackfill{ActionID: "backfill-2", ActionType: fleetapi.ActionTypeBackfill, Inputs: []string{"logs-3"}, Paths: []string{"/var/log/app.log"}}
+	h.backfill(context.Background(), action, ack)
+	assert.EqualError(t, action.Err, "no input found with the ID logs-3")
+	assert.Empty(t, coord.params)
+}
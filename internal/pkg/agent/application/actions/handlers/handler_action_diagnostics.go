@@ -131,11 +131,18 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 	h.log.Debug("Gathering unit diagnostics.")
 	uDiag := h.diagUnits(ctx)
 
+	redactor, err := diagnostics.NewRedactor(diagnostics.CurrentRedaction())
+	if err != nil {
+		// the configuration is validated when applied, fallback to the standard profile
+		h.log.Warnw("Diagnostics action unable to use the configured redaction profile, using the standard profile instead.", "error.message", err)
+		redactor = nil
+	}
+
 	var r io.Reader
 	// attempt to create the a temporary diagnostics file on disk in order to avoid loading a
 	// potentially large file in memory.
 	// if on-disk creation fails an in-memory buffer is used.
-	f, s, err := h.diagFile(aDiag, uDiag)
+	f, s, err := h.diagFile(redactor, aDiag, uDiag)
 	if err != nil {
 		var b bytes.Buffer
 		h.log.Warnw("Diagnostics action unable to use temporary file, using buffer instead.", "error.message", err)
@@ -145,7 +152,7 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 				h.log.Warn(str)
 			}
 		}()
-		err := diagnostics.ZipArchive(&wBuf, &b, aDiag, uDiag)
+		err := diagnostics.ZipArchive(&wBuf, &b, redactor, aDiag, uDiag)
 		if err != nil {
 			h.log.Errorw(
 				"diagnostics action handler failed generate zip archive",
@@ -241,7 +248,7 @@ func (h *Diagnostics) diagUnits(ctx context.Context) []client.DiagnosticUnitResu
 }
 
 // diagFile will write the diagnostics to a temporary file and return the file ready to be read
-func (h *Diagnostics) diagFile(aDiag []client.DiagnosticFileResult, uDiag []client.DiagnosticUnitResult) (*os.File, int64, error) {
+func (h *Diagnostics) diagFile(redactor *diagnostics.Redactor, aDiag []client.DiagnosticFileResult, uDiag []client.DiagnosticUnitResult) (*os.File, int64, error) {
 	f, err := os.CreateTemp("", "elastic-agent-diagnostics")
 	if err != nil {
 		return nil, 0, err
@@ -254,7 +261,7 @@ func (h *Diagnostics) diagFile(aDiag []client.DiagnosticFileResult, uDiag []clie
 			h.log.Warn(str)
 		}
 	}()
-	if err := diagnostics.ZipArchive(&wBuf, f, aDiag, uDiag); err != nil {
+	if err := diagnostics.ZipArchive(&wBuf, f, redactor, aDiag, uDiag); err != nil {
 		os.Remove(name)
 		return nil, 0, err
 	}
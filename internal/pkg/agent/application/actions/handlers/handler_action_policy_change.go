@@ -14,9 +14,9 @@ import (
 	"sort"
 	"time"
 
+	"go.elastic.co/apm"
 	"gopkg.in/yaml.v2"
 
-	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
@@ -24,6 +24,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
@@ -104,6 +105,9 @@ func (h *PolicyChangeHandler) Handle(ctx context.Context, a fleetapi.Action, ack
 		return errors.New(err, "could not parse the configuration from the policy", errors.TypeConfig)
 	}
 
+	h.log.Infow(fmt.Sprintf("Applying revision %v of policy %v", action.Policy["revision"], action.Policy["id"]),
+		"policy.id", action.Policy["id"], "policy.revision", action.Policy["revision"], "action_id", action.ActionID,
+		"event.action", diagnostics.EventActionPolicyChange)
 	h.log.Debugf("handlerPolicyChange: emit configuration for action %+v", a)
 	err = h.handleFleetServerHosts(ctx, c)
 	if err != nil {
@@ -149,6 +153,7 @@ func (h *PolicyChangeHandler) handleFleetServerHosts(ctx context.Context, c *con
 	prevHost := h.config.Fleet.Client.Host
 	prevHosts := h.config.Fleet.Client.Hosts
 	prevProxy := h.config.Fleet.Client.Transport.Proxy
+	prevHeaders := h.config.Fleet.Client.Headers
 	h.config.Fleet.Client.Protocol = cfg.Fleet.Client.Protocol
 	h.config.Fleet.Client.Path = cfg.Fleet.Client.Path
 	h.config.Fleet.Client.Host = cfg.Fleet.Client.Host
@@ -166,6 +171,15 @@ func (h *PolicyChangeHandler) handleFleetServerHosts(ctx context.Context, c *con
 		h.log.Debug("received proxy from fleet, applying it")
 	}
 
+	// Like the proxy, absent headers from fleet are ignored so the headers set by --fleet-header
+	// are kept, the headers sent by fleet-server take precedence.
+	if len(cfg.Fleet.Client.Headers) == 0 {
+		h.log.Debug("headers from fleet are empty, the headers will not be changed")
+	} else {
+		h.config.Fleet.Client.Headers = cfg.Fleet.Client.Headers
+		h.log.Debug("received headers from fleet, applying them")
+	}
+
 	// rollback on failure
 	defer func() {
 		if err != nil {
@@ -174,6 +188,7 @@ func (h *PolicyChangeHandler) handleFleetServerHosts(ctx context.Context, c *con
 			h.config.Fleet.Client.Host = prevHost
 			h.config.Fleet.Client.Hosts = prevHosts
 			h.config.Fleet.Client.Transport.Proxy = prevProxy
+			h.config.Fleet.Client.Headers = prevHeaders
 		}
 	}()
 
@@ -240,7 +255,7 @@ func clientEqual(k1 remote.Config, k2 remote.Config) bool {
 		}
 	}
 
-	headersEqual := func(h1, h2 httpcommon.ProxyHeaders) bool {
+	headersEqual := func(h1, h2 map[string]string) bool {
 		if len(h1) != len(h2) {
 			return false
 		}
@@ -262,6 +277,11 @@ func clientEqual(k1 remote.Config, k2 remote.Config) bool {
 		return false
 	}
 
+	// different headers, absent headers keep the current ones
+	if len(k2.Headers) > 0 && !headersEqual(k1.Headers, k2.Headers) {
+		return false
+	}
+
 	return true
 }
 
@@ -318,6 +338,11 @@ func (l *policyChange) Config() *config.Config {
 	return l.cfg
 }
 
+// Transaction returns the APM transaction of the action, the configuration is applied in its trace.
+func (l *policyChange) Transaction() *apm.Transaction {
+	return apm.TransactionFromContext(l.ctx)
+}
+
 func (l *policyChange) Ack() error {
 	if l.action == nil {
 		return nil
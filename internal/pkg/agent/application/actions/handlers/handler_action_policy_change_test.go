@@ -406,6 +406,56 @@ func TestPolicyChangeHandler_handleFleetServerHosts(t *testing.T) {
 				wantProxy,
 				h.config.Fleet.Client.Transport.Proxy.URL.String())
 		})
+
+	t.Run("A policy with headers changes the fleet client, absent headers keep them", func(t *testing.T) {
+		log, _ := logger.NewTesting("TestPolicyChangeHandler")
+		var setterCalledCount int
+		setter := testSetter{SetClientFn: func(c client.Sender) {
+			setterCalledCount++
+		}}
+
+		originalCfg := &configuration.Configuration{
+			Fleet: &configuration.FleetAgentConfig{
+				Server: &configuration.FleetServerConfig{
+					Host: fleetServerHost,
+					Port: uint16(fleetServerPort),
+				},
+				AccessAPIKey: "ignore",
+				Client: remote.Config{
+					Host:    fleetServer.URL,
+					Headers: map[string]string{"X-Org-Token": "original"}},
+			},
+			Settings: configuration.DefaultSettingsConfig()}
+
+		h := PolicyChangeHandler{
+			agentInfo: &info.AgentInfo{},
+			config:    originalCfg,
+			store:     &storage.NullStore{},
+			setters:   []actions.ClientSetter{&setter},
+			log:       log,
+		}
+
+		cfg := config.MustNewConfigFrom(
+			map[string]interface{}{
+				"fleet.host":    fleetServer.URL,
+				"fleet.headers": map[string]interface{}{"X-Org-Token": "policy"}})
+
+		err := h.handleFleetServerHosts(context.Background(), cfg)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, setterCalledCount)
+		assert.Equal(t, map[string]string{"X-Org-Token": "policy"}, h.config.Fleet.Client.Headers)
+
+		cfg = config.MustNewConfigFrom(
+			map[string]interface{}{
+				"fleet.host": fleetServer.URL})
+
+		err = h.handleFleetServerHosts(context.Background(), cfg)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, setterCalledCount)
+		assert.Equal(t, map[string]string{"X-Org-Token": "policy"}, h.config.Fleet.Client.Headers)
+	})
 }
 
 type testAcker struct {
@@ -14,6 +14,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
@@ -35,7 +36,7 @@ func (u *mockUpgradeManager) Reload(rawConfig *config.Config) error {
 	return nil
 }
 
-func (u *mockUpgradeManager) Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error) {
+func (u *mockUpgradeManager) Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, details *details.Details, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error) {
 	select {
 	case <-time.After(2 * time.Second):
 		u.msgChan <- "completed " + version
@@ -66,6 +67,7 @@ func TestUpgradeHandler(t *testing.T) {
 		configuration.DefaultConfiguration(),
 		logger.DefaultLogLevel,
 		agentInfo,
+		nil,
 		component.RuntimeSpecs{},
 		nil,
 		&mockUpgradeManager{msgChan: msgChan},
@@ -98,6 +100,7 @@ func TestUpgradeHandlerSameVersion(t *testing.T) {
 		configuration.DefaultConfiguration(),
 		logger.DefaultLogLevel,
 		agentInfo,
+		nil,
 		component.RuntimeSpecs{},
 		nil,
 		&mockUpgradeManager{msgChan: msgChan},
@@ -132,6 +135,7 @@ func TestUpgradeHandlerNewVersion(t *testing.T) {
 		configuration.DefaultConfiguration(),
 		logger.DefaultLogLevel,
 		agentInfo,
+		nil,
 		component.RuntimeSpecs{},
 		nil,
 		&mockUpgradeManager{msgChan: msgChan},
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
 	"github.com/elastic/elastic-agent/pkg/features"
 
 	"go.elastic.co/apm"
@@ -24,6 +25,10 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/composable"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/logrotation"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -85,10 +90,27 @@ func New(
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
+	// the DNS resolution, the trust store and the revocation checks are applied before the clients
+	// of Fleet and of the downloads connect
+	if err := resolver.Apply(rawConfig); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := truststore.Apply(rawConfig); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := revocation.Apply(rawConfig); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := logrotation.Apply(rawConfig); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := diagnostics.ApplyRedaction(rawConfig); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
 
 	// monitoring is not supported in bootstrap mode https://github.com/elastic/elastic-agent/issues/1761
 	isMonitoringSupported := !disableMonitoring && cfg.Settings.V1MonitoringEnabled
-	upgrader := upgrade.NewUpgrader(log, cfg.Settings.DownloadConfig, agentInfo)
+	upgrader := upgrade.NewUpgrader(log, cfg.Settings.DownloadConfig, agentInfo, caps)
 	monitor := monitoring.New(isMonitoringSupported, cfg.Settings.DownloadConfig.OS(), cfg.Settings.MonitoringConfig, agentInfo)
 
 	runtime, err := runtime.NewManager(
@@ -99,6 +121,9 @@ func New(
 		tracer,
 		monitor,
 		cfg.Settings.GRPC,
+		cfg.Settings.Shutdown,
+		cfg.Settings.CoreDumps,
+		cfg.Settings.Sandbox,
 	)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to initialize runtime manager: %w", err)
@@ -117,9 +142,15 @@ func New(
 	} else if configuration.IsStandalone(cfg.Fleet) {
 		log.Info("Parsed configuration and determined agent is managed locally")
 
-		loader := config.NewLoader(log, paths.ExternalInputs())
-		discover := config.Discoverer(pathConfigFile, cfg.Settings.Path, paths.ExternalInputs())
-		if !cfg.Settings.Reload.Enabled {
+		loader := config.NewLoader(log, paths.ExternalInputs()).WithFragments(cfg.Settings.Fragments)
+		discover := cfg.Settings.Fragments.Discoverer(config.Discoverer(pathConfigFile, cfg.Settings.Path, paths.ExternalInputs()))
+		if cfg.Settings.RemotePolicy != nil && cfg.Settings.RemotePolicy.Enabled {
+			log.Infof("Policy is pulled from %s every %s", cfg.Settings.RemotePolicy.URL, cfg.Settings.RemotePolicy.Interval)
+			configMgr, err = newRemotePolicy(log, cfg.Settings.RemotePolicy, paths.Data(), discover, loader)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to initialize remote policy: %w", err)
+			}
+		} else if !cfg.Settings.Reload.Enabled {
 			log.Debug("Reloading of configuration is off")
 			configMgr = newOnce(log, discover, loader)
 		} else {
@@ -148,7 +179,7 @@ func New(
 				EndpointSignedComponentModifier(),
 			)
 
-			managed, err = newManagedConfigManager(log, agentInfo, cfg, store, runtime, fleetInitTimeout)
+			managed, err = newManagedConfigManager(log, agentInfo, cfg, store, runtime, tracer, fleetInitTimeout)
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -156,12 +187,15 @@ func New(
 		}
 	}
 
+	// the outputs trust the trust store of the operating system in all the modes
+	compModifiers = append(compModifiers, TrustStoreComponentModifier(log))
+
 	composable, err := composable.New(log, rawConfig, composableManaged)
 	if err != nil {
 		return nil, nil, nil, errors.New(err, "failed to initialize composable controller")
 	}
 
-	coord := coordinator.New(log, cfg, logLevel, agentInfo, specs, reexec, upgrader, runtime, configMgr, composable, caps, monitor, isManaged, compModifiers...)
+	coord := coordinator.New(log, cfg, logLevel, agentInfo, tracer, specs, reexec, upgrader, runtime, configMgr, composable, caps, monitor, isManaged, compModifiers...)
 	if managed != nil {
 		// the coordinator requires the config manager as well as in managed-mode the config manager requires the
 		// coordinator, so it must be set here once the coordinator is created
@@ -173,6 +207,7 @@ func New(
 	if err := features.Apply(rawConfig); err != nil {
 		return nil, nil, nil, fmt.Errorf("could not parse and apply feature flags config: %w", err)
 	}
+	features.RegisterMetrics()
 
 	return coord, configMgr, composable, nil
 }
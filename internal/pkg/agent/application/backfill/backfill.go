@@ -0,0 +1,192 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package backfill has the log inputs re-read a time range or a set of files, to recover from a
+// loss of data downstream without editing the registry of the inputs on each host.
+//
+// The agent sends the backfill action to the units of the log inputs one after the other, so the
+// host isn't loaded by all the inputs re-reading their files at once. The inputs that don't
+// implement the action reply with an error, reported as the result of their unit.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// ActionName is the name of the action sent to the units.
+const ActionName = "backfill"
+
+// unitTimeout bounds the time a unit takes to reply to the action.
+const unitTimeout = time.Hour
+
+// logInputTypes are the types of the inputs reading files that can be backfilled.
+var logInputTypes = map[string]bool{
+	"filestream": true,
+	"log":        true,
+	"container":  true,
+}
+
+// Request describes what the log inputs re-read.
+type Request struct {
+	// Inputs are the IDs of the inputs to backfill, every log input when empty.
+	Inputs []string
+	// Paths are the files to re-read, every file of the inputs when empty.
+	Paths []string
+	// From and To bound the time range of the events to re-read, unbounded when zero.
+	From time.Time
+	To   time.Time
+}
+
+// Validate returns an error when the request would re-read everything or its time range is empty.
+func (r Request) Validate() error {
+	if len(r.Paths) == 0 && r.From.IsZero() && r.To.IsZero() {
+		return errors.New("a backfill needs the paths of the files or a time range to re-read")
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return fmt.Errorf("the start of the time range %s is not before its end %s", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Params returns the parameters of the action sent to the units.
+func (r Request) Params() map[string]interface{} {
+	params := make(map[string]interface{})
+	if len(r.Paths) > 0 {
+		paths := make([]interface{}, 0, len(r.Paths))
+		for _, p := range r.Paths {
+			paths = append(paths, p)
+		}
+		params["paths"] = paths
+	}
+	if !r.From.IsZero() {
+		params["from"] = r.From.UTC().Format(time.RFC3339Nano)
+	}
+	if !r.To.IsZero() {
+		params["to"] = r.To.UTC().Format(time.RFC3339Nano)
+	}
+	return params
+}
+
+// Target is a unit of a log input to backfill.
+type Target struct {
+	Component component.Component
+	Unit      component.Unit
+}
+
+// Targets returns the units of the log inputs of the running components matching the IDs of the
+// inputs, every log input when none is given. An error is returned when an input isn't found or
+// doesn't read files.
+func Targets(states []runtime.ComponentComponentState, inputs []string) ([]Target, error) {
+	wanted := make(map[string]bool, len(inputs))
+	for _, id := range inputs {
+		wanted[id] = false
+	}
+	var targets []Target
+	var notLogInputs []string
+	for _, state := range states {
+		comp := state.Component
+		for _, unit := range comp.Units {
+			if unit.Type != client.UnitTypeInput || unit.Config == nil {
+				continue
+			}
+			id := unit.Config.Id
+			if _, ok := wanted[id]; len(inputs) > 0 && !ok {
+				continue
+			}
+			if len(inputs) > 0 {
+				wanted[id] = true
+			}
+			if !logInputTypes[unit.Config.Type] {
+				if len(inputs) > 0 {
+					notLogInputs = append(notLogInputs, fmt.Sprintf("%s (%s)", id, unit.Config.Type))
+				}
+				continue
+			}
+			targets = append(targets, Target{Component: comp, Unit: unit})
+		}
+	}
+
+	var missing []string
+	for id, found := range wanted {
+		if !found {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	switch {
+	case len(missing) > 0:
+		return nil, fmt.Errorf("no input found with the ID %s", strings.Join(missing, ", "))
+	case len(notLogInputs) > 0:
+		return nil, fmt.Errorf("the inputs %s don't read files and can't be backfilled", strings.Join(notLogInputs, ", "))
+	case len(targets) == 0:
+		return nil, errors.New("no log input to backfill")
+	}
+	return targets, nil
+}
+
+// Result is the result of the backfill of a unit.
+type Result struct {
+	ComponentID string
+	UnitID      string
+	Response    map[string]interface{}
+	Err         error
+}
+
+// PerformFunc performs an action on a unit, like the coordinator does.
+type PerformFunc func(ctx context.Context, comp component.Component, unit component.Unit, name string, params map[string]interface{}) (map[string]interface{}, error)
+
+// Run backfills the targets one after the other. progress is called with the result of each unit
+// once it is done, with the number of units done so far.
+func Run(ctx context.Context, log *logger.Logger, perform PerformFunc, targets []Target, req Request, progress func(done, total int, result Result)) []Result {
+	params := req.Params()
+	results := make([]Result, 0, len(targets))
+	for i, t := range targets {
+		result := Result{ComponentID: t.Component.ID, UnitID: t.Unit.ID}
+		if err := ctx.Err(); err != nil {
+			result.Err = fmt.Errorf("backfill not started: %w", err)
+		} else {
+			log.Infow(fmt.Sprintf("Backfill of unit %s of component %s started (%d/%d)", t.Unit.ID, t.Component.ID, i+1, len(targets)),
+				"component.id", t.Component.ID, "unit.id", t.Unit.ID, "event.action", diagnostics.EventActionBackfill)
+			result.Response, result.Err = performUnit(ctx, perform, t, params)
+		}
+		if result.Err != nil {
+			log.Warnw(fmt.Sprintf("Backfill of unit %s of component %s failed: %s", t.Unit.ID, t.Component.ID, result.Err),
+				"component.id", t.Component.ID, "unit.id", t.Unit.ID, "event.action", diagnostics.EventActionBackfill)
+		} else {
+			log.Infow(fmt.Sprintf("Backfill of unit %s of component %s done (%d/%d)", t.Unit.ID, t.Component.ID, i+1, len(targets)),
+				"component.id", t.Component.ID, "unit.id", t.Unit.ID, "event.action", diagnostics.EventActionBackfill)
+		}
+		results = append(results, result)
+		if progress != nil {
+			progress(i+1, len(targets), result)
+		}
+	}
+	return results
+}
+
+func performUnit(ctx context.Context, perform PerformFunc, t Target, params map[string]interface{}) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, unitTimeout)
+	defer cancel()
+	res, err := perform(ctx, t.Component, t.Unit, ActionName, params)
+	if err != nil {
+		return nil, err
+	}
+	// like the application actions, a unit can report its failure in the response
+	if msg, ok := res["error"].(string); ok && msg != "" {
+		return res, errors.New(msg)
+	}
+	return res, nil
+}
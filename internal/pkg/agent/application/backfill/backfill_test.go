@@ -0,0 +1,135 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package backfill
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func inputUnit(id, inputID, inputType string) component.Unit {
+	return component.Unit{
+		ID:     id,
+		Type:   client.UnitTypeInput,
+		Config: &proto.UnitExpectedConfig{Id: inputID, Type: inputType},
+	}
+}
+
+var states = []runtime.ComponentComponentState{
+	{Component: component.Component{
+		ID: "filestream-default",
+		Units: []component.Unit{
+			inputUnit("filestream-default-logs-1", "logs-1", "filestream"),
+			inputUnit("filestream-default-logs-2", "logs-2", "log"),
+			{ID: "filestream-default", Type: client.UnitTypeOutput},
+		},
+	}},
+	{Component: component.Component{
+		ID:    "system/metrics-default",
+		Units: []component.Unit{inputUnit("system/metrics-default-cpu", "cpu", "system/metrics")},
+	}},
+}
+
+func TestRequestValidate(t *testing.T) {
+	from := time.Date(2023, 9, 10, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, Request{From: from}.Validate())
+	assert.NoError(t, Request{Paths: []string{"/var/log/app.log"}}.Validate())
+	assert.EqualError(t, Request{Inputs: []string{"logs-1"}}.Validate(), "a backfill needs the paths of the files or a time range to re-read")
+	assert.EqualError(t, Request{From: from, To: from}.Validate(), "the start of the time range 2023-09-10T10:00:00Z is not before its end 2023-09-10T10:00:00Z")
+}
+
+func TestRequestParams(t *testing.T) {
+	req := Request{
+		Paths: []string{"/var/log/app.log"},
+		From:  time.Date(2023, 9, 10, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+	}
+	assert.Equal(t, map[string]interface{}{
+		"paths": []interface{}{"/var/log/app.log"},
+		"from":  "2023-09-10T10:00:00Z",
+	}, req.Params())
+}
+
+func TestTargets(t *testing.T) {
+	targets, err := Targets(states, nil)
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+	assert.Equal(t, "filestream-default-logs-1", targets[0].Unit.ID)
+	assert.Equal(t, "filestream-default-logs-2", targets[1].Unit.ID)
+
+	targets, err = Targets(states, []string{"logs-2"})
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, "filestream-default-logs-2", targets[0].Unit.ID)
+
+	_, err = Targets(states, []string{"logs-1", "logs-3"})
+	assert.EqualError(t, err, "no input found with the ID logs-3")
+
+	_, err = Targets(states, []string{"cpu"})
+	assert.EqualError(t, err, "the inputs cpu (system/metrics) don't read files and can't be backfilled")
+
+	_, err = Targets(states[1:], nil)
+	assert.EqualError(t, err, "no log input to backfill")
+}
+
+func TestRun(t *testing.T) {
+	log, _ := logger.NewTesting("backfill")
+	targets, err := Targets(states, nil)
+	require.NoError(t, err)
+	req := Request{From: time.Date(2023, 9, 10, 10, 0, 0, 0, time.UTC)}
+
+	var performed []string
+	perform := func(_ context.Context, _ component.Component, unit component.Unit, name string, params map[string]interface{}) (map[string]interface{}, error) {
+		assert.Equal(t, ActionName, name)
+		assert.Equal(t, req.Params(), params)
+		performed = append(performed, unit.ID)
+		switch unit.ID {
+		case "filestream-default-logs-1":
+			return map[string]interface{}{"files": 3.0}, nil
+		default:
+			return nil, errors.New("unknown action")
+		}
+	}
+	var progress []int
+	results := Run(context.Background(), log, perform, targets, req, func(done, total int, _ Result) {
+		assert.Equal(t, 2, total)
+		progress = append(progress, done)
+	})
+
+	assert.Equal(t, []string{"filestream-default-logs-1", "filestream-default-logs-2"}, performed)
+	assert.Equal(t, []int{1, 2}, progress)
+	assert.Equal(t, []Result{
+		{ComponentID: "filestream-default", UnitID: "filestream-default-logs-1", Response: map[string]interface{}{"files": 3.0}},
+		{ComponentID: "filestream-default", UnitID: "filestream-default-logs-2", Err: errors.New("unknown action")},
+	}, results)
+}
+
+func TestRunCancelled(t *testing.T) {
+	log, _ := logger.NewTesting("backfill")
+	targets, err := Targets(states, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	perform := func(_ context.Context, _ component.Component, unit component.Unit, _ string, _ map[string]interface{}) (map[string]interface{}, error) {
+		// the backfill is cancelled while the first unit re-reads its files
+		cancel()
+		return map[string]interface{}{"error": "cancelled by the user"}, nil
+	}
+	results := Run(ctx, log, perform, targets, Request{Paths: []string{"/var/log/app.log"}}, nil)
+	require.Len(t, results, 2)
+	assert.EqualError(t, results[0].Err, "cancelled by the user")
+	assert.EqualError(t, results[1].Err, "backfill not started: context canceled")
+}
@@ -0,0 +1,220 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package certexpiry monitors the expiration of the certificates used by the agent and reports the
+// ones about to expire, before the connections to Fleet Server or to the outputs start failing.
+package certexpiry
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Material is a certificate or a bundle of certificates of a TLS configuration.
+type Material struct {
+	// Source is the setting of the material, like fleet.ssl.certificate_authorities.
+	Source string
+	// Value is the path of the PEM file or the inline PEM.
+	Value string
+}
+
+// Expiry is a certificate about to expire.
+type Expiry struct {
+	Source   string
+	Subject  string
+	NotAfter time.Time
+}
+
+// DaysRemaining returns the number of days before the expiration of the certificate, negative once
+// it expired.
+func (e Expiry) DaysRemaining(now time.Time) int {
+	return daysRemaining(e.NotAfter, now)
+}
+
+func (e Expiry) message(now time.Time) string {
+	days := e.DaysRemaining(now)
+	if days < 0 {
+		return fmt.Sprintf("certificate %q of %s expired on %s", e.Subject, e.Source, e.NotAfter.UTC().Format("2006-01-02"))
+	}
+	return fmt.Sprintf("certificate %q of %s expires in %d day(s) on %s", e.Subject, e.Source, days, e.NotAfter.UTC().Format("2006-01-02"))
+}
+
+// Result is the result of a check of the certificates.
+type Result struct {
+	// Checked is the number of certificates checked.
+	Checked int
+	// Expiring are the certificates expiring within the warning period, or expired, the soonest first.
+	Expiring []Expiry
+	// MinDaysRemaining is the number of days before the expiration of the first certificate to expire.
+	MinDaysRemaining int
+	// Errors are the materials that could not be read.
+	Errors []error
+}
+
+// Err returns the error reported for the expiring certificates, nil when none expires.
+func (r Result) Err(now time.Time) error {
+	if len(r.Expiring) == 0 {
+		return nil
+	}
+	msg := r.Expiring[0].message(now)
+	if len(r.Expiring) > 1 {
+		msg = fmt.Sprintf("%s, and %d other certificate(s) expire soon", msg, len(r.Expiring)-1)
+	}
+	return errors.New(msg)
+}
+
+// Check reads the certificates of the materials and returns the ones expiring within the warning
+// period.
+func Check(materials []Material, now time.Time, warning time.Duration) Result {
+	r := Result{MinDaysRemaining: math.MaxInt32}
+	for _, m := range materials {
+		certs, err := readCertificates(m.Value)
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Errorf("failed to read the certificates of %s: %w", m.Source, err))
+			continue
+		}
+		for _, cert := range certs {
+			r.Checked++
+			if days := daysRemaining(cert.NotAfter, now); days < r.MinDaysRemaining {
+				r.MinDaysRemaining = days
+			}
+			if cert.NotAfter.Sub(now) <= warning {
+				r.Expiring = append(r.Expiring, Expiry{
+					Source:   m.Source,
+					Subject:  cert.Subject.String(),
+					NotAfter: cert.NotAfter,
+				})
+			}
+		}
+	}
+	if r.Checked == 0 {
+		r.MinDaysRemaining = 0
+	}
+	sort.SliceStable(r.Expiring, func(i, j int) bool {
+		return r.Expiring[i].NotAfter.Before(r.Expiring[j].NotAfter)
+	})
+	return r
+}
+
+// readCertificates reads the certificates of a PEM file or of an inline PEM.
+func readCertificates(value string) ([]*x509.Certificate, error) {
+	r, err := tlscommon.NewPEMReader(value)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs, nil
+}
+
+func daysRemaining(notAfter time.Time, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
+
+// Checker checks the certificates used by the agent periodically and reports the ones about to
+// expire.
+type Checker struct {
+	log       *logger.Logger
+	cfg       *configuration.CertExpiryConfig
+	materials func() []Material
+	report    func(ctx context.Context, err error) error
+	now       func() time.Time
+	metrics   *checkerMetrics
+}
+
+// NewChecker creates a checker of the certificates returned by materials, report is called with the
+// error describing the certificates about to expire, or nil once none expires.
+func NewChecker(log *logger.Logger, cfg *configuration.CertExpiryConfig, materials func() []Material, report func(ctx context.Context, err error) error) *Checker {
+	return &Checker{
+		log:       log,
+		cfg:       cfg,
+		materials: materials,
+		report:    report,
+		now:       time.Now,
+		metrics:   &checkerMetrics{},
+	}
+}
+
+// Run checks the certificates until the context is done.
+func (c *Checker) Run(ctx context.Context) {
+	c.metrics.register()
+
+	t := time.NewTicker(c.cfg.Interval)
+	defer t.Stop()
+
+	reported := ""
+	for {
+		reported = c.check(ctx, reported)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// check checks the certificates and reports the result when it changed, it returns the reported
+// message.
+func (c *Checker) check(ctx context.Context, reported string) string {
+	now := c.now()
+	r := Check(c.materials(), now, c.cfg.Warning)
+	c.metrics.set(r, now)
+	for _, err := range r.Errors {
+		c.log.Warnw("Failed to check the expiration of a certificate", "error.message", err)
+	}
+
+	err := r.Err(now)
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if msg == reported {
+		return reported
+	}
+	if err != nil {
+		c.log.Warnf("Certificates used by Elastic Agent are about to expire: %s", msg)
+	} else {
+		c.log.Info("No certificate used by Elastic Agent is about to expire")
+	}
+	if err := c.report(ctx, err); err != nil {
+		c.log.Warnw("Failed to report the expiration of the certificates", "error.message", err)
+		return reported
+	}
+	return msg
+}
@@ -0,0 +1,128 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certexpiry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+var now = time.Date(2023, 8, 24, 12, 0, 0, 0, time.UTC)
+
+func certPEM(t *testing.T, cn string, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(bundle, []byte(
+		certPEM(t, "valid", now.Add(365*24*time.Hour))+certPEM(t, "expiring", now.Add(10*24*time.Hour+time.Hour))), 0o600))
+
+	r := Check([]Material{
+		{Source: "fleet.ssl.certificate_authorities", Value: bundle},
+		{Source: "fleet.ssl.certificate", Value: certPEM(t, "expired", now.Add(-48*time.Hour))},
+		{Source: "agent.download.ssl.certificate_authorities", Value: filepath.Join(dir, "missing.pem")},
+	}, now, 30*24*time.Hour)
+
+	assert.Equal(t, 3, r.Checked)
+	assert.Equal(t, -2, r.MinDaysRemaining)
+	assert.Len(t, r.Errors, 1)
+	require.Len(t, r.Expiring, 2)
+	assert.Equal(t, "CN=expired", r.Expiring[0].Subject)
+	assert.Equal(t, 10, r.Expiring[1].DaysRemaining(now))
+
+	err := r.Err(now)
+	require.Error(t, err)
+	assert.Equal(t, `certificate "CN=expired" of fleet.ssl.certificate expired on 2023-08-22, and 1 other certificate(s) expire soon`, err.Error())
+}
+
+func TestCheckNoExpiring(t *testing.T) {
+	r := Check([]Material{
+		{Source: "fleet.ssl.certificate", Value: certPEM(t, "valid", now.Add(90*24*time.Hour))},
+	}, now, 30*24*time.Hour)
+
+	assert.Equal(t, 1, r.Checked)
+	assert.Equal(t, 90, r.MinDaysRemaining)
+	assert.NoError(t, r.Err(now))
+}
+
+func TestCheckerReportsChanges(t *testing.T) {
+	log, _ := logger.NewTesting("cert_expiry")
+	materials := []Material{
+		{Source: "fleet.ssl.certificate", Value: certPEM(t, "agent", now.Add(20*24*time.Hour))},
+	}
+	var reported []error
+	c := NewChecker(log, configuration.DefaultCertExpiryConfig(), func() []Material { return materials }, func(_ context.Context, err error) error {
+		reported = append(reported, err)
+		return nil
+	})
+	c.now = func() time.Time { return now }
+
+	msg := c.check(context.Background(), "")
+	require.Len(t, reported, 1)
+	assert.EqualError(t, reported[0], `certificate "CN=agent" of fleet.ssl.certificate expires in 20 day(s) on 2023-09-13`)
+
+	// unchanged, not reported again
+	msg = c.check(context.Background(), msg)
+	require.Len(t, reported, 1)
+
+	// the certificate was renewed
+	materials[0].Value = certPEM(t, "agent", now.Add(365*24*time.Hour))
+	msg = c.check(context.Background(), msg)
+	require.Len(t, reported, 2)
+	assert.NoError(t, reported[1])
+	assert.Empty(t, msg)
+}
+
+func TestUnitMaterials(t *testing.T) {
+	materials := unitMaterials("unit default", map[string]interface{}{
+		"type":  "elasticsearch",
+		"hosts": []interface{}{"https://localhost:9200"},
+		"ssl": map[string]interface{}{
+			"certificate_authorities": []interface{}{"/etc/pki/ca.pem"},
+			"certificate":             "/etc/pki/client.pem",
+		},
+		"streams": []interface{}{
+			map[string]interface{}{
+				"ssl": map[string]interface{}{
+					"enabled":                 false,
+					"certificate_authorities": []interface{}{"/etc/pki/disabled.pem"},
+				},
+			},
+		},
+	})
+	assert.Equal(t, []Material{
+		{Source: "unit default ssl.certificate", Value: "/etc/pki/client.pem"},
+		{Source: "unit default ssl.certificate_authorities", Value: "/etc/pki/ca.pem"},
+	}, materials)
+}
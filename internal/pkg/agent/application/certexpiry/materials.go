@@ -0,0 +1,119 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certexpiry
+
+import (
+	"sort"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+// ConfigMaterials returns the certificates of the agent configuration: the client certificate and
+// the certificate authorities of the connection to Fleet Server, the ones of the Fleet Server
+// bootstrap and the ones of the artifact downloads.
+func ConfigMaterials(cfg *configuration.Configuration) []Material {
+	var materials []Material
+	if cfg.Fleet != nil && cfg.Fleet.Enabled {
+		materials = append(materials, tlsMaterials("fleet.ssl", cfg.Fleet.Client.Transport.TLS)...)
+		if cfg.Fleet.Server != nil {
+			materials = append(materials, tlsMaterials("fleet.server.ssl", cfg.Fleet.Server.TLS)...)
+			materials = append(materials, tlsMaterials("fleet.server.output.elasticsearch.ssl", cfg.Fleet.Server.Output.Elasticsearch.TLS)...)
+		}
+	}
+	if cfg.Settings != nil && cfg.Settings.DownloadConfig != nil {
+		materials = append(materials, tlsMaterials("agent.download.ssl", cfg.Settings.DownloadConfig.TLS)...)
+	}
+	return materials
+}
+
+func tlsMaterials(source string, cfg *tlscommon.Config) []Material {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+	var materials []Material
+	if cfg.Certificate.Certificate != "" {
+		materials = append(materials, Material{Source: source + ".certificate", Value: cfg.Certificate.Certificate})
+	}
+	for _, ca := range cfg.CAs {
+		materials = append(materials, Material{Source: source + ".certificate_authorities", Value: ca})
+	}
+	return materials
+}
+
+// ComponentMaterials returns the certificates of the ssl settings of the units of the components,
+// like the certificate authorities of the outputs or the certificate of Fleet Server. A certificate
+// shared by several units is returned once. The trust store of the operating system added to the
+// outputs is not returned, it is managed by the operating system.
+func ComponentMaterials(comps []runtime.ComponentComponentState) []Material {
+	bundle := truststore.BundlePath(paths.Data())
+	seen := make(map[string]bool)
+	var materials []Material
+	for _, comp := range comps {
+		for _, unit := range comp.Component.Units {
+			if unit.Config == nil || unit.Config.Source == nil {
+				continue
+			}
+			for _, m := range unitMaterials("unit "+unit.ID, unit.Config.Source.AsMap()) {
+				if m.Value == bundle || seen[m.Value] {
+					continue
+				}
+				seen[m.Value] = true
+				materials = append(materials, m)
+			}
+		}
+	}
+	return materials
+}
+
+// unitMaterials returns the certificates of the ssl settings found in the configuration of a unit.
+func unitMaterials(source string, cfg map[string]interface{}) []Material {
+	keys := make([]string, 0, len(cfg))
+	for key := range cfg {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var materials []Material
+	for _, key := range keys {
+		switch value := cfg[key].(type) {
+		case map[string]interface{}:
+			if key == "ssl" {
+				materials = append(materials, sslMaterials(source+" ssl", value)...)
+			} else {
+				materials = append(materials, unitMaterials(source+" "+key, value)...)
+			}
+		case []interface{}:
+			// the streams of the inputs
+			for _, item := range value {
+				if m, ok := item.(map[string]interface{}); ok {
+					materials = append(materials, unitMaterials(source+" "+key, m)...)
+				}
+			}
+		}
+	}
+	return materials
+}
+
+func sslMaterials(source string, ssl map[string]interface{}) []Material {
+	if enabled, ok := ssl["enabled"].(bool); ok && !enabled {
+		return nil
+	}
+	var materials []Material
+	if cert, ok := ssl["certificate"].(string); ok && cert != "" {
+		materials = append(materials, Material{Source: source + ".certificate", Value: cert})
+	}
+	cas, _ := ssl["certificate_authorities"].([]interface{})
+	for _, ca := range cas {
+		if s, ok := ca.(string); ok && s != "" {
+			materials = append(materials, Material{Source: source + ".certificate_authorities", Value: s})
+		}
+	}
+	return materials
+}
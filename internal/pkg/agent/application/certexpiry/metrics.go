@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package certexpiry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// metricsName is the name of the certificate metrics in the stats namespace of the monitoring endpoint.
+const metricsName = "certificates"
+
+// checkerMetrics holds the result of the last check, it is a monitoring.Var reported under
+// certificates in the stats of the monitoring endpoint.
+type checkerMetrics struct {
+	mx sync.Mutex
+
+	checked          int64
+	expiring         int64
+	expired          int64
+	errors           int64
+	minDaysRemaining int64
+}
+
+// register adds the metrics to the stats of the monitoring endpoint, replacing the ones of a previous checker.
+func (m *checkerMetrics) register() {
+	reg := monitoring.GetNamespace("stats").GetRegistry()
+	reg.Remove(metricsName)
+	reg.Add(metricsName, m, monitoring.Reported)
+}
+
+// set records the result of a check.
+func (m *checkerMetrics) set(r Result, now time.Time) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.checked = int64(r.Checked)
+	m.expiring = 0
+	m.expired = 0
+	for _, e := range r.Expiring {
+		if e.DaysRemaining(now) < 0 {
+			m.expired++
+		} else {
+			m.expiring++
+		}
+	}
+	m.errors = int64(len(r.Errors))
+	m.minDaysRemaining = int64(r.MinDaysRemaining)
+}
+
+// Visit reports the metrics to the monitoring visitor.
+func (m *checkerMetrics) Visit(_ monitoring.Mode, vs monitoring.Visitor) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	vs.OnRegistryStart()
+	defer vs.OnRegistryFinished()
+
+	monitoring.ReportInt(vs, "checked", m.checked)
+	monitoring.ReportInt(vs, "expiring", m.expiring)
+	monitoring.ReportInt(vs, "expired", m.expired)
+	monitoring.ReportInt(vs, "errors", m.errors)
+	monitoring.ReportInt(vs, "min_days_remaining", m.minDaysRemaining)
+}
@@ -0,0 +1,205 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package configdrift detects the units that stay on an old configuration, they keep checking in
+// but never acknowledge the latest configuration sent to them, which otherwise goes unnoticed
+// until an integration is found not to be updated.
+package configdrift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// maxReportedKeys is the number of keys listed for a unit in the reported error.
+const maxReportedKeys = 5
+
+// Drift is a unit that didn't apply the latest configuration sent to it.
+type Drift struct {
+	ComponentID string
+	UnitID      string
+	// ExpectedConfigIdx is the index of the latest configuration sent to the unit.
+	ExpectedConfigIdx uint64
+	// ObservedConfigIdx is the index of the configuration the unit applied.
+	ObservedConfigIdx uint64
+	// Keys are the keys of the configuration that differ from the applied one.
+	Keys []string
+	// Since is when the unit was first seen on the applied configuration while a newer one was
+	// sent to it.
+	Since time.Time
+}
+
+func (d Drift) message(now time.Time) string {
+	msg := fmt.Sprintf("unit %s of component %s is stuck on configuration %d instead of %d for %s",
+		d.UnitID, d.ComponentID, d.ObservedConfigIdx, d.ExpectedConfigIdx, now.Sub(d.Since).Truncate(time.Second))
+	if len(d.Keys) > 0 {
+		keys := d.Keys
+		more := ""
+		if len(keys) > maxReportedKeys {
+			more = fmt.Sprintf(" and %d more", len(keys)-maxReportedKeys)
+			keys = keys[:maxReportedKeys]
+		}
+		msg = fmt.Sprintf("%s (changed keys: %s%s)", msg, strings.Join(keys, ", "), more)
+	}
+	return msg
+}
+
+// Err returns the error reported for the units stuck on an old configuration, nil when none is.
+func Err(drifts []Drift, now time.Time) error {
+	if len(drifts) == 0 {
+		return nil
+	}
+	msg := "Configuration drift: " + drifts[0].message(now)
+	if len(drifts) > 1 {
+		msg = fmt.Sprintf("%s, and %d other unit(s) are stuck on an old configuration", msg, len(drifts)-1)
+	}
+	return errors.New(msg)
+}
+
+type unitKey struct {
+	componentID string
+	unitID      string
+}
+
+type pending struct {
+	observedIdx uint64
+	since       time.Time
+}
+
+// Checker checks the configurations applied by the units periodically and reports the units stuck
+// on an old configuration for longer than the threshold.
+type Checker struct {
+	log     *logger.Logger
+	cfg     *configuration.ConfigDriftConfig
+	states  func() []runtime.ComponentComponentState
+	report  func(ctx context.Context, err error) error
+	now     func() time.Time
+	pending map[unitKey]pending
+}
+
+// NewChecker creates a checker of the units of the components returned by states, report is called
+// with the error describing the units stuck on an old configuration, or nil once none is.
+func NewChecker(log *logger.Logger, cfg *configuration.ConfigDriftConfig, states func() []runtime.ComponentComponentState, report func(ctx context.Context, err error) error) *Checker {
+	return &Checker{
+		log:     log,
+		cfg:     cfg,
+		states:  states,
+		report:  report,
+		now:     time.Now,
+		pending: make(map[unitKey]pending),
+	}
+}
+
+// Run checks the units until the context is done.
+func (c *Checker) Run(ctx context.Context) {
+	t := time.NewTicker(c.cfg.Interval)
+	defer t.Stop()
+
+	reported := ""
+	for {
+		reported = c.check(ctx, reported)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Drifts returns the units stuck on an old configuration for longer than the threshold, the
+// oldest first. A unit is only considered while it checks in with a configuration it applied,
+// units that are starting or stopped are reported by their state instead.
+func (c *Checker) Drifts(states []runtime.ComponentComponentState, now time.Time) []Drift {
+	seen := make(map[unitKey]bool)
+	var drifts []Drift
+	for _, comp := range states {
+		for key, unit := range comp.State.Units {
+			if unit.ObservedConfigIdx == 0 || unit.ObservedConfigIdx == unit.ExpectedConfigIdx ||
+				unit.State == client.UnitStateStarting || unit.State == client.UnitStateStopping || unit.State == client.UnitStateStopped {
+				continue
+			}
+			k := unitKey{componentID: comp.Component.ID, unitID: key.UnitID}
+			seen[k] = true
+			p, ok := c.pending[k]
+			if !ok || p.observedIdx != unit.ObservedConfigIdx {
+				// the unit applied a configuration since the last check, it is not stuck
+				p = pending{observedIdx: unit.ObservedConfigIdx, since: now}
+				c.pending[k] = p
+			}
+			if now.Sub(p.since) < c.cfg.Threshold {
+				continue
+			}
+			drifts = append(drifts, Drift{
+				ComponentID:       comp.Component.ID,
+				UnitID:            key.UnitID,
+				ExpectedConfigIdx: unit.ExpectedConfigIdx,
+				ObservedConfigIdx: unit.ObservedConfigIdx,
+				Keys:              unit.ConfigDriftKeys,
+				Since:             p.since,
+			})
+		}
+	}
+	for k := range c.pending {
+		if !seen[k] {
+			delete(c.pending, k)
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		if !drifts[i].Since.Equal(drifts[j].Since) {
+			return drifts[i].Since.Before(drifts[j].Since)
+		}
+		if drifts[i].ComponentID != drifts[j].ComponentID {
+			return drifts[i].ComponentID < drifts[j].ComponentID
+		}
+		return drifts[i].UnitID < drifts[j].UnitID
+	})
+	return drifts
+}
+
+// check checks the units and reports the result when the units stuck on an old configuration
+// changed, it returns the reported units.
+func (c *Checker) check(ctx context.Context, reported string) string {
+	now := c.now()
+	drifts := c.Drifts(c.states(), now)
+
+	// the message includes the duration of the drift, the units are compared instead so the
+	// error is only reported again when the units or their configurations change
+	var ids []string
+	for _, d := range drifts {
+		ids = append(ids, fmt.Sprintf("%s/%s@%d/%d", d.ComponentID, d.UnitID, d.ObservedConfigIdx, d.ExpectedConfigIdx))
+	}
+	current := strings.Join(ids, ",")
+	if current == reported {
+		return reported
+	}
+
+	err := Err(drifts, now)
+	if err != nil {
+		for _, d := range drifts {
+			c.log.Warnw("Unit is stuck on an old configuration",
+				"component.id", d.ComponentID,
+				"unit.id", d.UnitID,
+				"unit.expected_config_idx", d.ExpectedConfigIdx,
+				"unit.observed_config_idx", d.ObservedConfigIdx,
+				"unit.config_drift_keys", d.Keys)
+		}
+	} else {
+		c.log.Info("All units applied their latest configuration")
+	}
+	if err := c.report(ctx, err); err != nil {
+		c.log.Warnw("Failed to report the configuration drift of the units", "error.message", err)
+		return reported
+	}
+	return current
+}
@@ -0,0 +1,113 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configdrift
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+var now = time.Date(2023, 9, 8, 10, 0, 0, 0, time.UTC)
+
+func componentState(id string, units map[string]runtime.ComponentUnitState) runtime.ComponentComponentState {
+	s := runtime.ComponentComponentState{
+		Component: component.Component{ID: id},
+		State:     runtime.ComponentState{Units: make(map[runtime.ComponentUnitKey]runtime.ComponentUnitState)},
+	}
+	for unitID, unit := range units {
+		s.State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: unitID}] = unit
+	}
+	return s
+}
+
+func TestCheckerReportsStuckUnits(t *testing.T) {
+	log, _ := logger.NewTesting("config_drift")
+	states := []runtime.ComponentComponentState{
+		componentState("filestream-default", map[string]runtime.ComponentUnitState{
+			"filestream-default-1": {
+				State:             client.UnitStateHealthy,
+				ExpectedConfigIdx: 3,
+				ObservedConfigIdx: 2,
+				ConfigDriftKeys:   []string{"paths", "policy.revision"},
+			},
+			"filestream-default-2": {State: client.UnitStateHealthy, ExpectedConfigIdx: 3, ObservedConfigIdx: 3},
+			// not started yet, reported by its state
+			"filestream-default-3": {State: client.UnitStateStarting, ExpectedConfigIdx: 1},
+		}),
+	}
+	var reported []error
+	c := NewChecker(log, configuration.DefaultConfigDriftConfig(), func() []runtime.ComponentComponentState { return states }, func(_ context.Context, err error) error {
+		reported = append(reported, err)
+		return nil
+	})
+	c.now = func() time.Time { return now }
+
+	// below the threshold
+	msg := c.check(context.Background(), "")
+	assert.Empty(t, reported)
+
+	c.now = func() time.Time { return now.Add(6 * time.Minute) }
+	msg = c.check(context.Background(), msg)
+	require.Len(t, reported, 1)
+	assert.EqualError(t, reported[0], "Configuration drift: unit filestream-default-1 of component filestream-default is stuck on configuration 2 instead of 3 for 6m0s (changed keys: paths, policy.revision)")
+
+	// still stuck, not reported again
+	c.now = func() time.Time { return now.Add(7 * time.Minute) }
+	msg = c.check(context.Background(), msg)
+	require.Len(t, reported, 1)
+
+	// the unit applied the configuration
+	unit := states[0].State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "filestream-default-1"}]
+	unit.ObservedConfigIdx = 3
+	unit.ConfigDriftKeys = nil
+	states[0].State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "filestream-default-1"}] = unit
+	msg = c.check(context.Background(), msg)
+	require.Len(t, reported, 2)
+	assert.NoError(t, reported[1])
+	assert.Empty(t, msg)
+}
+
+func TestDriftsResetOnProgress(t *testing.T) {
+	log, _ := logger.NewTesting("config_drift")
+	c := NewChecker(log, configuration.DefaultConfigDriftConfig(), nil, nil)
+	states := []runtime.ComponentComponentState{
+		componentState("filestream-default", map[string]runtime.ComponentUnitState{
+			"filestream-default-1": {State: client.UnitStateHealthy, ExpectedConfigIdx: 3, ObservedConfigIdx: 1},
+		}),
+	}
+	assert.Empty(t, c.Drifts(states, now))
+
+	// the unit applied a newer configuration but a newer one was sent meanwhile, it is not stuck
+	unit := states[0].State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "filestream-default-1"}]
+	unit.ObservedConfigIdx = 2
+	unit.ExpectedConfigIdx = 4
+	states[0].State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "filestream-default-1"}] = unit
+	assert.Empty(t, c.Drifts(states, now.Add(4*time.Minute)))
+	assert.Empty(t, c.Drifts(states, now.Add(8*time.Minute)))
+
+	drifts := c.Drifts(states, now.Add(9*time.Minute))
+	require.Len(t, drifts, 1)
+	assert.Equal(t, now.Add(4*time.Minute), drifts[0].Since)
+}
+
+func TestErrManyUnits(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e", "f", "g"}
+	err := Err([]Drift{
+		{ComponentID: "c1", UnitID: "u1", ExpectedConfigIdx: 2, ObservedConfigIdx: 1, Keys: keys, Since: now},
+		{ComponentID: "c2", UnitID: "u2", ExpectedConfigIdx: 2, ObservedConfigIdx: 1, Since: now},
+	}, now.Add(time.Hour))
+	assert.EqualError(t, err, "Configuration drift: unit u1 of component c1 is stuck on configuration 1 instead of 2 for 1h0m0s (changed keys: a, b, c, d, e and 2 more), and 1 other unit(s) are stuck on an old configuration")
+}
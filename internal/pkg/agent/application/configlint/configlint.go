@@ -0,0 +1,254 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package configlint flags the common mistakes of the inputs of a policy when it is applied. The
+// warnings don't prevent the policy from running, they only point at the settings to fix.
+package configlint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/eql"
+)
+
+// The rules of the linter.
+const (
+	// RuleDuplicateID flags streams sharing the same ID, the inputs sharing the same ID already
+	// fail the policy.
+	RuleDuplicateID = "duplicate-id"
+	// RuleMissingPath flags the paths of the inputs that don't exist on the host.
+	RuleMissingPath = "missing-path"
+	// RuleDeprecated flags the deprecated input types.
+	RuleDeprecated = "deprecated"
+	// RuleUnmatchedCondition flags the conditions that don't match the variables of the host.
+	RuleUnmatchedCondition = "unmatched-condition"
+)
+
+// deprecatedInputTypes are the deprecated input types and the ones replacing them.
+var deprecatedInputTypes = map[string]string{
+	"log":        "filestream",
+	"docker":     "container",
+	"log/docker": "container",
+}
+
+// staticProviders are the context providers whose variables are fixed for the host, a condition
+// that only uses them and doesn't match now never will.
+var staticProviders = []string{"agent.", "host.", "env.", "path."}
+
+var conditionVarRe = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// Warning is a problem found in the configuration.
+type Warning struct {
+	Rule string `yaml:"rule" json:"rule"`
+	// Path locates the setting in the policy, like inputs.<input id>.streams.<stream id>.paths.
+	Path    string `yaml:"path" json:"path"`
+	Message string `yaml:"message" json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Path, w.Message)
+}
+
+// Lint returns the warnings of the policy, policy is the configuration as received and rendered
+// is the configuration with the inputs rendered with the variables. Only the inputs of policy are
+// read.
+func Lint(policy map[string]interface{}, rendered map[string]interface{}, vars []*transpiler.Vars) []Warning {
+	var warnings []Warning
+	warnings = append(warnings, lintDeprecated(policy)...)
+	warnings = append(warnings, lintConditions(policy, vars)...)
+	warnings = append(warnings, lintDuplicateIDs(rendered)...)
+	warnings = append(warnings, lintPaths(rendered)...)
+	// reported the same way on every run
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Path < warnings[j].Path
+	})
+	return warnings
+}
+
+func lintDeprecated(policy map[string]interface{}) []Warning {
+	var warnings []Warning
+	for i, input := range inputs(policy) {
+		inputType, _ := input["type"].(string)
+		if replacement, ok := deprecatedInputTypes[inputType]; ok {
+			warnings = append(warnings, Warning{
+				Rule:    RuleDeprecated,
+				Path:    inputPath(input, i) + ".type",
+				Message: fmt.Sprintf("the %s input is deprecated, use the %s input instead", inputType, replacement),
+			})
+		}
+	}
+	return warnings
+}
+
+func lintConditions(policy map[string]interface{}, vars []*transpiler.Vars) []Warning {
+	if len(vars) == 0 {
+		return nil
+	}
+	var warnings []Warning
+	check := func(path string, settings map[string]interface{}) {
+		condition, _ := settings["condition"].(string)
+		if condition == "" || !static(condition) {
+			return
+		}
+		for _, v := range vars {
+			match, err := eql.Eval(condition, v, true)
+			if err != nil || match {
+				// invalid conditions fail the rendering of the inputs
+				return
+			}
+		}
+		warnings = append(warnings, Warning{
+			Rule:    RuleUnmatchedCondition,
+			Path:    path + ".condition",
+			Message: fmt.Sprintf("the condition %q never matches this host", condition),
+		})
+	}
+	for i, input := range inputs(policy) {
+		path := inputPath(input, i)
+		check(path, input)
+		for j, stream := range streams(input) {
+			check(streamPath(path, stream, j), stream)
+		}
+	}
+	return warnings
+}
+
+// static returns true when the condition only uses the variables of the static providers.
+func static(condition string) bool {
+	matches := conditionVarRe.FindAllStringSubmatch(condition, -1)
+	if len(matches) == 0 {
+		return false
+	}
+	for _, m := range matches {
+		for _, name := range strings.Split(m[1], "|") {
+			name = strings.TrimSpace(name)
+			if !hasStaticPrefix(name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasStaticPrefix(name string) bool {
+	for _, p := range staticProviders {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func lintDuplicateIDs(rendered map[string]interface{}) []Warning {
+	var warnings []Warning
+	streamIDs := make(map[string]string)
+	for i, input := range inputs(rendered) {
+		path := inputPath(input, i)
+		for j, stream := range streams(input) {
+			id, _ := stream["id"].(string)
+			if id == "" {
+				continue
+			}
+			sPath := streamPath(path, stream, j)
+			if other, ok := streamIDs[id]; ok {
+				warnings = append(warnings, Warning{
+					Rule:    RuleDuplicateID,
+					Path:    sPath + ".id",
+					Message: fmt.Sprintf("the ID %s is also used by a stream of %s", id, other),
+				})
+				continue
+			}
+			streamIDs[id] = path
+		}
+	}
+	return warnings
+}
+
+func lintPaths(rendered map[string]interface{}) []Warning {
+	var warnings []Warning
+	check := func(path string, settings map[string]interface{}) {
+		paths, _ := settings["paths"].([]interface{})
+		for _, p := range paths {
+			pattern, _ := p.(string)
+			if pattern == "" || !filepath.IsAbs(pattern) || strings.Contains(pattern, "${") || strings.Contains(pattern, "**") {
+				// relative to the component, still a variable, or a recursive glob that Glob
+				// doesn't support
+				continue
+			}
+			if exists(pattern) {
+				continue
+			}
+			warnings = append(warnings, Warning{
+				Rule:    RuleMissingPath,
+				Path:    path + ".paths",
+				Message: fmt.Sprintf("no file matches %s on this host", pattern),
+			})
+		}
+	}
+	for i, input := range inputs(rendered) {
+		path := inputPath(input, i)
+		check(path, input)
+		for j, stream := range streams(input) {
+			check(streamPath(path, stream, j), stream)
+		}
+	}
+	return warnings
+}
+
+func exists(pattern string) bool {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		// a malformed pattern is looked up as a plain path
+		_, err = os.Stat(pattern)
+		return err == nil
+	}
+	if len(matches) > 0 {
+		return true
+	}
+	if !strings.ContainsAny(filepath.Base(pattern), "*?[") {
+		return false
+	}
+	// the files matching a pattern are often rotated in later, an existing directory is enough
+	dirs, err := filepath.Glob(filepath.Dir(pattern))
+	return err == nil && len(dirs) > 0
+}
+
+func inputs(cfg map[string]interface{}) []map[string]interface{} {
+	return maps(cfg["inputs"])
+}
+
+func streams(input map[string]interface{}) []map[string]interface{} {
+	return maps(input["streams"])
+}
+
+func maps(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func inputPath(input map[string]interface{}, i int) string {
+	if id, _ := input["id"].(string); id != "" {
+		return "inputs." + id
+	}
+	return fmt.Sprintf("inputs.%d", i)
+}
+
+func streamPath(inputPath string, stream map[string]interface{}, j int) string {
+	if id, _ := stream["id"].(string); id != "" {
+		return inputPath + ".streams." + id
+	}
+	return fmt.Sprintf("%s.streams.%d", inputPath, j)
+}
@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configlint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), nil, 0o600))
+	existing := filepath.Join(dir, "app.log")
+	rotated := filepath.Join(dir, "rotated-*.log")
+	missing := filepath.Join(dir, "missing", "*.log")
+
+	policy := map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{
+				"id":   "logs-1",
+				"type": "filestream",
+				"streams": []interface{}{
+					map[string]interface{}{
+						"id":    "app",
+						"paths": []interface{}{existing, rotated, missing, "relative.log", "${data.path}/x.log"},
+					},
+					map[string]interface{}{
+						"id":        "windows",
+						"condition": "${host.platform} == 'windows'",
+					},
+					map[string]interface{}{
+						"id":        "pods",
+						"condition": "${kubernetes.namespace} == 'default'",
+					},
+				},
+			},
+			map[string]interface{}{
+				"id":   "logs-2",
+				"type": "log",
+				"streams": []interface{}{
+					map[string]interface{}{"id": "app"},
+				},
+			},
+		},
+	}
+	vars, err := transpiler.NewVars("", map[string]interface{}{
+		"host": map[string]interface{}{"platform": "linux"},
+	}, nil)
+	require.NoError(t, err)
+
+	warnings := Lint(policy, policy, []*transpiler.Vars{vars})
+	assert.Equal(t, []Warning{
+		{Rule: RuleMissingPath, Path: "inputs.logs-1.streams.app.paths", Message: "no file matches " + missing + " on this host"},
+		{Rule: RuleUnmatchedCondition, Path: "inputs.logs-1.streams.windows.condition", Message: `the condition "${host.platform} == 'windows'" never matches this host`},
+		{Rule: RuleDuplicateID, Path: "inputs.logs-2.streams.app.id", Message: "the ID app is also used by a stream of inputs.logs-1"},
+		{Rule: RuleDeprecated, Path: "inputs.logs-2.type", Message: "the log input is deprecated, use the filestream input instead"},
+	}, warnings)
+}
+
+func TestLintNoWarnings(t *testing.T) {
+	policy := map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{
+				"id":        "system-metrics",
+				"type":      "system/metrics",
+				"condition": "${host.platform} == 'linux'",
+			},
+		},
+	}
+	vars, err := transpiler.NewVars("", map[string]interface{}{
+		"host": map[string]interface{}{"platform": "linux"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, Lint(policy, policy, []*transpiler.Vars{vars}))
+}
@@ -0,0 +1,133 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+// cachedConfig is the last good policy and variables of the Coordinator, the component model is
+// computed from them on start until the config and vars managers send fresh ones.
+type cachedConfig struct {
+	Timestamp time.Time `json:"@timestamp"`
+	cachedPolicy
+}
+
+type cachedPolicy struct {
+	Config map[string]interface{} `json:"config"`
+	Vars   []*transpiler.Vars     `json:"vars"`
+}
+
+// saveConfigCache caches the current policy and variables, they are only saved when they changed
+// since the last save.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) saveConfigCache() error {
+	if c.configCache == nil || c.ast == nil {
+		return nil
+	}
+	m, err := c.ast.Map()
+	if err != nil {
+		return fmt.Errorf("could not create the map from the policy: %w", err)
+	}
+	policy := cachedPolicy{Config: m, Vars: c.vars}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("could not marshal the policy and variables: %w", err)
+	}
+	hash := sha256.Sum256(data)
+	if bytes.Equal(hash[:], c.configCacheHash) {
+		return nil
+	}
+
+	data, err = json.Marshal(cachedConfig{Timestamp: time.Now().UTC(), cachedPolicy: policy})
+	if err != nil {
+		return fmt.Errorf("could not marshal the policy and variables: %w", err)
+	}
+	if err := c.configCache.Save(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("could not save the configuration cache: %w", err)
+	}
+	c.configCacheHash = hash[:]
+	return nil
+}
+
+// loadConfigCache returns the cached policy and variables, nil when there is no cache.
+func (c *Coordinator) loadConfigCache() (*cachedConfig, error) {
+	exists, err := c.configCache.Exists()
+	if err != nil || !exists {
+		return nil, err
+	}
+	reader, err := c.configCache.Load()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var cached cachedConfig
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// restoreConfigCache computes the component model from the cached policy and variables, so the
+// components run while Fleet is not reachable after a restart. The state reports the time of the
+// cache until the config and vars managers send fresh ones.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) restoreConfigCache(ctx context.Context) {
+	if c.configCache == nil {
+		return
+	}
+	cached, err := c.loadConfigCache()
+	if err != nil {
+		c.logger.Warnw("Failed to load the configuration cache, waiting for the initial configuration", "error.message", err)
+		return
+	}
+	if cached == nil {
+		return
+	}
+	if c.configCacheMaxAge > 0 && time.Since(cached.Timestamp) > c.configCacheMaxAge {
+		c.logger.Infof("Ignoring the configuration cached at %s, it is older than %s", cached.Timestamp, c.configCacheMaxAge)
+		return
+	}
+	cfg, err := config.NewConfigFrom(cached.Config)
+	if err != nil {
+		c.logger.Warnw("Failed to read the configuration cache, waiting for the initial configuration", "error.message", err)
+		return
+	}
+
+	c.logger.Infof("Restoring the configuration cached at %s until the initial configuration is received", cached.Timestamp)
+	c.vars = cached.Vars
+	if err := c.processConfig(ctx, cfg); err != nil {
+		c.logger.Warnw("Failed to apply the configuration cache, waiting for the initial configuration", "error.message", err)
+		c.ast = nil
+		c.vars = nil
+		return
+	}
+	c.setConfigCacheTime(cached.Timestamp)
+}
+
+// configChangeReceived updates the freshness of the policy and variables once the config or vars
+// manager sent an update, the restored cache is no longer reported once both are fresh.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) configChangeReceived(config bool, vars bool) {
+	c.configFresh = c.configFresh || config
+	c.varsFresh = c.varsFresh || vars
+	if c.configFresh && c.varsFresh && !c.state.ConfigCacheTime.IsZero() {
+		c.logger.Info("Received the initial configuration, the configuration cache is no longer used")
+		c.setConfigCacheTime(time.Time{})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/utils/broadcaster"
+)
+
+const cachedPolicyConfig = `
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: test-input
+    type: filestream
+    use_output: default
+    paths: ${host.paths}
+`
+
+func newConfigCacheCoordinator(cache storage.Storage, updated *[]component.Component) (*Coordinator, chan ConfigChange, chan []*transpiler.Vars) {
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comps []component.Component) error {
+				*updated = comps
+				return nil
+			},
+		},
+		configCache: cache,
+	}
+	return coord, configChan, varsChan
+}
+
+func newCachedVars(t *testing.T) []*transpiler.Vars {
+	vars, err := transpiler.NewVars("", map[string]interface{}{
+		"host": map[string]interface{}{
+			"paths": []interface{}{"/var/log/test.log"},
+		},
+	}, nil)
+	require.NoError(t, err)
+	return []*transpiler.Vars{vars}
+}
+
+func TestCoordinatorConfigCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cache := storage.NewDiskStore(filepath.Join(t.TempDir(), "config_cache.enc"))
+
+	// a coordinator receiving the policy and variables caches them
+	var components []component.Component
+	coord, configChan, varsChan := newConfigCacheCoordinator(cache, &components)
+	varsChan <- newCachedVars(t)
+	coord.runLoopIteration(ctx)
+	configChan <- &configChange{cfg: config.MustNewConfigFrom(cachedPolicyConfig)}
+	coord.runLoopIteration(ctx)
+	require.Len(t, components, 1)
+	exists, err := cache.Exists()
+	require.NoError(t, err)
+	require.True(t, exists, "the policy and variables should be cached")
+
+	// after a restart the component model is restored from the cache
+	var restored []component.Component
+	coord, configChan, varsChan = newConfigCacheCoordinator(cache, &restored)
+	coord.restoreConfigCache(ctx)
+	require.Len(t, restored, 1)
+	assert.Equal(t, components[0].ID, restored[0].ID)
+	assert.Equal(t, components[0].Units[0].Config.Source.AsMap(), restored[0].Units[0].Config.Source.AsMap())
+	assert.False(t, coord.state.ConfigCacheTime.IsZero(), "the state should report the time of the cache")
+	state := coord.generateReportableState()
+	assert.Equal(t, agentclient.Degraded, state.State)
+	assert.Contains(t, state.Message, "Running the configuration cached at")
+
+	// the cache is reported until both the policy and the variables are fresh
+	configChan <- &configChange{cfg: config.MustNewConfigFrom(cachedPolicyConfig)}
+	coord.runLoopIteration(ctx)
+	assert.False(t, coord.state.ConfigCacheTime.IsZero())
+	varsChan <- newCachedVars(t)
+	coord.runLoopIteration(ctx)
+	assert.True(t, coord.state.ConfigCacheTime.IsZero())
+	assert.Equal(t, agentclient.Healthy, coord.generateReportableState().State)
+}
+
+func TestCoordinatorConfigCacheMaxAge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cache := storage.NewDiskStore(filepath.Join(t.TempDir(), "config_cache.enc"))
+
+	m, err := config.MustNewConfigFrom(cachedPolicyConfig).ToMapStr()
+	require.NoError(t, err)
+	data, err := json.Marshal(cachedConfig{
+		Timestamp: time.Now().Add(-2 * time.Hour),
+		cachedPolicy: cachedPolicy{
+			Config: m,
+			Vars:   newCachedVars(t),
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, cache.Save(bytes.NewReader(data)))
+
+	var restored []component.Component
+	coord, _, _ := newConfigCacheCoordinator(cache, &restored)
+	coord.configCacheMaxAge = time.Hour
+	coord.restoreConfigCache(ctx)
+	assert.Nil(t, restored, "a cache older than the max age should not be restored")
+	assert.Nil(t, coord.ast)
+	assert.True(t, coord.state.ConfigCacheTime.IsZero())
+
+	coord.configCacheMaxAge = 3 * time.Hour
+	coord.restoreConfigCache(ctx)
+	assert.Len(t, restored, 1)
+}
@@ -17,15 +17,25 @@ import (
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/configlint"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/gates"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/tcc"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/logrotation"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -44,6 +54,10 @@ var ErrNotUpgradable = errors.New(
 // attempted at the same time.
 var ErrUpgradeInProgress = errors.New("upgrade already in progress")
 
+// ErrReloadNotSupported is returned when reloading the configuration of an Elastic Agent that does not read it
+// from the local configuration files.
+var ErrReloadNotSupported = errors.New("configuration reload is only supported in standalone mode")
+
 // ReExecManager provides an interface to perform re-execution of the entire agent.
 type ReExecManager interface {
 	ReExec(callback reexec.ShutdownCallbackFn, argOverrides ...string)
@@ -58,7 +72,7 @@ type UpgradeManager interface {
 	Reload(rawConfig *config.Config) error
 
 	// Upgrade upgrades running agent.
-	Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error)
+	Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, details *details.Details, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error)
 
 	// Ack is used on startup to check if the agent has upgraded and needs to send an ack for the action
 	Ack(ctx context.Context, acker acker.Acker) error
@@ -74,6 +88,9 @@ type MonitorManager interface {
 
 	// MonitoringConfig injects monitoring configuration into resolved ast tree.
 	MonitoringConfig(map[string]interface{}, []component.Component, map[string]string) (map[string]interface{}, error)
+
+	// SetReduced reduces the collection of the monitoring metrics, or restores it.
+	SetReduced(reduced bool)
 }
 
 // Runner provides interface to run a manager and receive running errors.
@@ -106,6 +123,15 @@ type RuntimeManager interface {
 	// PerformDiagnostics executes the diagnostic action for the provided units. If no units are provided then
 	// it performs diagnostics for all current units.
 	PerformDiagnostics(context.Context, ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
+
+	// RotateCredentials restarts the running components with new connection credentials.
+	RotateCredentials(context.Context) error
+
+	// Heartbeat returns once the manager is able to apply an update or the context is done.
+	Heartbeat(context.Context) error
+
+	// ShutdownPhases returns the channel receiving the phases of the shutdown of the components.
+	ShutdownPhases() <-chan runtime.ShutdownPhase
 }
 
 // ConfigChange provides an interface for receiving a new configuration.
@@ -122,6 +148,15 @@ type ConfigChange interface {
 	Fail(err error)
 }
 
+// TracedConfigChange is a ConfigChange triggered by an action traced in its own
+// transaction, the application of the configuration is traced in the same trace.
+type TracedConfigChange interface {
+	ConfigChange
+
+	// Transaction returns the transaction of the action, nil when it's not traced.
+	Transaction() *apm.Transaction
+}
+
 // ErrorReporter provides an interface for any manager that is handled by the coordinator to report errors.
 type ErrorReporter interface{}
 
@@ -138,6 +173,14 @@ type ConfigManager interface {
 	Watch() <-chan ConfigChange
 }
 
+// ReloadableConfigManager is a ConfigManager that reads its configuration again on demand.
+type ReloadableConfigManager interface {
+	ConfigManager
+
+	// Reload reads the configuration again and emits it on the Watch channel even when it did not change.
+	Reload(ctx context.Context) error
+}
+
 // VarsManager provides an interface to run and watch for variable changes.
 type VarsManager interface {
 	Runner
@@ -161,6 +204,10 @@ type Coordinator struct {
 	agentInfo *info.AgentInfo
 	isManaged bool
 
+	// tracer traces the configuration changes that are not triggered by a
+	// traced action, nil when the agent is not traced.
+	tracer *apm.Tracer
+
 	cfg   *configuration.Configuration
 	specs component.RuntimeSpecs
 
@@ -203,6 +250,67 @@ type Coordinator struct {
 	// to the run loop in Coordinator's main goroutine.
 	logLevelCh chan logp.Level
 
+	// integrityErrCh forwards the result of the binary integrity check from
+	// the public API (SetIntegrityError) to the run loop.
+	integrityErrCh chan error
+
+	// certExpiryErrCh forwards the result of the certificate expiration check
+	// from the public API (SetCertificateExpiry) to the run loop.
+	certExpiryErrCh chan error
+
+	// diskQuotaErrCh forwards the result of the disk quota check from the
+	// public API (SetDiskQuotaError) to the run loop.
+	diskQuotaErrCh chan error
+
+	// configDriftErrCh forwards the result of the configuration drift check
+	// from the public API (SetConfigDriftError) to the run loop.
+	configDriftErrCh chan error
+
+	// dataPlaneCh forwards the events collected from the components from the
+	// public API (SetDataPlaneEvents) to the run loop.
+	dataPlaneCh chan map[string]runtime.UnitEvents
+
+	// upgradeDetailsChan forwards the progress of an upgrade from the public
+	// API (SetUpgradeDetails) to the run loop.
+	upgradeDetailsChan chan *details.Details
+
+	// pkgManagedVersionCh forwards the version the package manager is expected
+	// to install from the public API (SetPackageManagedVersion) to the run loop.
+	pkgManagedVersionCh chan string
+
+	// startupGatesCh forwards the closed startup gates from watchStartupGates
+	// to the run loop, an empty value means the gates are open.
+	startupGatesCh chan []gates.Closed
+
+	// loadSheddingCh forwards the load shedding changes from the public API
+	// (SetLoadShedding) to the run loop.
+	loadSheddingCh chan bool
+
+	// monitoringReducedCh forwards the changes of the collection of the
+	// monitoring metrics from the public API (SetMonitoringReduced) to the
+	// run loop.
+	monitoringReducedCh chan bool
+
+	// fleetCircuitBreakerCh forwards the state of the circuit breaker of the
+	// check-ins from the public API (SetFleetCircuitBreaker) to the run loop.
+	fleetCircuitBreakerCh chan string
+
+	// haStandbyCh forwards the role of the agent in its active/standby pair
+	// from the public API (SetHAStandby) to the run loop.
+	haStandbyCh chan bool
+
+	// handedOffCh forwards the handoff of the inputs when the node is
+	// drained from the public API (SetHandedOff) to the run loop.
+	handedOffCh chan bool
+
+	// fullDiskAccessCh forwards the status of the Full Disk Access of the
+	// agent on macOS from the public API (SetFullDiskAccess) to the run loop.
+	fullDiskAccessCh chan tcc.Status
+
+	// heartbeatCh receives the channels closed by the run loop to prove it
+	// makes progress, see Heartbeat.
+	heartbeatCh chan chan struct{}
+
 	// managerChans collects the channels used to receive updates from the
 	// various managers. Coordinator reads from all of them during the run loop.
 	// Tests can safely override these before calling Coordinator.Run, or in
@@ -219,12 +327,42 @@ type Coordinator struct {
 	actionsErr    error
 	varsMgrErr    error
 
+	// integrityErr is set when the agent binary does not match the manifest
+	// written at install or upgrade time. It is reported as degraded rather
+	// than failed, the agent keeps running.
+	integrityErr error
+
+	// certExpiryErr is set when a certificate used by the agent or its
+	// components expires soon. It is reported as degraded.
+	certExpiryErr error
+
+	// diskQuotaErr is set when the paths managed by the agent exceed their
+	// disk quota once their oldest files are evicted. It is reported as degraded.
+	diskQuotaErr error
+
+	// configDriftErr is set when units keep running an old configuration
+	// instead of the latest one sent to them.
+	configDriftErr error
+
+	// dataPlaneEvents are the events of the output unit of the components,
+	// by component ID.
+	dataPlaneEvents map[string]runtime.UnitEvents
+
+	// pkgManagedVersion is the version the package manager is expected to
+	// install when upgrades are managed by a package manager.
+	pkgManagedVersion string
+
 	// The raw policy before spec lookup or variable substitution
 	ast *transpiler.AST
 
 	// The current variables
 	vars []*transpiler.Vars
 
+	// renderer renders the inputs with the variables, only the inputs whose
+	// variables changed are rendered again. It is safe to use from the
+	// workers computing the component model.
+	renderer transpiler.Renderer
+
 	// The policy after spec and variable substitution
 	derivedConfig map[string]interface{}
 
@@ -232,6 +370,45 @@ type Coordinator struct {
 	// value that is sent to the runtime manager).
 	componentModel []component.Component
 
+	// componentModelTimeout is the maximum time the computation of the
+	// component model can take, zero disables it.
+	componentModelTimeout time.Duration
+
+	// componentModelSlowThreshold is the time after which the Coordinator
+	// reports it is configuring until the computation ends, zero disables it.
+	componentModelSlowThreshold time.Duration
+
+	// startupGates are the conditions the host must meet before the component
+	// model is sent to the runtime manager for the first time. They are
+	// checked by watchStartupGates every startupGatesInterval until they open
+	// or startupGatesTimeout expires.
+	startupGates         []gates.Gate
+	startupGatesInterval time.Duration
+	startupGatesTimeout  time.Duration
+
+	// closedStartupGates are the startup gates that are not open yet, the
+	// component model is held until it is empty.
+	closedStartupGates []gates.Closed
+
+	// componentModelHeld is set when the component model was not sent to the
+	// runtime manager because of the closed startup gates.
+	componentModelHeld bool
+
+	// configCache is the last good policy and variables of a Fleet-managed
+	// agent, it is restored on start so the components run while Fleet is not
+	// reachable. Nil when the cache is disabled.
+	configCache       storage.Storage
+	configCacheMaxAge time.Duration
+
+	// configCacheHash is the hash of the cached policy and variables, they are
+	// saved again only when they change.
+	configCacheHash []byte
+
+	// configFresh and varsFresh are set once the config and vars managers sent
+	// their first update, the cache is saved only when both are fresh.
+	configFresh bool
+	varsFresh   bool
+
 	// Disabled for 8.8.0 release in order to limit the surface
 	// https://github.com/elastic/security-team/issues/6501
 
@@ -259,7 +436,7 @@ type managerChans struct {
 var ErrFatalCoordinator = errors.New("fatal error in coordinator")
 
 // New creates a new coordinator.
-func New(logger *logger.Logger, cfg *configuration.Configuration, logLevel logp.Level, agentInfo *info.AgentInfo, specs component.RuntimeSpecs, reexecMgr ReExecManager, upgradeMgr UpgradeManager, runtimeMgr RuntimeManager, configMgr ConfigManager, varsMgr VarsManager, caps capabilities.Capabilities, monitorMgr MonitorManager, isManaged bool, modifiers ...ComponentsModifier) *Coordinator {
+func New(logger *logger.Logger, cfg *configuration.Configuration, logLevel logp.Level, agentInfo *info.AgentInfo, tracer *apm.Tracer, specs component.RuntimeSpecs, reexecMgr ReExecManager, upgradeMgr UpgradeManager, runtimeMgr RuntimeManager, configMgr ConfigManager, varsMgr VarsManager, caps capabilities.Capabilities, monitorMgr MonitorManager, isManaged bool, modifiers ...ComponentsModifier) *Coordinator {
 	var fleetState cproto.State
 	var fleetMessage string
 	if !isManaged {
@@ -278,6 +455,7 @@ func New(logger *logger.Logger, cfg *configuration.Configuration, logLevel logp.
 		logger:     logger,
 		cfg:        cfg,
 		agentInfo:  agentInfo,
+		tracer:     tracer,
 		isManaged:  isManaged,
 		specs:      specs,
 		reexecMgr:  reexecMgr,
@@ -306,8 +484,23 @@ func New(logger *logger.Logger, cfg *configuration.Configuration, logLevel logp.
 		// synchronization in the subscriber API, just set the input buffer to 0.
 		stateBroadcaster: broadcaster.New(state, 64, 32),
 
-		logLevelCh:        make(chan logp.Level),
-		overrideStateChan: make(chan *coordinatorOverrideState),
+		logLevelCh:            make(chan logp.Level),
+		integrityErrCh:        make(chan error),
+		certExpiryErrCh:       make(chan error),
+		diskQuotaErrCh:        make(chan error),
+		configDriftErrCh:      make(chan error),
+		dataPlaneCh:           make(chan map[string]runtime.UnitEvents),
+		pkgManagedVersionCh:   make(chan string),
+		upgradeDetailsChan:    make(chan *details.Details),
+		overrideStateChan:     make(chan *coordinatorOverrideState),
+		startupGatesCh:        make(chan []gates.Closed),
+		loadSheddingCh:        make(chan bool),
+		monitoringReducedCh:   make(chan bool),
+		fleetCircuitBreakerCh: make(chan string),
+		haStandbyCh:           make(chan bool),
+		handedOffCh:           make(chan bool),
+		fullDiskAccessCh:      make(chan tcc.Status),
+		heartbeatCh:           make(chan chan struct{}),
 	}
 	// Setup communication channels for any non-nil components. This pattern
 	// lets us transparently accept nil managers / simulated events during
@@ -336,6 +529,29 @@ func New(logger *logger.Logger, cfg *configuration.Configuration, logLevel logp.
 		c.managerChans.varsManagerUpdate = varsMgr.Watch()
 		c.managerChans.varsManagerError = varsMgr.Errors()
 	}
+	if cfg != nil && cfg.Settings != nil && cfg.Settings.ComponentModel != nil {
+		c.componentModelTimeout = cfg.Settings.ComponentModel.Timeout
+		c.componentModelSlowThreshold = cfg.Settings.ComponentModel.SlowThreshold
+	}
+	if isManaged && cfg != nil && cfg.Settings != nil && cfg.Settings.ConfigCache != nil && cfg.Settings.ConfigCache.Enabled {
+		c.configCache = storage.NewEncryptedDiskStore(paths.AgentConfigCacheFile())
+		c.configCacheMaxAge = cfg.Settings.ConfigCache.MaxAge
+	}
+	if cfg != nil && cfg.Settings != nil && cfg.Settings.StartupGates != nil {
+		c.startupGates = gates.FromConfig(cfg.Settings.StartupGates)
+		c.startupGatesInterval = cfg.Settings.StartupGates.Interval
+		c.startupGatesTimeout = cfg.Settings.StartupGates.Timeout
+		// the gates are closed until they are checked, so the first component
+		// model is held even if it is computed before the first check
+		for _, g := range c.startupGates {
+			c.closedStartupGates = append(c.closedStartupGates, gates.Closed{Name: g.Name(), Err: errors.New("not checked yet")})
+		}
+	}
+	if cfg != nil && cfg.Settings != nil && cfg.Settings.HA != nil && cfg.Settings.HA.Enabled {
+		// the agent of an active/standby pair runs no inputs until it knows
+		// it is the active agent
+		c.state.HAStandby = true
+	}
 	return c
 }
 
@@ -392,6 +608,47 @@ func (c *Coordinator) ReExec(callback reexec.ShutdownCallbackFn, argOverrides ..
 	c.reexecMgr.ReExec(callback, argOverrides...)
 }
 
+// RotateCredentials restarts the running components with new connection credentials.
+// Called from external goroutines.
+func (c *Coordinator) RotateCredentials(ctx context.Context) error {
+	return c.runtimeMgr.RotateCredentials(ctx)
+}
+
+// Heartbeat returns once the run loop of the Coordinator handled an event, it blocks when
+// the run loop is stuck handling another one.
+// Called from external goroutines.
+func (c *Coordinator) Heartbeat(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.heartbeatCh <- done:
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+// RuntimeHeartbeat returns once the runtime manager is able to apply an update, it blocks
+// when the runtime manager is stuck applying another one.
+// Called from external goroutines.
+func (c *Coordinator) RuntimeHeartbeat(ctx context.Context) error {
+	return c.runtimeMgr.Heartbeat(ctx)
+}
+
+// Reload reads the local configuration again, the components whose configuration did not change keep running.
+// Called from external goroutines.
+func (c *Coordinator) Reload(ctx context.Context) error {
+	reloadable, ok := c.configMgr.(ReloadableConfigManager)
+	if !ok {
+		return ErrReloadNotSupported
+	}
+	return reloadable.Reload(ctx)
+}
+
 // Upgrade runs the upgrade process.
 // Called from external goroutines.
 func (c *Coordinator) Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, skipVerifyOverride bool, pgpBytes ...string) error {
@@ -423,16 +680,35 @@ func (c *Coordinator) Upgrade(ctx context.Context, version string, sourceURI str
 		return err
 	}
 
+	// report the progress of the upgrade to Fleet
+	actionID := ""
+	if action != nil {
+		actionID = action.ActionID
+	}
+	det := details.NewDetails(version, details.StateRequested, actionID)
+	det.RegisterObserver(func(upgradeDetails *details.Details) {
+		// the progress is not reported once the upgrade is cancelled or the coordinator is stopped
+		_ = c.SetUpgradeDetails(ctx, upgradeDetails)
+	})
+
+	// the upgrade is traced in the trace of the upgrade action, if any
+	ctx, endTx := c.startTransaction(ctx, "upgrade", apm.TransactionFromContext(ctx))
+	defer endTx()
+
 	// override the overall state to upgrading until the re-execution is complete
 	c.SetOverrideState(agentclient.Upgrading, fmt.Sprintf("Upgrading to version %s", version))
-	cb, err := c.upgradeMgr.Upgrade(ctx, version, sourceURI, action, skipVerifyOverride, pgpBytes...)
+	cb, err := c.upgradeMgr.Upgrade(ctx, version, sourceURI, action, det, skipVerifyOverride, pgpBytes...)
 	if err != nil {
 		c.ClearOverrideState()
+		det.Fail(err)
 		return err
 	}
-	if cb != nil {
-		c.ReExec(cb)
+	if cb == nil {
+		// no re-execution, the upgrade was skipped or is performed by the package manager
+		c.ClearOverrideState()
+		return nil
 	}
+	c.ReExec(cb)
 	return nil
 }
 
@@ -468,6 +744,68 @@ func (c *Coordinator) SetLogLevel(ctx context.Context, lvl logp.Level) error {
 	}
 }
 
+// watchStartupGates checks the startup gates until they open or
+// startupGatesTimeout expires, and forwards the closed gates to the run loop
+// each time they change.
+// Runs in its own goroutine created in Coordinator.Run.
+func (c *Coordinator) watchStartupGates(ctx context.Context) {
+	if len(c.startupGates) == 0 {
+		return
+	}
+
+	var timeoutCh <-chan time.Time
+	if c.startupGatesTimeout > 0 {
+		timeout := time.NewTimer(c.startupGatesTimeout)
+		defer timeout.Stop()
+		timeoutCh = timeout.C
+	}
+	interval := c.startupGatesInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	reported := ""
+	for {
+		closed := gates.Check(ctx, c.startupGates)
+		if len(closed) == 0 {
+			c.logger.Info("Startup gates are open")
+			c.sendClosedStartupGates(ctx, nil)
+			return
+		}
+		if desc := gates.Describe(closed); desc != reported {
+			c.logger.Infof("Waiting for startup gates: %s", desc)
+			if !c.sendClosedStartupGates(ctx, closed) {
+				return
+			}
+			reported = desc
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeoutCh:
+			c.logger.Warnf("Startup gates not open after %s, starting the components anyway: %s", c.startupGatesTimeout, reported)
+			c.sendClosedStartupGates(ctx, nil)
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// sendClosedStartupGates forwards the closed startup gates to the run loop,
+// it returns false when the context is done.
+// Called from watchStartupGates.
+func (c *Coordinator) sendClosedStartupGates(ctx context.Context, closed []gates.Closed) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case c.startupGatesCh <- closed:
+		return true
+	}
+}
+
 // watchRuntimeComponents listens for state updates from the runtime
 // manager, logs them, and forwards them to CoordinatorState.
 // Runs in its own goroutine created in Coordinator.Run.
@@ -565,8 +903,10 @@ func (c *Coordinator) Run(ctx context.Context) error {
 	defer close(c.stateBroadcaster.InputChan)
 
 	go c.watchRuntimeComponents(watchCtx)
+	go c.watchStartupGates(watchCtx)
 
 	for {
+		c.state.ShutdownPhase = ""
 		c.setState(agentclient.Starting, "Waiting for initial configuration and composable variables")
 		// The usual state refresh happens in the main run loop in Coordinator.runner,
 		// so before/after the runner call we need to trigger state change broadcasts
@@ -811,6 +1151,15 @@ func (c *Coordinator) runner(ctx context.Context) error {
 		varsErrCh <- nil
 	}
 
+	// Run the cached policy until the managers send the initial configuration,
+	// the policy and variables are kept when the runner is restarted.
+	if c.ast == nil && c.vars == nil {
+		c.restoreConfigCache(ctx)
+		if c.stateNeedsRefresh {
+			c.refreshState()
+		}
+	}
+
 	// Keep looping until the context ends.
 	for ctx.Err() == nil {
 		c.runLoopIteration(ctx)
@@ -849,6 +1198,71 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	case overrideState := <-c.overrideStateChan:
 		c.setOverrideState(overrideState)
 
+	case integrityErr := <-c.integrityErrCh:
+		c.setIntegrityError(integrityErr)
+
+	case certExpiryErr := <-c.certExpiryErrCh:
+		c.setCertificateExpiry(certExpiryErr)
+
+	case diskQuotaErr := <-c.diskQuotaErrCh:
+		c.setDiskQuotaError(diskQuotaErr)
+
+	case configDriftErr := <-c.configDriftErrCh:
+		c.setConfigDriftError(configDriftErr)
+
+	case events := <-c.dataPlaneCh:
+		c.setDataPlaneEvents(events)
+
+	case pkgManagedVersion := <-c.pkgManagedVersionCh:
+		c.setPackageManagedVersion(pkgManagedVersion)
+
+	case upgradeDetails := <-c.upgradeDetailsChan:
+		c.setUpgradeDetails(upgradeDetails)
+
+	case closed := <-c.startupGatesCh:
+		c.setClosedStartupGates(closed)
+
+	case shedding := <-c.loadSheddingCh:
+		if ctx.Err() == nil {
+			if err := c.processLoadShedding(ctx, shedding); err != nil {
+				c.setState(agentclient.Failed, err.Error())
+				c.logger.Errorf("%s", err)
+			}
+		}
+
+	case state := <-c.fleetCircuitBreakerCh:
+		c.setFleetCircuitBreaker(state)
+
+	case standby := <-c.haStandbyCh:
+		if ctx.Err() == nil {
+			if err := c.processHAStandby(ctx, standby); err != nil {
+				c.setState(agentclient.Failed, err.Error())
+				c.logger.Errorf("%s", err)
+			}
+		}
+
+	case status := <-c.fullDiskAccessCh:
+		c.setFullDiskAccess(status)
+
+	case handedOff := <-c.handedOffCh:
+		if ctx.Err() == nil {
+			if err := c.processHandedOff(ctx, handedOff); err != nil {
+				c.setState(agentclient.Failed, err.Error())
+				c.logger.Errorf("%s", err)
+			}
+		}
+
+	case reduced := <-c.monitoringReducedCh:
+		if ctx.Err() == nil {
+			if err := c.processMonitoringReduced(ctx, reduced); err != nil {
+				c.setState(agentclient.Failed, err.Error())
+				c.logger.Errorf("%s", err)
+			}
+		}
+
+	case heartbeat := <-c.heartbeatCh:
+		close(heartbeat)
+
 	case componentState := <-c.managerChans.runtimeManagerUpdate:
 		// New component change reported by the runtime manager via
 		// Coordinator.watchRuntimeComponents(), merge it with the
@@ -856,7 +1270,10 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 		c.applyComponentState(componentState)
 
 	case change := <-c.managerChans.configManagerUpdate:
-		if err := c.processConfig(ctx, change.Config()); err != nil {
+		txCtx, endTx := c.traceConfigChange(ctx, change)
+		defer endTx()
+		c.configChangeReceived(true, false)
+		if err := c.processConfig(txCtx, change.Config()); err != nil {
 			c.setState(agentclient.Failed, err.Error())
 			c.logger.Errorf("%s", err)
 			change.Fail(err)
@@ -870,7 +1287,10 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 
 	case vars := <-c.managerChans.varsManagerUpdate:
 		if ctx.Err() == nil {
-			if err := c.processVars(ctx, vars); err != nil {
+			txCtx, endTx := c.startTransaction(ctx, "vars change", nil)
+			defer endTx()
+			c.configChangeReceived(false, true)
+			if err := c.processVars(txCtx, vars); err != nil {
 				c.setState(agentclient.Failed, err.Error())
 				c.logger.Errorf("%s", err)
 			}
@@ -926,6 +1346,26 @@ func (c *Coordinator) processConfig(ctx context.Context, cfg *config.Config) (er
 		return fmt.Errorf("could not update feature flags config: %w", err)
 	}
 
+	if err := resolver.Apply(cfg); err != nil {
+		return fmt.Errorf("could not update the DNS config: %w", err)
+	}
+
+	if err := truststore.Apply(cfg); err != nil {
+		return fmt.Errorf("could not update the trust store config: %w", err)
+	}
+
+	if err := revocation.Apply(cfg); err != nil {
+		return fmt.Errorf("could not update the TLS revocation config: %w", err)
+	}
+
+	if err := logrotation.Apply(cfg); err != nil {
+		return fmt.Errorf("could not update the log rotation config: %w", err)
+	}
+
+	if err := diagnostics.ApplyRedaction(cfg); err != nil {
+		return fmt.Errorf("could not update the diagnostics redaction config: %w", err)
+	}
+
 	// Check the upgrade and monitoring managers before updating them. Real
 	// Coordinators always have them, but not all tests do, and in that case
 	// we should skip the Reload call rather than segfault.
@@ -988,6 +1428,82 @@ func (c *Coordinator) processLogLevel(ctx context.Context, ll logp.Level) (err e
 	return nil
 }
 
+// processLoadShedding updates the load shedding state, the monitoring
+// components are removed from the component model while the agent sheds load.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) processLoadShedding(ctx context.Context, shedding bool) (err error) {
+	span, ctx := apm.StartSpan(ctx, "load_shedding", "app.internal")
+	defer func() {
+		apm.CaptureError(ctx, err).Send()
+		span.End()
+	}()
+
+	c.setLoadShedding(shedding)
+
+	if c.ast != nil && c.vars != nil {
+		return c.process(ctx)
+	}
+	return nil
+}
+
+// processHAStandby updates the role of the agent in its active/standby pair,
+// the inputs are removed from the component model while it is standby.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) processHAStandby(ctx context.Context, standby bool) (err error) {
+	span, ctx := apm.StartSpan(ctx, "ha_standby", "app.internal")
+	defer func() {
+		apm.CaptureError(ctx, err).Send()
+		span.End()
+	}()
+
+	c.setHAStandby(standby)
+
+	if c.ast != nil && c.vars != nil {
+		return c.process(ctx)
+	}
+	return nil
+}
+
+// processHandedOff updates the handoff of the inputs, they are removed from
+// the component model while the node is drained.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) processHandedOff(ctx context.Context, handedOff bool) (err error) {
+	span, ctx := apm.StartSpan(ctx, "handed_off", "app.internal")
+	defer func() {
+		apm.CaptureError(ctx, err).Send()
+		span.End()
+	}()
+
+	c.setHandedOff(handedOff)
+
+	if c.ast != nil && c.vars != nil {
+		return c.process(ctx)
+	}
+	return nil
+}
+
+// processMonitoringReduced reduces or restores the collection of the
+// monitoring metrics, the monitoring components are regenerated accordingly.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) processMonitoringReduced(ctx context.Context, reduced bool) (err error) {
+	span, ctx := apm.StartSpan(ctx, "monitoring_reduced", "app.internal")
+	defer func() {
+		apm.CaptureError(ctx, err).Send()
+		span.End()
+	}()
+
+	c.setMonitoringReduced(reduced)
+	if c.monitorMgr == nil {
+		return nil
+	}
+	c.monitorMgr.SetReduced(reduced)
+
+	if c.ast != nil && c.vars != nil {
+		return c.process(ctx)
+	}
+	return nil
+}
+
 // Always called on the main Coordinator goroutine.
 func (c *Coordinator) process(ctx context.Context) (err error) {
 	span, ctx := apm.StartSpan(ctx, "process", "app.internal")
@@ -997,14 +1513,28 @@ func (c *Coordinator) process(ctx context.Context) (err error) {
 	}()
 
 	// regenerate the component model
-	err = c.recomputeConfigAndComponents()
+	err = c.recomputeConfigAndComponents(ctx)
 	if err != nil {
 		return err
 	}
+	if c.configFresh && c.varsFresh {
+		if err := c.saveConfigCache(); err != nil {
+			c.logger.Warnw("Failed to cache the configuration", "error.message", err)
+		}
+	}
+
+	if len(c.closedStartupGates) > 0 {
+		c.logger.Info("Holding running component model until the startup gates open")
+		c.componentModelHeld = true
+		c.setState(agentclient.Starting, fmt.Sprintf("Waiting for startup gates: %s", gates.Describe(c.closedStartupGates)))
+		return nil
+	}
 
 	c.logger.Info("Updating running component model")
 	c.logger.With("components", c.componentModel).Debug("Updating running component model")
+	updateSpan, _ := apm.StartSpan(ctx, "runtime_update", "app.internal")
 	err = c.runtimeMgr.Update(c.componentModel)
+	updateSpan.End()
 	if err != nil {
 		return err
 	}
@@ -1012,41 +1542,181 @@ func (c *Coordinator) process(ctx context.Context) (err error) {
 	return nil
 }
 
+// setClosedStartupGates updates the closed startup gates, the held component
+// model is sent to the runtime manager once they are all open.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setClosedStartupGates(closed []gates.Closed) {
+	c.closedStartupGates = closed
+	if !c.componentModelHeld {
+		return
+	}
+	if len(closed) > 0 {
+		c.setState(agentclient.Starting, fmt.Sprintf("Waiting for startup gates: %s", gates.Describe(closed)))
+		return
+	}
+
+	c.componentModelHeld = false
+	c.logger.Info("Startup gates are open, updating running component model")
+	c.logger.With("components", c.componentModel).Debug("Updating running component model")
+	if err := c.runtimeMgr.Update(c.componentModel); err != nil {
+		c.setState(agentclient.Failed, err.Error())
+		c.logger.Errorf("%s", err)
+		return
+	}
+	c.setState(agentclient.Healthy, "Running")
+}
+
+// componentModelResult is the outcome of the computation of the component model in a worker.
+type componentModelResult struct {
+	cfg      map[string]interface{}
+	comps    []component.Component
+	warnings []configlint.Warning
+	err      error
+}
+
 // recomputeConfigAndComponents regenerates the configuration tree and
-// components from the current AST and vars and returns the result.
-// Called from both the main Coordinator goroutine and from external
-// goroutines via diagnostics hooks.
-func (c *Coordinator) recomputeConfigAndComponents() error {
+// components from the current AST and vars and stores the result.
+//
+// The computation runs in a worker goroutine, so rendering a large policy
+// does not stall the Coordinator: component states and override states keep
+// being applied and broadcast while it runs. The Coordinator reports that it
+// is configuring once the computation exceeds componentModelSlowThreshold, and
+// abandons it once it exceeds componentModelTimeout.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) recomputeConfigAndComponents(ctx context.Context) error {
+	var cancel context.CancelFunc
+	if c.componentModelTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.componentModelTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// The worker only gets copies of the values owned by the Coordinator
+	// goroutine, it is left running in the background when abandoned.
 	ast := c.ast.Clone()
+	vars := c.vars
+	logLevel := c.state.LogLevel
+	monitoring := c.monitorMgr != nil && c.monitorMgr.Enabled() && !c.state.LoadShedding
+	withoutInputs := c.state.HAStandby || c.state.HandedOff
+	resultCh := make(chan componentModelResult, 1)
+	go func() {
+		// the policy is linted as received, before its inputs are rendered,
+		// only its inputs are linted so only those are copied out of the AST
+		policy := map[string]interface{}{}
+		if inputs, ok := ast.Lookup("inputs"); ok {
+			policy["inputs"] = inputs
+		}
+		cfg, comps, err := c.generateComponentModel(ctx, ast, vars, logLevel, monitoring, withoutInputs)
+		var warnings []configlint.Warning
+		if err == nil {
+			warnings = configlint.Lint(policy, cfg, vars)
+		}
+		resultCh <- componentModelResult{cfg: cfg, comps: comps, warnings: warnings, err: err}
+	}()
+
+	var slowCh <-chan time.Time
+	if c.componentModelSlowThreshold > 0 {
+		slowTimer := time.NewTimer(c.componentModelSlowThreshold)
+		defer slowTimer.Stop()
+		slowCh = slowTimer.C
+	}
+
+	for {
+		select {
+		case result := <-resultCh:
+			if result.err != nil {
+				return result.err
+			}
+			// If we made it this far, update our internal derived values and
+			// return with no error
+			c.derivedConfig = result.cfg
+			c.componentModel = result.comps
+			c.setConfigWarnings(result.warnings)
+			return nil
+
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("computing the component model exceeded the timeout of %s", c.componentModelTimeout)
+			}
+			return ctx.Err()
+
+		case <-slowCh:
+			msg := fmt.Sprintf("Computing the component model is taking longer than %s", c.componentModelSlowThreshold)
+			c.logger.Warn(msg)
+			c.setState(agentclient.Configuring, msg)
+			c.refreshState()
+
+		case componentState := <-c.managerChans.runtimeManagerUpdate:
+			c.applyComponentState(componentState)
+			c.refreshState()
+
+		case overrideState := <-c.overrideStateChan:
+			c.setOverrideState(overrideState)
+			c.refreshState()
+		}
+	}
+}
+
+// generateComponentModel renders the inputs of the AST with the vars and
+// generates the components from the result, the monitoring components are
+// only injected when monitoring is true and the inputs are left out when
+// withoutInputs is true. It does not modify the Coordinator, so it is safe to
+// call from a worker goroutine.
+func (c *Coordinator) generateComponentModel(ctx context.Context, ast *transpiler.AST, vars []*transpiler.Vars, logLevel logp.Level, monitoring bool, withoutInputs bool) (_ map[string]interface{}, _ []component.Component, err error) {
+	span, ctx := apm.StartSpan(ctx, "component_model", "app.internal")
+	defer func() {
+		apm.CaptureError(ctx, err).Send()
+		span.End()
+	}()
+
 	inputs, ok := transpiler.Lookup(ast, "inputs")
 	if ok {
-		renderedInputs, err := transpiler.RenderInputs(inputs, c.vars)
+		renderedInputs, err := c.renderer.Render(inputs, vars)
 		if err != nil {
-			return fmt.Errorf("rendering inputs failed: %w", err)
+			return nil, nil, fmt.Errorf("rendering inputs failed: %w", err)
 		}
 		err = transpiler.Insert(ast, renderedInputs, "inputs")
 		if err != nil {
-			return fmt.Errorf("inserting rendered inputs failed: %w", err)
+			return nil, nil, fmt.Errorf("inserting rendered inputs failed: %w", err)
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 
 	cfg, err := ast.Map()
 	if err != nil {
-		return fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
+		return nil, nil, fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
 	}
 	var configInjector component.GenerateMonitoringCfgFn
-	if c.monitorMgr != nil && c.monitorMgr.Enabled() {
+	if monitoring {
 		configInjector = c.monitorMgr.MonitoringConfig
 	}
+	compsCfg := cfg
+	if withoutInputs {
+		// the standby agent of an active/standby pair, or the agent of a
+		// drained node, runs none of the inputs of the policy, the
+		// configuration keeps them for the diagnostics
+		compsCfg = make(map[string]interface{}, len(cfg))
+		for k, v := range cfg {
+			if k != "inputs" {
+				compsCfg[k] = v
+			}
+		}
+	}
 
 	comps, err := c.specs.ToComponents(
-		cfg,
+		compsCfg,
 		configInjector,
-		c.state.LogLevel,
+		logLevel,
 		c.agentInfo,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to render components: %w", err)
+		return nil, nil, fmt.Errorf("failed to render components: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
 
 	// Filter any disallowed inputs/outputs from the components
@@ -1055,15 +1725,10 @@ func (c *Coordinator) recomputeConfigAndComponents() error {
 	for _, modifier := range c.modifiers {
 		comps, err = modifier(comps, cfg)
 		if err != nil {
-			return fmt.Errorf("failed to modify components: %w", err)
+			return nil, nil, fmt.Errorf("failed to modify components: %w", err)
 		}
 	}
-
-	// If we made it this far, update our internal derived values and
-	// return with no error
-	c.derivedConfig = cfg
-	c.componentModel = comps
-	return nil
+	return cfg, comps, nil
 }
 
 // Filter any inputs and outputs in the generated component model
@@ -1102,6 +1767,11 @@ func (c *Coordinator) handleCoordinatorDone(ctx context.Context, varsErrCh, runt
 	timeoutWait := time.NewTimer(CoordinatorShutdownTimeout)
 	defer timeoutWait.Stop()
 	var returnedRuntime, returnedConfig, returnedVars bool
+	// the phases of the shutdown of the components are reported while waiting for the runtime
+	var shutdownPhases <-chan runtime.ShutdownPhase
+	if c.runtimeMgr != nil {
+		shutdownPhases = c.runtimeMgr.ShutdownPhases()
+	}
 	/*
 		Wait for all subcomponents to gently shut down.
 		Logic:
@@ -1120,6 +1790,9 @@ waitLoop:
 			returnedConfig = true
 		case varsErr = <-varsErrCh:
 			returnedVars = true
+		case phase := <-shutdownPhases:
+			c.setShutdownPhase(phase)
+			c.refreshState()
 		case <-timeoutWait.C:
 			var timeouts []string
 			if !returnedRuntime {
@@ -1135,6 +1808,17 @@ waitLoop:
 			break waitLoop
 		}
 	}
+	// the last phases may be sent right before the runtime returns
+	for drained := false; !drained; {
+		select {
+		case phase := <-shutdownPhases:
+			c.setShutdownPhase(phase)
+			c.refreshState()
+		default:
+			drained = true
+		}
+	}
+
 	// try not to lose any errors
 	var combinedErr error
 	if runtimeErr != nil && !errors.Is(runtimeErr, context.Canceled) {
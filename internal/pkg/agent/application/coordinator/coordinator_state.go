@@ -5,6 +5,15 @@
 package coordinator
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/configlint"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/tcc"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
@@ -20,6 +29,48 @@ type State struct {
 	FleetMessage string                            `yaml:"fleet_message"`
 	Components   []runtime.ComponentComponentState `yaml:"components"`
 	LogLevel     logp.Level                        `yaml:"log_level"`
+
+	// FleetCircuitBreaker is the state of the circuit breaker of the
+	// check-ins with Fleet: closed, open or half_open.
+	FleetCircuitBreaker string `yaml:"fleet_circuit_breaker,omitempty"`
+
+	UpgradeDetails *details.Details `yaml:"upgrade_details,omitempty"`
+
+	// LoadShedding is set while the agent approaches its memory limit, the
+	// monitoring is paused and the agent checks in less often with Fleet.
+	LoadShedding bool `yaml:"load_shedding,omitempty"`
+
+	// HAStandby is set while the agent is the standby agent of an
+	// active/standby pair, it runs none of the inputs of the policy.
+	HAStandby bool `yaml:"ha_standby,omitempty"`
+
+	// HandedOff is set while the Kubernetes node of the agent is drained,
+	// its inputs are stopped and handed off to the other nodes.
+	HandedOff bool `yaml:"handed_off,omitempty"`
+
+	// MonitoringReduced is set while the collection of the monitoring
+	// metrics is reduced to spare the resources of the host.
+	MonitoringReduced bool `yaml:"monitoring_reduced,omitempty"`
+
+	// ConfigCacheTime is the time the policy and variables were cached when
+	// the components run from the configuration cache, zero otherwise.
+	ConfigCacheTime time.Time `yaml:"config_cache_time,omitempty"`
+
+	// ConfigWarnings are the problems the linter found in the policy, they
+	// don't prevent it from running.
+	ConfigWarnings []configlint.Warning `yaml:"config_warnings,omitempty"`
+
+	// FullDiskAccess is the status of the Full Disk Access of the agent on
+	// macOS, the components inherit it. It is empty on the other platforms.
+	FullDiskAccess tcc.Status `yaml:"full_disk_access,omitempty"`
+
+	// ShutdownPhase is the phase of the shutdown of the components while
+	// the agent stops, empty while it runs.
+	ShutdownPhase runtime.ShutdownPhase `yaml:"shutdown_phase,omitempty"`
+
+	// IntegrityError is the reason the agent binary does not match the
+	// manifest written at install or upgrade time, empty when it matches.
+	IntegrityError string `yaml:"integrity_error,omitempty"`
 }
 
 type coordinatorOverrideState struct {
@@ -72,6 +123,298 @@ func (c *Coordinator) setVarsManagerError(err error) {
 	c.stateNeedsRefresh = true
 }
 
+// SetIntegrityError reports the result of the agent binary integrity check.
+// A non-nil error marks the Coordinator as degraded until it is cleared with a nil error.
+// Called from external goroutines.
+func (c *Coordinator) SetIntegrityError(ctx context.Context, err error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.integrityErrCh <- err:
+		return nil
+	}
+}
+
+// setIntegrityError updates the error state for the binary integrity check.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setIntegrityError(err error) {
+	c.integrityErr = err
+	c.state.IntegrityError = ""
+	if err != nil {
+		c.state.IntegrityError = err.Error()
+	}
+	c.stateNeedsRefresh = true
+}
+
+// SetCertificateExpiry reports the result of the certificate expiration check.
+// A non-nil error marks the Coordinator as degraded until it is cleared with a nil error.
+// Called from external goroutines.
+func (c *Coordinator) SetCertificateExpiry(ctx context.Context, err error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.certExpiryErrCh <- err:
+		return nil
+	}
+}
+
+// setCertificateExpiry updates the error state for the certificate expiration check.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setCertificateExpiry(err error) {
+	c.certExpiryErr = err
+	c.stateNeedsRefresh = true
+}
+
+// SetDiskQuotaError reports the result of the disk quota check.
+// A non-nil error marks the Coordinator as degraded until it is cleared with a nil error.
+// Called from external goroutines.
+func (c *Coordinator) SetDiskQuotaError(ctx context.Context, err error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.diskQuotaErrCh <- err:
+		return nil
+	}
+}
+
+// setDiskQuotaError updates the error state for the disk quota check.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setDiskQuotaError(err error) {
+	c.diskQuotaErr = err
+	c.stateNeedsRefresh = true
+}
+
+// SetConfigDriftError reports the result of the configuration drift check.
+// A non-nil error marks the Coordinator as degraded until it is cleared with a nil error.
+// Called from external goroutines.
+func (c *Coordinator) SetConfigDriftError(ctx context.Context, err error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.configDriftErrCh <- err:
+		return nil
+	}
+}
+
+// setConfigDriftError updates the error state for the configuration drift check.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setConfigDriftError(err error) {
+	c.configDriftErr = err
+	c.stateNeedsRefresh = true
+}
+
+// SetDataPlaneEvents reports the events collected from the components, by
+// component ID. They are added to the output unit of the components, and a
+// healthy unit that doesn't ship its events is reported as degraded.
+// Called from external goroutines.
+func (c *Coordinator) SetDataPlaneEvents(ctx context.Context, events map[string]runtime.UnitEvents) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.dataPlaneCh <- events:
+		return nil
+	}
+}
+
+// setDataPlaneEvents updates the events collected from the components.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setDataPlaneEvents(events map[string]runtime.UnitEvents) {
+	c.dataPlaneEvents = events
+	c.stateNeedsRefresh = true
+}
+
+// SetPackageManagedVersion reports the version the package manager is expected to install
+// when upgrades are managed by a package manager. An empty version means no upgrade is pending.
+// Called from external goroutines.
+func (c *Coordinator) SetPackageManagedVersion(ctx context.Context, version string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.pkgManagedVersionCh <- version:
+		return nil
+	}
+}
+
+// setPackageManagedVersion updates the version awaited from the package manager.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setPackageManagedVersion(version string) {
+	c.pkgManagedVersion = version
+	c.stateNeedsRefresh = true
+}
+
+// SetUpgradeDetails reports the progress of an upgrade, it is forwarded to Fleet on check-in.
+// Called from external goroutines, usually as an observer of the upgrade details.
+func (c *Coordinator) SetUpgradeDetails(ctx context.Context, upgradeDetails *details.Details) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.upgradeDetailsChan <- upgradeDetails:
+		return nil
+	}
+}
+
+// setUpgradeDetails updates the reported progress of an upgrade.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setUpgradeDetails(upgradeDetails *details.Details) {
+	if upgradeDetails != nil && (c.state.UpgradeDetails == nil || c.state.UpgradeDetails.State != upgradeDetails.State) {
+		c.logger.Infow(fmt.Sprintf("Upgrade to version %s is in state %s", upgradeDetails.TargetVersion, upgradeDetails.State),
+			"upgrade_details.target_version", upgradeDetails.TargetVersion, "upgrade_details.state", upgradeDetails.State,
+			"upgrade_details.metadata.error_msg", upgradeDetails.Metadata.ErrorMsg, "event.action", diagnostics.EventActionUpgradeStateChange)
+	}
+	c.state.UpgradeDetails = upgradeDetails
+	c.stateNeedsRefresh = true
+}
+
+// SetLoadShedding reports the agent approaches the limits of its resources and must shed load,
+// or that it can resume its normal operation.
+// Called from external goroutines.
+func (c *Coordinator) SetLoadShedding(ctx context.Context, shedding bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.loadSheddingCh <- shedding:
+		return nil
+	}
+}
+
+// setLoadShedding updates the load shedding state.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setLoadShedding(shedding bool) {
+	c.state.LoadShedding = shedding
+	c.stateNeedsRefresh = true
+}
+
+// SetHAStandby reports the agent became the standby agent of its
+// active/standby pair, or the active one.
+// Called from external goroutines.
+func (c *Coordinator) SetHAStandby(ctx context.Context, standby bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.haStandbyCh <- standby:
+		return nil
+	}
+}
+
+// setHAStandby updates the role of the agent in its active/standby pair.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setHAStandby(standby bool) {
+	c.state.HAStandby = standby
+	c.stateNeedsRefresh = true
+}
+
+// SetFullDiskAccess reports the status of the Full Disk Access of the agent
+// on macOS.
+// Called from external goroutines.
+func (c *Coordinator) SetFullDiskAccess(ctx context.Context, status tcc.Status) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.fullDiskAccessCh <- status:
+		return nil
+	}
+}
+
+// setFullDiskAccess updates the status of the Full Disk Access.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setFullDiskAccess(status tcc.Status) {
+	c.state.FullDiskAccess = status
+	c.stateNeedsRefresh = true
+}
+
+// SetHandedOff reports the Kubernetes node of the agent is drained and its
+// inputs must be stopped, or that it runs them again.
+// Called from external goroutines.
+func (c *Coordinator) SetHandedOff(ctx context.Context, handedOff bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.handedOffCh <- handedOff:
+		return nil
+	}
+}
+
+// setHandedOff updates the handoff of the inputs.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setHandedOff(handedOff bool) {
+	c.state.HandedOff = handedOff
+	c.stateNeedsRefresh = true
+}
+
+// setConfigWarnings updates the warnings of the linter of the policy, the new
+// warnings are logged.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setConfigWarnings(warnings []configlint.Warning) {
+	if reflect.DeepEqual(c.state.ConfigWarnings, warnings) {
+		return
+	}
+	for _, w := range warnings {
+		c.logger.Warnw(fmt.Sprintf("Configuration warning: %s", w),
+			"config.lint.rule", w.Rule,
+			"config.lint.path", w.Path,
+			"event.action", diagnostics.EventActionConfigLint)
+	}
+	c.state.ConfigWarnings = warnings
+	c.stateNeedsRefresh = true
+}
+
+// configWarningsMessage returns the message reporting the warnings of the
+// linter of the policy.
+func configWarningsMessage(warnings []configlint.Warning) string {
+	msg := fmt.Sprintf("Running with a configuration warning: %s", warnings[0])
+	if len(warnings) > 1 {
+		msg = fmt.Sprintf("Running with %d configuration warnings, the first one is %s", len(warnings), warnings[0])
+	}
+	return msg
+}
+
+// SetFleetCircuitBreaker reports the state of the circuit breaker of the
+// check-ins with Fleet.
+// Called from external goroutines.
+func (c *Coordinator) SetFleetCircuitBreaker(ctx context.Context, state string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.fleetCircuitBreakerCh <- state:
+		return nil
+	}
+}
+
+// setFleetCircuitBreaker updates the state of the circuit breaker of the
+// check-ins.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setFleetCircuitBreaker(state string) {
+	c.state.FleetCircuitBreaker = state
+	c.stateNeedsRefresh = true
+}
+
+// SetMonitoringReduced reduces the collection of the monitoring metrics while the resources of the
+// host are under pressure, or restores it.
+// Called from external goroutines.
+func (c *Coordinator) SetMonitoringReduced(ctx context.Context, reduced bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case c.monitoringReducedCh <- reduced:
+		return nil
+	}
+}
+
+// setMonitoringReduced updates the state of the collection of the monitoring metrics.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setMonitoringReduced(reduced bool) {
+	c.state.MonitoringReduced = reduced
+	c.stateNeedsRefresh = true
+}
+
+// setConfigCacheTime updates the time of the configuration cache the
+// components run from, zero once they run from a fresh configuration.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setConfigCacheTime(t time.Time) {
+	c.state.ConfigCacheTime = t
+	c.stateNeedsRefresh = true
+}
+
 // setOverrideState is the internal helper to set the override state and
 // set stateNeedsRefresh.
 // Must be called on the main Coordinator goroutine.
@@ -128,9 +471,25 @@ func (c *Coordinator) generateReportableState() (s State) {
 	s.Message = c.state.Message
 	s.FleetState = c.state.FleetState
 	s.FleetMessage = c.state.FleetMessage
+	s.FleetCircuitBreaker = c.state.FleetCircuitBreaker
 	s.LogLevel = c.state.LogLevel
+	s.UpgradeDetails = c.state.UpgradeDetails
+	s.LoadShedding = c.state.LoadShedding
+	s.HAStandby = c.state.HAStandby
+	s.HandedOff = c.state.HandedOff
+	s.MonitoringReduced = c.state.MonitoringReduced
+	s.ConfigCacheTime = c.state.ConfigCacheTime
+	s.ConfigWarnings = c.state.ConfigWarnings
+	s.FullDiskAccess = c.state.FullDiskAccess
+	s.ShutdownPhase = c.state.ShutdownPhase
+	s.IntegrityError = c.state.IntegrityError
 	s.Components = make([]runtime.ComponentComponentState, len(c.state.Components))
 	copy(s.Components, c.state.Components)
+	for i := range s.Components {
+		if events, ok := c.dataPlaneEvents[s.Components[i].Component.ID]; ok {
+			s.Components[i].State = withDataPlaneEvents(s.Components[i].State, events)
+		}
+	}
 
 	if c.overrideState != nil {
 		// state has been overridden due to an action that is occurring
@@ -155,12 +514,45 @@ func (c *Coordinator) generateReportableState() (s State) {
 		} else if c.varsMgrErr != nil {
 			s.State = agentclient.Failed
 			s.Message = c.varsMgrErr.Error()
+		} else if c.integrityErr != nil {
+			s.State = agentclient.Degraded
+			s.Message = c.integrityErr.Error()
+		} else if c.certExpiryErr != nil {
+			s.State = agentclient.Degraded
+			s.Message = c.certExpiryErr.Error()
+		} else if c.diskQuotaErr != nil {
+			s.State = agentclient.Degraded
+			s.Message = c.diskQuotaErr.Error()
+		} else if c.configDriftErr != nil {
+			s.State = agentclient.Degraded
+			s.Message = c.configDriftErr.Error()
+		} else if c.state.LoadShedding {
+			s.State = agentclient.Degraded
+			s.Message = "Elastic Agent is approaching its memory limit, monitoring is paused"
 		} else if hasState(s.Components, client.UnitStateFailed) {
 			s.State = agentclient.Degraded
 			s.Message = "1 or more components/units in a failed state"
 		} else if hasState(s.Components, client.UnitStateDegraded) {
 			s.State = agentclient.Degraded
 			s.Message = "1 or more components/units in a degraded state"
+		} else if !c.state.ConfigCacheTime.IsZero() {
+			s.State = agentclient.Degraded
+			s.Message = fmt.Sprintf("Running the configuration cached at %s until the initial configuration is received", c.state.ConfigCacheTime.UTC().Format(time.RFC3339))
+		} else if c.pkgManagedVersion != "" {
+			// still healthy, only let Fleet know the upgrade is pending
+			s.Message = fmt.Sprintf("Waiting for package manager to upgrade to version %s", c.pkgManagedVersion)
+		} else if c.state.HandedOff {
+			// still healthy, only let Fleet know the inputs were handed off
+			s.Message = "Handed off, the node is drained and the inputs are stopped"
+		} else if c.state.HAStandby {
+			// still healthy, only let Fleet know the peer runs the inputs
+			s.Message = "Standby, the active agent of the pair runs the inputs"
+		} else if len(c.state.ConfigWarnings) > 0 {
+			// still healthy, only let Fleet know the policy has problems to fix
+			s.Message = configWarningsMessage(c.state.ConfigWarnings)
+		} else if c.state.FullDiskAccess == tcc.StatusDenied {
+			// still healthy, the components only miss the protected files
+			s.Message = "Full Disk Access is not granted, " + tcc.Guidance
 		}
 	}
 	return s
@@ -174,6 +566,13 @@ func (c *Coordinator) setState(state agentclient.State, message string) {
 	c.stateNeedsRefresh = true
 }
 
+// setShutdownPhase reports the phase of the shutdown of the components.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setShutdownPhase(phase runtime.ShutdownPhase) {
+	c.state.ShutdownPhase = phase
+	c.setState(agentclient.Stopping, fmt.Sprintf("Stopping components: %s", phase))
+}
+
 // setFleetState changes the fleet state of the coordinator.
 // Must be called on the main Coordinator goroutine.
 func (c *Coordinator) setFleetState(state agentclient.State, message string) {
@@ -191,6 +590,25 @@ func (c *Coordinator) setLogLevel(logLevel logp.Level) {
 
 // Returns true if any component in the given list, or any unit in one of
 // those components, matches the given state.
+// withDataPlaneEvents returns a copy of the state of the component with the
+// events added to its output unit, the unit is degraded when it is healthy
+// but doesn't ship its events.
+func withDataPlaneEvents(state runtime.ComponentState, events runtime.UnitEvents) runtime.ComponentState {
+	units := make(map[runtime.ComponentUnitKey]runtime.ComponentUnitState, len(state.Units))
+	for key, unit := range state.Units {
+		if key.UnitType == client.UnitTypeOutput {
+			unit.Events = &events
+			if events.Problem != "" && unit.State == client.UnitStateHealthy {
+				unit.State = client.UnitStateDegraded
+				unit.Message = fmt.Sprintf("Healthy but not shipping: %s", events.Problem)
+			}
+		}
+		units[key] = unit
+	}
+	state.Units = units
+	return state
+}
+
 func hasState(components []runtime.ComponentComponentState, state client.UnitState) bool {
 	for _, comp := range components {
 		if comp.State.State == state {
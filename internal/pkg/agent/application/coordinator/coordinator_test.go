@@ -28,6 +28,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
@@ -37,6 +38,7 @@ import (
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils/broadcaster"
 )
 
 const (
@@ -345,6 +347,76 @@ func TestCoordinatorShutdownAllResponsesNoErrors(t *testing.T) {
 	}, handlerChan)
 }
 
+func TestCoordinatorShutdownPhases(t *testing.T) {
+	defer func(prev time.Duration) { CoordinatorShutdownTimeout = prev }(CoordinatorShutdownTimeout)
+	CoordinatorShutdownTimeout = 5 * time.Second
+
+	phases := make(chan runtime.ShutdownPhase, 4)
+	coord := &Coordinator{
+		logger:           logp.L(),
+		runtimeMgr:       &fakeRuntimeManager{shutdownPhases: phases},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stateChan := coord.StateSubscribe(ctx, 32)
+
+	runtimeErrCh := make(chan error)
+	errCh := make(chan error, 1)
+	go func() {
+		doneCtx, doneCancel := context.WithCancel(context.Background())
+		// emulate shutdown
+		doneCancel()
+		errCh <- coord.handleCoordinatorDone(doneCtx, errChWith(nil), runtimeErrCh, errChWith(nil))
+	}()
+
+	// the phases are reported while the runtime shuts the components down
+	phases <- runtime.ShutdownPhaseInputs
+	phases <- runtime.ShutdownPhaseShippers
+	awaitShutdownPhase(t, stateChan, runtime.ShutdownPhaseShippers)
+	phases <- runtime.ShutdownPhaseServices
+	phases <- runtime.ShutdownPhaseStopped
+	runtimeErrCh <- nil
+	require.ErrorIs(t, <-errCh, context.Canceled)
+
+	assert.Equal(t, runtime.ShutdownPhaseStopped, coord.state.ShutdownPhase)
+	assert.Equal(t, agentclient.Stopping, coord.state.State)
+}
+
+// awaitShutdownPhase waits for the state to report the phase, the earlier phases must come before it.
+func awaitShutdownPhase(t *testing.T, stateChan chan State, phase runtime.ShutdownPhase) {
+	t.Helper()
+	order := []runtime.ShutdownPhase{"", runtime.ShutdownPhaseInputs, runtime.ShutdownPhaseShippers, runtime.ShutdownPhaseServices, runtime.ShutdownPhaseStopped}
+	index := func(p runtime.ShutdownPhase) int {
+		for i, o := range order {
+			if o == p {
+				return i
+			}
+		}
+		return -1
+	}
+	last := 0
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case state := <-stateChan:
+			require.GreaterOrEqual(t, index(state.ShutdownPhase), last, "phase %q reported out of order", state.ShutdownPhase)
+			last = index(state.ShutdownPhase)
+			if state.ShutdownPhase == phase {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for shutdown phase %q", phase)
+		}
+	}
+}
+
+func errChWith(err error) chan error {
+	ch := make(chan error, 1)
+	ch <- err
+	return ch
+}
+
 func waitAndTestError(t *testing.T, check func(error) bool, handlerErr chan error) {
 	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second*4)
 	defer waitCancel()
@@ -502,7 +574,7 @@ func createCoordinator(t *testing.T, opts ...CoordinatorOpt) (*Coordinator, *fak
 	require.NoError(t, err)
 
 	monitoringMgr := newTestMonitoringMgr()
-	rm, err := runtime.NewManager(l, l, "localhost:0", ai, apmtest.DiscardTracer, monitoringMgr, configuration.DefaultGRPCConfig())
+	rm, err := runtime.NewManager(l, l, "localhost:0", ai, apmtest.DiscardTracer, monitoringMgr, configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 
 	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
@@ -511,7 +583,7 @@ func createCoordinator(t *testing.T, opts ...CoordinatorOpt) (*Coordinator, *fak
 	cfgMgr := newFakeConfigManager()
 	varsMgr := newFakeVarsManager()
 
-	coord := New(l, nil, logp.DebugLevel, ai, specs, &fakeReExecManager{}, &fakeUpgradeManager{}, rm, cfgMgr, varsMgr, caps, monitoringMgr, o.managed)
+	coord := New(l, nil, logp.DebugLevel, ai, nil, specs, &fakeReExecManager{}, &fakeUpgradeManager{}, rm, cfgMgr, varsMgr, caps, monitoringMgr, o.managed)
 	return coord, cfgMgr, varsMgr
 }
 
@@ -558,7 +630,7 @@ func (f *fakeUpgradeManager) Reload(cfg *config.Config) error {
 	return nil
 }
 
-func (f *fakeUpgradeManager) Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error) {
+func (f *fakeUpgradeManager) Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, details *details.Details, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error) {
 	f.upgradeCalled = true
 	if f.upgradeErr != nil {
 		return nil, f.upgradeErr
@@ -579,6 +651,7 @@ func (*testMonitoringManager) Prepare(_ string) error
 func (*testMonitoringManager) Cleanup(string) error                                  { return nil }
 func (*testMonitoringManager) Enabled() bool                                         { return false }
 func (*testMonitoringManager) Reload(rawConfig *config.Config) error                 { return nil }
+func (*testMonitoringManager) SetReduced(_ bool)                                     {}
 func (*testMonitoringManager) MonitoringConfig(_ map[string]interface{}, _ []component.Component, _ map[string]string) (map[string]interface{}, error) {
 	return nil, nil
 }
@@ -699,6 +772,7 @@ func (f *fakeVarsManager) Vars(ctx context.Context, vars []*transpiler.Vars) {
 type fakeRuntimeManager struct {
 	state          []runtime.ComponentComponentState
 	updateCallback func([]component.Component) error
+	shutdownPhases chan runtime.ShutdownPhase
 }
 
 func (r *fakeRuntimeManager) Run(ctx context.Context) error {
@@ -732,6 +806,18 @@ func (r *fakeRuntimeManager) SubscribeAll(context.Context) *runtime.Subscription
 
 // PerformDiagnostics executes the diagnostic action for the provided units. If no units are provided then
 // it performs diagnostics for all current units.
+func (r *fakeRuntimeManager) RotateCredentials(context.Context) error {
+	return nil
+}
+
+func (r *fakeRuntimeManager) Heartbeat(context.Context) error {
+	return nil
+}
+
+func (r *fakeRuntimeManager) ShutdownPhases() <-chan runtime.ShutdownPhase {
+	return r.shutdownPhases
+}
+
 func (r *fakeRuntimeManager) PerformDiagnostics(context.Context, ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic {
 	return nil
 }
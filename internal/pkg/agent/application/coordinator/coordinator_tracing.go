@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package coordinator
+
+import (
+	"context"
+
+	"go.elastic.co/apm"
+)
+
+// traceConfigChange returns the context the configuration change is applied
+// with and the function ending its transaction. The transaction of a change
+// triggered by a traced action is a child of the transaction of the action, so
+// the spans of the policy application, the component model and the runtime
+// update are part of the trace of the action.
+func (c *Coordinator) traceConfigChange(ctx context.Context, change ConfigChange) (context.Context, func()) {
+	var parent *apm.Transaction
+	if traced, ok := change.(TracedConfigChange); ok {
+		parent = traced.Transaction()
+	}
+	return c.startTransaction(ctx, "config change", parent)
+}
+
+// startTransaction starts a transaction of the Coordinator when the agent is
+// traced, it returns the context holding it and the function ending it. The
+// transaction is a child of parent when not nil, the work of the Coordinator
+// outlives the transactions of the actions so it's not traced in them directly.
+func (c *Coordinator) startTransaction(ctx context.Context, name string, parent *apm.Transaction) (context.Context, func()) {
+	if c.tracer == nil {
+		return ctx, func() {}
+	}
+	var opts apm.TransactionOptions
+	if parent != nil {
+		opts.TraceContext = parent.TraceContext()
+	}
+	tx := c.tracer.StartTransactionOptions(name, "coordinator", opts)
+	return apm.ContextWithTransaction(ctx, tx), tx.End
+}
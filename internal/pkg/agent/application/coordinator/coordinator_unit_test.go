@@ -20,12 +20,17 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/apmtest"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/gates"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/tcc"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/pkg/component"
@@ -318,6 +323,90 @@ func TestCoordinatorReportsUnhealthyUnits(t *testing.T) {
 	}
 }
 
+func TestCoordinatorReportsUnitsNotShipping(t *testing.T) {
+	// Set a one-second timeout -- nothing here should block, but if it
+	// does let's report a failure instead of timing out the test runner.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Channels have buffer length 1 so we don't have to run on multiple
+	// goroutines.
+	stateChan := make(chan State, 1)
+	runtimeChan := make(chan runtime.ComponentComponentState, 1)
+	dataPlaneCh := make(chan map[string]runtime.UnitEvents, 1)
+	coord := &Coordinator{
+		state: State{
+			State:   agentclient.Healthy,
+			Message: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		managerChans: managerChans{
+			runtimeManagerUpdate: runtimeChan,
+		},
+		dataPlaneCh: dataPlaneCh,
+	}
+
+	inputKey := runtime.ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "input-unit-1"}
+	outputKey := runtime.ComponentUnitKey{UnitType: client.UnitTypeOutput, UnitID: "output-unit-1"}
+	runtimeChan <- runtime.ComponentComponentState{
+		Component: component.Component{ID: "test-component-1"},
+		State: runtime.ComponentState{
+			State:   client.UnitStateHealthy,
+			Message: "everything is fine",
+			Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+				inputKey:  {State: client.UnitStateHealthy, Message: "everything is fine"},
+				outputKey: {State: client.UnitStateHealthy, Message: "everything is fine"},
+			},
+		},
+	}
+	coord.runLoopIteration(ctx)
+	<-stateChan
+
+	// The output unit ships its events
+	dataPlaneCh <- map[string]runtime.UnitEvents{
+		"test-component-1": {Published: 100},
+	}
+	coord.runLoopIteration(ctx)
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State)
+		unit := state.Components[0].State.Units[outputKey]
+		require.NotNil(t, unit.Events, "events should be added to the output unit")
+		assert.Equal(t, uint64(100), unit.Events.Published)
+		assert.Nil(t, state.Components[0].State.Units[inputKey].Events, "events should only be added to the output unit")
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+
+	// The output unit doesn't ship its events anymore
+	dataPlaneCh <- map[string]runtime.UnitEvents{
+		"test-component-1": {Published: 100, Queued: 4096, Problem: "4096 event(s) queued and none acknowledged by the output in the last 30s"},
+	}
+	coord.runLoopIteration(ctx)
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Degraded, state.State, "Unit not shipping its events should cause Degraded Coordinator state")
+		unit := state.Components[0].State.Units[outputKey]
+		assert.Equal(t, client.UnitStateDegraded, unit.State)
+		assert.Equal(t, "Healthy but not shipping: 4096 event(s) queued and none acknowledged by the output in the last 30s", unit.Message)
+		assert.Equal(t, map[string]interface{}{
+			"events": map[string]interface{}{
+				"published": uint64(100),
+				"dropped":   uint64(0),
+				"failed":    uint64(0),
+				"queued":    uint64(4096),
+				"problem":   "4096 event(s) queued and none acknowledged by the output in the last 30s",
+			},
+		}, unit.ReportedPayload())
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+	assert.Equal(t, client.UnitStateHealthy, coord.state.Components[0].State.Units[outputKey].State,
+		"the state received from the runtime manager shouldn't be modified")
+}
+
 func TestCoordinatorReportsInvalidPolicy(t *testing.T) {
 	// Test that an obviously invalid policy sent to Coordinator will call
 	// its Fail callback with an appropriate error.
@@ -355,6 +444,7 @@ func TestCoordinatorReportsInvalidPolicy(t *testing.T) {
 			logger,
 			&artifact.Config{},
 			&info.AgentInfo{},
+			nil,
 		),
 	}
 
@@ -548,6 +638,128 @@ func TestCoordinatorReportsRuntimeManagerUpdateFailure(t *testing.T) {
 	assert.Equal(t, "update failed for testing reasons", state.Message, "Failed policy update should be reported in Coordinator state message")
 }
 
+func TestCoordinatorReportsSlowComponentModel(t *testing.T) {
+	// Block the computation of the component model in a modifier and make
+	// sure Coordinator reports it is configuring, then applies the model
+	// once the computation ends.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	logger := logp.NewLogger("testing")
+
+	stateChan := make(chan State, 1)
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	release := make(chan struct{})
+
+	var updated bool
+	coord := &Coordinator{
+		logger:           logger,
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{InputChan: stateChan},
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				updated = true
+				return nil
+			},
+		},
+		modifiers: []ComponentsModifier{
+			func(comps []component.Component, cfg map[string]interface{}) ([]component.Component, error) {
+				<-release
+				return comps, nil
+			},
+		},
+		componentModelSlowThreshold: time.Millisecond,
+	}
+
+	varsChan <- []*transpiler.Vars{{}}
+	coord.runLoopIteration(ctx)
+
+	cfgChange := &configChange{cfg: config.MustNewConfigFrom(nil)}
+	configChan <- cfgChange
+	done := make(chan struct{})
+	go func() {
+		coord.runLoopIteration(ctx)
+		close(done)
+	}()
+
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Configuring, state.State, "slow computation should be reported as configuring")
+		assert.Equal(t, "Computing the component model is taking longer than 1ms", state.Message)
+	case <-ctx.Done():
+		require.Fail(t, "timed out waiting for the configuring state")
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-ctx.Done():
+		require.Fail(t, "timed out waiting for the component model")
+	}
+	assert.True(t, cfgChange.acked, "Coordinator should ACK the policy change once the computation ends")
+	assert.True(t, updated, "Runtime manager should be updated once the computation ends")
+
+	state := <-stateChan
+	assert.Equal(t, agentclient.Healthy, state.State)
+}
+
+func TestCoordinatorReportsComponentModelTimeout(t *testing.T) {
+	// Block the computation of the component model in a modifier past the
+	// timeout and make sure Coordinator fails the policy change.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	logger := logp.NewLogger("testing")
+
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	release := make(chan struct{})
+	defer close(release)
+
+	var updated bool
+	coord := &Coordinator{
+		logger:           logger,
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				updated = true
+				return nil
+			},
+		},
+		modifiers: []ComponentsModifier{
+			func(comps []component.Component, cfg map[string]interface{}) ([]component.Component, error) {
+				<-release
+				return comps, nil
+			},
+		},
+		componentModelTimeout: 10 * time.Millisecond,
+	}
+
+	varsChan <- []*transpiler.Vars{{}}
+	coord.runLoopIteration(ctx)
+
+	cfgChange := &configChange{cfg: config.MustNewConfigFrom(nil)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+
+	assert.True(t, cfgChange.failed, "Config change should fail when the computation times out")
+	require.Error(t, cfgChange.err)
+	assert.Equal(t, "computing the component model exceeded the timeout of 10ms", cfgChange.err.Error())
+	assert.False(t, updated, "Runtime manager should not be updated when the computation times out")
+
+	state := coord.State()
+	assert.Equal(t, agentclient.Failed, state.State)
+	assert.Equal(t, cfgChange.err.Error(), state.Message)
+}
+
 func TestCoordinatorAppliesVarsToPolicy(t *testing.T) {
 	// Make sure:
 	// - An input unit that depends on an undefined variable is not created
@@ -647,6 +859,78 @@ inputs:
 	assert.Equal(t, "changed-input-id", components[0].Units[0].Config.Id)
 }
 
+func TestCoordinatorReportsConfigWarnings(t *testing.T) {
+	// Set a one-second timeout -- nothing here should block, but if it
+	// does let's report a failure instead of timing out the test runner.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	logger := logp.NewLogger("testing")
+
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	var updated bool
+	coord := &Coordinator{
+		logger:           logger,
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				updated = true
+				return nil
+			},
+		},
+	}
+
+	vars, err := transpiler.NewVars("", map[string]interface{}{}, nil)
+	require.NoError(t, err, "Vars creation must succeed")
+	varsChan <- []*transpiler.Vars{vars}
+	coord.runLoopIteration(ctx)
+
+	// Two streams share the same ID
+	cfgChange := &configChange{cfg: config.MustNewConfigFrom(`
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: logs
+    type: filestream
+    use_output: default
+    streams:
+      - id: app
+      - id: app
+        index: logs-other
+`)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	assert.True(t, cfgChange.acked, "A policy with warnings should still be acknowledged")
+	assert.True(t, updated, "A policy with warnings should still run")
+
+	require.Len(t, coord.state.ConfigWarnings, 1)
+	state := coord.generateReportableState()
+	assert.Equal(t, coord.state.ConfigWarnings, state.ConfigWarnings)
+	assert.Equal(t, agentclient.Healthy, state.State, "Configuration warnings shouldn't affect the health of the agent")
+	assert.Equal(t, "Running with a configuration warning: inputs.logs.streams.app.id: the ID app is also used by a stream of inputs.logs", state.Message)
+
+	// The warning is fixed
+	cfgChange = &configChange{cfg: config.MustNewConfigFrom(`
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: logs
+    type: filestream
+    use_output: default
+`)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	assert.Empty(t, coord.state.ConfigWarnings)
+	assert.Equal(t, "Running", coord.generateReportableState().Message)
+}
+
 func TestCoordinatorReportsOverrideState(t *testing.T) {
 	// Set a one-second timeout -- nothing here should block, but if it
 	// does let's report a failure instead of timing out the test runner.
@@ -697,6 +981,98 @@ func TestCoordinatorReportsOverrideState(t *testing.T) {
 	}
 }
 
+func TestCoordinatorReportsPackageManagedVersion(t *testing.T) {
+	// Set a one-second timeout -- nothing here should block, but if it
+	// does let's report a failure instead of timing out the test runner.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Channels have buffer length 1 so we don't have to run on multiple
+	// goroutines.
+	stateChan := make(chan State, 1)
+	pkgManagedVersionCh := make(chan string, 1)
+	coord := &Coordinator{
+		state: State{
+			State:   agentclient.Healthy,
+			Message: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		pkgManagedVersionCh: pkgManagedVersionCh,
+	}
+
+	pkgManagedVersionCh <- "8.9.0"
+	coord.runLoopIteration(ctx)
+
+	// Waiting for the package manager doesn't affect the health of the agent
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State, "expected Healthy State")
+		assert.Equal(t, "Waiting for package manager to upgrade to version 8.9.0", state.Message)
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+
+	pkgManagedVersionCh <- ""
+	coord.runLoopIteration(ctx)
+
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State, "expected Healthy State")
+		assert.Equal(t, "Running", state.Message, "state message should return to its original value")
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+}
+
+func TestCoordinatorReportsFullDiskAccess(t *testing.T) {
+	// Set a one-second timeout -- nothing here should block, but if it
+	// does let's report a failure instead of timing out the test runner.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Channels have buffer length 1 so we don't have to run on multiple
+	// goroutines.
+	stateChan := make(chan State, 1)
+	fullDiskAccessCh := make(chan tcc.Status, 1)
+	coord := &Coordinator{
+		state: State{
+			State:   agentclient.Healthy,
+			Message: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		fullDiskAccessCh: fullDiskAccessCh,
+	}
+
+	fullDiskAccessCh <- tcc.StatusDenied
+	coord.runLoopIteration(ctx)
+
+	// Missing Full Disk Access doesn't affect the health of the agent
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State, "expected Healthy State")
+		assert.Equal(t, tcc.StatusDenied, state.FullDiskAccess)
+		assert.Contains(t, state.Message, "Full Disk Access is not granted")
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+
+	fullDiskAccessCh <- tcc.StatusGranted
+	coord.runLoopIteration(ctx)
+
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State, "expected Healthy State")
+		assert.Equal(t, tcc.StatusGranted, state.FullDiskAccess)
+		assert.Equal(t, "Running", state.Message, "state message should return to its original value")
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+}
+
 func TestCoordinatorInitiatesUpgrade(t *testing.T) {
 	// Set a one-second timeout -- nothing here should block, but if it
 	// does let's report a failure instead of timing out the test runner.
@@ -715,10 +1091,14 @@ func TestCoordinatorInitiatesUpgrade(t *testing.T) {
 		upgradeErr:  errors.New("failed upgrade"),
 	}
 
+	// upgradeDetailsChan has buffer 1 to receive the failed upgrade details
+	upgradeDetailsChan := make(chan *details.Details, 1)
+
 	coord := &Coordinator{
-		stateBroadcaster:  broadcaster.New(State{}, 0, 0),
-		overrideStateChan: overrideStateChan,
-		upgradeMgr:        upgradeMgr,
+		stateBroadcaster:   broadcaster.New(State{}, 0, 0),
+		overrideStateChan:  overrideStateChan,
+		upgradeDetailsChan: upgradeDetailsChan,
+		upgradeMgr:         upgradeMgr,
 	}
 
 	// Call upgrade and make sure the upgrade manager receives an Upgrade call
@@ -741,4 +1121,339 @@ func TestCoordinatorInitiatesUpgrade(t *testing.T) {
 	default:
 		assert.Fail(t, "Failed upgrade should clear the override state")
 	}
+
+	// Make sure the failure was reported in the upgrade details
+	select {
+	case upgradeDetails := <-upgradeDetailsChan:
+		require.NotNil(t, upgradeDetails, "Failed upgrade should report upgrade details")
+		assert.Equal(t, details.StateFailed, upgradeDetails.State)
+		assert.Equal(t, "1.2.3", upgradeDetails.TargetVersion)
+		assert.Equal(t, "failed upgrade", upgradeDetails.Metadata.ErrorMsg)
+	default:
+		assert.Fail(t, "Failed upgrade should report upgrade details")
+	}
+}
+
+func TestCoordinatorSetUpgradeDetailsStopped(t *testing.T) {
+	// the run loop is not running, the upgrade details are never read
+	coord := &Coordinator{upgradeDetailsChan: make(chan *details.Details)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := coord.SetUpgradeDetails(ctx, details.NewDetails("1.2.3", details.StateDownloading, ""))
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "SetUpgradeDetails should not block once the context is done")
+}
+
+func TestCoordinatorHeartbeat(t *testing.T) {
+	// Heartbeat only returns once the run loop handled it, or with the
+	// error of the context when the run loop does not iterate.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		heartbeatCh:      make(chan chan struct{}),
+	}
+
+	hbCtx, hbCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer hbCancel()
+	assert.ErrorIs(t, coord.Heartbeat(hbCtx), context.DeadlineExceeded, "Heartbeat should fail when the run loop does not iterate")
+
+	hbErr := make(chan error)
+	go func() {
+		hbErr <- coord.Heartbeat(ctx)
+	}()
+	coord.runLoopIteration(ctx)
+	assert.NoError(t, <-hbErr, "Heartbeat should succeed once handled by the run loop")
+}
+
+func TestCoordinatorHoldsComponentModelUntilStartupGatesOpen(t *testing.T) {
+	// The component model is computed but not sent to the runtime manager
+	// while a startup gate is closed, it is sent once the gates open.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+
+	var updated bool
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				updated = true
+				return nil
+			},
+		},
+		startupGatesCh:     make(chan []gates.Closed, 1),
+		closedStartupGates: []gates.Closed{{Name: "network", Err: errors.New("no interface has a global unicast address")}},
+	}
+
+	varsChan <- []*transpiler.Vars{{}}
+	coord.runLoopIteration(ctx)
+
+	cfgChange := &configChange{cfg: config.MustNewConfigFrom(nil)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+
+	assert.True(t, cfgChange.acked, "Coordinator should ack the held policy change")
+	assert.False(t, updated, "Runtime manager should not be updated while a startup gate is closed")
+	assert.Equal(t, agentclient.Starting, coord.state.State)
+	assert.Equal(t, "Waiting for startup gates: network (no interface has a global unicast address)", coord.state.Message)
+
+	coord.startupGatesCh <- []gates.Closed{{Name: "time_sync", Err: errors.New("the clock is not synchronized")}}
+	coord.runLoopIteration(ctx)
+	assert.False(t, updated, "Runtime manager should not be updated while a startup gate is closed")
+	assert.Equal(t, "Waiting for startup gates: time_sync (the clock is not synchronized)", coord.state.Message)
+
+	coord.startupGatesCh <- nil
+	coord.runLoopIteration(ctx)
+	assert.True(t, updated, "Runtime manager should be updated once the startup gates are open")
+	assert.Equal(t, agentclient.Healthy, coord.state.State)
+	assert.False(t, coord.componentModelHeld)
+}
+
+func TestCoordinatorPausesMonitoringWhileSheddingLoad(t *testing.T) {
+	// The monitoring configuration is not injected in the component model
+	// while the agent sheds load, and the agent reports it is degraded.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	monitorMgr := &fakeMonitorManager{}
+	var updates int
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				updates++
+				return nil
+			},
+		},
+		monitorMgr:     monitorMgr,
+		loadSheddingCh: make(chan bool, 1),
+	}
+
+	varsChan <- []*transpiler.Vars{{}}
+	coord.runLoopIteration(ctx)
+	configChan <- &configChange{cfg: config.MustNewConfigFrom(nil)}
+	coord.runLoopIteration(ctx)
+	assert.Equal(t, 1, monitorMgr.injected, "monitoring should be injected")
+
+	coord.loadSheddingCh <- true
+	coord.runLoopIteration(ctx)
+	assert.Equal(t, 2, updates, "Runtime manager should be updated when the load shedding starts")
+	assert.Equal(t, 1, monitorMgr.injected, "monitoring should not be injected while shedding load")
+	state := coord.generateReportableState()
+	assert.True(t, state.LoadShedding)
+	assert.Equal(t, agentclient.Degraded, state.State)
+
+	coord.loadSheddingCh <- false
+	coord.runLoopIteration(ctx)
+	assert.Equal(t, 3, updates, "Runtime manager should be updated when the load shedding stops")
+	assert.Equal(t, 2, monitorMgr.injected, "monitoring should be injected once the load shedding stops")
+	state = coord.generateReportableState()
+	assert.False(t, state.LoadShedding)
+	assert.Equal(t, agentclient.Healthy, state.State)
+}
+
+func TestCoordinatorRunsNoInputsWhileHAStandby(t *testing.T) {
+	// The inputs are left out of the component model while the agent is the
+	// standby agent of its active/standby pair, and they are started once it
+	// becomes the active agent.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	var components []component.Component
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{
+			updateCallback: func(comp []component.Component) error {
+				components = comp
+				return nil
+			},
+		},
+		haStandbyCh: make(chan bool, 1),
+		state:       State{HAStandby: true},
+	}
+
+	varsChan <- []*transpiler.Vars{{}}
+	coord.runLoopIteration(ctx)
+
+	cfg := config.MustNewConfigFrom(`
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: test-input
+    type: filestream
+    use_output: default
+`)
+	cfgChange := &configChange{cfg: cfg}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	assert.True(t, cfgChange.acked, "Coordinator should ACK a policy change while standby")
+	assert.Empty(t, components, "Standby agent should run no inputs")
+	assert.Contains(t, coord.derivedConfig, "inputs", "Configuration should keep the inputs while standby")
+	state := coord.generateReportableState()
+	assert.Equal(t, agentclient.Healthy, state.State)
+	assert.Equal(t, "Standby, the active agent of the pair runs the inputs", state.Message)
+
+	coord.haStandbyCh <- false
+	coord.runLoopIteration(ctx)
+	require.Len(t, components, 1, "Active agent should run the inputs")
+	assert.Equal(t, "filestream-default", components[0].ID)
+	assert.False(t, coord.generateReportableState().HAStandby)
+
+	coord.haStandbyCh <- true
+	coord.runLoopIteration(ctx)
+	assert.Empty(t, components, "Agent back to standby should stop the inputs")
+}
+
+func TestCoordinatorReducesMonitoring(t *testing.T) {
+	// The monitoring configuration is regenerated when the collection of the
+	// monitoring metrics is reduced and restored.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	monitorMgr := &fakeMonitorManager{}
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr:          &fakeRuntimeManager{},
+		monitorMgr:          monitorMgr,
+		monitoringReducedCh: make(chan bool, 1),
+	}
+
+	varsChan <- []*transpiler.Vars{{}}
+	coord.runLoopIteration(ctx)
+	configChan <- &configChange{cfg: config.MustNewConfigFrom(nil)}
+	coord.runLoopIteration(ctx)
+	assert.Equal(t, 1, monitorMgr.injected)
+
+	coord.monitoringReducedCh <- true
+	coord.runLoopIteration(ctx)
+	assert.True(t, monitorMgr.reduced, "monitor manager should reduce the collection")
+	assert.Equal(t, 2, monitorMgr.injected, "monitoring should be injected again once reduced")
+	state := coord.generateReportableState()
+	assert.True(t, state.MonitoringReduced)
+	assert.Equal(t, agentclient.Healthy, state.State, "reduced monitoring should not affect the health")
+
+	coord.monitoringReducedCh <- false
+	coord.runLoopIteration(ctx)
+	assert.False(t, monitorMgr.reduced, "monitor manager should restore the collection")
+	assert.Equal(t, 3, monitorMgr.injected, "monitoring should be injected again once restored")
+	assert.False(t, coord.generateReportableState().MonitoringReduced)
+}
+
+func TestCoordinatorTracesConfigChanges(t *testing.T) {
+	// The configuration changes triggered by an action are traced under the
+	// transaction of the action, the other ones in their own transaction.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	tracer := apmtest.NewRecordingTracer()
+	defer tracer.Close()
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		tracer:           tracer.Tracer,
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		runtimeMgr: &fakeRuntimeManager{},
+	}
+
+	varsChan <- []*transpiler.Vars{{}}
+	coord.runLoopIteration(ctx)
+	configChan <- &configChange{cfg: config.MustNewConfigFrom(nil)}
+	coord.runLoopIteration(ctx)
+	tracer.Flush(nil)
+	payloads := tracer.Payloads()
+	require.Len(t, payloads.Transactions, 2)
+	assert.Equal(t, "vars change", payloads.Transactions[0].Name)
+	assert.Equal(t, "config change", payloads.Transactions[1].Name)
+	assert.Equal(t, "coordinator", payloads.Transactions[1].Type)
+	tracer.ResetPayloads()
+
+	// the action transaction ends once the change is sent to the Coordinator
+	actionTx := tracer.StartTransaction("POLICY_CHANGE", "fleet.action")
+	actionTx.End()
+	change := &tracedConfigChange{configChange: configChange{cfg: config.MustNewConfigFrom(nil)}, tx: actionTx}
+	configChan <- change
+	coord.runLoopIteration(ctx)
+	require.True(t, change.acked, "the configuration change should be acked")
+
+	tracer.Flush(nil)
+	payloads = tracer.Payloads()
+	require.Len(t, payloads.Transactions, 2)
+	actionModel, changeModel := payloads.Transactions[0], payloads.Transactions[1]
+	assert.Equal(t, "POLICY_CHANGE", actionModel.Name)
+	assert.Equal(t, "config change", changeModel.Name)
+	assert.Equal(t, actionModel.TraceID, changeModel.TraceID, "the change should be part of the trace of the action")
+	assert.Equal(t, actionModel.ID, changeModel.ParentID, "the change should be a child of the action")
+	var spans []string
+	for _, span := range payloads.Spans {
+		assert.Equal(t, changeModel.ID, span.TransactionID, "span %q should be part of the transaction of the change", span.Name)
+		spans = append(spans, span.Name)
+	}
+	assert.ElementsMatch(t, []string{"config", "process", "component_model", "runtime_update"}, spans)
+}
+
+type tracedConfigChange struct {
+	configChange
+	tx *apm.Transaction
+}
+
+func (l *tracedConfigChange) Transaction() *apm.Transaction {
+	return l.tx
+}
+
+type fakeMonitorManager struct {
+	injected int
+	reduced  bool
+}
+
+func (m *fakeMonitorManager) Enabled() bool { return true }
+
+func (m *fakeMonitorManager) Reload(_ *config.Config) error { return nil }
+
+func (m *fakeMonitorManager) SetReduced(reduced bool) { m.reduced = reduced }
+
+func (m *fakeMonitorManager) MonitoringConfig(cfg map[string]interface{}, _ []component.Component, _ map[string]string) (map[string]interface{}, error) {
+	m.injected++
+	return cfg, nil
 }
@@ -0,0 +1,217 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package dataplane collects the numbers of events published, dropped, failed and queued by the
+// components, so a component that reports itself healthy but doesn't ship its events can be told
+// apart from a truly healthy one.
+package dataplane
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Stats are the cumulative counters of the events of a Beat, read from its stats endpoint.
+type Stats struct {
+	Acked   uint64
+	Dropped uint64
+	Failed  uint64
+	Active  uint64
+}
+
+// beatStats is the part of the stats of a Beat read by the collector.
+type beatStats struct {
+	Libbeat struct {
+		Output struct {
+			Events struct {
+				Acked   uint64 `json:"acked"`
+				Dropped uint64 `json:"dropped"`
+				Failed  uint64 `json:"failed"`
+			} `json:"events"`
+		} `json:"output"`
+		Pipeline struct {
+			Events struct {
+				Active  uint64 `json:"active"`
+				Dropped uint64 `json:"dropped"`
+				Failed  uint64 `json:"failed"`
+			} `json:"events"`
+		} `json:"pipeline"`
+	} `json:"libbeat"`
+}
+
+// ParseStats reads the counters of the events from the stats of a Beat.
+func ParseStats(data []byte) (Stats, error) {
+	var s beatStats
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Stats{}, fmt.Errorf("failed to parse the stats: %w", err)
+	}
+	return Stats{
+		Acked:   s.Libbeat.Output.Events.Acked,
+		Dropped: s.Libbeat.Output.Events.Dropped + s.Libbeat.Pipeline.Events.Dropped,
+		Failed:  s.Libbeat.Output.Events.Failed + s.Libbeat.Pipeline.Events.Failed,
+		Active:  s.Libbeat.Pipeline.Events.Active,
+	}, nil
+}
+
+// Events returns the events of the unit from the current counters and the ones of the previous
+// collection, the problem is set when the unit didn't ship its events since then.
+func Events(current Stats, previous *Stats, interval time.Duration) runtime.UnitEvents {
+	e := runtime.UnitEvents{
+		Published: current.Acked,
+		Dropped:   current.Dropped,
+		Failed:    current.Failed,
+		Queued:    current.Active,
+	}
+	if previous == nil || current.Acked < previous.Acked {
+		// first collection, or the component restarted and its counters were reset
+		return e
+	}
+	var problems []string
+	if dropped := delta(current.Dropped, previous.Dropped); dropped > 0 {
+		problems = append(problems, fmt.Sprintf("%d event(s) dropped", dropped))
+	}
+	if failed := delta(current.Failed, previous.Failed); failed > 0 {
+		problems = append(problems, fmt.Sprintf("%d event(s) failed", failed))
+	}
+	if current.Active > 0 && current.Acked == previous.Acked {
+		problems = append(problems, fmt.Sprintf("%d event(s) queued and none acknowledged by the output", current.Active))
+	}
+	if len(problems) > 0 {
+		e.Problem = fmt.Sprintf("%s in the last %s", strings.Join(problems, ", "), interval)
+	}
+	return e
+}
+
+func delta(current, previous uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// Collector collects the events of the output units of the components periodically.
+type Collector struct {
+	log    *logger.Logger
+	cfg    *configuration.DataPlaneConfig
+	states func() []runtime.ComponentComponentState
+	fetch  func(ctx context.Context, componentID string) ([]byte, error)
+	report func(ctx context.Context, events map[string]runtime.UnitEvents) error
+	now    func() time.Time
+
+	previous     map[string]Stats
+	reported     map[string]runtime.UnitEvents
+	lastReported time.Time
+}
+
+// NewCollector creates a collector of the events of the components returned by states, fetch
+// returns the stats of a component and report is called with the events of the output unit of
+// each component, by component ID.
+func NewCollector(log *logger.Logger, cfg *configuration.DataPlaneConfig, states func() []runtime.ComponentComponentState, fetch func(ctx context.Context, componentID string) ([]byte, error), report func(ctx context.Context, events map[string]runtime.UnitEvents) error) *Collector {
+	return &Collector{
+		log:      log,
+		cfg:      cfg,
+		states:   states,
+		fetch:    fetch,
+		report:   report,
+		now:      time.Now,
+		previous: make(map[string]Stats),
+	}
+}
+
+// Run collects the events until the context is done.
+func (c *Collector) Run(ctx context.Context) {
+	t := time.NewTicker(c.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			c.collect(ctx)
+		}
+	}
+}
+
+// collect collects the events of the components. They are reported when the problems of the units
+// change, or once the report interval elapsed, so the state of the agent isn't refreshed on every
+// collection.
+func (c *Collector) collect(ctx context.Context) {
+	events := make(map[string]runtime.UnitEvents)
+	current := make(map[string]Stats)
+	for _, comp := range c.states() {
+		if !hasOutputUnit(comp) || (comp.State.State != client.UnitStateHealthy && comp.State.State != client.UnitStateDegraded) {
+			continue
+		}
+		data, err := c.fetch(ctx, comp.Component.ID)
+		if err != nil {
+			// not every component exposes its stats
+			c.log.Debugw("Failed to fetch the stats of the component", "component.id", comp.Component.ID, "error.message", err)
+			continue
+		}
+		stats, err := ParseStats(data)
+		if err != nil {
+			c.log.Debugw("Failed to read the stats of the component", "component.id", comp.Component.ID, "error.message", err)
+			continue
+		}
+		var previous *Stats
+		if p, ok := c.previous[comp.Component.ID]; ok {
+			previous = &p
+		}
+		current[comp.Component.ID] = stats
+		events[comp.Component.ID] = Events(stats, previous, c.cfg.Interval)
+	}
+	c.previous = current
+
+	now := c.now()
+	if c.reported != nil && problems(events) == problems(c.reported) && now.Sub(c.lastReported) < c.cfg.ReportInterval {
+		return
+	}
+	if reflect.DeepEqual(events, c.reported) {
+		return
+	}
+	for id, e := range events {
+		if e.Problem != "" && c.reported[id].Problem == "" {
+			c.log.Warnw(fmt.Sprintf("Component %s is not shipping its events: %s", id, e.Problem), "component.id", id)
+		}
+	}
+	if err := c.report(ctx, events); err != nil {
+		c.log.Warnw("Failed to report the events of the components", "error.message", err)
+		return
+	}
+	c.reported = events
+	c.lastReported = now
+}
+
+// problems returns a description of the problems of the components comparable between collections.
+func problems(events map[string]runtime.UnitEvents) string {
+	var ids []string
+	for id, e := range events {
+		if e.Problem != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func hasOutputUnit(comp runtime.ComponentComponentState) bool {
+	for _, u := range comp.Component.Units {
+		if u.Type == client.UnitTypeOutput {
+			return true
+		}
+	}
+	return false
+}
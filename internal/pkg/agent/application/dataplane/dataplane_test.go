@@ -0,0 +1,127 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package dataplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func beatStatsJSON(acked, outputDropped, pipelineFailed, active uint64) []byte {
+	return []byte(fmt.Sprintf(`{"beat":{"info":{"uptime":{"ms":1000}}},"libbeat":{"output":{"events":{"acked":%d,"dropped":%d,"failed":0,"total":%d}},"pipeline":{"events":{"active":%d,"dropped":0,"failed":%d,"published":%d}}}}`,
+		acked, outputDropped, acked, active, pipelineFailed, acked))
+}
+
+func TestParseStats(t *testing.T) {
+	stats, err := ParseStats(beatStatsJSON(100, 2, 3, 40))
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Acked: 100, Dropped: 2, Failed: 3, Active: 40}, stats)
+
+	_, err = ParseStats([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestEvents(t *testing.T) {
+	interval := 30 * time.Second
+	previous := Stats{Acked: 100, Dropped: 2, Active: 10}
+
+	// first collection
+	assert.Equal(t, runtime.UnitEvents{Published: 100, Dropped: 2, Queued: 10}, Events(previous, nil, interval))
+
+	// shipping
+	assert.Empty(t, Events(Stats{Acked: 200, Dropped: 2, Active: 10}, &previous, interval).Problem)
+
+	// events dropped and failed
+	e := Events(Stats{Acked: 200, Dropped: 7, Failed: 1}, &previous, interval)
+	assert.Equal(t, "5 event(s) dropped, 1 event(s) failed in the last 30s", e.Problem)
+
+	// stuck, the output doesn't acknowledge the queued events
+	e = Events(Stats{Acked: 100, Dropped: 2, Active: 4096}, &previous, interval)
+	assert.Equal(t, "4096 event(s) queued and none acknowledged by the output in the last 30s", e.Problem)
+
+	// idle, nothing to ship
+	assert.Empty(t, Events(Stats{Acked: 100, Dropped: 2}, &previous, interval).Problem)
+
+	// restarted, the counters were reset
+	assert.Empty(t, Events(Stats{Acked: 1, Active: 4096}, &previous, interval).Problem)
+}
+
+func TestCollectorReportsProblems(t *testing.T) {
+	log, _ := logger.NewTesting("data_plane")
+	states := []runtime.ComponentComponentState{
+		{
+			Component: component.Component{
+				ID:    "filestream-default",
+				Units: []component.Unit{{ID: "filestream-default", Type: client.UnitTypeOutput}},
+			},
+			State: runtime.ComponentState{State: client.UnitStateHealthy},
+		},
+		{
+			// no output unit
+			Component: component.Component{ID: "endpoint-default"},
+			State:     runtime.ComponentState{State: client.UnitStateHealthy},
+		},
+	}
+	stats := beatStatsJSON(100, 0, 0, 0)
+	var fetched []string
+	fetch := func(_ context.Context, id string) ([]byte, error) {
+		fetched = append(fetched, id)
+		if id != "filestream-default" {
+			return nil, errors.New("no stats")
+		}
+		return stats, nil
+	}
+	var reported []map[string]runtime.UnitEvents
+	c := NewCollector(log, configuration.DefaultDataPlaneConfig(), func() []runtime.ComponentComponentState { return states }, fetch, func(_ context.Context, events map[string]runtime.UnitEvents) error {
+		reported = append(reported, events)
+		return nil
+	})
+	now := time.Date(2023, 9, 8, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	c.collect(context.Background())
+	assert.Equal(t, []string{"filestream-default"}, fetched)
+	require.Len(t, reported, 1)
+	assert.Equal(t, runtime.UnitEvents{Published: 100}, reported[0]["filestream-default"])
+
+	// shipping, the numbers are only reported again after the report interval
+	stats = beatStatsJSON(200, 0, 0, 0)
+	now = now.Add(30 * time.Second)
+	c.collect(context.Background())
+	require.Len(t, reported, 1)
+
+	// the output stops acknowledging the events, reported right away
+	stats = beatStatsJSON(200, 0, 0, 4096)
+	now = now.Add(30 * time.Second)
+	c.collect(context.Background())
+	require.Len(t, reported, 2)
+	assert.Equal(t, "4096 event(s) queued and none acknowledged by the output in the last 30s", reported[1]["filestream-default"].Problem)
+
+	// shipping again
+	stats = beatStatsJSON(4296, 0, 0, 0)
+	now = now.Add(30 * time.Second)
+	c.collect(context.Background())
+	require.Len(t, reported, 3)
+	assert.Empty(t, reported[2]["filestream-default"].Problem)
+
+	now = now.Add(5 * time.Minute)
+	stats = beatStatsJSON(5000, 0, 0, 0)
+	c.collect(context.Background())
+	require.Len(t, reported, 4)
+	assert.Equal(t, uint64(5000), reported[3]["filestream-default"].Published)
+}
@@ -0,0 +1,270 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package diskquota monitors the disk space used by the paths the agent manages against their
+// quotas, and evicts their oldest files so the agent does not fill the filesystem of the host.
+package diskquota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Names of the groups of paths with a quota.
+const (
+	Logs        = "logs"
+	Downloads   = "downloads"
+	Diagnostics = "diagnostics"
+	Spools      = "spools"
+)
+
+// Group is a group of paths sharing a quota.
+type Group struct {
+	Name  string
+	Paths []string
+	// Quota is the maximum number of bytes used by the paths, zero does not limit them.
+	Quota int64
+	// Evict allows to remove the oldest files of the paths once the quota is exceeded.
+	Evict bool
+}
+
+// Usage is the disk usage of a group of paths.
+type Usage struct {
+	Name  string
+	Bytes int64
+	Quota int64
+	// EvictedBytes and EvictedFiles are the bytes and the files removed to enforce the quota.
+	EvictedBytes int64
+	EvictedFiles int
+}
+
+// Exceeded returns true when the group uses more than its quota.
+func (u Usage) Exceeded() bool {
+	return u.Quota > 0 && u.Bytes > u.Quota
+}
+
+// Result is the result of a check of the disk usage.
+type Result struct {
+	Usages []Usage
+	// Errors are the paths that could not be read or evicted.
+	Errors []error
+}
+
+// Err returns the error reported for the groups exceeding their quota once their oldest files are
+// evicted, nil when none exceeds it.
+func (r Result) Err() error {
+	var exceeded []string
+	for _, u := range r.Usages {
+		if u.Exceeded() {
+			exceeded = append(exceeded, fmt.Sprintf("%s uses %s of %s", u.Name, units.BytesSize(float64(u.Bytes)), units.BytesSize(float64(u.Quota))))
+		}
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	return fmt.Errorf("disk quota exceeded: %s", strings.Join(exceeded, ", "))
+}
+
+// file is a file of a group of paths.
+type file struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Check measures the disk usage of the groups and evicts the oldest files of the groups exceeding
+// their quota. The newest file of each directory is never evicted, it is usually the file still
+// being written.
+func Check(groups []Group) Result {
+	var r Result
+	for _, g := range groups {
+		u := Usage{Name: g.Name, Quota: g.Quota}
+		var files []file
+		for _, p := range g.Paths {
+			pathFiles, err := walk(p)
+			if err != nil {
+				r.Errors = append(r.Errors, fmt.Errorf("failed to measure the disk usage of %s: %w", p, err))
+			}
+			files = append(files, pathFiles...)
+		}
+		for _, f := range files {
+			u.Bytes += f.size
+		}
+		if g.Evict && u.Exceeded() {
+			for _, f := range evictable(files) {
+				if !u.Exceeded() {
+					break
+				}
+				if err := os.Remove(f.path); err != nil {
+					r.Errors = append(r.Errors, fmt.Errorf("failed to evict %s: %w", f.path, err))
+					continue
+				}
+				u.Bytes -= f.size
+				u.EvictedBytes += f.size
+				u.EvictedFiles++
+			}
+		}
+		r.Usages = append(r.Usages, u)
+	}
+	return r
+}
+
+// walk returns the regular files under the path, a missing path has none.
+func walk(root string) ([]file, error) {
+	var files []file
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// removed meanwhile
+				return nil
+			}
+			return err
+		}
+		files = append(files, file{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// evictable returns the files that can be evicted, the oldest first. The newest file of each
+// directory is left out.
+func evictable(files []file) []file {
+	newest := make(map[string]file)
+	for _, f := range files {
+		dir := filepath.Dir(f.path)
+		if n, ok := newest[dir]; !ok || f.modTime.After(n.modTime) {
+			newest[dir] = f
+		}
+	}
+	result := make([]file, 0, len(files))
+	for _, f := range files {
+		if newest[filepath.Dir(f.path)].path != f.path {
+			result = append(result, f)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].modTime.Before(result[j].modTime)
+	})
+	return result
+}
+
+// Checker checks the disk usage of the paths managed by the agent periodically, enforces their
+// quotas and reports the groups exceeding them.
+type Checker struct {
+	log     *logger.Logger
+	cfg     *configuration.DiskQuotaConfig
+	groups  func() []Group
+	report  func(ctx context.Context, err error) error
+	metrics *checkerMetrics
+}
+
+// NewChecker creates a checker of the groups returned by groups, report is called with the error
+// describing the groups exceeding their quota, or nil once none exceeds it.
+func NewChecker(log *logger.Logger, cfg *configuration.DiskQuotaConfig, groups func() []Group, report func(ctx context.Context, err error) error) *Checker {
+	return &Checker{
+		log:     log,
+		cfg:     cfg,
+		groups:  groups,
+		report:  report,
+		metrics: &checkerMetrics{},
+	}
+}
+
+// Run checks the disk usage until the context is done.
+func (c *Checker) Run(ctx context.Context) {
+	c.metrics.register()
+
+	t := time.NewTicker(c.cfg.Interval)
+	defer t.Stop()
+
+	reported := ""
+	for {
+		reported = c.check(ctx, reported)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// check checks the disk usage and reports the result when it changed, it returns the reported
+// message.
+func (c *Checker) check(ctx context.Context, reported string) string {
+	r := Check(c.groups())
+	c.metrics.set(r)
+	for _, err := range r.Errors {
+		c.log.Warnw("Failed to enforce the disk quota", "error.message", err)
+	}
+	for _, u := range r.Usages {
+		if u.EvictedFiles > 0 {
+			c.log.Warnf("Disk quota of %s exceeded, evicted the %d oldest files (%s)", u.Name, u.EvictedFiles, units.BytesSize(float64(u.EvictedBytes)))
+		}
+	}
+
+	err := r.Err()
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if msg == reported {
+		return reported
+	}
+	if err != nil {
+		c.log.Warnf("Paths managed by Elastic Agent exceed their quota: %s", msg)
+	} else {
+		c.log.Info("Paths managed by Elastic Agent are within their quota")
+	}
+	if err := c.report(ctx, err); err != nil {
+		c.log.Warnw("Failed to report the disk quota", "error.message", err)
+		return reported
+	}
+	return msg
+}
+
+// ConfigGroups returns the groups of the paths managed by the agent with the quotas of the
+// configuration. The spools are the data paths of the shippers.
+func ConfigGroups(cfg *configuration.DiskQuotaConfig, logs string, downloads string, diagnostics []string, spools []string) []Group {
+	return []Group{
+		{Name: Logs, Paths: []string{logs}, Quota: cfg.Logs, Evict: true},
+		{Name: Downloads, Paths: []string{downloads}, Quota: cfg.Downloads, Evict: true},
+		{Name: Diagnostics, Paths: diagnostics, Quota: cfg.Diagnostics, Evict: true},
+		{Name: Spools, Paths: spools, Quota: cfg.Spools},
+	}
+}
+
+// SpoolPaths returns the data paths of the shippers among the components, their queues are
+// spooled there.
+func SpoolPaths(runDir string, components []runtime.ComponentComponentState) []string {
+	var spools []string
+	for _, comp := range components {
+		if comp.Component.ShipperSpec != nil {
+			spools = append(spools, filepath.Join(runDir, comp.Component.ID))
+		}
+	}
+	return spools
+}
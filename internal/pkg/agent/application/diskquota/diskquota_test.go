@@ -0,0 +1,108 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package diskquota
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+var now = time.Date(2023, 9, 2, 12, 0, 0, 0, time.UTC)
+
+// writeFile writes a file of the size, modified age ago.
+func writeFile(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+	require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+}
+
+func TestCheckEvictsTheOldestFiles(t *testing.T) {
+	dir := t.TempDir()
+	logs := filepath.Join(dir, "logs")
+	writeFile(t, filepath.Join(logs, "elastic-agent-1.ndjson"), 100, 3*time.Hour)
+	writeFile(t, filepath.Join(logs, "elastic-agent-2.ndjson"), 100, 2*time.Hour)
+	writeFile(t, filepath.Join(logs, "elastic-agent-3.ndjson"), 100, time.Hour)
+	writeFile(t, filepath.Join(logs, "elastic-agent-4.ndjson"), 100, 0)
+
+	r := Check([]Group{{Name: Logs, Paths: []string{logs, filepath.Join(dir, "missing")}, Quota: 250, Evict: true}})
+
+	assert.Empty(t, r.Errors, "missing paths should be ignored")
+	require.Len(t, r.Usages, 1)
+	assert.Equal(t, Usage{Name: Logs, Bytes: 200, Quota: 250, EvictedBytes: 200, EvictedFiles: 2}, r.Usages[0])
+	assert.NoError(t, r.Err())
+	assert.NoFileExists(t, filepath.Join(logs, "elastic-agent-1.ndjson"))
+	assert.NoFileExists(t, filepath.Join(logs, "elastic-agent-2.ndjson"))
+	assert.FileExists(t, filepath.Join(logs, "elastic-agent-3.ndjson"))
+	assert.FileExists(t, filepath.Join(logs, "elastic-agent-4.ndjson"))
+}
+
+func TestCheckKeepsTheNewestFileOfEachDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a", "old"), 100, time.Hour)
+	writeFile(t, filepath.Join(dir, "a", "new"), 100, 0)
+	writeFile(t, filepath.Join(dir, "b", "only"), 100, 2*time.Hour)
+
+	r := Check([]Group{{Name: Logs, Paths: []string{dir}, Quota: 50, Evict: true}})
+
+	require.Len(t, r.Usages, 1)
+	assert.Equal(t, int64(200), r.Usages[0].Bytes)
+	assert.Equal(t, 1, r.Usages[0].EvictedFiles)
+	assert.FileExists(t, filepath.Join(dir, "a", "new"))
+	assert.FileExists(t, filepath.Join(dir, "b", "only"))
+	assert.EqualError(t, r.Err(), "disk quota exceeded: logs uses 200B of 50B")
+}
+
+func TestCheckReportsWithoutEvicting(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "shipper", "segment-1"), 2048, time.Hour)
+	writeFile(t, filepath.Join(dir, "shipper", "segment-2"), 2048, 0)
+
+	r := Check([]Group{
+		{Name: Spools, Paths: []string{filepath.Join(dir, "shipper")}, Quota: 1024},
+		{Name: Downloads, Paths: []string{filepath.Join(dir, "downloads")}, Quota: 1024, Evict: true},
+		{Name: Diagnostics, Paths: []string{dir}},
+	})
+
+	require.Len(t, r.Usages, 3)
+	assert.Equal(t, 0, r.Usages[0].EvictedFiles, "spools should not be evicted")
+	assert.FileExists(t, filepath.Join(dir, "shipper", "segment-1"))
+	assert.False(t, r.Usages[2].Exceeded(), "zero quota should not limit the path")
+	assert.EqualError(t, r.Err(), "disk quota exceeded: spools uses 4KiB of 1KiB")
+}
+
+func TestCheckerReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "segment"), 2048, 0)
+
+	cfg := configuration.DefaultDiskQuotaConfig()
+	quota := int64(1024)
+	var reported []error
+	c := NewChecker(logger.NewWithoutConfig(""), cfg, func() []Group {
+		return []Group{{Name: Spools, Paths: []string{dir}, Quota: quota}}
+	}, func(_ context.Context, err error) error {
+		reported = append(reported, err)
+		return nil
+	})
+
+	msg := c.check(context.Background(), "")
+	msg = c.check(context.Background(), msg)
+	require.Len(t, reported, 1, "unchanged result should be reported once")
+	assert.Error(t, reported[0])
+
+	quota = 4096
+	c.check(context.Background(), msg)
+	require.Len(t, reported, 2)
+	assert.NoError(t, reported[1], "quota no longer exceeded should clear the error")
+}
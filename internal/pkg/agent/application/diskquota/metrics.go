@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package diskquota
+
+import (
+	"sync"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// metricsName is the name of the disk quota metrics in the stats namespace of the monitoring endpoint.
+const metricsName = "disk_quota"
+
+// checkerMetrics holds the usage of each group of paths, it is a monitoring.Var reported under
+// disk_quota in the stats of the monitoring endpoint. The evictions are counted since the start.
+type checkerMetrics struct {
+	mx sync.Mutex
+
+	usages       []Usage
+	evictedBytes map[string]int64
+	evictedFiles map[string]int64
+	errors       int64
+}
+
+// register adds the metrics to the stats of the monitoring endpoint, replacing the ones of a previous checker.
+func (m *checkerMetrics) register() {
+	reg := monitoring.GetNamespace("stats").GetRegistry()
+	reg.Remove(metricsName)
+	reg.Add(metricsName, m, monitoring.Reported)
+}
+
+// set records the result of a check.
+func (m *checkerMetrics) set(r Result) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.evictedBytes == nil {
+		m.evictedBytes = make(map[string]int64)
+		m.evictedFiles = make(map[string]int64)
+	}
+	m.usages = r.Usages
+	for _, u := range r.Usages {
+		m.evictedBytes[u.Name] += u.EvictedBytes
+		m.evictedFiles[u.Name] += int64(u.EvictedFiles)
+	}
+	m.errors = int64(len(r.Errors))
+}
+
+// Visit reports the metrics to the monitoring visitor.
+func (m *checkerMetrics) Visit(_ monitoring.Mode, vs monitoring.Visitor) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	vs.OnRegistryStart()
+	defer vs.OnRegistryFinished()
+
+	for _, u := range m.usages {
+		u := u
+		monitoring.ReportNamespace(vs, u.Name, func() {
+			monitoring.ReportInt(vs, "bytes", u.Bytes)
+			monitoring.ReportInt(vs, "quota", u.Quota)
+			monitoring.ReportBool(vs, "exceeded", u.Exceeded())
+			monitoring.ReportInt(vs, "evicted_bytes", m.evictedBytes[u.Name])
+			monitoring.ReportInt(vs, "evicted_files", m.evictedFiles[u.Name])
+		})
+	}
+	monitoring.ReportInt(vs, "errors", m.errors)
+}
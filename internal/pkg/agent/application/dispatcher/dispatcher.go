@@ -14,6 +14,7 @@ import (
 	"go.elastic.co/apm"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
@@ -43,6 +44,11 @@ type ActionDispatcher struct {
 	queue    priorityQueue
 	rt       *retryConfig
 	errCh    chan error
+
+	upgradeDetailsSetter func(context.Context, *details.Details) error
+
+	// tracer traces each action in its own transaction, nil when the agent is not traced.
+	tracer *apm.Tracer
 }
 
 // New creates a new action dispatcher.
@@ -73,6 +79,17 @@ func (ad *ActionDispatcher) Errors() <-chan error {
 	return ad.errCh
 }
 
+// SetUpgradeDetailsSetter sets the function used to report the upgrade actions added to the queue.
+func (ad *ActionDispatcher) SetUpgradeDetailsSetter(setter func(context.Context, *details.Details) error) {
+	ad.upgradeDetailsSetter = setter
+}
+
+// SetTracer sets the tracer recording a transaction for each dispatched action. The spans of its
+// handler, of the configuration changes and of the upgrade it triggers are recorded under it.
+func (ad *ActionDispatcher) SetTracer(tracer *apm.Tracer) {
+	ad.tracer = tracer
+}
+
 // Register registers a new handler for action.
 func (ad *ActionDispatcher) Register(a fleetapi.Action, handler actions.Handler) error {
 	k := ad.key(a)
@@ -110,7 +127,7 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, acker acker.Acker, act
 	}()
 
 	ad.removeQueuedUpgrades(actions)
-	actions = ad.queueScheduledActions(actions)
+	actions = ad.queueScheduledActions(ctx, actions)
 	actions = ad.dispatchCancelActions(ctx, actions, acker)
 	queued, expired := ad.gatherQueuedActions(time.Now().UTC())
 	ad.log.Debugf("Gathered %d actions from queue, %d actions expired", len(queued), len(expired))
@@ -162,7 +179,21 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, acker acker.Acker, act
 	}
 }
 
-func (ad *ActionDispatcher) dispatchAction(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
+func (ad *ActionDispatcher) dispatchAction(ctx context.Context, a fleetapi.Action, acker acker.Acker) (err error) {
+	if ad.tracer != nil {
+		tx := ad.tracer.StartTransaction(a.Type(), "fleet.action")
+		tx.Context.SetLabel("action_id", a.ID())
+		ctx = apm.ContextWithTransaction(ctx, tx)
+		defer func() {
+			apm.CaptureError(ctx, err).Send()
+			tx.Result = "success"
+			if err != nil {
+				tx.Result = "failure"
+			}
+			tx.End()
+		}()
+	}
+
 	handler, found := ad.handlers[(ad.key(a))]
 	if !found {
 		return ad.def.Handle(ctx, a, acker)
@@ -181,7 +212,7 @@ func detectTypes(actions []fleetapi.Action) []string {
 
 // queueScheduledActions will add any action in actions with a valid start time to the queue and return the rest.
 // start time to current time comparisons are purposefully not made in case of cancel actions.
-func (ad *ActionDispatcher) queueScheduledActions(input []fleetapi.Action) []fleetapi.Action {
+func (ad *ActionDispatcher) queueScheduledActions(ctx context.Context, input []fleetapi.Action) []fleetapi.Action {
 	actions := make([]fleetapi.Action, 0, len(input))
 	for _, action := range input {
 		sAction, ok := action.(fleetapi.ScheduledAction)
@@ -194,6 +225,7 @@ func (ad *ActionDispatcher) queueScheduledActions(input []fleetapi.Action) []fle
 			}
 			ad.log.Debugf("Adding action id: %s to queue.", sAction.ID())
 			ad.queue.Add(sAction, start.Unix())
+			ad.reportUpgradeScheduled(ctx, action, start)
 			continue
 		}
 		actions = append(actions, action)
@@ -201,6 +233,20 @@ func (ad *ActionDispatcher) queueScheduledActions(input []fleetapi.Action) []fle
 	return actions
 }
 
+// reportUpgradeScheduled reports the upgrade details of an upgrade action added to the queue.
+func (ad *ActionDispatcher) reportUpgradeScheduled(ctx context.Context, action fleetapi.Action, start time.Time) {
+	upgradeAction, ok := action.(*fleetapi.ActionUpgrade)
+	if !ok || ad.upgradeDetailsSetter == nil {
+		return
+	}
+
+	det := details.NewDetails(upgradeAction.Version, details.StateScheduled, upgradeAction.ActionID)
+	det.Metadata.ScheduledAt = &start
+	if err := ad.upgradeDetailsSetter(ctx, det); err != nil {
+		ad.log.Warnf("Failed to report the upgrade scheduled by action id %s: %v", upgradeAction.ActionID, err)
+	}
+}
+
 // dispatchCancelActions will separate and dispatch any cancel actions from the actions list and return the rest of the list.
 // cancel actions are dispatched seperatly as they may remove items from the queue.
 func (ad *ActionDispatcher) dispatchCancelActions(ctx context.Context, actions []fleetapi.Action, acker acker.Acker) []fleetapi.Action {
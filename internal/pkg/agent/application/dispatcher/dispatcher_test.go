@@ -12,7 +12,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/apmtest"
+	"go.elastic.co/apm/model"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
@@ -176,6 +180,44 @@ func TestActionDispatcher(t *testing.T) {
 		queue.AssertExpectations(t)
 	})
 
+	t.Run("Dispatched actions are traced", func(t *testing.T) {
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			span, _ := apm.StartSpan(args.Get(0).(context.Context), "handle", "app.internal")
+			span.End()
+		}).Return(nil).Once()
+		queue := &mockQueue{}
+		queue.On("Save").Return(nil).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		d, err := New(nil, def, queue)
+		require.NoError(t, err)
+		tracer := apmtest.NewRecordingTracer()
+		defer tracer.Close()
+		d.SetTracer(tracer.Tracer)
+
+		action := &mockAction{}
+		action.On("Type").Return("POLICY_CHANGE")
+		action.On("ID").Return("action-id")
+
+		go d.Dispatch(context.Background(), ack, action)
+		if err := <-d.Errors(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		tracer.Flush(nil)
+		payloads := tracer.Payloads()
+		require.Len(t, payloads.Transactions, 1)
+		tx := payloads.Transactions[0]
+		assert.Equal(t, "POLICY_CHANGE", tx.Name)
+		assert.Equal(t, "fleet.action", tx.Type)
+		assert.Equal(t, "success", tx.Result)
+		assert.Equal(t, model.IfaceMap{{Key: "action_id", Value: "action-id"}}, tx.Context.Tags)
+		require.Len(t, payloads.Spans, 1)
+		assert.Equal(t, "handle", payloads.Spans[0].Name)
+		assert.Equal(t, tx.ID, payloads.Spans[0].TransactionID)
+		def.AssertExpectations(t)
+	})
+
 	t.Run("Could not register two handlers on the same action", func(t *testing.T) {
 		success1 := &mockHandler{}
 		success2 := &mockHandler{}
@@ -225,6 +267,39 @@ func TestActionDispatcher(t *testing.T) {
 		queue.AssertExpectations(t)
 	})
 
+	t.Run("Scheduled upgrade action is reported", func(t *testing.T) {
+		def := &mockHandler{}
+		queue := &mockQueue{}
+		queue.On("Add", mock.Anything, mock.Anything).Once()
+
+		d, err := New(nil, def, queue)
+		require.NoError(t, err)
+
+		var reported *details.Details
+		d.SetUpgradeDetailsSetter(func(_ context.Context, upgradeDetails *details.Details) error {
+			reported = upgradeDetails
+			return nil
+		})
+
+		start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		action := &fleetapi.ActionUpgrade{
+			ActionID:        "upgrade-id",
+			ActionType:      fleetapi.ActionTypeUpgrade,
+			ActionStartTime: start.Format(time.RFC3339),
+			Version:         "8.9.0",
+		}
+		actions := d.queueScheduledActions(context.Background(), []fleetapi.Action{action})
+		assert.Empty(t, actions)
+		queue.AssertExpectations(t)
+
+		require.NotNil(t, reported, "scheduled upgrade should be reported")
+		assert.Equal(t, details.StateScheduled, reported.State)
+		assert.Equal(t, "8.9.0", reported.TargetVersion)
+		assert.Equal(t, "upgrade-id", reported.ActionID)
+		require.NotNil(t, reported.Metadata.ScheduledAt)
+		assert.True(t, start.Equal(*reported.Metadata.ScheduledAt))
+	})
+
 	t.Run("Cancel queued action", func(t *testing.T) {
 		def := &mockHandler{}
 		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
@@ -0,0 +1,164 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// monitoringSuffix is the suffix of the IDs of the components monitoring the Elastic Agent, they do not
+// count as a workload of an ephemeral agent.
+const monitoringSuffix = "-monitoring"
+
+type agentInfo interface {
+	AgentID() string
+}
+
+type ephemeralStateStore interface {
+	Add(fleetapi.Action)
+	SetAckToken(ackToken string)
+	SetQueue(q []fleetapi.Action)
+	Save() error
+	Actions() []fleetapi.Action
+}
+
+// UnenrollEphemeral unenrolls the agent from Fleet and purges its local state when it was enrolled as
+// ephemeral, it is called on a clean shutdown of the Elastic Agent. It does nothing for any other agent.
+func UnenrollEphemeral(ctx context.Context, log *logger.Logger, cfg *configuration.Configuration, agentInfo *info.AgentInfo) error {
+	if !configuration.IsEphemeral(cfg.Fleet) {
+		return nil
+	}
+
+	stateStore, err := store.NewStateStoreWithMigration(log, paths.AgentActionStoreFile(), paths.AgentStateStoreFile())
+	if err != nil {
+		return errors.New(err, fmt.Sprintf("fail to read action store '%s'", paths.AgentActionStoreFile()))
+	}
+	if wasUnenrolled(stateStore) {
+		return nil
+	}
+
+	client, err := fleetclient.NewAuthWithConfig(log, cfg.Fleet.AccessAPIKey, cfg.Fleet.Client)
+	if err != nil {
+		return errors.New(err,
+			"fail to create API client",
+			errors.TypeNetwork,
+			errors.M(errors.MetaKeyURI, cfg.Fleet.Client.Host))
+	}
+	return unenrollEphemeral(ctx, log, agentInfo, client, stateStore)
+}
+
+// WatchEphemeralInactivity calls onInactive once an ephemeral agent runs no workload for the inactivity
+// timeout of its configuration. It returns immediately when the agent is not ephemeral or has no timeout.
+func WatchEphemeralInactivity(ctx context.Context, log *logger.Logger, cfg *configuration.Configuration, coord *coordinator.Coordinator, onInactive func()) {
+	if !configuration.IsEphemeral(cfg.Fleet) || cfg.Fleet.Ephemeral.InactivityTimeout <= 0 {
+		return
+	}
+	timeout := cfg.Fleet.Ephemeral.InactivityTimeout
+	watchInactivity(ctx, clock.Real(), coord.StateSubscribe(ctx, 32), timeout, func() {
+		log.Infof("Ephemeral agent ran no integration for %s, shutting down", timeout)
+		onInactive()
+	})
+}
+
+// unenrollEphemeral informs Fleet that the agent left and purges the actions and the policy of the state
+// store, the unenroll action persisted in their place keeps the agent idle if it is started again.
+func unenrollEphemeral(
+	ctx context.Context,
+	log *logger.Logger,
+	agentInfo agentInfo,
+	client fleetclient.Sender,
+	stateStore ephemeralStateStore,
+) error {
+	log.Infof("Ephemeral agent %s is unenrolling from Fleet", agentInfo.AgentID())
+	cmd := fleetapi.NewAuditUnenrollCmd(agentInfo, client)
+	err := cmd.Execute(ctx, &fleetapi.AuditUnenrollRequest{
+		Reason:    fleetapi.ReasonUninstall,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		// the local state is purged anyway, Fleet marks the agent offline once it stops checking in
+		log.Warnf("Failed to notify Fleet of the unenrollment of the ephemeral agent: %v", err)
+	}
+
+	stateStore.SetQueue(nil)
+	stateStore.SetAckToken("")
+	stateStore.Add(&fleetapi.ActionUnenroll{ActionType: fleetapi.ActionTypeUnenroll, IsDetected: true})
+	if err := stateStore.Save(); err != nil {
+		return fmt.Errorf("failed to purge the state of the ephemeral agent: %w", err)
+	}
+	return nil
+}
+
+// watchInactivity calls onInactive once the agent runs no workload for the timeout, the monitoring
+// components excepted. The timeout starts over each time the last workload is removed.
+func watchInactivity(
+	ctx context.Context,
+	clk clock.Clock,
+	stateCh <-chan coordinator.State,
+	timeout time.Duration,
+	onInactive func(),
+) {
+	timer := clk.NewTimer(timeout)
+	defer timer.Stop()
+
+	active := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case state := <-stateCh:
+			nowActive := hasWorkload(state)
+			if nowActive == active {
+				continue
+			}
+			active = nowActive
+			if active {
+				if !timer.Stop() {
+					select {
+					case <-timer.C():
+					default:
+					}
+				}
+			} else {
+				timer.Reset(timeout)
+			}
+		case <-timer.C():
+			onInactive()
+			return
+		}
+	}
+}
+
+func hasWorkload(state coordinator.State) bool {
+	for _, comp := range state.Components {
+		if !strings.HasSuffix(comp.Component.ID, monitoringSuffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func wasUnenrolled(stateStore ephemeralStateStore) bool {
+	for _, a := range stateStore.Actions() {
+		if a.Type() == fleetapi.ActionTypeUnenroll {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+type testAgentInfo struct{}
+
+func (testAgentInfo) AgentID() string { return "agent-id" }
+
+type testSender struct {
+	path       string
+	statusCode int
+}
+
+func (s *testSender) Send(_ context.Context, _ string, path string, _ url.Values, _ http.Header, _ io.Reader) (*http.Response, error) {
+	s.path = path
+	return &http.Response{
+		StatusCode: s.statusCode,
+		Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+	}, nil
+}
+
+func (s *testSender) URI() string {
+	return "http://localhost"
+}
+
+type testStateStore struct {
+	actions  []fleetapi.Action
+	queue    []fleetapi.Action
+	ackToken string
+	saved    bool
+}
+
+func (s *testStateStore) Add(a fleetapi.Action)        { s.actions = []fleetapi.Action{a} }
+func (s *testStateStore) SetAckToken(ackToken string)  { s.ackToken = ackToken }
+func (s *testStateStore) SetQueue(q []fleetapi.Action) { s.queue = q }
+func (s *testStateStore) Save() error                  { s.saved = true; return nil }
+func (s *testStateStore) Actions() []fleetapi.Action   { return s.actions }
+
+func TestUnenrollEphemeral(t *testing.T) {
+	log, _ := logger.New("", false)
+
+	for name, statusCode := range map[string]int{
+		"fleet acknowledges the unenrollment": http.StatusOK,
+		"fleet fails the unenrollment":        http.StatusInternalServerError,
+	} {
+		t.Run(name, func(t *testing.T) {
+			sender := &testSender{statusCode: statusCode}
+			stateStore := &testStateStore{
+				queue:    []fleetapi.Action{&fleetapi.ActionUpgrade{ActionID: "upgrade"}},
+				ackToken: "token",
+			}
+
+			err := unenrollEphemeral(context.Background(), log, testAgentInfo{}, sender, stateStore)
+			require.NoError(t, err)
+
+			assert.Equal(t, "/api/fleet/agents/agent-id/audit/unenroll", sender.path)
+			assert.True(t, stateStore.saved)
+			assert.Empty(t, stateStore.queue)
+			assert.Empty(t, stateStore.ackToken)
+			assert.True(t, wasUnenrolled(stateStore), "the unenroll action must be persisted")
+		})
+	}
+}
+
+func TestWatchInactivity(t *testing.T) {
+	const timeout = 10 * time.Minute
+	workload := coordinator.State{Components: []runtime.ComponentComponentState{
+		{Component: component.Component{ID: "filestream-default"}},
+		{Component: component.Component{ID: "filestream-monitoring"}},
+	}}
+	monitoringOnly := coordinator.State{Components: []runtime.ComponentComponentState{
+		{Component: component.Component{ID: "filestream-monitoring"}},
+	}}
+
+	start := func(t *testing.T) (*clock.Mock, chan coordinator.State, chan struct{}) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		clk := clock.NewMock(time.Now())
+		stateCh := make(chan coordinator.State)
+		inactive := make(chan struct{})
+		go watchInactivity(ctx, clk, stateCh, timeout, func() { close(inactive) })
+		clk.BlockUntil(1)
+		return clk, stateCh, inactive
+	}
+
+	t.Run("inactive from the start", func(t *testing.T) {
+		clk, _, inactive := start(t)
+		clk.Advance(timeout)
+		<-inactive
+	})
+
+	t.Run("monitoring components are no workload", func(t *testing.T) {
+		clk, stateCh, inactive := start(t)
+		stateCh <- monitoringOnly
+		clk.Advance(timeout)
+		<-inactive
+	})
+
+	t.Run("workload stops the timeout", func(t *testing.T) {
+		clk, stateCh, inactive := start(t)
+		// the second state is only received once the first one is handled
+		stateCh <- workload
+		stateCh <- workload
+		clk.Advance(2 * timeout)
+		select {
+		case <-inactive:
+			t.Fatal("agent running a workload must not be inactive")
+		default:
+		}
+
+		// removing the workload starts the timeout over
+		stateCh <- monitoringOnly
+		stateCh <- monitoringOnly
+		clk.Advance(timeout - time.Second)
+		select {
+		case <-inactive:
+			t.Fatal("agent must not be inactive before the timeout")
+		default:
+		}
+		clk.Advance(time.Second)
+		<-inactive
+	})
+}
@@ -150,6 +150,15 @@ func StreamChecker(log *logger.Logger, ast *transpiler.AST) error {
 	return nil
 }
 
+// ValidateNamespace returns ErrInvalidNamespace when the namespace cannot be part of the name of a
+// data stream.
+func ValidateNamespace(namespace string) error {
+	if !matchesNamespaceContraints(namespace) {
+		return ErrInvalidNamespace
+	}
+	return nil
+}
+
 // The only two requirement are that it has only characters allowed in an Elasticsearch index name
 // Index names must meet the following criteria:
 //
@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package gates provides the startup gates, the conditions the host must meet before
+// the components are started.
+package gates
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+// Gate is a condition the host must meet before the components are started.
+type Gate interface {
+	// Name identifies the gate in the status.
+	Name() string
+	// Check returns nil when the gate is open, otherwise an error describing why it is closed.
+	Check(ctx context.Context) error
+}
+
+// Closed is a gate that is not open yet.
+type Closed struct {
+	Name string
+	Err  error
+}
+
+// FromConfig returns the gates enabled in the configuration.
+func FromConfig(cfg *configuration.StartupGatesConfig) []Gate {
+	if cfg == nil {
+		return nil
+	}
+	var gates []Gate
+	if cfg.Network.Enabled {
+		gates = append(gates, &networkGate{hosts: cfg.Network.Hosts})
+	}
+	if cfg.TimeSync.Enabled {
+		gates = append(gates, &timeSyncGate{})
+	}
+	for _, path := range cfg.Mounts {
+		gates = append(gates, &mountGate{path: path})
+	}
+	return gates
+}
+
+// Check checks the gates and returns the ones that are closed.
+func Check(ctx context.Context, gates []Gate) []Closed {
+	var closed []Closed
+	for _, g := range gates {
+		if err := g.Check(ctx); err != nil {
+			closed = append(closed, Closed{Name: g.Name(), Err: err})
+		}
+	}
+	return closed
+}
+
+// Describe returns a description of the closed gates.
+func Describe(closed []Closed) string {
+	descs := make([]string, 0, len(closed))
+	for _, c := range closed {
+		descs = append(descs, fmt.Sprintf("%s (%s)", c.Name, c.Err))
+	}
+	return strings.Join(descs, ", ")
+}
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gates
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+func TestFromConfig(t *testing.T) {
+	assert.Empty(t, FromConfig(nil))
+	assert.Empty(t, FromConfig(configuration.DefaultStartupGatesConfig()))
+
+	cfg := configuration.DefaultStartupGatesConfig()
+	cfg.Network.Enabled = true
+	cfg.TimeSync.Enabled = true
+	cfg.Mounts = []string{"/data", "/logs"}
+	names := make([]string, 0)
+	for _, g := range FromConfig(cfg) {
+		names = append(names, g.Name())
+	}
+	assert.Equal(t, []string{"network", "time_sync", "mount:/data", "mount:/logs"}, names)
+}
+
+func TestNetworkGate(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	reachable := l.Addr().String()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unreachable := closed.Addr().String()
+	require.NoError(t, closed.Close())
+	defer l.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, (&networkGate{hosts: []string{reachable}}).Check(ctx))
+	assert.ErrorContains(t, (&networkGate{hosts: []string{reachable, unreachable}}).Check(ctx), unreachable+" is not reachable")
+}
+
+func TestMountGate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	assert.ErrorContains(t, (&mountGate{path: filepath.Join(dir, "missing")}).Check(ctx), "does not exist")
+
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0755))
+	if runtime.GOOS == "windows" {
+		assert.NoError(t, (&mountGate{path: sub}).Check(ctx))
+		return
+	}
+	assert.ErrorContains(t, (&mountGate{path: sub}).Check(ctx), "is not mounted")
+	assert.NoError(t, (&mountGate{path: "/"}).Check(ctx))
+}
+
+func TestCheck(t *testing.T) {
+	closed := Check(context.Background(), []Gate{
+		fakeGate{name: "open"},
+		fakeGate{name: "closed", err: errors.New("not ready")},
+	})
+	assert.Equal(t, []Closed{{Name: "closed", Err: errors.New("not ready")}}, closed)
+	assert.Equal(t, "closed (not ready)", Describe(closed))
+}
+
+type fakeGate struct {
+	name string
+	err  error
+}
+
+func (g fakeGate) Name() string { return g.name }
+
+func (g fakeGate) Check(context.Context) error { return g.err }
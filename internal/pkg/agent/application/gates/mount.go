@@ -0,0 +1,41 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// mountGate is open once the path is a mount point.
+type mountGate struct {
+	path string
+}
+
+func (g *mountGate) Name() string {
+	return "mount:" + g.path
+}
+
+func (g *mountGate) Check(_ context.Context) error {
+	path := filepath.Clean(g.path)
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s does not exist", path)
+		}
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	parent, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", filepath.Dir(path), err)
+	}
+	if !isMountPoint(path, info, parent) {
+		return fmt.Errorf("%s is not mounted", path)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+
+package gates
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// isMountPoint returns true when the path is on another device than its parent or is the root.
+func isMountPoint(path string, info os.FileInfo, parent os.FileInfo) bool {
+	if filepath.Dir(path) == path {
+		return true
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	parentStat, ok := parent.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return stat.Dev != parentStat.Dev
+}
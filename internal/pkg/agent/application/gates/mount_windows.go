@@ -0,0 +1,15 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package gates
+
+import "os"
+
+// isMountPoint always returns true, the mount points of the volumes are not detected on
+// Windows, so the gate only waits for the path to exist.
+func isMountPoint(_ string, _ os.FileInfo, _ os.FileInfo) bool {
+	return true
+}
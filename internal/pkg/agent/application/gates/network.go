@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// maximum time to wait for a TCP connection to a host.
+const networkDialTimeout = 5 * time.Second
+
+// networkGate is open once the hosts accept TCP connections or, without hosts, once an
+// interface has a global unicast address.
+type networkGate struct {
+	hosts []string
+}
+
+func (g *networkGate) Name() string {
+	return "network"
+}
+
+func (g *networkGate) Check(ctx context.Context) error {
+	if len(g.hosts) == 0 {
+		return checkInterfaces()
+	}
+	dialer := net.Dialer{Timeout: networkDialTimeout}
+	for _, host := range g.hosts {
+		conn, err := dialer.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return fmt.Errorf("%s is not reachable: %w", host, err)
+		}
+		_ = conn.Close()
+	}
+	return nil
+}
+
+// checkInterfaces returns nil when an interface that is up has a global unicast address.
+func checkInterfaces() error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return fmt.Errorf("failed to list the network interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() {
+				return nil
+			}
+		}
+	}
+	return errors.New("no interface has a global unicast address")
+}
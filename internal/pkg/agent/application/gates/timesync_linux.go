@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package gates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// clock state returned by adjtimex when the clock is not synchronized, see adjtimex(2).
+	timeError = 5
+	// status flag of the kernel clock set while the clock is not synchronized.
+	staUnsync = 0x0040
+)
+
+// timeSyncGate is open once the kernel clock is synchronized by an NTP daemon.
+type timeSyncGate struct{}
+
+func (g *timeSyncGate) Name() string {
+	return "time_sync"
+}
+
+func (g *timeSyncGate) Check(_ context.Context) error {
+	// modes is zero, the clock is only read
+	var tx unix.Timex
+	state, err := unix.Adjtimex(&tx)
+	if err != nil {
+		return fmt.Errorf("failed to read the clock state: %w", err)
+	}
+	if state == timeError || tx.Status&staUnsync != 0 {
+		return errors.New("the clock is not synchronized")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+)
+
+// States of the circuit breaker.
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+type circuitBreakerSettings struct {
+	// Threshold is the number of consecutive check-ins failing because of fleet-server, the retry
+	// budget, before the breaker opens. 0 disables the breaker.
+	Threshold int `config:"threshold"`
+	// CoolDown is the time without check-in once the breaker opened, it doubles after each failed
+	// probe up to MaxCoolDown.
+	CoolDown    time.Duration `config:"cool_down"`
+	MaxCoolDown time.Duration `config:"max_cool_down"`
+}
+
+// circuitBreaker stops the check-ins once fleet-server failed too many times in a row, so the
+// agents don't amplify an outage of fleet-server with their retries. Once the cool-down elapsed a
+// single check-in probes fleet-server, the breaker closes when it succeeds and opens again for
+// twice the cool-down when it fails.
+type circuitBreaker struct {
+	settings circuitBreakerSettings
+	clock    clock.Clock
+	metrics  *gatewayMetrics
+
+	state     string
+	failures  int
+	coolDown  time.Duration
+	openUntil time.Time
+	trips     int64
+}
+
+func newCircuitBreaker(settings circuitBreakerSettings, clock clock.Clock, metrics *gatewayMetrics) *circuitBreaker {
+	b := &circuitBreaker{
+		settings: settings,
+		clock:    clock,
+		metrics:  metrics,
+		state:    breakerClosed,
+	}
+	b.report()
+	return b
+}
+
+// record records the outcome of a check-in, statusCode is 0 when no response was received.
+func (b *circuitBreaker) record(statusCode int, err error) {
+	if b.settings.Threshold <= 0 || errors.Is(err, errCheckinInterrupted) {
+		return
+	}
+	defer b.report()
+
+	if err == nil || !tripsBreaker(statusCode, err) {
+		// fleet-server answered
+		b.state = breakerClosed
+		b.failures = 0
+		b.coolDown = 0
+		return
+	}
+
+	b.failures++
+	switch b.state {
+	case breakerClosed:
+		if b.failures < b.settings.Threshold {
+			return
+		}
+		b.coolDown = b.settings.CoolDown
+	case breakerHalfOpen:
+		b.coolDown *= 2
+		if b.coolDown > b.settings.MaxCoolDown {
+			b.coolDown = b.settings.MaxCoolDown
+		}
+	}
+	b.state = breakerOpen
+	b.openUntil = b.clock.Now().Add(b.coolDown)
+	b.trips++
+}
+
+// isOpen returns true when the check-ins must wait for the end of the cool-down.
+func (b *circuitBreaker) isOpen() bool {
+	return b.state == breakerOpen
+}
+
+// wait returns the time remaining before the next probe.
+func (b *circuitBreaker) wait() time.Duration {
+	if wait := b.openUntil.Sub(b.clock.Now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// probe lets the next check-in probe fleet-server once the cool-down elapsed.
+func (b *circuitBreaker) probe() {
+	b.state = breakerHalfOpen
+	b.report()
+}
+
+// err describes the open breaker, it is reported as the state of the connection to Fleet.
+func (b *circuitBreaker) err(cause error) error {
+	return fmt.Errorf("circuit breaker open after %d consecutive failed checkins, next checkin in %s: %w",
+		b.failures, b.wait().Round(time.Second), cause)
+}
+
+func (b *circuitBreaker) report() {
+	b.metrics.breaker(b.state, b.trips, b.coolDown)
+}
+
+// tripsBreaker returns true for the failures caused by fleet-server or by the network to it: the
+// server errors, the timeouts and the failed connections. The other failures, like an invalid API
+// key, show that fleet-server answers.
+func tripsBreaker(statusCode int, err error) bool {
+	if statusCode >= http.StatusInternalServerError {
+		return true
+	}
+	if statusCode != 0 {
+		return false
+	}
+	switch failureReason(statusCode, err) {
+	case failureNetwork, failureTimeout:
+		return true
+	}
+	return false
+}
@@ -6,16 +6,22 @@ package fleet
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"go.elastic.co/apm"
+
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 
 	eaclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/gateway"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
@@ -41,12 +47,23 @@ var defaultGatewaySettings = &fleetGatewaySettings{
 		Init: 60 * time.Second,
 		Max:  10 * time.Minute,
 	},
+	PushInterval:         10 * time.Second, // minimum time between check-ins pushed on state changes
+	LoadSheddingDuration: 5 * time.Minute,  // time between successful calls while the agent sheds load
+	CircuitBreaker: circuitBreakerSettings{ // pause of the check-ins while fleet-server fails
+		Threshold:   5,
+		CoolDown:    10 * time.Minute,
+		MaxCoolDown: time.Hour,
+	},
 }
 
 type fleetGatewaySettings struct {
-	Duration time.Duration   `config:"checkin_frequency"`
-	Jitter   time.Duration   `config:"jitter"`
-	Backoff  backoffSettings `config:"backoff"`
+	Duration             time.Duration   `config:"checkin_frequency"`
+	Jitter               time.Duration   `config:"jitter"`
+	Backoff              backoffSettings `config:"backoff"`
+	PushInterval         time.Duration   `config:"push_interval"`
+	LoadSheddingDuration time.Duration   `config:"load_shedding_checkin_frequency"`
+
+	CircuitBreaker circuitBreakerSettings `config:"circuit_breaker"`
 }
 
 type backoffSettings struct {
@@ -66,19 +83,36 @@ type stateStore interface {
 	Actions() []fleetapi.Action
 }
 
+// errCheckinInterrupted is returned by execute when a state change interrupted the check-in.
+var errCheckinInterrupted = errors.New("checkin interrupted by a state change")
+
 type fleetGateway struct {
 	log                *logger.Logger
 	client             client.Sender
 	scheduler          scheduler.Scheduler
+	clock              clock.Clock
 	settings           *fleetGatewaySettings
 	agentInfo          agentInfo
 	acker              acker.Acker
 	unauthCounter      int
 	checkinFailCounter int
 	stateFetcher       func() coordinator.State
+	stateSubscriber    func(ctx context.Context, bufferLen int) chan coordinator.State
 	stateStore         stateStore
 	errCh              chan error
 	actionCh           chan []fleetapi.Action
+	// pushCh is signaled when a material state change must be pushed to Fleet with an immediate check-in
+	pushCh  chan struct{}
+	metrics *gatewayMetrics
+	breaker *circuitBreaker
+	// breakerReporter reports the state of the circuit breaker in the state of the agent, nil when
+	// it is not reported
+	breakerReporter func(ctx context.Context, state string) error
+	reportedBreaker string
+	// metadataHooks report the output of the metadata hooks in the local metadata, nil without hooks
+	metadataHooks *metadataHooks
+	// tracer traces each check-in in its own transaction, nil when the agent is not traced
+	tracer *apm.Tracer
 }
 
 // New creates a new fleet gateway
@@ -88,20 +122,34 @@ func New(
 	client client.Sender,
 	acker acker.Acker,
 	stateFetcher func() coordinator.State,
+	stateSubscriber func(ctx context.Context, bufferLen int) chan coordinator.State,
 	stateStore stateStore,
+	breakerReporter func(ctx context.Context, state string) error,
+	metadataCfg *configuration.MetadataConfig,
+	tracer *apm.Tracer,
 ) (gateway.FleetGateway, error) {
 
 	scheduler := scheduler.NewPeriodicJitter(defaultGatewaySettings.Duration, defaultGatewaySettings.Jitter)
-	return newFleetGatewayWithScheduler(
+	gw, err := newFleetGatewayWithScheduler(
 		log,
 		defaultGatewaySettings,
 		agentInfo,
 		client,
 		scheduler,
+		clock.Real(),
 		acker,
 		stateFetcher,
+		stateSubscriber,
 		stateStore,
 	)
+	if err != nil {
+		return nil, err
+	}
+	gw.(*fleetGateway).metrics.register()
+	gw.(*fleetGateway).metadataHooks = newMetadataHooks(log, clock.Real(), metadataCfg)
+	gw.(*fleetGateway).tracer = tracer
+	gw.(*fleetGateway).breakerReporter = breakerReporter
+	return gw, nil
 }
 
 func newFleetGatewayWithScheduler(
@@ -110,21 +158,29 @@ func newFleetGatewayWithScheduler(
 	agentInfo agentInfo,
 	client client.Sender,
 	scheduler scheduler.Scheduler,
+	clock clock.Clock,
 	acker acker.Acker,
 	stateFetcher func() coordinator.State,
+	stateSubscriber func(ctx context.Context, bufferLen int) chan coordinator.State,
 	stateStore stateStore,
 ) (gateway.FleetGateway, error) {
+	metrics := newGatewayMetrics()
 	return &fleetGateway{
-		log:          log,
-		client:       client,
-		settings:     settings,
-		agentInfo:    agentInfo,
-		scheduler:    scheduler,
-		acker:        acker,
-		stateFetcher: stateFetcher,
-		stateStore:   stateStore,
-		errCh:        make(chan error),
-		actionCh:     make(chan []fleetapi.Action, 1),
+		log:             log,
+		client:          client,
+		settings:        settings,
+		agentInfo:       agentInfo,
+		scheduler:       scheduler,
+		clock:           clock,
+		acker:           acker,
+		stateFetcher:    stateFetcher,
+		stateSubscriber: stateSubscriber,
+		stateStore:      stateStore,
+		errCh:           make(chan error),
+		actionCh:        make(chan []fleetapi.Action, 1),
+		pushCh:          make(chan struct{}, 1),
+		metrics:         metrics,
+		breaker:         newCircuitBreaker(settings.CircuitBreaker, clock, metrics),
 	}, nil
 }
 
@@ -140,12 +196,18 @@ func (f *fleetGateway) Run(ctx context.Context) error {
 		done,
 		f.settings.Backoff.Init,
 		f.settings.Backoff.Max,
+		backoff.WithClock(f.clock),
 	)
 	go func() {
 		<-ctx.Done()
 		close(done)
 	}()
 
+	if f.stateSubscriber != nil {
+		go f.watchState(ctx)
+	}
+
+	f.reportBreaker(ctx)
 	f.log.Info("Fleet gateway started")
 	for {
 		select {
@@ -155,24 +217,128 @@ func (f *fleetGateway) Run(ctx context.Context) error {
 			return ctx.Err()
 		case <-f.scheduler.WaitTick():
 			f.log.Debug("FleetGateway calling Checkin API")
+		case <-f.pushCh:
+			f.log.Debug("FleetGateway calling Checkin API to push a state change")
+		}
 
-			// Execute the checkin call and for any errors returned by the fleet-server API
-			// the function will retry to communicate with fleet-server with an exponential delay and some
-			// jitter to help better distribute the load from a fleet of agents.
-			resp, err := f.doExecute(ctx, backoff)
-			if err != nil {
-				continue
+		// Execute the checkin call and for any errors returned by the fleet-server API
+		// the function will retry to communicate with fleet-server with an exponential delay and some
+		// jitter to help better distribute the load from a fleet of agents.
+		resp, err := f.doExecute(ctx, backoff)
+		if err != nil {
+			continue
+		}
+
+		actions := make([]fleetapi.Action, len(resp.Actions))
+		copy(actions, resp.Actions)
+		if len(actions) > 0 {
+			f.actionCh <- actions
+		}
+
+		if f.stateFetcher != nil && f.stateFetcher().LoadShedding {
+			// the agent approaches the limits of its resources, the next check-in is delayed
+			f.log.Debugf("FleetGateway delaying the next checkin by %s while shedding load", f.settings.LoadSheddingDuration)
+			select {
+			case <-ctx.Done():
+			case <-f.clock.After(f.settings.LoadSheddingDuration):
 			}
+		}
+	}
+}
 
-			actions := make([]fleetapi.Action, len(resp.Actions))
-			copy(actions, resp.Actions)
-			if len(actions) > 0 {
-				f.actionCh <- actions
+// watchState signals pushCh on the material changes of the state of the agent, at most once per
+// PushInterval. The changes happening within the interval are coalesced into a single push.
+func (f *fleetGateway) watchState(ctx context.Context) {
+	stateCh := f.stateSubscriber(ctx, 32)
+
+	var prev *coordinator.State
+	var lastPush time.Time
+	pending := false
+	timer := f.clock.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C()
+	}
+	defer timer.Stop()
+
+	push := func() {
+		pending = false
+		lastPush = f.clock.Now()
+		f.signalPush()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+			if pending {
+				push()
 			}
+		case state := <-stateCh:
+			if prev != nil && !pending {
+				if reason := stateChangeReason(*prev, state); reason != "" {
+					f.log.Infof("Pushing state change to fleet-server: %s", reason)
+					if wait := f.settings.PushInterval - f.clock.Since(lastPush); !lastPush.IsZero() && wait > 0 {
+						pending = true
+						timer.Reset(wait)
+					} else {
+						push()
+					}
+				}
+			}
+			prev = &state
 		}
 	}
 }
 
+// stateChangeReason describes the material change between the two states of the agent, it returns
+// an empty string when the change can wait for the next periodic check-in.
+func stateChangeReason(prev, cur coordinator.State) string {
+	if (prev.State == agentclient.Failed) != (cur.State == agentclient.Failed) {
+		return fmt.Sprintf("agent state changed from %s to %s", prev.State, cur.State)
+	}
+
+	failed := func(s coordinator.State) map[string]bool {
+		m := make(map[string]bool, len(s.Components))
+		for _, c := range s.Components {
+			m[c.Component.ID] = c.State.State == eaclient.UnitStateFailed
+		}
+		return m
+	}
+	prevFailed := failed(prev)
+	for id, curFailed := range failed(cur) {
+		if prevFailed[id] != curFailed {
+			return fmt.Sprintf("component %s failed state changed to %t", id, curFailed)
+		}
+		delete(prevFailed, id)
+	}
+	for id, wasFailed := range prevFailed {
+		if wasFailed {
+			return fmt.Sprintf("failed component %s was removed", id)
+		}
+	}
+
+	if upgradeFinished(prev.UpgradeDetails) != upgradeFinished(cur.UpgradeDetails) ||
+		(prev.UpgradeDetails != nil && cur.UpgradeDetails == nil) {
+		if cur.UpgradeDetails == nil {
+			return "upgrade details cleared"
+		}
+		return fmt.Sprintf("upgrade to %s reached %s", cur.UpgradeDetails.TargetVersion, cur.UpgradeDetails.State)
+	}
+	return ""
+}
+
+func upgradeFinished(d *details.Details) bool {
+	if d == nil {
+		return false
+	}
+	switch d.State {
+	case details.StateCompleted, details.StateRolledBack, details.StateFailed:
+		return true
+	}
+	return false
+}
+
 // Errors returns the channel to watch for reported errors.
 func (f *fleetGateway) Errors() <-chan error {
 	return f.errCh
@@ -186,8 +352,20 @@ func (f *fleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 	for ctx.Err() == nil {
 		f.log.Debugf("Checking started")
 		resp, took, err := f.execute(ctx)
+		f.reportBreaker(ctx)
+		if errors.Is(err, errCheckinInterrupted) {
+			// check in again right away with the new state
+			continue
+		}
 		if err != nil {
 			f.checkinFailCounter++
+			if f.breaker.isOpen() {
+				if !f.coolDown(ctx, err, took) {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+			f.metrics.retrying(f.checkinFailCounter, bo.NextWait())
 
 			// Report the first two failures at warn level as they may be recoverable with retries.
 			if f.checkinFailCounter <= 2 {
@@ -222,6 +400,7 @@ func (f *fleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 		}
 
 		f.checkinFailCounter = 0
+		f.metrics.retrying(0, 0)
 		f.errCh <- nil
 		// Request was successful, return the collected actions.
 		return resp, nil
@@ -232,6 +411,38 @@ func (f *fleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 	return nil, ctx.Err()
 }
 
+// coolDown waits for the end of the cool-down of the open circuit breaker and lets the next
+// check-in probe fleet-server, it returns false when the context is done.
+func (f *fleetGateway) coolDown(ctx context.Context, cause error, took time.Duration) bool {
+	wait := f.breaker.wait()
+	f.metrics.retrying(f.checkinFailCounter, wait)
+	err := f.breaker.err(cause)
+	f.log.Errorw("Too many failed checkins with fleet-server, pausing the checkins",
+		"error.message", err, "request_duration_ns", took, "failed_checkins", f.checkinFailCounter,
+		"retry_after_ns", wait)
+	f.errCh <- err
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-f.clock.After(wait):
+	}
+	f.breaker.probe()
+	f.reportBreaker(ctx)
+	return true
+}
+
+// reportBreaker reports the state of the circuit breaker in the state of the agent when it changed.
+func (f *fleetGateway) reportBreaker(ctx context.Context) {
+	if f.breakerReporter == nil || f.breaker.state == f.reportedBreaker {
+		return
+	}
+	if err := f.breakerReporter(ctx, f.breaker.state); err != nil {
+		return
+	}
+	f.reportedBreaker = f.breaker.state
+}
+
 func (f *fleetGateway) convertToCheckinComponents(components []runtime.ComponentComponentState) []fleetapi.CheckinComponent {
 	if components == nil {
 		return nil
@@ -296,7 +507,7 @@ func (f *fleetGateway) convertToCheckinComponents(components []runtime.Component
 					Type:    unitTypeString(unitKey.UnitType),
 					Status:  stateString(unitState.State),
 					Message: unitState.Message,
-					Payload: unitState.Payload,
+					Payload: unitState.ReportedPayload(),
 				})
 			}
 			checkinComponent.Units = units
@@ -308,9 +519,17 @@ func (f *fleetGateway) convertToCheckinComponents(components []runtime.Component
 }
 
 func (f *fleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse, time.Duration, error) {
+	if f.tracer != nil {
+		tx := f.tracer.StartTransaction("checkin", "fleet")
+		ctx = apm.ContextWithTransaction(ctx, tx)
+		defer tx.End()
+	}
+
 	ecsMeta, err := info.Metadata(f.log)
 	if err != nil {
 		f.log.Error(errors.New("failed to load metadata", err))
+	} else {
+		ecsMeta.Custom = f.metadataHooks.collect(ctx)
 	}
 
 	// retrieve ack token from the store
@@ -326,16 +545,25 @@ func (f *fleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	components := f.convertToCheckinComponents(state.Components)
 
 	// checkin
-	cmd := fleetapi.NewCheckinCmd(f.agentInfo, f.client)
+	sender := &meteredSender{Sender: f.client, metrics: f.metrics}
+	cmd := fleetapi.NewCheckinCmd(f.agentInfo, sender)
 	req := &fleetapi.CheckinRequest{
-		AckToken:   ackToken,
-		Metadata:   ecsMeta,
-		Status:     agentStateToString(state.State),
-		Message:    state.Message,
-		Components: components,
+		AckToken:       ackToken,
+		Metadata:       ecsMeta,
+		Status:         agentStateToString(state.State),
+		Message:        state.Message,
+		Components:     components,
+		UpgradeDetails: state.UpgradeDetails,
 	}
 
-	resp, took, err := cmd.Execute(ctx, req)
+	resp, took, err := f.executeInterruptible(ctx, cmd, req)
+	if ctx.Err() == nil {
+		f.metrics.checkin(took, sender.statusCode, err)
+		f.breaker.record(sender.statusCode, err)
+	}
+	if errors.Is(err, errCheckinInterrupted) {
+		return nil, took, err
+	}
 	if isUnauth(err) {
 		f.unauthCounter++
 
@@ -366,6 +594,43 @@ func (f *fleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	return resp, took, nil
 }
 
+// executeInterruptible executes the check-in, a long poll, and interrupts it when a state change must be
+// pushed so the new state is reported without waiting for fleet-server to answer.
+func (f *fleetGateway) executeInterruptible(ctx context.Context, cmd *fleetapi.CheckinCmd, req *fleetapi.CheckinRequest) (*fleetapi.CheckinResponse, time.Duration, error) {
+	checkinCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-f.pushCh:
+			cancel()
+			interrupted <- true
+		case <-checkinCtx.Done():
+			interrupted <- false
+		}
+	}()
+
+	resp, took, err := cmd.Execute(checkinCtx, req)
+	cancel()
+	if <-interrupted && ctx.Err() == nil {
+		if err == nil {
+			// the check-in completed before being interrupted, push the change on the next one
+			f.signalPush()
+			return resp, took, nil
+		}
+		return nil, took, errCheckinInterrupted
+	}
+	return resp, took, err
+}
+
+func (f *fleetGateway) signalPush() {
+	select {
+	case f.pushCh <- struct{}{}:
+	default:
+	}
+}
+
 // shouldUnenroll checks if the max number of trying an invalid key is reached
 func (f *fleetGateway) shouldUnenroll() bool {
 	return f.unauthCounter > maxUnauthCounter
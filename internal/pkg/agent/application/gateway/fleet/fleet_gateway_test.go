@@ -7,6 +7,7 @@ package fleet
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"io"
@@ -22,13 +23,20 @@ import (
 	"github.com/stretchr/testify/require"
 	"gotest.tools/assert"
 
+	eaclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/gateway"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
 	"github.com/elastic/elastic-agent/internal/pkg/scheduler"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -70,11 +78,12 @@ func newTestingClient() *testingClient {
 	return &testingClient{received: make(chan struct{}, 1)}
 }
 
-type withGatewayFunc func(*testing.T, gateway.FleetGateway, *testingClient, *scheduler.Stepper)
+type withGatewayFunc func(*testing.T, gateway.FleetGateway, *testingClient, *scheduler.Stepper, *clock.Mock)
 
 func withGateway(agentInfo agentInfo, settings *fleetGatewaySettings, fn withGatewayFunc) func(t *testing.T) {
 	return func(t *testing.T) {
 		scheduler := scheduler.NewStepper()
+		clk := clock.NewMock(time.Now())
 		client := newTestingClient()
 
 		log, _ := logger.New("fleet_gateway", false)
@@ -87,14 +96,16 @@ func withGateway(agentInfo agentInfo, settings *fleetGatewaySettings, fn withGat
 			agentInfo,
 			client,
 			scheduler,
+			clk,
 			noop.New(),
 			emptyStateFetcher,
+			nil,
 			stateStore,
 		)
 
 		require.NoError(t, err)
 
-		fn(t, gateway, client, scheduler)
+		fn(t, gateway, client, scheduler, clk)
 	}
 }
 
@@ -131,6 +142,7 @@ func TestFleetGateway(t *testing.T) {
 		gateway gateway.FleetGateway,
 		client *testingClient,
 		scheduler *scheduler.Stepper,
+		_ *clock.Mock,
 	) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -163,6 +175,7 @@ func TestFleetGateway(t *testing.T) {
 		gateway gateway.FleetGateway,
 		client *testingClient,
 		scheduler *scheduler.Stepper,
+		_ *clock.Mock,
 	) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -226,8 +239,10 @@ func TestFleetGateway(t *testing.T) {
 			agentInfo,
 			client,
 			scheduler,
+			clock.Real(),
 			noop.New(),
 			emptyStateFetcher,
+			nil,
 			stateStore,
 		)
 		require.NoError(t, err)
@@ -278,8 +293,10 @@ func TestFleetGateway(t *testing.T) {
 			agentInfo,
 			client,
 			scheduler,
+			clock.Real(),
 			noop.New(),
 			emptyStateFetcher,
+			nil,
 			stateStore,
 		)
 		require.NoError(t, err)
@@ -315,7 +332,7 @@ func TestRetriesOnFailures(t *testing.T) {
 	agentInfo := &testAgentInfo{}
 	settings := &fleetGatewaySettings{
 		Duration: 5 * time.Second,
-		Backoff:  backoffSettings{Init: 100 * time.Millisecond, Max: 5 * time.Second},
+		Backoff:  backoffSettings{Init: 1 * time.Minute, Max: 10 * time.Minute},
 	}
 
 	t.Run("When the gateway fails to communicate with the checkin API we will retry",
@@ -324,6 +341,7 @@ func TestRetriesOnFailures(t *testing.T) {
 			gateway gateway.FleetGateway,
 			client *testingClient,
 			scheduler *scheduler.Stepper,
+			clk *clock.Mock,
 		) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
@@ -338,10 +356,13 @@ func TestRetriesOnFailures(t *testing.T) {
 			// Initial tick is done out of bound so we can block on channels.
 			scheduler.Next()
 
-			// Simulate a 500 errors for the next 3 calls.
-			<-clientWaitFn
-			<-clientWaitFn
+			// Simulate a 500 errors for the next 3 calls, the retries happen once the backoff elapsed on the clock.
 			<-clientWaitFn
+			for i := 0; i < 2; i++ {
+				clk.BlockUntil(1)
+				clk.Advance(settings.Backoff.Max)
+				<-clientWaitFn
+			}
 
 			// API recover
 			waitFn := ackSeq(
@@ -350,6 +371,8 @@ func TestRetriesOnFailures(t *testing.T) {
 					return resp, nil
 				}),
 			)
+			clk.BlockUntil(1)
+			clk.Advance(settings.Backoff.Max)
 
 			waitFn()
 
@@ -372,6 +395,7 @@ func TestRetriesOnFailures(t *testing.T) {
 			gateway gateway.FleetGateway,
 			client *testingClient,
 			scheduler *scheduler.Stepper,
+			_ *clock.Mock,
 		) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
@@ -396,6 +420,373 @@ func TestRetriesOnFailures(t *testing.T) {
 		}))
 }
 
+func TestCircuitBreaker(t *testing.T) {
+	agentInfo := &testAgentInfo{}
+	settings := &fleetGatewaySettings{
+		Duration: 5 * time.Second,
+		Backoff:  backoffSettings{Init: time.Second, Max: time.Second},
+		CircuitBreaker: circuitBreakerSettings{
+			Threshold:   2,
+			CoolDown:    time.Minute,
+			MaxCoolDown: 90 * time.Second,
+		},
+	}
+
+	t.Run("Repeated server errors open the circuit breaker until a probe succeeds",
+		withGateway(agentInfo, settings, func(
+			t *testing.T,
+			gw gateway.FleetGateway,
+			client *testingClient,
+			scheduler *scheduler.Stepper,
+			clk *clock.Mock,
+		) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			fail := func(_ http.Header, _ io.Reader) (*http.Response, error) {
+				return wrapStrToResp(http.StatusServiceUnavailable, "something is bad"), nil
+			}
+			clientWaitFn := client.Answer(fail)
+
+			fg := gw.(*fleetGateway)
+			states := make(chan string, 10)
+			fg.breakerReporter = func(_ context.Context, state string) error {
+				states <- state
+				return nil
+			}
+			errs := make(chan error, 10)
+			go func() {
+				_ = fg.Run(ctx)
+			}()
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case err := <-fg.Errors():
+						errs <- err
+					}
+				}
+			}()
+
+			scheduler.Next()
+
+			// the first failure is retried after the backoff, reported once it elapsed
+			<-clientWaitFn
+			clk.BlockUntil(1)
+			clk.Advance(2 * settings.Backoff.Init) // the first wait is up to twice the initial backoff
+			require.Error(t, <-errs)
+
+			// the second failure opens the breaker for the cool-down
+			<-clientWaitFn
+			err := <-errs
+			require.ErrorContains(t, err, "circuit breaker open after 2 consecutive failed checkins, next checkin in 1m0s")
+			clk.BlockUntil(1)
+			clk.Advance(settings.Backoff.Max)
+			select {
+			case <-clientWaitFn:
+				t.Fatal("no checkin is expected before the end of the cool-down")
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			// the probe fails, the cool-down doubles up to its maximum
+			clk.Advance(settings.CircuitBreaker.CoolDown)
+			<-clientWaitFn
+			err = <-errs
+			require.ErrorContains(t, err, "circuit breaker open after 3 consecutive failed checkins, next checkin in 1m30s")
+
+			// the probe succeeds, the breaker closes
+			waitFn := ackSeq(
+				client.Answer(func(_ http.Header, body io.Reader) (*http.Response, error) {
+					return wrapStrToResp(http.StatusOK, `{ "actions": [] }`), nil
+				}),
+			)
+			clk.BlockUntil(1)
+			clk.Advance(settings.CircuitBreaker.MaxCoolDown)
+			waitFn()
+			require.NoError(t, <-errs)
+			assert.Equal(t, breakerClosed, fg.breaker.state)
+			assert.Equal(t, int64(2), fg.breaker.trips)
+
+			// the changes of the state of the breaker are reported in the state of the agent
+			var reported []string
+			for len(states) > 0 {
+				reported = append(reported, <-states)
+			}
+			assert.DeepEqual(t, []string{breakerClosed, breakerOpen, breakerHalfOpen, breakerOpen, breakerHalfOpen, breakerClosed}, reported)
+		}))
+}
+
+func TestTripsBreaker(t *testing.T) {
+	assert.Assert(t, tripsBreaker(http.StatusBadGateway, errors.New("bad gateway")))
+	assert.Assert(t, tripsBreaker(0, context.DeadlineExceeded))
+	assert.Assert(t, tripsBreaker(0, errors.New("connection refused")))
+	assert.Assert(t, !tripsBreaker(http.StatusUnauthorized, client.ErrInvalidAPIKey))
+	assert.Assert(t, !tripsBreaker(http.StatusTooManyRequests, errors.New("too many requests")))
+}
+
+// longPollClient blocks the first check-in until its context is cancelled, like a long poll
+// fleet-server has nothing to answer to, and answers the next ones right away.
+type longPollClient struct {
+	mx       sync.Mutex
+	calls    int
+	statuses chan string
+}
+
+func (c *longPollClient) Send(
+	ctx context.Context,
+	_ string,
+	_ string,
+	_ url.Values,
+	_ http.Header,
+	body io.Reader,
+) (*http.Response, error) {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, err
+	}
+	c.statuses <- req.Status
+
+	c.mx.Lock()
+	c.calls++
+	first := c.calls == 1
+	c.mx.Unlock()
+	if first {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return wrapStrToResp(http.StatusOK, `{ "actions": [] }`), nil
+}
+
+func (c *longPollClient) URI() string {
+	return "http://localhost"
+}
+
+func TestFleetGatewayPushesStateChanges(t *testing.T) {
+	log, _ := logger.New("fleet_gateway", false)
+	scheduler := scheduler.NewStepper()
+	clk := clock.NewMock(time.Now())
+	client := &longPollClient{statuses: make(chan string, 10)}
+	settings := &fleetGatewaySettings{
+		Duration:     5 * time.Second,
+		Backoff:      backoffSettings{Init: 1 * time.Second, Max: 5 * time.Second},
+		PushInterval: 10 * time.Second,
+	}
+
+	var mx sync.Mutex
+	current := coordinator.State{State: agentclient.Healthy}
+	stateCh := make(chan coordinator.State)
+	setState := func(s agentclient.State) {
+		mx.Lock()
+		current = coordinator.State{State: s}
+		mx.Unlock()
+		stateCh <- current
+	}
+	stateFetcher := func() coordinator.State {
+		mx.Lock()
+		defer mx.Unlock()
+		return current
+	}
+	stateSubscriber := func(context.Context, int) chan coordinator.State {
+		return stateCh
+	}
+
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		settings,
+		&testAgentInfo{},
+		client,
+		scheduler,
+		clk,
+		noop.New(),
+		stateFetcher,
+		stateSubscriber,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := runFleetGateway(ctx, gateway)
+
+	nextStatus := func() string {
+		select {
+		case status := <-client.statuses:
+			return status
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for a check-in")
+		}
+		return ""
+	}
+
+	scheduler.Next()
+	require.Equal(t, fleetStateOnline, nextStatus())
+
+	// the failure interrupts the long poll to check in with the new state
+	setState(agentclient.Healthy)
+	setState(agentclient.Failed)
+	require.Equal(t, fleetStateError, nextStatus())
+
+	// the recovery happening within the push interval waits for the end of the interval
+	setState(agentclient.Healthy)
+	clk.BlockUntil(1)
+	select {
+	case status := <-client.statuses:
+		require.Failf(t, "unexpected check-in", "check-in with status %s within the push interval", status)
+	case <-time.After(100 * time.Millisecond):
+	}
+	clk.Advance(settings.PushInterval)
+	require.Equal(t, fleetStateOnline, nextStatus())
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestFleetGatewayDelaysCheckinsWhileSheddingLoad(t *testing.T) {
+	log, _ := logger.New("fleet_gateway", false)
+	scheduler := scheduler.NewStepper()
+	clk := clock.NewMock(time.Now())
+	client := newTestingClient()
+	settings := &fleetGatewaySettings{
+		Duration:             5 * time.Second,
+		Backoff:              backoffSettings{Init: 1 * time.Second, Max: 5 * time.Second},
+		LoadSheddingDuration: time.Minute,
+	}
+	stateFetcher := func() coordinator.State {
+		return coordinator.State{State: agentclient.Degraded, LoadShedding: true}
+	}
+
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		settings,
+		&testAgentInfo{},
+		client,
+		scheduler,
+		clk,
+		noop.New(),
+		stateFetcher,
+		nil,
+		newStateStore(t, log),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := client.Answer(func(headers http.Header, body io.Reader) (*http.Response, error) {
+		return wrapStrToResp(http.StatusOK, `{ "actions": [] }`), nil
+	})
+	errCh := runFleetGateway(ctx, gateway)
+
+	scheduler.Next()
+	<-received
+
+	// the next tick is not consumed until the load shedding delay expires
+	clk.BlockUntil(1)
+	select {
+	case scheduler.C <- time.Now():
+		require.Fail(t, "unexpected check-in while shedding load")
+	case <-time.After(100 * time.Millisecond):
+	}
+	clk.Advance(settings.LoadSheddingDuration)
+	scheduler.Next()
+	<-received
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestStateChangeReason(t *testing.T) {
+	comp := func(id string, state eaclient.UnitState) runtime.ComponentComponentState {
+		return runtime.ComponentComponentState{
+			Component: component.Component{ID: id},
+			State:     runtime.ComponentState{State: state},
+		}
+	}
+	upgrade := func(state details.State) *details.Details {
+		return details.NewDetails("8.9.0", state, "action-id")
+	}
+
+	testcases := map[string]struct {
+		prev     coordinator.State
+		cur      coordinator.State
+		material bool
+	}{
+		"no change": {
+			prev: coordinator.State{State: agentclient.Healthy},
+			cur:  coordinator.State{State: agentclient.Healthy},
+		},
+		"healthy to degraded": {
+			prev: coordinator.State{State: agentclient.Healthy},
+			cur:  coordinator.State{State: agentclient.Degraded},
+		},
+		"healthy to failed": {
+			prev:     coordinator.State{State: agentclient.Healthy},
+			cur:      coordinator.State{State: agentclient.Failed},
+			material: true,
+		},
+		"failed to healthy": {
+			prev:     coordinator.State{State: agentclient.Failed},
+			cur:      coordinator.State{State: agentclient.Healthy},
+			material: true,
+		},
+		"component failed": {
+			prev:     coordinator.State{Components: []runtime.ComponentComponentState{comp("a", eaclient.UnitStateHealthy)}},
+			cur:      coordinator.State{Components: []runtime.ComponentComponentState{comp("a", eaclient.UnitStateFailed)}},
+			material: true,
+		},
+		"component degraded": {
+			prev: coordinator.State{Components: []runtime.ComponentComponentState{comp("a", eaclient.UnitStateHealthy)}},
+			cur:  coordinator.State{Components: []runtime.ComponentComponentState{comp("a", eaclient.UnitStateDegraded)}},
+		},
+		"failed component added": {
+			prev:     coordinator.State{},
+			cur:      coordinator.State{Components: []runtime.ComponentComponentState{comp("a", eaclient.UnitStateFailed)}},
+			material: true,
+		},
+		"healthy component added": {
+			prev: coordinator.State{},
+			cur:  coordinator.State{Components: []runtime.ComponentComponentState{comp("a", eaclient.UnitStateHealthy)}},
+		},
+		"failed component removed": {
+			prev:     coordinator.State{Components: []runtime.ComponentComponentState{comp("a", eaclient.UnitStateFailed)}},
+			cur:      coordinator.State{},
+			material: true,
+		},
+		"upgrade progressing": {
+			prev: coordinator.State{UpgradeDetails: upgrade(details.StateDownloading)},
+			cur:  coordinator.State{UpgradeDetails: upgrade(details.StateVerifying)},
+		},
+		"upgrade failed": {
+			prev:     coordinator.State{UpgradeDetails: upgrade(details.StateDownloading)},
+			cur:      coordinator.State{UpgradeDetails: upgrade(details.StateFailed)},
+			material: true,
+		},
+		"upgrade rolled back": {
+			prev:     coordinator.State{UpgradeDetails: upgrade(details.StateWatching)},
+			cur:      coordinator.State{UpgradeDetails: upgrade(details.StateRolledBack)},
+			material: true,
+		},
+		"upgrade details cleared": {
+			prev:     coordinator.State{UpgradeDetails: upgrade(details.StateWatching)},
+			cur:      coordinator.State{},
+			material: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			reason := stateChangeReason(tc.prev, tc.cur)
+			if tc.material {
+				require.NotEmpty(t, reason)
+			} else {
+				require.Empty(t, reason)
+			}
+		})
+	}
+}
+
 type testAgentInfo struct{}
 
 func (testAgentInfo) AgentID() string { return "agent-secret" }
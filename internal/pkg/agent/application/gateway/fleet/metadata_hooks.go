@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// maxMetadataHookOutput is the maximum size of the output of a metadata hook.
+const maxMetadataHookOutput = 64 * 1024
+
+// metadataHooks runs the metadata hooks and caches their output, it is only called
+// from the check-in loop of the gateway.
+type metadataHooks struct {
+	log   *logger.Logger
+	clock clock.Clock
+	run   func(ctx context.Context, cfg configuration.MetadataHookConfig) (map[string]interface{}, error)
+	hooks []*metadataHook
+}
+
+type metadataHook struct {
+	cfg     configuration.MetadataHookConfig
+	value   map[string]interface{}
+	expires time.Time
+}
+
+func newMetadataHooks(log *logger.Logger, clk clock.Clock, cfg *configuration.MetadataConfig) *metadataHooks {
+	m := &metadataHooks{log: log, clock: clk, run: runMetadataHook}
+	if cfg == nil {
+		return m
+	}
+	for _, c := range cfg.Hooks {
+		if c.Timeout <= 0 {
+			c.Timeout = configuration.DefaultMetadataHookTimeout
+		}
+		if c.CacheTTL <= 0 {
+			c.CacheTTL = configuration.DefaultMetadataHookCacheTTL
+		}
+		m.hooks = append(m.hooks, &metadataHook{cfg: c})
+	}
+	return m
+}
+
+// collect returns the output of the hooks by hook name, the hooks whose cached output expired run
+// again. A hook failing keeps its previous output until the next run.
+func (m *metadataHooks) collect(ctx context.Context) map[string]interface{} {
+	if m == nil || len(m.hooks) == 0 {
+		return nil
+	}
+
+	custom := make(map[string]interface{}, len(m.hooks))
+	for _, h := range m.hooks {
+		if now := m.clock.Now(); !now.Before(h.expires) {
+			h.expires = now.Add(h.cfg.CacheTTL)
+			value, err := m.run(ctx, h.cfg)
+			if err != nil {
+				m.log.Warnw("Metadata hook failed", "hook", h.cfg.Name, "error.message", err)
+			} else {
+				h.value = value
+			}
+		}
+		if h.value != nil {
+			custom[h.cfg.Name] = h.value
+		}
+	}
+	if len(custom) == 0 {
+		return nil
+	}
+	return custom
+}
+
+func runMetadataHook(ctx context.Context, cfg configuration.MetadataHookConfig) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Stdout = &limitedWriter{w: &stdout, n: maxMetadataHookOutput}
+	cmd.Stderr = &limitedWriter{w: &stderr, n: maxMetadataHookOutput}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %s", cfg.Timeout)
+		}
+		return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var value map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &value); err != nil {
+		return nil, fmt.Errorf("output is not a JSON object: %w", err)
+	}
+	return value, nil
+}
+
+// limitedWriter discards the writes beyond n bytes, so a hook cannot grow the check-in without bounds.
+type limitedWriter struct {
+	w *bytes.Buffer
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if remaining := l.n - l.w.Len(); remaining > 0 {
+		if len(p) > remaining {
+			l.w.Write(p[:remaining])
+		} else {
+			l.w.Write(p)
+		}
+	}
+	return len(p), nil
+}
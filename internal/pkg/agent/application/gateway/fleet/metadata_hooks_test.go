@@ -0,0 +1,103 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestMetadataHooksCollect(t *testing.T) {
+	log, _ := logger.New("", false)
+	clk := clock.NewMock(time.Now())
+	hooks := newMetadataHooks(log, clk, &configuration.MetadataConfig{Hooks: []configuration.MetadataHookConfig{
+		{Name: "cmdb", Command: "cmdb-id", CacheTTL: time.Minute},
+		{Name: "asset", Command: "asset-tag"},
+	}})
+
+	runs := map[string]int{}
+	var failAsset bool
+	hooks.run = func(_ context.Context, cfg configuration.MetadataHookConfig) (map[string]interface{}, error) {
+		runs[cfg.Name]++
+		if cfg.Name == "asset" && failAsset {
+			return nil, errors.New("asset database unavailable")
+		}
+		return map[string]interface{}{"run": runs[cfg.Name]}, nil
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"cmdb":  map[string]interface{}{"run": 1},
+		"asset": map[string]interface{}{"run": 1},
+	}, hooks.collect(context.Background()))
+
+	// cached output is reused until the TTL expires
+	clk.Advance(30 * time.Second)
+	hooks.collect(context.Background())
+	assert.Equal(t, map[string]int{"cmdb": 1, "asset": 1}, runs)
+
+	// a failing hook keeps reporting its previous output
+	failAsset = true
+	clk.Advance(configuration.DefaultMetadataHookCacheTTL)
+	assert.Equal(t, map[string]interface{}{
+		"cmdb":  map[string]interface{}{"run": 2},
+		"asset": map[string]interface{}{"run": 1},
+	}, hooks.collect(context.Background()))
+	assert.Equal(t, map[string]int{"cmdb": 2, "asset": 2}, runs)
+}
+
+func TestMetadataHooksCollectWithoutHooks(t *testing.T) {
+	log, _ := logger.New("", false)
+	assert.Nil(t, newMetadataHooks(log, clock.Real(), nil).collect(context.Background()))
+
+	var hooks *metadataHooks
+	assert.Nil(t, hooks.collect(context.Background()))
+}
+
+func TestRunMetadataHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks of the test are shell scripts")
+	}
+
+	t.Run("JSON object output", func(t *testing.T) {
+		value, err := runMetadataHook(context.Background(), configuration.MetadataHookConfig{
+			Name:    "cmdb",
+			Command: "sh",
+			Args:    []string{"-c", `echo '{"id": "CI-1234", "rack": 12}'`},
+			Timeout: 5 * time.Second,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"id": "CI-1234", "rack": float64(12)}, value)
+	})
+
+	t.Run("not a JSON object", func(t *testing.T) {
+		_, err := runMetadataHook(context.Background(), configuration.MetadataHookConfig{
+			Name:    "cmdb",
+			Command: "sh",
+			Args:    []string{"-c", "echo CI-1234"},
+			Timeout: 5 * time.Second,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := runMetadataHook(context.Background(), configuration.MetadataHookConfig{
+			Name:    "cmdb",
+			Command: "sh",
+			Args:    []string{"-c", "exec sleep 10"},
+			Timeout: 100 * time.Millisecond,
+		})
+		assert.ErrorContains(t, err, "timed out")
+	})
+}
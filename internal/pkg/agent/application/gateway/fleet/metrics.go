@@ -0,0 +1,282 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+// metricsName is the name of the Fleet gateway metrics in the stats namespace of the monitoring endpoint.
+const metricsName = "fleet_gateway"
+
+// Reasons of the failed check-ins.
+const (
+	failureNetwork         = "network"
+	failureTimeout         = "timeout"
+	failureUnauthorized    = "unauthorized"
+	failureHTTPStatus      = "http_status"
+	failureInvalidResponse = "invalid_response"
+)
+
+// latencyBuckets are the upper bounds of the check-in latency histogram, a check-in being a long poll
+// the latency of a successful check-in goes up to the poll timeout of fleet-server.
+var latencyBuckets = []struct {
+	name  string
+	bound time.Duration
+}{
+	{"le_100ms", 100 * time.Millisecond},
+	{"le_500ms", 500 * time.Millisecond},
+	{"le_1s", time.Second},
+	{"le_5s", 5 * time.Second},
+	{"le_30s", 30 * time.Second},
+	{"le_1m", time.Minute},
+	{"le_5m", 5 * time.Minute},
+	{"le_10m", 10 * time.Minute},
+}
+
+// gatewayMetrics collects the metrics of the check-ins, it is a monitoring.Var reported under
+// fleet_gateway in the stats of the monitoring endpoint.
+type gatewayMetrics struct {
+	mx sync.Mutex
+
+	checkins    int64
+	succeeded   int64
+	interrupted int64
+	failures    map[string]int64
+
+	latencyCount   int64
+	latencySum     time.Duration
+	latencyBuckets []int64 // cumulative, the last one counts the latencies above all bounds
+
+	responses map[int]int64
+
+	requestBytes      int64
+	lastRequestBytes  int64
+	responseBytes     int64
+	lastResponseBytes int64
+
+	consecutiveFailures int
+	backoff             time.Duration
+
+	breakerState    string
+	breakerTrips    int64
+	breakerCoolDown time.Duration
+}
+
+func newGatewayMetrics() *gatewayMetrics {
+	return &gatewayMetrics{
+		failures:       make(map[string]int64),
+		latencyBuckets: make([]int64, len(latencyBuckets)+1),
+		responses:      make(map[int]int64),
+	}
+}
+
+// register adds the metrics to the stats of the monitoring endpoint, replacing the ones of a previous gateway.
+func (m *gatewayMetrics) register() {
+	reg := monitoring.GetNamespace("stats").GetRegistry()
+	reg.Remove(metricsName)
+	reg.Add(metricsName, m, monitoring.Reported)
+}
+
+// checkin records the outcome of a check-in that lasted took, statusCode is 0 when no response was received.
+func (m *gatewayMetrics) checkin(took time.Duration, statusCode int, err error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.checkins++
+	if errors.Is(err, errCheckinInterrupted) {
+		m.interrupted++
+		return
+	}
+
+	m.latencyCount++
+	m.latencySum += took
+	for i, b := range latencyBuckets {
+		if took <= b.bound {
+			m.latencyBuckets[i]++
+		}
+	}
+	m.latencyBuckets[len(latencyBuckets)]++
+
+	if err == nil {
+		m.succeeded++
+		return
+	}
+	m.failures[failureReason(statusCode, err)]++
+}
+
+// retrying records the state of the retries of the failed check-ins.
+func (m *gatewayMetrics) retrying(consecutiveFailures int, backoff time.Duration) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.consecutiveFailures = consecutiveFailures
+	m.backoff = backoff
+}
+
+// breaker records the state of the circuit breaker of the check-ins.
+func (m *gatewayMetrics) breaker(state string, trips int64, coolDown time.Duration) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.breakerState = state
+	m.breakerTrips = trips
+	m.breakerCoolDown = coolDown
+}
+
+func (m *gatewayMetrics) requestSent(n int64) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.requestBytes += n
+	m.lastRequestBytes = n
+}
+
+func (m *gatewayMetrics) responseReceived(statusCode int) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.responses[statusCode]++
+}
+
+func (m *gatewayMetrics) responseRead(n int64) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.responseBytes += n
+	m.lastResponseBytes = n
+}
+
+// Visit reports the metrics to the monitoring visitor.
+func (m *gatewayMetrics) Visit(_ monitoring.Mode, vs monitoring.Visitor) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	vs.OnRegistryStart()
+	defer vs.OnRegistryFinished()
+
+	monitoring.ReportNamespace(vs, "checkins", func() {
+		monitoring.ReportInt(vs, "total", m.checkins)
+		monitoring.ReportInt(vs, "succeeded", m.succeeded)
+		monitoring.ReportInt(vs, "interrupted", m.interrupted)
+		monitoring.ReportNamespace(vs, "failed", func() {
+			for _, reason := range []string{failureNetwork, failureTimeout, failureUnauthorized, failureHTTPStatus, failureInvalidResponse} {
+				monitoring.ReportInt(vs, reason, m.failures[reason])
+			}
+		})
+	})
+	monitoring.ReportNamespace(vs, "latency", func() {
+		monitoring.ReportInt(vs, "count", m.latencyCount)
+		monitoring.ReportInt(vs, "sum_ms", m.latencySum.Milliseconds())
+		monitoring.ReportNamespace(vs, "histogram", func() {
+			for i, b := range latencyBuckets {
+				monitoring.ReportInt(vs, b.name, m.latencyBuckets[i])
+			}
+			monitoring.ReportInt(vs, "le_inf", m.latencyBuckets[len(latencyBuckets)])
+		})
+	})
+	monitoring.ReportNamespace(vs, "responses", func() {
+		for code, count := range m.responses {
+			monitoring.ReportInt(vs, strconv.Itoa(code), count)
+		}
+	})
+	monitoring.ReportNamespace(vs, "retry", func() {
+		monitoring.ReportInt(vs, "consecutive_failures", int64(m.consecutiveFailures))
+		monitoring.ReportInt(vs, "backoff_ms", m.backoff.Milliseconds())
+	})
+	monitoring.ReportNamespace(vs, "circuit_breaker", func() {
+		monitoring.ReportString(vs, "state", m.breakerState)
+		monitoring.ReportInt(vs, "trips", m.breakerTrips)
+		monitoring.ReportInt(vs, "cool_down_ms", m.breakerCoolDown.Milliseconds())
+	})
+	monitoring.ReportNamespace(vs, "bytes", func() {
+		monitoring.ReportInt(vs, "request_total", m.requestBytes)
+		monitoring.ReportInt(vs, "request_last", m.lastRequestBytes)
+		monitoring.ReportInt(vs, "response_total", m.responseBytes)
+		monitoring.ReportInt(vs, "response_last", m.lastResponseBytes)
+	})
+}
+
+// failureReason classifies a failed check-in from the status code of the response, 0 when none was received.
+func failureReason(statusCode int, err error) string {
+	switch {
+	case statusCode == http.StatusUnauthorized || errors.Is(err, client.ErrInvalidAPIKey):
+		return failureUnauthorized
+	case statusCode != 0 && statusCode != http.StatusOK:
+		return failureHTTPStatus
+	case statusCode == http.StatusOK:
+		return failureInvalidResponse
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return failureTimeout
+	}
+	return failureNetwork
+}
+
+// meteredSender records the sizes of the check-in request and response and the status code of the response.
+type meteredSender struct {
+	client.Sender
+	metrics    *gatewayMetrics
+	statusCode int
+}
+
+func (s *meteredSender) Send(
+	ctx context.Context,
+	method string,
+	path string,
+	params url.Values,
+	headers http.Header,
+	body io.Reader,
+) (*http.Response, error) {
+	req := &countingReader{r: body}
+	if body == nil {
+		req.r = http.NoBody
+	}
+	resp, err := s.Sender.Send(ctx, method, path, params, headers, req)
+	s.metrics.requestSent(req.n)
+	if err != nil {
+		return resp, err
+	}
+
+	s.statusCode = resp.StatusCode
+	s.metrics.responseReceived(resp.StatusCode)
+	resp.Body = &countingReadCloser{
+		countingReader: countingReader{r: resp.Body},
+		closer:         resp.Body,
+		onClose:        s.metrics.responseRead,
+	}
+	return resp, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReadCloser struct {
+	countingReader
+	closer  io.Closer
+	onClose func(n int64)
+	once    sync.Once
+}
+
+func (c *countingReadCloser) Close() error {
+	c.once.Do(func() { c.onClose(c.n) })
+	return c.closer.Close()
+}
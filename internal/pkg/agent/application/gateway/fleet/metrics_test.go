@@ -0,0 +1,122 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+func TestGatewayMetrics(t *testing.T) {
+	metrics := newGatewayMetrics()
+	c := newTestingClient()
+	c.Answer(func(_ http.Header, body io.Reader) (*http.Response, error) {
+		_, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+		return wrapStrToResp(http.StatusOK, `{ "actions": [] }`), nil
+	})
+
+	sender := &meteredSender{Sender: c, metrics: metrics}
+	resp, err := sender.Send(context.Background(), "POST", "/checkin", nil, nil, bytes.NewBufferString("hello"))
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	<-c.received
+
+	metrics.checkin(200*time.Millisecond, sender.statusCode, nil)
+	metrics.checkin(2*time.Minute, 0, errors.New("connection refused"))
+	metrics.checkin(time.Second, 0, errCheckinInterrupted)
+	metrics.retrying(1, 90*time.Second)
+	metrics.breaker("open", 1, 5*time.Minute)
+
+	reg := monitoring.NewRegistry()
+	reg.Add(metricsName, metrics, monitoring.Reported)
+	snapshot := monitoring.CollectStructSnapshot(reg, monitoring.Full, false)
+
+	expected := map[string]interface{}{
+		metricsName: map[string]interface{}{
+			"checkins": map[string]interface{}{
+				"total":       int64(3),
+				"succeeded":   int64(1),
+				"interrupted": int64(1),
+				"failed": map[string]interface{}{
+					failureNetwork:         int64(1),
+					failureTimeout:         int64(0),
+					failureUnauthorized:    int64(0),
+					failureHTTPStatus:      int64(0),
+					failureInvalidResponse: int64(0),
+				},
+			},
+			"latency": map[string]interface{}{
+				"count":  int64(2),
+				"sum_ms": int64(120200),
+				"histogram": map[string]interface{}{
+					"le_100ms": int64(0),
+					"le_500ms": int64(1),
+					"le_1s":    int64(1),
+					"le_5s":    int64(1),
+					"le_30s":   int64(1),
+					"le_1m":    int64(1),
+					"le_5m":    int64(2),
+					"le_10m":   int64(2),
+					"le_inf":   int64(2),
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": int64(1),
+			},
+			"retry": map[string]interface{}{
+				"consecutive_failures": int64(1),
+				"backoff_ms":           int64(90000),
+			},
+			"circuit_breaker": map[string]interface{}{
+				"state":        "open",
+				"trips":        int64(1),
+				"cool_down_ms": int64(300000),
+			},
+			"bytes": map[string]interface{}{
+				"request_total":  int64(5),
+				"request_last":   int64(5),
+				"response_total": int64(17),
+				"response_last":  int64(17),
+			},
+		},
+	}
+	require.Equal(t, expected, snapshot)
+}
+
+func TestFailureReason(t *testing.T) {
+	testcases := []struct {
+		statusCode int
+		err        error
+		expected   string
+	}{
+		{0, errors.New("connection refused"), failureNetwork},
+		{0, fmt.Errorf("request failed: %w", context.DeadlineExceeded), failureTimeout},
+		{0, client.ErrInvalidAPIKey, failureUnauthorized},
+		{http.StatusUnauthorized, errors.New("unauthorized"), failureUnauthorized},
+		{http.StatusServiceUnavailable, errors.New("unavailable"), failureHTTPStatus},
+		{http.StatusOK, errors.New("fail to decode checkin response"), failureInvalidResponse},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.expected, func(t *testing.T) {
+			require.Equal(t, tc.expected, failureReason(tc.statusCode, tc.err))
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package ha pairs two agents in an active/standby pair, exactly one of them runs the inputs of
+// the policy while the other one runs none and takes over when the active agent disappears.
+package ha
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// leasePath is the path of the lease endpoint of the agents.
+	leasePath = "/ha/lease"
+
+	// maximum size of a claim, they are a few fields.
+	maxClaimSize = 4096
+)
+
+// claim is the state an agent sends to its peer when renewing the lease, the peer answers with
+// its own claim.
+type claim struct {
+	NodeID   string `json:"node_id"`
+	Priority int    `json:"priority"`
+	// Active is set when the agent runs the inputs.
+	Active bool `json:"active"`
+}
+
+// Pair coordinates the agent with its peer. Every renew interval it sends its claim to the peer
+// and decides whether it runs the inputs from the last claim received from the peer:
+//
+//   - without contact with the peer for the lease duration, the agent runs the inputs,
+//   - when only the peer runs the inputs, the agent runs none,
+//   - when both or none of them run the inputs, the preferred agent runs them.
+//
+// A running agent never preempts its peer, so a restarted agent stays standby.
+type Pair struct {
+	log        *logger.Logger
+	cfg        *configuration.HAConfig
+	clock      clock.Clock
+	nodeID     string
+	peerURL    string
+	client     *http.Client
+	tls        *tlscommon.TLSConfig
+	setStandby func(ctx context.Context, standby bool) error
+
+	mu       sync.Mutex
+	active   bool
+	started  time.Time
+	peer     claim
+	peerSeen time.Time
+}
+
+// New creates the pair of the agent, setStandby is called each time the agent starts or stops
+// running the inputs. The agent is standby until it runs.
+func New(log *logger.Logger, cfg *configuration.HAConfig, setStandby func(ctx context.Context, standby bool) error) (*Pair, error) {
+	if cfg.Peer == "" {
+		return nil, errors.New("the address of the peer is required")
+	}
+	if cfg.RenewInterval <= 0 || cfg.LeaseDuration <= cfg.RenewInterval {
+		return nil, fmt.Errorf("the lease duration %s must be longer than the renew interval %s", cfg.LeaseDuration, cfg.RenewInterval)
+	}
+	nodeID := cfg.NodeID
+	if nodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the host name as node ID: %w", err)
+		}
+		nodeID = hostname
+	}
+
+	scheme := "http"
+	var tlsCfg *tlscommon.TLSConfig
+	if cfg.TLS.IsEnabled() {
+		var err error
+		tlsCfg, err = tlscommon.LoadTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssl configuration: %w", err)
+		}
+		scheme = "https"
+	}
+	host, _, err := net.SplitHostPort(cfg.Peer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address of the peer %q: %w", cfg.Peer, err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsCfg != nil {
+		transport.TLSClientConfig = tlsCfg.BuildModuleClientConfig(host)
+	}
+
+	return &Pair{
+		log:        log,
+		cfg:        cfg,
+		clock:      clock.Real(),
+		nodeID:     nodeID,
+		peerURL:    scheme + "://" + cfg.Peer + leasePath,
+		client:     &http.Client{Transport: transport, Timeout: cfg.RenewInterval},
+		tls:        tlsCfg,
+		setStandby: setStandby,
+	}, nil
+}
+
+// Run listens for the lease requests of the peer and renews the lease until the context is done.
+func (p *Pair) Run(ctx context.Context) error {
+	lis, err := net.Listen("tcp", p.cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", p.cfg.Listen, err)
+	}
+	if p.tls != nil {
+		lis = tls.NewListener(lis, p.tls.BuildServerConfig(""))
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(leasePath, p.handleLease)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: p.cfg.RenewInterval}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.log.Errorw("The lease server of the active/standby pair stopped", "error.message", err)
+		}
+	}()
+	defer srv.Close()
+
+	p.log.Infof("Agent %s is standby until it reaches its peer %s or the lease of %s expires", p.nodeID, p.cfg.Peer, p.cfg.LeaseDuration)
+	p.run(ctx)
+	return nil
+}
+
+// run renews the lease every renew interval until the context is done.
+func (p *Pair) run(ctx context.Context) {
+	p.mu.Lock()
+	p.started = p.clock.Now()
+	p.mu.Unlock()
+
+	t := p.clock.NewTicker(p.cfg.RenewInterval)
+	defer t.Stop()
+	for {
+		p.renew(ctx)
+		p.decide(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C():
+		}
+	}
+}
+
+// renew sends the claim of the agent to the peer and records the claim it answers.
+func (p *Pair) renew(ctx context.Context) {
+	body, err := json.Marshal(p.claim())
+	if err != nil {
+		p.log.Errorw("Failed to encode the lease claim", "error.message", err)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.peerURL, bytes.NewReader(body))
+	if err != nil {
+		p.log.Errorw("Failed to create the lease request", "error.message", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.Token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		p.log.Debugw("Failed to renew the lease with the peer", "error.message", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		p.log.Warnf("The peer %s refused the lease request: %s", p.cfg.Peer, resp.Status)
+		return
+	}
+	var peer claim
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxClaimSize)).Decode(&peer); err != nil {
+		p.log.Warnw("Failed to decode the lease claim of the peer", "error.message", err)
+		return
+	}
+	p.record(peer)
+}
+
+// handleLease records the claim of the peer and answers with the claim of the agent.
+func (p *Pair) handleLease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if p.cfg.Token != "" {
+		expected := []byte("Bearer " + p.cfg.Token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+	var peer claim
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxClaimSize)).Decode(&peer); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if peer.NodeID == p.nodeID {
+		// both agents have the same node ID, the preferred agent can't be chosen
+		p.log.Errorf("The peer has the same node ID %s, set agent.ha.node_id", p.nodeID)
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	p.record(peer)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p.claim())
+}
+
+// claim returns the claim of the agent.
+func (p *Pair) claim() claim {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return claim{NodeID: p.nodeID, Priority: p.cfg.Priority, Active: p.active}
+}
+
+// record records a claim received from the peer.
+func (p *Pair) record(peer claim) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.peer = peer
+	p.peerSeen = p.clock.Now()
+}
+
+// decide decides whether the agent runs the inputs from the last claim of the peer, and
+// switches the agent when it changed.
+func (p *Pair) decide(ctx context.Context) {
+	p.mu.Lock()
+	now := p.clock.Now()
+	active := p.active
+	reason := ""
+	switch {
+	case p.peerSeen.IsZero() || now.Sub(p.peerSeen) >= p.cfg.LeaseDuration:
+		// the lease of the peer expired, or the peer was never reached since the start
+		if !active && now.Sub(p.started) >= p.cfg.LeaseDuration {
+			active = true
+			reason = fmt.Sprintf("no contact with the peer %s for %s", p.cfg.Peer, p.cfg.LeaseDuration)
+		}
+	case p.peer.Active && active:
+		// both run the inputs after they couldn't reach each other
+		if !p.preferred(p.peer) {
+			active = false
+			reason = fmt.Sprintf("the preferred peer %s runs the inputs", p.peer.NodeID)
+		}
+	case p.peer.Active:
+		if active {
+			active = false
+			reason = fmt.Sprintf("the peer %s runs the inputs", p.peer.NodeID)
+		}
+	case !active && p.preferred(p.peer):
+		active = true
+		reason = fmt.Sprintf("the peer %s is standby", p.peer.NodeID)
+	}
+	changed := active != p.active
+	p.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := p.setStandby(ctx, !active); err != nil {
+		// retried on the next renewal
+		p.log.Warnw("Failed to switch the role of the agent", "error.message", err)
+		return
+	}
+	p.mu.Lock()
+	p.active = active
+	p.mu.Unlock()
+	if active {
+		p.log.Infof("Agent %s is active and runs the inputs: %s", p.nodeID, reason)
+	} else {
+		p.log.Infof("Agent %s is standby and runs no inputs: %s", p.nodeID, reason)
+	}
+}
+
+// preferred returns true when the agent is preferred over the peer to run the inputs.
+func (p *Pair) preferred(peer claim) bool {
+	if p.cfg.Priority != peer.Priority {
+		return p.cfg.Priority > peer.Priority
+	}
+	return p.nodeID < peer.NodeID
+}
@@ -0,0 +1,178 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package ha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// testPair is a pair whose lease endpoint is served by an httptest server.
+type testPair struct {
+	*Pair
+	clock   *clock.Mock
+	srv     *httptest.Server
+	standby []bool
+}
+
+func newTestPair(t *testing.T, nodeID string, priority int) *testPair {
+	cfg := configuration.DefaultHAConfig()
+	cfg.Enabled = true
+	cfg.NodeID = nodeID
+	cfg.Priority = priority
+	cfg.Peer = "localhost:6792"
+	cfg.Token = "secret"
+
+	tp := &testPair{clock: clock.NewMock(time.Now())}
+	p, err := New(logger.NewWithoutConfig(nodeID), cfg, func(_ context.Context, standby bool) error {
+		tp.standby = append(tp.standby, standby)
+		return nil
+	})
+	require.NoError(t, err)
+	p.clock = tp.clock
+	p.started = tp.clock.Now()
+	tp.Pair = p
+	tp.srv = httptest.NewServer(http.HandlerFunc(p.handleLease))
+	t.Cleanup(tp.srv.Close)
+	return tp
+}
+
+// connect makes the pairs renew the lease with each other.
+func connect(a, b *testPair) {
+	a.peerURL = b.srv.URL + leasePath
+	b.peerURL = a.srv.URL + leasePath
+}
+
+// step renews the lease and decides the role of the pair.
+func (tp *testPair) step() {
+	tp.renew(context.Background())
+	tp.decide(context.Background())
+}
+
+func (tp *testPair) isActive() bool {
+	return tp.claim().Active
+}
+
+func TestPairElectsThePreferredAgent(t *testing.T) {
+	a := newTestPair(t, "a", 0)
+	b := newTestPair(t, "b", 0)
+	connect(a, b)
+
+	a.step()
+	b.step()
+	assert.True(t, a.isActive(), "lowest node ID should be active with equal priorities")
+	assert.False(t, b.isActive())
+	assert.Equal(t, []bool{false}, a.standby)
+	assert.Empty(t, b.standby, "standby agent should not be switched")
+
+	// the next renewals keep the roles
+	a.step()
+	b.step()
+	assert.True(t, a.isActive())
+	assert.False(t, b.isActive())
+
+	c := newTestPair(t, "c", 10)
+	d := newTestPair(t, "d", 0)
+	connect(c, d)
+	d.step()
+	c.step()
+	d.step()
+	assert.True(t, c.isActive(), "highest priority should be active")
+	assert.False(t, d.isActive())
+}
+
+func TestPairFailsOverWhenTheLeaseExpires(t *testing.T) {
+	a := newTestPair(t, "a", 0)
+	b := newTestPair(t, "b", 0)
+	connect(a, b)
+	a.step()
+	b.step()
+	require.True(t, a.isActive())
+
+	// the active agent disappears
+	a.srv.Close()
+	b.clock.Advance(b.cfg.LeaseDuration - time.Second)
+	b.step()
+	assert.False(t, b.isActive(), "standby agent should wait for the end of the lease")
+	b.clock.Advance(time.Second)
+	b.step()
+	assert.True(t, b.isActive(), "standby agent should take over once the lease expired")
+	assert.Equal(t, []bool{false}, b.standby)
+
+	// the restarted agent does not preempt its peer, even if it is preferred
+	restarted := newTestPair(t, "a", 0)
+	connect(restarted, b)
+	restarted.step()
+	b.step()
+	assert.False(t, restarted.isActive(), "restarted agent should stay standby")
+	assert.True(t, b.isActive())
+}
+
+func TestPairResolvesBothActive(t *testing.T) {
+	a := newTestPair(t, "a", 0)
+	b := newTestPair(t, "b", 0)
+
+	// both agents take over while they can't reach each other
+	a.clock.Advance(a.cfg.LeaseDuration)
+	b.clock.Advance(b.cfg.LeaseDuration)
+	a.decide(context.Background())
+	b.decide(context.Background())
+	require.True(t, a.isActive())
+	require.True(t, b.isActive())
+
+	// the preferred agent keeps the inputs once they reach each other again
+	connect(a, b)
+	b.step()
+	a.step()
+	assert.True(t, a.isActive())
+	assert.False(t, b.isActive())
+	assert.Equal(t, []bool{false, true}, b.standby)
+}
+
+func TestPairRejectsInvalidLeaseRequests(t *testing.T) {
+	a := newTestPair(t, "a", 0)
+	b := newTestPair(t, "b", 0)
+	b.cfg.Token = "other"
+	connect(a, b)
+
+	a.renew(context.Background())
+	b.renew(context.Background())
+	assert.True(t, a.peerSeen.IsZero(), "claim of a peer with another token should be rejected")
+	assert.True(t, b.peerSeen.IsZero(), "claim of a peer with another token should be rejected")
+
+	same := newTestPair(t, "a", 0)
+	connect(a, same)
+	a.renew(context.Background())
+	assert.True(t, a.peerSeen.IsZero(), "claim of a peer with the same node ID should be rejected")
+}
+
+func TestNewValidatesTheConfiguration(t *testing.T) {
+	setStandby := func(context.Context, bool) error { return nil }
+	log := logger.NewWithoutConfig("")
+
+	cfg := configuration.DefaultHAConfig()
+	_, err := New(log, cfg, setStandby)
+	assert.ErrorContains(t, err, "the address of the peer is required")
+
+	cfg.Peer = "localhost:6792"
+	cfg.LeaseDuration = cfg.RenewInterval
+	_, err = New(log, cfg, setStandby)
+	assert.ErrorContains(t, err, "must be longer than the renew interval")
+
+	cfg = configuration.DefaultHAConfig()
+	cfg.Peer = "localhost"
+	_, err = New(log, cfg, setStandby)
+	assert.ErrorContains(t, err, "invalid address of the peer")
+}
@@ -0,0 +1,328 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package handoff hands off the inputs of an agent running as a Kubernetes DaemonSet when its
+// node is cordoned to be drained.
+package handoff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// configMapPrefix prefixes the name of the node in the name of the ConfigMap of the handoff.
+	configMapPrefix = "elastic-agent-handoff-"
+	// configMapKey is the key of the record of the handoff in the ConfigMap.
+	configMapKey = "handoff.json"
+
+	// reasons of the Kubernetes events of the handoff.
+	reasonHandedOff = "ElasticAgentHandedOff"
+	reasonResumed   = "ElasticAgentResumed"
+
+	// monitoringSuffix is the suffix of the IDs of the components monitoring the agent, they keep
+	// running once the inputs are handed off.
+	monitoringSuffix = "-monitoring"
+
+	// interval between two checks of the components stopping.
+	drainCheckPeriod = 500 * time.Millisecond
+)
+
+// Record is the record of a handoff, published in the ConfigMap of the node.
+type Record struct {
+	Node    string    `json:"node"`
+	AgentID string    `json:"agent_id"`
+	Time    time.Time `json:"time"`
+	// Drained is false when the inputs or the shippers did not stop before the timeout, the
+	// events they still queued may be lost.
+	Drained bool          `json:"drained"`
+	Inputs  []InputCursor `json:"inputs"`
+}
+
+// InputCursor is the position an input reached when it was handed off.
+type InputCursor struct {
+	ComponentID string `json:"component_id"`
+	UnitID      string `json:"unit_id"`
+	InputType   string `json:"input_type"`
+	// Cursor is the last payload reported by the unit of the input, the inputs report their
+	// position in it.
+	Cursor map[string]interface{} `json:"cursor,omitempty"`
+}
+
+// Watcher checks the cordon of the node of the agent and hands off its inputs when the node is
+// cordoned: the inputs are stopped, the shippers flush their queues and the cursors of the
+// inputs are published in a ConfigMap and in a Kubernetes event. The inputs are started again
+// when the node is uncordoned.
+type Watcher struct {
+	log          *logger.Logger
+	cfg          *configuration.KubernetesHandoffConfig
+	client       k8s.Interface
+	node         string
+	namespace    string
+	agentID      string
+	state        func() coordinator.State
+	setHandedOff func(ctx context.Context, handedOff bool) error
+
+	handedOff bool
+}
+
+// New creates a watcher of the node of the agent, setHandedOff is called when the inputs must be
+// stopped or started again.
+func New(log *logger.Logger, cfg *configuration.KubernetesHandoffConfig, agentID string, state func() coordinator.State, setHandedOff func(ctx context.Context, handedOff bool) error) (*Watcher, error) {
+	client, err := kubernetes.GetKubernetesClient(cfg.KubeConfig, cfg.KubeClientOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the kubernetes client: %w", err)
+	}
+	node, err := kubernetes.DiscoverKubernetesNode(log, &kubernetes.DiscoverKubernetesNodeParams{
+		ConfigHost:  cfg.Node,
+		Client:      client,
+		IsInCluster: kubernetes.IsInCluster(cfg.KubeConfig),
+		HostUtils:   &kubernetes.DefaultDiscoveryUtils{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace, err = kubernetes.InClusterNamespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the namespace of the agent, set agent.kubernetes_handoff.namespace: %w", err)
+		}
+	}
+	return newWatcher(log, cfg, client, node, namespace, agentID, state, setHandedOff), nil
+}
+
+func newWatcher(log *logger.Logger, cfg *configuration.KubernetesHandoffConfig, client k8s.Interface, node string, namespace string, agentID string, state func() coordinator.State, setHandedOff func(ctx context.Context, handedOff bool) error) *Watcher {
+	return &Watcher{
+		log:          log,
+		cfg:          cfg,
+		client:       client,
+		node:         node,
+		namespace:    namespace,
+		agentID:      agentID,
+		state:        state,
+		setHandedOff: setHandedOff,
+	}
+}
+
+// Run checks the cordon of the node every interval until the context is done.
+func (w *Watcher) Run(ctx context.Context) {
+	w.log.Infof("Handing off the inputs when the node %s is cordoned", w.node)
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+	for {
+		w.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// check hands off the inputs when the node is cordoned, and starts them again when it is
+// uncordoned.
+func (w *Watcher) check(ctx context.Context) {
+	node, err := w.client.CoreV1().Nodes().Get(ctx, w.node, metav1.GetOptions{})
+	if err != nil {
+		w.log.Warnw("Failed to get the node of the agent", "error.message", err)
+		return
+	}
+	cordoned := node.Spec.Unschedulable
+	switch {
+	case cordoned && !w.handedOff:
+		w.handOff(ctx, node)
+	case !cordoned && w.handedOff:
+		w.resume(ctx, node)
+	}
+}
+
+// handOff stops the inputs, waits for them and for the shippers to stop and publishes their
+// cursors.
+func (w *Watcher) handOff(ctx context.Context, node *corev1.Node) {
+	w.log.Infof("Node %s is cordoned, handing off the inputs", w.node)
+	cursors := make(map[string]InputCursor)
+	collectCursors(w.state(), cursors)
+	if err := w.setHandedOff(ctx, true); err != nil {
+		// retried on the next check
+		w.log.Warnw("Failed to stop the inputs", "error.message", err)
+		return
+	}
+	w.handedOff = true
+
+	drained := w.waitDrained(ctx, cursors)
+	if !drained {
+		w.log.Warnf("Inputs and shippers not stopped after %s, handing off anyway, the events still queued may be lost", w.cfg.Timeout)
+	}
+	record := Record{
+		Node:    w.node,
+		AgentID: w.agentID,
+		Time:    time.Now().UTC(),
+		Drained: drained,
+		Inputs:  make([]InputCursor, 0, len(cursors)),
+	}
+	for _, c := range cursors {
+		record.Inputs = append(record.Inputs, c)
+	}
+	sort.Slice(record.Inputs, func(i, j int) bool {
+		return record.Inputs[i].UnitID < record.Inputs[j].UnitID
+	})
+	if err := w.publish(ctx, node, record); err != nil {
+		w.log.Errorw("Failed to publish the handoff of the inputs", "error.message", err)
+		return
+	}
+	w.log.Infof("Handed off %d inputs of node %s in ConfigMap %s/%s", len(record.Inputs), w.node, w.namespace, configMapPrefix+w.node)
+}
+
+// resume starts the inputs again once the node is uncordoned.
+func (w *Watcher) resume(ctx context.Context, node *corev1.Node) {
+	if err := w.setHandedOff(ctx, false); err != nil {
+		// retried on the next check
+		w.log.Warnw("Failed to start the inputs again", "error.message", err)
+		return
+	}
+	w.handedOff = false
+	w.log.Infof("Node %s is uncordoned, the inputs run again", w.node)
+	if err := w.event(ctx, node, reasonResumed, "Elastic Agent runs the inputs again"); err != nil {
+		w.log.Warnw("Failed to publish the resumption of the inputs", "error.message", err)
+	}
+}
+
+// waitDrained waits for the inputs and the shippers to stop, at most the timeout, and collects
+// the cursors they report while stopping. It returns false on timeout.
+func (w *Watcher) waitDrained(ctx context.Context, cursors map[string]InputCursor) bool {
+	timeout := time.NewTimer(w.cfg.Timeout)
+	defer timeout.Stop()
+	t := time.NewTicker(drainCheckPeriod)
+	defer t.Stop()
+	for {
+		state := w.state()
+		collectCursors(state, cursors)
+		if !hasWorkload(state) {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timeout.C:
+			return false
+		case <-t.C:
+		}
+	}
+}
+
+// publish writes the record of the handoff in the ConfigMap of the node and reports it in a
+// Kubernetes event.
+func (w *Watcher) publish(ctx context.Context, node *corev1.Node, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode the handoff: %w", err)
+	}
+	configMaps := w.client.CoreV1().ConfigMaps(w.namespace)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapPrefix + w.node,
+			Namespace: w.namespace,
+			Labels:    map[string]string{"k8s-app": "elastic-agent"},
+		},
+		Data: map[string]string{configMapKey: string(data)},
+	}
+	existing, err := configMaps.Get(ctx, cm.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+	case err == nil:
+		existing.Data = cm.Data
+		_, err = configMaps.Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write ConfigMap %s/%s: %w", w.namespace, cm.Name, err)
+	}
+
+	msg := fmt.Sprintf("Elastic Agent handed off %d inputs, their cursors are in ConfigMap %s/%s", len(record.Inputs), w.namespace, cm.Name)
+	if !record.Drained {
+		msg += ", the shippers did not flush their queues in time"
+	}
+	return w.event(ctx, node, reasonHandedOff, msg)
+}
+
+// event reports a Kubernetes event about the node.
+func (w *Watcher) event(ctx context.Context, node *corev1.Node, reason string, msg string) error {
+	now := metav1.Now()
+	ev := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			// named like the events of client-go
+			Name:      fmt.Sprintf("%s.%x", node.Name, now.UnixNano()),
+			Namespace: w.namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "Node",
+			APIVersion: "v1",
+			Name:       node.Name,
+			UID:        node.UID,
+		},
+		Reason:         reason,
+		Message:        msg,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: "elastic-agent", Host: w.node},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := w.client.CoreV1().Events(w.namespace).Create(ctx, ev, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create the %s event: %w", reason, err)
+	}
+	return nil
+}
+
+// collectCursors records the last payload reported by the units of the inputs.
+func collectCursors(state coordinator.State, cursors map[string]InputCursor) {
+	for _, comp := range state.Components {
+		if strings.HasSuffix(comp.Component.ID, monitoringSuffix) {
+			continue
+		}
+		for key, unit := range comp.State.Units {
+			if key.UnitType != client.UnitTypeInput {
+				continue
+			}
+			c, ok := cursors[key.UnitID]
+			if !ok {
+				c = InputCursor{
+					ComponentID: comp.Component.ID,
+					UnitID:      key.UnitID,
+					InputType:   comp.Component.InputType,
+				}
+			}
+			if len(unit.Payload) > 0 {
+				c.Cursor = unit.Payload
+			}
+			cursors[key.UnitID] = c
+		}
+	}
+}
+
+// hasWorkload returns true while components other than the monitoring ones run.
+func hasWorkload(state coordinator.State) bool {
+	for _, comp := range state.Components {
+		if !strings.HasSuffix(comp.Component.ID, monitoringSuffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package handoff
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// fakeAgent simulates the coordinator, the inputs stop a few checks after they are handed off.
+type fakeAgent struct {
+	mu        sync.Mutex
+	handedOff []bool
+	// stopping is the number of checks the inputs take to stop, -1 never stops them.
+	stopping int
+	offset   int
+}
+
+func (a *fakeAgent) setHandedOff(_ context.Context, handedOff bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handedOff = append(a.handedOff, handedOff)
+	return nil
+}
+
+func (a *fakeAgent) state() coordinator.State {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	monitoring := testComponent("filestream-monitoring", "filestream", "filestream-monitoring-agent", nil)
+	if len(a.handedOff) > 0 && a.handedOff[len(a.handedOff)-1] {
+		if a.stopping == 0 {
+			return coordinator.State{Components: []runtime.ComponentComponentState{monitoring}}
+		}
+		if a.stopping > 0 {
+			a.stopping--
+		}
+		// the input reports its last position while stopping
+		a.offset = 2048
+	} else {
+		a.offset = 1024
+	}
+	return coordinator.State{Components: []runtime.ComponentComponentState{
+		monitoring,
+		testComponent("filestream-default", "filestream", "filestream-default-logs", map[string]interface{}{"offset": a.offset}),
+	}}
+}
+
+func testComponent(id string, inputType string, unitID string, payload map[string]interface{}) runtime.ComponentComponentState {
+	return runtime.ComponentComponentState{
+		Component: component.Component{ID: id, InputType: inputType},
+		State: runtime.ComponentState{
+			State: client.UnitStateHealthy,
+			Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+				{UnitType: client.UnitTypeInput, UnitID: unitID}: {State: client.UnitStateHealthy, Payload: payload},
+				{UnitType: client.UnitTypeOutput, UnitID: id}:    {State: client.UnitStateHealthy},
+			},
+		},
+	}
+}
+
+func newTestWatcher(t *testing.T, agent *fakeAgent, timeout time.Duration) (*Watcher, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+	cfg := configuration.DefaultKubernetesHandoffConfig()
+	cfg.Timeout = timeout
+	return newWatcher(logger.NewWithoutConfig(""), cfg, clientset, "node-1", "kube-system", "agent-1", agent.state, agent.setHandedOff), clientset
+}
+
+func setCordon(t *testing.T, clientset *fake.Clientset, cordoned bool) {
+	nodes := clientset.CoreV1().Nodes()
+	node, err := nodes.Get(context.Background(), "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	node.Spec.Unschedulable = cordoned
+	_, err = nodes.Update(context.Background(), node, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+func readRecord(t *testing.T, clientset *fake.Clientset) Record {
+	cm, err := clientset.CoreV1().ConfigMaps("kube-system").Get(context.Background(), "elastic-agent-handoff-node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(cm.Data[configMapKey]), &record))
+	return record
+}
+
+func eventReasons(t *testing.T, clientset *fake.Clientset) []string {
+	events, err := clientset.CoreV1().Events("kube-system").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var reasons []string
+	for _, ev := range events.Items {
+		assert.Equal(t, "node-1", ev.InvolvedObject.Name)
+		reasons = append(reasons, ev.Reason)
+	}
+	return reasons
+}
+
+func TestWatcherHandsOffTheInputsOfACordonedNode(t *testing.T) {
+	ctx := context.Background()
+	agent := &fakeAgent{stopping: 2}
+	w, clientset := newTestWatcher(t, agent, time.Minute)
+
+	w.check(ctx)
+	assert.Empty(t, agent.handedOff, "inputs of a schedulable node should not be handed off")
+
+	setCordon(t, clientset, true)
+	w.check(ctx)
+	assert.Equal(t, []bool{true}, agent.handedOff)
+
+	record := readRecord(t, clientset)
+	assert.Equal(t, "node-1", record.Node)
+	assert.Equal(t, "agent-1", record.AgentID)
+	assert.True(t, record.Drained)
+	require.Len(t, record.Inputs, 1, "monitoring inputs should not be handed off")
+	assert.Equal(t, InputCursor{
+		ComponentID: "filestream-default",
+		UnitID:      "filestream-default-logs",
+		InputType:   "filestream",
+		Cursor:      map[string]interface{}{"offset": float64(2048)},
+	}, record.Inputs[0], "cursor should be the last one reported while stopping")
+	assert.Equal(t, []string{reasonHandedOff}, eventReasons(t, clientset))
+
+	// the inputs are handed off once
+	w.check(ctx)
+	assert.Equal(t, []bool{true}, agent.handedOff)
+
+	setCordon(t, clientset, false)
+	w.check(ctx)
+	assert.Equal(t, []bool{true, false}, agent.handedOff)
+	assert.ElementsMatch(t, []string{reasonHandedOff, reasonResumed}, eventReasons(t, clientset))
+}
+
+func TestWatcherHandsOffAfterTheTimeout(t *testing.T) {
+	agent := &fakeAgent{stopping: -1}
+	w, clientset := newTestWatcher(t, agent, 2*drainCheckPeriod)
+
+	setCordon(t, clientset, true)
+	w.check(context.Background())
+	assert.Equal(t, []bool{true}, agent.handedOff)
+
+	record := readRecord(t, clientset)
+	assert.False(t, record.Drained, "inputs not stopped before the timeout should not be drained")
+	require.Len(t, record.Inputs, 1)
+	assert.Equal(t, map[string]interface{}{"offset": float64(2048)}, record.Inputs[0].Cursor)
+}
@@ -29,9 +29,13 @@ const maxRetriesloadAgentInfo = 5
 
 type persistentAgentInfo struct {
 	ID             string                                 `json:"id" yaml:"id" config:"id"`
+	ReplaceToken   string                                 `json:"replace_token,omitempty" yaml:"replace_token,omitempty" config:"replace_token,omitempty"`
+	HostID         string                                 `json:"host_id,omitempty" yaml:"host_id,omitempty" config:"host_id,omitempty"`
 	Headers        map[string]string                      `json:"headers" yaml:"headers" config:"headers"`
 	LogLevel       string                                 `json:"logging.level,omitempty" yaml:"logging.level,omitempty" config:"logging.level,omitempty"`
 	MonitoringHTTP *monitoringConfig.MonitoringHTTPConfig `json:"monitoring.http,omitempty" yaml:"monitoring.http,omitempty" config:"monitoring.http,omitempty"`
+	Labels         map[string]string                      `json:"labels,omitempty" yaml:"labels,omitempty" config:"labels,omitempty"`
+	Tags           []string                               `json:"tags,omitempty" yaml:"tags,omitempty" config:"tags,omitempty"`
 }
 
 type ioStore interface {
@@ -17,6 +17,11 @@ type AgentInfo struct {
 	// esHeaders will be injected into the headers field of any elasticsearch
 	// output created by this agent (see component.toIntermediate).
 	esHeaders map[string]string
+
+	// labels and tags are set by the user with the metadata and tags commands
+	// and reported to Fleet in the local metadata.
+	labels map[string]string
+	tags   []string
 }
 
 // NewAgentInfoWithLog creates a new agent information.
@@ -35,6 +40,8 @@ func NewAgentInfoWithLog(level string, createAgentID bool) (*AgentInfo, error) {
 		agentID:   agentInfo.ID,
 		logLevel:  agentInfo.LogLevel,
 		esHeaders: agentInfo.Headers,
+		labels:    agentInfo.Labels,
+		tags:      agentInfo.Tags,
 	}, nil
 }
 
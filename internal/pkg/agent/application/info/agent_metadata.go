@@ -24,6 +24,12 @@ type ECSMeta struct {
 	Elastic *ElasticECSMeta `json:"elastic"`
 	Host    *HostECSMeta    `json:"host"`
 	OS      *SystemECSMeta  `json:"os"`
+	// Labels are the key/value pairs set with the metadata command (e.g. rack, site, owner).
+	Labels map[string]string `json:"labels,omitempty"`
+	// Tags are the tags set with the tags command.
+	Tags []string `json:"tags,omitempty"`
+	// Custom is the output of the metadata hooks, by hook name.
+	Custom map[string]interface{} `json:"custom,omitempty"`
 }
 
 // ElasticECSMeta is a collection of elastic vendor metadata in ECS compliant object form.
@@ -162,9 +168,9 @@ func (i *AgentInfo) ECSMetadata(l *logger.Logger) (*ECSMeta, error) {
 				Version:       release.Version(),
 				Snapshot:      release.Snapshot(),
 				BuildOriginal: release.Info().String(),
-				// only upgradeable if running from Agent installer and running under the
+				// only upgradeable if running from Agent installer or a system package and running under the
 				// control of the system supervisor (or built specifically with upgrading enabled)
-				Upgradeable: release.Upgradeable() || (RunningInstalled() && RunningUnderSupervisor()),
+				Upgradeable: release.Upgradeable() || ((RunningInstalled() || InstalledPackageManager() != PackageManagerNone) && RunningUnderSupervisor()),
 				LogLevel:    i.LogLevel(),
 			},
 		},
@@ -186,6 +192,9 @@ func (i *AgentInfo) ECSMetadata(l *logger.Logger) (*ECSMeta, error) {
 			Name:     info.OS.Name,
 			FullName: getFullOSName(info),
 		},
+
+		Labels: i.labels,
+		Tags:   i.tags,
 	}, nil
 }
 
@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+
+package info
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+func detectPackageManager() PackageManager {
+	if runtime.GOOS != "linux" {
+		return PackageManagerNone
+	}
+
+	binaryName := paths.BinaryName
+
+	// NOTE searching for english words might not be a great idea as far as portability goes.
+	// list all installed packages then search for paths.BinaryName?
+	// dpkg is strange as the remove and purge processes leads to the package bing isted after a remove, but not after a purge
+
+	// check debian based systems (or systems that use dpkg)
+	// If the package has been installed, the status starts with "install"
+	// If the package has been removed (but not pruged) status starts with "deinstall"
+	// If purged or never installed, rc is 1
+	if _, err := exec.Command("which", "dpkg-query").Output(); err == nil {
+		out, err := exec.Command("dpkg-query", "-W", "-f", "${Status}", binaryName).Output()
+		if err != nil {
+			return PackageManagerNone
+		}
+		if strings.HasPrefix(string(out), "deinstall") {
+			return PackageManagerNone
+		}
+		return PackageManagerDEB
+	}
+
+	// check rhel and sles based systems (or systems that use rpm)
+	// if package has been installed the query will returns the list of associated files.
+	// otherwise if uninstalled, or has never been installled status ends with "not installed"
+	if _, err := exec.Command("which", "rpm").Output(); err == nil {
+		out, err := exec.Command("rpm", "-q", binaryName, "--state").Output()
+		if err != nil {
+			return PackageManagerNone
+		}
+		if strings.HasSuffix(string(out), "not installed") {
+			return PackageManagerNone
+		}
+		return PackageManagerRPM
+	}
+
+	return PackageManagerNone
+}
@@ -0,0 +1,13 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package info
+
+// detectPackageManager is used for unix based systems to see if the Elastic-Agent was installed through a package manager.
+// returns PackageManagerNone
+func detectPackageManager() PackageManager {
+	return PackageManagerNone
+}
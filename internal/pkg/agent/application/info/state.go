@@ -7,6 +7,7 @@ package info
 import (
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 )
@@ -17,6 +18,23 @@ import (
 // the same topPath folder is an installed Agent.
 const MarkerFileName = ".installed"
 
+// PackageManager identifies the system package manager that installed the Elastic Agent.
+type PackageManager string
+
+const (
+	// PackageManagerNone is returned when the Elastic Agent was not installed through a package manager.
+	PackageManagerNone PackageManager = ""
+	// PackageManagerDEB is returned when the Elastic Agent was installed from a DEB package.
+	PackageManagerDEB PackageManager = "deb"
+	// PackageManagerRPM is returned when the Elastic Agent was installed from a RPM package.
+	PackageManagerRPM PackageManager = "rpm"
+)
+
+var (
+	packageManager     PackageManager
+	packageManagerOnce sync.Once
+)
+
 // RunningInstalled returns true when executing Agent is the installed Agent.
 func RunningInstalled() bool {
 	// Check if install marker created by `elastic-agent install` exists
@@ -36,3 +54,12 @@ func CreateInstallMarker(topPath string) error {
 
 	return nil
 }
+
+// InstalledPackageManager returns the package manager that installed the Elastic Agent (deb/rpm)
+// or PackageManagerNone. The package manager is only queried once per process.
+func InstalledPackageManager() PackageManager {
+	packageManagerOnce.Do(func() {
+		packageManager = detectPackageManager()
+	})
+	return packageManager
+}
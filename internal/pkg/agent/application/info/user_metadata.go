@@ -0,0 +1,113 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package info
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+)
+
+// UserMetadata is the metadata set by the user on the agent after its enrollment, it is
+// persisted with the agent information and reported to Fleet at each check-in.
+type UserMetadata struct {
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Tags   []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// LoadUserMetadata returns the metadata set by the user.
+func LoadUserMetadata() (*UserMetadata, error) {
+	ai, err := loadAgentInfoWithBackoff(false, defaultLogLevel, false)
+	if err != nil {
+		return nil, err
+	}
+	return &UserMetadata{Labels: ai.Labels, Tags: ai.Tags}, nil
+}
+
+// SetLabels adds the labels to the agent metadata, replacing the value of the existing keys.
+func SetLabels(labels map[string]string) error {
+	return updateUserMetadata(func(ai *persistentAgentInfo) {
+		if ai.Labels == nil {
+			ai.Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			ai.Labels[k] = v
+		}
+	})
+}
+
+// UnsetLabels removes the labels with the keys from the agent metadata.
+func UnsetLabels(keys ...string) error {
+	return updateUserMetadata(func(ai *persistentAgentInfo) {
+		for _, k := range keys {
+			delete(ai.Labels, k)
+		}
+	})
+}
+
+// AddTags adds the tags to the agent metadata, tags already present are ignored.
+func AddTags(tags ...string) error {
+	return updateUserMetadata(func(ai *persistentAgentInfo) {
+		ai.Tags = uniqueSorted(append(ai.Tags, tags...))
+	})
+}
+
+// RemoveTags removes the tags from the agent metadata.
+func RemoveTags(tags ...string) error {
+	return updateUserMetadata(func(ai *persistentAgentInfo) {
+		remove := make(map[string]bool, len(tags))
+		for _, t := range tags {
+			remove[t] = true
+		}
+		kept := ai.Tags[:0]
+		for _, t := range ai.Tags {
+			if !remove[t] {
+				kept = append(kept, t)
+			}
+		}
+		ai.Tags = kept
+	})
+}
+
+// updateUserMetadata applies the update to the agent information persisted on disk, the
+// lock of the agent configuration is held from the load to the save.
+func updateUserMetadata(update func(*persistentAgentInfo)) error {
+	idLock := paths.AgentConfigFileLock()
+	if err := idLock.TryLock(); err != nil {
+		return err
+	}
+	//nolint:errcheck // keeping the same behavior, and making linter happy
+	defer idLock.Unlock()
+
+	diskStore := storage.NewEncryptedDiskStore(paths.AgentConfigFile())
+	ai, err := getInfoFromStore(diskStore, defaultLogLevel)
+	if err != nil {
+		return fmt.Errorf("could not get agent info from store: %w", err)
+	}
+
+	update(ai)
+	if len(ai.Labels) == 0 {
+		ai.Labels = nil
+	}
+	if len(ai.Tags) == 0 {
+		ai.Tags = nil
+	}
+	return updateAgentInfo(diskStore, ai)
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
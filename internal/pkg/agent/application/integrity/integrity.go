@@ -0,0 +1,138 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package integrity
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+// ManifestFileName is the name of the file written into the versioned home directory
+// at install and upgrade time that holds the expected hash of the agent binary.
+const ManifestFileName = ".elastic-agent.binary.manifest"
+
+// ErrManifestNotFound is returned by Check when no manifest exists for the home directory.
+// This is expected for agents installed before the manifest was introduced.
+var ErrManifestNotFound = errors.New("binary integrity manifest not found")
+
+// Manifest describes the expected state of the agent binary.
+type Manifest struct {
+	Binary    string    `yaml:"binary"`
+	SHA512    string    `yaml:"sha512"`
+	CreatedOn time.Time `yaml:"created_on"`
+}
+
+// MismatchError is returned when the agent binary does not match the hash recorded in the manifest.
+type MismatchError struct {
+	File     string
+	Expected string
+	Computed string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("binary integrity check failed for %s: expected sha512 %s, computed %s", e.File, e.Expected, e.Computed)
+}
+
+// ManifestPath returns the path of the manifest for the provided versioned home directory.
+func ManifestPath(homePath string) string {
+	return filepath.Join(homePath, ManifestFileName)
+}
+
+// WriteManifest computes the hash of the agent binary inside of homePath and
+// writes the manifest next to it.
+func WriteManifest(homePath string) error {
+	binaryPath := paths.BinaryPath(homePath, paths.BinaryName)
+	hash, err := hashFile(binaryPath)
+	if err != nil {
+		return errors.New(err, "failed to hash agent binary", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, binaryPath))
+	}
+
+	rel, err := filepath.Rel(homePath, binaryPath)
+	if err != nil {
+		return err
+	}
+	manifest := Manifest{
+		Binary:    filepath.ToSlash(rel),
+		SHA512:    hash,
+		CreatedOn: time.Now().UTC(),
+	}
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return errors.New(err, "failed to marshal binary integrity manifest", errors.TypeConfig)
+	}
+
+	manifestPath := ManifestPath(homePath)
+	if err := os.WriteFile(manifestPath, data, 0600); err != nil {
+		return errors.New(err, "failed to write binary integrity manifest", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, manifestPath))
+	}
+	return nil
+}
+
+// LoadManifest reads the manifest from the provided versioned home directory.
+func LoadManifest(homePath string) (*Manifest, error) {
+	manifestPath := ManifestPath(homePath)
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrManifestNotFound
+		}
+		return nil, errors.New(err, "failed to read binary integrity manifest", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, manifestPath))
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.New(err, "failed to parse binary integrity manifest", errors.TypeConfig, errors.M(errors.MetaKeyPath, manifestPath))
+	}
+	return &manifest, nil
+}
+
+// Check verifies the agent binary inside of homePath against its manifest.
+//
+// Returns ErrManifestNotFound when the manifest does not exist and a *MismatchError
+// when the hash of the binary differs from the one recorded in the manifest.
+func Check(homePath string) error {
+	manifest, err := LoadManifest(homePath)
+	if err != nil {
+		return err
+	}
+
+	binaryPath := filepath.Join(homePath, filepath.FromSlash(manifest.Binary))
+	hash, err := hashFile(binaryPath)
+	if err != nil {
+		return errors.New(err, "failed to hash agent binary", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, binaryPath))
+	}
+	if hash != manifest.SHA512 {
+		return &MismatchError{
+			File:     binaryPath,
+			Expected: manifest.SHA512,
+			Computed: hash,
+		}
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha512.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
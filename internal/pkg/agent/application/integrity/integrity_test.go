@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package integrity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+func writeBinary(t *testing.T, home string, content string) {
+	t.Helper()
+	binaryPath := paths.BinaryPath(home, paths.BinaryName)
+	require.NoError(t, os.MkdirAll(filepath.Dir(binaryPath), 0755))
+	require.NoError(t, os.WriteFile(binaryPath, []byte(content), 0755))
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("manifest not found", func(t *testing.T) {
+		home := t.TempDir()
+		writeBinary(t, home, "agent binary")
+
+		err := Check(home)
+		assert.ErrorIs(t, err, ErrManifestNotFound)
+	})
+
+	t.Run("binary matches", func(t *testing.T) {
+		home := t.TempDir()
+		writeBinary(t, home, "agent binary")
+		require.NoError(t, WriteManifest(home))
+
+		assert.NoError(t, Check(home))
+	})
+
+	t.Run("binary modified", func(t *testing.T) {
+		home := t.TempDir()
+		writeBinary(t, home, "agent binary")
+		require.NoError(t, WriteManifest(home))
+		writeBinary(t, home, "tampered binary")
+
+		err := Check(home)
+		var mismatchErr *MismatchError
+		require.True(t, errors.As(err, &mismatchErr), "expected MismatchError, got %v", err)
+		assert.NotEqual(t, mismatchErr.Expected, mismatchErr.Computed)
+	})
+
+	t.Run("binary missing", func(t *testing.T) {
+		home := t.TempDir()
+		writeBinary(t, home, "agent binary")
+		require.NoError(t, WriteManifest(home))
+		require.NoError(t, os.Remove(paths.BinaryPath(home, paths.BinaryName)))
+
+		err := Check(home)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrManifestNotFound)
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+// period of the CPU quota of the cgroup in microseconds, the default of the kernel.
+const cgroupCPUPeriod = 100000
+
+// cgroupRoot is the mount point of the cgroup v2 hierarchy, it is changed by the tests.
+var cgroupRoot = "/sys/fs/cgroup"
+
+// procSelfCgroup lists the cgroups of the agent process, it is changed by the tests.
+var procSelfCgroup = "/proc/self/cgroup"
+
+// placeInCgroup creates the cgroup as a child of the cgroup of the agent, moves the agent process
+// in it and sets its limits.
+//
+// The cgroup of the agent is the one delegated to it, like the cgroup of its systemd service, so
+// the agent does not need to write outside of it. A cgroup v2 with processes cannot enable the
+// controllers of its children, so the agent moves itself to the child before enabling them.
+func placeInCgroup(cfg configuration.CgroupLimitsConfig) error {
+	if cfg.Path == "" {
+		return errors.New("the path of the cgroup is empty")
+	}
+	current, err := currentCgroup()
+	if err != nil {
+		return err
+	}
+	parent := filepath.Join(cgroupRoot, current)
+	path := filepath.Join(parent, filepath.Clean("/"+cfg.Path))
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	if err := writeCgroupFile(path, "cgroup.procs", strconv.Itoa(os.Getpid())); err != nil {
+		return err
+	}
+
+	var controllers []string
+	if cfg.MemoryMax > 0 {
+		controllers = append(controllers, "+memory")
+	}
+	if cfg.CPUs > 0 {
+		controllers = append(controllers, "+cpu")
+	}
+	if len(controllers) == 0 {
+		return nil
+	}
+	// the controllers of a cgroup are enabled by its parent
+	if err := writeCgroupFile(filepath.Dir(path), "cgroup.subtree_control", strings.Join(controllers, " ")); err != nil {
+		return err
+	}
+
+	if cfg.MemoryMax > 0 {
+		if err := writeCgroupFile(path, "memory.max", strconv.FormatInt(cfg.MemoryMax, 10)); err != nil {
+			return err
+		}
+	}
+	if cfg.CPUs > 0 {
+		quota := int64(cfg.CPUs * cgroupCPUPeriod)
+		if err := writeCgroupFile(path, "cpu.max", fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentCgroup returns the path of the cgroup v2 of the agent relative to the root of the hierarchy.
+func currentCgroup() (string, error) {
+	content, err := os.ReadFile(procSelfCgroup)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the cgroup of the agent: %w", err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// the cgroup v2 hierarchy has the ID 0 and no controllers: "0::/system.slice/elastic-agent.service"
+		if strings.HasPrefix(line, "0::") {
+			return filepath.Clean("/" + strings.TrimPrefix(line, "0::")), nil
+		}
+	}
+	return "", errors.New("the agent is not in a cgroup v2")
+}
+
+func writeCgroupFile(dir string, name string, value string) error {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %q to %s: %w", value, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package limits
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+func TestPlaceInCgroup(t *testing.T) {
+	root := t.TempDir()
+	defer func(prev string) { cgroupRoot = prev }(cgroupRoot)
+	cgroupRoot = root
+	defer func(prev string) { procSelfCgroup = prev }(procSelfCgroup)
+	procSelfCgroup = filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(procSelfCgroup, []byte("0::/system.slice/elastic-agent.service\n"), 0644))
+
+	assert.Error(t, placeInCgroup(configuration.CgroupLimitsConfig{Enabled: true}))
+
+	err := placeInCgroup(configuration.CgroupLimitsConfig{
+		Enabled:   true,
+		Path:      "../../elastic-agent",
+		MemoryMax: 512 * 1024 * 1024,
+		CPUs:      0.5,
+	})
+	require.NoError(t, err)
+
+	read := func(path ...string) string {
+		content, err := os.ReadFile(filepath.Join(append([]string{root, "system.slice", "elastic-agent.service"}, path...)...))
+		require.NoError(t, err)
+		return string(content)
+	}
+	assert.Equal(t, "+memory +cpu", read("cgroup.subtree_control"), "the controllers enabled in the cgroup of the agent")
+	assert.Equal(t, "536870912", read("elastic-agent", "memory.max"))
+	assert.Equal(t, "50000 100000", read("elastic-agent", "cpu.max"))
+	assert.Equal(t, strconv.Itoa(os.Getpid()), read("elastic-agent", "cgroup.procs"))
+	assert.NoDirExists(t, filepath.Join(root, "elastic-agent"), "the cgroup is created outside of the cgroup of the agent")
+}
+
+func TestCurrentCgroup(t *testing.T) {
+	defer func(prev string) { procSelfCgroup = prev }(procSelfCgroup)
+	procSelfCgroup = filepath.Join(t.TempDir(), "cgroup")
+
+	// hybrid hierarchy, the cgroup v1 controllers are listed along with the cgroup v2
+	require.NoError(t, os.WriteFile(procSelfCgroup, []byte("12:memory:/user.slice\n1:name=systemd:/user.slice/session-1.scope\n0::/user.slice/session-1.scope\n"), 0644))
+	current, err := currentCgroup()
+	require.NoError(t, err)
+	assert.Equal(t, "/user.slice/session-1.scope", current)
+
+	require.NoError(t, os.WriteFile(procSelfCgroup, []byte("12:memory:/user.slice\n"), 0644))
+	_, err = currentCgroup()
+	assert.Error(t, err, "only cgroup v1")
+}
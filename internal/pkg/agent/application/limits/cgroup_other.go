@@ -0,0 +1,17 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package limits
+
+import (
+	"errors"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+func placeInCgroup(_ configuration.CgroupLimitsConfig) error {
+	return errors.New("cgroups are only supported on Linux")
+}
@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package limits caps the resources used by the agent process itself and sheds load when its
+// memory usage approaches the limit.
+package limits
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// metric of the memory mapped by the Go runtime.
+	metricTotalMemory = "/memory/classes/total:bytes"
+	// metric of the memory mapped by the Go runtime and released to the operating system.
+	metricReleasedMemory = "/memory/classes/heap/released:bytes"
+)
+
+// Apply applies the limits of the configuration to the running process.
+func Apply(log *logger.Logger, cfg *configuration.LimitsConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.GoMaxProcs > 0 {
+		log.Infof("Limiting Elastic Agent to %d CPUs", cfg.GoMaxProcs)
+		runtime.GOMAXPROCS(cfg.GoMaxProcs)
+	}
+	if cfg.MemoryLimit > 0 {
+		log.Infof("Setting the memory limit of Elastic Agent to %d bytes", cfg.MemoryLimit)
+		debug.SetMemoryLimit(cfg.MemoryLimit)
+	}
+	if cfg.Cgroup.Enabled {
+		if err := placeInCgroup(cfg.Cgroup); err != nil {
+			return fmt.Errorf("failed to place Elastic Agent in cgroup %s: %w", cfg.Cgroup.Path, err)
+		}
+		log.Infof("Placed Elastic Agent in cgroup %s", cfg.Cgroup.Path)
+	}
+	return nil
+}
+
+// Monitor checks the memory used by the agent against the memory limit of the Go runtime and
+// starts shedding load when it exceeds the threshold, until it goes below the resume threshold.
+type Monitor struct {
+	log   *logger.Logger
+	cfg   *configuration.LoadSheddingConfig
+	shed  func(ctx context.Context, shedding bool) error
+	limit func() int64
+	usage func() uint64
+}
+
+// NewMonitor creates a monitor calling shed each time the agent starts or stops shedding load.
+func NewMonitor(log *logger.Logger, cfg *configuration.LoadSheddingConfig, shed func(ctx context.Context, shedding bool) error) *Monitor {
+	return &Monitor{
+		log:   log,
+		cfg:   cfg,
+		shed:  shed,
+		limit: memoryLimit,
+		usage: MemoryUsage,
+	}
+}
+
+// Run checks the memory used by the agent until the context is done.
+func (m *Monitor) Run(ctx context.Context) {
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+
+	shedding := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		shedding = m.check(ctx, shedding)
+	}
+}
+
+// check compares the memory used by the agent with the thresholds and returns whether the agent
+// sheds load.
+func (m *Monitor) check(ctx context.Context, shedding bool) bool {
+	limit := m.limit()
+	if limit <= 0 || limit == math.MaxInt64 {
+		// without memory limit there is nothing to approach
+		return shedding
+	}
+	usage := float64(m.usage())
+	switch {
+	case !shedding && usage >= m.cfg.Threshold*float64(limit):
+		m.log.Warnf("Elastic Agent uses %.0f bytes of its memory limit of %d bytes, pausing the monitoring and checking in less often", usage, limit)
+	case shedding && usage < m.cfg.ResumeThreshold*float64(limit):
+		m.log.Infof("Elastic Agent uses %.0f bytes of its memory limit of %d bytes, resuming the monitoring", usage, limit)
+	default:
+		return shedding
+	}
+	if err := m.shed(ctx, !shedding); err != nil {
+		m.log.Warnw("Failed to change the load shedding", "error.message", err)
+		return shedding
+	}
+	return !shedding
+}
+
+// memoryLimit returns the soft memory limit of the Go runtime, math.MaxInt64 when not limited.
+func memoryLimit() int64 {
+	// a negative input only reads the limit
+	return debug.SetMemoryLimit(-1)
+}
+
+// MemoryUsage returns the memory accounted by the Go runtime against its memory limit.
+func MemoryUsage() uint64 {
+	samples := []metrics.Sample{{Name: metricTotalMemory}, {Name: metricReleasedMemory}}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package limits
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestMonitorCheck(t *testing.T) {
+	log, _ := logger.NewTesting("limits")
+	cfg := configuration.DefaultLimitsConfig().LoadShedding
+
+	var calls []bool
+	var shedErr error
+	m := NewMonitor(log, &cfg, func(_ context.Context, shedding bool) error {
+		calls = append(calls, shedding)
+		return shedErr
+	})
+	limit := int64(1000)
+	usage := uint64(0)
+	m.limit = func() int64 { return limit }
+	m.usage = func() uint64 { return usage }
+	ctx := context.Background()
+
+	usage = 800
+	assert.False(t, m.check(ctx, false), "below the threshold")
+	usage = 850
+	assert.True(t, m.check(ctx, false), "reaching the threshold starts shedding load")
+	usage = 750
+	assert.True(t, m.check(ctx, true), "above the resume threshold keeps shedding load")
+	usage = 600
+	assert.False(t, m.check(ctx, true), "below the resume threshold stops shedding load")
+	assert.Equal(t, []bool{true, false}, calls)
+
+	shedErr = errors.New("coordinator stopped")
+	usage = 900
+	assert.False(t, m.check(ctx, false), "a failure to shed load keeps the previous state")
+
+	calls = nil
+	limit = math.MaxInt64
+	assert.False(t, m.check(ctx, false), "nothing to approach without memory limit")
+	assert.Empty(t, calls)
+}
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"time"
 
+	"go.elastic.co/apm"
+
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions/handlers"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
@@ -21,6 +23,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/upload"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/fleet"
@@ -48,6 +51,7 @@ type managedConfigManager struct {
 	actionQueue      *queue.ActionQueue
 	dispatcher       *dispatcher.ActionDispatcher
 	runtime          *runtime.Manager
+	tracer           *apm.Tracer
 	coord            *coordinator.Coordinator
 	fleetInitTimeout time.Duration
 
@@ -61,6 +65,7 @@ func newManagedConfigManager(
 	cfg *configuration.Configuration,
 	storeSaver storage.Store,
 	runtime *runtime.Manager,
+	tracer *apm.Tracer,
 	fleetInitTimeout time.Duration,
 ) (*managedConfigManager, error) {
 	client, err := fleetclient.NewAuthWithConfig(log, cfg.Fleet.AccessAPIKey, cfg.Fleet.Client)
@@ -86,6 +91,7 @@ func newManagedConfigManager(
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize action dispatcher: %w", err)
 	}
+	actionDispatcher.SetTracer(tracer)
 
 	return &managedConfigManager{
 		log:              log,
@@ -97,6 +103,7 @@ func newManagedConfigManager(
 		actionQueue:      actionQueue,
 		dispatcher:       actionDispatcher,
 		runtime:          runtime,
+		tracer:           tracer,
 		fleetInitTimeout: fleetInitTimeout,
 		ch:               make(chan coordinator.ConfigChange),
 		errCh:            make(chan error),
@@ -140,6 +147,8 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		m.log.Warnf("Failed to ack upgrade: %v", err)
 	}
 
+	go reportIntegrityFailure(ctx, m.log, ack, m.coord.StateSubscribe(ctx, 32))
+
 	// Run the retrier.
 	retrierRun := make(chan bool)
 	retrierCtx, retrierCancel := context.WithCancel(ctx)
@@ -185,7 +194,11 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		m.client,
 		actionAcker,
 		m.coord.State,
+		m.coord.StateSubscribe,
 		m.stateStore,
+		m.coord.SetFleetCircuitBreaker,
+		m.cfg.Settings.Metadata,
+		m.tracer,
 	)
 	if err != nil {
 		return err
@@ -221,6 +234,41 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 	return gatewayRunner.Err()
 }
 
+// eventSender sends events about the agent itself to fleet.
+type eventSender interface {
+	Event(ctx context.Context, event fleetapi.AckEvent) error
+}
+
+// reportIntegrityFailure sends an event to fleet when the agent binary integrity check fails,
+// in addition to the degraded state reported on check-in. The event is sent once per failure,
+// it is sent again on the next state update when sending it failed.
+func reportIntegrityFailure(ctx context.Context, log *logger.Logger, sender eventSender, states <-chan coordinator.State) {
+	reported := ""
+	for {
+		var state coordinator.State
+		select {
+		case <-ctx.Done():
+			return
+		case state = <-states:
+		}
+		if state.IntegrityError == reported {
+			continue
+		}
+		if state.IntegrityError != "" {
+			err := sender.Event(ctx, fleetapi.AckEvent{
+				EventType: "ERROR",
+				SubType:   "FAILED",
+				Message:   fmt.Sprintf("Elastic Agent binary integrity check failed: %s", state.IntegrityError),
+			})
+			if err != nil {
+				log.Warnw("Failed to send the binary integrity event to fleet", "error.message", err)
+				continue
+			}
+		}
+		reported = state.IntegrityError
+	}
+}
+
 // runDispatcher passes actions collected from gateway to dispatcher or calls Dispatch with no actions every flushInterval.
 func runDispatcher(ctx context.Context, actionDispatcher dispatcher.Dispatcher, fleetGateway gateway.FleetGateway, actionAcker acker.Acker, flushInterval time.Duration) {
 	t := time.NewTimer(flushInterval)
@@ -319,6 +367,8 @@ func fleetServerRunning(state runtime.ComponentState) bool {
 }
 
 func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *handlers.PolicyChangeHandler {
+	m.dispatcher.SetUpgradeDetailsSetter(m.coord.SetUpgradeDetails)
+
 	policyChanger := handlers.NewPolicyChangeHandler(
 		m.log,
 		m.agentInfo,
@@ -375,10 +425,20 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 			m.log,
 			m.coord,
 			m.cfg.Settings.MonitoringConfig.Diagnostics.Limit,
-			uploader.New(m.agentInfo.AgentID(), m.client, m.cfg.Settings.MonitoringConfig.Diagnostics.Uploader),
+			upload.NewUploader(
+				m.log,
+				m.cfg.Settings.MonitoringConfig.Diagnostics.Uploader,
+				uploader.New(m.agentInfo.AgentID(), m.client, m.cfg.Settings.MonitoringConfig.Diagnostics.Uploader),
+				paths.DiagnosticsUploads(),
+			),
 		),
 	)
 
+	m.dispatcher.MustRegister(
+		&fleetapi.ActionBackfill{},
+		handlers.NewBackfill(m.log, m.coord),
+	)
+
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionApp{},
 		handlers.NewAppAction(m.log, m.coord, m.agentInfo.AgentID()),
@@ -6,13 +6,16 @@ package application
 
 import (
 	"context"
+	"errors"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -139,3 +142,44 @@ func Test_runDispatcher(t *testing.T) {
 		})
 	}
 }
+
+type mockEventSender struct {
+	mock.Mock
+}
+
+func (m *mockEventSender) Event(ctx context.Context, event fleetapi.AckEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func Test_reportIntegrityFailure(t *testing.T) {
+	failed := fleetapi.AckEvent{
+		EventType: "ERROR",
+		SubType:   "FAILED",
+		Message:   "Elastic Agent binary integrity check failed: hash mismatch",
+	}
+	sender := &mockEventSender{}
+	// the first attempt fails, the event is sent again on the next state update
+	sender.On("Event", mock.Anything, failed).Return(errors.New("fleet unavailable")).Once()
+	sender.On("Event", mock.Anything, failed).Return(nil).Once()
+
+	log, _ := logger.New("managed_mode", false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	states := make(chan coordinator.State)
+	done := make(chan struct{})
+	go func() {
+		reportIntegrityFailure(ctx, log, sender, states)
+		close(done)
+	}()
+
+	for _, integrityErr := range []string{"", "hash mismatch", "hash mismatch", "hash mismatch", ""} {
+		states <- coordinator.State{IntegrityError: integrityErr}
+	}
+	// sync with the loop so the last state is handled
+	states <- coordinator.State{}
+	cancel()
+	<-done
+
+	sender.AssertExpectations(t)
+}
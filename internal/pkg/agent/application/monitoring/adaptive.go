@@ -0,0 +1,143 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/cpu"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/limits"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// fraction of the thresholds below which the collection of the metrics is restored, so it
+	// does not flap around the thresholds.
+	adaptiveResumeRatio = 0.8
+
+	// actions of the events logged when the collection of the metrics changes.
+	eventActionMonitoringReduced  = "monitoring-reduced"
+	eventActionMonitoringRestored = "monitoring-restored"
+)
+
+// AdaptiveMonitor reduces the collection of the monitoring metrics while the CPU usage of the host
+// or the memory used by the agent exceed their thresholds, and restores it once they go down.
+// Each change is logged as an event, so the gaps in the metrics can be explained.
+type AdaptiveMonitor struct {
+	log    *logger.Logger
+	cfg    *monitoringCfg.AdaptiveConfig
+	reduce func(ctx context.Context, reduced bool) error
+	cpu    func() (float64, error)
+	memory func() uint64
+}
+
+// NewAdaptiveMonitor creates an adaptive monitor calling reduce each time the collection of the
+// metrics must be reduced or restored.
+func NewAdaptiveMonitor(log *logger.Logger, cfg *monitoringCfg.AdaptiveConfig, reduce func(ctx context.Context, reduced bool) error) *AdaptiveMonitor {
+	return &AdaptiveMonitor{
+		log:    log,
+		cfg:    cfg,
+		reduce: reduce,
+		cpu:    hostCPUUsage(),
+		memory: limits.MemoryUsage,
+	}
+}
+
+// Run checks the resources until the context is done.
+func (m *AdaptiveMonitor) Run(ctx context.Context) {
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+
+	reduced := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		reduced = m.check(ctx, reduced)
+	}
+}
+
+// check compares the resources with the thresholds and returns whether the collection of the
+// metrics is reduced.
+func (m *AdaptiveMonitor) check(ctx context.Context, reduced bool) bool {
+	var exceeded, above []string
+	if m.cfg.CPUThreshold > 0 {
+		usage, err := m.cpu()
+		if err != nil {
+			m.log.Debugw("Failed to read the CPU usage of the host", "error.message", err)
+		} else {
+			if usage >= m.cfg.CPUThreshold {
+				exceeded = append(exceeded, fmt.Sprintf("host CPU usage %.0f%% exceeds %.0f%%", usage*100, m.cfg.CPUThreshold*100))
+			}
+			if usage >= m.cfg.CPUThreshold*adaptiveResumeRatio {
+				above = append(above, fmt.Sprintf("host CPU usage %.0f%%", usage*100))
+			}
+		}
+	}
+	if m.cfg.MemoryThreshold > 0 {
+		usage := m.memory()
+		if usage >= m.cfg.MemoryThreshold {
+			exceeded = append(exceeded, fmt.Sprintf("agent memory %d bytes exceeds %d bytes", usage, m.cfg.MemoryThreshold))
+		}
+		if float64(usage) >= float64(m.cfg.MemoryThreshold)*adaptiveResumeRatio {
+			above = append(above, fmt.Sprintf("agent memory %d bytes", usage))
+		}
+	}
+
+	switch {
+	case !reduced && len(exceeded) > 0:
+		if err := m.reduce(ctx, true); err != nil {
+			m.log.Warnw("Failed to reduce the collection of the monitoring metrics", "error.message", err)
+			return false
+		}
+		m.log.Warnw(fmt.Sprintf("Reduced the collection of the monitoring metrics to every %s: %s", m.cfg.MetricsPeriod(true), strings.Join(exceeded, ", ")),
+			"event.action", eventActionMonitoringReduced)
+		return true
+	case reduced && len(above) == 0:
+		if err := m.reduce(ctx, false); err != nil {
+			m.log.Warnw("Failed to restore the collection of the monitoring metrics", "error.message", err)
+			return true
+		}
+		m.log.Infow("Restored the collection of the monitoring metrics, the resources went back below their thresholds",
+			"event.action", eventActionMonitoringRestored)
+		return false
+	}
+	return reduced
+}
+
+// hostCPUUsage returns a function reading the normalized CPU usage of the host since its
+// previous call.
+func hostCPUUsage() func() (float64, error) {
+	monitor := cpu.New(resolve.NewTestResolver("/"))
+	// the first sample is the reference of the next one
+	_, _ = monitor.Fetch()
+	return func() (float64, error) {
+		sample, err := monitor.Fetch()
+		if err != nil {
+			return 0, err
+		}
+		metrics, err := sample.Format(cpu.MetricOpts{NormalizedPercentages: true})
+		if err != nil {
+			return 0, err
+		}
+		usage, err := metrics.GetValue("total.norm.pct")
+		if err != nil {
+			return 0, err
+		}
+		value, ok := usage.(float64)
+		if !ok {
+			return 0, fmt.Errorf("unexpected CPU usage %v", usage)
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestAdaptiveMonitorCheck(t *testing.T) {
+	log, obs := logger.NewTesting("monitoring")
+	cfg := &monitoringCfg.AdaptiveConfig{
+		Enabled:         true,
+		CPUThreshold:    0.9,
+		MemoryThreshold: 1000,
+		ReducedPeriod:   time.Minute,
+	}
+	var calls []bool
+	m := NewAdaptiveMonitor(log, cfg, func(_ context.Context, reduced bool) error {
+		calls = append(calls, reduced)
+		return nil
+	})
+	cpuUsage := 0.0
+	memory := uint64(0)
+	m.cpu = func() (float64, error) { return cpuUsage, nil }
+	m.memory = func() uint64 { return memory }
+	ctx := context.Background()
+
+	cpuUsage, memory = 0.5, 500
+	assert.False(t, m.check(ctx, false), "below the thresholds")
+	cpuUsage = 0.95
+	assert.True(t, m.check(ctx, false), "the host CPU usage exceeds its threshold")
+	cpuUsage = 0.8
+	assert.True(t, m.check(ctx, true), "restored only below a fraction of the thresholds")
+	cpuUsage = 0.5
+	assert.False(t, m.check(ctx, true), "below the thresholds again")
+	memory = 1000
+	assert.True(t, m.check(ctx, false), "the memory of the agent exceeds its threshold")
+	assert.Equal(t, []bool{true, false, true}, calls)
+
+	reducedEvents := obs.FilterField(zap.String("event.action", eventActionMonitoringReduced)).All()
+	require.Len(t, reducedEvents, 2)
+	assert.Equal(t, "Reduced the collection of the monitoring metrics to every 1m0s: host CPU usage 95% exceeds 90%", reducedEvents[0].Message)
+	assert.Len(t, obs.FilterField(zap.String("event.action", eventActionMonitoringRestored)).All(), 1)
+}
+
+func TestBeatsMonitorReduced(t *testing.T) {
+	cfg := monitoringCfg.DefaultConfig()
+	b := New(true, "linux", cfg, &info.AgentInfo{})
+
+	streams := func() map[string]string {
+		policy := map[string]interface{}{inputsKey: []interface{}{}}
+		require.NoError(t, b.injectMetricsInput(policy, map[string]string{"filestream-default": "filebeat"}, monitoringOutput, nil))
+		periods := make(map[string]string)
+		for _, input := range policy[inputsKey].([]interface{}) {
+			for _, s := range input.(map[string]interface{})["streams"].([]interface{}) {
+				stream := s.(map[string]interface{})
+				periods[fmt.Sprintf("%v%v", stream["path"], stream[idKey])] = stream["period"].(string)
+			}
+		}
+		return periods
+	}
+
+	assert.Contains(t, streams(), "/inputs/metrics-monitoring-filebeat-1")
+	for id, period := range streams() {
+		assert.Equal(t, "10s", period, id)
+	}
+
+	b.SetReduced(true)
+	reduced := streams()
+	assert.NotContains(t, reduced, "/inputs/metrics-monitoring-filebeat-1", "expensive collector should be disabled")
+	for id, period := range reduced {
+		assert.Equal(t, "1m0s", period, id)
+	}
+
+	b.SetReduced(false)
+	assert.Contains(t, streams(), "/inputs/metrics-monitoring-filebeat-1")
+}
@@ -134,6 +134,19 @@ func redirectToPath(w http.ResponseWriter, r *http.Request, id, path, operatingS
 	return nil
 }
 
+// ComponentStats returns the stats exposed by the component on its monitoring endpoint.
+func ComponentStats(ctx context.Context, componentID string) ([]byte, error) {
+	endpoint := prefixedEndpoint(utils.SocketURLWithFallback(componentID, paths.TempDir()))
+	data, statusCode, err := processMetrics(ctx, endpoint, "stats")
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the stats of %s returned status %d", componentID, statusCode)
+	}
+	return data, nil
+}
+
 func processMetrics(ctx context.Context, endpoint, path string) ([]byte, int, error) {
 	hostData, err := parseURL(endpoint, "http", "", "", path, "")
 	if err != nil {
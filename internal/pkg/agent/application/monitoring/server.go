@@ -20,10 +20,12 @@ import (
 	"github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/monitoring"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
-// New creates a new server exposing metrics and process information.
+// New creates a new server exposing metrics and process information, and the status page when
+// statusPage is set.
 func NewServer(
 	log *logger.Logger,
 	endpointConfig api.Config,
@@ -32,18 +34,21 @@ func NewServer(
 	coord *coordinator.Coordinator,
 	enableProcessStats bool,
 	operatingSystem string,
+	statusPage http.Handler,
 ) (*api.Server, error) {
 	if err := createAgentMonitoringDrop(endpointConfig.Host); err != nil {
 		// log but ignore
 		log.Errorf("failed to create monitoring drop: %v", err)
 	}
 
+	// the host is followed by the port, an IPv6 host must be bracketed
+	endpointConfig.Host = netutil.Bracket(endpointConfig.Host)
 	cfg, err := config.NewConfigFrom(endpointConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	return exposeMetricsEndpoint(log, cfg, ns, tracer, coord, enableProcessStats, operatingSystem)
+	return exposeMetricsEndpoint(log, cfg, ns, tracer, coord, enableProcessStats, operatingSystem, statusPage)
 }
 
 func exposeMetricsEndpoint(
@@ -54,6 +59,7 @@ func exposeMetricsEndpoint(
 	coord *coordinator.Coordinator,
 	enableProcessStats bool,
 	operatingSystem string,
+	statusPage http.Handler,
 ) (*api.Server, error) {
 	r := mux.NewRouter()
 	if tracer != nil {
@@ -69,6 +75,10 @@ func exposeMetricsEndpoint(
 		r.Handle("/processes/{componentID}/{metricsPath}", createHandler(processHandler(coord, statsHandler, operatingSystem)))
 	}
 
+	if statusPage != nil {
+		r.Handle("/ui", statusPage)
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/", r)
 
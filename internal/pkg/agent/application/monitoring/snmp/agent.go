@@ -0,0 +1,348 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package snmp is a minimal read-only SNMPv1 and SNMPv2c agent exposing the health, the version and the
+// component counts of the agent for the environments where SNMP is the only sanctioned monitoring channel.
+//
+// The objects are, under the root OID:
+//
+//	root.1.0  version           OCTET STRING
+//	root.2.0  state             INTEGER, the state of the control protocol, HEALTHY is 2
+//	root.3.0  message           OCTET STRING
+//	root.4.0  fleet state       INTEGER
+//	root.5.0  fleet message     OCTET STRING
+//	root.6.0  components        Gauge32
+//	root.7.0  healthy           Gauge32
+//	root.8.0  degraded          Gauge32, the components neither healthy nor failed
+//	root.9.0  failed            Gauge32
+//	root.10.1.1.<index>  component ID       OCTET STRING
+//	root.10.1.2.<index>  component state    INTEGER
+//	root.10.1.3.<index>  component message  OCTET STRING
+//
+// along with sysDescr, sysObjectID and sysUpTime of the system group of MIB-II.
+package snmp
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	versionV1  = 0
+	versionV2c = 1
+
+	// error statuses of the responses
+	errNoError    = 0
+	errTooBig     = 1
+	errNoSuchName = 2
+	errNoAccess   = 6
+
+	// maxMessageSize is the largest request read and the largest response sent.
+	maxMessageSize = 65507
+	// maxBulkVarbinds caps the objects of a GetBulk response.
+	maxBulkVarbinds = 256
+)
+
+// Agent answers the Get, GetNext and GetBulk requests about the agent.
+type Agent struct {
+	log       *logger.Logger
+	conn      net.PacketConn
+	community []byte
+	root      oid
+	version   string
+	state     func() coordinator.State
+	started   time.Time
+
+	wg sync.WaitGroup
+}
+
+// New creates the agent listening on the host and port of the configuration, state returns the current
+// state of the coordinator.
+func New(log *logger.Logger, cfg *monitoringCfg.MonitoringSNMPConfig, version string, state func() coordinator.State) (*Agent, error) {
+	if cfg.Community == "" {
+		return nil, errors.New("snmp community is required")
+	}
+	rootOID := cfg.RootOID
+	if rootOID == "" {
+		rootOID = monitoringCfg.DefaultSNMPRootOID
+	}
+	root, err := parseOID(rootOID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snmp root_oid: %w", err)
+	}
+	if root.hasPrefix(systemOID) {
+		return nil, fmt.Errorf("snmp root_oid %s overlaps the system group", root)
+	}
+
+	conn, err := net.ListenPacket("udp", net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for snmp requests: %w", err)
+	}
+	return &Agent{
+		log:       log,
+		conn:      conn,
+		community: []byte(cfg.Community),
+		root:      root,
+		version:   version,
+		state:     state,
+		started:   time.Now(),
+	}, nil
+}
+
+// Addr returns the address the agent listens on.
+func (a *Agent) Addr() net.Addr {
+	return a.conn.LocalAddr()
+}
+
+// Start answers the requests in the background until Stop is called.
+func (a *Agent) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, addr, err := a.conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					a.log.Errorw("Failed to read snmp request", "error.message", err)
+				}
+				return
+			}
+			resp, err := a.handle(buf[:n])
+			if err != nil {
+				a.log.Debugw("Dropped snmp request", "client.address", addr.String(), "error.message", err)
+				continue
+			}
+			if _, err := a.conn.WriteTo(resp, addr); err != nil {
+				a.log.Debugw("Failed to send snmp response", "client.address", addr.String(), "error.message", err)
+			}
+		}
+	}()
+}
+
+// Stop stops answering the requests.
+func (a *Agent) Stop() error {
+	err := a.conn.Close()
+	a.wg.Wait()
+	return err
+}
+
+type request struct {
+	version   int64
+	community []byte
+	pdu       byte
+	requestID int64
+	// nonRepeaters and maxRepetitions are the error status and the error index of requests other than GetBulk.
+	nonRepeaters   int64
+	maxRepetitions int64
+	oids           []oid
+}
+
+// handle answers the request, an error drops the request without a response as the agents do for
+// malformed requests or requests of another community.
+func (a *Agent) handle(msg []byte) ([]byte, error) {
+	req, err := parseRequest(msg)
+	if err != nil {
+		return nil, err
+	}
+	if req.version != versionV1 && req.version != versionV2c {
+		return nil, fmt.Errorf("unsupported snmp version %d", req.version)
+	}
+	if subtle.ConstantTimeCompare(req.community, a.community) != 1 {
+		return nil, errors.New("unknown community")
+	}
+
+	mib := snapshot(a.root, a.version, time.Since(a.started), a.state())
+	var varbinds []varbind
+	var errStatus, errIndex int64
+	switch req.pdu {
+	case tagGetRequest:
+		varbinds, errStatus, errIndex = a.get(req, mib)
+	case tagGetNextRequest:
+		varbinds, errStatus, errIndex = a.getNext(req, mib)
+	case tagGetBulkRequest:
+		if req.version == versionV1 {
+			return nil, errors.New("GetBulk request in snmp v1")
+		}
+		varbinds = a.getBulk(req, mib)
+	case tagSetRequest:
+		// the objects are read-only
+		varbinds, errIndex = nullVarbinds(req.oids), 1
+		errStatus = errNoAccess
+		if req.version == versionV1 {
+			errStatus = errNoSuchName
+		}
+	default:
+		return nil, fmt.Errorf("unsupported snmp PDU 0x%02x", req.pdu)
+	}
+	if errStatus != errNoError {
+		varbinds = nullVarbinds(req.oids)
+	}
+	resp := encodeResponse(req, errStatus, errIndex, varbinds)
+	// a GetBulk response can have fewer objects than requested, the other responses too big are tooBig errors
+	for len(resp) > maxMessageSize && req.pdu == tagGetBulkRequest && len(varbinds) > 1 {
+		varbinds = varbinds[:len(varbinds)/2]
+		resp = encodeResponse(req, errStatus, errIndex, varbinds)
+	}
+	if len(resp) > maxMessageSize {
+		resp = encodeResponse(req, errTooBig, 0, nullVarbinds(nil))
+	}
+	return resp, nil
+}
+
+func (a *Agent) get(req request, mib []varbind) ([]varbind, int64, int64) {
+	varbinds := make([]varbind, 0, len(req.oids))
+	for i, o := range req.oids {
+		if v := get(mib, o); v != nil {
+			varbinds = append(varbinds, *v)
+			continue
+		}
+		if req.version == versionV1 {
+			return nil, errNoSuchName, int64(i + 1)
+		}
+		tag := tagNoSuchObject
+		if exists(mib, o) {
+			tag = tagNoSuchInstance
+		}
+		varbinds = append(varbinds, varbind{o, encodeTLV(tag, nil)})
+	}
+	return varbinds, errNoError, 0
+}
+
+func (a *Agent) getNext(req request, mib []varbind) ([]varbind, int64, int64) {
+	varbinds := make([]varbind, 0, len(req.oids))
+	for i, o := range req.oids {
+		if v := next(mib, o); v != nil {
+			varbinds = append(varbinds, *v)
+			continue
+		}
+		if req.version == versionV1 {
+			return nil, errNoSuchName, int64(i + 1)
+		}
+		varbinds = append(varbinds, varbind{o, encodeTLV(tagEndOfMibView, nil)})
+	}
+	return varbinds, errNoError, 0
+}
+
+// getBulk answers a GetBulk request as defined by RFC 3416, the response being capped to maxBulkVarbinds objects.
+func (a *Agent) getBulk(req request, mib []varbind) []varbind {
+	nonRepeaters := int(clamp(req.nonRepeaters, 0, int64(len(req.oids))))
+	repetitions := int(clamp(req.maxRepetitions, 0, maxBulkVarbinds))
+
+	nextOrEnd := func(o oid) varbind {
+		if v := next(mib, o); v != nil {
+			return *v
+		}
+		return varbind{o, encodeTLV(tagEndOfMibView, nil)}
+	}
+	var varbinds []varbind
+	for _, o := range req.oids[:nonRepeaters] {
+		varbinds = append(varbinds, nextOrEnd(o))
+	}
+	repeaters := append([]oid(nil), req.oids[nonRepeaters:]...)
+	for r := 0; r < repetitions && len(repeaters) > 0; r++ {
+		if len(varbinds)+len(repeaters) > maxBulkVarbinds {
+			break
+		}
+		for i, o := range repeaters {
+			v := nextOrEnd(o)
+			varbinds = append(varbinds, v)
+			repeaters[i] = v.oid
+		}
+	}
+	return varbinds
+}
+
+func clamp(n, min, max int64) int64 {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+func nullVarbinds(oids []oid) []varbind {
+	varbinds := make([]varbind, 0, len(oids))
+	for _, o := range oids {
+		varbinds = append(varbinds, varbind{o, encodeTLV(tagNull, nil)})
+	}
+	return varbinds
+}
+
+func parseRequest(msg []byte) (request, error) {
+	var req request
+	body, _, err := readExpected(msg, tagSequence)
+	if err != nil {
+		return req, err
+	}
+	value, body, err := readExpected(body, tagInteger)
+	if err != nil {
+		return req, err
+	}
+	if req.version, err = decodeInteger(value); err != nil {
+		return req, err
+	}
+	if req.community, body, err = readExpected(body, tagOctetString); err != nil {
+		return req, err
+	}
+	var pdu []byte
+	if req.pdu, pdu, _, err = readTLV(body); err != nil {
+		return req, err
+	}
+	for _, field := range []*int64{&req.requestID, &req.nonRepeaters, &req.maxRepetitions} {
+		if value, pdu, err = readExpected(pdu, tagInteger); err != nil {
+			return req, err
+		}
+		if *field, err = decodeInteger(value); err != nil {
+			return req, err
+		}
+	}
+	list, _, err := readExpected(pdu, tagSequence)
+	if err != nil {
+		return req, err
+	}
+	for len(list) > 0 {
+		var vb []byte
+		if vb, list, err = readExpected(list, tagSequence); err != nil {
+			return req, err
+		}
+		if value, _, err = readExpected(vb, tagOID); err != nil {
+			return req, err
+		}
+		o, err := decodeOID(value)
+		if err != nil {
+			return req, err
+		}
+		req.oids = append(req.oids, o)
+	}
+	return req, nil
+}
+
+func encodeResponse(req request, errStatus, errIndex int64, varbinds []varbind) []byte {
+	list := make([][]byte, 0, len(varbinds))
+	for _, v := range varbinds {
+		list = append(list, encodeSequence(tagSequence, encodeOID(v.oid), v.value))
+	}
+	return encodeSequence(tagSequence,
+		encodeInteger(req.version),
+		encodeTLV(tagOctetString, req.community),
+		encodeSequence(tagGetResponse,
+			encodeInteger(req.requestID),
+			encodeInteger(errStatus),
+			encodeInteger(errIndex),
+			encodeSequence(tagSequence, list...),
+		),
+	)
+}
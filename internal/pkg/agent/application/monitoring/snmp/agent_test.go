@@ -0,0 +1,246 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package snmp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+var testRoot = oid{1, 3, 6, 1, 3, 6791}
+
+func testState() coordinator.State {
+	return coordinator.State{
+		State:        agentclient.Degraded,
+		Message:      "1 or more components/units in a degraded state",
+		FleetState:   agentclient.Stopped,
+		FleetMessage: "Not enrolled into Fleet",
+		Components: []runtime.ComponentComponentState{{
+			Component: component.Component{ID: "log-default"},
+			State:     runtime.ComponentState{State: client.UnitStateHealthy, Message: "Healthy"},
+		}, {
+			Component: component.Component{ID: "filestream-default"},
+			State:     runtime.ComponentState{State: client.UnitStateDegraded, Message: "slow output"},
+		}},
+	}
+}
+
+func newTestAgent(t *testing.T) *Agent {
+	log, _ := logger.New("", false)
+	a, err := New(log, &monitoringCfg.MonitoringSNMPConfig{
+		Host:      "127.0.0.1",
+		Port:      0,
+		Community: "secret",
+		RootOID:   testRoot.String(),
+	}, "8.9.0", testState)
+	require.NoError(t, err)
+	return a
+}
+
+func encodeRequest(version int64, community string, pdu byte, f1, f2 int64, oids ...oid) []byte {
+	list := make([][]byte, 0, len(oids))
+	for _, o := range oids {
+		list = append(list, encodeSequence(tagSequence, encodeOID(o), encodeTLV(tagNull, nil)))
+	}
+	return encodeSequence(tagSequence,
+		encodeInteger(version),
+		encodeString(community),
+		encodeSequence(pdu, encodeInteger(42), encodeInteger(f1), encodeInteger(f2), encodeSequence(tagSequence, list...)),
+	)
+}
+
+type response struct {
+	errStatus int64
+	errIndex  int64
+	oids      []oid
+	tags      []byte
+	values    [][]byte
+}
+
+func decodeResponse(t *testing.T, msg []byte) response {
+	var resp response
+	body, _, err := readExpected(msg, tagSequence)
+	require.NoError(t, err)
+	_, body, err = readExpected(body, tagInteger)
+	require.NoError(t, err)
+	_, body, err = readExpected(body, tagOctetString)
+	require.NoError(t, err)
+	pdu, _, err := readExpected(body, tagGetResponse)
+	require.NoError(t, err)
+	for _, field := range []*int64{nil, &resp.errStatus, &resp.errIndex} {
+		value, rest, err := readExpected(pdu, tagInteger)
+		require.NoError(t, err)
+		pdu = rest
+		if field != nil {
+			*field, err = decodeInteger(value)
+			require.NoError(t, err)
+		}
+	}
+	list, _, err := readExpected(pdu, tagSequence)
+	require.NoError(t, err)
+	for len(list) > 0 {
+		var vb []byte
+		vb, list, err = readExpected(list, tagSequence)
+		require.NoError(t, err)
+		value, vb, err := readExpected(vb, tagOID)
+		require.NoError(t, err)
+		o, err := decodeOID(value)
+		require.NoError(t, err)
+		tag, value, _, err := readTLV(vb)
+		require.NoError(t, err)
+		resp.oids = append(resp.oids, o)
+		resp.tags = append(resp.tags, tag)
+		resp.values = append(resp.values, value)
+	}
+	return resp
+}
+
+func TestOID(t *testing.T) {
+	o, err := parseOID("1.3.6.1.4.1.200000.1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.3.6.1.4.1.200000.1", o.String())
+
+	value, _, err := readExpected(encodeOID(o), tagOID)
+	require.NoError(t, err)
+	decoded, err := decodeOID(value)
+	require.NoError(t, err)
+	assert.Equal(t, o, decoded)
+
+	for _, invalid := range []string{"", "1", "1.a", "3.1", "1.40"} {
+		_, err := parseOID(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestInteger(t *testing.T) {
+	for _, n := range []int64{0, 1, 127, 128, -1, -128, -129, 65535, 1 << 40} {
+		value, _, err := readExpected(encodeInteger(n), tagInteger)
+		require.NoError(t, err)
+		decoded, err := decodeInteger(value)
+		require.NoError(t, err)
+		assert.Equal(t, n, decoded)
+	}
+}
+
+func TestAgent(t *testing.T) {
+	a := newTestAgent(t)
+	defer a.Stop()
+
+	t.Run("get", func(t *testing.T) {
+		msg, err := a.handle(encodeRequest(versionV2c, "secret", tagGetRequest, 0, 0,
+			testRoot.append(arcState, 0),
+			testRoot.append(arcDegraded, 0),
+			systemOID.append(1, 0),
+			testRoot.append(arcComponentTable, 1, columnComponentID, 3),
+			testRoot.append(42, 0),
+		))
+		require.NoError(t, err)
+		resp := decodeResponse(t, msg)
+		assert.EqualValues(t, errNoError, resp.errStatus)
+		assert.Equal(t, []byte{tagInteger, tagGauge32, tagOctetString, tagNoSuchInstance, tagNoSuchObject}, resp.tags)
+		assert.Equal(t, []byte{byte(agentclient.Degraded)}, resp.values[0])
+		assert.Equal(t, []byte{1}, resp.values[1])
+		assert.Equal(t, "Elastic Agent 8.9.0", string(resp.values[2]))
+	})
+
+	t.Run("walk", func(t *testing.T) {
+		var walked []string
+		for o := testRoot; ; {
+			msg, err := a.handle(encodeRequest(versionV2c, "secret", tagGetNextRequest, 0, 0, o))
+			require.NoError(t, err)
+			resp := decodeResponse(t, msg)
+			if resp.tags[0] == tagEndOfMibView {
+				break
+			}
+			o = resp.oids[0]
+			walked = append(walked, o.String())
+		}
+		assert.Len(t, walked, 9+3*2)
+		// the components are ordered by ID
+		assert.Contains(t, walked, "1.3.6.1.3.6791.10.1.1.2")
+		assert.Equal(t, "1.3.6.1.3.6791.10.1.3.2", walked[len(walked)-1])
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		msg, err := a.handle(encodeRequest(versionV2c, "secret", tagGetBulkRequest, 1, 3,
+			systemOID,
+			testRoot.append(arcComponentTable, 1, columnComponentID),
+			testRoot.append(arcComponentTable, 1, columnComponentState),
+		))
+		require.NoError(t, err)
+		resp := decodeResponse(t, msg)
+		require.Len(t, resp.oids, 1+3*2)
+		assert.Equal(t, "1.3.6.1.2.1.1.1.0", resp.oids[0].String())
+		assert.Equal(t, "filestream-default", string(resp.values[1]))
+		assert.Equal(t, []byte{byte(client.UnitStateDegraded)}, resp.values[2])
+		assert.Equal(t, "log-default", string(resp.values[3]))
+		assert.Equal(t, []byte{byte(client.UnitStateHealthy)}, resp.values[4])
+	})
+
+	t.Run("v1 no such name", func(t *testing.T) {
+		msg, err := a.handle(encodeRequest(versionV1, "secret", tagGetRequest, 0, 0,
+			testRoot.append(arcVersion, 0),
+			testRoot.append(42, 0),
+		))
+		require.NoError(t, err)
+		resp := decodeResponse(t, msg)
+		assert.EqualValues(t, errNoSuchName, resp.errStatus)
+		assert.EqualValues(t, 2, resp.errIndex)
+	})
+
+	t.Run("set is refused", func(t *testing.T) {
+		msg, err := a.handle(encodeRequest(versionV2c, "secret", tagSetRequest, 0, 0, testRoot.append(arcMessage, 0)))
+		require.NoError(t, err)
+		resp := decodeResponse(t, msg)
+		assert.EqualValues(t, errNoAccess, resp.errStatus)
+	})
+
+	t.Run("unknown community is dropped", func(t *testing.T) {
+		_, err := a.handle(encodeRequest(versionV2c, "public", tagGetRequest, 0, 0, testRoot.append(arcState, 0)))
+		assert.Error(t, err)
+	})
+
+	t.Run("bulk in v1 is dropped", func(t *testing.T) {
+		_, err := a.handle(encodeRequest(versionV1, "secret", tagGetBulkRequest, 0, 10, testRoot))
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed request is dropped", func(t *testing.T) {
+		_, err := a.handle([]byte{tagSequence, 0x10, tagInteger, 0x01})
+		assert.Error(t, err)
+	})
+}
+
+func TestAgentUDP(t *testing.T) {
+	a := newTestAgent(t)
+	a.Start()
+	defer a.Stop()
+
+	conn, err := net.Dial("udp", a.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(10*time.Second)))
+
+	_, err = conn.Write(encodeRequest(versionV2c, "secret", tagGetRequest, 0, 0, testRoot.append(arcComponents, 0)))
+	require.NoError(t, err)
+	buf := make([]byte, maxMessageSize)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	resp := decodeResponse(t, buf[:n])
+	assert.Equal(t, []byte{tagGauge32}, resp.tags)
+	assert.Equal(t, []byte{2}, resp.values[0])
+}
@@ -0,0 +1,229 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER tags of the types used by SNMPv1 and SNMPv2c.
+const (
+	tagInteger     byte = 0x02
+	tagOctetString byte = 0x04
+	tagNull        byte = 0x05
+	tagOID         byte = 0x06
+	tagSequence    byte = 0x30
+	tagGauge32     byte = 0x42
+	tagTimeTicks   byte = 0x43
+
+	tagNoSuchObject   byte = 0x80
+	tagNoSuchInstance byte = 0x81
+	tagEndOfMibView   byte = 0x82
+
+	tagGetRequest     byte = 0xa0
+	tagGetNextRequest byte = 0xa1
+	tagGetResponse    byte = 0xa2
+	tagSetRequest     byte = 0xa3
+	tagGetBulkRequest byte = 0xa5
+)
+
+var errTruncated = errors.New("truncated BER encoding")
+
+// oid is an object identifier.
+type oid []uint32
+
+func parseOID(s string) (oid, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", s)
+	}
+	o := make(oid, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q: %w", s, err)
+		}
+		o = append(o, uint32(n))
+	}
+	if o[0] > 2 || (o[0] < 2 && o[1] >= 40) {
+		return nil, fmt.Errorf("invalid OID %q", s)
+	}
+	return o, nil
+}
+
+func (o oid) String() string {
+	parts := make([]string, len(o))
+	for i, n := range o {
+		parts[i] = strconv.FormatUint(uint64(n), 10)
+	}
+	return strings.Join(parts, ".")
+}
+
+// append returns a copy of the OID with the arcs appended.
+func (o oid) append(arcs ...uint32) oid {
+	r := make(oid, 0, len(o)+len(arcs))
+	return append(append(r, o...), arcs...)
+}
+
+// compare orders the OIDs lexicographically, like the walk of a MIB.
+func (o oid) compare(other oid) int {
+	for i := 0; i < len(o) && i < len(other); i++ {
+		switch {
+		case o[i] < other[i]:
+			return -1
+		case o[i] > other[i]:
+			return 1
+		}
+	}
+	return len(o) - len(other)
+}
+
+func (o oid) hasPrefix(prefix oid) bool {
+	return len(o) >= len(prefix) && o[:len(prefix)].compare(prefix) == 0
+}
+
+// readTLV reads the first type-length-value of b and returns its tag, its value and the bytes after it.
+func readTLV(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errTruncated
+	}
+	tag, l := b[0], int(b[1])
+	b = b[2:]
+	if l&0x80 != 0 {
+		n := l & 0x7f
+		if n == 0 || n > 4 || len(b) < n {
+			return 0, nil, nil, fmt.Errorf("unsupported BER length of %d bytes", n)
+		}
+		l = 0
+		for _, c := range b[:n] {
+			l = l<<8 | int(c)
+		}
+		b = b[n:]
+	}
+	if l < 0 || len(b) < l {
+		return 0, nil, nil, errTruncated
+	}
+	return tag, b[:l], b[l:], nil
+}
+
+// readExpected reads the first type-length-value of b and fails when its tag is not the expected one.
+func readExpected(b []byte, expected byte) ([]byte, []byte, error) {
+	tag, value, rest, err := readTLV(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag != expected {
+		return nil, nil, fmt.Errorf("unexpected BER tag 0x%02x, expected 0x%02x", tag, expected)
+	}
+	return value, rest, nil
+}
+
+func decodeInteger(value []byte) (int64, error) {
+	if len(value) == 0 || len(value) > 8 {
+		return 0, fmt.Errorf("invalid BER integer of %d bytes", len(value))
+	}
+	n := int64(int8(value[0]))
+	for _, c := range value[1:] {
+		n = n<<8 | int64(c)
+	}
+	return n, nil
+}
+
+func decodeOID(value []byte) (oid, error) {
+	if len(value) == 0 {
+		return nil, errors.New("empty OID")
+	}
+	var o oid
+	var n uint64
+	for i, c := range value {
+		n = n<<7 | uint64(c&0x7f)
+		if n > 0xffffffff {
+			return nil, errors.New("OID arc overflows 32 bits")
+		}
+		if c&0x80 != 0 {
+			if i == len(value)-1 {
+				return nil, errTruncated
+			}
+			continue
+		}
+		if o == nil {
+			switch {
+			case n < 40:
+				o = oid{0, uint32(n)}
+			case n < 80:
+				o = oid{1, uint32(n - 40)}
+			default:
+				o = oid{2, uint32(n - 80)}
+			}
+		} else {
+			o = append(o, uint32(n))
+		}
+		n = 0
+	}
+	return o, nil
+}
+
+func encodeTLV(tag byte, value []byte) []byte {
+	l := len(value)
+	var b []byte
+	switch {
+	case l < 0x80:
+		b = append(make([]byte, 0, 2+l), tag, byte(l))
+	case l <= 0xff:
+		b = append(make([]byte, 0, 3+l), tag, 0x81, byte(l))
+	case l <= 0xffff:
+		b = append(make([]byte, 0, 4+l), tag, 0x82, byte(l>>8), byte(l))
+	default:
+		b = append(make([]byte, 0, 6+l), tag, 0x84, byte(l>>24), byte(l>>16), byte(l>>8), byte(l))
+	}
+	return append(b, value...)
+}
+
+func encodeSequence(tag byte, items ...[]byte) []byte {
+	var value []byte
+	for _, item := range items {
+		value = append(value, item...)
+	}
+	return encodeTLV(tag, value)
+}
+
+func encodeInteger(n int64) []byte {
+	value := []byte{byte(n)}
+	for n > 0x7f || n < -0x80 {
+		n >>= 8
+		value = append([]byte{byte(n)}, value...)
+	}
+	return encodeTLV(tagInteger, value)
+}
+
+// encodeUnsigned encodes the unsigned application types, Gauge32 and TimeTicks.
+func encodeUnsigned(tag byte, n uint32) []byte {
+	value := []byte{byte(n)}
+	for n > 0x7f {
+		n >>= 8
+		value = append([]byte{byte(n)}, value...)
+	}
+	return encodeTLV(tag, value)
+}
+
+func encodeString(s string) []byte {
+	return encodeTLV(tagOctetString, []byte(s))
+}
+
+func encodeOID(o oid) []byte {
+	var value []byte
+	first := o[0]*40 + o[1]
+	for _, n := range append(oid{first}, o[2:]...) {
+		arc := []byte{byte(n & 0x7f)}
+		for n >>= 7; n > 0; n >>= 7 {
+			arc = append([]byte{byte(n&0x7f) | 0x80}, arc...)
+		}
+		value = append(value, arc...)
+	}
+	return encodeTLV(tagOID, value)
+}
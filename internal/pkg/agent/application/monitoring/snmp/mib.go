@@ -0,0 +1,133 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package snmp
+
+import (
+	"sort"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+)
+
+// systemOID is the system group of MIB-II, RFC 1213.
+var systemOID = oid{1, 3, 6, 1, 2, 1, 1}
+
+// Objects of the agent under the root OID, the scalars have the instance .0 and the component table is
+// indexed by the position of the component, ordered by ID.
+const (
+	arcVersion      = 1
+	arcState        = 2
+	arcMessage      = 3
+	arcFleetState   = 4
+	arcFleetMessage = 5
+	arcComponents   = 6
+	arcHealthy      = 7
+	arcDegraded     = 8
+	arcFailed       = 9
+	// arcComponentTable is the table of the components, root.10.1.<column>.<index>.
+	arcComponentTable = 10
+
+	columnComponentID      = 1
+	columnComponentState   = 2
+	columnComponentMessage = 3
+)
+
+// varbind is an object and its BER encoded value.
+type varbind struct {
+	oid   oid
+	value []byte
+}
+
+// snapshot returns the objects of the agent ordered by OID. The states are the values of the
+// state enumerations of the control protocol, STARTING is 0, HEALTHY 2 and FAILED 4.
+func snapshot(root oid, version string, uptime time.Duration, state coordinator.State) []varbind {
+	components := componentsOf(state)
+	sort.Slice(components, func(i, j int) bool { return components[i].id < components[j].id })
+
+	var healthy, degraded, failed uint32
+	for _, c := range components {
+		switch c.state {
+		case client.UnitStateHealthy:
+			healthy++
+		case client.UnitStateFailed:
+			failed++
+		default:
+			degraded++
+		}
+	}
+
+	mib := []varbind{
+		{systemOID.append(1, 0), encodeString("Elastic Agent " + version)},
+		{systemOID.append(2, 0), encodeOID(root)},
+		{systemOID.append(3, 0), encodeUnsigned(tagTimeTicks, uint32(uptime/(10*time.Millisecond)))},
+		{root.append(arcVersion, 0), encodeString(version)},
+		{root.append(arcState, 0), encodeInteger(int64(state.State))},
+		{root.append(arcMessage, 0), encodeString(state.Message)},
+		{root.append(arcFleetState, 0), encodeInteger(int64(state.FleetState))},
+		{root.append(arcFleetMessage, 0), encodeString(state.FleetMessage)},
+		{root.append(arcComponents, 0), encodeUnsigned(tagGauge32, uint32(len(components)))},
+		{root.append(arcHealthy, 0), encodeUnsigned(tagGauge32, healthy)},
+		{root.append(arcDegraded, 0), encodeUnsigned(tagGauge32, degraded)},
+		{root.append(arcFailed, 0), encodeUnsigned(tagGauge32, failed)},
+	}
+	table := root.append(arcComponentTable, 1)
+	for i, c := range components {
+		index := uint32(i + 1)
+		mib = append(mib,
+			varbind{table.append(columnComponentID, index), encodeString(c.id)},
+			varbind{table.append(columnComponentState, index), encodeInteger(int64(c.state))},
+			varbind{table.append(columnComponentMessage, index), encodeString(c.message)},
+		)
+	}
+	sort.Slice(mib, func(i, j int) bool { return mib[i].oid.compare(mib[j].oid) < 0 })
+	return mib
+}
+
+type componentState struct {
+	id      string
+	state   client.UnitState
+	message string
+}
+
+func componentsOf(state coordinator.State) []componentState {
+	components := make([]componentState, 0, len(state.Components))
+	for _, c := range state.Components {
+		components = append(components, componentState{
+			id:      c.Component.ID,
+			state:   c.State.State,
+			message: c.State.Message,
+		})
+	}
+	return components
+}
+
+// get returns the object of the OID, nil when there is none.
+func get(mib []varbind, o oid) *varbind {
+	i := sort.Search(len(mib), func(i int) bool { return mib[i].oid.compare(o) >= 0 })
+	if i < len(mib) && mib[i].oid.compare(o) == 0 {
+		return &mib[i]
+	}
+	return nil
+}
+
+// next returns the first object after the OID, nil at the end of the MIB.
+func next(mib []varbind, o oid) *varbind {
+	i := sort.Search(len(mib), func(i int) bool { return mib[i].oid.compare(o) > 0 })
+	if i < len(mib) {
+		return &mib[i]
+	}
+	return nil
+}
+
+// exists returns whether the object of the OID exists, an instance of it being in the MIB.
+func exists(mib []varbind, o oid) bool {
+	if len(o) == 0 {
+		return false
+	}
+	parent := o[:len(o)-1]
+	n := next(mib, parent)
+	return n != nil && n.oid.hasPrefix(parent) && len(n.oid) == len(o)
+}
@@ -0,0 +1,284 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package statuspage serves a read-only HTML page with the health of the agent and of its
+// components, their versions, the upgrade in progress and the recent state transitions, for the
+// operators working directly on a host.
+package statuspage
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+)
+
+// maxTransitions is the number of the most recent state transitions kept.
+const maxTransitions = 50
+
+var page = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="10">
+<title>Elastic Agent status</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 16px; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.time { white-space: nowrap; font-family: monospace; }
+.HEALTHY { color: #017d73; }
+.DEGRADED, .STARTING, .CONFIGURING, .STOPPING, .UPGRADING, .ROLLBACK { color: #b0800b; }
+.FAILED, .STOPPED { color: #bd271e; font-weight: bold; }
+td.unit { padding-left: 24px; }
+</style>
+</head>
+<body>
+<h1>Elastic Agent {{ .Version }}</h1>
+<table>
+<tr><th>Agent</th><td class="{{ .State }}">{{ .State }}</td><td>{{ .Message }}</td></tr>
+<tr><th>Fleet</th><td class="{{ .FleetState }}">{{ .FleetState }}</td><td>{{ .FleetMessage }}</td></tr>
+{{- if .Upgrade }}
+<tr><th>Upgrade</th><td colspan="2">{{ .Upgrade }}</td></tr>
+{{- end }}
+</table>
+<h2>Components</h2>
+<table>
+<tr><th>ID</th><th>State</th><th>Version</th><th>Message</th></tr>
+{{- range .Components }}
+<tr><td>{{ .ID }}</td><td class="{{ .State }}">{{ .State }}</td><td>{{ .Version }}</td><td>{{ .Message }}</td></tr>
+{{- range .Units }}
+<tr><td class="unit">{{ .ID }} ({{ .Type }})</td><td class="{{ .State }}">{{ .State }}</td><td></td><td>{{ .Message }}</td></tr>
+{{- end }}
+{{- else }}
+<tr><td colspan="4">No component is running.</td></tr>
+{{- end }}
+</table>
+<h2>Recent state transitions</h2>
+<table>
+<tr><th>Time (UTC)</th><th>Subject</th><th>From</th><th>To</th><th>Message</th></tr>
+{{- range .Transitions }}
+<tr><td class="time">{{ .Time.UTC.Format "2006-01-02T15:04:05Z07:00" }}</td><td>{{ .Subject }}</td><td class="{{ .From }}">{{ .From }}</td><td class="{{ .To }}">{{ .To }}</td><td>{{ .Message }}</td></tr>
+{{- else }}
+<tr><td colspan="5">No state transition since the Elastic Agent started.</td></tr>
+{{- end }}
+</table>
+<p>Generated at {{ .Generated.UTC.Format "2006-01-02T15:04:05Z07:00" }}, refreshed every 10 seconds.</p>
+</body>
+</html>
+`))
+
+// Transition is a change of the state of the agent, of a component or of a unit.
+type Transition struct {
+	Time    time.Time
+	Subject string
+	From    string
+	To      string
+	Message string
+}
+
+// Recorder keeps the most recent state transitions of the agent and of its components.
+type Recorder struct {
+	mx          sync.Mutex
+	now         func() time.Time
+	states      map[string]string
+	transitions []Transition
+}
+
+// NewRecorder creates a recorder of the state transitions.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		now:    time.Now,
+		states: make(map[string]string),
+	}
+}
+
+// Run records the transitions of the states until the context is done.
+func (r *Recorder) Run(ctx context.Context, states <-chan coordinator.State) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s, ok := <-states:
+			if !ok {
+				return
+			}
+			r.Observe(s)
+		}
+	}
+}
+
+// Observe records the transitions between the previous state and this one. The agent, the
+// components and the units seen for the first time are not transitions.
+func (r *Recorder) Observe(s coordinator.State) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	now := r.now()
+	current := make(map[string]string)
+	record := func(subject, state, message string) {
+		current[subject] = state
+		if previous, ok := r.states[subject]; ok && previous != state {
+			r.transitions = append(r.transitions, Transition{Time: now, Subject: subject, From: previous, To: state, Message: message})
+		}
+	}
+	record("agent", s.State.String(), s.Message)
+	for _, comp := range s.Components {
+		record("component "+comp.Component.ID, comp.State.State.String(), comp.State.Message)
+		for key, unit := range comp.State.Units {
+			record(fmt.Sprintf("unit %s of component %s", key.UnitID, comp.Component.ID), unit.State.String(), unit.Message)
+		}
+	}
+	r.states = current
+	if len(r.transitions) > maxTransitions {
+		r.transitions = r.transitions[len(r.transitions)-maxTransitions:]
+	}
+}
+
+// Transitions returns the recorded transitions, the most recent first.
+func (r *Recorder) Transitions() []Transition {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	transitions := make([]Transition, 0, len(r.transitions))
+	for i := len(r.transitions) - 1; i >= 0; i-- {
+		transitions = append(transitions, r.transitions[i])
+	}
+	return transitions
+}
+
+type pageComponent struct {
+	ID      string
+	State   string
+	Message string
+	Version string
+	Units   []pageUnit
+}
+
+type pageUnit struct {
+	ID      string
+	Type    string
+	State   string
+	Message string
+}
+
+type pageData struct {
+	Version      string
+	State        string
+	Message      string
+	FleetState   string
+	FleetMessage string
+	Upgrade      string
+	Components   []pageComponent
+	Transitions  []Transition
+	Generated    time.Time
+}
+
+type handler struct {
+	cfg      *monitoringCfg.StatusPageConfig
+	version  string
+	state    func() coordinator.State
+	recorder *Recorder
+	now      func() time.Time
+}
+
+// NewHandler returns the handler of the status page, state returns the current state of the
+// coordinator.
+func NewHandler(cfg *monitoringCfg.StatusPageConfig, version string, state func() coordinator.State, recorder *Recorder) http.Handler {
+	return &handler{
+		cfg:      cfg,
+		version:  version,
+		state:    state,
+		recorder: recorder,
+		now:      time.Now,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.Username != "" || h.cfg.Password != "" {
+		username, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(h.cfg.Username)) != 1 || subtle.ConstantTimeCompare([]byte(password), []byte(h.cfg.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Elastic Agent"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	} else if !isLocal(r.RemoteAddr) {
+		http.Error(w, "The status page is only served to this host, set credentials to access it remotely", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := page.Execute(w, h.data(h.state())); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *handler) data(s coordinator.State) pageData {
+	d := pageData{
+		Version:      h.version,
+		State:        s.State.String(),
+		Message:      s.Message,
+		FleetState:   s.FleetState.String(),
+		FleetMessage: s.FleetMessage,
+		Generated:    h.now(),
+	}
+	if s.UpgradeDetails != nil {
+		d.Upgrade = fmt.Sprintf("to %s: %s", s.UpgradeDetails.TargetVersion, s.UpgradeDetails.State)
+		if s.UpgradeDetails.Metadata.ErrorMsg != "" {
+			d.Upgrade += " (" + s.UpgradeDetails.Metadata.ErrorMsg + ")"
+		}
+	}
+	for _, comp := range s.Components {
+		c := pageComponent{
+			ID:      comp.Component.ID,
+			State:   comp.State.State.String(),
+			Message: comp.State.Message,
+		}
+		if comp.State.VersionInfo.Version != "" {
+			c.Version = comp.State.VersionInfo.Name + " " + comp.State.VersionInfo.Version
+		}
+		for key, unit := range comp.State.Units {
+			c.Units = append(c.Units, pageUnit{
+				ID:      key.UnitID,
+				Type:    key.UnitType.String(),
+				State:   unit.State.String(),
+				Message: unit.Message,
+			})
+		}
+		sort.Slice(c.Units, func(i, j int) bool {
+			return c.Units[i].ID < c.Units[j].ID
+		})
+		d.Components = append(d.Components, c)
+	}
+	sort.Slice(d.Components, func(i, j int) bool {
+		return d.Components[i].ID < d.Components[j].ID
+	})
+	if h.recorder != nil {
+		d.Transitions = h.recorder.Transitions()
+	}
+	return d
+}
+
+// isLocal returns true when the request comes from the host, the requests over a unix socket or
+// a named pipe have no remote IP address.
+func isLocal(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+var now = time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)
+
+func state(agent agentclient.State, comp client.UnitState, unit client.UnitState) coordinator.State {
+	return coordinator.State{
+		State:      agent,
+		Message:    "Running",
+		FleetState: agentclient.Healthy,
+		Components: []runtime.ComponentComponentState{{
+			Component: component.Component{ID: "filestream-default"},
+			State: runtime.ComponentState{
+				State:       comp,
+				Message:     "Healthy: communicating with pid '42'",
+				VersionInfo: runtime.ComponentVersionInfo{Name: "beat-v2-client", Version: "8.11.0"},
+				Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+					{UnitType: client.UnitTypeInput, UnitID: "filestream-default-logs"}: {State: unit, Message: "reading <app.log>"},
+				},
+			},
+		}},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	r.now = func() time.Time { return now }
+
+	r.Observe(state(agentclient.Healthy, client.UnitStateHealthy, client.UnitStateHealthy))
+	assert.Empty(t, r.Transitions())
+
+	r.Observe(state(agentclient.Degraded, client.UnitStateHealthy, client.UnitStateDegraded))
+	r.Observe(state(agentclient.Healthy, client.UnitStateHealthy, client.UnitStateHealthy))
+	assert.Equal(t, []Transition{
+		{Time: now, Subject: "unit filestream-default-logs of component filestream-default", From: "DEGRADED", To: "HEALTHY", Message: "reading <app.log>"},
+		{Time: now, Subject: "agent", From: "DEGRADED", To: "HEALTHY", Message: "Running"},
+		{Time: now, Subject: "unit filestream-default-logs of component filestream-default", From: "HEALTHY", To: "DEGRADED", Message: "reading <app.log>"},
+		{Time: now, Subject: "agent", From: "HEALTHY", To: "DEGRADED", Message: "Running"},
+	}, r.Transitions())
+
+	for i := 0; i < maxTransitions; i++ {
+		r.Observe(state(agentclient.Healthy, client.UnitStateFailed, client.UnitStateHealthy))
+		r.Observe(state(agentclient.Healthy, client.UnitStateHealthy, client.UnitStateHealthy))
+	}
+	assert.Len(t, r.Transitions(), maxTransitions)
+}
+
+func TestHandler(t *testing.T) {
+	s := state(agentclient.Healthy, client.UnitStateHealthy, client.UnitStateHealthy)
+	s.UpgradeDetails = &details.Details{TargetVersion: "8.11.1", State: details.StateDownloading}
+	r := NewRecorder()
+	r.now = func() time.Time { return now }
+	r.Observe(s)
+	r.Observe(state(agentclient.Degraded, client.UnitStateHealthy, client.UnitStateHealthy))
+
+	h := NewHandler(&monitoringCfg.StatusPageConfig{Enabled: true}, "8.11.0", func() coordinator.State { return s }, r)
+
+	req := httptest.NewRequest(http.MethodGet, "/ui", nil)
+	req.RemoteAddr = "127.0.0.1:51234"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "<h1>Elastic Agent 8.11.0</h1>")
+	assert.Contains(t, body, "to 8.11.1: UPG_DOWNLOADING")
+	assert.Contains(t, body, "<td>beat-v2-client 8.11.0</td>")
+	assert.Contains(t, body, "reading &lt;app.log&gt;")
+	assert.Contains(t, body, `<td class="time">2023-09-11T10:00:00Z</td><td>agent</td><td class="HEALTHY">HEALTHY</td><td class="DEGRADED">DEGRADED</td>`)
+
+	// not from the host
+	req = httptest.NewRequest(http.MethodGet, "/ui", nil)
+	req.RemoteAddr = "10.0.0.8:51234"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// over the unix socket
+	req = httptest.NewRequest(http.MethodGet, "/ui", nil)
+	req.RemoteAddr = "@"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/ui", nil)
+	req.RemoteAddr = "127.0.0.1:51234"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandlerBasicAuth(t *testing.T) {
+	s := state(agentclient.Healthy, client.UnitStateHealthy, client.UnitStateHealthy)
+	h := NewHandler(&monitoringCfg.StatusPageConfig{Enabled: true, Username: "ops", Password: "changeme"}, "8.11.0", func() coordinator.State { return s }, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/ui", nil)
+	req.RemoteAddr = "10.0.0.8:51234"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Basic realm="Elastic Agent"`, rec.Header().Get("WWW-Authenticate"))
+
+	req.SetBasicAuth("ops", "wrong")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req.SetBasicAuth("ops", "changeme")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "No state transition since the Elastic Agent started.")
+}
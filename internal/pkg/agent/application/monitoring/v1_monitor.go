@@ -12,11 +12,14 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"unicode"
 
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/utils"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filters"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
@@ -33,7 +36,7 @@ const (
 	// args: pipeline name, application name
 	agentMbEndpointFileFormatWin = `npipe:///elastic-agent`
 	// agentMbEndpointHTTP is used with cloud and exposes metrics on http endpoint
-	agentMbEndpointHTTP = "http://%s:%d"
+	agentMbEndpointHTTP = "http://%s"
 	httpPlusPrefix      = "http+"
 	httpPrefix          = "http"
 	fileSchemePrefix    = "file"
@@ -65,6 +68,8 @@ type BeatsMonitor struct {
 	config          *monitoringConfig
 	operatingSystem string
 	agentInfo       *info.AgentInfo
+	// reduced is set while the collection of the metrics is reduced, see SetReduced
+	reduced atomic.Bool
 }
 
 type monitoringConfig struct {
@@ -98,6 +103,11 @@ func (b *BeatsMonitor) Reload(rawConfig *config.Config) error {
 	if err := rawConfig.Unpack(&b.config); err != nil {
 		return errors.New(err, "failed to unpack monitoring config during reload")
 	}
+	for _, ns := range []string{b.logsNamespace(), b.metricsNamespace()} {
+		if err := filters.ValidateNamespace(ns); err != nil {
+			return fmt.Errorf("invalid monitoring namespace %q: %w", ns, err)
+		}
+	}
 	return nil
 }
 
@@ -157,6 +167,12 @@ func (b *BeatsMonitor) MonitoringConfig(
 	return cfg, nil
 }
 
+// SetReduced reduces the collection of the metrics to spare the resources of the host, or
+// restores it. It applies to the monitoring configuration generated afterwards.
+func (b *BeatsMonitor) SetReduced(reduced bool) {
+	b.reduced.Store(reduced)
+}
+
 // EnrichArgs enriches arguments provided to application, in order to enable
 // monitoring
 func (b *BeatsMonitor) EnrichArgs(unit, binary string, args []string) []string {
@@ -291,7 +307,7 @@ func (b *BeatsMonitor) injectMonitoringOutput(source, dest map[string]interface{
 }
 
 func (b *BeatsMonitor) injectLogsInput(cfg map[string]interface{}, components []component.Component, monitoringOutput string) error {
-	monitoringNamespace := b.monitoringNamespace()
+	monitoringNamespace := b.logsNamespace()
 	logsDrop := filepath.Dir(loggingPath("unit", b.operatingSystem))
 
 	streams := []interface{}{
@@ -494,6 +510,35 @@ func (b *BeatsMonitor) injectLogsInput(cfg map[string]interface{}, components []
 			"streams":    streams,
 		},
 	}
+	return b.appendInputs(cfg, inputs)
+}
+
+func (b *BeatsMonitor) monitoringNamespace() string {
+	if ns := b.config.C.Namespace; ns != "" {
+		return ns
+	}
+	return defaultMonitoringNamespace
+}
+
+// logsNamespace returns the namespace of the monitoring logs.
+func (b *BeatsMonitor) logsNamespace() string {
+	if ns := b.config.C.DataStreams.LogsNamespace; ns != "" {
+		return ns
+	}
+	return b.monitoringNamespace()
+}
+
+// metricsNamespace returns the namespace of the monitoring metrics.
+func (b *BeatsMonitor) metricsNamespace() string {
+	if ns := b.config.C.DataStreams.MetricsNamespace; ns != "" {
+		return ns
+	}
+	return b.monitoringNamespace()
+}
+
+// appendInputs appends the monitoring inputs to the inputs of the configuration, with the ingest
+// pipeline of the monitoring events when configured.
+func (b *BeatsMonitor) appendInputs(cfg map[string]interface{}, inputs []interface{}) error {
 	inputsNode, found := cfg[inputsKey]
 	if !found {
 		return fmt.Errorf("no inputs in config")
@@ -504,21 +549,29 @@ func (b *BeatsMonitor) injectLogsInput(cfg map[string]interface{}, components []
 		return fmt.Errorf("inputs is not an array")
 	}
 
+	if pipeline := b.config.C.DataStreams.Pipeline; pipeline != "" {
+		for _, input := range inputs {
+			if inputMap, ok := input.(map[string]interface{}); ok {
+				inputMap["pipeline"] = pipeline
+			}
+		}
+	}
+
 	inputsCfg = append(inputsCfg, inputs...)
 	cfg[inputsKey] = inputsCfg
 	return nil
 }
 
-func (b *BeatsMonitor) monitoringNamespace() string {
-	if ns := b.config.C.Namespace; ns != "" {
-		return ns
-	}
-	return defaultMonitoringNamespace
-}
-
 func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentIDToBinary map[string]string, monitoringOutputName string, componentList []component.Component) error {
-	monitoringNamespace := b.monitoringNamespace()
+	monitoringNamespace := b.metricsNamespace()
 	fixedAgentName := strings.ReplaceAll(agentName, "-", "_")
+	reduced := b.reduced.Load()
+	period := b.config.C.Adaptive.MetricsPeriod(reduced).String()
+	beatsMetricsets := []interface{}{"stats", "state"}
+	if reduced {
+		// the state of the beats barely changes, it is not worth its collection
+		beatsMetricsets = []interface{}{"stats"}
+	}
 	beatsStreams := make([]interface{}, 0, len(componentIDToBinary))
 	streams := []interface{}{
 		map[string]interface{}{
@@ -532,7 +585,7 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 			"path":       "/stats",
 			"hosts":      []interface{}{HttpPlusAgentMonitoringEndpoint(b.operatingSystem, b.config.C)},
 			"namespace":  "agent",
-			"period":     "10s",
+			"period":     period,
 			"index":      fmt.Sprintf("metrics-elastic_agent.%s-%s", fixedAgentName, monitoringNamespace),
 			"processors": []interface{}{
 				map[string]interface{}{
@@ -606,9 +659,9 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 					"dataset":   fmt.Sprintf("elastic_agent.%s", name),
 					"namespace": monitoringNamespace,
 				},
-				"metricsets": []interface{}{"stats", "state"},
+				"metricsets": beatsMetricsets,
 				"hosts":      endpoints,
-				"period":     "10s",
+				"period":     period,
 				"index":      fmt.Sprintf("metrics-elastic_agent.%s-%s", name, monitoringNamespace),
 				"processors": []interface{}{
 					map[string]interface{}{
@@ -663,7 +716,7 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 			"hosts":      endpoints,
 			"path":       "/stats",
 			"namespace":  "agent",
-			"period":     "10s",
+			"period":     period,
 			"index":      fmt.Sprintf("metrics-elastic_agent.%s-%s", fixedAgentName, monitoringNamespace),
 			"processors": []interface{}{
 				map[string]interface{}{
@@ -711,7 +764,9 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 			},
 		})
 
-		if strings.EqualFold(name, "filebeat") {
+		// the metrics of the inputs of filebeat grow with their number, they are not collected
+		// while reduced
+		if strings.EqualFold(name, "filebeat") && !reduced {
 			fbDataStreamName := "filebeat_input"
 			streams = append(streams, map[string]interface{}{
 				idKey: "metrics-monitoring-" + name + "-1",
@@ -725,7 +780,7 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 				"path":          "/inputs/",
 				"namespace":     fbDataStreamName,
 				"json.is_array": true,
-				"period":        "10s",
+				"period":        period,
 				"index":         fmt.Sprintf("metrics-elastic_agent.%s-%s", fbDataStreamName, monitoringNamespace),
 				"processors": []interface{}{
 					map[string]interface{}{
@@ -799,7 +854,7 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 				"path":       "/shipper",
 				"hosts":      endpoints,
 				"namespace":  "application",
-				"period":     "10s",
+				"period":     period,
 				"processors": createProcessorsForJSONInput(name, monitoringNamespace, b.agentInfo),
 			},
 				map[string]interface{}{
@@ -813,7 +868,7 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 					"path":       "/stats",
 					"hosts":      endpoints,
 					"namespace":  "agent",
-					"period":     "10s",
+					"period":     period,
 					"processors": createProcessorsForJSONInput(name, monitoringNamespace, b.agentInfo),
 				})
 		}
@@ -856,19 +911,7 @@ func (b *BeatsMonitor) injectMetricsInput(cfg map[string]interface{}, componentI
 		})
 	}
 
-	inputsNode, found := cfg[inputsKey]
-	if !found {
-		return fmt.Errorf("no inputs in config")
-	}
-
-	inputsCfg, ok := inputsNode.([]interface{})
-	if !ok {
-		return fmt.Errorf("inputs is not an array")
-	}
-
-	inputsCfg = append(inputsCfg, inputs...)
-	cfg[inputsKey] = inputsCfg
-	return nil
+	return b.appendInputs(cfg, inputs)
 }
 
 func createProcessorsForJSONInput(name string, monitoringNamespace string, agentInfo *info.AgentInfo) []interface{} {
@@ -1001,7 +1044,7 @@ func HttpPlusAgentMonitoringEndpoint(operatingSystem string, cfg *monitoringCfg.
 // AgentMonitoringEndpoint provides an agent monitoring endpoint path.
 func AgentMonitoringEndpoint(operatingSystem string, cfg *monitoringCfg.MonitoringConfig) string {
 	if cfg != nil && cfg.Enabled {
-		return fmt.Sprintf(agentMbEndpointHTTP, cfg.HTTP.Host, cfg.HTTP.Port)
+		return fmt.Sprintf(agentMbEndpointHTTP, netutil.JoinHostPort(cfg.HTTP.Host, cfg.HTTP.Port))
 	}
 
 	if operatingSystem == windowsOS {
@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package monitoring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+)
+
+func TestBeatsMonitorDataStreams(t *testing.T) {
+	b := New(true, "linux", monitoringCfg.DefaultConfig(), &info.AgentInfo{})
+	require.NoError(t, b.Reload(config.MustNewConfigFrom(`
+agent.monitoring:
+  namespace: team_a
+  data_streams:
+    metrics_namespace: team_a_metrics
+    pipeline: team-a-monitoring
+`)))
+
+	policy := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{"type": "elasticsearch"},
+		},
+	}
+	cfg, err := b.MonitoringConfig(policy, nil, map[string]string{"filestream-default": "filebeat"})
+	require.NoError(t, err)
+
+	inputs := cfg[inputsKey].([]interface{})
+	require.NotEmpty(t, inputs)
+	for _, i := range inputs {
+		input := i.(map[string]interface{})
+		assert.Equal(t, "team-a-monitoring", input["pipeline"], input[idKey])
+
+		expected := "team_a_metrics"
+		if input["type"] == "filestream" {
+			expected = "team_a"
+		}
+		for _, s := range input["streams"].([]interface{}) {
+			stream := s.(map[string]interface{})
+			dataStream := stream["data_stream"].(map[string]interface{})
+			assert.Equal(t, expected, dataStream["namespace"], stream[idKey])
+			if index, ok := stream["index"]; ok {
+				assert.Contains(t, index, "-"+expected, stream[idKey])
+			}
+		}
+	}
+
+	err = b.Reload(config.MustNewConfigFrom(`
+agent.monitoring:
+  data_streams:
+    logs_namespace: Team A
+`))
+	assert.ErrorContains(t, err, `invalid monitoring namespace "Team A"`)
+}
@@ -21,13 +21,35 @@ type once struct {
 	loader   *config.Loader
 	ch       chan coordinator.ConfigChange
 	errCh    chan error
+	reloadCh chan chan error
 }
 
 func newOnce(log *logger.Logger, discover config.DiscoverFunc, loader *config.Loader) *once {
-	return &once{log: log, discover: discover, loader: loader, ch: make(chan coordinator.ConfigChange), errCh: make(chan error)}
+	return &once{log: log, discover: discover, loader: loader, ch: make(chan coordinator.ConfigChange), errCh: make(chan error), reloadCh: make(chan chan error)}
 }
 
 func (o *once) Run(ctx context.Context) error {
+	if err := o.load(ctx); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case res := <-o.reloadCh:
+			// the configuration loaded at start keeps running when a reload fails
+			res <- o.load(ctx)
+		}
+	}
+}
+
+// Reload reads the configuration files again.
+func (o *once) Reload(ctx context.Context) error {
+	return requestReload(ctx, o.reloadCh)
+}
+
+func (o *once) load(ctx context.Context) error {
 	files, err := o.discover()
 	if err != nil {
 		return errors.New(err, "could not discover configuration files", errors.TypeConfig)
@@ -41,9 +63,12 @@ func (o *once) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	o.ch <- &localConfigChange{cfg}
-	<-ctx.Done()
-	return ctx.Err()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case o.ch <- &localConfigChange{cfg}:
+	}
+	return nil
 }
 
 func (o *once) Errors() <-chan error {
@@ -60,6 +85,23 @@ func (o *once) Watch() <-chan coordinator.ConfigChange {
 	return o.ch
 }
 
+// requestReload asks the Run loop of a config manager to reload its configuration and waits for the result.
+func requestReload(ctx context.Context, reloadCh chan chan error) error {
+	res := make(chan error, 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case reloadCh <- res:
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-res:
+		return err
+	}
+}
+
 func readfiles(files []string, loader *config.Loader) (*config.Config, error) {
 	c, err := loader.Load(files)
 	if err != nil {
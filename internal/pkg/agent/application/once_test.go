@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestReload(t *testing.T) {
+	log, _ := logger.New("", false)
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "elastic-agent.yml")
+	writeCfg := func(t *testing.T, content string) {
+		require.NoError(t, os.WriteFile(cfgPath, []byte(content), 0o600))
+	}
+	discover := config.Discoverer(cfgPath)
+	loader := config.NewLoader(log, filepath.Join(dir, "inputs.d"))
+
+	managers := map[string]func() coordinator.ReloadableConfigManager{
+		"once": func() coordinator.ReloadableConfigManager {
+			return newOnce(log, discover, loader)
+		},
+		"periodic": func() coordinator.ReloadableConfigManager {
+			return newPeriodic(log, time.Hour, discover, loader)
+		},
+	}
+	for name, newManager := range managers {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			writeCfg(t, "outputs:\n  default:\n    type: elasticsearch\n")
+			mgr := newManager()
+			go func() {
+				_ = mgr.Run(ctx)
+			}()
+			<-mgr.Watch()
+
+			// unchanged files are emitted again
+			reloadErr := make(chan error, 1)
+			go func() { reloadErr <- mgr.Reload(ctx) }()
+			assert.Equal(t, "elasticsearch", outputType(t, <-mgr.Watch()))
+			require.NoError(t, <-reloadErr)
+
+			writeCfg(t, "outputs:\n  default:\n    type: logstash\n")
+			go func() { reloadErr <- mgr.Reload(ctx) }()
+			assert.Equal(t, "logstash", outputType(t, <-mgr.Watch()))
+			require.NoError(t, <-reloadErr)
+
+			// an invalid configuration fails the reload and the manager keeps running
+			writeCfg(t, "outputs: [")
+			require.Error(t, mgr.Reload(ctx))
+			require.NoError(t, ctx.Err())
+		})
+	}
+}
+
+func outputType(t *testing.T, change coordinator.ConfigChange) string {
+	var cfg struct {
+		Outputs map[string]struct {
+			Type string `config:"type"`
+		} `config:"outputs"`
+	}
+	require.NoError(t, change.Config().Unpack(&cfg))
+	return cfg.Outputs["default"].Type
+}
@@ -155,6 +155,22 @@ func Run() string {
 	return filepath.Join(Home(), "run")
 }
 
+// ServiceOperations returns the directory the output of the service components operations is recorded to.
+func ServiceOperations() string {
+	return filepath.Join(Run(), "service-operations")
+}
+
+// CoreDumps returns the directory the compressed core dumps of the crashed components are captured to.
+func CoreDumps() string {
+	return filepath.Join(Data(), "coredumps")
+}
+
+// DiagnosticsUploads returns the directory the diagnostics bundles are spooled to while they are uploaded, so
+// an interrupted upload resumes where it stopped.
+func DiagnosticsUploads() string {
+	return filepath.Join(Data(), "diagnostics-uploads")
+}
+
 // Components returns the component directory for Agent
 func Components() string {
 	return componentsPath
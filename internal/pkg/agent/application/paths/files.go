@@ -35,6 +35,15 @@ const defaultAgentStateStoreFile = "state.enc"
 // defaultInputDPath return the location of the inputs.d.
 const defaultInputsDPath = "inputs.d"
 
+// defaultAgentFaultsFile is the file with the faults injected by agents built with the faultinject tag.
+const defaultAgentFaultsFile = "faults.yml"
+
+// defaultAgentSBOMFile is the CycloneDX SBOM of the agent binary shipped in the versioned home.
+const defaultAgentSBOMFile = "elastic-agent.sbom.cdx.json"
+
+// defaultAgentConfigCacheFile is the last good policy and variables of a Fleet-managed agent encrypted.
+const defaultAgentConfigCacheFile = "config_cache.enc"
+
 // AgentConfigYmlFile is a name of file used to store agent information
 func AgentConfigYmlFile() string {
 	return filepath.Join(Config(), defaultAgentFleetYmlFile)
@@ -71,6 +80,11 @@ func AgentCapabilitiesPath() string {
 	return filepath.Join(Config(), defaultAgentCapabilitiesFile)
 }
 
+// AgentFaultsFile is the file with the faults injected by agents built with the faultinject tag.
+func AgentFaultsFile() string {
+	return filepath.Join(Config(), defaultAgentFaultsFile)
+}
+
 // AgentActionStoreFile is the file that contains the action that can be replayed after restart.
 func AgentActionStoreFile() string {
 	return filepath.Join(Home(), defaultAgentActionStoreFile)
@@ -90,3 +104,14 @@ func AgentStateStoreFile() string {
 func AgentInputsDPath() string {
 	return filepath.Join(Config(), defaultInputsDPath)
 }
+
+// AgentSBOMFile is the CycloneDX SBOM of the agent binary, it is shipped next to the components.
+func AgentSBOMFile() string {
+	return filepath.Join(filepath.Dir(Components()), defaultAgentSBOMFile)
+}
+
+// AgentConfigCacheFile is the last good policy and variables of a Fleet-managed agent encrypted, it is
+// in the data directory so it survives the upgrades.
+func AgentConfigCacheFile() string {
+	return filepath.Join(Data(), defaultAgentConfigCacheFile)
+}
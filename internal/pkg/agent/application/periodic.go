@@ -25,6 +25,7 @@ type periodic struct {
 	discover config.DiscoverFunc
 	ch       chan coordinator.ConfigChange
 	errCh    chan error
+	reloadCh chan chan error
 }
 
 func (p *periodic) Run(ctx context.Context) error {
@@ -39,6 +40,12 @@ func (p *periodic) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-t.C:
+		case res := <-p.reloadCh:
+			// a reload reads the files even when they did not change, its error goes back to the
+			// caller and the running configuration is kept
+			p.watcher.Invalidate()
+			res <- p.work(ctx)
+			continue
 		}
 
 		if err := p.work(ctx); err != nil {
@@ -47,6 +54,11 @@ func (p *periodic) Run(ctx context.Context) error {
 	}
 }
 
+// Reload reads the configuration files again.
+func (p *periodic) Reload(ctx context.Context) error {
+	return requestReload(ctx, p.reloadCh)
+}
+
 func (p *periodic) Errors() <-chan error {
 	return p.errCh
 }
@@ -144,6 +156,7 @@ func newPeriodic(
 		loader:   loader,
 		ch:       make(chan coordinator.ConfigChange),
 		errCh:    make(chan error),
+		reloadCh: make(chan chan error),
 	}
 }
 
@@ -0,0 +1,349 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"bytes"
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-ucfg"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/migration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/remotepolicy"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// remotePolicy is the config manager of a standalone agent pulling its policy from a Git repository, an
+// OCI artifact or an HTTP(S) URL. Like a policy from Fleet, the policy is merged on top of the local
+// configuration and replaces the running policy when its revision changes. A revision that fails to
+// validate is not applied, and one failing in the coordinator is rolled back to the last good policy.
+type remotePolicy struct {
+	log      *logger.Logger
+	source   remotepolicy.Source
+	interval time.Duration
+	discover config.DiscoverFunc
+	loader   *config.Loader
+
+	// pgp is the source of the PGP key, empty when the signature is not verified
+	pgp    string
+	pgpKey []byte
+	// cache is the last good policy, encrypted, it runs on start until the source is reachable
+	cache     storage.Storage
+	cachePath string
+	// legacyCachePath is the unencrypted cache of the previous versions, migrated to cache on start
+	legacyCachePath string
+
+	// revision is the last pulled revision and revisionErr its error, reported until the revision changes
+	revision    string
+	revisionErr error
+	// good is the last policy applied by the coordinator
+	good    []byte
+	lastErr error
+
+	// results of the changes acked or failed by the coordinator, resultCh notifies Run of new results
+	mu       sync.Mutex
+	results  []remotePolicyResult
+	resultCh chan struct{}
+
+	ch       chan coordinator.ConfigChange
+	errCh    chan error
+	reloadCh chan chan error
+}
+
+type remotePolicyResult struct {
+	revision string
+	policy   []byte
+	err      error
+}
+
+func newRemotePolicy(
+	log *logger.Logger,
+	cfg *configuration.RemotePolicyConfig,
+	dataPath string,
+	discover config.DiscoverFunc,
+	loader *config.Loader,
+) (*remotePolicy, error) {
+	dir := filepath.Join(dataPath, "remote_policy")
+	source, err := remotepolicy.New(cfg, filepath.Join(dir, "source"))
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(dir, "policy.enc")
+	r := &remotePolicy{
+		log:             log,
+		source:          source,
+		interval:        cfg.Interval,
+		discover:        discover,
+		loader:          loader,
+		cache:           storage.NewEncryptedDiskStore(cachePath),
+		cachePath:       cachePath,
+		legacyCachePath: filepath.Join(dir, "policy.yml"),
+		resultCh:        make(chan struct{}, 1),
+		ch:              make(chan coordinator.ConfigChange),
+		errCh:           make(chan error),
+		reloadCh:        make(chan chan error),
+	}
+	if !cfg.SkipVerify {
+		r.pgp = cfg.PGP
+	}
+	return r, nil
+}
+
+func (r *remotePolicy) Run(ctx context.Context) error {
+	if err := migration.MigrateToEncryptedStore(r.log, r.legacyCachePath, r.cachePath); err != nil {
+		return fmt.Errorf("failed to encrypt the cached remote policy: %w", err)
+	}
+	cached, err := r.readCache()
+	if err != nil {
+		return fmt.Errorf("failed to read the cached remote policy: %w", err)
+	}
+	// without a cached policy only the local configuration runs until the first pull
+	r.good = cached
+	cfg, err := r.merge(cached)
+	if err != nil {
+		return err
+	}
+	if err := r.emit(ctx, &localConfigChange{cfg}); err != nil {
+		return err
+	}
+	_ = r.update(ctx, false)
+
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			_ = r.update(ctx, false)
+		case res := <-r.reloadCh:
+			res <- r.update(ctx, true)
+		case <-r.resultCh:
+			r.handleResults(ctx)
+		}
+	}
+}
+
+// Reload pulls the remote policy and reads the local configuration files again.
+func (r *remotePolicy) Reload(ctx context.Context) error {
+	return requestReload(ctx, r.reloadCh)
+}
+
+func (r *remotePolicy) Errors() <-chan error {
+	return r.errCh
+}
+
+// ActionErrors returns the error channel for actions.
+// Returns nil channel.
+func (r *remotePolicy) ActionErrors() <-chan error {
+	return nil
+}
+
+func (r *remotePolicy) Watch() <-chan coordinator.ConfigChange {
+	return r.ch
+}
+
+// update pulls the policy and applies it when its revision changed, or always when forced. The
+// running policy is kept when the pull fails.
+func (r *remotePolicy) update(ctx context.Context, force bool) error {
+	err := r.pull(ctx, force)
+	r.report(ctx, err)
+	return err
+}
+
+// report sends the error to the coordinator, a nil error is only sent to clear a previous one.
+func (r *remotePolicy) report(ctx context.Context, err error) {
+	if err == nil && r.lastErr == nil || ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		r.log.Errorw("Failed to update the remote policy", "error.message", err)
+	}
+	r.lastErr = err
+	select {
+	case <-ctx.Done():
+	case r.errCh <- err:
+	}
+}
+
+func (r *remotePolicy) pull(ctx context.Context, force bool) error {
+	policy, err := r.source.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to pull the remote policy: %w", err)
+	}
+	if policy.Revision == r.revision && !force {
+		r.log.Debugf("Remote policy revision %s did not change", policy.Revision)
+		return r.revisionErr
+	}
+	r.revision = policy.Revision
+	r.revisionErr = r.apply(ctx, policy)
+	return r.revisionErr
+}
+
+// apply verifies and validates the policy before sending it to the coordinator, the running policy
+// is kept when the policy is invalid.
+func (r *remotePolicy) apply(ctx context.Context, policy *remotepolicy.Policy) error {
+	if err := r.verify(policy); err != nil {
+		return err
+	}
+
+	cfg, err := r.merge(policy.Data)
+	if err != nil {
+		return fmt.Errorf("invalid remote policy revision %s: %w", policy.Revision, err)
+	}
+	if _, err := configuration.NewFromConfig(cfg); err != nil {
+		return fmt.Errorf("invalid remote policy revision %s: %w", policy.Revision, err)
+	}
+	return r.emit(ctx, &remotePolicyChange{cfg: cfg, revision: policy.Revision, policy: policy.Data, done: r.done})
+}
+
+func (r *remotePolicy) verify(policy *remotepolicy.Policy) error {
+	if r.pgp == "" {
+		return nil
+	}
+	if len(policy.Signature) == 0 {
+		return fmt.Errorf("remote policy revision %s is not signed", policy.Revision)
+	}
+	if r.pgpKey == nil {
+		key, err := download.PgpBytesFromSource(r.pgp, http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			return fmt.Errorf("failed to read the PGP key of the remote policy: %w", err)
+		}
+		r.pgpKey = key
+	}
+	if err := download.VerifyGPGSignatureData(policy.Revision, policy.Data, policy.Signature, r.pgpKey); err != nil {
+		return fmt.Errorf("remote policy revision %s: %w", policy.Revision, err)
+	}
+	return nil
+}
+
+// merge merges the policy on top of the local configuration files, the keys of the policy win over
+// the local ones.
+func (r *remotePolicy) merge(policy []byte) (*config.Config, error) {
+	cfg := config.New()
+	files, err := r.discover()
+	if err != nil && !goerrors.Is(err, config.ErrNoConfiguration) {
+		return nil, errors.New(err, "could not discover configuration files", errors.TypeConfig)
+	}
+	if len(files) > 0 {
+		if cfg, err = readfiles(files, r.loader); err != nil {
+			return nil, err
+		}
+	}
+	if len(policy) > 0 {
+		remote, err := config.NewConfigFrom(policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the remote policy: %w", err)
+		}
+		// the lists of the policy, like its inputs, replace the local ones as a policy from Fleet does
+		if err := cfg.Merge(remote, ucfg.ReplaceArrValues); err != nil {
+			return nil, fmt.Errorf("failed to merge the remote policy: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+func (r *remotePolicy) emit(ctx context.Context, change coordinator.ConfigChange) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r.ch <- change:
+	}
+	return nil
+}
+
+// done is called by the coordinator, from its own goroutine, once a revision is applied or failed.
+func (r *remotePolicy) done(change *remotePolicyChange, err error) {
+	r.mu.Lock()
+	r.results = append(r.results, remotePolicyResult{revision: change.revision, policy: change.policy, err: err})
+	r.mu.Unlock()
+	select {
+	case r.resultCh <- struct{}{}:
+	default:
+	}
+}
+
+// handleResults caches the revisions applied by the coordinator and rolls back the failed ones.
+func (r *remotePolicy) handleResults(ctx context.Context) {
+	r.mu.Lock()
+	results := r.results
+	r.results = nil
+	r.mu.Unlock()
+
+	for _, res := range results {
+		if res.err == nil {
+			r.good = res.policy
+			if err := r.writeCache(res.policy); err != nil {
+				r.log.Errorw("Failed to cache the remote policy", "error.message", err)
+			}
+			r.log.Infof("Applied remote policy revision %s", res.revision)
+			continue
+		}
+
+		err := fmt.Errorf("remote policy revision %s failed, rolled back to the last good policy: %w", res.revision, res.err)
+		if res.revision == r.revision {
+			r.revisionErr = err
+		}
+		cfg, mergeErr := r.merge(r.good)
+		if mergeErr == nil {
+			mergeErr = r.emit(ctx, &localConfigChange{cfg})
+		}
+		if mergeErr != nil {
+			err = fmt.Errorf("remote policy revision %s failed and the last good policy could not be restored: %w", res.revision, mergeErr)
+		}
+		r.report(ctx, err)
+	}
+}
+
+func (r *remotePolicy) readCache() ([]byte, error) {
+	reader, err := r.cache.Load()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func (r *remotePolicy) writeCache(policy []byte) error {
+	if err := os.MkdirAll(filepath.Dir(r.cachePath), 0o750); err != nil {
+		return err
+	}
+	return r.cache.Save(bytes.NewReader(policy))
+}
+
+// remotePolicyChange is a revision of the remote policy, the coordinator acks or fails it.
+type remotePolicyChange struct {
+	cfg      *config.Config
+	revision string
+	policy   []byte
+	done     func(change *remotePolicyChange, err error)
+}
+
+func (c *remotePolicyChange) Config() *config.Config {
+	return c.cfg
+}
+
+func (c *remotePolicyChange) Ack() error {
+	c.done(c, nil)
+	return nil
+}
+
+func (c *remotePolicyChange) Fail(err error) {
+	c.done(c, err)
+}
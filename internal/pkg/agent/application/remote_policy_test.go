@@ -0,0 +1,160 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"       //nolint:staticcheck // crypto/openpgp is only receiving security updates.
+	"golang.org/x/crypto/openpgp/armor" //nolint:staticcheck // crypto/openpgp is only receiving security updates.
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/remotepolicy"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+type fakePolicySource struct {
+	policy *remotepolicy.Policy
+	err    error
+}
+
+func (f *fakePolicySource) Fetch(context.Context) (*remotepolicy.Policy, error) {
+	return f.policy, f.err
+}
+
+func TestRemotePolicy(t *testing.T) {
+	log, _ := logger.New("", false)
+	dir := t.TempDir()
+	top := paths.Top()
+	paths.SetTop(dir)
+	defer paths.SetTop(top)
+	testutils.InitStorage(t)
+	cfgPath := filepath.Join(dir, "elastic-agent.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("agent.logging.level: debug\noutputs:\n  default:\n    type: elasticsearch\n"), 0o600))
+
+	entity, err := openpgp.NewEntity("policy", "", "policy@example.com", nil)
+	require.NoError(t, err)
+	var pubKey bytes.Buffer
+	w, err := armor.Encode(&pubKey, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	sign := func(t *testing.T, revision, data string) *remotepolicy.Policy {
+		var sig bytes.Buffer
+		require.NoError(t, openpgp.ArmoredDetachSign(&sig, entity, bytes.NewReader([]byte(data)), nil))
+		return &remotepolicy.Policy{Revision: revision, Data: []byte(data), Signature: sig.Bytes()}
+	}
+
+	newManager := func(source remotepolicy.Source) *remotePolicy {
+		mgr, err := newRemotePolicy(log, &configuration.RemotePolicyConfig{
+			Type:     configuration.RemotePolicyOCI,
+			URL:      "localhost/agent",
+			Interval: time.Hour,
+			PGP:      "pgp_raw:" + pubKey.String(),
+		}, dir, config.Discoverer(cfgPath), config.NewLoader(log, ""))
+		require.NoError(t, err)
+		mgr.source = source
+		return mgr
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	legacyCache := filepath.Join(dir, "remote_policy", "policy.yml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(legacyCache), 0o755))
+	require.NoError(t, os.WriteFile(legacyCache, []byte("outputs:\n  default:\n    type: shipper\n"), 0o600))
+	source := &fakePolicySource{policy: sign(t, "1", "outputs:\n  default:\n    type: logstash\n")}
+	mgr := newManager(source)
+	runCtx, stop := context.WithCancel(ctx)
+	go func() {
+		_ = mgr.Run(runCtx)
+	}()
+
+	// the unencrypted cache of a previous version is encrypted and runs until the first pull, then
+	// the policy is merged on top of the local configuration
+	assert.Equal(t, "shipper", outputType(t, <-mgr.Watch()))
+	assert.NoFileExists(t, legacyCache)
+	change := <-mgr.Watch()
+	assert.Equal(t, "logstash", outputType(t, change))
+	m, err := change.Config().ToMapStr()
+	require.NoError(t, err)
+	assert.Equal(t, "debug", m["agent"].(map[string]interface{})["logging"].(map[string]interface{})["level"])
+	require.NoError(t, change.Ack())
+
+	// a policy with an invalid signature is reported and the running policy is kept
+	tampered := sign(t, "2", "outputs: {}\n")
+	tampered.Data = []byte("outputs:\n  default:\n    type: kafka\n")
+	source.policy = tampered
+	reloadErr := make(chan error, 1)
+	go func() { reloadErr <- mgr.Reload(ctx) }()
+	assert.Error(t, <-mgr.Errors())
+	require.Error(t, <-reloadErr)
+
+	source.policy = &remotepolicy.Policy{Revision: "3", Data: tampered.Data}
+	go func() { reloadErr <- mgr.Reload(ctx) }()
+	assert.ErrorContains(t, <-mgr.Errors(), "not signed")
+	require.Error(t, <-reloadErr)
+
+	// a failing source does not stop the manager, the error clears on the next successful pull
+	source.err = errors.New("registry unavailable")
+	go func() { reloadErr <- mgr.Reload(ctx) }()
+	assert.ErrorContains(t, <-mgr.Errors(), "registry unavailable")
+	require.Error(t, <-reloadErr)
+
+	source.err = nil
+	source.policy = sign(t, "4", "outputs:\n  default:\n    type: kafka\n")
+	go func() { reloadErr <- mgr.Reload(ctx) }()
+	change = <-mgr.Watch()
+	assert.Equal(t, "kafka", outputType(t, change))
+	assert.NoError(t, <-mgr.Errors())
+	require.NoError(t, <-reloadErr)
+	require.NoError(t, change.Ack())
+
+	// an invalid policy is not applied
+	source.policy = sign(t, "5", "agent.grpc.port: not-a-port\n")
+	go func() { reloadErr <- mgr.Reload(ctx) }()
+	assert.ErrorContains(t, <-mgr.Errors(), "invalid remote policy revision 5")
+	require.Error(t, <-reloadErr)
+
+	// a policy failing in the coordinator is rolled back to the last good policy
+	source.policy = sign(t, "6", "outputs:\n  default:\n    type: redis\n")
+	go func() { reloadErr <- mgr.Reload(ctx) }()
+	change = <-mgr.Watch()
+	assert.Equal(t, "redis", outputType(t, change))
+	assert.NoError(t, <-mgr.Errors())
+	require.NoError(t, <-reloadErr)
+	change.Fail(errors.New("unknown output type redis"))
+	assert.Equal(t, "kafka", outputType(t, <-mgr.Watch()))
+	assert.ErrorContains(t, <-mgr.Errors(), "rolled back to the last good policy")
+	stop()
+
+	// the last good policy is encrypted and runs on start while the source is unreachable
+	if runtime.GOOS != "darwin" {
+		cached, err := os.ReadFile(filepath.Join(dir, "remote_policy", "policy.enc"))
+		require.NoError(t, err)
+		assert.NotContains(t, string(cached), "kafka")
+	}
+	source = &fakePolicySource{err: errors.New("registry unavailable")}
+	mgr = newManager(source)
+	go func() {
+		_ = mgr.Run(ctx)
+	}()
+	assert.Equal(t, "kafka", outputType(t, <-mgr.Watch()))
+	assert.Error(t, <-mgr.Errors())
+}
+
+var _ coordinator.ReloadableConfigManager = &remotePolicy{}
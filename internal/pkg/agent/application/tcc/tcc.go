@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package tcc detects whether the Full Disk Access of the Transparency, Consent, and Control
+// (TCC) framework of macOS is granted to Elastic Agent. The components inherit the access of the
+// agent, without it they can't read the protected files, like the logs of the users, and Endpoint
+// can't protect the host.
+package tcc
+
+import (
+	"context"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Status is the status of the Full Disk Access of Elastic Agent.
+type Status string
+
+const (
+	// StatusNotApplicable is the status on the platforms without TCC.
+	StatusNotApplicable Status = ""
+	// StatusGranted is the status when Full Disk Access is granted.
+	StatusGranted Status = "granted"
+	// StatusDenied is the status when Full Disk Access isn't granted.
+	StatusDenied Status = "denied"
+	// StatusUnknown is the status when the access can't be determined.
+	StatusUnknown Status = "unknown"
+)
+
+// DefaultInterval is the interval between two checks, the access can be granted at any time in
+// the System Settings.
+const DefaultInterval = time.Minute
+
+// Guidance tells how to grant Full Disk Access to Elastic Agent.
+const Guidance = "grant it to Elastic Agent in System Settings > Privacy & Security > Full Disk Access, the components inherit it"
+
+// Checker checks the Full Disk Access of the agent periodically and reports its changes.
+type Checker struct {
+	log      *logger.Logger
+	interval time.Duration
+	check    func() Status
+	report   func(ctx context.Context, status Status) error
+}
+
+// NewChecker creates a checker reporting the status of the Full Disk Access with report.
+func NewChecker(log *logger.Logger, interval time.Duration, report func(ctx context.Context, status Status) error) *Checker {
+	return &Checker{
+		log:      log,
+		interval: interval,
+		check:    Check,
+		report:   report,
+	}
+}
+
+// Run checks the Full Disk Access until the context is done, it returns immediately on the
+// platforms without TCC.
+func (c *Checker) Run(ctx context.Context) {
+	reported := c.checkOnce(ctx, StatusNotApplicable)
+	if reported == StatusNotApplicable {
+		return
+	}
+
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		reported = c.checkOnce(ctx, reported)
+	}
+}
+
+// checkOnce checks the access and reports it when it changed, it returns the reported status.
+func (c *Checker) checkOnce(ctx context.Context, reported Status) Status {
+	status := c.check()
+	if status == reported {
+		return reported
+	}
+	switch status {
+	case StatusDenied:
+		c.log.Warnf("Full Disk Access is not granted, %s", Guidance)
+	case StatusUnknown:
+		c.log.Warn("Failed to determine whether Full Disk Access is granted")
+	default:
+		c.log.Infof("Full Disk Access is %s", status)
+	}
+	if err := c.report(ctx, status); err != nil {
+		c.log.Warnw("Failed to report the Full Disk Access", "error.message", err)
+		return reported
+	}
+	return status
+}
@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build darwin
+
+package tcc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// systemTCCDatabase is only readable by the processes with Full Disk Access, even as root.
+const systemTCCDatabase = "/Library/Application Support/com.apple.TCC/TCC.db"
+
+// Check returns whether Full Disk Access is granted to the running process.
+func Check() Status {
+	f, err := os.Open(systemTCCDatabase)
+	if err == nil {
+		_ = f.Close()
+		return StatusGranted
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return StatusDenied
+	}
+	return StatusUnknown
+}
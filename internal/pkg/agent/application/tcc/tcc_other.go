@@ -0,0 +1,12 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !darwin
+
+package tcc
+
+// Check returns StatusNotApplicable, TCC only exists on macOS.
+func Check() Status {
+	return StatusNotApplicable
+}
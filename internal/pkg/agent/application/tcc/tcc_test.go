@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tcc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestCheckerReportsChanges(t *testing.T) {
+	log, _ := logger.NewTesting("full_disk_access")
+	var reported []Status
+	c := NewChecker(log, time.Minute, func(_ context.Context, status Status) error {
+		reported = append(reported, status)
+		return nil
+	})
+
+	status := StatusDenied
+	c.check = func() Status { return status }
+
+	got := c.checkOnce(context.Background(), StatusNotApplicable)
+	assert.Equal(t, StatusDenied, got)
+	got = c.checkOnce(context.Background(), got)
+	assert.Equal(t, StatusDenied, got)
+
+	status = StatusGranted
+	got = c.checkOnce(context.Background(), got)
+	assert.Equal(t, StatusGranted, got)
+	assert.Equal(t, []Status{StatusDenied, StatusGranted}, reported, "only the changes are reported")
+}
+
+func TestCheckerRetriesFailedReport(t *testing.T) {
+	log, _ := logger.NewTesting("full_disk_access")
+	c := NewChecker(log, time.Minute, func(_ context.Context, _ Status) error {
+		return errors.New("coordinator stopped")
+	})
+	c.check = func() Status { return StatusDenied }
+
+	assert.Equal(t, StatusGranted, c.checkOnce(context.Background(), StatusGranted), "the status is reported again at the next check")
+}
+
+func TestCheckerNotApplicable(t *testing.T) {
+	log, _ := logger.NewTesting("full_disk_access")
+	reports := 0
+	c := NewChecker(log, time.Millisecond, func(_ context.Context, _ Status) error {
+		reports++
+		return nil
+	})
+	c.check = func() Status { return StatusNotApplicable }
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c.Run(ctx)
+	assert.NoError(t, ctx.Err(), "the checker stops at once without TCC")
+	assert.Zero(t, reports)
+}
@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package telemetry reports anonymized usage of the agent to an endpoint chosen by the user, it is
+// disabled unless agent.telemetry.enabled is set.
+package telemetry
+
+import (
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+// Report is the anonymized usage of an agent, it holds no identifier, host name, address or
+// configuration value, only counts.
+type Report struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Version   string    `json:"version"`
+	Snapshot  bool      `json:"snapshot"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	// Components counts the running components by type, like filestream or system/metrics.
+	Components map[string]int `json:"components"`
+	// Errors counts the components, units and Fleet connection which are failed or degraded,
+	// like component_failed or unit_degraded.
+	Errors map[string]int `json:"errors"`
+}
+
+// AgentState is the part of the state of the agent a report is built from, the daemon and the
+// telemetry command build the same report from the coordinator state and from the control protocol.
+type AgentState struct {
+	Version    string
+	Snapshot   bool
+	FleetState string
+	Components []ComponentState
+}
+
+// ComponentState is the state of a component and of its units.
+type ComponentState struct {
+	Type       string
+	State      string
+	UnitStates []string
+}
+
+// NewReport builds the report of the state at the given time.
+func NewReport(state AgentState, now time.Time) Report {
+	r := Report{
+		Timestamp:  now.UTC(),
+		Version:    state.Version,
+		Snapshot:   state.Snapshot,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Components: make(map[string]int),
+		Errors:     make(map[string]int),
+	}
+	for _, c := range state.Components {
+		r.Components[c.Type]++
+		r.countError("component", c.State)
+		for _, u := range c.UnitStates {
+			r.countError("unit", u)
+		}
+	}
+	r.countError("fleet", state.FleetState)
+	return r
+}
+
+// countError counts the failed and degraded states, the other ones are not errors.
+func (r *Report) countError(scope string, state string) {
+	switch state {
+	case client.Failed.String(), client.Degraded.String():
+		r.Errors[scope+"_"+strings.ToLower(state)]++
+	}
+}
+
+// StateFromCoordinator returns the state of the agent run by the coordinator.
+func StateFromCoordinator(state coordinator.State) AgentState {
+	s := AgentState{
+		Version:    release.Version(),
+		Snapshot:   release.Snapshot(),
+		FleetState: state.FleetState.String(),
+		Components: make([]ComponentState, 0, len(state.Components)),
+	}
+	for _, c := range state.Components {
+		comp := ComponentState{
+			Type:  c.Component.Type(),
+			State: c.State.State.String(),
+		}
+		for _, u := range c.State.Units {
+			comp.UnitStates = append(comp.UnitStates, u.State.String())
+		}
+		s.Components = append(s.Components, comp)
+	}
+	return s
+}
+
+// StateFromClient returns the state of the agent reported by the control protocol.
+func StateFromClient(state *client.AgentState) AgentState {
+	s := AgentState{
+		Version:    state.Info.Version,
+		Snapshot:   state.Info.Snapshot,
+		FleetState: state.FleetState.String(),
+		Components: make([]ComponentState, 0, len(state.Components)),
+	}
+	for _, c := range state.Components {
+		comp := ComponentState{
+			Type:  c.Name,
+			State: c.State.String(),
+		}
+		for _, u := range c.Units {
+			comp.UnitStates = append(comp.UnitStates, u.State.String())
+		}
+		s.Components = append(s.Components, comp)
+	}
+	return s
+}
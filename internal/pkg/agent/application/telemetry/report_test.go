@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package telemetry
+
+import (
+	goruntime "runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	agentclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func TestNewReport(t *testing.T) {
+	now := time.Date(2023, 8, 13, 12, 0, 0, 0, time.UTC)
+	fromCoordinator := StateFromCoordinator(coordinator.State{
+		State:      client.Degraded,
+		FleetState: client.Failed,
+		Components: []runtime.ComponentComponentState{
+			{
+				Component: component.Component{ID: "filestream-default", InputSpec: &component.InputRuntimeSpec{InputType: "filestream"}},
+				State: runtime.ComponentState{
+					State: agentclient.UnitStateHealthy,
+					Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+						{UnitType: agentclient.UnitTypeInput, UnitID: "filestream-default-1"}: {State: agentclient.UnitStateHealthy},
+						{UnitType: agentclient.UnitTypeInput, UnitID: "filestream-default-2"}: {State: agentclient.UnitStateDegraded},
+					},
+				},
+			},
+			{
+				Component: component.Component{ID: "filestream-other", InputSpec: &component.InputRuntimeSpec{InputType: "filestream"}},
+				State:     runtime.ComponentState{State: agentclient.UnitStateFailed},
+			},
+			{
+				Component: component.Component{ID: "system/metrics-default", InputSpec: &component.InputRuntimeSpec{InputType: "system/metrics"}},
+				State:     runtime.ComponentState{State: agentclient.UnitStateHealthy},
+			},
+		},
+	})
+	fromClient := StateFromClient(&client.AgentState{
+		Info:       client.AgentStateInfo{ID: "e5b7b5b8-5e4c-4d1e-9b3a-8f6f3c2a1d0e", Version: release.Version(), Snapshot: release.Snapshot()},
+		State:      client.Degraded,
+		FleetState: client.Failed,
+		Components: []client.ComponentState{
+			{
+				ID:    "filestream-default",
+				Name:  "filestream",
+				State: client.Healthy,
+				Units: []client.ComponentUnitState{
+					{UnitType: client.UnitTypeInput, UnitID: "filestream-default-1", State: client.Healthy},
+					{UnitType: client.UnitTypeInput, UnitID: "filestream-default-2", State: client.Degraded},
+				},
+			},
+			{ID: "filestream-other", Name: "filestream", State: client.Failed},
+			{ID: "system/metrics-default", Name: "system/metrics", State: client.Healthy},
+		},
+	})
+
+	expected := Report{
+		Timestamp:  now,
+		Version:    release.Version(),
+		Snapshot:   release.Snapshot(),
+		OS:         goruntime.GOOS,
+		Arch:       goruntime.GOARCH,
+		Components: map[string]int{"filestream": 2, "system/metrics": 1},
+		Errors:     map[string]int{"component_failed": 1, "unit_degraded": 1, "fleet_failed": 1},
+	}
+	// the daemon and the telemetry command report the same
+	assert.Equal(t, expected, NewReport(fromCoordinator, now))
+	assert.Equal(t, expected, NewReport(fromClient, now))
+}
@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// sendTimeout is the maximum time to send one report.
+const sendTimeout = 30 * time.Second
+
+// SpoolDir returns the directory of the spooled reports.
+func SpoolDir() string {
+	return filepath.Join(paths.Data(), "telemetry")
+}
+
+// Reporter periodically spools the report of the state of the agent and sends the spooled
+// reports to the endpoint. The reports which cannot be sent are retried at the next interval.
+type Reporter struct {
+	log    *logger.Logger
+	cfg    *configuration.TelemetryConfig
+	spool  *Spool
+	state  func() AgentState
+	client *http.Client
+}
+
+// New creates a reporter spooling the reports of the state returned by state in spoolDir.
+func New(log *logger.Logger, cfg *configuration.TelemetryConfig, spoolDir string, state func() AgentState) *Reporter {
+	return &Reporter{
+		log:    log,
+		cfg:    cfg,
+		spool:  NewSpool(spoolDir, cfg.MaxSpooled),
+		state:  state,
+		client: &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Run reports the state at each interval until the context is done, the first report is built
+// after one interval so restarts do not add reports.
+func (r *Reporter) Run(ctx context.Context) {
+	t := time.NewTicker(r.cfg.Interval)
+	defer t.Stop()
+
+	// reports spooled before a restart are sent right away
+	r.flush(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if err := r.spool.Add(NewReport(r.state(), now)); err != nil {
+				r.log.Warnw("Failed to spool telemetry report", "error.message", err)
+			}
+			r.flush(ctx)
+		}
+	}
+}
+
+// flush sends the spooled reports in order, it stops at the first failure so the order is kept.
+func (r *Reporter) flush(ctx context.Context) {
+	pending, err := r.spool.Pending()
+	if err != nil {
+		r.log.Warnw("Failed to read telemetry spool", "error.message", err)
+		return
+	}
+	for _, path := range pending {
+		if err := r.send(ctx, path); err != nil {
+			r.log.Warnw("Failed to send telemetry report, it is retried at the next interval",
+				"error.message", err, "url.full", r.cfg.Endpoint, "telemetry.spooled", len(pending))
+			return
+		}
+		if err := r.spool.Remove(path); err != nil {
+			r.log.Warnw("Failed to remove sent telemetry report", "error.message", err)
+			return
+		}
+	}
+}
+
+func (r *Reporter) send(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "elastic-agent/"+release.Version())
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestSpool(t *testing.T) {
+	spool := NewSpool(t.TempDir(), 2)
+	pending, err := spool.Pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	start := time.Date(2023, 8, 13, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, spool.Add(Report{Timestamp: start.Add(time.Duration(i) * time.Hour)}))
+	}
+
+	// the oldest report is dropped
+	pending, err = spool.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	var first Report
+	require.NoError(t, json.Unmarshal(mustReadFile(t, pending[0]), &first))
+	assert.Equal(t, start.Add(time.Hour), first.Timestamp)
+
+	require.NoError(t, spool.Remove(pending[0]))
+	pending, err = spool.Pending()
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func TestReporter(t *testing.T) {
+	var mx sync.Mutex
+	var received []Report
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var report Report
+		if err := json.Unmarshal(body, &report); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, report)
+	}))
+	defer server.Close()
+
+	log, _ := logger.NewTesting("telemetry")
+	cfg := &configuration.TelemetryConfig{Enabled: true, Endpoint: server.URL, Interval: 10 * time.Millisecond, MaxSpooled: 100}
+	dir := t.TempDir()
+	r := New(log, cfg, dir, func() AgentState {
+		return AgentState{Version: "8.11.0", Components: []ComponentState{{Type: "filestream", State: "HEALTHY"}}}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	// the reports are spooled while the endpoint fails
+	require.Eventually(t, func() bool {
+		pending, err := r.spool.Pending()
+		return err == nil && len(pending) >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mx.Lock()
+	fail = false
+	mx.Unlock()
+
+	// then sent in order once it recovers
+	require.Eventually(t, func() bool {
+		mx.Lock()
+		defer mx.Unlock()
+		return len(received) >= 3
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+
+	mx.Lock()
+	defer mx.Unlock()
+	for i, report := range received {
+		assert.Equal(t, "8.11.0", report.Version)
+		assert.Equal(t, map[string]int{"filestream": 1}, report.Components)
+		if i > 0 {
+			assert.True(t, report.Timestamp.After(received[i-1].Timestamp), "reports are sent in order")
+		}
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
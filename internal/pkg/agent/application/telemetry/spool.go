@@ -0,0 +1,92 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const spoolFileExt = ".json"
+
+// Spool keeps the reports not sent yet on disk, so they survive restarts and outages of the
+// endpoint. Each report is a file named after the time it was built.
+type Spool struct {
+	dir        string
+	maxSpooled int
+}
+
+// NewSpool creates a spool in dir keeping at most maxSpooled reports.
+func NewSpool(dir string, maxSpooled int) *Spool {
+	return &Spool{dir: dir, maxSpooled: maxSpooled}
+}
+
+// Add writes the report to the spool, dropping the oldest reports above the maximum.
+func (s *Spool) Add(r Report) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create telemetry spool: %w", err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry report: %w", err)
+	}
+	name := strconv.FormatInt(r.Timestamp.UnixNano(), 10) + spoolFileExt
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write telemetry report: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to write telemetry report: %w", err)
+	}
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	for len(files) > s.maxSpooled {
+		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to drop telemetry report: %w", err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// Pending returns the paths of the spooled reports, the oldest first.
+func (s *Spool) Pending() ([]string, error) {
+	return s.files()
+}
+
+// Remove removes a report once it is sent.
+func (s *Spool) Remove(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove telemetry report: %w", err)
+	}
+	return nil
+}
+
+func (s *Spool) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry spool: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolFileExt) {
+			continue
+		}
+		files = append(files, filepath.Join(s.dir, e.Name()))
+	}
+	// the names are timestamps of the same length until 2286
+	sort.Strings(files)
+	return files, nil
+}
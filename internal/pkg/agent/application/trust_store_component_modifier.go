@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// TrustStoreComponentModifier adds the trust store of the operating system to the certificate
+// authorities of the outputs when agent.trust_store.system is enabled. The components replace the
+// trust store of the operating system with the certificate authorities of their output, so the
+// certificates of the trust store are exported to a file added to them.
+// Example:
+//
+//	ssl:
+//	  certificate_authorities:
+//	    - /etc/pki/output-ca.pem
+//	    - /opt/Elastic/Agent/data/system-ca.pem
+//
+// The outputs without certificate_authorities already trust the trust store of the operating system.
+func TrustStoreComponentModifier(log *logger.Logger) coordinator.ComponentsModifier {
+	return func(comps []component.Component, _ map[string]interface{}) ([]component.Component, error) {
+		if !truststore.SystemEnabled() {
+			return comps, nil
+		}
+
+		var bundle string
+		for i, comp := range comps {
+			for j, unit := range comp.Units {
+				if unit.Type != client.UnitTypeOutput || unit.Config == nil {
+					continue
+				}
+				unitCfgMap := unit.Config.Source.AsMap()
+				ssl, _ := unitCfgMap["ssl"].(map[string]interface{})
+				cas, _ := ssl["certificate_authorities"].([]interface{})
+				if len(cas) == 0 {
+					continue
+				}
+				if bundle == "" {
+					var err error
+					bundle, err = truststore.Bundle(paths.Data())
+					if err != nil {
+						// the outputs keep their certificate authorities
+						log.Warnf("Failed to add the trust store of the operating system to the outputs: %v", err)
+						return comps, nil
+					}
+				}
+				ssl["certificate_authorities"] = append(cas, bundle)
+
+				unitCfg, err := component.ExpectedConfig(unitCfgMap)
+				if err != nil {
+					return nil, err
+				}
+				unit.Config = unitCfg
+				comp.Units[j] = unit
+			}
+			comps[i] = comp
+		}
+		return comps, nil
+	}
+}
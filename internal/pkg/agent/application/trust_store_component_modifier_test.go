@@ -0,0 +1,115 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package application
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestTrustStoreComponentModifier(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("the trust store of the operating system is only overridden with SSL_CERT_FILE on Unix")
+	}
+	top := paths.Top()
+	paths.SetTop(t.TempDir())
+	defer paths.SetTop(top)
+	systemFile := filepath.Join(t.TempDir(), "system.pem")
+	require.NoError(t, os.WriteFile(systemFile, []byte(testCA), 0600))
+	t.Setenv("SSL_CERT_FILE", systemFile)
+	t.Setenv("SSL_CERT_DIR", t.TempDir())
+
+	comps := func() []component.Component {
+		return []component.Component{
+			{
+				ID: "filestream-default",
+				Units: []component.Unit{
+					{
+						ID:   "filestream-default",
+						Type: client.UnitTypeOutput,
+						Config: component.MustExpectedConfig(map[string]interface{}{
+							"type": "elasticsearch",
+							"ssl": map[string]interface{}{
+								"certificate_authorities": []interface{}{"/etc/pki/output-ca.pem"},
+							},
+						}),
+					},
+					{
+						ID:     "filestream-default-filestream-0",
+						Type:   client.UnitTypeInput,
+						Config: component.MustExpectedConfig(map[string]interface{}{"type": "filestream"}),
+					},
+				},
+			},
+			{
+				ID: "system/metrics-default",
+				Units: []component.Unit{
+					{
+						ID:     "system/metrics-default",
+						Type:   client.UnitTypeOutput,
+						Config: component.MustExpectedConfig(map[string]interface{}{"type": "elasticsearch"}),
+					},
+				},
+			},
+		}
+	}
+	modifier := TrustStoreComponentModifier(logger.NewWithoutConfig(""))
+
+	// disabled, the components are unchanged
+	result, err := modifier(comps(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, comps(), result)
+
+	cfg, err := config.NewConfigFrom(map[string]interface{}{"agent.trust_store.system": true})
+	require.NoError(t, err)
+	require.NoError(t, truststore.Apply(cfg))
+	defer func() {
+		cfg, _ := config.NewConfigFrom(map[string]interface{}{"agent.trust_store.system": false})
+		_ = truststore.Apply(cfg)
+	}()
+
+	result, err = modifier(comps(), nil)
+	require.NoError(t, err)
+	bundle := filepath.Join(paths.Data(), "system-ca.pem")
+	assert.Equal(t, component.MustExpectedConfig(map[string]interface{}{
+		"type": "elasticsearch",
+		"ssl": map[string]interface{}{
+			"certificate_authorities": []interface{}{"/etc/pki/output-ca.pem", bundle},
+		},
+	}), result[0].Units[0].Config)
+	content, err := os.ReadFile(bundle)
+	require.NoError(t, err)
+	assert.Equal(t, testCA, string(content))
+
+	// the input units and the outputs trusting the trust store already are unchanged
+	assert.Equal(t, comps()[0].Units[1], result[0].Units[1])
+	assert.Equal(t, comps()[1], result[1])
+}
+
+const testCA = `-----BEGIN CERTIFICATE-----
+MIIBhTCCASugAwIBAgIQIRi6zePL6mKjOipn+dNuaTAKBggqhkjOPQQDAjASMRAw
+DgYDVQQKEwdBY21lIENvMB4XDTE3MTAyMDE5NDMwNloXDTE4MTAyMDE5NDMwNlow
+EjEQMA4GA1UEChMHQWNtZSBDbzBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABD0d
+7VNhbWvZLWPuj/RtHFjvtJBEwOkhbN/BnnE8rnZR8+sbwnc/KhCk3FhnpHZnQz7B
+5aETbbIgmuvewdjvSBSjYzBhMA4GA1UdDwEB/wQEAwICpDATBgNVHSUEDDAKBggr
+BgEFBQcDATAPBgNVHRMBAf8EBTADAQH/MCkGA1UdEQQiMCCCDmxvY2FsaG9zdDo1
+NDUzgg4xMjcuMC4wLjE6NTQ1MzAKBggqhkjOPQQDAgNIADBFAiEA2zpJEPQyz6/l
+Wf86aX6PepsntZv2GYlA5UpabfT2EZICICpJ5h/iI+i341gBmLiAFQOyTDT+/wQc
+6MF9+Yw1Yy0t
+-----END CERTIFICATE-----
+`
@@ -11,6 +11,15 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 )
 
+const (
+	// BinaryPackage is the package type of the tar.gz and zip archives.
+	BinaryPackage = "binary"
+	// DebPackage is the package type of DEB packages.
+	DebPackage = "deb"
+	// RpmPackage is the package type of RPM packages.
+	RpmPackage = "rpm"
+)
+
 var packageArchMap = map[string]string{
 	"linux-binary-32":         "linux-x86.tar.gz",
 	"linux-binary-64":         "linux-x86_64.tar.gz",
@@ -21,6 +30,12 @@ var packageArchMap = map[string]string{
 	"darwin-binary-64":        "darwin-x86_64.tar.gz",
 	"darwin-binary-arm64":     "darwin-aarch64.tar.gz",
 	"darwin-binary-universal": "darwin-universal.tar.gz",
+	"linux-deb-32":            "i386.deb",
+	"linux-deb-64":            "amd64.deb",
+	"linux-deb-arm64":         "arm64.deb",
+	"linux-rpm-32":            "i686.rpm",
+	"linux-rpm-64":            "x86_64.rpm",
+	"linux-rpm-arm64":         "aarch64.rpm",
 }
 
 // Artifact provides info for fetching from artifact store.
@@ -31,8 +46,8 @@ type Artifact struct {
 }
 
 // GetArtifactName constructs a path to a downloaded artifact
-func GetArtifactName(a Artifact, version, operatingSystem, arch string) (string, error) {
-	key := fmt.Sprintf("%s-binary-%s", operatingSystem, arch)
+func GetArtifactName(a Artifact, version, operatingSystem, arch, packageType string) (string, error) {
+	key := fmt.Sprintf("%s-%s-%s", operatingSystem, packageType, arch)
 	suffix, found := packageArchMap[key]
 	if !found {
 		return "", errors.New(fmt.Sprintf("'%s' is not a valid combination for a package", key), errors.TypeConfig)
@@ -42,8 +57,8 @@ func GetArtifactName(a Artifact, version, operatingSystem, arch string) (string,
 }
 
 // GetArtifactPath returns a full path of artifact for a program in specific version
-func GetArtifactPath(a Artifact, version, operatingSystem, arch, targetDir string) (string, error) {
-	artifactName, err := GetArtifactName(a, version, operatingSystem, arch)
+func GetArtifactPath(a Artifact, version, operatingSystem, arch, packageType, targetDir string) (string, error) {
+	artifactName, err := GetArtifactName(a, version, operatingSystem, arch, packageType)
 	if err != nil {
 		return "", err
 	}
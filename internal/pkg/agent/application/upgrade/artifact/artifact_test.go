@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package artifact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetArtifactName(t *testing.T) {
+	a := Artifact{Cmd: "elastic-agent"}
+
+	testCases := map[string]struct {
+		os, arch, packageType string
+		expected              string
+	}{
+		"linux tar.gz":  {"linux", "64", BinaryPackage, "elastic-agent-8.9.0-linux-x86_64.tar.gz"},
+		"windows zip":   {"windows", "64", BinaryPackage, "elastic-agent-8.9.0-windows-x86_64.zip"},
+		"deb amd64":     {"linux", "64", DebPackage, "elastic-agent-8.9.0-amd64.deb"},
+		"deb arm64":     {"linux", "arm64", DebPackage, "elastic-agent-8.9.0-arm64.deb"},
+		"rpm x86_64":    {"linux", "64", RpmPackage, "elastic-agent-8.9.0-x86_64.rpm"},
+		"rpm aarch64":   {"linux", "arm64", RpmPackage, "elastic-agent-8.9.0-aarch64.rpm"},
+		"darwin binary": {"darwin", "arm64", BinaryPackage, "elastic-agent-8.9.0-darwin-aarch64.tar.gz"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := GetArtifactName(a, "8.9.0", tc.os, tc.arch, tc.packageType)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	_, err := GetArtifactName(a, "8.9.0", "windows", "64", DebPackage)
+	assert.Error(t, err)
+}
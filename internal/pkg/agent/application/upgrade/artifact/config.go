@@ -5,6 +5,7 @@
 package artifact
 
 import (
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
@@ -38,6 +39,9 @@ type Config struct {
 	// Architecture: target architecture [32, 64]
 	Architecture string `json:"-" config:",ignore"`
 
+	// PackageType: type of the package to download [binary, deb, rpm], defaults to binary
+	PackageType string `json:"-" config:",ignore"`
+
 	// SourceURI: source of the artifacts, e.g https://artifacts.elastic.co/downloads/
 	SourceURI string `json:"sourceURI" config:"sourceURI"`
 
@@ -58,9 +62,34 @@ type Config struct {
 	// will increase for subsequent retry attempts in a randomized exponential backoff manner.
 	RetrySleepInitDuration time.Duration `yaml:"retry_sleep_init_duration" config:"retry_sleep_init_duration"`
 
+	// TUF: verification of the artifacts with the metadata of a TUF repository instead of the PGP signature.
+	TUF TUFConfig `yaml:"tuf" config:"tuf" json:"tuf"`
+
+	// Headers: headers added to the requests of the downloads, like the headers required by the
+	// gateways in front of the artifact repository.
+	Headers map[string]string `yaml:"headers" config:"headers" json:"headers"`
+
 	httpcommon.HTTPTransportSettings `config:",inline" yaml:",inline"` // Note: use anonymous struct for json inline
 }
 
+// TUFConfig is the configuration of the verification of the artifacts with The Update Framework.
+// The trusted metadata keeps the keys of the repository up to date and protects the agent from
+// the rollback of the artifacts to older versions.
+type TUFConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+
+	// MetadataURI: location of the metadata of the TUF repository, defaults to the tuf/ directory
+	// of the source URI.
+	MetadataURI string `yaml:"metadata_uri" config:"metadata_uri" json:"metadata_uri"`
+
+	// RootPath: path to the initial trusted root metadata, the newer versions of the root are
+	// fetched from the repository.
+	RootPath string `yaml:"root_path" config:"root_path" json:"root_path"`
+
+	// MetadataDirectory: path to the directory containing the trusted metadata.
+	MetadataDirectory string `yaml:"metadata_directory" config:"metadata_directory" json:"metadata_directory"`
+}
+
 type Reloader struct {
 	log       *logger.Logger
 	cfg       *Config
@@ -111,6 +140,8 @@ func (r *Reloader) reloadConfig(rawConfig *config.Config) error {
 		TargetDirectory:       tmp.C.TargetDirectory,
 		InstallPath:           tmp.C.InstallPath,
 		DropPath:              tmp.C.DropPath,
+		TUF:                   tmp.C.TUF,
+		Headers:               tmp.C.Headers,
 		HTTPTransportSettings: tmp.C.HTTPTransportSettings,
 	}
 
@@ -165,7 +196,10 @@ func DefaultConfig() *Config {
 		TargetDirectory:        paths.Downloads(),
 		InstallPath:            paths.Install(),
 		RetrySleepInitDuration: 30 * time.Second,
-		HTTPTransportSettings:  transport,
+		TUF: TUFConfig{
+			MetadataDirectory: filepath.Join(paths.Data(), "tuf"),
+		},
+		HTTPTransportSettings: transport,
 	}
 }
 
@@ -204,15 +238,25 @@ func (c *Config) Arch() string {
 	return c.Architecture
 }
 
+// Package returns the configured package type or falls back to the binary archive
+func (c *Config) Package() string {
+	if c.PackageType != "" {
+		return c.PackageType
+	}
+	return BinaryPackage
+}
+
 // Unpack reads a config object into the settings.
 func (c *Config) Unpack(cfg *c.C) error {
 	tmp := struct {
-		OperatingSystem string `json:"-" config:",ignore"`
-		Architecture    string `json:"-" config:",ignore"`
-		SourceURI       string `json:"sourceURI" config:"sourceURI"`
-		TargetDirectory string `json:"targetDirectory" config:"target_directory"`
-		InstallPath     string `yaml:"installPath" config:"install_path"`
-		DropPath        string `yaml:"dropPath" config:"drop_path"`
+		OperatingSystem string            `json:"-" config:",ignore"`
+		Architecture    string            `json:"-" config:",ignore"`
+		SourceURI       string            `json:"sourceURI" config:"sourceURI"`
+		TargetDirectory string            `json:"targetDirectory" config:"target_directory"`
+		InstallPath     string            `yaml:"installPath" config:"install_path"`
+		DropPath        string            `yaml:"dropPath" config:"drop_path"`
+		TUF             TUFConfig         `yaml:"tuf" config:"tuf"`
+		Headers         map[string]string `yaml:"headers" config:"headers"`
 	}{
 		OperatingSystem: c.OperatingSystem,
 		Architecture:    c.Architecture,
@@ -220,6 +264,8 @@ func (c *Config) Unpack(cfg *c.C) error {
 		TargetDirectory: c.TargetDirectory,
 		InstallPath:     c.InstallPath,
 		DropPath:        c.DropPath,
+		TUF:             c.TUF,
+		Headers:         c.Headers,
 	}
 
 	if err := cfg.Unpack(&tmp); err != nil {
@@ -238,6 +284,8 @@ func (c *Config) Unpack(cfg *c.C) error {
 		TargetDirectory:       tmp.TargetDirectory,
 		InstallPath:           tmp.InstallPath,
 		DropPath:              tmp.DropPath,
+		TUF:                   tmp.TUF,
+		Headers:               tmp.Headers,
 		HTTPTransportSettings: transport,
 	}
 	return nil
@@ -6,6 +6,8 @@ package composed
 
 import (
 	"context"
+	"fmt"
+	"io/fs"
 
 	"github.com/hashicorp/go-multierror"
 	"go.elastic.co/apm"
@@ -20,7 +22,8 @@ import (
 // the next one.
 // Error is returned if all of them fail.
 type Downloader struct {
-	dd []download.Downloader
+	dd   []download.Downloader
+	warn download.Warner
 }
 
 // NewDownloader creates a downloader out of predefined set of downloaders.
@@ -40,18 +43,35 @@ func (e *Downloader) Download(ctx context.Context, a artifact.Artifact, version
 	span, ctx := apm.StartSpan(ctx, "download", "app.internal")
 	defer span.End()
 
+	var fallbackErr error
 	for _, d := range e.dd {
-		s, e := d.Download(ctx, a, version)
-		if e == nil {
+		s, e2 := d.Download(ctx, a, version)
+		if e2 == nil {
+			if fallbackErr != nil && e.warn != nil {
+				e.warn(fmt.Sprintf("downloaded %s %s from a fallback source: %v", a.Name, version, fallbackErr))
+			}
 			return s, nil
 		}
 
-		err = multierror.Append(err, e)
+		err = multierror.Append(err, e2)
+		// a package missing from the local drop path is not a degradation
+		if !errors.Is(e2, fs.ErrNotExist) {
+			fallbackErr = e2
+		}
 	}
 
 	return "", err
 }
 
+// SetWarner sets the warner reporting the fallbacks to the next downloaders, and the warner of
+// the downloaders themselves.
+func (e *Downloader) SetWarner(w download.Warner) {
+	e.warn = w
+	for _, d := range e.dd {
+		download.SetWarner(d, w)
+	}
+}
+
 func (e *Downloader) Reload(c *artifact.Config) error {
 	for _, d := range e.dd {
 		reloadable, ok := d.(download.Reloader)
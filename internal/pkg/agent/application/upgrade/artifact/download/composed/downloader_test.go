@@ -81,3 +81,21 @@ type testCase struct {
 	checkFunc      func(downloaders []CheckableDownloader) bool
 	expectedResult bool
 }
+
+func TestComposedWarnsOnFallback(t *testing.T) {
+	var warnings []string
+	d := NewDownloader(&FailingDownloader{}, &SuccDownloader{})
+	d.SetWarner(func(msg string) { warnings = append(warnings, msg) })
+
+	r, err := d.Download(context.TODO(), artifact.Artifact{Name: "a"}, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, succ, r)
+	assert.Equal(t, []string{"downloaded a b from a fallback source: failing"}, warnings)
+
+	warnings = nil
+	d = NewDownloader(&SuccDownloader{}, &FailingDownloader{})
+	d.SetWarner(func(msg string) { warnings = append(warnings, msg) })
+	_, err = d.Download(context.TODO(), artifact.Artifact{Name: "a"}, "b")
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -54,6 +54,13 @@ func (e *Verifier) Verify(a artifact.Artifact, version string, pgpBytes ...strin
 	return err
 }
 
+// SetWarner sets the warner of the verifiers reporting their degradations.
+func (e *Verifier) SetWarner(w download.Warner) {
+	for _, v := range e.vv {
+		download.SetWarner(v, w)
+	}
+}
+
 func (e *Verifier) Reload(c *artifact.Config) error {
 	for _, v := range e.vv {
 		reloadable, ok := v.(download.Reloader)
@@ -63,13 +63,24 @@ func (e *Downloader) Download(ctx context.Context, a artifact.Artifact, version
 	return path, err
 }
 
+// Exists checks whether the package is available in the drop path.
+func (e *Downloader) Exists(a artifact.Artifact, version string) bool {
+	filename, err := artifact.GetArtifactName(a, version, e.config.OS(), e.config.Arch(), e.config.Package())
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(filepath.Join(e.dropPath, filename))
+	return err == nil
+}
+
 func (e *Downloader) download(operatingSystem string, a artifact.Artifact, version string) (string, error) {
-	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch())
+	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch(), e.config.Package())
 	if err != nil {
 		return "", errors.New(err, "generating package name failed")
 	}
 
-	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.TargetDirectory)
+	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.Package(), e.config.TargetDirectory)
 	if err != nil {
 		return "", errors.New(err, "generating package path failed")
 	}
@@ -78,12 +89,12 @@ func (e *Downloader) download(operatingSystem string, a artifact.Artifact, versi
 }
 
 func (e *Downloader) downloadHash(operatingSystem string, a artifact.Artifact, version string) (string, error) {
-	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch())
+	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch(), e.config.Package())
 	if err != nil {
 		return "", errors.New(err, "generating package name failed")
 	}
 
-	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.TargetDirectory)
+	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.Package(), e.config.TargetDirectory)
 	if err != nil {
 		return "", errors.New(err, "generating package path failed")
 	}
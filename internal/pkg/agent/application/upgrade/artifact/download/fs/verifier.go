@@ -15,6 +15,9 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -30,6 +33,7 @@ type Verifier struct {
 	client        http.Client
 	pgpBytes      []byte
 	allowEmptyPgp bool
+	warn          download.Warner
 	log           *logger.Logger
 }
 
@@ -42,8 +46,11 @@ func NewVerifier(log *logger.Logger, config *artifact.Config, allowEmptyPgp bool
 
 	client, err := config.HTTPTransportSettings.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&config.HTTPTransportSettings),
+		truststore.TransportOption(&config.HTTPTransportSettings),
+		revocation.TransportOption(&config.HTTPTransportSettings),
 		httpcommon.WithModRoundtripper(func(rt http.RoundTripper) http.RoundTripper {
-			return download.WithHeaders(rt, download.Headers)
+			return download.WithHeaders(rt, download.ConfigHeaders(config))
 		}),
 	)
 	if err != nil {
@@ -64,7 +71,7 @@ func NewVerifier(log *logger.Logger, config *artifact.Config, allowEmptyPgp bool
 // Verify checks downloaded package on preconfigured
 // location against a key stored on elastic.co website.
 func (v *Verifier) Verify(a artifact.Artifact, version string, pgpBytes ...string) error {
-	filename, err := artifact.GetArtifactName(a, version, v.config.OS(), v.config.Arch())
+	filename, err := artifact.GetArtifactName(a, version, v.config.OS(), v.config.Arch(), v.config.Package())
 	if err != nil {
 		return errors.New(err, "retrieving package name")
 	}
@@ -91,12 +98,27 @@ func (v *Verifier) Verify(a artifact.Artifact, version string, pgpBytes ...strin
 	return nil
 }
 
+// SetWarner sets the warner reporting the PGP keys which could not be loaded.
+func (v *Verifier) SetWarner(w download.Warner) {
+	v.warn = w
+}
+
+func (v *Verifier) warning(msg string) {
+	v.log.Warnf("%s", msg)
+	if v.warn != nil {
+		v.warn(msg)
+	}
+}
+
 func (v *Verifier) Reload(c *artifact.Config) error {
 	// reload client
 	client, err := c.HTTPTransportSettings.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&c.HTTPTransportSettings),
+		truststore.TransportOption(&c.HTTPTransportSettings),
+		revocation.TransportOption(&c.HTTPTransportSettings),
 		httpcommon.WithModRoundtripper(func(rt http.RoundTripper) http.RoundTripper {
-			return download.WithHeaders(rt, download.Headers)
+			return download.WithHeaders(rt, download.ConfigHeaders(c))
 		}),
 	)
 	if err != nil {
@@ -116,20 +138,7 @@ func (v *Verifier) verifyAsc(fullPath string, pgpSources ...string) error {
 		pgpBytes = append(pgpBytes, v.pgpBytes)
 	}
 
-	for _, check := range pgpSources {
-		if len(check) == 0 {
-			continue
-		}
-		raw, err := download.PgpBytesFromSource(check, v.client)
-		if err != nil {
-			return err
-		}
-		if len(raw) == 0 {
-			continue
-		}
-
-		pgpBytes = append(pgpBytes, raw)
-	}
+	pgpBytes = append(pgpBytes, download.PgpBytesFromSources(v.client, v.warning, pgpSources...)...)
 
 	if len(pgpBytes) == 0 {
 		// no pgp available skip verification process
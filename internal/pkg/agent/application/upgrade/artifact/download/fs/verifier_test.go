@@ -220,7 +220,7 @@ func TestVerify(t *testing.T) {
 }
 
 func prepareTestCase(a artifact.Artifact, version string, cfg *artifact.Config) error {
-	filename, err := artifact.GetArtifactName(a, version, cfg.OperatingSystem, cfg.Architecture)
+	filename, err := artifact.GetArtifactName(a, version, cfg.OperatingSystem, cfg.Architecture, cfg.Package())
 	if err != nil {
 		return err
 	}
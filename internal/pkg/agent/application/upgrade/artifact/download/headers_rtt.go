@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 )
 
@@ -33,3 +34,19 @@ func (r *rttWithHeaders) RoundTrip(req *http.Request) (*http.Response, error) {
 	}
 	return r.target.RoundTrip(req)
 }
+
+// ConfigHeaders returns the headers of the requests of the downloads, the headers configured with
+// agent.download.headers are added to the default ones and take precedence.
+func ConfigHeaders(config *artifact.Config) map[string]string {
+	if len(config.Headers) == 0 {
+		return Headers
+	}
+	headers := make(map[string]string, len(Headers)+len(config.Headers))
+	for k, v := range Headers {
+		headers[k] = v
+	}
+	for k, v := range config.Headers {
+		headers[k] = v
+	}
+	return headers
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 )
 
@@ -36,3 +37,14 @@ func TestAddingHeaders(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, b, msg)
 }
+
+func TestConfigHeaders(t *testing.T) {
+	assert.Equal(t, Headers, ConfigHeaders(&artifact.Config{}))
+
+	headers := ConfigHeaders(&artifact.Config{Headers: map[string]string{
+		"X-Org-Token": "secret",
+		"User-Agent":  "gateway",
+	}})
+	assert.Equal(t, map[string]string{"X-Org-Token": "secret", "User-Agent": "gateway"}, headers)
+	assert.Equal(t, fmt.Sprintf("Beat elastic-agent v%s", release.Version()), Headers["User-Agent"], "the default headers are not modified")
+}
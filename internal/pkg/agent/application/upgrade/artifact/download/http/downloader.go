@@ -25,6 +25,9 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 )
 
 const (
@@ -51,13 +54,16 @@ type Downloader struct {
 func NewDownloader(log progressLogger, config *artifact.Config) (*Downloader, error) {
 	client, err := config.HTTPTransportSettings.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&config.HTTPTransportSettings),
+		truststore.TransportOption(&config.HTTPTransportSettings),
+		revocation.TransportOption(&config.HTTPTransportSettings),
 		httpcommon.WithKeepaliveSettings{Disable: false, IdleConnTimeout: 30 * time.Second},
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	client.Transport = download.WithHeaders(client.Transport, download.Headers)
+	client.Transport = download.WithHeaders(client.Transport, download.ConfigHeaders(config))
 	return NewDownloaderWithClient(log, config, *client), nil
 }
 
@@ -74,12 +80,15 @@ func (e *Downloader) Reload(c *artifact.Config) error {
 	// reload client
 	client, err := c.HTTPTransportSettings.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&c.HTTPTransportSettings),
+		truststore.TransportOption(&c.HTTPTransportSettings),
+		revocation.TransportOption(&c.HTTPTransportSettings),
 	)
 	if err != nil {
 		return errors.New(err, "http.downloader: failed to generate client out of config")
 	}
 
-	client.Transport = download.WithHeaders(client.Transport, download.Headers)
+	client.Transport = download.WithHeaders(client.Transport, download.ConfigHeaders(c))
 
 	e.client = *client
 	e.config = c
@@ -114,6 +123,40 @@ func (e *Downloader) Download(ctx context.Context, a artifact.Artifact, version
 	return path, err
 }
 
+// Exists checks whether the package is published at the configured source
+// without downloading it.
+func (e *Downloader) Exists(ctx context.Context, a artifact.Artifact, version string) (bool, error) {
+	filename, err := artifact.GetArtifactName(a, version, e.config.OS(), e.config.Arch(), e.config.Package())
+	if err != nil {
+		return false, errors.New(err, "generating package name failed")
+	}
+
+	sourceURI, err := e.composeURI(a.Artifact, filename)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, sourceURI, nil)
+	if err != nil {
+		return false, errors.New(err, "checking package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return false, errors.New(err, "checking package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, errors.New(fmt.Sprintf("call to '%s' returned unsuccessful status code: %d", sourceURI, resp.StatusCode), errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+}
+
 func (e *Downloader) composeURI(artifactName, packageName string) (string, error) {
 	upstream := e.config.SourceURI
 	if !strings.HasPrefix(upstream, "http") && !strings.HasPrefix(upstream, "file") && !strings.HasPrefix(upstream, "/") {
@@ -132,12 +175,12 @@ func (e *Downloader) composeURI(artifactName, packageName string) (string, error
 }
 
 func (e *Downloader) download(ctx context.Context, remoteArtifact string, operatingSystem string, a artifact.Artifact, version string) (string, error) {
-	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch())
+	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch(), e.config.Package())
 	if err != nil {
 		return "", errors.New(err, "generating package name failed")
 	}
 
-	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.TargetDirectory)
+	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.Package(), e.config.TargetDirectory)
 	if err != nil {
 		return "", errors.New(err, "generating package path failed")
 	}
@@ -146,12 +189,12 @@ func (e *Downloader) download(ctx context.Context, remoteArtifact string, operat
 }
 
 func (e *Downloader) downloadHash(ctx context.Context, remoteArtifact string, operatingSystem string, a artifact.Artifact, version string) (string, error) {
-	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch())
+	filename, err := artifact.GetArtifactName(a, version, operatingSystem, e.config.Arch(), e.config.Package())
 	if err != nil {
 		return "", errors.New(err, "generating package name failed")
 	}
 
-	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.TargetDirectory)
+	fullPath, err := artifact.GetArtifactPath(a, version, operatingSystem, e.config.Arch(), e.config.Package(), e.config.TargetDirectory)
 	if err != nil {
 		return "", errors.New(err, "generating package path failed")
 	}
@@ -18,6 +18,9 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 )
 
 const (
@@ -31,6 +34,7 @@ type Verifier struct {
 	client        http.Client
 	pgpBytes      []byte
 	allowEmptyPgp bool
+	warn          download.Warner
 	log           progressLogger
 }
 
@@ -43,8 +47,11 @@ func NewVerifier(log progressLogger, config *artifact.Config, allowEmptyPgp bool
 
 	client, err := config.HTTPTransportSettings.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&config.HTTPTransportSettings),
+		truststore.TransportOption(&config.HTTPTransportSettings),
+		revocation.TransportOption(&config.HTTPTransportSettings),
 		httpcommon.WithModRoundtripper(func(rt http.RoundTripper) http.RoundTripper {
-			return download.WithHeaders(rt, download.Headers)
+			return download.WithHeaders(rt, download.ConfigHeaders(config))
 		}),
 	)
 	if err != nil {
@@ -62,12 +69,27 @@ func NewVerifier(log progressLogger, config *artifact.Config, allowEmptyPgp bool
 	return v, nil
 }
 
+// SetWarner sets the warner reporting the PGP keys which could not be loaded.
+func (v *Verifier) SetWarner(w download.Warner) {
+	v.warn = w
+}
+
+func (v *Verifier) warning(msg string) {
+	v.log.Warnf("%s", msg)
+	if v.warn != nil {
+		v.warn(msg)
+	}
+}
+
 func (v *Verifier) Reload(c *artifact.Config) error {
 	// reload client
 	client, err := c.HTTPTransportSettings.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&c.HTTPTransportSettings),
+		truststore.TransportOption(&c.HTTPTransportSettings),
+		revocation.TransportOption(&c.HTTPTransportSettings),
 		httpcommon.WithModRoundtripper(func(rt http.RoundTripper) http.RoundTripper {
-			return download.WithHeaders(rt, download.Headers)
+			return download.WithHeaders(rt, download.ConfigHeaders(c))
 		}),
 	)
 	if err != nil {
@@ -83,7 +105,7 @@ func (v *Verifier) Reload(c *artifact.Config) error {
 // Verify checks downloaded package on preconfigured
 // location against a key stored on elastic.co website.
 func (v *Verifier) Verify(a artifact.Artifact, version string, pgpBytes ...string) error {
-	fullPath, err := artifact.GetArtifactPath(a, version, v.config.OS(), v.config.Arch(), v.config.TargetDirectory)
+	fullPath, err := artifact.GetArtifactPath(a, version, v.config.OS(), v.config.Arch(), v.config.Package(), v.config.TargetDirectory)
 	if err != nil {
 		return errors.New(err, "retrieving package path")
 	}
@@ -115,20 +137,7 @@ func (v *Verifier) verifyAsc(a artifact.Artifact, version string, pgpSources ...
 		pgpBytes = append(pgpBytes, v.pgpBytes)
 	}
 
-	for _, check := range pgpSources {
-		if len(check) == 0 {
-			continue
-		}
-		raw, err := download.PgpBytesFromSource(check, v.client)
-		if err != nil {
-			return err
-		}
-		if len(raw) == 0 {
-			continue
-		}
-
-		pgpBytes = append(pgpBytes, raw)
-	}
+	pgpBytes = append(pgpBytes, download.PgpBytesFromSources(v.client, v.warning, pgpSources...)...)
 
 	if len(pgpBytes) == 0 {
 		// no pgp available skip verification process
@@ -137,12 +146,12 @@ func (v *Verifier) verifyAsc(a artifact.Artifact, version string, pgpSources ...
 	}
 	v.log.Infof("Using %d PGP keys", len(pgpBytes))
 
-	filename, err := artifact.GetArtifactName(a, version, v.config.OS(), v.config.Arch())
+	filename, err := artifact.GetArtifactName(a, version, v.config.OS(), v.config.Arch(), v.config.Package())
 	if err != nil {
 		return errors.New(err, "retrieving package name")
 	}
 
-	fullPath, err := artifact.GetArtifactPath(a, version, v.config.OS(), v.config.Arch(), v.config.TargetDirectory)
+	fullPath, err := artifact.GetArtifactPath(a, version, v.config.OS(), v.config.Arch(), v.config.Package(), v.config.TargetDirectory)
 	if err != nil {
 		return errors.New(err, "retrieving package path")
 	}
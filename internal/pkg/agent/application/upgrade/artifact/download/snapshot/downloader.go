@@ -16,11 +16,25 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	agtversion "github.com/elastic/elastic-agent/pkg/version"
 )
 
-const snapshotURIFormat = "https://snapshots.elastic.co/%s-%s/downloads/"
+const (
+	// artifactsAPIURI is the API searching the latest snapshot build of a version.
+	artifactsAPIURI = "https://artifacts-api.elastic.co/v1/search/"
+	// snapshotsURI is the repository of the snapshot builds.
+	snapshotsURI      = "https://snapshots.elastic.co/"
+	snapshotURIFormat = snapshotsURI + "%s-%s/downloads/"
+)
+
+// SourceURIs returns the locations the snapshot builds are searched and downloaded from.
+func SourceURIs() []string {
+	return []string{artifactsAPIURI, snapshotsURI}
+}
 
 type Downloader struct {
 	downloader      download.Downloader
@@ -82,6 +96,7 @@ func snapshotConfig(config *artifact.Config, versionOverride *agtversion.ParsedS
 		TargetDirectory: config.TargetDirectory,
 		InstallPath:     config.InstallPath,
 		DropPath:        config.DropPath,
+		PackageType:     config.PackageType,
 
 		HTTPTransportSettings: config.HTTPTransportSettings,
 	}, nil
@@ -102,12 +117,18 @@ func snapshotURI(versionOverride *agtversion.ParsedSemVer, config *artifact.Conf
 	}
 
 	// we go through the artifact API to find the location of the latest snapshot build for the specified version
-	client, err := config.HTTPTransportSettings.Client(httpcommon.WithAPMHTTPInstrumentation())
+	client, err := config.HTTPTransportSettings.Client(
+		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&config.HTTPTransportSettings),
+		truststore.TransportOption(&config.HTTPTransportSettings),
+		revocation.TransportOption(&config.HTTPTransportSettings),
+	)
 	if err != nil {
 		return "", err
 	}
+	client.Transport = download.WithHeaders(client.Transport, download.ConfigHeaders(config))
 
-	artifactsURI := fmt.Sprintf("https://artifacts-api.elastic.co/v1/search/%s-SNAPSHOT/elastic-agent", version)
+	artifactsURI := fmt.Sprintf(artifactsAPIURI+"%s-SNAPSHOT/elastic-agent", version)
 	resp, err := client.Get(artifactsURI)
 	if err != nil {
 		return "", err
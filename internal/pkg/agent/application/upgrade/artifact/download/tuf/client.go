@@ -0,0 +1,427 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tuf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const (
+	// maximum number of versions of the root fetched by one update.
+	maxRootRotations = 32
+
+	// maximum lengths of the metadata files whose length is not listed by other metadata.
+	maxRootLength      = 512 * 1024
+	maxTimestampLength = 16 * 1024
+	maxMetadataLength  = 16 * 1024 * 1024
+
+	rootFile      = "root.json"
+	timestampFile = "timestamp.json"
+	snapshotFile  = "snapshot.json"
+	targetsFile   = "targets.json"
+)
+
+var errNotFound = errors.New("not found")
+
+// Client keeps the metadata of a TUF repository up to date. The root metadata is rotated from the
+// initial trusted root and the top-level metadata is checked against its signatures, versions and
+// expiration, so the repository can rotate its keys and an attacker can not freeze or roll back
+// the artifacts served to the agent. Delegated targets are not followed.
+type Client struct {
+	metadataURI string
+	client      *http.Client
+	directory   string
+	now         func() time.Time
+
+	root      *Root
+	timestamp *Timestamp
+	snapshot  *Snapshot
+	targets   *Targets
+}
+
+// NewClient creates a client of the repository at metadataURI storing the trusted metadata in
+// directory. The initial root is used when directory does not contain a trusted root yet.
+func NewClient(metadataURI string, client *http.Client, directory string, initialRoot []byte) (*Client, error) {
+	if !strings.HasSuffix(metadataURI, "/") {
+		metadataURI += "/"
+	}
+	c := &Client{
+		metadataURI: metadataURI,
+		client:      client,
+		directory:   directory,
+		now:         time.Now,
+	}
+	if err := os.MkdirAll(directory, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create TUF metadata directory: %w", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(directory, rootFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if len(initialRoot) == 0 {
+			return nil, errors.New("no trusted TUF root metadata")
+		}
+		raw = initialRoot
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read trusted root metadata: %w", err)
+	}
+
+	var root Root
+	if err := verifyRoot(raw, &root); err != nil {
+		return nil, fmt.Errorf("invalid trusted root metadata: %w", err)
+	}
+	c.root = &root
+	if err := c.persist(rootFile, raw); err != nil {
+		return nil, err
+	}
+	c.loadTrusted()
+	return c, nil
+}
+
+// SetHTTPClient replaces the HTTP client used to fetch the metadata.
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// Update refreshes the trusted metadata from the repository.
+func (c *Client) Update(ctx context.Context) error {
+	if err := c.updateRoot(ctx); err != nil {
+		return err
+	}
+	if err := c.updateTimestamp(ctx); err != nil {
+		return err
+	}
+	if err := c.updateSnapshot(ctx); err != nil {
+		return err
+	}
+	return c.updateTargets(ctx)
+}
+
+// Target returns the trusted description of a target, Update must be called first.
+func (c *Client) Target(name string) (TargetFile, error) {
+	if c.targets == nil {
+		return TargetFile{}, errors.New("no trusted targets metadata")
+	}
+	target, ok := c.targets.Targets[name]
+	if !ok {
+		return TargetFile{}, fmt.Errorf("target %q is not listed by the trusted targets metadata", name)
+	}
+	return target, nil
+}
+
+// Versions returns the versions of the artifact listed by the trusted targets metadata in
+// ascending order, Update must be called first.
+func (c *Client) Versions(a artifact.Artifact, operatingSystem, arch, packageType string) ([]string, error) {
+	if c.targets == nil {
+		return nil, errors.New("no trusted targets metadata")
+	}
+	const placeholder = "{version}"
+	name, err := artifact.GetArtifactName(a, placeholder, operatingSystem, arch, packageType)
+	if err != nil {
+		return nil, err
+	}
+	prefix, suffix, _ := strings.Cut(path.Join(a.Artifact, name), placeholder)
+
+	var versions agtversion.SortableParsedVersions
+	for target := range c.targets.Targets {
+		if !strings.HasPrefix(target, prefix) || !strings.HasSuffix(target, suffix) || len(target) <= len(prefix)+len(suffix) {
+			continue
+		}
+		version, err := agtversion.ParseVersion(target[len(prefix) : len(target)-len(suffix)])
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Sort(versions)
+
+	result := make([]string, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, v.Original())
+	}
+	return result, nil
+}
+
+// updateRoot rotates the trusted root through the newer versions published by the repository,
+// each of them must be signed by the keys of the previous and of the new root.
+func (c *Client) updateRoot(ctx context.Context) error {
+	previous := c.root
+	for i := 0; i < maxRootRotations; i++ {
+		next := c.root.Version + 1
+		raw, err := c.fetch(ctx, fmt.Sprintf("%d.%s", next, rootFile), maxRootLength)
+		if errors.Is(err, errNotFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var s Signed
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("failed to decode root metadata: %w", err)
+		}
+		if err := verifySignatures(&s, roleRoot, c.root.Keys, c.root.Roles[roleRoot]); err != nil {
+			return fmt.Errorf("root metadata version %d is not signed by the trusted root: %w", next, err)
+		}
+		var root Root
+		if err := verifyRoot(raw, &root); err != nil {
+			return fmt.Errorf("invalid root metadata version %d: %w", next, err)
+		}
+		if root.Version != next {
+			return fmt.Errorf("%w: expected root metadata version %d, got %d", ErrRollback, next, root.Version)
+		}
+		c.root = &root
+		if err := c.persist(rootFile, raw); err != nil {
+			return err
+		}
+	}
+
+	if c.root.Expires.Before(c.now()) {
+		return fmt.Errorf("%w: root metadata version %d expired at %s", ErrExpired, c.root.Version, c.root.Expires)
+	}
+
+	// the trusted metadata signed by rotated keys can not be used to detect rollbacks anymore
+	if !sameRole(previous.Roles[roleTimestamp], c.root.Roles[roleTimestamp]) {
+		c.timestamp = nil
+		c.remove(timestampFile)
+	}
+	if !sameRole(previous.Roles[roleSnapshot], c.root.Roles[roleSnapshot]) {
+		c.snapshot = nil
+		c.remove(snapshotFile)
+	}
+	return nil
+}
+
+func (c *Client) updateTimestamp(ctx context.Context) error {
+	raw, err := c.fetch(ctx, timestampFile, maxTimestampLength)
+	if err != nil {
+		return err
+	}
+	var timestamp Timestamp
+	if err := verifySigned(raw, roleTimestamp, c.root.Keys, c.root.Roles[roleTimestamp], &timestamp); err != nil {
+		return err
+	}
+	snapshotMeta, ok := timestamp.Meta[snapshotFile]
+	if !ok {
+		return fmt.Errorf("timestamp metadata does not list %s", snapshotFile)
+	}
+	if c.timestamp != nil {
+		if timestamp.Version < c.timestamp.Version {
+			return fmt.Errorf("%w: timestamp metadata version %d is older than the trusted version %d", ErrRollback, timestamp.Version, c.timestamp.Version)
+		}
+		if trusted := c.timestamp.Meta[snapshotFile]; snapshotMeta.Version < trusted.Version {
+			return fmt.Errorf("%w: snapshot metadata version %d is older than the trusted version %d", ErrRollback, snapshotMeta.Version, trusted.Version)
+		}
+	}
+	if timestamp.Expires.Before(c.now()) {
+		return fmt.Errorf("%w: timestamp metadata version %d expired at %s", ErrExpired, timestamp.Version, timestamp.Expires)
+	}
+	c.timestamp = &timestamp
+	return c.persist(timestampFile, raw)
+}
+
+func (c *Client) updateSnapshot(ctx context.Context) error {
+	meta := c.timestamp.Meta[snapshotFile]
+	raw, err := c.fetchMeta(ctx, snapshotFile, meta)
+	if err != nil {
+		return err
+	}
+	var snapshot Snapshot
+	if err := verifySigned(raw, roleSnapshot, c.root.Keys, c.root.Roles[roleSnapshot], &snapshot); err != nil {
+		return err
+	}
+	if snapshot.Version != meta.Version {
+		return fmt.Errorf("expected snapshot metadata version %d, got %d", meta.Version, snapshot.Version)
+	}
+	if c.snapshot != nil {
+		for name, trusted := range c.snapshot.Meta {
+			current, ok := snapshot.Meta[name]
+			if !ok {
+				return fmt.Errorf("%w: snapshot metadata does not list %s anymore", ErrRollback, name)
+			}
+			if current.Version < trusted.Version {
+				return fmt.Errorf("%w: %s version %d is older than the trusted version %d", ErrRollback, name, current.Version, trusted.Version)
+			}
+		}
+	}
+	if snapshot.Expires.Before(c.now()) {
+		return fmt.Errorf("%w: snapshot metadata version %d expired at %s", ErrExpired, snapshot.Version, snapshot.Expires)
+	}
+	c.snapshot = &snapshot
+	return c.persist(snapshotFile, raw)
+}
+
+func (c *Client) updateTargets(ctx context.Context) error {
+	meta, ok := c.snapshot.Meta[targetsFile]
+	if !ok {
+		return fmt.Errorf("snapshot metadata does not list %s", targetsFile)
+	}
+	if c.targets != nil && c.targets.Version == meta.Version {
+		// the trusted targets are current, only their expiration must be checked again
+		if c.targets.Expires.Before(c.now()) {
+			return fmt.Errorf("%w: targets metadata version %d expired at %s", ErrExpired, c.targets.Version, c.targets.Expires)
+		}
+		return nil
+	}
+
+	raw, err := c.fetchMeta(ctx, targetsFile, meta)
+	if err != nil {
+		return err
+	}
+	var targets Targets
+	if err := verifySigned(raw, roleTargets, c.root.Keys, c.root.Roles[roleTargets], &targets); err != nil {
+		return err
+	}
+	if targets.Version != meta.Version {
+		return fmt.Errorf("expected targets metadata version %d, got %d", meta.Version, targets.Version)
+	}
+	if targets.Expires.Before(c.now()) {
+		return fmt.Errorf("%w: targets metadata version %d expired at %s", ErrExpired, targets.Version, targets.Expires)
+	}
+	c.targets = &targets
+	return c.persist(targetsFile, raw)
+}
+
+// fetchMeta fetches a metadata file listed by other metadata and checks its length and hashes.
+func (c *Client) fetchMeta(ctx context.Context, name string, meta MetaFile) ([]byte, error) {
+	remoteName := name
+	if c.root.ConsistentSnapshot {
+		remoteName = fmt.Sprintf("%d.%s", meta.Version, name)
+	}
+	maxLength := int64(maxMetadataLength)
+	if meta.Length > 0 {
+		maxLength = meta.Length
+	}
+	raw, err := c.fetch(ctx, remoteName, maxLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyHashes(raw, meta.Length, meta.Hashes); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return raw, nil
+}
+
+// fetch downloads a metadata file from the repository, refusing files longer than maxLength.
+func (c *Client) fetch(ctx context.Context, name string, maxLength int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.metadataURI+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusForbidden:
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, errNotFound)
+	default:
+		return nil, fmt.Errorf("failed to fetch %s: unexpected status %s", name, resp.Status)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxLength+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", name, err)
+	}
+	if int64(len(raw)) > maxLength {
+		return nil, fmt.Errorf("failed to fetch %s: longer than %d bytes", name, maxLength)
+	}
+	return raw, nil
+}
+
+// loadTrusted loads the timestamp, snapshot and targets metadata trusted by the previous runs,
+// so rollbacks are detected across restarts. Metadata which is not signed by the keys of the
+// trusted root is ignored.
+func (c *Client) loadTrusted() {
+	var timestamp Timestamp
+	if c.loadFile(timestampFile, roleTimestamp, &timestamp) {
+		c.timestamp = &timestamp
+	}
+	var snapshot Snapshot
+	if c.loadFile(snapshotFile, roleSnapshot, &snapshot) {
+		c.snapshot = &snapshot
+	}
+	var targets Targets
+	if c.loadFile(targetsFile, roleTargets, &targets) {
+		c.targets = &targets
+	}
+}
+
+func (c *Client) loadFile(name, roleName string, v interface{}) bool {
+	raw, err := os.ReadFile(filepath.Join(c.directory, name))
+	if err != nil {
+		return false
+	}
+	return verifySigned(raw, roleName, c.root.Keys, c.root.Roles[roleName], v) == nil
+}
+
+func (c *Client) persist(name string, raw []byte) error {
+	dst := filepath.Join(c.directory, name)
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0640); err != nil {
+		return fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	return nil
+}
+
+func (c *Client) remove(name string) {
+	_ = os.Remove(filepath.Join(c.directory, name))
+}
+
+// verifyRoot checks that the root metadata is signed by its own root keys.
+func verifyRoot(raw []byte, root *Root) error {
+	var s Signed
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("failed to decode root metadata: %w", err)
+	}
+	if err := decodeSigned(&s, roleRoot, root); err != nil {
+		return err
+	}
+	for _, name := range []string{roleRoot, roleTimestamp, roleSnapshot, roleTargets} {
+		if _, ok := root.Roles[name]; !ok {
+			return fmt.Errorf("root metadata does not define the %s role", name)
+		}
+	}
+	return verifySignatures(&s, roleRoot, root.Keys, root.Roles[roleRoot])
+}
+
+// sameRole returns whether both roles are signed by the same keys.
+func sameRole(a, b *Role) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Threshold != b.Threshold || len(a.KeyIDs) != len(b.KeyIDs) {
+		return false
+	}
+	ids := make(map[string]bool, len(a.KeyIDs))
+	for _, id := range a.KeyIDs {
+		ids[id] = true
+	}
+	for _, id := range b.KeyIDs {
+		if !ids[id] {
+			return false
+		}
+	}
+	return true
+}
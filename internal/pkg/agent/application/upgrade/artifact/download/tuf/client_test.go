@@ -0,0 +1,291 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tuf
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+)
+
+var agentArtifact = artifact.Artifact{Name: "Elastic Agent", Cmd: "elastic-agent", Artifact: "beats/elastic-agent"}
+
+func TestClientUpdate(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.addTarget("beats/elastic-agent/elastic-agent-8.10.0-linux-x86_64.tar.gz", []byte("8.10.0"))
+	repo.addTarget("beats/elastic-agent/elastic-agent-8.9.1-linux-x86_64.tar.gz", []byte("8.9.1"))
+	repo.addTarget("beats/elastic-agent/elastic-agent-8.9.1-windows-x86_64.zip", []byte("8.9.1"))
+	repo.publish()
+
+	client := repo.newClient(t.TempDir())
+	require.NoError(t, client.Update(context.Background()))
+
+	target, err := client.Target("beats/elastic-agent/elastic-agent-8.10.0-linux-x86_64.tar.gz")
+	require.NoError(t, err)
+	assert.EqualValues(t, len("8.10.0"), target.Length)
+
+	_, err = client.Target("beats/elastic-agent/elastic-agent-8.11.0-linux-x86_64.tar.gz")
+	assert.Error(t, err)
+
+	versions, err := client.Versions(agentArtifact, "linux", "64", artifact.BinaryPackage)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"8.9.1", "8.10.0"}, versions)
+}
+
+func TestClientConsistentSnapshot(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.root.ConsistentSnapshot = true
+	repo.initialRoot = repo.signRoot(repo.keys[roleRoot])
+	repo.addTarget("beats/elastic-agent/elastic-agent-8.10.0-linux-x86_64.tar.gz", []byte("8.10.0"))
+	repo.publish()
+
+	client := repo.newClient(t.TempDir())
+	require.NoError(t, client.Update(context.Background()))
+	assert.Contains(t, repo.requested(), "1.snapshot.json")
+	assert.Contains(t, repo.requested(), "1.targets.json")
+}
+
+func TestClientRootRotation(t *testing.T) {
+	t.Run("rotated keys are trusted", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.publish()
+		dir := t.TempDir()
+		client := repo.newClient(dir)
+		require.NoError(t, client.Update(context.Background()))
+
+		// version 2 of the root rotates the root and the timestamp keys
+		oldRootKey := repo.keys[roleRoot]
+		repo.rotateKey(roleRoot)
+		repo.rotateKey(roleTimestamp)
+		repo.root.Version = 2
+		repo.files["2.root.json"] = repo.signRoot(oldRootKey, repo.keys[roleRoot])
+		repo.timestamp.Version = 0
+		repo.publish()
+
+		require.NoError(t, client.Update(context.Background()))
+		assert.EqualValues(t, 2, client.root.Version)
+
+		// the rotated root is persisted
+		restarted := repo.newClient(dir)
+		assert.EqualValues(t, 2, restarted.root.Version)
+	})
+
+	t.Run("root not signed by the trusted keys", func(t *testing.T) {
+		repo := newTestRepo(t)
+		repo.publish()
+		client := repo.newClient(t.TempDir())
+
+		repo.rotateKey(roleRoot)
+		repo.root.Version = 2
+		repo.files["2.root.json"] = repo.signRoot(repo.keys[roleRoot])
+
+		err := client.Update(context.Background())
+		assert.ErrorContains(t, err, "not signed by the trusted root")
+		assert.EqualValues(t, 1, client.root.Version)
+	})
+}
+
+func TestClientRollback(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.publish()
+	dir := t.TempDir()
+	client := repo.newClient(dir)
+	require.NoError(t, client.Update(context.Background()))
+
+	oldTimestamp := repo.files[timestampFile]
+	repo.publish()
+	require.NoError(t, client.Update(context.Background()))
+
+	// an attacker replays the previous timestamp, also after a restart of the agent
+	repo.files[timestampFile] = oldTimestamp
+	assert.ErrorIs(t, client.Update(context.Background()), ErrRollback)
+	assert.ErrorIs(t, repo.newClient(dir).Update(context.Background()), ErrRollback)
+}
+
+func TestClientExpired(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.targets.Expires = time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	repo.publish()
+
+	client := repo.newClient(t.TempDir())
+	assert.ErrorIs(t, client.Update(context.Background()), ErrExpired)
+}
+
+func TestClientTamperedMetadata(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.addTarget("beats/elastic-agent/elastic-agent-8.10.0-linux-x86_64.tar.gz", []byte("8.10.0"))
+	repo.publish()
+
+	// the targets are changed without updating the snapshot
+	repo.files[targetsFile] = []byte(strings.Replace(string(repo.files[targetsFile]), `"length":6`, `"length":7`, 1))
+
+	client := repo.newClient(t.TempDir())
+	assert.ErrorContains(t, client.Update(context.Background()), "invalid targets.json")
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	encoded, err := canonicalJSON([]byte(`{"b": [1, true, null], "a": "quote \" backslash \\ <tag>"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"quote \" backslash \\ <tag>","b":[1,true,null]}`, string(encoded))
+
+	_, err = canonicalJSON([]byte(`{"a": 1.5}`))
+	assert.Error(t, err)
+}
+
+// testRepo is a TUF repository served over HTTP with one ed25519 key per top-level role.
+type testRepo struct {
+	t      *testing.T
+	server *httptest.Server
+
+	keys        map[string]ed25519.PrivateKey
+	root        Root
+	initialRoot []byte
+	timestamp   Timestamp
+	snapshot    Snapshot
+	targets     Targets
+
+	mx       sync.Mutex
+	files    map[string][]byte
+	requests []string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	expires := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	r := &testRepo{
+		t:    t,
+		keys: make(map[string]ed25519.PrivateKey),
+		root: Root{
+			Type:        roleRoot,
+			SpecVersion: "1.0.31",
+			Version:     1,
+			Expires:     expires,
+			Keys:        make(map[string]*Key),
+			Roles:       make(map[string]*Role),
+		},
+		timestamp: Timestamp{Type: roleTimestamp, SpecVersion: "1.0.31", Expires: expires},
+		snapshot:  Snapshot{Type: roleSnapshot, SpecVersion: "1.0.31", Expires: expires},
+		targets:   Targets{Type: roleTargets, SpecVersion: "1.0.31", Expires: expires, Targets: make(map[string]TargetFile)},
+		files:     make(map[string][]byte),
+	}
+	for _, role := range []string{roleRoot, roleTimestamp, roleSnapshot, roleTargets} {
+		r.rotateKey(role)
+	}
+	r.initialRoot = r.signRoot(r.keys[roleRoot])
+
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mx.Lock()
+		defer r.mx.Unlock()
+		name := strings.TrimPrefix(req.URL.Path, "/tuf/")
+		r.requests = append(r.requests, name)
+		raw, ok := r.files[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(raw)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *testRepo) newClient(dir string) *Client {
+	client, err := NewClient(r.server.URL+"/tuf", r.server.Client(), dir, r.initialRoot)
+	require.NoError(r.t, err)
+	return client
+}
+
+func (r *testRepo) requested() []string {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return append([]string(nil), r.requests...)
+}
+
+// rotateKey replaces the key of the role in the root metadata.
+func (r *testRepo) rotateKey(role string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(r.t, err)
+	id := fmt.Sprintf("%x", sha256.Sum256(public))
+	r.keys[role] = private
+	r.root.Keys[id] = &Key{Type: keyTypeEd25519, Scheme: keyTypeEd25519, Value: KeyValue{Public: hex.EncodeToString(public)}}
+	r.root.Roles[role] = &Role{KeyIDs: []string{id}, Threshold: 1}
+}
+
+func (r *testRepo) addTarget(name string, content []byte) {
+	sum := sha512.Sum512(content)
+	r.targets.Targets[name] = TargetFile{
+		Length: int64(len(content)),
+		Hashes: map[string]string{"sha512": hex.EncodeToString(sum[:])},
+	}
+}
+
+func (r *testRepo) signRoot(keys ...ed25519.PrivateKey) []byte {
+	return r.sign(r.root, keys...)
+}
+
+// publish signs a new version of the timestamp, snapshot and targets metadata.
+func (r *testRepo) publish() {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.targets.Version++
+	targets := r.sign(r.targets, r.keys[roleTargets])
+	r.snapshot.Version++
+	r.snapshot.Meta = map[string]MetaFile{targetsFile: metaFile(r.targets.Version, targets)}
+	snapshot := r.sign(r.snapshot, r.keys[roleSnapshot])
+	r.timestamp.Version++
+	r.timestamp.Meta = map[string]MetaFile{snapshotFile: metaFile(r.snapshot.Version, snapshot)}
+
+	targetsName, snapshotName := targetsFile, snapshotFile
+	if r.root.ConsistentSnapshot {
+		targetsName = fmt.Sprintf("%d.%s", r.targets.Version, targetsFile)
+		snapshotName = fmt.Sprintf("%d.%s", r.snapshot.Version, snapshotFile)
+	}
+	r.files[targetsName] = targets
+	r.files[snapshotName] = snapshot
+	r.files[timestampFile] = r.sign(r.timestamp, r.keys[roleTimestamp])
+}
+
+func (r *testRepo) sign(v interface{}, keys ...ed25519.PrivateKey) []byte {
+	signed, err := json.Marshal(v)
+	require.NoError(r.t, err)
+	msg, err := canonicalJSON(signed)
+	require.NoError(r.t, err)
+
+	envelope := Signed{Signed: signed}
+	for _, key := range keys {
+		envelope.Signatures = append(envelope.Signatures, Signature{
+			KeyID: fmt.Sprintf("%x", sha256.Sum256(key.Public().(ed25519.PublicKey))),
+			Sig:   hex.EncodeToString(ed25519.Sign(key, msg)),
+		})
+	}
+	raw, err := json.Marshal(envelope)
+	require.NoError(r.t, err)
+	return raw
+}
+
+func metaFile(version int64, raw []byte) MetaFile {
+	sum := sha256.Sum256(raw)
+	return MetaFile{
+		Version: version,
+		Length:  int64(len(raw)),
+		Hashes:  map[string]string{"sha256": hex.EncodeToString(sum[:])},
+	}
+}
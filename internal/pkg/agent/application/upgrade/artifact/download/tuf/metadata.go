@@ -0,0 +1,342 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tuf
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	roleRoot      = "root"
+	roleTimestamp = "timestamp"
+	roleSnapshot  = "snapshot"
+	roleTargets   = "targets"
+
+	keyTypeEd25519 = "ed25519"
+	keyTypeECDSA   = "ecdsa-sha2-nistp256"
+)
+
+// Signed is the envelope of a metadata file, the signatures are computed over the canonical JSON
+// encoding of the signed part.
+type Signed struct {
+	Signed     json.RawMessage `json:"signed"`
+	Signatures []Signature     `json:"signatures"`
+}
+
+// Signature is the signature of the signed part of a metadata file by one key.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Key is a public key of the repository.
+type Key struct {
+	Type   string   `json:"keytype"`
+	Scheme string   `json:"scheme"`
+	Value  KeyValue `json:"keyval"`
+}
+
+// KeyValue is the value of a public key, hex encoded for ed25519 and PEM encoded for ECDSA.
+type KeyValue struct {
+	Public string `json:"public"`
+}
+
+// Role lists the keys trusted to sign the metadata of a role and how many of them must sign it.
+type Role struct {
+	KeyIDs    []string `json:"keyids"`
+	Threshold int      `json:"threshold"`
+}
+
+// Root is the metadata of the root role, it delegates the trust to the keys of the top-level roles.
+type Root struct {
+	Type               string           `json:"_type"`
+	SpecVersion        string           `json:"spec_version"`
+	Version            int64            `json:"version"`
+	Expires            time.Time        `json:"expires"`
+	ConsistentSnapshot bool             `json:"consistent_snapshot"`
+	Keys               map[string]*Key  `json:"keys"`
+	Roles              map[string]*Role `json:"roles"`
+}
+
+// MetaFile describes a metadata file listed by the timestamp and snapshot metadata.
+type MetaFile struct {
+	Version int64             `json:"version"`
+	Length  int64             `json:"length,omitempty"`
+	Hashes  map[string]string `json:"hashes,omitempty"`
+}
+
+// Timestamp is the metadata of the timestamp role, it points to the latest snapshot.
+type Timestamp struct {
+	Type        string              `json:"_type"`
+	SpecVersion string              `json:"spec_version"`
+	Version     int64               `json:"version"`
+	Expires     time.Time           `json:"expires"`
+	Meta        map[string]MetaFile `json:"meta"`
+}
+
+// Snapshot is the metadata of the snapshot role, it lists the versions of the targets metadata.
+type Snapshot struct {
+	Type        string              `json:"_type"`
+	SpecVersion string              `json:"spec_version"`
+	Version     int64               `json:"version"`
+	Expires     time.Time           `json:"expires"`
+	Meta        map[string]MetaFile `json:"meta"`
+}
+
+// Targets is the metadata of the targets role, it lists the artifacts of the repository.
+type Targets struct {
+	Type        string                `json:"_type"`
+	SpecVersion string                `json:"spec_version"`
+	Version     int64                 `json:"version"`
+	Expires     time.Time             `json:"expires"`
+	Targets     map[string]TargetFile `json:"targets"`
+}
+
+// TargetFile describes an artifact of the repository.
+type TargetFile struct {
+	Length int64             `json:"length"`
+	Hashes map[string]string `json:"hashes"`
+	Custom json.RawMessage   `json:"custom,omitempty"`
+}
+
+// ErrExpired is returned when trusted metadata expired.
+var ErrExpired = errors.New("metadata expired")
+
+// ErrRollback is returned when the repository serves metadata older than the trusted one.
+var ErrRollback = errors.New("metadata rollback")
+
+// verifySigned checks the signatures of the envelope against the keys of the role and unmarshals
+// the signed part into v.
+func verifySigned(raw []byte, roleName string, keys map[string]*Key, role *Role, v interface{}) error {
+	var s Signed
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("failed to decode %s metadata: %w", roleName, err)
+	}
+	if err := verifySignatures(&s, roleName, keys, role); err != nil {
+		return err
+	}
+	return decodeSigned(&s, roleName, v)
+}
+
+// decodeSigned unmarshals the signed part of the envelope into v and checks its type.
+func decodeSigned(s *Signed, roleName string, v interface{}) error {
+	var header struct {
+		Type string `json:"_type"`
+	}
+	if err := json.Unmarshal(s.Signed, &header); err != nil {
+		return fmt.Errorf("failed to decode %s metadata: %w", roleName, err)
+	}
+	if !strings.EqualFold(header.Type, roleName) {
+		return fmt.Errorf("expected %s metadata, got %q", roleName, header.Type)
+	}
+	if err := json.Unmarshal(s.Signed, v); err != nil {
+		return fmt.Errorf("failed to decode %s metadata: %w", roleName, err)
+	}
+	return nil
+}
+
+// verifySignatures checks that at least threshold distinct keys of the role signed the envelope.
+func verifySignatures(s *Signed, roleName string, keys map[string]*Key, role *Role) error {
+	if role == nil || role.Threshold < 1 {
+		return fmt.Errorf("invalid %s role", roleName)
+	}
+	msg, err := canonicalJSON(s.Signed)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s metadata: %w", roleName, err)
+	}
+
+	authorized := make(map[string]bool, len(role.KeyIDs))
+	for _, id := range role.KeyIDs {
+		authorized[id] = true
+	}
+	valid := make(map[string]bool)
+	for _, sig := range s.Signatures {
+		if !authorized[sig.KeyID] || valid[sig.KeyID] {
+			continue
+		}
+		key, ok := keys[sig.KeyID]
+		if !ok {
+			continue
+		}
+		if err := key.verify(msg, sig.Sig); err == nil {
+			valid[sig.KeyID] = true
+		}
+	}
+	if len(valid) < role.Threshold {
+		return fmt.Errorf("%s metadata has %d valid signatures, %d required", roleName, len(valid), role.Threshold)
+	}
+	return nil
+}
+
+// verify checks the hex encoded signature of the message.
+func (k *Key) verify(msg []byte, sig string) error {
+	rawSig, err := hex.DecodeString(sig)
+	if err != nil {
+		return err
+	}
+	switch k.Type {
+	case keyTypeEd25519:
+		public, err := hex.DecodeString(k.Value.Public)
+		if err != nil {
+			return err
+		}
+		if len(public) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid ed25519 key length %d", len(public))
+		}
+		if !ed25519.Verify(public, msg, rawSig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case keyTypeECDSA:
+		block, _ := pem.Decode([]byte(k.Value.Public))
+		if block == nil {
+			return errors.New("invalid PEM encoded key")
+		}
+		public, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return err
+		}
+		ecdsaKey, ok := public.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("not an ECDSA key")
+		}
+		digest := sha256.Sum256(msg)
+		if !ecdsa.VerifyASN1(ecdsaKey, digest[:], rawSig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %q", k.Type)
+	}
+}
+
+// verifyHashes checks the length and the hashes of data, at least one supported hash must be set.
+func verifyHashes(data []byte, length int64, hashes map[string]string) error {
+	if length > 0 && int64(len(data)) != length {
+		return fmt.Errorf("expected length %d, got %d", length, len(data))
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	checked := false
+	for alg, expected := range hashes {
+		h := newHash(alg)
+		if h == nil {
+			continue
+		}
+		h.Write(data)
+		if computed := hex.EncodeToString(h.Sum(nil)); computed != expected {
+			return fmt.Errorf("%s hash mismatch: expected %s, computed %s", alg, expected, computed)
+		}
+		checked = true
+	}
+	if !checked {
+		return errors.New("no supported hash algorithm")
+	}
+	return nil
+}
+
+// newHash returns the hash of a TUF hash algorithm, nil when it is not supported.
+func newHash(alg string) hash.Hash {
+	switch alg {
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	default:
+		return nil
+	}
+}
+
+// canonicalJSON encodes the JSON document in the canonical form of the OLPC specification used
+// by TUF: sorted keys, no insignificant whitespace and only quotes and backslashes escaped.
+func canonicalJSON(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := encodeCanonical(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeCanonical(buf *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if value {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return fmt.Errorf("floating point number %s is not canonical", value)
+		}
+		buf.WriteString(value.String())
+	case string:
+		buf.WriteByte('"')
+		for i := 0; i < len(value); i++ {
+			if c := value[i]; c == '"' || c == '\\' {
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(value[i])
+		}
+		buf.WriteByte('"')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeCanonical(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeCanonical(buf, value[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tuf
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Verifier verifies a downloaded package against the targets metadata of a TUF repository. The
+// PGP signatures are not used, the trust comes from the root metadata of the repository.
+type Verifier struct {
+	config *artifact.Config
+	client *Client
+	log    *logger.Logger
+}
+
+// NewVerifier creates a verifier checking downloaded packages against the TUF repository of the
+// configuration.
+func NewVerifier(log *logger.Logger, config *artifact.Config) (*Verifier, error) {
+	var initialRoot []byte
+	if config.TUF.RootPath != "" {
+		raw, err := os.ReadFile(config.TUF.RootPath)
+		if err != nil {
+			return nil, errors.New(err, "reading TUF root metadata", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, config.TUF.RootPath))
+		}
+		initialRoot = raw
+	}
+
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(MetadataURI(config), httpClient, config.TUF.MetadataDirectory, initialRoot)
+	if err != nil {
+		return nil, errors.New(err, "creating TUF client", errors.TypeSecurity)
+	}
+
+	return &Verifier{
+		config: config,
+		client: client,
+		log:    log,
+	}, nil
+}
+
+func (v *Verifier) Reload(c *artifact.Config) error {
+	httpClient, err := newHTTPClient(c)
+	if err != nil {
+		return errors.New(err, "tuf.verifier: failed to generate client out of config")
+	}
+
+	v.client.SetHTTPClient(httpClient)
+	v.client.metadataURI = MetadataURI(c)
+	v.config = c
+
+	return nil
+}
+
+// Verify refreshes the TUF metadata and checks the length and the hashes of the downloaded
+// package against its target. Packages not listed by the trusted targets are rejected.
+func (v *Verifier) Verify(a artifact.Artifact, version string, _ ...string) error {
+	fullPath, err := artifact.GetArtifactPath(a, version, v.config.OS(), v.config.Arch(), v.config.Package(), v.config.TargetDirectory)
+	if err != nil {
+		return errors.New(err, "retrieving package path")
+	}
+	filename, err := artifact.GetArtifactName(a, version, v.config.OS(), v.config.Arch(), v.config.Package())
+	if err != nil {
+		return errors.New(err, "retrieving package name")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.config.Timeout)
+	defer cancel()
+	if err := v.client.Update(ctx); err != nil {
+		return &download.InvalidSignatureError{File: fullPath, Err: fmt.Errorf("failed to update TUF metadata: %w", err)}
+	}
+
+	target, err := v.client.Target(path.Join(a.Artifact, filename))
+	if err != nil {
+		return &download.InvalidSignatureError{File: fullPath, Err: err}
+	}
+	v.log.Infof("Verifying %s against TUF targets metadata version %d", fullPath, v.client.targets.Version)
+
+	if err := verifyTarget(fullPath, target); err != nil {
+		var checksumMismatchErr *download.ChecksumMismatchError
+		if errors.As(err, &checksumMismatchErr) {
+			os.Remove(fullPath)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// verifyTarget checks the length and the SHA512 hash of the file against the target.
+func verifyTarget(filename string, target TargetFile) error {
+	expectedHash, ok := target.Hashes["sha512"]
+	if !ok {
+		return &download.InvalidSignatureError{File: filename, Err: fmt.Errorf("target does not have a sha512 hash")}
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.New(err, errors.TypeFilesystem, errors.M(errors.MetaKeyPath, filename))
+	}
+	defer f.Close()
+
+	hash := sha512.New()
+	length, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	computedHash := hex.EncodeToString(hash.Sum(nil))
+
+	if length != target.Length || computedHash != expectedHash {
+		return &download.ChecksumMismatchError{
+			Expected: expectedHash,
+			Computed: computedHash,
+			File:     filename,
+		}
+	}
+
+	return nil
+}
+
+// MetadataURI returns the location of the TUF metadata, the tuf/ directory of the source URI by default.
+func MetadataURI(config *artifact.Config) string {
+	if config.TUF.MetadataURI != "" {
+		return config.TUF.MetadataURI
+	}
+	return strings.TrimSuffix(config.SourceURI, "/") + "/tuf/"
+}
+
+func newHTTPClient(config *artifact.Config) (*http.Client, error) {
+	return config.HTTPTransportSettings.Client(
+		httpcommon.WithAPMHTTPInstrumentation(),
+		resolver.TransportOption(&config.HTTPTransportSettings),
+		truststore.TransportOption(&config.HTTPTransportSettings),
+		revocation.TransportOption(&config.HTTPTransportSettings),
+		httpcommon.WithModRoundtripper(func(rt http.RoundTripper) http.RoundTripper {
+			return download.WithHeaders(rt, download.ConfigHeaders(config))
+		}),
+	)
+}
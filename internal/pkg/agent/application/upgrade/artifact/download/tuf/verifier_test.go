@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tuf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestVerifier(t *testing.T) {
+	const version = "8.10.0"
+	content := []byte("elastic-agent package")
+
+	repo := newTestRepo(t)
+	repo.addTarget("beats/elastic-agent/elastic-agent-8.10.0-linux-x86_64.tar.gz", content)
+	repo.publish()
+
+	rootPath := filepath.Join(t.TempDir(), "root.json")
+	require.NoError(t, os.WriteFile(rootPath, repo.initialRoot, 0600))
+
+	newVerifier := func(t *testing.T) (*Verifier, string) {
+		config := &artifact.Config{
+			SourceURI:       repo.server.URL,
+			TargetDirectory: t.TempDir(),
+			OperatingSystem: "linux",
+			Architecture:    "64",
+			TUF: artifact.TUFConfig{
+				Enabled:           true,
+				RootPath:          rootPath,
+				MetadataDirectory: t.TempDir(),
+			},
+			HTTPTransportSettings: httpcommon.HTTPTransportSettings{
+				Timeout: 10 * time.Second,
+			},
+		}
+		log, _ := logger.New("", false)
+		v, err := NewVerifier(log, config)
+		require.NoError(t, err)
+		return v, config.TargetDirectory
+	}
+
+	t.Run("listed target", func(t *testing.T) {
+		v, dir := newVerifier(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "elastic-agent-8.10.0-linux-x86_64.tar.gz"), content, 0600))
+
+		assert.NoError(t, v.Verify(agentArtifact, version))
+	})
+
+	t.Run("tampered package", func(t *testing.T) {
+		v, dir := newVerifier(t)
+		packagePath := filepath.Join(dir, "elastic-agent-8.10.0-linux-x86_64.tar.gz")
+		require.NoError(t, os.WriteFile(packagePath, []byte("malicious package"), 0600))
+
+		err := v.Verify(agentArtifact, version)
+		var checksumMismatchErr *download.ChecksumMismatchError
+		assert.True(t, errors.As(err, &checksumMismatchErr), "expected a checksum mismatch, got %v", err)
+		assert.NoFileExists(t, packagePath)
+	})
+
+	t.Run("target not listed", func(t *testing.T) {
+		v, dir := newVerifier(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "elastic-agent-8.11.0-linux-x86_64.tar.gz"), content, 0600))
+
+		err := v.Verify(agentArtifact, "8.11.0")
+		var invalidSignatureErr *download.InvalidSignatureError
+		assert.True(t, errors.As(err, &invalidSignatureErr), "expected an invalid signature, got %v", err)
+	})
+}
@@ -146,20 +146,30 @@ func readChecksumFile(checksumFile, filename string) (string, error) {
 // check against. If there is a problem with the signature then a
 // *download.InvalidSignatureError is returned.
 func VerifyGPGSignature(file string, asciiArmorSignature, publicKey []byte) error {
-	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
-	if err != nil {
-		return errors.New(err, "read armored key ring", errors.TypeSecurity)
-	}
-
 	f, err := os.Open(file)
 	if err != nil {
 		return errors.New(err, errors.TypeFilesystem, errors.M(errors.MetaKeyPath, file))
 	}
 	defer f.Close()
 
-	_, err = openpgp.CheckArmoredDetachedSignature(keyring, f, bytes.NewReader(asciiArmorSignature))
+	return verifyGPGSignature(file, f, asciiArmorSignature, publicKey)
+}
+
+// VerifyGPGSignatureData verifies the GPG signature of data held in memory, name identifies
+// the data in the *download.InvalidSignatureError returned when the signature is invalid.
+func VerifyGPGSignatureData(name string, data, asciiArmorSignature, publicKey []byte) error {
+	return verifyGPGSignature(name, bytes.NewReader(data), asciiArmorSignature, publicKey)
+}
+
+func verifyGPGSignature(name string, r io.Reader, asciiArmorSignature, publicKey []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	if err != nil {
+		return errors.New(err, "read armored key ring", errors.TypeSecurity)
+	}
+
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, r, bytes.NewReader(asciiArmorSignature))
 	if err != nil {
-		return &InvalidSignatureError{File: file, Err: err}
+		return &InvalidSignatureError{File: name, Err: err}
 	}
 
 	return nil
@@ -177,6 +187,29 @@ func PgpBytesFromSource(source string, client http.Client) ([]byte, error) {
 	return nil, errors.New("unknown pgp source")
 }
 
+// PgpBytesFromSources loads the PGP keys of the sources. The keys are alternatives, a source
+// which can not be loaded is reported to warn and skipped, the remaining keys can still verify
+// the package.
+func PgpBytesFromSources(client http.Client, warn Warner, sources ...string) [][]byte {
+	var pgpBytes [][]byte
+	for _, source := range sources {
+		if len(source) == 0 {
+			continue
+		}
+		raw, err := PgpBytesFromSource(source, client)
+		if err != nil {
+			warn(fmt.Sprintf("failed to load PGP key from %s, verifying with the remaining keys: %v", strings.TrimPrefix(source, PgpSourceURIPrefix), err))
+			continue
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		pgpBytes = append(pgpBytes, raw)
+	}
+	return pgpBytes
+}
+
 func CheckValidDownloadUri(rawURI string) error {
 	uri, err := url.Parse(rawURI)
 	if err != nil {
@@ -0,0 +1,21 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package download
+
+// Warner is called with the non-fatal degradations of a download or a verification, such as a
+// fallback to another source or key, so they are reported with the result of the upgrade.
+type Warner func(msg string)
+
+// WarnerSetter is implemented by the downloaders and verifiers reporting their degradations.
+type WarnerSetter interface {
+	SetWarner(w Warner)
+}
+
+// SetWarner sets the warner of d when it reports its degradations.
+func SetWarner(d interface{}, w Warner) {
+	if setter, ok := d.(WarnerSetter); ok {
+		setter.SetWarner(w)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -33,6 +34,7 @@ type CrashChecker struct {
 	log           *logger.Logger
 	sc            serviceHandler
 	checkInterval time.Duration
+	clock         clock.Clock
 }
 
 // NewCrashChecker creates a new crash checker.
@@ -47,6 +49,7 @@ func NewCrashChecker(ctx context.Context, ch chan error, log *logger.Logger, che
 		q:             q,
 		log:           log,
 		checkInterval: checkInterval,
+		clock:         clock.Real(),
 	}
 
 	if err := c.Init(ctx, log); err != nil {
@@ -65,13 +68,13 @@ func (ch *CrashChecker) Run(ctx context.Context) {
 	ch.log.Debug("Crash checker started")
 	for {
 		ch.log.Debugf("watcher having PID: %d", os.Getpid())
-		t := time.NewTimer(ch.checkInterval)
+		t := ch.clock.NewTimer(ch.checkInterval)
 
 		select {
 		case <-ctx.Done():
 			t.Stop()
 			return
-		case <-t.C:
+		case <-t.C():
 			pid, err := ch.sc.PID(ctx)
 			if err != nil {
 				ch.log.Error(err)
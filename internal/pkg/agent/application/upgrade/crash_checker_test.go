@@ -12,6 +12,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -22,112 +23,85 @@ var (
 func TestChecker(t *testing.T) {
 	t.Run("no failure when no change", func(t *testing.T) {
 		pider := &testPider{}
-		ch, errChan := testableChecker(t, pider)
+		ch, errChan, clk := testableChecker(t, pider)
 		ctx, canc := context.WithCancel(context.Background())
+		defer canc()
+		go ch.Run(ctx)
 
-		var wg sync.WaitGroup
-		wg.Add(1)
-		go func() {
-			wg.Done()
-			ch.Run(ctx)
-		}()
-
-		wg.Wait()
-		<-time.After(6 * testCheckPeriod)
+		advance(clk, 6)
 
 		var err error
 		select {
 		case err = <-errChan:
 		default:
 		}
-
-		canc()
 		require.NoError(t, err)
 	})
 
 	t.Run("no failure when unfrequent change", func(t *testing.T) {
 		pider := &testPider{}
-		ch, errChan := testableChecker(t, pider)
+		ch, errChan, clk := testableChecker(t, pider)
 		ctx, canc := context.WithCancel(context.Background())
+		defer canc()
+		go ch.Run(ctx)
 
-		var wg sync.WaitGroup
-		wg.Add(1)
-		go func() {
-			wg.Done()
-			ch.Run(ctx)
-		}()
-
-		wg.Wait()
 		for i := 0; i < 2; i++ {
-			<-time.After(3 * testCheckPeriod)
+			advance(clk, 3)
 			pider.Change(i)
 		}
+		advance(clk, 1)
+
 		var err error
 		select {
 		case err = <-errChan:
 		default:
 		}
-
-		canc()
 		require.NoError(t, err)
 	})
 
 	t.Run("no failure when change lower than limit", func(t *testing.T) {
 		pider := &testPider{}
-		ch, errChan := testableChecker(t, pider)
+		ch, errChan, clk := testableChecker(t, pider)
 		ctx, canc := context.WithCancel(context.Background())
+		defer canc()
+		go ch.Run(ctx)
 
-		var wg sync.WaitGroup
-		wg.Add(1)
-		go func() {
-			wg.Done()
-			ch.Run(ctx)
-		}()
-
-		wg.Wait()
 		for i := 0; i < 3; i++ {
-			<-time.After(7 * testCheckPeriod)
+			advance(clk, 7)
 			pider.Change(i)
 		}
+		advance(clk, 1)
+
 		var err error
 		select {
 		case err = <-errChan:
 		default:
 		}
-
-		canc()
 		require.NoError(t, err)
 	})
 
 	t.Run("fails when pid changes frequently", func(t *testing.T) {
 		pider := &testPider{}
-		ch, errChan := testableChecker(t, pider)
+		ch, errChan, clk := testableChecker(t, pider)
 		ctx, canc := context.WithCancel(context.Background())
+		defer canc()
+		go ch.Run(ctx)
 
-		var wg sync.WaitGroup
-		wg.Add(1)
-		go func() {
-			wg.Done()
-			ch.Run(ctx)
-		}()
-
-		wg.Wait()
-		for i := 0; i < 12; i++ {
-			<-time.After(testCheckPeriod / 2)
+		for i := 1; i <= crashesAllowed+1; i++ {
 			pider.Change(i)
+			advance(clk, 1)
 		}
+
 		var err error
 		select {
 		case err = <-errChan:
 		default:
 		}
-
-		canc()
 		require.Error(t, err)
 	})
 }
 
-func testableChecker(t *testing.T, pider *testPider) (*CrashChecker, chan error) {
+func testableChecker(t *testing.T, pider *testPider) (*CrashChecker, chan error, *clock.Mock) {
 	errChan := make(chan error, 1)
 	l, _ := logger.New("", false)
 	ch, err := NewCrashChecker(context.Background(), errChan, l, testCheckPeriod)
@@ -135,8 +109,21 @@ func testableChecker(t *testing.T, pider *testPider) (*CrashChecker, chan error)
 
 	ch.sc.Close()
 	ch.sc = pider
+	clk := clock.NewMock(time.Now())
+	ch.clock = clk
+
+	return ch, errChan, clk
+}
 
-	return ch, errChan
+// advance advances the clock of the checker by the number of check periods, once done the last
+// check has been performed.
+func advance(clk *clock.Mock, periods int) {
+	for i := 0; i < periods; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(testCheckPeriod)
+	}
+	// the checker waits on a new timer once done with the check
+	clk.BlockUntil(1)
 }
 
 type testPider struct {
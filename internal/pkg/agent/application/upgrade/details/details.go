@@ -0,0 +1,203 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package details
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the stage of the upgrade lifecycle.
+type State string
+
+// The states of the upgrade lifecycle, in the order they are reached.
+const (
+	StateRequested   State = "UPG_REQUESTED"
+	StateScheduled   State = "UPG_SCHEDULED"
+	StateDownloading State = "UPG_DOWNLOADING"
+	StateVerifying   State = "UPG_VERIFYING"
+	StateApplying    State = "UPG_APPLYING"
+	StateWatching    State = "UPG_WATCHING"
+	StateCompleted   State = "UPG_COMPLETED"
+	StateRolledBack  State = "UPG_ROLLED_BACK"
+	StateFailed      State = "UPG_FAILED"
+)
+
+// Observer is called with a snapshot of the details every time they change.
+type Observer func(details *Details)
+
+// Details describes the progress of an upgrade. It is reported to Fleet on
+// check-in so the upgrade of every agent can be tracked.
+type Details struct {
+	TargetVersion string   `json:"target_version" yaml:"target_version"`
+	State         State    `json:"state" yaml:"state"`
+	ActionID      string   `json:"action_id,omitempty" yaml:"action_id,omitempty"`
+	Metadata      Metadata `json:"metadata" yaml:"metadata"`
+
+	mu        sync.Mutex
+	observers []Observer
+}
+
+// Metadata holds the additional information about the current state.
+type Metadata struct {
+	// ScheduledAt is the time a scheduled upgrade starts
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty" yaml:"scheduled_at,omitempty"`
+	// UpdatedAt is the time the current state was entered
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+	// FailedState is the state the upgrade failed in, only set with StateFailed
+	FailedState State `json:"failed_state,omitempty" yaml:"failed_state,omitempty"`
+	// ErrorMsg describes the failure, only set with StateFailed and StateRolledBack
+	ErrorMsg string `json:"error_msg,omitempty" yaml:"error_msg,omitempty"`
+	// Warnings describe the non-fatal degradations of the upgrade, e.g. a fallback to another source
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// NewDetails creates the details of an upgrade to targetVersion starting in initialState.
+func NewDetails(targetVersion string, initialState State, actionID string) *Details {
+	return &Details{
+		TargetVersion: targetVersion,
+		State:         initialState,
+		ActionID:      actionID,
+		Metadata: Metadata{
+			UpdatedAt: time.Now().UTC(),
+		},
+	}
+}
+
+// SetState moves the upgrade to state and notifies the observers.
+func (d *Details) SetState(s State) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	d.State = s
+	d.Metadata.UpdatedAt = time.Now().UTC()
+	notify := d.changed()
+	d.mu.Unlock()
+
+	notify()
+}
+
+// Fail moves the upgrade to StateFailed, recording the state the error happened in.
+func (d *Details) Fail(err error) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	// don't overwrite the state the upgrade originally failed in
+	if d.State != StateFailed {
+		d.Metadata.FailedState = d.State
+	}
+	d.State = StateFailed
+	d.Metadata.ErrorMsg = err.Error()
+	d.Metadata.UpdatedAt = time.Now().UTC()
+	notify := d.changed()
+	d.mu.Unlock()
+
+	notify()
+}
+
+// Warn records a non-fatal degradation of the upgrade and notifies the observers, a warning
+// already recorded is ignored.
+func (d *Details) Warn(msg string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	for _, w := range d.Metadata.Warnings {
+		if w == msg {
+			d.mu.Unlock()
+			return
+		}
+	}
+	d.Metadata.Warnings = append(d.Metadata.Warnings, msg)
+	notify := d.changed()
+	d.mu.Unlock()
+
+	notify()
+}
+
+// RegisterObserver registers o to be notified of every change of the details.
+func (d *Details) RegisterObserver(o Observer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.observers = append(d.observers, o)
+}
+
+// Clone returns a snapshot of the details without the observers.
+func (d *Details) Clone() *Details {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.clone()
+}
+
+// Equals returns true if both details describe the same upgrade in the same state.
+func (d *Details) Equals(other *Details) bool {
+	if d == nil || other == nil {
+		return d == other
+	}
+
+	a, b := d.Clone(), other.Clone()
+	return a.TargetVersion == b.TargetVersion &&
+		a.State == b.State &&
+		a.ActionID == b.ActionID &&
+		a.Metadata.UpdatedAt.Equal(b.Metadata.UpdatedAt) &&
+		a.Metadata.FailedState == b.Metadata.FailedState &&
+		a.Metadata.ErrorMsg == b.Metadata.ErrorMsg &&
+		equalWarnings(a.Metadata.Warnings, b.Metadata.Warnings)
+}
+
+func (d *Details) clone() *Details {
+	c := &Details{
+		TargetVersion: d.TargetVersion,
+		State:         d.State,
+		ActionID:      d.ActionID,
+		Metadata:      d.Metadata,
+	}
+	if d.Metadata.ScheduledAt != nil {
+		scheduledAt := *d.Metadata.ScheduledAt
+		c.Metadata.ScheduledAt = &scheduledAt
+	}
+	if d.Metadata.Warnings != nil {
+		c.Metadata.Warnings = append([]string(nil), d.Metadata.Warnings...)
+	}
+	return c
+}
+
+func equalWarnings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// changed returns the function notifying the observers of the current snapshot of the details.
+// It must be called with the lock held and the returned function once the lock is released, so
+// the observers can block or read the details without holding up the upgrade.
+func (d *Details) changed() func() {
+	observers := append([]Observer(nil), d.observers...)
+	snapshots := make([]*Details, len(observers))
+	for i := range observers {
+		snapshots[i] = d.clone()
+	}
+	return func() {
+		for i, o := range observers {
+			o(snapshots[i])
+		}
+	}
+}
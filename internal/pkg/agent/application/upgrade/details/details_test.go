@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package details
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetailsObserver(t *testing.T) {
+	det := NewDetails("8.9.0", StateScheduled, "action-id")
+
+	var observed []*Details
+	det.RegisterObserver(func(d *Details) {
+		observed = append(observed, d)
+	})
+
+	det.SetState(StateDownloading)
+	det.SetState(StateVerifying)
+	det.Fail(errors.New("verification failed"))
+
+	require.Len(t, observed, 3)
+	assert.Equal(t, StateDownloading, observed[0].State)
+	assert.Equal(t, StateVerifying, observed[1].State)
+	assert.Equal(t, StateFailed, observed[2].State)
+	assert.Equal(t, StateVerifying, observed[2].Metadata.FailedState)
+	assert.Equal(t, "verification failed", observed[2].Metadata.ErrorMsg)
+	assert.Equal(t, "8.9.0", observed[2].TargetVersion)
+	assert.Equal(t, "action-id", observed[2].ActionID)
+
+	// observers get snapshots, later changes don't affect them
+	det.SetState(StateApplying)
+	assert.Equal(t, StateDownloading, observed[0].State)
+}
+
+func TestDetailsFailKeepsFailedState(t *testing.T) {
+	det := NewDetails("8.9.0", StateDownloading, "")
+	det.Fail(errors.New("first"))
+	det.Fail(errors.New("second"))
+
+	assert.Equal(t, StateDownloading, det.Metadata.FailedState)
+	assert.Equal(t, "second", det.Metadata.ErrorMsg)
+}
+
+func TestDetailsEquals(t *testing.T) {
+	var nilDetails *Details
+	det := NewDetails("8.9.0", StateDownloading, "")
+
+	assert.True(t, nilDetails.Equals(nil))
+	assert.False(t, nilDetails.Equals(det))
+	assert.False(t, det.Equals(nil))
+	assert.True(t, det.Equals(det.Clone()))
+
+	other := det.Clone()
+	other.State = StateVerifying
+	assert.False(t, det.Equals(other))
+}
+
+func TestDetailsWarn(t *testing.T) {
+	det := NewDetails("8.9.0", StateDownloading, "")
+
+	var observed []*Details
+	det.RegisterObserver(func(d *Details) {
+		observed = append(observed, d)
+	})
+
+	det.Warn("fell back to the embedded PGP key")
+	det.Warn("fell back to the embedded PGP key")
+	det.Warn("download succeeded after 2 attempts")
+
+	require.Len(t, observed, 2, "a warning already recorded should not notify the observers")
+	assert.Equal(t, []string{"fell back to the embedded PGP key"}, observed[0].Metadata.Warnings)
+	assert.Equal(t, []string{"fell back to the embedded PGP key", "download succeeded after 2 attempts"}, det.Metadata.Warnings)
+
+	other := det.Clone()
+	assert.True(t, det.Equals(other))
+	other.Metadata.Warnings[1] = "changed"
+	assert.False(t, det.Equals(other))
+	assert.Equal(t, "download succeeded after 2 attempts", det.Metadata.Warnings[1], "clones should not share the warnings")
+}
+
+func TestDetailsObserverWithoutLock(t *testing.T) {
+	det := NewDetails("8.9.0", StateDownloading, "")
+
+	// an observer reading the details would deadlock if it was notified with the lock held
+	var observed *Details
+	det.RegisterObserver(func(d *Details) {
+		observed = det.Clone()
+	})
+
+	det.SetState(StateVerifying)
+	require.NotNil(t, observed)
+	assert.Equal(t, StateVerifying, observed.State)
+}
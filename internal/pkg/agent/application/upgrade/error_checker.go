@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -32,6 +33,7 @@ type ErrorChecker struct {
 	log             *logger.Logger
 	agentClient     client.Client
 	checkInterval   time.Duration
+	clock           clock.Clock
 }
 
 // NewErrorChecker creates a new error checker.
@@ -42,6 +44,7 @@ func NewErrorChecker(ch chan error, log *logger.Logger, checkInterval time.Durat
 		agentClient:   c,
 		log:           log,
 		checkInterval: checkInterval,
+		clock:         clock.Real(),
 	}
 
 	return ec, nil
@@ -51,12 +54,12 @@ func NewErrorChecker(ch chan error, log *logger.Logger, checkInterval time.Durat
 func (ch *ErrorChecker) Run(ctx context.Context) {
 	ch.log.Debug("Error checker started")
 	for {
-		t := time.NewTimer(ch.checkInterval)
+		t := ch.clock.NewTimer(ch.checkInterval)
 		select {
 		case <-ctx.Done():
 			t.Stop()
 			return
-		case <-t.C:
+		case <-t.C():
 			err := ch.agentClient.Connect(ctx)
 			if err != nil {
 				ch.failuresCounter++
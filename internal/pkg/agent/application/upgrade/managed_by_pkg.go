@@ -0,0 +1,137 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+)
+
+const managedByPkgFilename = ".upgrade-managed-by-pkg"
+
+// ManagedByPackageState is persisted when the upgrades of the Elastic Agent are managed by
+// an external package manager (apt, yum, SCCM...). While the state file exists the
+// Elastic Agent never upgrades itself, it only records the desired version and waits
+// for the package manager to install it.
+type ManagedByPackageState struct {
+	// Version is the desired version, empty when no upgrade is pending
+	Version string `yaml:"version,omitempty"`
+	// UpdatedOn marks the date the desired version was requested
+	UpdatedOn time.Time `yaml:"updated_on"`
+	// Action is the Fleet action requesting the desired version, acked once the version is installed
+	Action *MarkerActionUpgrade `yaml:"action,omitempty"`
+}
+
+// Waiting returns the version the Elastic Agent is waiting for the package manager to install,
+// or an empty string when the running version is the desired one.
+func (s *ManagedByPackageState) Waiting() string {
+	if s == nil || s.Version == "" || s.Version == currentVersion() {
+		return ""
+	}
+	return s.Version
+}
+
+// LoadManagedByPackage loads the package managed upgrade state. If the file does not
+// exist the upgrades are not managed by a package manager and it returns nil and no error.
+func LoadManagedByPackage() (*ManagedByPackageState, error) {
+	statePath := managedByPkgFilePath()
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errors.New(err, "failed to read package managed upgrade state", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, statePath))
+	}
+
+	state := &ManagedByPackageState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, errors.New(err, "failed to parse package managed upgrade state", errors.TypeConfig, errors.M(errors.MetaKeyPath, statePath))
+	}
+	return state, nil
+}
+
+// MarkManagedByPackage switches the Elastic Agent to upgrades managed by a package manager and
+// records version as the desired version.
+func MarkManagedByPackage(version string, action *fleetapi.ActionUpgrade) error {
+	return saveManagedByPackage(&ManagedByPackageState{
+		Version:   version,
+		UpdatedOn: time.Now().UTC(),
+		Action:    convertToMarkerAction(action),
+	})
+}
+
+// ClearManagedByPackage switches the Elastic Agent back to upgrading itself, a desired version not
+// installed yet is dropped. It returns false when the upgrades were not managed by a package manager.
+func ClearManagedByPackage() (bool, error) {
+	statePath := managedByPkgFilePath()
+	if err := os.Remove(statePath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, errors.New(err, "failed to remove package managed upgrade state", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, statePath))
+	}
+	return true, nil
+}
+
+func saveManagedByPackage(state *ManagedByPackageState) error {
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return errors.New(err, "failed to marshal package managed upgrade state", errors.TypeConfig)
+	}
+
+	statePath := managedByPkgFilePath()
+	if err := os.WriteFile(statePath, data, 0600); err != nil {
+		return errors.New(err, "failed to write package managed upgrade state", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, statePath))
+	}
+	return nil
+}
+
+// ackManagedByPackage acks the upgrade action once the package manager installed the desired version.
+// The state file is kept, so later upgrades are still managed by the package manager.
+func ackManagedByPackage(ctx context.Context, acker acker.Acker) error {
+	state, err := LoadManagedByPackage()
+	if err != nil || state == nil || state.Version == "" || state.Waiting() != "" {
+		return err
+	}
+
+	if action := convertToActionUpgrade(state.Action); action != nil {
+		if err := acker.Ack(ctx, action); err != nil {
+			return err
+		}
+		if err := acker.Commit(ctx); err != nil {
+			return err
+		}
+	}
+
+	return saveManagedByPackage(&ManagedByPackageState{UpdatedOn: time.Now().UTC()})
+}
+
+// PackagePinConfig returns the path and the content of the package manager configuration pinning
+// the elastic-agent package to version, so automatic updates of the host install exactly the
+// desired version. It returns false when the package manager is not supported.
+func PackagePinConfig(pm info.PackageManager, version string) (string, string, bool) {
+	switch pm {
+	case info.PackageManagerDEB:
+		return "/etc/apt/preferences.d/elastic-agent", fmt.Sprintf("Package: elastic-agent\nPin: version %s\nPin-Priority: 1001\n", version), true
+	case info.PackageManagerRPM:
+		return "/etc/yum/pluginconf.d/versionlock.list", fmt.Sprintf("elastic-agent-0:%s-1.*\n", version), true
+	}
+	return "", "", false
+}
+
+func managedByPkgFilePath() string {
+	return filepath.Join(paths.Data(), managedByPkgFilename)
+}
@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upgrade
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+func TestManagedByPackageStateWaiting(t *testing.T) {
+	var state *ManagedByPackageState
+	assert.Empty(t, state.Waiting(), "no state, nothing to wait for")
+
+	state = &ManagedByPackageState{}
+	assert.Empty(t, state.Waiting(), "no desired version, nothing to wait for")
+
+	state.Version = currentVersion()
+	assert.Empty(t, state.Waiting(), "desired version installed, nothing to wait for")
+
+	state.Version = "99.0.0"
+	assert.Equal(t, "99.0.0", state.Waiting())
+}
+
+func TestClearManagedByPackage(t *testing.T) {
+	defer func(prev string) { paths.SetTop(prev) }(paths.Top())
+	paths.SetTop(t.TempDir())
+	require.NoError(t, os.MkdirAll(paths.Data(), 0755))
+
+	cleared, err := ClearManagedByPackage()
+	require.NoError(t, err)
+	assert.False(t, cleared, "upgrades not managed by the package manager")
+
+	require.NoError(t, MarkManagedByPackage("99.0.0", nil))
+	cleared, err = ClearManagedByPackage()
+	require.NoError(t, err)
+	assert.True(t, cleared)
+
+	state, err := LoadManagedByPackage()
+	require.NoError(t, err)
+	assert.Nil(t, state, "the Elastic Agent upgrades itself again")
+}
+
+func TestPackagePinConfig(t *testing.T) {
+	path, content, ok := PackagePinConfig(info.PackageManagerDEB, "8.9.0")
+	assert.True(t, ok)
+	assert.Equal(t, "/etc/apt/preferences.d/elastic-agent", path)
+	assert.Contains(t, content, "Pin: version 8.9.0")
+
+	path, content, ok = PackagePinConfig(info.PackageManagerRPM, "8.9.0")
+	assert.True(t, ok)
+	assert.Equal(t, "/etc/yum/pluginconf.d/versionlock.list", path)
+	assert.Equal(t, "elastic-agent-0:8.9.0-1.*\n", content)
+
+	_, _, ok = PackagePinConfig(info.PackageManagerNone, "8.9.0")
+	assert.False(t, ok)
+}
@@ -0,0 +1,86 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/fs"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+// minimumSupportedVersion is the oldest version the agent can be downgraded to, the agents of
+// the 7.x series can not run with the configuration and the state written by this version.
+const minimumSupportedVersion = "8.0.0"
+
+// ErrNoArtifact is returned when the version to upgrade to is not published for the platform
+// of the agent.
+var ErrNoArtifact = errors.New("no artifact")
+
+// ErrUnsupportedVersion is returned when the version to upgrade to is older than the minimum
+// supported version.
+var ErrUnsupportedVersion = errors.New("unsupported version")
+
+// archNames are the names of the architectures of the artifact configuration reported to users.
+var archNames = map[string]string{
+	"32":    "x86",
+	"64":    "x86_64",
+	"arm64": "arm64",
+}
+
+// checkArtifact confirms that the version is supported and that its package exists for the
+// platform of the agent before any bytes are downloaded. The package is looked up in the drop
+// path first, then at the source URI. Snapshots are not checked, their location is only
+// known once the snapshot downloader resolves it.
+func (u *Upgrader) checkArtifact(ctx context.Context, version *agtversion.ParsedSemVer, settings *artifact.Config) error {
+	minVersion, err := agtversion.ParseVersion(minimumSupportedVersion)
+	if err != nil {
+		return err
+	}
+	coreVersion := agtversion.NewParsedSemVer(version.Major(), version.Minor(), version.Patch(), "", "")
+	if coreVersion.Less(*minVersion) {
+		return fmt.Errorf("%w: %s is older than the minimum supported version %s", ErrUnsupportedVersion, version.Original(), minimumSupportedVersion)
+	}
+
+	platform := fmt.Sprintf("%s/%s", settings.OS(), settings.Arch())
+	if name, ok := archNames[settings.Arch()]; ok {
+		platform = fmt.Sprintf("%s/%s", settings.OS(), name)
+	}
+	if settings.Package() != artifact.BinaryPackage {
+		platform = fmt.Sprintf("%s (%s)", platform, settings.Package())
+	}
+
+	versionStr := version.VersionWithPrerelease()
+	if _, err := artifact.GetArtifactName(agentArtifact, versionStr, settings.OS(), settings.Arch(), settings.Package()); err != nil {
+		return fmt.Errorf("%w for %s: %v", ErrNoArtifact, platform, err)
+	}
+
+	if version.IsSnapshot() {
+		return nil
+	}
+
+	if fs.NewDownloader(settings).Exists(agentArtifact, versionStr) {
+		return nil
+	}
+
+	downloader, err := http.NewDownloader(u.log, settings)
+	if err != nil {
+		return fmt.Errorf("initiating downloader: %w", err)
+	}
+	exists, err := downloader.Exists(ctx, agentArtifact, versionStr)
+	if err != nil {
+		// the download retries on transient failures, only a missing package fails the upgrade early
+		u.log.Warnw("Unable to check the upgrade artifact before downloading it", "version", versionStr, "error.message", err)
+		return nil
+	}
+	if !exists {
+		return fmt.Errorf("%w of version %s for %s at %s", ErrNoArtifact, versionStr, platform, settings.SourceURI)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upgrade
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+func TestCheckArtifact(t *testing.T) {
+	testLogger, _ := logger.NewTesting("TestCheckArtifact")
+
+	var requests atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusNotFound)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method == http.MethodHead && r.URL.Path == "/beats/elastic-agent/elastic-agent-8.11.0-linux-arm64.tar.gz" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	newSettings := func() *artifact.Config {
+		return &artifact.Config{
+			SourceURI:       server.URL,
+			DropPath:        t.TempDir(),
+			OperatingSystem: "linux",
+			Architecture:    "arm64",
+			HTTPTransportSettings: httpcommon.HTTPTransportSettings{
+				Timeout: 5 * time.Second,
+			},
+		}
+	}
+	check := func(t *testing.T, version string, settings *artifact.Config) error {
+		parsedVersion, err := agtversion.ParseVersion(version)
+		require.NoError(t, err)
+		u := NewUpgrader(testLogger, settings, &info.AgentInfo{}, nil)
+		return u.checkArtifact(context.Background(), parsedVersion, settings)
+	}
+
+	t.Run("published version", func(t *testing.T) {
+		assert.NoError(t, check(t, "8.11.0", newSettings()))
+	})
+
+	t.Run("version not published for the platform", func(t *testing.T) {
+		err := check(t, "8.12.0", newSettings())
+		assert.ErrorIs(t, err, ErrNoArtifact)
+		assert.ErrorContains(t, err, "no artifact of version 8.12.0 for linux/arm64")
+	})
+
+	t.Run("package in the drop path", func(t *testing.T) {
+		settings := newSettings()
+		require.NoError(t, os.WriteFile(filepath.Join(settings.DropPath, "elastic-agent-8.12.0-linux-arm64.tar.gz"), []byte("package"), 0600))
+		assert.NoError(t, check(t, "8.12.0", settings))
+	})
+
+	t.Run("invalid package for the platform", func(t *testing.T) {
+		settings := newSettings()
+		settings.OperatingSystem = "windows"
+		settings.Architecture = "64"
+		settings.PackageType = artifact.DebPackage
+		err := check(t, "8.11.0", settings)
+		assert.ErrorIs(t, err, ErrNoArtifact)
+		assert.ErrorContains(t, err, "no artifact for windows/x86_64 (deb)")
+	})
+
+	t.Run("version older than the minimum supported version", func(t *testing.T) {
+		err := check(t, "7.17.10", newSettings())
+		assert.ErrorIs(t, err, ErrUnsupportedVersion)
+		assert.NoError(t, check(t, "8.0.0-SNAPSHOT", newSettings()))
+	})
+
+	t.Run("snapshots are not checked", func(t *testing.T) {
+		before := requests.Load()
+		assert.NoError(t, check(t, "8.12.0-SNAPSHOT", newSettings()))
+		assert.Equal(t, before, requests.Load())
+	})
+
+	t.Run("unavailable source", func(t *testing.T) {
+		status.Store(http.StatusInternalServerError)
+		defer status.Store(http.StatusNotFound)
+		assert.NoError(t, check(t, "8.12.0", newSettings()))
+	})
+}
@@ -15,6 +15,7 @@ import (
 
 	"go.elastic.co/apm"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
@@ -23,7 +24,10 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/localremote"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/snapshot"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/tuf"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fault"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	agtversion "github.com/elastic/elastic-agent/pkg/version"
@@ -33,7 +37,11 @@ const (
 	defaultUpgradeFallbackPGP = "https://artifacts.elastic.co/GPG-KEY-elastic-agent"
 )
 
-func (u *Upgrader) downloadArtifact(ctx context.Context, version, sourceURI string, skipVerifyOverride bool, pgpBytes ...string) (_ string, err error) {
+// ErrDownloadSourceNotAllowed is returned when the capabilities do not allow downloading an
+// upgrade from one of its sources.
+var ErrDownloadSourceNotAllowed = errors.New("download source not allowed by the capabilities")
+
+func (u *Upgrader) downloadArtifact(ctx context.Context, version, sourceURI string, det *details.Details, skipVerifyOverride bool, pgpBytes ...string) (_ string, err error) {
 	span, ctx := apm.StartSpan(ctx, "downloadArtifact", "app.internal")
 	defer func() {
 		apm.CaptureError(ctx, err).Send()
@@ -44,6 +52,9 @@ func (u *Upgrader) downloadArtifact(ctx context.Context, version, sourceURI stri
 
 	// do not update source config
 	settings := *u.settings
+	if u.packageManager != info.PackageManagerNone {
+		settings.PackageType = string(u.packageManager)
+	}
 	if sourceURI != "" {
 		if strings.HasPrefix(sourceURI, "file://") {
 			// update the DropPath so the fs.Downloader can download from this
@@ -63,11 +74,22 @@ func (u *Upgrader) downloadArtifact(ctx context.Context, version, sourceURI stri
 		return "", fmt.Errorf("error parsing version %q: %w", version, err)
 	}
 
+	pgpBytes, err = u.checkDownloadSources(parsedVersion, &settings, pgpBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if err := u.checkArtifact(ctx, parsedVersion, &settings); err != nil {
+		return "", err
+	}
+
 	if err := os.MkdirAll(paths.Downloads(), 0750); err != nil {
 		return "", errors.New(err, fmt.Sprintf("failed to create download directory at %s", paths.Downloads()))
 	}
 
-	path, err := u.downloadWithRetries(ctx, newDownloader, parsedVersion, &settings)
+	det.SetState(details.StateDownloading)
+
+	path, err := u.downloadWithRetries(ctx, newDownloader, parsedVersion, &settings, det)
 	if err != nil {
 		return "", errors.New(err, "failed download of agent binary")
 	}
@@ -76,10 +98,12 @@ func (u *Upgrader) downloadArtifact(ctx context.Context, version, sourceURI stri
 		return path, nil
 	}
 
+	det.SetState(details.StateVerifying)
 	verifier, err := newVerifier(parsedVersion, u.log, &settings)
 	if err != nil {
 		return "", errors.New(err, "initiating verifier")
 	}
+	download.SetWarner(verifier, det.Warn)
 
 	if err := verifier.Verify(agentArtifact, parsedVersion.VersionWithPrerelease(), pgpBytes...); err != nil {
 		return "", errors.New(err, "failed verification of agent binary")
@@ -88,6 +112,40 @@ func (u *Upgrader) downloadArtifact(ctx context.Context, version, sourceURI stri
 	return path, nil
 }
 
+// checkDownloadSources checks the sources of the upgrade against the download source capabilities
+// before any request is made: the effective source URI, set by the upgrade action or by the policy,
+// the repositories of the snapshot builds and the TUF metadata. An upgrade with one of them not
+// allowed is refused, the PGP keys whose location is not allowed are left out of the verification.
+func (u *Upgrader) checkDownloadSources(version *agtversion.ParsedSemVer, settings *artifact.Config, pgpBytes []string) ([]string, error) {
+	if u.caps == nil {
+		return pgpBytes, nil
+	}
+	sources := []string{settings.SourceURI}
+	if version.IsSnapshot() || release.Snapshot() {
+		sources = append(sources, snapshot.SourceURIs()...)
+	}
+	if settings.TUF.Enabled {
+		sources = append(sources, tuf.MetadataURI(settings))
+	}
+	for _, source := range sources {
+		if source != "" && !u.caps.AllowDownloadSource(source) {
+			u.log.Warnw("Refused to upgrade from a download source not allowed by the capabilities",
+				"version", version.Original(), "source_uri", source, "event.action", "upgrade-source-refused")
+			return nil, fmt.Errorf("%w: %q", ErrDownloadSourceNotAllowed, source)
+		}
+	}
+
+	allowed := make([]string, 0, len(pgpBytes))
+	for _, pgp := range pgpBytes {
+		if uri := strings.TrimPrefix(pgp, download.PgpSourceURIPrefix); uri != pgp && !u.caps.AllowDownloadSource(uri) {
+			u.log.Warnw("Ignoring a PGP key whose location is not allowed by the capabilities", "pgp_uri", uri)
+			continue
+		}
+		allowed = append(allowed, pgp)
+	}
+	return allowed, nil
+}
+
 func appendFallbackPGP(pgpBytes []string) []string {
 	if pgpBytes == nil {
 		pgpBytes = make([]string, 0, 1)
@@ -120,6 +178,10 @@ func newDownloader(version *agtversion.ParsedSemVer, log *logger.Logger, setting
 }
 
 func newVerifier(version *agtversion.ParsedSemVer, log *logger.Logger, settings *artifact.Config) (download.Verifier, error) {
+	if settings.TUF.Enabled {
+		return tuf.NewVerifier(log, settings)
+	}
+
 	allowEmptyPgp, pgp := release.PGP()
 
 	if !version.IsSnapshot() {
@@ -149,6 +211,7 @@ func (u *Upgrader) downloadWithRetries(
 	downloaderCtor func(*agtversion.ParsedSemVer, *logger.Logger, *artifact.Config) (download.Downloader, error),
 	version *agtversion.ParsedSemVer,
 	settings *artifact.Config,
+	det *details.Details,
 ) (string, error) {
 	cancelCtx, cancel := context.WithTimeout(ctx, settings.Timeout)
 	defer cancel()
@@ -159,6 +222,7 @@ func (u *Upgrader) downloadWithRetries(
 
 	var path string
 	var attempt uint
+	var lastErr error
 
 	opFn := func() error {
 		attempt++
@@ -168,6 +232,7 @@ func (u *Upgrader) downloadWithRetries(
 		if err != nil {
 			return fmt.Errorf("unable to create fetcher: %w", err)
 		}
+		download.SetWarner(downloader, det.Warn)
 		// All download artifacts expect a name that includes <major>.<minor.<patch>[-SNAPSHOT] so we have to
 		// make sure not to include build metadata we might have in the parsed version (for snapshots we already
 		// used that to configure the URL we download the files from)
@@ -175,12 +240,19 @@ func (u *Upgrader) downloadWithRetries(
 		if err != nil {
 			return fmt.Errorf("unable to download package: %w", err)
 		}
+		if err := fault.CorruptDownloadedFile(agentArtifact.Cmd, path); err != nil {
+			return err
+		}
 
 		// Download successful
+		if lastErr != nil {
+			det.Warn(fmt.Sprintf("downloaded after %d attempts, the previous attempt failed: %v", attempt, lastErr))
+		}
 		return nil
 	}
 
 	opFailureNotificationFn := func(err error, retryAfter time.Duration) {
+		lastErr = err
 		u.log.Warnf("%s; retrying (will be retry %d) in %s.", err.Error(), attempt, retryAfter)
 	}
 
@@ -7,6 +7,10 @@ package upgrade
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,7 +20,9 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	agtversion "github.com/elastic/elastic-agent/pkg/version"
 )
@@ -69,10 +75,10 @@ func TestDownloadWithRetries(t *testing.T) {
 			return &mockDownloader{expectedDownloadPath, nil}, nil
 		}
 
-		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{})
+		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{}, nil)
 		parsedVersion, err := agtversion.ParseVersion("8.9.0")
 		require.NoError(t, err)
-		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &settings)
+		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &settings, nil)
 		require.NoError(t, err)
 		require.Equal(t, expectedDownloadPath, path)
 
@@ -103,10 +109,10 @@ func TestDownloadWithRetries(t *testing.T) {
 			return nil, nil
 		}
 
-		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{})
+		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{}, nil)
 		parsedVersion, err := agtversion.ParseVersion("8.9.0")
 		require.NoError(t, err)
-		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &settings)
+		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &settings, nil)
 		require.NoError(t, err)
 		require.Equal(t, expectedDownloadPath, path)
 
@@ -139,10 +145,11 @@ func TestDownloadWithRetries(t *testing.T) {
 			return nil, nil
 		}
 
-		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{})
+		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{}, nil)
 		parsedVersion, err := agtversion.ParseVersion("8.9.0")
 		require.NoError(t, err)
-		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &settings)
+		det := details.NewDetails("8.9.0", details.StateDownloading, "")
+		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &settings, det)
 		require.NoError(t, err)
 		require.Equal(t, expectedDownloadPath, path)
 
@@ -151,6 +158,10 @@ func TestDownloadWithRetries(t *testing.T) {
 		require.Equal(t, "download attempt 1", logs[0].Message)
 		require.Contains(t, logs[1].Message, "unable to download package: download failed; retrying (will be retry 1)")
 		require.Equal(t, "download attempt 2", logs[2].Message)
+
+		// the retry is reported in the upgrade details
+		require.Len(t, det.Metadata.Warnings, 1)
+		require.Equal(t, "downloaded after 2 attempts, the previous attempt failed: unable to download package: download failed", det.Metadata.Warnings[0])
 	})
 
 	// Download timeout expired (before all retries are exhausted)
@@ -163,10 +174,10 @@ func TestDownloadWithRetries(t *testing.T) {
 			return &mockDownloader{"", errors.New("download failed")}, nil
 		}
 
-		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{})
+		u := NewUpgrader(testLogger, &settings, &info.AgentInfo{}, nil)
 		parsedVersion, err := agtversion.ParseVersion("8.9.0")
 		require.NoError(t, err)
-		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &testCaseSettings)
+		path, err := u.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &testCaseSettings, nil)
 		require.Equal(t, "context deadline exceeded", err.Error())
 		require.Equal(t, "", path)
 
@@ -179,3 +190,91 @@ func TestDownloadWithRetries(t *testing.T) {
 		}
 	})
 }
+
+func TestDownloadArtifactRefusesDisallowedSource(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	testLogger, _ := logger.NewTesting("TestDownloadArtifactRefusesDisallowedSource")
+	caps, err := capabilities.Load(strings.NewReader(`
+capabilities:
+- download_source: "127.0.0.0/8"
+  rule: deny
+`), testLogger)
+	require.NoError(t, err)
+
+	// the source URI configured by the agent.download.sourceURI of the policy
+	settings := artifact.Config{
+		SourceURI:              server.URL + "/downloads/",
+		RetrySleepInitDuration: 20 * time.Millisecond,
+		HTTPTransportSettings: httpcommon.HTTPTransportSettings{
+			Timeout: 2 * time.Second,
+		},
+	}
+	u := NewUpgrader(testLogger, &settings, &info.AgentInfo{}, caps)
+
+	det := details.NewDetails("8.9.0", details.StateRequested, "")
+	_, err = u.downloadArtifact(context.Background(), "8.9.0", u.sourceURI(""), det, false)
+	require.ErrorIs(t, err, ErrDownloadSourceNotAllowed)
+	require.Contains(t, err.Error(), server.URL)
+	require.Zero(t, requests.Load(), "no request is made to a refused source")
+
+	// the upgrade action overriding the source URI is checked the same way
+	_, err = u.downloadArtifact(context.Background(), "8.9.0", u.sourceURI(server.URL+"/other/"), det, false)
+	require.ErrorIs(t, err, ErrDownloadSourceNotAllowed)
+	require.Zero(t, requests.Load(), "no request is made to a refused source")
+}
+
+func TestCheckDownloadSources(t *testing.T) {
+	testLogger, _ := logger.NewTesting("TestCheckDownloadSources")
+	caps, err := capabilities.Load(strings.NewReader(`
+capabilities:
+- download_source: "mirror.example.com"
+  rule: allow
+- download_source: "*"
+  rule: deny
+`), testLogger)
+	require.NoError(t, err)
+	u := NewUpgrader(testLogger, &artifact.Config{}, &info.AgentInfo{}, caps)
+	release, err := agtversion.ParseVersion("8.9.0")
+	require.NoError(t, err)
+	snapshot, err := agtversion.ParseVersion("8.9.0-SNAPSHOT")
+	require.NoError(t, err)
+
+	t.Run("allowed source, the PGP keys of other locations are left out", func(t *testing.T) {
+		pgp := appendFallbackPGP([]string{
+			"pgp-bytes",
+			download.PgpSourceURIPrefix + "https://mirror.example.com/GPG-KEY",
+		})
+		allowed, err := u.checkDownloadSources(release, &artifact.Config{SourceURI: "https://mirror.example.com/downloads/"}, pgp)
+		require.NoError(t, err)
+		require.Equal(t, []string{"pgp-bytes", download.PgpSourceURIPrefix + "https://mirror.example.com/GPG-KEY"}, allowed)
+	})
+
+	t.Run("snapshot repositories", func(t *testing.T) {
+		_, err := u.checkDownloadSources(snapshot, &artifact.Config{SourceURI: "https://mirror.example.com/downloads/"}, nil)
+		require.ErrorIs(t, err, ErrDownloadSourceNotAllowed)
+	})
+
+	t.Run("TUF metadata", func(t *testing.T) {
+		settings := &artifact.Config{SourceURI: "https://mirror.example.com/downloads/"}
+		settings.TUF.Enabled = true
+		_, err := u.checkDownloadSources(release, settings, nil)
+		require.NoError(t, err)
+		settings.TUF.MetadataURI = "https://tuf.example.com/"
+		_, err = u.checkDownloadSources(release, settings, nil)
+		require.ErrorIs(t, err, ErrDownloadSourceNotAllowed)
+	})
+
+	t.Run("without capabilities", func(t *testing.T) {
+		u := NewUpgrader(testLogger, &artifact.Config{}, &info.AgentInfo{}, nil)
+		pgp := appendFallbackPGP(nil)
+		allowed, err := u.checkDownloadSources(release, &artifact.Config{SourceURI: "https://evil.example.com/"}, pgp)
+		require.NoError(t, err)
+		require.Equal(t, pgp, allowed)
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
@@ -37,6 +38,12 @@ type UpdateMarker struct {
 	// Acked is a flag marking whether or not action was acked
 	Acked  bool                    `json:"acked" yaml:"acked"`
 	Action *fleetapi.ActionUpgrade `json:"action" yaml:"action"`
+
+	// PackageManager is set when the upgrade is performed by the system package manager
+	PackageManager string `json:"package_manager,omitempty" yaml:"package_manager,omitempty"`
+
+	// Details is the progress of the upgrade reported by the new version once it starts
+	Details *details.Details `json:"details,omitempty" yaml:"details,omitempty"`
 }
 
 // MarkerActionUpgrade adapter struct compatible with pre 8.3 version of the marker file format
@@ -72,27 +79,31 @@ func convertToActionUpgrade(a *MarkerActionUpgrade) *fleetapi.ActionUpgrade {
 }
 
 type updateMarkerSerializer struct {
-	Hash        string               `yaml:"hash"`
-	UpdatedOn   time.Time            `yaml:"updated_on"`
-	PrevVersion string               `yaml:"prev_version"`
-	PrevHash    string               `yaml:"prev_hash"`
-	Acked       bool                 `yaml:"acked"`
-	Action      *MarkerActionUpgrade `yaml:"action"`
+	Hash           string               `yaml:"hash"`
+	UpdatedOn      time.Time            `yaml:"updated_on"`
+	PrevVersion    string               `yaml:"prev_version"`
+	PrevHash       string               `yaml:"prev_hash"`
+	Acked          bool                 `yaml:"acked"`
+	Action         *MarkerActionUpgrade `yaml:"action"`
+	PackageManager string               `yaml:"package_manager,omitempty"`
+	Details        *details.Details     `yaml:"details,omitempty"`
 }
 
 func newMarkerSerializer(m *UpdateMarker) *updateMarkerSerializer {
 	return &updateMarkerSerializer{
-		Hash:        m.Hash,
-		UpdatedOn:   m.UpdatedOn,
-		PrevVersion: m.PrevVersion,
-		PrevHash:    m.PrevHash,
-		Acked:       m.Acked,
-		Action:      convertToMarkerAction(m.Action),
+		Hash:           m.Hash,
+		UpdatedOn:      m.UpdatedOn,
+		PrevVersion:    m.PrevVersion,
+		PrevHash:       m.PrevHash,
+		Acked:          m.Acked,
+		Action:         convertToMarkerAction(m.Action),
+		PackageManager: m.PackageManager,
+		Details:        m.Details,
 	}
 }
 
 // markUpgrade marks update happened so we can handle grace period
-func (u *Upgrader) markUpgrade(_ context.Context, log *logger.Logger, hash string, action *fleetapi.ActionUpgrade) error {
+func (u *Upgrader) markUpgrade(_ context.Context, log *logger.Logger, hash string, action *fleetapi.ActionUpgrade, det *details.Details) error {
 	prevVersion := release.Version()
 	prevHash := release.Commit()
 	if len(prevHash) > hashLen {
@@ -105,6 +116,8 @@ func (u *Upgrader) markUpgrade(_ context.Context, log *logger.Logger, hash strin
 		PrevVersion: prevVersion,
 		PrevHash:    prevHash,
 		Action:      action,
+		// the new version is watched once it starts
+		Details: markerDetails(det, details.StateWatching),
 	}
 
 	markerBytes, err := yaml.Marshal(newMarkerSerializer(marker))
@@ -125,6 +138,26 @@ func (u *Upgrader) markUpgrade(_ context.Context, log *logger.Logger, hash strin
 	return nil
 }
 
+// markerDetails returns a snapshot of det in state, to be reported by the new version.
+func markerDetails(det *details.Details, state details.State) *details.Details {
+	markerDet := det.Clone()
+	if markerDet != nil {
+		markerDet.SetState(state)
+	}
+	return markerDet
+}
+
+// SetMarkerDetailsState updates the state of the upgrade details stored in the marker, errMsg
+// describes the reason of the state change. It does nothing when the marker has no details.
+func SetMarkerDetailsState(marker *UpdateMarker, state details.State, errMsg string) error {
+	if marker.Details == nil {
+		return nil
+	}
+	marker.Details.Metadata.ErrorMsg = errMsg
+	marker.Details.SetState(state)
+	return saveMarker(marker)
+}
+
 // UpdateActiveCommit updates active.commit file to point to active version.
 func UpdateActiveCommit(log *logger.Logger, hash string) error {
 	activeCommitPath := filepath.Join(paths.Top(), agentCommitFile)
@@ -165,25 +198,19 @@ func LoadMarker() (*UpdateMarker, error) {
 	}
 
 	return &UpdateMarker{
-		Hash:        marker.Hash,
-		UpdatedOn:   marker.UpdatedOn,
-		PrevVersion: marker.PrevVersion,
-		PrevHash:    marker.PrevHash,
-		Acked:       marker.Acked,
-		Action:      convertToActionUpgrade(marker.Action),
+		Hash:           marker.Hash,
+		UpdatedOn:      marker.UpdatedOn,
+		PrevVersion:    marker.PrevVersion,
+		PrevHash:       marker.PrevHash,
+		Acked:          marker.Acked,
+		Action:         convertToActionUpgrade(marker.Action),
+		PackageManager: marker.PackageManager,
+		Details:        marker.Details,
 	}, nil
 }
 
 func saveMarker(marker *UpdateMarker) error {
-	makerSerializer := &updateMarkerSerializer{
-		Hash:        marker.Hash,
-		UpdatedOn:   marker.UpdatedOn,
-		PrevVersion: marker.PrevVersion,
-		PrevHash:    marker.PrevHash,
-		Acked:       marker.Acked,
-		Action:      convertToMarkerAction(marker.Action),
-	}
-	markerBytes, err := yaml.Marshal(makerSerializer)
+	markerBytes, err := yaml.Marshal(newMarkerSerializer(marker))
 	if err != nil {
 		return err
 	}
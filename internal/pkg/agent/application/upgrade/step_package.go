@@ -0,0 +1,152 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+)
+
+// packageUpgradeUnit is the name of the transient systemd unit running the package installation.
+const packageUpgradeUnit = "elastic-agent-package-upgrade"
+
+// ErrPackageUpgradeUnsupported is returned when the Elastic Agent was installed with a system
+// package manager but the package cannot be installed from the running Elastic Agent.
+var ErrPackageUpgradeUnsupported = errors.New("upgrade of package installed agent is not supported on this host")
+
+// IsPackageUpgradeable returns true when the agent was installed with a system package manager
+// and is running as a service, in this case the upgrade is delegated to the package manager.
+func IsPackageUpgradeable() bool {
+	return info.InstalledPackageManager() != info.PackageManagerNone && info.RunningUnderSupervisor()
+}
+
+// upgradePackage upgrades an agent installed from a DEB or RPM package. The new package is downloaded
+// and verified like any other artifact, the installation itself is handed over to the package manager
+// so its database stays consistent with the files on disk.
+//
+// The package manager replaces the running binary and restarts the service, the Elastic Agent
+// is not re-executed and the watcher is not started, so no shutdown callback is returned.
+func (u *Upgrader) upgradePackage(ctx context.Context, version, sourceURI string, action *fleetapi.ActionUpgrade, det *details.Details, skipVerifyOverride bool, pgpBytes ...string) (reexec.ShutdownCallbackFn, error) {
+	if version == currentVersion() {
+		u.log.Warn("Upgrade action skipped: upgrade did not occur because its the same version")
+		det.SetState(details.StateCompleted)
+		return nil, nil
+	}
+
+	if _, err := exec.LookPath("systemd-run"); err != nil {
+		return nil, fmt.Errorf("%w: systemd-run is not available, upgrade the %s package with the system package manager instead: %s",
+			ErrPackageUpgradeUnsupported, u.packageManager, packageManagerHint(u.packageManager, version))
+	}
+
+	packagePath, err := u.downloadArtifact(ctx, version, u.sourceURI(sourceURI), det, skipVerifyOverride, pgpBytes...)
+	if err != nil {
+		if dErr := cleanNonMatchingVersionsFromDownloads(u.log, u.agentInfo.Version()); dErr != nil {
+			u.log.Errorw("Unable to remove file after verification failure", "error.message", dErr)
+		}
+		return nil, err
+	}
+
+	args, err := packageUpgradeCmd(u.packageManager, packagePath, paths.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	det.SetState(details.StateApplying)
+	if err := u.markPackageUpgrade(action, det); err != nil {
+		return nil, err
+	}
+
+	u.log.Infow("Handing over upgrade to the package manager", "package_manager", u.packageManager, "file.path", packagePath, "unit", packageUpgradeUnit)
+	// use a fresh context, the unit must be created even if the action context is cancelled in the meantime
+	// nolint:gosec // args are built by packageUpgradeCmd
+	cmd := exec.CommandContext(context.Background(), args[0], args[1:]...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if cErr := CleanMarker(u.log); cErr != nil {
+			u.log.Errorw("Unable to remove upgrade marker", "error.message", cErr)
+		}
+		return nil, errors.New(err, fmt.Sprintf("failed to start package upgrade: %s", output), errors.TypeApplication)
+	}
+
+	return nil, nil
+}
+
+// markPackageUpgrade writes the upgrade marker for a package upgrade. The marker is only used to ack the
+// upgrade action once the new version starts, the hash of the new version is unknown and the active
+// commit is not updated as the package manager manages the binary.
+func (u *Upgrader) markPackageUpgrade(action *fleetapi.ActionUpgrade, det *details.Details) error {
+	prevHash := release.Commit()
+	if len(prevHash) > hashLen {
+		prevHash = prevHash[:hashLen]
+	}
+
+	marker := &UpdateMarker{
+		UpdatedOn:      time.Now(),
+		PrevVersion:    release.Version(),
+		PrevHash:       prevHash,
+		Action:         action,
+		PackageManager: string(u.packageManager),
+		// no watcher runs after a package upgrade, the upgrade is completed once the new version starts
+		Details: markerDetails(det, details.StateCompleted),
+	}
+
+	u.log.Infow("Writing upgrade marker file", "file.path", markerFilePath(), "package_manager", marker.PackageManager, "prev_hash", prevHash)
+	if err := saveMarker(marker); err != nil {
+		return errors.New(err, errors.TypeFilesystem, "failed to create update marker file", errors.M(errors.MetaKeyPath, markerFilePath()))
+	}
+	return nil
+}
+
+// packageUpgradeCmd returns the command installing the package at packagePath and restarting the service.
+//
+// The installation runs in a transient systemd unit outside of the control group of the Elastic Agent
+// service, otherwise it would be killed together with the service once the service is restarted.
+func packageUpgradeCmd(pm info.PackageManager, packagePath, serviceName string) ([]string, error) {
+	var install string
+	switch pm {
+	case info.PackageManagerDEB:
+		// keep the configuration files modified by the user
+		install = `dpkg -i --force-confold "$1"`
+	case info.PackageManagerRPM:
+		install = `rpm -U -v "$1"`
+	default:
+		return nil, fmt.Errorf("unsupported package manager %q", pm)
+	}
+
+	// the paths are passed as positional arguments, so they are never interpreted by the shell
+	script := install + ` && systemctl restart "$2"`
+	return []string{
+		"systemd-run", "--no-block", "--collect", "--unit", packageUpgradeUnit,
+		"/bin/sh", "-c", script, "sh", packagePath, serviceName,
+	}, nil
+}
+
+// packageManagerHint returns the command a user can run to upgrade the package manually.
+func packageManagerHint(pm info.PackageManager, version string) string {
+	switch pm {
+	case info.PackageManagerDEB:
+		return fmt.Sprintf("apt-get install elastic-agent=%s", version)
+	case info.PackageManagerRPM:
+		return fmt.Sprintf("yum install elastic-agent-%s", version)
+	}
+	return ""
+}
+
+// currentVersion returns the version of the running agent in the format used by upgrade actions.
+func currentVersion() string {
+	if release.Snapshot() {
+		return release.Version() + "-SNAPSHOT"
+	}
+	return release.Version()
+}
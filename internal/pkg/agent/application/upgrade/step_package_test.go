@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upgrade
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+)
+
+func TestPackageUpgradeCmd(t *testing.T) {
+	t.Run("deb", func(t *testing.T) {
+		args, err := packageUpgradeCmd(info.PackageManagerDEB, "/tmp/elastic-agent-8.9.0-amd64.deb", "elastic-agent")
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"systemd-run", "--no-block", "--collect", "--unit", packageUpgradeUnit,
+			"/bin/sh", "-c", `dpkg -i --force-confold "$1" && systemctl restart "$2"`, "sh",
+			"/tmp/elastic-agent-8.9.0-amd64.deb", "elastic-agent",
+		}, args)
+	})
+
+	t.Run("rpm", func(t *testing.T) {
+		args, err := packageUpgradeCmd(info.PackageManagerRPM, "/tmp/elastic-agent-8.9.0-x86_64.rpm", "elastic-agent")
+		require.NoError(t, err)
+		assert.Equal(t, `rpm -U -v "$1" && systemctl restart "$2"`, args[7])
+		assert.Equal(t, "/tmp/elastic-agent-8.9.0-x86_64.rpm", args[9])
+	})
+
+	t.Run("no package manager", func(t *testing.T) {
+		_, err := packageUpgradeCmd(info.PackageManagerNone, "/tmp/elastic-agent.tar.gz", "elastic-agent")
+		assert.Error(t, err)
+	})
+}
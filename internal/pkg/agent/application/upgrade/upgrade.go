@@ -17,10 +17,13 @@ import (
 	"go.elastic.co/apm"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/integrity"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
@@ -45,10 +48,12 @@ var ErrSameVersion = errors.New("upgrade did not occur because its the same vers
 
 // Upgrader performs an upgrade
 type Upgrader struct {
-	log         *logger.Logger
-	settings    *artifact.Config
-	agentInfo   *info.AgentInfo
-	upgradeable bool
+	log            *logger.Logger
+	settings       *artifact.Config
+	agentInfo      *info.AgentInfo
+	upgradeable    bool
+	packageManager info.PackageManager
+	caps           capabilities.Capabilities
 }
 
 // IsUpgradeable when agent is installed and running as a service or flag was provided.
@@ -58,13 +63,16 @@ func IsUpgradeable() bool {
 	return release.Upgradeable() || (info.RunningInstalled() && info.RunningUnderSupervisor())
 }
 
-// NewUpgrader creates an upgrader which is capable of performing upgrade operation
-func NewUpgrader(log *logger.Logger, settings *artifact.Config, agentInfo *info.AgentInfo) *Upgrader {
+// NewUpgrader creates an upgrader which is capable of performing upgrade operation, the
+// capabilities restrict the sources the upgrades are downloaded from.
+func NewUpgrader(log *logger.Logger, settings *artifact.Config, agentInfo *info.AgentInfo, caps capabilities.Capabilities) *Upgrader {
 	return &Upgrader{
-		log:         log,
-		settings:    settings,
-		agentInfo:   agentInfo,
-		upgradeable: IsUpgradeable(),
+		log:            log,
+		settings:       settings,
+		agentInfo:      agentInfo,
+		upgradeable:    IsUpgradeable() || IsPackageUpgradeable(),
+		packageManager: info.InstalledPackageManager(),
+		caps:           caps,
 	}
 }
 
@@ -108,18 +116,32 @@ func (u *Upgrader) Upgradeable() bool {
 }
 
 // Upgrade upgrades running agent, function returns shutdown callback that must be called by reexec.
-func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error) {
+func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, det *details.Details, skipVerifyOverride bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error) {
 	u.log.Infow("Upgrading agent", "version", version, "source_uri", sourceURI)
 	span, ctx := apm.StartSpan(ctx, "upgrade", "app.internal")
 	defer span.End()
 
+	pkgState, err := LoadManagedByPackage()
+	if err != nil {
+		return nil, err
+	}
+	if pkgState != nil {
+		// upgrades are managed by a package manager, only record the desired version
+		u.log.Infow("Upgrades are managed by the package manager, waiting for it to install the version", "version", version)
+		return nil, MarkManagedByPackage(version, action)
+	}
+
 	err = cleanNonMatchingVersionsFromDownloads(u.log, u.agentInfo.Version())
 	if err != nil {
 		u.log.Errorw("Unable to clean downloads before update", "error.message", err, "downloads.path", paths.Downloads())
 	}
 
+	if u.packageManager != info.PackageManagerNone {
+		return u.upgradePackage(ctx, version, sourceURI, action, det, skipVerifyOverride, pgpBytes...)
+	}
+
 	sourceURI = u.sourceURI(sourceURI)
-	archivePath, err := u.downloadArtifact(ctx, version, sourceURI, skipVerifyOverride, pgpBytes...)
+	archivePath, err := u.downloadArtifact(ctx, version, sourceURI, det, skipVerifyOverride, pgpBytes...)
 	if err != nil {
 		// Run the same pre-upgrade cleanup task to get rid of any newly downloaded files
 		// This may have an issue if users are upgrading to the same version number.
@@ -129,6 +151,7 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string
 		return nil, err
 	}
 
+	det.SetState(details.StateApplying)
 	newHash, err := u.unpack(version, archivePath)
 	if err != nil {
 		return nil, err
@@ -140,9 +163,17 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string
 
 	if strings.HasPrefix(release.Commit(), newHash) {
 		u.log.Warn("Upgrade action skipped: upgrade did not occur because its the same version")
+		det.SetState(details.StateCompleted)
 		return nil, nil
 	}
 
+	newHome := filepath.Join(paths.Data(), fmt.Sprintf("%s-%s", agentName, newHash))
+	if err := integrity.WriteManifest(newHome); err != nil {
+		u.log.Errorw("Rolling back: writing binary integrity manifest failed", "error.message", err)
+		rollbackInstall(ctx, u.log, newHash)
+		return nil, err
+	}
+
 	if err := copyActionStore(u.log, newHash); err != nil {
 		return nil, errors.New(err, "failed to copy action store")
 	}
@@ -157,7 +188,7 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string
 		return nil, err
 	}
 
-	if err := u.markUpgrade(ctx, u.log, newHash, action); err != nil {
+	if err := u.markUpgrade(ctx, u.log, newHash, action, det); err != nil {
 		u.log.Errorw("Rolling back: marking upgrade failed", "error.message", err)
 		rollbackInstall(ctx, u.log, newHash)
 		return nil, err
@@ -183,6 +214,10 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, sourceURI string
 
 // Ack acks last upgrade action
 func (u *Upgrader) Ack(ctx context.Context, acker acker.Acker) error {
+	if err := ackManagedByPackage(ctx, acker); err != nil {
+		return err
+	}
+
 	// get upgrade action
 	marker, err := LoadMarker()
 	if err != nil {
@@ -199,6 +234,20 @@ func (u *Upgrader) Ack(ctx context.Context, acker acker.Acker) error {
 	// Action can be nil if the upgrade was called locally.
 	// Should handle gracefully
 	// https://github.com/elastic/elastic-agent/issues/1788
+	if marker.Action != nil && marker.Details != nil && marker.Details.State == details.StateRolledBack {
+		// the watcher rolled back the upgrade, report the action as failed
+		marker.Action.Err = fmt.Errorf("upgrade to version %s was rolled back: %s", marker.Action.Version, marker.Details.Metadata.ErrorMsg)
+	}
+	if marker.PackageManager != "" && marker.Action != nil && marker.Action.Version != currentVersion() {
+		// package upgrades are not watched, the package manager failed to install the new version
+		u.log.Warnw("Package upgrade was not applied, reporting the upgrade action as failed", "version", marker.Action.Version, "package_manager", marker.PackageManager)
+		marker.Action.Err = fmt.Errorf("package manager %s did not install version %s, running version %s", marker.PackageManager, marker.Action.Version, currentVersion())
+	}
+	if marker.Action != nil && marker.Action.Err == nil && marker.Details != nil {
+		// the degradations of the successful upgrade are reported with its result
+		marker.Action.Warnings = marker.Details.Metadata.Warnings
+	}
+
 	if marker.Action != nil {
 		if err := acker.Ack(ctx, marker.Action); err != nil {
 			return err
@@ -209,6 +258,11 @@ func (u *Upgrader) Ack(ctx context.Context, acker acker.Acker) error {
 		}
 	}
 
+	if marker.PackageManager != "" {
+		// no watcher runs after a package upgrade, nothing else needs the marker
+		return CleanMarker(u.log)
+	}
+
 	marker.Acked = true
 
 	return saveMarker(marker)
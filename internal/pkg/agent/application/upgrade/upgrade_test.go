@@ -5,6 +5,7 @@
 package upgrade
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -14,8 +15,13 @@ import (
 	"testing"
 
 	"github.com/gofrs/flock"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions/handlers/mocks"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -134,3 +140,35 @@ func TestShutdownCallback(t *testing.T) {
 	require.NoError(t, err, "reading file failed")
 	require.Equal(t, content, newContent, "contents are not equal")
 }
+
+func TestAckPackageUpgradeNotApplied(t *testing.T) {
+	defer func(prev string) { paths.SetTop(prev) }(paths.Top())
+	paths.SetTop(t.TempDir())
+	require.NoError(t, os.MkdirAll(paths.Data(), 0755))
+
+	action := &fleetapi.ActionUpgrade{ActionID: "upgrade-id", ActionType: fleetapi.ActionTypeUpgrade, Version: "99.0.0"}
+	require.NoError(t, saveMarker(&UpdateMarker{
+		Action:         action,
+		PackageManager: string(info.PackageManagerDEB),
+	}))
+
+	var acked *fleetapi.ActionUpgrade
+	acker := mocks.NewAcker(t)
+	acker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, a fleetapi.Action) error {
+		acked, _ = a.(*fleetapi.ActionUpgrade)
+		return nil
+	})
+	acker.EXPECT().Commit(mock.Anything).Return(nil)
+
+	l, _ := logger.New("test", false)
+	u := NewUpgrader(l, nil, &info.AgentInfo{}, nil)
+	require.NoError(t, u.Ack(context.Background(), acker))
+
+	require.NotNil(t, acked, "the upgrade action is acked")
+	require.Error(t, acked.Err, "the upgrade action is reported as failed")
+	require.Contains(t, acked.Err.Error(), "did not install version 99.0.0")
+
+	marker, err := LoadMarker()
+	require.NoError(t, err)
+	require.Nil(t, marker, "the marker is removed")
+}
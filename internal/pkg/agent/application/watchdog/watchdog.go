@@ -0,0 +1,139 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package watchdog detects the event loops of the agent that stop making progress, it is
+// independent of the watchdog of the service manager which only checks the process is alive.
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Probe checks an event loop makes progress.
+type Probe struct {
+	// Name identifies the event loop in the logs and in the name of the goroutine dumps.
+	Name string
+	// Heartbeat returns once the event loop handled it, or with an error when the context is done.
+	Heartbeat func(ctx context.Context) error
+}
+
+// Watchdog periodically calls the heartbeat of the probes. When a heartbeat does not return
+// within the timeout, the goroutines are dumped to a file and, when configured, the agent
+// is restarted.
+type Watchdog struct {
+	log     *logger.Logger
+	cfg     *configuration.WatchdogConfig
+	dumpDir string
+	restart func()
+	probes  []Probe
+}
+
+// New creates a watchdog checking the probes, the goroutines are dumped in dumpDir and restart
+// is called when a probe is stuck and the restart is enabled.
+func New(log *logger.Logger, cfg *configuration.WatchdogConfig, dumpDir string, restart func(), probes ...Probe) *Watchdog {
+	return &Watchdog{
+		log:     log,
+		cfg:     cfg,
+		dumpDir: dumpDir,
+		restart: restart,
+		probes:  probes,
+	}
+}
+
+// Run checks the probes until the context is done.
+func (w *Watchdog) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, p := range w.probes {
+		wg.Add(1)
+		go func(p Probe) {
+			defer wg.Done()
+			w.watch(ctx, p)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// watch checks one probe until the context is done, each stall is reported once.
+func (w *Watchdog) watch(ctx context.Context, p Probe) {
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+
+	stalled := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		hbCtx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+		err := p.Heartbeat(hbCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			if stalled {
+				w.log.Warnf("Watchdog: the %s loop makes progress again", p.Name)
+				stalled = false
+			}
+			continue
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			w.log.Warnw(fmt.Sprintf("Watchdog: failed to check the %s loop", p.Name), "error.message", err)
+			continue
+		}
+		if stalled {
+			continue
+		}
+		stalled = true
+		w.stalled(p)
+	}
+}
+
+// stalled reports a probe that did not make progress within the timeout.
+func (w *Watchdog) stalled(p Probe) {
+	w.log.Errorf("Watchdog: the %s loop made no progress for %s, it may be deadlocked", p.Name, w.cfg.Timeout)
+	path, err := dumpGoroutines(w.dumpDir, p.Name, time.Now())
+	if err != nil {
+		w.log.Errorw("Watchdog: failed to dump the goroutines", "error.message", err)
+	} else {
+		w.log.Errorw("Watchdog: dumped the goroutines", "file.path", path)
+	}
+	if w.cfg.Restart && w.restart != nil {
+		w.log.Errorf("Watchdog: restarting Elastic Agent as the %s loop made no progress", p.Name)
+		w.restart()
+	}
+}
+
+// dumpGoroutines writes the stack of all the goroutines to a file in dir and returns its path.
+func dumpGoroutines(dir string, name string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	name = strings.ReplaceAll(name, " ", "-")
+	path := filepath.Join(dir, fmt.Sprintf("watchdog-%s-goroutines-%s.txt", name, now.UTC().Format("20060102T150405Z")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	defer f.Close()
+	// debug level 2 prints the stacks in the format of an unrecovered panic, with the time
+	// each goroutine has been blocked for
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return "", fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	return path, nil
+}
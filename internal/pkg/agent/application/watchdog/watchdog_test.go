@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package watchdog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestWatchdog(t *testing.T) {
+	log, obs := logger.NewTesting("watchdog")
+	dir := t.TempDir()
+	cfg := &configuration.WatchdogConfig{
+		Enabled:  true,
+		Interval: 10 * time.Millisecond,
+		Timeout:  50 * time.Millisecond,
+		Restart:  true,
+	}
+
+	var stuck atomic.Bool
+	stuck.Store(true)
+	var restarts atomic.Int32
+	healthy := Probe{Name: "healthy", Heartbeat: func(context.Context) error { return nil }}
+	deadlocked := Probe{Name: "deadlocked", Heartbeat: func(ctx context.Context) error {
+		if !stuck.Load() {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		New(log, cfg, dir, func() { restarts.Add(1) }, healthy, deadlocked).Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return restarts.Load() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	// the stall is only reported once
+	time.Sleep(5 * cfg.Timeout)
+	assert.Equal(t, int32(1), restarts.Load())
+
+	dumps, err := filepath.Glob(filepath.Join(dir, "watchdog-deadlocked-goroutines-*.txt"))
+	require.NoError(t, err)
+	require.Len(t, dumps, 1)
+	dump, err := os.ReadFile(dumps[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(dump), "goroutine ")
+
+	stuck.Store(false)
+	require.Eventually(t, func() bool {
+		return obs.FilterMessage("Watchdog: the deadlocked loop makes progress again").Len() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Zero(t, obs.FilterMessageSnippet("healthy").Len())
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchdog not stopped once the context is done")
+	}
+}
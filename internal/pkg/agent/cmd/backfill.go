@@ -0,0 +1,116 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newBackfillCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Have the log inputs re-read a time range or a set of files",
+		Long: `This command instructs the log inputs of the running Elastic Agent to re-read a time range or a set of files, to recover from a loss of data downstream without editing the registry of the inputs.
+The units of the inputs are backfilled one after the other and the result of each one is printed once it is done.
+
+The bounds of the time range are RFC3339 timestamps or durations before now, like 6h.`,
+		Example: `  elastic-agent backfill --input logs-nginx --from 2023-09-10T10:00:00Z --to 2023-09-10T12:00:00Z
+  elastic-agent backfill --path /var/log/nginx/access.log --from 6h`,
+		Run: func(c *cobra.Command, _ []string) {
+			if err := backfillCmd(streams, c); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringSlice("input", []string{}, "ID of an input to backfill, every log input when not set")
+	cmd.Flags().StringSlice("path", []string{}, "File to re-read, every file of the inputs when not set")
+	cmd.Flags().String("from", "", "Start of the time range to re-read")
+	cmd.Flags().String("to", "", "End of the time range to re-read")
+
+	return cmd
+}
+
+func backfillCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
+	req, err := backfillRequestFromFlags(cmd, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx := handleSignal(context.Background())
+	daemon := client.New()
+	err = daemon.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	done, failed := 0, 0
+	err = daemon.Backfill(ctx, req, func(r client.BackfillUnitResult) {
+		done++
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(streams.Out, "Unit %s of component %s failed to backfill: %s\n", r.UnitID, r.ComponentID, r.Err)
+			return
+		}
+		fmt.Fprintf(streams.Out, "Unit %s of component %s backfilled\n", r.UnitID, r.ComponentID)
+		if r.Response != "" {
+			fmt.Fprintf(streams.Out, "  %s\n", r.Response)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to backfill: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d unit(s) failed to backfill", failed, done)
+	}
+	fmt.Fprintf(streams.Out, "%d unit(s) backfilled\n", done)
+	return nil
+}
+
+func backfillRequestFromFlags(cmd *cobra.Command, now time.Time) (client.BackfillRequest, error) {
+	inputs, _ := cmd.Flags().GetStringSlice("input")
+	paths, _ := cmd.Flags().GetStringSlice("path")
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+
+	req := client.BackfillRequest{Inputs: inputs, Paths: paths}
+	var err error
+	if req.From, err = parseBackfillTime(from, now); err != nil {
+		return req, fmt.Errorf("invalid --from: %w", err)
+	}
+	if req.To, err = parseBackfillTime(to, now); err != nil {
+		return req, fmt.Errorf("invalid --to: %w", err)
+	}
+	if len(req.Paths) == 0 && req.From.IsZero() && req.To.IsZero() {
+		return req, errors.New("--path, --from or --to must be set")
+	}
+	return req, nil
+}
+
+// parseBackfillTime parses an RFC3339 timestamp or a duration before now.
+func parseBackfillTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC3339 timestamp nor a duration", value)
+	}
+	return t, nil
+}
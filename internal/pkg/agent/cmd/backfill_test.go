@@ -0,0 +1,43 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func TestBackfillRequestFromFlags(t *testing.T) {
+	now := time.Date(2023, 9, 10, 12, 0, 0, 0, time.UTC)
+	streams, _, _, _ := cli.NewTestingIOStreams()
+
+	cmd := newBackfillCommandWithArgs(nil, streams)
+	require.NoError(t, cmd.ParseFlags([]string{"--input", "logs-1,logs-2", "--from", "6h", "--to", "2023-09-10T11:00:00Z"}))
+	req, err := backfillRequestFromFlags(cmd, now)
+	require.NoError(t, err)
+	assert.Equal(t, client.BackfillRequest{
+		Inputs: []string{"logs-1", "logs-2"},
+		Paths:  []string{},
+		From:   now.Add(-6 * time.Hour),
+		To:     time.Date(2023, 9, 10, 11, 0, 0, 0, time.UTC),
+	}, req)
+
+	cmd = newBackfillCommandWithArgs(nil, streams)
+	require.NoError(t, cmd.ParseFlags([]string{"--from", "yesterday"}))
+	_, err = backfillRequestFromFlags(cmd, now)
+	assert.EqualError(t, err, `invalid --from: "yesterday" is neither an RFC3339 timestamp nor a duration`)
+
+	// re-reading every file from the beginning is never what is wanted
+	cmd = newBackfillCommandWithArgs(nil, streams)
+	require.NoError(t, cmd.ParseFlags([]string{"--input", "logs-1"}))
+	_, err = backfillRequestFromFlags(cmd, now)
+	assert.EqualError(t, err, "--path, --from or --to must be set")
+}
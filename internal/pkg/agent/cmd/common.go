@@ -38,6 +38,8 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return tryContainerLoadPaths()
 		},
+		// replaced by the completion command documenting how to load the scripts
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
 	}
 
 	// path flags
@@ -63,21 +65,37 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(run)
 	cmd.AddCommand(newInstallCommandWithArgs(args, streams))
 	cmd.AddCommand(newUninstallCommandWithArgs(args, streams))
+	cmd.AddCommand(newRestoreStateCommandWithArgs(args, streams))
 	cmd.AddCommand(newUpgradeCommandWithArgs(args, streams))
 	cmd.AddCommand(newEnrollCommandWithArgs(args, streams))
 	cmd.AddCommand(newInspectCommandWithArgs(args, streams))
 	cmd.AddCommand(newWatchCommandWithArgs(args, streams))
 	cmd.AddCommand(newContainerCommand(args, streams))
 	cmd.AddCommand(newStatusCommand(args, streams))
+	cmd.AddCommand(newHealthcheckCommand(args, streams))
 	cmd.AddCommand(newDiagnosticsCommand(args, streams))
 	cmd.AddCommand(newComponentCommandWithArgs(args, streams))
 	cmd.AddCommand(newLogsCommandWithArgs(args, streams))
+	cmd.AddCommand(newRotateCredentialsCommand(args, streams))
+	cmd.AddCommand(newReloadCommandWithArgs(args, streams))
+	cmd.AddCommand(newBackfillCommandWithArgs(args, streams))
+	cmd.AddCommand(newMetadataCommandWithArgs(args, streams))
+	cmd.AddCommand(newTagsCommandWithArgs(args, streams))
+	cmd.AddCommand(newTelemetryCommandWithArgs(args, streams))
+	cmd.AddCommand(newSpecCommandWithArgs(args, streams))
+	cmd.AddCommand(newCompletionCommandWithArgs(args, streams))
+	cmd.AddCommand(newIntrospectCommandWithArgs(args, streams))
 
 	// windows special hidden sub-command (only added on Windows)
 	reexec := newReExecWindowsCommand(args, streams)
 	if reexec != nil {
 		cmd.AddCommand(reexec)
 	}
+	// linux special hidden sub-command (only added on Linux)
+	seccompExec := newSeccompExecCommand(args, streams)
+	if seccompExec != nil {
+		cmd.AddCommand(seccompExec)
+	}
 	cmd.Run = run.Run
 	cmd.RunE = run.RunE
 
@@ -0,0 +1,165 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+// introspectCommandName is the name of the hidden command describing the commands and their flags.
+const introspectCommandName = "__introspect"
+
+func newCompletionCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Output the shell completion script",
+		Long: `This command outputs the script completing the commands and the flags of the Elastic Agent for the given shell.
+
+Bash:
+  source <(elastic-agent completion bash)
+  # or, to load it in every session on Linux:
+  elastic-agent completion bash > /etc/bash_completion.d/elastic-agent
+
+Zsh:
+  elastic-agent completion zsh > "${fpath[1]}/_elastic-agent"
+
+Fish:
+  elastic-agent completion fish > ~/.config/fish/completions/elastic-agent.fish
+
+PowerShell:
+  elastic-agent completion powershell | Out-String | Invoke-Expression
+  # or, to load it in every session, add the output to the profile:
+  elastic-agent completion powershell >> $PROFILE`,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.ExactValidArgs(1),
+		DisableFlagsInUseLine: true,
+		Run: func(c *cobra.Command, args []string) {
+			if err := completionCmd(streams.Out, c.Root(), args[0]); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	return cmd
+}
+
+func completionCmd(w io.Writer, root *cobra.Command, shell string) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(w)
+	}
+	return fmt.Errorf("unsupported shell %q", shell)
+}
+
+func newIntrospectCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    introspectCommandName,
+		Short:  "Describe the commands and their flags as JSON",
+		Long:   "This command describes the commands of the Elastic Agent and their flags as JSON, for the wrappers and the configuration management modules generated from the CLI.",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		Run: func(c *cobra.Command, _ []string) {
+			all, _ := c.Flags().GetBool("all")
+			if err := introspectCmd(streams.Out, c.Root(), all); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().Bool("all", false, "Include the hidden commands and flags")
+	return cmd
+}
+
+// commandDescription describes a command and its sub-commands.
+type commandDescription struct {
+	Name       string               `json:"name"`
+	Path       string               `json:"path"`
+	Usage      string               `json:"usage"`
+	Short      string               `json:"short,omitempty"`
+	Long       string               `json:"long,omitempty"`
+	Example    string               `json:"example,omitempty"`
+	Aliases    []string             `json:"aliases,omitempty"`
+	ValidArgs  []string             `json:"valid_args,omitempty"`
+	Hidden     bool                 `json:"hidden,omitempty"`
+	Deprecated string               `json:"deprecated,omitempty"`
+	Flags      []flagDescription    `json:"flags,omitempty"`
+	Commands   []commandDescription `json:"commands,omitempty"`
+}
+
+// flagDescription describes a flag, the persistent flags are described on the command defining
+// them and apply to its sub-commands.
+type flagDescription struct {
+	Name       string `json:"name"`
+	Shorthand  string `json:"shorthand,omitempty"`
+	Type       string `json:"type"`
+	Default    string `json:"default,omitempty"`
+	Usage      string `json:"usage,omitempty"`
+	Persistent bool   `json:"persistent,omitempty"`
+	Hidden     bool   `json:"hidden,omitempty"`
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+func introspectCmd(w io.Writer, root *cobra.Command, all bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(describeCommand(root, all))
+}
+
+func describeCommand(c *cobra.Command, all bool) commandDescription {
+	d := commandDescription{
+		Name:       c.Name(),
+		Path:       c.CommandPath(),
+		Usage:      c.UseLine(),
+		Short:      c.Short,
+		Long:       c.Long,
+		Example:    c.Example,
+		Aliases:    c.Aliases,
+		ValidArgs:  c.ValidArgs,
+		Hidden:     c.Hidden,
+		Deprecated: c.Deprecated,
+	}
+	persistent := c.PersistentFlags()
+	addFlag := func(f *pflag.Flag) {
+		if f.Hidden && !all {
+			return
+		}
+		d.Flags = append(d.Flags, flagDescription{
+			Name:       f.Name,
+			Shorthand:  f.Shorthand,
+			Type:       f.Value.Type(),
+			Default:    f.DefValue,
+			Usage:      f.Usage,
+			Persistent: persistent.Lookup(f.Name) != nil,
+			Hidden:     f.Hidden,
+			Deprecated: f.Deprecated,
+		})
+	}
+	c.LocalFlags().VisitAll(addFlag)
+	sort.Slice(d.Flags, func(i, j int) bool {
+		return d.Flags[i].Name < d.Flags[j].Name
+	})
+	for _, sub := range c.Commands() {
+		if (sub.Hidden || sub.Name() == "help") && !all {
+			continue
+		}
+		d.Commands = append(d.Commands, describeCommand(sub, all))
+	}
+	return d
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestCompletionCmd(t *testing.T) {
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	root := NewCommandWithArgs(nil, streams)
+
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		var b bytes.Buffer
+		require.NoError(t, completionCmd(&b, root, shell), shell)
+		assert.Contains(t, b.String(), "elastic-agent", shell)
+	}
+	assert.EqualError(t, completionCmd(&bytes.Buffer{}, root, "tcsh"), `unsupported shell "tcsh"`)
+}
+
+func TestIntrospectCmd(t *testing.T) {
+	root := &cobra.Command{Use: "elastic-agent [subcommand]"}
+	root.PersistentFlags().StringP("config", "c", "elastic-agent.yml", "Configuration file")
+	install := &cobra.Command{Use: "install", Short: "Install Elastic Agent permanently on this system", Run: func(*cobra.Command, []string) {}}
+	install.Flags().BoolP("force", "f", false, "Force overwrite the current installation")
+	install.Flags().String("internal", "", "Internal flag")
+	_ = install.Flags().MarkHidden("internal")
+	root.AddCommand(install)
+	root.AddCommand(&cobra.Command{Use: "secret", Hidden: true, Run: func(*cobra.Command, []string) {}})
+
+	var b bytes.Buffer
+	require.NoError(t, introspectCmd(&b, root, false))
+	var d commandDescription
+	require.NoError(t, json.Unmarshal(b.Bytes(), &d))
+	assert.Equal(t, commandDescription{
+		Name:  "elastic-agent",
+		Path:  "elastic-agent",
+		Usage: "elastic-agent [subcommand]",
+		Flags: []flagDescription{
+			{Name: "config", Shorthand: "c", Type: "string", Default: "elastic-agent.yml", Usage: "Configuration file", Persistent: true},
+		},
+		Commands: []commandDescription{
+			{
+				Name:  "install",
+				Path:  "elastic-agent install",
+				Usage: "elastic-agent install [flags]",
+				Short: "Install Elastic Agent permanently on this system",
+				Flags: []flagDescription{
+					{Name: "force", Shorthand: "f", Type: "bool", Default: "false", Usage: "Force overwrite the current installation"},
+				},
+			},
+		},
+	}, d)
+
+	b.Reset()
+	require.NoError(t, introspectCmd(&b, root, true))
+	require.NoError(t, json.Unmarshal(b.Bytes(), &d))
+	require.Len(t, d.Commands, 2)
+	assert.Len(t, d.Commands[0].Flags, 2)
+	assert.True(t, d.Commands[1].Hidden)
+}
@@ -7,22 +7,39 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
+	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 
 	"github.com/spf13/cobra"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/config/operations"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics/upload"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
 func newDiagnosticsCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "diagnostics",
 		Short: "Gather diagnostics information from the Elastic Agent and write it to a zip archive",
-		Long:  "This command gathers diagnostics information from the Elastic Agent and writes it to a zip archive.",
+		Long: `This command gathers diagnostics information from the Elastic Agent and writes it to a zip archive.
+
+With --upload the archive is uploaded in chunks to Fleet or to the S3 bucket of agent.monitoring.diagnostics.uploader.s3 instead.
+Fleet only accepts the diagnostics requested from Fleet, --action-id is the ID of the request. An interrupted upload is resumed
+with --resume and the ID it printed.
+
+The files are redacted with the profile of agent.diagnostics.redaction, --redaction-profile overrides it.`,
 		Run: func(c *cobra.Command, args []string) {
 			if err := diagnosticCmd(streams, c); err != nil {
 				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
@@ -32,11 +49,25 @@ func newDiagnosticsCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 	}
 
 	cmd.Flags().StringP("file", "f", "", "name of the output diagnostics zip archive")
+	cmd.Flags().String("redaction-profile", "", "redaction profile of the diagnostics: standard, strict or custom, overrides the configuration")
+	cmd.Flags().String("upload", "", "upload the diagnostics to fleet or s3 instead of writing them to a file")
+	cmd.Flags().String("action-id", "", "ID of the request of diagnostics from Fleet the uploaded diagnostics answer")
+	cmd.Flags().String("resume", "", "resume the interrupted upload of this ID")
+	cmd.Flags().String("s3-bucket", "", "bucket the diagnostics are uploaded to, overrides the configuration")
+	cmd.Flags().String("s3-prefix", "", "prefix of the key of the uploaded diagnostics, overrides the configuration")
+	cmd.Flags().String("s3-region", "", "region of the bucket, overrides the configuration")
+	cmd.Flags().String("s3-endpoint", "", "endpoint of an S3 compatible service, overrides the configuration")
 
 	return cmd
 }
 
 func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
+	destination, _ := cmd.Flags().GetString("upload")
+	resume, _ := cmd.Flags().GetString("resume")
+	if destination != "" || resume != "" {
+		return diagnosticUploadCmd(streams, cmd)
+	}
+
 	fileName, _ := cmd.Flags().GetString("file")
 	if fileName == "" {
 		ts := time.Now().UTC()
@@ -45,6 +76,37 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 
 	ctx := handleSignal(context.Background())
 
+	l, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
+	if err != nil {
+		return err
+	}
+	rawCfg, err := operations.LoadFullAgentConfig(l, paths.ConfigFile(), false)
+	if err != nil {
+		// the diagnostics are still useful without the configuration, the default profile is used
+		fmt.Fprintf(streams.Err, "[WARNING] Could not load the configuration: %s\n", err)
+		rawCfg = nil
+	}
+	redactor, err := diagnosticsRedactor(cmd, rawCfg)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := writeDiagnostics(ctx, streams.Err, f, redactor); err != nil {
+		return fmt.Errorf("unable to create archive %q: %w", fileName, err)
+	}
+	fmt.Fprintf(streams.Out, "Created diagnostics archive %q\n", fileName)
+	fmt.Fprintln(streams.Out, "***** WARNING *****\nCreated archive may contain plain text credentials.\nEnsure that files in archive are redacted before sharing.\n*******************")
+	return nil
+}
+
+// writeDiagnostics fetches the diagnostics from the daemon and writes their zip archive to w.
+func writeDiagnostics(ctx context.Context, errOut io.Writer, w io.Writer, redactor *diagnostics.Redactor) error {
 	daemon := client.New()
 	err := daemon.Connect(ctx)
 	if err != nil {
@@ -62,16 +124,135 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		return fmt.Errorf("failed to fetch component/unit diagnostics: %w", err)
 	}
 
-	f, err := os.Create(fileName)
+	return diagnostics.ZipArchive(errOut, w, redactor, agentDiag, unitDiags)
+}
+
+// diagnosticUploadCmd uploads the diagnostics to Fleet or to S3, or resumes an interrupted upload.
+func diagnosticUploadCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
+	destination, _ := cmd.Flags().GetString("upload")
+	actionID, _ := cmd.Flags().GetString("action-id")
+	resume, _ := cmd.Flags().GetString("resume")
+
+	l, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	rawCfg, err := operations.LoadFullAgentConfig(l, paths.ConfigFile(), false)
+	if err != nil {
+		return fmt.Errorf("failed to load the configuration: %w", err)
+	}
+	cfg, err := configuration.NewFromConfig(rawCfg)
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration: %w", err)
+	}
+	redactor, err := diagnosticsRedactor(cmd, rawCfg)
+	if err != nil {
+		return err
+	}
+	uploaderCfg := cfg.Settings.MonitoringConfig.Diagnostics.Uploader
+	overrideS3Config(cmd, &uploaderCfg.S3)
 
-	if err := diagnostics.ZipArchive(streams.Err, f, agentDiag, unitDiags); err != nil {
-		return fmt.Errorf("unable to create archive %q: %w", fileName, err)
+	spool := upload.NewSpool(paths.DiagnosticsUploads())
+	ctx := handleSignal(context.Background())
+
+	var (
+		id string
+		f  *os.File
+		p  *upload.Progress
+	)
+	if resume != "" {
+		id = resume
+		f, p, err = spool.Pending(id)
+		if err != nil {
+			return err
+		}
+		if f == nil {
+			return fmt.Errorf("no interrupted upload of %s", id)
+		}
+		defer f.Close()
+		// resumed to the destination it was started with
+		uploaderCfg.Destination = p.Destination
+		actionID = id
+		if p.Destination != upload.DestinationFleet {
+			actionID = ""
+		}
+	} else {
+		uploaderCfg.Destination = destination
+		ts := time.Now().UTC().Format("2006-01-02T15-04-05Z07-00") // RFC3339 format that replaces : with -, so it will work on Windows
+		id = actionID
+		if destination != upload.DestinationFleet {
+			id = "cli-" + ts
+		}
+		p = &upload.Progress{Destination: destination, Name: upload.BundleName(ts)}
 	}
-	fmt.Fprintf(streams.Out, "Created diagnostics archive %q\n", fileName)
-	fmt.Fprintln(streams.Out, "***** WARNING *****\nCreated archive may contain plain text credentials.\nEnsure that files in archive are redacted before sharing.\n*******************")
+
+	var fleet *uploader.Client
+	if !configuration.IsStandalone(cfg.Fleet) {
+		sender, err := fleetclient.NewAuthWithConfig(l, cfg.Fleet.AccessAPIKey, cfg.Fleet.Client)
+		if err != nil {
+			return fmt.Errorf("failed to create fleet-server client: %w", err)
+		}
+		fleet = uploader.New(cfg.Fleet.Info.ID, sender, uploaderCfg)
+	}
+	dest, err := upload.NewDestination(uploaderCfg, fleet, actionID)
+	if err != nil {
+		return err
+	}
+
+	if f == nil {
+		if f, err = spool.Create(id); err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := writeDiagnostics(ctx, streams.Err, f, redactor); err != nil {
+			spool.Remove(id)
+			return fmt.Errorf("unable to create the diagnostics archive: %w", err)
+		}
+	}
+
+	fmt.Fprintf(streams.Out, "Uploading the diagnostics archive %q to %s\n", p.Name, dest.Name())
+	uploadID, err := upload.Upload(ctx, l, spool, id, f, p, dest)
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintf(streams.Out, "Upload interrupted, run the command with --resume %s to resume it\n", id)
+		}
+		return fmt.Errorf("failed to upload the diagnostics: %w", err)
+	}
+	fmt.Fprintf(streams.Out, "Uploaded the diagnostics archive %q to %s, upload %s\n", p.Name, dest.Name(), uploadID)
 	return nil
 }
+
+// overrideS3Config overrides the configuration of the S3 uploads with the flags set.
+func overrideS3Config(cmd *cobra.Command, cfg *monitoringCfg.S3Uploader) {
+	for flag, field := range map[string]*string{
+		"s3-bucket":   &cfg.Bucket,
+		"s3-prefix":   &cfg.Prefix,
+		"s3-region":   &cfg.Region,
+		"s3-endpoint": &cfg.Endpoint,
+	} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			*field = v
+		}
+	}
+}
+
+// diagnosticsRedactor returns the redactor of the agent.diagnostics.redaction configuration with the
+// profile of the --redaction-profile flag when set.
+func diagnosticsRedactor(cmd *cobra.Command, rawCfg *config.Config) (*diagnostics.Redactor, error) {
+	redactionCfg, err := diagnostics.ParseRedaction(rawCfg)
+	if err != nil {
+		return nil, err
+	}
+	if redactionCfg == nil {
+		redactionCfg = diagnostics.DefaultRedactionConfig()
+	}
+	if profile, _ := cmd.Flags().GetString("redaction-profile"); profile != "" && profile != redactionCfg.Profile {
+		// the keys and the patterns of the configuration only apply to its custom profile
+		redactionCfg = &diagnostics.RedactionConfig{Profile: profile}
+	}
+	redactor, err := diagnostics.NewRedactor(redactionCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid diagnostics redaction: %w", err)
+	}
+	return redactor, nil
+}
@@ -17,6 +17,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
@@ -29,7 +30,10 @@ func newEnrollCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 	cmd := &cobra.Command{
 		Use:   "enroll",
 		Short: "Enroll the Elastic Agent into Fleet",
-		Long:  "This command will enroll the Elastic Agent into Fleet.",
+		Long: `This command will enroll the Elastic Agent into Fleet.
+
+The flags can be set by their name in the YAML file given with --config-file. When the installed Elastic Agent is
+already enrolled with the same flags the command exits without enrolling it again.`,
 		Run: func(c *cobra.Command, args []string) {
 			if err := enroll(streams, c); err != nil {
 				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
@@ -39,6 +43,7 @@ func newEnrollCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 	}
 
 	addEnrollFlags(cmd)
+	addConfigFileFlag(cmd)
 	cmd.Flags().BoolP("force", "f", false, "Force overwrite the current and do not prompt for confirmation")
 
 	// used by install command
@@ -72,10 +77,15 @@ func addEnrollFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("proxy-url", "", "", "Configures the proxy url")
 	cmd.Flags().BoolP("proxy-disabled", "", false, "Disable proxy support including environment variables")
 	cmd.Flags().StringSliceP("proxy-header", "", []string{}, "Proxy headers used with CONNECT request")
+	cmd.Flags().StringSliceP("fleet-header", "", []string{}, "Headers added to the requests to Fleet Server")
 	cmd.Flags().BoolP("delay-enroll", "", false, "Delays enrollment to occur on first start of the Elastic Agent service")
 	cmd.Flags().DurationP("daemon-timeout", "", 0, "Timeout waiting for Elastic Agent daemon")
 	cmd.Flags().DurationP("fleet-server-timeout", "", 0, "Timeout waiting for Fleet Server to be ready to start enrollment")
 	cmd.Flags().StringSliceP("tag", "", []string{}, "User set tags")
+	cmd.Flags().StringP("id", "", "", "Agent ID to enroll with, replaces the agent with this ID in Fleet when the replace token matches")
+	cmd.Flags().StringP("replace-token", "", "", "Token allowing the agent to be replaced by enrolling again with the same ID")
+	cmd.Flags().BoolP("ephemeral", "", false, "Unenroll the agent and purge its local state when it shuts down, for short-lived hosts")
+	cmd.Flags().DurationP("ephemeral-inactivity-timeout", "", 0, "Unenroll an ephemeral agent once it runs no integration for this long")
 }
 
 func validateEnrollFlags(cmd *cobra.Command) error {
@@ -138,10 +148,15 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 	fProxyURL, _ := cmd.Flags().GetString("proxy-url")
 	fProxyDisabled, _ := cmd.Flags().GetBool("proxy-disabled")
 	fProxyHeaders, _ := cmd.Flags().GetStringSlice("proxy-header")
+	fFleetHeaders, _ := cmd.Flags().GetStringSlice("fleet-header")
 	delayEnroll, _ := cmd.Flags().GetBool("delay-enroll")
 	daemonTimeout, _ := cmd.Flags().GetDuration("daemon-timeout")
 	fTimeout, _ := cmd.Flags().GetDuration("fleet-server-timeout")
 	fTags, _ := cmd.Flags().GetStringSlice("tag")
+	id, _ := cmd.Flags().GetString("id")
+	replaceToken, _ := cmd.Flags().GetString("replace-token")
+	ephemeral, _ := cmd.Flags().GetBool("ephemeral")
+	ephemeralInactivity, _ := cmd.Flags().GetDuration("ephemeral-inactivity-timeout")
 	args := []string{}
 	if url != "" {
 		args = append(args, "--url")
@@ -240,6 +255,10 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 		args = append(args, "--proxy-header")
 		args = append(args, k+"="+v)
 	}
+	for k, v := range mapFromEnvList(fFleetHeaders) {
+		args = append(args, "--fleet-header")
+		args = append(args, k+"="+v)
+	}
 
 	if delayEnroll {
 		args = append(args, "--delay-enroll")
@@ -252,11 +271,27 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 	for _, v := range fTags {
 		args = append(args, "--tag", v)
 	}
+	if id != "" {
+		args = append(args, "--id", id)
+	}
+	if replaceToken != "" {
+		args = append(args, "--replace-token", replaceToken)
+	}
+	if ephemeral {
+		args = append(args, "--ephemeral")
+	}
+	if ephemeralInactivity != 0 {
+		args = append(args, "--ephemeral-inactivity-timeout", ephemeralInactivity.String())
+	}
 	return args
 }
 
 func enroll(streams *cli.IOStreams, cmd *cobra.Command) error {
-	err := validateEnrollFlags(cmd)
+	err := applyConfigFile(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateEnrollFlags(cmd)
 	if err != nil {
 		return err
 	}
@@ -292,6 +327,17 @@ func enroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 		force = true
 	}
 
+	// nothing to do when the installed agent is already enrolled with the same flags
+	desired := desiredInstallState(cmd, false)
+	installed, err := loadInstallState(paths.Top())
+	if err != nil {
+		return err
+	}
+	if !force && (cfg.Fleet != nil && cfg.Fleet.Enabled) && installed != nil && len(installed.diff(desired)) == 0 {
+		fmt.Fprintln(streams.Out, "Elastic Agent is already enrolled in the desired state.")
+		return nil
+	}
+
 	// prompt only when it is not forced and is already enrolled
 	if !force && (cfg.Fleet != nil && cfg.Fleet.Enabled) {
 		confirm, err := cli.Confirm("This will replace your current settings. Do you want to continue?", true)
@@ -335,10 +381,15 @@ func enroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 	proxyURL, _ := cmd.Flags().GetString("proxy-url")
 	proxyDisabled, _ := cmd.Flags().GetBool("proxy-disabled")
 	proxyHeaders, _ := cmd.Flags().GetStringSlice("proxy-header")
+	fleetHeaders, _ := cmd.Flags().GetStringSlice("fleet-header")
 	delayEnroll, _ := cmd.Flags().GetBool("delay-enroll")
 	daemonTimeout, _ := cmd.Flags().GetDuration("daemon-timeout")
 	fTimeout, _ := cmd.Flags().GetDuration("fleet-server-timeout")
 	tags, _ := cmd.Flags().GetStringSlice("tag")
+	id, _ := cmd.Flags().GetString("id")
+	replaceToken, _ := cmd.Flags().GetString("replace-token")
+	ephemeral, _ := cmd.Flags().GetBool("ephemeral")
+	ephemeralInactivity, _ := cmd.Flags().GetDuration("ephemeral-inactivity-timeout")
 
 	caStr, _ := cmd.Flags().GetString("certificate-authorities")
 	CAs := cli.StringToSlice(caStr)
@@ -368,9 +419,14 @@ func enroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 		ProxyURL:             proxyURL,
 		ProxyDisabled:        proxyDisabled,
 		ProxyHeaders:         mapFromEnvList(proxyHeaders),
+		FleetHeaders:         mapFromEnvList(fleetHeaders),
 		DelayEnroll:          delayEnroll,
 		DaemonTimeout:        daemonTimeout,
 		Tags:                 tags,
+		ID:                   id,
+		ReplaceToken:         replaceToken,
+		Ephemeral:            ephemeral,
+		EphemeralInactivity:  ephemeralInactivity,
 		FleetServer: enrollCmdFleetServerOption{
 			ConnStr:               fServer,
 			ElasticsearchCA:       fElasticSearchCA,
@@ -402,7 +458,24 @@ func enroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 		return err
 	}
 
-	return c.Execute(ctx, streams)
+	err = c.Execute(ctx, streams)
+	if err != nil {
+		return err
+	}
+
+	// the install command records the state once the agent is installed
+	if !fromInstall && !delayEnroll && info.RunningInstalled() {
+		if installed == nil {
+			installed = make(installState)
+		}
+		for name, value := range desired {
+			installed[name] = value
+		}
+		if err := installed.save(paths.Top()); err != nil {
+			return errors.New(err, "failed to record the installation state")
+		}
+	}
+	return nil
 }
 
 func handleSignal(ctx context.Context) context.Context {
@@ -15,8 +15,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 
+	"github.com/gofrs/uuid"
 	"go.elastic.co/apm"
 	"gopkg.in/yaml.v2"
 
@@ -63,6 +65,10 @@ type saver interface {
 	Save(io.Reader) error
 }
 
+type loader interface {
+	Load() (io.ReadCloser, error)
+}
+
 // enrollCmd is an enroll subcommand that interacts between the Kibana API and the Agent.
 type enrollCmd struct {
 	log          *logger.Logger
@@ -72,6 +78,9 @@ type enrollCmd struct {
 	remoteConfig remote.Config
 	agentProc    *process.Info
 	configPath   string
+
+	// agentInfoStore loads the agent information of the previous enrollment, nil to never reuse it
+	agentInfoStore loader
 }
 
 // enrollCmdFleetServerOption define all the supported enrollment options for bootstrapping with Fleet Server.
@@ -107,6 +116,7 @@ type enrollCmdOption struct {
 	ProxyURL             string                     `yaml:"proxy_url,omitempty"`
 	ProxyDisabled        bool                       `yaml:"proxy_disabled,omitempty"`
 	ProxyHeaders         map[string]string          `yaml:"proxy_headers,omitempty"`
+	FleetHeaders         map[string]string          `yaml:"fleet_headers,omitempty"`
 	DaemonTimeout        time.Duration              `yaml:"daemon_timeout,omitempty"`
 	UserProvidedMetadata map[string]interface{}     `yaml:"-"`
 	FixPermissions       bool                       `yaml:"-"`
@@ -114,6 +124,10 @@ type enrollCmdOption struct {
 	FleetServer          enrollCmdFleetServerOption `yaml:"-"`
 	SkipCreateSecret     bool                       `yaml:"-"`
 	Tags                 []string                   `yaml:"omitempty"`
+	ID                   string                     `yaml:"id,omitempty"`
+	ReplaceToken         string                     `yaml:"replace_token,omitempty"`
+	Ephemeral            bool                       `yaml:"ephemeral,omitempty"`
+	EphemeralInactivity  time.Duration              `yaml:"ephemeral_inactivity_timeout,omitempty"`
 }
 
 // remoteConfig returns the configuration used to connect the agent to a fleet process.
@@ -145,6 +159,7 @@ func (e *enrollCmdOption) remoteConfig() (remote.Config, error) {
 	}
 
 	cfg.Transport.Proxy = *proxySettings
+	cfg.Headers = e.FleetHeaders
 
 	return cfg, nil
 }
@@ -157,18 +172,24 @@ func newEnrollCmd(
 	configPath string,
 ) (*enrollCmd, error) {
 
+	diskStore := storage.NewEncryptedDiskStore(paths.AgentConfigFile())
 	store := storage.NewReplaceOnSuccessStore(
 		configPath,
 		application.DefaultAgentFleetConfig,
-		storage.NewEncryptedDiskStore(paths.AgentConfigFile()),
+		diskStore,
 	)
 
-	return newEnrollCmdWithStore(
+	c, err := newEnrollCmdWithStore(
 		log,
 		options,
 		configPath,
 		store,
 	)
+	if err != nil {
+		return nil, err
+	}
+	c.agentInfoStore = diskStore
+	return c, nil
 }
 
 // newEnrollCmdWithStore creates an new enrollment and accept a custom store.
@@ -402,7 +423,7 @@ func (c *enrollCmd) prepareFleetTLS() error {
 			if c.options.FleetServer.Host == "" {
 				c.options.FleetServer.Host = defaultFleetServerInternalHost
 			}
-			c.options.URL = fmt.Sprintf("http://%s:%d", host, port)
+			c.options.URL = "http://" + netutil.JoinHostPort(host, int(port))
 			c.options.Insecure = true
 			return nil
 		}
@@ -422,7 +443,7 @@ func (c *enrollCmd) prepareFleetTLS() error {
 		}
 		c.options.FleetServer.Cert = string(pair.Crt)
 		c.options.FleetServer.CertKey = string(pair.Key)
-		c.options.URL = fmt.Sprintf("https://%s:%d", hostname, port)
+		c.options.URL = "https://" + netutil.JoinHostPort(hostname, int(port))
 		c.options.CAs = []string{string(ca.Crt())}
 	}
 	// running with custom Cert and CertKey; URL is required to be set
@@ -434,7 +455,7 @@ func (c *enrollCmd) prepareFleetTLS() error {
 		if c.options.FleetServer.InternalPort != defaultFleetServerInternalPort {
 			c.log.Warnf("Internal endpoint configured to: %d. Changing this value is not supported.", c.options.FleetServer.InternalPort)
 		}
-		c.options.InternalURL = fmt.Sprintf("%s:%d", defaultFleetServerInternalHost, c.options.FleetServer.InternalPort)
+		c.options.InternalURL = netutil.JoinHostPort(defaultFleetServerInternalHost, int(c.options.FleetServer.InternalPort))
 	}
 
 	return nil
@@ -509,9 +530,16 @@ func (c *enrollCmd) enroll(ctx context.Context, persistentConfig map[string]inte
 		return errors.New(err, "acquiring metadata failed")
 	}
 
+	var hostID string
+	if metadata.Host != nil {
+		hostID = metadata.Host.ID
+	}
+
 	r := &fleetapi.EnrollRequest{
 		EnrollAPIKey: c.options.EnrollAPIKey,
 		Type:         fleetapi.PermanentEnroll,
+		ID:           c.options.ID,
+		ReplaceToken: c.options.ReplaceToken,
 		Metadata: fleetapi.Metadata{
 			Local:        metadata,
 			UserProvided: c.options.UserProvidedMetadata,
@@ -519,7 +547,29 @@ func (c *enrollCmd) enroll(ctx context.Context, persistentConfig map[string]inte
 		},
 	}
 
+	reenroll := false
+	if r.ID == "" {
+		if prev := c.previousEnrollment(hostID); prev != nil {
+			c.log.Infof("Agent %s was already enrolled on this host, replacing it in Fleet", prev.ID)
+			r.ID = prev.ID
+			r.ReplaceToken = prev.ReplaceToken
+			reenroll = true
+		}
+	}
+	if r.ReplaceToken == "" {
+		token, err := uuid.NewV4()
+		if err != nil {
+			return errors.New(err, "failed to generate the replace token")
+		}
+		r.ReplaceToken = token.String()
+	}
+
 	resp, err := cmd.Execute(ctx, r)
+	if reenroll && errors.Is(err, fleetapi.ErrConflict) {
+		c.log.Warnf("Fleet refused to replace agent %s, enrolling as a new agent", r.ID)
+		r.ID = ""
+		resp, err = cmd.Execute(ctx, r)
+	}
 	if err != nil {
 		return errors.New(err,
 			"fail to execute request to fleet-server",
@@ -530,8 +580,18 @@ func (c *enrollCmd) enroll(ctx context.Context, persistentConfig map[string]inte
 	if err != nil {
 		return err
 	}
+	if c.options.Ephemeral {
+		fleetConfig.Ephemeral = &configuration.EphemeralConfig{
+			Enabled:           true,
+			InactivityTimeout: c.options.EphemeralInactivity,
+		}
+	}
 
 	agentConfig := c.createAgentConfig(resp.Item.ID, persistentConfig, c.options.FleetServer.Headers)
+	agentConfig["replace_token"] = r.ReplaceToken
+	if hostID != "" {
+		agentConfig["host_id"] = hostID
+	}
 
 	localFleetServer := c.options.FleetServer.ConnStr != ""
 	if localFleetServer {
@@ -589,6 +649,46 @@ func (c *enrollCmd) enroll(ctx context.Context, persistentConfig map[string]inte
 	return nil
 }
 
+// previousEnrollment returns the agent information of the previous enrollment when it can be reused to
+// replace the agent in Fleet. It is only reused on the same host, the ID of a cloned disk or VM image
+// must not be shared by all the clones.
+func (c *enrollCmd) previousEnrollment(hostID string) *previousEnrollment {
+	if c.agentInfoStore == nil || hostID == "" {
+		return nil
+	}
+	reader, err := c.agentInfoStore.Load()
+	if err != nil {
+		return nil
+	}
+
+	// reader is closed by this function
+	cfg, err := config.NewConfigFrom(reader)
+	if err != nil {
+		c.log.Warnf("Failed to read the previous enrollment, enrolling as a new agent: %v", err)
+		return nil
+	}
+	stored := struct {
+		Agent previousEnrollment `config:"agent"`
+	}{}
+	if err := cfg.Unpack(&stored); err != nil {
+		c.log.Warnf("Failed to read the previous enrollment, enrolling as a new agent: %v", err)
+		return nil
+	}
+
+	prev := stored.Agent
+	if prev.ID == "" || prev.ReplaceToken == "" || prev.HostID != hostID {
+		return nil
+	}
+	return &prev
+}
+
+// previousEnrollment is the agent information stored by the previous enrollment.
+type previousEnrollment struct {
+	ID           string `config:"id"`
+	ReplaceToken string `config:"replace_token"`
+	HostID       string `config:"host_id"`
+}
+
 func (c *enrollCmd) startAgent(ctx context.Context) (<-chan *os.ProcessState, error) {
 	cmd, err := os.Executable()
 	if err != nil {
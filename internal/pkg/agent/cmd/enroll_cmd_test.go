@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
@@ -16,15 +18,18 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/core/authority"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/testutils"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -47,6 +52,18 @@ func (m *mockStore) Save(in io.Reader) error {
 	return nil
 }
 
+type mockLoader struct {
+	Err     error
+	Content string
+}
+
+func (m *mockLoader) Load() (io.ReadCloser, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return io.NopCloser(strings.NewReader(m.Content)), nil
+}
+
 func TestEnroll(t *testing.T) {
 	testutils.InitStorage(t)
 	skipCreateSecret := false
@@ -326,6 +343,127 @@ func TestEnroll(t *testing.T) {
 	))
 }
 
+func TestReenroll(t *testing.T) {
+	testutils.InitStorage(t)
+	skipCreateSecret := false
+	if runtime.GOOS == "darwin" {
+		skipCreateSecret = true
+	}
+
+	log, _ := logger.New("tst", false)
+	metadata, err := info.Metadata(log)
+	require.NoError(t, err)
+	previous := &mockLoader{Content: fmt.Sprintf(`
+agent:
+  id: previous-agent-id
+  replace_token: previous-replace-token
+  host_id: %s
+`, metadata.Host.ID)}
+
+	// enrollServer answers the enrollments with the agent ID of the request, or a new one, and a conflict
+	// for the agent IDs in conflicts.
+	enrollServer := func(requests *[]fleetapi.EnrollRequest, conflicts ...string) func(t *testing.T) *http.ServeMux {
+		return func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/enroll", func(w http.ResponseWriter, r *http.Request) {
+				var req fleetapi.EnrollRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				*requests = append(*requests, req)
+				for _, id := range conflicts {
+					if req.ID == id {
+						w.WriteHeader(http.StatusConflict)
+						_, _ = w.Write([]byte(`{"statusCode": 409, "error": "Conflict"}`))
+						return
+					}
+				}
+				id := req.ID
+				if id == "" {
+					id = "new-agent-id"
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = fmt.Fprintf(w, `{"action": "created", "item": {"id": %q, "type": "PERMANENT", "access_api_key": "my-access-api-key"}}`, id)
+			})
+			return mux
+		}
+	}
+	enroll := func(t *testing.T, host string, options enrollCmdOption, infoStore loader) map[string]interface{} {
+		options.URL = "http://" + host
+		options.EnrollAPIKey = "my-enrollment-api-key"
+		options.Insecure = true
+		options.SkipCreateSecret = skipCreateSecret
+		store := &mockStore{}
+		cmd, err := newEnrollCmdWithStore(log, &options, "", store)
+		require.NoError(t, err)
+		cmd.agentInfoStore = infoStore
+
+		streams, _, _, _ := cli.NewTestingIOStreams()
+		require.NoError(t, cmd.Execute(context.Background(), streams))
+
+		stored, err := config.NewConfigFrom(store.Content)
+		require.NoError(t, err)
+		agent := struct {
+			Agent map[string]interface{} `config:"agent"`
+		}{}
+		require.NoError(t, stored.Unpack(&agent))
+		return agent.Agent
+	}
+
+	var requests []fleetapi.EnrollRequest
+	t.Run("new agent gets a replace token", withServer(enrollServer(&requests), func(t *testing.T, host string) {
+		requests = nil
+		agent := enroll(t, host, enrollCmdOption{}, &mockLoader{Err: os.ErrNotExist})
+
+		require.Len(t, requests, 1)
+		require.Empty(t, requests[0].ID)
+		require.NotEmpty(t, requests[0].ReplaceToken)
+		require.Equal(t, "new-agent-id", agent["id"])
+		require.Equal(t, requests[0].ReplaceToken, agent["replace_token"])
+		require.Equal(t, metadata.Host.ID, agent["host_id"])
+	}))
+
+	t.Run("previous enrollment is reused", withServer(enrollServer(&requests), func(t *testing.T, host string) {
+		requests = nil
+		agent := enroll(t, host, enrollCmdOption{}, previous)
+
+		require.Len(t, requests, 1)
+		require.Equal(t, "previous-agent-id", requests[0].ID)
+		require.Equal(t, "previous-replace-token", requests[0].ReplaceToken)
+		require.Equal(t, "previous-agent-id", agent["id"])
+		require.Equal(t, "previous-replace-token", agent["replace_token"])
+	}))
+
+	t.Run("previous enrollment of another host is not reused", withServer(enrollServer(&requests), func(t *testing.T, host string) {
+		requests = nil
+		cloned := &mockLoader{Content: strings.Replace(previous.Content, metadata.Host.ID, "another-host-id", 1)}
+		agent := enroll(t, host, enrollCmdOption{}, cloned)
+
+		require.Len(t, requests, 1)
+		require.Empty(t, requests[0].ID)
+		require.Equal(t, "new-agent-id", agent["id"])
+		require.NotEqual(t, "previous-replace-token", agent["replace_token"])
+	}))
+
+	t.Run("conflict enrolls a new agent", withServer(enrollServer(&requests, "previous-agent-id"), func(t *testing.T, host string) {
+		requests = nil
+		agent := enroll(t, host, enrollCmdOption{}, previous)
+
+		require.Len(t, requests, 2)
+		require.Equal(t, "previous-agent-id", requests[0].ID)
+		require.Empty(t, requests[1].ID)
+		require.Equal(t, "new-agent-id", agent["id"])
+	}))
+
+	t.Run("ID from the options takes precedence", withServer(enrollServer(&requests), func(t *testing.T, host string) {
+		requests = nil
+		agent := enroll(t, host, enrollCmdOption{ID: "my-agent-id", ReplaceToken: "my-replace-token"}, previous)
+
+		require.Len(t, requests, 1)
+		require.Equal(t, "my-agent-id", requests[0].ID)
+		require.Equal(t, "my-replace-token", requests[0].ReplaceToken)
+		require.Equal(t, "my-agent-id", agent["id"])
+	}))
+}
+
 func TestValidateArgs(t *testing.T) {
 	url := "http://localhost:8220"
 	enrolmentToken := "my-enrollment-token"
@@ -378,6 +516,19 @@ func TestValidateArgs(t *testing.T) {
 		require.NotContains(t, cleanedTags, "")
 	})
 
+	t.Run("id and replace token are passed", func(t *testing.T) {
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err := cmd.Flags().Set("id", "my-agent-id")
+		require.NoError(t, err)
+		err = cmd.Flags().Set("replace-token", "my-replace-token")
+		require.NoError(t, err)
+		args := buildEnrollmentFlags(cmd, url, enrolmentToken)
+		require.Contains(t, args, "--id")
+		require.Contains(t, args, "my-agent-id")
+		require.Contains(t, args, "--replace-token")
+		require.Contains(t, args, "my-replace-token")
+	})
+
 	t.Run("secret paths are passed", func(t *testing.T) {
 		cmd := newEnrollCommandWithArgs([]string{}, streams)
 		err := cmd.Flags().Set("fleet-server-cert-key-passphrase", "/path/to/passphrase")
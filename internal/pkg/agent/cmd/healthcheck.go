@@ -0,0 +1,169 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+// The exit codes of the healthcheck command follow the Nagios plugin guidelines.
+const (
+	healthOK       = 0
+	healthWarning  = 1
+	healthCritical = 2
+	healthUnknown  = 3
+)
+
+var healthStatusNames = map[int]string{
+	healthOK:       "OK",
+	healthWarning:  "WARNING",
+	healthCritical: "CRITICAL",
+	healthUnknown:  "UNKNOWN",
+}
+
+var healthcheckFormats = map[string]func(io.Writer, healthResult){
+	"nagios":  nagiosOutput,
+	"checkmk": checkmkOutput,
+}
+
+// healthResult is the health of the agent derived from the state of the coordinator and of the components.
+type healthResult struct {
+	code       int
+	summary    string
+	components int
+	degraded   int
+	failed     int
+}
+
+func newHealthcheckCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Check the health of the running Elastic Agent daemon for monitoring systems",
+		Long: `This command checks the health of the running Elastic Agent daemon and prints a single line in the format of classic monitoring systems.
+The exit code is 0 when the agent is healthy, 1 when it is degraded or still starting, 2 when it or one of its components failed or the daemon cannot be reached, 3 when the check itself is invalid.`,
+		Run: func(c *cobra.Command, args []string) {
+			os.Exit(healthcheckCmd(streams, c))
+		},
+	}
+
+	cmd.Flags().String("format", "nagios", "Output the health in either 'nagios' or 'checkmk' local check format.")
+	cmd.Flags().Duration("timeout", 10*time.Second, "Time to wait for the Elastic Agent daemon before reporting it critical.")
+
+	return cmd
+}
+
+func healthcheckCmd(streams *cli.IOStreams, cmd *cobra.Command) int {
+	format, _ := cmd.Flags().GetString("format")
+	output, ok := healthcheckFormats[format]
+	if !ok {
+		nagiosOutput(streams.Out, healthResult{code: healthUnknown, summary: fmt.Sprintf("unsupported format: %s", format)})
+		return healthUnknown
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	ctx, cancel := context.WithTimeout(handleSignal(context.Background()), timeout)
+	defer cancel()
+
+	var result healthResult
+	state, err := getDaemonState(ctx)
+	if err != nil {
+		result = healthResult{code: healthCritical, summary: fmt.Sprintf("failed to communicate with Elastic Agent daemon: %v", err)}
+	} else {
+		result = evaluateHealth(state)
+	}
+	output(streams.Out, result)
+	return result.code
+}
+
+// evaluateHealth derives the health from the states, the worst state wins. Failed and stopped states are
+// critical, the other states that are not healthy are warnings. Fleet failing is a warning as the agent
+// keeps running its last policy.
+func evaluateHealth(state *client.AgentState) healthResult {
+	result := healthResult{components: len(state.Components)}
+	var problems []string
+	report := func(code int, name string, s client.State, message string) {
+		if code == healthOK {
+			return
+		}
+		if code > result.code {
+			result.code = code
+		}
+		problems = append(problems, fmt.Sprintf("%s: %s (%s)", name, s, message))
+	}
+
+	report(stateHealth(state.State), "elastic-agent", state.State, state.Message)
+	if state.FleetState == client.Failed {
+		report(healthWarning, "fleet", state.FleetState, state.FleetMessage)
+	}
+	for _, c := range state.Components {
+		worst := stateHealth(c.State)
+		report(worst, c.ID, c.State, c.Message)
+		for _, u := range c.Units {
+			code := stateHealth(u.State)
+			report(code, u.UnitID, u.State, u.Message)
+			if code > worst {
+				worst = code
+			}
+		}
+		switch worst {
+		case healthWarning:
+			result.degraded++
+		case healthCritical:
+			result.failed++
+		}
+	}
+
+	if len(problems) == 0 {
+		result.summary = fmt.Sprintf("%s: %s", state.State, state.Message)
+	} else {
+		result.summary = strings.Join(problems, "; ")
+	}
+	return result
+}
+
+func stateHealth(s client.State) int {
+	switch s {
+	case client.Healthy:
+		return healthOK
+	case client.Failed, client.Stopped, client.Rollback:
+		return healthCritical
+	default:
+		return healthWarning
+	}
+}
+
+// perfdata returns the metrics of the result, the perfdata of Nagios and the metrics of checkmk share the same syntax.
+func (r healthResult) perfdata(sep string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("components=%d", r.components),
+		fmt.Sprintf("degraded=%d", r.degraded),
+		fmt.Sprintf("failed=%d", r.failed),
+	}, sep)
+}
+
+// nagiosOutput writes SERVICE STATUS - summary | perfdata.
+func nagiosOutput(w io.Writer, r healthResult) {
+	fmt.Fprintf(w, "ELASTIC-AGENT %s - %s | %s\n", healthStatusNames[r.code], singleLine(r.summary), r.perfdata(" "))
+}
+
+// checkmkOutput writes a checkmk local check, status service metrics summary.
+func checkmkOutput(w io.Writer, r healthResult) {
+	fmt.Fprintf(w, "%d Elastic_Agent %s %s - %s\n", r.code, r.perfdata("|"), healthStatusNames[r.code], singleLine(r.summary))
+}
+
+// singleLine keeps the summary on one line, the pipe separates the perfdata of Nagios.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "|", "/").Replace(s)
+}
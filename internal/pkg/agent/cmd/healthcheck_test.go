@@ -0,0 +1,87 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func TestHealthcheck(t *testing.T) {
+	healthy := client.ComponentState{
+		ID:    "log-default",
+		State: client.Healthy,
+		Units: []client.ComponentUnitState{{UnitID: "log-default", State: client.Healthy}},
+	}
+
+	testcases := []struct {
+		name    string
+		state   *client.AgentState
+		code    int
+		nagios  string
+		checkmk string
+	}{{
+		name: "healthy standalone",
+		state: &client.AgentState{
+			State:        client.Healthy,
+			Message:      "Running",
+			FleetState:   client.Stopped,
+			FleetMessage: "Not enrolled into Fleet",
+			Components:   []client.ComponentState{healthy},
+		},
+		code:    healthOK,
+		nagios:  "ELASTIC-AGENT OK - HEALTHY: Running | components=1 degraded=0 failed=0\n",
+		checkmk: "0 Elastic_Agent components=1|degraded=0|failed=0 OK - HEALTHY: Running\n",
+	}, {
+		name: "degraded unit",
+		state: &client.AgentState{
+			State:   client.Healthy,
+			Message: "Running",
+			Components: []client.ComponentState{healthy, {
+				ID:    "filestream-default",
+				State: client.Healthy,
+				Units: []client.ComponentUnitState{{UnitID: "filestream-default-logs", State: client.Degraded, Message: "slow\noutput"}},
+			}},
+		},
+		code:    healthWarning,
+		nagios:  "ELASTIC-AGENT WARNING - filestream-default-logs: DEGRADED (slow output) | components=2 degraded=1 failed=0\n",
+		checkmk: "1 Elastic_Agent components=2|degraded=1|failed=0 WARNING - filestream-default-logs: DEGRADED (slow output)\n",
+	}, {
+		name: "failed component and fleet",
+		state: &client.AgentState{
+			State:        client.Degraded,
+			Message:      "1 or more components/units in a failed state",
+			FleetState:   client.Failed,
+			FleetMessage: "checkin failed",
+			Components: []client.ComponentState{{
+				ID:      "httpjson-default",
+				State:   client.Failed,
+				Message: "crashed | exit 2",
+			}},
+		},
+		code:    healthCritical,
+		nagios:  "ELASTIC-AGENT CRITICAL - elastic-agent: DEGRADED (1 or more components/units in a failed state); fleet: FAILED (checkin failed); httpjson-default: FAILED (crashed / exit 2) | components=1 degraded=0 failed=1\n",
+		checkmk: "2 Elastic_Agent components=1|degraded=0|failed=1 CRITICAL - elastic-agent: DEGRADED (1 or more components/units in a failed state); fleet: FAILED (checkin failed); httpjson-default: FAILED (crashed / exit 2)\n",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := evaluateHealth(tc.state)
+			assert.Equal(t, tc.code, result.code)
+
+			var b bytes.Buffer
+			nagiosOutput(&b, result)
+			assert.Equal(t, tc.nagios, b.String())
+
+			b.Reset()
+			checkmkOutput(&b, result)
+			assert.Equal(t, tc.checkmk, b.String())
+		})
+	}
+}
@@ -7,6 +7,7 @@ package cmd
 import (
 	// include the composable providers
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/agent"
+	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/containerd"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/docker"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/env"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/host"
@@ -16,4 +17,8 @@ import (
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/local"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/localdynamic"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/path"
+	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/podman"
+
+	// include the builtin inputs
+	_ "github.com/elastic/elastic-agent/internal/pkg/hostwatcher"
 )
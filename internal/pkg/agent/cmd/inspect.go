@@ -94,6 +94,13 @@ first set of computed variables are used. This can prevent some of the dynamic p
 providing all the possible variables it could have discovered if given more time. The --variables-wait allows an
 amount of time to be provided for variable discovery, when set it will wait that amount of time before using the
 variables for the configuration.
+
+The --policy flag computes the components for a policy file instead of the current configuration, without touching
+the running Elastic Agent, so policy changes can be tested before they are deployed. In this mode no variable provider
+is run, the variables are read from the JSON file given with --vars: an object is a single set of variables, an array
+of objects are multiple sets like a dynamic provider discovers, the inputs rendered from each set get "-vars-<index>"
+appended to their ID. Without --vars the inputs referencing a variable are
+removed. Capabilities and monitoring components are not applied in this mode.
 `,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(c *cobra.Command, args []string) {
@@ -104,6 +111,8 @@ variables for the configuration.
 			opts.showConfig, _ = c.Flags().GetBool("show-config")
 			opts.showSpec, _ = c.Flags().GetBool("show-spec")
 			opts.variablesWait, _ = c.Flags().GetDuration("variables-wait")
+			opts.policyPath, _ = c.Flags().GetString("policy")
+			opts.varsPath, _ = c.Flags().GetString("vars")
 
 			ctx, cancel := context.WithCancel(context.Background())
 			service.HandleSignals(func() {}, cancel)
@@ -117,6 +126,8 @@ variables for the configuration.
 	cmd.Flags().Bool("show-config", false, "show the configuration for all units")
 	cmd.Flags().Bool("show-spec", false, "show the runtime specification for a component")
 	cmd.Flags().Duration("variables-wait", time.Duration(0), "wait this amount of time for variables before performing substitution")
+	cmd.Flags().String("policy", "", "compute the components for this policy file instead of the current configuration")
+	cmd.Flags().String("vars", "", "JSON file with the variables used to render the --policy file")
 
 	return cmd
 }
@@ -176,7 +187,7 @@ func inspectConfig(ctx context.Context, cfgPath string, opts inspectConfigOpts,
 		// binary name.
 		binaryMapping := make(map[string]string)
 		for _, component := range components {
-			if spec := component.InputSpec; spec != nil {
+			if spec := component.InputSpec; spec != nil && spec.Spec.Builtin == nil && spec.Spec.OTel == nil {
 				binaryMapping[component.ID] = spec.BinaryName
 			}
 		}
@@ -225,6 +236,8 @@ type inspectComponentsOpts struct {
 	showConfig    bool
 	showSpec      bool
 	variablesWait time.Duration
+	policyPath    string
+	varsPath      string
 }
 
 // returns true if the given Capabilities config blocks the given component.
@@ -237,6 +250,9 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 	if err != nil {
 		return err
 	}
+	if opts.varsPath != "" && opts.policyPath == "" {
+		return errors.New("--vars can only be used with --policy")
+	}
 
 	// Load the requirements before trying to load the configuration. These should always load
 	// even if the configuration is wrong.
@@ -249,23 +265,38 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 		return fmt.Errorf("failed to detect inputs and outputs: %w", err)
 	}
 
-	m, lvl, err := getConfigWithVariables(ctx, l, cfgPath, opts.variablesWait)
-	if err != nil {
-		return err
-	}
+	var (
+		m         map[string]interface{}
+		lvl       logp.Level
+		monitorFn component.GenerateMonitoringCfgFn
+		headers   component.HeadersProvider
+	)
+	if opts.policyPath != "" {
+		// simulation, nothing is loaded from the running Elastic Agent
+		m, lvl, err = getPolicyWithVariables(opts.policyPath, opts.varsPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		m, lvl, err = getConfigWithVariables(ctx, l, cfgPath, opts.variablesWait)
+		if err != nil {
+			return err
+		}
 
-	monitorFn, err := getMonitoringFn(m)
-	if err != nil {
-		return fmt.Errorf("failed to get monitoring: %w", err)
-	}
+		monitorFn, err = getMonitoringFn(m)
+		if err != nil {
+			return fmt.Errorf("failed to get monitoring: %w", err)
+		}
 
-	agentInfo, err := info.NewAgentInfoWithLog("error", false)
-	if err != nil {
-		return fmt.Errorf("could not load agent info: %w", err)
+		agentInfo, err := info.NewAgentInfoWithLog("error", false)
+		if err != nil {
+			return fmt.Errorf("could not load agent info: %w", err)
+		}
+		headers = agentInfo
 	}
 
 	// Compute the components from the computed configuration.
-	comps, err := specs.ToComponents(m, monitorFn, lvl, agentInfo)
+	comps, err := specs.ToComponents(m, monitorFn, lvl, headers)
 	if err != nil {
 		return fmt.Errorf("failed to render components: %w", err)
 	}
@@ -312,6 +343,10 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 		return fmt.Errorf("unable to find component with ID: %s", compID)
 	}
 
+	if opts.policyPath != "" {
+		return printComponents(comps, nil, streams)
+	}
+
 	// Separate any components that are blocked by capabilities config
 	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
 	if err != nil {
@@ -364,10 +399,6 @@ func getConfigWithVariables(ctx context.Context, l *logger.Logger, cfgPath strin
 	if err != nil {
 		return nil, lvl, err
 	}
-	ast, err := transpiler.NewAST(m)
-	if err != nil {
-		return nil, lvl, fmt.Errorf("could not create the AST from the configuration: %w", err)
-	}
 
 	// Wait for the variables based on the timeout.
 	vars, err := vars.WaitForVariables(ctx, l, cfg, timeout)
@@ -375,23 +406,60 @@ func getConfigWithVariables(ctx context.Context, l *logger.Logger, cfgPath strin
 		return nil, lvl, fmt.Errorf("failed to gather variables: %w", err)
 	}
 
-	// Render the inputs using the discovered inputs.
-	inputs, ok := transpiler.Lookup(ast, "inputs")
-	if ok {
-		renderedInputs, err := transpiler.RenderInputs(inputs, vars)
-		if err != nil {
-			return nil, lvl, fmt.Errorf("rendering inputs failed: %w", err)
-		}
-		err = transpiler.Insert(ast, renderedInputs, "inputs")
+	m, err = transpiler.RenderPolicyInputs(m, vars)
+	if err != nil {
+		return nil, lvl, err
+	}
+	return m, lvl, nil
+}
+
+// getPolicyWithVariables loads the policy file and renders its inputs with the variables from the vars file.
+func getPolicyWithVariables(policyPath string, varsPath string) (map[string]interface{}, logp.Level, error) {
+	cfg, err := config.LoadFile(policyPath)
+	if err != nil {
+		return nil, logp.InfoLevel, fmt.Errorf("could not load policy file %s: %w", policyPath, err)
+	}
+	lvl, err := getLogLevel(cfg, policyPath)
+	if err != nil {
+		return nil, logp.InfoLevel, err
+	}
+	m, err := cfg.ToMapStr()
+	if err != nil {
+		return nil, lvl, err
+	}
+
+	vars, err := loadVarsFile(varsPath)
+	if err != nil {
+		return nil, lvl, err
+	}
+
+	m, err = transpiler.RenderPolicyInputs(m, vars)
+	if err != nil {
+		return nil, lvl, err
+	}
+	return m, lvl, nil
+}
+
+// loadVarsFile loads the sets of variables from a JSON file, either an object for a single set or an array of
+// objects. A single empty set is returned when path is empty.
+func loadVarsFile(path string) ([]*transpiler.Vars, error) {
+	if path == "" {
+		empty, err := transpiler.NewVars("", map[string]interface{}{}, nil)
 		if err != nil {
-			return nil, lvl, fmt.Errorf("inserting rendered inputs failed: %w", err)
+			return nil, err
 		}
+		return []*transpiler.Vars{empty}, nil
 	}
-	m, err = ast.Map()
+
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, lvl, fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
+		return nil, fmt.Errorf("could not read vars file %s: %w", path, err)
 	}
-	return m, lvl, nil
+	vars, err := transpiler.NewVarsFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vars file %s: %w", path, err)
+	}
+	return vars, nil
 }
 
 func getLogLevel(rawCfg *config.Config, cfgPath string) (logp.Level, error) {
@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+func TestGetPolicyWithVariables(t *testing.T) {
+	policyPath := filepath.Join("testdata", "inspect", "policy.yml")
+
+	inputIDs := func(t *testing.T, m map[string]interface{}) []string {
+		inputs, ok := m["inputs"].([]interface{})
+		require.True(t, ok, "inputs must be a list")
+		var ids []string
+		for _, input := range inputs {
+			ids = append(ids, input.(map[string]interface{})["id"].(string))
+		}
+		return ids
+	}
+
+	t.Run("without vars", func(t *testing.T) {
+		m, lvl, err := getPolicyWithVariables(policyPath, "")
+		require.NoError(t, err)
+		assert.Equal(t, logp.DebugLevel, lvl)
+		// inputs referencing variables are removed
+		assert.Equal(t, []string{"system-logs"}, inputIDs(t, m))
+	})
+
+	t.Run("with vars", func(t *testing.T) {
+		m, _, err := getPolicyWithVariables(policyPath, filepath.Join("testdata", "inspect", "vars.json"))
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			"system-logs-vars-0",
+			"container-logs-vars-0",
+			"container-logs-vars-1",
+			"host-metrics-vars-0",
+		}, inputIDs(t, m))
+	})
+
+	t.Run("invalid vars", func(t *testing.T) {
+		varsPath := filepath.Join(t.TempDir(), "vars.json")
+		require.NoError(t, os.WriteFile(varsPath, []byte(`["host"]`), 0600))
+		_, _, err := getPolicyWithVariables(policyPath, varsPath)
+		assert.ErrorContains(t, err, "entry 0 must be an object")
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -21,7 +23,20 @@ import (
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
-const flagInstallBasePath = "base-path"
+const (
+	flagInstallBasePath     = "base-path"
+	flagInstallRestoreState = "restore-state"
+
+	flagInstallRestartDelay    = "service-restart-delay"
+	flagInstallRestartMaxDelay = "service-restart-max-delay"
+	flagInstallResetPeriod     = "service-reset-period"
+
+	flagInstallLaunchdDomain = "launchd-domain"
+	flagInstallLaunchdUser   = "launchd-user"
+
+	flagInstallServiceAccount         = "service-account"
+	flagInstallServiceAccountPassword = "service-account-password"
+)
 
 func newInstallCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
@@ -31,6 +46,16 @@ func newInstallCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comman
 
 Unless all the require command-line parameters are provided or -f is used this command will ask questions on how you
 would like the Agent to operate.
+
+With --restore-state the agent is installed with the state preserved by uninstall --preserve-state, it keeps its
+identity and enrollment in Fleet and the components resume from their cursors, it can't be enrolled again.
+
+On Windows the Elastic Agent can be installed under another Program Files directory with --base-path and its
+service run as a domain, local or group Managed Service Account with --service-account, the account is given access
+to the installation directory.
+
+The flags can be set by their name in the YAML file given with --config-file. When the Elastic Agent is already
+installed with the same flags and version the command exits without changing the installation.
 `,
 		Run: func(c *cobra.Command, _ []string) {
 			if err := installCmd(streams, c); err != nil {
@@ -43,26 +68,63 @@ would like the Agent to operate.
 	cmd.Flags().BoolP("force", "f", false, "Force overwrite the current and do not prompt for confirmation")
 	cmd.Flags().BoolP("non-interactive", "n", false, "Install Elastic Agent in non-interactive mode which will not prompt on missing parameters but fails instead.")
 	cmd.Flags().String(flagInstallBasePath, paths.DefaultBasePath, "The path where the Elastic Agent will be installed. It must be an absolute path.")
+	recovery := install.DefaultRecoveryOptions()
+	cmd.Flags().Duration(flagInstallRestartDelay, recovery.RestartDelay, "Delay before the service is restarted after a failure, it doubles on each consecutive failure (Windows only, the minimum time between two launches on macOS)")
+	cmd.Flags().Duration(flagInstallRestartMaxDelay, recovery.RestartMaxDelay, "Maximum delay before the service is restarted after a failure (Windows only)")
+	cmd.Flags().Duration(flagInstallResetPeriod, recovery.ResetPeriod, "Time without failure after which the count of failures of the service is reset (Windows only)")
+	cmd.Flags().String(flagInstallLaunchdDomain, string(install.LaunchdDomainSystem), "The launchd domain of the service (macOS only): system installs a launch daemon run as root at boot, user a launch agent run as --launchd-user in its session")
+	cmd.Flags().String(flagInstallLaunchdUser, "", "The user the launch agent runs as in the user launchd domain (macOS only), the user running sudo by default")
+	cmd.Flags().String(flagInstallServiceAccount, "", `The account the service runs as instead of LocalSystem (Windows only): DOMAIN\user, .\user for a local account or DOMAIN\name$ for a group Managed Service Account. It must hold the "Log on as a service" right`)
+	cmd.Flags().String(flagInstallServiceAccountPassword, "", "The password of the service account, not needed for a group Managed Service Account (Windows only)")
+	cmd.Flags().String(flagInstallRestoreState, "", "Restore the state of the agent from the archive written by uninstall --preserve-state")
 	addEnrollFlags(cmd)
+	addConfigFileFlag(cmd)
 
 	// We are not supporting a custom base path, supplied via the `--base-path` CLI
 	// flag, just yet because we don't have Endpoint support for it yet. So we mark
-	// this flag as hidden.
+	// this flag as hidden, except on Windows where the Program Files directory can
+	// be moved to another drive.
 	// See also: https://github.com/elastic/elastic-agent/pull/2592
-	_ = cmd.Flags().MarkHidden(flagInstallBasePath)
+	if runtime.GOOS != "windows" {
+		_ = cmd.Flags().MarkHidden(flagInstallBasePath)
+	}
 
 	return cmd
 }
 
 func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
-	err := validateEnrollFlags(cmd)
+	err := applyConfigFile(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateEnrollFlags(cmd)
 	if err != nil {
 		return err
 	}
 
 	basePath, _ := cmd.Flags().GetString(flagInstallBasePath)
-	if !filepath.IsAbs(basePath) {
-		return fmt.Errorf("base path [%s] is not absolute", basePath)
+	if err := validateBasePath(basePath); err != nil {
+		return err
+	}
+
+	recovery := recoveryOptions(cmd)
+	if err := recovery.Validate(); err != nil {
+		return err
+	}
+
+	launchd := launchdOptions(cmd)
+	if err := launchd.Validate(); err != nil {
+		return err
+	}
+
+	account := serviceAccount(cmd)
+	if err := account.Validate(); err != nil {
+		return err
+	}
+
+	restoreState, err := restoreStatePath(cmd)
+	if err != nil {
+		return err
 	}
 
 	isAdmin, err := utils.HasRoot()
@@ -75,10 +137,25 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 
 	topPath := installPath(basePath)
 
+	desired := desiredInstallState(cmd, true)
 	status, reason := install.Status(topPath)
 	force, _ := cmd.Flags().GetBool("force")
-	if status == install.Installed && !force {
-		return fmt.Errorf("already installed at: %s", topPath)
+	if (status == install.Installed || status == install.PackageInstall) && !force {
+		installed, err := loadInstallState(topPath)
+		if err != nil {
+			return err
+		}
+		switch {
+		case installed == nil && status == install.Installed:
+			return fmt.Errorf("already installed at: %s", topPath)
+		case installed == nil:
+			// installed by the package, without a previous enrollment
+		case len(installed.diff(desired)) > 0:
+			return fmt.Errorf("already installed at %s with a different %s, use --force to re-install", topPath, strings.Join(installed.diff(desired), ", "))
+		default:
+			fmt.Fprintf(streams.Out, "Elastic Agent is already installed at %s in the desired state.\n", topPath)
+			return nil
+		}
 	}
 
 	nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
@@ -87,6 +164,9 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	}
 
 	if status == install.PackageInstall {
+		if restoreState != "" {
+			return fmt.Errorf("--%s can't be used when installed as a system package", flagInstallRestoreState)
+		}
 		fmt.Fprintf(streams.Out, "Installed as a system package, installation will not be altered.\n")
 	}
 
@@ -132,7 +212,7 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		askEnroll = false
 	}
 	fleetServer, _ := cmd.Flags().GetString("fleet-server-es")
-	if fleetServer != "" || force || delayEnroll || nonInteractive {
+	if fleetServer != "" || force || delayEnroll || nonInteractive || restoreState != "" {
 		askEnroll = false
 	}
 	if askEnroll {
@@ -149,6 +229,10 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		// force was performed without required enrollment arguments, all done (standalone mode)
 		enroll = false
 	}
+	if restoreState != "" {
+		// the restored agent keeps its enrollment
+		enroll = false
+	}
 
 	if enroll && fleetServer == "" {
 		if url == "" {
@@ -181,7 +265,7 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 
 	cfgFile := paths.ConfigFile()
 	if status != install.PackageInstall {
-		err = install.Install(cfgFile, topPath)
+		err = install.Install(cfgFile, topPath, recovery, launchd, account)
 		if err != nil {
 			return err
 		}
@@ -192,6 +276,22 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 			}
 		}()
 
+		if restoreState != "" {
+			// the installed agent restores the state in its own paths and with its own secret
+			restoreCmd := exec.Command(install.ExecutablePath(topPath), restoreStateCommandName, restoreState) //nolint:gosec // it's not tainted
+			restoreCmd.Stdout = os.Stdout
+			restoreCmd.Stderr = os.Stderr
+			err = restoreCmd.Run()
+			if err != nil {
+				return fmt.Errorf("failed to restore the state of the agent: %w", err)
+			}
+			// the restored files are accessible to the account the service runs as
+			err = install.FixPermissions(topPath)
+			if err != nil {
+				return fmt.Errorf("failed to fix the permissions of the restored state: %w", err)
+			}
+		}
+
 		if !delayEnroll {
 			err = install.StartService(topPath)
 			if err != nil {
@@ -234,11 +334,79 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	if err := info.CreateInstallMarker(topPath); err != nil {
 		return fmt.Errorf("failed to create install marker: %w", err)
 	}
+	if err := desired.save(topPath); err != nil {
+		return fmt.Errorf("failed to record the installation state: %w", err)
+	}
 
 	fmt.Fprint(streams.Out, "Elastic Agent has been successfully installed.\n")
 	return nil
 }
 
+// restoreStatePath returns the absolute path of the state to restore, the state replaces the
+// enrollment.
+func restoreStatePath(cmd *cobra.Command) (string, error) {
+	restoreState, _ := cmd.Flags().GetString(flagInstallRestoreState)
+	if restoreState == "" {
+		return "", nil
+	}
+	for _, name := range []string{"url", "enrollment-token", "fleet-server-es", "delay-enroll"} {
+		if cmd.Flags().Changed(name) {
+			return "", fmt.Errorf("--%s can't be used with --%s, the restored agent keeps its enrollment", name, flagInstallRestoreState)
+		}
+	}
+	restoreState, err := filepath.Abs(restoreState)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(restoreState); err != nil {
+		return "", fmt.Errorf("invalid --%s: %w", flagInstallRestoreState, err)
+	}
+	return restoreState, nil
+}
+
+// recoveryOptions returns the service recovery options set with the install flags.
+func recoveryOptions(cmd *cobra.Command) install.RecoveryOptions {
+	var recovery install.RecoveryOptions
+	recovery.RestartDelay, _ = cmd.Flags().GetDuration(flagInstallRestartDelay)
+	recovery.RestartMaxDelay, _ = cmd.Flags().GetDuration(flagInstallRestartMaxDelay)
+	recovery.ResetPeriod, _ = cmd.Flags().GetDuration(flagInstallResetPeriod)
+	return recovery
+}
+
+// launchdOptions returns the launchd options set with the install flags, the user is only kept for
+// the user domain and defaults to the user running sudo.
+func launchdOptions(cmd *cobra.Command) install.LaunchdOptions {
+	domain, _ := cmd.Flags().GetString(flagInstallLaunchdDomain)
+	launchd := install.LaunchdOptions{Domain: install.LaunchdDomain(domain)}
+	if launchd.Domain == install.LaunchdDomainUser {
+		launchd.User, _ = cmd.Flags().GetString(flagInstallLaunchdUser)
+		if launchd.User == "" {
+			launchd.User = os.Getenv("SUDO_USER")
+		}
+	}
+	return launchd
+}
+
+// serviceAccount returns the account the service runs as set with the install flags.
+func serviceAccount(cmd *cobra.Command) install.ServiceAccount {
+	var account install.ServiceAccount
+	account.Name, _ = cmd.Flags().GetString(flagInstallServiceAccount)
+	account.Password, _ = cmd.Flags().GetString(flagInstallServiceAccountPassword)
+	return account
+}
+
+// validateBasePath validates the path the Elastic Agent is installed in. On Windows it must be on a
+// drive of the host, the service can't access the network shares before the user logs in.
+func validateBasePath(basePath string) error {
+	if !filepath.IsAbs(basePath) {
+		return fmt.Errorf("base path [%s] is not absolute", basePath)
+	}
+	if strings.HasPrefix(filepath.VolumeName(basePath), `\\`) {
+		return fmt.Errorf("base path [%s] is a network share, it must be on a local drive", basePath)
+	}
+	return nil
+}
+
 func installPath(basePath string) string {
 	return filepath.Join(basePath, "Elastic", "Agent")
 }
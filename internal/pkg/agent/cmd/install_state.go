@@ -0,0 +1,180 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+)
+
+const (
+	flagConfigFile = "config-file"
+
+	// installStateFileName is the file in the installation directory recording the state
+	// requested by the last install or enroll.
+	installStateFileName = ".install-state.yml"
+
+	// installStateVersion is the key of the version of the installed Elastic Agent in the state.
+	installStateVersion = "version"
+)
+
+// nonStateFlags are the flags that don't describe the state of the installation: the one-shot
+// options of the commands and the tokens, which are rotated without the agent being re-enrolled.
+var nonStateFlags = map[string]bool{
+	flagConfigFile:                    true,
+	"force":                           true,
+	"non-interactive":                 true,
+	"from-install":                    true,
+	flagInstallRestoreState:           true,
+	"delay-enroll":                    true,
+	"daemon-timeout":                  true,
+	"fleet-server-timeout":            true,
+	"enrollment-token":                true,
+	"replace-token":                   true,
+	"fleet-server-service-token":      true,
+	"fleet-server-service-token-path": true,
+	flagInstallServiceAccountPassword: true,
+}
+
+// addConfigFileFlag adds the flag reading the flags of the command from a file.
+func addConfigFileFlag(cmd *cobra.Command) {
+	cmd.Flags().String(flagConfigFile, "", "YAML file setting the flags of the command by their name, the flags set on the command line take precedence")
+}
+
+// applyConfigFile sets the flags of the command from the file given with --config-file. The
+// keys of the file are the names of the flags, the flags set on the command line are kept.
+func applyConfigFile(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString(flagConfigFile)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := cmd.LocalFlags().Lookup(name)
+		if f == nil || name == flagConfigFile {
+			return fmt.Errorf("%s: unknown flag %q", path, name)
+		}
+		if f.Changed {
+			continue
+		}
+		if err := setFlag(f, values[name]); err != nil {
+			return fmt.Errorf("%s: invalid value for %q: %w", path, name, err)
+		}
+	}
+	return nil
+}
+
+func setFlag(f *pflag.Flag, value interface{}) error {
+	list, isList := value.([]interface{})
+	slice, isSlice := f.Value.(pflag.SliceValue)
+	switch {
+	case isList && isSlice:
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+		if err := slice.Replace(items); err != nil {
+			return err
+		}
+	case isList:
+		return errors.New("the flag takes a single value")
+	case isSlice:
+		if err := slice.Replace([]string{fmt.Sprint(value)}); err != nil {
+			return err
+		}
+	default:
+		if err := f.Value.Set(fmt.Sprint(value)); err != nil {
+			return err
+		}
+	}
+	f.Changed = true
+	return nil
+}
+
+// installState is the state requested by an install or an enroll, the hash of the value of each
+// flag describing the installation, so the recorded state holds no secret.
+type installState map[string]string
+
+// desiredInstallState returns the state requested by the flags of the command.
+func desiredInstallState(cmd *cobra.Command, withVersion bool) installState {
+	s := make(installState)
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if !nonStateFlags[f.Name] {
+			s[f.Name] = hashStateValue(f.Value.String())
+		}
+	})
+	if withVersion {
+		s[installStateVersion] = hashStateValue(release.Version() + "-" + release.Commit())
+	}
+	return s
+}
+
+// diff returns the sorted names of the flags of the desired state with a different value in this
+// state, the flags missing from the desired state are ignored.
+func (s installState) diff(desired installState) []string {
+	var names []string
+	for name, value := range desired {
+		if s[name] != value {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadInstallState returns the state recorded in the installation directory, nil when none is.
+func loadInstallState(topPath string) (installState, error) {
+	data, err := os.ReadFile(filepath.Join(topPath, installStateFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the installation state: %w", err)
+	}
+	s := make(installState)
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse the installation state: %w", err)
+	}
+	return s, nil
+}
+
+// save records the state in the installation directory.
+func (s installState) save(topPath string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(topPath, installStateFileName), data, 0600)
+}
+
+func hashStateValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
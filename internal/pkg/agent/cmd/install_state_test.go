@@ -0,0 +1,158 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func newTestInstallCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	cmd := newInstallCommandWithArgs(nil, streams)
+	require.NoError(t, cmd.ParseFlags(args))
+	return cmd
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "install.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path := writeConfigFile(t, `
+base-path: /opt/custom
+url: https://fleet.example.com:8220
+enrollment-token: token-from-file
+proxy-url: http://proxy.example.com:3128
+tag: [web, production]
+insecure: true
+`)
+	cmd := newTestInstallCommand(t, "--config-file", path, "--enrollment-token", "token-from-flag")
+	require.NoError(t, applyConfigFile(cmd))
+
+	basePath, _ := cmd.Flags().GetString(flagInstallBasePath)
+	url, _ := cmd.Flags().GetString("url")
+	token, _ := cmd.Flags().GetString("enrollment-token")
+	proxyURL, _ := cmd.Flags().GetString("proxy-url")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	insecure, _ := cmd.Flags().GetBool("insecure")
+	assert.Equal(t, "/opt/custom", basePath)
+	assert.Equal(t, "https://fleet.example.com:8220", url)
+	assert.Equal(t, "token-from-flag", token, "the command line takes precedence")
+	assert.Equal(t, "http://proxy.example.com:3128", proxyURL)
+	assert.Equal(t, []string{"web", "production"}, tags)
+	assert.True(t, insecure)
+	assert.Contains(t, buildEnrollmentFlags(cmd, "", ""), "https://fleet.example.com:8220")
+}
+
+func TestApplyConfigFileErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown flag": "unprivileged: true\n",
+		"config file":  "config-file: other.yml\n",
+		"list":         "url: [a, b]\n",
+		"invalid":      "insecure: maybe\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmd := newTestInstallCommand(t, "--config-file", writeConfigFile(t, content))
+			assert.Error(t, applyConfigFile(cmd))
+		})
+	}
+}
+
+func TestInstallState(t *testing.T) {
+	dir := t.TempDir()
+	installed, err := loadInstallState(dir)
+	require.NoError(t, err)
+	assert.Nil(t, installed)
+
+	desired := desiredInstallState(newTestInstallCommand(t, "--url", "https://fleet.example.com:8220", "--enrollment-token", "a", "--tag", "web", "--force"), true)
+	require.NoError(t, desired.save(dir))
+	installed, err = loadInstallState(dir)
+	require.NoError(t, err)
+	assert.Empty(t, installed.diff(desired))
+
+	// the tokens and the one-shot options don't change the state
+	same := desiredInstallState(newTestInstallCommand(t, "--url", "https://fleet.example.com:8220", "--enrollment-token", "b", "--tag", "web", "--non-interactive"), true)
+	assert.Empty(t, installed.diff(same))
+
+	other := desiredInstallState(newTestInstallCommand(t, "--url", "https://other.example.com:8220", "--tag", "web", "--tag", "db"), true)
+	assert.Equal(t, []string{"tag", "url"}, installed.diff(other))
+
+	// the state of an enroll is compared without the install flags and the version
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	enrollCmd := newEnrollCommandWithArgs(nil, streams)
+	require.NoError(t, enrollCmd.ParseFlags([]string{"--url", "https://fleet.example.com:8220", "--tag", "web"}))
+	assert.Empty(t, installed.diff(desiredInstallState(enrollCmd, false)))
+
+	data, err := os.ReadFile(filepath.Join(dir, installStateFileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "fleet.example.com", "the state only records hashes")
+}
+
+func TestRestoreStatePath(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "elastic-agent-state.tar.gz")
+	require.NoError(t, os.WriteFile(snapshot, nil, 0600))
+
+	path, err := restoreStatePath(newTestInstallCommand(t))
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	path, err = restoreStatePath(newTestInstallCommand(t, "--restore-state", snapshot))
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, path)
+
+	_, err = restoreStatePath(newTestInstallCommand(t, "--restore-state", snapshot, "--url", "https://fleet.example.com:8220"))
+	assert.EqualError(t, err, "--url can't be used with --restore-state, the restored agent keeps its enrollment")
+
+	_, err = restoreStatePath(newTestInstallCommand(t, "--restore-state", filepath.Join(t.TempDir(), "missing.tar.gz")))
+	assert.Error(t, err)
+}
+
+func TestLaunchdOptions(t *testing.T) {
+	t.Setenv("SUDO_USER", "elastic")
+
+	assert.Equal(t, install.DefaultLaunchdOptions(), launchdOptions(newTestInstallCommand(t)))
+	assert.Equal(t, install.DefaultLaunchdOptions(), launchdOptions(newTestInstallCommand(t, "--launchd-user", "other")), "the user is only kept for the user domain")
+
+	launchd := launchdOptions(newTestInstallCommand(t, "--launchd-domain", "user"))
+	assert.Equal(t, install.LaunchdOptions{Domain: install.LaunchdDomainUser, User: "elastic"}, launchd, "the user running sudo by default")
+
+	launchd = launchdOptions(newTestInstallCommand(t, "--launchd-domain", "user", "--launchd-user", "other"))
+	assert.Equal(t, "other", launchd.User)
+}
+
+func TestServiceAccountFlags(t *testing.T) {
+	account := serviceAccount(newTestInstallCommand(t))
+	assert.Equal(t, install.ServiceAccount{}, account)
+
+	account = serviceAccount(newTestInstallCommand(t, "--service-account", `EXAMPLE\elastic-agent`, "--service-account-password", "changeme"))
+	assert.Equal(t, install.ServiceAccount{Name: `EXAMPLE\elastic-agent`, Password: "changeme"}, account)
+
+	desired := desiredInstallState(newTestInstallCommand(t, "--service-account-password", "changeme"), false)
+	assert.NotContains(t, desired, "service-account-password", "the password isn't part of the installation state")
+}
+
+func TestValidateBasePath(t *testing.T) {
+	assert.NoError(t, validateBasePath(paths.DefaultBasePath))
+	assert.Error(t, validateBasePath(filepath.Join("relative", "path")))
+	if runtime.GOOS == "windows" {
+		assert.NoError(t, validateBasePath(`D:\Program Files`))
+		assert.Error(t, validateBasePath(`\\fileserver\share\Program Files`), "the base path can't be a network share")
+	}
+}
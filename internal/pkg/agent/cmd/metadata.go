@@ -0,0 +1,143 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+const metadataReportedMessage = "The running Elastic Agent reports the change to Fleet at its next check-in."
+
+func newMetadataCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metadata <subcommand>",
+		Short: "Manage the labels reported to Fleet",
+		Long: `Manage the labels (key=value pairs like rack, site or owner) reported to Fleet in the local metadata of the Elastic Agent.
+The labels persist across restarts and do not require the Elastic Agent to enroll again.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <key=value>...",
+		Short: "Set labels",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			runMetadataCmd(streams, func() error {
+				labels, err := parseLabels(args)
+				if err != nil {
+					return err
+				}
+				return info.SetLabels(labels)
+			})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "unset <key>...",
+		Short: "Remove labels",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			runMetadataCmd(streams, func() error {
+				return info.UnsetLabels(args...)
+			})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the labels and tags",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			if err := listMetadataCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	})
+
+	return cmd
+}
+
+func newTagsCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tags <subcommand>",
+		Short: "Manage the tags reported to Fleet",
+		Long: `Manage the tags reported to Fleet in the local metadata of the Elastic Agent.
+The tags persist across restarts and do not require the Elastic Agent to enroll again.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add <tag>...",
+		Short: "Add tags",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			runMetadataCmd(streams, func() error {
+				return info.AddTags(args...)
+			})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove <tag>...",
+		Short: "Remove tags",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			runMetadataCmd(streams, func() error {
+				return info.RemoveTags(args...)
+			})
+		},
+	})
+
+	return cmd
+}
+
+func runMetadataCmd(streams *cli.IOStreams, update func() error) {
+	if err := update(); err != nil {
+		fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+		os.Exit(1)
+	}
+	fmt.Fprintln(streams.Out, metadataReportedMessage)
+}
+
+func listMetadataCmd(streams *cli.IOStreams) error {
+	meta, err := info.LoadUserMetadata()
+	if err != nil {
+		return fmt.Errorf("failed to load the metadata: %w", err)
+	}
+
+	keys := make([]string, 0, len(meta.Labels))
+	for k := range meta.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(streams.Out, "Labels:")
+	for _, k := range keys {
+		fmt.Fprintf(streams.Out, "  %s=%s\n", k, meta.Labels[k])
+	}
+	fmt.Fprintln(streams.Out, "Tags:")
+	for _, t := range meta.Tags {
+		fmt.Fprintf(streams.Out, "  %s\n", t)
+	}
+	return nil
+}
+
+// parseLabels parses the key=value arguments of metadata set.
+func parseLabels(args []string) (map[string]string, error) {
+	labels := make(map[string]string, len(args))
+	for _, arg := range args {
+		k, v, found := strings.Cut(arg, "=")
+		k = strings.TrimSpace(k)
+		if !found || k == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", arg)
+		}
+		labels[k] = v
+	}
+	return labels, nil
+}
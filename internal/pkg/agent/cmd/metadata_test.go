@@ -0,0 +1,32 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabels(t *testing.T) {
+	t.Run("key=value pairs", func(t *testing.T) {
+		labels, err := parseLabels([]string{"rack=r12", "site=ams-1", "owner=", "query=a=b"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"rack":  "r12",
+			"site":  "ams-1",
+			"owner": "",
+			"query": "a=b",
+		}, labels)
+	})
+
+	for _, arg := range []string{"rack", "=r12", " =r12"} {
+		t.Run("invalid "+arg, func(t *testing.T) {
+			_, err := parseLabels([]string{arg})
+			assert.Error(t, err)
+		})
+	}
+}
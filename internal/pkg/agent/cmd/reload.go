@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newReloadCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Reload the configuration of the running Elastic Agent in standalone mode",
+		Long: `This command reloads the configuration files of the running Elastic Agent in standalone mode, like sending it SIGHUP.
+The variables are rendered again and only the components whose configuration changed are updated, the others keep running.`,
+		Run: func(c *cobra.Command, args []string) {
+			if err := reloadCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func reloadCmd(streams *cli.IOStreams) error {
+	ctx := handleSignal(context.Background())
+
+	daemon := client.New()
+	err := daemon.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	if err := daemon.Reload(ctx); err != nil {
+		return fmt.Errorf("failed to reload the configuration: %w", err)
+	}
+	fmt.Fprintln(streams.Out, "Configuration reloaded")
+	return nil
+}
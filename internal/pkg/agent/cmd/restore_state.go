@@ -0,0 +1,47 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// restoreStateCommandName is the name of the hidden command run by install --restore-state with the
+// installed agent.
+const restoreStateCommandName = "restore-state"
+
+func newRestoreStateCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:    restoreStateCommandName + " <path>",
+		Short:  "Restore the state preserved by uninstall --preserve-state",
+		Long:   "This command restores the state preserved by uninstall --preserve-state in the installed Elastic Agent, it is run by install --restore-state before the agent is started.",
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		Run: func(c *cobra.Command, args []string) {
+			if err := restoreStateCmd(args[0]); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func restoreStateCmd(path string) error {
+	isAdmin, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("unable to perform command while checking for administrator rights, %w", err)
+	}
+	if !isAdmin {
+		return fmt.Errorf("unable to perform command, not executed with %s permissions", utils.PermissionUser)
+	}
+	return install.RestoreState(path)
+}
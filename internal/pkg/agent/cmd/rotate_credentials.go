@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newRotateCredentialsCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate-credentials",
+		Short: "Restart the running components with new connection credentials",
+		Long: `This command restarts the components run by the Elastic Agent one after the other, so they connect back to the Elastic Agent with a new token and certificate.
+Service components are not restarted and keep their credentials.`,
+		Run: func(c *cobra.Command, args []string) {
+			if err := rotateCredentialsCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func rotateCredentialsCmd(streams *cli.IOStreams) error {
+	ctx := handleSignal(context.Background())
+
+	daemon := client.New()
+	err := daemon.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	if err := daemon.RotateCredentials(ctx); err != nil {
+		return fmt.Errorf("failed to rotate the credentials: %w", err)
+	}
+	fmt.Fprintln(streams.Out, "Components restarted with new credentials")
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
@@ -27,14 +28,28 @@ import (
 
 	"github.com/elastic/elastic-agent-system-metrics/report"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/certexpiry"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/configdrift"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/dataplane"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/diskquota"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/ha"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/handoff"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/integrity"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/limits"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/snmp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/statuspage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/tcc"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/telemetry"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/watchdog"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/migration"
@@ -43,8 +58,11 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/logrotation"
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/control/v2/server"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/version"
@@ -53,6 +71,8 @@ import (
 const (
 	agentName            = "elastic-agent"
 	fleetInitTimeoutName = "FLEET_SERVER_INIT_TIMEOUT"
+
+	ephemeralUnenrollTimeout = 30 * time.Second
 )
 
 type cfgOverrider func(cfg *configuration.Configuration)
@@ -148,6 +168,16 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 		"source": agentName,
 	})
 
+	if err := limits.Apply(l, cfg.Settings.Limits); err != nil {
+		// the agent keeps running without the limits rather than leaving the host unmonitored
+		l.Errorw("Failed to apply the limits of Elastic Agent", "error.message", err)
+	}
+
+	if cfg.Settings.Network != nil {
+		// the local listeners use the IP family of the host, they are created by application.New
+		netutil.SetConfig(cfg.Settings.Network)
+	}
+
 	cfg, err = tryDelayEnroll(ctx, l, cfg, override)
 	if err != nil {
 		err = errors.New(err, "failed to perform delayed enrollment")
@@ -172,19 +202,34 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 	}
 
 	// Migrate .yml files if the corresponding .enc does not exist
+	migrate := migration.MigrateToEncryptedConfig
+	standalone := configuration.IsStandalone(cfg.Fleet)
+	if standalone {
+		// standalone configurations often hold output credentials in plain text, no unencrypted
+		// copy of the stores is kept on disk once migrated
+		migrate = migration.MigrateToEncryptedStore
+	}
 
 	// the encrypted config does not exist but the unencrypted file does
-	err = migration.MigrateToEncryptedConfig(l, paths.AgentConfigYmlFile(), paths.AgentConfigFile())
+	err = migrate(l, paths.AgentConfigYmlFile(), paths.AgentConfigFile())
 	if err != nil {
 		return errors.New(err, "error migrating fleet config")
 	}
 
 	// the encrypted state does not exist but the unencrypted file does
-	err = migration.MigrateToEncryptedConfig(l, paths.AgentStateStoreYmlFile(), paths.AgentStateStoreFile())
+	err = migrate(l, paths.AgentStateStoreYmlFile(), paths.AgentStateStoreFile())
 	if err != nil {
 		return errors.New(err, "error migrating agent state")
 	}
 
+	if standalone {
+		// the action store predates the encrypted state store
+		err = migration.MigrateActionStore(l, paths.AgentActionStoreFile(), paths.AgentStateStoreFile())
+		if err != nil {
+			return errors.New(err, "error migrating action store")
+		}
+	}
+
 	agentInfo, err := info.NewAgentInfoWithLog(defaultLogLevel(cfg, logLvl.String()), createAgentID)
 	if err != nil {
 		return errors.New(err,
@@ -242,7 +287,7 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 	}
 	defer composable.Close()
 
-	serverStopFn, err := setupMetrics(l, cfg.Settings.DownloadConfig.OS(), cfg.Settings.MonitoringConfig, tracer, coord)
+	serverStopFn, err := setupMetrics(ctx, l, cfg.Settings.DownloadConfig.OS(), cfg.Settings.MonitoringConfig, tracer, coord)
 	if err != nil {
 		return err
 	}
@@ -250,6 +295,14 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 		_ = serverStopFn()
 	}()
 
+	snmpStopFn, err := setupSNMP(l, cfg.Settings.MonitoringConfig, coord)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = snmpStopFn()
+	}()
+
 	diagHooks := diagnostics.GlobalHooks()
 	diagHooks = append(diagHooks, coord.DiagnosticHooks()...)
 	control := server.New(l.Named("control"), agentInfo, coord, tracer, diagHooks, cfg.Settings.GRPC)
@@ -268,6 +321,13 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 	}
 	defer control.Stop()
 
+	// load the upgrade marker before the Coordinator starts acking the upgrade,
+	// the upgrade details it holds are reported even if the marker is removed
+	upgradeMarker, err := upgrade.LoadMarker()
+	if err != nil {
+		l.Warnw("Failed to load upgrade marker", "error.message", err)
+	}
+
 	appDone := make(chan bool)
 	appErr := make(chan error)
 	// Spawn the main Coordinator goroutine
@@ -277,6 +337,30 @@ func run(override cfgOverrider, testingMode bool, fleetInitTimeout time.Duration
 		appErr <- err
 	}()
 
+	go checkBinaryIntegrity(ctx, l, coord)
+	go runWatchdog(ctx, l, cfg.Settings.Watchdog, coord)
+	go runLoadShedding(ctx, l, cfg.Settings.Limits, coord)
+	go runHAPair(ctx, l, cfg.Settings.HA, coord)
+	go runKubernetesHandoff(ctx, l, cfg.Settings.Handoff, agentInfo.AgentID(), coord)
+	go runAdaptiveMonitoring(ctx, l, cfg.Settings.MonitoringConfig, coord)
+	go runTelemetry(ctx, l, cfg.Settings.Telemetry, coord)
+	go runCertExpiry(ctx, l, cfg, coord)
+	go runDiskQuota(ctx, l, cfg.Settings.DiskQuota, coord)
+	go runConfigDrift(ctx, l, cfg.Settings.ConfigDrift, coord)
+	go runDataPlane(ctx, l, cfg.Settings.DataPlane, coord)
+	go tcc.NewChecker(l.Named("full_disk_access"), tcc.DefaultInterval, coord.SetFullDiskAccess).Run(ctx)
+	go logrotation.Run(ctx, l.Named("log_rotation"), paths.Logs())
+	go watchPackageManagedUpgrade(ctx, l, coord)
+	go watchUpgradeDetails(ctx, l, coord, upgradeMarker)
+
+	ephemeralInactive := make(chan struct{})
+	go application.WatchEphemeralInactivity(ctx, l, cfg, coord, func() {
+		close(ephemeralInactive)
+	})
+
+	// in standalone mode SIGHUP reloads the configuration files, otherwise it re-executes the Elastic Agent
+	reloadOnSIGHUP := !testingMode && configuration.IsStandalone(cfg.Fleet)
+
 	// listen for signals
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
@@ -292,6 +376,9 @@ LOOP:
 			l.Info("application done, coordinator exited")
 			logShutdown = false
 			break LOOP
+		case <-ephemeralInactive:
+			l.Info("ephemeral agent is inactive. Shutting down")
+			break LOOP
 		case <-rex.ShutdownChan():
 			l.Info("reexec shutdown channel triggered")
 			isRex = true
@@ -299,7 +386,14 @@ LOOP:
 			break LOOP
 		case sig := <-signals:
 			l.Infof("signal %q received", sig)
-			if sig == syscall.SIGHUP {
+			if sig == syscall.SIGHUP && reloadOnSIGHUP {
+				l.Info("SIGHUP triggered configuration reload")
+				go func() {
+					if err := coord.Reload(ctx); err != nil {
+						l.Errorw("Failed to reload the configuration", "error.message", err)
+					}
+				}()
+			} else if sig == syscall.SIGHUP {
 				rexLogger.Infof("SIGHUP triggered re-exec")
 				isRex = true
 				rex.ReExec(nil)
@@ -316,6 +410,12 @@ LOOP:
 	err = <-appErr
 
 	if logShutdown {
+		// only a clean shutdown unenrolls an ephemeral agent, a re-exec or an upgrade starts it again
+		unenrollCtx, unenrollCancel := context.WithTimeout(context.Background(), ephemeralUnenrollTimeout)
+		if err := application.UnenrollEphemeral(unenrollCtx, l, cfg, agentInfo); err != nil {
+			l.Errorw("Failed to unenroll the ephemeral agent", "error.message", err)
+		}
+		unenrollCancel()
 		l.Info("Shutting down completed.")
 	}
 	if isRex {
@@ -526,15 +626,23 @@ func initTracer(agentName, version string, mcfg *monitoringCfg.MonitoringConfig)
 		ts.SetSecretToken(cfg.SecretToken)
 	}
 
-	return apm.NewTracerOptions(apm.TracerOptions{
+	tracer, err := apm.NewTracerOptions(apm.TracerOptions{
 		ServiceName:        agentName,
 		ServiceVersion:     version,
 		ServiceEnvironment: cfg.Environment,
 		Transport:          ts,
 	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SamplingRate != nil {
+		tracer.SetSampler(apm.NewRatioSampler(float64(*cfg.SamplingRate)))
+	}
+	return tracer, nil
 }
 
 func setupMetrics(
+	ctx context.Context,
 	logger *logger.Logger,
 	operatingSystem string,
 	cfg *monitoringCfg.MonitoringConfig,
@@ -551,7 +659,7 @@ func setupMetrics(
 		Host:    monitoring.AgentMonitoringEndpoint(operatingSystem, cfg),
 	}
 
-	s, err := monitoring.NewServer(logger, endpointConfig, monitoringLib.GetNamespace, tracer, coord, isProcessStatsEnabled(cfg), operatingSystem)
+	s, err := monitoring.NewServer(logger, endpointConfig, monitoringLib.GetNamespace, tracer, coord, isProcessStatsEnabled(cfg), operatingSystem, setupStatusPage(ctx, cfg, coord))
 	if err != nil {
 		return nil, errors.New(err, "could not start the HTTP server for the API")
 	}
@@ -561,10 +669,262 @@ func setupMetrics(
 	return s.Stop, nil
 }
 
+// setupStatusPage returns the handler of the status page when it is enabled, it is served by the
+// HTTP endpoint so it needs to be enabled as well.
+func setupStatusPage(ctx context.Context, cfg *monitoringCfg.MonitoringConfig, coord *coordinator.Coordinator) http.Handler {
+	if !isProcessStatsEnabled(cfg) || cfg.HTTP.StatusPage == nil || !cfg.HTTP.StatusPage.Enabled {
+		return nil
+	}
+	recorder := statuspage.NewRecorder()
+	go recorder.Run(ctx, coord.StateSubscribe(ctx, 32))
+	return statuspage.NewHandler(cfg.HTTP.StatusPage, release.Version(), coord.State, recorder)
+}
+
+// setupSNMP starts the SNMP agent exposing the health of the agent when it is enabled.
+func setupSNMP(logger *logger.Logger, cfg *monitoringCfg.MonitoringConfig, coord *coordinator.Coordinator) (func() error, error) {
+	if cfg == nil || cfg.SNMP == nil || !cfg.SNMP.Enabled {
+		return func() error { return nil }, nil
+	}
+
+	a, err := snmp.New(logger.Named("snmp"), cfg.SNMP, release.Version(), coord.State)
+	if err != nil {
+		return nil, errors.New(err, "could not start the SNMP agent")
+	}
+	a.Start()
+	logger.Infof("SNMP agent listening on %s", a.Addr())
+
+	return a.Stop, nil
+}
+
 func isProcessStatsEnabled(cfg *monitoringCfg.MonitoringConfig) bool {
 	return cfg != nil && cfg.HTTP.Enabled
 }
 
+// runWatchdog checks the run loops of the coordinator and of the runtime manager make progress
+// until the context is done. A deadlocked coordinator cannot be shut down gracefully, so when
+// the restart is enabled the process exits and relies on the service manager to start it again.
+func runWatchdog(ctx context.Context, log *logger.Logger, cfg *configuration.WatchdogConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	log = log.Named("watchdog")
+	restart := func() {
+		_ = log.Sync()
+		os.Exit(1)
+	}
+	watchdog.New(log, cfg, paths.Logs(), restart,
+		watchdog.Probe{Name: "coordinator", Heartbeat: coord.Heartbeat},
+		watchdog.Probe{Name: "runtime manager", Heartbeat: coord.RuntimeHeartbeat},
+	).Run(ctx)
+}
+
+// runLoadShedding pauses the monitoring and delays the check-ins while the memory used by the
+// agent approaches its memory limit, until the context is done.
+func runLoadShedding(ctx context.Context, log *logger.Logger, cfg *configuration.LimitsConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.LoadShedding.Enabled {
+		return
+	}
+	limits.NewMonitor(log.Named("limits"), &cfg.LoadShedding, coord.SetLoadShedding).Run(ctx)
+}
+
+// runHAPair coordinates the agent with its peer in an active/standby pair, so only one of them
+// runs the inputs, until the context is done.
+func runHAPair(ctx context.Context, log *logger.Logger, cfg *configuration.HAConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	log = log.Named("ha")
+	pair, err := ha.New(log, cfg, coord.SetHAStandby)
+	if err == nil {
+		err = pair.Run(ctx)
+	}
+	if err != nil {
+		// the agent stays standby, running the inputs could duplicate the ones of the peer
+		log.Errorw("Failed to run the active/standby pair, the agent runs no inputs", "error.message", err)
+	}
+}
+
+// runKubernetesHandoff hands off the inputs when the Kubernetes node of the agent is cordoned to
+// be drained, until the context is done.
+func runKubernetesHandoff(ctx context.Context, log *logger.Logger, cfg *configuration.KubernetesHandoffConfig, agentID string, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	log = log.Named("handoff")
+	w, err := handoff.New(log, cfg, agentID, coord.State, coord.SetHandedOff)
+	if err != nil {
+		log.Errorw("Failed to watch the node to hand off the inputs", "error.message", err)
+		return
+	}
+	w.Run(ctx)
+}
+
+// runAdaptiveMonitoring reduces the collection of the monitoring metrics while the resources of
+// the host are under pressure, until the context is done.
+func runAdaptiveMonitoring(ctx context.Context, log *logger.Logger, cfg *monitoringCfg.MonitoringConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled || !cfg.MonitorMetrics || cfg.Adaptive == nil || !cfg.Adaptive.Enabled {
+		return
+	}
+	monitoring.NewAdaptiveMonitor(log.Named("monitoring"), cfg.Adaptive, coord.SetMonitoringReduced).Run(ctx)
+}
+
+// runTelemetry reports the anonymized usage of the agent when the telemetry is enabled, until
+// the context is done.
+func runTelemetry(ctx context.Context, log *logger.Logger, cfg *configuration.TelemetryConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	log = log.Named("telemetry")
+	log.Infof("Usage telemetry is sent to %s every %s", cfg.Endpoint, cfg.Interval)
+	telemetry.New(log, cfg, telemetry.SpoolDir(), func() telemetry.AgentState {
+		return telemetry.StateFromCoordinator(coord.State())
+	}).Run(ctx)
+}
+
+// runCertExpiry periodically checks the expiration of the certificates used by the agent and
+// its components and reports the ones expiring soon to the coordinator, until the context is done.
+func runCertExpiry(ctx context.Context, log *logger.Logger, cfg *configuration.Configuration, coord *coordinator.Coordinator) {
+	if cfg.Settings.CertExpiry == nil || !cfg.Settings.CertExpiry.Enabled {
+		return
+	}
+	certexpiry.NewChecker(log.Named("cert_expiry"), cfg.Settings.CertExpiry, func() []certexpiry.Material {
+		return append(certexpiry.ConfigMaterials(cfg), certexpiry.ComponentMaterials(coord.State().Components)...)
+	}, coord.SetCertificateExpiry).Run(ctx)
+}
+
+// runDiskQuota periodically checks the disk usage of the paths managed by the agent, evicts
+// their oldest files once they exceed their quota and reports the ones still exceeding it to the
+// coordinator, until the context is done.
+func runDiskQuota(ctx context.Context, log *logger.Logger, cfg *configuration.DiskQuotaConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	diskquota.NewChecker(log.Named("disk_quota"), cfg, func() []diskquota.Group {
+		diagnostics := []string{paths.CoreDumps(), paths.ServiceOperations()}
+		spools := diskquota.SpoolPaths(paths.Run(), coord.State().Components)
+		return diskquota.ConfigGroups(cfg, paths.Logs(), paths.Downloads(), diagnostics, spools)
+	}, coord.SetDiskQuotaError).Run(ctx)
+}
+
+// runConfigDrift periodically compares the configurations applied by the units with the latest
+// ones sent to them and reports the units stuck on an old configuration to the coordinator, until
+// the context is done.
+func runConfigDrift(ctx context.Context, log *logger.Logger, cfg *configuration.ConfigDriftConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	configdrift.NewChecker(log.Named("config_drift"), cfg, func() []runtime.ComponentComponentState {
+		return coord.State().Components
+	}, coord.SetConfigDriftError).Run(ctx)
+}
+
+// runDataPlane periodically collects the events published, dropped, failed and queued by the
+// components exposing their stats and reports them to the coordinator, until the context is done.
+// The components only expose their stats while the monitoring is enabled.
+func runDataPlane(ctx context.Context, log *logger.Logger, cfg *configuration.DataPlaneConfig, coord *coordinator.Coordinator) {
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+	dataplane.NewCollector(log.Named("data_plane"), cfg, func() []runtime.ComponentComponentState {
+		return coord.State().Components
+	}, monitoring.ComponentStats, coord.SetDataPlaneEvents).Run(ctx)
+}
+
+// checkBinaryIntegrity verifies the running binary against the manifest written
+// at install or upgrade time and reports a mismatch to the coordinator, which
+// surfaces it as a degraded state to Fleet. When managed by Fleet an event is
+// also sent to Fleet, see reportIntegrityFailure.
+func checkBinaryIntegrity(ctx context.Context, log *logger.Logger, coord *coordinator.Coordinator) {
+	err := integrity.Check(paths.Home())
+	if err == nil {
+		log.Debug("Agent binary integrity check passed")
+		return
+	}
+	if errors.Is(err, integrity.ErrManifestNotFound) {
+		log.Debugw("Agent binary integrity check skipped, no manifest present", "file.path", integrity.ManifestPath(paths.Home()))
+		return
+	}
+
+	log.Errorw("Agent binary integrity check failed, the binary may have been tampered with or an upgrade was only partially applied", "error.message", err)
+	if err := coord.SetIntegrityError(ctx, err); err != nil && !errors.Is(err, context.Canceled) {
+		log.Errorw("Failed to report agent binary integrity check result", "error.message", err)
+	}
+}
+
+// watchPackageManagedUpgrade reports to the coordinator the version the package manager
+// is expected to install when upgrades are managed by a package manager. The state is
+// written by `elastic-agent upgrade --managed-by-pkg` or by Fleet upgrade actions, so it
+// is polled.
+func watchPackageManagedUpgrade(ctx context.Context, log *logger.Logger, coord *coordinator.Coordinator) {
+	const pollInterval = 30 * time.Second
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	reported := ""
+	for {
+		state, err := upgrade.LoadManagedByPackage()
+		if err != nil {
+			log.Warnw("Failed to load package managed upgrade state", "error.message", err)
+		} else if waiting := state.Waiting(); waiting != reported {
+			if waiting != "" {
+				log.Infow("Waiting for package manager to upgrade Elastic Agent", "version", waiting)
+			}
+			if err := coord.SetPackageManagedVersion(ctx, waiting); err != nil {
+				return
+			}
+			reported = waiting
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// watchUpgradeDetails reports to the coordinator the progress of an upgrade that continues
+// once the new version started, the upgrade details are handed over in the upgrade marker.
+// The watcher updates the marker on rollback and removes it once the upgrade is completed.
+func watchUpgradeDetails(ctx context.Context, log *logger.Logger, coord *coordinator.Coordinator, marker *upgrade.UpdateMarker) {
+	const pollInterval = 10 * time.Second
+
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	var reported *details.Details
+	for {
+		upgradeDetails := reported
+		if marker != nil && marker.Details != nil {
+			upgradeDetails = marker.Details
+		} else if marker == nil && reported != nil && reported.State == details.StateWatching {
+			// the watcher removes the marker once the grace period passed without errors
+			upgradeDetails = reported.Clone()
+			upgradeDetails.SetState(details.StateCompleted)
+		}
+		if !upgradeDetails.Equals(reported) {
+			if err := coord.SetUpgradeDetails(ctx, upgradeDetails); err != nil {
+				return
+			}
+			reported = upgradeDetails
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		m, err := upgrade.LoadMarker()
+		if err != nil {
+			log.Warnw("Failed to load upgrade marker", "error.message", err)
+			continue
+		}
+		marker = m
+	}
+}
+
 // handleUpgrade checks if agent is being run as part of an
 // ongoing upgrade operation, i.e. being re-exec'd and performs
 // any upgrade-specific work, if needed.
@@ -579,6 +939,12 @@ func handleUpgrade() error {
 		return nil
 	}
 
+	if upgradeMarker.PackageManager != "" {
+		// Upgraded by the system package manager, the package owns
+		// the installation and no installation marker is used.
+		return nil
+	}
+
 	// In v8.8.0, we introduced a new installation marker file to indicate that
 	// an Agent was running as installed. When an installed Agent that's older
 	// than v8.8.0 is upgraded, this installation marker file is not present.
@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+func newSeccompExecCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Hidden: true,
+		Use:    runtime.SeccompExecCommand + " --profile <profile> -- <command> [args]",
+		Short:  "Execute a component with a seccomp profile",
+		Long:   "This installs the seccomp profile of a sandboxed component then executes the component in its place.",
+		Args:   cobra.MinimumNArgs(1),
+		// the Elastic Agent is not started, the paths of the container are not loaded
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error { return nil },
+		Run: func(c *cobra.Command, args []string) {
+			profile, _ := c.Flags().GetString("profile")
+			err := runtime.ExecSeccomp(profile, args)
+			fmt.Fprintf(streams.Err, "Error: %v\n", err)
+			os.Exit(1)
+		},
+	}
+	cmd.Flags().String("profile", "", "seccomp profile of the component in JSON")
+
+	return cmd
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func newSpecCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spec <subcommand>",
+		Short: "Tools to work on component specifications",
+	}
+
+	cmd.AddCommand(newSpecValidateCommandWithArgs(args, streams))
+
+	return cmd
+}
+
+func newSpecValidateCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate <file>...",
+		Short: "Validates component specifications",
+		Long: `Validates component specification files the same way the Elastic Agent does when it loads them, and
+reports the mistakes it only hits when it runs the component as warnings.`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			strict, _ := c.Flags().GetBool("strict")
+			if err := specValidateCmd(streams, args, strict); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().Bool("strict", false, "Fail on warnings")
+
+	return cmd
+}
+
+func specValidateCmd(streams *cli.IOStreams, files []string, strict bool) error {
+	failed := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		issues := component.LintSpec(data)
+		for _, issue := range issues {
+			fmt.Fprintf(streams.Out, "%s: %s\n", file, issue)
+		}
+		if issues.HasErrors() || (strict && len(issues) > 0) {
+			failed = true
+			continue
+		}
+		fmt.Fprintf(streams.Out, "%s: component specification is valid\n", file)
+	}
+	if failed {
+		return errors.New("invalid component specification")
+	}
+	return nil
+}
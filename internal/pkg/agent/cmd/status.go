@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -28,10 +29,11 @@ import (
 type outputter func(io.Writer, interface{}) error
 
 var statusOutputs = map[string]outputter{
-	"human": humanOutput,
-	"full":  humanFullOutput,
-	"json":  jsonOutput,
-	"yaml":  yamlOutput,
+	"human":  humanOutput,
+	"full":   humanFullOutput,
+	"json":   versionedOutput(jsonOutput),
+	"yaml":   versionedOutput(yamlOutput),
+	"schema": schemaOutput,
 }
 
 func newStatusCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
@@ -47,7 +49,7 @@ func newStatusCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().String("output", "human", "Output the status information in either 'human', 'full', 'json', or 'yaml'.  'human' only shows non-healthy details, others show full details. (default: human)")
+	cmd.Flags().String("output", "human", "Output the status information in either 'human', 'full', 'json', or 'yaml'.  'human' only shows non-healthy details, others show full details. 'schema' prints the JSON schema of the 'json' and 'yaml' outputs. (default: human)")
 
 	return cmd
 }
@@ -58,6 +60,9 @@ func statusCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error
 	if !ok {
 		return fmt.Errorf("unsupported output: %s", output)
 	}
+	if output == "schema" {
+		return outputFunc(streams.Out, nil)
+	}
 
 	ctx := handleSignal(context.Background())
 	innerCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -93,6 +98,23 @@ func formatStatus(state client.State, message string) string {
 	return fmt.Sprintf("status: (%s) %s", state, message)
 }
 
+func formatConfigRevision(u client.ComponentUnitState) string {
+	return fmt.Sprintf("config: not yet applied (expected: index %d, revision %d; observed: index %d, revision %d)",
+		u.ExpectedConfigIdx, u.ExpectedPolicyRevision, u.ObservedConfigIdx, u.ObservedPolicyRevision)
+}
+
+func formatEvents(events map[string]interface{}) string {
+	count := func(key string) string {
+		if f, ok := events[key].(float64); ok {
+			// JSON numbers are decoded as float64, printed as integers
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return fmt.Sprint(events[key])
+	}
+	return fmt.Sprintf("events: published %s, dropped %s, failed %s, queued %s",
+		count("published"), count("dropped"), count("failed"), count("queued"))
+}
+
 func listComponentState(l list.Writer, components []client.ComponentState, all bool) {
 	for _, c := range components {
 		// see if any unit is not Healthy because component
@@ -123,6 +145,12 @@ func listComponentState(l list.Writer, components []client.ComponentState, all b
 			if all {
 				l.AppendItem(fmt.Sprintf("type: %s", u.UnitType))
 			}
+			if !u.ConfigApplied() {
+				l.AppendItem(formatConfigRevision(u))
+			}
+			if events, ok := u.Payload["events"].(map[string]interface{}); ok {
+				l.AppendItem(formatEvents(events))
+			}
 			l.UnIndent()
 			l.UnIndent()
 		}
@@ -141,6 +169,19 @@ func listAgentState(l list.Writer, state *client.AgentState, all bool) {
 		l.AppendItem("id: " + state.Info.ID)
 		l.AppendItem("version: " + state.Info.Version)
 		l.AppendItem("commit: " + state.Info.Commit)
+		if len(state.Info.Features) > 0 {
+			l.AppendItem("features")
+			l.Indent()
+			names := make([]string, 0, len(state.Info.Features))
+			for name := range state.Info.Features {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				l.AppendItem(fmt.Sprintf("%s: %t", name, state.Info.Features[name]))
+			}
+			l.UnIndent()
+		}
 		l.UnIndent()
 	}
 	l.UnIndent()
@@ -151,6 +192,9 @@ func listFleetState(l list.Writer, state *client.AgentState, all bool) {
 	l.AppendItem("fleet")
 	l.Indent()
 	l.AppendItem(formatStatus(state.FleetState, state.FleetMessage))
+	if state.FleetCircuitBreaker != "" && (all || state.FleetCircuitBreaker != "closed") {
+		l.AppendItem("circuit breaker: " + state.FleetCircuitBreaker)
+	}
 	l.UnIndent()
 }
 
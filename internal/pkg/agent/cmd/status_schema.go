@@ -0,0 +1,138 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	_ "embed" // statusSchema
+	"fmt"
+	"io"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+// statusSchemaVersion is the version of the schema of the json and yaml outputs of the status command,
+// statusSchema. Within a major version fields are only added, renaming or removing a field or changing
+// its type bumps the major version. The outputs do not depend on the types of the control protocol so
+// changes to the protocol do not break the tools parsing them.
+const statusSchemaVersion = "1.2"
+
+// statusSchema is the JSON schema of the json and yaml outputs of the status command.
+//
+//go:embed status_schema.json
+var statusSchema []byte
+
+type statusOutput struct {
+	SchemaVersion string            `json:"schema_version" yaml:"schema_version"`
+	Info          statusInfo        `json:"info" yaml:"info"`
+	State         string            `json:"state" yaml:"state"`
+	Message       string            `json:"message" yaml:"message"`
+	FleetState    string            `json:"fleet_state" yaml:"fleet_state"`
+	FleetMessage  string            `json:"fleet_message" yaml:"fleet_message"`
+	Components    []statusComponent `json:"components" yaml:"components"`
+
+	FleetCircuitBreaker string `json:"fleet_circuit_breaker,omitempty" yaml:"fleet_circuit_breaker,omitempty"`
+}
+
+type statusInfo struct {
+	ID        string          `json:"id" yaml:"id"`
+	Version   string          `json:"version" yaml:"version"`
+	Commit    string          `json:"commit" yaml:"commit"`
+	BuildTime string          `json:"build_time" yaml:"build_time"`
+	Snapshot  bool            `json:"snapshot" yaml:"snapshot"`
+	Features  map[string]bool `json:"features,omitempty" yaml:"features,omitempty"`
+}
+
+type statusComponent struct {
+	ID          string            `json:"id" yaml:"id"`
+	Name        string            `json:"name" yaml:"name"`
+	State       string            `json:"state" yaml:"state"`
+	Message     string            `json:"message" yaml:"message"`
+	VersionInfo statusVersionInfo `json:"version_info" yaml:"version_info"`
+	Units       []statusUnit      `json:"units" yaml:"units"`
+}
+
+type statusVersionInfo struct {
+	Name    string            `json:"name" yaml:"name"`
+	Version string            `json:"version" yaml:"version"`
+	Meta    map[string]string `json:"meta,omitempty" yaml:"meta,omitempty"`
+}
+
+type statusUnit struct {
+	UnitID                 string                 `json:"unit_id" yaml:"unit_id"`
+	UnitType               string                 `json:"unit_type" yaml:"unit_type"`
+	State                  string                 `json:"state" yaml:"state"`
+	Message                string                 `json:"message" yaml:"message"`
+	Payload                map[string]interface{} `json:"payload,omitempty" yaml:"payload,omitempty"`
+	ExpectedConfigIdx      uint64                 `json:"expected_config_idx,omitempty" yaml:"expected_config_idx,omitempty"`
+	ObservedConfigIdx      uint64                 `json:"observed_config_idx,omitempty" yaml:"observed_config_idx,omitempty"`
+	ExpectedPolicyRevision int64                  `json:"expected_policy_revision,omitempty" yaml:"expected_policy_revision,omitempty"`
+	ObservedPolicyRevision int64                  `json:"observed_policy_revision,omitempty" yaml:"observed_policy_revision,omitempty"`
+}
+
+func newStatusOutput(state *client.AgentState) *statusOutput {
+	out := &statusOutput{
+		SchemaVersion: statusSchemaVersion,
+		Info: statusInfo{
+			ID:        state.Info.ID,
+			Version:   state.Info.Version,
+			Commit:    state.Info.Commit,
+			BuildTime: state.Info.BuildTime,
+			Snapshot:  state.Info.Snapshot,
+			Features:  state.Info.Features,
+		},
+		State:        state.State.String(),
+		Message:      state.Message,
+		FleetState:   state.FleetState.String(),
+		FleetMessage: state.FleetMessage,
+		Components:   make([]statusComponent, 0, len(state.Components)),
+
+		FleetCircuitBreaker: state.FleetCircuitBreaker,
+	}
+	for _, c := range state.Components {
+		comp := statusComponent{
+			ID:      c.ID,
+			Name:    c.Name,
+			State:   c.State.String(),
+			Message: c.Message,
+			VersionInfo: statusVersionInfo{
+				Name:    c.VersionInfo.Name,
+				Version: c.VersionInfo.Version,
+				Meta:    c.VersionInfo.Meta,
+			},
+			Units: make([]statusUnit, 0, len(c.Units)),
+		}
+		for _, u := range c.Units {
+			comp.Units = append(comp.Units, statusUnit{
+				UnitID:                 u.UnitID,
+				UnitType:               u.UnitType.String(),
+				State:                  u.State.String(),
+				Message:                u.Message,
+				Payload:                u.Payload,
+				ExpectedConfigIdx:      u.ExpectedConfigIdx,
+				ObservedConfigIdx:      u.ObservedConfigIdx,
+				ExpectedPolicyRevision: u.ExpectedPolicyRevision,
+				ObservedPolicyRevision: u.ObservedPolicyRevision,
+			})
+		}
+		out.Components = append(out.Components, comp)
+	}
+	return out
+}
+
+// versionedOutput writes the state in the versioned schema of the status command.
+func versionedOutput(o outputter) outputter {
+	return func(w io.Writer, obj interface{}) error {
+		state, ok := obj.(*client.AgentState)
+		if !ok {
+			return fmt.Errorf("unable to cast %T as *client.AgentState", obj)
+		}
+		return o(w, newStatusOutput(state))
+	}
+}
+
+func schemaOutput(w io.Writer, _ interface{}) error {
+	_, err := w.Write(statusSchema)
+	return err
+}
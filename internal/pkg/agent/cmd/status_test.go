@@ -6,10 +6,14 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -24,6 +28,7 @@ func TestHumanOutput(t *testing.T) {
 			Commit:    "adf44ef2c6dfc56b5e60400ecdfbf46ceda5a6f4",
 			BuildTime: "2023-05-24 00:01:09 +0000 UTC",
 			Snapshot:  false,
+			Features:  map[string]bool{"fqdn": false, "new_downloader": true},
 		},
 		State:        client.Degraded,
 		Message:      "1 or more components/units in a failed state",
@@ -136,6 +141,8 @@ func TestHumanOutput(t *testing.T) {
 		{output: "human", state_name: "healthy", state: stateHealthy},
 		{output: "full", state_name: "healthy", state: stateHealthy},
 		{output: "full", state_name: "degraded", state: stateDegraded},
+		{output: "json", state_name: "degraded", state: stateDegraded},
+		{output: "yaml", state_name: "degraded", state: stateDegraded},
 	}
 	for _, test := range tests {
 		b.Reset()
@@ -148,3 +155,51 @@ func TestHumanOutput(t *testing.T) {
 		require.Equalf(t, string(expected), b.String(), "unexpected input with output: %s, state: %s", test.output, test.state_name)
 	}
 }
+
+func TestStatusSchema(t *testing.T) {
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(statusSchema, &schema))
+	assert.Regexp(t, schema["properties"].(map[string]interface{})["schema_version"].(map[string]interface{})["pattern"], statusSchemaVersion)
+
+	// every field of the output is described by the schema, the fields always present are required
+	defs := schema["$defs"].(map[string]interface{})
+	var check func(path string, typ reflect.Type, def map[string]interface{})
+	check = func(path string, typ reflect.Type, def map[string]interface{}) {
+		if ref, ok := def["$ref"].(string); ok {
+			def = defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		}
+		if items, ok := def["items"].(map[string]interface{}); ok && typ.Kind() == reflect.Slice {
+			check(path+"[]", typ.Elem(), items)
+			return
+		}
+		if typ.Kind() != reflect.Struct {
+			return
+		}
+		properties, _ := def["properties"].(map[string]interface{})
+		required := map[string]bool{}
+		for _, r := range def["required"].([]interface{}) {
+			required[r.(string)] = true
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			tag := strings.Split(typ.Field(i).Tag.Get("json"), ",")
+			field := path + "." + tag[0]
+			prop, ok := properties[tag[0]].(map[string]interface{})
+			if !assert.Truef(t, ok, "field %s is not in the schema", field) {
+				continue
+			}
+			omitempty := len(tag) > 1 && tag[1] == "omitempty"
+			assert.Equalf(t, !omitempty, required[tag[0]], "field %s required mismatch", field)
+			check(field, typ.Field(i).Type, prop)
+		}
+		assert.Lenf(t, properties, typ.NumField(), "schema of %s has fields the output does not have", path)
+	}
+	check("", reflect.TypeOf(statusOutput{}), schema)
+}
+
+func TestFormatEvents(t *testing.T) {
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"events":{"published":1000000,"dropped":2,"failed":0,"queued":4096}}`), &payload))
+	events, ok := payload["events"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "events: published 1000000, dropped 2, failed 0, queued 4096", formatEvents(events))
+}
@@ -0,0 +1,105 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/telemetry"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/config/operations"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func newTelemetryCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry <subcommand>",
+		Short: "Inspect the usage telemetry",
+		Long: `Inspect the opt-in usage telemetry of the Elastic Agent, enabled with agent.telemetry.enabled.
+The reports hold the version, OS and architecture of the Elastic Agent and the counts of the component types and of the error categories, no identifier or configuration value.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Print the report the running Elastic Agent would send",
+		Args:  cobra.NoArgs,
+		Run: func(c *cobra.Command, args []string) {
+			if err := telemetryShowCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	})
+
+	return cmd
+}
+
+func telemetryShowCmd(streams *cli.IOStreams) error {
+	cfg, err := loadTelemetryConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx := handleSignal(context.Background())
+	innerCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	state, err := getDaemonState(innerCtx)
+	if err != nil {
+		return fmt.Errorf("failed to communicate with Elastic Agent daemon: %w", err)
+	}
+
+	pending, err := telemetry.NewSpool(telemetry.SpoolDir(), cfg.MaxSpooled).Pending()
+	if err != nil {
+		return err
+	}
+	return writeTelemetryReport(streams.Out, cfg, len(pending), telemetry.NewReport(telemetry.StateFromClient(state), time.Now()))
+}
+
+// writeTelemetryReport writes the settings of the telemetry and the report with the fields sent to the endpoint.
+func writeTelemetryReport(w io.Writer, cfg *configuration.TelemetryConfig, spooled int, report telemetry.Report) error {
+	if cfg.Enabled {
+		fmt.Fprintf(w, "Telemetry is enabled, a report is sent to %s every %s.\n", cfg.Endpoint, cfg.Interval)
+	} else {
+		fmt.Fprintln(w, "Telemetry is disabled, no report is sent. Set agent.telemetry.enabled to send this report.")
+	}
+	fmt.Fprintf(w, "Spooled reports: %d\n", spooled)
+	fmt.Fprintln(w, "Report:")
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal telemetry report: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+func loadTelemetryConfig() (*configuration.TelemetryConfig, error) {
+	l, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
+	if err != nil {
+		return nil, err
+	}
+	rawCfg, err := operations.LoadFullAgentConfig(l, paths.ConfigFile(), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the configuration: %w", err)
+	}
+	cfg, err := configuration.NewFromConfig(rawCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration: %w", err)
+	}
+	if cfg.Settings.Telemetry == nil {
+		return configuration.DefaultTelemetryConfig(), nil
+	}
+	return cfg.Settings.Telemetry, nil
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/telemetry"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+func TestWriteTelemetryReport(t *testing.T) {
+	report := telemetry.Report{
+		Timestamp:  time.Date(2023, 8, 13, 12, 0, 0, 0, time.UTC),
+		Version:    "8.11.0",
+		OS:         "linux",
+		Arch:       "amd64",
+		Components: map[string]int{"filestream": 2},
+		Errors:     map[string]int{"unit_failed": 1},
+	}
+	cfg := configuration.DefaultTelemetryConfig()
+	cfg.Enabled = true
+	cfg.Endpoint = "https://telemetry.example/v1/agent"
+
+	var b bytes.Buffer
+	require.NoError(t, writeTelemetryReport(&b, cfg, 3, report))
+	assert.Equal(t, `Telemetry is enabled, a report is sent to https://telemetry.example/v1/agent every 24h0m0s.
+Spooled reports: 3
+Report:
+{
+  "@timestamp": "2023-08-13T12:00:00Z",
+  "version": "8.11.0",
+  "snapshot": false,
+  "os": "linux",
+  "arch": "amd64",
+  "components": {
+    "filestream": 2
+  },
+  "errors": {
+    "unit_failed": 1
+  }
+}
+`, b.String())
+}
@@ -7,6 +7,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -24,6 +25,9 @@ func newUninstallCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comm
 		Long: `This command uninstalls the Elastic Agent permanently from this system.  The system's service manager will no longer manage Elastic agent.
 
 Unless -f is used this command will ask confirmation before performing removal.
+
+With --preserve-state the identity, the enrollment and the cursors of the components are kept in an archive, restored
+with install --restore-state to keep the same agent in Fleet without re-reading the logs from the start.
 `,
 		Run: func(c *cobra.Command, _ []string) {
 			if err := uninstallCmd(streams, c); err != nil {
@@ -34,10 +38,20 @@ Unless -f is used this command will ask confirmation before performing removal.
 	}
 
 	cmd.Flags().BoolP("force", "f", false, "Force overwrite the current and do not prompt for confirmation")
+	cmd.Flags().String(flagPreserveState, "", "Write the state of the agent to this archive before it is uninstalled, it holds its credentials and must be kept safe")
+	cmd.Flags().Lookup(flagPreserveState).NoOptDefVal = defaultStateSnapshotPath()
 
 	return cmd
 }
 
+const flagPreserveState = "preserve-state"
+
+// defaultStateSnapshotPath returns the archive the state is preserved in when --preserve-state has
+// no value, next to the installation directory removed by the uninstall.
+func defaultStateSnapshotPath() string {
+	return filepath.Join(filepath.Dir(paths.Top()), "elastic-agent-state.tar.gz")
+}
+
 func uninstallCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	isAdmin, err := utils.HasRoot()
 	if err != nil {
@@ -78,6 +92,18 @@ func uninstallCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		}
 	}
 
+	preserve, _ := cmd.Flags().GetString(flagPreserveState)
+	if preserve != "" {
+		// the components must not write their cursors while they are archived
+		if err := install.StopService(paths.Top()); err != nil && status != install.Broken {
+			return err
+		}
+		if err := install.PreserveState(preserve); err != nil {
+			return err
+		}
+		fmt.Fprintf(streams.Out, "The state of the Elastic Agent has been written to %s, it holds the credentials of the agent and must be kept safe.\n", preserve)
+	}
+
 	err = install.Uninstall(paths.ConfigFile(), paths.Top())
 	if err != nil {
 		return err
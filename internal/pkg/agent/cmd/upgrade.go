@@ -15,6 +15,8 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
@@ -26,6 +28,7 @@ const (
 	flagPGPBytes     = "pgp"
 	flagPGPBytesPath = "pgp-path"
 	flagPGPBytesURI  = "pgp-uri"
+	flagManagedByPkg = "managed-by-pkg"
 )
 
 func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
@@ -33,7 +36,7 @@ func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comman
 		Use:   "upgrade <version>",
 		Short: "Upgrade the currently installed Elastic Agent to the specified version",
 		Long:  "This command upgrades the currently installed Elastic Agent to the specified version.",
-		Args:  cobra.ExactArgs(1),
+		Args:  upgradeArgs,
 		Run: func(c *cobra.Command, args []string) {
 			if err := upgradeCmd(streams, c, args); err != nil {
 				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
@@ -47,11 +50,33 @@ func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comman
 	cmd.Flags().String(flagPGPBytes, "", "PGP to use for package verification")
 	cmd.Flags().String(flagPGPBytesURI, "", "Path to a web location containing PGP to use for package verification")
 	cmd.Flags().String(flagPGPBytesPath, "", "Path to a file containing PGP to use for package verification")
+	cmd.Flags().Bool(flagManagedByPkg, false, "Leave upgrades to the system package manager, the version is only recorded as the desired version and reported to Fleet. Set to false to let Elastic Agent upgrade itself again, the version is then optional")
 
 	return cmd
 }
 
+// upgradeArgs requires the version, it is optional when only leaving the upgrades managed by the package manager.
+func upgradeArgs(cmd *cobra.Command, args []string) error {
+	if managedByPkg, _ := cmd.Flags().GetBool(flagManagedByPkg); !managedByPkg && cmd.Flags().Changed(flagManagedByPkg) {
+		return cobra.MaximumNArgs(1)(cmd, args)
+	}
+	return cobra.ExactArgs(1)(cmd, args)
+}
+
 func upgradeCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed(flagManagedByPkg) {
+		managedByPkg, _ := cmd.Flags().GetBool(flagManagedByPkg)
+		if managedByPkg {
+			return managedByPkgUpgradeCmd(streams, args[0])
+		}
+		if err := clearManagedByPkgCmd(streams); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return nil
+		}
+	}
+
 	version := args[0]
 	sourceURI, _ := cmd.Flags().GetString(flagSourceURI)
 
@@ -100,3 +125,34 @@ func upgradeCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error
 	fmt.Fprintf(streams.Out, "Upgrade triggered to version %s, Elastic Agent is currently restarting\n", version)
 	return nil
 }
+
+// managedByPkgUpgradeCmd records the desired version for an Elastic Agent whose upgrades are
+// managed by a package manager and prints the configuration pinning the package to that version.
+func managedByPkgUpgradeCmd(streams *cli.IOStreams, version string) error {
+	if err := upgrade.MarkManagedByPackage(version, nil); err != nil {
+		return errors.New(err, "Failed to record the desired version")
+	}
+	fmt.Fprintf(streams.Out, "Upgrades are managed by the package manager, Elastic Agent is waiting for version %s to be installed\n", version)
+
+	pinPath, pinConfig, ok := upgrade.PackagePinConfig(info.InstalledPackageManager(), version)
+	if !ok {
+		return nil
+	}
+	fmt.Fprintf(streams.Out, "\nTo pin automatic updates to this version, add the following to %s:\n\n%s", pinPath, pinConfig)
+	return nil
+}
+
+// clearManagedByPkgCmd lets the Elastic Agent upgrade itself again when its upgrades are managed
+// by a package manager.
+func clearManagedByPkgCmd(streams *cli.IOStreams) error {
+	cleared, err := upgrade.ClearManagedByPackage()
+	if err != nil {
+		return errors.New(err, "Failed to stop managing upgrades by the package manager")
+	}
+	if !cleared {
+		fmt.Fprintln(streams.Out, "Upgrades are not managed by the package manager")
+		return nil
+	}
+	fmt.Fprintln(streams.Out, "Upgrades are no longer managed by the package manager, Elastic Agent upgrades itself")
+	return nil
+}
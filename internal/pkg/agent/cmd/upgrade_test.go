@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestUpgradeArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags []string
+		args  []string
+		valid bool
+	}{
+		{name: "version", args: []string{"8.9.0"}, valid: true},
+		{name: "no version", valid: false},
+		{name: "managed by pkg", flags: []string{"--managed-by-pkg"}, args: []string{"8.9.0"}, valid: true},
+		{name: "managed by pkg without version", flags: []string{"--managed-by-pkg"}, valid: false},
+		{name: "leave managed by pkg", flags: []string{"--managed-by-pkg=false"}, valid: true},
+		{name: "leave managed by pkg and upgrade", flags: []string{"--managed-by-pkg=false"}, args: []string{"8.9.0"}, valid: true},
+		{name: "too many versions", flags: []string{"--managed-by-pkg=false"}, args: []string{"8.9.0", "8.10.0"}, valid: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			streams, _, _, _ := cli.NewTestingIOStreams()
+			cmd := newUpgradeCommandWithArgs(nil, streams)
+			require.NoError(t, cmd.ParseFlags(tc.flags))
+			err := upgradeArgs(cmd, tc.args)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestUpgradeCmdLeaveManagedByPkg(t *testing.T) {
+	defer func(prev string) { paths.SetTop(prev) }(paths.Top())
+	paths.SetTop(t.TempDir())
+	require.NoError(t, os.MkdirAll(paths.Data(), 0755))
+
+	streams, _, out, _ := cli.NewTestingIOStreams()
+	cmd := newUpgradeCommandWithArgs(nil, streams)
+	require.NoError(t, cmd.ParseFlags([]string{"--managed-by-pkg"}))
+	require.NoError(t, upgradeCmd(streams, cmd, []string{"99.0.0"}))
+	state, err := upgrade.LoadManagedByPackage()
+	require.NoError(t, err)
+	require.NotNil(t, state)
+	assert.Equal(t, "99.0.0", state.Version)
+
+	out.Reset()
+	cmd = newUpgradeCommandWithArgs(nil, streams)
+	require.NoError(t, cmd.ParseFlags([]string{"--managed-by-pkg=false"}))
+	require.NoError(t, upgradeCmd(streams, cmd, nil))
+	assert.Contains(t, out.String(), "no longer managed by the package manager")
+	state, err = upgrade.LoadManagedByPackage()
+	require.NoError(t, err)
+	assert.Nil(t, state, "the Elastic Agent upgrades itself again")
+
+	out.Reset()
+	require.NoError(t, upgradeCmd(streams, cmd, nil))
+	assert.Contains(t, out.String(), "not managed by the package manager")
+}
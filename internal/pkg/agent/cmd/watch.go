@@ -22,6 +22,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
@@ -103,6 +104,9 @@ func watchCmd(log *logp.Logger, cfg *configuration.Configuration) error {
 	ctx := context.Background()
 	if err := watch(ctx, tilGrace, errorCheckInterval, crashCheckInterval, log); err != nil {
 		log.Error("Error detected proceeding to rollback: %v", err)
+		if detErr := upgrade.SetMarkerDetailsState(marker, details.StateRolledBack, err.Error()); detErr != nil {
+			log.Error("failed to record rollback in upgrade marker", detErr)
+		}
 		err = upgrade.Rollback(ctx, log, marker.PrevHash, marker.Hash)
 		if err != nil {
 			log.Error("rollback failed", err)
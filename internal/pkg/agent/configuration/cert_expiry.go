@@ -0,0 +1,35 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// interval between two checks of the certificates.
+	defaultCertExpiryInterval = 12 * time.Hour
+
+	// time before the expiration of a certificate from which it is reported.
+	defaultCertExpiryWarning = 30 * 24 * time.Hour
+)
+
+// CertExpiryConfig is the configuration of the monitoring of the expiration of the certificates
+// used by the agent: the ones of its connection to Fleet Server, of the artifact downloads and of
+// the TLS settings of the components.
+type CertExpiryConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Interval is the interval between two checks of the certificates.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Warning is the time before the expiration of a certificate from which the agent is degraded.
+	Warning time.Duration `yaml:"warning" config:"warning" json:"warning"`
+}
+
+// DefaultCertExpiryConfig creates a default certificate expiration configuration.
+func DefaultCertExpiryConfig() *CertExpiryConfig {
+	return &CertExpiryConfig{
+		Enabled:  true,
+		Interval: defaultCertExpiryInterval,
+		Warning:  defaultCertExpiryWarning,
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// maximum time the computation of the component model can take before it is abandoned.
+	defaultComponentModelTimeout = 5 * time.Minute
+
+	// time after which the agent reports it is still computing the component model.
+	defaultComponentModelSlowThreshold = 10 * time.Second
+)
+
+// ComponentModelConfig is the configuration of the computation of the component model,
+// the rendering of the policy with the variables of the providers.
+type ComponentModelConfig struct {
+	// Timeout is the maximum time the computation can take, the policy fails to apply when it is exceeded.
+	// Zero disables the timeout.
+	Timeout time.Duration `yaml:"timeout" config:"timeout" json:"timeout"`
+	// SlowThreshold is the time after which the agent reports it is configuring until the computation ends.
+	// Zero disables the report.
+	SlowThreshold time.Duration `yaml:"slow_threshold" config:"slow_threshold" json:"slow_threshold"`
+}
+
+// DefaultComponentModelConfig creates a default component model configuration.
+func DefaultComponentModelConfig() *ComponentModelConfig {
+	return &ComponentModelConfig{
+		Timeout:       defaultComponentModelTimeout,
+		SlowThreshold: defaultComponentModelSlowThreshold,
+	}
+}
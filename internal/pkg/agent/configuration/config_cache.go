@@ -0,0 +1,28 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+// maximum age of the cached configuration restored on start.
+const defaultConfigCacheMaxAge = 7 * 24 * time.Hour
+
+// ConfigCacheConfig is the configuration of the cache of the last good policy and variables of a
+// Fleet-managed agent. The cache is restored on start, so the components run while Fleet is not
+// reachable instead of waiting for the first check-in.
+type ConfigCacheConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// MaxAge is the maximum age of the cache restored on start, an older cache is ignored.
+	// Zero restores the cache whatever its age.
+	MaxAge time.Duration `yaml:"max_age" config:"max_age" json:"max_age"`
+}
+
+// DefaultConfigCacheConfig creates a default configuration cache configuration.
+func DefaultConfigCacheConfig() *ConfigCacheConfig {
+	return &ConfigCacheConfig{
+		Enabled: true,
+		MaxAge:  defaultConfigCacheMaxAge,
+	}
+}
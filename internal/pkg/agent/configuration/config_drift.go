@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// interval between two checks of the configurations applied by the units.
+	defaultConfigDriftInterval = 30 * time.Second
+
+	// time a unit can stay on an old configuration before it is reported.
+	defaultConfigDriftThreshold = 5 * time.Minute
+)
+
+// ConfigDriftConfig is the configuration of the detection of the units that don't apply the latest
+// configuration sent to them.
+type ConfigDriftConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Interval is the interval between two checks of the configurations applied by the units.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Threshold is the time a unit can stay on an old configuration before the agent is degraded.
+	Threshold time.Duration `yaml:"threshold" config:"threshold" json:"threshold"`
+}
+
+// DefaultConfigDriftConfig creates a default configuration drift detection configuration.
+func DefaultConfigDriftConfig() *ConfigDriftConfig {
+	return &ConfigDriftConfig{
+		Enabled:   true,
+		Interval:  defaultConfigDriftInterval,
+		Threshold: defaultConfigDriftThreshold,
+	}
+}
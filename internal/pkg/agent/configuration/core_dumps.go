@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "errors"
+
+// maximum number of captured dumps kept, the oldest ones are removed.
+const defaultCoreDumpsMaxDumps = 5
+
+// CoreDumpsConfig is the configuration of the capture of the core dumps of the components killed
+// by a signal, or of the minidumps of the crashed components on Windows.
+type CoreDumpsConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// MaxDumps is the maximum number of compressed dumps kept, the oldest ones are removed.
+	MaxDumps int `yaml:"max_dumps" config:"max_dumps" json:"max_dumps"`
+}
+
+// Validate ensures at least one dump is kept.
+func (c *CoreDumpsConfig) Validate() error {
+	if c.MaxDumps < 1 {
+		return errors.New("max_dumps must be at least 1")
+	}
+	return nil
+}
+
+// DefaultCoreDumpsConfig creates a default core dumps configuration, the capture is disabled.
+func DefaultCoreDumpsConfig() *CoreDumpsConfig {
+	return &CoreDumpsConfig{
+		Enabled:  false,
+		MaxDumps: defaultCoreDumpsMaxDumps,
+	}
+}
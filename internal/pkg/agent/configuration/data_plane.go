@@ -0,0 +1,36 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// interval between two collections of the events of the components.
+	defaultDataPlaneInterval = 30 * time.Second
+
+	// interval between two reports of the events while the problems of the units don't change.
+	defaultDataPlaneReportInterval = 5 * time.Minute
+)
+
+// DataPlaneConfig is the configuration of the collection of the events published, dropped, failed
+// and queued by the components, reported in the state of their output unit.
+type DataPlaneConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Interval is the interval between two collections of the events, the problems of the units
+	// are detected over this interval.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// ReportInterval is the interval between two reports of the events while the problems of the
+	// units don't change.
+	ReportInterval time.Duration `yaml:"report_interval" config:"report_interval" json:"report_interval"`
+}
+
+// DefaultDataPlaneConfig creates a default configuration of the collection of the events.
+func DefaultDataPlaneConfig() *DataPlaneConfig {
+	return &DataPlaneConfig{
+		Enabled:        true,
+		Interval:       defaultDataPlaneInterval,
+		ReportInterval: defaultDataPlaneReportInterval,
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// interval between two checks of the disk usage.
+	defaultDiskQuotaInterval = 5 * time.Minute
+
+	// default quotas of the paths managed by the agent, in bytes.
+	defaultDiskQuotaLogs        = 1 << 30
+	defaultDiskQuotaDownloads   = 2 << 30
+	defaultDiskQuotaDiagnostics = 512 << 20
+)
+
+// DiskQuotaConfig is the configuration of the quotas of the disk space used by the paths the agent
+// manages, so the agent does not fill the filesystem of the host. A zero quota does not limit the
+// path, its usage is still reported.
+type DiskQuotaConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Interval is the interval between two checks of the disk usage.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Logs is the quota of the logs of the agent and of the components.
+	Logs int64 `yaml:"logs" config:"logs" json:"logs"`
+	// Downloads is the quota of the downloaded artifacts.
+	Downloads int64 `yaml:"downloads" config:"downloads" json:"downloads"`
+	// Diagnostics is the quota of the core dumps and of the output of the service operations.
+	Diagnostics int64 `yaml:"diagnostics" config:"diagnostics" json:"diagnostics"`
+	// Spools is the quota of the queues of the shippers, they are only reported as their
+	// segments can't be evicted without corrupting the queues.
+	Spools int64 `yaml:"spools" config:"spools" json:"spools"`
+}
+
+// DefaultDiskQuotaConfig creates a default disk quota configuration, it is disabled.
+func DefaultDiskQuotaConfig() *DiskQuotaConfig {
+	return &DiskQuotaConfig{
+		Interval:    defaultDiskQuotaInterval,
+		Logs:        defaultDiskQuotaLogs,
+		Downloads:   defaultDiskQuotaDownloads,
+		Diagnostics: defaultDiskQuotaDiagnostics,
+	}
+}
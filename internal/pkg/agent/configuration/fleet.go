@@ -5,6 +5,8 @@
 package configuration
 
 import (
+	"time"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
 )
@@ -17,6 +19,14 @@ type FleetAgentConfig struct {
 	Client       remote.Config      `config:",inline" yaml:",inline"`
 	Info         *AgentInfo         `config:"agent" yaml:"agent"`
 	Server       *FleetServerConfig `config:"server" yaml:"server,omitempty"`
+	Ephemeral    *EphemeralConfig   `config:"ephemeral" yaml:"ephemeral,omitempty"`
+}
+
+// EphemeralConfig marks the agent as ephemeral, an ephemeral agent unenrolls itself and purges its local
+// state on a clean shutdown or, when InactivityTimeout is set, once it runs no workload for that long.
+type EphemeralConfig struct {
+	Enabled           bool          `config:"enabled" yaml:"enabled"`
+	InactivityTimeout time.Duration `config:"inactivity_timeout" yaml:"inactivity_timeout,omitempty"`
 }
 
 // Valid validates the required fields for accessing the API.
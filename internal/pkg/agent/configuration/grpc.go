@@ -4,13 +4,37 @@
 
 package configuration
 
-import "fmt"
+import (
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
+)
+
+const (
+	unixScheme  = "unix://"
+	npipeScheme = "npipe://"
+)
 
 // GRPCConfig is a configuration of GRPC server.
 type GRPCConfig struct {
-	Address    string `config:"address"`
-	Port       uint16 `config:"port"`
-	MaxMsgSize int    `config:"max_message_size"`
+	Address string `config:"address"`
+	Port    uint16 `config:"port"`
+	// Local makes the GRPC server listen on a Unix domain socket (on Windows a named pipe) instead
+	// of a TCP port. The socket is derived from the data path, unless Address is a unix:// or
+	// npipe:// address.
+	Local      bool `config:"local"`
+	MaxMsgSize int  `config:"max_message_size"`
+	// MaxCheckinMsgSize limits the size of the expected check-in messages sent to the components,
+	// larger messages are sent in chunks. Zero disables chunking.
+	MaxCheckinMsgSize int `config:"max_checkin_message_size"`
+	// CredentialsRotationInterval is the interval at which the components are restarted with new
+	// connection credentials. Zero disables the rotation.
+	CredentialsRotationInterval time.Duration `config:"credentials_rotation_interval"`
+	// CheckinDebounce is how long the expected check-in message of a configuration change waits
+	// for the following changes, the changes arriving together reach the component in a single
+	// message. Zero sends each change right away.
+	CheckinDebounce time.Duration `config:"checkin_debounce"`
 }
 
 // DefaultGRPCConfig creates a default server configuration.
@@ -19,10 +43,25 @@ func DefaultGRPCConfig() *GRPCConfig {
 		Address:    "localhost",
 		Port:       6789,
 		MaxMsgSize: 1024 * 1024 * 100, // grpc default 4MB is unsufficient for diagnostics
+		// components receive with the grpc default 4MB limit, keep some room for the rest of the message
+		MaxCheckinMsgSize: 1024 * 1024 * 3,
+		CheckinDebounce:   100 * time.Millisecond,
 	}
 }
 
 // String returns the composed listen address for the GRPC.
 func (cfg *GRPCConfig) String() string {
-	return fmt.Sprintf("%s:%d", cfg.Address, cfg.Port)
+	if cfg.Local {
+		if IsLocalAddress(cfg.Address) {
+			return cfg.Address
+		}
+		return localGRPCAddress()
+	}
+	// listening on localhost only uses its IPv4 address, use the IPv6 loopback on IPv6-only hosts
+	return netutil.JoinHostPort(netutil.LocalHost(cfg.Address), int(cfg.Port))
+}
+
+// IsLocalAddress returns true when the address is a Unix domain socket or a Windows named pipe.
+func IsLocalAddress(addr string) bool {
+	return strings.HasPrefix(addr, unixScheme) || strings.HasPrefix(addr, npipeScheme)
 }
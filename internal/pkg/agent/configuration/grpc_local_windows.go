@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package configuration
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// localGRPCAddress returns the named pipe the GRPC server listens on in local mode.
+func localGRPCAddress() string {
+	// unique per data path, so multiple agents can run side by side
+	return fmt.Sprintf(`npipe:///elastic-agent-grpc-%x`, sha256.Sum256([]byte(paths.Data())))
+}
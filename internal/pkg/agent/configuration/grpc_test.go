@@ -0,0 +1,30 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
+)
+
+func TestGRPCConfigString(t *testing.T) {
+	defer netutil.SetIPFamily(netutil.FamilyAuto)
+
+	cfg := DefaultGRPCConfig()
+	netutil.SetIPFamily(netutil.FamilyIPv4)
+	assert.Equal(t, "localhost:6789", cfg.String())
+
+	// localhost only listens on IPv4, the IPv6 loopback is used on IPv6-only hosts
+	netutil.SetIPFamily(netutil.FamilyIPv6)
+	assert.Equal(t, "[::1]:6789", cfg.String())
+
+	cfg.Address = "::1"
+	assert.Equal(t, "[::1]:6789", cfg.String())
+	cfg.Address = "[fd00::1]"
+	assert.Equal(t, "[fd00::1]:6789", cfg.String())
+}
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+const (
+	// address the agent listens on for the lease requests of its peer.
+	defaultHAListen = "0.0.0.0:6792"
+
+	// time without contact with the active peer after which the standby agent takes over.
+	defaultHALeaseDuration = 15 * time.Second
+
+	// interval between two renewals of the lease with the peer, it is a fraction of the lease
+	// duration so a few lost requests do not trigger a failover.
+	defaultHARenewInterval = 5 * time.Second
+)
+
+// HAConfig is the configuration of the active/standby pair of agents. The two agents of the pair
+// exchange a lease so exactly one of them runs the inputs of the policy while the other one runs
+// none, and the standby agent takes over when the lease of the active agent expires. It is meant
+// for the inputs polling network devices, where a duplicate polling is harmful.
+type HAConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// NodeID identifies the agent in the pair, the host name when empty.
+	NodeID string `yaml:"node_id" config:"node_id" json:"node_id"`
+	// Priority breaks the ties when both agents claim the lease, the agent with the highest
+	// priority runs the inputs, the lowest node ID when the priorities are equal.
+	Priority int `yaml:"priority" config:"priority" json:"priority"`
+	// Listen is the address the agent listens on for the lease requests of its peer.
+	Listen string `yaml:"listen" config:"listen" json:"listen"`
+	// Peer is the host:port address the peer listens on.
+	Peer string `yaml:"peer" config:"peer" json:"peer"`
+	// Token is the secret shared by the two agents, the lease requests without it are rejected.
+	Token string `yaml:"token" config:"token" json:"token"`
+	// LeaseDuration is the time without contact with the active peer after which the standby
+	// agent takes over.
+	LeaseDuration time.Duration `yaml:"lease_duration" config:"lease_duration" json:"lease_duration"`
+	// RenewInterval is the interval between two renewals of the lease with the peer.
+	RenewInterval time.Duration     `yaml:"renew_interval" config:"renew_interval" json:"renew_interval"`
+	TLS           *tlscommon.Config `yaml:"ssl,omitempty" config:"ssl" json:"ssl,omitempty"`
+}
+
+// DefaultHAConfig creates a default configuration of the active/standby pair, it is disabled.
+func DefaultHAConfig() *HAConfig {
+	return &HAConfig{
+		Listen:        defaultHAListen,
+		LeaseDuration: defaultHALeaseDuration,
+		RenewInterval: defaultHARenewInterval,
+	}
+}
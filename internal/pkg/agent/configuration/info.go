@@ -13,3 +13,8 @@ func IsStandalone(cfg *FleetAgentConfig) bool {
 func IsFleetServerBootstrap(cfg *FleetAgentConfig) bool {
 	return cfg != nil && cfg.Server != nil && cfg.Server.Bootstrap
 }
+
+// IsEphemeral decides if Elastic Agent unenrolls itself from Fleet when it shuts down.
+func IsEphemeral(cfg *FleetAgentConfig) bool {
+	return !IsStandalone(cfg) && cfg.Ephemeral != nil && cfg.Ephemeral.Enabled
+}
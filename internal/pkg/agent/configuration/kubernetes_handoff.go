@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
+)
+
+const (
+	// interval between two checks of the cordon of the node.
+	defaultKubernetesHandoffInterval = 10 * time.Second
+
+	// maximum time the inputs and the shippers have to stop and flush their queues once the
+	// node is cordoned, before the handoff is published anyway.
+	defaultKubernetesHandoffTimeout = 2 * time.Minute
+)
+
+// KubernetesHandoffConfig is the configuration of the handoff of the inputs of an agent running
+// as a DaemonSet when its node is cordoned to be drained. The inputs are stopped, the shippers
+// flush their queues and the cursors of the inputs are published in a ConfigMap and a Kubernetes
+// event, so the collection replacing them on another node neither misses nor duplicates data.
+type KubernetesHandoffConfig struct {
+	Enabled           bool                         `yaml:"enabled" config:"enabled" json:"enabled"`
+	KubeConfig        string                       `yaml:"kube_config" config:"kube_config" json:"kube_config"`
+	KubeClientOptions kubernetes.KubeClientOptions `yaml:"kube_client_options" config:"kube_client_options" json:"kube_client_options"`
+	// Node is the name of the node of the agent, discovered when empty.
+	Node string `yaml:"node" config:"node" json:"node"`
+	// Namespace is the namespace of the ConfigMap and of the event of the handoff, the namespace
+	// of the pod of the agent when empty.
+	Namespace string `yaml:"namespace" config:"namespace" json:"namespace"`
+	// Interval is the interval between two checks of the cordon of the node.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Timeout is the maximum time the inputs and the shippers have to stop once the node is
+	// cordoned, the handoff is published anyway once it expires.
+	Timeout time.Duration `yaml:"timeout" config:"timeout" json:"timeout"`
+}
+
+// DefaultKubernetesHandoffConfig creates a default handoff configuration, it is disabled.
+func DefaultKubernetesHandoffConfig() *KubernetesHandoffConfig {
+	return &KubernetesHandoffConfig{
+		Interval: defaultKubernetesHandoffInterval,
+		Timeout:  defaultKubernetesHandoffTimeout,
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// fraction of the memory limit above which the agent sheds load.
+	defaultLoadSheddingThreshold = 0.85
+
+	// fraction of the memory limit below which the agent stops shedding load, it is lower
+	// than the threshold so the agent does not flap around it.
+	defaultLoadSheddingResumeThreshold = 0.7
+
+	// interval between two checks of the memory used by the agent.
+	defaultLoadSheddingInterval = 10 * time.Second
+)
+
+// LimitsConfig is the configuration of the resources the agent process uses itself, protecting
+// small hosts where the agent competes with the workloads it observes.
+type LimitsConfig struct {
+	// GoMaxProcs is the maximum number of CPUs executing the agent simultaneously, zero uses
+	// all the CPUs.
+	GoMaxProcs int `yaml:"go_max_procs" config:"go_max_procs" json:"go_max_procs"`
+	// MemoryLimit is the soft memory limit of the Go runtime in bytes, the equivalent of
+	// GOMEMLIMIT. Zero keeps the limit set by GOMEMLIMIT, if any.
+	MemoryLimit  int64              `yaml:"memory_limit" config:"memory_limit" json:"memory_limit"`
+	Cgroup       CgroupLimitsConfig `yaml:"cgroup" config:"cgroup" json:"cgroup"`
+	LoadShedding LoadSheddingConfig `yaml:"load_shedding" config:"load_shedding" json:"load_shedding"`
+}
+
+// CgroupLimitsConfig is the configuration of the cgroup the agent places itself in on Linux.
+// The components started by the agent inherit it, so its limits apply to all the processes of
+// the agent.
+type CgroupLimitsConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Path is the path of the cgroup relative to the cgroup the agent is started in, like the
+	// cgroup of its systemd service.
+	Path string `yaml:"path" config:"path" json:"path"`
+	// MemoryMax is the hard memory limit of the cgroup in bytes, zero does not limit it.
+	MemoryMax int64 `yaml:"memory_max" config:"memory_max" json:"memory_max"`
+	// CPUs is the number of CPUs the cgroup can use, zero does not limit it.
+	CPUs float64 `yaml:"cpus" config:"cpus" json:"cpus"`
+}
+
+// LoadSheddingConfig is the configuration of the load shedding of the agent when its memory
+// usage approaches the memory limit. While shedding load, the monitoring components are stopped
+// and the agent checks in less often with Fleet.
+type LoadSheddingConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Threshold is the fraction of the memory limit above which the agent sheds load.
+	Threshold float64 `yaml:"threshold" config:"threshold" json:"threshold"`
+	// ResumeThreshold is the fraction of the memory limit below which the agent stops shedding load.
+	ResumeThreshold float64 `yaml:"resume_threshold" config:"resume_threshold" json:"resume_threshold"`
+	// Interval is the interval between two checks of the memory used by the agent.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+}
+
+// DefaultLimitsConfig creates a default limits configuration, the resources of the agent are not limited.
+func DefaultLimitsConfig() *LimitsConfig {
+	return &LimitsConfig{
+		LoadShedding: LoadSheddingConfig{
+			Enabled:         true,
+			Threshold:       defaultLoadSheddingThreshold,
+			ResumeThreshold: defaultLoadSheddingResumeThreshold,
+			Interval:        defaultLoadSheddingInterval,
+		},
+	}
+}
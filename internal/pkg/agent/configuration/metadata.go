@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultMetadataHookTimeout is the time a metadata hook has to print its output when no timeout is set.
+	DefaultMetadataHookTimeout = 10 * time.Second
+	// DefaultMetadataHookCacheTTL is the time the output of a metadata hook is reused when no TTL is set.
+	DefaultMetadataHookCacheTTL = time.Hour
+)
+
+// MetadataConfig defines the custom metadata reported to Fleet in the local metadata of the agent.
+type MetadataConfig struct {
+	Hooks []MetadataHookConfig `config:"hooks" yaml:"hooks,omitempty" json:"hooks,omitempty"`
+}
+
+// MetadataHookConfig defines an executable printing a JSON object on its standard output, the object
+// is reported under local_metadata.custom.<name>.
+type MetadataHookConfig struct {
+	Name    string   `config:"name" yaml:"name" json:"name"`
+	Command string   `config:"command" yaml:"command" json:"command"`
+	Args    []string `config:"args" yaml:"args,omitempty" json:"args,omitempty"`
+	// Timeout is the time the hook has to exit, DefaultMetadataHookTimeout when not set.
+	Timeout time.Duration `config:"timeout" yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// CacheTTL is the time the output of the hook is reused before the hook runs again,
+	// DefaultMetadataHookCacheTTL when not set.
+	CacheTTL time.Duration `config:"cache_ttl" yaml:"cache_ttl,omitempty" json:"cache_ttl,omitempty"`
+}
+
+// Validate validates settings of configuration.
+func (m *MetadataConfig) Validate() error {
+	names := make(map[string]bool, len(m.Hooks))
+	for _, h := range m.Hooks {
+		if h.Name == "" {
+			return fmt.Errorf("metadata hook %q has no name", h.Command)
+		}
+		if h.Command == "" {
+			return fmt.Errorf("metadata hook %q has no command", h.Name)
+		}
+		if h.Timeout < 0 || h.CacheTTL < 0 {
+			return fmt.Errorf("metadata hook %q has a negative timeout or cache_ttl", h.Name)
+		}
+		if names[h.Name] {
+			return fmt.Errorf("metadata hook %q is defined more than once", h.Name)
+		}
+		names[h.Name] = true
+	}
+	return nil
+}
+
+// DefaultMetadataConfig creates a config without metadata hooks.
+func DefaultMetadataConfig() *MetadataConfig {
+	return &MetadataConfig{}
+}
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// RemotePolicyGit pulls the policy from a Git repository.
+	RemotePolicyGit = "git"
+	// RemotePolicyOCI pulls the policy from an artifact of an OCI registry.
+	RemotePolicyOCI = "oci"
+	// RemotePolicyHTTP pulls the policy from an HTTP(S) URL.
+	RemotePolicyHTTP = "http"
+
+	// DefaultRemotePolicyPath is the file of the policy in the repository or the artifact when no path is set.
+	DefaultRemotePolicyPath = "elastic-agent.yml"
+	// DefaultRemotePolicyInterval is the time between two pulls of the policy when no interval is set.
+	DefaultRemotePolicyInterval = 5 * time.Minute
+)
+
+// RemotePolicyConfig defines a remote source of the policy of a standalone agent. The policy is pulled
+// periodically, verified with its detached PGP signature, <path>.asc or <url>.asc, and merged on top of
+// the local configuration.
+type RemotePolicyConfig struct {
+	Enabled bool `config:"enabled" yaml:"enabled" json:"enabled"`
+	// Type is the kind of source, RemotePolicyGit, RemotePolicyOCI or RemotePolicyHTTP.
+	Type string `config:"type" yaml:"type" json:"type"`
+	// URL is the URL of the Git repository, the reference of the OCI artifact, registry/repository:tag,
+	// or the URL of the policy.
+	URL string `config:"url" yaml:"url" json:"url"`
+	// Ref is the branch or tag of the Git repository, the default branch when not set.
+	Ref string `config:"ref" yaml:"ref,omitempty" json:"ref,omitempty"`
+	// Path is the file of the policy in the repository or the title of the layer in the artifact, it is
+	// not used by an HTTP(S) URL.
+	Path     string        `config:"path" yaml:"path" json:"path"`
+	Interval time.Duration `config:"interval" yaml:"interval" json:"interval"`
+	// PGP is the public key verifying the signature of the policy, prefixed with pgp_raw: or pgp_uri:
+	// like the PGP keys of upgrades.
+	PGP string `config:"pgp" yaml:"pgp,omitempty" json:"pgp,omitempty"`
+	// SkipVerify disables the verification of the signature of the policy.
+	SkipVerify bool `config:"skip_verify" yaml:"skip_verify,omitempty" json:"skip_verify,omitempty"`
+	// Token is the bearer token of the OCI registry or of the HTTP(S) server.
+	Token string `config:"token" yaml:"token,omitempty" json:"token,omitempty"`
+	// Insecure allows to pull the OCI artifact or the policy over HTTP.
+	Insecure bool `config:"insecure" yaml:"insecure,omitempty" json:"insecure,omitempty"`
+}
+
+// Validate validates settings of configuration.
+func (r *RemotePolicyConfig) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	switch r.Type {
+	case RemotePolicyGit, RemotePolicyOCI, RemotePolicyHTTP:
+	default:
+		return fmt.Errorf("remote policy type must be %q, %q or %q, got %q", RemotePolicyGit, RemotePolicyOCI, RemotePolicyHTTP, r.Type)
+	}
+	if r.URL == "" {
+		return errors.New("remote policy url is required")
+	}
+	if r.Path == "" {
+		return errors.New("remote policy path is required")
+	}
+	if r.Interval <= 0 {
+		return errors.New("remote policy interval must be positive")
+	}
+	if r.PGP == "" && !r.SkipVerify {
+		return errors.New("remote policy pgp key is required unless skip_verify is set")
+	}
+	return nil
+}
+
+// DefaultRemotePolicyConfig creates a config with the remote policy disabled.
+func DefaultRemotePolicyConfig() *RemotePolicyConfig {
+	return &RemotePolicyConfig{
+		Path:     DefaultRemotePolicyPath,
+		Interval: DefaultRemotePolicyInterval,
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+// SandboxConfig is the configuration of the sandboxing of the command components, the sandbox
+// of a component is defined by its specification.
+type SandboxConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+}
+
+// DefaultSandboxConfig creates a default sandbox configuration, the sandboxing is disabled.
+func DefaultSandboxConfig() *SandboxConfig {
+	return &SandboxConfig{
+		Enabled: false,
+	}
+}
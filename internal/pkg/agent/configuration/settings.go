@@ -6,6 +6,12 @@ package configuration
 
 import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/logrotation"
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -21,11 +27,34 @@ type SettingsConfig struct {
 	MonitoringConfig *monitoringCfg.MonitoringConfig `yaml:"monitoring" config:"monitoring" json:"monitoring"`
 	LoggingConfig    *logger.Config                  `yaml:"logging,omitempty" config:"logging,omitempty" json:"logging,omitempty"`
 	Upgrade          *UpgradeConfig                  `yaml:"upgrade" config:"upgrade" json:"upgrade"`
+	Shutdown         *ShutdownConfig                 `yaml:"shutdown" config:"shutdown" json:"shutdown"`
+	Metadata         *MetadataConfig                 `yaml:"metadata" config:"metadata" json:"metadata"`
+	ComponentModel   *ComponentModelConfig           `yaml:"component_model" config:"component_model" json:"component_model"`
+	ConfigCache      *ConfigCacheConfig              `yaml:"config_cache" config:"config_cache" json:"config_cache"`
+	CoreDumps        *CoreDumpsConfig                `yaml:"core_dumps" config:"core_dumps" json:"core_dumps"`
+	Sandbox          *SandboxConfig                  `yaml:"sandbox" config:"sandbox" json:"sandbox"`
+	Watchdog         *WatchdogConfig                 `yaml:"watchdog" config:"watchdog" json:"watchdog"`
+	StartupGates     *StartupGatesConfig             `yaml:"startup_gates" config:"startup_gates" json:"startup_gates"`
+	Limits           *LimitsConfig                   `yaml:"limits" config:"limits" json:"limits"`
+	Telemetry        *TelemetryConfig                `yaml:"telemetry" config:"telemetry" json:"telemetry"`
+	TrustStore       *truststore.Config              `yaml:"trust_store" config:"trust_store" json:"trust_store"`
+	CertExpiry       *CertExpiryConfig               `yaml:"cert_expiry" config:"cert_expiry" json:"cert_expiry"`
+	TLSRevocation    *revocation.Config              `yaml:"tls_revocation" config:"tls_revocation" json:"tls_revocation"`
+	Network          *netutil.Config                 `yaml:"network" config:"network" json:"network"`
+	DNS              *resolver.Config                `yaml:"dns" config:"dns" json:"dns"`
+	HA               *HAConfig                       `yaml:"ha" config:"ha" json:"ha"`
+	Handoff          *KubernetesHandoffConfig        `yaml:"kubernetes_handoff" config:"kubernetes_handoff" json:"kubernetes_handoff"`
+	DiskQuota        *DiskQuotaConfig                `yaml:"disk_quota" config:"disk_quota" json:"disk_quota"`
+	LogRotation      *logrotation.Config             `yaml:"log_rotation" config:"log_rotation" json:"log_rotation"`
+	ConfigDrift      *ConfigDriftConfig              `yaml:"config_drift" config:"config_drift" json:"config_drift"`
+	DataPlane        *DataPlaneConfig                `yaml:"data_plane" config:"data_plane" json:"data_plane"`
 
 	// standalone config
-	Reload              *ReloadConfig `config:"reload" yaml:"reload" json:"reload"`
-	Path                string        `config:"path" yaml:"path" json:"path"`
-	V1MonitoringEnabled bool          `config:"v1_monitoring_enabled" yaml:"v1_monitoring_enabled" json:"v1_monitoring_enabled"`
+	Reload              *ReloadConfig       `config:"reload" yaml:"reload" json:"reload"`
+	Path                string              `config:"path" yaml:"path" json:"path"`
+	Fragments           *config.Fragments   `config:"fragments" yaml:"fragments,omitempty" json:"fragments,omitempty"`
+	RemotePolicy        *RemotePolicyConfig `config:"remote_policy" yaml:"remote_policy" json:"remote_policy"`
+	V1MonitoringEnabled bool                `config:"v1_monitoring_enabled" yaml:"v1_monitoring_enabled" json:"v1_monitoring_enabled"`
 }
 
 // DefaultSettingsConfig creates a config with pre-set default values.
@@ -37,7 +66,29 @@ func DefaultSettingsConfig() *SettingsConfig {
 		MonitoringConfig:    monitoringCfg.DefaultConfig(),
 		GRPC:                DefaultGRPCConfig(),
 		Upgrade:             DefaultUpgradeConfig(),
+		Shutdown:            DefaultShutdownConfig(),
+		Metadata:            DefaultMetadataConfig(),
+		ComponentModel:      DefaultComponentModelConfig(),
+		ConfigCache:         DefaultConfigCacheConfig(),
+		CoreDumps:           DefaultCoreDumpsConfig(),
+		Sandbox:             DefaultSandboxConfig(),
+		Watchdog:            DefaultWatchdogConfig(),
+		StartupGates:        DefaultStartupGatesConfig(),
+		Limits:              DefaultLimitsConfig(),
+		Telemetry:           DefaultTelemetryConfig(),
+		TrustStore:          truststore.DefaultConfig(),
+		CertExpiry:          DefaultCertExpiryConfig(),
+		TLSRevocation:       revocation.DefaultConfig(),
+		Network:             netutil.DefaultConfig(),
+		DNS:                 resolver.DefaultConfig(),
+		HA:                  DefaultHAConfig(),
+		Handoff:             DefaultKubernetesHandoffConfig(),
+		DiskQuota:           DefaultDiskQuotaConfig(),
+		LogRotation:         logrotation.DefaultConfig(),
+		ConfigDrift:         DefaultConfigDriftConfig(),
+		DataPlane:           DefaultDataPlaneConfig(),
 		Reload:              DefaultReloadConfig(),
+		RemotePolicy:        DefaultRemotePolicyConfig(),
 		V1MonitoringEnabled: true,
 	}
 }
@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// maximum time the inputs have to stop before the shippers are stopped.
+	defaultShutdownInputsTimeout = 30 * time.Second
+
+	// maximum time the shippers have to flush their queues before the services are stopped.
+	defaultShutdownDrainTimeout = 30 * time.Second
+)
+
+// ShutdownConfig is the configuration of the shutdown of the running components.
+//
+// On shutdown the inputs are stopped first, then the shippers are stopped and flush the
+// events they have buffered, the service components are stopped last.
+type ShutdownConfig struct {
+	// InputsTimeout is the maximum time to wait for the inputs to stop.
+	InputsTimeout time.Duration `yaml:"inputs_timeout" config:"inputs_timeout" json:"inputs_timeout"`
+	// DrainTimeout is the maximum time to wait for the shippers to flush and stop.
+	DrainTimeout time.Duration `yaml:"drain_timeout" config:"drain_timeout" json:"drain_timeout"`
+}
+
+// DefaultShutdownConfig creates a default shutdown configuration.
+func DefaultShutdownConfig() *ShutdownConfig {
+	return &ShutdownConfig{
+		InputsTimeout: defaultShutdownInputsTimeout,
+		DrainTimeout:  defaultShutdownDrainTimeout,
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// maximum time the components wait for the startup gates before they are started anyway.
+	defaultStartupGatesTimeout = 5 * time.Minute
+
+	// interval between two checks of the closed startup gates.
+	defaultStartupGatesInterval = 5 * time.Second
+)
+
+// StartupGatesConfig is the configuration of the conditions the host must meet before the
+// components are started, so the inputs do not flood connection errors or send events with
+// a wrong timestamp during the early boot.
+type StartupGatesConfig struct {
+	// Timeout is the maximum time to wait for the gates, the components are started anyway
+	// once it expires. Zero waits until the gates open.
+	Timeout time.Duration `yaml:"timeout" config:"timeout" json:"timeout"`
+	// Interval is the interval between two checks of the closed gates.
+	Interval time.Duration            `yaml:"interval" config:"interval" json:"interval"`
+	Network  NetworkStartupGateConfig `yaml:"network" config:"network" json:"network"`
+	TimeSync TimeSyncGateConfig       `yaml:"time_sync" config:"time_sync" json:"time_sync"`
+	// Mounts are the paths that must be mount points.
+	Mounts []string `yaml:"mounts" config:"mounts" json:"mounts"`
+}
+
+// NetworkStartupGateConfig is the configuration of the gate waiting for the network.
+type NetworkStartupGateConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Hosts are the host:port addresses that must all accept TCP connections. When empty,
+	// the gate waits for an interface with a global unicast address.
+	Hosts []string `yaml:"hosts" config:"hosts" json:"hosts"`
+}
+
+// TimeSyncGateConfig is the configuration of the gate waiting for the clock to be synchronized.
+type TimeSyncGateConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+}
+
+// DefaultStartupGatesConfig creates a default startup gates configuration, all the gates are disabled.
+func DefaultStartupGatesConfig() *StartupGatesConfig {
+	return &StartupGatesConfig{
+		Timeout:  defaultStartupGatesTimeout,
+		Interval: defaultStartupGatesInterval,
+	}
+}
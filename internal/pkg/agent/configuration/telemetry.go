@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// interval between two usage reports.
+	defaultTelemetryInterval = 24 * time.Hour
+
+	// maximum number of reports kept in the spool while the endpoint cannot be reached.
+	defaultTelemetryMaxSpooled = 30
+)
+
+// TelemetryConfig is the configuration of the opt-in usage telemetry. The agent periodically
+// writes an anonymized report, the counts of the component types and of the error categories
+// along with its version and OS, to an on-disk spool and sends the spooled reports to the endpoint.
+type TelemetryConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Endpoint is the HTTP(S) URL the reports are POSTed to.
+	Endpoint string `yaml:"endpoint" config:"endpoint" json:"endpoint"`
+	// Interval is the time between two reports.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// MaxSpooled is the maximum number of reports kept while the endpoint cannot be reached, the
+	// oldest ones are dropped.
+	MaxSpooled int `yaml:"max_spooled" config:"max_spooled" json:"max_spooled"`
+}
+
+// Validate validates settings of configuration.
+func (t *TelemetryConfig) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	if t.Endpoint == "" {
+		return errors.New("telemetry endpoint is required")
+	}
+	u, err := url.Parse(t.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid telemetry endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("telemetry endpoint must be an http or https URL, got %q", t.Endpoint)
+	}
+	if t.Interval <= 0 {
+		return errors.New("telemetry interval must be positive")
+	}
+	if t.MaxSpooled <= 0 {
+		return errors.New("telemetry max_spooled must be positive")
+	}
+	return nil
+}
+
+// DefaultTelemetryConfig creates a config with the telemetry disabled.
+func DefaultTelemetryConfig() *TelemetryConfig {
+	return &TelemetryConfig{
+		Enabled:    false,
+		Interval:   defaultTelemetryInterval,
+		MaxSpooled: defaultTelemetryMaxSpooled,
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelemetryConfigValidate(t *testing.T) {
+	cfg := DefaultTelemetryConfig()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Enabled = true
+	assert.ErrorContains(t, cfg.Validate(), "telemetry endpoint is required")
+	cfg.Endpoint = "ftp://telemetry.example"
+	assert.ErrorContains(t, cfg.Validate(), "must be an http or https URL")
+	cfg.Endpoint = "https://telemetry.example/v1/agent"
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package configuration
+
+import "time"
+
+const (
+	// interval between two checks of the progress of the event loops.
+	defaultWatchdogInterval = time.Minute
+
+	// time without progress after which an event loop is considered deadlocked, it is larger
+	// than the default timeout of the computation of the component model which blocks the
+	// coordinator loop.
+	defaultWatchdogTimeout = 10 * time.Minute
+)
+
+// WatchdogConfig is the configuration of the watchdog detecting when the event loops of the
+// coordinator and of the runtime manager stop making progress.
+type WatchdogConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Interval is the interval between two checks of the event loops.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Timeout is the time without progress after which an event loop is considered deadlocked.
+	Timeout time.Duration `yaml:"timeout" config:"timeout" json:"timeout"`
+	// Restart exits the agent process once the goroutines are dumped, so the service manager
+	// starts it again.
+	Restart bool `yaml:"restart" config:"restart" json:"restart"`
+}
+
+// DefaultWatchdogConfig creates a default watchdog configuration.
+func DefaultWatchdogConfig() *WatchdogConfig {
+	return &WatchdogConfig{
+		Enabled:  true,
+		Interval: defaultWatchdogInterval,
+		Timeout:  defaultWatchdogTimeout,
+		Restart:  false,
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// serviceAccountFile is the file in the installation directory naming the account the Windows
+// service runs as, it is missing when the service runs as LocalSystem.
+const serviceAccountFile = ".service-account"
+
+// ServiceAccount is the account the service runs as on Windows, LocalSystem when its name is
+// empty. The account must hold the "Log on as a service" right.
+type ServiceAccount struct {
+	// Name is the name of the account, DOMAIN\user, .\user for a local account or DOMAIN\name$
+	// for a group Managed Service Account (gMSA).
+	Name string
+	// Password is the password of the account, empty for a gMSA, its password is managed by
+	// Active Directory.
+	Password string
+}
+
+// Managed returns true when the account is a group Managed Service Account.
+func (a ServiceAccount) Managed() bool {
+	return strings.HasSuffix(a.Name, "$")
+}
+
+// Validate validates the service account.
+func (a ServiceAccount) Validate() error {
+	if a.Name == "" {
+		if a.Password != "" {
+			return errors.New("a service account password needs a service account")
+		}
+		return nil
+	}
+	if runtime.GOOS != "windows" {
+		return errors.New("a service account is only supported on Windows")
+	}
+	if !strings.Contains(a.Name, `\`) && !strings.Contains(a.Name, "@") {
+		return errors.New(`the service account must be qualified by its domain, DOMAIN\user or .\user for a local account`)
+	}
+	if a.Managed() && a.Password != "" {
+		return errors.New("a group Managed Service Account has no password, its password is managed by Active Directory")
+	}
+	if !a.Managed() && a.Password == "" {
+		return errors.New("the service account needs a password, unless it is a group Managed Service Account")
+	}
+	return nil
+}
+
+// serviceAccount returns the account the service was installed to run as, empty for LocalSystem.
+func serviceAccount(topPath string) string {
+	if runtime.GOOS != "windows" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(topPath, serviceAccountFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceAccountValidate(t *testing.T) {
+	require.NoError(t, ServiceAccount{}.Validate(), "the service runs as LocalSystem by default")
+	assert.Error(t, ServiceAccount{Password: "changeme"}.Validate())
+
+	if runtime.GOOS != "windows" {
+		assert.Error(t, ServiceAccount{Name: `EXAMPLE\elastic-agent`, Password: "changeme"}.Validate(), "the service account is only supported on Windows")
+		return
+	}
+	assert.NoError(t, ServiceAccount{Name: `EXAMPLE\elastic-agent`, Password: "changeme"}.Validate())
+	assert.NoError(t, ServiceAccount{Name: `.\elastic-agent`, Password: "changeme"}.Validate())
+	assert.NoError(t, ServiceAccount{Name: `EXAMPLE\elastic-agent$`}.Validate())
+	assert.Error(t, ServiceAccount{Name: "elastic-agent", Password: "changeme"}.Validate(), "the account must be qualified")
+	assert.Error(t, ServiceAccount{Name: `EXAMPLE\elastic-agent`}.Validate(), "an account needs its password")
+	assert.Error(t, ServiceAccount{Name: `EXAMPLE\elastic-agent$`, Password: "changeme"}.Validate(), "a gMSA has no password")
+}
+
+func TestServiceAccountManaged(t *testing.T) {
+	assert.True(t, ServiceAccount{Name: `EXAMPLE\elastic-agent$`}.Managed())
+	assert.False(t, ServiceAccount{Name: `EXAMPLE\elastic-agent`}.Managed())
+}
+
+func TestServiceAccountFile(t *testing.T) {
+	topPath := t.TempDir()
+	assert.Empty(t, serviceAccount(topPath), "the service runs as LocalSystem without the account file")
+
+	require.NoError(t, os.WriteFile(filepath.Join(topPath, serviceAccountFile), []byte(`EXAMPLE\elastic-agent$`), 0644))
+	if runtime.GOOS != "windows" {
+		assert.Empty(t, serviceAccount(topPath), "the account file is ignored outside of Windows")
+		return
+	}
+	assert.Equal(t, `EXAMPLE\elastic-agent$`, serviceAccount(topPath))
+}
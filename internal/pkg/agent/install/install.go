@@ -13,8 +13,10 @@ import (
 
 	"github.com/otiai10/copy"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/integrity"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/lsm"
 )
 
 const (
@@ -22,7 +24,24 @@ const (
 )
 
 // Install installs Elastic Agent persistently on the system including creating and starting its service.
-func Install(cfgFile, topPath string) error {
+//
+// The recovery options configure how the service manager restarts the service when it fails, the
+// launchd options the domain the service is installed in on macOS and the account the one it runs
+// as on Windows.
+func Install(cfgFile, topPath string, recovery RecoveryOptions, launchd LaunchdOptions, account ServiceAccount) error {
+	err := recovery.Validate()
+	if err != nil {
+		return errors.New(err, "invalid service recovery options", errors.TypeConfig)
+	}
+	err = launchd.Validate()
+	if err != nil {
+		return errors.New(err, "invalid launchd options", errors.TypeConfig)
+	}
+	err = account.Validate()
+	if err != nil {
+		return errors.New(err, "invalid service account", errors.TypeConfig)
+	}
+
 	dir, err := findDirectory()
 	if err != nil {
 		return errors.New(err, "failed to discover the source directory for installation", errors.TypeFilesystem)
@@ -55,6 +74,15 @@ func Install(cfgFile, topPath string) error {
 			errors.M("source", dir), errors.M("destination", topPath))
 	}
 
+	// record the hash of the installed binary so it can be verified on start
+	err = integrity.WriteManifest(paths.VersionedHome(topPath))
+	if err != nil {
+		return errors.New(
+			err,
+			"failed to write binary integrity manifest",
+			errors.M("destination", topPath))
+	}
+
 	// place shell wrapper, if present on platform
 	if paths.ShellWrapperPath != "" {
 		pathDir := filepath.Dir(paths.ShellWrapperPath)
@@ -107,6 +135,31 @@ func Install(cfgFile, topPath string) error {
 		return err
 	}
 
+	// load the SELinux modules or AppArmor profiles shipped for the agent and its components
+	if kind := lsm.Detect(); kind != lsm.None {
+		_, err = lsm.Load(kind, paths.VersionedHome(topPath))
+		if err != nil {
+			return errors.New(
+				err,
+				fmt.Sprintf("failed to load the %s profiles", kind),
+				errors.M("destination", topPath))
+		}
+	}
+
+	// record the account the service runs as, the permissions give it access to the installation
+	switch {
+	case launchd.Domain == LaunchdDomainUser:
+		err = os.WriteFile(filepath.Join(topPath, launchdUserFile), []byte(launchd.User), 0644)
+	case account.Name != "":
+		err = os.WriteFile(filepath.Join(topPath, serviceAccountFile), []byte(account.Name), 0644)
+	}
+	if err != nil {
+		return errors.New(
+			err,
+			"failed to record the account of the service",
+			errors.M("destination", topPath))
+	}
+
 	// fix permissions
 	err = FixPermissions(topPath)
 	if err != nil {
@@ -117,7 +170,7 @@ func Install(cfgFile, topPath string) error {
 	}
 
 	// install service
-	svc, err := newService(topPath)
+	svc, err := newServiceInDomain(topPath, launchd, recovery, account)
 	if err != nil {
 		return err
 	}
@@ -128,6 +181,13 @@ func Install(cfgFile, topPath string) error {
 			fmt.Sprintf("failed to install service (%s)", paths.ServiceName),
 			errors.M("service", paths.ServiceName))
 	}
+	err = configureRecovery(recovery)
+	if err != nil {
+		return errors.New(
+			err,
+			fmt.Sprintf("failed to configure recovery of service (%s)", paths.ServiceName),
+			errors.M("service", paths.ServiceName))
+	}
 	return nil
 }
 
@@ -165,9 +225,18 @@ func StopService(topPath string) error {
 	return nil
 }
 
-// FixPermissions fixes the permissions on the installed system.
+// FixPermissions fixes the permissions on the installed system, the account the service runs as
+// keeps its access to the installation.
 func FixPermissions(topPath string) error {
-	return fixPermissions(topPath)
+	err := fixPermissions(topPath)
+	if err != nil {
+		return err
+	}
+	// a launch agent runs as its user, the installation belongs to this user
+	if username := launchdUser(topPath); username != "" {
+		return chownToLaunchdUser(topPath, username)
+	}
+	return nil
 }
 
 // findDirectory returns the directory to copy into the installation location.
@@ -11,7 +11,10 @@ import (
 	"os"
 	"path/filepath"
 
+	"golang.org/x/sys/windows/svc/mgr"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 )
 
@@ -35,8 +38,30 @@ func postInstall(topPath string) error {
 	return nil
 }
 
-// checkPackageInstall is used for unix based systems to see if the Elastic-Agent was installed through a package manager.
-// returns false
-func checkPackageInstall() bool {
-	return false
+// configureRecovery configures the service manager to restart the installed service when it fails.
+func configureRecovery(opts RecoveryOptions) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.New("failed to initiate service manager", err)
+	}
+	defer func() {
+		_ = m.Disconnect()
+	}()
+
+	s, err := m.OpenService(paths.ServiceName)
+	if err != nil {
+		return errors.New("failed to open windows service", err, errors.M("service", paths.ServiceName))
+	}
+	defer s.Close()
+
+	delays := opts.restartDelays()
+	actions := make([]mgr.RecoveryAction, 0, len(delays))
+	for _, delay := range delays {
+		actions = append(actions, mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: delay})
+	}
+	err = s.SetRecoveryActions(actions, uint32(opts.ResetPeriod.Seconds()))
+	if err != nil {
+		return errors.New("failed to set windows service recovery actions", err, errors.M("service", paths.ServiceName))
+	}
+	return nil
 }
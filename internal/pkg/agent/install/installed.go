@@ -10,6 +10,7 @@ import (
 
 	"github.com/kardianos/service"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 )
 
@@ -31,7 +32,7 @@ const (
 func Status(topPath string) (StatusType, string) {
 	expected := filepath.Join(topPath, paths.BinaryName)
 	status, reason := checkService(topPath)
-	if checkPackageInstall() {
+	if info.InstalledPackageManager() != info.PackageManagerNone {
 		if status == Installed {
 			return PackageInstall, "service running"
 		}
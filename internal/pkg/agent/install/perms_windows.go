@@ -8,19 +8,30 @@ package install
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"path/filepath"
 
 	"github.com/hectane/go-acl"
+	"github.com/hectane/go-acl/api"
 	"golang.org/x/sys/windows"
 )
 
-// fixPermissions fixes the permissions so only SYSTEM and Administrators have access to the files in the install path
+// fixPermissions fixes the permissions so only SYSTEM, Administrators and the account the service
+// runs as have access to the files in the install path
 func fixPermissions(topPath string) error {
-	return recursiveSystemAdminPermissions(topPath)
+	var accounts []*windows.SID
+	if account := serviceAccount(topPath); account != "" {
+		sid, _, _, err := windows.LookupSID("", account)
+		if err != nil {
+			return fmt.Errorf("failed to look up the service account %s: %w", account, err)
+		}
+		accounts = append(accounts, sid)
+	}
+	return recursiveSystemAdminPermissions(topPath, accounts...)
 }
 
-func recursiveSystemAdminPermissions(path string) error {
+func recursiveSystemAdminPermissions(path string, accounts ...*windows.SID) error {
 	return filepath.Walk(path, func(name string, info fs.FileInfo, err error) error {
 		if err == nil {
 			// first level doesn't inherit
@@ -28,7 +39,7 @@ func recursiveSystemAdminPermissions(path string) error {
 			if path == name {
 				inherit = false
 			}
-			err = systemAdministratorsOnly(name, inherit)
+			err = systemAdministratorsOnly(name, inherit, accounts...)
 		} else if errors.Is(err, fs.ErrNotExist) {
 			return nil
 		}
@@ -36,7 +47,9 @@ func recursiveSystemAdminPermissions(path string) error {
 	})
 }
 
-func systemAdministratorsOnly(path string, inherit bool) error {
+// systemAdministratorsOnly gives the full control of the path to SYSTEM, Administrators and the
+// accounts only.
+func systemAdministratorsOnly(path string, inherit bool, accounts ...*windows.SID) error {
 	// https://support.microsoft.com/en-us/help/243330/well-known-security-identifiers-in-windows-operating-systems
 	systemSID, err := windows.StringToSid("S-1-5-18")
 	if err != nil {
@@ -48,8 +61,12 @@ func systemAdministratorsOnly(path string, inherit bool) error {
 	}
 
 	// https://docs.microsoft.com/en-us/windows/win32/secauthz/access-mask
-	return acl.Apply(
-		path, true, inherit,
+	entries := []api.ExplicitAccess{
 		acl.GrantSid(0xF10F0000, systemSID), // full control of all acl's
-		acl.GrantSid(0xF10F0000, administratorsSID))
+		acl.GrantSid(0xF10F0000, administratorsSID),
+	}
+	for _, sid := range accounts {
+		entries = append(entries, acl.GrantSid(0xF10F0000, sid))
+	}
+	return acl.Apply(path, true, inherit, entries...)
 }
@@ -0,0 +1,245 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+)
+
+// preservedFile is a file of the state of the agent, named in the snapshot independently of the
+// installation so it can be restored by another version of the agent.
+type preservedFile struct {
+	name string
+	path func() string
+	// encrypted files are stored decrypted in the snapshot and encrypted again with the secret of
+	// the agent restoring them, the secret of the previous installation may not survive a re-install
+	// of the operating system.
+	encrypted bool
+}
+
+// preservedFiles are the agent identity, its enrollment and the state it replays after a restart.
+var preservedFiles = []preservedFile{
+	{name: "fleet.enc", path: paths.AgentConfigFile, encrypted: true},
+	{name: "state.enc", path: paths.AgentStateStoreFile, encrypted: true},
+	{name: "config_cache.enc", path: paths.AgentConfigCacheFile, encrypted: true},
+	{name: "state.yml", path: paths.AgentStateStoreYmlFile},
+	{name: "action_store.yml", path: paths.AgentActionStoreFile},
+	{name: "elastic-agent.yml", path: paths.ConfigFile},
+	{name: "capabilities.yml", path: paths.AgentCapabilitiesPath},
+}
+
+// preservedDirs are the directories of the state, the run directory holds the cursors of the
+// components, like the registries of the log inputs.
+var preservedDirs = []preservedFile{
+	{name: "run", path: paths.Run},
+	{name: "inputs.d", path: paths.AgentInputsDPath},
+}
+
+var (
+	loadEncrypted = func(path string) (io.ReadCloser, error) {
+		return storage.NewEncryptedDiskStore(path).Load()
+	}
+	saveEncrypted = func(path string, in io.Reader) error {
+		return storage.NewEncryptedDiskStore(path).Save(in)
+	}
+)
+
+// PreserveState writes a snapshot of the state of the agent to the archive at dst, it must be
+// taken while the agent is stopped. The snapshot holds the credentials of the agent decrypted, it
+// is only readable by its owner.
+func PreserveState(dst string) (err error) {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create the state snapshot: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write the state snapshot: %w", closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(dst)
+		}
+	}()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	for _, pf := range preservedFiles {
+		if err := preserveFile(tw, pf); err != nil {
+			return err
+		}
+	}
+	for _, pd := range preservedDirs {
+		if err := preserveDir(tw, pd); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write the state snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write the state snapshot: %w", err)
+	}
+	return nil
+}
+
+func preserveFile(tw *tar.Writer, pf preservedFile) error {
+	var rc io.ReadCloser
+	var err error
+	if pf.encrypted {
+		rc, err = loadEncrypted(pf.path())
+	} else {
+		rc, err = os.Open(pf.path())
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", pf.path(), err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", pf.path(), err)
+	}
+	return writeSnapshotEntry(tw, pf.name, data)
+}
+
+func preserveDir(tw *tar.Writer, pd preservedFile) error {
+	root := pd.path()
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// only the regular files are kept, the sockets and the pipes of the components are recreated
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return writeSnapshotEntry(tw, path.Join(pd.name, filepath.ToSlash(rel)), data)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	return nil
+}
+
+func writeSnapshotEntry(tw *tar.Writer, name string, data []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0600,
+		Size:     int64(len(data)),
+		Typeflag: tar.TypeReg,
+	})
+	if err == nil {
+		_, err = tw.Write(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s to the state snapshot: %w", name, err)
+	}
+	return nil
+}
+
+// RestoreState restores the state of the agent from the snapshot at src, before the agent is
+// started. The encrypted files are encrypted with the secret of this agent.
+func RestoreState(src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open the state snapshot: %w", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read the state snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the state snapshot: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := restoreEntry(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func restoreEntry(name string, r io.Reader) error {
+	for _, pf := range preservedFiles {
+		if name != pf.name {
+			continue
+		}
+		if pf.encrypted {
+			if err := os.MkdirAll(filepath.Dir(pf.path()), 0750); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", pf.path(), err)
+			}
+			if err := saveEncrypted(pf.path(), r); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", pf.path(), err)
+			}
+			return nil
+		}
+		return restoreFile(pf.path(), r)
+	}
+	for _, pd := range preservedDirs {
+		rel := strings.TrimPrefix(name, pd.name+"/")
+		if rel == name {
+			continue
+		}
+		// the entries can't be written outside of their directory
+		if rel == "" || path.IsAbs(rel) || rel != path.Clean(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("invalid entry %s in the state snapshot", name)
+		}
+		return restoreFile(filepath.Join(pd.path(), filepath.FromSlash(rel)), r)
+	}
+	return fmt.Errorf("unknown entry %s in the state snapshot", name)
+}
+
+func restoreFile(p string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", p, err)
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", p, err)
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", p, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// setTestPaths points the paths of the agent to a new directory, with the encrypted stores
+// replaced by plain files prefixed with "encrypted:".
+func setTestPaths(t *testing.T) string {
+	t.Helper()
+	top := t.TempDir()
+	prevTop, prevConfig := paths.Top(), paths.Config()
+	prevLoad, prevSave := loadEncrypted, saveEncrypted
+	t.Cleanup(func() {
+		paths.SetTop(prevTop)
+		paths.SetConfig(prevConfig)
+		loadEncrypted, saveEncrypted = prevLoad, prevSave
+	})
+	paths.SetTop(top)
+	paths.SetConfig(top)
+	loadEncrypted = func(path string) (io.ReadCloser, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data[len("encrypted:"):])), nil
+	}
+	saveEncrypted = func(path string, in io.Reader) error {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, append([]byte("encrypted:"), data...), 0600)
+	}
+	return top
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestPreserveRestoreState(t *testing.T) {
+	setTestPaths(t)
+	writeTestFile(t, paths.AgentConfigFile(), "encrypted:agent:\n  id: agent-1\n")
+	writeTestFile(t, paths.AgentStateStoreFile(), "encrypted:ack_token: token\n")
+	writeTestFile(t, paths.ConfigFile(), "fleet:\n  enabled: true\n")
+	writeTestFile(t, filepath.Join(paths.Run(), "filestream-default", "registry", "filebeat", "log.json"), `{"offset": 1024}`)
+
+	snapshot := filepath.Join(t.TempDir(), "state.tar.gz")
+	require.NoError(t, PreserveState(snapshot))
+	info, err := os.Stat(snapshot)
+	require.NoError(t, err)
+	if filepath.Separator == '/' {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "the snapshot holds the credentials")
+	}
+
+	// restored in a new installation
+	setTestPaths(t)
+	require.NoError(t, RestoreState(snapshot))
+	for path, content := range map[string]string{
+		paths.AgentConfigFile():     "encrypted:agent:\n  id: agent-1\n",
+		paths.AgentStateStoreFile(): "encrypted:ack_token: token\n",
+		paths.ConfigFile():          "fleet:\n  enabled: true\n",
+		filepath.Join(paths.Run(), "filestream-default", "registry", "filebeat", "log.json"): `{"offset": 1024}`,
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, content, string(data), path)
+	}
+	_, err = os.Stat(paths.AgentConfigCacheFile())
+	assert.ErrorIs(t, err, os.ErrNotExist, "the files missing from the snapshot are not created")
+}
+
+func TestRestoreStateInvalidEntry(t *testing.T) {
+	for _, name := range []string{"run/../../etc/passwd", "run/..", "/etc/passwd", "unknown.yml"} {
+		t.Run(name, func(t *testing.T) {
+			top := setTestPaths(t)
+			snapshot := filepath.Join(t.TempDir(), "state.tar.gz")
+			f, err := os.Create(snapshot)
+			require.NoError(t, err)
+			gz := gzip.NewWriter(f)
+			tw := tar.NewWriter(gz)
+			require.NoError(t, writeSnapshotEntry(tw, name, []byte("content")))
+			require.NoError(t, tw.Close())
+			require.NoError(t, gz.Close())
+			require.NoError(t, f.Close())
+
+			assert.Error(t, RestoreState(snapshot))
+			_, err = os.Stat(filepath.Join(filepath.Dir(top), "etc", "passwd"))
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
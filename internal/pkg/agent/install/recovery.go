@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// maximum number of recovery actions registered, the service manager repeats the last one
+	// for every following failure.
+	maxRecoveryActions = 8
+
+	defaultRestartDelay    = time.Second
+	defaultRestartMaxDelay = time.Minute
+	defaultResetPeriod     = 10 * time.Minute
+)
+
+// RecoveryOptions are the options used by the service manager to restart the service when it fails.
+//
+// They are applied on Windows. On macOS the restart delay is the minimum time between two launches of
+// the service, of at least 10 seconds, the other service managers always restart the service.
+type RecoveryOptions struct {
+	// RestartDelay is the delay before the service is restarted after its first failure,
+	// it doubles on each following failure.
+	RestartDelay time.Duration
+	// RestartMaxDelay caps the delay before the service is restarted.
+	RestartMaxDelay time.Duration
+	// ResetPeriod is the time without failure after which the failure count is reset.
+	ResetPeriod time.Duration
+}
+
+// DefaultRecoveryOptions returns the default recovery options of the service.
+func DefaultRecoveryOptions() RecoveryOptions {
+	return RecoveryOptions{
+		RestartDelay:    defaultRestartDelay,
+		RestartMaxDelay: defaultRestartMaxDelay,
+		ResetPeriod:     defaultResetPeriod,
+	}
+}
+
+// Validate validates the recovery options.
+func (o RecoveryOptions) Validate() error {
+	if o.RestartDelay <= 0 {
+		return fmt.Errorf("service restart delay must be positive, got %s", o.RestartDelay)
+	}
+	if o.RestartMaxDelay < o.RestartDelay {
+		return fmt.Errorf("service restart max delay (%s) must not be lower than the restart delay (%s)", o.RestartMaxDelay, o.RestartDelay)
+	}
+	if o.ResetPeriod < 0 {
+		return fmt.Errorf("service reset period must not be negative, got %s", o.ResetPeriod)
+	}
+	return nil
+}
+
+// restartDelays returns the delay of the restart performed after each consecutive failure.
+func (o RecoveryOptions) restartDelays() []time.Duration {
+	delays := make([]time.Duration, 0, maxRecoveryActions)
+	delay := o.RestartDelay
+	for len(delays) < maxRecoveryActions {
+		if delay >= o.RestartMaxDelay {
+			delays = append(delays, o.RestartMaxDelay)
+			break
+		}
+		delays = append(delays, delay)
+		delay *= 2
+	}
+	return delays
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryOptionsRestartDelays(t *testing.T) {
+	tests := map[string]struct {
+		opts     RecoveryOptions
+		expected []time.Duration
+	}{
+		"default": {
+			opts: DefaultRecoveryOptions(),
+			expected: []time.Duration{
+				time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+				16 * time.Second, 32 * time.Second, time.Minute,
+			},
+		},
+		"no backoff": {
+			opts:     RecoveryOptions{RestartDelay: 5 * time.Second, RestartMaxDelay: 5 * time.Second},
+			expected: []time.Duration{5 * time.Second},
+		},
+		"capped number of actions": {
+			opts: RecoveryOptions{RestartDelay: time.Second, RestartMaxDelay: time.Hour},
+			expected: []time.Duration{
+				time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+				16 * time.Second, 32 * time.Second, 64 * time.Second, 128 * time.Second,
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, tc.opts.Validate())
+			assert.Equal(t, tc.expected, tc.opts.restartDelays())
+		})
+	}
+}
+
+func TestRecoveryOptionsValidate(t *testing.T) {
+	assert.Error(t, RecoveryOptions{}.Validate())
+	assert.Error(t, RecoveryOptions{RestartDelay: time.Minute, RestartMaxDelay: time.Second}.Validate())
+	assert.Error(t, RecoveryOptions{RestartDelay: time.Second, RestartMaxDelay: time.Second, ResetPeriod: -time.Second}.Validate())
+}
@@ -5,8 +5,12 @@
 package install
 
 import (
+	"fmt"
+	"math"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/kardianos/service"
 
@@ -25,8 +29,66 @@ const (
 	// and the launchd sends SIGKILL after 5 secs which causes the beats processes to be left running orphaned
 	// depending on the shutdown timing.
 	darwinServiceExitTimeout = 60
+
+	// darwinServiceMinThrottleInterval is the default ThrottleInterval of launchd, in seconds, a
+	// service relaunched sooner after a crash is considered to be in a crash loop.
+	darwinServiceMinThrottleInterval = 10
+
+	// launchdUserFile is the file in the installation directory naming the user the launch agent
+	// was installed for, it is missing when the service is a launch daemon.
+	launchdUserFile = ".launchd-user"
+)
+
+// LaunchdDomain is the launchd domain the service is installed in on macOS.
+type LaunchdDomain string
+
+const (
+	// LaunchdDomainSystem installs a launch daemon, started at boot and run as root.
+	LaunchdDomainSystem LaunchdDomain = "system"
+	// LaunchdDomainUser installs a launch agent in the GUI session of a user, started when the
+	// user logs in and run as this user, for the collection of the data of the session.
+	LaunchdDomainUser LaunchdDomain = "user"
 )
 
+// Validate validates the launchd domain.
+func (d LaunchdDomain) Validate() error {
+	switch d {
+	case LaunchdDomainSystem:
+		return nil
+	case LaunchdDomainUser:
+		if runtime.GOOS != darwin {
+			return fmt.Errorf("the launchd domain %q is only supported on macOS", d)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown launchd domain %q, it must be %q or %q", d, LaunchdDomainSystem, LaunchdDomainUser)
+}
+
+// LaunchdOptions are the options of the launchd service on macOS, they are ignored on the other
+// platforms.
+type LaunchdOptions struct {
+	// Domain is the launchd domain the service is installed in.
+	Domain LaunchdDomain
+	// User is the user the launch agent is installed for in the user domain.
+	User string
+}
+
+// DefaultLaunchdOptions returns the default launchd options, a launch daemon.
+func DefaultLaunchdOptions() LaunchdOptions {
+	return LaunchdOptions{Domain: LaunchdDomainSystem}
+}
+
+// Validate validates the launchd options.
+func (o LaunchdOptions) Validate() error {
+	if err := o.Domain.Validate(); err != nil {
+		return err
+	}
+	if o.Domain == LaunchdDomainUser && (o.User == "" || o.User == "root") {
+		return fmt.Errorf("the launchd domain %q needs the non-root user the launch agent runs as", o.Domain)
+	}
+	return nil
+}
+
 // ExecutablePath returns the path for the installed Agents executable.
 func ExecutablePath(topPath string) string {
 	exec := filepath.Join(topPath, paths.BinaryName)
@@ -36,7 +98,42 @@ func ExecutablePath(topPath string) string {
 	return exec
 }
 
+// newService returns the installed service, in the launchd domain it was installed in.
 func newService(topPath string) (service.Service, error) {
+	if username := launchdUser(topPath); username != "" {
+		return newLaunchAgent(topPath, username, DefaultRecoveryOptions())
+	}
+	return newSystemService(topPath, DefaultRecoveryOptions(), ServiceAccount{})
+}
+
+// newServiceInDomain returns the service to install in the launchd domain, the domain is
+// ignored on the other platforms.
+func newServiceInDomain(topPath string, launchd LaunchdOptions, recovery RecoveryOptions, account ServiceAccount) (service.Service, error) {
+	if launchd.Domain == LaunchdDomainUser {
+		return newLaunchAgent(topPath, launchd.User, recovery)
+	}
+	return newSystemService(topPath, recovery, account)
+}
+
+// launchdUser returns the user the launch agent was installed for, empty for a system service.
+func launchdUser(topPath string) string {
+	if runtime.GOOS != darwin {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(topPath, launchdUserFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// throttleInterval returns the ThrottleInterval of launchd, the minimum time in seconds between two
+// launches of the service, from the restart delay.
+func throttleInterval(recovery RecoveryOptions) int {
+	return int(math.Max(darwinServiceMinThrottleInterval, math.Ceil(recovery.RestartDelay.Seconds())))
+}
+
+func newSystemService(topPath string, recovery RecoveryOptions, account ServiceAccount) (service.Service, error) {
 	cfg := &service.Config{
 		Name:             paths.ServiceName,
 		DisplayName:      ServiceDisplayName,
@@ -47,26 +144,34 @@ func newService(topPath string) (service.Service, error) {
 			// Linux (systemd) always restart on failure
 			"Restart": "always",
 
-			// Windows setup restart on failure
+			// Windows setup restart on failure, the recovery actions are
+			// configured after the installation with the install options
 			"OnFailure":              "restart",
-			"OnFailureDelayDuration": "1s",
-			"OnFailureResetPeriod":   10,
+			"OnFailureDelayDuration": defaultRestartDelay.String(),
+			"OnFailureResetPeriod":   int(defaultResetPeriod.Seconds()),
 		},
 	}
 
-	if runtime.GOOS == "darwin" {
+	if account.Name != "" {
+		// Windows runs the service as the account instead of LocalSystem
+		cfg.UserName = account.Name
+		cfg.Option["Password"] = account.Password
+	}
+
+	if runtime.GOOS == darwin {
 		// The github.com/kardianos/service library doesn't support ExitTimeOut in their prebuilt template.
 		// This option allows to pass our own template for the launch daemon plist, which is a copy
 		// of the prebuilt template with added ExitTimeOut option
 		cfg.Option["LaunchdConfig"] = darwinLaunchdConfig
 		cfg.Option["ExitTimeOut"] = darwinServiceExitTimeout
+		cfg.Option["ThrottleInterval"] = throttleInterval(recovery)
 	}
 
 	return service.New(nil, cfg)
 }
 
 // A copy of the launchd plist template from github.com/kardianos/service
-// with added .Config.Option.ExitTimeOut option
+// with added .Config.Option.ExitTimeOut and .Config.Option.ThrottleInterval options
 const darwinLaunchdConfig = `<?xml version='1.0' encoding='UTF-8'?>
 <!DOCTYPE plist PUBLIC "-//Apple Computer//DTD PLIST 1.0//EN"
 "http://www.apple.com/DTDs/PropertyList-1.0.dtd" >
@@ -87,6 +192,8 @@ const darwinLaunchdConfig = `<?xml version='1.0' encoding='UTF-8'?>
     <string>{{html .ChRoot}}</string>{{end}}
     {{if .Config.Option.ExitTimeOut}}<key>ExitTimeOut</key>
     <integer>{{html .Config.Option.ExitTimeOut}}</integer>{{end}}
+    {{if .Config.Option.ThrottleInterval}}<key>ThrottleInterval</key>
+    <integer>{{html .Config.Option.ThrottleInterval}}</integer>{{end}}
     {{if .WorkingDirectory}}<key>WorkingDirectory</key>
     <string>{{html .WorkingDirectory}}</string>{{end}}
     <key>SessionCreate</key>
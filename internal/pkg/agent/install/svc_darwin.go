@@ -0,0 +1,238 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build darwin
+
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/kardianos/service"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// launchAgent is the Elastic Agent service installed as a launch agent in the GUI domain of a user,
+// the service library only installs the launch agents of the user running the installation.
+type launchAgent struct {
+	topPath  string
+	username string
+	uid      int
+	gid      int
+	home     string
+	recovery RecoveryOptions
+}
+
+func newLaunchAgent(topPath, username string, recovery RecoveryOptions) (service.Service, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the user %s of the launch agent: %w", username, err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid %s of the user %s: %w", u.Uid, username, err)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid %s of the user %s: %w", u.Gid, username, err)
+	}
+	return &launchAgent{
+		topPath:  topPath,
+		username: username,
+		uid:      uid,
+		gid:      gid,
+		home:     u.HomeDir,
+		recovery: recovery,
+	}, nil
+}
+
+func (a *launchAgent) plistPath() string {
+	return filepath.Join(a.home, "Library", "LaunchAgents", paths.ServiceName+".plist")
+}
+
+func (a *launchAgent) domain() string {
+	return fmt.Sprintf("gui/%d", a.uid)
+}
+
+func (a *launchAgent) target() string {
+	return a.domain() + "/" + paths.ServiceName
+}
+
+func (a *launchAgent) launchctl(args ...string) (string, error) {
+	out, err := exec.Command("launchctl", args...).CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("launchctl %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// loggedIn returns true when the user has a GUI session the launch agent can be loaded in, it is
+// loaded by launchd when the user logs in otherwise.
+func (a *launchAgent) loggedIn() bool {
+	_, err := a.launchctl("print", a.domain())
+	return err == nil
+}
+
+// Install writes the plist of the launch agent, owned by the user, and loads it when the user is
+// logged in.
+func (a *launchAgent) Install() error {
+	var plist bytes.Buffer
+	err := launchAgentPlist.Execute(&plist, map[string]interface{}{
+		"Label":            paths.ServiceName,
+		"Program":          ExecutablePath(a.topPath),
+		"WorkingDirectory": a.topPath,
+		"ExitTimeOut":      darwinServiceExitTimeout,
+		"ThrottleInterval": throttleInterval(a.recovery),
+		"LogPath":          filepath.Join(a.home, "Library", "Logs", paths.ServiceName),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.plistPath()), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(a.plistPath(), plist.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Chown(a.plistPath(), a.uid, a.gid); err != nil {
+		return err
+	}
+	if !a.loggedIn() {
+		return nil
+	}
+	_, err = a.launchctl("bootstrap", a.domain(), a.plistPath())
+	return err
+}
+
+// Uninstall unloads the launch agent and removes its plist.
+func (a *launchAgent) Uninstall() error {
+	if a.loggedIn() {
+		_, _ = a.launchctl("bootout", a.target())
+	}
+	return os.Remove(a.plistPath())
+}
+
+// Start loads the launch agent, it is started at load.
+func (a *launchAgent) Start() error {
+	if !a.loggedIn() {
+		return fmt.Errorf("the user %s is not logged in, the launch agent starts at the next login", a.username)
+	}
+	if _, err := a.launchctl("print", a.target()); err == nil {
+		_, err = a.launchctl("kickstart", a.target())
+		return err
+	}
+	_, err := a.launchctl("bootstrap", a.domain(), a.plistPath())
+	return err
+}
+
+// Stop unloads the launch agent, launchd would relaunch it otherwise.
+func (a *launchAgent) Stop() error {
+	_, err := a.launchctl("bootout", a.target())
+	return err
+}
+
+// Restart kills the running launch agent and starts it again.
+func (a *launchAgent) Restart() error {
+	_, err := a.launchctl("kickstart", "-k", a.target())
+	return err
+}
+
+// Status returns unknown when the launch agent isn't installed, running when it runs in the
+// session of the user and stopped otherwise.
+func (a *launchAgent) Status() (service.Status, error) {
+	if _, err := os.Stat(a.plistPath()); err != nil {
+		return service.StatusUnknown, service.ErrNotInstalled
+	}
+	out, err := a.launchctl("print", a.target())
+	if err != nil || !strings.Contains(out, "state = running") {
+		return service.StatusStopped, nil
+	}
+	return service.StatusRunning, nil
+}
+
+// Run is not used, the Elastic Agent doesn't run through the service library on macOS.
+func (a *launchAgent) Run() error {
+	return fmt.Errorf("running the launch agent through the service library is not supported")
+}
+
+func (a *launchAgent) Logger(_ chan<- error) (service.Logger, error) {
+	return service.ConsoleLogger, nil
+}
+
+func (a *launchAgent) SystemLogger(_ chan<- error) (service.Logger, error) {
+	return service.ConsoleLogger, nil
+}
+
+func (a *launchAgent) String() string {
+	return ServiceDisplayName
+}
+
+func (a *launchAgent) Platform() string {
+	return "darwin-launchd-agent"
+}
+
+// chownToLaunchdUser gives the installation to the user the launch agent runs as.
+func chownToLaunchdUser(topPath, username string) error {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("failed to look up the user %s of the launch agent: %w", username, err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return err
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(topPath, func(name string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(name, uid, gid)
+	})
+}
+
+var launchAgentPlist = template.Must(template.New("plist").Parse(`<?xml version='1.0' encoding='UTF-8'?>
+<!DOCTYPE plist PUBLIC "-//Apple Computer//DTD PLIST 1.0//EN"
+"http://www.apple.com/DTDs/PropertyList-1.0.dtd" >
+<plist version='1.0'>
+  <dict>
+    <key>Label</key>
+    <string>{{html .Label}}</string>
+    <key>ProgramArguments</key>
+    <array>
+      <string>{{html .Program}}</string>
+    </array>
+    <key>WorkingDirectory</key>
+    <string>{{html .WorkingDirectory}}</string>
+    <key>LimitLoadToSessionType</key>
+    <string>Aqua</string>
+    <key>ExitTimeOut</key>
+    <integer>{{.ExitTimeOut}}</integer>
+    <key>ThrottleInterval</key>
+    <integer>{{.ThrottleInterval}}</integer>
+    <key>KeepAlive</key>
+    <true/>
+    <key>RunAtLoad</key>
+    <true/>
+    <key>ProcessType</key>
+    <string>Background</string>
+
+    <key>StandardOutPath</key>
+    <string>{{html .LogPath}}.out.log</string>
+    <key>StandardErrorPath</key>
+    <string>{{html .LogPath}}.err.log</string>
+  </dict>
+</plist>
+`))
@@ -0,0 +1,23 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !darwin
+
+package install
+
+import (
+	"errors"
+
+	"github.com/kardianos/service"
+)
+
+var errLaunchAgentNotSupported = errors.New("launch agents are only supported on macOS")
+
+func newLaunchAgent(_, _ string, _ RecoveryOptions) (service.Service, error) {
+	return nil, errLaunchAgentNotSupported
+}
+
+func chownToLaunchdUser(_, _ string) error {
+	return errLaunchAgentNotSupported
+}
@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottleInterval(t *testing.T) {
+	assert.Equal(t, 10, throttleInterval(DefaultRecoveryOptions()), "launchd relaunches a service after 10 seconds at the earliest")
+	assert.Equal(t, 30, throttleInterval(RecoveryOptions{RestartDelay: 30 * time.Second}))
+	assert.Equal(t, 16, throttleInterval(RecoveryOptions{RestartDelay: 15500 * time.Millisecond}))
+}
+
+func TestLaunchdOptionsValidate(t *testing.T) {
+	require.NoError(t, DefaultLaunchdOptions().Validate())
+	assert.Error(t, LaunchdOptions{Domain: "session"}.Validate())
+	assert.Error(t, LaunchdOptions{}.Validate())
+
+	user := LaunchdOptions{Domain: LaunchdDomainUser, User: "elastic"}
+	if runtime.GOOS != darwin {
+		assert.Error(t, user.Validate(), "the user domain only exists on macOS")
+		return
+	}
+	assert.NoError(t, user.Validate())
+	assert.Error(t, LaunchdOptions{Domain: LaunchdDomainUser}.Validate(), "the user domain needs a user")
+	assert.Error(t, LaunchdOptions{Domain: LaunchdDomainUser, User: "root"}.Validate(), "the launch agent of root is a launch daemon")
+}
+
+func TestLaunchdUser(t *testing.T) {
+	topPath := t.TempDir()
+	assert.Empty(t, launchdUser(topPath), "a launch daemon is installed without the user file")
+
+	require.NoError(t, os.WriteFile(filepath.Join(topPath, launchdUserFile), []byte("elastic\n"), 0644))
+	if runtime.GOOS != darwin {
+		assert.Empty(t, launchdUser(topPath), "the user file is ignored outside of macOS")
+		return
+	}
+	assert.Equal(t, "elastic", launchdUser(topPath))
+}
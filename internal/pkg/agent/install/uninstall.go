@@ -175,11 +175,6 @@ func uninstallComponents(ctx context.Context, cfgFile string) error {
 		return err
 	}
 
-	// nothing to remove
-	if len(comps) == 0 {
-		return nil
-	}
-
 	// check caps so we don't try uninstalling things that were already
 	// prevented from installing
 	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), log)
@@ -198,6 +193,15 @@ func uninstallComponents(ctx context.Context, cfgFile string) error {
 		}
 	}
 
+	// the hooks clean up after the components that ran, whether they are still in the policy or not
+	failures := runUninstallHooks(ctx, log, specs.UninstallHookSpecs(), comprt.RunUninstallHook)
+	for _, err := range failures {
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", err))
+	}
+	if len(failures) > 0 {
+		os.Stderr.WriteString(fmt.Sprintf("%d uninstall hook(s) failed, the artifacts they remove may remain on this host\n", len(failures)))
+	}
+
 	return nil
 }
 
@@ -205,6 +209,28 @@ func uninstallComponent(ctx context.Context, log *logp.Logger, comp component.Co
 	return comprt.UninstallService(ctx, log, comp)
 }
 
+type uninstallHookFunc func(ctx context.Context, log *logger.Logger, spec component.InputRuntimeSpec, hook component.UninstallHookSpec) error
+
+// runUninstallHooks runs the uninstall hooks of the inputs and returns their failures. The inputs sharing a
+// binary usually define the same hooks, a hook is only run once per binary.
+func runUninstallHooks(ctx context.Context, log *logger.Logger, specs []component.InputRuntimeSpec, run uninstallHookFunc) []error {
+	var failures []error
+	done := make(map[string]bool)
+	for _, spec := range specs {
+		for _, hook := range spec.Spec.UninstallHooks {
+			key := spec.BinaryPath + "\x00" + hook.Name
+			if done[key] {
+				continue
+			}
+			done[key] = true
+			if err := run(ctx, log, spec, hook); err != nil {
+				failures = append(failures, fmt.Errorf("uninstall hook %q of %s failed: %w", hook.Name, spec.BinaryName, err))
+			}
+		}
+	}
+	return failures
+}
+
 func serviceComponentsFromConfig(specs component.RuntimeSpecs, cfg *config.Config) ([]component.Component, error) {
 	mm, err := cfg.ToMapStr()
 	if err != nil {
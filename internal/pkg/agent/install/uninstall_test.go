@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package install
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestRunUninstallHooks(t *testing.T) {
+	hooks := []component.UninstallHookSpec{
+		{Name: "kernel-module", Args: []string{"cleanup", "kernel-module"}},
+		{Name: "firewall", Args: []string{"cleanup", "firewall"}},
+	}
+	specs := []component.InputRuntimeSpec{
+		// the inputs of a binary share its hooks
+		{InputType: "auditd", BinaryName: "auditbeat", BinaryPath: "/components/auditbeat", Spec: component.InputSpec{UninstallHooks: hooks}},
+		{InputType: "file_integrity", BinaryName: "auditbeat", BinaryPath: "/components/auditbeat", Spec: component.InputSpec{UninstallHooks: hooks}},
+		{InputType: "packet", BinaryName: "packetbeat", BinaryPath: "/components/packetbeat", Spec: component.InputSpec{UninstallHooks: hooks[:1]}},
+	}
+
+	var ran []string
+	failures := runUninstallHooks(context.Background(), logger.NewWithoutConfig(""), specs,
+		func(_ context.Context, _ *logger.Logger, spec component.InputRuntimeSpec, hook component.UninstallHookSpec) error {
+			ran = append(ran, spec.BinaryName+"/"+hook.Name)
+			if hook.Name == "firewall" {
+				return errors.New("exit status 1")
+			}
+			return nil
+		})
+
+	assert.Equal(t, []string{"auditbeat/kernel-module", "auditbeat/firewall", "packetbeat/kernel-module"}, ran,
+		"every hook runs once per binary even when another one fails")
+	require.Len(t, failures, 1)
+	assert.EqualError(t, failures[0], `uninstall hook "firewall" of auditbeat failed: exit status 1`)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 )
 
 // MigrateToEncryptedConfig will copy content from the store specified in unencryptedConfigPath
@@ -62,3 +63,35 @@ func MigrateToEncryptedConfig(l *logp.Logger, unencryptedConfigPath string, encr
 
 	return nil
 }
+
+// MigrateToEncryptedStore migrates the store specified in unencryptedPath to the encrypted store specified in
+// encryptedPath like MigrateToEncryptedConfig, then removes the unencrypted store so no plain text copy is left
+// on disk. The unencrypted store is removed as well when the encrypted store already exists, as it supersedes it.
+func MigrateToEncryptedStore(l *logp.Logger, unencryptedPath string, encryptedPath string) error {
+	if err := MigrateToEncryptedConfig(l, unencryptedPath, encryptedPath); err != nil {
+		return err
+	}
+
+	encStat, err := os.Stat(encryptedPath)
+	if err != nil || encStat.Size() == 0 {
+		// nothing was migrated, keep the unencrypted store if any
+		return nil
+	}
+	if err := os.Remove(unencryptedPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errors.New(err, fmt.Sprintf("error removing unencrypted store %q", unencryptedPath))
+	}
+	return nil
+}
+
+// MigrateActionStore migrates the unencrypted action store of the previous versions to the encrypted state
+// store when the state store does not exist yet, then removes the action store. An action store left next to
+// an existing state store is stale and removed as well.
+func MigrateActionStore(l *logp.Logger, actionStorePath string, stateStorePath string) error {
+	if _, err := store.NewStateStoreWithMigration(l, actionStorePath, stateStorePath); err != nil {
+		return errors.New(err, fmt.Sprintf("error migrating action store %q to %q", actionStorePath, stateStorePath))
+	}
+	if err := os.Remove(actionStorePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errors.New(err, fmt.Sprintf("error removing action store %q", actionStorePath))
+	}
+	return nil
+}
@@ -154,6 +154,82 @@ func TestMigrateToEncryptedConfig(t *testing.T) {
 	}
 }
 
+func TestMigrateToEncryptedStore(t *testing.T) {
+	testcases := []struct {
+		name                     string
+		unencryptedConfig        configfile
+		encryptedConfig          configfile
+		expectedEncryptedContent []byte
+	}{
+		{
+			name:              "no files, no migration",
+			unencryptedConfig: configfile{name: "policy.yml"},
+			encryptedConfig:   configfile{name: "policy.enc"},
+		},
+		{
+			name:                     "unencrypted exists encrypted does not -> migrated and removed",
+			unencryptedConfig:        configfile{name: "policy.yml", create: true, content: []byte("outputs: {}"), permissions: 0600},
+			encryptedConfig:          configfile{name: "policy.enc"},
+			expectedEncryptedContent: []byte("outputs: {}"),
+		},
+		{
+			name:                     "both unencrypted and encrypted exist -> unencrypted removed",
+			unencryptedConfig:        configfile{name: "policy.yml", create: true, content: []byte("outputs: {}"), permissions: 0600},
+			encryptedConfig:          configfile{name: "policy.enc", create: true, content: []byte("inputs: []"), permissions: 0600},
+			expectedEncryptedContent: []byte("inputs: []"),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			top := t.TempDir()
+			paths.SetTop(top)
+			require.NoError(t, secret.CreateAgentSecret(secret.WithVaultPath(paths.AgentVaultPath())))
+
+			createAndPersistStore(t, top, tc.unencryptedConfig, false)
+			encryptedStore := createAndPersistStore(t, top, tc.encryptedConfig, true)
+			absUnencryptedFile := path.Join(top, tc.unencryptedConfig.name)
+			absEncryptedFile := path.Join(top, tc.encryptedConfig.name)
+
+			err := MigrateToEncryptedStore(logp.NewLogger("test_migrate_store"), absUnencryptedFile, absEncryptedFile)
+			require.NoError(t, err)
+
+			assert.NoFileExists(t, absUnencryptedFile)
+			if len(tc.expectedEncryptedContent) == 0 {
+				assert.NoFileExists(t, absEncryptedFile)
+				return
+			}
+			readCloser, err := encryptedStore.Load()
+			require.NoError(t, err)
+			defer readCloser.Close()
+			actualEncryptedContent, err := io.ReadAll(readCloser)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedEncryptedContent, actualEncryptedContent)
+		})
+	}
+}
+
+func TestMigrateActionStore(t *testing.T) {
+	top := t.TempDir()
+	paths.SetTop(top)
+	require.NoError(t, secret.CreateAgentSecret(secret.WithVaultPath(paths.AgentVaultPath())))
+
+	actionStore := path.Join(top, "action_store.yml")
+	stateStore := path.Join(top, "state.enc")
+	log := logp.NewLogger("test_migrate_action_store")
+
+	// no action store, nothing to migrate
+	require.NoError(t, MigrateActionStore(log, actionStore, stateStore))
+	assert.NoFileExists(t, actionStore)
+
+	// an action store left next to the state store is removed
+	createAndPersistStore(t, top, configfile{name: "state.enc", create: true, content: []byte("ack_token: token"), permissions: 0600}, true)
+	createAndPersistStore(t, top, configfile{name: "action_store.yml", create: true, content: []byte("[]"), permissions: 0600}, false)
+	require.NoError(t, MigrateActionStore(log, actionStore, stateStore))
+	assert.NoFileExists(t, actionStore)
+	assert.FileExists(t, stateStore)
+}
+
 func TestErrorMigrateToEncryptedConfig(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("cannot reliably reproduce permission errors on windows")
@@ -1016,9 +1016,9 @@ func Insert(a *AST, node Node, to Selector) error {
 }
 
 // Map transforms the AST into a map[string]interface{} and will abort and return any errors related
-// to type conversion.
+// to type conversion. Equal strings share a single copy in the map.
 func (a *AST) Map() (map[string]interface{}, error) {
-	m := &MapVisitor{}
+	m := &MapVisitor{strs: make(map[string]string)}
 	a.Accept(m)
 	mapped, ok := m.Content.(map[string]interface{})
 	if !ok {
@@ -7,7 +7,9 @@ package transpiler
 import (
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
+	"unsafe"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
@@ -614,6 +616,34 @@ func TestASTToMapStr(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(m, expected))
 }
 
+func TestASTToMapStrInternsStrings(t *testing.T) {
+	// the values are built at runtime so each one is its own copy
+	ns1 := strings.Repeat("kube-system", 1)
+	ns2 := strings.Join([]string{"kube", "system"}, "-")
+	require.NotEqual(t, stringData(ns1), stringData(ns2))
+
+	ast, err := NewAST(map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{"namespace": ns1},
+			map[string]interface{}{"namespace": ns2},
+		},
+	})
+	require.NoError(t, err)
+
+	m, err := ast.Map()
+	require.NoError(t, err)
+
+	inputs := m["inputs"].([]interface{})
+	v1 := inputs[0].(map[string]interface{})["namespace"].(string)
+	v2 := inputs[1].(map[string]interface{})["namespace"].(string)
+	assert.Equal(t, "kube-system", v1)
+	assert.Equal(t, stringData(v1), stringData(v2))
+}
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
 func TestHash(t *testing.T) {
 	tests := map[string]struct {
 		c1    *AST
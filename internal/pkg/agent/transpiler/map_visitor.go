@@ -8,11 +8,15 @@ package transpiler
 // to a YAML document.
 type MapVisitor struct {
 	Content interface{}
+
+	// strs interns the strings of the map when set, the values rendered
+	// from the same variables are equal but each one is its own copy.
+	strs map[string]string
 }
 
 // OnStr is called when we visit a StrVal.
 func (m *MapVisitor) OnStr(v string) {
-	m.Content = v
+	m.Content = m.intern(v)
 }
 
 // OnInt is called when we visit a IntVal.
@@ -39,7 +43,7 @@ func (m *MapVisitor) OnBool(v bool) {
 func (m *MapVisitor) OnDict() VisitorDict {
 	newMap := make(map[string]interface{})
 	m.Content = newMap
-	return &MapVisitorDict{Content: newMap}
+	return &MapVisitorDict{Content: newMap, strs: m.strs}
 }
 
 // OnList is called when we visit a List and we return a VisitorList.
@@ -52,6 +56,7 @@ func (m *MapVisitor) OnList() VisitorList {
 type MapVisitorDict struct {
 	Content        map[string]interface{}
 	lastVisitedKey string
+	strs           map[string]string
 }
 
 // OnKey is called when we visit a key of a Dict.
@@ -67,7 +72,7 @@ func (m *MapVisitorDict) OnValue(v Visitor) {
 
 // Visitor returns a MapVisitor.
 func (m *MapVisitorDict) Visitor() Visitor {
-	return &MapVisitor{}
+	return &MapVisitor{strs: m.strs}
 }
 
 // OnComplete is called when you are done visiting the current Dict.
@@ -89,5 +94,16 @@ func (m *MapVisitorList) OnValue(v Visitor) {
 
 // Visitor return a visitor.
 func (m *MapVisitorList) Visitor() Visitor {
-	return &MapVisitor{}
+	return &MapVisitor{strs: m.MapVisitor.strs}
+}
+
+func (m *MapVisitor) intern(v string) string {
+	if m.strs == nil {
+		return v
+	}
+	if s, ok := m.strs[v]; ok {
+		return s
+	}
+	m.strs[v] = v
+	return v
 }
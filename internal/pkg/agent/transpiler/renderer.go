@@ -0,0 +1,179 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transpiler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Renderer renders the inputs with the sets of vars like RenderInputs. It keeps each input rendered
+// with each set of vars along with the variables read to render it, the input is only rendered again
+// when it changed or when one of those variables changed. On a node running many pods a change of
+// the variables of one pod only renders the inputs of that pod again.
+//
+// The zero value is ready to use and a Renderer is safe for concurrent use.
+type Renderer struct {
+	mx       sync.Mutex
+	rendered map[renderKey]*renderedInput
+}
+
+// renderKey identifies an input rendered with a set of vars.
+type renderKey struct {
+	// input is the hash of the input before rendering.
+	input string
+	// vars is the ID of the set of vars.
+	vars string
+}
+
+// renderedInput is an input rendered with a set of vars.
+type renderedInput struct {
+	// deps are the variables read to render the input.
+	deps *varDeps
+	// processorsKey and processors are the processors of the vars, compared when they were attached.
+	processorsKey string
+	processors    Processors
+	// hash is the hash of the rendered input before its ID is updated, it deduplicates the inputs.
+	hash string
+	// node is the rendered input, nil when a condition or a missing variable removed it.
+	node Node
+}
+
+// unchanged returns true when rendering the input with vars gives the same result.
+func (r *renderedInput) unchanged(vars *Vars) bool {
+	if !vars.unchanged(r.deps) {
+		return false
+	}
+	if r.deps.processors {
+		return r.processorsKey == vars.processorsKey && reflect.DeepEqual(r.processors, vars.processors)
+	}
+	return true
+}
+
+// Render renders the inputs with each set of vars.
+func (r *Renderer) Render(inputs Node, varsArray []*Vars) (Node, error) {
+	l, ok := inputs.Value().(*List)
+	if !ok {
+		return nil, fmt.Errorf("inputs must be an array")
+	}
+	var dicts []*Dict
+	var hashes []string
+	for _, node := range l.Value().([]Node) {
+		dict, ok := node.(*Dict)
+		if !ok {
+			continue
+		}
+		dicts = append(dicts, dict)
+		hashes = append(hashes, string(dict.Hash()))
+	}
+
+	r.mx.Lock()
+	previous := r.rendered
+	r.mx.Unlock()
+
+	rendered := make(map[renderKey]*renderedInput, len(previous))
+	seen := make(map[string]bool)
+	var nInputs []Node
+	for _, vars := range varsArray {
+		for i, dict := range dicts {
+			key := renderKey{input: hashes[i], vars: vars.ID()}
+			// the sets of vars without an ID share the same key, the input
+			// rendered with the previous one is only reused when unchanged
+			input, ok := rendered[key]
+			if !ok {
+				input, ok = previous[key]
+			}
+			if !ok || !input.unchanged(vars) {
+				var err error
+				input, err = renderInput(dict, vars)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if !input.deps.fetched {
+				rendered[key] = input
+			}
+			if input.node == nil || seen[input.hash] {
+				continue
+			}
+			seen[input.hash] = true
+			nInputs = append(nInputs, input.node)
+		}
+	}
+
+	r.mx.Lock()
+	r.rendered = rendered
+	r.mx.Unlock()
+	return NewList(nInputs), nil
+}
+
+// renderInput renders the input with the vars, concatenating the ID of the vars to the ID of the input.
+func renderInput(dict *Dict, vars *Vars) (*renderedInput, error) {
+	tracked, deps := vars.track()
+	result := &renderedInput{deps: deps}
+	hadStreams := false
+	if streams := getStreams(dict); streams != nil {
+		hadStreams = true
+	}
+	n, err := dict.Apply(tracked)
+	if deps.processors {
+		result.processorsKey = vars.processorsKey
+		result.processors = vars.processors
+	}
+	if errors.Is(err, ErrNoMatch) {
+		// has a variable that didn't exist, so we ignore it
+		return result, nil
+	}
+	if err != nil {
+		// another error that needs to be reported
+		return nil, err
+	}
+	if n == nil {
+		// condition removed it
+		return result, nil
+	}
+	dict = n.(*Dict)
+	if hadStreams {
+		streams := getStreams(dict)
+		if streams == nil {
+			// conditions removed all streams (input is removed)
+			return result, nil
+		}
+	}
+	result.hash = string(dict.Hash())
+
+	if vars.ID() != "" {
+		// vars has unique ID, concat ID onto existing ID
+		idNode, ok := dict.Find("id")
+		if ok {
+			idKey, _ := idNode.(*Key) // always a Key
+
+			// clone original and update its key to 'original_id'
+			origKey, _ := idKey.Clone().(*Key) // always a Key
+			origKey.name = "original_id"
+			dict.Insert(origKey)
+
+			// update id field to concat the id of the variable context set
+			switch idVal := idKey.value.(type) {
+			case *StrVal:
+				idKey.value = NewStrValWithProcessors(fmt.Sprintf("%s-%s", idVal.value, vars.ID()), idVal.processors)
+			case *IntVal:
+				idKey.value = NewStrVal(fmt.Sprintf("%d-%s", idVal.value, vars.ID()))
+			case *UIntVal:
+				idKey.value = NewStrVal(fmt.Sprintf("%d-%s", idVal.value, vars.ID()))
+			case *FloatVal:
+				idKey.value = NewStrVal(fmt.Sprintf("%f-%s", idVal.value, vars.ID()))
+			default:
+				return nil, fmt.Errorf("id field type invalid, expected string, int, uint, or float got: %T", idKey.value)
+			}
+		} else {
+			dict.Insert(NewKey("id", NewStrVal(vars.ID())))
+		}
+	}
+	result.node = promoteProcessors(dict)
+	return result, nil
+}
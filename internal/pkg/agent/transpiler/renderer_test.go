@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package transpiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderer(t *testing.T) {
+	inputs := NewKey("inputs", NewList([]Node{
+		NewDict([]Node{
+			NewKey("id", NewStrVal("logs")),
+			NewKey("condition", NewStrVal("${kubernetes.namespace} != 'kube-system'")),
+			NewKey("paths", NewList([]Node{
+				NewStrVal("/var/log/${kubernetes.pod.name}.log"),
+			})),
+		}),
+		NewDict([]Node{
+			NewKey("id", NewStrVal("app")),
+			NewKey("app", NewStrVal("${kubernetes.labels.app}")),
+		}),
+	}))
+	pod := func(id, name, namespace string, labels map[string]interface{}) *Vars {
+		return mustMakeVarsP(id, map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"namespace": namespace,
+				"pod":       map[string]interface{}{"name": name},
+				"labels":    labels,
+			},
+		}, "kubernetes", Processors{
+			{"add_fields": map[string]interface{}{"fields": map[string]interface{}{"pod": name}}},
+		})
+	}
+
+	var r Renderer
+	render := func(varsArray []*Vars) []Node {
+		t.Helper()
+		rendered, err := r.Render(inputs, varsArray)
+		require.NoError(t, err)
+		expected, err := RenderInputs(inputs, varsArray)
+		require.NoError(t, err)
+		require.Equal(t, expected.String(), rendered.String())
+		return rendered.Value().([]Node)
+	}
+
+	first := render([]*Vars{
+		pod("pod-1", "pod-1", "default", map[string]interface{}{"app": "web"}),
+		pod("pod-2", "pod-2", "default", map[string]interface{}{}),
+	})
+	require.Len(t, first, 3)
+
+	// the variables of pod-2 are set again with a label its inputs do not read
+	second := render([]*Vars{
+		pod("pod-1", "pod-1", "default", map[string]interface{}{"app": "web"}),
+		pod("pod-2", "pod-2", "default", map[string]interface{}{"tier": "backend"}),
+	})
+	require.Len(t, second, 3)
+	for i := range first {
+		assert.Same(t, first[i], second[i], "input %d rendered again", i)
+	}
+
+	// pod-2 gets the label read by the app input and pod-1 moves to a namespace removing its logs
+	third := render([]*Vars{
+		pod("pod-1", "pod-1", "kube-system", map[string]interface{}{"app": "web"}),
+		pod("pod-2", "pod-2", "default", map[string]interface{}{"app": "db"}),
+	})
+	require.Len(t, third, 3)
+	assert.Same(t, second[1], third[0], "the app input of pod-1 rendered again")
+	assert.Same(t, second[2], third[1], "the logs input of pod-2 rendered again")
+	assert.Contains(t, third[2].String(), "{app:db}")
+
+	// the processors of pod-2 change without its variables
+	fourth := render([]*Vars{
+		pod("pod-1", "pod-1", "kube-system", map[string]interface{}{"app": "web"}),
+		mustMakeVarsP("pod-2", map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"namespace": "default",
+				"pod":       map[string]interface{}{"name": "pod-2"},
+				"labels":    map[string]interface{}{"app": "db"},
+			},
+		}, "kubernetes", Processors{
+			{"add_fields": map[string]interface{}{"fields": map[string]interface{}{"pod": "renamed"}}},
+		}),
+	})
+	require.Len(t, fourth, 3)
+	assert.Same(t, third[0], fourth[0])
+	assert.NotSame(t, third[1], fourth[1])
+	assert.Contains(t, fourth[1].String(), "renamed")
+}
+
+func TestRendererInputChanged(t *testing.T) {
+	vars := []*Vars{
+		mustMakeVarsP("pod-1", map[string]interface{}{"var1": map[string]interface{}{"name": "value1"}}, "", nil),
+	}
+	input := func(path string) Node {
+		return NewKey("inputs", NewList([]Node{
+			NewDict([]Node{
+				NewKey("paths", NewList([]Node{NewStrVal(path)})),
+			}),
+		}))
+	}
+
+	var r Renderer
+	_, err := r.Render(input("/var/log/${var1.name}.log"), vars)
+	require.NoError(t, err)
+	rendered, err := r.Render(input("/var/lib/${var1.name}.log"), vars)
+	require.NoError(t, err)
+	assert.Equal(t, "[{paths:[/var/lib/value1.log]},{id:pod-1}]", rendered.String())
+}
@@ -5,7 +5,6 @@
 package transpiler
 
 import (
-	"errors"
 	"fmt"
 )
 
@@ -16,91 +15,34 @@ const (
 	streamsKey = "streams"
 )
 
-// RenderInputs renders dynamic inputs section
-func RenderInputs(inputs Node, varsArray []*Vars) (Node, error) {
-	l, ok := inputs.Value().(*List)
-	if !ok {
-		return nil, fmt.Errorf("inputs must be an array")
+// RenderPolicyInputs renders the inputs section of the policy with each set of variables.
+func RenderPolicyInputs(policy map[string]interface{}, varsArray []*Vars) (map[string]interface{}, error) {
+	ast, err := NewAST(policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the AST from the configuration: %w", err)
 	}
-	var nodes []varIDMap
-	nodesMap := map[string]*Dict{}
-	for _, vars := range varsArray {
-		for _, node := range l.Value().([]Node) {
-			dict, ok := node.Clone().(*Dict)
-			if !ok {
-				continue
-			}
-			hadStreams := false
-			if streams := getStreams(dict); streams != nil {
-				hadStreams = true
-			}
-			n, err := dict.Apply(vars)
-			if errors.Is(err, ErrNoMatch) {
-				// has a variable that didn't exist, so we ignore it
-				continue
-			}
-			if err != nil {
-				// another error that needs to be reported
-				return nil, err
-			}
-			if n == nil {
-				// condition removed it
-				continue
-			}
-			dict = n.(*Dict)
-			if hadStreams {
-				streams := getStreams(dict)
-				if streams == nil {
-					// conditions removed all streams (input is removed)
-					continue
-				}
-			}
-			hash := string(dict.Hash())
-			_, exists := nodesMap[hash]
-			if !exists {
-				nodesMap[hash] = dict
-				nodes = append(nodes, varIDMap{vars.ID(), dict})
-			}
-		}
-	}
-	var nInputs []Node
-	for _, node := range nodes {
-		if node.id != "" {
-			// vars has unique ID, concat ID onto existing ID
-			idNode, ok := node.d.Find("id")
-			if ok {
-				idKey, _ := idNode.(*Key) // always a Key
-
-				// clone original and update its key to 'original_id'
-				origKey, _ := idKey.Clone().(*Key) // always a Key
-				origKey.name = "original_id"
-				node.d.Insert(origKey)
 
-				// update id field to concat the id of the variable context set
-				switch idVal := idKey.value.(type) {
-				case *StrVal:
-					idVal.value = fmt.Sprintf("%s-%s", idVal.value, node.id)
-				case *IntVal:
-					idKey.value = NewStrVal(fmt.Sprintf("%d-%s", idVal.value, node.id))
-				case *UIntVal:
-					idKey.value = NewStrVal(fmt.Sprintf("%d-%s", idVal.value, node.id))
-				case *FloatVal:
-					idKey.value = NewStrVal(fmt.Sprintf("%f-%s", idVal.value, node.id))
-				default:
-					return nil, fmt.Errorf("id field type invalid, expected string, int, uint, or float got: %T", idKey.value)
-				}
-			} else {
-				node.d.Insert(NewKey("id", NewStrVal(node.id)))
-			}
+	inputs, ok := Lookup(ast, "inputs")
+	if ok {
+		renderedInputs, err := RenderInputs(inputs, varsArray)
+		if err != nil {
+			return nil, fmt.Errorf("rendering inputs failed: %w", err)
 		}
-		nInputs = append(nInputs, promoteProcessors(node.d))
+		err = Insert(ast, renderedInputs, "inputs")
+		if err != nil {
+			return nil, fmt.Errorf("inserting rendered inputs failed: %w", err)
+		}
+	}
+	m, err := ast.Map()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
 	}
-	return NewList(nInputs), nil
+	return m, nil
 }
 
-type varIDMap struct {
-	id string
-	d  *Dict
+// RenderInputs renders dynamic inputs section
+func RenderInputs(inputs Node, varsArray []*Vars) (Node, error) {
+	return new(Renderer).Render(inputs, varsArray)
 }
 
 func getStreams(dict *Dict) *List {
@@ -5,6 +5,7 @@
 package transpiler
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -784,6 +785,26 @@ func TestRenderInputs(t *testing.T) {
 	}
 }
 
+func TestRenderInputsLeavesInputsUnchanged(t *testing.T) {
+	inputs := NewKey("inputs", NewList([]Node{
+		NewDict([]Node{
+			NewKey("id", NewStrVal("logs")),
+			NewKey("count", NewIntVal(1)),
+			NewKey("paths", NewList([]Node{
+				NewStrVal("/var/log/${var1.name}.log"),
+			})),
+		}),
+	}))
+	before := inputs.String()
+
+	_, err := RenderInputs(inputs, []*Vars{
+		mustMakeVarsP("id1", map[string]interface{}{"var1": map[string]interface{}{"name": "value1"}}, "", nil),
+		mustMakeVarsP("id2", map[string]interface{}{"var1": map[string]interface{}{"name": "value2"}}, "", nil),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, before, inputs.String())
+}
+
 func mustMakeVarsP(id string, mapping map[string]interface{}, processorKey string, processors Processors) *Vars {
 	v, err := NewVarsWithProcessors(id, mapping, processorKey, processors, nil)
 	if err != nil {
@@ -791,3 +812,23 @@ func mustMakeVarsP(id string, mapping map[string]interface{}, processorKey strin
 	}
 	return v
 }
+
+func FuzzRenderPolicyInputs(f *testing.F) {
+	f.Add([]byte(`{"inputs":[{"type":"logfile","streams":[{"paths":["/var/log/${host.name}"]}]}]}`), "my-host")
+	f.Add([]byte(`{"inputs":[{"type":"logfile","condition":"${host.name} == 'my-host'","paths":["${host.paths|'/var/log'}"]}]}`), "other-host")
+	f.Add([]byte(`{"inputs":[{"type":"logfile","processors":[{"add_fields":{"fields":{"name":"${host.name}"}}}]}]}`), "$${host.name}")
+
+	f.Fuzz(func(t *testing.T, data []byte, hostname string) {
+		var policy map[string]interface{}
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return
+		}
+		vars, err := NewVars("", map[string]interface{}{
+			"host": map[string]interface{}{
+				"name": hostname,
+			},
+		}, nil)
+		require.NoError(t, err)
+		_, _ = RenderPolicyInputs(policy, []*Vars{vars})
+	})
+}
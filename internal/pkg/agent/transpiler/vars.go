@@ -5,6 +5,7 @@
 package transpiler
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -26,6 +27,9 @@ type Vars struct {
 	processorsKey         string
 	processors            Processors
 	fetchContextProviders mapstr.M
+
+	// deps records the variables read when set, see track.
+	deps *varDeps
 }
 
 // NewVars returns a new instance of vars.
@@ -39,13 +43,63 @@ func NewVarsWithProcessors(id string, mapping map[string]interface{}, processorK
 	if err != nil {
 		return nil, err
 	}
-	return &Vars{id, tree, processorKey, processors, fetchContextProviders}, nil
+	return &Vars{
+		id:                    id,
+		tree:                  tree,
+		processorsKey:         processorKey,
+		processors:            processors,
+		fetchContextProviders: fetchContextProviders,
+	}, nil
+}
+
+// NewVarsFromJSON returns the sets of variables defined in JSON, either an object for a single set or an array of
+// objects for multiple sets. Like dynamic providers, each set of multiple sets has its own ID so the inputs rendered
+// from each set are unique.
+func NewVarsFromJSON(data []byte) ([]*Vars, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var mappings []map[string]interface{}
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		mappings = append(mappings, v)
+	case []interface{}:
+		for i, item := range v {
+			mapping, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("entry %d must be an object not a %T", i, item)
+			}
+			mappings = append(mappings, mapping)
+		}
+	default:
+		return nil, fmt.Errorf("must contain an object or an array of objects not a %T", raw)
+	}
+
+	result := make([]*Vars, 0, len(mappings))
+	for i, mapping := range mappings {
+		id := ""
+		if len(mappings) > 1 {
+			id = fmt.Sprintf("vars-%d", i)
+		}
+		v, err := NewVars(id, mapping, nil)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		result = append(result, v)
+	}
+	return result, nil
 }
 
 // Replace returns a new value based on variable replacement.
 func (v *Vars) Replace(value string) (Node, error) {
+	if !strings.Contains(value, "$") {
+		// nothing to replace, the string is kept as is
+		return NewStrVal(value), nil
+	}
 	var processors Processors
-	matchIdxs := varsRegex.FindAllSubmatchIndex([]byte(value), -1)
+	matchIdxs := varsRegex.FindAllStringSubmatchIndex(value, -1)
 	if !validBrackets(value, matchIdxs) {
 		return nil, fmt.Errorf("starting ${ is missing ending }")
 	}
@@ -69,6 +123,9 @@ func (v *Vars) Replace(value string) (Node, error) {
 						node := nodeToValue(node)
 						if v.processorsKey != "" && varPrefixMatched(val.Value(), v.processorsKey) {
 							processors = v.processors
+							if v.deps != nil {
+								v.deps.processors = true
+							}
 						}
 						if r[i] == 0 && r[i+1] == len(value) {
 							// possible for complete replacement of object, because the variable
@@ -99,6 +156,10 @@ func (v *Vars) ID() string {
 
 // Lookup returns the value from the vars.
 func (v *Vars) Lookup(name string) (interface{}, bool) {
+	if v.deps != nil {
+		node, ok := Lookup(v.tree, name)
+		v.deps.record(name, node, ok)
+	}
 	// lookup in the AST tree
 	return v.tree.Lookup(name)
 }
@@ -109,6 +170,43 @@ func (v *Vars) Map() (map[string]interface{}, error) {
 	return v.tree.Map()
 }
 
+// varsJSON is the serialized form of Vars.
+type varsJSON struct {
+	ID            string                 `json:"id"`
+	Mapping       map[string]interface{} `json:"mapping"`
+	ProcessorsKey string                 `json:"processors_key,omitempty"`
+	Processors    Processors             `json:"processors,omitempty"`
+}
+
+// MarshalJSON serializes the variables and their processors. The fetch context providers are live
+// objects so they are not serialized, their variables do not resolve once unmarshalled.
+func (v *Vars) MarshalJSON() ([]byte, error) {
+	mapping, err := v.Map()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(varsJSON{
+		ID:            v.id,
+		Mapping:       mapping,
+		ProcessorsKey: v.processorsKey,
+		Processors:    v.processors,
+	})
+}
+
+// UnmarshalJSON deserializes the variables serialized by MarshalJSON.
+func (v *Vars) UnmarshalJSON(data []byte) error {
+	var raw varsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := NewVarsWithProcessors(raw.ID, raw.Mapping, raw.ProcessorsKey, raw.Processors, nil)
+	if err != nil {
+		return err
+	}
+	*v = *parsed
+	return nil
+}
+
 // lookupNode performs a lookup on the AST, but keeps the result as a `Node`.
 //
 // This is different from `Lookup` which returns the actual type, not the AST type.
@@ -116,6 +214,10 @@ func (v *Vars) lookupNode(name string) (Node, bool) {
 	// check if the value can be retrieved from a FetchContextProvider
 	for providerName, provider := range v.fetchContextProviders {
 		if varPrefixMatched(name, providerName) {
+			if v.deps != nil {
+				// fetched values are not part of the vars, they cannot be tracked
+				v.deps.fetched = true
+			}
 			fetchProvider, ok := provider.(composable.FetchContextProvider)
 			if !ok {
 				return &StrVal{value: ""}, false
@@ -128,7 +230,56 @@ func (v *Vars) lookupNode(name string) (Node, bool) {
 		}
 	}
 	// lookup in the AST tree
-	return Lookup(v.tree, name)
+	node, ok := Lookup(v.tree, name)
+	if v.deps != nil {
+		v.deps.record(name, node, ok)
+	}
+	return node, ok
+}
+
+// varDeps are the variables read while rendering an input with a set of vars.
+type varDeps struct {
+	// values maps the name of each variable read to its value, see depValue.
+	values map[string]string
+	// processors is true when the processors of the vars were attached.
+	processors bool
+	// fetched is true when a variable was read from a fetch context provider.
+	fetched bool
+}
+
+func (d *varDeps) record(name string, node Node, found bool) {
+	d.values[name] = depValue(node, found)
+}
+
+// depValue returns the value compared to detect a change of a variable, a variable that does not
+// exist differs from any value, even empty.
+func depValue(node Node, found bool) string {
+	if !found || node == nil {
+		return ""
+	}
+	return "=" + string(node.Hash())
+}
+
+// track returns a copy of the vars recording the variables read through it in the returned varDeps.
+func (v *Vars) track() (*Vars, *varDeps) {
+	deps := &varDeps{values: make(map[string]string)}
+	tracked := *v
+	tracked.deps = deps
+	return &tracked, deps
+}
+
+// unchanged returns true when the variables recorded in deps have the same values in v.
+func (v *Vars) unchanged(deps *varDeps) bool {
+	if deps.fetched {
+		return false
+	}
+	for name, value := range deps.values {
+		node, ok := Lookup(v.tree, name)
+		if depValue(node, ok) != value {
+			return false
+		}
+	}
+	return true
 }
 
 // nodeToValue ensures that the node is an actual value.
@@ -5,6 +5,7 @@
 package transpiler
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -349,6 +350,38 @@ func TestVars_ReplaceWithFetchContextProvider(t *testing.T) {
 	assert.Equal(t, NewStrVal("mockedFetchContent"), res)
 }
 
+func TestVars_JSON(t *testing.T) {
+	processors := Processors{
+		{
+			"add_fields": map[string]interface{}{
+				"dynamic": "added",
+			},
+		},
+	}
+	vars, err := NewVarsWithProcessors(
+		"pod-1",
+		map[string]interface{}{
+			"dynamic": map[string]interface{}{
+				"key1": "dynamic1",
+			},
+		},
+		"dynamic",
+		processors,
+		nil)
+	require.NoError(t, err)
+
+	data, err := json.Marshal([]*Vars{vars})
+	require.NoError(t, err)
+
+	var parsed []*Vars
+	require.NoError(t, json.Unmarshal(data, &parsed))
+	require.Len(t, parsed, 1)
+	assert.Equal(t, "pod-1", parsed[0].ID())
+	res, err := parsed[0].Replace("${dynamic.key1}")
+	require.NoError(t, err)
+	assert.Equal(t, NewStrValWithProcessors("dynamic1", processors), res)
+}
+
 type contextProviderMock struct {
 }
 
@@ -6,23 +6,35 @@ package version
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/elastic/elastic-agent-libs/logp"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/config/operations"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
 // Output returns the output when `--yaml` is used.
 type Output struct {
-	Binary *release.VersionInfo `yaml:"binary"`
-	Daemon *release.VersionInfo `yaml:"daemon,omitempty"`
+	Binary        *release.VersionInfo `yaml:"binary"`
+	Daemon        *release.VersionInfo `yaml:"daemon,omitempty"`
+	Compatibility []Compatibility      `yaml:"compatibility,omitempty"`
 }
 
+// checkTimeout is the timeout of the compatibility check of the remotes.
+const checkTimeout = 30 * time.Second
+
 // queryDaemon gather version information from a running agent
 func queryDaemon() (*release.VersionInfo, error) {
 	c := client.New()
@@ -44,6 +56,22 @@ func queryDaemon() (*release.VersionInfo, error) {
 	}, nil
 }
 
+// checkRemotes checks the compatibility of the binary with the remotes of the configuration
+func checkRemotes(binary *release.VersionInfo) ([]Compatibility, error) {
+	l, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := operations.LoadFullAgentConfig(l, paths.ConfigFile(), false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the configuration: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	return checkCompatibility(ctx, l, binary.Version, cfg)
+}
+
 // NewCommandWithArgs returns a new version command.
 func NewCommandWithArgs(streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
@@ -59,6 +87,16 @@ func NewCommandWithArgs(streams *cli.IOStreams) *cobra.Command {
 					cmd.SilenceUsage = true
 				}
 			}()
+			if sbom, _ := cmd.Flags().GetBool("sbom"); sbom {
+				data, err := os.ReadFile(paths.AgentSBOMFile())
+				if err != nil {
+					returnErr = fmt.Errorf("could not read the SBOM of the binary: %w", err)
+					return returnErr
+				}
+				_, err = streams.Out.Write(data)
+				return err
+			}
+
 			var daemon *release.VersionInfo
 
 			binary := release.Info()
@@ -74,11 +112,24 @@ func NewCommandWithArgs(streams *cli.IOStreams) *cobra.Command {
 				}
 			}
 
+			var compatibilities []Compatibility
+			checkFleet, _ := cmd.Flags().GetBool("check-fleet")
+			if checkFleet {
+				c, err := checkRemotes(&binary)
+				if err != nil {
+					returnErr = fmt.Errorf("could not check the compatibility of the remotes: %w", err)
+				} else if !allCompatible(c) && returnErr == nil {
+					returnErr = errors.New("version skew detected: the version of a remote is not supported or unknown")
+				}
+				compatibilities = c
+			}
+
 			outputYaml, _ := cmd.Flags().GetBool("yaml")
 			if outputYaml {
 				out, err := yaml.Marshal(Output{
-					Binary: &binary,
-					Daemon: daemon,
+					Binary:        &binary,
+					Daemon:        daemon,
+					Compatibility: compatibilities,
 				})
 				if err != nil {
 					return fmt.Errorf("failed to render YAML: %w", err)
@@ -88,25 +139,37 @@ func NewCommandWithArgs(streams *cli.IOStreams) *cobra.Command {
 			}
 
 			fmt.Fprintf(streams.Out, "Binary: %s\n", binary.String())
-			if binaryOnly {
-				return returnErr
+			if !binaryOnly {
+				str := "<failed to communicate>"
+				if daemon != nil {
+					str = daemon.String()
+				}
+				fmt.Fprintf(streams.Out, "Daemon: %s\n", str)
 			}
-
-			str := "<failed to communicate>"
-			if daemon != nil {
-				str = daemon.String()
+			for _, c := range compatibilities {
+				fmt.Fprintf(streams.Out, "%s\n", c.String())
 			}
-			fmt.Fprintf(streams.Out, "Daemon: %s\n", str)
 			return returnErr
 		},
 	}
 
 	cmd.Flags().Bool("binary-only", false, "Version of current binary only")
 	cmd.Flags().Bool("yaml", false, "Output information in YAML format")
+	cmd.Flags().Bool("check-fleet", false, "Check the compatibility with the versions of Fleet Server and of the Elasticsearch outputs")
+	cmd.Flags().Bool("sbom", false, "Output the CycloneDX SBOM of the current binary")
 
 	return cmd
 }
 
+func allCompatible(compatibilities []Compatibility) bool {
+	for _, c := range compatibilities {
+		if !c.Compatible {
+			return false
+		}
+	}
+	return true
+}
+
 func isMismatch(a *release.VersionInfo, b *release.VersionInfo) bool {
 	if a.Commit != "unknown" && b.Commit != "unknown" {
 		return a.Commit != b.Commit
@@ -5,7 +5,10 @@
 package version
 
 import (
+	"errors"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -17,6 +20,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
@@ -129,6 +133,33 @@ func TestCmdDaemonErrYAML(t *testing.T) {
 	assert.Equal(t, release.Info(), *output.Binary)
 }
 
+func TestCmdSBOM(t *testing.T) {
+	sbomFile := paths.AgentSBOMFile()
+	_, err := os.Stat(filepath.Dir(sbomFile))
+	if errors.Is(err, os.ErrNotExist) {
+		t.Cleanup(func() { _ = os.RemoveAll(filepath.Dir(sbomFile)) })
+	}
+
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	cmd := NewCommandWithArgs(streams)
+	require.NoError(t, cmd.Flags().Set("sbom", "true"))
+	err = cmd.Execute()
+	assert.ErrorContains(t, err, "could not read the SBOM of the binary")
+
+	sbom := `{"bomFormat":"CycloneDX","specVersion":"1.4"}`
+	require.NoError(t, os.MkdirAll(filepath.Dir(sbomFile), 0755))
+	require.NoError(t, os.WriteFile(sbomFile, []byte(sbom), 0644))
+	defer os.Remove(sbomFile)
+
+	streams, _, out, _ := cli.NewTestingIOStreams()
+	cmd = NewCommandWithArgs(streams)
+	require.NoError(t, cmd.Flags().Set("sbom", "true"))
+	require.NoError(t, cmd.Execute())
+	output, err := ioutil.ReadAll(out)
+	require.NoError(t, err)
+	assert.Equal(t, sbom, string(output))
+}
+
 func newErrorLogger(t *testing.T) *logger.Logger {
 	t.Helper()
 
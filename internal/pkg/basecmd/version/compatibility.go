@@ -0,0 +1,282 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const (
+	fleetServerRemote   = "fleet-server"
+	elasticsearchRemote = "elasticsearch"
+
+	elasticsearchDefaultPort = "9200"
+)
+
+// Compatibility reports whether a remote the agent connects to runs a version supported by the binary.
+type Compatibility struct {
+	Remote string `yaml:"remote"`
+	Name   string `yaml:"name,omitempty"`
+	Hosts  string `yaml:"hosts"`
+	// Version is the version of the remote, empty when it could not be retrieved.
+	Version string `yaml:"version,omitempty"`
+	// MinVersion is the minimum supported version of the remote, inclusive.
+	MinVersion string `yaml:"min_version"`
+	// MaxVersion is the maximum supported version of the remote, exclusive.
+	MaxVersion string `yaml:"max_version"`
+	Compatible bool   `yaml:"compatible"`
+	Error      string `yaml:"error,omitempty"`
+}
+
+// String returns the human readable compatibility.
+func (c Compatibility) String() string {
+	name := c.Remote
+	if c.Name != "" {
+		name = fmt.Sprintf("%s %s", c.Remote, c.Name)
+	}
+	supported := fmt.Sprintf("supported: >= %s, < %s", c.MinVersion, c.MaxVersion)
+	if c.Error != "" {
+		return fmt.Sprintf("%s (%s): unknown version, %s (%s)", name, c.Hosts, c.Error, supported)
+	}
+	if !c.Compatible {
+		return fmt.Sprintf("%s (%s): %s, incompatible (%s)", name, c.Hosts, c.Version, supported)
+	}
+	return fmt.Sprintf("%s (%s): %s, compatible (%s)", name, c.Hosts, c.Version, supported)
+}
+
+// elasticsearchOutput is the part of an output configuration used to connect to Elasticsearch.
+type elasticsearchOutput struct {
+	Hosts     []string                         `config:"hosts"`
+	Protocol  string                           `config:"protocol"`
+	Path      string                           `config:"path"`
+	APIKey    string                           `config:"api_key"`
+	Username  string                           `config:"username"`
+	Password  string                           `config:"password"`
+	Transport httpcommon.HTTPTransportSettings `config:",inline"`
+}
+
+// checkCompatibility checks the versions of Fleet Server, when the agent is enrolled, and of the
+// Elasticsearch clusters of the outputs of the configuration.
+func checkCompatibility(ctx context.Context, log *logger.Logger, binaryVersion string, rawConfig *config.Config) ([]Compatibility, error) {
+	parsed, err := agtversion.ParseVersion(binaryVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the version of the binary: %w", err)
+	}
+	maxVersion := agtversion.NewParsedSemVer(parsed.Major()+1, 0, 0, "", "").String()
+
+	cfg, err := configuration.NewFromConfig(rawConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration: %w", err)
+	}
+
+	var compatibilities []Compatibility
+	if !configuration.IsStandalone(cfg.Fleet) {
+		// Fleet Server cannot be older than the agents enrolled in it
+		c := Compatibility{
+			Remote:     fleetServerRemote,
+			Hosts:      strings.Join(fleetHosts(cfg.Fleet), ","),
+			MinVersion: parsed.CoreVersion(),
+			MaxVersion: maxVersion,
+		}
+		c.Version, err = fleetServerVersion(ctx, log, cfg.Fleet)
+		compatibilities = append(compatibilities, c.check(err))
+	}
+
+	outputs, err := elasticsearchOutputs(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		output := outputs[name]
+		// Elasticsearch accepts the data of the agents of its minor and of the previous ones
+		c := Compatibility{
+			Remote:     elasticsearchRemote,
+			Name:       name,
+			Hosts:      strings.Join(output.Hosts, ","),
+			MinVersion: agtversion.NewParsedSemVer(parsed.Major(), parsed.Minor(), 0, "", "").String(),
+			MaxVersion: maxVersion,
+		}
+		c.Version, err = elasticsearchVersion(ctx, output)
+		compatibilities = append(compatibilities, c.check(err))
+	}
+	return compatibilities, nil
+}
+
+// check sets whether the version of the remote is in the supported range.
+func (c Compatibility) check(err error) Compatibility {
+	if err != nil {
+		c.Error = err.Error()
+		return c
+	}
+	v, err := agtversion.ParseVersion(c.Version)
+	if err != nil {
+		c.Error = fmt.Sprintf("failed to parse version %q: %s", c.Version, err)
+		return c
+	}
+	// snapshots and pre-releases of the supported versions are supported as well
+	core, _ := agtversion.ParseVersion(v.CoreVersion())
+	minVersion, _ := agtversion.ParseVersion(c.MinVersion)
+	maxVersion, _ := agtversion.ParseVersion(c.MaxVersion)
+	c.Compatible = !core.Less(*minVersion) && core.Less(*maxVersion)
+	return c
+}
+
+func fleetHosts(cfg *configuration.FleetAgentConfig) []string {
+	if len(cfg.Client.Hosts) > 0 {
+		return cfg.Client.Hosts
+	}
+	return []string{cfg.Client.Host}
+}
+
+// fleetServerVersion returns the version of Fleet Server from its status.
+func fleetServerVersion(ctx context.Context, log *logger.Logger, cfg *configuration.FleetAgentConfig) (string, error) {
+	client, err := fleetclient.NewAuthWithConfig(log, cfg.AccessAPIKey, cfg.Client)
+	if err != nil {
+		return "", fmt.Errorf("failed to create fleet-server client: %w", err)
+	}
+	status, err := fleetapi.NewStatusCmd(client).Execute(ctx)
+	if err != nil {
+		return "", err
+	}
+	if status.Version == nil || status.Version.Number == "" {
+		return "", fmt.Errorf("fleet-server did not return its version")
+	}
+	return status.Version.Number, nil
+}
+
+// elasticsearchOutputs returns the Elasticsearch outputs of the configuration by name.
+func elasticsearchOutputs(rawConfig *config.Config) (map[string]elasticsearchOutput, error) {
+	var c struct {
+		Outputs map[string]interface{} `config:"outputs"`
+	}
+	if err := rawConfig.Unpack(&c); err != nil {
+		return nil, fmt.Errorf("failed to read the outputs: %w", err)
+	}
+
+	outputs := make(map[string]elasticsearchOutput)
+	for name, o := range c.Outputs {
+		oc, err := config.NewConfigFrom(o)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output %s: %w", name, err)
+		}
+		var outputType struct {
+			Type string `config:"type"`
+		}
+		if err := oc.Unpack(&outputType); err != nil {
+			return nil, fmt.Errorf("failed to read output %s: %w", name, err)
+		}
+		if outputType.Type != elasticsearchRemote {
+			continue
+		}
+		var output elasticsearchOutput
+		if err := oc.Unpack(&output); err != nil {
+			return nil, fmt.Errorf("failed to read output %s: %w", name, err)
+		}
+		outputs[name] = output
+	}
+	return outputs, nil
+}
+
+// elasticsearchVersion returns the version of the first host of the output to answer.
+func elasticsearchVersion(ctx context.Context, output elasticsearchOutput) (string, error) {
+	client, err := output.Transport.Client()
+	if err != nil {
+		return "", fmt.Errorf("failed to create elasticsearch client: %w", err)
+	}
+
+	var lastErr error
+	for _, host := range output.Hosts {
+		hostURL, err := elasticsearchURL(host, output.Protocol, output.Path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, hostURL, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if output.APIKey != "" {
+			req.Header.Set("Authorization", "ApiKey "+output.APIKey)
+		} else if output.Username != "" {
+			req.SetBasicAuth(output.Username, output.Password)
+		}
+		version, err := doElasticsearchVersion(client, req)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", host, err)
+			continue
+		}
+		return version, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no hosts")
+	}
+	return "", lastErr
+}
+
+func doElasticsearchVersion(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("failed to decode the response: %w", err)
+	}
+	if info.Version.Number == "" {
+		return "", fmt.Errorf("elasticsearch did not return its version")
+	}
+	return info.Version.Number, nil
+}
+
+// elasticsearchURL returns the URL of the root of the host, the same way the outputs build it.
+func elasticsearchURL(host string, protocol string, path string) (string, error) {
+	if !strings.Contains(host, "://") {
+		if protocol == "" {
+			protocol = "http"
+		}
+		host = protocol + "://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %w", host, err)
+	}
+	if u.Port() == "" {
+		u.Host = u.Host + ":" + elasticsearchDefaultPort
+	}
+	if u.Path == "" && path != "" {
+		u.Path = path
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/"
+	return u.String(), nil
+}
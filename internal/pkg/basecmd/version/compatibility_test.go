@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package version
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	newServer := func(t *testing.T, path string, auth string, body string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != path || r.Header.Get("Authorization") != auth {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, body)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	newConfig := func(fleetHost string, esHost string) *config.Config {
+		return config.MustNewConfigFrom(map[string]interface{}{
+			"fleet": map[string]interface{}{
+				"enabled":        true,
+				"access_api_key": "fleet-key",
+				"hosts":          []string{fleetHost},
+			},
+			"outputs": map[string]interface{}{
+				"default": map[string]interface{}{
+					"type":    "elasticsearch",
+					"hosts":   []string{esHost},
+					"api_key": "id:es-key",
+				},
+				"logstash": map[string]interface{}{
+					"type":  "logstash",
+					"hosts": []string{"localhost:5044"},
+				},
+			},
+		})
+	}
+	fleetStatus := func(version string) string {
+		return fmt.Sprintf(`{"name": "fleet-server", "status": "HEALTHY", "version": {"number": %q}}`, version)
+	}
+	esInfo := func(version string) string {
+		return fmt.Sprintf(`{"name": "es", "version": {"number": %q}}`, version)
+	}
+
+	t.Run("compatible", func(t *testing.T) {
+		fleet := newServer(t, "/api/status", "ApiKey fleet-key", fleetStatus("8.11.0-SNAPSHOT"))
+		es := newServer(t, "/", "ApiKey id:es-key", esInfo("8.12.1"))
+
+		compatibilities, err := checkCompatibility(context.Background(), newErrorLogger(t), "8.11.0", newConfig(fleet.Listener.Addr().String(), es.URL))
+		require.NoError(t, err)
+		assert.Equal(t, []Compatibility{
+			{
+				Remote:     fleetServerRemote,
+				Hosts:      fleet.Listener.Addr().String(),
+				Version:    "8.11.0-SNAPSHOT",
+				MinVersion: "8.11.0",
+				MaxVersion: "9.0.0",
+				Compatible: true,
+			},
+			{
+				Remote:     elasticsearchRemote,
+				Name:       "default",
+				Hosts:      es.URL,
+				Version:    "8.12.1",
+				MinVersion: "8.11.0",
+				MaxVersion: "9.0.0",
+				Compatible: true,
+			},
+		}, compatibilities)
+		assert.True(t, allCompatible(compatibilities))
+	})
+
+	t.Run("version skew", func(t *testing.T) {
+		fleet := newServer(t, "/api/status", "ApiKey fleet-key", fleetStatus("8.10.4"))
+		es := newServer(t, "/", "ApiKey id:es-key", esInfo("9.0.0"))
+
+		compatibilities, err := checkCompatibility(context.Background(), newErrorLogger(t), "8.11.0", newConfig(fleet.Listener.Addr().String(), es.URL))
+		require.NoError(t, err)
+		require.Len(t, compatibilities, 2)
+		assert.False(t, compatibilities[0].Compatible)
+		assert.Equal(t, fmt.Sprintf("fleet-server (%s): 8.10.4, incompatible (supported: >= 8.11.0, < 9.0.0)", fleet.Listener.Addr().String()), compatibilities[0].String())
+		assert.False(t, compatibilities[1].Compatible)
+		assert.False(t, allCompatible(compatibilities))
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		fleet := newServer(t, "/api/status", "ApiKey fleet-key", `{"name": "fleet-server", "status": "HEALTHY"}`)
+		es := newServer(t, "/", "ApiKey another-key", esInfo("8.11.0"))
+
+		compatibilities, err := checkCompatibility(context.Background(), newErrorLogger(t), "8.11.0", newConfig(fleet.Listener.Addr().String(), es.URL))
+		require.NoError(t, err)
+		require.Len(t, compatibilities, 2)
+		assert.Equal(t, "fleet-server did not return its version", compatibilities[0].Error)
+		assert.Contains(t, compatibilities[1].Error, "unexpected status 401 Unauthorized")
+		assert.False(t, allCompatible(compatibilities))
+	})
+
+	t.Run("standalone", func(t *testing.T) {
+		compatibilities, err := checkCompatibility(context.Background(), newErrorLogger(t), "8.11.0", config.MustNewConfigFrom(map[string]interface{}{
+			"outputs": map[string]interface{}{
+				"default": map[string]interface{}{
+					"type":  "logstash",
+					"hosts": []string{"localhost:5044"},
+				},
+			},
+		}))
+		require.NoError(t, err)
+		assert.Empty(t, compatibilities)
+	})
+}
+
+func TestElasticsearchURL(t *testing.T) {
+	for host, expected := range map[string]string{
+		"localhost":                  "http://localhost:9200/",
+		"localhost:9201":             "http://localhost:9201/",
+		"https://es.example.com:443": "https://es.example.com:443/",
+		"https://es.example.com/es":  "https://es.example.com:9200/es/",
+	} {
+		u, err := elasticsearchURL(host, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, expected, u, host)
+	}
+
+	u, err := elasticsearchURL("localhost", "https", "/es")
+	require.NoError(t, err)
+	assert.Equal(t, "https://localhost:9200/es/", u)
+}
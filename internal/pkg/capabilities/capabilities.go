@@ -19,6 +19,7 @@ type Capabilities interface {
 	AllowUpgrade(version string, sourceURI string) bool
 	AllowInput(name string) bool
 	AllowOutput(name string) bool
+	AllowDownloadSource(sourceURI string) bool
 }
 
 type capabilitiesManager struct {
@@ -26,6 +27,8 @@ type capabilitiesManager struct {
 	inputChecks  []*stringMatcher
 	outputChecks []*stringMatcher
 	upgradeCaps  []*upgradeCapability
+
+	downloadSourceChecks []*downloadSourceMatcher
 }
 
 func (cm *capabilitiesManager) AllowInput(inputType string) bool {
@@ -40,6 +43,10 @@ func (cm *capabilitiesManager) AllowUpgrade(version string, uri string) bool {
 	return allowUpgrade(cm.log, version, uri, cm.upgradeCaps)
 }
 
+func (cm *capabilitiesManager) AllowDownloadSource(sourceURI string) bool {
+	return allowDownloadSource(sourceURI, cm.downloadSourceChecks)
+}
+
 func LoadFile(capsFile string, log *logger.Logger) (Capabilities, error) {
 	// load capabilities from file
 	fd, err := os.Open(capsFile)
@@ -68,8 +75,11 @@ func Load(capsReader io.Reader, log *logger.Logger) (Capabilities, error) {
 	caps := spec.Capabilities
 
 	return &capabilitiesManager{
+		log:          log,
 		inputChecks:  caps.inputChecks,
 		outputChecks: caps.outputChecks,
 		upgradeCaps:  caps.upgradeChecks,
+
+		downloadSourceChecks: caps.downloadSourceChecks,
 	}, nil
 }
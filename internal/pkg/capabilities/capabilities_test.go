@@ -102,6 +102,32 @@ capabilities:
 
 }
 
+func TestDownloadSource(t *testing.T) {
+	// Allow downloads from elastic.co and from the internal network, deny all others
+	yml := `
+capabilities:
+- download_source: "*.elastic.co"
+  rule: allow
+- download_source: "10.0.0.0/8"
+  rule: allow
+- download_source: "https://mirror.example.com/elastic/*"
+  rule: allow
+- download_source: "*"
+  rule: deny
+`
+
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+	assert.True(t, caps.AllowDownloadSource("https://artifacts.elastic.co/downloads/"))
+	assert.True(t, caps.AllowDownloadSource("https://10.1.2.3:8443/downloads/"))
+	assert.True(t, caps.AllowDownloadSource("https://mirror.example.com/elastic/downloads/"))
+	assert.False(t, caps.AllowDownloadSource("https://mirror.example.com/other/downloads/"))
+	assert.False(t, caps.AllowDownloadSource("https://artifacts.elastic.co.evil.com/downloads/"))
+	assert.False(t, caps.AllowDownloadSource("https://192.168.1.1/downloads/"))
+	assert.False(t, caps.AllowDownloadSource("file:///tmp/downloads"))
+	assert.True(t, caps.AllowUpgrade("8.10.0", "https://192.168.1.1/downloads/"))
+}
+
 func TestNoCaps(t *testing.T) {
 	// Make sure capabilities loaded from a nonexistent file don't interfere
 	// with anything
@@ -112,4 +138,5 @@ func TestNoCaps(t *testing.T) {
 	assert.True(t, caps.AllowInput("system/metrics"))
 	assert.True(t, caps.AllowInput("system/logs"))
 	assert.True(t, caps.AllowOutput("elasticsearch"))
+	assert.True(t, caps.AllowDownloadSource("https://artifacts.elastic.co/downloads/"))
 }
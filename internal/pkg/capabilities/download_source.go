@@ -0,0 +1,98 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package capabilities
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+type downloadSourceMatcher struct {
+	// The pattern to match against, one of:
+	// - a CIDR, e.g. "10.0.0.0/8", matching the sources whose host is an IP of the network
+	// - a pattern containing "://", e.g. "https://artifacts.elastic.co/*", matching the whole URI
+	// - any other pattern, e.g. "*.elastic.co", matching the host of the source
+	// '*' matches any sequence of characters.
+	pattern string
+
+	// The network of a CIDR pattern, nil for the other patterns.
+	network *net.IPNet
+
+	// Whether matching this pattern results in allowing or denying the
+	// download source.
+	rule allowOrDeny
+}
+
+func newDownloadSourceMatcher(pattern string, rule allowOrDeny) (*downloadSourceMatcher, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("download source pattern can not be empty")
+	}
+	m := &downloadSourceMatcher{
+		pattern: strings.ToLower(pattern),
+		rule:    rule,
+	}
+	if _, network, err := net.ParseCIDR(pattern); err == nil {
+		m.network = network
+	}
+	return m, nil
+}
+
+func (m *downloadSourceMatcher) matches(sourceURI string, u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case m.network != nil:
+		ip := net.ParseIP(host)
+		return ip != nil && m.network.Contains(ip)
+	case strings.Contains(m.pattern, "://"):
+		return matchesWildcard(m.pattern, strings.ToLower(sourceURI))
+	default:
+		return matchesWildcard(m.pattern, host)
+	}
+}
+
+// allowDownloadSource checks the source URI an upgrade is downloaded from
+// against the download source capabilities. The first matching capability
+// decides, a source URI which can not be parsed is denied as soon as download
+// sources are restricted.
+func allowDownloadSource(sourceURI string, matchers []*downloadSourceMatcher) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+	u, err := url.Parse(sourceURI)
+	if err != nil {
+		return false
+	}
+	for _, matcher := range matchers {
+		if matcher.matches(sourceURI, u) {
+			return matcher.rule == ruleTypeAllow
+		}
+	}
+	// If nothing blocked it, default to allow.
+	return true
+}
+
+// matchesWildcard matches the target against a pattern where '*' matches any
+// sequence of characters.
+func matchesWildcard(pattern, target string) bool {
+	parts := strings.Split(pattern, wild)
+	if len(parts) == 1 {
+		return pattern == target
+	}
+	if !strings.HasPrefix(target, parts[0]) {
+		return false
+	}
+	target = target[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		idx := strings.Index(target, part)
+		if idx < 0 {
+			return false
+		}
+		target = target[idx+len(part):]
+	}
+	return strings.HasSuffix(target, parts[last])
+}
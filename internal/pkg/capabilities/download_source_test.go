@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package capabilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchesWildcard(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		target  string
+		match   bool
+	}{
+		{"*", "", true},
+		{"*", "artifacts.elastic.co", true},
+		{"artifacts.elastic.co", "artifacts.elastic.co", true},
+		{"artifacts.elastic.co", "artifacts.elastic.com", false},
+		{"*.elastic.co", "artifacts.elastic.co", true},
+		{"*.elastic.co", "elastic.co", false},
+		{"*.elastic.co", "elastic.co.evil.com", false},
+		{"https://*.example.com/*/downloads/*", "https://mirror.example.com/elastic/downloads/beats/", true},
+		{"https://*.example.com/*/downloads/*", "https://mirror.example.com/elastic/beats/", false},
+		{"https://*.example.com/*", "http://mirror.example.com/", false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.match, matchesWildcard(tc.pattern, tc.target), "pattern %q, target %q", tc.pattern, tc.target)
+	}
+}
+
+func TestAllowDownloadSource(t *testing.T) {
+	mustMatcher := func(pattern string, rule allowOrDeny) *downloadSourceMatcher {
+		m, err := newDownloadSourceMatcher(pattern, rule)
+		require.NoError(t, err)
+		return m
+	}
+
+	t.Run("no capabilities", func(t *testing.T) {
+		assert.True(t, allowDownloadSource("https://artifacts.elastic.co/downloads/", nil))
+		assert.True(t, allowDownloadSource("://invalid", nil))
+	})
+
+	t.Run("first match decides", func(t *testing.T) {
+		matchers := []*downloadSourceMatcher{
+			mustMatcher("evil.elastic.co", ruleTypeDeny),
+			mustMatcher("*.ELASTIC.co", ruleTypeAllow),
+			mustMatcher("*", ruleTypeDeny),
+		}
+		assert.True(t, allowDownloadSource("https://Artifacts.Elastic.co/downloads/", matchers))
+		assert.False(t, allowDownloadSource("https://evil.elastic.co/downloads/", matchers))
+		assert.False(t, allowDownloadSource("https://example.com/downloads/", matchers))
+		assert.False(t, allowDownloadSource("://invalid", matchers))
+	})
+
+	t.Run("CIDR", func(t *testing.T) {
+		matchers := []*downloadSourceMatcher{
+			mustMatcher("192.168.0.0/16", ruleTypeAllow),
+			mustMatcher("fd00::/8", ruleTypeAllow),
+			mustMatcher("*", ruleTypeDeny),
+		}
+		assert.True(t, allowDownloadSource("http://192.168.10.1:8080/downloads/", matchers))
+		assert.True(t, allowDownloadSource("http://[fd00::1]/downloads/", matchers))
+		assert.False(t, allowDownloadSource("http://10.0.0.1/downloads/", matchers))
+		assert.False(t, allowDownloadSource("http://192.168.10.1.example.com/downloads/", matchers))
+	})
+
+	t.Run("empty pattern", func(t *testing.T) {
+		_, err := newDownloadSourceMatcher("", ruleTypeAllow)
+		assert.Error(t, err)
+	})
+}
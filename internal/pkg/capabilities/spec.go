@@ -16,6 +16,8 @@ type capabilitiesList struct {
 	inputChecks   []*stringMatcher
 	outputChecks  []*stringMatcher
 	upgradeChecks []*upgradeCapability
+
+	downloadSourceChecks []*downloadSourceMatcher
 }
 
 // a type for capability values that must equal "allow" or "deny", enforced
@@ -81,6 +83,19 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 				return err
 			}
 			r.upgradeChecks = append(r.upgradeChecks, cap)
+		} else if _, found = mm["download_source"]; found {
+			spec := struct {
+				Type           allowOrDeny `yaml:"rule"`
+				DownloadSource string      `yaml:"download_source"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			matcher, err := newDownloadSourceMatcher(spec.DownloadSource, spec.Type)
+			if err != nil {
+				return err
+			}
+			r.downloadSourceChecks = append(r.downloadSourceChecks, matcher)
 		} else {
 			return fmt.Errorf("unexpected capability type for definition number '%d'", i)
 		}
@@ -23,6 +23,7 @@ func TestUnmarshal(t *testing.T) {
 		assert.Equal(t, 1, len(rr.Capabilities.inputChecks))
 		assert.Equal(t, 1, len(rr.Capabilities.outputChecks))
 		assert.Equal(t, 1, len(rr.Capabilities.upgradeChecks))
+		assert.Equal(t, 1, len(rr.Capabilities.downloadSourceChecks))
 	})
 
 	t.Run("invalid yaml", func(t *testing.T) {
@@ -44,6 +45,9 @@ var yamlDefinitionValid = []byte(`capabilities:
 -
   output: "elasticsearch"
   rule: "allow"
+-
+  download_source: "*.elastic.co"
+  rule: "allow"
 `)
 
 var yamlDefinitionInvalid = []byte(`
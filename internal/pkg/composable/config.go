@@ -4,9 +4,37 @@
 
 package composable
 
-import "github.com/elastic/elastic-agent/internal/pkg/config"
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+const (
+	defaultDebounceDelay    = 100 * time.Millisecond
+	defaultDebounceMaxDelay = 100 * time.Millisecond
+)
 
 // Config is config for multiple providers.
 type Config struct {
 	Providers map[string]*config.Config `config:"providers"`
+	Debounce  DebounceConfig            `config:"agent.providers.debounce"`
+}
+
+// DebounceConfig is the configuration of the coalescing of the changes of the providers
+// before the variables are updated and the policy is rendered again.
+type DebounceConfig struct {
+	// Delay is the time without any change after which the variables are updated.
+	Delay time.Duration `config:"delay" validate:"positive"`
+	// MaxDelay is the maximum time the variables are not updated while the providers keep changing.
+	MaxDelay time.Duration `config:"max_delay" validate:"positive"`
+}
+
+// DefaultDebounceConfig creates the default debounce configuration, the changes of the providers
+// are accumulated in 100 milliseconds chunks.
+func DefaultDebounceConfig() DebounceConfig {
+	return DebounceConfig{
+		Delay:    defaultDebounceDelay,
+		MaxDelay: defaultDebounceMaxDelay,
+	}
 }
@@ -47,13 +47,15 @@ type controller struct {
 	errCh            chan error
 	contextProviders map[string]*contextProviderState
 	dynamicProviders map[string]*dynamicProviderState
+	debounce         DebounceConfig
+	metrics          *controllerMetrics
 }
 
 // New creates a new controller.
 func New(log *logger.Logger, c *config.Config, managed bool) (Controller, error) {
 	l := log.Named("composable")
 
-	var providersCfg Config
+	providersCfg := Config{Debounce: DefaultDebounceConfig()}
 	if c != nil {
 		err := c.Unpack(&providersCfg)
 		if err != nil {
@@ -98,12 +100,17 @@ func New(log *logger.Logger, c *config.Config, managed bool) (Controller, error)
 		}
 	}
 
+	metrics := &controllerMetrics{}
+	metrics.register()
+
 	return &controller{
 		logger:           l,
 		ch:               make(chan []*transpiler.Vars, 1),
 		errCh:            make(chan error),
 		contextProviders: contextProviders,
 		dynamicProviders: dynamicProviders,
+		debounce:         providersCfg.Debounce,
+		metrics:          metrics,
 	}, nil
 }
 
@@ -125,6 +132,7 @@ func (c *controller) Run(ctx context.Context) error {
 	for name, state := range c.contextProviders {
 		state.Context = localCtx
 		state.signal = stateChangedChan
+		state.metrics = c.metrics
 		go func(name string, state *contextProviderState) {
 			defer wg.Done()
 			err := state.provider.Run(state)
@@ -142,6 +150,7 @@ func (c *controller) Run(ctx context.Context) error {
 	for name, state := range c.dynamicProviders {
 		state.Context = localCtx
 		state.signal = stateChangedChan
+		state.metrics = c.metrics
 		go func(name string, state *dynamicProviderState) {
 			defer wg.Done()
 			err := state.provider.Run(state)
@@ -178,8 +187,11 @@ func (c *controller) Run(ctx context.Context) error {
 		wg.Wait()
 	}
 
-	// performs debounce of notifies; accumulates them into 100 millisecond chunks
+	// performs debounce of notifies; the variables are updated once the providers did not change for
+	// the debounce delay, or at the latest after the debounce max delay
+	var last *varsState
 	for {
+		var deadline time.Time
 	DEBOUNCE:
 		for {
 			select {
@@ -187,7 +199,8 @@ func (c *controller) Run(ctx context.Context) error {
 				cleanupFn()
 				return ctx.Err()
 			case <-stateChangedChan:
-				t.Reset(100 * time.Millisecond)
+				deadline = time.Now().Add(c.debounce.MaxDelay)
+				t.Reset(c.debounce.wait(deadline))
 				c.logger.Debugf("Variable state changed for composable inputs; debounce started")
 				drainChan(stateChangedChan)
 				break DEBOUNCE
@@ -195,29 +208,58 @@ func (c *controller) Run(ctx context.Context) error {
 		}
 
 		// notification received, wait for batch
-		select {
-		case <-ctx.Done():
-			cleanupFn()
-			return ctx.Err()
-		case <-t.C:
-			drainChan(stateChangedChan)
-			// batching done, gather results
+	BATCH:
+		for {
+			select {
+			case <-ctx.Done():
+				cleanupFn()
+				return ctx.Err()
+			case <-stateChangedChan:
+				// still changing, push the update back up to the deadline
+				if !t.Stop() {
+					select {
+					case <-t.C:
+					default:
+					}
+				}
+				t.Reset(c.debounce.wait(deadline))
+			case <-t.C:
+				drainChan(stateChangedChan)
+				// batching done, gather results
+				break BATCH
+			}
 		}
+		c.metrics.batched()
 
 		c.logger.Debugf("Computing new variable state for composable inputs")
 
 		// build the vars list of mappings
-		vars := make([]*transpiler.Vars, 1)
-		mapping := map[string]interface{}{}
+		current := &varsState{
+			contexts: map[string]interface{}{},
+			dynamics: map[string][]dynamicProviderMapping{},
+		}
 		for name, state := range c.contextProviders {
-			mapping[name] = state.Current()
+			current.contexts[name] = state.Current()
 		}
+		for name, state := range c.dynamicProviders {
+			current.dynamics[name] = state.Mappings()
+		}
+		if last != nil && reflect.DeepEqual(last, current) {
+			// the providers reverted their changes during the debounce
+			c.metrics.unchanged.Add(1)
+			c.logger.Debugf("Variable state unchanged for composable inputs; update suppressed")
+			continue
+		}
+		last = current
+
+		vars := make([]*transpiler.Vars, 1)
+		mapping := current.contexts
 		// this is ensured not to error, by how the mappings states are verified
 		vars[0], _ = transpiler.NewVars("", mapping, fetchContextProviders)
 
 		// add to the vars list for each dynamic providers mappings
-		for name, state := range c.dynamicProviders {
-			for _, mappings := range state.Mappings() {
+		for name, mappings := range current.dynamics {
+			for _, mappings := range mappings {
 				local, _ := cloneMap(mapping) // will not fail; already been successfully cloned once
 				local[name] = mappings.mapping
 				id := fmt.Sprintf("%s-%s", name, mappings.id)
@@ -226,6 +268,7 @@ func (c *controller) Run(ctx context.Context) error {
 				vars = append(vars, v)
 			}
 		}
+		c.metrics.updates.Add(1)
 
 	UPDATEVARS:
 		for {
@@ -247,6 +290,25 @@ func (c *controller) Run(ctx context.Context) error {
 	}
 }
 
+// wait returns the time to wait for another change before updating the variables, the update
+// not being pushed back beyond the deadline.
+func (d DebounceConfig) wait(deadline time.Time) time.Duration {
+	wait := d.Delay
+	if remaining := time.Until(deadline); remaining < wait {
+		wait = remaining
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// varsState is the state of the providers the variables are built from.
+type varsState struct {
+	contexts map[string]interface{}
+	dynamics map[string][]dynamicProviderMapping
+}
+
 // Errors returns the channel to watch for reported errors.
 func (c *controller) Errors() <-chan error {
 	return c.errCh
@@ -292,6 +354,7 @@ type contextProviderState struct {
 	lock     sync.RWMutex
 	mapping  map[string]interface{}
 	signal   chan bool
+	metrics  *controllerMetrics
 }
 
 // Set sets the current mapping.
@@ -315,6 +378,7 @@ func (c *contextProviderState) Set(mapping map[string]interface{}) error {
 		return nil
 	}
 	c.mapping = mapping
+	c.metrics.changed()
 
 	// Notify the controller Run loop that a state has changed. The notification
 	// channel has buffer size 1 so this ensures that an update will always
@@ -348,6 +412,7 @@ type dynamicProviderState struct {
 	lock     sync.Mutex
 	mappings map[string]dynamicProviderMapping
 	signal   chan bool
+	metrics  *controllerMetrics
 }
 
 // AddOrUpdate adds or updates the current mapping for the dynamic provider.
@@ -384,6 +449,7 @@ func (c *dynamicProviderState) AddOrUpdate(id string, priority int, mapping map[
 		mapping:    mapping,
 		processors: processors,
 	}
+	c.metrics.changed()
 
 	select {
 	case c.signal <- true:
@@ -400,6 +466,7 @@ func (c *dynamicProviderState) Remove(id string) {
 	if exists {
 		// existed; remove and signal
 		delete(c.mappings, id)
+		c.metrics.changed()
 
 		select {
 		case c.signal <- true:
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package composable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// flappingProvider adds and removes a pod many times, ending with the state it started from.
+type flappingProvider struct {
+	flaps int
+	done  chan struct{}
+}
+
+func (p *flappingProvider) Run(comm DynamicProviderComm) error {
+	mapping := map[string]interface{}{"pod": map[string]interface{}{"name": "stable"}}
+	if err := comm.AddOrUpdate("stable", 0, mapping, nil); err != nil {
+		return err
+	}
+	// let the first update through before flapping
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < p.flaps; i++ {
+		mapping := map[string]interface{}{"pod": map[string]interface{}{"name": "flapping"}}
+		if err := comm.AddOrUpdate("flapping", 0, mapping, nil); err != nil {
+			return err
+		}
+		comm.Remove("flapping")
+	}
+	close(p.done)
+	<-comm.Done()
+	return comm.Err()
+}
+
+func TestControllerDebounce(t *testing.T) {
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+
+	provider := &flappingProvider{flaps: 50, done: make(chan struct{})}
+	c := &controller{
+		logger: log,
+		ch:     make(chan []*transpiler.Vars, 1),
+		errCh:  make(chan error),
+		dynamicProviders: map[string]*dynamicProviderState{
+			"fake": {provider: provider, mappings: map[string]dynamicProviderMapping{}},
+		},
+		debounce: DebounceConfig{Delay: 50 * time.Millisecond, MaxDelay: time.Second},
+		metrics:  &controllerMetrics{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		_ = c.Run(ctx)
+	}()
+
+	select {
+	case vars := <-c.Watch():
+		assert.Len(t, vars, 2)
+	case <-ctx.Done():
+		require.Fail(t, "timed out waiting for the first update")
+	}
+
+	<-provider.done
+	// the flapping pod ends removed, the update is suppressed once the debounce ends
+	require.Eventually(t, func() bool {
+		return c.metrics.unchanged.Load() == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	select {
+	case <-c.Watch():
+		assert.Fail(t, "no update expected when the variables did not change")
+	default:
+	}
+
+	assert.EqualValues(t, 1+2*provider.flaps, c.metrics.changes.Load())
+	assert.EqualValues(t, 1, c.metrics.updates.Load())
+	assert.EqualValues(t, 2*provider.flaps-1, c.metrics.coalesced.Load())
+}
+
+func TestDebounceWait(t *testing.T) {
+	d := DebounceConfig{Delay: time.Second, MaxDelay: time.Minute}
+	assert.Equal(t, time.Second, d.wait(time.Now().Add(time.Minute)))
+	assert.InDelta(t, 500*time.Millisecond, d.wait(time.Now().Add(500*time.Millisecond)), float64(100*time.Millisecond))
+	assert.Equal(t, time.Duration(0), d.wait(time.Now().Add(-time.Second)))
+}
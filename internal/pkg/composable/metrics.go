@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package composable
+
+import (
+	"sync/atomic"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// metricsName is the name of the composable metrics in the stats namespace of the monitoring endpoint.
+const metricsName = "composable"
+
+// controllerMetrics collects the metrics of the variable updates, it is a monitoring.Var reported under
+// composable in the stats of the monitoring endpoint.
+type controllerMetrics struct {
+	// changes counts the changes reported by the providers.
+	changes atomic.Int64
+	// pending counts the changes not yet part of an update.
+	pending atomic.Int64
+	// updates counts the variable updates sent to the coordinator.
+	updates atomic.Int64
+	// coalesced counts the changes merged into the update of another change.
+	coalesced atomic.Int64
+	// unchanged counts the updates dropped because the variables did not change, the providers
+	// having reverted their changes before the end of the debounce.
+	unchanged atomic.Int64
+}
+
+// register adds the metrics to the stats of the monitoring endpoint, replacing the ones of a previous controller.
+func (m *controllerMetrics) register() {
+	reg := monitoring.GetNamespace("stats").GetRegistry()
+	reg.Remove(metricsName)
+	reg.Add(metricsName, m, monitoring.Reported)
+}
+
+// changed records a change reported by a provider, m can be nil when the provider runs outside a controller.
+func (m *controllerMetrics) changed() {
+	if m == nil {
+		return
+	}
+	m.changes.Add(1)
+	m.pending.Add(1)
+}
+
+// batched records the end of a debounce, all the pending changes but one being coalesced.
+func (m *controllerMetrics) batched() {
+	if n := m.pending.Swap(0); n > 1 {
+		m.coalesced.Add(n - 1)
+	}
+}
+
+// Visit reports the metrics to the monitoring visitor.
+func (m *controllerMetrics) Visit(_ monitoring.Mode, vs monitoring.Visitor) {
+	vs.OnRegistryStart()
+	defer vs.OnRegistryFinished()
+
+	monitoring.ReportInt(vs, "changes", m.changes.Load())
+	monitoring.ReportInt(vs, "updates", m.updates.Load())
+	monitoring.ReportNamespace(vs, "suppressed", func() {
+		monitoring.ReportInt(vs, "coalesced", m.coalesced.Load())
+		monitoring.ReportInt(vs, "unchanged", m.unchanged.Load())
+	})
+}
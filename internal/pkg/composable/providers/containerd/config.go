@@ -0,0 +1,25 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package containerd
+
+import "time"
+
+// Config for containerd provider
+type Config struct {
+	// Host is the address of the CRI runtime service of containerd.
+	Host string `config:"host"`
+	// PollInterval is the interval at which the containers are listed.
+	PollInterval time.Duration `config:"poll_interval" validate:"nonzero,positive"`
+	// CleanupTimeout is the grace period between the stop of a container and the removal of its mapping,
+	// the container being reported as stopped in the meantime so its inputs can collect its last logs.
+	CleanupTimeout time.Duration `config:"cleanup_timeout" validate:"positive"`
+}
+
+// InitDefaults initializes the default values for the config.
+func (c *Config) InitDefaults() {
+	c.Host = "unix:///run/containerd/containerd.sock"
+	c.PollInterval = 10 * time.Second
+	c.CleanupTimeout = 60 * time.Second
+}
@@ -0,0 +1,286 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package containerd provides the containerd dynamic provider. It lists the containers with the
+// CRI API of containerd and exposes the same variables as the docker provider under containerd.
+package containerd
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/elastic/elastic-agent-autodiscover/utils"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent-libs/safemapstr"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// ContainerPriority is the priority that container mappings are added to the provider.
+const ContainerPriority = 0
+
+// States of the containers reported in the mapping, the same as the docker provider.
+const (
+	stateRunning = "running"
+	// stateStopped is reported during the cleanup timeout, until the mapping of the container is removed.
+	stateStopped = "stopped"
+)
+
+// requestTimeout is the maximum time a request to the CRI API can take.
+const requestTimeout = 10 * time.Second
+
+func init() {
+	composable.Providers.MustAddDynamicProvider("containerd", DynamicProviderBuilder)
+}
+
+// runtimeClient is the part of the CRI runtime service used by the provider.
+type runtimeClient interface {
+	ListContainers(ctx context.Context, in *runtimeapi.ListContainersRequest, opts ...grpc.CallOption) (*runtimeapi.ListContainersResponse, error)
+	ContainerStatus(ctx context.Context, in *runtimeapi.ContainerStatusRequest, opts ...grpc.CallOption) (*runtimeapi.ContainerStatusResponse, error)
+	PodSandboxStatus(ctx context.Context, in *runtimeapi.PodSandboxStatusRequest, opts ...grpc.CallOption) (*runtimeapi.PodSandboxStatusResponse, error)
+}
+
+type dynamicProvider struct {
+	logger *logger.Logger
+	config *Config
+}
+
+// Run runs the containerd dynamic provider.
+func (p *dynamicProvider) Run(comm composable.DynamicProviderComm) error {
+	conn, err := grpc.DialContext(comm, p.config.Host, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		// info only; return nil (do nothing)
+		p.logger.Infof("Containerd provider skipped, unable to connect: %s", err)
+		return nil
+	}
+	defer conn.Close()
+
+	w := newWatcher(p.logger, runtimeapi.NewRuntimeServiceClient(conn), p.config.CleanupTimeout)
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.poll(comm); err != nil {
+			p.logger.Errorf("failed to list containerd containers: %s", err)
+		}
+		select {
+		case <-comm.Done():
+			return comm.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DynamicProviderBuilder builds the dynamic provider.
+func DynamicProviderBuilder(logger *logger.Logger, c *config.Config, managed bool) (composable.DynamicProvider, error) {
+	var cfg Config
+	if c == nil {
+		c = config.New()
+	}
+	err := c.Unpack(&cfg)
+	if err != nil {
+		return nil, errors.New(err, "failed to unpack configuration")
+	}
+	return &dynamicProvider{logger, &cfg}, nil
+}
+
+// watchedContainer is a container the provider added a mapping for.
+type watchedContainer struct {
+	mapping    map[string]interface{}
+	processors []map[string]interface{}
+	// state is the reported state, empty until the mapping is added.
+	state string
+	// stoppedAt is when the container was seen stopped, zero while it runs.
+	stoppedAt time.Time
+	// removed is set once its mapping is removed, the container can still be listed until it is deleted.
+	removed bool
+}
+
+// watcher turns the listings of the containers into mapping changes.
+type watcher struct {
+	logger         *logger.Logger
+	client         runtimeClient
+	cleanupTimeout time.Duration
+	now            func() time.Time
+
+	containers map[string]*watchedContainer
+}
+
+func newWatcher(logger *logger.Logger, client runtimeClient, cleanupTimeout time.Duration) *watcher {
+	return &watcher{
+		logger:         logger,
+		client:         client,
+		cleanupTimeout: cleanupTimeout,
+		now:            time.Now,
+		containers:     map[string]*watchedContainer{},
+	}
+}
+
+// poll lists the containers and updates the mappings. A container that exited, or was deleted, is
+// reported as stopped until the cleanup timeout, even when it exited before it was seen running.
+func (w *watcher) poll(comm composable.DynamicProviderComm) error {
+	ctx, cancel := context.WithTimeout(comm, requestTimeout)
+	defer cancel()
+	resp, err := w.client.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return err
+	}
+
+	now := w.now()
+	listed := make(map[string]bool, len(resp.Containers))
+	for _, c := range resp.Containers {
+		if c.State == runtimeapi.ContainerState_CONTAINER_CREATED {
+			// not started yet
+			continue
+		}
+		listed[c.Id] = true
+		state := stateStopped
+		if c.State == runtimeapi.ContainerState_CONTAINER_RUNNING {
+			state = stateRunning
+		}
+
+		watched, ok := w.containers[c.Id]
+		if !ok {
+			watched = w.generateData(comm, c)
+			w.containers[c.Id] = watched
+		}
+		if !watched.removed && watched.state != state {
+			w.update(comm, c.Id, watched, state, now)
+		}
+		w.cleanup(comm, c.Id, watched, now)
+	}
+
+	for id, watched := range w.containers {
+		if listed[id] {
+			continue
+		}
+		// deleted, forget it once the cleanup timeout is over
+		if !watched.removed && watched.state != stateStopped {
+			w.update(comm, id, watched, stateStopped, now)
+		}
+		w.cleanup(comm, id, watched, now)
+		if watched.removed {
+			delete(w.containers, id)
+		}
+	}
+	return nil
+}
+
+// update reports the container in the state.
+func (w *watcher) update(comm composable.DynamicProviderComm, id string, watched *watchedContainer, state string, now time.Time) {
+	watched.state = state
+	watched.mapping["container"].(map[string]interface{})["state"] = state
+	if state == stateRunning {
+		watched.stoppedAt = time.Time{}
+	} else if watched.stoppedAt.IsZero() {
+		watched.stoppedAt = now
+	}
+	if err := comm.AddOrUpdate(id, ContainerPriority, watched.mapping, watched.processors); err != nil {
+		w.logger.Errorf("%s", err)
+	}
+}
+
+// cleanup removes the mapping of the container once it has been stopped for the cleanup timeout.
+func (w *watcher) cleanup(comm composable.DynamicProviderComm, id string, watched *watchedContainer, now time.Time) {
+	if watched.removed || watched.stoppedAt.IsZero() || now.Sub(watched.stoppedAt) < w.cleanupTimeout {
+		return
+	}
+	watched.removed = true
+	comm.Remove(id)
+}
+
+// generateData generates the mapping and the processors of the container, with the same fields as
+// the docker provider along with the path of its log file.
+func (w *watcher) generateData(ctx context.Context, c *runtimeapi.Container) *watchedContainer {
+	labelMap := mapstr.M{}
+	processorLabelMap := mapstr.M{}
+	for k, v := range c.Labels {
+		_ = safemapstr.Put(labelMap, k, v)
+		_, _ = processorLabelMap.Put(utils.DeDot(k), v)
+	}
+
+	var name, image string
+	if c.Metadata != nil {
+		name = c.Metadata.Name
+	}
+	if c.Image != nil {
+		image = c.Image.Image
+	}
+	if image == "" {
+		image = c.ImageRef
+	}
+
+	mapping := map[string]interface{}{
+		"id":   c.Id,
+		"name": name,
+		"image": map[string]interface{}{
+			"name": image,
+		},
+		"labels":       labelMap,
+		"ip_addresses": w.ipAddresses(ctx, c.PodSandboxId),
+	}
+	if logPath := w.logPath(ctx, c.Id); logPath != "" {
+		mapping["log_path"] = logPath
+	}
+
+	return &watchedContainer{
+		mapping: map[string]interface{}{
+			"container": mapping,
+		},
+		processors: []map[string]interface{}{
+			{
+				"add_fields": map[string]interface{}{
+					"fields": map[string]interface{}{
+						"id":         c.Id,
+						"name":       name,
+						"image.name": image,
+						"labels":     processorLabelMap,
+					},
+					"target": "container",
+				},
+			},
+		},
+	}
+}
+
+// ipAddresses returns the IP addresses of the pod sandbox of the container, the containers of a
+// sandbox sharing its network namespace.
+func (w *watcher) ipAddresses(ctx context.Context, sandboxID string) []interface{} {
+	ips := []interface{}{}
+	if sandboxID == "" {
+		return ips
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	resp, err := w.client.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{PodSandboxId: sandboxID})
+	if err != nil {
+		w.logger.Debugf("unable to get the status of pod sandbox %s: %s", sandboxID, err)
+		return ips
+	}
+	network := resp.GetStatus().GetNetwork()
+	if network.GetIp() != "" {
+		ips = append(ips, network.GetIp())
+	}
+	for _, ip := range network.GetAdditionalIps() {
+		ips = append(ips, ip.GetIp())
+	}
+	return ips
+}
+
+// logPath returns the path of the log file of the container, empty when it is unknown.
+func (w *watcher) logPath(ctx context.Context, containerID string) string {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	resp, err := w.client.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: containerID})
+	if err != nil {
+		w.logger.Debugf("unable to get the status of container %s: %s", containerID, err)
+		return ""
+	}
+	return resp.GetStatus().GetLogPath()
+}
@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package containerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+type fakeRuntimeClient struct {
+	containers []*runtimeapi.Container
+}
+
+func (f *fakeRuntimeClient) ListContainers(context.Context, *runtimeapi.ListContainersRequest, ...grpc.CallOption) (*runtimeapi.ListContainersResponse, error) {
+	return &runtimeapi.ListContainersResponse{Containers: f.containers}, nil
+}
+
+func (f *fakeRuntimeClient) ContainerStatus(_ context.Context, in *runtimeapi.ContainerStatusRequest, _ ...grpc.CallOption) (*runtimeapi.ContainerStatusResponse, error) {
+	return &runtimeapi.ContainerStatusResponse{Status: &runtimeapi.ContainerStatus{
+		LogPath: "/var/log/pods/" + in.ContainerId + ".log",
+	}}, nil
+}
+
+func (f *fakeRuntimeClient) PodSandboxStatus(context.Context, *runtimeapi.PodSandboxStatusRequest, ...grpc.CallOption) (*runtimeapi.PodSandboxStatusResponse, error) {
+	return &runtimeapi.PodSandboxStatusResponse{Status: &runtimeapi.PodSandboxStatus{
+		Network: &runtimeapi.PodSandboxNetworkStatus{
+			Ip:            "10.0.0.2",
+			AdditionalIps: []*runtimeapi.PodIP{{Ip: "fd00::2"}},
+		},
+	}}, nil
+}
+
+func TestWatcher(t *testing.T) {
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+	client := &fakeRuntimeClient{containers: []*runtimeapi.Container{{
+		Id:           "abc",
+		PodSandboxId: "sandbox",
+		Metadata:     &runtimeapi.ContainerMetadata{Name: "nginx"},
+		Image:        &runtimeapi.ImageSpec{Image: "nginx:latest"},
+		State:        runtimeapi.ContainerState_CONTAINER_RUNNING,
+		Labels:       map[string]string{"io.kubernetes.pod.name": "web"},
+	}, {
+		Id:    "created",
+		State: runtimeapi.ContainerState_CONTAINER_CREATED,
+	}}}
+	now := time.Now()
+	w := newWatcher(log, client, time.Minute)
+	w.now = func() time.Time { return now }
+	comm := ctesting.NewDynamicComm(context.Background())
+
+	require.NoError(t, w.poll(comm))
+	assert.Equal(t, []string{"abc"}, comm.CurrentIDs())
+	state, ok := comm.Current("abc")
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"container": map[string]interface{}{
+			"id":   "abc",
+			"name": "nginx",
+			"image": map[string]interface{}{
+				"name": "nginx:latest",
+			},
+			"labels":       map[string]interface{}{"io": map[string]interface{}{"kubernetes": map[string]interface{}{"pod": map[string]interface{}{"name": "web"}}}},
+			"ip_addresses": []interface{}{"10.0.0.2", "fd00::2"},
+			"log_path":     "/var/log/pods/abc.log",
+			"state":        "running",
+		},
+	}, state.Mapping)
+	assert.Equal(t, map[string]interface{}{"io_kubernetes_pod_name": "web"}, state.Processors[0]["add_fields"].(map[string]interface{})["fields"].(map[string]interface{})["labels"])
+
+	// exited, reported as stopped during the cleanup timeout
+	client.containers[0].State = runtimeapi.ContainerState_CONTAINER_EXITED
+	// short-lived, exited before it was seen running
+	client.containers = append(client.containers, &runtimeapi.Container{
+		Id:    "short",
+		State: runtimeapi.ContainerState_CONTAINER_EXITED,
+	})
+	now = now.Add(time.Second)
+	require.NoError(t, w.poll(comm))
+	assert.ElementsMatch(t, []string{"abc", "short"}, comm.CurrentIDs())
+	for _, id := range []string{"abc", "short"} {
+		state, _ := comm.Current(id)
+		assert.Equal(t, "stopped", state.Mapping["container"].(map[string]interface{})["state"], id)
+	}
+
+	// deleted, still reported until the cleanup timeout
+	client.containers = client.containers[1:]
+	now = now.Add(30 * time.Second)
+	require.NoError(t, w.poll(comm))
+	assert.ElementsMatch(t, []string{"abc", "short"}, comm.CurrentIDs())
+
+	now = now.Add(31 * time.Second)
+	require.NoError(t, w.poll(comm))
+	assert.Empty(t, comm.CurrentIDs())
+	assert.True(t, comm.Deleted("abc"))
+	assert.True(t, comm.Deleted("short"))
+	assert.NotContains(t, w.containers, "abc")
+	// still listed, not added again
+	assert.Contains(t, w.containers, "short")
+
+	require.NoError(t, w.poll(comm))
+	assert.Empty(t, comm.CurrentIDs())
+}
@@ -12,13 +12,22 @@ import (
 
 // Config for docker provider
 type Config struct {
-	Host           string            `config:"host"`
-	TLS            *docker.TLSConfig `config:"ssl"`
-	CleanupTimeout time.Duration     `config:"cleanup_timeout" validate:"positive"`
+	Host string            `config:"host"`
+	TLS  *docker.TLSConfig `config:"ssl"`
+	// CleanupTimeout is the grace period between the stop of a container and the removal of its mapping,
+	// the container being reported as stopped in the meantime so its inputs can collect its last logs.
+	CleanupTimeout time.Duration `config:"cleanup_timeout" validate:"positive"`
+	// Inspect inspects the running containers to report their health status and their networks.
+	Inspect bool `config:"inspect"`
+	// InspectInterval is the interval at which the running containers are inspected again, 0 only
+	// inspects them when they start.
+	InspectInterval time.Duration `config:"inspect_interval" validate:"positive"`
 }
 
 // InitDefaults initializes the default values for the config.
 func (c *Config) InitDefaults() {
 	c.Host = "unix:///var/run/docker.sock"
 	c.CleanupTimeout = 60 * time.Second
+	c.Inspect = true
+	c.InspectInterval = 30 * time.Second
 }
@@ -22,6 +22,13 @@ import (
 // ContainerPriority is the priority that container mappings are added to the provider.
 const ContainerPriority = 0
 
+// States of the containers reported in the mapping.
+const (
+	stateRunning = "running"
+	// stateStopped is reported during the cleanup timeout, until the mapping of the container is removed.
+	stateStopped = "stopped"
+)
+
 func init() {
 	composable.Providers.MustAddDynamicProvider("docker", DynamicProviderBuilder)
 }
@@ -47,7 +54,7 @@ func (c *dynamicProvider) Run(comm composable.DynamicProviderComm) error {
 	startListener := watcher.ListenStart()
 	stopListener := watcher.ListenStop()
 	stoppers := map[string]*time.Timer{}
-	stopTrigger := make(chan *dockerContainerData)
+	stopTrigger := make(chan *docker.Container)
 
 	if err := watcher.Start(); err != nil {
 		// info only; return nil (do nothing)
@@ -56,6 +63,41 @@ func (c *dynamicProvider) Run(comm composable.DynamicProviderComm) error {
 	}
 	defer watcher.Stop()
 
+	var client inspector
+	var refresh <-chan time.Time
+	if c.config.Inspect {
+		client, err = newInspector(c.config.Host, c.config.TLS)
+		if err != nil {
+			// info only; the containers are reported without their health status and networks
+			c.logger.Infof("Docker provider not inspecting containers, unable to connect: %s", err)
+		} else {
+			defer client.Close()
+			if c.config.InspectInterval > 0 {
+				ticker := time.NewTicker(c.config.InspectInterval)
+				defer ticker.Stop()
+				refresh = ticker.C
+			}
+		}
+	}
+	running := map[string]*docker.Container{}
+	details := map[string]*containerDetails{}
+
+	// update inspects the container again when it is running and updates its mapping.
+	update := func(container *docker.Container, state string) {
+		if client != nil && state == stateRunning {
+			d, err := inspect(comm, client, container.ID)
+			if err != nil {
+				c.logger.Debugf("unable to inspect container %s: %s", container.ID, err)
+			} else {
+				details[container.ID] = d
+			}
+		}
+		data := generateData(container, state, details[container.ID])
+		if err := comm.AddOrUpdate(container.ID, ContainerPriority, data.mapping, data.processors); err != nil {
+			c.logger.Errorf("%s", err)
+		}
+	}
+
 	for {
 		select {
 		case <-comm.Done():
@@ -69,34 +111,41 @@ func (c *dynamicProvider) Run(comm composable.DynamicProviderComm) error {
 			close(stopTrigger)
 			return comm.Err()
 		case event := <-startListener.Events():
-			data, err := generateData(event)
+			container, err := containerFromEvent(event)
 			if err != nil {
 				c.logger.Errorf("%s", err)
 				continue
 			}
-			if stopper, ok := stoppers[data.container.ID]; ok {
-				c.logger.Debugf("container %s is restarting, aborting pending stop", data.container.ID)
+			if stopper, ok := stoppers[container.ID]; ok {
+				c.logger.Debugf("container %s is restarting, aborting pending stop", container.ID)
 				stopper.Stop()
-				delete(stoppers, data.container.ID)
-				continue
-			}
-			err = comm.AddOrUpdate(data.container.ID, ContainerPriority, data.mapping, data.processors)
-			if err != nil {
-				c.logger.Errorf("%s", err)
+				delete(stoppers, container.ID)
 			}
+			running[container.ID] = container
+			update(container, stateRunning)
 		case event := <-stopListener.Events():
-			data, err := generateData(event)
+			container, err := containerFromEvent(event)
 			if err != nil {
 				c.logger.Errorf("%s", err)
 				continue
 			}
+			// the container is reported as stopped until the cleanup timeout, so its inputs
+			// can still collect its last logs before they are torn down
+			delete(running, container.ID)
+			update(container, stateStopped)
 			stopper := time.AfterFunc(c.config.CleanupTimeout, func() {
-				stopTrigger <- data
+				stopTrigger <- container
 			})
-			stoppers[data.container.ID] = stopper
-		case data := <-stopTrigger:
-			delete(stoppers, data.container.ID)
-			comm.Remove(data.container.ID)
+			stoppers[container.ID] = stopper
+		case container := <-stopTrigger:
+			delete(stoppers, container.ID)
+			delete(details, container.ID)
+			comm.Remove(container.ID)
+		case <-refresh:
+			// the health status changes while the container runs
+			for _, container := range running {
+				update(container, stateRunning)
+			}
 		}
 	}
 }
@@ -114,12 +163,42 @@ func DynamicProviderBuilder(logger *logger.Logger, c *config.Config, managed boo
 	return &dynamicProvider{logger, &cfg}, nil
 }
 
-func generateData(event bus.Event) (*dockerContainerData, error) {
+// CompatibleProviderBuilder returns the builder of a dynamic provider for a container runtime exposing
+// a Docker compatible API, like Podman. The provider has the configuration and the variables of the docker
+// provider, its host defaults to defaultHost.
+func CompatibleProviderBuilder(defaultHost string) composable.DynamicProviderBuilder {
+	return func(logger *logger.Logger, c *config.Config, managed bool) (composable.DynamicProvider, error) {
+		if c == nil {
+			c = config.New()
+		}
+		var host struct {
+			Host string `config:"host"`
+		}
+		if err := c.Unpack(&host); err != nil {
+			return nil, errors.New(err, "failed to unpack configuration")
+		}
+		p, err := DynamicProviderBuilder(logger, c, managed)
+		if err != nil {
+			return nil, err
+		}
+		if host.Host == "" {
+			p.(*dynamicProvider).config.Host = defaultHost
+		}
+		return p, nil
+	}
+}
+
+func containerFromEvent(event bus.Event) (*docker.Container, error) {
 	container, ok := event["container"].(*docker.Container)
 	if !ok {
 		return nil, fmt.Errorf("unable to get container from watcher event")
 	}
+	return container, nil
+}
 
+// generateData generates the mapping and the processors of the container, details is nil
+// when the container was not inspected.
+func generateData(container *docker.Container, state string, details *containerDetails) *dockerContainerData {
 	labelMap := mapstr.M{}
 	processorLabelMap := mapstr.M{}
 	for k, v := range container.Labels {
@@ -127,17 +206,47 @@ func generateData(event bus.Event) (*dockerContainerData, error) {
 		_, _ = processorLabelMap.Put(utils.DeDot(k), v)
 	}
 
-	data := &dockerContainerData{
+	ports := make([]interface{}, 0, len(container.Ports))
+	for _, p := range container.Ports {
+		port := map[string]interface{}{
+			"private": int(p.PrivatePort),
+			"type":    p.Type,
+		}
+		if p.PublicPort != 0 {
+			port["public"] = int(p.PublicPort)
+			port["ip"] = p.IP
+		}
+		ports = append(ports, port)
+	}
+	ipAddresses := make([]interface{}, 0, len(container.IPAddresses))
+	for _, ip := range container.IPAddresses {
+		ipAddresses = append(ipAddresses, ip)
+	}
+
+	mapping := map[string]interface{}{
+		"id":   container.ID,
+		"name": container.Name,
+		"image": map[string]interface{}{
+			"name": container.Image,
+		},
+		"labels":       labelMap,
+		"state":        state,
+		"ip_addresses": ipAddresses,
+		"ports":        ports,
+	}
+	if details != nil {
+		mapping["health"] = details.health
+		mapping["hostname"] = details.hostname
+		mapping["network"] = map[string]interface{}{
+			"mode": details.networkMode,
+		}
+		mapping["networks"] = details.networks
+	}
+
+	return &dockerContainerData{
 		container: container,
 		mapping: map[string]interface{}{
-			"container": map[string]interface{}{
-				"id":   container.ID,
-				"name": container.Name,
-				"image": map[string]interface{}{
-					"name": container.Image,
-				},
-				"labels": labelMap,
-			},
+			"container": mapping,
 		},
 		processors: []map[string]interface{}{
 			{
@@ -153,5 +262,4 @@ func generateData(event bus.Event) (*dockerContainerData, error) {
 			},
 		},
 	}
-	return data, nil
 }
@@ -5,14 +5,21 @@
 package docker
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent-autodiscover/bus"
 	"github.com/elastic/elastic-agent-autodiscover/docker"
 	"github.com/elastic/elastic-agent-libs/mapstr"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
 func TestGenerateData(t *testing.T) {
@@ -24,13 +31,19 @@ func TestGenerateData(t *testing.T) {
 			"do.not.include":          "true",
 			"co.elastic.logs/disable": "true",
 		},
+		IPAddresses: []string{"172.17.0.2"},
+		Ports: []types.Port{
+			{PrivatePort: 80, PublicPort: 8080, IP: "0.0.0.0", Type: "tcp"},
+			{PrivatePort: 53, Type: "udp"},
+		},
 	}
 	event := bus.Event{
 		"container": container,
 	}
 
-	data, err := generateData(event)
+	eventContainer, err := containerFromEvent(event)
 	require.NoError(t, err)
+	data := generateData(eventContainer, stateRunning, nil)
 	mapping := map[string]interface{}{
 		"container": map[string]interface{}{
 			"id":   container.ID,
@@ -42,6 +55,12 @@ func TestGenerateData(t *testing.T) {
 				"do": mapstr.M{"not": mapstr.M{"include": "true"}},
 				"co": mapstr.M{"elastic": mapstr.M{"logs/disable": "true"}},
 			},
+			"state":        "running",
+			"ip_addresses": []interface{}{"172.17.0.2"},
+			"ports": []interface{}{
+				map[string]interface{}{"private": 80, "public": 8080, "ip": "0.0.0.0", "type": "tcp"},
+				map[string]interface{}{"private": 53, "type": "udp"},
+			},
 		},
 	}
 	processors := []map[string]interface{}{
@@ -64,4 +83,70 @@ func TestGenerateData(t *testing.T) {
 	assert.Equal(t, container, data.container)
 	assert.Equal(t, mapping, data.mapping)
 	assert.Equal(t, processors, data.processors)
+
+	_, err = containerFromEvent(bus.Event{})
+	assert.Error(t, err)
+}
+
+type fakeInspector struct {
+	info types.ContainerJSON
+}
+
+func (f *fakeInspector) ContainerInspect(context.Context, string) (types.ContainerJSON, error) {
+	return f.info, nil
+}
+
+func (f *fakeInspector) Close() error {
+	return nil
+}
+
+func TestInspect(t *testing.T) {
+	client := &fakeInspector{info: types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State:      &types.ContainerState{Health: &types.Health{Status: types.Unhealthy}},
+			HostConfig: &container.HostConfig{NetworkMode: "bridge"},
+		},
+		Config: &container.Config{Hostname: "abc"},
+		NetworkSettings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"frontend": {IPAddress: "10.0.1.2", Gateway: "10.0.1.1", MacAddress: "02:42:0a:00:01:02"},
+			"backend":  {IPAddress: "10.0.0.2", Gateway: "10.0.0.1", MacAddress: "02:42:0a:00:00:02"},
+		}},
+	}}
+
+	details, err := inspect(context.Background(), client, "abc")
+	require.NoError(t, err)
+	data := generateData(&docker.Container{ID: "abc"}, stateStopped, details)
+	mapping := data.mapping["container"].(map[string]interface{})
+	assert.Equal(t, "stopped", mapping["state"])
+	assert.Equal(t, "unhealthy", mapping["health"])
+	assert.Equal(t, "abc", mapping["hostname"])
+	assert.Equal(t, map[string]interface{}{"mode": "bridge"}, mapping["network"])
+	// the networks are ordered by name
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "backend", "ip": "10.0.0.2", "gateway": "10.0.0.1", "mac_address": "02:42:0a:00:00:02"},
+		map[string]interface{}{"name": "frontend", "ip": "10.0.1.2", "gateway": "10.0.1.1", "mac_address": "02:42:0a:00:01:02"},
+	}, mapping["networks"])
+
+	// containers without a health check
+	client.info.State.Health = nil
+	details, err = inspect(context.Background(), client, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "none", details.health)
+}
+
+func TestCompatibleProviderBuilder(t *testing.T) {
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+	builder := CompatibleProviderBuilder("unix:///run/podman/podman.sock")
+
+	p, err := builder(log, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///run/podman/podman.sock", p.(*dynamicProvider).config.Host)
+	assert.Equal(t, 60*time.Second, p.(*dynamicProvider).config.CleanupTimeout)
+
+	p, err = builder(log, config.MustNewConfigFrom(map[string]interface{}{
+		"host": "unix:///run/user/1000/podman/podman.sock",
+	}), false)
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///run/user/1000/podman/podman.sock", p.(*dynamicProvider).config.Host)
 }
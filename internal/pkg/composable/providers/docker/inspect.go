@@ -0,0 +1,103 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package docker
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/tlsconfig"
+
+	"github.com/elastic/elastic-agent-autodiscover/docker"
+)
+
+// inspectTimeout is the maximum time an inspection of a container can take.
+const inspectTimeout = 5 * time.Second
+
+// inspector returns the low-level information of a container.
+type inspector interface {
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	Close() error
+}
+
+// newInspector creates a docker client connecting to the host the same way the watcher does.
+func newInspector(host string, tls *docker.TLSConfig) (inspector, error) {
+	var httpClient *http.Client
+	if tls != nil {
+		tlsc, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:   tls.CA,
+			CertFile: tls.Certificate,
+			KeyFile:  tls.Key,
+		})
+		if err != nil {
+			return nil, err
+		}
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsc,
+			},
+		}
+	}
+	client, err := docker.NewClient(host, httpClient, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// containerDetails are the details of a container only available by inspecting it.
+type containerDetails struct {
+	health      string
+	hostname    string
+	networkMode string
+	networks    []interface{}
+}
+
+// inspect returns the details of the container, nil when it cannot be inspected.
+func inspect(ctx context.Context, client inspector, containerID string) (*containerDetails, error) {
+	ctx, cancel := context.WithTimeout(ctx, inspectTimeout)
+	defer cancel()
+	info, err := client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &containerDetails{health: types.NoHealthcheck}
+	if info.ContainerJSONBase != nil {
+		if info.State != nil && info.State.Health != nil {
+			details.health = info.State.Health.Status
+		}
+		if info.HostConfig != nil {
+			details.networkMode = string(info.HostConfig.NetworkMode)
+		}
+	}
+	if info.Config != nil {
+		details.hostname = info.Config.Hostname
+	}
+	if info.NetworkSettings != nil {
+		// sorted so the mapping does not change when the container is inspected again
+		names := make([]string, 0, len(info.NetworkSettings.Networks))
+		for name := range info.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			endpoint := info.NetworkSettings.Networks[name]
+			if endpoint == nil {
+				continue
+			}
+			details.networks = append(details.networks, map[string]interface{}{
+				"name":        name,
+				"ip":          endpoint.IPAddress,
+				"gateway":     endpoint.Gateway,
+				"mac_address": endpoint.MacAddress,
+			})
+		}
+	}
+	return details, nil
+}
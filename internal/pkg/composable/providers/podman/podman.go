@@ -0,0 +1,24 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package podman provides the podman dynamic provider. Podman serves a Docker compatible REST API
+// on its socket, so the provider watches the containers the same way the docker provider does and
+// exposes the same variables under podman.
+package podman
+
+import (
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/docker"
+)
+
+// defaultHost is the socket of the rootful Podman service, rootless services listen on
+// unix:///run/user/<uid>/podman/podman.sock.
+const defaultHost = "unix:///run/podman/podman.sock"
+
+func init() {
+	composable.Providers.MustAddDynamicProvider("podman", DynamicProviderBuilder)
+}
+
+// DynamicProviderBuilder builds the dynamic provider.
+var DynamicProviderBuilder = docker.CompatibleProviderBuilder(defaultHost)
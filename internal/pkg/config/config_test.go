@@ -127,3 +127,20 @@ func dumpToYAML(t *testing.T, out string, in interface{}) {
 	err = os.WriteFile(out, b, 0600)
 	require.NoError(t, err)
 }
+
+func FuzzNewConfigFrom(f *testing.F) {
+	for _, name := range []string{"standalone1.yml", "standalone2.yml", "standalone-with-inputs.yml"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(f, err)
+		f.Add(data)
+	}
+	f.Add([]byte("inputs:\n  - type: logfile\n    paths: ['/var/log/${host.name}']\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cfg, err := NewConfigFrom(data)
+		if err != nil {
+			return
+		}
+		_, _ = cfg.ToMapStr()
+	})
+}
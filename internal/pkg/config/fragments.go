@@ -0,0 +1,109 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DefaultFragmentsInclude are the patterns of the fragment files when no include pattern is set.
+var DefaultFragmentsInclude = []string{"*.yml", "*.yaml"}
+
+// Fragments defines directories of configuration fragments, like /etc/elastic-agent/inputs.d, merged into
+// the standalone configuration. A fragment is a partial configuration, its inputs are appended to the
+// inputs of the configuration and its other settings are merged, in the order of the directories then of
+// the file names so the result does not depend on the file system.
+type Fragments struct {
+	// Paths are the directories of the fragments.
+	Paths []string `config:"paths" yaml:"paths,omitempty" json:"paths,omitempty"`
+	// Include are the patterns of the names of the fragment files, DefaultFragmentsInclude when empty.
+	Include []string `config:"include" yaml:"include,omitempty" json:"include,omitempty"`
+	// Exclude are the patterns of the names of the files ignored, even when they match an include pattern.
+	Exclude []string `config:"exclude" yaml:"exclude,omitempty" json:"exclude,omitempty"`
+}
+
+// Validate validates the patterns of the fragments.
+func (f *Fragments) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid fragment pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Discoverer returns a DiscoverFunc discovering the files of discover followed by the fragments.
+func (f *Fragments) Discoverer(discover DiscoverFunc) DiscoverFunc {
+	if f == nil || len(f.Paths) == 0 {
+		return discover
+	}
+	return func() ([]string, error) {
+		files, err := discover()
+		if err != nil && !errors.Is(err, ErrNoConfiguration) {
+			return nil, err
+		}
+		fragments, err := f.discover()
+		if err != nil {
+			return nil, err
+		}
+		return append(files, fragments...), nil
+	}
+}
+
+// Contains returns true when the file is a fragment.
+func (f *Fragments) Contains(file string) bool {
+	if f == nil {
+		return false
+	}
+	dir := filepath.Dir(file)
+	for _, p := range f.Paths {
+		if filepath.Clean(p) == dir {
+			return f.matches(filepath.Base(file))
+		}
+	}
+	return false
+}
+
+func (f *Fragments) discover() ([]string, error) {
+	var files []string
+	for _, p := range f.Paths {
+		// entries are sorted by file name
+		entries, err := os.ReadDir(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the configuration fragments of %s: %w", p, err)
+		}
+		for _, e := range entries {
+			if e.IsDir() || !f.matches(e.Name()) {
+				continue
+			}
+			files = append(files, filepath.Join(filepath.Clean(p), e.Name()))
+		}
+	}
+	return files, nil
+}
+
+func (f *Fragments) matches(name string) bool {
+	include := f.Include
+	if len(include) == 0 {
+		include = DefaultFragmentsInclude
+	}
+	return matchAny(include, name) && !matchAny(f.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
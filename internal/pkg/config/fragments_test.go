@@ -0,0 +1,132 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestFragmentsDiscoverer(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"20-metrics.yml":     "",
+		"10-logs.yml":        "",
+		"30-output.yaml":     "",
+		"40-old.yml.bak":     "",
+		"50-draft.yml":       "",
+		"README.md":          "",
+		"nested/60-skip.yml": "",
+	})
+	writeFiles(t, other, map[string]string{"00-first.yml": ""})
+
+	fragments := &Fragments{
+		Paths:   []string{dir, other, filepath.Join(dir, "missing")},
+		Exclude: []string{"*-draft.yml"},
+	}
+	discover := fragments.Discoverer(Discoverer("elastic-agent.yml"))
+	files, err := discover()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "10-logs.yml"),
+		filepath.Join(dir, "20-metrics.yml"),
+		filepath.Join(dir, "30-output.yaml"),
+		filepath.Join(other, "00-first.yml"),
+	}, files)
+
+	assert.True(t, fragments.Contains(filepath.Join(dir, "10-logs.yml")))
+	assert.False(t, fragments.Contains(filepath.Join(dir, "50-draft.yml")))
+	assert.False(t, fragments.Contains("elastic-agent.yml"))
+	assert.False(t, (*Fragments)(nil).Contains(filepath.Join(dir, "10-logs.yml")))
+}
+
+func TestFragmentsValidate(t *testing.T) {
+	assert.NoError(t, (&Fragments{Include: []string{"*.yml"}, Exclude: []string{"*~"}}).Validate())
+	assert.Error(t, (&Fragments{Exclude: []string{"[a-"}}).Validate())
+}
+
+func TestLoaderWithFragments(t *testing.T) {
+	log, _ := logger.New("", false)
+	dir := t.TempDir()
+	fragmentsDir := filepath.Join(dir, "inputs.d")
+	writeFiles(t, dir, map[string]string{
+		"elastic-agent.yml": `
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: system-metrics
+    type: system/metrics
+`,
+		"inputs.d/10-logs.yml": `
+inputs:
+  - id: nginx-logs
+    type: filestream
+agent.logging.level: debug
+`,
+		"inputs.d/20-logs.yml": `
+inputs:
+  - id: app-logs
+    type: filestream
+agent.logging.level: warning
+`,
+	})
+	fragments := &Fragments{Paths: []string{fragmentsDir}}
+
+	load := func(t *testing.T) (map[string]interface{}, error) {
+		files, err := fragments.Discoverer(Discoverer(filepath.Join(dir, "elastic-agent.yml")))()
+		require.NoError(t, err)
+		cfg, err := NewLoader(log, "").WithFragments(fragments).Load(files)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.ToMapStr()
+	}
+
+	t.Run("fragments are merged in the order of their names", func(t *testing.T) {
+		m, err := load(t)
+		require.NoError(t, err)
+
+		var ids []interface{}
+		for _, inp := range m["inputs"].([]interface{}) {
+			ids = append(ids, inp.(map[string]interface{})["id"])
+		}
+		assert.Equal(t, []interface{}{"system-metrics", "nginx-logs", "app-logs"}, ids)
+		assert.Equal(t, "warning", m["agent"].(map[string]interface{})["logging"].(map[string]interface{})["level"])
+		assert.Contains(t, m, "outputs")
+	})
+
+	t.Run("input defined twice", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"inputs.d/30-dup.yml": "inputs:\n  - id: system-metrics\n    type: system/metrics\n"})
+		defer os.Remove(filepath.Join(fragmentsDir, "30-dup.yml"))
+
+		_, err := load(t)
+		assert.ErrorContains(t, err, "input 'system-metrics'")
+	})
+
+	t.Run("invalid fragment", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"inputs.d/30-invalid.yml": "inputs: ["})
+		defer os.Remove(filepath.Join(fragmentsDir, "30-invalid.yml"))
+
+		_, err := load(t)
+		assert.ErrorContains(t, err, "30-invalid.yml")
+	})
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	}
+}
@@ -18,6 +18,7 @@ import (
 type Loader struct {
 	logger       *logger.Logger
 	inputsFolder string
+	fragments    *Fragments
 }
 
 // NewLoader creates a new Loader instance to load configuration
@@ -26,6 +27,12 @@ func NewLoader(logger *logger.Logger, inputsFolder string) *Loader {
 	return &Loader{logger: logger, inputsFolder: inputsFolder}
 }
 
+// WithFragments sets the configuration fragments of the files to load, see Fragments.
+func (l *Loader) WithFragments(fragments *Fragments) *Loader {
+	l.fragments = fragments
+	return l
+}
+
 // Load iterates over the list of files and loads the confguration from them.
 // If a configuration file is under the folder set in `agent.config.inputs.path`
 // it is appended to a list. If it is a regular config file, it is merged into
@@ -34,23 +41,51 @@ func NewLoader(logger *logger.Logger, inputsFolder string) *Loader {
 func (l *Loader) Load(files []string) (*Config, error) {
 	inputsList := make([]*ucfg.Config, 0)
 	merger := cfgutil.NewCollector(nil)
+	inputIDs := newInputIDs()
 	for _, f := range files {
 		cfg, err := LoadFile(f)
 		if err != nil {
 			if l.isFileUnderInputsFolder(f) {
 				return nil, fmt.Errorf("failed to load external configuration file '%s': %w. Are you sure it contains an inputs section?", f, err)
 			}
+			if l.fragments.Contains(f) {
+				return nil, fmt.Errorf("failed to load configuration fragment '%s': %w", f, err)
+			}
 			return nil, fmt.Errorf("failed to load configuration file '%s': %w", f, err)
 		}
 		l.logger.Debugf("Loaded configuration from %s", f)
-		if l.isFileUnderInputsFolder(f) {
+		if l.fragments.Contains(f) {
+			inp, err := getInput(cfg)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get configuration from fragment '%s': %w", f, err)
+			}
+			if err := inputIDs.add(f, inp, true); err != nil {
+				return nil, err
+			}
+			inputsList = append(inputsList, inp...)
+			if _, err := cfg.access().Remove("inputs", -1); err != nil {
+				return nil, fmt.Errorf("failed to remove the inputs of fragment '%s': %w", f, err)
+			}
+			if err := merger.Add(cfg.access(), nil); err != nil {
+				return nil, fmt.Errorf("failed to merge configuration fragment '%s' to existing one: %w", f, err)
+			}
+			l.logger.Debugf("Merged configuration fragment %s with %d input(s) into result", f, len(inp))
+		} else if l.isFileUnderInputsFolder(f) {
 			inp, err := getInput(cfg)
 			if err != nil {
 				return nil, fmt.Errorf("cannot get configuration from '%s': %w", f, err)
 			}
+			if err := inputIDs.add(f, inp, false); err != nil {
+				return nil, err
+			}
 			inputsList = append(inputsList, inp...)
 			l.logger.Debugf("Loaded %s input(s) from configuration from %s", len(inp), f)
 		} else {
+			if inp, err := getInput(cfg); err == nil {
+				if err := inputIDs.add(f, inp, false); err != nil {
+					return nil, err
+				}
+			}
 			if err := merger.Add(cfg.access(), err); err != nil {
 				return nil, fmt.Errorf("failed to merge configuration file '%s' to existing one: %w", f, err)
 			}
@@ -96,6 +131,33 @@ func getInput(c *Config) ([]*ucfg.Config, error) {
 	return tmpConfig.Inputs, nil
 }
 
+// inputIDs tracks the files defining the input IDs, so an input of a fragment cannot silently
+// collide with an input defined in another file.
+type inputIDs map[string]inputSource
+
+type inputSource struct {
+	file     string
+	fragment bool
+}
+
+func newInputIDs() inputIDs {
+	return make(inputIDs)
+}
+
+func (ids inputIDs) add(file string, inputs []*ucfg.Config, fragment bool) error {
+	for _, inp := range inputs {
+		id, err := inp.String("id", -1)
+		if err != nil || id == "" {
+			continue
+		}
+		if prev, found := ids[id]; found && (fragment || prev.fragment) {
+			return fmt.Errorf("input '%s' of '%s' is already defined in '%s'", id, file, prev.file)
+		}
+		ids[id] = inputSource{file: file, fragment: fragment}
+	}
+	return nil
+}
+
 func (l *Loader) isFileUnderInputsFolder(f string) bool {
 	if matches, err := filepath.Match(l.inputsFolder, f); !matches || err != nil {
 		return false
@@ -38,8 +38,8 @@ func LoadFullAgentConfig(logger *logger.Logger, cfgPath string, failOnFleetMissi
 
 	if configuration.IsStandalone(cfg.Fleet) {
 		// When in standalone we load the configuration again with inputs that are defined in the paths.ExternalInputs.
-		loader := config.NewLoader(logger, paths.ExternalInputs())
-		discover := config.Discoverer(cfgPath, cfg.Settings.Path, paths.ExternalInputs())
+		loader := config.NewLoader(logger, paths.ExternalInputs()).WithFragments(cfg.Settings.Fragments)
+		discover := cfg.Settings.Fragments.Discoverer(config.Discoverer(cfgPath, cfg.Settings.Path, paths.ExternalInputs()))
 		files, err := discover()
 		if err != nil {
 			return nil, fmt.Errorf("could not discover configuration files: %w", err)
@@ -4,7 +4,11 @@
 
 package backoff
 
-import "time"
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+)
 
 // Backoff defines the interface for backoff strategies.
 type Backoff interface {
@@ -27,3 +31,25 @@ func WaitOnError(b Backoff, err error) bool {
 	}
 	return b.Wait()
 }
+
+// Option is an option of the backoff strategies.
+type Option func(o *options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock makes the backoff wait on the clock, tests use a clock.Mock to not wait for real.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.Real()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
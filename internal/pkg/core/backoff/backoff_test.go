@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 )
 
 type factory func(<-chan struct{}) Backoff
@@ -96,3 +98,44 @@ func TestNextWait(t *testing.T) {
 		})
 	}
 }
+
+func TestWaitOnClock(t *testing.T) {
+	init := time.Minute
+	max := time.Hour
+
+	tests := map[string]func(<-chan struct{}, clock.Clock) Backoff{
+		"ExpBackoff": func(done <-chan struct{}, c clock.Clock) Backoff {
+			return NewExpBackoff(done, init, max, WithClock(c))
+		},
+		"EqualJitterBackoff": func(done <-chan struct{}, c clock.Clock) Backoff {
+			return NewEqualJitterBackoff(done, init, max, WithClock(c))
+		},
+	}
+
+	for name, f := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := make(chan struct{})
+			defer close(c)
+
+			m := clock.NewMock(time.Now())
+			b := f(c, m)
+			nextWait := b.NextWait()
+
+			waited := make(chan bool)
+			go func() {
+				waited <- b.Wait()
+			}()
+
+			m.BlockUntil(1)
+			m.Advance(nextWait - time.Nanosecond)
+			select {
+			case <-waited:
+				t.Fatal("backoff returned before its wait elapsed on the clock")
+			default:
+			}
+
+			m.Advance(time.Nanosecond)
+			assert.True(t, <-waited)
+		})
+	}
+}
@@ -7,6 +7,8 @@ package backoff
 import (
 	"math/rand"
 	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 )
 
 // EqualJitterBackoff implements an equal jitter strategy, meaning the wait time will consist of two parts,
@@ -20,17 +22,20 @@ type EqualJitterBackoff struct {
 	max      time.Duration
 	nextRand time.Duration
 
-	last time.Time
+	last  time.Time
+	clock clock.Clock
 }
 
 // NewEqualJitterBackoff returns a new EqualJitter object.
-func NewEqualJitterBackoff(done <-chan struct{}, init, max time.Duration) Backoff {
+func NewEqualJitterBackoff(done <-chan struct{}, init, max time.Duration, opts ...Option) Backoff {
+	o := newOptions(opts)
 	return &EqualJitterBackoff{
 		duration: init * 2, // Allow to sleep at least the init period on the first wait.
 		done:     done,
 		init:     init,
 		max:      max,
 		nextRand: time.Duration(rand.Int63n(int64(init))), //nolint:gosec
+		clock:    o.clock,
 	}
 }
 
@@ -60,8 +65,8 @@ func (b *EqualJitterBackoff) Wait() bool {
 	select {
 	case <-b.done:
 		return false
-	case <-time.After(backoff):
-		b.last = time.Now()
+	case <-b.clock.After(backoff):
+		b.last = b.clock.Now()
 		return true
 	}
 }
@@ -6,6 +6,8 @@ package backoff
 
 import (
 	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
 )
 
 // ExpBackoff exponential backoff, will wait an initial time and exponentially
@@ -18,16 +20,19 @@ type ExpBackoff struct {
 	init time.Duration
 	max  time.Duration
 
-	last time.Time
+	last  time.Time
+	clock clock.Clock
 }
 
 // NewExpBackoff returns a new exponential backoff.
-func NewExpBackoff(done <-chan struct{}, init, max time.Duration) Backoff {
+func NewExpBackoff(done <-chan struct{}, init, max time.Duration, opts ...Option) Backoff {
+	o := newOptions(opts)
 	return &ExpBackoff{
 		duration: init,
 		done:     done,
 		init:     init,
 		max:      max,
+		clock:    o.clock,
 	}
 }
 
@@ -52,8 +57,8 @@ func (b *ExpBackoff) Wait() bool {
 	select {
 	case <-b.done:
 		return false
-	case <-time.After(b.duration):
-		b.last = time.Now()
+	case <-b.clock.After(b.duration):
+		b.last = b.clock.Now()
 		return true
 	}
 }
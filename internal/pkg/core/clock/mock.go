@@ -0,0 +1,196 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Mock is a Clock where the time only moves when advanced by the test. The timers, tickers and After
+// channels fire during Advance once their deadline is reached, in the order of their deadlines.
+type Mock struct {
+	mx      sync.Mutex
+	now     time.Time
+	waiters []*mockWaiter
+	// changed is closed and replaced each time a waiter is added, see BlockUntil
+	changed chan struct{}
+}
+
+// NewMock returns a Mock clock set at now.
+func NewMock(now time.Time) *Mock {
+	return &Mock{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the current virtual time.
+func (m *Mock) Now() time.Time {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return m.now
+}
+
+// Since returns the virtual time elapsed since t.
+func (m *Mock) Since(t time.Time) time.Duration {
+	return m.Now().Sub(t)
+}
+
+// After returns a channel receiving the virtual time once advanced by d.
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	return m.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer firing once the clock is advanced by d.
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	w := &mockWaiter{clock: m, c: make(chan time.Time, 1)}
+	m.add(w, m.now.Add(d))
+	return &mockTimer{w}
+}
+
+// NewTicker returns a Ticker firing each time the clock is advanced by d.
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	w := &mockWaiter{clock: m, c: make(chan time.Time, 1), period: d}
+	m.add(w, m.now.Add(d))
+	return &mockTicker{w}
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers reaching their deadline.
+func (m *Mock) Advance(d time.Duration) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	end := m.now.Add(d)
+	for len(m.waiters) > 0 && !m.waiters[0].deadline.After(end) {
+		w := m.waiters[0]
+		m.waiters = m.waiters[1:]
+		m.now = w.deadline
+		// like the time package the tick is dropped when the receiver is behind
+		select {
+		case w.c <- m.now:
+		default:
+		}
+		if w.period > 0 {
+			m.add(w, w.deadline.Add(w.period))
+		} else {
+			w.active = false
+		}
+	}
+	m.now = end
+}
+
+// Waiters returns the number of active timers, tickers and After channels.
+func (m *Mock) Waiters() int {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return len(m.waiters)
+}
+
+// BlockUntil blocks until at least n timers, tickers or After channels are active, allowing a test to
+// wait for the code under test to reach the point where it waits on the clock before advancing it.
+func (m *Mock) BlockUntil(n int) {
+	for {
+		m.mx.Lock()
+		count := len(m.waiters)
+		changed := m.changed
+		m.mx.Unlock()
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// add schedules the waiter at the deadline, called with the lock held.
+func (m *Mock) add(w *mockWaiter, deadline time.Time) {
+	w.deadline = deadline
+	w.active = true
+	i := sort.Search(len(m.waiters), func(i int) bool {
+		return m.waiters[i].deadline.After(deadline)
+	})
+	m.waiters = append(m.waiters, nil)
+	copy(m.waiters[i+1:], m.waiters[i:])
+	m.waiters[i] = w
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// remove unschedules the waiter, called with the lock held.
+func (m *Mock) remove(w *mockWaiter) bool {
+	if !w.active {
+		return false
+	}
+	w.active = false
+	for i, other := range m.waiters {
+		if other == w {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+type mockWaiter struct {
+	clock    *Mock
+	c        chan time.Time
+	deadline time.Time
+	period   time.Duration
+	active   bool
+}
+
+type mockTimer struct {
+	*mockWaiter
+}
+
+func (t *mockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	t.clock.mx.Lock()
+	defer t.clock.mx.Unlock()
+	return t.clock.remove(t.mockWaiter)
+}
+
+func (t *mockTimer) Reset(d time.Duration) bool {
+	t.clock.mx.Lock()
+	defer t.clock.mx.Unlock()
+	active := t.clock.remove(t.mockWaiter)
+	t.clock.add(t.mockWaiter, t.clock.now.Add(d))
+	return active
+}
+
+type mockTicker struct {
+	*mockWaiter
+}
+
+func (t *mockTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTicker) Stop() {
+	t.clock.mx.Lock()
+	defer t.clock.mx.Unlock()
+	t.clock.remove(t.mockWaiter)
+}
+
+func (t *mockTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	t.clock.mx.Lock()
+	defer t.clock.mx.Unlock()
+	t.clock.remove(t.mockWaiter)
+	t.period = d
+	t.clock.add(t.mockWaiter, t.clock.now.Add(d))
+}
@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMockTimer(t *testing.T) {
+	m := NewMock(start)
+	timer := m.NewTimer(time.Second)
+
+	m.Advance(999 * time.Millisecond)
+	assertNotFired(t, timer.C())
+	assert.Equal(t, start.Add(999*time.Millisecond), m.Now())
+
+	m.Advance(time.Millisecond)
+	assertFired(t, timer.C(), start.Add(time.Second))
+	assert.False(t, timer.Stop(), "expired timer must not be active")
+	assert.Equal(t, 0, m.Waiters())
+
+	assert.False(t, timer.Reset(time.Second))
+	assert.True(t, timer.Stop())
+	m.Advance(time.Hour)
+	assertNotFired(t, timer.C())
+	assert.Equal(t, time.Hour+time.Second, m.Since(start))
+}
+
+func TestMockTicker(t *testing.T) {
+	m := NewMock(start)
+	ticker := m.NewTicker(time.Second)
+
+	m.Advance(time.Second)
+	assertFired(t, ticker.C(), start.Add(time.Second))
+
+	// ticks are dropped when the receiver is behind
+	m.Advance(3 * time.Second)
+	assertFired(t, ticker.C(), start.Add(2*time.Second))
+	assertNotFired(t, ticker.C())
+
+	ticker.Reset(time.Minute)
+	m.Advance(time.Second)
+	assertNotFired(t, ticker.C())
+	m.Advance(time.Minute)
+	assertFired(t, ticker.C(), start.Add(4*time.Second+time.Minute))
+
+	ticker.Stop()
+	m.Advance(time.Hour)
+	assertNotFired(t, ticker.C())
+}
+
+func TestMockFiresInDeadlineOrder(t *testing.T) {
+	m := NewMock(start)
+	late := m.After(2 * time.Second)
+	early := m.After(time.Second)
+
+	m.Advance(time.Minute)
+	assertFired(t, early, start.Add(time.Second))
+	assertFired(t, late, start.Add(2*time.Second))
+}
+
+func TestMockBlockUntil(t *testing.T) {
+	m := NewMock(start)
+	fired := make(chan time.Time)
+	go func() {
+		fired <- <-m.After(time.Second)
+	}()
+
+	m.BlockUntil(1)
+	m.Advance(time.Second)
+	select {
+	case now := <-fired:
+		assert.Equal(t, start.Add(time.Second), now)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for the timer to fire")
+	}
+}
+
+func assertFired(t *testing.T, c <-chan time.Time, expected time.Time) {
+	t.Helper()
+	select {
+	case now := <-c:
+		assert.Equal(t, expected, now)
+	default:
+		assert.Fail(t, "expected the channel to fire")
+	}
+}
+
+func assertNotFired(t *testing.T, c <-chan time.Time) {
+	t.Helper()
+	select {
+	case now := <-c:
+		assert.Failf(t, "unexpected fire", "channel fired at %s", now)
+	default:
+	}
+}
@@ -8,6 +8,12 @@ import "time"
 
 const defaultPort = 6791
 const defaultNamespace = "default"
+const defaultSNMPPort = 161
+const defaultMetricsPeriod = 10 * time.Second
+
+// DefaultSNMPRootOID is the root of the objects exposed by the SNMP agent when no root is set, it is
+// in the experimental arc and should be replaced by an OID of the private enterprise arc of the organisation.
+const DefaultSNMPRootOID = "1.3.6.1.3.6791"
 
 // MonitoringConfig describes a configuration of a monitoring
 type MonitoringConfig struct {
@@ -16,7 +22,10 @@ type MonitoringConfig struct {
 	MonitorMetrics bool                  `yaml:"metrics" config:"metrics"`
 	LogMetrics     bool                  `yaml:"-" config:"-"`
 	HTTP           *MonitoringHTTPConfig `yaml:"http" config:"http"`
+	SNMP           *MonitoringSNMPConfig `yaml:"snmp" config:"snmp"`
+	Adaptive       *AdaptiveConfig       `yaml:"adaptive" config:"adaptive"`
 	Namespace      string                `yaml:"namespace" config:"namespace"`
+	DataStreams    DataStreamsConfig     `yaml:"data_streams" config:"data_streams"`
 	Pprof          *PprofConfig          `yaml:"pprof" config:"pprof"`
 	MonitorTraces  bool                  `yaml:"traces" config:"traces"`
 	APM            APMConfig             `yaml:"apm,omitempty" config:"apm,omitempty" json:"apm,omitempty"`
@@ -27,10 +36,69 @@ type MonitoringConfig struct {
 // for other processes to watch its metrics.
 // Processes are only exposed when HTTP is enabled.
 type MonitoringHTTPConfig struct {
-	Enabled bool          `yaml:"enabled" config:"enabled"`
-	Host    string        `yaml:"host" config:"host"`
-	Port    int           `yaml:"port" config:"port" validate:"min=0,max=65535,nonzero"`
-	Buffer  *BufferConfig `yaml:"buffer" config:"buffer"`
+	Enabled    bool              `yaml:"enabled" config:"enabled"`
+	Host       string            `yaml:"host" config:"host"`
+	Port       int               `yaml:"port" config:"port" validate:"min=0,max=65535,nonzero"`
+	Buffer     *BufferConfig     `yaml:"buffer" config:"buffer"`
+	StatusPage *StatusPageConfig `yaml:"status_page" config:"status_page"`
+}
+
+// StatusPageConfig is a config defining the read-only status page served on /ui by the HTTP
+// endpoint. Without credentials the page is only served to the requests coming from the host.
+type StatusPageConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled"`
+	// Username and Password require the basic authentication of the requests when set.
+	Username string `yaml:"username" config:"username"`
+	Password string `yaml:"password" config:"password"`
+}
+
+// MonitoringSNMPConfig is a config defining the read-only SNMP agent exposing the health,
+// the version and the component counts of the agent to network management systems.
+type MonitoringSNMPConfig struct {
+	Enabled bool   `yaml:"enabled" config:"enabled"`
+	Host    string `yaml:"host" config:"host"`
+	Port    int    `yaml:"port" config:"port" validate:"min=0,max=65535,nonzero"`
+	// Community is the SNMPv1 and SNMPv2c community of the requests, requests of another community are dropped.
+	Community string `yaml:"community" config:"community"`
+	// RootOID is the root of the objects of the agent.
+	RootOID string `yaml:"root_oid" config:"root_oid"`
+}
+
+// AdaptiveConfig is a config defining when the collection of the monitoring metrics is reduced
+// to spare the resources of the host. While reduced, the metrics are collected every ReducedPeriod
+// instead of every 10s and the expensive collectors are disabled.
+type AdaptiveConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled"`
+	// Interval is the interval between two checks of the resources.
+	Interval time.Duration `yaml:"interval" config:"interval"`
+	// CPUThreshold is the normalized CPU usage of the host, between 0 and 1, above which the
+	// collection is reduced. Zero disables the check.
+	CPUThreshold float64 `yaml:"cpu_threshold" config:"cpu_threshold"`
+	// MemoryThreshold is the memory used by the agent in bytes above which the collection is
+	// reduced. Zero disables the check.
+	MemoryThreshold uint64 `yaml:"memory_threshold" config:"memory_threshold"`
+	// ReducedPeriod is the period of the collection of the metrics while reduced.
+	ReducedPeriod time.Duration `yaml:"reduced_period" config:"reduced_period"`
+}
+
+// MetricsPeriod returns the period of the collection of the metrics.
+func (c *AdaptiveConfig) MetricsPeriod(reduced bool) time.Duration {
+	if reduced && c != nil && c.ReducedPeriod > 0 {
+		return c.ReducedPeriod
+	}
+	return defaultMetricsPeriod
+}
+
+// DataStreamsConfig is a config overriding the data streams the monitoring events are sent to, so the
+// telemetry of the agents of different policies can be separated, for example by team. The lifecycle
+// of the data streams is set by the index templates matching them.
+type DataStreamsConfig struct {
+	// LogsNamespace is the namespace of the monitoring logs, Namespace is used when empty.
+	LogsNamespace string `yaml:"logs_namespace" config:"logs_namespace"`
+	// MetricsNamespace is the namespace of the monitoring metrics, Namespace is used when empty.
+	MetricsNamespace string `yaml:"metrics_namespace" config:"metrics_namespace"`
+	// Pipeline is the ingest pipeline processing the monitoring events.
+	Pipeline string `yaml:"pipeline" config:"pipeline"`
 }
 
 // PprofConfig is a struct for the pprof enablement flag.
@@ -57,6 +125,22 @@ func DefaultConfig() *MonitoringConfig {
 			Enabled: false,
 			Host:    "localhost",
 			Port:    defaultPort,
+			StatusPage: &StatusPageConfig{
+				Enabled: false,
+			},
+		},
+		SNMP: &MonitoringSNMPConfig{
+			Enabled:   false,
+			Host:      "localhost",
+			Port:      defaultSNMPPort,
+			Community: "public",
+			RootOID:   DefaultSNMPRootOID,
+		},
+		Adaptive: &AdaptiveConfig{
+			Enabled:       true,
+			Interval:      30 * time.Second,
+			CPUThreshold:  0.9,
+			ReducedPeriod: time.Minute,
 		},
 		Namespace:   defaultNamespace,
 		APM:         defaultAPMConfig(),
@@ -71,6 +155,8 @@ type APMConfig struct {
 	SecretToken string   `config:"secret_token"`
 	Hosts       []string `config:"hosts"`
 	TLS         APMTLS   `config:"tls"`
+	// SamplingRate is the ratio of the transactions that are sampled, all of them when nil.
+	SamplingRate *float32 `config:"sampling_rate"`
 }
 
 // APMTLS contains the configuration options necessary for configuring TLS in
@@ -90,13 +176,38 @@ type Uploader struct {
 	MaxRetries int           `config:"max_retries"`
 	InitDur    time.Duration `config:"init_duration"`
 	MaxDur     time.Duration `config:"max_duration"`
+	// Destination is where the diagnostics bundles are uploaded, fleet or s3.
+	Destination string `config:"destination"`
+	// S3 is the bucket the diagnostics bundles are uploaded to with the s3 destination.
+	S3 S3Uploader `config:"s3"`
+}
+
+// S3Uploader contains the configuration of the S3 bucket the diagnostics bundles are uploaded to.
+type S3Uploader struct {
+	Bucket string `config:"bucket"`
+	Prefix string `config:"prefix"`
+	Region string `config:"region"`
+	// Endpoint is the URL of an S3 compatible service, the AWS endpoint of the region when empty.
+	Endpoint string `config:"endpoint"`
+	// The credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN environment variables when empty.
+	AccessKeyID     string `config:"access_key_id"`
+	SecretAccessKey string `config:"secret_access_key"`
+	SessionToken    string `config:"session_token"`
+	// PartSize is the size of the parts of the multipart uploads, at least 5MiB.
+	PartSize int64 `config:"part_size"`
 }
 
 func defaultUploader() Uploader {
 	return Uploader{
-		MaxRetries: 10,
-		InitDur:    time.Second,
-		MaxDur:     time.Minute * 10,
+		MaxRetries:  10,
+		InitDur:     time.Second,
+		MaxDur:      time.Minute * 10,
+		Destination: "fleet",
+		S3: S3Uploader{
+			Region:   "us-east-1",
+			PartSize: 16 << 20,
+		},
 	}
 }
 
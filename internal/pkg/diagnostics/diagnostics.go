@@ -14,8 +14,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"reflect"
 	"runtime/pprof"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,6 +34,8 @@ const (
 	// REDACTED is used to replace sensative fields
 	REDACTED  = "<REDACTED>"
 	agentName = "elastic-agent"
+	// maxCoreDumps is the number of the most recent core dumps of the components in the bundle
+	maxCoreDumps = 2
 )
 
 // Hook is a hook that gets used when diagnostic information is requested from the Elastic Agent.
@@ -65,6 +67,19 @@ func GlobalHooks() Hooks {
 				return o
 			},
 		},
+		{
+			Name:        "sbom",
+			Filename:    "sbom.cdx.json",
+			Description: "CycloneDX software bill of materials of the agent binary",
+			ContentType: "application/vnd.cyclonedx+json",
+			Hook: func(_ context.Context) []byte {
+				sbom, err := os.ReadFile(paths.AgentSBOMFile())
+				if err != nil {
+					return []byte(fmt.Sprintf("error: %q", err))
+				}
+				return sbom
+			},
+		},
 		{
 			Name:        "goroutine",
 			Filename:    "goroutine.pprof.gz",
@@ -123,8 +138,12 @@ func pprofDiag(name string, debug int) func(context.Context) []byte {
 }
 
 // ZipArchive creates a zipped diagnostics bundle using the passed writer with the passed diagnostics and local logs.
+// The files are redacted by the passed redactor, the standard profile when nil.
 // If any error is encountered when writing the contents of the archive it is returned.
-func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, unitDiags []client.DiagnosticUnitResult) error {
+func ZipArchive(errOut, w io.Writer, r *Redactor, agentDiag []client.DiagnosticFileResult, unitDiags []client.DiagnosticUnitResult) error {
+	if r == nil {
+		r, _ = NewRedactor(nil)
+	}
 	ts := time.Now().UTC()
 	zw := zip.NewWriter(w)
 	defer zw.Close()
@@ -139,7 +158,7 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 		if err != nil {
 			return err
 		}
-		err = writeRedacted(errOut, zf, ad.Filename, ad)
+		err = writeRedacted(errOut, zf, r, ad.Filename, ad)
 		if err != nil {
 			return err
 		}
@@ -154,6 +173,7 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 	}
 	// write each units diagnostics into its own directory
 	// layout becomes components/<component-id>/<unit-id>/<filename>
+	// the results of the diagnostic providers of the component, without a unit, become components/<component-id>/<filename>
 	_, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     "components/",
 		Method:   zip.Deflate,
@@ -172,18 +192,21 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 			return err
 		}
 		for _, ud := range units {
-			unitDir := strings.ReplaceAll(strings.TrimPrefix(ud.UnitID, ud.ComponentID+"-"), "/", "-")
-			_, err := zw.CreateHeader(&zip.FileHeader{
-				Name:     fmt.Sprintf("components/%s/%s/", dirName, unitDir),
-				Method:   zip.Deflate,
-				Modified: ts,
-			})
-			if err != nil {
-				return err
+			unitDir := fmt.Sprintf("components/%s/", dirName)
+			if ud.UnitID != "" {
+				unitDir += strings.ReplaceAll(strings.TrimPrefix(ud.UnitID, ud.ComponentID+"-"), "/", "-") + "/"
+				_, err := zw.CreateHeader(&zip.FileHeader{
+					Name:     unitDir,
+					Method:   zip.Deflate,
+					Modified: ts,
+				})
+				if err != nil {
+					return err
+				}
 			}
 			if ud.Err != nil {
 				w, err := zw.CreateHeader(&zip.FileHeader{
-					Name:     fmt.Sprintf("components/%s/%s/error.txt", dirName, unitDir),
+					Name:     unitDir + "error.txt",
 					Method:   zip.Deflate,
 					Modified: ts,
 				})
@@ -197,7 +220,7 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 				continue
 			}
 			for _, fr := range ud.Results {
-				filePath := fmt.Sprintf("components/%s/%s/%s", dirName, unitDir, fr.Filename)
+				filePath := unitDir + fr.Filename
 				w, err := zw.CreateHeader(&zip.FileHeader{
 					Name:     filePath,
 					Method:   zip.Deflate,
@@ -206,7 +229,7 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 				if err != nil {
 					return err
 				}
-				err = writeRedacted(errOut, w, filePath, fr)
+				err = writeRedacted(errOut, w, r, filePath, fr)
 				if err != nil {
 					return err
 				}
@@ -214,70 +237,103 @@ func ZipArchive(errOut, w io.Writer, agentDiag []client.DiagnosticFileResult, un
 		}
 	}
 
+	if err := zipCoreDumps(zw); err != nil {
+		return err
+	}
+
 	// Gather Logs:
-	return zipLogs(zw, ts)
+	timeline := &Timeline{}
+	if err := zipLogs(zw, r, timeline, ts); err != nil {
+		return err
+	}
+	return zipTimeline(zw, timeline, ts)
 }
 
-func writeRedacted(errOut, w io.Writer, fullFilePath string, fr client.DiagnosticFileResult) error {
-	out := &fr.Content
-
-	// Should we support json too?
-	if fr.ContentType == "application/yaml" {
-		unmarshalled := map[interface{}]interface{}{}
-		err := yaml.Unmarshal(fr.Content, &unmarshalled)
+// zipTimeline writes the timeline built from the logs as timeline.json and timeline.html.
+func zipTimeline(zw *zip.Writer, timeline *Timeline, ts time.Time) error {
+	for _, f := range []struct {
+		name  string
+		write func(io.Writer) error
+	}{
+		{"timeline.json", timeline.WriteJSON},
+		{"timeline.html", timeline.WriteHTML},
+	} {
+		zf, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: ts,
+		})
 		if err != nil {
-			// Best effort, output a warning but still include the file
-			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", fullFilePath, err)
-		} else {
-			redacted, err := yaml.Marshal(redactMap(unmarshalled))
-			if err != nil {
-				// Best effort, output a warning but still include the file
-				fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", fullFilePath, err)
-			} else {
-				out = &redacted
-			}
+			return err
+		}
+		if err := f.write(zf); err != nil {
+			return fmt.Errorf("unable to write %s: %w", f.name, err)
 		}
 	}
-
-	_, err := w.Write(*out)
-	return err
+	return nil
 }
 
-func redactMap(m map[interface{}]interface{}) map[interface{}]interface{} {
-	for k, v := range m {
-		if v != nil && reflect.TypeOf(v).Kind() == reflect.Map {
-			v = redactMap(v.(map[interface{}]interface{}))
+// zipCoreDumps copies the most recent compressed core dumps of the components into "coredumps/".
+func zipCoreDumps(zw *zip.Writer) error {
+	entries, err := os.ReadDir(paths.CoreDumps())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
 		}
-		if s, ok := k.(string); ok {
-			if redactKey(s) {
-				v = REDACTED
-			}
-			m[k] = v
+		return fmt.Errorf("unable to read core dumps directory: %w", err)
+	}
+	var dumps []fs.FileInfo
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || !strings.HasSuffix(e.Name(), ".gz") {
+			continue
+		}
+		dumps = append(dumps, info)
+	}
+	sort.Slice(dumps, func(i, j int) bool {
+		return dumps[i].ModTime().After(dumps[j].ModTime())
+	})
+	if len(dumps) > maxCoreDumps {
+		dumps = dumps[:maxCoreDumps]
+	}
+	for _, dump := range dumps {
+		if err := zipCoreDump(zw, dump); err != nil {
+			return err
 		}
 	}
-	return m
+	return nil
 }
 
-func redactKey(k string) bool {
-	// "routekey" shouldn't be redacted.
-	// Add any other exceptions here.
-	if k == "routekey" {
-		return false
+func zipCoreDump(zw *zip.Writer, dump fs.FileInfo) error {
+	f, err := os.Open(filepath.Join(paths.CoreDumps(), dump.Name()))
+	if err != nil {
+		return fmt.Errorf("unable to open core dump: %w", err)
+	}
+	defer f.Close()
+	// the dumps are already compressed
+	zf, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "coredumps/" + dump.Name(),
+		Method:   zip.Store,
+		Modified: dump.ModTime(),
+	})
+	if err != nil {
+		return err
 	}
+	_, err = io.Copy(zf, f)
+	return err
+}
 
-	return strings.Contains(k, "certificate") ||
-		strings.Contains(k, "passphrase") ||
-		strings.Contains(k, "password") ||
-		strings.Contains(k, "token") ||
-		strings.Contains(k, "key")
+func writeRedacted(errOut, w io.Writer, r *Redactor, fullFilePath string, fr client.DiagnosticFileResult) error {
+	_, err := w.Write(r.redactContent(errOut, fullFilePath, fr.ContentType, fr.Content))
+	return err
 }
 
-func zipLogs(zw *zip.Writer, ts time.Time) error {
+func zipLogs(zw *zip.Writer, r *Redactor, timeline *Timeline, ts time.Time) error {
 	currentDir := fmt.Sprintf("%s-%s", agentName, release.ShortCommit())
 	if !paths.IsVersionHome() {
 		// running in a container with custom top path set
 		// logs are directly under top path
-		return zipLogsWithPath(paths.Home(), currentDir, true, zw, ts)
+		return zipLogsWithPath(paths.Home(), currentDir, true, zw, r, timeline, ts)
 	}
 
 	dataDir, err := os.Open(paths.Data())
@@ -298,7 +354,7 @@ func zipLogs(zw *zip.Writer, ts time.Time) error {
 		}
 		collectServices := dir == currentDir
 		path := filepath.Join(paths.Data(), dir)
-		if err := zipLogsWithPath(path, dir, collectServices, zw, ts); err != nil {
+		if err := zipLogsWithPath(path, dir, collectServices, zw, r, timeline, ts); err != nil {
 			return err
 		}
 	}
@@ -307,7 +363,7 @@ func zipLogs(zw *zip.Writer, ts time.Time) error {
 }
 
 // zipLogs walks paths.Logs() and copies the file structure into zw in "logs/"
-func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip.Writer, ts time.Time) error {
+func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip.Writer, r *Redactor, timeline *Timeline, ts time.Time) error {
 	_, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     "logs/",
 		Method:   zip.Deflate,
@@ -318,9 +374,12 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip
 	}
 
 	if collectServices {
-		if err := collectServiceComponentsLogs(zw); err != nil {
+		if err := collectServiceComponentsLogs(zw, r); err != nil {
 			return fmt.Errorf("failed to collect endpoint-security logs: %w", err)
 		}
+		if err := collectServiceOperationsOutput(zw, r); err != nil {
+			return fmt.Errorf("failed to collect service operations output: %w", err)
+		}
 	}
 
 	_, err = zw.CreateHeader(&zip.FileHeader{
@@ -363,11 +422,11 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices bool, zw *zip
 			return nil
 		}
 
-		return saveLogs(name, path, zw)
+		return saveLogs(name, path, zw, r, timeline)
 	})
 }
 
-func collectServiceComponentsLogs(zw *zip.Writer) error {
+func collectServiceComponentsLogs(zw *zip.Writer, r *Redactor) error {
 	platform, err := component.LoadPlatformDetail()
 	if err != nil {
 		return fmt.Errorf("failed to gather system information: %w", err)
@@ -401,7 +460,7 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 				return nil
 			}
 
-			return saveLogs("services/"+name, path, zw)
+			return saveLogs("services/"+name, path, zw, r, nil)
 		})
 		if err != nil {
 			return err
@@ -410,7 +469,31 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 	return nil
 }
 
-func saveLogs(name string, logPath string, zw *zip.Writer) error {
+// collectServiceOperationsOutput copies the recorded output of the check, install and uninstall
+// commands of the service components into "logs/service-operations/".
+func collectServiceOperationsOutput(zw *zip.Writer, r *Redactor) error {
+	outputPath := paths.ServiceOperations() + string(filepath.Separator)
+	return filepath.WalkDir(outputPath, func(path string, d fs.DirEntry, fErr error) error {
+		if fErr != nil {
+			if errors.Is(fErr, fs.ErrNotExist) {
+				return nil
+			}
+
+			return fmt.Errorf("unable to walk service operations output directory %q: %w", outputPath, fErr)
+		}
+
+		name := filepath.ToSlash(strings.TrimPrefix(path, outputPath))
+		if name == "" || d.IsDir() {
+			return nil
+		}
+
+		return saveLogs("service-operations/"+name, path, zw, r, nil)
+	})
+}
+
+// saveLogs copies the log file into "logs/", the lines are redacted by the patterns of the profile.
+// The redacted lines are added to the timeline when not nil.
+func saveLogs(name string, logPath string, zw *zip.Writer, r *Redactor, timeline *Timeline) error {
 	ts := time.Now().UTC()
 	lf, err := os.Open(logPath)
 	if err != nil {
@@ -428,7 +511,13 @@ func saveLogs(name string, logPath string, zw *zip.Writer) error {
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(zf, lf)
+	var w io.Writer = zf
+	if timeline != nil {
+		tw := timeline.Writer("logs/" + filepath.ToSlash(name))
+		defer tw.Close()
+		w = io.MultiWriter(zf, tw)
+	}
+	err = r.copyRedacted(w, lf)
 	if err != nil {
 		return err
 	}
@@ -9,6 +9,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
@@ -22,6 +24,7 @@ import (
 	"github.com/google/pprof/profile"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 )
 
 func TestZipLogs(t *testing.T) {
@@ -36,7 +39,9 @@ func TestZipLogs(t *testing.T) {
 	// Zip the logs directory.
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)
-	require.NoError(t, zipLogs(w, time.Now()))
+	redactor, err := NewRedactor(nil)
+	require.NoError(t, err)
+	require.NoError(t, zipLogs(w, redactor, &Timeline{}, time.Now()))
 	require.NoError(t, w.Close())
 
 	type zippedItem struct {
@@ -63,6 +68,14 @@ func TestZipLogs(t *testing.T) {
 }
 
 func TestGlobalHooks(t *testing.T) {
+	sbomFile := paths.AgentSBOMFile()
+	if _, err := os.Stat(filepath.Dir(sbomFile)); errors.Is(err, os.ErrNotExist) {
+		t.Cleanup(func() { _ = os.RemoveAll(filepath.Dir(sbomFile)) })
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(sbomFile), 0o755))
+	require.NoError(t, os.WriteFile(sbomFile, []byte(`{"bomFormat":"CycloneDX","specVersion":"1.4"}`), 0o644))
+	t.Cleanup(func() { _ = os.Remove(sbomFile) })
+
 	hooks := GlobalHooks()
 	assert.NotEmpty(t, hooks, "multiple hooks should be returned")
 	deadline, _ := t.Deadline()
@@ -76,6 +89,8 @@ func TestGlobalHooks(t *testing.T) {
 		switch h.Name {
 		case "version":
 			ok, err = isVersion(output)
+		case "sbom":
+			ok, err = isSBOM(output)
 		default:
 			ok, err = isPprof(output)
 		}
@@ -87,6 +102,16 @@ func isVersion(input []byte) (bool, error) {
 	return strings.Contains(string(input), "version:"), nil
 }
 
+func isSBOM(input []byte) (bool, error) {
+	var sbom struct {
+		BOMFormat string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(input, &sbom); err != nil {
+		return false, err
+	}
+	return sbom.BOMFormat == "CycloneDX", nil
+}
+
 func isPprof(input []byte) (bool, error) {
 	gz, err := gzip.NewReader(bytes.NewBuffer(input))
 	if err != nil {
@@ -102,3 +127,37 @@ func isPprof(input []byte) (bool, error) {
 	}
 	return true, nil
 }
+
+func TestZipArchiveComponentProviders(t *testing.T) {
+	paths.SetTop(t.TempDir())
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.Home(), "logs"), 0o700))
+	unitDiags := []client.DiagnosticUnitResult{
+		{
+			ComponentID: "filestream-default",
+			UnitID:      "filestream-default-filestream-1",
+			Results:     []client.DiagnosticFileResult{{Filename: "goroutine.txt", Content: []byte("goroutines")}},
+		},
+		{
+			ComponentID: "filestream-default",
+			Results:     []client.DiagnosticFileResult{{Filename: "registry-log.json", Content: []byte("{}")}},
+		},
+	}
+	buf := new(bytes.Buffer)
+	require.NoError(t, ZipArchive(io.Discard, buf, nil, nil, unitDiags))
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range r.File {
+		if strings.HasPrefix(f.Name, "components/") {
+			names = append(names, f.Name)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"components/",
+		"components/filestream-default/",
+		"components/filestream-default/filestream-1/",
+		"components/filestream-default/filestream-1/goroutine.txt",
+		"components/filestream-default/registry-log.json",
+	}, names)
+}
@@ -0,0 +1,305 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package diagnostics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+// The redaction profiles of the diagnostics.
+const (
+	// RedactionStandard redacts the values of the sensitive keys of the YAML files.
+	RedactionStandard = "standard"
+	// RedactionStrict also redacts the JSON files and the credentials, addresses and emails found
+	// in all the files, the logs included.
+	RedactionStrict = "strict"
+	// RedactionCustom redacts like the standard profile, plus the keys and the patterns of the
+	// configuration in all the files, the logs included.
+	RedactionCustom = "custom"
+)
+
+// RedactionConfig is the configuration of the redaction of the diagnostics.
+type RedactionConfig struct {
+	// Profile is standard, strict or custom.
+	Profile string `yaml:"profile" config:"profile" json:"profile"`
+	// Keys are the additional keys whose values are redacted by the custom profile, a key is
+	// redacted when it contains one of them.
+	Keys []string `yaml:"keys" config:"keys" json:"keys"`
+	// Patterns are the regular expressions whose matches are redacted by the custom profile.
+	Patterns []string `yaml:"patterns" config:"patterns" json:"patterns"`
+}
+
+// DefaultRedactionConfig creates a default redaction configuration, the standard profile.
+func DefaultRedactionConfig() *RedactionConfig {
+	return &RedactionConfig{Profile: RedactionStandard}
+}
+
+// Validate validates the configuration.
+func (c *RedactionConfig) Validate() error {
+	_, err := NewRedactor(c)
+	return err
+}
+
+var currentRedaction atomic.Pointer[RedactionConfig]
+
+// ApplyRedaction applies the agent.diagnostics.redaction configuration, the diagnostics collected
+// from then on use it. A configuration without agent.diagnostics.redaction keeps the current
+// one, so a policy that doesn't set it keeps the one of the local configuration.
+func ApplyRedaction(c *config.Config) error {
+	cfg, err := ParseRedaction(c)
+	if err != nil || cfg == nil {
+		return err
+	}
+	currentRedaction.Store(cfg)
+	return nil
+}
+
+// ParseRedaction returns the agent.diagnostics.redaction configuration, nil when the
+// configuration doesn't set it.
+func ParseRedaction(c *config.Config) (*RedactionConfig, error) {
+	if c == nil {
+		return nil, nil
+	}
+	present := struct {
+		Agent struct {
+			Diagnostics struct {
+				Redaction map[string]interface{} `config:"redaction"`
+			} `config:"diagnostics"`
+		} `config:"agent"`
+	}{}
+	if err := c.Unpack(&present); err != nil {
+		return nil, fmt.Errorf("could not parse the diagnostics redaction configuration: %w", err)
+	}
+	if present.Agent.Diagnostics.Redaction == nil {
+		return nil, nil
+	}
+
+	parsed := struct {
+		Agent struct {
+			Diagnostics struct {
+				Redaction *RedactionConfig `config:"redaction"`
+			} `config:"diagnostics"`
+		} `config:"agent"`
+	}{}
+	parsed.Agent.Diagnostics.Redaction = DefaultRedactionConfig()
+	if err := c.Unpack(&parsed); err != nil {
+		return nil, fmt.Errorf("could not parse the diagnostics redaction configuration: %w", err)
+	}
+	return parsed.Agent.Diagnostics.Redaction, nil
+}
+
+// CurrentRedaction returns the configuration of the redaction of the diagnostics.
+func CurrentRedaction() *RedactionConfig {
+	if cfg := currentRedaction.Load(); cfg != nil {
+		return cfg
+	}
+	return DefaultRedactionConfig()
+}
+
+// standardKeys are the parts of the keys redacted by all the profiles.
+var standardKeys = []string{"certificate", "passphrase", "password", "token", "key"}
+
+// strictKeys are the parts of the keys also redacted by the strict profile.
+var strictKeys = []string{"secret", "credential", "authorization", "cookie", "private", "auth"}
+
+// textPattern is a pattern redacted in the text of the files, the prefix matched by its first
+// group is kept.
+type textPattern struct {
+	re         *regexp.Regexp
+	keepPrefix bool
+}
+
+// strictPatterns are the patterns redacted by the strict profile.
+var strictPatterns = []textPattern{
+	// credentials in headers and key-value pairs, the name of the key is kept
+	{re: regexp.MustCompile(`(?i)((?:authorization|api[_-]?key|apikey|password|passphrase|secret|token)["']?\s*[:=]\s*["']?)(?:(?:basic|bearer|apikey)\s+)?[^\s"',;}&]+`), keepPrefix: true},
+	// user info of URLs, the scheme is kept
+	{re: regexp.MustCompile(`(?i)([a-z][a-z0-9+.-]*://)[^/\s:@]+(?::[^/\s@]*)?@`), keepPrefix: true},
+	// emails
+	{re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	// IPv4 addresses
+	{re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\b`)},
+}
+
+// Redactor redacts the files of the diagnostics with a profile.
+type Redactor struct {
+	profile  string
+	keys     []string
+	patterns []textPattern
+}
+
+// NewRedactor returns the redactor of the configuration.
+func NewRedactor(cfg *RedactionConfig) (*Redactor, error) {
+	if cfg == nil {
+		cfg = DefaultRedactionConfig()
+	}
+	r := &Redactor{profile: cfg.Profile, keys: standardKeys}
+	switch cfg.Profile {
+	case "", RedactionStandard:
+		r.profile = RedactionStandard
+	case RedactionStrict:
+		r.keys = append(append([]string{}, standardKeys...), strictKeys...)
+		r.patterns = strictPatterns
+	case RedactionCustom:
+		r.keys = append([]string{}, standardKeys...)
+		for _, k := range cfg.Keys {
+			r.keys = append(r.keys, strings.ToLower(k))
+		}
+		for _, p := range cfg.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid diagnostics redaction pattern %q: %w", p, err)
+			}
+			r.patterns = append(r.patterns, textPattern{re: re})
+		}
+	default:
+		return nil, fmt.Errorf("unknown diagnostics redaction profile %q, must be one of %s, %s or %s", cfg.Profile, RedactionStandard, RedactionStrict, RedactionCustom)
+	}
+	if cfg.Profile != RedactionCustom && (len(cfg.Keys) > 0 || len(cfg.Patterns) > 0) {
+		return nil, errors.New("the diagnostics redaction keys and patterns need the custom profile")
+	}
+	return r, nil
+}
+
+// Profile returns the name of the profile of the redactor.
+func (r *Redactor) Profile() string {
+	return r.profile
+}
+
+// redactKey returns true when the value of the key is redacted.
+func (r *Redactor) redactKey(k string) bool {
+	// "routekey" shouldn't be redacted.
+	// Add any other exceptions here.
+	if k == "routekey" {
+		return false
+	}
+	k = strings.ToLower(k)
+	for _, part := range r.keys {
+		if strings.Contains(k, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactContent redacts the content of a file of the content type: the values of the sensitive
+// keys of the YAML files, of the JSON files with the strict profile, then the patterns of the
+// profile in all of them.
+func (r *Redactor) redactContent(errOut io.Writer, fullFilePath string, contentType string, content []byte) []byte {
+	switch {
+	case contentType == "application/yaml":
+		unmarshalled := map[interface{}]interface{}{}
+		err := yaml.Unmarshal(content, &unmarshalled)
+		if err != nil {
+			// Best effort, output a warning but still include the file
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", fullFilePath, err)
+			break
+		}
+		redacted, err := yaml.Marshal(r.redactMap(unmarshalled))
+		if err != nil {
+			// Best effort, output a warning but still include the file
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", fullFilePath, err)
+			break
+		}
+		content = redacted
+	case contentType == "application/json" && r.profile != RedactionStandard:
+		var unmarshalled interface{}
+		if err := json.Unmarshal(content, &unmarshalled); err != nil {
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", fullFilePath, err)
+			break
+		}
+		var redacted bytes.Buffer
+		enc := json.NewEncoder(&redacted)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r.redactJSON(unmarshalled)); err != nil {
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", fullFilePath, err)
+			break
+		}
+		content = redacted.Bytes()
+	}
+	return r.redactText(content)
+}
+
+func (r *Redactor) redactMap(m map[interface{}]interface{}) map[interface{}]interface{} {
+	for k, v := range m {
+		if v != nil && reflect.TypeOf(v).Kind() == reflect.Map {
+			v = r.redactMap(v.(map[interface{}]interface{}))
+		}
+		if s, ok := k.(string); ok {
+			if r.redactKey(s) {
+				v = REDACTED
+			}
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func (r *Redactor) redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if r.redactKey(k) {
+				v[k] = REDACTED
+			} else {
+				v[k] = r.redactJSON(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = r.redactJSON(e)
+		}
+	}
+	return v
+}
+
+// redactText redacts the matches of the patterns of the profile.
+func (r *Redactor) redactText(text []byte) []byte {
+	for _, p := range r.patterns {
+		if p.keepPrefix {
+			text = p.re.ReplaceAll(text, []byte("${1}"+REDACTED))
+		} else {
+			text = p.re.ReplaceAll(text, []byte(REDACTED))
+		}
+	}
+	return text
+}
+
+// copyRedacted copies the lines of src to dst with the patterns of the profile redacted.
+func (r *Redactor) copyRedacted(dst io.Writer, src io.Reader) error {
+	if len(r.patterns) == 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+	br := bufio.NewReader(src)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, werr := dst.Write(r.redactText(line)); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The event.action of the log events added to the timeline of the diagnostics.
+const (
+	// EventActionPolicyChange is logged when a new policy revision is applied.
+	EventActionPolicyChange = "policy-change"
+	// EventActionUpgradeStateChange is logged when an upgrade reaches a new state.
+	EventActionUpgradeStateChange = "upgrade-state-change"
+	// EventActionConfigLint is logged for the problems the linter finds in a policy.
+	EventActionConfigLint = "config-lint"
+	// EventActionBackfill is logged when the backfill of a unit starts and ends.
+	EventActionBackfill = "backfill"
+)
+
+// The categories of the events of the timeline.
+const (
+	TimelineLog       = "log"
+	TimelineComponent = "component"
+	TimelineUnit      = "unit"
+	TimelinePolicy    = "policy"
+	TimelineUpgrade   = "upgrade"
+)
+
+// maxTimelineEvents is the number of the most recent events kept in the timeline.
+const maxTimelineEvents = 10000
+
+// TimelineEvent is an event of the timeline of the diagnostics.
+type TimelineEvent struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Category  string    `json:"category"`
+	Level     string    `json:"level"`
+	Component string    `json:"component,omitempty"`
+	Message   string    `json:"message"`
+	// Source is the file of the bundle the event comes from.
+	Source string `json:"source"`
+}
+
+// Timeline merges the agent log events, the state transitions of the components and of their
+// units, the policy revisions and the upgrade steps found in the logs of the diagnostics into a
+// single time-ordered view.
+type Timeline struct {
+	events []TimelineEvent
+}
+
+// logEvent is the part of a log line of the Elastic Agent read by the timeline.
+type logEvent struct {
+	Timestamp   string `json:"@timestamp"`
+	Level       string `json:"log.level"`
+	Message     string `json:"message"`
+	EventAction string `json:"event.action"`
+	Component   struct {
+		ID       string `json:"id"`
+		OldState string `json:"old_state"`
+	} `json:"component"`
+	Unit struct {
+		OldState string `json:"old_state"`
+	} `json:"unit"`
+}
+
+// AddLogLine adds the event of the log line to the timeline when it is one of its categories.
+// Lines that are not JSON log events are ignored.
+func (t *Timeline) AddLogLine(source string, line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return
+	}
+	var e logEvent
+	if err := json.Unmarshal(line, &e); err != nil {
+		return
+	}
+	ts, err := time.Parse(time.RFC3339Nano, e.Timestamp)
+	if err != nil {
+		// the Elastic Agent logs with a numeric zone offset without a colon
+		if ts, err = time.Parse("2006-01-02T15:04:05.000Z0700", e.Timestamp); err != nil {
+			return
+		}
+	}
+
+	category := ""
+	switch {
+	case e.EventAction == EventActionPolicyChange:
+		category = TimelinePolicy
+	case strings.HasPrefix(e.EventAction, "upgrade-"):
+		category = TimelineUpgrade
+	case e.Unit.OldState != "":
+		category = TimelineUnit
+	case e.Component.OldState != "":
+		category = TimelineComponent
+	case isWarningLevel(e.Level):
+		category = TimelineLog
+	default:
+		return
+	}
+	t.events = append(t.events, TimelineEvent{
+		Timestamp: ts.UTC(),
+		Category:  category,
+		Level:     e.Level,
+		Component: e.Component.ID,
+		Message:   e.Message,
+		Source:    source,
+	})
+}
+
+func isWarningLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "warn", "warning", "error", "critical", "dpanic", "panic", "fatal":
+		return true
+	}
+	return false
+}
+
+// Writer returns a writer adding the events of the log lines written to it, the last line is added
+// when it is closed.
+func (t *Timeline) Writer(source string) io.WriteCloser {
+	return &timelineWriter{timeline: t, source: source}
+}
+
+// Events returns the most recent events of the timeline, ordered by time.
+func (t *Timeline) Events() []TimelineEvent {
+	sort.SliceStable(t.events, func(i, j int) bool {
+		return t.events[i].Timestamp.Before(t.events[j].Timestamp)
+	})
+	if len(t.events) > maxTimelineEvents {
+		t.events = t.events[len(t.events)-maxTimelineEvents:]
+	}
+	return t.events
+}
+
+// WriteJSON writes the events of the timeline as a JSON array.
+func (t *Timeline) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t.Events())
+}
+
+// WriteHTML writes the events of the timeline as an HTML page.
+func (t *Timeline) WriteHTML(w io.Writer) error {
+	return timelineTemplate.Execute(w, t.Events())
+}
+
+type timelineWriter struct {
+	timeline *Timeline
+	source   string
+	partial  []byte
+}
+
+func (w *timelineWriter) Write(p []byte) (int, error) {
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.timeline.AddLogLine(w.source, data[:i])
+		data = data[i+1:]
+	}
+	w.partial = append([]byte{}, data...)
+	return len(p), nil
+}
+
+func (w *timelineWriter) Close() error {
+	w.timeline.AddLogLine(w.source, w.partial)
+	w.partial = nil
+	return nil
+}
+
+var timelineTemplate = template.Must(template.New("timeline").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Elastic Agent diagnostics timeline</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.time { white-space: nowrap; font-family: monospace; }
+tr.error td, tr.critical td, tr.fatal td, tr.panic td, tr.dpanic td { background: #fde8e8; }
+tr.warn td, tr.warning td { background: #fdf6e3; }
+tr.policy td.category, tr.upgrade td.category { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Elastic Agent diagnostics timeline</h1>
+<p>Agent log warnings and errors, component and unit state transitions, policy revisions and upgrade steps, oldest first.</p>
+<table>
+<tr><th>Time (UTC)</th><th>Category</th><th>Level</th><th>Component</th><th>Message</th><th>Source</th></tr>
+{{- range . }}
+<tr class="{{ .Category }} {{ .Level }}"><td class="time">{{ .Timestamp.Format "2006-01-02T15:04:05.000Z07:00" }}</td><td class="category">{{ .Category }}</td><td>{{ .Level }}</td><td>{{ .Component }}</td><td>{{ .Message }}</td><td>{{ .Source }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,92 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+const agentLog = `{"log.level":"info","@timestamp":"2023-09-05T10:00:02.000Z","message":"Applying revision 3 of policy p1","policy.id":"p1","policy.revision":3,"event.action":"policy-change"}
+{"log.level":"info","@timestamp":"2023-09-05T10:00:01.000Z","message":"Starting","log.origin":{"file.name":"cmd/run.go"}}
+{"log.level":"error","@timestamp":"2023-09-05T10:00:05.000+0200","message":"Component state changed filestream-default (HEALTHY->FAILED): crashed","component":{"id":"filestream-default","state":"FAILED","old_state":"HEALTHY"}}
+{"log.level":"warn","@timestamp":"2023-09-05T10:00:04.000Z","message":"Unit state changed filestream-default-1 (HEALTHY->DEGRADED): slow","component":{"id":"filestream-default","state":"HEALTHY"},"unit":{"id":"filestream-default-1","state":"DEGRADED","old_state":"HEALTHY"}}
+{"log.level":"info","@timestamp":"2023-09-05T10:00:06.000Z","message":"Upgrade to version 8.11.0 is in state UPG_DOWNLOADING","event.action":"upgrade-state-change"}
+not a json line
+{"log.level":"error","@timestamp":"2023-09-05T10:00:03.000Z","message":"failed to connect"}`
+
+func TestTimeline(t *testing.T) {
+	timeline := &Timeline{}
+	w := timeline.Writer("logs/elastic-agent.ndjson")
+	// the lines are split across writes
+	for _, chunk := range []string{agentLog[:100], agentLog[100:]} {
+		_, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	events := timeline.Events()
+	var summary []string
+	for _, e := range events {
+		summary = append(summary, e.Timestamp.Format("15:04:05")+" "+e.Category+" "+e.Component)
+	}
+	assert.Equal(t, []string{
+		"08:00:05 component filestream-default",
+		"10:00:02 policy ",
+		"10:00:03 log ",
+		"10:00:04 unit filestream-default",
+		"10:00:06 upgrade ",
+	}, summary)
+	assert.Equal(t, "logs/elastic-agent.ndjson", events[0].Source)
+
+	var html bytes.Buffer
+	require.NoError(t, timeline.WriteHTML(&html))
+	assert.Contains(t, html.String(), "Component state changed filestream-default (HEALTHY-&gt;FAILED): crashed")
+}
+
+func TestZipArchiveTimeline(t *testing.T) {
+	paths.SetTop(t.TempDir())
+	dir := filepath.Join(paths.Home(), "logs")
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "elastic-agent-20230905.ndjson"), []byte(agentLog), 0o600))
+
+	var buf bytes.Buffer
+	require.NoError(t, ZipArchive(io.Discard, &buf, nil, nil, nil))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var timelineJSON []byte
+	var names []string
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, "timeline.") {
+			names = append(names, f.Name)
+		}
+		if f.Name == "timeline.json" {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			timelineJSON, err = io.ReadAll(rc)
+			rc.Close()
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, []string{"timeline.json", "timeline.html"}, names)
+
+	var events []TimelineEvent
+	require.NoError(t, json.Unmarshal(timelineJSON, &events))
+	require.Len(t, events, 5)
+	assert.Equal(t, TimelinePolicy, events[1].Category)
+	assert.Equal(t, "logs/elastic-agent-unknow/elastic-agent-20230905.ndjson", events[1].Source)
+}
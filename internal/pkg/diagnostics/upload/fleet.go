@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
+)
+
+// Fleet uploads the bundles with the file upload API of Fleet Server, attached to a request of
+// diagnostics.
+type Fleet struct {
+	client   *uploader.Client
+	actionID string
+}
+
+// NewFleet returns the Fleet destination of the bundle of the action.
+func NewFleet(client *uploader.Client, actionID string) *Fleet {
+	return &Fleet{client: client, actionID: actionID}
+}
+
+// Name returns the name of the destination.
+func (d *Fleet) Name() string {
+	return DestinationFleet
+}
+
+// Upload uploads the bundle in the chunks requested by Fleet Server.
+func (d *Fleet) Upload(ctx context.Context, r io.ReaderAt, size int64, p *Progress, save func() error) (string, error) {
+	if p.UploadID == "" {
+		resp, err := d.client.New(ctx, &uploader.NewUploadRequest{
+			ActionID: d.actionID,
+			AgentID:  d.client.AgentID(),
+			Source:   "agent",
+			File: uploader.FileData{
+				Size:      size,
+				Name:      p.Name,
+				Extension: "zip",
+				Mime:      "application/zip",
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		if resp.ChunkSize <= 0 {
+			return resp.UploadID, fmt.Errorf("fleet-server returned an invalid chunk size %d", resp.ChunkSize)
+		}
+		p.UploadID = resp.UploadID
+		p.ChunkSize = resp.ChunkSize
+		p.Parts = nil
+		if err := save(); err != nil {
+			return p.UploadID, err
+		}
+	}
+
+	for offset := int64(len(p.Parts)) * p.ChunkSize; offset < size; offset += p.ChunkSize {
+		var data bytes.Buffer
+		if _, err := io.Copy(&data, io.NewSectionReader(r, offset, p.ChunkSize)); err != nil {
+			return p.UploadID, fmt.Errorf("failed to read the diagnostics: %w", err)
+		}
+		hash := sha256.Sum256(data.Bytes())
+		if err := d.client.Chunk(ctx, p.UploadID, len(p.Parts), hash[:], &data); err != nil {
+			return p.UploadID, err
+		}
+		p.Parts = append(p.Parts, hex.EncodeToString(hash[:]))
+		if err := save(); err != nil {
+			return p.UploadID, err
+		}
+	}
+
+	// the transit hash is the hash of the hashes of the chunks
+	transitHash := sha256.New()
+	for _, part := range p.Parts {
+		hash, err := hex.DecodeString(part)
+		if err != nil {
+			return p.UploadID, fmt.Errorf("invalid hash of chunk: %w", err)
+		}
+		transitHash.Write(hash)
+	}
+	var fr uploader.FinishRequest
+	fr.TransitHash.SHA256 = fmt.Sprintf("%x", transitHash.Sum(nil))
+	return p.UploadID, d.client.Finish(ctx, p.UploadID, &fr)
+}
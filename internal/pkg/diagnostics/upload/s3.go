@@ -0,0 +1,335 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+)
+
+const (
+	// minimum size of the parts of a multipart upload, except the last one.
+	s3MinPartSize = 5 << 20
+
+	// attempts of each request before the upload fails, it is resumed from the last part uploaded.
+	s3Attempts = 3
+)
+
+// S3 uploads the bundles to an S3 bucket with multipart uploads.
+type S3 struct {
+	cfg    config.S3Uploader
+	client *http.Client
+	// retryWait is the wait before the first retry of a request, it doubles with each attempt.
+	retryWait time.Duration
+	// now is replaced by the tests.
+	now func() time.Time
+}
+
+// NewS3 returns the S3 destination of the configuration.
+func NewS3(cfg config.S3Uploader) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("the bucket of the diagnostics uploads is not set")
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("the region of the diagnostics uploads is not set")
+	}
+	if cfg.AccessKeyID == "" && cfg.SecretAccessKey == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("the credentials of the diagnostics uploads are not set")
+	}
+	if cfg.PartSize < s3MinPartSize {
+		cfg.PartSize = s3MinPartSize
+	}
+	return &S3{
+		cfg:       cfg,
+		client:    &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}, Timeout: 5 * time.Minute},
+		retryWait: time.Second,
+		now:       time.Now,
+	}, nil
+}
+
+// Name returns the name of the destination.
+func (d *S3) Name() string {
+	return DestinationS3
+}
+
+// Upload uploads the bundle in parts of a multipart upload, it returns the key of the object.
+func (d *S3) Upload(ctx context.Context, r io.ReaderAt, size int64, p *Progress, save func() error) (string, error) {
+	key := path.Join(d.cfg.Prefix, p.Name)
+	if p.UploadID == "" {
+		var result struct {
+			UploadID string `xml:"UploadId"`
+		}
+		if err := d.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, &result); err != nil {
+			return key, fmt.Errorf("failed to start the upload: %w", err)
+		}
+		p.UploadID = result.UploadID
+		p.ChunkSize = d.cfg.PartSize
+		p.Parts = nil
+		if err := save(); err != nil {
+			return key, err
+		}
+	}
+
+	for offset := int64(len(p.Parts)) * p.ChunkSize; offset < size; offset += p.ChunkSize {
+		data := make([]byte, min(p.ChunkSize, size-offset))
+		if _, err := r.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+			return key, fmt.Errorf("failed to read the diagnostics: %w", err)
+		}
+		query := url.Values{
+			"partNumber": {strconv.Itoa(len(p.Parts) + 1)},
+			"uploadId":   {p.UploadID},
+		}
+		var etag string
+		if err := d.doWithETag(ctx, http.MethodPut, key, query, data, &etag); err != nil {
+			return key, fmt.Errorf("failed to upload part %d: %w", len(p.Parts)+1, err)
+		}
+		p.Parts = append(p.Parts, etag)
+		if err := save(); err != nil {
+			return key, err
+		}
+	}
+
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	complete := struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{}
+	for i, etag := range p.Parts {
+		complete.Parts = append(complete.Parts, part{PartNumber: i + 1, ETag: etag})
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return key, err
+	}
+	if err := d.do(ctx, http.MethodPost, key, url.Values{"uploadId": {p.UploadID}}, body, nil); err != nil {
+		return key, fmt.Errorf("failed to complete the upload: %w", err)
+	}
+	return key, nil
+}
+
+// do sends a request to the bucket and decodes its XML response in result when not nil.
+func (d *S3) do(ctx context.Context, method string, key string, query url.Values, body []byte, result interface{}) error {
+	resp, err := d.send(ctx, method, key, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil {
+		return nil
+	}
+	return xml.NewDecoder(resp.Body).Decode(result)
+}
+
+// doWithETag sends a request to the bucket and returns the ETag of its response.
+func (d *S3) doWithETag(ctx context.Context, method string, key string, query url.Values, body []byte, etag *string) error {
+	resp, err := d.send(ctx, method, key, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	*etag = resp.Header.Get("ETag")
+	return nil
+}
+
+// send sends a signed request, the errors and the server errors are retried.
+func (d *S3) send(ctx context.Context, method string, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := d.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	var lastErr error
+	for attempt := 0; attempt < s3Attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(d.retryWait << (attempt - 1)):
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		d.sign(req, body)
+		resp, err := d.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode < 300 {
+			return resp, nil
+		}
+		lastErr = s3Error(resp)
+		resp.Body.Close()
+		if resp.StatusCode < 500 {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// objectURL returns the URL of the object of the key, in the virtual host of the bucket on AWS or
+// in its path on the configured endpoint.
+func (d *S3) objectURL(key string) (*url.URL, error) {
+	if d.cfg.Endpoint == "" {
+		return url.Parse(fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", d.cfg.Bucket, d.cfg.Region, escapePath(key)))
+	}
+	u, err := url.Parse(d.cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	u.Path = path.Join("/", u.Path, d.cfg.Bucket, key)
+	u.RawPath = escapePath(u.Path)
+	return u, nil
+}
+
+// s3Error returns the error of an S3 response.
+func s3Error(resp *http.Response) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := xml.Unmarshal(data, &e); err == nil && e.Code != "" {
+		return fmt.Errorf("status code: %d, S3 returned an error: %s: %s", resp.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Errorf("status code: %d, S3 returned an error", resp.StatusCode)
+}
+
+// sign signs the request with AWS Signature Version 4, the host and the headers set on the request
+// are signed.
+func (d *S3) sign(req *http.Request, body []byte) {
+	now := d.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if d.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", d.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + d.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+d.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, d.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", d.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes the query sorted by key, as signed by Signature Version 4.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath encodes each segment of the path as signed by Signature Version 4.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = uriEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode encodes all the bytes but the unreserved characters of RFC 3986.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,248 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package upload uploads the diagnostics bundles to Fleet Server or to an S3 bucket in chunks. The
+// bundles are spooled on disk with the progress of their upload, so an upload interrupted by a
+// restart of the agent resumes from the next chunk.
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// The destinations of the uploads.
+const (
+	DestinationFleet = "fleet"
+	DestinationS3    = "s3"
+)
+
+// spoolMaxAge is the age of the spooled bundles removed without being resumed, the requests of
+// diagnostics are not resent for that long.
+const spoolMaxAge = 24 * time.Hour
+
+// Progress is the progress of the upload of a bundle, saved after each chunk.
+type Progress struct {
+	// Destination is the destination of the upload, a bundle is only resumed to the same one.
+	Destination string `json:"destination"`
+	// Name is the name of the uploaded file.
+	Name string `json:"name"`
+	// UploadID is the ID of the upload, empty until it is started.
+	UploadID  string `json:"upload_id,omitempty"`
+	ChunkSize int64  `json:"chunk_size,omitempty"`
+	// Parts are the SHA-256 of the chunks uploaded to Fleet Server or the ETags of the parts
+	// uploaded to S3.
+	Parts []string `json:"parts,omitempty"`
+}
+
+// Destination uploads bundles in chunks.
+type Destination interface {
+	// Name returns the name of the destination.
+	Name() string
+	// Upload uploads the size bytes read from r, from the chunk following the ones of the
+	// progress. save is called after each chunk. It returns the ID of the upload.
+	Upload(ctx context.Context, r io.ReaderAt, size int64, p *Progress, save func() error) (string, error)
+}
+
+// NewDestination returns the destination of the configuration, the Fleet destination uploads
+// with the client for the action.
+func NewDestination(cfg config.Uploader, fleet *uploader.Client, actionID string) (Destination, error) {
+	switch cfg.Destination {
+	case "", DestinationFleet:
+		if fleet == nil {
+			return nil, errors.New("the diagnostics can only be uploaded to Fleet by an enrolled agent")
+		}
+		if actionID == "" {
+			return nil, errors.New("the diagnostics can only be uploaded to Fleet for a request of diagnostics, its action ID is needed")
+		}
+		return NewFleet(fleet, actionID), nil
+	case DestinationS3:
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown diagnostics upload destination %q, must be %s or %s", cfg.Destination, DestinationFleet, DestinationS3)
+	}
+}
+
+// Spool is the directory the bundles are written to until they are uploaded.
+type Spool struct {
+	dir string
+}
+
+// NewSpool returns the spool of the directory.
+func NewSpool(dir string) *Spool {
+	return &Spool{dir: dir}
+}
+
+func (s *Spool) bundlePath(id string) string {
+	return filepath.Join(s.dir, id+".zip")
+}
+
+func (s *Spool) progressPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Create creates the file the bundle of the ID is written to.
+func (s *Spool) Create(id string) (*os.File, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the diagnostics upload directory: %w", err)
+	}
+	return os.OpenFile(s.bundlePath(id), os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0o600)
+}
+
+// Pending returns the spooled bundle of the ID and the progress of its upload, a nil file when
+// there is none to resume.
+func (s *Spool) Pending(id string) (*os.File, *Progress, error) {
+	data, err := os.ReadFile(s.progressPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the progress of the upload of %s: %w", id, err)
+	}
+	var p Progress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, nil, fmt.Errorf("failed to read the progress of the upload of %s: %w", id, err)
+	}
+	f, err := os.Open(s.bundlePath(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the bundle of %s: %w", id, err)
+	}
+	return f, &p, nil
+}
+
+// Save saves the progress of the upload of the bundle of the ID.
+func (s *Spool) Save(id string, p *Progress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmp := s.progressPath(id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save the progress of the upload of %s: %w", id, err)
+	}
+	return os.Rename(tmp, s.progressPath(id))
+}
+
+// Remove removes the bundle of the ID and the progress of its upload.
+func (s *Spool) Remove(id string) {
+	_ = os.Remove(s.bundlePath(id))
+	_ = os.Remove(s.progressPath(id))
+}
+
+// Prune removes the bundles older than the maximum age, they are not resumed anymore.
+func (s *Spool) Prune(maxAge time.Duration) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && time.Since(info.ModTime()) > maxAge {
+			_ = os.Remove(filepath.Join(s.dir, e.Name()))
+		}
+	}
+}
+
+// Upload uploads the bundle of the ID spooled in f to the destination, resuming the progress of a
+// previous attempt when it has one. The bundle is kept to be resumed when the context is done
+// before the upload completes, it is removed otherwise.
+func Upload(ctx context.Context, log *logger.Logger, spool *Spool, id string, f *os.File, p *Progress, dest Destination) (string, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		spool.Remove(id)
+		return "", err
+	}
+	if p.Destination != dest.Name() {
+		// uploaded elsewhere before, start over
+		*p = Progress{Destination: dest.Name(), Name: p.Name}
+	}
+	save := func() error {
+		return spool.Save(id, p)
+	}
+	if err := save(); err != nil {
+		spool.Remove(id)
+		return "", err
+	}
+
+	resumed := p.UploadID != ""
+	uploadID, err := dest.Upload(ctx, f, fi.Size(), p, save)
+	if err != nil && resumed && ctx.Err() == nil {
+		// the upload may have expired on the destination, start over
+		log.Warnw("Failed to resume the upload of the diagnostics, uploading them again", "error.message", err)
+		*p = Progress{Destination: dest.Name(), Name: p.Name}
+		uploadID, err = dest.Upload(ctx, f, fi.Size(), p, save)
+	}
+	if err != nil && ctx.Err() != nil {
+		log.Infof("Upload of the diagnostics %s interrupted after %d chunks, it resumes with the same request", id, len(p.Parts))
+		return uploadID, err
+	}
+	spool.Remove(id)
+	return uploadID, err
+}
+
+// Uploader uploads the bundles of the requests of diagnostics of Fleet, it resumes the uploads
+// interrupted by a restart of the agent when Fleet sends the request again.
+type Uploader struct {
+	log   *logger.Logger
+	cfg   config.Uploader
+	fleet *uploader.Client
+	spool *Spool
+}
+
+// NewUploader returns the uploader of the bundles of the requests of diagnostics, spooled in the
+// directory.
+func NewUploader(log *logger.Logger, cfg config.Uploader, fleet *uploader.Client, dir string) *Uploader {
+	spool := NewSpool(dir)
+	spool.Prune(spoolMaxAge)
+	return &Uploader{
+		log:   log,
+		cfg:   cfg,
+		fleet: fleet,
+		spool: spool,
+	}
+}
+
+// UploadDiagnostics uploads the bundle of the action read from r. When an upload of the action
+// was interrupted, it is resumed and r is ignored.
+func (u *Uploader) UploadDiagnostics(ctx context.Context, actionID string, timestamp string, size int64, r io.Reader) (string, error) {
+	dest, err := NewDestination(u.cfg, u.fleet, actionID)
+	if err != nil {
+		return "", err
+	}
+	f, p, err := u.spool.Pending(actionID)
+	if err != nil {
+		u.log.Warnw("Failed to resume the upload of the diagnostics, uploading them again", "error.message", err)
+	}
+	if f != nil {
+		u.log.Infof("Resuming the upload of the diagnostics %s after %d chunks", actionID, len(p.Parts))
+	} else {
+		if f, err = u.spool.Create(actionID); err != nil {
+			return "", err
+		}
+		if _, err := io.CopyN(f, r, size); err != nil {
+			f.Close()
+			u.spool.Remove(actionID)
+			return "", fmt.Errorf("failed to spool the diagnostics: %w", err)
+		}
+		p = &Progress{Destination: dest.Name(), Name: BundleName(timestamp)}
+	}
+	defer f.Close()
+	return Upload(ctx, u.log, u.spool, actionID, f, p, dest)
+}
+
+// BundleName returns the name of the file of a bundle created at the timestamp.
+func BundleName(timestamp string) string {
+	return fmt.Sprintf("elastic-agent-diagnostics-%s.zip", timestamp)
+}
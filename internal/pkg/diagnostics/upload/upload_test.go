@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// fakeFleet simulates the file upload API of Fleet Server, it cancels the upload before the
+// chunk interruptAt.
+type fakeFleet struct {
+	chunkSize   int64
+	interruptAt int
+	cancel      context.CancelFunc
+
+	newUploads int
+	chunks     map[int][]byte
+	finish     *uploader.FinishRequest
+}
+
+func (f *fakeFleet) Send(ctx context.Context, method, path string, _ url.Values, _ http.Header, body io.Reader) (*http.Response, error) {
+	data, _ := io.ReadAll(body)
+	switch {
+	case path == uploader.PathNewUpload:
+		f.newUploads++
+		var req uploader.NewUploadRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, err
+		}
+		return response(fmt.Sprintf(`{"upload_id":"upload-%d","chunk_size":%d}`, f.newUploads, f.chunkSize)), nil
+	case method == http.MethodPut:
+		var id, chunk int
+		if _, err := fmt.Sscanf(path, "/api/fleet/uploads/upload-%d/%d", &id, &chunk); err != nil {
+			return nil, err
+		}
+		if chunk == f.interruptAt && f.cancel != nil {
+			f.cancel()
+			f.cancel = nil
+			return nil, ctx.Err()
+		}
+		f.chunks[chunk] = data
+		return response("{}"), nil
+	default:
+		f.finish = &uploader.FinishRequest{}
+		if err := json.Unmarshal(data, f.finish); err != nil {
+			return nil, err
+		}
+		return response("{}"), nil
+	}
+}
+
+func (f *fakeFleet) URI() string {
+	return "http://localhost"
+}
+
+func response(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestUploaderResumesAnInterruptedUpload(t *testing.T) {
+	bundle := []byte("0123456789")
+	fleet := &fakeFleet{chunkSize: 4, interruptAt: 1, chunks: make(map[int][]byte)}
+	client := uploader.New("agent-1", fleet, config.Uploader{MaxRetries: 1, InitDur: time.Millisecond, MaxDur: time.Millisecond})
+	dir := t.TempDir()
+	u := NewUploader(logger.NewWithoutConfig(""), config.Uploader{Destination: DestinationFleet}, client, dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fleet.cancel = cancel
+	_, err := u.UploadDiagnostics(ctx, "action-1", "2023-09-04T12-00-00Z", int64(len(bundle)), bytes.NewReader(bundle))
+	require.Error(t, err)
+	assert.Nil(t, fleet.finish)
+
+	f, p, err := u.spool.Pending("action-1")
+	require.NoError(t, err)
+	require.NotNil(t, f, "interrupted upload should be kept to be resumed")
+	f.Close()
+	assert.Equal(t, "upload-1", p.UploadID)
+	assert.Len(t, p.Parts, 1)
+
+	// the request is sent again after a restart, the diagnostics gathered again are ignored
+	uploadID, err := u.UploadDiagnostics(context.Background(), "action-1", "2023-09-04T12-10-00Z", 3, bytes.NewReader([]byte("new")))
+	require.NoError(t, err)
+	assert.Equal(t, "upload-1", uploadID)
+	assert.Equal(t, 1, fleet.newUploads, "upload should be resumed")
+	assert.Equal(t, map[int][]byte{0: []byte("0123"), 1: []byte("4567"), 2: []byte("89")}, fleet.chunks)
+
+	transitHash := sha256.New()
+	for i := 0; i < 3; i++ {
+		hash := sha256.Sum256(fleet.chunks[i])
+		transitHash.Write(hash[:])
+	}
+	require.NotNil(t, fleet.finish)
+	assert.Equal(t, fmt.Sprintf("%x", transitHash.Sum(nil)), fleet.finish.TransitHash.SHA256)
+
+	f, _, err = u.spool.Pending("action-1")
+	assert.NoError(t, err)
+	assert.Nil(t, f, "completed upload should be removed")
+}
+
+func TestUploaderRejectsInvalidDestinations(t *testing.T) {
+	fleet := &fakeFleet{chunkSize: 4, chunks: make(map[int][]byte)}
+	client := uploader.New("agent-1", fleet, config.Uploader{MaxRetries: 1, InitDur: time.Millisecond, MaxDur: time.Millisecond})
+	u := NewUploader(logger.NewWithoutConfig(""), config.Uploader{Destination: "ftp"}, client, t.TempDir())
+
+	_, err := u.UploadDiagnostics(context.Background(), "action-1", "2023-09-04T12-00-00Z", 4, bytes.NewReader([]byte("0123")))
+	assert.Error(t, err, "unknown destination should fail")
+
+	u.cfg.Destination = DestinationFleet
+	_, err = u.UploadDiagnostics(context.Background(), "", "2023-09-04T12-00-00Z", 4, bytes.NewReader([]byte("0123")))
+	assert.Error(t, err, "upload to Fleet without an action should fail")
+}
@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package fault injects faults in the Elastic Agent so its failure handling can be tested.
+//
+// Faults are only injected when the Elastic Agent is built with the faultinject build tag, otherwise the
+// hooks of this package do nothing. Integration tests control the faults of a running Elastic Agent by
+// writing them to the faults file (see paths.AgentFaultsFile), unit tests can inject them directly.
+//
+// Example of faults file:
+//
+//	faults:
+//	  - kind: drop_checkin
+//	    target: filestream-default
+//	  - kind: delay_checkin_expected
+//	    delay: 10s
+//	  - kind: kill_component
+//	    target: system/metrics-default
+//	    count: 3
+//	  - kind: corrupt_download
+//	    target: elastic-agent
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrDisabled is returned when injecting faults in an Elastic Agent built without the faultinject build tag.
+var ErrDisabled = errors.New("fault injection is disabled, build with the faultinject tag to enable it")
+
+// Kind is the kind of fault.
+type Kind string
+
+const (
+	// DropCheckin drops the observed check-ins of the targeted components, as if they stopped checking in.
+	DropCheckin Kind = "drop_checkin"
+	// DelayCheckinExpected delays the delivery of the expected check-ins to the targeted components.
+	DelayCheckinExpected Kind = "delay_checkin_expected"
+	// KillComponent kills the process of the targeted components.
+	KillComponent Kind = "kill_component"
+	// CorruptDownload corrupts the downloaded artifacts of the targeted names.
+	CorruptDownload Kind = "corrupt_download"
+)
+
+// Fault is a fault injected in the Elastic Agent.
+type Fault struct {
+	// Kind is the kind of fault.
+	Kind Kind `yaml:"kind" json:"kind"`
+	// Target is the component ID or artifact name the fault applies to, every target when empty.
+	Target string `yaml:"target,omitempty" json:"target,omitempty"`
+	// Delay is the delay of DelayCheckinExpected faults.
+	Delay time.Duration `yaml:"delay,omitempty" json:"delay,omitempty"`
+	// Count is the number of times the fault is triggered before being removed, unlimited when 0.
+	Count int `yaml:"count,omitempty" json:"count,omitempty"`
+}
+
+// Validate returns an error when the fault is invalid.
+func (f Fault) Validate() error {
+	switch f.Kind {
+	case DropCheckin, KillComponent, CorruptDownload:
+	case DelayCheckinExpected:
+		if f.Delay <= 0 {
+			return fmt.Errorf("fault %s requires a positive delay", f.Kind)
+		}
+	default:
+		return fmt.Errorf("unknown fault kind %q", f.Kind)
+	}
+	if f.Count < 0 {
+		return fmt.Errorf("fault %s count cannot be negative", f.Kind)
+	}
+	return nil
+}
+
+type faultsFile struct {
+	Faults []Fault `yaml:"faults,omitempty"`
+}
+
+// Parse parses the faults of a faults file.
+func Parse(data []byte) ([]Fault, error) {
+	var file faultsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse faults: %w", err)
+	}
+	for i, f := range file.Faults {
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("fault %d: %w", i, err)
+		}
+	}
+	return file.Faults, nil
+}
+
+// Marshal returns the faults in the format of the faults file.
+func Marshal(faults []Fault) ([]byte, error) {
+	return yaml.Marshal(faultsFile{Faults: faults})
+}
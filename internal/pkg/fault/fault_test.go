@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	scenarios := []struct {
+		Name   string
+		Data   string
+		Faults []Fault
+		Err    string
+	}{
+		{
+			Name: "empty",
+			Data: "",
+		},
+		{
+			Name: "all kinds",
+			Data: `
+faults:
+  - kind: drop_checkin
+    target: filestream-default
+  - kind: delay_checkin_expected
+    delay: 10s
+  - kind: kill_component
+    target: system/metrics-default
+    count: 3
+  - kind: corrupt_download
+    target: elastic-agent
+`,
+			Faults: []Fault{
+				{Kind: DropCheckin, Target: "filestream-default"},
+				{Kind: DelayCheckinExpected, Delay: 10 * time.Second},
+				{Kind: KillComponent, Target: "system/metrics-default", Count: 3},
+				{Kind: CorruptDownload, Target: "elastic-agent"},
+			},
+		},
+		{
+			Name: "unknown kind",
+			Data: "faults: [{kind: explode}]",
+			Err:  `fault 0: unknown fault kind "explode"`,
+		},
+		{
+			Name: "delay without duration",
+			Data: "faults: [{kind: drop_checkin}, {kind: delay_checkin_expected}]",
+			Err:  "fault 1: fault delay_checkin_expected requires a positive delay",
+		},
+		{
+			Name: "negative count",
+			Data: "faults: [{kind: kill_component, count: -1}]",
+			Err:  "fault 0: fault kill_component count cannot be negative",
+		},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			faults, err := Parse([]byte(scenario.Data))
+			if scenario.Err != "" {
+				assert.EqualError(t, err, scenario.Err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, scenario.Faults, faults)
+
+			// marshalled faults parse back to the same faults
+			data, err := Marshal(faults)
+			require.NoError(t, err)
+			parsed, err := Parse(data)
+			require.NoError(t, err)
+			assert.Equal(t, faults, parsed)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build faultinject
+
+package fault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Enabled is true when the Elastic Agent is built with the faultinject build tag.
+const Enabled = true
+
+// watchPeriod is the period the faults file is checked for changes.
+const watchPeriod = time.Second
+
+var (
+	faultsMx sync.Mutex
+	faults   []Fault
+)
+
+// Inject adds the fault to the active faults.
+func Inject(f Fault) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	faultsMx.Lock()
+	defer faultsMx.Unlock()
+	faults = append(faults, f)
+	return nil
+}
+
+// Set replaces the active faults.
+func Set(fs []Fault) error {
+	for _, f := range fs {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+	faultsMx.Lock()
+	defer faultsMx.Unlock()
+	faults = append([]Fault(nil), fs...)
+	return nil
+}
+
+// Clear removes all the active faults.
+func Clear() {
+	faultsMx.Lock()
+	defer faultsMx.Unlock()
+	faults = nil
+}
+
+// Active returns the active faults.
+func Active() []Fault {
+	faultsMx.Lock()
+	defer faultsMx.Unlock()
+	return append([]Fault(nil), faults...)
+}
+
+// Watch sets the active faults from the faults file each time it changes until the context is done, the
+// faults are cleared when the file is removed.
+func Watch(ctx context.Context, log *logger.Logger, path string) {
+	log.Warnf("Fault injection is enabled, faults are read from %s", path)
+	var last []byte
+	t := time.NewTicker(watchPeriod)
+	defer t.Stop()
+	for {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			data, err = nil, nil
+		}
+		if err != nil {
+			log.Errorf("Failed to read faults file %s: %s", path, err)
+		} else if !bytes.Equal(data, last) {
+			last = data
+			fs, err := Parse(data)
+			if err == nil {
+				err = Set(fs)
+			}
+			if err != nil {
+				log.Errorf("Invalid faults file %s: %s", path, err)
+			} else {
+				log.Warnf("Injecting %d faults", len(fs))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (f Fault) matches(kind Kind, target string) bool {
+	return f.Kind == kind && (f.Target == "" || f.Target == target)
+}
+
+// trigger returns the first active fault of the kind matching the target, the fault is removed once
+// triggered its number of times.
+func trigger(kind Kind, target string) (Fault, bool) {
+	faultsMx.Lock()
+	defer faultsMx.Unlock()
+	for i, f := range faults {
+		if !f.matches(kind, target) {
+			continue
+		}
+		if f.Count > 0 {
+			faults[i].Count--
+			if faults[i].Count == 0 {
+				faults = append(faults[:i], faults[i+1:]...)
+			}
+		}
+		return f, true
+	}
+	return Fault{}, false
+}
+
+// DropCheckinObserved returns true when the observed check-in of the component must be dropped.
+func DropCheckinObserved(componentID string) bool {
+	_, ok := trigger(DropCheckin, componentID)
+	return ok
+}
+
+// CheckinExpectedDelay returns how long the delivery of the expected check-in to the component must be delayed.
+func CheckinExpectedDelay(componentID string) time.Duration {
+	f, ok := trigger(DelayCheckinExpected, componentID)
+	if !ok {
+		return 0
+	}
+	return f.Delay
+}
+
+// KillComponentProcess returns true when the process of the component must be killed.
+func KillComponentProcess(componentID string) bool {
+	_, ok := trigger(KillComponent, componentID)
+	return ok
+}
+
+// CorruptDownloadedFile corrupts the file of the downloaded artifact when requested by a fault.
+func CorruptDownloadedFile(name string, path string) error {
+	if _, ok := trigger(CorruptDownload, name); !ok {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open downloaded file to corrupt: %w", err)
+	}
+	defer f.Close()
+	// flipping the bits of the first byte is enough to fail the hash verification
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		return fmt.Errorf("failed to read downloaded file to corrupt: %w", err)
+	}
+	b[0] = ^b[0]
+	if _, err := f.WriteAt(b, 0); err != nil {
+		return fmt.Errorf("failed to corrupt downloaded file: %w", err)
+	}
+	return nil
+}
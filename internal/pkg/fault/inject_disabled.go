@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !faultinject
+
+package fault
+
+import (
+	"context"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Enabled is true when the Elastic Agent is built with the faultinject build tag.
+const Enabled = false
+
+// Inject returns ErrDisabled.
+func Inject(Fault) error {
+	return ErrDisabled
+}
+
+// Set returns ErrDisabled.
+func Set([]Fault) error {
+	return ErrDisabled
+}
+
+// Clear does nothing.
+func Clear() {}
+
+// Active returns no faults.
+func Active() []Fault {
+	return nil
+}
+
+// Watch does nothing.
+func Watch(context.Context, *logger.Logger, string) {}
+
+// DropCheckinObserved returns false.
+func DropCheckinObserved(string) bool {
+	return false
+}
+
+// CheckinExpectedDelay returns 0.
+func CheckinExpectedDelay(string) time.Duration {
+	return 0
+}
+
+// KillComponentProcess returns false.
+func KillComponentProcess(string) bool {
+	return false
+}
+
+// CorruptDownloadedFile does nothing.
+func CorruptDownloadedFile(string, string) error {
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build faultinject
+
+package fault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestTrigger(t *testing.T) {
+	t.Cleanup(Clear)
+
+	require.NoError(t, Inject(Fault{Kind: DropCheckin, Target: "filestream-default"}))
+	require.NoError(t, Inject(Fault{Kind: KillComponent, Count: 2}))
+	require.NoError(t, Inject(Fault{Kind: DelayCheckinExpected, Delay: time.Second}))
+
+	assert.True(t, DropCheckinObserved("filestream-default"))
+	assert.True(t, DropCheckinObserved("filestream-default"), "unlimited fault is triggered again")
+	assert.False(t, DropCheckinObserved("log-default"), "other component is not targeted")
+
+	assert.True(t, KillComponentProcess("log-default"))
+	assert.True(t, KillComponentProcess("filestream-default"))
+	assert.False(t, KillComponentProcess("log-default"), "fault is removed after its count")
+
+	assert.Equal(t, time.Second, CheckinExpectedDelay("log-default"))
+	assert.Len(t, Active(), 2)
+}
+
+func TestCorruptDownloadedFile(t *testing.T) {
+	t.Cleanup(Clear)
+
+	path := filepath.Join(t.TempDir(), "elastic-agent.tar.gz")
+	require.NoError(t, os.WriteFile(path, []byte("artifact"), 0600))
+
+	require.NoError(t, CorruptDownloadedFile("elastic-agent", path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "artifact", string(data), "file is unchanged without faults")
+
+	require.NoError(t, Inject(Fault{Kind: CorruptDownload, Target: "elastic-agent", Count: 1}))
+	require.NoError(t, CorruptDownloadedFile("elastic-agent", path))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotEqual(t, "artifact", string(data))
+	assert.Empty(t, Active())
+}
+
+func TestWatch(t *testing.T) {
+	t.Cleanup(Clear)
+
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "faults.yml")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, log, path)
+
+	data, err := Marshal([]Fault{{Kind: DropCheckin}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	assert.Eventually(t, func() bool {
+		return len(Active()) == 1
+	}, 5*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, os.Remove(path))
+	assert.Eventually(t, func() bool {
+		return len(Active()) == 0
+	}, 5*time.Second, 100*time.Millisecond)
+}
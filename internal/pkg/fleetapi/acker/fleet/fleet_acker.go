@@ -76,6 +76,31 @@ func (f *Acker) Ack(ctx context.Context, action fleetapi.Action) (err error) {
 	return nil
 }
 
+// Event sends an event about the agent itself to fleet, it isn't tied to an action.
+func (f *Acker) Event(ctx context.Context, event fleetapi.AckEvent) (err error) {
+	span, ctx := apm.StartSpan(ctx, "event", "app.internal")
+	defer func() {
+		apm.CaptureError(ctx, err).Send()
+		span.End()
+	}()
+	agentID := f.agentInfo.AgentID()
+	cmd := fleetapi.NewAckCmd(f.agentInfo, f.client)
+	event.AgentID = agentID
+	event.Timestamp = time.Now().Format(fleetTimeFormat)
+	req := &fleetapi.AckRequest{
+		Events: []fleetapi.AckEvent{event},
+	}
+
+	_, err = cmd.Execute(ctx, req)
+	if err != nil {
+		return errors.New(err, fmt.Sprintf("sending %s event for elastic-agent '%s' failed", event.EventType, agentID), errors.TypeNetwork)
+	}
+
+	f.log.Debugf("%s event '%s' was just sent", event.EventType, event.SubType)
+
+	return nil
+}
+
 // AckBatch acknowledges multiple actions at once.
 func (f *Acker) AckBatch(ctx context.Context, actions []fleetapi.Action) (res *fleetapi.AckResponse, err error) {
 	f.log.Debugf("fleet acker: ackbatch, actions: %#v", actions)
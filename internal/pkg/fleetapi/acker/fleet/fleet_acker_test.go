@@ -210,3 +210,27 @@ func TestAcker_Ack(t *testing.T) {
 		})
 	}
 }
+
+func TestAcker_Event(t *testing.T) {
+	log, _ := logger.New("fleet_acker", false)
+	sender := &testSender{}
+	acker, err := NewAcker(log, &testAgentInfo{}, sender)
+	require.NoError(t, err)
+
+	err = acker.Event(context.Background(), fleetapi.AckEvent{
+		EventType: "ERROR",
+		SubType:   "FAILED",
+		Message:   "binary integrity check failed",
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, sender.req)
+	require.Len(t, sender.req.Events, 1)
+	event := sender.req.Events[0]
+	assert.Equal(t, "ERROR", event.EventType)
+	assert.Equal(t, "FAILED", event.SubType)
+	assert.Equal(t, "agent-secret", event.AgentID)
+	assert.Equal(t, "binary integrity check failed", event.Message)
+	assert.Empty(t, event.ActionID)
+	assert.NotEmpty(t, event.Timestamp)
+}
@@ -35,6 +35,8 @@ const (
 	ActionTypeCancel = "CANCEL"
 	// ActionTypeDiagnostics specifies a diagnostics action.
 	ActionTypeDiagnostics = "REQUEST_DIAGNOSTICS"
+	// ActionTypeBackfill specifies a backfill action of the log inputs.
+	ActionTypeBackfill = "BACKFILL"
 )
 
 // Error values that the Action interface can return
@@ -232,6 +234,8 @@ type ActionUpgrade struct {
 	SourceURI        string `json:"source_uri,omitempty" yaml:"source_uri,omitempty"`
 	Retry            int    `json:"retry_attempt,omitempty" yaml:"retry_attempt,omitempty"`
 	Err              error
+	// Warnings are the non-fatal degradations of a successful upgrade, reported in the ack payload
+	Warnings []string `json:"-" yaml:"-"`
 }
 
 func (a *ActionUpgrade) String() string {
@@ -259,6 +263,9 @@ func (a *ActionUpgrade) AckEvent() AckEvent {
 		}
 		p, _ := json.Marshal(payload)
 		event.Payload = p
+	} else if len(a.Warnings) > 0 {
+		p, _ := json.Marshal(map[string]interface{}{"warnings": a.Warnings})
+		event.Payload = p
 	}
 	return event
 }
@@ -460,6 +467,56 @@ func (a *ActionDiagnostics) AckEvent() AckEvent {
 	return event
 }
 
+// ActionBackfill instructs the log inputs to re-read a time range or a set of files.
+type ActionBackfill struct {
+	ActionID   string `json:"action_id" yaml:"action_id"`
+	ActionType string `json:"type" yaml:"type"`
+	// Inputs are the IDs of the inputs to backfill, every log input when empty.
+	Inputs []string `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	// Paths are the files to re-read, every file of the inputs when empty.
+	Paths []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+	// From and To are the RFC3339 bounds of the time range to re-read.
+	From string `json:"from,omitempty" yaml:"from,omitempty"`
+	To   string `json:"to,omitempty" yaml:"to,omitempty"`
+
+	Response    map[string]interface{} `json:"-" yaml:"-"`
+	StartedAt   string                 `json:"-" yaml:"-"`
+	CompletedAt string                 `json:"-" yaml:"-"`
+	Err         error                  `json:"-" yaml:"-"`
+}
+
+// ID returns the ID of the action.
+func (a *ActionBackfill) ID() string {
+	return a.ActionID
+}
+
+// Type returns the type of the action.
+func (a *ActionBackfill) Type() string {
+	return a.ActionType
+}
+
+func (a *ActionBackfill) String() string {
+	var s strings.Builder
+	s.WriteString("action_id: ")
+	s.WriteString(a.ActionID)
+	s.WriteString(", type: ")
+	s.WriteString(a.ActionType)
+	s.WriteString(", inputs: ")
+	s.WriteString(strings.Join(a.Inputs, ","))
+	return s.String()
+}
+
+func (a *ActionBackfill) AckEvent() AckEvent {
+	event := newAckEvent(a.ActionID, a.ActionType)
+	event.ActionResponse = a.Response
+	event.StartedAt = a.StartedAt
+	event.CompletedAt = a.CompletedAt
+	if a.Err != nil {
+		event.Error = a.Err.Error()
+	}
+	return event
+}
+
 // ActionApp is the application action request.
 type ActionApp struct {
 	ActionID    string                 `json:"id" mapstructure:"id"`
@@ -607,6 +664,16 @@ func (a *Actions) UnmarshalJSON(data []byte) error {
 					"fail to decode REQUEST_DIAGNOSTICS_ACTION action",
 					errors.TypeConfig)
 			}
+		case ActionTypeBackfill:
+			action = &ActionBackfill{
+				ActionID:   response.ActionID,
+				ActionType: response.ActionType,
+			}
+			if err := json.Unmarshal(response.Data, action); err != nil {
+				return errors.New(err,
+					"fail to decode BACKFILL action",
+					errors.TypeConfig)
+			}
 		default:
 			action = &ActionUnknown{
 				ActionID:     response.ActionID,
@@ -705,6 +772,16 @@ func (a *Actions) UnmarshalYAML(unmarshal func(interface{}) error) error {
 					"fail to decode REQUEST_DIAGNOSTICS_ACTION action",
 					errors.TypeConfig)
 			}
+		case ActionTypeBackfill:
+			action = &ActionBackfill{
+				ActionID:   n.ActionID,
+				ActionType: n.ActionType,
+			}
+			if err := yaml.Unmarshal(n.Data, action); err != nil {
+				return errors.New(err,
+					"fail to decode BACKFILL action",
+					errors.TypeConfig)
+			}
 		default:
 			action = &ActionUnknown{
 				ActionID:     n.ActionID,
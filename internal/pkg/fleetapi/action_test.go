@@ -7,6 +7,7 @@ package fleetapi
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -152,4 +153,41 @@ func TestActionsUnmarshalJSON(t *testing.T) {
 		assert.Equal(t, "http://example.com", action.SourceURI)
 		assert.Equal(t, 1, action.Retry)
 	})
+	t.Run("ActionBackfill", func(t *testing.T) {
+		p := []byte(`[{"id":"testid","type":"BACKFILL","data":{"inputs":["logs-1"],"paths":["/var/log/app.log"],"from":"2023-09-10T10:00:00Z","to":"2023-09-10T12:00:00Z"}}]`)
+		a := &Actions{}
+		err := a.UnmarshalJSON(p)
+		require.Nil(t, err)
+		action, ok := (*a)[0].(*ActionBackfill)
+		require.True(t, ok, "unable to cast action to specific type")
+		assert.Equal(t, "testid", action.ActionID)
+		assert.Equal(t, ActionTypeBackfill, action.ActionType)
+		assert.Equal(t, []string{"logs-1"}, action.Inputs)
+		assert.Equal(t, []string{"/var/log/app.log"}, action.Paths)
+		assert.Equal(t, "2023-09-10T10:00:00Z", action.From)
+		assert.Equal(t, "2023-09-10T12:00:00Z", action.To)
+	})
+}
+
+func TestActionUpgradeAckEvent(t *testing.T) {
+	t.Run("successful upgrade", func(t *testing.T) {
+		a := &ActionUpgrade{ActionID: "testid", ActionType: ActionTypeUpgrade}
+		event := a.AckEvent()
+		assert.Empty(t, event.Error)
+		assert.Empty(t, event.Payload)
+	})
+
+	t.Run("successful upgrade with warnings", func(t *testing.T) {
+		a := &ActionUpgrade{ActionID: "testid", ActionType: ActionTypeUpgrade, Warnings: []string{"fell back to the embedded PGP key"}}
+		event := a.AckEvent()
+		assert.Empty(t, event.Error)
+		assert.JSONEq(t, `{"warnings":["fell back to the embedded PGP key"]}`, string(event.Payload))
+	})
+
+	t.Run("failed upgrade", func(t *testing.T) {
+		a := &ActionUpgrade{ActionID: "testid", ActionType: ActionTypeUpgrade, Err: errors.New("failed"), Warnings: []string{"ignored"}}
+		event := a.AckEvent()
+		assert.Equal(t, "failed", event.Error)
+		assert.JSONEq(t, `{"retry":false}`, string(event.Payload))
+	})
 }
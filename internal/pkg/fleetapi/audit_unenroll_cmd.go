@@ -0,0 +1,91 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+const auditUnenrollPath = "/api/fleet/agents/%s/audit/unenroll"
+
+// AuditUnenrollReason is the reason an agent unenrolls itself.
+type AuditUnenrollReason string
+
+const (
+	// ReasonUninstall is sent when the agent leaves for good, like an ephemeral agent on shutdown.
+	ReasonUninstall AuditUnenrollReason = "uninstall"
+)
+
+// AuditUnenrollRequest informs Fleet that the agent unenrolled itself.
+// POST /api/fleet/agents/{agentId}/audit/unenroll
+// Authorization: ApiKey {AgentAccessApiKey}
+//
+//	{
+//	  "reason": "uninstall",
+//	  "timestamp": "2023-07-18T09:12:45.512Z"
+//	}
+type AuditUnenrollRequest struct {
+	Reason    AuditUnenrollReason `json:"reason"`
+	Timestamp time.Time           `json:"timestamp"`
+}
+
+// Validate validates the request before sending it to the API.
+func (e *AuditUnenrollRequest) Validate() error {
+	if e.Reason == "" {
+		return errors.New("missing unenroll reason")
+	}
+	return nil
+}
+
+// AuditUnenrollCmd is the command informing Fleet that the agent unenrolled itself.
+type AuditUnenrollCmd struct {
+	client client.Sender
+	info   agentInfo
+}
+
+// NewAuditUnenrollCmd creates a new api command.
+func NewAuditUnenrollCmd(info agentInfo, client client.Sender) *AuditUnenrollCmd {
+	return &AuditUnenrollCmd{
+		client: client,
+		info:   info,
+	}
+}
+
+// Execute sends the unenrollment of the agent to Fleet.
+func (e *AuditUnenrollCmd) Execute(ctx context.Context, r *AuditUnenrollRequest) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.New(err,
+			"fail to encode the audit unenroll request",
+			errors.TypeUnexpected)
+	}
+
+	p := fmt.Sprintf(auditUnenrollPath, e.info.AgentID())
+	resp, err := e.client.Send(ctx, "POST", p, nil, nil, bytes.NewBuffer(b))
+	if err != nil {
+		return errors.New(err,
+			"fail to send the unenrollment to fleet-server",
+			errors.TypeNetwork,
+			errors.M(errors.MetaKeyURI, p))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return client.ExtractError(resp.Body)
+	}
+	return nil
+}
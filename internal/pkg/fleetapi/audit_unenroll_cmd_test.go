@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+func TestAuditUnenroll(t *testing.T) {
+	const withAPIKey = "secret"
+	agentInfo := &agentinfo{}
+	path := fmt.Sprintf("/api/fleet/agents/%s/audit/unenroll", agentInfo.AgentID())
+	now := time.Date(2023, 7, 18, 9, 12, 45, 0, time.UTC)
+
+	t.Run("Test audit unenroll roundtrip", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc(path, authHandler(func(w http.ResponseWriter, r *http.Request) {
+				var req AuditUnenrollRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				require.Equal(t, ReasonUninstall, req.Reason)
+				require.Equal(t, now, req.Timestamp)
+				w.WriteHeader(http.StatusOK)
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			cmd := NewAuditUnenrollCmd(agentInfo, client)
+			err := cmd.Execute(context.Background(), &AuditUnenrollRequest{Reason: ReasonUninstall, Timestamp: now})
+			require.NoError(t, err)
+		},
+	))
+
+	t.Run("Test audit unenroll error", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc(path, authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"statusCode": 404, "error": "AgentNotFound", "message": "agent not found"}`)
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			cmd := NewAuditUnenrollCmd(agentInfo, client)
+			err := cmd.Execute(context.Background(), &AuditUnenrollRequest{Reason: ReasonUninstall, Timestamp: now})
+			require.Error(t, err)
+		},
+	))
+}
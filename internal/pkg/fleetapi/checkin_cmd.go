@@ -13,7 +13,10 @@ import (
 	"net/http"
 	"time"
 
+	"go.elastic.co/apm"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
 )
@@ -52,6 +55,8 @@ type CheckinRequest struct {
 	Metadata   *info.ECSMeta      `json:"local_metadata,omitempty"`
 	Message    string             `json:"message"`    // V2 Agent message
 	Components []CheckinComponent `json:"components"` // V2 Agent components
+
+	UpgradeDetails *details.Details `json:"upgrade_details,omitempty"`
 }
 
 // SerializableEvent is a representation of the event to be send to the Fleet Server API via the checkin
@@ -106,7 +111,12 @@ func NewCheckinCmd(info agentInfo, client client.Sender) *CheckinCmd {
 
 // Execute enroll the Agent in the Fleet Server. Returns the decoded check in response, a duration indicating
 // how long the request took, and an error.
-func (e *CheckinCmd) Execute(ctx context.Context, r *CheckinRequest) (*CheckinResponse, time.Duration, error) {
+func (e *CheckinCmd) Execute(ctx context.Context, r *CheckinRequest) (_ *CheckinResponse, _ time.Duration, err error) {
+	span, ctx := apm.StartSpan(ctx, "execute", "app.internal")
+	defer func() {
+		apm.CaptureError(ctx, err).Send()
+		span.End()
+	}()
 	if err := r.Validate(); err != nil {
 		return nil, 0, err
 	}
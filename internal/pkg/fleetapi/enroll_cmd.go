@@ -30,6 +30,9 @@ var ErrTooManyRequests = errors.New("too many requests received (429)")
 // ErrConnRefused is returned when the connection to the server is refused.
 var ErrConnRefused = errors.New("connection refused")
 
+// ErrConflict is received when the agent ID of the request is enrolled with another replace token.
+var ErrConflict = errors.New("agent ID is enrolled with another replace token (409)")
+
 const (
 	// PermanentEnroll is default enrollment type, by default an Agent is permanently enroll to Agent.
 	PermanentEnroll = EnrollType("PERMANENT")
@@ -81,6 +84,8 @@ func (p EnrollType) MarshalJSON() ([]byte, error) {
 //
 //	{
 //		"type": "PERMANENT",
+//	  "id": "a4937110-e53e-11e9-934f-47a8e38a522c",
+//	  "replace_token": "30e5d3fd-d1d1-4e1f-9b2c-e8bd5e3dc6e4",
 //	  "metadata": {
 //		  "local": { "os": "macos"},
 //		  "user_provided": { "region": "us-east"}
@@ -89,7 +94,12 @@ func (p EnrollType) MarshalJSON() ([]byte, error) {
 type EnrollRequest struct {
 	EnrollAPIKey string     `json:"-"`
 	Type         EnrollType `json:"type"`
-	Metadata     Metadata   `json:"metadata"`
+	// ID is the ID of an existing agent to replace, Fleet Server creates a new agent when empty.
+	ID string `json:"id,omitempty"`
+	// ReplaceToken is stored with the enrolled agent, enrolling again with its ID replaces the agent
+	// only when the replace token matches.
+	ReplaceToken string   `json:"replace_token,omitempty"`
+	Metadata     Metadata `json:"metadata"`
 }
 
 // Metadata is a all the metadata send or received from the elastic-agent.
@@ -214,6 +224,10 @@ func (e *EnrollCmd) Execute(ctx context.Context, r *EnrollRequest) (*EnrollRespo
 		return nil, ErrTooManyRequests
 	}
 
+	if resp.StatusCode == http.StatusConflict {
+		return nil, ErrConflict
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, client.ExtractError(resp.Body)
 	}
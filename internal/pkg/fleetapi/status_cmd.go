@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+const statusPath = "/api/status"
+
+// StatusResponse is the status of Fleet Server, the version is only returned to authenticated requests.
+// GET /api/status
+// Authorization: ApiKey {AgentAccessApiKey}
+//
+//	{
+//	  "name": "fleet-server",
+//	  "status": "HEALTHY",
+//	  "version": {
+//	    "number": "8.11.0",
+//	    "build_hash": "f8f5d0f",
+//	    "build_time": "2023-08-10T12:00:00Z"
+//	  }
+//	}
+type StatusResponse struct {
+	Name    string                 `json:"name"`
+	Status  string                 `json:"status"`
+	Version *StatusResponseVersion `json:"version,omitempty"`
+}
+
+// StatusResponseVersion is the version of Fleet Server.
+type StatusResponseVersion struct {
+	Number    string `json:"number"`
+	BuildHash string `json:"build_hash"`
+	BuildTime string `json:"build_time"`
+}
+
+// StatusCmd is the command retrieving the status of Fleet Server.
+type StatusCmd struct {
+	client client.Sender
+}
+
+// NewStatusCmd creates a new api command.
+func NewStatusCmd(client client.Sender) *StatusCmd {
+	return &StatusCmd{
+		client: client,
+	}
+}
+
+// Execute retrieves the status of Fleet Server.
+func (e *StatusCmd) Execute(ctx context.Context) (*StatusResponse, error) {
+	resp, err := e.client.Send(ctx, http.MethodGet, statusPath, nil, nil, nil)
+	if err != nil {
+		return nil, errors.New(err,
+			"fail to retrieve the status of fleet-server",
+			errors.TypeNetwork,
+			errors.M(errors.MetaKeyURI, statusPath))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, client.ExtractError(resp.Body)
+	}
+
+	status := &StatusResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(status); err != nil {
+		return nil, errors.New(err,
+			"fail to decode the status of fleet-server",
+			errors.TypeNetwork,
+			errors.M(errors.MetaKeyURI, statusPath))
+	}
+	return status, nil
+}
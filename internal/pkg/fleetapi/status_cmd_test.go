@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+func TestStatus(t *testing.T) {
+	const withAPIKey = "secret"
+
+	t.Run("Test status roundtrip", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/status", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"name": "fleet-server", "status": "HEALTHY", "version": {"number": "8.11.0", "build_hash": "f8f5d0f", "build_time": "2023-08-10T12:00:00Z"}}`)
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			status, err := NewStatusCmd(client).Execute(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "HEALTHY", status.Status)
+			require.NotNil(t, status.Version)
+			require.Equal(t, "8.11.0", status.Version.Number)
+		},
+	))
+
+	t.Run("Test status error", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/status", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, `{"statusCode": 503, "error": "Unavailable", "message": "service unavailable"}`)
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			_, err := NewStatusCmd(client).Execute(context.Background())
+			require.Error(t, err)
+		},
+	))
+}
@@ -122,6 +122,11 @@ func New(id string, c client.Sender, cfg config.Uploader) *Client {
 	}
 }
 
+// AgentID returns the ID of the agent uploading the files.
+func (c *Client) AgentID() string {
+	return c.agentID
+}
+
 // New sends a new file upload request to the fleet-server.
 //
 // Request may return a 400 if the specified file.size is too large.
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package hostwatcher
+
+import (
+	"errors"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+// inputConfig is the configuration of an input unit.
+type inputConfig struct {
+	Process struct {
+		Enabled bool `config:"enabled"`
+	} `config:"process"`
+	Network struct {
+		Enabled bool `config:"enabled"`
+	} `config:"network"`
+}
+
+func defaultInputConfig() inputConfig {
+	var c inputConfig
+	c.Process.Enabled = true
+	c.Network.Enabled = true
+	return c
+}
+
+// Validate validates the configuration of an input unit.
+func (c *inputConfig) Validate() error {
+	if !c.Process.Enabled && !c.Network.Enabled {
+		return errors.New("process and network events are disabled, one of them must be enabled")
+	}
+	return nil
+}
+
+// outputConfig is the configuration of the elasticsearch output unit, the settings the host watcher
+// doesn't use are ignored.
+type outputConfig struct {
+	Hosts         []string          `config:"hosts"`
+	Protocol      string            `config:"protocol"`
+	Path          string            `config:"path"`
+	Username      string            `config:"username"`
+	Password      string            `config:"password"`
+	APIKey        string            `config:"api_key"`
+	Headers       map[string]string `config:"headers"`
+	TLS           *tlscommon.Config `config:"ssl"`
+	Timeout       time.Duration     `config:"timeout"`
+	BulkMaxSize   int               `config:"bulk_max_size"`
+	FlushInterval time.Duration     `config:"flush_interval"`
+}
+
+func defaultOutputConfig() outputConfig {
+	return outputConfig{
+		Protocol:      "http",
+		Timeout:       90 * time.Second,
+		BulkMaxSize:   1600,
+		FlushInterval: time.Second,
+	}
+}
+
+// Validate validates the configuration of the output unit.
+func (c *outputConfig) Validate() error {
+	if len(c.Hosts) == 0 {
+		return errors.New("output has no hosts")
+	}
+	if c.BulkMaxSize <= 0 {
+		return errors.New("bulk_max_size must be greater than 0")
+	}
+	if c.FlushInterval <= 0 {
+		return errors.New("flush_interval must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package hostwatcher
+
+import (
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// processDataset is the dataset of the process events.
+	processDataset = "host_watcher.process"
+	// networkDataset is the dataset of the network events.
+	networkDataset = "host_watcher.network"
+)
+
+// the states of a TCP socket and the address families the inet_sock_set_state tracepoint reports
+const (
+	tcpEstablished = 1
+	tcpSynSent     = 2
+	tcpSynRecv     = 3
+
+	ipprotoTCP = 6
+	afInet     = 2
+	afInet6    = 10
+)
+
+// event is an event observed on the host.
+type event struct {
+	Timestamp time.Time
+	Dataset   string
+
+	Action string
+	PID    uint32
+	Name   string
+
+	// process events
+	Executable string
+	Args       []string
+
+	// network events
+	Direction   string
+	Family      uint16
+	Source      net.IP
+	SourcePort  uint16
+	Destination net.IP
+	DestPort    uint16
+}
+
+// decoder decodes the records of a tracepoint, the PID and TGID of the task that emitted the record
+// is sent before it. It returns false when the record is not reported.
+type decoder func(f format, tgid, pid uint32, record []byte) (event, bool)
+
+// decodeExec decodes the records of the sched/sched_process_exec tracepoint.
+func decodeExec(f format, tgid, _ uint32, record []byte) (event, bool) {
+	exe := f.dataLoc(record, "filename")
+	return event{
+		Dataset:    processDataset,
+		Action:     "exec",
+		PID:        tgid,
+		Name:       filepath.Base(exe),
+		Executable: exe,
+	}, true
+}
+
+// decodeExit decodes the records of the sched/sched_process_exit tracepoint, only the exits of the
+// processes are reported, not the ones of their threads.
+func decodeExit(f format, tgid, pid uint32, record []byte) (event, bool) {
+	if tgid != pid {
+		return event{}, false
+	}
+	return event{
+		Dataset: processDataset,
+		Action:  "exit",
+		PID:     tgid,
+		Name:    f.string(record, "comm"),
+	}, true
+}
+
+// decodeSockState decodes the records of the sock/inet_sock_set_state tracepoint, the TCP
+// connections opened by the host and the ones it accepted are reported.
+func decodeSockState(f format, tgid, _ uint32, record []byte) (event, bool) {
+	if f.uint(record, "protocol") != ipprotoTCP {
+		return event{}, false
+	}
+	oldState, newState := f.uint(record, "oldstate"), f.uint(record, "newstate")
+	e := event{
+		Dataset:    networkDataset,
+		Family:     uint16(f.uint(record, "family")),
+		SourcePort: uint16(f.uint(record, "sport")),
+		DestPort:   uint16(f.uint(record, "dport")),
+	}
+	switch {
+	case newState == tcpSynSent:
+		// the connection is opened in the context of the process calling connect
+		e.Action = "connection_attempted"
+		e.Direction = "egress"
+		e.PID = tgid
+	case oldState == tcpSynRecv && newState == tcpEstablished:
+		// the handshake is completed in the softirq context, the process is not known
+		e.Action = "connection_accepted"
+		e.Direction = "ingress"
+	default:
+		return event{}, false
+	}
+	switch e.Family {
+	case afInet:
+		e.Source = net.IP(append([]byte(nil), f.bytes(record, "saddr")...))
+		e.Destination = net.IP(append([]byte(nil), f.bytes(record, "daddr")...))
+	case afInet6:
+		e.Source = net.IP(append([]byte(nil), f.bytes(record, "saddr_v6")...))
+		e.Destination = net.IP(append([]byte(nil), f.bytes(record, "daddr_v6")...))
+	default:
+		return event{}, false
+	}
+	if e.Direction == "ingress" {
+		// the record holds the addresses of the socket, the source of the connection is the peer
+		e.Source, e.Destination = e.Destination, e.Source
+		e.SourcePort, e.DestPort = e.DestPort, e.SourcePort
+	}
+	return e, true
+}
+
+// decodeSample decodes the raw data of a sample sent by the probe program, the PID and TGID of the
+// task precede the record of the tracepoint.
+func decodeSample(f format, decode decoder, raw []byte) (event, bool) {
+	if len(raw) < 8 {
+		return event{}, false
+	}
+	pidTgid := binary.LittleEndian.Uint64(raw)
+	return decode(f, uint32(pidTgid>>32), uint32(pidTgid), raw[8:])
+}
+
+// document returns the ECS document of the event sent to the data stream of the namespace.
+func (e event) document(namespace string, hostname string) map[string]interface{} {
+	doc := map[string]interface{}{
+		"@timestamp": e.Timestamp.UTC().Format(time.RFC3339Nano),
+		"data_stream": map[string]interface{}{
+			"type":      "logs",
+			"dataset":   e.Dataset,
+			"namespace": namespace,
+		},
+		"host": map[string]interface{}{
+			"hostname": hostname,
+		},
+	}
+	process := map[string]interface{}{}
+	if e.PID != 0 {
+		process["pid"] = e.PID
+	}
+	if e.Name != "" {
+		process["name"] = e.Name
+	}
+
+	switch e.Dataset {
+	case processDataset:
+		eventType := "start"
+		if e.Action == "exit" {
+			eventType = "end"
+		}
+		doc["event"] = map[string]interface{}{
+			"kind":     "event",
+			"category": []string{"process"},
+			"type":     []string{eventType},
+			"action":   e.Action,
+			"dataset":  e.Dataset,
+		}
+		if e.Executable != "" {
+			process["executable"] = e.Executable
+		}
+		if len(e.Args) > 0 {
+			process["args"] = e.Args
+		}
+	case networkDataset:
+		networkType := "ipv4"
+		if e.Family == afInet6 {
+			networkType = "ipv6"
+		}
+		doc["event"] = map[string]interface{}{
+			"kind":     "event",
+			"category": []string{"network"},
+			"type":     []string{"connection", "start"},
+			"action":   e.Action,
+			"dataset":  e.Dataset,
+		}
+		doc["network"] = map[string]interface{}{
+			"direction": e.Direction,
+			"transport": "tcp",
+			"type":      networkType,
+		}
+		doc["source"] = map[string]interface{}{
+			"ip":   e.Source.String(),
+			"port": e.SourcePort,
+		}
+		doc["destination"] = map[string]interface{}{
+			"ip":   e.Destination.String(),
+			"port": e.DestPort,
+		}
+	}
+	if len(process) > 0 {
+		doc["process"] = process
+	}
+	return doc
+}
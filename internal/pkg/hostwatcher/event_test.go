@@ -0,0 +1,204 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package hostwatcher
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const execFormat = `name: sched_process_exec
+ID: 365
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+	field:unsigned char common_preempt_count;	offset:3;	size:1;	signed:0;
+	field:int common_pid;	offset:4;	size:4;	signed:1;
+
+	field:__data_loc char[] filename;	offset:8;	size:4;	signed:0;
+	field:pid_t pid;	offset:12;	size:4;	signed:1;
+	field:pid_t old_pid;	offset:16;	size:4;	signed:1;
+
+print fmt: "filename=%s pid=%d old_pid=%d", __get_str(filename), REC->pid, REC->old_pid
+`
+
+const exitFormat = `name: sched_process_exit
+ID: 369
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+	field:unsigned char common_preempt_count;	offset:3;	size:1;	signed:0;
+	field:int common_pid;	offset:4;	size:4;	signed:1;
+
+	field:char comm[16];	offset:8;	size:16;	signed:0;
+	field:pid_t pid;	offset:24;	size:4;	signed:1;
+	field:int prio;	offset:28;	size:4;	signed:1;
+
+print fmt: "comm=%s pid=%d prio=%d", REC->comm, REC->pid, REC->prio
+`
+
+const sockFormat = `name: inet_sock_set_state
+ID: 2187
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+	field:unsigned char common_preempt_count;	offset:3;	size:1;	signed:0;
+	field:int common_pid;	offset:4;	size:4;	signed:1;
+
+	field:const void * skaddr;	offset:8;	size:8;	signed:0;
+	field:int oldstate;	offset:16;	size:4;	signed:1;
+	field:int newstate;	offset:20;	size:4;	signed:1;
+	field:__u16 sport;	offset:24;	size:2;	signed:0;
+	field:__u16 dport;	offset:26;	size:2;	signed:0;
+	field:__u16 family;	offset:28;	size:2;	signed:0;
+	field:__u16 protocol;	offset:30;	size:2;	signed:0;
+	field:__u8 saddr[4];	offset:32;	size:4;	signed:0;
+	field:__u8 daddr[4];	offset:36;	size:4;	signed:0;
+	field:__u8 saddr_v6[16];	offset:40;	size:16;	signed:0;
+	field:__u8 daddr_v6[16];	offset:56;	size:16;	signed:0;
+
+print fmt: "family=%s"
+`
+
+func mustParseFormat(t *testing.T, content string) format {
+	t.Helper()
+	f, err := parseFormat(strings.NewReader(content))
+	require.NoError(t, err)
+	return f
+}
+
+func TestParseFormat(t *testing.T) {
+	f := mustParseFormat(t, sockFormat)
+	assert.Equal(t, 72, f.Size)
+	assert.Equal(t, field{Offset: 8, Size: 8}, f.Fields["skaddr"])
+	assert.Equal(t, field{Offset: 32, Size: 4}, f.Fields["saddr"])
+	assert.Equal(t, field{Offset: 56, Size: 16}, f.Fields["daddr_v6"])
+	assert.NoError(t, f.require(networkTracepoints[0].Fields...))
+	assert.EqualError(t, f.require("missing"), `format has no field "missing"`)
+
+	_, err := parseFormat(strings.NewReader("name: empty\nformat:\n"))
+	assert.EqualError(t, err, "format has no fields")
+	_, err = parseFormat(strings.NewReader("\tfield:int pid;\toffset:x;\tsize:4;\n"))
+	assert.Error(t, err)
+}
+
+// sample returns the raw data of a sample with the PID, TGID and the record.
+func sample(tgid, pid uint32, record []byte) []byte {
+	raw := binary.LittleEndian.AppendUint64(nil, uint64(tgid)<<32|uint64(pid))
+	return append(raw, record...)
+}
+
+func TestDecodeExec(t *testing.T) {
+	f := mustParseFormat(t, execFormat)
+	filename := "/usr/bin/curl\x00"
+	record := make([]byte, 20, 20+len(filename))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(filename))<<16|20)
+	record = append(record, filename...)
+
+	e, ok := decodeSample(f, decodeExec, sample(42, 43, record))
+	require.True(t, ok)
+	assert.Equal(t, "exec", e.Action)
+	assert.Equal(t, uint32(42), e.PID)
+	assert.Equal(t, "curl", e.Name)
+	assert.Equal(t, "/usr/bin/curl", e.Executable)
+
+	// the filename is truncated with the record
+	e, ok = decodeSample(f, decodeExec, sample(42, 43, record[:25]))
+	require.True(t, ok)
+	assert.Equal(t, "/usr/", e.Executable)
+}
+
+func TestDecodeExit(t *testing.T) {
+	f := mustParseFormat(t, exitFormat)
+	record := make([]byte, 32)
+	copy(record[8:], "nginx\x00")
+
+	e, ok := decodeSample(f, decodeExit, sample(42, 42, record))
+	require.True(t, ok)
+	assert.Equal(t, "exit", e.Action)
+	assert.Equal(t, uint32(42), e.PID)
+	assert.Equal(t, "nginx", e.Name)
+
+	// the exits of the threads are not reported
+	_, ok = decodeSample(f, decodeExit, sample(42, 43, record))
+	assert.False(t, ok)
+}
+
+func sockRecord(oldState, newState uint32, family uint16, sport, dport uint16, saddr, daddr net.IP) []byte {
+	record := make([]byte, 72)
+	binary.LittleEndian.PutUint32(record[16:], oldState)
+	binary.LittleEndian.PutUint32(record[20:], newState)
+	binary.LittleEndian.PutUint16(record[24:], sport)
+	binary.LittleEndian.PutUint16(record[26:], dport)
+	binary.LittleEndian.PutUint16(record[28:], family)
+	binary.LittleEndian.PutUint16(record[30:], ipprotoTCP)
+	if family == afInet {
+		copy(record[32:], saddr.To4())
+		copy(record[36:], daddr.To4())
+	} else {
+		copy(record[40:], saddr.To16())
+		copy(record[56:], daddr.To16())
+	}
+	return record
+}
+
+func TestDecodeSockState(t *testing.T) {
+	f := mustParseFormat(t, sockFormat)
+
+	t.Run("outbound", func(t *testing.T) {
+		record := sockRecord(7, tcpSynSent, afInet, 0, 443, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"))
+		e, ok := decodeSample(f, decodeSockState, sample(42, 42, record))
+		require.True(t, ok)
+		assert.Equal(t, "connection_attempted", e.Action)
+		assert.Equal(t, "egress", e.Direction)
+		assert.Equal(t, uint32(42), e.PID)
+		assert.Equal(t, "10.0.0.1", e.Source.String())
+		assert.Equal(t, "10.0.0.2", e.Destination.String())
+		assert.Equal(t, uint16(443), e.DestPort)
+	})
+
+	t.Run("inbound", func(t *testing.T) {
+		record := sockRecord(tcpSynRecv, tcpEstablished, afInet6, 22, 50000, net.ParseIP("fd00::1"), net.ParseIP("fd00::2"))
+		e, ok := decodeSample(f, decodeSockState, sample(42, 42, record))
+		require.True(t, ok)
+		assert.Equal(t, "connection_accepted", e.Action)
+		assert.Equal(t, "ingress", e.Direction)
+		assert.Zero(t, e.PID, "the process accepting the connection is not known")
+		assert.Equal(t, "fd00::2", e.Source.String())
+		assert.Equal(t, uint16(50000), e.SourcePort)
+		assert.Equal(t, "fd00::1", e.Destination.String())
+		assert.Equal(t, uint16(22), e.DestPort)
+	})
+
+	t.Run("other transitions", func(t *testing.T) {
+		record := sockRecord(tcpEstablished, 4, afInet, 22, 50000, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"))
+		_, ok := decodeSample(f, decodeSockState, sample(42, 42, record))
+		assert.False(t, ok)
+	})
+}
+
+func TestEventDocument(t *testing.T) {
+	e := event{
+		Dataset:     networkDataset,
+		Action:      "connection_attempted",
+		PID:         42,
+		Name:        "curl",
+		Direction:   "egress",
+		Family:      afInet6,
+		Source:      net.ParseIP("fd00::1"),
+		Destination: net.ParseIP("fd00::2"),
+		DestPort:    443,
+	}
+	doc := e.document("prod", "host-1")
+	assert.Equal(t, map[string]interface{}{"type": "logs", "dataset": networkDataset, "namespace": "prod"}, doc["data_stream"])
+	assert.Equal(t, map[string]interface{}{"pid": uint32(42), "name": "curl"}, doc["process"])
+	assert.Equal(t, map[string]interface{}{"direction": "egress", "transport": "tcp", "type": "ipv6"}, doc["network"])
+	assert.Equal(t, map[string]interface{}{"ip": "fd00::2", "port": uint16(443)}, doc["destination"])
+}
@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package hostwatcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// field is a field of the record of a tracepoint.
+type field struct {
+	Offset int
+	Size   int
+}
+
+// format is the layout of the record of a tracepoint, as described by its tracefs format file.
+type format struct {
+	Fields map[string]field
+	// Size is the size of the record without its dynamic data.
+	Size int
+}
+
+// parseFormat parses the content of the format file of a tracepoint.
+func parseFormat(r io.Reader) (format, error) {
+	f := format{Fields: make(map[string]field)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "field:") {
+			continue
+		}
+		var name string
+		fld := field{Offset: -1, Size: -1}
+		for _, part := range strings.Split(line, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "field":
+				// the name is the last word of the declaration, without the size of an array
+				decl := strings.Fields(value)
+				if len(decl) == 0 {
+					return format{}, fmt.Errorf("invalid field declaration %q", value)
+				}
+				name = decl[len(decl)-1]
+				if i := strings.Index(name, "["); i >= 0 {
+					name = name[:i]
+				}
+			case "offset", "size":
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return format{}, fmt.Errorf("invalid %s of field %q: %w", key, name, err)
+				}
+				if key == "offset" {
+					fld.Offset = n
+				} else {
+					fld.Size = n
+				}
+			}
+		}
+		if name == "" || fld.Offset < 0 || fld.Size < 0 {
+			return format{}, fmt.Errorf("invalid field %q", line)
+		}
+		f.Fields[name] = fld
+		if end := fld.Offset + fld.Size; end > f.Size {
+			f.Size = end
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return format{}, err
+	}
+	if len(f.Fields) == 0 {
+		return format{}, fmt.Errorf("format has no fields")
+	}
+	return f, nil
+}
+
+// require returns an error when one of the fields is missing from the format.
+func (f format) require(names ...string) error {
+	for _, name := range names {
+		if _, ok := f.Fields[name]; !ok {
+			return fmt.Errorf("format has no field %q", name)
+		}
+	}
+	return nil
+}
+
+// uint reads an unsigned integer field of the record.
+func (f format) uint(record []byte, name string) uint64 {
+	fld, ok := f.Fields[name]
+	if !ok || fld.Offset+fld.Size > len(record) {
+		return 0
+	}
+	b := record[fld.Offset : fld.Offset+fld.Size]
+	switch fld.Size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b))
+	case 8:
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// bytes reads an array field of the record.
+func (f format) bytes(record []byte, name string) []byte {
+	fld, ok := f.Fields[name]
+	if !ok || fld.Offset+fld.Size > len(record) {
+		return nil
+	}
+	return record[fld.Offset : fld.Offset+fld.Size]
+}
+
+// string reads a NUL terminated string field of the record.
+func (f format) string(record []byte, name string) string {
+	return cString(f.bytes(record, name))
+}
+
+// dataLoc reads a __data_loc string field of the record, its value holds the offset of the string
+// in the record in the low 16 bits and its length in the high 16 bits. The string is truncated when
+// the record was truncated.
+func (f format) dataLoc(record []byte, name string) string {
+	loc := f.uint(record, name)
+	offset, end := int(loc&0xffff), int(loc&0xffff)+int(loc>>16)
+	if offset >= len(record) {
+		return ""
+	}
+	if end > len(record) {
+		end = len(record)
+	}
+	return cString(record[offset:end])
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
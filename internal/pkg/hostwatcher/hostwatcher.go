@@ -0,0 +1,231 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package hostwatcher implements the host_watcher input, a lightweight watcher of the processes
+// started and stopped and of the TCP connections opened on a Linux host. It gives a baseline
+// security visibility on the hosts where Endpoint cannot be installed.
+//
+// The input is run in-process by Elastic Agent with the builtin runtime, the kernel events are
+// collected with eBPF programs attached to tracepoints and sent to the elasticsearch output of the
+// component.
+package hostwatcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/component/sdk"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Name is the input type of the host watcher.
+const Name = "host_watcher"
+
+// queueSize is the number of events waiting to be published, the events are dropped once it's full.
+const queueSize = 4096
+
+// tracepoint is a kernel tracepoint watched by the host watcher.
+type tracepoint struct {
+	Group string
+	Name  string
+	// Fields are the fields of the record the decoder reads.
+	Fields []string
+	// Dynamic is the size of the dynamic data copied after the record.
+	Dynamic int
+	Decode  decoder
+}
+
+var (
+	processTracepoints = []tracepoint{
+		{Group: "sched", Name: "sched_process_exec", Fields: []string{"filename"}, Dynamic: 256, Decode: decodeExec},
+		{Group: "sched", Name: "sched_process_exit", Fields: []string{"comm"}, Decode: decodeExit},
+	}
+	networkTracepoints = []tracepoint{
+		{
+			Group:  "sock",
+			Name:   "inet_sock_set_state",
+			Fields: []string{"oldstate", "newstate", "sport", "dport", "family", "protocol", "saddr", "daddr", "saddr_v6", "daddr_v6"},
+			Decode: decodeSockState,
+		},
+	}
+)
+
+var spec = component.InputSpec{
+	Name:        Name,
+	Description: "Host Watcher",
+	Platforms:   []string{"linux/amd64", "linux/arm64"},
+	Outputs:     []string{"elasticsearch"},
+	Builtin:     &component.BuiltinSpec{},
+}
+
+func init() {
+	runtime.MustRegisterBuiltin(spec, newComponent)
+}
+
+func newComponent(log *logger.Logger) (*sdk.Component, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the hostname: %w", err)
+	}
+	w := &watcher{
+		log:      log,
+		hostname: hostname,
+		queue:    make(chan document, queueSize),
+	}
+	return sdk.New(Name, release.Version(), w.newHandler), nil
+}
+
+// watcher is shared by the units of the component, the input units queue the events they watch and
+// the output unit publishes them.
+type watcher struct {
+	log      *logger.Logger
+	hostname string
+	queue    chan document
+	dropped  uint64
+}
+
+func (w *watcher) newHandler(unit *sdk.Unit) (sdk.Handler, error) {
+	switch unit.Type() {
+	case client.UnitTypeOutput:
+		return &outputHandler{w: w}, nil
+	case client.UnitTypeInput:
+		return &inputHandler{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown unit type %v", unit.Type())
+}
+
+// enqueue queues the event for the namespace, it's dropped when the queue is full.
+func (w *watcher) enqueue(e event, namespace string) {
+	doc := document{
+		Index: fmt.Sprintf("logs-%s-%s", e.Dataset, namespace),
+		Body:  e.document(namespace, w.hostname),
+	}
+	select {
+	case w.queue <- doc:
+	default:
+		if n := atomic.AddUint64(&w.dropped, 1); n%queueSize == 1 {
+			w.log.Warnf("%d events were dropped, the queue is full", n)
+		}
+	}
+}
+
+// outputHandler publishes the queued events to the elasticsearch output.
+type outputHandler struct {
+	w      *watcher
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Configure starts publishing with the configuration of the output, the publishing with the
+// previous configuration is stopped first.
+func (h *outputHandler) Configure(unit *sdk.Unit) error {
+	cfg := defaultOutputConfig()
+	if err := unit.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	p, err := newPublisher(h.w.log, cfg, func(err error) {
+		if err != nil {
+			unit.Degraded(fmt.Sprintf("Failed to publish: %s", err))
+		} else {
+			unit.Healthy("Healthy: publishing")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	h.stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		p.Run(ctx, h.w.queue)
+	}(h.done)
+	return nil
+}
+
+// Stop stops publishing, the queued events are published once the output is configured again.
+func (h *outputHandler) Stop(_ *sdk.Unit) error {
+	h.stop()
+	return nil
+}
+
+func (h *outputHandler) stop() {
+	if h.cancel != nil {
+		h.cancel()
+		<-h.done
+		h.cancel = nil
+	}
+}
+
+// inputHandler watches the events of the tracepoints enabled by the configuration of the input.
+type inputHandler struct {
+	w      *watcher
+	probes *probes
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Configure attaches to the tracepoints of the configuration, the tracepoints of the previous
+// configuration are detached first.
+func (h *inputHandler) Configure(unit *sdk.Unit) error {
+	cfg := defaultInputConfig()
+	if err := unit.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	namespace := unit.Config().GetDataStream().GetNamespace()
+	if namespace == "" {
+		namespace = "default"
+	}
+	var tracepoints []tracepoint
+	if cfg.Process.Enabled {
+		tracepoints = append(tracepoints, processTracepoints...)
+	}
+	if cfg.Network.Enabled {
+		tracepoints = append(tracepoints, networkTracepoints...)
+	}
+
+	h.stop()
+	p, err := newProbes(h.w.log, tracepoints)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.probes = p
+	h.cancel = cancel
+	h.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		err := p.Run(ctx, func(e event) {
+			h.w.enqueue(e, namespace)
+		})
+		if err != nil {
+			unit.Failed(fmt.Sprintf("Failed: %s", err))
+		}
+	}(h.done)
+	return nil
+}
+
+// Stop detaches from the tracepoints.
+func (h *inputHandler) Stop(_ *sdk.Unit) error {
+	h.stop()
+	return nil
+}
+
+func (h *inputHandler) stop() {
+	if h.cancel != nil {
+		h.cancel()
+		<-h.done
+		h.probes.Close()
+		h.cancel = nil
+		h.probes = nil
+	}
+}
@@ -0,0 +1,548 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package hostwatcher
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// tracingDirs are the mount points of tracefs, the first one with the tracepoints is used.
+var tracingDirs = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+
+const (
+	// maxRecordSize is the most the probe program copies from a record, the record and the PID
+	// and TGID it's sent with must fit on the 512 bytes of the stack of the program.
+	maxRecordSize = 496
+	// ringPages is the number of pages of the ring buffer of every CPU, must be a power of 2.
+	ringPages = 64
+	// pollTimeout is how long the rings are polled before the context is checked.
+	pollTimeout = 250 * time.Millisecond
+)
+
+// the BPF commands, map and program types and helpers the probes use
+const (
+	bpfMapCreate    = 0
+	bpfMapUpdateEl  = 2
+	bpfProgLoad     = 5
+	bpfPseudoMapFD  = 1
+	bpfMapPerfArray = 4
+	bpfProgTrace    = 5
+
+	helperProbeRead       = 4
+	helperGetPidTgid      = 14
+	helperPerfEventOutput = 25
+	helperProbeReadKernel = 113
+)
+
+// probe is a tracepoint the probe program is attached to.
+type probe struct {
+	tracepoint
+	format format
+	prog   int
+	event  int
+}
+
+// probes sends the records of tracepoints to the ring buffers of the CPUs with a BPF program
+// attached to every tracepoint, the records are decoded in user space.
+type probes struct {
+	log    *logger.Logger
+	perfFD int
+	probes map[uint16]*probe
+	rings  []*ring
+}
+
+// newProbes attaches the probe program to the tracepoints, the records are sent once Run is called.
+func newProbes(log *logger.Logger, tracepoints []tracepoint) (_ *probes, err error) {
+	dir, err := tracingDir(tracepoints[0])
+	if err != nil {
+		return nil, err
+	}
+	// before 5.11 the memory of the BPF maps and programs is accounted to the locked memory
+	raiseMemlock()
+
+	cpus, err := readCPUList("/sys/devices/system/cpu/possible")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the possible CPUs: %w", err)
+	}
+	online, err := readCPUList("/sys/devices/system/cpu/online")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the online CPUs: %w", err)
+	}
+
+	p := &probes{log: log, perfFD: -1, probes: make(map[uint16]*probe)}
+	defer func() {
+		if err != nil {
+			p.Close()
+		}
+	}()
+
+	p.perfFD, err = createPerfArray(maxCPU(cpus) + 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the perf event array: %w", err)
+	}
+	for _, cpu := range online {
+		r, err := newRing(cpu)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the ring buffer of CPU %d: %w", cpu, err)
+		}
+		p.rings = append(p.rings, r)
+		if err := updateElem(p.perfFD, uint32(cpu), uint32(r.fd)); err != nil {
+			return nil, fmt.Errorf("failed to add the ring buffer of CPU %d: %w", cpu, err)
+		}
+	}
+
+	for _, tp := range tracepoints {
+		if err := p.attach(dir, tp); err != nil {
+			return nil, fmt.Errorf("failed to attach to tracepoint %s/%s: %w", tp.Group, tp.Name, err)
+		}
+	}
+	return p, nil
+}
+
+// attach loads the probe program of the tracepoint and attaches it, its records are decoded by the
+// ID of the tracepoint which is their common type.
+func (p *probes) attach(dir string, tp tracepoint) error {
+	tpDir := filepath.Join(dir, "events", tp.Group, tp.Name)
+	formatFile, err := os.Open(filepath.Join(tpDir, "format"))
+	if err != nil {
+		return err
+	}
+	defer formatFile.Close()
+	f, err := parseFormat(formatFile)
+	if err != nil {
+		return err
+	}
+	if err := f.require(tp.Fields...); err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(filepath.Join(tpDir, "id"))
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid tracepoint id: %w", err)
+	}
+
+	size := (f.Size + tp.Dynamic + 7) &^ 7
+	if size > maxRecordSize {
+		size = maxRecordSize
+	}
+	pr := &probe{tracepoint: tp, format: f, prog: -1, event: -1}
+	p.probes[uint16(id)] = pr
+	// bpf_probe_read_kernel is not available before 5.5, bpf_probe_read is not available on the
+	// architectures where the kernel and the user space addresses overlap
+	pr.prog, err = loadProgram(probeProgram(p.perfFD, uint16(id), int32(size), helperProbeReadKernel))
+	if err != nil {
+		pr.prog, err = loadProgram(probeProgram(p.perfFD, uint16(id), int32(size), helperProbeRead))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load the probe program: %w", err)
+	}
+
+	attr := unix.PerfEventAttr{
+		Type:        unix.PERF_TYPE_TRACEPOINT,
+		Config:      id,
+		Sample_type: unix.PERF_SAMPLE_RAW,
+		Sample:      1,
+		Wakeup:      1,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+	// the program runs on every CPU, whatever the CPU of the event it's attached to
+	pr.event, err = unix.PerfEventOpen(&attr, -1, 0, -1, unix.PERF_FLAG_FD_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("failed to open the tracepoint event: %w", err)
+	}
+	if err := unix.IoctlSetInt(pr.event, unix.PERF_EVENT_IOC_SET_BPF, pr.prog); err != nil {
+		return fmt.Errorf("failed to attach the probe program: %w", err)
+	}
+	if err := unix.IoctlSetInt(pr.event, unix.PERF_EVENT_IOC_ENABLE, 0); err != nil {
+		return fmt.Errorf("failed to enable the tracepoint event: %w", err)
+	}
+	return nil
+}
+
+// Run decodes the records of the tracepoints and emits their events until the context is
+// cancelled.
+func (p *probes) Run(ctx context.Context, emit func(event)) error {
+	fds := make([]unix.PollFd, len(p.rings))
+	for i, r := range p.rings {
+		fds[i] = unix.PollFd{Fd: int32(r.fd), Events: unix.POLLIN}
+	}
+	var lost uint64
+	for ctx.Err() == nil {
+		if _, err := unix.Poll(fds, int(pollTimeout/time.Millisecond)); err != nil && !errors.Is(err, unix.EINTR) {
+			return fmt.Errorf("failed to poll the ring buffers: %w", err)
+		}
+		for _, r := range p.rings {
+			err := r.read(func(raw []byte) {
+				if e, ok := p.decode(raw); ok {
+					emit(e)
+				}
+			}, func(n uint64) {
+				lost += n
+			})
+			if err != nil {
+				p.log.Warnf("Records of a ring buffer were skipped: %v", err)
+			}
+		}
+		if lost > 0 {
+			p.log.Warnf("%d records were lost, the ring buffers are full", lost)
+			lost = 0
+		}
+	}
+	return nil
+}
+
+// decode decodes the sample, its tracepoint is the common type of its record.
+func (p *probes) decode(raw []byte) (event, bool) {
+	if len(raw) < 10 {
+		return event{}, false
+	}
+	pr, ok := p.probes[binary.LittleEndian.Uint16(raw[8:])]
+	if !ok {
+		return event{}, false
+	}
+	e, ok := decodeSample(pr.format, pr.Decode, raw)
+	if !ok {
+		return event{}, false
+	}
+	e.Timestamp = time.Now()
+	if e.Action == "exec" {
+		e.Args = readCmdline(e.PID)
+	}
+	if e.Name == "" && e.PID != 0 {
+		e.Name = readComm(e.PID)
+	}
+	return e, true
+}
+
+// Close detaches the probe programs and releases the ring buffers.
+func (p *probes) Close() {
+	for _, pr := range p.probes {
+		if pr.event >= 0 {
+			_ = unix.IoctlSetInt(pr.event, unix.PERF_EVENT_IOC_DISABLE, 0)
+			_ = unix.Close(pr.event)
+		}
+		if pr.prog >= 0 {
+			_ = unix.Close(pr.prog)
+		}
+	}
+	for _, r := range p.rings {
+		r.close()
+	}
+	if p.perfFD >= 0 {
+		_ = unix.Close(p.perfFD)
+	}
+}
+
+// tracingDir returns the mount point of tracefs.
+func tracingDir(tp tracepoint) (string, error) {
+	for _, dir := range tracingDirs {
+		if _, err := os.Stat(filepath.Join(dir, "events", tp.Group, tp.Name, "id")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("tracefs is not mounted in %s", strings.Join(tracingDirs, " or "))
+}
+
+// readCmdline returns the arguments of the process, nil when it already exited.
+func readCmdline(pid uint32) []string {
+	raw, err := os.ReadFile(filepath.Join("/proc", strconv.FormatUint(uint64(pid), 10), "cmdline"))
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(raw), "\x00"), "\x00")
+}
+
+// readComm returns the name of the process, empty when it already exited.
+func readComm(pid uint32) string {
+	raw, err := os.ReadFile(filepath.Join("/proc", strconv.FormatUint(uint64(pid), 10), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// readCPUList reads a list of CPUs like 0-3,5.
+func readCPUList(path string) ([]int, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(string(raw)), ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list %q", raw)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid CPU list %q", raw)
+			}
+		}
+		for cpu := from; cpu <= to; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+func maxCPU(cpus []int) int {
+	m := 0
+	for _, cpu := range cpus {
+		if cpu > m {
+			m = cpu
+		}
+	}
+	return m
+}
+
+func raiseMemlock() {
+	var limit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil || limit.Cur == unix.RLIM_INFINITY {
+		return
+	}
+	_ = unix.Setrlimit(unix.RLIMIT_MEMLOCK, &unix.Rlimit{Cur: unix.RLIM_INFINITY, Max: unix.RLIM_INFINITY})
+}
+
+// insn is an instruction of a BPF program.
+type insn struct {
+	code byte
+	dst  byte
+	src  byte
+	off  int16
+	imm  int32
+}
+
+// probeProgram returns the program sending the PID and TGID of the current task followed by the
+// first size bytes of the record of the tracepoint to the perf event array. The common fields of the
+// record are overwritten by the kernel before the program runs, the common type is set back to the
+// ID of the tracepoint.
+func probeProgram(perfFD int, id uint16, size int32, readHelper int32) []insn {
+	const (
+		r0, r1, r2, r3, r4, r5, r6, r10 = 0, 1, 2, 3, 4, 5, 6, 10
+
+		mov64Reg = 0xbf
+		mov64Imm = 0xb7
+		mov32Imm = 0xb4
+		add64Imm = 0x07
+		stDW     = 0x7a
+		stxDW    = 0x7b
+		ldImm64  = 0x18
+		call     = 0x85
+		exit     = 0x95
+	)
+	buf := 8 + size
+	return []insn{
+		{code: mov64Reg, dst: r6, src: r1}, // r6 = ctx
+		{code: call, imm: helperGetPidTgid},
+		{code: stxDW, dst: r10, src: r0, off: int16(-buf)}, // buf[0:8] = pid_tgid
+		{code: mov64Reg, dst: r1, src: r10},
+		{code: add64Imm, dst: r1, imm: -size}, // r1 = buf + 8
+		{code: mov64Imm, dst: r2, imm: size},
+		{code: mov64Reg, dst: r3, src: r6},
+		{code: call, imm: readHelper},                             // copy the record after pid_tgid
+		{code: stDW, dst: r10, off: int16(-size), imm: int32(id)}, // common type
+		{code: mov64Reg, dst: r1, src: r6},
+		{code: ldImm64, dst: r2, src: bpfPseudoMapFD, imm: int32(perfFD)},
+		{},
+		{code: mov32Imm, dst: r3, imm: -1}, // BPF_F_CURRENT_CPU
+		{code: mov64Reg, dst: r4, src: r10},
+		{code: add64Imm, dst: r4, imm: -buf},
+		{code: mov64Imm, dst: r5, imm: buf},
+		{code: call, imm: helperPerfEventOutput},
+		{code: mov64Imm, dst: r0, imm: 0},
+		{code: exit},
+	}
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func createPerfArray(entries int) (int, error) {
+	attr := struct {
+		mapType    uint32
+		keySize    uint32
+		valueSize  uint32
+		maxEntries uint32
+		mapFlags   uint32
+	}{mapType: bpfMapPerfArray, keySize: 4, valueSize: 4, maxEntries: uint32(entries)}
+	return bpf(bpfMapCreate, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+}
+
+func updateElem(mapFD int, key uint32, value uint32) error {
+	attr := struct {
+		mapFD uint32
+		_     uint32
+		key   uint64
+		value uint64
+		flags uint64
+	}{mapFD: uint32(mapFD), key: uint64(uintptr(unsafe.Pointer(&key))), value: uint64(uintptr(unsafe.Pointer(&value)))}
+	_, err := bpf(bpfMapUpdateEl, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(&key)
+	runtime.KeepAlive(&value)
+	return err
+}
+
+// loadProgram loads the tracepoint program, the log of the verifier is returned on failure.
+func loadProgram(insns []insn) (int, error) {
+	code := make([]byte, 0, len(insns)*8)
+	for _, i := range insns {
+		code = append(code, i.code, i.dst|i.src<<4)
+		code = binary.LittleEndian.AppendUint16(code, uint16(i.off))
+		code = binary.LittleEndian.AppendUint32(code, uint32(i.imm))
+	}
+	license := []byte("GPL\x00")
+	logBuf := make([]byte, 64*1024)
+	attr := struct {
+		progType    uint32
+		insnCnt     uint32
+		insns       uint64
+		license     uint64
+		logLevel    uint32
+		logSize     uint32
+		logBuf      uint64
+		kernVersion uint32
+		progFlags   uint32
+	}{
+		progType: bpfProgTrace,
+		insnCnt:  uint32(len(insns)),
+		insns:    uint64(uintptr(unsafe.Pointer(&code[0]))),
+		license:  uint64(uintptr(unsafe.Pointer(&license[0]))),
+		logLevel: 1,
+		logSize:  uint32(len(logBuf)),
+		logBuf:   uint64(uintptr(unsafe.Pointer(&logBuf[0]))),
+	}
+	fd, err := bpf(bpfProgLoad, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(code)
+	runtime.KeepAlive(license)
+	runtime.KeepAlive(logBuf)
+	if err != nil {
+		if log := strings.TrimSpace(cString(logBuf)); log != "" {
+			return -1, fmt.Errorf("%w: %s", err, log)
+		}
+		return -1, err
+	}
+	return fd, nil
+}
+
+// ring is the ring buffer of the BPF output event of a CPU.
+type ring struct {
+	fd   int
+	mem  []byte
+	meta *unix.PerfEventMmapPage
+	data []byte
+}
+
+func newRing(cpu int) (*ring, error) {
+	attr := unix.PerfEventAttr{
+		Type:        unix.PERF_TYPE_SOFTWARE,
+		Config:      unix.PERF_COUNT_SW_BPF_OUTPUT,
+		Sample_type: unix.PERF_SAMPLE_RAW,
+		Wakeup:      1,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+	fd, err := unix.PerfEventOpen(&attr, -1, cpu, -1, unix.PERF_FLAG_FD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := os.Getpagesize()
+	mem, err := unix.Mmap(fd, 0, (ringPages+1)*pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	if err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_ENABLE, 0); err != nil {
+		_ = unix.Munmap(mem)
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	return &ring{
+		fd:   fd,
+		mem:  mem,
+		meta: (*unix.PerfEventMmapPage)(unsafe.Pointer(&mem[0])),
+		data: mem[pageSize:],
+	}, nil
+}
+
+// read reads the records written since the last read, the raw data of the samples is passed to
+// sample and the number of lost records to lost. A corrupt record header makes the records after
+// it unreadable, they are skipped up to the last written record so the next reads go on, and an
+// error is returned.
+func (r *ring) read(sample func(raw []byte), lost func(n uint64)) error {
+	const (
+		recordLost   = 2
+		recordSample = 9
+	)
+	head := atomic.LoadUint64(&r.meta.Data_head)
+	tail := r.meta.Data_tail
+	var header [8]byte
+	for tail < head {
+		r.copy(header[:], tail)
+		recordType := binary.LittleEndian.Uint32(header[0:])
+		size := uint64(binary.LittleEndian.Uint16(header[6:]))
+		if size < 8 || size > head-tail {
+			skipped := head - tail
+			atomic.StoreUint64(&r.meta.Data_tail, head)
+			return fmt.Errorf("corrupt record header of size %d, skipped %d bytes", size, skipped)
+		}
+		body := make([]byte, size-8)
+		r.copy(body, tail+8)
+		switch recordType {
+		case recordSample:
+			if len(body) >= 4 {
+				n := int(binary.LittleEndian.Uint32(body))
+				if 4+n <= len(body) {
+					sample(body[4 : 4+n])
+				}
+			}
+		case recordLost:
+			if len(body) >= 16 {
+				lost(binary.LittleEndian.Uint64(body[8:]))
+			}
+		}
+		tail += size
+	}
+	atomic.StoreUint64(&r.meta.Data_tail, tail)
+	return nil
+}
+
+// copy copies the data at the offset of the ring, wrapping around its end.
+func (r *ring) copy(dst []byte, offset uint64) {
+	start := int(offset % uint64(len(r.data)))
+	n := copy(dst, r.data[start:])
+	copy(dst[n:], r.data)
+}
+
+func (r *ring) close() {
+	_ = unix.IoctlSetInt(r.fd, unix.PERF_EVENT_IOC_DISABLE, 0)
+	_ = unix.Munmap(r.mem)
+	_ = unix.Close(r.fd)
+}
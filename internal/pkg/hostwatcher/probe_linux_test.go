@@ -0,0 +1,203 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package hostwatcher
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// TestProbes loads the probe program in the kernel, attaches it to the process tracepoints and
+// decodes the records of a process it starts. It needs the privileges to load BPF programs.
+func TestProbes(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	if _, err := tracingDir(processTracepoints[0]); err != nil {
+		t.Skip(err.Error())
+	}
+
+	log, _ := logger.NewTesting(t.Name())
+	p, err := newProbes(log, processTracepoints)
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.ENOSYS) {
+		t.Skipf("BPF is not available: %v", err)
+	}
+	require.NoError(t, err, "the verifier must accept the probe program")
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan event, 1024)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Run(ctx, func(e event) {
+			select {
+			case events <- e:
+			default:
+			}
+		})
+	}()
+
+	exe, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("true is not installed")
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	require.NoError(t, err)
+	cmd := exec.Command(exe)
+	require.NoError(t, cmd.Run())
+	pid := uint32(cmd.Process.Pid)
+
+	var execed, exited bool
+	timeout := time.After(10 * time.Second)
+	for !execed || !exited {
+		select {
+		case e := <-events:
+			if e.PID != pid {
+				continue
+			}
+			assert.Equal(t, processDataset, e.Dataset)
+			switch e.Action {
+			case "exec":
+				execed = true
+				assert.Equal(t, exe, e.Executable)
+				assert.Equal(t, filepath.Base(exe), e.Name)
+			case "exit":
+				exited = true
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for the records of process %d, exec: %v, exit: %v", pid, execed, exited)
+		}
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// writeRecord writes a record of the given type and body in the ring at offset, wrapping around
+// its end, and returns the offset after it.
+func writeRecord(r *ring, offset uint64, recordType uint32, body []byte) uint64 {
+	record := make([]byte, 8+len(body))
+	binary.LittleEndian.PutUint32(record[0:], recordType)
+	binary.LittleEndian.PutUint16(record[6:], uint16(len(record)))
+	copy(record[8:], body)
+	for i, b := range record {
+		r.data[(offset+uint64(i))%uint64(len(r.data))] = b
+	}
+	return offset + uint64(len(record))
+}
+
+// sampleBody returns the body of a sample record holding raw.
+func sampleBody(raw string) []byte {
+	body := make([]byte, 4+len(raw))
+	binary.LittleEndian.PutUint32(body, uint32(len(raw)))
+	copy(body[4:], raw)
+	return body
+}
+
+// lostBody returns the body of a lost record counting n records.
+func lostBody(n uint64) []byte {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint64(body[8:], n)
+	return body
+}
+
+// readRing reads the ring and returns the samples, the lost records and the error of the read.
+func readRing(r *ring) ([]string, uint64, error) {
+	var samples []string
+	var lost uint64
+	err := r.read(func(raw []byte) {
+		samples = append(samples, string(raw))
+	}, func(n uint64) {
+		lost += n
+	})
+	return samples, lost, err
+}
+
+func TestRingCopy(t *testing.T) {
+	r := &ring{data: []byte("0123456789")}
+
+	dst := make([]byte, 4)
+	r.copy(dst, 2)
+	assert.Equal(t, "2345", string(dst))
+	r.copy(dst, 8)
+	assert.Equal(t, "8901", string(dst), "the copy wraps around the end of the ring")
+	r.copy(dst, 27)
+	assert.Equal(t, "7890", string(dst), "the offset is taken modulo the size of the ring")
+}
+
+func TestRingRead(t *testing.T) {
+	r := &ring{meta: &unix.PerfEventMmapPage{}, data: make([]byte, 64)}
+
+	head := writeRecord(r, 0, 9, sampleBody("first"))
+	head = writeRecord(r, head, 2, lostBody(3))
+	head = writeRecord(r, head, 9, sampleBody("second"))
+	r.meta.Data_head = head
+
+	samples, lost, err := readRing(r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, samples)
+	assert.EqualValues(t, 3, lost)
+	assert.Equal(t, head, r.meta.Data_tail)
+
+	// the next record wraps around the end of the ring
+	head = writeRecord(r, head, 9, sampleBody("wrapped around"))
+	require.Greater(t, head%uint64(len(r.data)), uint64(0))
+	require.Less(t, head%uint64(len(r.data)), r.meta.Data_tail%uint64(len(r.data)))
+	r.meta.Data_head = head
+
+	samples, lost, err = readRing(r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wrapped around"}, samples)
+	assert.Zero(t, lost)
+	assert.Equal(t, head, r.meta.Data_tail)
+
+	samples, _, err = readRing(r)
+	require.NoError(t, err)
+	assert.Empty(t, samples, "the records are only read once")
+}
+
+func TestRingReadCorrupt(t *testing.T) {
+	for name, size := range map[string]uint16{
+		"smaller than the header": 4,
+		"larger than the data":    40,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := &ring{meta: &unix.PerfEventMmapPage{}, data: make([]byte, 64)}
+
+			head := writeRecord(r, 0, 9, sampleBody("first"))
+			corrupt := head
+			head = writeRecord(r, head, 9, sampleBody("lost"))
+			binary.LittleEndian.PutUint16(r.data[corrupt+6:], size)
+			r.meta.Data_head = head
+
+			samples, _, err := readRing(r)
+			assert.Error(t, err)
+			assert.Equal(t, []string{"first"}, samples)
+			assert.Equal(t, head, r.meta.Data_tail, "the corrupt records are skipped")
+
+			// the records written after the corrupt one are read
+			head = writeRecord(r, head, 9, sampleBody("next"))
+			r.meta.Data_head = head
+			samples, _, err = readRing(r)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"next"}, samples)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package hostwatcher
+
+import (
+	"context"
+	"errors"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// probes is not implemented outside of Linux.
+type probes struct{}
+
+func newProbes(_ *logger.Logger, _ []tracepoint) (*probes, error) {
+	return nil, errors.New("the host watcher is only supported on Linux")
+}
+
+// Run does nothing outside of Linux.
+func (p *probes) Run(_ context.Context, _ func(event)) error {
+	return nil
+}
+
+// Close does nothing outside of Linux.
+func (p *probes) Close() {}
@@ -0,0 +1,200 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package hostwatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// document is a document indexed in a data stream.
+type document struct {
+	Index string
+	Body  map[string]interface{}
+}
+
+// publisher sends the documents in bulk to Elasticsearch, the hosts are tried in turn until one of
+// them accepts the request.
+type publisher struct {
+	log    *logger.Logger
+	client *http.Client
+	urls   []string
+	header http.Header
+
+	bulkMaxSize   int
+	flushInterval time.Duration
+
+	// onPublish is called with the result of every bulk request
+	onPublish func(err error)
+}
+
+func newPublisher(log *logger.Logger, cfg outputConfig, onPublish func(err error)) (*publisher, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	scheme := cfg.Protocol
+	if cfg.TLS != nil && cfg.TLS.IsEnabled() {
+		tlsCfg, err := tlscommon.LoadTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssl configuration: %w", err)
+		}
+		transport.TLSClientConfig = tlsCfg.ToConfig()
+		scheme = "https"
+	}
+
+	urls := make([]string, 0, len(cfg.Hosts))
+	for _, host := range cfg.Hosts {
+		if !strings.Contains(host, "://") {
+			host = scheme + "://" + host
+		}
+		url := strings.TrimSuffix(host, "/")
+		if path := strings.Trim(cfg.Path, "/"); path != "" {
+			url += "/" + path
+		}
+		urls = append(urls, url+"/_bulk")
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range cfg.Headers {
+		header.Set(k, v)
+	}
+	switch {
+	case cfg.APIKey != "":
+		header.Set("Authorization", "ApiKey "+base64.StdEncoding.EncodeToString([]byte(cfg.APIKey)))
+	case cfg.Username != "":
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+cfg.Password)))
+	}
+
+	return &publisher{
+		log:           log,
+		client:        &http.Client{Transport: transport, Timeout: cfg.Timeout},
+		urls:          urls,
+		header:        header,
+		bulkMaxSize:   cfg.BulkMaxSize,
+		flushInterval: cfg.FlushInterval,
+		onPublish:     onPublish,
+	}, nil
+}
+
+// Run sends the documents of the queue until the context is cancelled. A batch that failed to be
+// sent is retried with a backoff, the documents of the queue wait meanwhile.
+func (p *publisher) Run(ctx context.Context, queue <-chan document) {
+	bo := backoff.NewEqualJitterBackoff(ctx.Done(), time.Second, time.Minute)
+	t := time.NewTicker(p.flushInterval)
+	defer t.Stop()
+
+	batch := make([]document, 0, p.bulkMaxSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case doc := <-queue:
+			batch = append(batch, doc)
+			if len(batch) < p.bulkMaxSize {
+				continue
+			}
+		case <-t.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		for {
+			err := p.send(ctx, batch)
+			p.onPublish(err)
+			if err == nil {
+				break
+			}
+			p.log.Errorf("failed to publish %d events: %s", len(batch), err)
+			if !bo.Wait() {
+				return
+			}
+		}
+		bo.Reset()
+		batch = batch[:0]
+	}
+}
+
+// send sends the batch in a single bulk request.
+func (p *publisher) send(ctx context.Context, batch []document) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, doc := range batch {
+		action := map[string]interface{}{"create": map[string]interface{}{"_index": doc.Index}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(doc.Body); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	for _, url := range p.urls {
+		lastErr = p.post(ctx, url, body.Bytes())
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+func (p *publisher) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = p.header.Clone()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("bulk request to %s failed with status %d: %s", url, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	// the rejected documents are dropped, sending them again fails the same way
+	failed := 0
+	for _, item := range result.Items {
+		for _, r := range item {
+			if r.Status >= 300 {
+				if failed == 0 {
+					p.log.Warnf("event dropped, status %d: %s: %s", r.Status, r.Error.Type, r.Error.Reason)
+				}
+				failed++
+			}
+		}
+	}
+	p.log.Warnf("%d of %d events were dropped by Elasticsearch", failed, len(result.Items))
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package hostwatcher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestPublisher(t *testing.T) {
+	var mx sync.Mutex
+	var lines []string
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prefix/_bulk" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mx.Lock()
+		auth = r.Header.Get("Authorization")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		mx.Unlock()
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	cfg := defaultOutputConfig()
+	// the first host is down, the documents are sent to the next one
+	cfg.Hosts = []string{"http://127.0.0.1:1", strings.TrimPrefix(srv.URL, "http://")}
+	cfg.Path = "/prefix/"
+	cfg.APIKey = "id:key"
+	cfg.BulkMaxSize = 2
+	require.NoError(t, cfg.Validate())
+
+	published := make(chan error, 10)
+	p, err := newPublisher(logger.NewWithoutConfig(""), cfg, func(err error) {
+		published <- err
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := make(chan document, 2)
+	go p.Run(ctx, queue)
+	queue <- document{Index: "logs-host_watcher.process-default", Body: map[string]interface{}{"message": "first"}}
+	queue <- document{Index: "logs-host_watcher.network-default", Body: map[string]interface{}{"message": "second"}}
+
+	select {
+	case err := <-published:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the bulk request")
+	}
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, "ApiKey aWQ6a2V5", auth)
+	require.Len(t, lines, 4)
+	var action map[string]map[string]string
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &action))
+	assert.Equal(t, "logs-host_watcher.process-default", action["create"]["_index"])
+	assert.JSONEq(t, `{"message":"first"}`, lines[1])
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &action))
+	assert.Equal(t, "logs-host_watcher.network-default", action["create"]["_index"])
+	assert.JSONEq(t, `{"message":"second"}`, lines[3])
+}
+
+func TestPublisher_Failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing authentication"))
+	}))
+	defer srv.Close()
+
+	cfg := defaultOutputConfig()
+	cfg.Hosts = []string{srv.URL}
+	cfg.FlushInterval = 10 * time.Millisecond
+
+	published := make(chan error, 10)
+	p, err := newPublisher(logger.NewWithoutConfig(""), cfg, func(err error) {
+		published <- err
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue := make(chan document, 1)
+	go p.Run(ctx, queue)
+	queue <- document{Index: "logs-host_watcher.process-default", Body: map[string]interface{}{}}
+
+	select {
+	case err := <-published:
+		assert.ErrorContains(t, err, "failed with status 401: missing authentication")
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the bulk request")
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package logrotation caps the total disk space used by the log files of the agent and of its
+// components. Each of them rotates its own files, the cap is enforced across all of them by
+// evicting the oldest file of the stream using the most space first, so a noisy component does
+// not evict the logs of the others.
+package logrotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Config is the configuration of the cap on the total size of the log files.
+type Config struct {
+	// MaxTotalSize is the maximum number of bytes used by all the log files, 0 doesn't cap them.
+	MaxTotalSize int64 `yaml:"max_total_size" config:"max_total_size" json:"max_total_size"`
+	// KeepFiles is the number of files of each stream never evicted, the newest ones.
+	KeepFiles int `yaml:"keep_files" config:"keep_files" json:"keep_files"`
+	// Interval is the interval between two checks of the size of the log files.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+}
+
+// DefaultConfig creates a default log rotation configuration, the log files are not capped.
+func DefaultConfig() *Config {
+	return &Config{
+		KeepFiles: 1,
+		Interval:  time.Minute,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.MaxTotalSize < 0 {
+		return fmt.Errorf("the max_total_size of the log files must not be negative")
+	}
+	if c.KeepFiles < 1 {
+		return fmt.Errorf("at least 1 file of each log stream must be kept")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("the interval of the log rotation must be positive")
+	}
+	return nil
+}
+
+var current atomic.Pointer[Config]
+
+// Apply applies the agent.log_rotation configuration, it is enforced from the next check on. A
+// configuration without agent.log_rotation keeps the current one, so a policy that doesn't set it
+// keeps the one of the local configuration.
+func Apply(c *config.Config) error {
+	if c == nil {
+		return nil
+	}
+	present := struct {
+		Agent struct {
+			LogRotation map[string]interface{} `config:"log_rotation"`
+		} `config:"agent"`
+	}{}
+	if err := c.Unpack(&present); err != nil {
+		return fmt.Errorf("could not parse the log rotation configuration: %w", err)
+	}
+	if present.Agent.LogRotation == nil {
+		return nil
+	}
+
+	parsed := struct {
+		Agent struct {
+			LogRotation *Config `config:"log_rotation"`
+		} `config:"agent"`
+	}{}
+	parsed.Agent.LogRotation = DefaultConfig()
+	if err := c.Unpack(&parsed); err != nil {
+		return fmt.Errorf("could not parse the log rotation configuration: %w", err)
+	}
+	current.Store(parsed.Agent.LogRotation)
+	return nil
+}
+
+// Current returns the configuration of the log rotation.
+func Current() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return DefaultConfig()
+}
+
+// file is a log file.
+type file struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// stream is the files written by the same logger, the oldest first.
+type stream struct {
+	name  string
+	files []file
+	size  int64
+}
+
+// Result is the result of an enforcement of the cap.
+type Result struct {
+	// TotalSize is the number of bytes used by the log files once evicted.
+	TotalSize    int64
+	EvictedBytes int64
+	// Evicted is the number of files evicted by stream.
+	Evicted map[string]int
+	Errors  []error
+}
+
+// Enforce evicts log files under dir until they use at most the maximum total size. The files are
+// grouped in streams, the files rotated by the same logger, and the oldest file of the stream
+// using the most space is evicted first. The newest keepFiles files of each stream are never
+// evicted.
+func Enforce(dir string, maxTotalSize int64, keepFiles int) Result {
+	r := Result{Evicted: make(map[string]int)}
+	streams, err := readStreams(dir)
+	if err != nil {
+		r.Errors = append(r.Errors, err)
+	}
+	for _, s := range streams {
+		r.TotalSize += s.size
+	}
+	for maxTotalSize > 0 && r.TotalSize > maxTotalSize {
+		s := largestEvictable(streams, keepFiles)
+		if s == nil {
+			break
+		}
+		f := s.files[0]
+		s.files = s.files[1:]
+		s.size -= f.size
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			r.Errors = append(r.Errors, fmt.Errorf("failed to evict %s: %w", f.path, err))
+			continue
+		}
+		r.TotalSize -= f.size
+		r.EvictedBytes += f.size
+		r.Evicted[s.name]++
+	}
+	return r
+}
+
+// largestEvictable returns the stream using the most space among the ones with files to evict.
+func largestEvictable(streams []*stream, keepFiles int) *stream {
+	var largest *stream
+	for _, s := range streams {
+		if len(s.files) <= keepFiles {
+			continue
+		}
+		if largest == nil || s.size > largest.size {
+			largest = s
+		}
+	}
+	return largest
+}
+
+// readStreams returns the streams of the log files under dir, sorted by name.
+func readStreams(dir string) ([]*stream, error) {
+	byName := make(map[string]*stream)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := StreamName(rel)
+		s, ok := byName[name]
+		if !ok {
+			s = &stream{name: name}
+			byName[name] = s
+		}
+		s.files = append(s.files, file{path: path, size: info.Size(), modTime: info.ModTime()})
+		s.size += info.Size()
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to read the log files of %s: %w", dir, err)
+	}
+
+	streams := make([]*stream, 0, len(byName))
+	for _, s := range byName {
+		sort.SliceStable(s.files, func(i, j int) bool {
+			return s.files[i].modTime.Before(s.files[j].modTime)
+		})
+		streams = append(streams, s)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].name < streams[j].name
+	})
+	return streams, err
+}
+
+// StreamName returns the name of the stream of the log file at the relative path: its directory
+// and its name without the extensions, nor the dates and counters added by the rotation.
+// elastic-agent-20230901-1.ndjson and elastic-agent-20230902.ndjson are both in the
+// elastic-agent stream, filebeat.log.1 is in the filebeat stream.
+func StreamName(rel string) string {
+	base := filepath.Base(rel)
+	stem, _, _ := strings.Cut(base, ".")
+	parts := strings.FieldsFunc(stem, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	kept := parts[:0]
+	for _, p := range parts {
+		if !isDigits(p) {
+			kept = append(kept, p)
+		}
+	}
+	name := strings.Join(kept, "-")
+	if name == "" {
+		name = base
+	}
+	if dir := filepath.Dir(rel); dir != "." {
+		name = filepath.ToSlash(filepath.Join(dir, name))
+	}
+	return name
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Run enforces the cap of the current configuration on the log files under dir every interval,
+// until the context is done.
+func Run(ctx context.Context, log *logger.Logger, dir string) {
+	for {
+		cfg := Current()
+		if cfg.MaxTotalSize > 0 {
+			r := Enforce(dir, cfg.MaxTotalSize, cfg.KeepFiles)
+			for _, err := range r.Errors {
+				log.Warnw("Failed to enforce the maximum total size of the log files", "error.message", err)
+			}
+			if r.EvictedBytes > 0 {
+				log.Infof("Log files exceeded their maximum total size of %s, evicted %s: %s", units.BytesSize(float64(cfg.MaxTotalSize)), units.BytesSize(float64(r.EvictedBytes)), formatEvicted(r.Evicted))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cfg.Interval):
+		}
+	}
+}
+
+// formatEvicted lists the number of files evicted by stream.
+func formatEvicted(evicted map[string]int) string {
+	names := make([]string, 0, len(evicted))
+	for name := range evicted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%d files of %s", evicted[name], name))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package logrotation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+var now = time.Date(2023, 9, 3, 12, 0, 0, 0, time.UTC)
+
+func writeLog(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+	require.NoError(t, os.Chtimes(path, now.Add(-age), now.Add(-age)))
+}
+
+func TestStreamName(t *testing.T) {
+	for rel, name := range map[string]string{
+		"elastic-agent-20230901.ndjson":           "elastic-agent",
+		"elastic-agent-20230901-1.ndjson":         "elastic-agent",
+		"filebeat.log.3":                          "filebeat",
+		"events/elastic-agent-event-log.ndjson":   "events/elastic-agent-event-log",
+		filepath.Join("endpoint", "endpoint.log"): "endpoint/endpoint",
+		"20230901.log":                            "20230901.log",
+	} {
+		assert.Equal(t, name, StreamName(rel), rel)
+	}
+}
+
+func TestEnforceEvictsFairly(t *testing.T) {
+	dir := t.TempDir()
+	// a noisy component with large files, older than the files of the agent
+	writeLog(t, filepath.Join(dir, "noisy-20230901.ndjson"), 300, 5*time.Hour)
+	writeLog(t, filepath.Join(dir, "noisy-20230901-1.ndjson"), 300, 4*time.Hour)
+	writeLog(t, filepath.Join(dir, "noisy-20230901-2.ndjson"), 300, 0)
+	writeLog(t, filepath.Join(dir, "elastic-agent-20230901.ndjson"), 100, 6*time.Hour)
+	writeLog(t, filepath.Join(dir, "elastic-agent-20230901-1.ndjson"), 100, time.Hour)
+
+	r := Enforce(dir, 600, 1)
+
+	assert.Empty(t, r.Errors)
+	assert.Equal(t, int64(500), r.TotalSize)
+	assert.Equal(t, int64(600), r.EvictedBytes)
+	assert.Equal(t, map[string]int{"noisy": 2}, r.Evicted, "the stream using the most space should be evicted first")
+	assert.NoFileExists(t, filepath.Join(dir, "noisy-20230901.ndjson"))
+	assert.NoFileExists(t, filepath.Join(dir, "noisy-20230901-1.ndjson"))
+	assert.FileExists(t, filepath.Join(dir, "elastic-agent-20230901.ndjson"), "older files of smaller streams should be kept")
+}
+
+func TestEnforceKeepsTheNewestFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, "a-1.log"), 100, 2*time.Hour)
+	writeLog(t, filepath.Join(dir, "a-2.log"), 100, time.Hour)
+	writeLog(t, filepath.Join(dir, "b-1.log"), 100, 3*time.Hour)
+
+	r := Enforce(dir, 10, 2)
+
+	assert.Empty(t, r.Evicted, "streams with keep_files files should not be evicted")
+	assert.Equal(t, int64(300), r.TotalSize)
+
+	r = Enforce(dir, 0, 1)
+	assert.Empty(t, r.Evicted, "log files without a maximum total size should not be evicted")
+}
+
+func TestApply(t *testing.T) {
+	defer current.Store(nil)
+
+	cfg, err := config.NewConfigFrom(map[string]interface{}{"agent.log_rotation.max_total_size": 1024})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.Equal(t, int64(1024), Current().MaxTotalSize)
+	assert.Equal(t, 1, Current().KeepFiles, "the defaults are kept")
+
+	// a configuration without the log rotation keeps the current one
+	cfg, err = config.NewConfigFrom(map[string]interface{}{"agent.logging.level": "debug"})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.Equal(t, int64(1024), Current().MaxTotalSize)
+
+	cfg, err = config.NewConfigFrom(map[string]interface{}{"agent.log_rotation.keep_files": 0})
+	require.NoError(t, err)
+	require.Error(t, Apply(cfg))
+}
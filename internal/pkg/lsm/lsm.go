@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package lsm detects the Linux Security Module enforcing the mandatory access control of the
+// host, SELinux or AppArmor, loads the profiles shipped with the Elastic Agent and parses the
+// denials they log.
+package lsm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is a Linux Security Module enforcing the mandatory access control of the host.
+type Kind string
+
+const (
+	// None is returned when no mandatory access control is enforced.
+	None Kind = ""
+	// SELinux is returned when SELinux is enforcing.
+	SELinux Kind = "SELinux"
+	// AppArmor is returned when AppArmor is enabled.
+	AppArmor Kind = "AppArmor"
+)
+
+// ProfilesDir is the directory of the versioned home of the Elastic Agent holding the shipped
+// profiles, in a sub-directory per Kind.
+const ProfilesDir = "security"
+
+// Extensions of the profiles a component ships in the components directory.
+const (
+	SELinuxModuleExt   = ".cil"
+	AppArmorProfileExt = ".apparmor"
+)
+
+// Denial is an operation of a process denied by the mandatory access control of the host.
+type Denial struct {
+	Kind      Kind
+	PID       int
+	Comm      string
+	Operation string
+	Target    string
+}
+
+// String returns the denial as reported in the state of a component.
+func (d Denial) String() string {
+	if d.Target == "" {
+		return fmt.Sprintf("%s denied %s", d.Kind, d.Operation)
+	}
+	return fmt.Sprintf("%s denied %s on %s", d.Kind, d.Operation, d.Target)
+}
+
+// ParseDenial parses a line of the audit log or of the kernel log, it returns false when the line
+// is not a denial of SELinux or AppArmor.
+//
+// SELinux logs: avc:  denied  { read } for  pid=123 comm="filebeat" name="shadow" ...
+// AppArmor logs: apparmor="DENIED" operation="open" profile="elastic-agent" name="/etc/shadow" pid=123 ...
+func ParseDenial(line string) (Denial, bool) {
+	if i := strings.Index(line, "avc:"); i >= 0 {
+		rest := strings.TrimSpace(line[i+len("avc:"):])
+		if !strings.HasPrefix(rest, "denied") {
+			return Denial{}, false
+		}
+		start, end := strings.Index(rest, "{"), strings.Index(rest, "}")
+		if start < 0 || end < start {
+			return Denial{}, false
+		}
+		fields := parseFields(rest[end+1:])
+		d := Denial{
+			Kind:      SELinux,
+			Comm:      fields["comm"],
+			Operation: strings.TrimSpace(rest[start+1 : end]),
+			Target:    fields["path"],
+		}
+		if d.Target == "" {
+			d.Target = fields["name"]
+		}
+		d.PID, _ = strconv.Atoi(fields["pid"])
+		return d, d.PID > 0
+	}
+	if strings.Contains(line, `apparmor="DENIED"`) {
+		fields := parseFields(line)
+		d := Denial{
+			Kind:      AppArmor,
+			Comm:      fields["comm"],
+			Operation: fields["operation"],
+			Target:    fields["name"],
+		}
+		if mask := fields["requested_mask"]; mask != "" {
+			d.Operation = fmt.Sprintf("%s (%s)", d.Operation, mask)
+		}
+		d.PID, _ = strconv.Atoi(fields["pid"])
+		return d, d.PID > 0
+	}
+	return Denial{}, false
+}
+
+// parseFields parses the key=value fields of an audit record, the quotes around the values are
+// removed.
+func parseFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := s[:eq]
+		if sp := strings.LastIndexByte(key, ' '); sp >= 0 {
+			key = key[sp+1:]
+		}
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				value, s = s, ""
+			} else {
+				value, s = s[:end], s[end:]
+			}
+		}
+		fields[key] = value
+	}
+	return fields
+}
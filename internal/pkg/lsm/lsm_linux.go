@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package lsm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// selinuxEnforce is 1 when SELinux is enforcing.
+	selinuxEnforce = "/sys/fs/selinux/enforce"
+	// apparmorEnabled is Y when AppArmor is enabled.
+	apparmorEnabled = "/sys/module/apparmor/parameters/enabled"
+	// apparmorProfiles lists the loaded AppArmor profiles.
+	apparmorProfiles = "/sys/kernel/security/apparmor/profiles"
+
+	// AuditLogs are the logs holding the denials, the first one readable is used. The kernel log
+	// has them when auditd does not run, which is common with AppArmor.
+	AuditLogs = []string{"/var/log/audit/audit.log", "/var/log/kern.log"}
+)
+
+// Detect returns the Linux Security Module enforcing the mandatory access control of the host.
+// SELinux in permissive mode only logs the denials, it is not returned.
+func Detect() Kind {
+	if data, err := os.ReadFile(selinuxEnforce); err == nil && strings.TrimSpace(string(data)) == "1" {
+		return SELinux
+	}
+	if data, err := os.ReadFile(apparmorEnabled); err == nil && strings.TrimSpace(string(data)) == "Y" {
+		return AppArmor
+	}
+	return None
+}
+
+// Load loads the profiles of the Linux Security Module shipped in the versioned home of the
+// Elastic Agent and by its components, then verifies they are loaded. It returns the names of
+// the loaded profiles, none when no profile is shipped for the module.
+func Load(kind Kind, versionedHome string) ([]string, error) {
+	var dir, ext string
+	switch kind {
+	case SELinux:
+		dir, ext = filepath.Join(versionedHome, ProfilesDir, "selinux"), SELinuxModuleExt
+	case AppArmor:
+		dir, ext = filepath.Join(versionedHome, ProfilesDir, "apparmor"), AppArmorProfileExt
+	default:
+		return nil, nil
+	}
+	profiles, err := shippedProfiles(ext, dir, filepath.Join(versionedHome, "components"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, profile := range profiles {
+		name := strings.TrimSuffix(filepath.Base(profile), ext)
+		if kind == SELinux {
+			err = loadSELinuxModule(name, profile)
+		} else {
+			err = loadAppArmorProfile(name, profile)
+		}
+		if err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	if kind == SELinux && len(names) > 0 {
+		// the modules label the files of the Elastic Agent, the labels are applied to the files
+		// already installed
+		if out, err := exec.Command("restorecon", "-R", filepath.Dir(filepath.Dir(versionedHome))).CombinedOutput(); err != nil {
+			return names, fmt.Errorf("failed to restore the SELinux labels: %w: %s", err, bytes.TrimSpace(out))
+		}
+	}
+	return names, nil
+}
+
+// shippedProfiles returns the profiles of the directories, the ones with the extension.
+func shippedProfiles(ext string, dirs ...string) ([]string, error) {
+	var profiles []string
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, matches...)
+	}
+	return profiles, nil
+}
+
+// loadSELinuxModule installs the CIL module and verifies it is listed by semodule.
+func loadSELinuxModule(name, path string) error {
+	if out, err := exec.Command("semodule", "-i", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to install the SELinux module %s: %w: %s", name, err, bytes.TrimSpace(out))
+	}
+	out, err := exec.Command("semodule", "-l").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list the SELinux modules: %w", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("SELinux module %s is not loaded after its installation", name)
+}
+
+// loadAppArmorProfile loads or replaces the profile, caches it so it is loaded on boot, and
+// verifies it is listed by the kernel. The profile must be named like its file.
+func loadAppArmorProfile(name, path string) error {
+	if out, err := exec.Command("apparmor_parser", "-r", "-W", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to load the AppArmor profile %s: %w: %s", name, err, bytes.TrimSpace(out))
+	}
+	data, err := os.ReadFile(apparmorProfiles)
+	if err != nil {
+		return fmt.Errorf("failed to list the AppArmor profiles: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// each line is "<name> (<mode>)"
+		if loaded, _, _ := strings.Cut(line, " ("); loaded == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("AppArmor profile %s is not loaded after its installation", name)
+}
@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package lsm
+
+// AuditLogs is empty, there is no Linux Security Module on this platform.
+var AuditLogs []string
+
+// Detect always returns None, there is no Linux Security Module on this platform.
+func Detect() Kind {
+	return None
+}
+
+// Load does nothing, there is no Linux Security Module on this platform.
+func Load(_ Kind, _ string) ([]string, error) {
+	return nil, nil
+}
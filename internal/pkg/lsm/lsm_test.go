@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package lsm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDenial(t *testing.T) {
+	scenarios := []struct {
+		Name   string
+		Line   string
+		Denial Denial
+		Ok     bool
+	}{
+		{
+			Name: "SELinux",
+			Line: `type=AVC msg=audit(1692438118.123:456): avc:  denied  { read } for  pid=1234 comm="filebeat" name="shadow" dev="dm-0" ino=1234 scontext=system_u:system_r:unconfined_service_t:s0 tcontext=system_u:object_r:shadow_t:s0 tclass=file permissive=0`,
+			Denial: Denial{
+				Kind:      SELinux,
+				PID:       1234,
+				Comm:      "filebeat",
+				Operation: "read",
+				Target:    "shadow",
+			},
+			Ok: true,
+		},
+		{
+			Name: "SELinux Path",
+			Line: `type=AVC msg=audit(1692438118.123:457): avc:  denied  { name_connect } for  pid=1234 comm="metricbeat" path="/run/docker.sock" scontext=system_u:system_r:unconfined_service_t:s0 tclass=unix_stream_socket permissive=0`,
+			Denial: Denial{
+				Kind:      SELinux,
+				PID:       1234,
+				Comm:      "metricbeat",
+				Operation: "name_connect",
+				Target:    "/run/docker.sock",
+			},
+			Ok: true,
+		},
+		{
+			Name: "SELinux Granted",
+			Line: `type=AVC msg=audit(1692438118.123:458): avc:  granted  { setsecparam } for  pid=1234 comm="load_policy"`,
+		},
+		{
+			Name: "AppArmor",
+			Line: `Aug 19 10:41:58 host kernel: [ 1234.5678] audit: type=1400 audit(1692438118.123:459): apparmor="DENIED" operation="open" profile="elastic-agent" name="/etc/shadow" pid=1234 comm="filebeat" requested_mask="r" denied_mask="r" fsuid=0 ouid=0`,
+			Denial: Denial{
+				Kind:      AppArmor,
+				PID:       1234,
+				Comm:      "filebeat",
+				Operation: "open (r)",
+				Target:    "/etc/shadow",
+			},
+			Ok: true,
+		},
+		{
+			Name: "AppArmor Allowed",
+			Line: `audit: type=1400 audit(1692438118.123:460): apparmor="ALLOWED" operation="open" profile="elastic-agent" name="/etc/shadow" pid=1234 comm="filebeat"`,
+		},
+		{
+			Name: "Other",
+			Line: `type=SYSCALL msg=audit(1692438118.123:461): arch=c000003e syscall=257 success=no exit=-13 pid=1234 comm="filebeat"`,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.Name, func(t *testing.T) {
+			denial, ok := ParseDenial(s.Line)
+			assert.Equal(t, s.Ok, ok)
+			assert.Equal(t, s.Denial, denial)
+		})
+	}
+}
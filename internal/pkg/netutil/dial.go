@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package netutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport"
+)
+
+// Dial dials the addresses of the host until a connection succeeds, each connection attempt
+// times out after timeout. With happy_eyeballs the attempts race as specified by RFC 8305,
+// otherwise they are made one after the other in a random order.
+func Dial(network, host string, addresses []string, port string, timeout time.Duration) (net.Conn, error) {
+	cfg := Current()
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: cfg.KeepAlive}
+	if !cfg.HappyEyeballs || len(addresses) < 2 {
+		return transport.DialWith(dialer, network, host, addresses, port)
+	}
+	return dialParallel(context.Background(), dialer, network, interleave(addresses), port, cfg.ConnectionAttemptDelay)
+}
+
+// interleave orders the addresses alternating the IPv6 and IPv4 addresses, starting with IPv6.
+func interleave(addresses []string) []string {
+	var v6, v4 []string
+	for _, addr := range addresses {
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+			v6 = append(v6, addr)
+		} else {
+			v4 = append(v4, addr)
+		}
+	}
+	ordered := make([]string, 0, len(addresses))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			ordered = append(ordered, v6[i])
+		}
+		if i < len(v4) {
+			ordered = append(ordered, v4[i])
+		}
+	}
+	return ordered
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// dialParallel starts a connection attempt to the next address every delay, or as soon as the
+// previous attempt failed, and returns the first established connection. The other attempts are
+// cancelled and their connections closed.
+func dialParallel(ctx context.Context, dialer *net.Dialer, network string, addresses []string, port string, delay time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(addresses))
+	next, pending := 0, 0
+	start := func() {
+		addr := net.JoinHostPort(addresses[next], port)
+		next++
+		pending++
+		go func() {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	restart := func() {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(delay)
+	}
+
+	start()
+	var errs []string
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go closeResults(results, pending)
+				return r.conn, nil
+			}
+			errs = append(errs, r.err.Error())
+			if next < len(addresses) {
+				start()
+				restart()
+			}
+		case <-timer.C:
+			if next < len(addresses) {
+				start()
+				timer.Reset(delay)
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no address to dial")
+	}
+	return nil, fmt.Errorf("failed to connect to any address: %s", strings.Join(errs, ", "))
+}
+
+// closeResults closes the connections of the attempts still pending once a connection won.
+func closeResults(results chan dialResult, pending int) {
+	for i := 0; i < pending; i++ {
+		if r := <-results; r.conn != nil {
+			_ = r.conn.Close()
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package netutil
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterleave(t *testing.T) {
+	addresses := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "fd00::1", "fd00::2"}
+	assert.Equal(t, []string{"fd00::1", "10.0.0.1", "fd00::2", "10.0.0.2", "10.0.0.3"}, interleave(addresses))
+}
+
+func TestDialParallel(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+
+	// the first address doesn't answer, the next attempt starts after the delay
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	start := time.Now()
+	conn, err := dialParallel(context.Background(), dialer, "tcp", []string{"192.0.2.1", "127.0.0.1"}, port, 50*time.Millisecond)
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, l.Addr().String(), conn.RemoteAddr().String())
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// all the attempts fail
+	l.Close()
+	_, err = dialParallel(context.Background(), dialer, "tcp", []string{"127.0.0.1", "127.0.0.1"}, port, 50*time.Millisecond)
+	assert.Error(t, err)
+}
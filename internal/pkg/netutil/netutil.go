@@ -0,0 +1,174 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package netutil selects the IP family of the local listeners of Elastic Agent, builds the
+// addresses of IPv6 hosts and dials the addresses of the remote hosts, so the agent runs on
+// IPv4-only, IPv6-only and dual-stack hosts.
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+)
+
+const (
+	// FamilyAuto listens on the IPv4 loopback when the host has one, on the IPv6 loopback otherwise.
+	FamilyAuto = "auto"
+	// FamilyIPv4 listens on the IPv4 loopback.
+	FamilyIPv4 = "ipv4"
+	// FamilyIPv6 listens on the IPv6 loopback.
+	FamilyIPv6 = "ipv6"
+
+	loopbackIPv4 = "127.0.0.1"
+	loopbackIPv6 = "::1"
+)
+
+// Config is the configuration of the networking of the agent.
+type Config struct {
+	// IPFamily is the IP family of the local listeners: the gRPC server of the components, the
+	// connection info server of the service components and the health endpoint of the
+	// OpenTelemetry Collector.
+	IPFamily string `yaml:"ip_family" config:"ip_family" json:"ip_family"`
+
+	// HappyEyeballs dials the addresses of a host alternating the IPv6 and IPv4 addresses and
+	// starts the next connection attempt after ConnectionAttemptDelay without waiting for the
+	// previous one to fail, as specified by RFC 8305. Otherwise the addresses are dialed one after
+	// the other in a random order.
+	HappyEyeballs          bool          `yaml:"happy_eyeballs" config:"happy_eyeballs" json:"happy_eyeballs"`
+	ConnectionAttemptDelay time.Duration `yaml:"connection_attempt_delay" config:"connection_attempt_delay" json:"connection_attempt_delay"`
+	// KeepAlive is the period of the TCP keep-alive probes of the connections to Fleet Server.
+	KeepAlive time.Duration `yaml:"keep_alive" config:"keep_alive" json:"keep_alive"`
+	// MaxIdleConnsPerHost is the number of idle connections kept open to each Fleet Server.
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host" config:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+	// IdleConnTimeout is the time an idle connection to Fleet Server is kept open.
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout" config:"idle_conn_timeout" json:"idle_conn_timeout"`
+}
+
+// DefaultConfig creates a default network configuration, the IP family is detected.
+func DefaultConfig() *Config {
+	return &Config{
+		IPFamily:               FamilyAuto,
+		HappyEyeballs:          true,
+		ConnectionAttemptDelay: 250 * time.Millisecond,
+		KeepAlive:              30 * time.Second,
+		MaxIdleConnsPerHost:    2,
+		IdleConnTimeout:        90 * time.Second,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	switch c.IPFamily {
+	case FamilyAuto, FamilyIPv4, FamilyIPv6:
+	default:
+		return fmt.Errorf("invalid ip_family %q, must be one of %s, %s or %s", c.IPFamily, FamilyAuto, FamilyIPv4, FamilyIPv6)
+	}
+	if c.HappyEyeballs && c.ConnectionAttemptDelay <= 0 {
+		return fmt.Errorf("the connection_attempt_delay must be positive")
+	}
+	if c.MaxIdleConnsPerHost < 0 || c.IdleConnTimeout < 0 || c.KeepAlive < 0 {
+		return fmt.Errorf("the keep_alive, max_idle_conns_per_host and idle_conn_timeout cannot be negative")
+	}
+	return nil
+}
+
+var (
+	current atomic.Pointer[Config]
+
+	ipv4Once      sync.Once
+	ipv4Available bool
+)
+
+// SetConfig sets the network configuration, it must be called before the local listeners are
+// created.
+func SetConfig(cfg *Config) {
+	current.Store(cfg)
+}
+
+// SetIPFamily sets the IP family of the local listeners, it must be called before they are
+// created.
+func SetIPFamily(f string) {
+	cfg := *Current()
+	cfg.IPFamily = f
+	current.Store(&cfg)
+}
+
+// Current returns the network configuration.
+func Current() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return DefaultConfig()
+}
+
+// Loopback returns the loopback address of the IP family of the local listeners.
+func Loopback() string {
+	switch Current().IPFamily {
+	case FamilyIPv4:
+		return loopbackIPv4
+	case FamilyIPv6:
+		return loopbackIPv6
+	}
+	ipv4Once.Do(func() {
+		// an IPv6-only host can have no IPv4 loopback address
+		l, err := net.Listen("tcp4", net.JoinHostPort(loopbackIPv4, "0"))
+		if err == nil {
+			ipv4Available = true
+			_ = l.Close()
+		}
+	})
+	if ipv4Available {
+		return loopbackIPv4
+	}
+	return loopbackIPv6
+}
+
+// LocalHost returns the loopback address of the IP family of the local listeners for localhost,
+// and the host unchanged otherwise. Listening on localhost only uses its IPv4 address.
+func LocalHost(host string) string {
+	if host == "localhost" && Loopback() == loopbackIPv6 {
+		return loopbackIPv6
+	}
+	return host
+}
+
+// JoinHostPort combines the host and the port into an address, an IPv6 host is bracketed. The
+// host can already be bracketed.
+func JoinHostPort(host string, port int) string {
+	return net.JoinHostPort(Unbracket(host), strconv.Itoa(port))
+}
+
+// Unbracket removes the brackets of an IPv6 host.
+func Unbracket(host string) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host[1 : len(host)-1]
+	}
+	return host
+}
+
+// Bracket adds the brackets to an IPv6 host, so it can be followed by a port.
+func Bracket(host string) string {
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// TransportOption returns the option of the transport applying the keep-alive settings of the
+// connection pool.
+func TransportOption() httpcommon.TransportOption {
+	return httpcommon.WithTransportFunc(func(t *http.Transport) {
+		cfg := Current()
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	})
+}
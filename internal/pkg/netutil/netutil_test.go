@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package netutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinHostPort(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:6789", JoinHostPort("127.0.0.1", 6789))
+	assert.Equal(t, "localhost:6789", JoinHostPort("localhost", 6789))
+	assert.Equal(t, "[::1]:6789", JoinHostPort("::1", 6789))
+	assert.Equal(t, "[fd00::1]:6789", JoinHostPort("[fd00::1]", 6789))
+}
+
+func TestBracket(t *testing.T) {
+	assert.Equal(t, "[::1]", Bracket("::1"))
+	assert.Equal(t, "[::1]", Bracket("[::1]"))
+	assert.Equal(t, "127.0.0.1", Bracket("127.0.0.1"))
+	assert.Equal(t, "localhost", Bracket("localhost"))
+}
+
+func TestLoopback(t *testing.T) {
+	defer SetIPFamily(FamilyAuto)
+
+	SetIPFamily(FamilyIPv6)
+	assert.Equal(t, "::1", Loopback())
+	assert.Equal(t, "::1", LocalHost("localhost"))
+	assert.Equal(t, "fleet.example.com", LocalHost("fleet.example.com"))
+
+	SetIPFamily(FamilyIPv4)
+	assert.Equal(t, "127.0.0.1", Loopback())
+	assert.Equal(t, "localhost", LocalHost("localhost"))
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"sort"
 	"strings"
@@ -22,6 +23,10 @@ import (
 	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/id"
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+	"github.com/elastic/elastic-agent/internal/pkg/revocation"
+	"github.com/elastic/elastic-agent/internal/pkg/truststore"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -122,6 +127,10 @@ func NewWithConfig(log *logger.Logger, cfg Config, wrapper wrapperFunc) (*Client
 		transport, err := cfg.Transport.RoundTripper(
 			httpcommon.WithAPMHTTPInstrumentation(),
 			httpcommon.WithForceAttemptHTTP2(true),
+			netutil.TransportOption(),
+			resolver.TransportOption(&cfg.Transport),
+			truststore.TransportOption(&cfg.Transport),
+			revocation.TransportOption(&cfg.Transport),
 		)
 		if err != nil {
 			return nil, err
@@ -145,6 +154,7 @@ func NewWithConfig(log *logger.Logger, cfg Config, wrapper wrapperFunc) (*Client
 		}
 	}
 
+	metrics.register()
 	return newClient(log, cfg, clients...)
 }
 
@@ -192,6 +202,13 @@ func (c *Client) Send(
 		// This header should be specific to fleet-server or remove it
 		req.Header.Set("kbn-xsrf", "1") // Without this Kibana will refuse to answer the request.
 
+		// configured headers, the headers of the caller take precedence.
+		for header, value := range c.config.Headers {
+			if len(headers.Values(header)) == 0 {
+				req.Header.Set(header, value)
+			}
+		}
+
 		// If available, add the request id as an HTTP header
 		if reqID != "" {
 			req.Header.Add("X-Request-ID", reqID)
@@ -204,7 +221,7 @@ func (c *Client) Send(
 			}
 		}
 
-		resp, err = requester.client.Do(req.WithContext(ctx))
+		resp, err = requester.client.Do(req.WithContext(httptrace.WithClientTrace(ctx, metrics.trace())))
 
 		// Using the same lock that was used for sorting above
 		c.clientLock.Lock()
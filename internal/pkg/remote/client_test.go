@@ -369,6 +369,38 @@ func TestHTTPClient(t *testing.T) {
 			assert.Equal(t, successResp, string(body))
 		},
 	))
+
+	t.Run("Configured headers", withServer(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/echo-hello", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "secret", r.Header.Get("X-Org-Token"))
+				assert.Equal(t, "caller", r.Header.Get("X-Trace-Id"))
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, successResp)
+			})
+			return mux
+		}, func(t *testing.T, host string) {
+			cfg := config.MustNewConfigFrom(map[string]interface{}{
+				"host": host,
+				"headers": map[string]interface{}{
+					"X-Org-Token": "secret",
+					"X-Trace-Id":  "configured",
+				},
+			})
+
+			client, err := NewWithRawConfig(nil, cfg, nil)
+			require.NoError(t, err)
+			// the headers of the caller override the configured ones
+			resp, err := client.Send(ctx, http.MethodGet, "/echo-hello", nil, http.Header{"X-Trace-Id": []string{"caller"}}, nil)
+			require.NoError(t, err)
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, successResp, string(body))
+		},
+	))
 }
 
 func TestSortClients(t *testing.T) {
@@ -19,6 +19,10 @@ type Config struct {
 	Host     string   `config:"host" yaml:"host,omitempty"`
 	Hosts    []string `config:"hosts" yaml:"hosts,omitempty"`
 
+	// Headers are added to every request, like the headers required by the gateways in front of
+	// Fleet Server.
+	Headers map[string]string `config:"headers" yaml:"headers,omitempty"`
+
 	Transport httpcommon.HTTPTransportSettings `config:",inline" yaml:",inline"`
 }
 
@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remote
+
+import (
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// metricsName is the name of the connection metrics in the stats namespace of the monitoring endpoint.
+const metricsName = "fleet_connections"
+
+// connectionMetrics counts the connections used by the requests of the clients, they are reported
+// under fleet_connections in the stats of the monitoring endpoint. A low reuse means the
+// connections to Fleet Server are closed between the check-ins and each one pays a new connection.
+type connectionMetrics struct {
+	requests atomic.Int64
+	reused   atomic.Int64
+	newIPv4  atomic.Int64
+	newIPv6  atomic.Int64
+}
+
+var (
+	metrics         = &connectionMetrics{}
+	metricsRegister sync.Once
+)
+
+// register adds the metrics to the stats of the monitoring endpoint, once for all the clients.
+func (m *connectionMetrics) register() {
+	metricsRegister.Do(func() {
+		reg := monitoring.GetNamespace("stats").GetRegistry()
+		reg.Remove(metricsName)
+		reg.Add(metricsName, m, monitoring.Reported)
+	})
+}
+
+// trace returns the trace recording the connection used by a request.
+func (m *connectionMetrics) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			m.requests.Add(1)
+			if info.Reused {
+				m.reused.Add(1)
+				return
+			}
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok && addr.IP.To4() == nil {
+				m.newIPv6.Add(1)
+			} else {
+				m.newIPv4.Add(1)
+			}
+		},
+	}
+}
+
+// Visit reports the metrics to the monitoring visitor.
+func (m *connectionMetrics) Visit(_ monitoring.Mode, vs monitoring.Visitor) {
+	vs.OnRegistryStart()
+	defer vs.OnRegistryFinished()
+
+	monitoring.ReportInt(vs, "requests", m.requests.Load())
+	monitoring.ReportInt(vs, "reused", m.reused.Load())
+	monitoring.ReportInt(vs, "new_ipv4", m.newIPv4.Load())
+	monitoring.ReportInt(vs, "new_ipv6", m.newIPv6.Load())
+}
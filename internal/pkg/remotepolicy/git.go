@@ -0,0 +1,112 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remotepolicy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+// gitSource keeps a shallow clone of the repository and pulls the policy with the git executable,
+// so the credentials and the transports of the host git configuration apply.
+type gitSource struct {
+	url  string
+	ref  string
+	path string
+	dir  string
+}
+
+func newGitSource(cfg *configuration.RemotePolicyConfig, dir string) *gitSource {
+	return &gitSource{url: cfg.URL, ref: cfg.Ref, path: cfg.Path, dir: dir}
+}
+
+// Fetch clones the repository on the first call and fetches the last commit of the ref afterwards.
+func (g *gitSource) Fetch(ctx context.Context) (*Policy, error) {
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); err != nil {
+		if err := g.clone(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		ref := g.ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := g.git(ctx, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return nil, err
+		}
+		if _, err := g.git(ctx, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return nil, err
+		}
+	}
+
+	revision, err := g.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	data, err := readLimited(filepath.Join(g.dir, filepath.FromSlash(g.path)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy at revision %s: %w", revision, err)
+	}
+	sig, err := readLimited(filepath.Join(g.dir, filepath.FromSlash(g.path)+signatureExt))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read the policy signature at revision %s: %w", revision, err)
+	}
+	return &Policy{Revision: revision, Data: data, Signature: sig}, nil
+}
+
+func (g *gitSource) clone(ctx context.Context) error {
+	// a partial clone cannot be fetched, start again from scratch
+	if err := os.RemoveAll(g.dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", g.dir, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(g.dir), 0o750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(g.dir), err)
+	}
+
+	args := []string{"clone", "--depth", "1"}
+	if g.ref != "" {
+		args = append(args, "--branch", g.ref)
+	}
+	_, err := runGit(ctx, "", append(args, "--", g.url, g.dir)...)
+	return err
+}
+
+func (g *gitSource) git(ctx context.Context, args ...string) (string, error) {
+	return runGit(ctx, g.dir, args...)
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// never wait on a prompt for credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func readLimited(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxPolicySize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", path, maxPolicySize)
+	}
+	return os.ReadFile(path)
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remotepolicy
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+func TestGitSourceFetch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := runGit(ctx, repo, args...)
+		require.NoError(t, err)
+		return out
+	}
+	commit := func(files map[string]string) string {
+		t.Helper()
+		for name, content := range files {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(repo, name)), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(repo, name), []byte(content), 0o600))
+		}
+		git("add", "-A")
+		git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "policy")
+		return git("rev-parse", "HEAD")
+	}
+	git("init", "-q", "-b", "main")
+	first := commit(map[string]string{"agents/elastic-agent.yml": "outputs: {}\n"})
+
+	source, err := New(&configuration.RemotePolicyConfig{
+		Type: configuration.RemotePolicyGit,
+		URL:  "file://" + filepath.ToSlash(repo),
+		Ref:  "main",
+		Path: "agents/elastic-agent.yml",
+	}, filepath.Join(t.TempDir(), "source"))
+	require.NoError(t, err)
+
+	policy, err := source.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, first, policy.Revision)
+	assert.Equal(t, "outputs: {}\n", string(policy.Data))
+	assert.Nil(t, policy.Signature)
+
+	second := commit(map[string]string{
+		"agents/elastic-agent.yml":     "inputs: []\n",
+		"agents/elastic-agent.yml.asc": "signature",
+	})
+	policy, err = source.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, second, policy.Revision)
+	assert.Equal(t, "inputs: []\n", string(policy.Data))
+	assert.Equal(t, "signature", string(policy.Signature))
+
+	git("rm", "-q", "agents/elastic-agent.yml")
+	git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "remove")
+	_, err = source.Fetch(ctx)
+	assert.ErrorContains(t, err, "failed to read the policy")
+}
@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remotepolicy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+// httpSource pulls the policy from a URL, its signature is at the same URL with the signature extension.
+// The requests are conditional on the ETag of the last response so an unchanged policy is not downloaded.
+type httpSource struct {
+	client *http.Client
+	url    string
+	token  string
+
+	etag string
+	last *Policy
+}
+
+func newHTTPSource(cfg *configuration.RemotePolicyConfig) (*httpSource, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote policy URL %q: %w", cfg.URL, err)
+	}
+	if !strings.EqualFold(u.Scheme, "https") && !(cfg.Insecure && strings.EqualFold(u.Scheme, "http")) {
+		return nil, fmt.Errorf("remote policy URL %q must use HTTPS unless insecure is set", cfg.URL)
+	}
+	return &httpSource{client: &http.Client{}, url: cfg.URL, token: cfg.Token}, nil
+}
+
+// Fetch downloads the policy, or reuses the last one when the server replies it did not change, and its
+// signature. The signature is always downloaded as it can be published after the policy, the revision is
+// the digest of both.
+func (h *httpSource) Fetch(ctx context.Context) (*Policy, error) {
+	var header http.Header
+	if h.last != nil && h.etag != "" {
+		header = http.Header{"If-None-Match": []string{h.etag}}
+	}
+	data, resp, err := h.get(ctx, h.url, header)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		h.etag = resp.Header.Get("ETag")
+	case http.StatusNotModified:
+		data = h.last.Data
+	default:
+		return nil, fmt.Errorf("%s returned status code %d", h.url, resp.StatusCode)
+	}
+
+	sig, resp, err := h.get(ctx, h.url+signatureExt, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		sig = nil
+	default:
+		return nil, fmt.Errorf("%s returned status code %d", h.url+signatureExt, resp.StatusCode)
+	}
+
+	h.last = &Policy{Revision: digestOf(append(append([]byte{}, data...), sig...)), Data: data, Signature: sig}
+	return h.last, nil
+}
+
+func (h *httpSource) get(ctx context.Context, u string, header http.Header) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicySize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", u, err)
+	}
+	if len(body) > maxPolicySize {
+		return nil, nil, fmt.Errorf("%s is larger than %d bytes", u, maxPolicySize)
+	}
+	return body, resp, nil
+}
@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remotepolicy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+func TestHTTPSourceFetch(t *testing.T) {
+	policy, etag := "outputs: {}\n", `"v1"`
+	signature := ""
+	var policyDownloads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/agent.yml":
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			policyDownloads++
+			w.Header().Set("ETag", etag)
+			_, _ = w.Write([]byte(policy))
+		case "/agent.yml.asc":
+			if signature == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(signature))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	_, err := New(&configuration.RemotePolicyConfig{Type: configuration.RemotePolicyHTTP, URL: srv.URL + "/agent.yml"}, "")
+	assert.ErrorContains(t, err, "must use HTTPS")
+
+	source, err := New(&configuration.RemotePolicyConfig{
+		Type:     configuration.RemotePolicyHTTP,
+		URL:      srv.URL + "/agent.yml",
+		Insecure: true,
+	}, "")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	first, err := source.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, policy, string(first.Data))
+	assert.Nil(t, first.Signature)
+
+	// the unchanged policy is not downloaded again, a signature published afterwards is a new revision
+	signature = "signature"
+	second, err := source.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, policyDownloads)
+	assert.Equal(t, policy, string(second.Data))
+	assert.Equal(t, signature, string(second.Signature))
+	assert.NotEqual(t, first.Revision, second.Revision)
+
+	third, err := source.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, second.Revision, third.Revision)
+
+	policy, etag = "inputs: []\n", `"v2"`
+	fourth, err := source.Fetch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, policyDownloads)
+	assert.Equal(t, policy, string(fourth.Data))
+	assert.NotEqual(t, third.Revision, fourth.Revision)
+}
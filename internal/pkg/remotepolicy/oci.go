@@ -0,0 +1,184 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remotepolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	// ociTitleAnnotation is the annotation naming the file of a layer, as set by ORAS.
+	ociTitleAnnotation = "org.opencontainers.image.title"
+)
+
+// ociSource pulls the policy from the layers of an OCI artifact, the layers are the files of the
+// artifact named by their title annotation like ORAS pushes them.
+type ociSource struct {
+	client     *http.Client
+	baseURL    string
+	repository string
+	reference  string
+	path       string
+	token      string
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+func newOCISource(cfg *configuration.RemotePolicyConfig) (*ociSource, error) {
+	registry, repository, reference, err := parseOCIReference(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "https"
+	if cfg.Insecure {
+		scheme = "http"
+	}
+	return &ociSource{
+		client:     &http.Client{},
+		baseURL:    scheme + "://" + registry + "/v2/" + repository,
+		repository: repository,
+		reference:  reference,
+		path:       cfg.Path,
+		token:      cfg.Token,
+	}, nil
+}
+
+// parseOCIReference splits registry/repository:tag or registry/repository@digest, the tag is latest when
+// the reference has neither a tag nor a digest.
+func parseOCIReference(ref string) (registry, repository, reference string, err error) {
+	ref = strings.TrimPrefix(ref, "oci://")
+	i := strings.Index(ref, "/")
+	if i <= 0 {
+		return "", "", "", fmt.Errorf("invalid OCI reference %q, expected registry/repository:tag", ref)
+	}
+	registry, repository = ref[:i], ref[i+1:]
+
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, reference = repository[:i], repository[i+1:]
+	} else if i := strings.LastIndex(repository, ":"); i >= 0 {
+		repository, reference = repository[:i], repository[i+1:]
+	} else {
+		reference = "latest"
+	}
+	if repository == "" || reference == "" {
+		return "", "", "", fmt.Errorf("invalid OCI reference %q, expected registry/repository:tag", ref)
+	}
+	return registry, repository, reference, nil
+}
+
+// Fetch downloads the manifest of the artifact then the layers of the policy and of its signature.
+func (o *ociSource) Fetch(ctx context.Context) (*Policy, error) {
+	body, err := o.get(ctx, "/manifests/"+o.reference, ociManifestMediaType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the manifest of %s:%s: %w", o.repository, o.reference, err)
+	}
+	revision := digestOf(body)
+	if strings.HasPrefix(o.reference, "sha256:") && o.reference != revision {
+		return nil, fmt.Errorf("manifest digest %s does not match %s", revision, o.reference)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest %s: %w", revision, err)
+	}
+
+	var policy, signature *ociDescriptor
+	for i, l := range manifest.Layers {
+		switch l.Annotations[ociTitleAnnotation] {
+		case o.path:
+			policy = &manifest.Layers[i]
+		case o.path + signatureExt:
+			signature = &manifest.Layers[i]
+		}
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("artifact %s has no layer titled %q", revision, o.path)
+	}
+
+	p := &Policy{Revision: revision}
+	if p.Data, err = o.blob(ctx, policy); err != nil {
+		return nil, err
+	}
+	if signature != nil {
+		if p.Signature, err = o.blob(ctx, signature); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// blob downloads a layer and verifies its content matches its digest.
+func (o *ociSource) blob(ctx context.Context, desc *ociDescriptor) ([]byte, error) {
+	if !strings.HasPrefix(desc.Digest, "sha256:") {
+		return nil, fmt.Errorf("unsupported digest %q", desc.Digest)
+	}
+	if desc.Size > maxPolicySize {
+		return nil, fmt.Errorf("layer %s is larger than %d bytes", desc.Digest, maxPolicySize)
+	}
+	body, err := o.get(ctx, "/blobs/"+desc.Digest, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the layer %s: %w", desc.Digest, err)
+	}
+	if digest := digestOf(body); digest != desc.Digest {
+		return nil, fmt.Errorf("layer digest %s does not match %s", digest, desc.Digest)
+	}
+	return body, nil
+}
+
+func (o *ociSource) get(ctx context.Context, path, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if o.token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.token)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxPolicySize {
+		return nil, fmt.Errorf("response is larger than %d bytes", maxPolicySize)
+	}
+	return body, nil
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package remotepolicy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+func TestParseOCIReference(t *testing.T) {
+	tests := map[string][3]string{
+		"registry.example.com/policies/agent:prod":              {"registry.example.com", "policies/agent", "prod"},
+		"oci://localhost:5000/agent":                            {"localhost:5000", "agent", "latest"},
+		"registry.example.com/agent@sha256:0123456789abcdef012": {"registry.example.com", "agent", "sha256:0123456789abcdef012"},
+	}
+	for ref, expected := range tests {
+		registry, repository, reference, err := parseOCIReference(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, expected, [3]string{registry, repository, reference}, ref)
+	}
+
+	for _, ref := range []string{"agent:prod", "registry.example.com/:prod", "registry.example.com/agent:"} {
+		_, _, _, err := parseOCIReference(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestOCISourceFetch(t *testing.T) {
+	blobs := map[string][]byte{}
+	layer := func(title string, content string) ociDescriptor {
+		digest := digestOf([]byte(content))
+		blobs[digest] = []byte(content)
+		return ociDescriptor{
+			MediaType:   "application/vnd.oci.image.layer.v1.tar",
+			Digest:      digest,
+			Size:        int64(len(content)),
+			Annotations: map[string]string{ociTitleAnnotation: title},
+		}
+	}
+	manifest, err := json.Marshal(ociManifest{
+		MediaType: ociManifestMediaType,
+		Layers: []ociDescriptor{
+			layer("README.md", "readme"),
+			layer("elastic-agent.yml", "outputs: {}\n"),
+			layer("elastic-agent.yml.asc", "signature"),
+		},
+	})
+	require.NoError(t, err)
+
+	var tampered bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/policies/agent/manifests/prod":
+			assert.Equal(t, ociManifestMediaType, r.Header.Get("Accept"))
+			_, _ = w.Write(manifest)
+		case strings.HasPrefix(r.URL.Path, "/v2/policies/agent/blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/policies/agent/blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if tampered {
+				blob = append([]byte("inputs: []\n"), blob...)
+			}
+			_, _ = w.Write(blob)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &configuration.RemotePolicyConfig{
+		Type:     configuration.RemotePolicyOCI,
+		URL:      strings.TrimPrefix(srv.URL, "http://") + "/policies/agent:prod",
+		Path:     "elastic-agent.yml",
+		Token:    "secret",
+		Insecure: true,
+	}
+	source, err := New(cfg, "")
+	require.NoError(t, err)
+
+	policy, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, digestOf(manifest), policy.Revision)
+	assert.Equal(t, "outputs: {}\n", string(policy.Data))
+	assert.Equal(t, "signature", string(policy.Signature))
+
+	tampered = true
+	_, err = source.Fetch(context.Background())
+	assert.ErrorContains(t, err, "does not match")
+	tampered = false
+
+	cfg.Path = "missing.yml"
+	source, err = New(cfg, "")
+	require.NoError(t, err)
+	_, err = source.Fetch(context.Background())
+	assert.ErrorContains(t, err, `no layer titled "missing.yml"`)
+
+	cfg.Token = "wrong"
+	source, err = New(cfg, "")
+	require.NoError(t, err)
+	_, err = source.Fetch(context.Background())
+	assert.ErrorContains(t, err, "status code 401")
+}
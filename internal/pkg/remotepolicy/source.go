@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package remotepolicy pulls the policy of a standalone agent from a Git repository, an OCI registry or a URL.
+package remotepolicy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+// signatureExt is the extension of the detached signature of the policy, next to the policy.
+const signatureExt = ".asc"
+
+// maxPolicySize is the maximum size of a policy or of its signature.
+const maxPolicySize = 10 * 1024 * 1024
+
+// Policy is a policy pulled from a source.
+type Policy struct {
+	// Revision identifies the content of the policy, the commit of a Git repository, the digest
+	// of the manifest of an OCI artifact or the digest of the policy and its signature of a URL.
+	Revision string
+	// Data is the YAML policy.
+	Data []byte
+	// Signature is the ASCII armored detached PGP signature of the policy, nil when the source has none.
+	Signature []byte
+}
+
+// Source pulls the policy.
+type Source interface {
+	Fetch(ctx context.Context) (*Policy, error)
+}
+
+// New creates the source of the configuration, dir is where a source can keep its state between two pulls.
+func New(cfg *configuration.RemotePolicyConfig, dir string) (Source, error) {
+	switch cfg.Type {
+	case configuration.RemotePolicyGit:
+		return newGitSource(cfg, dir), nil
+	case configuration.RemotePolicyOCI:
+		return newOCISource(cfg)
+	case configuration.RemotePolicyHTTP:
+		return newHTTPSource(cfg)
+	default:
+		return nil, fmt.Errorf("unknown remote policy type %q", cfg.Type)
+	}
+}
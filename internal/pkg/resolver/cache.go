@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+type entry struct {
+	addresses []string
+	expires   time.Time
+}
+
+// cache caches the resolutions of the hostnames for their TTL. An expired resolution is kept and
+// used when the hostname cannot be resolved anymore, the agent keeps connecting to the last known
+// addresses while the resolver is broken.
+type cache struct {
+	mx      sync.Mutex
+	entries map[string]entry
+
+	now          func() time.Time
+	lookupSystem func(ctx context.Context, host string) ([]string, error)
+	lookupServer func(ctx context.Context, servers []string, host string) ([]string, time.Duration, error)
+}
+
+var defaultCache = newCache()
+
+func newCache() *cache {
+	return &cache{
+		entries:      make(map[string]entry),
+		now:          time.Now,
+		lookupSystem: net.DefaultResolver.LookupHost,
+		lookupServer: lookupServers,
+	}
+}
+
+func (c *cache) reset() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.entries = make(map[string]entry)
+}
+
+// lookup returns the addresses of the host, from the cache while its resolution is valid.
+func (c *cache) lookup(ctx context.Context, cfg *Config, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	now := c.now()
+	c.mx.Lock()
+	cached, ok := c.entries[host]
+	c.mx.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.addresses, nil
+	}
+
+	addresses, ttl, err := c.resolve(ctx, cfg, host)
+	if err != nil {
+		if ok {
+			logp.NewLogger("resolver").Warnf("Failed to resolve %s, using its last addresses %v: %v", host, cached.addresses, err)
+			return cached.addresses, nil
+		}
+		return nil, err
+	}
+	ttl = cfg.ttl(ttl)
+	if ttl > 0 {
+		c.mx.Lock()
+		c.entries[host] = entry{addresses: addresses, expires: now.Add(ttl)}
+		c.mx.Unlock()
+	}
+	return addresses, nil
+}
+
+// resolve resolves the host with the configured DNS servers or with the resolver of the host, the
+// resolutions of the resolver of the host have no TTL.
+func (c *cache) resolve(ctx context.Context, cfg *Config, host string) ([]string, time.Duration, error) {
+	// localhost is not in the DNS servers
+	if len(cfg.Servers) == 0 || host == "localhost" {
+		addresses, err := c.lookupSystem(ctx, host)
+		return addresses, 0, err
+	}
+	return c.lookupServer(ctx, cfg.Servers, host)
+}
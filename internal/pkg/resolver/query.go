@@ -0,0 +1,190 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package resolver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// maxUDPSize is the largest DNS response over UDP.
+const maxUDPSize = 1232
+
+// lookupServers resolves the A and AAAA records of the host with the first DNS server answering,
+// it returns the shortest TTL of the records.
+func lookupServers(ctx context.Context, servers []string, host string) ([]string, time.Duration, error) {
+	var errs []string
+	for _, server := range servers {
+		addr, err := serverAddress(server)
+		if err != nil {
+			return nil, 0, err
+		}
+		addresses, ttl, err := lookupServer(ctx, addr, host)
+		if err == nil {
+			return addresses, ttl, nil
+		}
+		var nxErr *net.DNSError
+		if errors.As(err, &nxErr) && nxErr.IsNotFound {
+			// the other servers have the same records
+			return nil, 0, err
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, 0, fmt.Errorf("failed to resolve %s: %s", host, strings.Join(errs, ", "))
+}
+
+func lookupServer(ctx context.Context, server string, host string) ([]string, time.Duration, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid hostname %q: %w", host, err)
+	}
+	var addresses []string
+	var ttl uint32
+	first := true
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, answersTTL, err := exchange(ctx, server, name, qtype)
+		if err != nil {
+			if len(addresses) > 0 {
+				// the IPv4 addresses are enough
+				break
+			}
+			return nil, 0, err
+		}
+		if len(answers) == 0 {
+			continue
+		}
+		addresses = append(addresses, answers...)
+		if first || answersTTL < ttl {
+			ttl = answersTTL
+			first = false
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, Server: server, IsNotFound: true}
+	}
+	return addresses, time.Duration(ttl) * time.Second, nil
+}
+
+// exchange sends the query to the server over UDP, and over TCP when the response is truncated.
+func exchange(ctx context.Context, server string, name dnsmessage.Name, qtype dnsmessage.Type) ([]string, uint32, error) {
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+	query, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := roundTrip(ctx, "udp", server, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	msg, err := parse(resp, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if msg.Truncated {
+		if resp, err = roundTrip(ctx, "tcp", server, query); err != nil {
+			return nil, 0, err
+		}
+		if msg, err = parse(resp, id); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	switch msg.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, 0, &net.DNSError{Err: "no such host", Name: name.String(), Server: server, IsNotFound: true}
+	default:
+		return nil, 0, fmt.Errorf("DNS server %s answered %s for %s", server, msg.RCode, name)
+	}
+
+	var addresses []string
+	var ttl uint32
+	for _, answer := range msg.Answers {
+		var ip net.IP
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = body.A[:]
+		case *dnsmessage.AAAAResource:
+			ip = body.AAAA[:]
+		default:
+			// the CNAME records of the chain
+			continue
+		}
+		if len(addresses) == 0 || answer.Header.TTL < ttl {
+			ttl = answer.Header.TTL
+		}
+		addresses = append(addresses, ip.String())
+	}
+	return addresses, ttl, nil
+}
+
+func parse(resp []byte, id uint16) (*dnsmessage.Message, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		return nil, fmt.Errorf("invalid DNS response: %w", err)
+	}
+	if msg.ID != id || !msg.Response {
+		return nil, errors.New("DNS response doesn't match the query")
+	}
+	return &msg, nil
+}
+
+func roundTrip(ctx context.Context, network string, server string, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		resp := make([]byte, maxUDPSize)
+		n, err := conn.Read(resp)
+		if err != nil {
+			return nil, err
+		}
+		return resp[:n], nil
+	}
+
+	// over TCP the messages are prefixed by their length
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,166 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package resolver resolves the hostnames of Fleet Server and of the artifact download servers
+// with the DNS servers and the caching of the agent.dns configuration, so a broken resolver of the
+// host doesn't disconnect the agent from Fleet.
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport"
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
+)
+
+const defaultDNSPort = "53"
+
+// Config is the configuration of the resolution of the hostnames.
+type Config struct {
+	// Servers are the DNS servers queried instead of the resolver of the host, as IP or IP:port.
+	// They are tried in order.
+	Servers []string `yaml:"servers" config:"servers" json:"servers"`
+	// Timeout is the timeout of a resolution.
+	Timeout time.Duration `yaml:"timeout" config:"timeout" json:"timeout"`
+	// MinTTL is the shortest time a resolution is cached, it overrides the shorter TTLs of the
+	// records. The resolutions of the resolver of the host have no TTL, they are cached for MinTTL.
+	MinTTL time.Duration `yaml:"min_ttl" config:"min_ttl" json:"min_ttl"`
+	// MaxTTL is the longest time a resolution is cached, it overrides the longer TTLs of the
+	// records. Zero keeps the TTLs of the records.
+	MaxTTL time.Duration `yaml:"max_ttl" config:"max_ttl" json:"max_ttl"`
+}
+
+// DefaultConfig creates a default resolution configuration, the hostnames are resolved by the
+// resolver of the host without caching.
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout: 30 * time.Second,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	for _, server := range c.Servers {
+		if _, err := serverAddress(server); err != nil {
+			return err
+		}
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("the DNS timeout must be positive")
+	}
+	if c.MinTTL < 0 || c.MaxTTL < 0 {
+		return fmt.Errorf("the DNS min_ttl and max_ttl cannot be negative")
+	}
+	if c.MaxTTL > 0 && c.MinTTL > c.MaxTTL {
+		return fmt.Errorf("the DNS min_ttl %s is longer than the max_ttl %s", c.MinTTL, c.MaxTTL)
+	}
+	return nil
+}
+
+// ttl clamps the TTL of a resolution between MinTTL and MaxTTL.
+func (c *Config) ttl(ttl time.Duration) time.Duration {
+	if ttl < c.MinTTL {
+		ttl = c.MinTTL
+	}
+	if c.MaxTTL > 0 && ttl > c.MaxTTL {
+		ttl = c.MaxTTL
+	}
+	return ttl
+}
+
+// serverAddress returns the address of a DNS server, with the default port when it has none.
+func serverAddress(server string) (string, error) {
+	if ip := net.ParseIP(server); ip != nil {
+		return net.JoinHostPort(server, defaultDNSPort), nil
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid DNS server %q, must be an IP or an IP:port", server)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("invalid port of the DNS server %q", server)
+	}
+	return server, nil
+}
+
+var current atomic.Pointer[Config]
+
+// Apply applies the agent.dns configuration, the connections opened from then on use it. A
+// configuration without agent.dns keeps the current one, so a policy that doesn't set it keeps the
+// one of the local configuration.
+func Apply(c *config.Config) error {
+	if c == nil {
+		return nil
+	}
+	present := struct {
+		Agent struct {
+			DNS map[string]interface{} `config:"dns"`
+		} `config:"agent"`
+	}{}
+	if err := c.Unpack(&present); err != nil {
+		return fmt.Errorf("could not parse the DNS configuration: %w", err)
+	}
+	if present.Agent.DNS == nil {
+		return nil
+	}
+
+	parsed := struct {
+		Agent struct {
+			DNS *Config `config:"dns"`
+		} `config:"agent"`
+	}{}
+	parsed.Agent.DNS = DefaultConfig()
+	if err := c.Unpack(&parsed); err != nil {
+		return fmt.Errorf("could not parse the DNS configuration: %w", err)
+	}
+	current.Store(parsed.Agent.DNS)
+	defaultCache.reset()
+	return nil
+}
+
+// Current returns the configuration of the resolution of the hostnames.
+func Current() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return DefaultConfig()
+}
+
+// LookupHost resolves the host with the current configuration.
+func LookupHost(ctx context.Context, host string) ([]string, error) {
+	return defaultCache.lookup(ctx, Current(), host)
+}
+
+// Dialer returns a dialer resolving the hostnames with the current configuration, the addresses
+// are dialed as configured by agent.network.
+func Dialer(timeout time.Duration) transport.Dialer {
+	return transport.DialerFunc(func(network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		cfg := Current()
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		addresses, err := defaultCache.lookup(ctx, cfg, host)
+		if err != nil {
+			return nil, err
+		}
+		return netutil.Dial(network, host, addresses, port, timeout)
+	})
+}
+
+// TransportOption returns the option of the transport of the settings resolving the hostnames with
+// the current configuration, for the connections with and without TLS.
+func TransportOption(settings *httpcommon.HTTPTransportSettings) httpcommon.TransportOption {
+	return httpcommon.WithBaseDialer(Dialer(settings.Timeout))
+}
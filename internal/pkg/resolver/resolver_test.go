@@ -0,0 +1,182 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+// dnsServer answers the A and AAAA queries of fleet.example.com over UDP.
+func dnsServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			q := query.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true},
+				Questions: query.Questions,
+			}
+			switch {
+			case q.Name.String() != "fleet.example.com.":
+				resp.RCode = dnsmessage.RCodeNameError
+			case q.Type == dnsmessage.TypeA:
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 300},
+					Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+				}}
+			case q.Type == dnsmessage.TypeAAAA:
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+					Body:   &dnsmessage.AAAAResource{AAAA: [16]byte{0xfd, 15: 1}},
+				}}
+			}
+			packed, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestLookupServers(t *testing.T) {
+	server := dnsServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addresses, ttl, err := lookupServers(ctx, []string{server}, "fleet.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "fd00::1"}, addresses)
+	assert.Equal(t, time.Minute, ttl, "the shortest TTL of the records")
+
+	_, _, err = lookupServers(ctx, []string{server}, "unknown.example.com")
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	assert.True(t, dnsErr.IsNotFound)
+}
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Now()
+	lookups := 0
+	c := newCache()
+	c.now = func() time.Time { return now }
+	c.lookupServer = func(context.Context, []string, string) ([]string, time.Duration, error) {
+		lookups++
+		return []string{"10.0.0.1"}, 10 * time.Second, nil
+	}
+	cfg := &Config{Servers: []string{"10.0.0.53"}, Timeout: time.Second, MinTTL: time.Minute, MaxTTL: time.Hour}
+
+	_, err := c.lookup(context.Background(), cfg, "fleet.example.com")
+	require.NoError(t, err)
+	now = now.Add(30 * time.Second)
+	_, err = c.lookup(context.Background(), cfg, "fleet.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, lookups, "the TTL of the record is raised to the min_ttl")
+
+	now = now.Add(time.Minute)
+	_, err = c.lookup(context.Background(), cfg, "fleet.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 2, lookups)
+}
+
+func TestCacheStale(t *testing.T) {
+	now := time.Now()
+	c := newCache()
+	c.now = func() time.Time { return now }
+	c.lookupSystem = func(context.Context, string) ([]string, error) {
+		return []string{"10.0.0.1"}, nil
+	}
+	cfg := &Config{Timeout: time.Second, MinTTL: time.Minute}
+
+	addresses, err := c.lookup(context.Background(), cfg, "fleet.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addresses)
+
+	// the resolver of the host breaks, the last addresses are used
+	now = now.Add(time.Hour)
+	c.lookupSystem = func(context.Context, string) ([]string, error) {
+		return nil, errors.New("i/o timeout")
+	}
+	addresses, err = c.lookup(context.Background(), cfg, "fleet.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addresses)
+
+	_, err = c.lookup(context.Background(), cfg, "other.example.com")
+	assert.Error(t, err)
+}
+
+func TestCacheDisabled(t *testing.T) {
+	lookups := 0
+	c := newCache()
+	c.lookupSystem = func(context.Context, string) ([]string, error) {
+		lookups++
+		return []string{"10.0.0.1"}, nil
+	}
+	cfg := DefaultConfig()
+	for i := 0; i < 2; i++ {
+		_, err := c.lookup(context.Background(), cfg, "fleet.example.com")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, lookups, "the resolutions of the resolver of the host are not cached by default")
+
+	addresses, err := c.lookup(context.Background(), cfg, "::1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"::1"}, addresses)
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Servers = []string{"10.0.0.53", "[fd00::53]:5353"}
+	assert.NoError(t, cfg.Validate())
+	cfg.Servers = []string{"dns.example.com"}
+	assert.ErrorContains(t, cfg.Validate(), "invalid DNS server")
+
+	cfg.Servers = nil
+	cfg.MinTTL = time.Hour
+	cfg.MaxTTL = time.Minute
+	assert.ErrorContains(t, cfg.Validate(), "longer than the max_ttl")
+}
+
+func TestApply(t *testing.T) {
+	defer current.Store(nil)
+
+	cfg, err := config.NewConfigFrom(map[string]interface{}{"agent.dns.servers": []string{"10.0.0.53"}})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.Equal(t, []string{"10.0.0.53"}, Current().Servers)
+	assert.Equal(t, 30*time.Second, Current().Timeout, "the defaults are kept")
+
+	// a configuration without agent.dns keeps the current one
+	cfg, err = config.NewConfigFrom(map[string]interface{}{"agent.logging.level": "debug"})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.Equal(t, []string{"10.0.0.53"}, Current().Servers)
+}
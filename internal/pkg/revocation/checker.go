@@ -0,0 +1,228 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package revocation
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// maxCRLSize is the largest CRL fetched.
+const maxCRLSize = 32 << 20
+
+// ErrRevoked is returned when the certificate of a server is revoked.
+var ErrRevoked = errors.New("certificate revoked")
+
+var checker = newRevocationChecker()
+
+type cachedCRL struct {
+	crl     *x509.RevocationList
+	expires time.Time
+}
+
+// revocationChecker checks the revocation status of the certificates of the servers, the CRLs are
+// cached by distribution point.
+type revocationChecker struct {
+	mx   sync.Mutex
+	crls map[string]cachedCRL
+
+	now   func() time.Time
+	fetch func(ctx context.Context, url string) ([]byte, error)
+}
+
+func newRevocationChecker() *revocationChecker {
+	return &revocationChecker{
+		crls:  make(map[string]cachedCRL),
+		now:   time.Now,
+		fetch: fetchCRL,
+	}
+}
+
+func (c *revocationChecker) resetCache() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.crls = make(map[string]cachedCRL)
+}
+
+// verify checks the revocation status of the certificate of the server of the connection. The
+// stapled OCSP response is used first, then the CRL of the certificate.
+func (c *revocationChecker) verify(cfg *Config, cs tls.ConnectionState) error {
+	if cfg.Mode == ModeOff || len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := cs.PeerCertificates[0]
+	issuer := findIssuer(leaf, cs)
+	if issuer == nil {
+		return c.unknown(cfg, leaf, []string{"the issuer of the certificate was not sent by the server"})
+	}
+
+	var reasons []string
+	if cfg.OCSPStapling {
+		if len(cs.OCSPResponse) == 0 {
+			reasons = append(reasons, "no OCSP response stapled by the server")
+		} else {
+			known, err := c.verifyOCSP(leaf, issuer, cs.OCSPResponse)
+			if err != nil {
+				return err
+			}
+			if known != "" {
+				reasons = append(reasons, known)
+			} else {
+				return nil
+			}
+		}
+	}
+	if cfg.CRL.Enabled {
+		if len(leaf.CRLDistributionPoints) == 0 {
+			reasons = append(reasons, "no CRL distribution point in the certificate")
+		} else {
+			known, err := c.verifyCRL(cfg, leaf, issuer)
+			if err != nil {
+				return err
+			}
+			if known != "" {
+				reasons = append(reasons, known)
+			} else {
+				return nil
+			}
+		}
+	}
+	return c.unknown(cfg, leaf, reasons)
+}
+
+// unknown is called when the revocation status of the certificate is unknown, the connection is
+// rejected in hard_fail mode.
+func (c *revocationChecker) unknown(cfg *Config, leaf *x509.Certificate, reasons []string) error {
+	msg := fmt.Sprintf("revocation status of certificate %q unknown: %s", leaf.Subject, strings.Join(reasons, ", "))
+	if cfg.Mode == ModeHardFail {
+		return errors.New(msg)
+	}
+	logp.NewLogger("revocation").Debug(msg)
+	return nil
+}
+
+// verifyOCSP verifies the stapled OCSP response, it returns the reason why the status is unknown
+// or an error when the certificate is revoked.
+func (c *revocationChecker) verifyOCSP(leaf, issuer *x509.Certificate, staple []byte) (string, error) {
+	resp, err := ocsp.ParseResponseForCert(staple, leaf, issuer)
+	if err != nil {
+		return fmt.Sprintf("invalid OCSP response: %v", err), nil
+	}
+	if !resp.NextUpdate.IsZero() && c.now().After(resp.NextUpdate) {
+		return "expired OCSP response", nil
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return "", nil
+	case ocsp.Revoked:
+		return "", fmt.Errorf("%w: certificate %q was revoked on %s according to the OCSP response", ErrRevoked, leaf.Subject, resp.RevokedAt.UTC().Format(time.RFC3339))
+	default:
+		return "OCSP responder doesn't know the certificate", nil
+	}
+}
+
+// verifyCRL checks the certificate against the CRL of its first reachable distribution point, it
+// returns the reason why the status is unknown or an error when the certificate is revoked.
+func (c *revocationChecker) verifyCRL(cfg *Config, leaf, issuer *x509.Certificate) (string, error) {
+	var reasons []string
+	for _, url := range leaf.CRLDistributionPoints {
+		crl, err := c.getCRL(cfg, url, issuer)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		for _, revoked := range crl.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return "", fmt.Errorf("%w: certificate %q was revoked on %s according to the CRL %s", ErrRevoked, leaf.Subject, revoked.RevocationTime.UTC().Format(time.RFC3339), url)
+			}
+		}
+		return "", nil
+	}
+	return strings.Join(reasons, ", "), nil
+}
+
+// getCRL returns the CRL of the distribution point, from the cache when it is still valid.
+func (c *revocationChecker) getCRL(cfg *Config, url string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	now := c.now()
+	c.mx.Lock()
+	cached, ok := c.crls[url]
+	c.mx.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.crl, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.CRL.Timeout)
+	defer cancel()
+	content, err := c.fetch(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the CRL %s: %w", url, err)
+	}
+	crl, err := x509.ParseRevocationList(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the CRL %s: %w", url, err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("invalid signature of the CRL %s: %w", url, err)
+	}
+	if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+		return nil, fmt.Errorf("the CRL %s expired on %s", url, crl.NextUpdate.UTC().Format(time.RFC3339))
+	}
+
+	expires := now.Add(cfg.CRL.CacheTTL)
+	if !crl.NextUpdate.IsZero() && crl.NextUpdate.Before(expires) {
+		expires = crl.NextUpdate
+	}
+	c.mx.Lock()
+	c.crls[url] = cachedCRL{crl: crl, expires: expires}
+	c.mx.Unlock()
+	return crl, nil
+}
+
+// findIssuer returns the certificate of the chain of the server that issued the leaf certificate.
+func findIssuer(leaf *x509.Certificate, cs tls.ConnectionState) *x509.Certificate {
+	for _, chain := range cs.VerifiedChains {
+		if len(chain) > 1 {
+			return chain[1]
+		}
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		if leaf.CheckSignatureFrom(cert) == nil {
+			return cert
+		}
+	}
+	return nil
+}
+
+// fetchCRL downloads a CRL, the distribution points are HTTP URLs.
+func fetchCRL(ctx context.Context, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("unsupported distribution point")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+}
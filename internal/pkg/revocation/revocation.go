@@ -0,0 +1,168 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package revocation checks the revocation status of the certificates of Fleet Server and of the
+// artifact download servers, with the OCSP response stapled by the server or with the CRL of the
+// certificate.
+package revocation
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+const (
+	// ModeOff doesn't check the revocation status of the certificates.
+	ModeOff = "off"
+	// ModeSoftFail rejects the revoked certificates and accepts the ones with an unknown status.
+	ModeSoftFail = "soft_fail"
+	// ModeHardFail rejects the revoked certificates and the ones with an unknown status.
+	ModeHardFail = "hard_fail"
+)
+
+// Config is the configuration of the revocation checks.
+type Config struct {
+	// Mode is off, soft_fail or hard_fail.
+	Mode string `yaml:"mode" config:"mode" json:"mode"`
+	// OCSPStapling verifies the OCSP response stapled by the server.
+	OCSPStapling bool `yaml:"ocsp_stapling" config:"ocsp_stapling" json:"ocsp_stapling"`
+	// CRL fetches the CRL of the certificates without a stapled OCSP response.
+	CRL CRLConfig `yaml:"crl" config:"crl" json:"crl"`
+}
+
+// CRLConfig is the configuration of the CRL fetches.
+type CRLConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// CacheTTL is the longest time a CRL is cached, it is fetched again earlier when its next update
+	// is due.
+	CacheTTL time.Duration `yaml:"cache_ttl" config:"cache_ttl" json:"cache_ttl"`
+	// Timeout is the timeout of a CRL fetch.
+	Timeout time.Duration `yaml:"timeout" config:"timeout" json:"timeout"`
+}
+
+// DefaultConfig creates a default revocation configuration, the revocation status of the
+// certificates is not checked.
+func DefaultConfig() *Config {
+	return &Config{
+		Mode:         ModeOff,
+		OCSPStapling: true,
+		CRL: CRLConfig{
+			Enabled:  true,
+			CacheTTL: time.Hour,
+			Timeout:  10 * time.Second,
+		},
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case ModeOff, ModeSoftFail, ModeHardFail:
+	default:
+		return fmt.Errorf("invalid revocation mode %q, must be one of %s, %s or %s", c.Mode, ModeOff, ModeSoftFail, ModeHardFail)
+	}
+	if c.Mode != ModeOff && !c.OCSPStapling && !c.CRL.Enabled {
+		return fmt.Errorf("the revocation mode %s needs ocsp_stapling or crl enabled", c.Mode)
+	}
+	if c.CRL.Enabled && (c.CRL.CacheTTL <= 0 || c.CRL.Timeout <= 0) {
+		return fmt.Errorf("the cache_ttl and the timeout of the CRL must be positive")
+	}
+	return nil
+}
+
+var current atomic.Pointer[Config]
+
+// Apply applies the agent.tls_revocation configuration, the connections opened from then on use it.
+// A configuration without agent.tls_revocation keeps the current one, so a policy that doesn't set
+// it keeps the one of the local configuration.
+func Apply(c *config.Config) error {
+	if c == nil {
+		return nil
+	}
+	present := struct {
+		Agent struct {
+			Revocation map[string]interface{} `config:"tls_revocation"`
+		} `config:"agent"`
+	}{}
+	if err := c.Unpack(&present); err != nil {
+		return fmt.Errorf("could not parse the revocation configuration: %w", err)
+	}
+	if present.Agent.Revocation == nil {
+		return nil
+	}
+
+	parsed := struct {
+		Agent struct {
+			Revocation *Config `config:"tls_revocation"`
+		} `config:"agent"`
+	}{}
+	parsed.Agent.Revocation = DefaultConfig()
+	if err := c.Unpack(&parsed); err != nil {
+		return fmt.Errorf("could not parse the revocation configuration: %w", err)
+	}
+	current.Store(parsed.Agent.Revocation)
+	checker.resetCache()
+	return nil
+}
+
+// Current returns the configuration of the revocation checks.
+func Current() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return DefaultConfig()
+}
+
+// TransportOption returns the option of the transport of the settings checking the revocation
+// status of the certificates of the servers, while it is enabled. The transport is unchanged when
+// TLS is disabled or its verification is disabled.
+func TransportOption(settings *httpcommon.HTTPTransportSettings) httpcommon.TransportOption {
+	return httpcommon.WithTransportFunc(func(t *http.Transport) {
+		if !settings.TLS.IsEnabled() || settings.TLS.VerificationMode == tlscommon.VerifyNone {
+			return
+		}
+
+		dialTLS := t.DialTLS //nolint:staticcheck // the transport of httpcommon uses the deprecated function
+		if dialTLS != nil {
+			t.DialTLS = func(network, addr string) (net.Conn, error) { //nolint:staticcheck // see above
+				conn, err := dialTLS(network, addr)
+				if err != nil {
+					return nil, err
+				}
+				tlsConn, ok := conn.(*tls.Conn)
+				if !ok {
+					return conn, nil
+				}
+				if err := checker.verify(Current(), tlsConn.ConnectionState()); err != nil {
+					_ = conn.Close()
+					return nil, err
+				}
+				return conn, nil
+			}
+		}
+
+		// the connections through a proxy are upgraded to TLS by the transport
+		if t.TLSClientConfig != nil {
+			clientCfg := t.TLSClientConfig
+			t.TLSClientConfig = clientCfg.Clone()
+			t.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+				if clientCfg.VerifyConnection != nil {
+					if err := clientCfg.VerifyConnection(cs); err != nil {
+						return err
+					}
+				}
+				return checker.verify(Current(), cs)
+			}
+		}
+	})
+}
@@ -0,0 +1,240 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package revocation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+const crlURL = "http://crl.example.com/ca.crl"
+
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+}
+
+// leaf creates a certificate for localhost signed by the CA.
+func (ca *testCA) leaf(t *testing.T, serial int64) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		CRLDistributionPoints: []string{crlURL},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key, Leaf: cert}
+}
+
+func (ca *testCA) ocsp(t *testing.T, leaf *x509.Certificate, status int) []byte {
+	t.Helper()
+	resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+		Status:       status,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+		RevokedAt:    time.Now().Add(-time.Minute),
+	}, ca.key)
+	require.NoError(t, err)
+	return resp
+}
+
+func (ca *testCA) crl(t *testing.T, revoked ...*big.Int) []byte {
+	t.Helper()
+	var entries []pkix.RevokedCertificate
+	for _, serial := range revoked {
+		entries = append(entries, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: time.Now().Add(-time.Minute)})
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:              big.NewInt(1),
+		ThisUpdate:          time.Now().Add(-time.Minute),
+		NextUpdate:          time.Now().Add(time.Hour),
+		RevokedCertificates: entries,
+	}, ca.cert, ca.key)
+	require.NoError(t, err)
+	return crl
+}
+
+func connectionState(cert tls.Certificate, staple []byte) tls.ConnectionState {
+	return tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert.Leaf, mustParse(cert.Certificate[1])},
+		OCSPResponse:     staple,
+	}
+}
+
+func mustParse(der []byte) *x509.Certificate {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return cert
+}
+
+func TestApply(t *testing.T) {
+	defer current.Store(nil)
+
+	cfg, err := config.NewConfigFrom(map[string]interface{}{"agent.tls_revocation.mode": "hard_fail"})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.Equal(t, ModeHardFail, Current().Mode)
+	assert.True(t, Current().OCSPStapling, "the defaults are kept")
+	assert.Equal(t, time.Hour, Current().CRL.CacheTTL)
+
+	// a configuration without the revocation checks keeps the current one
+	cfg, err = config.NewConfigFrom(map[string]interface{}{"agent.logging.level": "debug"})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.Equal(t, ModeHardFail, Current().Mode)
+
+	cfg, err = config.NewConfigFrom(map[string]interface{}{"agent.tls_revocation.mode": "strict"})
+	require.NoError(t, err)
+	require.Error(t, Apply(cfg))
+}
+
+func TestVerifyOCSP(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.leaf(t, 2)
+	c := newRevocationChecker()
+	c.fetch = func(context.Context, string) ([]byte, error) { return nil, errors.New("unreachable") }
+	cfg := &Config{Mode: ModeHardFail, OCSPStapling: true}
+
+	assert.NoError(t, c.verify(cfg, connectionState(cert, ca.ocsp(t, cert.Leaf, ocsp.Good))))
+
+	err := c.verify(cfg, connectionState(cert, ca.ocsp(t, cert.Leaf, ocsp.Revoked)))
+	assert.ErrorIs(t, err, ErrRevoked)
+
+	err = c.verify(cfg, connectionState(cert, nil))
+	assert.ErrorContains(t, err, "no OCSP response stapled by the server")
+
+	cfg.Mode = ModeSoftFail
+	assert.NoError(t, c.verify(cfg, connectionState(cert, nil)), "an unknown status is accepted in soft_fail mode")
+	err = c.verify(cfg, connectionState(cert, ca.ocsp(t, cert.Leaf, ocsp.Revoked)))
+	assert.ErrorIs(t, err, ErrRevoked, "a revoked certificate is rejected in soft_fail mode")
+}
+
+func TestVerifyCRL(t *testing.T) {
+	ca := newTestCA(t)
+	good := ca.leaf(t, 2)
+	revoked := ca.leaf(t, 3)
+
+	fetches := 0
+	c := newRevocationChecker()
+	c.fetch = func(_ context.Context, url string) ([]byte, error) {
+		assert.Equal(t, crlURL, url)
+		fetches++
+		return ca.crl(t, revoked.Leaf.SerialNumber), nil
+	}
+	cfg := &Config{Mode: ModeHardFail, CRL: CRLConfig{Enabled: true, CacheTTL: time.Hour, Timeout: time.Second}}
+
+	assert.NoError(t, c.verify(cfg, connectionState(good, nil)))
+	err := c.verify(cfg, connectionState(revoked, nil))
+	assert.ErrorIs(t, err, ErrRevoked)
+	assert.Equal(t, 1, fetches, "the CRL is cached")
+
+	// the CRL is fetched again once the cache expired
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	c.fetch = func(context.Context, string) ([]byte, error) { return nil, errors.New("unreachable") }
+	err = c.verify(cfg, connectionState(good, nil))
+	assert.ErrorContains(t, err, "failed to fetch the CRL")
+}
+
+func TestVerifyCRLSignature(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	cert := ca.leaf(t, 2)
+
+	c := newRevocationChecker()
+	c.fetch = func(context.Context, string) ([]byte, error) { return other.crl(t), nil }
+	cfg := &Config{Mode: ModeHardFail, CRL: CRLConfig{Enabled: true, CacheTTL: time.Hour, Timeout: time.Second}}
+
+	err := c.verify(cfg, connectionState(cert, nil))
+	assert.ErrorContains(t, err, "invalid signature of the CRL")
+}
+
+func TestTransportOption(t *testing.T) {
+	defer current.Store(nil)
+
+	ca := newTestCA(t)
+	cert := ca.leaf(t, 2)
+	cert.OCSPStaple = ca.ocsp(t, cert.Leaf, ocsp.Revoked)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}} //nolint:gosec // testing server
+	srv.StartTLS()
+	defer srv.Close()
+
+	settings := httpcommon.DefaultHTTPTransportSettings()
+	settings.TLS = &tlscommon.Config{CAs: []string{ca.pem()}}
+	client, err := settings.Client(TransportOption(&settings))
+	require.NoError(t, err)
+
+	resp, err := client.Get(srv.URL) //nolint:noctx // testing request
+	require.NoError(t, err, "the revocation checks are disabled")
+	resp.Body.Close()
+	client.CloseIdleConnections()
+
+	current.Store(&Config{Mode: ModeSoftFail, OCSPStapling: true})
+	_, err = client.Get(srv.URL) //nolint:noctx // testing request
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrRevoked)
+}
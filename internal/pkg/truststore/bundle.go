@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package truststore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// bundleFile is the name of the file with the certificates of the trust store of the operating system.
+const bundleFile = "system-ca.pem"
+
+var bundleMx sync.Mutex
+
+// BundlePath returns the path of the file written by Bundle in the directory.
+func BundlePath(dir string) string {
+	return filepath.Join(dir, bundleFile)
+}
+
+// Bundle writes the certificates of the trust store of the operating system as PEM in the directory
+// and returns the path of the file, so they can be added to the certificate authorities of the
+// components. The file is only rewritten when the trust store changed.
+func Bundle(dir string) (string, error) {
+	bundleMx.Lock()
+	defer bundleMx.Unlock()
+
+	content, err := systemCertificates()
+	if err != nil {
+		return "", fmt.Errorf("failed to export the trust store of the operating system: %w", err)
+	}
+	if len(content) == 0 {
+		return "", errors.New("the trust store of the operating system has no certificates")
+	}
+
+	path := BundlePath(dir)
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, content) {
+		return path, nil
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+	// the file is replaced at once, so the components never read a partial bundle
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil { //nolint:gosec // the certificates are public
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
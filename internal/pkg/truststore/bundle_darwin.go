@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build darwin
+
+package truststore
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// keychains are the keychains of the roots shipped with macOS and of the certificates added by the
+// administrators or by a configuration profile.
+var keychains = []string{
+	"/System/Library/Keychains/SystemRootCertificates.keychain",
+	"/Library/Keychains/System.keychain",
+}
+
+// systemCertificates returns the certificates of the system keychains as PEM.
+func systemCertificates() ([]byte, error) {
+	args := append([]string{"find-certificate", "-a", "-p"}, keychains...)
+	out, err := exec.Command("/usr/bin/security", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("security find-certificate failed: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows && !darwin
+
+package truststore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// certFiles are the bundles of the distributions, the first one found is the trust store, the same
+// way the Go runtime loads it.
+var certFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux
+}
+
+// certDirectories are the directories of the certificates added to the trust store.
+var certDirectories = []string{
+	"/etc/ssl/certs",     // SLES10/SLES11
+	"/etc/pki/tls/certs", // Fedora/RHEL
+}
+
+// systemCertificates returns the certificates of the trust store as PEM. SSL_CERT_FILE and
+// SSL_CERT_DIR override the locations like they do for the Go runtime.
+func systemCertificates() ([]byte, error) {
+	files := certFiles
+	if f := os.Getenv("SSL_CERT_FILE"); f != "" {
+		files = []string{f}
+	}
+	var content []byte
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			content = append(content, data...)
+			break
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	dirs := certDirectories
+	if d := os.Getenv("SSL_CERT_DIR"); d != "" {
+		dirs = filepath.SplitList(d)
+	} else if len(content) > 0 {
+		// the bundle of the distribution already holds the certificates of its directory
+		return content, nil
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil || !bytes.Contains(data, []byte("-----BEGIN CERTIFICATE-----")) {
+				continue
+			}
+			content = append(content, data...)
+			content = append(content, '\n')
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package truststore
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// stores are the system certificate stores of the trusted roots and of the intermediate authorities.
+var stores = []string{"ROOT", "CA"}
+
+// systemCertificates returns the certificates of the system certificate stores as PEM.
+func systemCertificates() ([]byte, error) {
+	var buf bytes.Buffer
+	for _, name := range stores {
+		if err := exportStore(&buf, name); err != nil {
+			return nil, fmt.Errorf("failed to export the %s certificate store: %w", name, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func exportStore(buf *bytes.Buffer, name string) error {
+	storeName, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	store, err := windows.CertOpenSystemStore(0, storeName)
+	if err != nil {
+		return err
+	}
+	defer windows.CertCloseStore(store, 0) //nolint:errcheck // nothing to do on failure
+
+	var cert *windows.CertContext
+	for {
+		cert, err = windows.CertEnumCertificatesInStore(store, cert)
+		if err != nil {
+			if errors.Is(err, windows.Errno(windows.CRYPT_E_NOT_FOUND)) {
+				return nil
+			}
+			return err
+		}
+		der := unsafe.Slice(cert.EncodedCert, cert.Length)
+		if err := pem.Encode(buf, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package truststore adds the certificates of the trust store of the operating system to the
+// certificate authorities of the destinations of Elastic Agent: Fleet Server, the outputs and the
+// artifact downloads.
+//
+// The certificate_authorities of a TLS configuration replace the trust store of the operating
+// system. When the trust store is enabled they are trusted in addition to it, so the CA of each
+// destination doesn't need to be distributed to the agents when the hosts already trust it.
+package truststore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/transport"
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/resolver"
+)
+
+// Config is the configuration of the trust store.
+type Config struct {
+	// System trusts the certificates of the trust store of the operating system in addition to the
+	// certificate authorities of each destination.
+	System bool `yaml:"system" config:"system" json:"system"`
+}
+
+// DefaultConfig creates a default trust store configuration, the certificate authorities of a
+// destination replace the trust store of the operating system.
+func DefaultConfig() *Config {
+	return &Config{
+		System: false,
+	}
+}
+
+var system atomic.Bool
+
+// Apply applies the agent.trust_store configuration, the connections opened from then on use it. A
+// configuration without agent.trust_store keeps the current one, so a policy that doesn't set it
+// keeps the one of the local configuration.
+func Apply(c *config.Config) error {
+	if c == nil {
+		return nil
+	}
+	parsed := struct {
+		Agent struct {
+			TrustStore *Config `config:"trust_store"`
+		} `config:"agent"`
+	}{}
+	if err := c.Unpack(&parsed); err != nil {
+		return fmt.Errorf("could not parse the trust store configuration: %w", err)
+	}
+	if parsed.Agent.TrustStore != nil {
+		system.Store(parsed.Agent.TrustStore.System)
+	}
+	return nil
+}
+
+// SystemEnabled returns true when the trust store of the operating system is trusted in addition to
+// the certificate authorities of the destinations.
+func SystemEnabled() bool {
+	return system.Load()
+}
+
+// TransportOption returns the option of the transport of the settings trusting the trust store of
+// the operating system in addition to their certificate authorities, while it is enabled. The
+// transport is unchanged when the settings have no certificate authorities, the trust store of the
+// operating system is already used in that case.
+func TransportOption(settings *httpcommon.HTTPTransportSettings) httpcommon.TransportOption {
+	return httpcommon.WithTransportFunc(func(t *http.Transport) {
+		if !settings.TLS.IsEnabled() || len(settings.TLS.CAs) == 0 {
+			return
+		}
+		tlsCfg, err := systemTLSConfig(settings.TLS)
+		if err != nil {
+			// the settings were already loaded by the transport, it fails the same way on connect
+			logp.NewLogger("truststore").Warnf("Failed to add the trust store of the operating system: %v", err)
+			return
+		}
+
+		dialTLS := t.DialTLS //nolint:staticcheck // the transport of httpcommon uses the deprecated function
+		systemDialer := transport.TLSDialer(resolver.Dialer(settings.Timeout), tlsCfg, settings.Timeout)
+		t.DialTLS = func(network, addr string) (net.Conn, error) { //nolint:staticcheck // see above
+			if SystemEnabled() {
+				return systemDialer.Dial(network, addr)
+			}
+			return dialTLS(network, addr)
+		}
+
+		// the connections through a proxy are upgraded to TLS by the transport
+		clientCfg := t.TLSClientConfig
+		systemCfg := tlsCfg.ToConfig()
+		t.TLSClientConfig = clientCfg.Clone()
+		t.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			verify := clientCfg.VerifyConnection
+			if SystemEnabled() {
+				verify = systemCfg.VerifyConnection
+			}
+			if verify == nil {
+				return nil
+			}
+			return verify(cs)
+		}
+	})
+}
+
+// systemTLSConfig loads the TLS configuration with the trust store of the operating system added
+// to its certificate authorities.
+func systemTLSConfig(cfg *tlscommon.Config) (*tlscommon.TLSConfig, error) {
+	tlsCfg, err := tlscommon.LoadTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the trust store of the operating system: %w", err)
+	}
+	for _, ca := range cfg.CAs {
+		r, err := tlscommon.NewPEMReader(ca)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the certificate authority: %w", err)
+		}
+		content, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the certificate authority %v: %w", r, err)
+		}
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificate in the certificate authority %v", r)
+		}
+	}
+	tlsCfg.RootCAs = pool
+	return tlsCfg, nil
+}
@@ -0,0 +1,93 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package truststore
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/core/authority"
+)
+
+func TestApply(t *testing.T) {
+	defer system.Store(false)
+
+	cfg, err := config.NewConfigFrom(map[string]interface{}{"agent.trust_store.system": true})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.True(t, SystemEnabled())
+
+	// a configuration without the trust store keeps the current one
+	cfg, err = config.NewConfigFrom(map[string]interface{}{"agent.logging.level": "debug"})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.True(t, SystemEnabled())
+
+	cfg, err = config.NewConfigFrom(map[string]interface{}{"agent.trust_store.system": false})
+	require.NoError(t, err)
+	require.NoError(t, Apply(cfg))
+	assert.False(t, SystemEnabled())
+}
+
+func TestTransportOption(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("the trust store of the operating system is only overridden with SSL_CERT_FILE on Unix")
+	}
+	defer system.Store(false)
+
+	// the CA of the server is in the trust store, the destination is configured with another CA
+	systemCA, err := authority.NewCA()
+	require.NoError(t, err)
+	destinationCA, err := authority.NewCA()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	systemFile := filepath.Join(dir, "system.pem")
+	require.NoError(t, os.WriteFile(systemFile, systemCA.Crt(), 0600))
+	t.Setenv("SSL_CERT_FILE", systemFile)
+	t.Setenv("SSL_CERT_DIR", t.TempDir())
+
+	pair, err := systemCA.GeneratePair()
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{*pair.Certificate}} //nolint:gosec // testing server
+	srv.StartTLS()
+	defer srv.Close()
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	settings := httpcommon.DefaultHTTPTransportSettings()
+	settings.TLS = &tlscommon.Config{CAs: []string{string(destinationCA.Crt())}}
+	client, err := settings.Client(TransportOption(&settings))
+	require.NoError(t, err)
+
+	_, err = client.Get(url) //nolint:noctx // testing request
+	require.Error(t, err, "the trust store of the operating system is disabled")
+
+	system.Store(true)
+	resp, err := client.Get(url) //nolint:noctx // testing request
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	bundle, err := Bundle(dir)
+	require.NoError(t, err)
+	content, err := os.ReadFile(bundle)
+	require.NoError(t, err)
+	assert.Equal(t, systemCA.Crt(), content)
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/elastic/elastic-agent/dev-tools/mage/target/common"
 
 	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/component/golden"
 	"github.com/elastic/elastic-agent/pkg/testing/define"
 	"github.com/elastic/elastic-agent/pkg/testing/ess"
 	"github.com/elastic/elastic-agent/pkg/testing/runner"
@@ -65,12 +66,18 @@ const (
 	commitLen         = 7
 
 	cloudImageTmpl = "docker.elastic.co/observability-ci/elastic-agent:%s"
+
+	// edgePlatforms are the platforms of the embedded devices, the agent is only packaged as a
+	// tar.gz with the components built for them.
+	edgePlatforms = "linux/armv7 linux/mips linux/mipsle linux/mips64le"
 )
 
 // Aliases for commands required by master makefile
 var Aliases = map[string]interface{}{
-	"build": Build.All,
-	"demo":  Demo.Enroll,
+	"build":              Build.All,
+	"demo":               Demo.Enroll,
+	"package:containers": PackageContainers,
+	"package:edge":       PackageEdge,
 }
 
 func init() {
@@ -334,6 +341,33 @@ func (Test) Unit(ctx context.Context) error {
 	return devtools.GoTest(ctx, params)
 }
 
+// Fuzz runs each fuzz target for FUZZ_TIME (default 30s), FUZZ_TARGET restricts the fuzz targets run. The
+// failing inputs are collected in build/fuzz/crashers.
+func (Test) Fuzz(ctx context.Context) error {
+	mg.Deps(Prepare.Env)
+	return devtools.GoFuzz(ctx, devtools.DefaultGoFuzzArgs())
+}
+
+// UpdateGolden regenerates the golden files of the component model regression tests, review the changes before
+// committing them.
+func (Test) UpdateGolden() error {
+	results, err := golden.Run(golden.Options{
+		FixturesDir: filepath.Join("pkg", "component", "golden", "testdata"),
+		SpecsDir:    "specs",
+		Update:      true,
+	})
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("failed to update golden file of %s: %w", result.Name, result.Err)
+		}
+		fmt.Printf(">> updated %s%s\n", result.Name, golden.GoldenSuffix)
+	}
+	return nil
+}
+
 // Coverage takes the coverages report from running all the tests and display the results in the browser.
 func (Test) Coverage() error {
 	mg.Deps(Prepare.Env, Build.TestBinaries)
@@ -394,6 +428,55 @@ func Package() {
 	packageAgent(platforms, devtools.UseElasticAgentPackaging)
 }
 
+// PackageContainers packages the multi-arch (linux/amd64 and linux/arm64) docker images
+// of all the variants with buildx, including their SBOMs and provenance attestations.
+// Use SNAPSHOT=true to build snapshots.
+func PackageContainers() {
+	platforms := os.Getenv(platformsEnv)
+	defer os.Setenv(platformsEnv, platforms)
+
+	packages := os.Getenv(packagesEnv)
+	defer os.Setenv(packagesEnv, packages)
+
+	os.Setenv(platformsEnv, "linux/amd64 linux/arm64")
+	os.Setenv(packagesEnv, "docker")
+
+	devtools.DockerBuildx = true
+	devtools.Platforms = devtools.NewPlatformList("linux/amd64 linux/arm64")
+	devtools.SelectedPackageTypes = []devtools.PackageType{devtools.Docker}
+
+	Package()
+}
+
+// PackageEdge packages the tar.gz of the reduced agent of the embedded devices (linux/armv7 and
+// linux/mips*), bundling only the components built for them.
+// Use SNAPSHOT=true to build snapshots.
+func PackageEdge() {
+	platforms := os.Getenv(platformsEnv)
+	defer os.Setenv(platformsEnv, platforms)
+
+	packages := os.Getenv(packagesEnv)
+	defer os.Setenv(packagesEnv, packages)
+
+	os.Setenv(platformsEnv, edgePlatforms)
+	os.Setenv(packagesEnv, "tar.gz")
+
+	devtools.Platforms = devtools.NewPlatformList(edgePlatforms)
+	devtools.SelectedPackageTypes = []devtools.PackageType{devtools.TarGz}
+
+	Package()
+}
+
+// isEdgePlatform returns true when the platform is the one of an embedded device.
+func isEdgePlatform(platform string) bool {
+	for _, p := range strings.Fields(edgePlatforms) {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
 func getPackageName(beat, version, pkg string) (string, string) {
 	if _, ok := os.LookupEnv(snapshotEnv); ok {
 		version += "-SNAPSHOT"
@@ -458,6 +541,11 @@ func CrossBuild() error {
 	return devtools.CrossBuild()
 }
 
+// SBOM generates the CycloneDX SBOMs of the cross-built binaries, the packages ship them.
+func SBOM() error {
+	return devtools.CrossBuildSBOMs()
+}
+
 // CrossBuildGoDaemon cross-builds the go-daemon binary using Docker.
 func CrossBuildGoDaemon() error {
 	return devtools.CrossBuildGoDaemon()
@@ -470,6 +558,7 @@ func PackageAgentCore() {
 	defer func() { fmt.Println("packageAgentCore ran for", time.Since(start)) }()
 
 	mg.Deps(CrossBuild, CrossBuildGoDaemon)
+	mg.Deps(SBOM)
 
 	devtools.UseElasticAgentCorePackaging()
 
@@ -768,6 +857,11 @@ func packageAgent(platforms []string, packagingFn func()) {
 		"linux/amd64":   "linux-x86_64.tar.gz",
 		"linux/arm64":   "linux-arm64.tar.gz",
 		"windows/amd64": "windows-x86_64.zip",
+		// the embedded devices
+		"linux/armv7":    "linux-armv7.tar.gz",
+		"linux/mips":     "linux-mips.tar.gz",
+		"linux/mipsle":   "linux-mipsle.tar.gz",
+		"linux/mips64le": "linux-mips64le.tar.gz",
 	}
 
 	requiredPackages := []string{}
@@ -806,9 +900,15 @@ func packageAgent(platforms []string, packagingFn func()) {
 				"pf-host-agent",
 			}
 
+			// the embedded devices only run the data collection of the Beats
+			edgeBinaries := map[string]bool{"filebeat": true, "metricbeat": true}
+
 			ctx := context.Background()
 			for _, binary := range externalBinaries {
 				for _, platform := range platforms {
+					if isEdgePlatform(platform) && !edgeBinaries[binary] {
+						continue
+					}
 					reqPackage := platformPackages[platform]
 					targetPath := filepath.Join(archivePath, reqPackage)
 					os.MkdirAll(targetPath, 0755)
@@ -965,6 +1065,7 @@ func packageAgent(platforms []string, packagingFn func()) {
 
 	mg.Deps(Update)
 	mg.Deps(CrossBuild, CrossBuildGoDaemon)
+	mg.Deps(SBOM)
 	mg.SerialDeps(devtools.Package, TestPackages)
 }
 
@@ -1380,6 +1481,14 @@ func (Integration) Single(ctx context.Context, testName string) error {
 	return integRunner(ctx, false, testName)
 }
 
+// run the end-to-end upgrade test on remote host, it upgrades the previous released version to the
+// local build standalone, enrolled into a mock Fleet Server and with a rollback. The diagnostics of the
+// upgraded agents are archived in build/diagnostics on the remote host.
+func (Integration) Upgrade(ctx context.Context) error {
+	mg.CtxDeps(ctx, Integration.Clean)
+	return integRunner(ctx, false, "TestUpgradeEndToEnd")
+}
+
 // don't call locally (called on remote host to prepare it for testing)
 func (Integration) PrepareOnRemote() {
 	mg.Deps(mage.InstallGoTestTools)
@@ -1527,17 +1636,15 @@ func createTestRunner(matrix bool, singleTest string, batches ...define.Batch) (
 	if !ok {
 		return nil, fmt.Errorf("ESS api key missing; run 'mage integration:auth'")
 	}
-	serviceTokenPath, ok, err := getGCEServiceTokenPath()
+	provisioner := os.Getenv("TEST_INTEG_PROVISIONER")
+	gceConfig, err := getGCEConfig()
 	if err != nil {
 		return nil, err
 	}
-	if !ok {
+	awsConfig := getAWSConfig()
+	if provisioner != runner.VagrantProvisioner && gceConfig == nil && awsConfig == nil {
 		return nil, fmt.Errorf("GCE service token missing; run 'mage integration:auth'")
 	}
-	datacenter := os.Getenv("TEST_INTEG_AUTH_GCP_DATACENTER")
-	if datacenter == "" {
-		datacenter = "us-central1-a"
-	}
 	essRegion := os.Getenv("TEST_INTEG_AUTH_ESS_REGION")
 	if essRegion == "" {
 		essRegion = "gcp-us-central1"
@@ -1553,10 +1660,9 @@ func createTestRunner(matrix bool, singleTest string, batches ...define.Batch) (
 			APIKey: essToken,
 			Region: essRegion,
 		},
-		GCE: &runner.GCEConfig{
-			ServiceTokenPath: serviceTokenPath,
-			Datacenter:       datacenter,
-		},
+		GCE:         gceConfig,
+		AWS:         awsConfig,
+		Provisioner: provisioner,
 		Matrix:      matrix,
 		SingleTest:  singleTest,
 		VerboseMode: mg.Verbose(),
@@ -1568,6 +1674,44 @@ func createTestRunner(matrix bool, singleTest string, batches ...define.Batch) (
 	return r, nil
 }
 
+// getGCEConfig returns the configuration of GCE, nil when the service token is missing.
+func getGCEConfig() (*runner.GCEConfig, error) {
+	serviceTokenPath, ok, err := getGCEServiceTokenPath()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	datacenter := os.Getenv("TEST_INTEG_AUTH_GCP_DATACENTER")
+	if datacenter == "" {
+		datacenter = "us-central1-a"
+	}
+	return &runner.GCEConfig{
+		ServiceTokenPath: serviceTokenPath,
+		Datacenter:       datacenter,
+	}, nil
+}
+
+// getAWSConfig returns the configuration of AWS from the standard environment variables of the AWS
+// CLI, nil when the credentials are missing.
+func getAWSConfig() *runner.AWSConfig {
+	accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &runner.AWSConfig{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Region:          region,
+	}
+}
+
 func shouldBuildAgent() bool {
 	build := os.Getenv("BUILD_AGENT")
 	if build == "" {
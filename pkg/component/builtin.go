@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package component
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BuiltinBinaryName is the binary name of the inputs run in-process in Elastic Agent.
+const BuiltinBinaryName = "elastic-agent"
+
+var (
+	builtinMx     sync.RWMutex
+	builtinInputs = make(map[string]InputSpec)
+)
+
+// RegisterBuiltinInput registers the specification of an input run in-process in Elastic Agent, it
+// is loaded with the specifications of the components directory.
+func RegisterBuiltinInput(spec InputSpec) error {
+	if spec.Builtin == nil {
+		return fmt.Errorf("input '%s' must define builtin", spec.Name)
+	}
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	builtin := *spec.Builtin
+	if builtin.Timeouts == (CommandTimeoutSpec{}) {
+		builtin.Timeouts.InitDefaults()
+	}
+	spec.Builtin = &builtin
+	builtinMx.Lock()
+	defer builtinMx.Unlock()
+	if _, exists := builtinInputs[spec.Name]; exists {
+		return fmt.Errorf("builtin input '%s' is already registered", spec.Name)
+	}
+	builtinInputs[spec.Name] = spec
+	return nil
+}
+
+// builtinSpecs returns the specifications of the builtin inputs.
+func builtinSpecs() []InputSpec {
+	builtinMx.RLock()
+	defer builtinMx.RUnlock()
+	specs := make([]InputSpec, 0, len(builtinInputs))
+	for _, spec := range builtinInputs {
+		specs = append(specs, spec)
+	}
+	return specs
+}
@@ -113,6 +113,11 @@ type Component struct {
 	// ShipperRef references the component/unit that this component used as its output.
 	// (only applies to inputs targeting a shipper, not set when ShipperSpec is)
 	ShipperRef *ShipperReference `yaml:"shipper,omitempty"`
+
+	// DependsOn are the input types of the components that must be healthy before
+	// this component is started, and that are stopped only after this component.
+	// (combines the dependencies of the input specification and of the policy inputs)
+	DependsOn []string `yaml:"depends_on,omitempty"`
 }
 
 // Type returns the type of the component.
@@ -143,7 +148,9 @@ func (r *RuntimeSpecs) ToComponents(
 		// binary name
 		binaryMapping := make(map[string]string)
 		for _, component := range components {
-			if spec := component.InputSpec; spec != nil {
+			// the builtin inputs run in-process, they are monitored with Elastic Agent, and the
+			// OpenTelemetry Collector has its own telemetry
+			if spec := component.InputSpec; spec != nil && spec.Spec.Builtin == nil && spec.Spec.OTel == nil {
 				binaryMapping[component.ID] = spec.BinaryName
 			}
 		}
@@ -177,8 +184,18 @@ func unitForInput(input inputI, id string) Unit {
 	}
 }
 
-func unitForOutput(output outputI, id string) Unit {
-	cfg, cfgErr := ExpectedConfig(output.config)
+// unitForOutput creates the output unit of a component, the APM instrumentation
+// is added to its configuration when not nil.
+func unitForOutput(output outputI, id string, instrumentation map[string]interface{}) Unit {
+	outputCfg := output.config
+	if instrumentation != nil {
+		outputCfg = make(map[string]interface{}, len(output.config)+1)
+		for k, v := range output.config {
+			outputCfg[k] = v
+		}
+		outputCfg[instrumentationKey] = instrumentation
+	}
+	cfg, cfgErr := ExpectedConfig(outputCfg)
 	return Unit{
 		ID:       id,
 		Type:     client.UnitTypeOutput,
@@ -208,6 +225,7 @@ func (r *RuntimeSpecs) componentForInputType(
 	inputType string,
 	output outputI,
 	featureFlags *features.Flags,
+	instrumentation map[string]interface{},
 ) Component {
 	componentID := fmt.Sprintf("%s-%s", inputType, output.name)
 
@@ -248,10 +266,19 @@ func (r *RuntimeSpecs) componentForInputType(
 	// indicate that it can't be run.
 
 	var units []Unit
+	dependsOn := appendMissingStr(nil, inputSpec.Spec.DependsOn...)
 	for _, input := range output.inputs[inputType] {
 		if input.enabled {
 			unitID := fmt.Sprintf("%s-%s", componentID, input.id)
 			units = append(units, unitForInput(input, unitID))
+			dependsOn = appendMissingStr(dependsOn, input.dependsOn...)
+		}
+	}
+	for i, dep := range dependsOn {
+		if dep == inputType {
+			// an input type cannot depend on itself
+			dependsOn = append(dependsOn[:i], dependsOn[i+1:]...)
+			break
 		}
 	}
 	if len(units) > 0 {
@@ -263,7 +290,11 @@ func (r *RuntimeSpecs) componentForInputType(
 					unitForShipperOutput(output, componentID, shipperRef.ShipperType))
 			}
 		} else {
-			units = append(units, unitForOutput(output, componentID))
+			if inputSpec.Spec.Command == nil || !inputSpec.Spec.Command.Instrumentation {
+				// the component does not support the instrumentation
+				instrumentation = nil
+			}
+			units = append(units, unitForOutput(output, componentID, instrumentation))
 		}
 	}
 	return Component{
@@ -275,10 +306,11 @@ func (r *RuntimeSpecs) componentForInputType(
 		Units:      units,
 		Features:   featureFlags.AsProto(),
 		ShipperRef: shipperRef,
+		DependsOn:  dependsOn,
 	}
 }
 
-func (r *RuntimeSpecs) componentsForOutput(output outputI, featureFlags *features.Flags) []Component {
+func (r *RuntimeSpecs) componentsForOutput(output outputI, featureFlags *features.Flags, instrumentation map[string]interface{}) []Component {
 	var components []Component
 	shipperTypes := make(map[string]bool)
 	for inputType := range output.inputs {
@@ -287,7 +319,7 @@ func (r *RuntimeSpecs) componentsForOutput(output outputI, featureFlags *feature
 		// from running then it will be in the Component's Err field and
 		// we will report it later. The only thing we skip is a component
 		// with no units.
-		component := r.componentForInputType(inputType, output, featureFlags)
+		component := r.componentForInputType(inputType, output, featureFlags, instrumentation)
 		if len(component.Units) > 0 {
 			if component.ShipperRef != nil {
 				// If this component uses a shipper, mark that shipper type as active
@@ -370,12 +402,23 @@ func (r *RuntimeSpecs) PolicyToComponents(
 		return nil, fmt.Errorf("could not parse feature flags from policy: %w", err)
 	}
 
+	instrumentation, err := instrumentationFromPolicy(policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse APM instrumentation from policy: %w", err)
+	}
+
+	// the OpenTelemetry Collector pipelines do not use the outputs of the policy
+	var otelComponents []Component
+	if comp := r.componentForOTel(policy, ll, featureFlags); comp != nil {
+		otelComponents = append(otelComponents, *comp)
+	}
+
 	outputsMap, err := toIntermediate(policy, r.aliasMapping, ll, headers)
 	if err != nil {
 		return nil, err
 	}
 	if outputsMap == nil {
-		return nil, nil
+		return otelComponents, nil
 	}
 
 	// order output keys; ensures result is always the same order
@@ -390,11 +433,11 @@ func (r *RuntimeSpecs) PolicyToComponents(
 		output := outputsMap[outputName]
 		if output.enabled {
 			components = append(components,
-				r.componentsForOutput(output, featureFlags)...)
+				r.componentsForOutput(output, featureFlags, instrumentation)...)
 		}
 	}
 
-	return components, nil
+	return append(components, otelComponents...), nil
 }
 
 func componentToShipperConfig(shipperType string, comp Component) (*proto.UnitExpectedConfig, error) {
@@ -499,6 +542,7 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 		idKey        = "id"
 		useOutputKey = "use_output"
 		shipperKey   = "shipper"
+		dependsOnKey = "depends_on"
 	)
 
 	// intermediate structure for output to input mapping (this structure allows different input types per output)
@@ -654,6 +698,24 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 		if err != nil {
 			return nil, fmt.Errorf("invalid 'inputs.%d.log_level', %w", idx, err)
 		}
+		var dependsOn []string
+		if dependsOnRaw, ok := input[dependsOnKey]; ok {
+			dependsOnVal, ok := dependsOnRaw.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid 'inputs.%d.depends_on', expected an array not a %T", idx, dependsOnRaw)
+			}
+			for i, depRaw := range dependsOnVal {
+				dep, ok := depRaw.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid 'inputs.%d.depends_on.%d', expected a string not a %T", idx, i, depRaw)
+				}
+				if realInputType, found := aliasMapping[dep]; found {
+					dep = realInputType
+				}
+				dependsOn = append(dependsOn, dep)
+			}
+			delete(input, dependsOnKey)
+		}
 
 		// Inject the top level fleet policy revision into each input configuration. This
 		// allows individual inputs (like endpoint) to detect policy changes more easily.
@@ -665,6 +727,7 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 			enabled:   enabled,
 			logLevel:  logLevel,
 			inputType: t,
+			dependsOn: dependsOn,
 			config:    input,
 		})
 	}
@@ -679,10 +742,11 @@ type inputI struct {
 	id        string
 	enabled   bool
 	logLevel  client.UnitLogLevel
-	inputType string // canonical (non-alias) type
+	inputType string   // canonical (non-alias) type
+	dependsOn []string // canonical (non-alias) types
 
 	// The raw configuration for this input, with small cleanups:
-	// - the "enabled", "use_output", and "log_level" keys are removed
+	// - the "enabled", "use_output", "depends_on" and "log_level" keys are removed
 	// - the key "policy.revision" is set to the current fleet policy revision
 	config map[string]interface{}
 }
@@ -723,6 +787,7 @@ func varsForPlatform(platform PlatformDetail) (*transpiler.Vars, error) {
 			"platform": platform.String(),
 			"os":       platform.OS,
 			"arch":     platform.Arch,
+			"edge":     platform.Edge(),
 			"family":   platform.Family,
 			"major":    platform.Major,
 			"minor":    platform.Minor,
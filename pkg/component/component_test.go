@@ -289,6 +289,26 @@ func TestToComponents(t *testing.T) {
 			},
 			Err: "invalid 'inputs.0.enabled', expected a bool not a string",
 		},
+		{
+			Name:     "Invalid: inputs entry depends_on not an array",
+			Platform: linuxAMD64Platform,
+			Policy: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":    "elasticsearch",
+						"enabled": true,
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type":       "filestream",
+						"id":         "filestream-0",
+						"depends_on": "log",
+					},
+				},
+			},
+			Err: "invalid 'inputs.0.depends_on', expected an array not a string",
+		},
 		{
 			Name:     "Invalid: inputs unknown type",
 			Platform: linuxAMD64Platform,
@@ -603,6 +623,56 @@ func TestToComponents(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:     "Input dependencies",
+			Platform: linuxAMD64Platform,
+			Policy: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":    "elasticsearch",
+						"enabled": true,
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type":       "filestream",
+						"id":         "filestream-0",
+						"depends_on": []interface{}{"logfile", "filestream"},
+					},
+				},
+			},
+			Result: []Component{
+				{
+					InputType:  "filestream",
+					OutputType: "elasticsearch",
+					InputSpec: &InputRuntimeSpec{
+						InputType:  "filestream",
+						BinaryName: "filebeat",
+						BinaryPath: filepath.Join("..", "..", "specs", "filebeat"),
+					},
+					DependsOn: []string{"log"},
+					Units: []Unit{
+						{
+							ID:       "filestream-default",
+							Type:     client.UnitTypeOutput,
+							LogLevel: defaultUnitLogLevel,
+							Config: MustExpectedConfig(map[string]interface{}{
+								"type": "elasticsearch",
+							}),
+						},
+						{
+							ID:       "filestream-default-filestream-0",
+							Type:     client.UnitTypeInput,
+							LogLevel: defaultUnitLogLevel,
+							Config: MustExpectedConfig(map[string]interface{}{
+								"type": "filestream",
+								"id":   "filestream-0",
+							}),
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:     "Debug log level",
 			Platform: linuxAMD64Platform,
@@ -1874,6 +1944,100 @@ func TestToComponents(t *testing.T) {
 				"header-one": "val-1",
 			}},
 		},
+		{
+			Name:     "OpenTelemetry Collector pipelines",
+			Platform: linuxAMD64Platform,
+			Policy: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{},
+				},
+				"exporters": map[string]interface{}{
+					"logging": map[string]interface{}{},
+				},
+				"service": map[string]interface{}{
+					"pipelines": map[string]interface{}{
+						"traces": map[string]interface{}{
+							"receivers": []interface{}{"otlp"},
+							"exporters": []interface{}{"logging"},
+						},
+					},
+				},
+			},
+			Result: []Component{
+				{
+					ID:        "otel",
+					InputType: "otel",
+					InputSpec: &InputRuntimeSpec{
+						InputType:  "otel",
+						BinaryName: "otelcol",
+						BinaryPath: filepath.Join("..", "..", "specs", "otelcol"),
+					},
+					Units: []Unit{
+						{
+							ID:       "otel",
+							Type:     client.UnitTypeInput,
+							LogLevel: defaultUnitLogLevel,
+							Config: MustExpectedConfig(map[string]interface{}{
+								"type": "otel",
+								"id":   "otel",
+								"receivers": map[string]interface{}{
+									"otlp": map[string]interface{}{},
+								},
+								"exporters": map[string]interface{}{
+									"logging": map[string]interface{}{},
+								},
+								"service": map[string]interface{}{
+									"pipelines": map[string]interface{}{
+										"traces": map[string]interface{}{
+											"receivers": []interface{}{"otlp"},
+											"exporters": []interface{}{"logging"},
+										},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:     "Invalid: OpenTelemetry Collector pipelines without service.pipelines",
+			Platform: linuxAMD64Platform,
+			Policy: map[string]interface{}{
+				"receivers": map[string]interface{}{
+					"otlp": map[string]interface{}{},
+				},
+				"exporters": map[string]interface{}{
+					"logging": map[string]interface{}{},
+				},
+				"service": map[string]interface{}{},
+			},
+			Result: []Component{
+				{
+					ID:        "otel",
+					InputType: "otel",
+					Err:       errors.New("the OpenTelemetry Collector pipelines must define service.pipelines"),
+					Units: []Unit{
+						{
+							ID:       "otel",
+							Type:     client.UnitTypeInput,
+							LogLevel: defaultUnitLogLevel,
+							Config: MustExpectedConfig(map[string]interface{}{
+								"type": "otel",
+								"id":   "otel",
+								"receivers": map[string]interface{}{
+									"otlp": map[string]interface{}{},
+								},
+								"exporters": map[string]interface{}{
+									"logging": map[string]interface{}{},
+								},
+								"service": map[string]interface{}{},
+							}),
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -1899,6 +2063,7 @@ func TestToComponents(t *testing.T) {
 					}
 					assert.Equal(t, expected.InputType, actual.InputType, "%q: component %q has wrong input type", scenario.Name, actual.ID)
 					assert.Equal(t, expected.OutputType, actual.OutputType, "%q: component %q has wrong output type", scenario.Name, actual.ID)
+					assert.Equal(t, expected.DependsOn, actual.DependsOn, "%q: component %q has wrong dependencies", scenario.Name, actual.ID)
 					if expected.InputSpec != nil {
 						assert.Nil(t, actual.ShipperSpec)
 						assert.Equal(t, expected.InputSpec.InputType, actual.InputSpec.InputType)
@@ -2090,6 +2255,106 @@ func TestInjectingInputPolicyID(t *testing.T) {
 	}
 }
 
+func TestToComponentsInstrumentation(t *testing.T) {
+	linuxAMD64Platform := PlatformDetail{
+		Platform: Platform{
+			OS:   Linux,
+			Arch: AMD64,
+			GOOS: Linux,
+		},
+	}
+	runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+	require.NoError(t, err)
+
+	policy := func(monitoring map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"agent": map[string]interface{}{
+				"monitoring": monitoring,
+			},
+			"outputs": map[string]interface{}{
+				"default": map[string]interface{}{
+					"type": "elasticsearch",
+				},
+			},
+			"inputs": []interface{}{
+				map[string]interface{}{
+					"type": "filestream",
+					"id":   "filestream-0",
+				},
+				map[string]interface{}{
+					"type": "apm",
+					"id":   "apm-0",
+				},
+			},
+		}
+	}
+
+	t.Run("traces disabled", func(t *testing.T) {
+		result, err := runtime.ToComponents(policy(map[string]interface{}{
+			"traces": false,
+			"apm": map[string]interface{}{
+				"hosts": []interface{}{"https://apm.example.com:8200"},
+			},
+		}), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		for _, comp := range result {
+			for _, unit := range comp.Units {
+				if unit.Type == client.UnitTypeOutput {
+					assert.NotContains(t, unit.Config.Source.AsMap(), instrumentationKey, "component %q", comp.ID)
+				}
+			}
+		}
+	})
+
+	t.Run("traces enabled", func(t *testing.T) {
+		result, err := runtime.ToComponents(policy(map[string]interface{}{
+			"traces": true,
+			"apm": map[string]interface{}{
+				"hosts":         []interface{}{"https://apm.example.com:8200"},
+				"environment":   "production",
+				"api_key":       "key",
+				"sampling_rate": 0.5,
+				"tls": map[string]interface{}{
+					"skip_verify": true,
+				},
+			},
+		}), nil, logp.InfoLevel, nil)
+		require.NoError(t, err)
+		require.Len(t, result, 2)
+		sortComponents(result)
+
+		// apm-server does not support the instrumentation
+		require.Equal(t, "apm-default", result[0].ID)
+		require.Equal(t, client.UnitTypeOutput, result[0].Units[0].Type)
+		assert.NotContains(t, result[0].Units[0].Config.Source.AsMap(), instrumentationKey)
+
+		require.Equal(t, "filestream-default", result[1].ID)
+		require.Equal(t, client.UnitTypeOutput, result[1].Units[0].Type)
+		assert.Equal(t, map[string]interface{}{
+			"enabled":       true,
+			"hosts":         []interface{}{"https://apm.example.com:8200"},
+			"environment":   "production",
+			"api_key":       "key",
+			"sampling_rate": 0.5,
+			"tls": map[string]interface{}{
+				"skip_verify": true,
+			},
+		}, result[1].Units[0].Config.Source.AsMap()[instrumentationKey])
+	})
+
+	t.Run("invalid sampling rate", func(t *testing.T) {
+		_, err := runtime.ToComponents(policy(map[string]interface{}{
+			"traces": true,
+			"apm": map[string]interface{}{
+				"hosts":         []interface{}{"https://apm.example.com:8200"},
+				"sampling_rate": 2,
+			},
+		}), nil, logp.InfoLevel, nil)
+		assert.ErrorContains(t, err, "sampling rate 2 is not between 0 and 1")
+	})
+}
+
 func assertEqualUnitExpectedConfigs(t *testing.T, expected *Unit, actual *Unit) {
 	t.Helper()
 	assert.Equal(t, expected.ID, actual.ID)
@@ -57,14 +57,16 @@ func ExpectedConfig(cfg map[string]interface{}) (*proto.UnitExpectedConfig, erro
 		return nil, rewrapErr(err)
 	}
 
-	if err := setSource(result, cfg); err != nil {
+	if err := setSource(result, cfg, nil); err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
 
-func setSource(val interface{}, cfg map[string]interface{}) error {
+// setSource sets the source fields of val from cfg. The source of a nested field is the
+// matching value of the source of its parent, so each part of cfg is only converted once.
+func setSource(val interface{}, cfg map[string]interface{}, source *structpb.Struct) error {
 	// find the source field on the val
 	resVal := reflect.ValueOf(val).Elem()
 	sourceFieldByTag, ok := getSourceField(resVal.Type())
@@ -77,9 +79,12 @@ func setSource(val interface{}, cfg map[string]interface{}) error {
 	}
 
 	// create the source (as the original source is always sent)
-	source, err := structpb.NewStruct(cfg)
-	if err != nil {
-		return err
+	if source == nil {
+		var err error
+		source, err = structpb.NewStruct(cfg)
+		if err != nil {
+			return err
+		}
 	}
 	sourceField.Set(reflect.ValueOf(source))
 
@@ -105,7 +110,7 @@ func setSource(val interface{}, cfg map[string]interface{}) error {
 		case reflect.Ptr:
 			cfgDict, ok := cfgVal.(map[string]interface{})
 			if ok && hasSourceField(valType.Elem()) {
-				err := setSource(valField.Interface(), cfgDict)
+				err := setSource(valField.Interface(), cfgDict, source.Fields[jsonName].GetStructValue())
 				if err != nil {
 					return fmt.Errorf("setting source for field %s failed: %w", jsonName, err)
 				}
@@ -113,12 +118,17 @@ func setSource(val interface{}, cfg map[string]interface{}) error {
 		case reflect.Slice:
 			cfgSlice, ok := cfgVal.([]interface{})
 			if ok {
+				sourceList := source.Fields[jsonName].GetListValue().GetValues()
 				valElem := reflect.ValueOf(valField.Interface())
 				for j := 0; j < valElem.Len(); j++ {
 					valIdx := valElem.Index(j)
 					cfgDict, ok := cfgSlice[j].(map[string]interface{})
 					if ok && hasSourceField(valIdx.Elem().Type()) {
-						err := setSource(valIdx.Interface(), cfgDict)
+						var itemSource *structpb.Struct
+						if j < len(sourceList) {
+							itemSource = sourceList[j].GetStructValue()
+						}
+						err := setSource(valIdx.Interface(), cfgDict, itemSource)
 						if err != nil {
 							return fmt.Errorf("setting source for field %s.%d failed: %w", jsonName, j, err)
 						}
@@ -202,3 +202,28 @@ func TestExpectedConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestExpectedConfigSharesSources(t *testing.T) {
+	observed, err := ExpectedConfig(map[string]interface{}{
+		"id":   "simple-0",
+		"type": "simple",
+		"data_stream": map[string]interface{}{
+			"dataset": "other",
+		},
+		"streams": []interface{}{
+			map[string]interface{}{
+				"id": "simple-stream-0",
+				"data_stream": map[string]interface{}{
+					"dataset": "other",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// the nested sources are the values of the source of the unit, not copies
+	assert.Same(t, observed.Source.Fields["data_stream"].GetStructValue(), observed.DataStream.Source)
+	stream := observed.Source.Fields["streams"].GetListValue().Values[0].GetStructValue()
+	assert.Same(t, stream, observed.Streams[0].Source)
+	assert.Same(t, stream.Fields["data_stream"].GetStructValue(), observed.Streams[0].DataStream.Source)
+}
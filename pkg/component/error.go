@@ -7,6 +7,9 @@ package component
 // ErrorReason is an error that can be marshalled/unmarshalled to and from YAML.
 type ErrorReason struct {
 	Reason string
+
+	// err is the error the reason details, it is lost when marshalled.
+	err error
 }
 
 func newError(reason string) error {
@@ -17,6 +20,10 @@ func (e *ErrorReason) Error() string {
 	return e.Reason
 }
 
+func (e *ErrorReason) Unwrap() error {
+	return e.err
+}
+
 func (e *ErrorReason) MarshalYAML() (interface{}, error) {
 	return e.Reason, nil
 }
@@ -0,0 +1,281 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package golden is a regression test harness for the conversion of policies to the component model.
+//
+// A fixture is a policy file named <name>.policy.yml with an optional <name>.vars.json holding the
+// variables its inputs are rendered with, in the format of `elastic-agent inspect components --vars`.
+// Each fixture is converted to its component model and compared to the golden file <name>.golden.yml,
+// any difference is reported. The golden files are written instead of compared when updating.
+//
+// The fixtures are converted for a fixed platform, but the runtime preventions on ${user.root} still
+// depend on the user running the harness, fixtures should not use the inputs with such preventions.
+package golden
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+const (
+	// PolicySuffix is the suffix of the policy file of a fixture.
+	PolicySuffix = ".policy.yml"
+	// VarsSuffix is the suffix of the optional variables file of a fixture.
+	VarsSuffix = ".vars.json"
+	// GoldenSuffix is the suffix of the golden file of a fixture.
+	GoldenSuffix = ".golden.yml"
+
+	// UpdateEnv is the environment variable that makes Test update the golden files.
+	UpdateEnv = "UPDATE_GOLDEN"
+)
+
+// DefaultPlatform is the platform the fixtures are converted for when none is given, so the result
+// doesn't depend on the host running the harness.
+var DefaultPlatform = component.PlatformDetail{
+	Platform: component.Platform{
+		OS:   component.Linux,
+		Arch: component.AMD64,
+		GOOS: component.Linux,
+	},
+	Family: "debian",
+	Major:  "12",
+	Minor:  "0",
+}
+
+// Options are the options of a harness run.
+type Options struct {
+	// FixturesDir is the directory with the fixtures and their golden files.
+	FixturesDir string
+	// SpecsDir is the directory with the component specifications.
+	SpecsDir string
+	// Platform is the platform the fixtures are converted for, DefaultPlatform when empty.
+	Platform component.PlatformDetail
+	// Update writes the golden files instead of comparing them.
+	Update bool
+}
+
+// Result is the result of a fixture.
+type Result struct {
+	// Name is the name of the fixture.
+	Name string
+	// Diff is the difference between the golden file and the component model, empty when they match.
+	Diff string
+	// Err is set when the fixture could not be converted or its golden file could not be read or written.
+	Err error
+}
+
+// Failed returns true when the fixture didn't match its golden file.
+func (r Result) Failed() bool {
+	return r.Err != nil || r.Diff != ""
+}
+
+// Test runs the harness as a Go test with a subtest per fixture. The golden files are updated when the
+// UpdateEnv environment variable is true.
+func Test(t *testing.T, opts Options) {
+	t.Helper()
+	if update, _ := strconv.ParseBool(os.Getenv(UpdateEnv)); update {
+		opts.Update = true
+	}
+	results, err := Run(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		result := result
+		t.Run(result.Name, func(t *testing.T) {
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			if result.Diff != "" {
+				t.Errorf("component model differs from %s%s (-golden +actual):\n%s", result.Name, GoldenSuffix, result.Diff)
+			}
+		})
+	}
+}
+
+// Run converts all the fixtures and compares them to their golden files.
+func Run(opts Options) ([]Result, error) {
+	platform := opts.Platform
+	if platform.OS == "" {
+		platform = DefaultPlatform
+	}
+	specs, err := component.LoadRuntimeSpecs(opts.SpecsDir, platform, component.SkipBinaryCheck())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load component specifications: %w", err)
+	}
+
+	policies, err := filepath.Glob(filepath.Join(opts.FixturesDir, "*"+PolicySuffix))
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("no fixtures in %s", opts.FixturesDir)
+	}
+	sort.Strings(policies)
+
+	results := make([]Result, 0, len(policies))
+	for _, policyPath := range policies {
+		name := strings.TrimSuffix(filepath.Base(policyPath), PolicySuffix)
+		result := Result{Name: name}
+		result.Diff, result.Err = runFixture(specs, filepath.Join(opts.FixturesDir, name), opts.Update)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func runFixture(specs component.RuntimeSpecs, path string, update bool) (string, error) {
+	policy, err := os.ReadFile(path + PolicySuffix)
+	if err != nil {
+		return "", err
+	}
+	vars, err := os.ReadFile(path + VarsSuffix)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	actual, err := Transpile(specs, policy, vars)
+	if err != nil {
+		return "", err
+	}
+
+	goldenPath := path + GoldenSuffix
+	if update {
+		return "", os.WriteFile(goldenPath, actual, 0644)
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read golden file (update the golden files to create it): %w", err)
+	}
+	return cmp.Diff(string(expected), string(actual)), nil
+}
+
+// Transpile converts the policy to its component model, its inputs are rendered with the variables when
+// vars is not empty. The model is returned in the YAML format of the golden files.
+func Transpile(specs component.RuntimeSpecs, policy []byte, vars []byte) ([]byte, error) {
+	cfg, err := config.NewConfigFrom(string(policy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	agentCfg, err := configuration.NewFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse agent settings of policy: %w", err)
+	}
+	lvl := logp.InfoLevel
+	if agentCfg.Settings.LoggingConfig != nil {
+		lvl = agentCfg.Settings.LoggingConfig.Level
+	}
+	m, err := cfg.ToMapStr()
+	if err != nil {
+		return nil, err
+	}
+
+	varsArray := []*transpiler.Vars{}
+	if len(vars) > 0 {
+		varsArray, err = transpiler.NewVarsFromJSON(vars)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse variables: %w", err)
+		}
+	} else {
+		empty, err := transpiler.NewVars("", map[string]interface{}{}, nil)
+		if err != nil {
+			return nil, err
+		}
+		varsArray = append(varsArray, empty)
+	}
+	m, err = transpiler.RenderPolicyInputs(m, varsArray)
+	if err != nil {
+		return nil, err
+	}
+
+	comps, err := specs.PolicyToComponents(m, lvl, nil)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(toModel(comps))
+}
+
+// model is the stable representation of the components in the golden files, it leaves out what
+// depends on the host like the path of the binaries.
+type model struct {
+	Components []modelComponent `yaml:"components"`
+}
+
+type modelComponent struct {
+	ID          string                      `yaml:"id"`
+	Error       string                      `yaml:"error,omitempty"`
+	InputType   string                      `yaml:"input_type,omitempty"`
+	OutputType  string                      `yaml:"output_type"`
+	Binary      string                      `yaml:"binary,omitempty"`
+	ShipperType string                      `yaml:"shipper_type,omitempty"`
+	ShipperRef  *component.ShipperReference `yaml:"shipper,omitempty"`
+	DependsOn   []string                    `yaml:"depends_on,omitempty"`
+	Units       []modelUnit                 `yaml:"units"`
+}
+
+type modelUnit struct {
+	ID       string                 `yaml:"id"`
+	Type     string                 `yaml:"type"`
+	LogLevel string                 `yaml:"log_level"`
+	Error    string                 `yaml:"error,omitempty"`
+	Config   map[string]interface{} `yaml:"config,omitempty"`
+}
+
+func toModel(comps []component.Component) model {
+	var m model
+	for _, comp := range comps {
+		c := modelComponent{
+			ID:         comp.ID,
+			InputType:  comp.InputType,
+			OutputType: comp.OutputType,
+			ShipperRef: comp.ShipperRef,
+			DependsOn:  comp.DependsOn,
+		}
+		if comp.Err != nil {
+			c.Error = comp.Err.Error()
+		}
+		if comp.InputSpec != nil {
+			c.Binary = comp.InputSpec.BinaryName
+		}
+		if comp.ShipperSpec != nil {
+			c.ShipperType = comp.ShipperSpec.ShipperType
+			c.Binary = comp.ShipperSpec.BinaryName
+		}
+		for _, unit := range comp.Units {
+			u := modelUnit{
+				ID:       unit.ID,
+				Type:     unit.Type.String(),
+				LogLevel: unit.LogLevel.String(),
+			}
+			if unit.Err != nil {
+				u.Error = unit.Err.Error()
+			}
+			if unit.Config.GetSource() != nil {
+				u.Config = unit.Config.GetSource().AsMap()
+			}
+			c.Units = append(c.Units, u)
+		}
+		sort.Slice(c.Units, func(i, j int) bool {
+			return c.Units[i].ID < c.Units[j].ID
+		})
+		m.Components = append(m.Components, c)
+	}
+	sort.Slice(m.Components, func(i, j int) bool {
+		return m.Components[i].ID < m.Components[j].ID
+	})
+	return m
+}
@@ -0,0 +1,17 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package golden
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestComponentModel(t *testing.T) {
+	Test(t, Options{
+		FixturesDir: "testdata",
+		SpecsDir:    filepath.Join("..", "..", "..", "specs"),
+	})
+}
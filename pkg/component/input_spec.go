@@ -19,15 +19,23 @@ type InputSpec struct {
 	Outputs     []string    `config:"outputs,omitempty" yaml:"outputs,omitempty"`
 	Shippers    []string    `config:"shippers,omitempty" yaml:"shippers,omitempty"`
 	Runtime     RuntimeSpec `config:"runtime,omitempty" yaml:"runtime,omitempty"`
+	// DependsOn are the input types that must be healthy before this input is started.
+	DependsOn []string `config:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	// Diagnostics are the diagnostic providers of the component, added to the diagnostics bundle.
+	Diagnostics []DiagnosticProviderSpec `config:"diagnostics,omitempty" yaml:"diagnostics,omitempty"`
+	// UninstallHooks are run when Elastic Agent is uninstalled.
+	UninstallHooks []UninstallHookSpec `config:"uninstall_hooks,omitempty" yaml:"uninstall_hooks,omitempty"`
 
 	Command *CommandSpec `config:"command,omitempty" yaml:"command,omitempty"`
 	Service *ServiceSpec `config:"service,omitempty" yaml:"service,omitempty"`
+	Builtin *BuiltinSpec `config:"builtin,omitempty" yaml:"builtin,omitempty"`
+	OTel    *OTelSpec    `config:"otel,omitempty" yaml:"otel,omitempty"`
 }
 
 // Validate ensures correctness of input specification.
 func (s *InputSpec) Validate() error {
-	if s.Command == nil && s.Service == nil {
-		return fmt.Errorf("input '%s' must define either command or service", s.Name)
+	if s.Command == nil && s.Service == nil && s.Builtin == nil && s.OTel == nil {
+		return fmt.Errorf("input '%s' must define either command, service, builtin or otel", s.Name)
 	}
 	for i, a := range s.Platforms {
 		if !GlobalPlatforms.Exists(a) {
@@ -39,7 +47,8 @@ func (s *InputSpec) Validate() error {
 			}
 		}
 	}
-	if len(s.Outputs) == 0 && len(s.Shippers) == 0 {
+	// the OpenTelemetry Collector sends to the exporters of its pipelines
+	if len(s.Outputs) == 0 && len(s.Shippers) == 0 && s.OTel == nil {
 		return fmt.Errorf("input '%s' must define at least one output or one shipper", s.Name)
 	}
 	for i, a := range s.Outputs {
@@ -56,6 +65,31 @@ func (s *InputSpec) Validate() error {
 			}
 		}
 	}
+	for _, d := range s.DependsOn {
+		if d == s.Name {
+			return fmt.Errorf("input '%s' cannot depend on itself", s.Name)
+		}
+	}
+	filenames := make(map[string]bool)
+	for i, d := range s.Diagnostics {
+		if filenames[d.Filename] {
+			return fmt.Errorf("input '%s' defines the diagnostic filename '%s' more than once", s.Name, d.Filename)
+		}
+		filenames[d.Filename] = true
+		if len(d.Args) > 0 && s.Builtin != nil {
+			return fmt.Errorf("input '%s' defined 'diagnostics.%d.args' but a builtin input has no binary to run", s.Name, i)
+		}
+	}
+	hooks := make(map[string]bool)
+	for i, h := range s.UninstallHooks {
+		if hooks[h.Name] {
+			return fmt.Errorf("input '%s' defines the uninstall hook '%s' more than once", s.Name, h.Name)
+		}
+		hooks[h.Name] = true
+		if s.Builtin != nil {
+			return fmt.Errorf("input '%s' defined 'uninstall_hooks.%d' but a builtin input has no binary to run", s.Name, i)
+		}
+	}
 	for idx, prevention := range s.Runtime.Preventions {
 		_, err := eql.New(prevention.Condition)
 		if err != nil {
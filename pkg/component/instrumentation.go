@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package component
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+)
+
+// instrumentationKey is the key of the APM instrumentation in the configuration of the output
+// units, it matches the instrumentation settings of the beats.
+const instrumentationKey = "instrumentation"
+
+type instrumentationCfg struct {
+	Agent struct {
+		Monitoring struct {
+			Traces bool                    `config:"traces"`
+			APM    monitoringCfg.APMConfig `config:"apm"`
+		} `config:"monitoring"`
+	} `config:"agent"`
+}
+
+// instrumentationFromPolicy returns the APM instrumentation of the components, taken from the APM
+// configuration of the agent in the policy. It returns nil when the traces are disabled or no
+// APM server is set.
+func instrumentationFromPolicy(policy map[string]interface{}) (map[string]interface{}, error) {
+	c, err := config.NewConfigFrom(policy)
+	if err != nil {
+		return nil, err
+	}
+	var cfg instrumentationCfg
+	if err := c.Unpack(&cfg); err != nil {
+		return nil, err
+	}
+	monitoring := cfg.Agent.Monitoring
+	if !monitoring.Traces || len(monitoring.APM.Hosts) == 0 {
+		return nil, nil
+	}
+
+	apm := monitoring.APM
+	hosts := make([]interface{}, 0, len(apm.Hosts))
+	for _, host := range apm.Hosts {
+		hosts = append(hosts, host)
+	}
+	instrumentation := map[string]interface{}{
+		"enabled": true,
+		"hosts":   hosts,
+	}
+	if apm.Environment != "" {
+		instrumentation["environment"] = apm.Environment
+	}
+	if apm.APIKey != "" {
+		instrumentation["api_key"] = apm.APIKey
+	} else if apm.SecretToken != "" {
+		instrumentation["secret_token"] = apm.SecretToken
+	}
+	if apm.SamplingRate != nil {
+		if *apm.SamplingRate < 0 || *apm.SamplingRate > 1 {
+			return nil, fmt.Errorf("sampling rate %v is not between 0 and 1", *apm.SamplingRate)
+		}
+		instrumentation["sampling_rate"] = float64(*apm.SamplingRate)
+	}
+	tls := make(map[string]interface{})
+	if apm.TLS.SkipVerify {
+		tls["skip_verify"] = true
+	}
+	if apm.TLS.ServerCertificate != "" {
+		tls["server_certificate"] = apm.TLS.ServerCertificate
+	}
+	if apm.TLS.ServerCA != "" {
+		tls["server_ca"] = apm.TLS.ServerCA
+	}
+	if len(tls) > 0 {
+		instrumentation["tls"] = tls
+	}
+	return instrumentation, nil
+}
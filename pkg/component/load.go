@@ -10,6 +10,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/elastic/go-ucfg/yaml"
 )
@@ -60,6 +62,9 @@ type RuntimeSpecs struct {
 	// inputTypes all input types even if that input is not supported on the current platform
 	inputTypes []string
 
+	// inputPlatforms the platforms supporting each input type, including the other platforms
+	inputPlatforms map[string][]string
+
 	// inputSpecs only the input specs for the current platform
 	inputSpecs map[string]InputRuntimeSpec
 
@@ -92,7 +97,8 @@ func SkipBinaryCheck() LoadRuntimeOption {
 // Returns a mapping of the input to binary name with specification for that input. The filenames in the directory
 // are required to be {binary-name} with {binary-name}.spec.yml to be next to it. If a {binary-name}.spec.yml exists
 // but no matching {binary-name} is found that will result in an error. If a {binary-name} exists without a
-// {binary-name}.spec.yml then it will be ignored.
+// {binary-name}.spec.yml then it will be ignored. The builtin inputs registered with RegisterBuiltinInput are
+// loaded along with them.
 func LoadRuntimeSpecs(dir string, platform PlatformDetail, opts ...LoadRuntimeOption) (RuntimeSpecs, error) {
 	var opt loadRuntimeOpts
 	for _, o := range opts {
@@ -103,6 +109,7 @@ func LoadRuntimeSpecs(dir string, platform PlatformDetail, opts ...LoadRuntimeOp
 		return RuntimeSpecs{}, err
 	}
 	var inputTypes []string
+	inputPlatforms := make(map[string][]string)
 	inputSpecs := make(map[string]InputRuntimeSpec)
 	inputAliases := make(map[string]string)
 	shipperSpecs := make(map[string]ShipperRuntimeSpec)
@@ -127,6 +134,7 @@ func LoadRuntimeSpecs(dir string, platform PlatformDetail, opts ...LoadRuntimeOp
 			if !containsStr(inputTypes, input.Name) {
 				inputTypes = append(inputTypes, input.Name)
 			}
+			inputPlatforms[input.Name] = append(inputPlatforms[input.Name], input.Platforms...)
 			if !containsStr(input.Platforms, platform.String()) {
 				// input spec doesn't support this platform
 				continue
@@ -177,9 +185,30 @@ func LoadRuntimeSpecs(dir string, platform PlatformDetail, opts ...LoadRuntimeOp
 			}
 		}
 	}
+	for _, input := range builtinSpecs() {
+		if !containsStr(inputTypes, input.Name) {
+			inputTypes = append(inputTypes, input.Name)
+		}
+		inputPlatforms[input.Name] = append(inputPlatforms[input.Name], input.Platforms...)
+		if !containsStr(input.Platforms, platform.String()) {
+			continue
+		}
+		if existing, exists := inputSpecs[input.Name]; exists {
+			return RuntimeSpecs{}, fmt.Errorf("failed loading builtin input '%s': input already exists in spec '%s'", input.Name, existing.BinaryName)
+		}
+		if existing, exists := inputAliases[input.Name]; exists {
+			return RuntimeSpecs{}, fmt.Errorf("failed loading builtin input '%s': input collides with an alias from another input '%s'", input.Name, existing)
+		}
+		inputSpecs[input.Name] = InputRuntimeSpec{
+			InputType:  input.Name,
+			BinaryName: BuiltinBinaryName,
+			Spec:       input,
+		}
+	}
 	return RuntimeSpecs{
 		platform:       platform,
 		inputTypes:     inputTypes,
+		inputPlatforms: inputPlatforms,
 		inputSpecs:     inputSpecs,
 		aliasMapping:   inputAliases,
 		shipperSpecs:   shipperSpecs,
@@ -213,6 +242,7 @@ func specFilesForDirectory(dir string) (map[string]Spec, error) {
 // Only used for testing.
 func NewRuntimeSpecs(platform PlatformDetail, inputSpecs []InputRuntimeSpec, shipperSpecs []ShipperRuntimeSpec) (RuntimeSpecs, error) {
 	var inputTypes []string
+	inputPlatforms := make(map[string][]string)
 	inputSpecsMap := make(map[string]InputRuntimeSpec)
 	inputAliases := make(map[string]string)
 	shipperSpecsMap := make(map[string]ShipperRuntimeSpec)
@@ -221,6 +251,7 @@ func NewRuntimeSpecs(platform PlatformDetail, inputSpecs []InputRuntimeSpec, shi
 		if !containsStr(inputTypes, inputSpec.Spec.Name) {
 			inputTypes = append(inputTypes, inputSpec.Spec.Name)
 		}
+		inputPlatforms[inputSpec.Spec.Name] = append(inputPlatforms[inputSpec.Spec.Name], inputSpec.Spec.Platforms...)
 		if !containsStr(inputSpec.Spec.Platforms, platform.String()) {
 			// input spec doesn't support this platform
 			continue
@@ -263,6 +294,7 @@ func NewRuntimeSpecs(platform PlatformDetail, inputSpecs []InputRuntimeSpec, shi
 	return RuntimeSpecs{
 		platform:       platform,
 		inputTypes:     inputTypes,
+		inputPlatforms: inputPlatforms,
 		inputSpecs:     inputSpecsMap,
 		aliasMapping:   inputAliases,
 		shipperSpecs:   shipperSpecsMap,
@@ -287,7 +319,7 @@ func (r *RuntimeSpecs) GetInput(inputType string) (InputRuntimeSpec, error) {
 	runtimeSpec, ok := r.inputSpecs[inputType]
 	if !ok {
 		// supported but not on this platform
-		return InputRuntimeSpec{}, ErrInputNotSupportedOnPlatform
+		return InputRuntimeSpec{}, r.errNotSupportedOnPlatform(inputType)
 	}
 	err := validateRuntimeChecks(&runtimeSpec.Spec.Runtime, r.platform)
 	if err != nil {
@@ -296,6 +328,20 @@ func (r *RuntimeSpecs) GetInput(inputType string) (InputRuntimeSpec, error) {
 	return runtimeSpec, nil
 }
 
+// errNotSupportedOnPlatform returns the error of an input supported on other platforms, naming
+// them, an embedded device only runs the inputs built for its architecture.
+func (r *RuntimeSpecs) errNotSupportedOnPlatform(inputType string) error {
+	platforms := r.inputPlatforms[inputType]
+	if len(platforms) == 0 {
+		return ErrInputNotSupportedOnPlatform
+	}
+	reason := fmt.Sprintf("%s (%s), it runs on %s", ErrInputNotSupportedOnPlatform, r.platform.String(), strings.Join(platforms, ", "))
+	if r.platform.Edge() {
+		reason += ", the agent of embedded devices only runs the inputs built for their architecture"
+	}
+	return &ErrorReason{Reason: reason, err: ErrInputNotSupportedOnPlatform}
+}
+
 // ShippersForOutputType returns the shippers that support the outputType.
 // If the list is empty, then the returned error will be either
 // ErrOutputNotSupportedOnPlatform (output is supported but not on this
@@ -331,6 +377,20 @@ func (r *RuntimeSpecs) ServiceSpecs() []InputRuntimeSpec {
 	return services
 }
 
+// UninstallHookSpecs returns the input specifications defining uninstall hooks, sorted by input type.
+func (r *RuntimeSpecs) UninstallHookSpecs() []InputRuntimeSpec {
+	var specs []InputRuntimeSpec
+	for _, s := range r.inputSpecs {
+		if len(s.Spec.UninstallHooks) > 0 {
+			specs = append(specs, s)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].InputType < specs[j].InputType
+	})
+	return specs
+}
+
 // LoadSpec loads the component specification.
 //
 // Will error in the case that the specification is not valid. Only valid specifications are allowed.
@@ -355,3 +415,13 @@ func containsStr(s []string, v string) bool {
 	}
 	return false
 }
+
+// appendMissingStr appends the values of v that are not yet in s.
+func appendMissingStr(s []string, v ...string) []string {
+	for _, i := range v {
+		if !containsStr(s, i) {
+			s = append(s, i)
+		}
+	}
+	return s
+}
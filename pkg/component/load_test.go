@@ -36,6 +36,38 @@ func TestLoadRuntimeSpecs(t *testing.T) {
 	}
 }
 
+func TestLoadRuntimeSpecsEdge(t *testing.T) {
+	detail := PlatformDetail{
+		Platform: Platform{OS: Linux, Arch: ARM, GOOS: Linux},
+	}
+	require.True(t, detail.Edge())
+	runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), detail, SkipBinaryCheck())
+	require.NoError(t, err)
+
+	// the data collection of the Beats runs on the embedded devices
+	for _, inputType := range []string{"filestream", "log", "system/metrics"} {
+		_, err := runtime.GetInput(inputType)
+		assert.NoError(t, err, inputType)
+	}
+
+	// the input names the platforms it runs on
+	_, err = runtime.GetInput("aws-s3")
+	require.ErrorIs(t, err, ErrInputNotSupportedOnPlatform)
+	assert.Contains(t, err.Error(), "input not supported on this platform (linux/arm), it runs on linux/amd64, linux/arm64,")
+	assert.Contains(t, err.Error(), "the agent of embedded devices only runs the inputs built for their architecture")
+}
+
+func TestPlatformEdge(t *testing.T) {
+	for _, platform := range GlobalPlatforms {
+		switch platform.Arch {
+		case AMD64, ARM64:
+			assert.False(t, platform.Edge(), platform.String())
+		default:
+			assert.True(t, platform.Edge(), platform.String())
+		}
+	}
+}
+
 func TestLoadSpec_Components(t *testing.T) {
 	scenarios := []struct {
 		Name string
@@ -108,3 +140,17 @@ func TestLoadSpec_Components(t *testing.T) {
 		})
 	}
 }
+
+func FuzzLoadSpec(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "specs", "*.spec.yml"))
+	require.NoError(f, err)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		require.NoError(f, err)
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = LoadSpec(data)
+	})
+}
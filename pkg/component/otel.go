@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package component
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/logp"
+
+	"github.com/elastic/elastic-agent/pkg/features"
+)
+
+const (
+	// OTelInputType is the input type of the component running the OpenTelemetry Collector
+	// pipelines of the policy.
+	OTelInputType = "otel"
+	// OTelComponentID is the ID of the component running the OpenTelemetry Collector pipelines.
+	OTelComponentID = "otel"
+	// OTelUnitID is the ID of the input unit holding the configuration of the collector.
+	OTelUnitID = "otel"
+)
+
+// OTelSections are the top-level sections of the policy translated into the configuration of the
+// OpenTelemetry Collector.
+var OTelSections = []string{"receivers", "processors", "exporters", "connectors", "extensions", "service"}
+
+// otelConfig returns the sections of the policy for the OpenTelemetry Collector, nil when the
+// policy has no receivers section.
+func otelConfig(policy map[string]interface{}) map[string]interface{} {
+	if _, ok := policy["receivers"]; !ok {
+		return nil
+	}
+	cfg := make(map[string]interface{}, len(OTelSections))
+	for _, section := range OTelSections {
+		if value, ok := policy[section]; ok {
+			cfg[section] = value
+		}
+	}
+	return cfg
+}
+
+// validateOTelConfig ensures the sections define at least a pipeline from receivers to exporters.
+func validateOTelConfig(cfg map[string]interface{}) error {
+	for _, section := range []string{"receivers", "exporters", "service"} {
+		if _, ok := cfg[section].(map[string]interface{}); !ok {
+			return fmt.Errorf("the %s section of the OpenTelemetry Collector pipelines must be a map", section)
+		}
+	}
+	service := cfg["service"].(map[string]interface{})
+	pipelines, ok := service["pipelines"].(map[string]interface{})
+	if !ok || len(pipelines) == 0 {
+		return errors.New("the OpenTelemetry Collector pipelines must define service.pipelines")
+	}
+	return nil
+}
+
+// componentForOTel returns the component running the OpenTelemetry Collector pipelines of the
+// policy, nil when the policy has none. The component has a single input unit with the
+// configuration of the collector.
+func (r *RuntimeSpecs) componentForOTel(policy map[string]interface{}, ll logp.Level, featureFlags *features.Flags) *Component {
+	cfg := otelConfig(policy)
+	if cfg == nil {
+		return nil
+	}
+	inputSpec, componentErr := r.GetInput(OTelInputType)
+	if componentErr == nil && inputSpec.Spec.OTel == nil {
+		componentErr = fmt.Errorf("input '%s' must define otel to run the OpenTelemetry Collector pipelines", OTelInputType)
+	}
+	if componentErr == nil {
+		componentErr = validateOTelConfig(cfg)
+	}
+
+	cfg["type"] = OTelInputType
+	cfg["id"] = OTelUnitID
+	logLevel, err := stringToLogLevel(ll.String())
+	if err != nil {
+		logLevel = defaultUnitLogLevel
+	}
+	unitCfg, cfgErr := ExpectedConfig(cfg)
+	return &Component{
+		ID:        OTelComponentID,
+		Err:       componentErr,
+		InputSpec: &inputSpec,
+		InputType: OTelInputType,
+		Units: []Unit{
+			{
+				ID:       OTelUnitID,
+				Type:     client.UnitTypeInput,
+				LogLevel: logLevel,
+				Config:   unitCfg,
+				Err:      cfgErr,
+			},
+		},
+		Features: featureFlags.AsProto(),
+	}
+}
@@ -29,8 +29,22 @@ const (
 	AMD64 = "amd64"
 	// ARM64 represents the arm64 architecture
 	ARM64 = "arm64"
+	// ARM represents the 32-bit arm architecture
+	ARM = "arm"
+	// MIPS represents the 32-bit big-endian mips architecture
+	MIPS = "mips"
+	// MIPSLE represents the 32-bit little-endian mips architecture
+	MIPSLE = "mipsle"
+	// MIPS64 represents the 64-bit big-endian mips architecture
+	MIPS64 = "mips64"
+	// MIPS64LE represents the 64-bit little-endian mips architecture
+	MIPS64LE = "mips64le"
 )
 
+// edgeArchs are the architectures of the embedded devices, they run a reduced agent with only the
+// components built for them.
+var edgeArchs = []string{ARM, MIPS, MIPSLE, MIPS64, MIPS64LE}
+
 // Platform defines the platform that a component can support
 type Platform struct {
 	OS   string
@@ -73,6 +87,31 @@ var GlobalPlatforms = Platforms{
 		Arch: ARM64,
 		GOOS: Linux,
 	},
+	{
+		OS:   Linux,
+		Arch: ARM,
+		GOOS: Linux,
+	},
+	{
+		OS:   Linux,
+		Arch: MIPS,
+		GOOS: Linux,
+	},
+	{
+		OS:   Linux,
+		Arch: MIPSLE,
+		GOOS: Linux,
+	},
+	{
+		OS:   Linux,
+		Arch: MIPS64,
+		GOOS: Linux,
+	},
+	{
+		OS:   Linux,
+		Arch: MIPS64LE,
+		GOOS: Linux,
+	},
 	{
 		OS:   Windows,
 		Arch: AMD64,
@@ -85,6 +124,17 @@ func (p *Platform) String() string {
 	return fmt.Sprintf("%s/%s", p.OS, p.Arch)
 }
 
+// Edge returns true when the platform is an embedded device architecture, it only runs the
+// components built for it.
+func (p *Platform) Edge() bool {
+	for _, arch := range edgeArchs {
+		if p.Arch == arch {
+			return true
+		}
+	}
+	return false
+}
+
 // Exists returns true if the
 func (p Platforms) Exists(platform string) bool {
 	pieces := strings.SplitN(platform, "/", 2)
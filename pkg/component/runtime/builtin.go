@@ -0,0 +1,368 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fault"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/sdk"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// BuiltinFactory creates the component run in-process by the builtin runtime, the logger is the
+// one of the component.
+type BuiltinFactory func(log *logger.Logger) (*sdk.Component, error)
+
+var (
+	builtinsMx sync.RWMutex
+	builtins   = make(map[string]BuiltinFactory)
+)
+
+// MustRegisterBuiltin registers a builtin input and panics if RegisterBuiltin returns an error.
+func MustRegisterBuiltin(spec component.InputSpec, factory BuiltinFactory) {
+	if err := RegisterBuiltin(spec, factory); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterBuiltin registers the specification of an input run in-process in Elastic Agent and the
+// factory of the component implementing it.
+func RegisterBuiltin(spec component.InputSpec, factory BuiltinFactory) error {
+	if factory == nil {
+		return fmt.Errorf("builtin input '%s' has no factory", spec.Name)
+	}
+	if err := component.RegisterBuiltinInput(spec); err != nil {
+		return err
+	}
+	builtinsMx.Lock()
+	defer builtinsMx.Unlock()
+	builtins[spec.Name] = factory
+	return nil
+}
+
+// builtinRun is a run of the component of a builtin input.
+type builtinRun struct {
+	cancel context.CancelFunc
+	err    error
+}
+
+// builtinRuntime provides the builtin runtime for running a component in-process in Elastic Agent.
+// The component is connected to the control protocol like a command component, so it has the same
+// lifecycle and reports its state the same way.
+type builtinRuntime struct {
+	log     *logger.Logger
+	current component.Component
+	factory BuiltinFactory
+
+	ch       chan ComponentState
+	actionCh chan actionMode
+	doneCh   chan *builtinRun
+	compCh   chan component.Component
+
+	actionState actionMode
+	run         *builtinRun
+
+	state          ComponentState
+	lastCheckin    time.Time
+	missedCheckins int
+
+	// credsUsed is set once a run got the connection credentials, every following run gets new
+	// credentials
+	credsUsed bool
+}
+
+// newBuiltinRuntime creates a new builtin runtime for the provided component.
+func newBuiltinRuntime(comp component.Component, log *logger.Logger) (*builtinRuntime, error) {
+	if comp.InputSpec == nil || comp.InputSpec.Spec.Builtin == nil {
+		return nil, errors.New("must have builtin defined in specification")
+	}
+	builtinsMx.RLock()
+	factory, ok := builtins[comp.InputSpec.InputType]
+	builtinsMx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("builtin input '%s' is not implemented by this Elastic Agent", comp.InputSpec.InputType)
+	}
+	return &builtinRuntime{
+		log:         log.With("component", map[string]interface{}{"id": comp.ID, "type": comp.InputSpec.InputType}),
+		current:     comp,
+		factory:     factory,
+		ch:          make(chan ComponentState),
+		actionCh:    make(chan actionMode, 1),
+		doneCh:      make(chan *builtinRun),
+		compCh:      make(chan component.Component, 1),
+		actionState: actionStop,
+		state:       newComponentState(&comp),
+	}, nil
+}
+
+// Run starts the runtime for the component.
+//
+// Called by Manager inside a goroutine. Run does not return until the passed in context is done. Run is always
+// called before any of the other methods in the interface and once the context is done none of those methods should
+// ever be called again.
+func (b *builtinRuntime) Run(ctx context.Context, comm Communicator) error {
+	timeouts := b.current.InputSpec.Spec.Builtin.Timeouts
+	b.forceCompState(client.UnitStateStarting, "Starting")
+	t := time.NewTicker(timeouts.Checkin)
+	defer t.Stop()
+	defer func() {
+		if b.run != nil {
+			b.run.cancel()
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case as := <-b.actionCh:
+			b.actionState = as
+			switch as {
+			case actionStart:
+				if err := b.start(ctx, comm); err != nil {
+					b.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+				}
+				t.Reset(timeouts.Checkin)
+			case actionStop, actionTeardown:
+				b.stop()
+			}
+		case run := <-b.doneCh:
+			// ignores old runs
+			if run == b.run {
+				b.run = nil
+				if b.handleDone(run) {
+					// start again after restart period
+					t.Reset(timeouts.Restart)
+				}
+			}
+		case newComp := <-b.compCh:
+			b.current = newComp
+			sendExpected := b.state.syncExpected(&newComp)
+			changed := b.state.syncUnits(&newComp)
+			if sendExpected || b.state.unsettled() {
+				comm.CheckinExpected(b.state.toCheckinExpected(), nil)
+			}
+			if changed {
+				b.sendObserved()
+			}
+		case checkin := <-comm.CheckinObserved():
+			if fault.DropCheckinObserved(b.current.ID) {
+				continue
+			}
+			sendExpected := false
+			changed := false
+			if b.state.State == client.UnitStateStarting {
+				// first observation after start set component to healthy
+				b.state.State = client.UnitStateHealthy
+				b.state.Message = "Healthy: communicating with builtin"
+				changed = true
+			}
+			if b.lastCheckin.IsZero() {
+				// first check-in
+				sendExpected = true
+			}
+			b.lastCheckin = time.Now().UTC()
+			if b.state.syncCheckin(checkin) {
+				changed = true
+			}
+			if b.state.unsettled() {
+				sendExpected = true
+			}
+			if sendExpected {
+				comm.CheckinExpected(b.state.toCheckinExpected(), checkin)
+			}
+			if changed {
+				b.sendObserved()
+			}
+			if b.state.cleanupStopped() {
+				b.sendObserved()
+			}
+		case <-t.C:
+			t.Reset(timeouts.Checkin)
+			if b.actionState != actionStart {
+				continue
+			}
+			if b.run == nil {
+				// not running, but should be running
+				if err := b.start(ctx, comm); err != nil {
+					b.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+				}
+				continue
+			}
+			now := time.Now().UTC()
+			if b.lastCheckin.IsZero() || now.Sub(b.lastCheckin) > timeouts.Checkin {
+				b.missedCheckins++
+			} else {
+				b.missedCheckins = 0
+			}
+			switch {
+			case b.missedCheckins == 0:
+				b.compState(client.UnitStateHealthy, "Healthy: communicating with builtin")
+			case b.missedCheckins < maxCheckinMisses:
+				b.compState(client.UnitStateDegraded, fmt.Sprintf("Degraded: builtin missed %d check-ins", b.missedCheckins))
+			default:
+				// the component is restarted, its goroutines cannot be killed like a process so it
+				// is asked to stop and a new run is started once it did
+				b.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: builtin missed %d check-ins and will be restarted", maxCheckinMisses))
+				b.run.cancel()
+			}
+		}
+	}
+}
+
+// Watch returns the channel that sends component state.
+//
+// Channel should send a new state anytime a state for a unit or the whole component changes.
+func (b *builtinRuntime) Watch() <-chan ComponentState {
+	return b.ch
+}
+
+// Start starts the component.
+//
+// Non-blocking and never returns an error.
+func (b *builtinRuntime) Start() error {
+	b.setAction(actionStart)
+	return nil
+}
+
+// Update updates the currComp runtime with a new-revision for the component definition.
+//
+// Non-blocking and never returns an error.
+func (b *builtinRuntime) Update(comp component.Component) error {
+	// clear channel so it's the latest component
+	select {
+	case <-b.compCh:
+	default:
+	}
+	b.compCh <- comp
+	return nil
+}
+
+// Stop stops the component.
+//
+// Non-blocking and never returns an error.
+func (b *builtinRuntime) Stop() error {
+	b.setAction(actionStop)
+	return nil
+}
+
+// Teardown tears down the component.
+//
+// Non-blocking and never returns an error.
+func (b *builtinRuntime) Teardown() error {
+	b.setAction(actionTeardown)
+	return nil
+}
+
+func (b *builtinRuntime) setAction(action actionMode) {
+	// clear channel so it's the latest action
+	select {
+	case <-b.actionCh:
+	default:
+	}
+	b.actionCh <- action
+}
+
+// start runs the component, the end of the run is reported until the context of the runtime is done.
+func (b *builtinRuntime) start(runtimeCtx context.Context, comm Communicator) error {
+	if b.run != nil {
+		// already running
+		return nil
+	}
+	if b.credsUsed {
+		if err := comm.RotateCredentials(); err != nil {
+			return err
+		}
+	}
+	b.credsUsed = true
+
+	var connInfo bytes.Buffer
+	if err := comm.WriteConnInfo(&connInfo); err != nil {
+		return err
+	}
+	comp, err := b.factory(b.log)
+	if err != nil {
+		return fmt.Errorf("failed to create builtin: %w", err)
+	}
+	agentClient, err := sdk.NewClientFromReader(&connInfo, client.VersionInfo{Name: b.current.InputSpec.InputType})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	// reset checkin state before starting the run.
+	b.lastCheckin = time.Time{}
+	b.missedCheckins = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &builtinRun{cancel: cancel}
+	b.run = run
+	go func() {
+		run.err = comp.RunWithClient(ctx, agentClient)
+		select {
+		case b.doneCh <- run:
+		case <-runtimeCtx.Done():
+		}
+	}()
+	b.forceCompState(client.UnitStateStarting, "Starting: builtin started")
+	return nil
+}
+
+func (b *builtinRuntime) stop() {
+	if b.run == nil {
+		// already stopped, ensure that state of the component is also stopped
+		if b.state.State != client.UnitStateStopped {
+			if b.state.State == client.UnitStateFailed {
+				b.forceCompState(client.UnitStateStopped, "Stopped: never started successfully")
+			} else {
+				b.forceCompState(client.UnitStateStopped, "Stopped: already stopped")
+			}
+		}
+		return
+	}
+	// the component stops its units before the run returns
+	b.run.cancel()
+}
+
+// handleDone handles the end of a run, returns true when the component must be started again.
+func (b *builtinRuntime) handleDone(run *builtinRun) bool {
+	switch b.actionState {
+	case actionStart:
+		reason := "builtin exited"
+		if run.err != nil {
+			reason = fmt.Sprintf("builtin exited: %s", run.err)
+		}
+		b.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", reason))
+		return true
+	case actionStop, actionTeardown:
+		b.forceCompState(client.UnitStateStopped, "Stopped: builtin stopped")
+	}
+	return false
+}
+
+// forceCompState force updates the state for the entire component, forcing that state on all units.
+func (b *builtinRuntime) forceCompState(state client.UnitState, msg string) {
+	if b.state.forceState(state, msg) {
+		b.sendObserved()
+	}
+}
+
+// compState updates just the component state not all the units.
+func (b *builtinRuntime) compState(state client.UnitState, msg string) {
+	if b.state.compState(state, msg) {
+		b.sendObserved()
+	}
+}
+
+func (b *builtinRuntime) sendObserved() {
+	b.ch <- b.state.Copy()
+}
@@ -0,0 +1,177 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/apmtest"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/sdk"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+var builtinInputSpec = component.InputSpec{
+	Name:        "builtin-testing",
+	Description: "Builtin Testing Input",
+	Platforms:   []string{"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64", "windows/amd64"},
+	Outputs:     []string{"elasticsearch"},
+	Builtin: &component.BuiltinSpec{
+		Timeouts: component.CommandTimeoutSpec{
+			Checkin: 30 * time.Second,
+			Restart: 10 * time.Millisecond, // quick restart during tests
+			Stop:    30 * time.Second,
+		},
+	},
+}
+
+type builtinTestingHandler struct{}
+
+func (builtinTestingHandler) Configure(unit *sdk.Unit) error {
+	unit.Healthy("Builtin Healthy")
+	return nil
+}
+
+func (builtinTestingHandler) Stop(_ *sdk.Unit) error {
+	return nil
+}
+
+func init() {
+	MustRegisterBuiltin(builtinInputSpec, func(_ *logger.Logger) (*sdk.Component, error) {
+		return sdk.New("builtin-testing", "1.0.0", func(_ *sdk.Unit) (sdk.Handler, error) {
+			return builtinTestingHandler{}, nil
+		}), nil
+	})
+}
+
+func TestRegisterBuiltin_Duplicate(t *testing.T) {
+	err := RegisterBuiltin(builtinInputSpec, func(_ *logger.Logger) (*sdk.Component, error) {
+		return nil, errors.New("not used")
+	})
+	require.EqualError(t, err, "builtin input 'builtin-testing' is already registered")
+}
+
+func TestManager_Builtin_StartStop(t *testing.T) {
+	testPaths(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ai, _ := info.NewAgentInfo(true)
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
+	require.NoError(t, err)
+	errCh := make(chan error)
+	go func() {
+		err := m.Run(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		errCh <- err
+	}()
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 1*time.Second)
+	defer waitCancel()
+	if err := m.waitForReady(waitCtx); err != nil {
+		require.NoError(t, err)
+	}
+
+	comp := component.Component{
+		ID: "builtin-testing-default",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType:  "builtin-testing",
+			BinaryName: component.BuiltinBinaryName,
+			Spec:       builtinInputSpec,
+		},
+		Units: []component.Unit{
+			{
+				ID:       "builtin-testing-input",
+				Type:     client.UnitTypeInput,
+				LogLevel: client.UnitLogLevelTrace,
+				Config: component.MustExpectedConfig(map[string]interface{}{
+					"type": "builtin-testing",
+				}),
+			},
+		},
+	}
+
+	subCtx, subCancel := context.WithCancel(context.Background())
+	defer subCancel()
+	subErrCh := make(chan error)
+	go func() {
+		sub := m.Subscribe(subCtx, "builtin-testing-default")
+		for {
+			select {
+			case <-subCtx.Done():
+				return
+			case state := <-sub.Ch():
+				t.Logf("component state changed: %+v", state)
+				if state.State == client.UnitStateFailed {
+					subErrCh <- fmt.Errorf("component failed: %s", state.Message)
+					continue
+				}
+				unit, ok := state.Units[ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "builtin-testing-input"}]
+				if !ok {
+					subErrCh <- errors.New("unit missing: builtin-testing-input")
+					continue
+				}
+				switch unit.State {
+				case client.UnitStateFailed:
+					subErrCh <- fmt.Errorf("unit failed: %s", unit.Message)
+				case client.UnitStateHealthy:
+					if unit.Message != "Builtin Healthy" {
+						subErrCh <- fmt.Errorf("unit reported unexpected message: %s", unit.Message)
+					}
+					// remove the component which will stop it
+					if err := m.Update([]component.Component{}); err != nil {
+						subErrCh <- err
+					}
+				case client.UnitStateStopped:
+					subErrCh <- nil
+				case client.UnitStateStarting, client.UnitStateConfiguring, client.UnitStateStopping:
+					// acceptable
+				default:
+					subErrCh <- fmt.Errorf("unit reported unexpected state: %v", unit.State)
+				}
+			}
+		}
+	}()
+
+	defer drainErrChan(errCh)
+	defer drainErrChan(subErrCh)
+
+	err = m.Update([]component.Component{comp})
+	require.NoError(t, err)
+
+	endTimer := time.NewTimer(30 * time.Second)
+	defer endTimer.Stop()
+LOOP:
+	for {
+		select {
+		case <-endTimer.C:
+			t.Fatalf("timed out after 30 seconds")
+		case err := <-errCh:
+			require.NoError(t, err)
+		case err := <-subErrCh:
+			require.NoError(t, err)
+			break LOOP
+		}
+	}
+
+	subCancel()
+	cancel()
+
+	err = <-errCh
+	require.NoError(t, err)
+}
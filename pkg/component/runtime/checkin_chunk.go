@@ -0,0 +1,105 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"sort"
+
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+)
+
+// chunkExpected limits the size of the expected check-in message sent to a component to maxSize bytes.
+//
+// A component must always receive all its units in an expected message, a missing unit is stopped.
+// The protocol has no way to reassemble a split message, so the message is not split, instead the
+// configuration of the units that don't fit is deferred:
+//   - a unit whose configuration the component already applied is sent without it, the component
+//     ignores the configuration of a unit with the configuration index it has;
+//   - a unit the component knows is sent with the configuration index the component observed last
+//     and without a configuration, so the component keeps running it unchanged;
+//   - a new unit the component doesn't know yet is left out of the message until its configuration
+//     fits, the component does not run it in the meantime.
+//
+// As the component observes the sent configurations the runtime sends the expected state again and
+// the deferred configurations follow, in the order of the unit types and IDs. At least one pending
+// configuration is sent in each message, even if it alone is larger than maxSize, and the applied
+// ones no longer take space, so a deferred unit waits at most one check-in of the component for each
+// pending configuration ordered before it. It returns the message to send and the number of deferred
+// units.
+func chunkExpected(expected *proto.CheckinExpected, observed *proto.CheckinObserved, maxSize int) (*proto.CheckinExpected, int) {
+	if maxSize <= 0 || protobuf.Size(expected) <= maxSize {
+		return expected, 0
+	}
+
+	observedIdx := make(map[ComponentUnitKey]uint64)
+	for _, unit := range observed.GetUnits() {
+		observedIdx[ComponentUnitKey{UnitType: client.UnitType(unit.Type), UnitID: unit.Id}] = unit.ConfigStateIdx
+	}
+
+	chunk := &proto.CheckinExpected{
+		AgentInfo:   expected.AgentInfo,
+		Features:    expected.Features,
+		FeaturesIdx: expected.FeaturesIdx,
+	}
+	var withConfig []*proto.UnitExpected
+	for _, unit := range expected.Units {
+		if unit.Config == nil {
+			chunk.Units = append(chunk.Units, unit)
+			continue
+		}
+		withConfig = append(withConfig, unit)
+	}
+	// deterministic order, so the same units are deferred until the component observes the others
+	sort.Slice(withConfig, func(i, j int) bool {
+		if withConfig[i].Type != withConfig[j].Type {
+			return withConfig[i].Type < withConfig[j].Type
+		}
+		return withConfig[i].Id < withConfig[j].Id
+	})
+
+	size := protobuf.Size(chunk)
+	sent := 0
+	deferred := 0
+	for _, unit := range withConfig {
+		key := ComponentUnitKey{UnitType: client.UnitType(unit.Type), UnitID: unit.Id}
+		if idx, ok := observedIdx[key]; ok && idx == unit.ConfigStateIdx {
+			// already applied by the component
+			chunk.Units = append(chunk.Units, withoutConfig(unit, idx))
+			continue
+		}
+
+		unitSize := protobuf.Size(&proto.CheckinExpected{Units: []*proto.UnitExpected{unit}})
+		if sent == 0 || size+unitSize <= maxSize {
+			chunk.Units = append(chunk.Units, unit)
+			size += unitSize
+			sent++
+			continue
+		}
+
+		deferred++
+		idx, ok := observedIdx[key]
+		if !ok || idx == 0 {
+			// unknown to the component; sent once it fits
+			continue
+		}
+		// known to the component; keep it running with the configuration it has
+		chunk.Units = append(chunk.Units, withoutConfig(unit, idx))
+	}
+	return chunk, deferred
+}
+
+// withoutConfig returns the unit without its configuration, at the configuration index idx.
+func withoutConfig(unit *proto.UnitExpected, idx uint64) *proto.UnitExpected {
+	return &proto.UnitExpected{
+		Id:             unit.Id,
+		Type:           unit.Type,
+		State:          unit.State,
+		LogLevel:       unit.LogLevel,
+		ConfigStateIdx: idx,
+	}
+}
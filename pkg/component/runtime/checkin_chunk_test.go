@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestChunkExpected(t *testing.T) {
+	unit := func(id string, idx uint64) *proto.UnitExpected {
+		return &proto.UnitExpected{
+			Id:             id,
+			Type:           proto.UnitType_INPUT,
+			State:          proto.State_HEALTHY,
+			ConfigStateIdx: idx,
+			Config: component.MustExpectedConfig(map[string]interface{}{
+				"type":  "filestream",
+				"large": strings.Repeat("a", 1000),
+			}),
+		}
+	}
+	expected := &proto.CheckinExpected{
+		Units: []*proto.UnitExpected{
+			unit("unit-c", 2),
+			unit("unit-a", 2),
+			unit("unit-b", 1),
+			{Id: "output", Type: proto.UnitType_OUTPUT, State: proto.State_HEALTHY, ConfigStateIdx: 1},
+		},
+	}
+	observed := &proto.CheckinObserved{
+		Units: []*proto.UnitObserved{
+			{Id: "unit-a", Type: proto.UnitType_INPUT, ConfigStateIdx: 1},
+			{Id: "unit-c", Type: proto.UnitType_INPUT, ConfigStateIdx: 1},
+			{Id: "output", Type: proto.UnitType_OUTPUT, ConfigStateIdx: 1},
+		},
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		chunk, deferred := chunkExpected(expected, observed, protobuf.Size(expected))
+		assert.Zero(t, deferred)
+		assert.Same(t, expected, chunk)
+
+		chunk, deferred = chunkExpected(expected, observed, 0)
+		assert.Zero(t, deferred)
+		assert.Same(t, expected, chunk)
+	})
+
+	t.Run("chunked", func(t *testing.T) {
+		chunk, deferred := chunkExpected(expected, observed, 1500)
+		assert.Equal(t, 2, deferred)
+		assert.LessOrEqual(t, protobuf.Size(chunk), 1500)
+
+		units := make(map[string]*proto.UnitExpected)
+		for _, u := range chunk.Units {
+			units[u.Id] = u
+		}
+		// output without config is always sent, unit-a sent first by order
+		require.Contains(t, units, "output")
+		require.Contains(t, units, "unit-a")
+		assert.NotNil(t, units["unit-a"].Config)
+		assert.Equal(t, uint64(2), units["unit-a"].ConfigStateIdx)
+		// unit-b is unknown to the component, left out until it fits
+		assert.NotContains(t, units, "unit-b")
+		// unit-c is kept running with the configuration the component has
+		require.Contains(t, units, "unit-c")
+		assert.Nil(t, units["unit-c"].Config)
+		assert.Equal(t, uint64(1), units["unit-c"].ConfigStateIdx)
+	})
+
+	t.Run("always progresses", func(t *testing.T) {
+		chunk, deferred := chunkExpected(expected, nil, 10)
+		assert.Equal(t, 2, deferred)
+		require.Len(t, chunk.Units, 2)
+		assert.Equal(t, "output", chunk.Units[0].Id)
+		assert.Equal(t, "unit-a", chunk.Units[1].Id)
+	})
+}
+
+func TestChunkExpectedConverges(t *testing.T) {
+	unit := func(id string, idx uint64, size int) *proto.UnitExpected {
+		return &proto.UnitExpected{
+			Id:             id,
+			Type:           proto.UnitType_INPUT,
+			State:          proto.State_HEALTHY,
+			ConfigStateIdx: idx,
+			Config: component.MustExpectedConfig(map[string]interface{}{
+				"type":  "filestream",
+				"large": strings.Repeat("a", size),
+			}),
+		}
+	}
+	// unit-a alone exceeds the limit, unit-d is new to the component
+	expected := &proto.CheckinExpected{
+		Units: []*proto.UnitExpected{
+			unit("unit-a", 2, 5000),
+			unit("unit-b", 2, 1000),
+			unit("unit-c", 2, 1000),
+			unit("unit-d", 1, 1000),
+		},
+	}
+	// the component runs the previous configuration of the known units
+	applied := map[string]uint64{"unit-a": 1, "unit-b": 1, "unit-c": 1}
+	observed := func() *proto.CheckinObserved {
+		o := &proto.CheckinObserved{}
+		for id, idx := range applied {
+			o.Units = append(o.Units, &proto.UnitObserved{Id: id, Type: proto.UnitType_INPUT, ConfigStateIdx: idx})
+		}
+		return o
+	}
+
+	// each round the component applies the expected message and checks in
+	missing := 0
+	for round := 1; ; round++ {
+		require.LessOrEqual(t, round, len(expected.Units), "a deferred unit waits at most one round per configuration ordered before it")
+		chunk, deferred := chunkExpected(expected, observed(), 1500)
+
+		sent := make(map[string]bool)
+		for _, u := range chunk.Units {
+			sent[u.Id] = true
+			if u.ConfigStateIdx != applied[u.Id] {
+				require.NotNil(t, u.Config, "%s: a new configuration index must come with its configuration", u.Id)
+			}
+			applied[u.Id] = u.ConfigStateIdx
+		}
+		for id := range applied {
+			require.True(t, sent[id], "%s: a unit known to the component is never left out", id)
+		}
+		if !sent["unit-d"] {
+			missing++
+		}
+		if deferred == 0 {
+			break
+		}
+	}
+	for _, u := range expected.Units {
+		assert.Equal(t, u.ConfigStateIdx, applied[u.Id], "%s: configuration not applied", u.Id)
+	}
+	assert.Equal(t, 3, missing, "the new unit is left out until the configurations ordered before it are applied")
+}
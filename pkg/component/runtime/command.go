@@ -21,6 +21,7 @@ import (
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/fault"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/core/process"
@@ -55,11 +56,15 @@ type procState struct {
 
 // commandRuntime provides the command runtime for running a component as a subprocess.
 type commandRuntime struct {
+	log    *logger.Logger
 	logStd *logWriter
 	logErr *logWriter
 
-	current component.Component
-	monitor MonitoringManager
+	current   component.Component
+	monitor   MonitoringManager
+	coreDumps *coreDumps
+	// sandbox is set when the sandbox of the specification is applied
+	sandbox bool
 
 	ch       chan ComponentState
 	actionCh chan actionMode
@@ -68,18 +73,27 @@ type commandRuntime struct {
 
 	actionState actionMode
 	proc        *process.Info
+	oom         *oomWatch
+	denials     *denialWatch
 
 	state          ComponentState
 	lastCheckin    time.Time
 	missedCheckins int
 	restartBucket  *rate.Limiter
+
+	// credsUsed is set once a process got the connection credentials, every
+	// following process gets new credentials
+	credsUsed bool
 }
 
 // newCommandRuntime creates a new command runtime for the provided component.
-func newCommandRuntime(comp component.Component, log *logger.Logger, monitor MonitoringManager) (*commandRuntime, error) {
+func newCommandRuntime(comp component.Component, log *logger.Logger, monitor MonitoringManager, coreDumps *coreDumps, sandbox bool) (*commandRuntime, error) {
 	c := &commandRuntime{
+		log:         log,
 		current:     comp,
 		monitor:     monitor,
+		coreDumps:   coreDumps,
+		sandbox:     sandbox,
 		ch:          make(chan ComponentState),
 		actionCh:    make(chan actionMode, 1),
 		procCh:      make(chan procState),
@@ -153,6 +167,9 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 				c.sendObserved()
 			}
 		case checkin := <-comm.CheckinObserved():
+			if fault.DropCheckinObserved(c.current.ID) {
+				continue
+			}
 			sendExpected := false
 			changed := false
 			if c.state.State == client.UnitStateStarting {
@@ -190,7 +207,14 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 						c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
 					}
 				} else {
+					if fault.KillComponentProcess(c.current.ID) {
+						c.log.Warnf("Fault injection killing pid '%d'", c.proc.PID)
+						_ = c.proc.Kill() // watcher will handle it from here
+						continue
+					}
 					// running and should be running
+					c.oom.sample()
+					c.denials.sample()
 					now := time.Now().UTC()
 					if c.lastCheckin.IsZero() {
 						// never checked-in
@@ -291,6 +315,11 @@ func (c *commandRuntime) compState(state client.UnitState) {
 	msg := stateUnknownMessage
 	if state == client.UnitStateHealthy {
 		msg = fmt.Sprintf("Healthy: communicating with pid '%d'", c.proc.PID)
+		if hint := c.denials.hint(time.Now()); hint != "" {
+			// the process checks in but the mandatory access control of the host denies some of its operations
+			state = client.UnitStateDegraded
+			msg = fmt.Sprintf("Degraded: pid '%d' %s", c.proc.PID, hint)
+		}
 	} else if state == client.UnitStateDegraded {
 		if c.missedCheckins == 1 {
 			msg = fmt.Sprintf("Degraded: pid '%d' missed 1 check-in", c.proc.PID)
@@ -319,6 +348,10 @@ func (c *commandRuntime) start(comm Communicator) error {
 	}
 	env = append(env, fmt.Sprintf("%s=%s", envAgentComponentID, c.current.ID))
 	env = append(env, fmt.Sprintf("%s=%s", envAgentComponentType, c.getSpecType()))
+	if c.coreDumps != nil && !hasEnv(cmdSpec.Env, envGoTraceback) {
+		// Go components exit on a fatal error unless they are told to dump their core
+		env = append(env, envGoTraceback+"=crash")
+	}
 	uid, gid := os.Geteuid(), os.Getegid()
 	workDir, err := c.workDir(uid, gid)
 	if err != nil {
@@ -333,29 +366,60 @@ func (c *commandRuntime) start(comm Communicator) error {
 		return fmt.Errorf("execution of component prevented: %w", err)
 	}
 
+	// differentiate data paths
+	dataPath := filepath.Join(paths.Home(), "run", c.current.ID)
+	if err := checkRequiredResources(cmdSpec.Requires, dataPath); err != nil {
+		return err
+	}
+
 	if err := c.monitor.Prepare(c.current.ID); err != nil {
 		return err
 	}
 	args := c.monitor.EnrichArgs(c.current.ID, c.getSpecBinaryName(), cmdSpec.Args)
 
-	// differentiate data paths
-	dataPath := filepath.Join(paths.Home(), "run", c.current.ID)
 	_ = os.MkdirAll(dataPath, 0755)
 	args = append(args, "-E", "path.data="+dataPath)
 
+	cmd := &sandboxedCommand{path: path, args: args}
+	if c.sandbox {
+		cmd, err = sandboxCommand(cmdSpec.Sandbox, path, args)
+		if err != nil {
+			return fmt.Errorf("failed to apply the sandbox: %w", err)
+		}
+	}
+
 	// reset checkin state before starting the process.
 	c.lastCheckin = time.Time{}
 	c.missedCheckins = 0
 
-	proc, err := process.Start(path,
-		process.WithArgs(args),
+	if c.credsUsed {
+		if err := comm.RotateCredentials(); err != nil {
+			return err
+		}
+	}
+	c.credsUsed = true
+
+	opts := append([]process.CmdOption{attachOutErr(c.logStd, c.logErr), dirPath(workDir)}, cmd.opts...)
+	proc, err := process.Start(cmd.path,
+		process.WithArgs(cmd.args),
 		process.WithEnv(env),
-		process.WithCmdOptions(attachOutErr(c.logStd, c.logErr), dirPath(workDir)))
+		process.WithCmdOptions(opts...))
+	if cmd.release != nil {
+		cmd.release()
+	}
 	if err != nil {
 		return err
 	}
 
 	c.proc = proc
+	c.state.Sandbox = cmd.description()
+	c.oom = newOOMWatch(proc.PID)
+	c.denials = newDenialWatch(proc.PID)
+	if c.coreDumps != nil {
+		if err := enableCoreDumps(proc.PID); err != nil {
+			c.log.Warnf("Failed to enable the core dumps of pid '%d': %s", proc.PID, err)
+		}
+	}
 	c.forceCompState(client.UnitStateStarting, fmt.Sprintf("Starting: spawned pid '%d'", c.proc.PID))
 	c.startWatcher(proc, comm)
 	return nil
@@ -414,12 +478,18 @@ func (c *commandRuntime) startWatcher(info *process.Info, comm Communicator) {
 func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
 	switch c.actionState {
 	case actionStart:
+		exit := fmt.Sprintf("exited with code '%d'", state.ExitCode())
+		if reason, killed := c.oomKilled(state); killed {
+			exit = reason
+		} else if reason, crashed := crashReason(state); crashed && c.coreDumps != nil {
+			exit = reason + c.captureCoreDump(state.Pid())
+		}
 		if c.restartBucket != nil && c.restartBucket.Allow() {
-			stopMsg := fmt.Sprintf("Suppressing FAILED state due to restart for '%d' exited with code '%d'", state.Pid(), state.ExitCode())
+			stopMsg := fmt.Sprintf("Suppressing FAILED state due to restart for '%d' %s", state.Pid(), exit)
 			c.forceCompState(client.UnitStateStopped, stopMsg)
 		} else {
 			// report failure only if bucket is full of restart events
-			stopMsg := fmt.Sprintf("Failed: pid '%d' exited with code '%d'", state.Pid(), state.ExitCode())
+			stopMsg := fmt.Sprintf("Failed: pid '%d' %s", state.Pid(), exit)
 			c.forceCompState(client.UnitStateFailed, stopMsg)
 		}
 		return true
@@ -435,6 +505,36 @@ func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
 	return false
 }
 
+// oomKilled returns the failure reason when the process was killed by the OOM killer.
+func (c *commandRuntime) oomKilled(state *os.ProcessState) (string, bool) {
+	if c.oom == nil || c.oom.pid != state.Pid() {
+		return "", false
+	}
+	return c.oom.killed(state)
+}
+
+// captureCoreDump captures the core dump of the crashed process and returns the note on the dump
+// added to the failure message.
+func (c *commandRuntime) captureCoreDump(pid int) string {
+	path, err := c.coreDumps.capture(c.current.ID, c.getSpecBinaryName(), c.workDirPath(), pid)
+	if err != nil {
+		c.log.Warnf("Failed to capture the core dump of pid '%d': %s", pid, err)
+		return fmt.Sprintf(", core dump not captured: %s", err)
+	}
+	c.log.Infof("Captured the core dump of pid '%d' in %s", pid, path)
+	return fmt.Sprintf(", core dump captured in %s", path)
+}
+
+// hasEnv returns true when the environment variable is set by the specification.
+func hasEnv(env []component.CommandEnvSpec, name string) bool {
+	for _, e := range env {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *commandRuntime) workDirPath() string {
 	return filepath.Join(paths.Run(), c.current.ID)
 }
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+)
+
+// configDriftKeys returns the flattened keys that differ between the configuration a unit applied
+// and the configuration it is expected to apply. Only the keys are returned, never the values, so
+// secrets of the configuration don't end up in the state. All the keys of the expected
+// configuration are returned when the unit never applied a configuration.
+func configDriftKeys(applied *proto.UnitExpectedConfig, expected *proto.UnitExpectedConfig) []string {
+	appliedKeys := make(map[string]interface{})
+	if applied != nil && applied.GetSource() != nil {
+		flattenConfig("", applied.GetSource().AsMap(), appliedKeys)
+	}
+	expectedKeys := make(map[string]interface{})
+	if expected != nil && expected.GetSource() != nil {
+		flattenConfig("", expected.GetSource().AsMap(), expectedKeys)
+	}
+
+	var keys []string
+	for k, v := range expectedKeys {
+		if av, ok := appliedKeys[k]; !ok || !reflect.DeepEqual(av, v) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range appliedKeys {
+		if _, ok := expectedKeys[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flattenConfig adds the leaves of the configuration to out with their dotted keys, lists are
+// compared as a whole.
+func flattenConfig(prefix string, m map[string]interface{}, out map[string]interface{}) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+			flattenConfig(key, child, out)
+			continue
+		}
+		out[key] = v
+	}
+}
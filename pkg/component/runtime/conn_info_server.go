@@ -12,6 +12,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -28,7 +29,7 @@ type connInfoServer struct {
 }
 
 func newConnInfoServer(log *logger.Logger, comm Communicator, port int) (*connInfoServer, error) {
-	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	listener, err := net.Listen("tcp", netutil.JoinHostPort(netutil.Loopback(), port))
 	if err != nil {
 		return nil, fmt.Errorf("failed to start connection credentials listener: %w", err)
 	}
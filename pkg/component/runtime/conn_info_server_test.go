@@ -67,6 +67,10 @@ func (c *mockCommunicator) CheckinObserved() <-chan *proto.CheckinObserved {
 	return c.ch
 }
 
+func (c *mockCommunicator) RotateCredentials() error {
+	return nil
+}
+
 const testPort = 6788
 
 func getAddress() string {
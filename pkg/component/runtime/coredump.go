@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+)
+
+// envGoTraceback makes the Go components abort with a core dump on a fatal error instead of exiting.
+const envGoTraceback = "GOTRACEBACK"
+
+// coreDumps captures the core dumps of the crashed command components, compressed, in a directory
+// bounded to a maximum number of dumps.
+type coreDumps struct {
+	dir string
+	max int
+}
+
+// newCoreDumps returns the capture of the core dumps, nil when it is disabled.
+func newCoreDumps(cfg *configuration.CoreDumpsConfig) *coreDumps {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+	return &coreDumps{dir: paths.CoreDumps(), max: cfg.MaxDumps}
+}
+
+// capture finds the dump of the crashed process and compresses it in the directory, the oldest
+// dumps are removed once there are more than the maximum. It returns the path of the compressed dump.
+func (d *coreDumps) capture(componentID string, binaryName string, workDir string, pid int) (string, error) {
+	src, err := findCoreDump(binaryName, workDir, pid)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(d.dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", d.dir, err)
+	}
+	ext := ".core"
+	if strings.EqualFold(filepath.Ext(src), ".dmp") {
+		ext = ".dmp"
+	}
+	name := fmt.Sprintf("%s-%s-%d%s.gz",
+		strings.ReplaceAll(componentID, "/", "-"), time.Now().UTC().Format("20060102T150405Z"), pid, ext)
+	dst := filepath.Join(d.dir, name)
+	if err := compressFile(src, dst); err != nil {
+		return "", err
+	}
+	// the dump can take a lot of space, only the compressed copy is kept
+	_ = os.Remove(src)
+	d.prune()
+	return dst, nil
+}
+
+// prune removes the oldest dumps until the maximum is kept.
+func (d *coreDumps) prune() {
+	dumps := listCoreDumps(d.dir)
+	for len(dumps) > d.max {
+		_ = os.Remove(dumps[0])
+		dumps = dumps[1:]
+	}
+}
+
+// listCoreDumps returns the paths of the compressed dumps from the oldest to the most recent.
+func listCoreDumps(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	type dump struct {
+		path    string
+		modTime time.Time
+	}
+	var dumps []dump
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || !strings.HasSuffix(e.Name(), ".gz") {
+			continue
+		}
+		dumps = append(dumps, dump{path: filepath.Join(dir, e.Name()), modTime: info.ModTime()})
+	}
+	sort.SliceStable(dumps, func(i, j int) bool {
+		return dumps[i].modTime.Before(dumps[j].modTime)
+	})
+	result := make([]string, 0, len(dumps))
+	for _, d := range dumps {
+		result = append(result, d.path)
+	}
+	return result
+}
+
+// compressFile compresses src to dst with gzip, dst is only created once it is complete.
+func compressFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open the dump %s: %w", src, err)
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	defer os.Remove(tmp)
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to compress the dump %s: %w", src, err)
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to compress the dump %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, dst)
+}
@@ -0,0 +1,107 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	corePatternPath = "/proc/sys/kernel/core_pattern"
+	coreUsesPIDPath = "/proc/sys/kernel/core_uses_pid"
+)
+
+// enableCoreDumps raises the core size limit of the process, to unlimited when the agent is
+// privileged and to the hard limit otherwise.
+func enableCoreDumps(pid int) error {
+	limit := unix.Rlimit{Cur: unix.RLIM_INFINITY, Max: unix.RLIM_INFINITY}
+	if err := unix.Prlimit(pid, unix.RLIMIT_CORE, &limit, nil); err == nil {
+		return nil
+	}
+	if err := unix.Prlimit(pid, unix.RLIMIT_CORE, nil, &limit); err != nil {
+		return err
+	}
+	limit.Cur = limit.Max
+	return unix.Prlimit(pid, unix.RLIMIT_CORE, &limit, nil)
+}
+
+// findCoreDump returns the path of the core dump of the process, written where kernel.core_pattern
+// tells the kernel to.
+func findCoreDump(_ string, workDir string, pid int) (string, error) {
+	pattern, err := os.ReadFile(corePatternPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read kernel.core_pattern: %w", err)
+	}
+	usesPID, _ := os.ReadFile(coreUsesPIDPath)
+	return findCoreDumpWithPattern(strings.TrimSpace(string(pattern)), strings.TrimSpace(string(usesPID)) == "1", workDir, pid)
+}
+
+func findCoreDumpWithPattern(pattern string, usesPID bool, workDir string, pid int) (string, error) {
+	if strings.HasPrefix(pattern, "|") {
+		handler := strings.Fields(strings.TrimPrefix(pattern, "|"))
+		if len(handler) == 0 {
+			return "", fmt.Errorf("kernel.core_pattern pipes the core dumps to no program")
+		}
+		return "", fmt.Errorf("kernel.core_pattern pipes the core dumps to %s", handler[0])
+	}
+
+	glob := expandCorePattern(pattern, pid)
+	if usesPID && !strings.Contains(pattern, "%p") {
+		glob += "." + strconv.Itoa(pid)
+	}
+	if !filepath.IsAbs(glob) {
+		// relative to the working directory of the process
+		glob = filepath.Join(workDir, glob)
+	}
+	matches, err := filepath.Glob(glob)
+	if err != nil {
+		return "", fmt.Errorf("invalid kernel.core_pattern %s: %w", pattern, err)
+	}
+	var newest string
+	var newestInfo os.FileInfo
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if newestInfo == nil || info.ModTime().After(newestInfo.ModTime()) {
+			newest, newestInfo = m, info
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no core dump matches %s, check the core size limit", glob)
+	}
+	return newest, nil
+}
+
+// expandCorePattern replaces the PID specifiers of kernel.core_pattern and matches the other ones
+// with a wildcard.
+func expandCorePattern(pattern string, pid int) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case '%':
+			b.WriteByte('%')
+		case 'p', 'P':
+			b.WriteString(strconv.Itoa(pid))
+		default:
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandCorePattern(t *testing.T) {
+	assert.Equal(t, "core", expandCorePattern("core", 1234))
+	assert.Equal(t, "/var/crash/core.*.1234.*", expandCorePattern("/var/crash/core.%e.%p.%t", 1234))
+	assert.Equal(t, "core%1234", expandCorePattern("core%%%P", 1234))
+}
+
+func TestFindCoreDumpWithPattern(t *testing.T) {
+	workDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "core.filebeat.1234"), nil, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "core.filebeat.5678"), nil, 0600))
+
+	path, err := findCoreDumpWithPattern("core.%e.%p", false, workDir, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(workDir, "core.filebeat.1234"), path)
+
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "core.1234"), nil, 0600))
+	path, err = findCoreDumpWithPattern("core", true, workDir, 1234)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(workDir, "core.1234"), path)
+
+	_, err = findCoreDumpWithPattern("core.%p", false, workDir, 42)
+	assert.ErrorContains(t, err, "no core dump matches")
+
+	_, err = findCoreDumpWithPattern("|/usr/lib/systemd/systemd-coredump %P %u %g", false, workDir, 1234)
+	assert.EqualError(t, err, "kernel.core_pattern pipes the core dumps to /usr/lib/systemd/systemd-coredump")
+}
+
+func TestCrashReason(t *testing.T) {
+	// a core dump may be written in the working directory
+	cmd := exec.Command("/bin/sh", "-c", "kill -ABRT $$")
+	cmd.Dir = t.TempDir()
+	_ = cmd.Run()
+	reason, crashed := crashReason(cmd.ProcessState)
+	assert.True(t, crashed)
+	assert.Equal(t, "was killed by signal 'aborted'", reason)
+
+	cmd = exec.Command("/bin/sh", "-c", "kill -TERM $$")
+	_ = cmd.Run()
+	_, crashed = crashReason(cmd.ProcessState)
+	assert.False(t, crashed, "a terminated process did not crash")
+
+	cmd = exec.Command("/bin/sh", "-c", "exit 2")
+	_ = cmd.Run()
+	_, crashed = crashReason(cmd.ProcessState)
+	assert.False(t, crashed, "an exited process did not crash")
+}
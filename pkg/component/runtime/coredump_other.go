@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux && !windows
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+)
+
+// enableCoreDumps is a no-op, the core size limit is inherited from the Elastic Agent.
+func enableCoreDumps(int) error {
+	return nil
+}
+
+// findCoreDump returns the path of the core dump of the process in /cores.
+func findCoreDump(_ string, _ string, pid int) (string, error) {
+	path := fmt.Sprintf("/cores/core.%d", pid)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no core dump %s, check the core size limit: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "core.1234")
+	require.NoError(t, os.WriteFile(src, []byte("core dump content"), 0600))
+
+	dst := filepath.Join(dir, "dump.core.gz")
+	require.NoError(t, compressFile(src, dst))
+
+	f, err := os.Open(dst)
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "core dump content", string(content))
+	assert.NoFileExists(t, dst+".tmp")
+}
+
+func TestCoreDumpsPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"oldest.core.gz", "older.core.gz", "newest.core.gz"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, nil, 0600))
+		modTime := now.Add(time.Duration(i-3) * time.Hour)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	// only the compressed dumps are counted
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.txt"), nil, 0600))
+
+	d := &coreDumps{dir: dir, max: 2}
+	d.prune()
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "older.core.gz"),
+		filepath.Join(dir, "newest.core.gz"),
+	}, listCoreDumps(dir))
+	assert.FileExists(t, filepath.Join(dir, "other.txt"))
+}
@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// coreSignals are the signals terminating a process with a core dump.
+var coreSignals = map[syscall.Signal]bool{
+	syscall.SIGQUIT: true,
+	syscall.SIGILL:  true,
+	syscall.SIGTRAP: true,
+	syscall.SIGABRT: true,
+	syscall.SIGBUS:  true,
+	syscall.SIGFPE:  true,
+	syscall.SIGSEGV: true,
+	syscall.SIGSYS:  true,
+	syscall.SIGXCPU: true,
+	syscall.SIGXFSZ: true,
+}
+
+// crashReason returns how the process crashed, false when it exited or was killed by a signal
+// which does not dump its core.
+func crashReason(state *os.ProcessState) (string, bool) {
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() || !coreSignals[ws.Signal()] {
+		return "", false
+	}
+	return fmt.Sprintf("was killed by signal '%s'", ws.Signal()), true
+}
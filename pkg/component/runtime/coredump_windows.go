@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// exceptionCodes are the NTSTATUS error codes a crashed process exits with, like
+// 0xC0000005 for an access violation.
+const exceptionCodes = 0xC0000000
+
+// enableCoreDumps is a no-op, the minidumps are written by Windows Error Reporting.
+func enableCoreDumps(int) error {
+	return nil
+}
+
+// crashReason returns the exception the process crashed with, false when it exited.
+func crashReason(state *os.ProcessState) (string, bool) {
+	code := uint32(state.ExitCode())
+	if code < exceptionCodes {
+		return "", false
+	}
+	return fmt.Sprintf("crashed with exception '0x%08X'", code), true
+}
+
+// findCoreDump returns the path of the minidump of the process written by Windows Error Reporting
+// in the default folder of the local dumps.
+func findCoreDump(binaryName string, _ string, pid int) (string, error) {
+	exe := binaryName
+	if !strings.HasSuffix(strings.ToLower(exe), ".exe") {
+		exe += ".exe"
+	}
+	path := filepath.Join(os.Getenv("LOCALAPPDATA"), "CrashDumps", fmt.Sprintf("%s.%d.dmp", exe, pid))
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no minidump %s, Windows Error Reporting must be configured to write the local dumps: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,63 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/lsm"
+)
+
+// denialHintPeriod is how long a component is reported degraded after the last denial of its process.
+const denialHintPeriod = 5 * time.Minute
+
+// denialWatch follows the audit log for the operations of a command component denied by SELinux or
+// AppArmor, they are reported as a hint in the state of the component instead of being left for the
+// user to find in the audit log.
+type denialWatch struct {
+	pid  int
+	kind lsm.Kind
+
+	// log is the audit log read, empty when none can be read, and offset is where the next read
+	// starts
+	log    string
+	offset int64
+
+	// count is the number of denials of the process and last is the last of them
+	count    int
+	last     lsm.Denial
+	lastSeen time.Time
+}
+
+// newDenialWatch starts watching the audit log for the denials of the process.
+func newDenialWatch(pid int) *denialWatch {
+	w := &denialWatch{pid: pid}
+	w.init()
+	return w
+}
+
+// observe records the denial when it is one of the process.
+func (w *denialWatch) observe(d lsm.Denial, now time.Time) {
+	if d.PID != w.pid {
+		return
+	}
+	w.count++
+	w.last = d
+	w.lastSeen = now
+}
+
+// hint returns the hint reported in the state of the component, empty when the process had no
+// denial during the last denialHintPeriod.
+func (w *denialWatch) hint(now time.Time) string {
+	if w == nil || w.count == 0 || now.Sub(w.lastSeen) > denialHintPeriod {
+		return ""
+	}
+	denials := "1 denial"
+	if w.count > 1 {
+		denials = fmt.Sprintf("%d denials", w.count)
+	}
+	return fmt.Sprintf("had %s (last: %s), the %s policy of the host may be missing rules for the component", denials, w.last, w.last.Kind)
+}
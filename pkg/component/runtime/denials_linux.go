@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/lsm"
+)
+
+// init finds the audit log and starts reading it from its end, nothing is watched when the host
+// enforces no mandatory access control.
+func (w *denialWatch) init() {
+	w.kind = lsm.Detect()
+	if w.kind == lsm.None {
+		return
+	}
+	for _, path := range lsm.AuditLogs {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		info, err := f.Stat()
+		_ = f.Close()
+		if err != nil {
+			continue
+		}
+		w.log = path
+		w.offset = info.Size()
+		return
+	}
+}
+
+// sample reads the records added to the audit log since the last sample.
+func (w *denialWatch) sample() {
+	if w.log == "" {
+		return
+	}
+	f, err := os.Open(w.log)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return
+	}
+	if info.Size() < w.offset {
+		// the log was rotated, the new one is read from its start
+		w.offset = 0
+	}
+	if _, err := f.Seek(w.offset, io.SeekStart); err != nil {
+		return
+	}
+	now := time.Now()
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// a partial line is read again with the next sample
+			return
+		}
+		w.offset += int64(len(line))
+		if d, ok := lsm.ParseDenial(line); ok {
+			w.observe(d, now)
+		}
+	}
+}
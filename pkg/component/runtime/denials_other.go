@@ -0,0 +1,13 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package runtime
+
+// init does nothing, the denials are only watched on Linux.
+func (w *denialWatch) init() {}
+
+// sample does nothing, the denials are only watched on Linux.
+func (w *denialWatch) sample() {}
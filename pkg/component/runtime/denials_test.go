@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/internal/pkg/lsm"
+)
+
+func TestDenialWatchHint(t *testing.T) {
+	now := time.Now()
+	w := &denialWatch{pid: 1234}
+	assert.Empty(t, w.hint(now))
+
+	// denials of other processes are ignored
+	w.observe(lsm.Denial{Kind: lsm.SELinux, PID: 4321, Operation: "read", Target: "shadow"}, now)
+	assert.Empty(t, w.hint(now))
+
+	w.observe(lsm.Denial{Kind: lsm.SELinux, PID: 1234, Operation: "read", Target: "shadow"}, now)
+	assert.Equal(t, "had 1 denial (last: SELinux denied read on shadow), the SELinux policy of the host may be missing rules for the component", w.hint(now))
+
+	w.observe(lsm.Denial{Kind: lsm.SELinux, PID: 1234, Operation: "name_connect", Target: "/run/docker.sock"}, now)
+	assert.Equal(t, "had 2 denials (last: SELinux denied name_connect on /run/docker.sock), the SELinux policy of the host may be missing rules for the component", w.hint(now))
+
+	// the hint is cleared once the process has no more denials
+	assert.Empty(t, w.hint(now.Add(denialHintPeriod+time.Second)))
+}
@@ -0,0 +1,139 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// performProviderDiagnostics runs the diagnostic providers of the component one after the other,
+// each within its own timeout and size limit.
+func performProviderDiagnostics(ctx context.Context, log *logger.Logger, comp component.Component) []*proto.ActionDiagnosticUnitResult {
+	if comp.InputSpec == nil {
+		return nil
+	}
+	results := make([]*proto.ActionDiagnosticUnitResult, 0, len(comp.InputSpec.Spec.Diagnostics))
+	for _, spec := range comp.InputSpec.Spec.Diagnostics {
+		if ctx.Err() != nil {
+			break
+		}
+		startTime := time.Now()
+		results = append(results, runDiagnosticProvider(ctx, log, comp, spec))
+		log.Debugf("Diagnostic provider %s of component %s complete, took %s", spec.Name, comp.ID, time.Since(startTime))
+	}
+	return results
+}
+
+// runDiagnosticProvider returns the output of the provider, cut at its timeout and truncated at its
+// maximum size. An error is added at the end of the output.
+func runDiagnosticProvider(ctx context.Context, log *logger.Logger, comp component.Component, spec component.DiagnosticProviderSpec) *proto.ActionDiagnosticUnitResult {
+	output := &limitedBuffer{max: spec.MaxSize}
+	var err error
+	if len(spec.Args) > 0 {
+		errOutput := &limitedBuffer{max: maxServiceStatusLen}
+		err = executeCommandWithOutput(ctx, log, comp.InputSpec.BinaryPath, spec.Args, envSpecToEnv(spec.Env), spec.Timeout, output, errOutput)
+		if err != nil && errOutput.buf.Len() > 0 {
+			err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(errOutput.buf.Bytes()))
+		}
+	} else {
+		err = readDiagnosticFile(ctx, providerPath(comp, spec.Path), spec.Timeout, output)
+	}
+
+	content := output.buf.Bytes()
+	if output.truncated {
+		content = append(content, fmt.Sprintf("\n[truncated: the output exceeds the maximum size of %d bytes]\n", spec.MaxSize)...)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", spec.Timeout, err)
+		}
+		content = append(content, fmt.Sprintf("\nerror: %s\n", err)...)
+	}
+	return &proto.ActionDiagnosticUnitResult{
+		Name:        spec.Name,
+		Filename:    spec.Filename,
+		Description: spec.Description,
+		ContentType: spec.ContentType,
+		Content:     content,
+		Generated:   timestamppb.New(time.Now().UTC()),
+	}
+}
+
+// providerPath returns the path of the file of a provider, a relative path is relative to the data
+// path of the component.
+func providerPath(comp component.Component, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(paths.Run(), comp.ID, path)
+}
+
+// readDiagnosticFile copies the file to output. The file is read in the background, a read blocked
+// past the timeout, on a network file system for example, is abandoned.
+func readDiagnosticFile(ctx context.Context, path string, timeout time.Duration, output *limitedBuffer) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		data      []byte
+		truncated bool
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		f, err := os.Open(path)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer f.Close()
+		data := &limitedBuffer{max: output.max}
+		_, err = io.Copy(data, f)
+		done <- result{data: data.buf.Bytes(), truncated: data.truncated, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r := <-done:
+		_, _ = output.Write(r.data)
+		output.truncated = output.truncated || r.truncated
+		return r.err
+	}
+}
+
+// limitedBuffer is a buffer that keeps the first max bytes written to it, the rest is discarded.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int64
+	truncated bool
+}
+
+// Write never fails so a command isn't blocked once its output is truncated.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.max - int64(b.buf.Len())
+	if int64(len(p)) > remaining {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
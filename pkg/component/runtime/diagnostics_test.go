@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestRunDiagnosticProviderFile(t *testing.T) {
+	paths.SetTop(t.TempDir())
+	comp := component.Component{ID: "filestream-default", InputSpec: &component.InputRuntimeSpec{}}
+	dir := filepath.Join(paths.Run(), comp.ID, "registry", "filebeat")
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "log.json"), []byte("0123456789"), 0o600))
+
+	spec := component.DiagnosticProviderSpec{
+		Name:        "registry",
+		Filename:    "registry-log.json",
+		ContentType: "application/x-ndjson",
+		Path:        "registry/filebeat/log.json",
+		Timeout:     time.Second,
+		MaxSize:     100,
+	}
+	res := runDiagnosticProvider(context.Background(), logger.NewWithoutConfig(""), comp, spec)
+	assert.Equal(t, "registry-log.json", res.Filename)
+	assert.Equal(t, "application/x-ndjson", res.ContentType)
+	assert.Equal(t, "0123456789", string(res.Content))
+
+	spec.MaxSize = 4
+	res = runDiagnosticProvider(context.Background(), logger.NewWithoutConfig(""), comp, spec)
+	assert.True(t, strings.HasPrefix(string(res.Content), "0123\n[truncated: "), "output should be truncated: %s", res.Content)
+
+	spec.Path = "missing.json"
+	res = runDiagnosticProvider(context.Background(), logger.NewWithoutConfig(""), comp, spec)
+	assert.Contains(t, string(res.Content), "error: ")
+}
+
+func TestRunDiagnosticProviderCommand(t *testing.T) {
+	log := logp.NewLogger("test_service")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	exePath, err := prepareTestProg(ctx, log, t.TempDir(), progConfig{Output: "summary of the component"})
+	require.NoError(t, err)
+	comp := component.Component{ID: "endpoint-default", InputSpec: &component.InputRuntimeSpec{BinaryPath: exePath}}
+	spec := component.DiagnosticProviderSpec{
+		Name:     "summary",
+		Filename: "summary.txt",
+		Args:     []string{"summary"},
+		Timeout:  10 * time.Second,
+		MaxSize:  1024,
+	}
+	res := runDiagnosticProvider(ctx, log, comp, spec)
+	assert.Equal(t, "summary of the component", string(res.Content))
+
+	slowPath, err := prepareTestProg(ctx, log, t.TempDir(), progConfig{Output: "partial", SleepMS: 10000})
+	require.NoError(t, err)
+	comp.InputSpec.BinaryPath = slowPath
+	spec.Timeout = 500 * time.Millisecond
+	startTime := time.Now()
+	res = runDiagnosticProvider(ctx, log, comp, spec)
+	assert.Less(t, time.Since(startTime), 5*time.Second, "provider should be stopped at its timeout")
+	assert.Contains(t, string(res.Content), "error: timed out after 500ms")
+}
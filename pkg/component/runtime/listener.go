@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+
+package runtime
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// createListener creates the listener for the GRPC server, a Unix domain socket when the address
+// starts with unix:// otherwise a TCP listener.
+func createListener(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, "unix://") {
+		return net.Listen("tcp", addr)
+	}
+	path := strings.TrimPrefix(addr, "unix://")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		// left over from a previous run that didn't shutdown cleanly
+		_ = os.Remove(path)
+	}
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// only the user running the agent (and its spawned components) can connect
+	err = os.Chmod(path, 0700)
+	if err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateListener(t *testing.T) {
+	t.Run("tcp", func(t *testing.T) {
+		lis, err := createListener("localhost:0")
+		require.NoError(t, err)
+		defer lis.Close()
+		assert.Equal(t, "tcp", lis.Addr().Network())
+	})
+
+	t.Run("unix", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "sub", "grpc.sock")
+		lis, err := createListener("unix://" + path)
+		require.NoError(t, err)
+		assert.Equal(t, "unix", lis.Addr().Network())
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+		require.NoError(t, lis.Close())
+
+		// stale socket file from a previous run is replaced
+		require.NoError(t, os.WriteFile(path, nil, 0600))
+		lis, err = createListener("unix://" + path)
+		require.NoError(t, err)
+		require.NoError(t, lis.Close())
+	})
+}
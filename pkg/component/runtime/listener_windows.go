@@ -0,0 +1,40 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package runtime
+
+import (
+	"fmt"
+	"net"
+	"os/user"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/api/npipe"
+)
+
+// createListener creates the listener for the GRPC server, a named pipe when the address starts
+// with npipe:// otherwise a TCP listener.
+func createListener(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, "npipe://") {
+		return net.Listen("tcp", addr)
+	}
+	sd, err := securityDescriptor()
+	if err != nil {
+		return nil, err
+	}
+	return npipe.NewListener(npipe.TransformString(addr), sd)
+}
+
+func securityDescriptor() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	// Named pipe security and access rights.
+	// Only the user running the agent (and its spawned components) is given generic read/write access.
+	// See docs: https://docs.microsoft.com/en-us/windows/win32/ipc/named-pipe-security-and-access-rights
+	return "D:P(A;;GA;;;" + u.Uid + ")", nil
+}
@@ -12,7 +12,8 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"strings"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -29,8 +30,10 @@ import (
 	"github.com/elastic/elastic-agent-libs/atomic"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/core/authority"
+	"github.com/elastic/elastic-agent/internal/pkg/fault"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -47,6 +50,23 @@ const (
 
 	// stopCheckRetryPeriod is a idle time between checks for component stopped state
 	stopCheckRetryPeriod = 200 * time.Millisecond
+
+	// heartbeatPollInterval is the idle time between two attempts to lock the manager on heartbeat
+	heartbeatPollInterval = 100 * time.Millisecond
+)
+
+// ShutdownPhase is the phase of the shutdown of the components by the Manager.
+type ShutdownPhase string
+
+const (
+	// ShutdownPhaseInputs is the phase stopping the input components.
+	ShutdownPhaseInputs ShutdownPhase = "stopping_inputs"
+	// ShutdownPhaseShippers is the phase stopping the shipper components once they flushed their queues.
+	ShutdownPhaseShippers ShutdownPhase = "draining_shippers"
+	// ShutdownPhaseServices is the phase stopping the service components.
+	ShutdownPhaseServices ShutdownPhase = "stopping_services"
+	// ShutdownPhaseStopped is reported once all the components are stopped.
+	ShutdownPhaseStopped ShutdownPhase = "stopped"
 )
 
 var (
@@ -68,6 +88,7 @@ type ComponentUnitDiagnosticRequest struct {
 }
 
 // ComponentUnitDiagnostic provides a structure to map a component/unit to diagnostic results.
+// The Unit is empty for the results of the diagnostic providers of the component.
 type ComponentUnitDiagnostic struct {
 	Component component.Component
 	Unit      component.Unit
@@ -79,14 +100,20 @@ type ComponentUnitDiagnostic struct {
 type Manager struct {
 	proto.UnimplementedElasticAgentServer
 
-	logger     *logger.Logger
-	baseLogger *logger.Logger
-	ca         *authority.CertificateAuthority
-	listenAddr string
-	agentInfo  *info.AgentInfo
-	tracer     *apm.Tracer
-	monitor    MonitoringManager
-	grpcConfig *configuration.GRPCConfig
+	logger         *logger.Logger
+	baseLogger     *logger.Logger
+	ca             *authority.CertificateAuthority
+	listenAddr     string
+	agentInfo      *info.AgentInfo
+	tracer         *apm.Tracer
+	monitor        MonitoringManager
+	grpcConfig     *configuration.GRPCConfig
+	shutdownConfig *configuration.ShutdownConfig
+	// shutdownPhaseCh reports the phases of the shutdown, it is buffered so the
+	// shutdown never waits for them to be read
+	shutdownPhaseCh chan ShutdownPhase
+	coreDumps       *coreDumps
+	sandbox         bool
 
 	// netMx synchronizes the access to listener and server only
 	netMx    sync.RWMutex
@@ -129,25 +156,35 @@ func NewManager(
 	tracer *apm.Tracer,
 	monitor MonitoringManager,
 	grpcConfig *configuration.GRPCConfig,
+	shutdownConfig *configuration.ShutdownConfig,
+	coreDumpsConfig *configuration.CoreDumpsConfig,
+	sandboxConfig *configuration.SandboxConfig,
 ) (*Manager, error) {
 	ca, err := authority.NewCA()
 	if err != nil {
 		return nil, err
 	}
+	if shutdownConfig == nil {
+		shutdownConfig = configuration.DefaultShutdownConfig()
+	}
 	m := &Manager{
-		logger:        logger,
-		baseLogger:    baseLogger,
-		ca:            ca,
-		listenAddr:    listenAddr,
-		agentInfo:     agentInfo,
-		tracer:        tracer,
-		waitReady:     make(map[string]waitForReady),
-		current:       make(map[string]*componentRuntimeState),
-		shipperConns:  make(map[string]*shipperConn),
-		subscriptions: make(map[string][]*Subscription),
-		errCh:         make(chan error),
-		monitor:       monitor,
-		grpcConfig:    grpcConfig,
+		logger:          logger,
+		baseLogger:      baseLogger,
+		ca:              ca,
+		listenAddr:      listenAddr,
+		agentInfo:       agentInfo,
+		tracer:          tracer,
+		waitReady:       make(map[string]waitForReady),
+		current:         make(map[string]*componentRuntimeState),
+		shipperConns:    make(map[string]*shipperConn),
+		subscriptions:   make(map[string][]*Subscription),
+		errCh:           make(chan error),
+		monitor:         monitor,
+		grpcConfig:      grpcConfig,
+		shutdownConfig:  shutdownConfig,
+		shutdownPhaseCh: make(chan ShutdownPhase, 4),
+		coreDumps:       newCoreDumps(coreDumpsConfig),
+		sandbox:         sandboxConfig != nil && sandboxConfig.Enabled,
 	}
 	return m, nil
 }
@@ -163,9 +200,9 @@ func (m *Manager) Run(ctx context.Context) error {
 	m.running.Store(true)
 	m.shuttingDown.Store(false)
 
-	lis, err := net.Listen("tcp", m.listenAddr)
+	lis, err := createListener(m.listenAddr)
 	if err != nil {
-		return fmt.Errorf("error starting tcp listener for runtime manager: %w", err)
+		return fmt.Errorf("error starting listener for runtime manager: %w", err)
 	}
 	m.netMx.Lock()
 	m.listener = lis
@@ -218,6 +255,22 @@ func (m *Manager) Run(ctx context.Context) error {
 		}
 	}()
 
+	if interval := m.grpcConfig.CredentialsRotationInterval; interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.rotateCredentialsEvery(ctx, interval)
+		}()
+	}
+
+	if fault.Enabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fault.Watch(ctx, m.logger, paths.AgentFaultsFile())
+		}()
+	}
+
 	<-ctx.Done()
 	m.running.Store(false)
 	m.shuttingDown.Store(true)
@@ -405,11 +458,108 @@ func (m *Manager) PerformAction(ctx context.Context, comp component.Component, u
 	return respBody, nil
 }
 
+// RotateCredentials restarts the running components one after the other, so they connect back with new
+// credentials. Service components are not restarted, they keep their credentials.
+func (m *Manager) RotateCredentials(ctx context.Context) error {
+	m.currentMx.RLock()
+	states := make([]*componentRuntimeState, 0, len(m.current))
+	for _, state := range m.current {
+		comp := state.getCurrent()
+		if comp.InputSpec != nil && comp.InputSpec.Spec.Service != nil {
+			continue
+		}
+		states = append(states, state)
+	}
+	m.currentMx.RUnlock()
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].id < states[j].id
+	})
+
+	for _, state := range states {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if state.shuttingDown.Load() {
+			// removed in the meantime
+			continue
+		}
+		m.logger.Infof("Restarting component %s to rotate its credentials", state.id)
+		if err := state.runtime.Stop(); err != nil {
+			return fmt.Errorf("failed to stop component %s: %w", state.id, err)
+		}
+		m.waitForStopped(state, 0)
+		if state.shuttingDown.Load() {
+			continue
+		}
+		// the command runtime rotates the credentials when it starts the process again
+		if err := state.runtime.Start(); err != nil {
+			return fmt.Errorf("failed to start component %s: %w", state.id, err)
+		}
+	}
+	return nil
+}
+
+// ShutdownPhases returns the channel receiving the phases of the shutdown of the components once
+// the context of Run is done. The phases not read before the next shutdown are dropped.
+func (m *Manager) ShutdownPhases() <-chan ShutdownPhase {
+	return m.shutdownPhaseCh
+}
+
+// setShutdownPhase reports the phase of the shutdown without waiting for it to be read.
+func (m *Manager) setShutdownPhase(phase ShutdownPhase) {
+	select {
+	case m.shutdownPhaseCh <- phase:
+	default:
+	}
+}
+
+// Heartbeat returns once the manager is able to apply an update, it blocks while an update
+// or a change of the current components is in progress.
+func (m *Manager) Heartbeat(ctx context.Context) error {
+	t := time.NewTicker(heartbeatPollInterval)
+	defer t.Stop()
+
+	// polled instead of locked, so a deadlocked manager does not leak the goroutine calling Heartbeat
+	for !m.updateMx.TryLock() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	defer m.updateMx.Unlock()
+	for !m.currentMx.TryRLock() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	m.currentMx.RUnlock()
+	return nil
+}
+
+func (m *Manager) rotateCredentialsEvery(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := m.RotateCredentials(ctx); err != nil && ctx.Err() == nil {
+				m.logger.Errorf("Failed to rotate the credentials of the components: %s", err)
+			}
+		}
+	}
+}
+
 // PerformDiagnostics executes the diagnostic action for the provided units. If no units are provided then
-// it performs diagnostics for all current units.
+// it performs diagnostics for all current units and runs the diagnostic providers of all current components.
 func (m *Manager) PerformDiagnostics(ctx context.Context, req ...ComponentUnitDiagnosticRequest) []ComponentUnitDiagnostic {
 	// build results from units
 	var results []ComponentUnitDiagnostic
+	var providers []component.Component
 	if len(req) > 0 {
 		for _, q := range req {
 			r := m.getRuntimeFromUnit(q.Component, q.Unit)
@@ -429,6 +579,9 @@ func (m *Manager) PerformDiagnostics(ctx context.Context, req ...ComponentUnitDi
 		m.currentMx.RLock()
 		for _, r := range m.current {
 			currComp := r.getCurrent()
+			if currComp.InputSpec != nil && len(currComp.InputSpec.Spec.Diagnostics) > 0 {
+				providers = append(providers, currComp)
+			}
 			for _, u := range currComp.Units {
 				var err error
 				if currComp.Err != nil {
@@ -466,6 +619,12 @@ func (m *Manager) PerformDiagnostics(ctx context.Context, req ...ComponentUnitDi
 		}
 		results[i] = r
 	}
+	for _, comp := range providers {
+		results = append(results, ComponentUnitDiagnostic{
+			Component: comp,
+			Results:   performProviderDiagnostics(ctx, m.logger, comp),
+		})
+	}
 	return results
 }
 
@@ -708,20 +867,15 @@ func (m *Manager) update(components []component.Component, teardown bool) error
 		stop = append(stop, existing)
 	}
 	m.currentMx.RUnlock()
-	if len(stop) > 0 {
-		var stoppedWg sync.WaitGroup
-		stoppedWg.Add(len(stop))
-		for _, existing := range stop {
-			_ = existing.stop(teardown)
-			// stop is async, wait for operation to finish,
-			// otherwise new instance may be started and components
-			// may fight for resources (e.g ports, files, locks)
-			go func(state *componentRuntimeState) {
-				m.waitForStopped(state)
-				stoppedWg.Done()
-			}(existing)
+	m.stopComponents(stop, teardown, 0)
+
+	// components only start once the components they depend on are ready
+	deps := componentDependencies(components)
+	if _, cyclic := dependencyLevels(deps); len(cyclic) > 0 {
+		m.logger.Warnf("Components %v have cyclic dependencies, ignoring their dependencies", cyclic)
+		for _, id := range cyclic {
+			deps[id] = nil
 		}
-		stoppedWg.Wait()
 	}
 
 	// start all not started
@@ -735,6 +889,11 @@ func (m *Manager) update(components []component.Component, teardown bool) error
 		m.currentMx.Lock()
 		m.current[comp.ID] = state
 		m.currentMx.Unlock()
+		if len(deps[comp.ID]) > 0 {
+			// don't block the update while the dependencies are starting
+			go m.startWhenReady(state, deps[comp.ID])
+			continue
+		}
 		if err = state.start(); err != nil {
 			return fmt.Errorf("failed to start component %s: %w", comp.ID, err)
 		}
@@ -743,19 +902,90 @@ func (m *Manager) update(components []component.Component, teardown bool) error
 	return nil
 }
 
-func (m *Manager) waitForStopped(comp *componentRuntimeState) {
+// stopComponents stops the components and waits for them to be stopped. The dependents are
+// stopped before their dependencies, components without dependencies between them are stopped
+// concurrently.
+//
+// When timeout is zero each component is waited for the time it needs to stop, see stopTimeout.
+// Otherwise, timeout bounds the time to wait for all the components. It returns false when
+// the components are not all stopped in time.
+func (m *Manager) stopComponents(states []*componentRuntimeState, teardown bool, timeout time.Duration) bool {
+	if len(states) == 0 {
+		return true
+	}
+
+	byID := make(map[string]*componentRuntimeState, len(states))
+	comps := make([]component.Component, 0, len(states))
+	for _, state := range states {
+		comp := state.getCurrent()
+		byID[comp.ID] = state
+		comps = append(comps, comp)
+	}
+	levels, cyclic := dependencyLevels(componentDependencies(comps))
+	if len(cyclic) > 0 {
+		m.logger.Warnf("Components %v have cyclic dependencies, ignoring their dependencies", cyclic)
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	stopped := true
+	for i := len(levels) - 1; i >= 0; i-- {
+		levelTimeout := timeout
+		if !deadline.IsZero() {
+			// the components left still get the chance to start stopping
+			levelTimeout = time.Until(deadline)
+			if levelTimeout <= 0 {
+				levelTimeout = time.Nanosecond
+			}
+		}
+
+		var stoppedWg sync.WaitGroup
+		var stoppedMx sync.Mutex
+		stoppedWg.Add(len(levels[i]))
+		for _, id := range levels[i] {
+			existing := byID[id]
+			_ = existing.stop(teardown)
+			// stop is async, wait for operation to finish,
+			// otherwise new instance may be started and components
+			// may fight for resources (e.g ports, files, locks)
+			go func(state *componentRuntimeState) {
+				if !m.waitForStopped(state, levelTimeout) {
+					stoppedMx.Lock()
+					stopped = false
+					stoppedMx.Unlock()
+				}
+				stoppedWg.Done()
+			}(existing)
+		}
+		stoppedWg.Wait()
+	}
+	return stopped
+}
+
+// stopTimeout returns the maximum time to wait for the component to stop.
+func stopTimeout(comp component.Component) time.Duration {
+	if comp.InputSpec != nil &&
+		comp.InputSpec.Spec.Service != nil &&
+		comp.InputSpec.Spec.Service.Operations.Uninstall != nil &&
+		comp.InputSpec.Spec.Service.Operations.Uninstall.Timeout > 0 {
+		// if component is a service and timeout is defined, use the one defined
+		return comp.InputSpec.Spec.Service.Operations.Uninstall.Timeout
+	}
+	return defaultStopTimeout
+}
+
+// waitForStopped waits for the component to be stopped or removed, at most timeout or the
+// stopTimeout of the component when timeout is zero. It returns false on timeout.
+func (m *Manager) waitForStopped(comp *componentRuntimeState, timeout time.Duration) bool {
 	if comp == nil {
-		return
+		return true
 	}
 	currComp := comp.getCurrent()
 	compID := currComp.ID
-	timeout := defaultStopTimeout
-	if currComp.InputSpec != nil &&
-		currComp.InputSpec.Spec.Service != nil &&
-		currComp.InputSpec.Spec.Service.Operations.Uninstall != nil &&
-		currComp.InputSpec.Spec.Service.Operations.Uninstall.Timeout > 0 {
-		// if component is a service and timeout is defined, use the one defined
-		timeout = currComp.InputSpec.Spec.Service.Operations.Uninstall.Timeout
+	if timeout <= 0 {
+		timeout = stopTimeout(currComp)
 	}
 
 	timeoutCh := time.After(timeout)
@@ -764,29 +994,62 @@ func (m *Manager) waitForStopped(comp *componentRuntimeState) {
 		latestState := comp.latestState
 		comp.latestMx.RUnlock()
 		if latestState.State == client.UnitStateStopped {
-			return
+			return true
 		}
 
 		m.currentMx.RLock()
 		if _, exists := m.current[compID]; !exists {
 			m.currentMx.RUnlock()
-			return
+			return true
 		}
 		m.currentMx.RUnlock()
 
 		select {
 		case <-timeoutCh:
-			return
+			return false
 		case <-time.After(stopCheckRetryPeriod):
 		}
 	}
 }
 
 // Called from Manager's Run goroutine.
+//
+// The components are stopped in sequence so no data is lost: the inputs are stopped first so
+// nothing new is published, then the shippers are stopped and flush their queues, bounded by
+// the drain timeout, the service components are stopped last.
 func (m *Manager) shutdown() {
 	// don't tear down as this is just a shutdown, so components most likely will come back
 	// on next start of the manager
-	_ = m.update([]component.Component{}, false)
+	m.updateMx.Lock()
+	var inputs, shippers, services []*componentRuntimeState
+	m.currentMx.RLock()
+	for _, state := range m.current {
+		comp := state.getCurrent()
+		switch {
+		case comp.ShipperSpec != nil:
+			shippers = append(shippers, state)
+		case comp.InputSpec != nil && comp.InputSpec.Spec.Service != nil:
+			services = append(services, state)
+		default:
+			inputs = append(inputs, state)
+		}
+	}
+	m.currentMx.RUnlock()
+
+	m.setShutdownPhase(ShutdownPhaseInputs)
+	m.logger.Infof("Shutdown: stopping %d input components", len(inputs))
+	if !m.stopComponents(inputs, false, m.shutdownConfig.InputsTimeout) {
+		m.logger.Warnf("Shutdown: input components not stopped after %s, stopping the shippers anyway", m.shutdownConfig.InputsTimeout)
+	}
+	m.setShutdownPhase(ShutdownPhaseShippers)
+	m.logger.Infof("Shutdown: stopping %d shipper components, waiting up to %s for them to flush", len(shippers), m.shutdownConfig.DrainTimeout)
+	if !m.stopComponents(shippers, false, m.shutdownConfig.DrainTimeout) {
+		m.logger.Warnf("Shutdown: shipper components not flushed after %s, events still queued may be lost", m.shutdownConfig.DrainTimeout)
+	}
+	m.setShutdownPhase(ShutdownPhaseServices)
+	m.logger.Infof("Shutdown: stopping %d service components", len(services))
+	m.stopComponents(services, false, 0)
+	m.updateMx.Unlock()
 
 	// wait until all components are removed
 	for {
@@ -794,6 +1057,8 @@ func (m *Manager) shutdown() {
 		length := len(m.current)
 		m.currentMx.RUnlock()
 		if length <= 0 {
+			m.logger.Info("Shutdown: all components stopped")
+			m.setShutdownPhase(ShutdownPhaseStopped)
 			return
 		}
 		<-time.After(100 * time.Millisecond)
@@ -841,8 +1106,8 @@ func (m *Manager) getCertificate(chi *tls.ClientHelloInfo) (*tls.Certificate, er
 
 	m.currentMx.RLock()
 	for _, runtime := range m.current {
-		if runtime.comm.name == chi.ServerName {
-			cert = runtime.comm.cert.Certificate
+		if name, _, pair := runtime.comm.credentials(); name == chi.ServerName {
+			cert = pair.Certificate
 			break
 		}
 	}
@@ -872,7 +1137,7 @@ func (m *Manager) getRuntimeFromToken(token string) *componentRuntimeState {
 	defer m.currentMx.RUnlock()
 
 	for _, runtime := range m.current {
-		if runtime.comm.token == token {
+		if _, t, _ := runtime.comm.credentials(); t == token {
 			return runtime
 		}
 	}
@@ -896,14 +1161,16 @@ func (m *Manager) getRuntimeFromUnit(comp component.Component, unit component.Un
 }
 
 func (m *Manager) getListenAddr() string {
-	addr := strings.SplitN(m.listenAddr, ":", 2)
-	if len(addr) == 2 && addr[1] == "0" {
+	host, port, err := net.SplitHostPort(m.listenAddr)
+	if err == nil && port == "0" {
 		m.netMx.RLock()
 		lis := m.listener
 		m.netMx.RUnlock()
 		if lis != nil {
-			port := lis.Addr().(*net.TCPAddr).Port
-			return fmt.Sprintf("%s:%d", addr[0], port)
+			// only a TCP listener has a random port to resolve
+			if tcpAddr, ok := lis.Addr().(*net.TCPAddr); ok {
+				return net.JoinHostPort(host, strconv.Itoa(tcpAddr.Port))
+			}
 		}
 	}
 	return m.listenAddr
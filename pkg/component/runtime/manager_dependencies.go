@@ -0,0 +1,252 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"sort"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// dependencyReadyTimeout is the maximum amount of time a component waits for its dependencies
+// to be ready before it is started anyway.
+var dependencyReadyTimeout = 30 * time.Second
+
+// componentDependencies returns the IDs of the components each component depends on.
+//
+// A component depends on the components running the input types in its DependsOn and on the
+// shipper it uses as output. Dependencies that are not part of components are ignored.
+func componentDependencies(components []component.Component) map[string][]string {
+	byType := make(map[string][]string)
+	ids := make(map[string]bool, len(components))
+	for _, comp := range components {
+		ids[comp.ID] = true
+		if comp.InputSpec != nil {
+			byType[comp.InputType] = append(byType[comp.InputType], comp.ID)
+		}
+	}
+
+	deps := make(map[string][]string, len(components))
+	for _, comp := range components {
+		var compDeps []string
+		for _, inputType := range comp.DependsOn {
+			for _, id := range byType[inputType] {
+				if id != comp.ID {
+					compDeps = appendMissing(compDeps, id)
+				}
+			}
+		}
+		if comp.ShipperRef != nil && ids[comp.ShipperRef.ComponentID] {
+			compDeps = appendMissing(compDeps, comp.ShipperRef.ComponentID)
+		}
+		sort.Strings(compDeps)
+		deps[comp.ID] = compDeps
+	}
+	return deps
+}
+
+// dependencyLevels orders the components in levels, every component only depends on components
+// of the previous levels. Components are sorted by ID inside a level so the order is deterministic.
+//
+// The components that are part of a dependency cycle cannot be ordered, they are returned in
+// cyclic and their dependencies are ignored to order them. The components that only depend on
+// a cycle are ordered after it.
+func dependencyLevels(deps map[string][]string) (levels [][]string, cyclic []string) {
+	cyclic = dependencyCycles(deps)
+	inCycle := make(map[string]bool, len(cyclic))
+	for _, id := range cyclic {
+		inCycle[id] = true
+	}
+
+	remaining := make(map[string]int, len(deps))
+	dependents := make(map[string][]string, len(deps))
+	for id, compDeps := range deps {
+		remaining[id] = 0
+		if inCycle[id] {
+			continue
+		}
+		for _, dep := range compDeps {
+			if _, ok := deps[dep]; !ok {
+				continue
+			}
+			remaining[id]++
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	var level []string
+	for id, count := range remaining {
+		if count == 0 {
+			level = append(level, id)
+		}
+	}
+	for len(level) > 0 {
+		sort.Strings(level)
+		levels = append(levels, level)
+		var next []string
+		for _, id := range level {
+			for _, dependent := range dependents[id] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		level = next
+	}
+	return levels, cyclic
+}
+
+// dependencyCycles returns the sorted IDs of the components that are part of a dependency cycle,
+// a component depending on itself included. It finds the strongly connected components of the
+// dependency graph with Tarjan's algorithm.
+func dependencyCycles(deps map[string][]string) []string {
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	index := make(map[string]int, len(deps))
+	lowLink := make(map[string]int, len(deps))
+	onStack := make(map[string]bool, len(deps))
+	var stack []string
+	var cyclic []string
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		lowLink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		selfLoop := false
+		for _, dep := range deps[id] {
+			if _, ok := deps[dep]; !ok {
+				continue
+			}
+			if dep == id {
+				selfLoop = true
+			}
+			if _, visited := index[dep]; !visited {
+				visit(dep)
+				if lowLink[dep] < lowLink[id] {
+					lowLink[id] = lowLink[dep]
+				}
+			} else if onStack[dep] && index[dep] < lowLink[id] {
+				lowLink[id] = index[dep]
+			}
+		}
+		if lowLink[id] != index[id] {
+			return
+		}
+		// id is the root of a strongly connected component, pop its members
+		var members []string
+		for {
+			member := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[member] = false
+			members = append(members, member)
+			if member == id {
+				break
+			}
+		}
+		if len(members) > 1 || selfLoop {
+			cyclic = append(cyclic, members...)
+		}
+	}
+	for _, id := range ids {
+		if _, visited := index[id]; !visited {
+			visit(id)
+		}
+	}
+	sort.Strings(cyclic)
+	return cyclic
+}
+
+// startWhenReady starts the component once all the components it depends on are ready.
+//
+// A dependency is ready once it reports healthy or degraded. The component is started anyway
+// when a dependency fails or when they are not ready after dependencyReadyTimeout. Nothing
+// is started if the component is stopped or replaced in the meantime.
+func (m *Manager) startWhenReady(state *componentRuntimeState, dependsOn []string) {
+	compID := state.getCurrent().ID
+	timeoutCh := time.After(dependencyReadyTimeout)
+	for {
+		if !m.isCurrent(compID, state) {
+			return
+		}
+
+		ready, failed := m.dependenciesReady(dependsOn)
+		if ready {
+			break
+		}
+		if failed != "" {
+			state.logger.Warnf("Component %s depends on %s which failed, starting it anyway", compID, failed)
+			break
+		}
+
+		select {
+		case <-timeoutCh:
+			state.logger.Warnf("Component %s dependencies %v not ready after %s, starting it anyway", compID, dependsOn, dependencyReadyTimeout)
+		case <-time.After(stopCheckRetryPeriod):
+			continue
+		}
+		break
+	}
+
+	if !m.isCurrent(compID, state) {
+		return
+	}
+	if err := state.start(); err != nil {
+		state.logger.Errorf("Failed to start component %s: %s", compID, err)
+	}
+}
+
+// isCurrent returns true when state is the running state of the component and it is not stopping.
+func (m *Manager) isCurrent(compID string, state *componentRuntimeState) bool {
+	if state.shuttingDown.Load() {
+		return false
+	}
+	m.currentMx.RLock()
+	defer m.currentMx.RUnlock()
+	current, ok := m.current[compID]
+	return ok && current == state
+}
+
+// dependenciesReady returns true when all the components in dependsOn are ready, a removed
+// component is not waited for. When a component failed its ID is returned.
+func (m *Manager) dependenciesReady(dependsOn []string) (bool, string) {
+	m.currentMx.RLock()
+	defer m.currentMx.RUnlock()
+
+	ready := true
+	for _, id := range dependsOn {
+		dep, ok := m.current[id]
+		if !ok {
+			continue
+		}
+		dep.latestMx.RLock()
+		state := dep.latestState.State
+		dep.latestMx.RUnlock()
+		switch state {
+		case client.UnitStateHealthy, client.UnitStateDegraded:
+		case client.UnitStateFailed:
+			return false, id
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
+func appendMissing(s []string, v string) []string {
+	for _, i := range s {
+		if i == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
@@ -0,0 +1,155 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/apm/apmtest"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestComponentDependencies(t *testing.T) {
+	components := []component.Component{
+		{
+			ID:         "filestream-default",
+			InputType:  "filestream",
+			InputSpec:  &component.InputRuntimeSpec{InputType: "filestream"},
+			ShipperRef: &component.ShipperReference{ComponentID: "shipper-default"},
+		},
+		{
+			ID:        "endpoint-default",
+			InputType: "endpoint",
+			InputSpec: &component.InputRuntimeSpec{InputType: "endpoint"},
+			DependsOn: []string{"kernel-loader", "unknown"},
+		},
+		{
+			ID:        "kernel-loader-default",
+			InputType: "kernel-loader",
+			InputSpec: &component.InputRuntimeSpec{InputType: "kernel-loader"},
+		},
+		{
+			ID:        "kernel-loader-other",
+			InputType: "kernel-loader",
+			InputSpec: &component.InputRuntimeSpec{InputType: "kernel-loader"},
+		},
+		{
+			ID:          "shipper-default",
+			ShipperSpec: &component.ShipperRuntimeSpec{ShipperType: "shipper"},
+		},
+	}
+
+	deps := componentDependencies(components)
+	assert.Equal(t, map[string][]string{
+		"filestream-default":    {"shipper-default"},
+		"endpoint-default":      {"kernel-loader-default", "kernel-loader-other"},
+		"kernel-loader-default": nil,
+		"kernel-loader-other":   nil,
+		"shipper-default":       nil,
+	}, deps)
+
+	levels, cyclic := dependencyLevels(deps)
+	assert.Empty(t, cyclic)
+	assert.Equal(t, [][]string{
+		{"kernel-loader-default", "kernel-loader-other", "shipper-default"},
+		{"endpoint-default", "filestream-default"},
+	}, levels)
+}
+
+func TestDependencyLevelsCycle(t *testing.T) {
+	levels, cyclic := dependencyLevels(map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"b"},
+		"d": nil,
+		"e": {"d", "removed"},
+		"f": {"f"},
+		"g": {"a", "h"},
+		"h": {"g"},
+	})
+	// only the members of a cycle are cyclic, "a" depends on a cycle and is ordered after it
+	assert.Equal(t, []string{"b", "c", "f", "g", "h"}, cyclic)
+	assert.Equal(t, [][]string{{"b", "c", "d", "f", "g", "h"}, {"a", "e"}}, levels)
+}
+
+func TestManagerStartWhenReady(t *testing.T) {
+	ai, _ := info.NewAgentInfo(true)
+	m, err := NewManager(
+		newDebugLogger(t),
+		newDebugLogger(t),
+		"localhost:0",
+		ai,
+		apmtest.DiscardTracer,
+		newTestMonitoringMgr(),
+		configuration.DefaultGRPCConfig(),
+		configuration.DefaultShutdownConfig(),
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	add := func(id string, latest client.UnitState) (*componentRuntimeState, *fakeStopRuntime) {
+		rt := &fakeStopRuntime{}
+		state := &componentRuntimeState{
+			manager:     m,
+			logger:      m.logger,
+			id:          id,
+			currComp:    component.Component{ID: id, InputSpec: &component.InputRuntimeSpec{InputType: id}},
+			runtime:     rt,
+			latestState: ComponentState{State: latest},
+			actions:     make(map[string]func(*proto.ActionResponse)),
+		}
+		rt.state = state
+		m.current[id] = state
+		return state, rt
+	}
+	setLatest := func(state *componentRuntimeState, latest client.UnitState) {
+		state.latestMx.Lock()
+		state.latestState = ComponentState{State: latest}
+		state.latestMx.Unlock()
+	}
+
+	t.Run("waits for the dependency to be healthy", func(t *testing.T) {
+		dependency, _ := add("kernel-loader", client.UnitStateStarting)
+		dependent, rt := add("endpoint", client.UnitStateStarting)
+
+		done := make(chan struct{})
+		go func() {
+			m.startWhenReady(dependent, []string{"kernel-loader"})
+			close(done)
+		}()
+
+		<-time.After(3 * stopCheckRetryPeriod)
+		assert.True(t, rt.startCalled().IsZero(), "started before its dependency is healthy")
+
+		setLatest(dependency, client.UnitStateHealthy)
+		select {
+		case <-done:
+		case <-time.After(dependencyReadyTimeout):
+			t.Fatalf("not started after its dependency is healthy")
+		}
+		assert.False(t, rt.startCalled().IsZero(), "not started after its dependency is healthy")
+	})
+
+	t.Run("started anyway once the dependency is not ready in time", func(t *testing.T) {
+		defer func(prev time.Duration) { dependencyReadyTimeout = prev }(dependencyReadyTimeout)
+		dependencyReadyTimeout = 3 * stopCheckRetryPeriod
+
+		add("kernel-loader", client.UnitStateStarting)
+		dependent, rt := add("endpoint", client.UnitStateStarting)
+
+		start := time.Now()
+		m.startWhenReady(dependent, []string{"kernel-loader"})
+		assert.GreaterOrEqual(t, rt.startCalled().Sub(start), dependencyReadyTimeout)
+	})
+}
@@ -77,6 +77,9 @@ func TestManager_SimpleComponentErr(t *testing.T) {
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
 		configuration.DefaultGRPCConfig(),
+		configuration.DefaultShutdownConfig(),
+		nil,
+		nil,
 	)
 	require.NoError(t, err)
 
@@ -173,7 +176,7 @@ func TestManager_FakeInput_StartStop(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -299,7 +302,7 @@ func TestManager_FakeInput_Features(t *testing.T) {
 		agentInfo,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 
 	managerErrCh := make(chan error)
@@ -489,7 +492,7 @@ func TestManager_FakeInput_BadUnitToGood(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -655,7 +658,7 @@ func TestManager_FakeInput_GoodUnitToBad(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -811,7 +814,7 @@ func TestManager_FakeInput_NoDeadlock(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -945,7 +948,7 @@ func TestManager_FakeInput_Configure(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1065,7 +1068,7 @@ func TestManager_FakeInput_RemoveUnit(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1218,7 +1221,7 @@ func TestManager_FakeInput_ActionState(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1342,7 +1345,7 @@ func TestManager_FakeInput_Restarts(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1477,7 +1480,7 @@ func TestManager_FakeInput_Restarts_ConfigKill(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1619,7 +1622,7 @@ func TestManager_FakeInput_KeepsRestarting(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1761,7 +1764,7 @@ func TestManager_FakeInput_RestartsOnMissedCheckins(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -1876,7 +1879,7 @@ func TestManager_FakeInput_InvalidAction(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2000,7 +2003,7 @@ func TestManager_FakeInput_MultiComponent(t *testing.T) {
 		agentInfo,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 
 	errCh := make(chan error)
@@ -2213,7 +2216,7 @@ func TestManager_FakeInput_LogLevel(t *testing.T) {
 		ai,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 
 	errCh := make(chan error)
@@ -2358,7 +2361,7 @@ func TestManager_FakeShipper(t *testing.T) {
 	defer cancel()
 
 	ai, _ := info.NewAgentInfo(true)
-	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig())
+	m, err := NewManager(newDebugLogger(t), newDebugLogger(t), "localhost:0", ai, apmtest.DiscardTracer, newTestMonitoringMgr(), configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err)
 	errCh := make(chan error)
 	go func() {
@@ -2657,7 +2660,7 @@ func TestManager_FakeInput_OutputChange(t *testing.T) {
 		ai,
 		apmtest.DiscardTracer,
 		newTestMonitoringMgr(),
-		configuration.DefaultGRPCConfig())
+		configuration.DefaultGRPCConfig(), configuration.DefaultShutdownConfig(), nil, nil)
 	require.NoError(t, err, "could not crete new manager")
 
 	errCh := make(chan error)
@@ -2998,3 +3001,177 @@ func newTestMonitoringMgr() *testMonitoringManager { return &testMonitoringManag
 func (*testMonitoringManager) EnrichArgs(_ string, _ string, args []string) []string { return args }
 func (*testMonitoringManager) Prepare(_ string) error                                { return nil }
 func (*testMonitoringManager) Cleanup(string) error                                  { return nil }
+
+func TestManager_Heartbeat(t *testing.T) {
+	ai, _ := info.NewAgentInfo(true)
+	m, err := NewManager(
+		newDebugLogger(t),
+		newDebugLogger(t),
+		"localhost:0",
+		ai,
+		apmtest.DiscardTracer,
+		newTestMonitoringMgr(),
+		configuration.DefaultGRPCConfig(),
+		configuration.DefaultShutdownConfig(),
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Heartbeat(context.Background()))
+
+	// an update stuck in progress blocks the heartbeat
+	m.updateMx.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*heartbeatPollInterval)
+	defer cancel()
+	assert.ErrorIs(t, m.Heartbeat(ctx), context.DeadlineExceeded)
+
+	done := make(chan error)
+	go func() {
+		done <- m.Heartbeat(context.Background())
+	}()
+	m.updateMx.Unlock()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("heartbeat not returned once the manager is unlocked")
+	}
+}
+
+func TestManagerShutdownSequence(t *testing.T) {
+	ai, _ := info.NewAgentInfo(true)
+	shutdownCfg := &configuration.ShutdownConfig{
+		InputsTimeout: 500 * time.Millisecond,
+		DrainTimeout:  500 * time.Millisecond,
+	}
+	m, err := NewManager(
+		newDebugLogger(t),
+		newDebugLogger(t),
+		"localhost:0",
+		ai,
+		apmtest.DiscardTracer,
+		newTestMonitoringMgr(),
+		configuration.DefaultGRPCConfig(),
+		shutdownCfg,
+		nil,
+		nil,
+	)
+	require.NoError(t, err)
+
+	const slowStop = 3 * time.Second
+	runtimes := map[string]*fakeStopRuntime{}
+	add := func(comp component.Component, delay time.Duration) {
+		rt := &fakeStopRuntime{delay: delay}
+		state := &componentRuntimeState{
+			manager:     m,
+			logger:      m.logger,
+			id:          comp.ID,
+			currComp:    comp,
+			runtime:     rt,
+			latestState: ComponentState{State: client.UnitStateHealthy},
+			actions:     make(map[string]func(*proto.ActionResponse)),
+		}
+		rt.state = state
+		runtimes[comp.ID] = rt
+		m.current[comp.ID] = state
+	}
+	add(component.Component{ID: "fast-input", InputSpec: &component.InputRuntimeSpec{InputType: "fast"}}, 10*time.Millisecond)
+	add(component.Component{ID: "slow-input", InputSpec: &component.InputRuntimeSpec{InputType: "slow"}}, slowStop)
+	add(component.Component{ID: "shipper", ShipperSpec: &component.ShipperRuntimeSpec{ShipperType: "shipper"}}, slowStop)
+	add(component.Component{ID: "service", InputSpec: &component.InputRuntimeSpec{
+		InputType: "service",
+		Spec:      component.InputSpec{Service: &component.ServiceSpec{}},
+	}}, 10*time.Millisecond)
+
+	start := time.Now()
+	m.shutdown()
+
+	m.currentMx.RLock()
+	assert.Empty(t, m.current, "all components must be removed")
+	m.currentMx.RUnlock()
+
+	slowInput, shipper, service := runtimes["slow-input"], runtimes["shipper"], runtimes["service"]
+	assert.GreaterOrEqual(t, shipper.stopCalled().Sub(start), shutdownCfg.InputsTimeout, "shipper stopped before the inputs timeout")
+	assert.True(t, shipper.stopCalled().Before(slowInput.stopped()), "shipper stopped after the inputs")
+	assert.GreaterOrEqual(t, service.stopCalled().Sub(start), shutdownCfg.InputsTimeout+shutdownCfg.DrainTimeout, "service stopped before the drain timeout")
+	assert.True(t, service.stopCalled().Before(shipper.stopped()), "service stopped after the shipper")
+	assert.False(t, runtimes["fast-input"].stopCalled().After(shipper.stopCalled()), "fast input stopped after the shipper")
+
+	var phases []ShutdownPhase
+	for drained := false; !drained; {
+		select {
+		case phase := <-m.ShutdownPhases():
+			phases = append(phases, phase)
+		default:
+			drained = true
+		}
+	}
+	assert.Equal(t, []ShutdownPhase{ShutdownPhaseInputs, ShutdownPhaseShippers, ShutdownPhaseServices, ShutdownPhaseStopped}, phases)
+}
+
+// fakeStopRuntime is a component runtime that records when it is started and reports the component
+// stopped delay after it is told to stop.
+type fakeStopRuntime struct {
+	state *componentRuntimeState
+	delay time.Duration
+
+	mx           sync.Mutex
+	startCalledT time.Time
+	stopCalledT  time.Time
+	stoppedT     time.Time
+}
+
+func (r *fakeStopRuntime) Run(ctx context.Context, _ Communicator) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (r *fakeStopRuntime) Watch() <-chan ComponentState { return nil }
+
+func (r *fakeStopRuntime) Start() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.startCalledT = time.Now()
+	return nil
+}
+
+func (r *fakeStopRuntime) Update(component.Component) error { return nil }
+
+func (r *fakeStopRuntime) Stop() error {
+	r.mx.Lock()
+	r.stopCalledT = time.Now()
+	r.mx.Unlock()
+	go func() {
+		<-time.After(r.delay)
+		latest := ComponentState{State: client.UnitStateStopped}
+		r.state.latestMx.Lock()
+		r.state.latestState = latest
+		r.state.latestMx.Unlock()
+		r.mx.Lock()
+		r.stoppedT = time.Now()
+		r.mx.Unlock()
+		r.state.manager.stateChanged(r.state, latest)
+	}()
+	return nil
+}
+
+func (r *fakeStopRuntime) Teardown() error { return r.Stop() }
+
+func (r *fakeStopRuntime) startCalled() time.Time {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.startCalledT
+}
+
+func (r *fakeStopRuntime) stopCalled() time.Time {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.stopCalledT
+}
+
+func (r *fakeStopRuntime) stopped() time.Time {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.stoppedT
+}
@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"fmt"
+	"strings"
+)
+
+// oomWatch holds what is needed to tell whether a command component was killed by the OOM killer
+// of the kernel, and the memory of the process reported once it died.
+type oomWatch struct {
+	pid int
+
+	// oomEvents is the file of the memory cgroup of the process counting the OOM kills, empty when
+	// the cgroup is unknown
+	oomEvents string
+	// oomKills is the count of the OOM kills of the cgroup when the process started
+	oomKills uint64
+
+	// startedUsec is the monotonic time the process started at, in microseconds like the records of
+	// the kernel log
+	startedUsec uint64
+
+	// rssKB and peakRSSKB are the memory of the process the last time it was sampled
+	rssKB     uint64
+	peakRSSKB uint64
+}
+
+// oomKill is the memory of a process killed by the OOM killer, as logged by the kernel.
+type oomKill struct {
+	totalVMKB uint64
+	anonRSSKB uint64
+	fileRSSKB uint64
+}
+
+// newOOMWatch starts watching the process for the OOM killer.
+func newOOMWatch(pid int) *oomWatch {
+	w := &oomWatch{pid: pid}
+	w.init()
+	return w
+}
+
+// reason returns the failure reason of a process killed by the OOM killer, with its memory at the
+// time of its death.
+func (w *oomWatch) reason(kill *oomKill) string {
+	var stats []string
+	if kill != nil {
+		stats = append(stats,
+			fmt.Sprintf("total-vm: %d kB", kill.totalVMKB),
+			fmt.Sprintf("anon-rss: %d kB", kill.anonRSSKB),
+			fmt.Sprintf("file-rss: %d kB", kill.fileRSSKB))
+	}
+	if w.rssKB > 0 {
+		stats = append(stats, fmt.Sprintf("last rss: %d kB", w.rssKB))
+	}
+	if w.peakRSSKB > 0 {
+		stats = append(stats, fmt.Sprintf("peak rss: %d kB", w.peakRSSKB))
+	}
+	if len(stats) == 0 {
+		return "was killed by OOM"
+	}
+	return fmt.Sprintf("was killed by OOM (%s)", strings.Join(stats, ", "))
+}
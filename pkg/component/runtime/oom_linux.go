@@ -0,0 +1,198 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// cgroupRoot is the mount point of the cgroups.
+	cgroupRoot = "/sys/fs/cgroup"
+
+	// kmsgPath is the device of the kernel log.
+	kmsgPath = "/dev/kmsg"
+
+	// oomKillRegexp matches the message of the kernel when the OOM killer kills a process, both for
+	// the system and for a memory cgroup.
+	oomKillRegexp = regexp.MustCompile(`Killed process (\d+) \(.*\) total-vm:(\d+)kB, anon-rss:(\d+)kB, file-rss:(\d+)kB`)
+)
+
+// init finds the memory cgroup of the process and the count of its OOM kills, and the time the
+// process started for the kernel log.
+func (w *oomWatch) init() {
+	w.oomEvents = memoryCgroupEvents(w.pid)
+	if w.oomEvents != "" {
+		w.oomKills, _ = readOOMKills(w.oomEvents)
+	}
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err == nil {
+		w.startedUsec = uint64(ts.Nano() / 1000)
+	}
+}
+
+// sample reads the current and peak resident memory of the process, they are reported when the
+// process is killed by the OOM killer.
+func (w *oomWatch) sample() {
+	f, err := os.Open(filepath.Join(procRoot, strconv.Itoa(w.pid), "status"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "VmRSS":
+			w.rssKB = parseKB(value)
+		case "VmHWM":
+			w.peakRSSKB = parseKB(value)
+		}
+	}
+}
+
+// killed returns the failure reason when the process was killed by the OOM killer, false when it
+// exited or was killed by anything else.
+func (w *oomWatch) killed(state *os.ProcessState) (string, bool) {
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() || ws.Signal() != syscall.SIGKILL {
+		return "", false
+	}
+	if kill := findOOMKill(w.pid, w.startedUsec); kill != nil {
+		return w.reason(kill), true
+	}
+	// the kernel log cannot be read without privileges, the OOM kills of the cgroup still tell that
+	// the process was killed by the OOM killer, without its memory at that time
+	if w.oomEvents != "" {
+		if kills, err := readOOMKills(w.oomEvents); err == nil && kills > w.oomKills {
+			return w.reason(nil), true
+		}
+	}
+	return "", false
+}
+
+// memoryCgroupEvents returns the file counting the OOM kills of the memory cgroup of the process,
+// memory.events with cgroup v2 and memory.oom_control with cgroup v1, empty when it is unknown.
+func memoryCgroupEvents(pid int) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	var v2 string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2 = filepath.Join(cgroupRoot, filepath.Clean("/"+parts[2]), "memory.events")
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "memory" {
+				path := filepath.Join(cgroupRoot, "memory", filepath.Clean("/"+parts[2]), "memory.oom_control")
+				if _, err := os.Stat(path); err == nil {
+					return path
+				}
+			}
+		}
+	}
+	if v2 != "" {
+		if _, err := os.Stat(v2); err == nil {
+			return v2
+		}
+	}
+	return ""
+}
+
+// readOOMKills returns the oom_kill counter of memory.events or memory.oom_control.
+func readOOMKills(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, errors.New("no oom_kill counter in " + path)
+}
+
+// findOOMKill returns the memory of the process logged by the kernel when the OOM killer killed it
+// after it started, nil when the kernel log does not have it or cannot be read.
+func findOOMKill(pid int, sinceUsec uint64) *oomKill {
+	// the kernel log is read without blocking and without the poller of the runtime, a read returns
+	// a single record and EAGAIN once all of them are read
+	fd, err := unix.Open(kmsgPath, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil
+	}
+	defer unix.Close(fd)
+
+	var found *oomKill
+	buf := make([]byte, 8192)
+	for {
+		n, err := unix.Read(fd, buf)
+		if errors.Is(err, unix.EPIPE) {
+			// the record was overwritten while reading, continue with the next one
+			continue
+		}
+		if err != nil || n <= 0 {
+			return found
+		}
+		if kill, ok := parseOOMKillRecord(string(buf[:n]), pid, sinceUsec); ok {
+			found = kill
+		}
+	}
+}
+
+// parseOOMKillRecord parses a record of the kernel log, "<prio>,<seq>,<usec>,<flags>;<message>",
+// and returns the memory of the process when it is the OOM kill of the process after sinceUsec.
+func parseOOMKillRecord(record string, pid int, sinceUsec uint64) (*oomKill, bool) {
+	header, message, ok := strings.Cut(record, ";")
+	if !ok {
+		return nil, false
+	}
+	message, _, _ = strings.Cut(message, "\n")
+	fields := strings.Split(header, ",")
+	if len(fields) < 3 {
+		return nil, false
+	}
+	usec, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil || usec < sinceUsec {
+		return nil, false
+	}
+	m := oomKillRegexp.FindStringSubmatch(message)
+	if m == nil || m[1] != strconv.Itoa(pid) {
+		return nil, false
+	}
+	kill := &oomKill{}
+	kill.totalVMKB, _ = strconv.ParseUint(m[2], 10, 64)
+	kill.anonRSSKB, _ = strconv.ParseUint(m[3], 10, 64)
+	kill.fileRSSKB, _ = strconv.ParseUint(m[4], 10, 64)
+	return kill, true
+}
+
+// parseKB parses a value of /proc/<pid>/status in kB.
+func parseKB(value string) uint64 {
+	kb, _ := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+	return kb
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOOMKillRecord(t *testing.T) {
+	const record = "3,1234,5000000,-;Out of memory: Killed process 4321 (filebeat) total-vm:2048000kB, anon-rss:1024000kB, file-rss:512kB, shmem-rss:0kB, UID:0 pgtables:2048kB oom_score_adj:0\n SUBSYSTEM=memory\n"
+
+	kill, ok := parseOOMKillRecord(record, 4321, 1000000)
+	require.True(t, ok)
+	assert.Equal(t, &oomKill{totalVMKB: 2048000, anonRSSKB: 1024000, fileRSSKB: 512}, kill)
+
+	_, ok = parseOOMKillRecord(record, 1234, 1000000)
+	assert.False(t, ok, "the record is the kill of another process")
+	_, ok = parseOOMKillRecord(record, 4321, 6000000)
+	assert.False(t, ok, "the record is older than the process")
+	_, ok = parseOOMKillRecord("6,1235,5000001,-;filebeat invoked oom-killer: gfp_mask=0xcc0(GFP_KERNEL), order=0", 4321, 0)
+	assert.False(t, ok)
+
+	kill, ok = parseOOMKillRecord("3,1236,5000002,-;Memory cgroup out of memory: Killed process 4321 (metricbeat) total-vm:100kB, anon-rss:50kB, file-rss:10kB, shmem-rss:0kB", 4321, 0)
+	require.True(t, ok)
+	assert.Equal(t, &oomKill{totalVMKB: 100, anonRSSKB: 50, fileRSSKB: 10}, kill)
+}
+
+func TestReadOOMKills(t *testing.T) {
+	dir := t.TempDir()
+	events := filepath.Join(dir, "memory.events")
+	require.NoError(t, os.WriteFile(events, []byte("low 0\nhigh 0\nmax 12\noom 3\noom_kill 2\noom_group_kill 0\n"), 0600))
+	kills, err := readOOMKills(events)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), kills)
+
+	control := filepath.Join(dir, "memory.oom_control")
+	require.NoError(t, os.WriteFile(control, []byte("oom_kill_disable 0\nunder_oom 0\n"), 0600))
+	_, err = readOOMKills(control)
+	assert.ErrorContains(t, err, "no oom_kill counter")
+}
+
+func TestOOMWatchReason(t *testing.T) {
+	w := &oomWatch{rssKB: 900, peakRSSKB: 1000}
+	assert.Equal(t, "was killed by OOM (total-vm: 3000 kB, anon-rss: 1000 kB, file-rss: 20 kB, last rss: 900 kB, peak rss: 1000 kB)",
+		w.reason(&oomKill{totalVMKB: 3000, anonRSSKB: 1000, fileRSSKB: 20}))
+	assert.Equal(t, "was killed by OOM", (&oomWatch{}).reason(nil))
+}
+
+func TestOOMWatchKilled(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "sleep 10")
+	require.NoError(t, cmd.Start())
+	w := newOOMWatch(cmd.Process.Pid)
+	w.sample()
+	assert.NotZero(t, w.peakRSSKB)
+	require.NoError(t, cmd.Process.Kill())
+	_ = cmd.Wait()
+	_, killed := w.killed(cmd.ProcessState)
+	assert.False(t, killed, "a process killed by the agent was not killed by OOM")
+
+	cmd = exec.Command("/bin/sh", "-c", "exit 2")
+	_ = cmd.Run()
+	_, killed = newOOMWatch(0).killed(cmd.ProcessState)
+	assert.False(t, killed, "an exited process was not killed by OOM")
+}
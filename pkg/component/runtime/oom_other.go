@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package runtime
+
+import "os"
+
+// init does nothing, the OOM killer is only detected on Linux.
+func (w *oomWatch) init() {}
+
+// sample does nothing, the OOM killer is only detected on Linux.
+func (w *oomWatch) sample() {}
+
+// killed always returns false, the OOM killer is only detected on Linux.
+func (w *oomWatch) killed(_ *os.ProcessState) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,500 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/netutil"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+const (
+	// otelConfigFile is the name of the configuration file of the collector in its work directory.
+	otelConfigFile = "otel.yml"
+	// otelHealthCheck is the extension of the collector reporting its health.
+	otelHealthCheck = "health_check"
+	// otelHealthCheckTimeout is the most a health check waits for the collector.
+	otelHealthCheckTimeout = 5 * time.Second
+)
+
+// otelHealth is the result of a health check of a collector.
+type otelHealth struct {
+	proc *process.Info
+	err  error
+}
+
+// otelRuntime provides the otel runtime for running the OpenTelemetry Collector pipelines of the policy
+// in a collector subprocess.
+//
+// The collector does not communicate with Elastic Agent, its configuration is written to a file and it is
+// restarted when the configuration changes. Its health is checked with the health_check extension, a
+// collector that fails the health checks is killed and started again.
+type otelRuntime struct {
+	log    *logger.Logger
+	logStd *logWriter
+	logErr *logWriter
+
+	current component.Component
+
+	ch       chan ComponentState
+	actionCh chan actionMode
+	procCh   chan procState
+	compCh   chan component.Component
+	healthCh chan otelHealth
+
+	actionState actionMode
+	proc        *process.Info
+	// config is the configuration the running collector was started with
+	config []byte
+	// reloading is set while the collector is stopped to apply a new configuration
+	reloading bool
+	// healthEndpoint is the endpoint of the health_check extension added to the configuration, the same one
+	// is kept so the configuration only changes with the one of the unit
+	healthEndpoint string
+	// healthURL is the URL of the health_check extension of the running collector
+	healthURL string
+	checking  bool
+
+	state        ComponentState
+	failedChecks int
+}
+
+// newOTelRuntime creates a new otel runtime for the provided component.
+func newOTelRuntime(comp component.Component, log *logger.Logger) (*otelRuntime, error) {
+	if comp.InputSpec == nil || comp.InputSpec.Spec.OTel == nil {
+		return nil, errors.New("must have otel defined in specification")
+	}
+	o := &otelRuntime{
+		log:         log,
+		current:     comp,
+		ch:          make(chan ComponentState),
+		actionCh:    make(chan actionMode, 1),
+		procCh:      make(chan procState),
+		compCh:      make(chan component.Component, 1),
+		healthCh:    make(chan otelHealth),
+		actionState: actionStop,
+		state:       newComponentState(&comp),
+	}
+	// the collector logs are read like the logs of a command
+	cmdSpec := &component.CommandSpec{Log: comp.InputSpec.Spec.OTel.Log}
+	ll, unitLevels := getLogLevels(comp)
+	o.logStd = createLogWriter(comp, log, cmdSpec, comp.InputSpec.InputType, comp.InputSpec.BinaryName, ll, unitLevels, logSourceStdout)
+	ll, unitLevels = getLogLevels(comp) // don't want to share mapping of units (so new map is generated)
+	o.logErr = createLogWriter(comp, log, cmdSpec, comp.InputSpec.InputType, comp.InputSpec.BinaryName, ll, unitLevels, logSourceStderr)
+	return o, nil
+}
+
+// Run starts the runtime for the component.
+//
+// Called by Manager inside a goroutine. Run does not return until the passed in context is done. Run is always
+// called before any of the other methods in the interface and once the context is done none of those methods should
+// ever be called again.
+func (o *otelRuntime) Run(ctx context.Context, _ Communicator) error {
+	timeouts := o.current.InputSpec.Spec.OTel.Timeouts
+	o.forceCompState(client.UnitStateStarting, "Starting")
+	t := time.NewTicker(timeouts.Checkin)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case as := <-o.actionCh:
+			o.actionState = as
+			switch as {
+			case actionStart:
+				if err := o.start(); err != nil {
+					o.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+				}
+				t.Reset(timeouts.Checkin)
+			case actionStop, actionTeardown:
+				o.reloading = false
+				o.stop(ctx)
+			}
+		case ps := <-o.procCh:
+			// ignores old processes
+			if ps.proc != o.proc {
+				continue
+			}
+			o.proc = nil
+			if o.reloading && o.actionState == actionStart {
+				o.reloading = false
+				if err := o.start(); err != nil {
+					o.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+				}
+				t.Reset(timeouts.Checkin)
+				continue
+			}
+			if o.handleProc(ps.state) {
+				// start again after restart period
+				t.Reset(timeouts.Restart)
+			}
+		case newComp := <-o.compCh:
+			o.current = newComp
+			o.state.syncExpected(&newComp)
+			changed := o.state.syncUnits(&newComp)
+			if o.state.cleanupStopped() {
+				changed = true
+			}
+			if changed {
+				o.sendObserved()
+			}
+			if o.proc != nil && !o.reloading {
+				config, _, err := o.collectorConfig()
+				if err != nil {
+					o.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+					continue
+				}
+				if !bytes.Equal(config, o.config) {
+					// the collector is started again with the new configuration once it stopped
+					o.reloading = true
+					o.stop(ctx)
+				}
+			}
+		case health := <-o.healthCh:
+			o.checking = false
+			if health.proc == o.proc && o.proc != nil {
+				o.handleHealth(health.err)
+			}
+		case <-t.C:
+			t.Reset(timeouts.Checkin)
+			if o.actionState != actionStart || o.reloading {
+				continue
+			}
+			if o.proc == nil {
+				// not running, but should be running
+				if err := o.start(); err != nil {
+					o.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+				}
+				continue
+			}
+			if !o.checking {
+				o.checking = true
+				go o.checkHealth(ctx, o.proc, o.healthURL)
+			}
+		}
+	}
+}
+
+// Watch returns the channel that sends component state.
+//
+// Channel should send a new state anytime a state for a unit or the whole component changes.
+func (o *otelRuntime) Watch() <-chan ComponentState {
+	return o.ch
+}
+
+// Start starts the component.
+//
+// Non-blocking and never returns an error.
+func (o *otelRuntime) Start() error {
+	o.setAction(actionStart)
+	return nil
+}
+
+// Update updates the currComp runtime with a new-revision for the component definition.
+//
+// Non-blocking and never returns an error.
+func (o *otelRuntime) Update(comp component.Component) error {
+	// clear channel so it's the latest component
+	select {
+	case <-o.compCh:
+	default:
+	}
+	o.compCh <- comp
+	return nil
+}
+
+// Stop stops the component.
+//
+// Non-blocking and never returns an error.
+func (o *otelRuntime) Stop() error {
+	o.setAction(actionStop)
+	return nil
+}
+
+// Teardown tears down the component.
+//
+// Non-blocking and never returns an error.
+func (o *otelRuntime) Teardown() error {
+	o.setAction(actionTeardown)
+	return nil
+}
+
+func (o *otelRuntime) setAction(action actionMode) {
+	// clear channel so it's the latest action
+	select {
+	case <-o.actionCh:
+	default:
+	}
+	o.actionCh <- action
+}
+
+// start writes the configuration of the collector and starts it.
+func (o *otelRuntime) start() error {
+	if o.proc != nil {
+		// already running
+		return nil
+	}
+	spec := o.current.InputSpec.Spec.OTel
+	workDir := filepath.Join(paths.Run(), o.current.ID)
+	if err := os.MkdirAll(workDir, runDirMod); err != nil {
+		return fmt.Errorf("failed to create path %q: %w", workDir, err)
+	}
+	config, healthURL, err := o.collectorConfig()
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(workDir, otelConfigFile)
+	// the configuration has the credentials of the exporters
+	if err := os.WriteFile(configPath, config, 0600); err != nil {
+		return fmt.Errorf("failed to write the collector configuration: %w", err)
+	}
+
+	path, err := filepath.Abs(o.current.InputSpec.BinaryPath)
+	if err != nil {
+		return fmt.Errorf("failed to determine absolute path: %w", err)
+	}
+	if err := utils.HasStrictExecPerms(path, os.Geteuid()); err != nil {
+		return fmt.Errorf("execution of component prevented: %w", err)
+	}
+	env := make([]string, 0, len(spec.Env)+2)
+	for _, e := range spec.Env {
+		env = append(env, fmt.Sprintf("%s=%s", e.Name, e.Value))
+	}
+	env = append(env, fmt.Sprintf("%s=%s", envAgentComponentID, o.current.ID))
+	env = append(env, fmt.Sprintf("%s=%s", envAgentComponentType, o.current.InputSpec.InputType))
+	args := append(append([]string{}, spec.Args...), "--config", configPath)
+
+	proc, err := process.Start(path,
+		process.WithArgs(args),
+		process.WithEnv(env),
+		process.WithCmdOptions(attachOutErr(o.logStd, o.logErr), dirPath(workDir)))
+	if err != nil {
+		return err
+	}
+	// the collector doesn't read its stdin
+	_ = proc.Stdin.Close()
+
+	o.proc = proc
+	o.config = config
+	o.healthURL = healthURL
+	o.failedChecks = 0
+	o.forceCompState(client.UnitStateStarting, fmt.Sprintf("Starting: spawned pid '%d'", proc.PID))
+	go func() {
+		s := <-proc.Wait()
+		o.procCh <- procState{proc: proc, state: s}
+	}()
+	return nil
+}
+
+func (o *otelRuntime) stop(ctx context.Context) {
+	if o.proc == nil {
+		// already stopped, ensure that state of the component is also stopped
+		if o.state.State != client.UnitStateStopped {
+			if o.state.State == client.UnitStateFailed {
+				o.forceCompState(client.UnitStateStopped, "Stopped: never started successfully")
+			} else {
+				o.forceCompState(client.UnitStateStopped, "Stopped: already stopped")
+			}
+		}
+		return
+	}
+	go func(info *process.Info, timeout time.Duration) {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			// kill no matter what (might already be stopped)
+			_ = info.Kill()
+		}
+	}(o.proc, o.current.InputSpec.Spec.OTel.Timeouts.Stop)
+	_ = o.proc.Stop()
+}
+
+func (o *otelRuntime) handleProc(state *os.ProcessState) bool {
+	switch o.actionState {
+	case actionStart:
+		o.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: pid '%d' exited with code '%d'", state.Pid(), state.ExitCode()))
+		return true
+	case actionStop, actionTeardown:
+		if o.actionState == actionTeardown {
+			// teardown so the entire component has been removed (cleanup work directory)
+			_ = os.RemoveAll(filepath.Join(paths.Run(), o.current.ID))
+		}
+		o.forceCompState(client.UnitStateStopped, fmt.Sprintf("Stopped: pid '%d' exited with code '%d'", state.Pid(), state.ExitCode()))
+	}
+	return false
+}
+
+// checkHealth queries the health_check extension of the collector, the result is sent to the run loop.
+func (o *otelRuntime) checkHealth(ctx context.Context, proc *process.Info, url string) {
+	err := func() error {
+		ctx, cancel := context.WithTimeout(ctx, otelHealthCheckTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("collector is not ready, health check returned status %d", resp.StatusCode)
+		}
+		return nil
+	}()
+	select {
+	case o.healthCh <- otelHealth{proc: proc, err: err}:
+	case <-ctx.Done():
+	}
+}
+
+// handleHealth updates the state of the component with the result of a health check, a collector that
+// keeps failing the health checks is killed and started again.
+func (o *otelRuntime) handleHealth(err error) {
+	if err == nil {
+		o.failedChecks = 0
+		if o.state.observeApplied() {
+			o.sendObserved()
+		}
+		o.forceCompState(client.UnitStateHealthy, fmt.Sprintf("Healthy: collector pid '%d' is ready", o.proc.PID))
+		return
+	}
+	o.failedChecks++
+	if o.failedChecks < maxCheckinMisses {
+		o.forceCompState(client.UnitStateDegraded, fmt.Sprintf("Degraded: pid '%d' failed %d health check(s): %s", o.proc.PID, o.failedChecks, err))
+		return
+	}
+	o.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: pid '%d' failed %d health checks and will be killed: %s", o.proc.PID, o.failedChecks, err))
+	_ = o.proc.Kill() // watcher will handle it from here
+}
+
+// collectorConfig returns the configuration of the collector from the configuration of its unit and the
+// URL of its health_check extension. The extension is added when the pipelines don't configure it.
+func (o *otelRuntime) collectorConfig() ([]byte, string, error) {
+	var cfg map[string]interface{}
+	for _, unit := range o.current.Units {
+		if unit.Type == client.UnitTypeInput && unit.ID == component.OTelUnitID && unit.Config != nil {
+			cfg = unit.Config.GetSource().AsMap()
+		}
+	}
+	if cfg == nil {
+		return nil, "", errors.New("component has no collector configuration")
+	}
+	delete(cfg, "type")
+	delete(cfg, "id")
+
+	extensions, _ := cfg["extensions"].(map[string]interface{})
+	if extensions == nil {
+		extensions = make(map[string]interface{})
+		cfg["extensions"] = extensions
+	}
+	healthCheck, _ := extensions[otelHealthCheck].(map[string]interface{})
+	if healthCheck == nil {
+		healthCheck = make(map[string]interface{})
+		extensions[otelHealthCheck] = healthCheck
+	}
+	endpoint, _ := healthCheck["endpoint"].(string)
+	if endpoint == "" {
+		if o.healthEndpoint == "" {
+			port, err := freePort()
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to find a port for the health check: %w", err)
+			}
+			o.healthEndpoint = netutil.JoinHostPort(netutil.Loopback(), port)
+		}
+		endpoint = o.healthEndpoint
+		healthCheck["endpoint"] = endpoint
+	}
+	healthPath, _ := healthCheck["path"].(string)
+
+	service, _ := cfg["service"].(map[string]interface{})
+	if service == nil {
+		return nil, "", errors.New("collector configuration has no service section")
+	}
+	enabled, _ := service["extensions"].([]interface{})
+	if !containsValue(enabled, otelHealthCheck) {
+		service["extensions"] = append(enabled, otelHealthCheck)
+	}
+	// the logs of the collector are read as JSON lines
+	telemetry, _ := service["telemetry"].(map[string]interface{})
+	if telemetry == nil {
+		telemetry = make(map[string]interface{})
+		service["telemetry"] = telemetry
+	}
+	logs, _ := telemetry["logs"].(map[string]interface{})
+	if logs == nil {
+		logs = make(map[string]interface{})
+		telemetry["logs"] = logs
+	}
+	if _, ok := logs["encoding"]; !ok {
+		logs["encoding"] = "json"
+	}
+
+	config, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal the collector configuration: %w", err)
+	}
+	// a health check listening on all the interfaces is queried on the loopback
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid health check endpoint %q: %w", endpoint, err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = netutil.Loopback()
+	}
+	return config, fmt.Sprintf("http://%s/%s", net.JoinHostPort(host, port), strings.TrimPrefix(healthPath, "/")), nil
+}
+
+// forceCompState force updates the state for the entire component, forcing that state on all units.
+func (o *otelRuntime) forceCompState(state client.UnitState, msg string) {
+	if o.state.forceState(state, msg) {
+		o.sendObserved()
+	}
+}
+
+func (o *otelRuntime) sendObserved() {
+	o.ch <- o.state.Copy()
+}
+
+func containsValue(values []interface{}, value string) bool {
+	for _, v := range values {
+		if s, ok := v.(string); ok && s == value {
+			return true
+		}
+	}
+	return false
+}
+
+// freePort returns a TCP port free on the loopback.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", netutil.JoinHostPort(netutil.Loopback(), 0))
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
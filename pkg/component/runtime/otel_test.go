@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func otelComponent(cfg map[string]interface{}) component.Component {
+	cfg["type"] = component.OTelInputType
+	cfg["id"] = component.OTelUnitID
+	return component.Component{
+		ID: component.OTelComponentID,
+		InputSpec: &component.InputRuntimeSpec{
+			InputType: component.OTelInputType,
+			Spec: component.InputSpec{
+				Name: component.OTelInputType,
+				OTel: &component.OTelSpec{},
+			},
+		},
+		Units: []component.Unit{
+			{
+				ID:     component.OTelUnitID,
+				Type:   client.UnitTypeInput,
+				Config: component.MustExpectedConfig(cfg),
+			},
+		},
+	}
+}
+
+func TestOTelCollectorConfig(t *testing.T) {
+	pipelines := func() map[string]interface{} {
+		return map[string]interface{}{
+			"receivers": map[string]interface{}{"otlp": map[string]interface{}{}},
+			"exporters": map[string]interface{}{"logging": map[string]interface{}{}},
+			"service": map[string]interface{}{
+				"pipelines": map[string]interface{}{
+					"traces": map[string]interface{}{
+						"receivers": []interface{}{"otlp"},
+						"exporters": []interface{}{"logging"},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("health check added", func(t *testing.T) {
+		o := &otelRuntime{current: otelComponent(pipelines())}
+		raw, url, err := o.collectorConfig()
+		require.NoError(t, err)
+		require.NotEmpty(t, o.healthEndpoint)
+		assert.Equal(t, "http://"+o.healthEndpoint+"/", url)
+
+		var cfg map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(raw, &cfg))
+		assert.NotContains(t, cfg, "type")
+		assert.NotContains(t, cfg, "id")
+		extensions := cfg["extensions"].(map[interface{}]interface{})
+		assert.Equal(t, map[interface{}]interface{}{"endpoint": o.healthEndpoint}, extensions[otelHealthCheck])
+		service := cfg["service"].(map[interface{}]interface{})
+		assert.Equal(t, []interface{}{otelHealthCheck}, service["extensions"])
+		logs := service["telemetry"].(map[interface{}]interface{})["logs"].(map[interface{}]interface{})
+		assert.Equal(t, "json", logs["encoding"])
+
+		// the endpoint is kept between updates so an unchanged policy doesn't restart the collector
+		again, _, err := o.collectorConfig()
+		require.NoError(t, err)
+		assert.Equal(t, raw, again)
+	})
+
+	t.Run("health check configured", func(t *testing.T) {
+		cfg := pipelines()
+		cfg["extensions"] = map[string]interface{}{
+			otelHealthCheck: map[string]interface{}{"endpoint": "0.0.0.0:13133", "path": "/health"},
+		}
+		cfg["service"].(map[string]interface{})["extensions"] = []interface{}{otelHealthCheck}
+		o := &otelRuntime{current: otelComponent(cfg)}
+		raw, url, err := o.collectorConfig()
+		require.NoError(t, err)
+		assert.Empty(t, o.healthEndpoint)
+		assert.Equal(t, "http://127.0.0.1:13133/health", url)
+
+		var out map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(raw, &out))
+		service := out["service"].(map[interface{}]interface{})
+		assert.Equal(t, []interface{}{otelHealthCheck}, service["extensions"])
+	})
+
+	t.Run("no configuration", func(t *testing.T) {
+		o := &otelRuntime{current: component.Component{ID: component.OTelComponentID}}
+		_, _, err := o.collectorConfig()
+		assert.EqualError(t, err, "component has no collector configuration")
+	})
+}
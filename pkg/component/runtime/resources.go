@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/gofrs/flock"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// checkRequiredResources checks the resources required by the specification of a command are
+// available before it is started, so a conflict is reported precisely instead of the command
+// crashing with an opaque message. Lock files are relative to the data path of the command.
+func checkRequiredResources(requires component.CommandRequiresSpec, dataPath string) error {
+	var conflicts []string
+	for _, port := range requires.Ports {
+		if err := checkPort(port); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	for _, lockFile := range requires.LockFiles {
+		if err := checkLockFile(filepath.Join(dataPath, lockFile)); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	for _, module := range requires.KernelModules {
+		if err := checkKernelModule(module); err != nil {
+			conflicts = append(conflicts, err.Error())
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("required resources are not available: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// checkPort checks the TCP port can be listened on.
+func checkPort(port int) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err == nil {
+		_ = l.Close()
+		return nil
+	}
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		return fmt.Errorf("port %d is not available: %w", port, err)
+	}
+	if pid, ok := portOwner(port); ok {
+		return fmt.Errorf("port %d already in use by %s", port, describeProcess(pid))
+	}
+	return fmt.Errorf("port %d already in use", port)
+}
+
+// checkLockFile checks the lock file is not locked by another process, a missing lock file is
+// not locked.
+func checkLockFile(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	lock := flock.New(path)
+	locked, err := lock.TryLock()
+	if err != nil {
+		return fmt.Errorf("lock file %s cannot be checked: %w", path, err)
+	}
+	if locked {
+		return lock.Unlock()
+	}
+	if pid, ok := lockOwner(path); ok {
+		return fmt.Errorf("lock file %s already locked by %s", path, describeProcess(pid))
+	}
+	return fmt.Errorf("lock file %s already locked by another process", path)
+}
+
+// describeProcess returns the name and PID of a process for the error messages.
+func describeProcess(pid int) string {
+	if name := processName(pid); name != "" {
+		return fmt.Sprintf("process '%s' (pid %d)", name, pid)
+	}
+	return fmt.Sprintf("pid %d", pid)
+}
@@ -0,0 +1,148 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	procRoot = "/proc"
+
+	// tcpListen is the state of a listening socket in /proc/net/tcp.
+	tcpListen = "0A"
+)
+
+// portOwner returns the PID of the process listening on the TCP port, from the inode of the
+// socket in /proc/net/tcp and the file descriptors of the processes.
+func portOwner(port int) (int, bool) {
+	inodes := make(map[string]bool)
+	for _, name := range []string{"tcp", "tcp6"} {
+		for _, inode := range listeningInodes(filepath.Join(procRoot, "net", name), port) {
+			inodes[inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return 0, false
+	}
+
+	pids, err := os.ReadDir(procRoot)
+	if err != nil {
+		return 0, false
+	}
+	for _, entry := range pids {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			// processes of other users cannot be inspected without privileges
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				return pid, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// listeningInodes returns the inodes of the sockets listening on the port in a /proc/net/tcp file.
+func listeningInodes(path string, port int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	hexPort := fmt.Sprintf("%04X", port)
+	var inodes []string
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+		if strings.HasSuffix(fields[1], ":"+hexPort) {
+			inodes = append(inodes, fields[9])
+		}
+	}
+	return inodes
+}
+
+// lockOwner returns the PID of the process holding a lock on the file, from /proc/locks.
+func lockOwner(path string) (int, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	inode := strconv.FormatUint(stat.Ino, 10)
+
+	f, err := os.Open(filepath.Join(procRoot, "locks"))
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 1: FLOCK  ADVISORY  WRITE 1234 08:01:5678 0 EOF
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[1] == "->" {
+			continue
+		}
+		id := strings.Split(fields[5], ":")
+		if id[len(id)-1] != inode {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[4]); err == nil {
+			return pid, true
+		}
+	}
+	return 0, false
+}
+
+// processName returns the name of the process, empty when it cannot be read.
+func processName(pid int) string {
+	comm, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+// checkKernelModule checks the kernel module is loaded or built in the kernel.
+func checkKernelModule(name string) error {
+	module := strings.ReplaceAll(name, "-", "_")
+	if _, err := os.Stat(filepath.Join("/sys/module", module)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("kernel module %s is not loaded", name)
+		}
+		return fmt.Errorf("kernel module %s cannot be checked: %w", name, err)
+	}
+	return nil
+}
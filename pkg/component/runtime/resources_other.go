@@ -0,0 +1,27 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux
+
+package runtime
+
+// portOwner is only implemented on Linux, the conflicts are reported without the process.
+func portOwner(int) (int, bool) {
+	return 0, false
+}
+
+// lockOwner is only implemented on Linux, the conflicts are reported without the process.
+func lockOwner(string) (int, bool) {
+	return 0, false
+}
+
+// processName is only implemented on Linux.
+func processName(int) string {
+	return ""
+}
+
+// checkKernelModule is a no-op, kernel modules are specific to Linux.
+func checkKernelModule(string) error {
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/gofrs/flock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestCheckRequiredResources(t *testing.T) {
+	dataPath := t.TempDir()
+
+	t.Run("available", func(t *testing.T) {
+		l, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+		port := l.Addr().(*net.TCPAddr).Port
+		require.NoError(t, l.Close())
+		require.NoError(t, os.WriteFile(filepath.Join(dataPath, "unlocked.lock"), nil, 0600))
+
+		err = checkRequiredResources(component.CommandRequiresSpec{
+			Ports:     []int{port},
+			LockFiles: []string{"unlocked.lock", "missing.lock"},
+		}, dataPath)
+		assert.NoError(t, err)
+	})
+
+	t.Run("port in use", func(t *testing.T) {
+		l, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+		defer l.Close()
+		port := l.Addr().(*net.TCPAddr).Port
+
+		err = checkRequiredResources(component.CommandRequiresSpec{Ports: []int{port}}, dataPath)
+		require.Error(t, err)
+		if runtime.GOOS == "linux" {
+			assert.Contains(t, err.Error(), fmt.Sprintf("port %d already in use by process", port))
+			assert.Contains(t, err.Error(), fmt.Sprintf("(pid %d)", os.Getpid()))
+		}
+	})
+
+	t.Run("lock file locked", func(t *testing.T) {
+		path := filepath.Join(dataPath, "locked.lock")
+		lock := flock.New(path)
+		locked, err := lock.TryLock()
+		require.NoError(t, err)
+		require.True(t, locked)
+		defer func() { _ = lock.Unlock() }()
+
+		err = checkRequiredResources(component.CommandRequiresSpec{LockFiles: []string{"locked.lock"}}, dataPath)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("lock file %s already locked by", path))
+		if runtime.GOOS == "linux" {
+			assert.Contains(t, err.Error(), fmt.Sprintf("(pid %d)", os.Getpid()))
+		}
+	})
+
+	t.Run("kernel module missing", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("kernel modules are only checked on Linux")
+		}
+		err := checkRequiredResources(component.CommandRequiresSpec{KernelModules: []string{"not-a-kernel-module"}}, dataPath)
+		require.Error(t, err)
+		assert.EqualError(t, err, "required resources are not available: kernel module not-a-kernel-module is not loaded")
+	})
+}
@@ -58,22 +58,30 @@ func newComponentRuntime(
 	comp component.Component,
 	logger *logger.Logger,
 	monitor MonitoringManager,
+	coreDumps *coreDumps,
+	sandbox bool,
 ) (componentRuntime, error) {
 	if comp.Err != nil {
 		return newFailedRuntime(comp)
 	}
 	if comp.InputSpec != nil {
 		if comp.InputSpec.Spec.Command != nil {
-			return newCommandRuntime(comp, logger, monitor)
+			return newCommandRuntime(comp, logger, monitor, coreDumps, sandbox)
 		}
 		if comp.InputSpec.Spec.Service != nil {
 			return newServiceRuntime(comp, logger)
 		}
+		if comp.InputSpec.Spec.Builtin != nil {
+			return newBuiltinRuntime(comp, logger)
+		}
+		if comp.InputSpec.Spec.OTel != nil {
+			return newOTelRuntime(comp, logger)
+		}
 		return nil, errors.New("unknown component runtime")
 	}
 	if comp.ShipperSpec != nil {
 		if comp.ShipperSpec.Spec.Command != nil {
-			return newCommandRuntime(comp, logger, monitor)
+			return newCommandRuntime(comp, logger, monitor, coreDumps, sandbox)
 		}
 		return nil, errors.New("components for shippers can only support command runtime")
 	}
@@ -100,11 +108,11 @@ type componentRuntimeState struct {
 }
 
 func newComponentRuntimeState(m *Manager, logger *logger.Logger, monitor MonitoringManager, comp component.Component) (*componentRuntimeState, error) {
-	comm, err := newRuntimeComm(logger, m.getListenAddr(), m.ca, m.agentInfo)
+	comm, err := newRuntimeComm(logger, comp.ID, m.getListenAddr(), m.ca, m.agentInfo, m.grpcConfig.MaxCheckinMsgSize, m.grpcConfig.CheckinDebounce)
 	if err != nil {
 		return nil, err
 	}
-	runtime, err := newComponentRuntime(comp, logger, monitor)
+	runtime, err := newComponentRuntime(comp, logger, monitor, m.coreDumps, m.sandbox)
 	if err != nil {
 		return nil, err
 	}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -21,6 +22,7 @@ import (
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/core/authority"
+	"github.com/elastic/elastic-agent/internal/pkg/fault"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -37,29 +39,42 @@ type Communicator interface {
 	CheckinExpected(expected *proto.CheckinExpected, observed *proto.CheckinObserved)
 	// CheckinObserved receives the observed state from the component.
 	CheckinObserved() <-chan *proto.CheckinObserved
+	// RotateCredentials replaces the token and certificate of the component. The connected component
+	// keeps its connection, the new credentials are used from the next WriteConnInfo.
+	RotateCredentials() error
 }
 
 type runtimeComm struct {
 	logger     *logger.Logger
+	id         string
 	listenAddr string
 	ca         *authority.CertificateAuthority
 	agentInfo  *info.AgentInfo
 
-	name  string
-	token string
-	cert  *authority.Pair
+	name    string
+	token   string
+	cert    *authority.Pair
+	credsMx sync.RWMutex
 
 	checkinConn bool
 	checkinDone chan bool
 	checkinLock sync.RWMutex
 
-	checkinExpected chan *proto.CheckinExpected
+	checkinExpected chan queuedExpected
 	checkinObserved chan *proto.CheckinObserved
 
 	initCheckinObserved   *proto.CheckinObserved
 	initCheckinExpectedCh chan *proto.CheckinExpected
 	initCheckinObservedMx sync.Mutex
 
+	// maxCheckinSize limits the size of the expected messages, see chunkExpected
+	maxCheckinSize int
+	lastObserved   *proto.CheckinObserved
+	lastObservedMx sync.Mutex
+
+	// checkinDebounce delays the expected messages of configuration changes, see queuedExpected
+	checkinDebounce time.Duration
+
 	actionsConn     bool
 	actionsDone     chan bool
 	actionsLock     sync.RWMutex
@@ -67,29 +82,32 @@ type runtimeComm struct {
 	actionsResponse chan *proto.ActionResponse
 }
 
-func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.CertificateAuthority, agentInfo *info.AgentInfo) (*runtimeComm, error) {
-	token, err := uuid.NewV4()
-	if err != nil {
-		return nil, err
-	}
-	name, err := genServerName()
-	if err != nil {
-		return nil, err
-	}
-	pair, err := ca.GeneratePairWithName(name)
+// queuedExpected is an expected message waiting to be sent. The message of a configuration change,
+// not answering an observed message, is debounced: it is sent once checkinDebounce elapsed unless
+// a newer message replaces it, so the changes arriving together reach the component in one message.
+type queuedExpected struct {
+	expected *proto.CheckinExpected
+	debounce bool
+}
+
+func newRuntimeComm(logger *logger.Logger, id string, listenAddr string, ca *authority.CertificateAuthority, agentInfo *info.AgentInfo, maxCheckinSize int, checkinDebounce time.Duration) (*runtimeComm, error) {
+	name, token, pair, err := genCredentials(ca)
 	if err != nil {
 		return nil, err
 	}
 	return &runtimeComm{
 		logger:          logger,
+		id:              id,
 		listenAddr:      listenAddr,
 		ca:              ca,
 		agentInfo:       agentInfo,
 		name:            name,
-		token:           token.String(),
+		token:           token,
 		cert:            pair,
 		checkinConn:     true,
-		checkinExpected: make(chan *proto.CheckinExpected, 1),
+		checkinExpected: make(chan queuedExpected, 1),
+		maxCheckinSize:  maxCheckinSize,
+		checkinDebounce: checkinDebounce,
 		checkinObserved: make(chan *proto.CheckinObserved),
 		actionsConn:     true,
 		actionsRequest:  make(chan *proto.ActionRequest),
@@ -112,13 +130,14 @@ func (c *runtimeComm) WriteConnInfo(w io.Writer, services ...client.Service) err
 	if !hasV2 {
 		srvs = append(srvs, proto.ConnInfoServices_CheckinV2)
 	}
+	name, token, cert := c.credentials()
 	connInfo := &proto.ConnInfo{
 		Addr:       c.listenAddr,
-		ServerName: c.name,
-		Token:      c.token,
+		ServerName: name,
+		Token:      token,
 		CaCert:     c.ca.Crt(),
-		PeerCert:   c.cert.Crt,
-		PeerKey:    c.cert.Key,
+		PeerCert:   cert.Crt,
+		PeerKey:    cert.Key,
 		Services:   srvs,
 	}
 	infoBytes, err := protobuf.Marshal(connInfo)
@@ -146,6 +165,17 @@ func (c *runtimeComm) CheckinExpected(
 		expected.AgentInfo = nil
 	}
 
+	// large expected messages are sent in chunks, the rest follows as the component observes them
+	chunkObserved := observed
+	if chunkObserved == nil {
+		chunkObserved = c.getLastObserved()
+	}
+	chunk, deferred := chunkExpected(expected, chunkObserved, c.maxCheckinSize)
+	if deferred > 0 {
+		c.logger.Debugf("check-in expected state exceeds %d bytes, deferring the configuration of %d units", c.maxCheckinSize, deferred)
+	}
+	expected = chunk
+
 	// we need to determine if the communicator is currently in the initial observed message path
 	// in the case that it is we send the expected state over a different channel
 	c.initCheckinObservedMx.Lock()
@@ -174,7 +204,35 @@ func (c *runtimeComm) CheckinExpected(
 	case <-c.checkinExpected:
 	default:
 	}
-	c.checkinExpected <- expected
+	c.checkinExpected <- queuedExpected{expected: expected, debounce: observed == nil && c.checkinDebounce > 0}
+}
+
+// debounceExpected waits checkinDebounce for newer expected messages replacing queued, a message
+// answering an observed message is sent right away. Returns false when the stream is done.
+func (c *runtimeComm) debounceExpected(queued queuedExpected, checkinDone chan bool, recvDone chan bool) (queuedExpected, bool) {
+	t := time.NewTimer(c.checkinDebounce)
+	defer t.Stop()
+	replaced := 0
+	defer func() {
+		if replaced > 0 {
+			c.logger.Debugf("coalesced %d configuration changes into a single check-in expected message", replaced+1)
+		}
+	}()
+	for {
+		select {
+		case <-checkinDone:
+			return queued, false
+		case <-recvDone:
+			return queued, false
+		case <-t.C:
+			return queued, true
+		case queued = <-c.checkinExpected:
+			replaced++
+			if !queued.debounce {
+				return queued, true
+			}
+		}
+	}
 }
 
 func (c *runtimeComm) CheckinObserved() <-chan *proto.CheckinObserved {
@@ -221,6 +279,9 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 		case <-recvDone:
 			return
 		case expected := <-initExp:
+			if !c.faultDelay(checkinDone, recvDone) {
+				return
+			}
 			err := server.Send(expected)
 			if err != nil {
 				if reportableErr(err) {
@@ -231,15 +292,26 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 		}
 
 		for {
-			var expected *proto.CheckinExpected
+			var queued queuedExpected
 			select {
 			case <-checkinDone:
 				return
 			case <-recvDone:
 				return
-			case expected = <-c.checkinExpected:
+			case queued = <-c.checkinExpected:
+			}
+			if queued.debounce {
+				var ok bool
+				queued, ok = c.debounceExpected(queued, checkinDone, recvDone)
+				if !ok {
+					return
+				}
 			}
+			expected := queued.expected
 
+			if !c.faultDelay(checkinDone, recvDone) {
+				return
+			}
 			err := server.Send(expected)
 			if err != nil {
 				if reportableErr(err) {
@@ -266,6 +338,7 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 	c.initCheckinObservedMx.Unlock()
 
 	// send the initial message (manager then calls `CheckinExpected` method with the result)
+	c.setLastObserved(init)
 	c.checkinObserved <- init
 
 	go func() {
@@ -278,6 +351,7 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 				close(recvDone)
 				return
 			}
+			c.setLastObserved(checkin)
 			c.checkinObserved <- checkin
 		}
 	}()
@@ -286,6 +360,57 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 	return nil
 }
 
+// faultDelay waits for the delay of an injected fault before sending an expected message, it returns false
+// when the stream is done while waiting.
+func (c *runtimeComm) faultDelay(checkinDone <-chan bool, recvDone <-chan bool) bool {
+	d := fault.CheckinExpectedDelay(c.id)
+	if d <= 0 {
+		return true
+	}
+	c.logger.Warnf("Fault injection delaying expected check-in by %s", d)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-checkinDone:
+		return false
+	case <-recvDone:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (c *runtimeComm) RotateCredentials() error {
+	name, token, pair, err := genCredentials(c.ca)
+	if err != nil {
+		return fmt.Errorf("failed to generate credentials: %w", err)
+	}
+	c.credsMx.Lock()
+	defer c.credsMx.Unlock()
+	c.name = name
+	c.token = token
+	c.cert = pair
+	return nil
+}
+
+func (c *runtimeComm) credentials() (string, string, *authority.Pair) {
+	c.credsMx.RLock()
+	defer c.credsMx.RUnlock()
+	return c.name, c.token, c.cert
+}
+
+func (c *runtimeComm) getLastObserved() *proto.CheckinObserved {
+	c.lastObservedMx.Lock()
+	defer c.lastObservedMx.Unlock()
+	return c.lastObserved
+}
+
+func (c *runtimeComm) setLastObserved(observed *proto.CheckinObserved) {
+	c.lastObservedMx.Lock()
+	defer c.lastObservedMx.Unlock()
+	c.lastObserved = observed
+}
+
 func (c *runtimeComm) actions(server proto.ElasticAgent_ActionsServer) error {
 	c.actionsLock.Lock()
 	if c.actionsDone != nil {
@@ -394,6 +519,23 @@ func reportableErr(err error) bool {
 	return true
 }
 
+// genCredentials generates the server name, the token and the certificate a component connects with.
+func genCredentials(ca *authority.CertificateAuthority) (string, string, *authority.Pair, error) {
+	token, err := uuid.NewV4()
+	if err != nil {
+		return "", "", nil, err
+	}
+	name, err := genServerName()
+	if err != nil {
+		return "", "", nil, err
+	}
+	pair, err := ca.GeneratePairWithName(name)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return name, token.String(), pair, nil
+}
+
 func genServerName() (string, error) {
 	u, err := uuid.NewV4()
 	if err != nil {
@@ -0,0 +1,93 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/internal/pkg/core/authority"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils"
+)
+
+func TestRuntimeCommRotateCredentials(t *testing.T) {
+	ca, err := authority.NewCA()
+	require.NoError(t, err)
+	comm, err := newRuntimeComm(testutils.NewErrorLogger(t), "test", "localhost:0", ca, nil, 0, 0)
+	require.NoError(t, err)
+
+	connInfo := func() *proto.ConnInfo {
+		var buf bytes.Buffer
+		require.NoError(t, comm.WriteConnInfo(&buf))
+		var info proto.ConnInfo
+		require.NoError(t, protobuf.Unmarshal(buf.Bytes(), &info))
+		return &info
+	}
+
+	before := connInfo()
+	require.NoError(t, comm.RotateCredentials())
+	after := connInfo()
+
+	assert.NotEqual(t, before.Token, after.Token)
+	assert.NotEqual(t, before.ServerName, after.ServerName)
+	assert.NotEqual(t, before.PeerCert, after.PeerCert)
+	assert.Equal(t, before.CaCert, after.CaCert)
+
+	name, token, _ := comm.credentials()
+	assert.Equal(t, after.ServerName, name)
+	assert.Equal(t, after.Token, token)
+}
+
+func TestRuntimeCommDebounceExpected(t *testing.T) {
+	ca, err := authority.NewCA()
+	require.NoError(t, err)
+	done := make(chan bool)
+
+	t.Run("configuration changes are coalesced", func(t *testing.T) {
+		comm, err := newRuntimeComm(testutils.NewErrorLogger(t), "test", "localhost:0", ca, nil, 0, 100*time.Millisecond)
+		require.NoError(t, err)
+
+		comm.CheckinExpected(&proto.CheckinExpected{FeaturesIdx: 1}, nil)
+		queued := <-comm.checkinExpected
+		require.True(t, queued.debounce)
+		comm.CheckinExpected(&proto.CheckinExpected{FeaturesIdx: 2}, nil)
+		comm.CheckinExpected(&proto.CheckinExpected{FeaturesIdx: 3}, nil)
+
+		started := time.Now()
+		queued, ok := comm.debounceExpected(queued, done, done)
+		require.True(t, ok)
+		assert.Equal(t, uint64(3), queued.expected.FeaturesIdx)
+		assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+	})
+
+	t.Run("answer to an observed message is sent right away", func(t *testing.T) {
+		comm, err := newRuntimeComm(testutils.NewErrorLogger(t), "test", "localhost:0", ca, nil, 0, time.Hour)
+		require.NoError(t, err)
+
+		comm.CheckinExpected(&proto.CheckinExpected{FeaturesIdx: 1}, nil)
+		queued := <-comm.checkinExpected
+		comm.CheckinExpected(&proto.CheckinExpected{FeaturesIdx: 2}, &proto.CheckinObserved{})
+
+		queued, ok := comm.debounceExpected(queued, done, done)
+		require.True(t, ok)
+		assert.Equal(t, uint64(2), queued.expected.FeaturesIdx)
+		assert.False(t, queued.debounce)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		comm, err := newRuntimeComm(testutils.NewErrorLogger(t), "test", "localhost:0", ca, nil, 0, 0)
+		require.NoError(t, err)
+
+		comm.CheckinExpected(&proto.CheckinExpected{FeaturesIdx: 1}, nil)
+		queued := <-comm.checkinExpected
+		assert.False(t, queued.debounce)
+	})
+}
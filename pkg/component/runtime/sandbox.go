@@ -0,0 +1,28 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"strings"
+
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
+// sandboxedCommand is the command of a component with its sandbox applied.
+type sandboxedCommand struct {
+	path string
+	args []string
+	// opts set the sandbox on the process
+	opts []process.CmdOption
+	// applied are the restrictions of the sandbox, reported in the state of the component
+	applied []string
+	// release frees what the sandbox holds once the process is started, can be nil
+	release func()
+}
+
+// description returns the applied sandbox reported in the state of the component.
+func (s *sandboxedCommand) description() string {
+	return strings.Join(s.applied, ", ")
+}
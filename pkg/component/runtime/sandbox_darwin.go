@@ -0,0 +1,34 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build darwin
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// sandboxExecPath is the path of the macOS sandbox-exec command.
+const sandboxExecPath = "/usr/bin/sandbox-exec"
+
+// sandboxCommand runs the command with sandbox-exec and the profile of the specification.
+func sandboxCommand(spec component.CommandSandboxSpec, path string, args []string) (*sandboxedCommand, error) {
+	cmd := &sandboxedCommand{path: path, args: args}
+	if spec.Profile == "" {
+		return cmd, nil
+	}
+	profile := filepath.Join(filepath.Dir(path), spec.Profile)
+	if _, err := os.Stat(profile); err != nil {
+		return nil, fmt.Errorf("failed to find the sandbox profile: %w", err)
+	}
+	cmd.path = sandboxExecPath
+	cmd.args = append([]string{"-f", profile, path}, args...)
+	cmd.applied = append(cmd.applied, fmt.Sprintf("sandbox-exec profile %s", spec.Profile))
+	return cmd, nil
+}
@@ -0,0 +1,41 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build darwin
+
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestSandboxCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "component")
+
+	cmd, err := sandboxCommand(component.CommandSandboxSpec{}, path, []string{"run"})
+	require.NoError(t, err)
+	assert.Equal(t, path, cmd.path)
+	assert.Equal(t, []string{"run"}, cmd.args)
+	assert.Empty(t, cmd.description())
+
+	spec := component.CommandSandboxSpec{Profile: "component.sb"}
+	_, err = sandboxCommand(spec, path, nil)
+	assert.Error(t, err, "the profile doesn't exist")
+
+	profile := filepath.Join(dir, "component.sb")
+	require.NoError(t, os.WriteFile(profile, []byte("(version 1)\n(allow default)\n"), 0600))
+	cmd, err = sandboxCommand(spec, path, []string{"run"})
+	require.NoError(t, err)
+	assert.Equal(t, sandboxExecPath, cmd.path)
+	assert.Equal(t, []string{"-f", profile, path, "run"}, cmd.args)
+	assert.Equal(t, "sandbox-exec profile component.sb", cmd.description())
+}
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// geteuid returns the effective user of the Elastic Agent, replaced by the tests.
+var geteuid = os.Geteuid
+
+// sandboxCommand applies the user namespace and the seccomp profile of the specification to the command.
+func sandboxCommand(spec component.CommandSandboxSpec, path string, args []string) (*sandboxedCommand, error) {
+	cmd := &sandboxedCommand{path: path, args: args}
+	if spec.UserNamespace {
+		cmd.opts = append(cmd.opts, withUserNamespace)
+		cmd.applied = append(cmd.applied, "user namespace")
+	}
+	if spec.Seccomp != nil {
+		// the profile is checked before the process is started, so an error is reported in the
+		// state of the component instead of the exit code of the Elastic Agent executing it
+		if _, err := newSeccompFilter(spec.Seccomp); err != nil {
+			return nil, err
+		}
+		profile, err := json.Marshal(spec.Seccomp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the seccomp profile: %w", err)
+		}
+		// a seccomp filter is installed by the process it applies to, the Elastic Agent installs it
+		// then executes the component in place of itself, the component keeps the pid
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the executable of the Elastic Agent: %w", err)
+		}
+		cmd.path = exe
+		cmd.args = append([]string{SeccompExecCommand, "--profile", string(profile), "--", path}, args...)
+		cmd.applied = append(cmd.applied, "seccomp")
+	}
+	return cmd, nil
+}
+
+// withUserNamespace runs the process in a new user namespace. The user and group of the process
+// are mapped to themselves, all of them when the Elastic Agent runs as root so the process keeps
+// its access to the files, but the process loses the capabilities over the resources of the host
+// like loading kernel modules, mounting file systems or opening raw sockets.
+func withUserNamespace(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	uid, gid := geteuid(), os.Getegid()
+	if cred := cmd.SysProcAttr.Credential; cred != nil {
+		uid, gid = int(cred.Uid), int(cred.Gid)
+	}
+	uidMap := syscall.SysProcIDMap{ContainerID: uid, HostID: uid, Size: 1}
+	gidMap := syscall.SysProcIDMap{ContainerID: gid, HostID: gid, Size: 1}
+	if geteuid() == 0 {
+		uidMap = syscall.SysProcIDMap{ContainerID: 0, HostID: 0, Size: math.MaxInt32}
+		gidMap = syscall.SysProcIDMap{ContainerID: 0, HostID: 0, Size: math.MaxInt32}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{uidMap}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{gidMap}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"math"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestWithUserNamespace(t *testing.T) {
+	defer func(prev func() int) { geteuid = prev }(geteuid)
+	gid := os.Getegid()
+
+	t.Run("root", func(t *testing.T) {
+		geteuid = func() int { return 0 }
+		cmd := exec.Command("true")
+		require.NoError(t, withUserNamespace(cmd))
+
+		all := []syscall.SysProcIDMap{{ContainerID: 0, HostID: 0, Size: math.MaxInt32}}
+		assert.NotZero(t, cmd.SysProcAttr.Cloneflags&syscall.CLONE_NEWUSER)
+		assert.Equal(t, all, cmd.SysProcAttr.UidMappings, "all the users are mapped to themselves")
+		assert.Equal(t, all, cmd.SysProcAttr.GidMappings, "all the groups are mapped to themselves")
+		assert.False(t, cmd.SysProcAttr.GidMappingsEnableSetgroups)
+	})
+
+	t.Run("non-root", func(t *testing.T) {
+		geteuid = func() int { return 1000 }
+		cmd := exec.Command("true")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		require.NoError(t, withUserNamespace(cmd))
+
+		assert.True(t, cmd.SysProcAttr.Setpgid, "the attributes already set are kept")
+		assert.NotZero(t, cmd.SysProcAttr.Cloneflags&syscall.CLONE_NEWUSER)
+		assert.Equal(t, []syscall.SysProcIDMap{{ContainerID: 1000, HostID: 1000, Size: 1}}, cmd.SysProcAttr.UidMappings)
+		assert.Equal(t, []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}, cmd.SysProcAttr.GidMappings)
+		assert.False(t, cmd.SysProcAttr.GidMappingsEnableSetgroups)
+	})
+
+	t.Run("non-root with credential", func(t *testing.T) {
+		geteuid = func() int { return 1000 }
+		cmd := exec.Command("true")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: 1001, Gid: 1002}}
+		require.NoError(t, withUserNamespace(cmd))
+
+		assert.Equal(t, []syscall.SysProcIDMap{{ContainerID: 1001, HostID: 1001, Size: 1}}, cmd.SysProcAttr.UidMappings)
+		assert.Equal(t, []syscall.SysProcIDMap{{ContainerID: 1002, HostID: 1002, Size: 1}}, cmd.SysProcAttr.GidMappings)
+	})
+}
+
+func TestSandboxCommand(t *testing.T) {
+	cmd, err := sandboxCommand(component.CommandSandboxSpec{}, "/usr/bin/component", []string{"run"})
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/component", cmd.path)
+	assert.Equal(t, []string{"run"}, cmd.args)
+	assert.Empty(t, cmd.opts)
+	assert.Empty(t, cmd.description())
+
+	if seccompArch == 0 {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	spec := component.CommandSandboxSpec{
+		UserNamespace: true,
+		Seccomp: &component.CommandSeccompSpec{
+			DefaultAction: component.SeccompActionAllow,
+			Syscalls:      []component.CommandSeccompRuleSpec{{Names: []string{"ptrace"}, Action: component.SeccompActionErrno}},
+		},
+	}
+	cmd, err = sandboxCommand(spec, "/usr/bin/component", []string{"run"})
+	require.NoError(t, err)
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	assert.Equal(t, exe, cmd.path, "the Elastic Agent installs the profile then executes the component")
+	require.Len(t, cmd.args, 6)
+	assert.Equal(t, []string{SeccompExecCommand, "--profile"}, cmd.args[:2])
+	assert.JSONEq(t, `{"DefaultAction":"allow","Syscalls":[{"Names":["ptrace"],"Action":"errno"}]}`, cmd.args[2])
+	assert.Equal(t, []string{"--", "/usr/bin/component", "run"}, cmd.args[3:])
+	assert.Len(t, cmd.opts, 1)
+	assert.Equal(t, "user namespace, seccomp", cmd.description())
+
+	spec.Seccomp.DefaultAction = component.SeccompActionKillProcess
+	_, err = sandboxCommand(spec, "/usr/bin/component", nil)
+	assert.Error(t, err, "an invalid profile is reported before the process is started")
+}
@@ -0,0 +1,14 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !linux && !darwin && !windows
+
+package runtime
+
+import "github.com/elastic/elastic-agent/pkg/component"
+
+// sandboxCommand returns the command as is, there is no sandbox on this platform.
+func sandboxCommand(_ component.CommandSandboxSpec, path string, args []string) (*sandboxedCommand, error) {
+	return &sandboxedCommand{path: path, args: args}, nil
+}
@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package runtime
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// disableMaxPrivilege removes all the privileges of the restricted token but SeChangeNotifyPrivilege.
+const disableMaxPrivilege = 0x1
+
+// advapi32.dll is a known DLL, always loaded from the system directory
+var procCreateRestrictedToken = syscall.NewLazyDLL("advapi32.dll").NewProc("CreateRestrictedToken")
+
+// sandboxCommand runs the command with a restricted token when the specification asks for it.
+func sandboxCommand(spec component.CommandSandboxSpec, path string, args []string) (*sandboxedCommand, error) {
+	cmd := &sandboxedCommand{path: path, args: args}
+	if !spec.RestrictedToken {
+		return cmd, nil
+	}
+	token, err := restrictedToken()
+	if err != nil {
+		return nil, err
+	}
+	cmd.opts = append(cmd.opts, func(c *exec.Cmd) error {
+		if c.SysProcAttr == nil {
+			c.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		c.SysProcAttr.Token = token
+		return nil
+	})
+	cmd.release = func() {
+		_ = token.Close()
+	}
+	cmd.applied = append(cmd.applied, "restricted token")
+	return cmd, nil
+}
+
+// restrictedToken returns a primary token of the Elastic Agent without its privileges.
+func restrictedToken() (syscall.Token, error) {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the Elastic Agent process: %w", err)
+	}
+	var token syscall.Token
+	access := uint32(syscall.TOKEN_DUPLICATE | syscall.TOKEN_ASSIGN_PRIMARY | syscall.TOKEN_QUERY | syscall.TOKEN_ADJUST_DEFAULT | syscall.TOKEN_ADJUST_SESSIONID)
+	if err := syscall.OpenProcessToken(process, access, &token); err != nil {
+		return 0, fmt.Errorf("failed to open the token of the Elastic Agent: %w", err)
+	}
+	defer token.Close()
+
+	var restricted syscall.Token
+	r, _, err := procCreateRestrictedToken.Call(
+		uintptr(token), disableMaxPrivilege,
+		0, 0, // no SID disabled
+		0, 0, // no privilege deleted, all of them are with disableMaxPrivilege
+		0, 0, // no restricting SID
+		uintptr(unsafe.Pointer(&restricted)))
+	if r == 0 {
+		return 0, fmt.Errorf("failed to create the restricted token: %w", err)
+	}
+	return restricted, nil
+}
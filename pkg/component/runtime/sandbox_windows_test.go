@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package runtime
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestRestrictedToken(t *testing.T) {
+	token, err := restrictedToken()
+	require.NoError(t, err)
+	defer token.Close()
+
+	// only SeChangeNotifyPrivilege is left
+	var size uint32
+	_ = syscall.GetTokenInformation(token, syscall.TokenPrivileges, nil, 0, &size)
+	require.NotZero(t, size)
+	buf := make([]byte, size)
+	require.NoError(t, syscall.GetTokenInformation(token, syscall.TokenPrivileges, &buf[0], size, &size))
+	count := *(*uint32)(unsafe.Pointer(&buf[0]))
+	assert.LessOrEqual(t, count, uint32(1), "the privileges of the Elastic Agent are removed")
+}
+
+func TestSandboxCommand(t *testing.T) {
+	cmd, err := sandboxCommand(component.CommandSandboxSpec{}, `C:\component.exe`, []string{"run"})
+	require.NoError(t, err)
+	assert.Equal(t, `C:\component.exe`, cmd.path)
+	assert.Empty(t, cmd.opts)
+	assert.Nil(t, cmd.release)
+	assert.Empty(t, cmd.description())
+
+	cmd, err = sandboxCommand(component.CommandSandboxSpec{RestrictedToken: true}, `C:\component.exe`, []string{"run"})
+	require.NoError(t, err)
+	require.NotNil(t, cmd.release)
+	defer cmd.release()
+	assert.Equal(t, "restricted token", cmd.description())
+
+	c := exec.Command(`C:\component.exe`)
+	for _, opt := range cmd.opts {
+		require.NoError(t, opt(c))
+	}
+	require.NotNil(t, c.SysProcAttr)
+	assert.NotZero(t, c.SysProcAttr.Token, "the process runs with the restricted token")
+}
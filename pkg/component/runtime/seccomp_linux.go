@@ -0,0 +1,183 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// SeccompExecCommand is the hidden sub-command of the Elastic Agent installing a seccomp profile
+// then executing a component in its place.
+const SeccompExecCommand = "seccomp_exec"
+
+// return actions of a seccomp filter and operations of the seccomp system call, from linux/seccomp.h
+const (
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetLog         = 0x7ffc0000
+	seccompRetAllow       = 0x7fff0000
+
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTsync = 1
+
+	// offsets of the system call number and of the architecture in struct seccomp_data
+	seccompDataNr   = 0
+	seccompDataArch = 4
+)
+
+// seccompSyscalls are the system calls a seccomp profile can name, the ones the components should
+// not need and which give access to the host.
+var seccompSyscalls = map[string]uint32{
+	"acct":              unix.SYS_ACCT,
+	"add_key":           unix.SYS_ADD_KEY,
+	"bpf":               unix.SYS_BPF,
+	"clock_adjtime":     unix.SYS_CLOCK_ADJTIME,
+	"clock_settime":     unix.SYS_CLOCK_SETTIME,
+	"delete_module":     unix.SYS_DELETE_MODULE,
+	"finit_module":      unix.SYS_FINIT_MODULE,
+	"init_module":       unix.SYS_INIT_MODULE,
+	"kcmp":              unix.SYS_KCMP,
+	"kexec_load":        unix.SYS_KEXEC_LOAD,
+	"keyctl":            unix.SYS_KEYCTL,
+	"mount":             unix.SYS_MOUNT,
+	"name_to_handle_at": unix.SYS_NAME_TO_HANDLE_AT,
+	"open_by_handle_at": unix.SYS_OPEN_BY_HANDLE_AT,
+	"perf_event_open":   unix.SYS_PERF_EVENT_OPEN,
+	"pivot_root":        unix.SYS_PIVOT_ROOT,
+	"process_vm_readv":  unix.SYS_PROCESS_VM_READV,
+	"process_vm_writev": unix.SYS_PROCESS_VM_WRITEV,
+	"ptrace":            unix.SYS_PTRACE,
+	"quotactl":          unix.SYS_QUOTACTL,
+	"reboot":            unix.SYS_REBOOT,
+	"request_key":       unix.SYS_REQUEST_KEY,
+	"setdomainname":     unix.SYS_SETDOMAINNAME,
+	"sethostname":       unix.SYS_SETHOSTNAME,
+	"setns":             unix.SYS_SETNS,
+	"settimeofday":      unix.SYS_SETTIMEOFDAY,
+	"swapoff":           unix.SYS_SWAPOFF,
+	"swapon":            unix.SYS_SWAPON,
+	"syslog":            unix.SYS_SYSLOG,
+	"umount2":           unix.SYS_UMOUNT2,
+	"unshare":           unix.SYS_UNSHARE,
+	"userfaultfd":       unix.SYS_USERFAULTFD,
+}
+
+// ExecSeccomp installs the seccomp profile, marshalled in JSON, on the process then executes the
+// command in its place. It only returns on failure.
+func ExecSeccomp(profile string, argv []string) error {
+	if len(argv) == 0 {
+		return errors.New("no command to execute")
+	}
+	var spec component.CommandSeccompSpec
+	if err := json.Unmarshal([]byte(profile), &spec); err != nil {
+		return fmt.Errorf("failed to read the seccomp profile: %w", err)
+	}
+	filter, err := newSeccompFilter(&spec)
+	if err != nil {
+		return err
+	}
+	goruntime.LockOSThread()
+	if err := loadSeccompFilter(filter); err != nil {
+		return err
+	}
+	if err := unix.Exec(argv[0], argv, os.Environ()); err != nil {
+		return fmt.Errorf("failed to execute %s: %w", argv[0], err)
+	}
+	return nil
+}
+
+// newSeccompFilter compiles the seccomp profile to a BPF program. A system call of another
+// architecture kills the process, the rules are checked in order and the other system calls
+// are allowed.
+func newSeccompFilter(spec *component.CommandSeccompSpec) ([]unix.SockFilter, error) {
+	if seccompArch == 0 {
+		return nil, fmt.Errorf("seccomp is not supported on %s", goruntime.GOARCH)
+	}
+	if spec.DefaultAction != component.SeccompActionAllow {
+		// only the listed system calls can be named, any other default blocks the component
+		return nil, fmt.Errorf("seccomp default action '%s' must be '%s'", spec.DefaultAction, component.SeccompActionAllow)
+	}
+	filter := []unix.SockFilter{
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArch),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, seccompArch, 1, 0),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNr),
+	}
+	if seccompX32SyscallBit != 0 {
+		// the x32 system calls share the architecture of x86-64, they would bypass the rules
+		filter = append(filter,
+			bpfJump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, seccompX32SyscallBit, 0, 1),
+			bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess))
+	}
+	matched := make(map[uint32]bool)
+	for _, rule := range spec.Syscalls {
+		action, err := seccompAction(rule.Action)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range rule.Names {
+			nr, ok := seccompSyscalls[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown system call '%s' in the seccomp profile", name)
+			}
+			if matched[nr] {
+				// the first rule naming the system call applies
+				continue
+			}
+			matched[nr] = true
+			filter = append(filter,
+				bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nr, 0, 1),
+				bpfStmt(unix.BPF_RET|unix.BPF_K, action))
+		}
+	}
+	return append(filter, bpfStmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow)), nil
+}
+
+// loadSeccompFilter installs the filter on all the threads of the process, the filter is kept
+// when the process executes another program.
+func loadSeccompFilter(filter []unix.SockFilter) error {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFilterFlagTsync, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return fmt.Errorf("failed to load the seccomp filter: %w", errno)
+	}
+	return nil
+}
+
+func seccompAction(action string) (uint32, error) {
+	switch action {
+	case component.SeccompActionAllow:
+		return seccompRetAllow, nil
+	case component.SeccompActionErrno:
+		return seccompRetErrno | uint32(unix.EPERM), nil
+	case component.SeccompActionKillProcess:
+		return seccompRetKillProcess, nil
+	case component.SeccompActionLog:
+		return seccompRetLog, nil
+	}
+	return 0, fmt.Errorf("unknown seccomp action '%s'", action)
+}
+
+func bpfStmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt uint8, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
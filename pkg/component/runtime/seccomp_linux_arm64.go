@@ -0,0 +1,16 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux && arm64
+
+package runtime
+
+import "golang.org/x/sys/unix"
+
+const (
+	// seccompArch is the architecture of the system calls allowed by the seccomp filters.
+	seccompArch = unix.AUDIT_ARCH_AARCH64
+	// seccompX32SyscallBit is not used on arm64.
+	seccompX32SyscallBit = 0
+)
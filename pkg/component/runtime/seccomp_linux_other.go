@@ -0,0 +1,14 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux && !amd64 && !arm64
+
+package runtime
+
+const (
+	// seccompArch is not set, the seccomp profiles are not supported on this architecture.
+	seccompArch = 0
+	// seccompX32SyscallBit is not used on this architecture.
+	seccompX32SyscallBit = 0
+)
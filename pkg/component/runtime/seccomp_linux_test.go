@@ -0,0 +1,170 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build linux
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestNewSeccompFilter(t *testing.T) {
+	if seccompArch == 0 {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	errno := seccompRetErrno | uint32(unix.EPERM)
+
+	filter, err := newSeccompFilter(&component.CommandSeccompSpec{
+		DefaultAction: component.SeccompActionAllow,
+		Syscalls: []component.CommandSeccompRuleSpec{
+			{Names: []string{"ptrace", "bpf"}, Action: component.SeccompActionErrno},
+			{Names: []string{"bpf", "mount"}, Action: component.SeccompActionKillProcess},
+			{Names: []string{"syslog"}, Action: component.SeccompActionLog},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("other architecture", func(t *testing.T) {
+		assert.EqualValues(t, seccompRetKillProcess, runSeccompFilter(t, filter, seccompArch+1, unix.SYS_GETPID))
+	})
+	t.Run("x32 system calls", func(t *testing.T) {
+		if seccompX32SyscallBit == 0 {
+			t.Skip("no x32 system calls on this architecture")
+		}
+		assert.EqualValues(t, seccompRetKillProcess, runSeccompFilter(t, filter, seccompArch, seccompX32SyscallBit|unix.SYS_GETPID))
+	})
+	t.Run("first rule wins", func(t *testing.T) {
+		assert.EqualValues(t, errno, runSeccompFilter(t, filter, seccompArch, unix.SYS_PTRACE))
+		assert.EqualValues(t, errno, runSeccompFilter(t, filter, seccompArch, unix.SYS_BPF))
+		assert.EqualValues(t, seccompRetKillProcess, runSeccompFilter(t, filter, seccompArch, unix.SYS_MOUNT))
+		assert.EqualValues(t, seccompRetLog, runSeccompFilter(t, filter, seccompArch, unix.SYS_SYSLOG))
+	})
+	t.Run("default action", func(t *testing.T) {
+		assert.EqualValues(t, seccompRetAllow, runSeccompFilter(t, filter, seccompArch, unix.SYS_GETPID))
+		assert.EqualValues(t, seccompRetAllow, runSeccompFilter(t, filter, seccompArch, unix.SYS_EXECVE))
+	})
+
+	t.Run("default action not allow", func(t *testing.T) {
+		_, err := newSeccompFilter(&component.CommandSeccompSpec{DefaultAction: component.SeccompActionErrno})
+		assert.Error(t, err)
+		_, err = newSeccompFilter(&component.CommandSeccompSpec{})
+		assert.Error(t, err, "an empty default action is not allow")
+	})
+	t.Run("unknown system call", func(t *testing.T) {
+		_, err := newSeccompFilter(&component.CommandSeccompSpec{
+			DefaultAction: component.SeccompActionAllow,
+			Syscalls:      []component.CommandSeccompRuleSpec{{Names: []string{"read"}, Action: component.SeccompActionErrno}},
+		})
+		assert.ErrorContains(t, err, "unknown system call 'read'")
+	})
+	t.Run("unknown action", func(t *testing.T) {
+		_, err := newSeccompFilter(&component.CommandSeccompSpec{
+			DefaultAction: component.SeccompActionAllow,
+			Syscalls:      []component.CommandSeccompRuleSpec{{Names: []string{"ptrace"}}},
+		})
+		assert.ErrorContains(t, err, "unknown seccomp action ''")
+	})
+}
+
+// runSeccompFilter runs the filter on the system call nr of the architecture arch and returns its action.
+// It only knows the instructions newSeccompFilter uses.
+func runSeccompFilter(t *testing.T, filter []unix.SockFilter, arch uint32, nr uint32) uint32 {
+	t.Helper()
+	var acc uint32
+	for pc := 0; pc < len(filter); pc++ {
+		ins := filter[pc]
+		switch ins.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			switch ins.K {
+			case seccompDataNr:
+				acc = nr
+			case seccompDataArch:
+				acc = arch
+			default:
+				t.Fatalf("load of unknown offset %d at %d", ins.K, pc)
+			}
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K:
+			if acc == ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			if acc >= ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_RET | unix.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("unknown instruction %#x at %d", ins.Code, pc)
+		}
+	}
+	t.Fatal("the filter returns no action")
+	return 0
+}
+
+// seccompHelperEnv selects what TestSeccompExecHelper does when the test binary runs as a helper.
+const seccompHelperEnv = "GO_SECCOMP_HELPER"
+
+func TestSeccompExec(t *testing.T) {
+	if seccompArch == 0 {
+		t.Skip("seccomp is not supported on this architecture")
+	}
+	if err := kcmpSelf(); err == unix.ENOSYS { //nolint:errorlint // errno from a raw system call
+		t.Skip("kcmp is not supported by the kernel")
+	}
+
+	profile, err := json.Marshal(&component.CommandSeccompSpec{
+		DefaultAction: component.SeccompActionAllow,
+		Syscalls:      []component.CommandSeccompRuleSpec{{Names: []string{"kcmp"}, Action: component.SeccompActionErrno}},
+	})
+	require.NoError(t, err)
+
+	// the test binary installs the profile then executes itself to call the denied system call
+	cmd := exec.Command(os.Args[0], "-test.run", "^TestSeccompExecHelper$")
+	cmd.Env = append(os.Environ(), seccompHelperEnv+"=exec", seccompHelperEnv+"_PROFILE="+string(profile))
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), fmt.Sprintf("kcmp: %d\n", unix.EPERM), string(output))
+}
+
+func TestSeccompExecHelper(t *testing.T) {
+	switch os.Getenv(seccompHelperEnv) {
+	case "exec":
+		require.NoError(t, os.Setenv(seccompHelperEnv, "syscall"))
+		err := ExecSeccomp(os.Getenv(seccompHelperEnv+"_PROFILE"), []string{os.Args[0], "-test.run", "^TestSeccompExecHelper$"})
+		t.Fatalf("failed to execute the helper: %v", err)
+	case "syscall":
+		var errno unix.Errno
+		if err := kcmpSelf(); err != nil {
+			errno = err.(unix.Errno) //nolint:errorlint // errno from a raw system call
+		}
+		fmt.Printf("kcmp: %d\n", errno)
+	default:
+		t.Skip("only runs as the helper of TestSeccompExec")
+	}
+}
+
+// kcmpSelf compares the standard input of the process with itself.
+func kcmpSelf() error {
+	pid := uintptr(os.Getpid())
+	_, _, errno := unix.Syscall6(unix.SYS_KCMP, pid, pid, 0, 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
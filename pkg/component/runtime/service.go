@@ -14,6 +14,8 @@ import (
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/internal/pkg/fault"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -27,25 +29,62 @@ var (
 	ErrOperationSpecUndefined = errors.New("operation spec undefined")
 	// ErrInvalidServiceSpec error invalid service specification.
 	ErrInvalidServiceSpec = errors.New("invalid service spec")
+	// ErrRetriesExhausted error for a service operation still failing once all its retries are exhausted.
+	ErrRetriesExhausted = errors.New("retries exhausted")
 )
 
 // executeServiceCommandFunc executes the given binary according to configuration in spec. If shouldRetry == true,
-// the command will be retried indefinitely; otherwise, it will not be retried.
-type executeServiceCommandFunc func(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, shouldRetry bool) error
+// the command will be retried as configured in spec, onRetriesExhausted is called once the retries are exhausted;
+// otherwise, it will not be retried.
+type executeServiceCommandFunc func(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, outputPath string, shouldRetry bool, onRetriesExhausted func(error)) error
+
+// executeServiceStatusCommandFunc executes the given binary according to configuration in spec and returns its output.
+type executeServiceStatusCommandFunc func(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec) (string, error)
+
+// serviceOperationState is the state of the operation the runtime performs on the service.
+type serviceOperationState int
+
+const (
+	// serviceOperationIdle no operation is running, actions are performed right away.
+	serviceOperationIdle serviceOperationState = iota
+	// serviceOperationStarting the service is being checked and installed.
+	serviceOperationStarting
+	// serviceOperationAwaitingCheckin the teardown awaits the first check-in of the service.
+	serviceOperationAwaitingCheckin
+	// serviceOperationUninstalling the service is being uninstalled.
+	serviceOperationUninstalling
+)
+
+// serviceOperationResult is the result of an operation run in a worker goroutine.
+type serviceOperationResult struct {
+	action actionMode
+	err    error
+}
+
+// serviceStatusResult is the parsed output of the status command of the service run after it missed check-ins.
+type serviceStatusResult struct {
+	missedCheckins int
+	status         string
+}
 
 // serviceRuntime provides the command runtime for running a component as a service.
 type serviceRuntime struct {
 	comp component.Component
 	log  *logger.Logger
 
-	ch       chan ComponentState
-	actionCh chan actionMode
-	compCh   chan component.Component
-	statusCh chan service.Status
+	ch              chan ComponentState
+	actionCh        chan actionMode
+	compCh          chan component.Component
+	statusCh        chan service.Status
+	installFailedCh chan error
+	operationCh     chan serviceOperationResult
+	serviceStatusCh chan serviceStatusResult
 
 	state ComponentState
+	clock clock.Clock
 
-	executeServiceCommandImpl executeServiceCommandFunc
+	executeServiceCommandImpl       executeServiceCommandFunc
+	executeServiceStatusCommandImpl executeServiceStatusCommandFunc
 }
 
 // newServiceRuntime creates a new command runtime for the provided component.
@@ -63,14 +102,19 @@ func newServiceRuntime(comp component.Component, logger *logger.Logger) (*servic
 	state := newComponentState(&comp)
 
 	s := &serviceRuntime{
-		comp:                      comp,
-		log:                       logger.Named("service_runtime"),
-		ch:                        make(chan ComponentState),
-		actionCh:                  make(chan actionMode, 1),
-		compCh:                    make(chan component.Component, 1),
-		statusCh:                  make(chan service.Status),
-		state:                     state,
-		executeServiceCommandImpl: executeServiceCommand,
+		comp:                            comp,
+		log:                             logger.Named("service_runtime"),
+		ch:                              make(chan ComponentState),
+		actionCh:                        make(chan actionMode, 1),
+		compCh:                          make(chan component.Component, 1),
+		statusCh:                        make(chan service.Status),
+		installFailedCh:                 make(chan error, 1),
+		operationCh:                     make(chan serviceOperationResult),
+		serviceStatusCh:                 make(chan serviceStatusResult),
+		state:                           state,
+		clock:                           clock.Real(),
+		executeServiceCommandImpl:       executeServiceCommand,
+		executeServiceStatusCommandImpl: executeServiceStatusCommand,
 	}
 
 	// Set initial state as STOPPED
@@ -83,17 +127,33 @@ func newServiceRuntime(comp component.Component, logger *logger.Logger) (*servic
 // Called by Manager inside a goroutine. Run does not return until the passed in context is done. Run is always
 // called before any of the other methods in the interface and once the context is done none of those methods should
 // ever be called again.
+//
+// The check, install and uninstall commands of the service run in worker goroutines, so the check-ins of the
+// service keep being processed while they run. Actions received while an operation is running are deferred until
+// the operation is done. The status command run after missed check-ins also runs in a worker goroutine, its output
+// is added to the state once it is done.
 func (s *serviceRuntime) Run(ctx context.Context, comm Communicator) (err error) {
-	checkinTimer := time.NewTimer(s.checkinPeriod())
+	checkinTimer := s.clock.NewTimer(s.checkinPeriod())
 	defer checkinTimer.Stop()
 
 	// Stop the check-ins timer initially
 	checkinTimer.Stop()
 
+	// Timer for awaiting the first check-in of the service before the teardown
+	teardownTimer := s.clock.NewTimer(s.checkinPeriod())
+	defer teardownTimer.Stop()
+	teardownTimer.Stop()
+
 	var (
 		cis            *connInfoServer
 		lastCheckin    time.Time
 		missedCheckins int
+
+		opState serviceOperationState
+		// only the latest action received while an operation is running is performed
+		pendingAction *actionMode
+		// only one status command runs at a time
+		statusRunning bool
 	)
 
 	cisStop := func() {
@@ -104,71 +164,159 @@ func (s *serviceRuntime) Run(ctx context.Context, comm Communicator) (err error)
 	}
 	defer cisStop()
 
+	teardown := func(checkedIn bool) {
+		opState = serviceOperationUninstalling
+		s.teardown(ctx, comm, checkedIn)
+	}
+
+	performAction := func(as actionMode) {
+		switch as {
+		case actionStart:
+			// Initial state on start
+			lastCheckin = time.Time{}
+			missedCheckins = 0
+			checkinTimer.Stop()
+			cisStop()
+
+			// Start connection info
+			var err error
+			cis, err = newConnInfoServer(s.log, comm, s.comp.InputSpec.Spec.Service.CPort)
+			if err != nil {
+				s.forceCompState(client.UnitStateFailed, fmt.Sprintf("failed to start connection info service %s: %v", s.name(), err))
+				return
+			}
+
+			// Start service
+			s.forceCompState(client.UnitStateStarting, fmt.Sprintf("Starting: %s service runtime", s.name()))
+			opState = serviceOperationStarting
+			s.runOperation(ctx, actionStart, s.start)
+		case actionStop, actionTeardown:
+			// Stop check-in timer
+			s.log.Debugf("stop check-in timer for %s service", s.name())
+			checkinTimer.Stop()
+
+			// Stop connection info
+			s.log.Debugf("stop connection info for %s service", s.name())
+			cisStop()
+
+			s.log.Infof("stopping %s service runtime", s.name())
+			if as == actionStop {
+				s.stopped()
+				return
+			}
+
+			// If never checked in await for the checkin with the timeout
+			if s.isRunning() && lastCheckin.IsZero() {
+				timeout := s.checkinPeriod()
+				s.log.Infof("%s service had never checked in, await for check-in for %v", s.name(), timeout)
+				opState = serviceOperationAwaitingCheckin
+				teardownTimer.Reset(timeout)
+				return
+			}
+			teardown(!lastCheckin.IsZero())
+		}
+	}
+
 	for {
-		var err error
 		select {
 		case <-ctx.Done():
 			s.log.Debug("context is done. exiting.")
 			return ctx.Err()
 		case as := <-s.actionCh:
-			switch as {
+			if opState != serviceOperationIdle {
+				s.log.Debugf("operation on %s service in progress, action deferred until it's done", s.name())
+				pendingAction = &as
+				break
+			}
+			performAction(as)
+		case res := <-s.operationCh:
+			opState = serviceOperationIdle
+			switch res.action {
 			case actionStart:
-				// Initial state on start
-				lastCheckin = time.Time{}
-				missedCheckins = 0
-				checkinTimer.Stop()
-				cisStop()
-
-				// Start connection info
-				if cis == nil {
-					cis, err = newConnInfoServer(s.log, comm, s.comp.InputSpec.Spec.Service.CPort)
-					if err != nil {
-						err = fmt.Errorf("failed to start connection info service %s: %w", s.name(), err)
-						break
-					}
-				}
-
-				// Start service
-				err = s.start(ctx)
-				if err != nil {
+				if res.err != nil {
 					cisStop()
-					break
+					s.forceCompState(client.UnitStateFailed, res.err.Error())
+				} else if pendingAction == nil {
+					// Start check-in timer
+					checkinTimer.Reset(s.checkinPeriod())
 				}
-
-				// Start check-in timer
-				checkinTimer.Reset(s.checkinPeriod())
-			case actionStop, actionTeardown:
-				// Stop check-in timer
-				s.log.Debugf("stop check-in timer for %s service", s.name())
-				checkinTimer.Stop()
-
-				// Stop connection info
-				s.log.Debugf("stop connection info for %s service", s.name())
-				cisStop()
-
-				// Stop service
-				s.stop(ctx, comm, lastCheckin, as == actionTeardown)
+			case actionTeardown:
+				if res.err != nil {
+					s.log.Errorf("failed %s service uninstall, err: %v", s.name(), res.err)
+				}
+				s.stopped()
 			}
-			if err != nil {
-				s.forceCompState(client.UnitStateFailed, err.Error())
+			if pendingAction != nil {
+				as := *pendingAction
+				pendingAction = nil
+				performAction(as)
 			}
 		case newComp := <-s.compCh:
 			s.processNewComp(newComp, comm)
 		case checkin := <-comm.CheckinObserved():
+			if opState == serviceOperationAwaitingCheckin {
+				// Received check in, proceed with the teardown
+				teardownTimer.Stop()
+				teardown(true)
+				break
+			}
+			if fault.DropCheckinObserved(s.comp.ID) {
+				break
+			}
 			s.processCheckin(checkin, comm, &lastCheckin)
-		case <-checkinTimer.C:
-			s.checkStatus(s.checkinPeriod(), &lastCheckin, &missedCheckins)
+		case <-teardownTimer.C():
+			if opState == serviceOperationAwaitingCheckin {
+				s.log.Debugf("stopping %s service, timed out awaiting check-in", s.name())
+				teardown(false)
+			}
+		case err := <-s.installFailedCh:
+			// the service is not installed, don't wait for its check-ins
+			if s.isRunning() {
+				checkinTimer.Stop()
+				s.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: install retries exhausted for %s service: %v", s.name(), err))
+			}
+		case <-checkinTimer.C():
+			if s.checkStatus(s.checkinPeriod(), &lastCheckin, &missedCheckins) && !statusRunning && s.comp.InputSpec.Spec.Service.Operations.Status != nil {
+				statusRunning = true
+				s.runServiceStatus(ctx, missedCheckins)
+			}
 			checkinTimer.Reset(s.checkinPeriod())
+		case res := <-s.serviceStatusCh:
+			statusRunning = false
+			// the status is outdated when the check-ins changed while the command was running
+			if s.isRunning() && res.missedCheckins == missedCheckins && res.status != "" {
+				s.missedCheckinsState(missedCheckins, res.status)
+			}
 		}
 	}
 }
 
+// runServiceStatus runs the status command of the service in a worker goroutine, its output is sent to the Run loop.
+func (s *serviceRuntime) runServiceStatus(ctx context.Context, missedCheckins int) {
+	go func() {
+		status := s.serviceStatus(ctx)
+		select {
+		case <-ctx.Done():
+		case s.serviceStatusCh <- serviceStatusResult{missedCheckins: missedCheckins, status: status}:
+		}
+	}()
+}
+
+// runOperation runs the operation in a worker goroutine, its result is sent to the Run loop.
+func (s *serviceRuntime) runOperation(ctx context.Context, as actionMode, op func(context.Context) error) {
+	go func() {
+		err := op(ctx)
+		select {
+		case <-ctx.Done():
+		case s.operationCh <- serviceOperationResult{action: as, err: err}:
+		}
+	}()
+}
+
+// start checks if the service is installed and installs it otherwise, called from a worker goroutine.
 func (s *serviceRuntime) start(ctx context.Context) (err error) {
 	name := s.name()
 
-	// Set state to starting
-	s.forceCompState(client.UnitStateStarting, fmt.Sprintf("Starting: %s service runtime", name))
-
 	// Call the check command of the service
 	s.log.Infof("check if %s service is installed", name)
 	err = s.check(ctx)
@@ -186,65 +334,30 @@ func (s *serviceRuntime) start(ctx context.Context) (err error) {
 	return nil
 }
 
-func (s *serviceRuntime) stop(ctx context.Context, comm Communicator, lastCheckin time.Time, teardown bool) {
+// teardown sends the stopping state to the service when it checked in and uninstalls it in a worker goroutine.
+func (s *serviceRuntime) teardown(ctx context.Context, comm Communicator, checkedIn bool) {
 	name := s.name()
 
-	s.log.Infof("stopping %s service runtime", name)
-
-	checkedIn := !lastCheckin.IsZero()
-
-	if teardown {
-		// If checked in before, send STOPPING
-		if s.isRunning() {
-			// If never checked in await for the checkin with the timeout
-			if !checkedIn {
-				timeout := s.checkinPeriod()
-				s.log.Infof("%s service had never checked in, await for check-in for %v", name, timeout)
-				checkedIn = s.awaitCheckin(ctx, comm, timeout)
-			}
-
-			// Received check in send STOPPING
-			if checkedIn {
-				s.log.Infof("%s service has checked in, send stopping state to service", name)
-				s.state.forceExpectedState(client.UnitStateStopping)
-				comm.CheckinExpected(s.state.toCheckinExpected(), nil)
-			} else {
-				s.log.Infof("%s service had never checked in, proceed to uninstall", name)
-			}
-		}
-
-		s.log.Infof("uninstall %s service", name)
-		err := s.uninstall(ctx)
-		if err != nil {
-			s.log.Errorf("failed %s service uninstall, err: %v", name, err)
+	if s.isRunning() {
+		// Received check in send STOPPING
+		if checkedIn {
+			s.log.Infof("%s service has checked in, send stopping state to service", name)
+			s.state.forceExpectedState(client.UnitStateStopping)
+			comm.CheckinExpected(s.state.toCheckinExpected(), nil)
+		} else {
+			s.log.Infof("%s service had never checked in, proceed to uninstall", name)
 		}
 	}
 
-	// Force component stopped state
-	s.log.Debug("set %s service runtime to stopped state", name)
-	s.forceCompState(client.UnitStateStopped, fmt.Sprintf("Stopped: %s service runtime", name))
+	s.log.Infof("uninstall %s service", name)
+	s.runOperation(ctx, actionTeardown, s.uninstall)
 }
 
-// awaitCheckin awaits checkin with timeout.
-func (s *serviceRuntime) awaitCheckin(ctx context.Context, comm Communicator, timeout time.Duration) bool {
+// stopped forces the component to the stopped state.
+func (s *serviceRuntime) stopped() {
 	name := s.name()
-	t := time.NewTimer(timeout)
-	defer t.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			// stop cancelled
-			s.log.Debugf("stopping %s service, cancelled", name)
-			return false
-		case <-t.C:
-			// stop timed out
-			s.log.Debugf("stopping %s service, timed out", name)
-			return false
-		case <-comm.CheckinObserved():
-			return true
-		}
-	}
+	s.log.Debugf("set %s service runtime to stopped state", name)
+	s.forceCompState(client.UnitStateStopped, fmt.Sprintf("Stopped: %s service runtime", name))
 }
 
 func (s *serviceRuntime) processNewComp(newComp component.Component, comm Communicator) {
@@ -281,7 +394,7 @@ func (s *serviceRuntime) processCheckin(checkin *proto.CheckinObserved, comm Com
 		// first check-in
 		sendExpected = true
 	}
-	*lastCheckin = time.Now().UTC()
+	*lastCheckin = s.clock.Now().UTC()
 	if s.state.syncCheckin(checkin) {
 		changed = true
 	}
@@ -305,28 +418,36 @@ func (s *serviceRuntime) isRunning() bool {
 		s.state.State != client.UnitStateStopped
 }
 
-// checkStatus checks check-ins state, called on timer
-func (s *serviceRuntime) checkStatus(checkinPeriod time.Duration, lastCheckin *time.Time, missedCheckins *int) {
-	if s.isRunning() {
-		now := time.Now().UTC()
-		if lastCheckin.IsZero() {
-			// never checked-in
-			*missedCheckins++
-		} else if now.Sub(*lastCheckin) > checkinPeriod {
-			// missed check-in during required period
-			*missedCheckins++
-		} else if now.Sub(*lastCheckin) <= checkinPeriod {
-			*missedCheckins = 0
-		}
-		if *missedCheckins == 0 {
-			s.compState(client.UnitStateHealthy, *missedCheckins)
-		} else if *missedCheckins > 0 && *missedCheckins < maxCheckinMisses {
-			s.compState(client.UnitStateDegraded, *missedCheckins)
-		} else if *missedCheckins >= maxCheckinMisses {
-			// something is wrong; the service should be checking in
-			msg := fmt.Sprintf("Failed: %s service missed %d check-ins", s.name(), maxCheckinMisses)
-			s.forceCompState(client.UnitStateFailed, msg)
-		}
+// checkStatus checks check-ins state, called on timer. It returns true when the running service missed check-ins.
+func (s *serviceRuntime) checkStatus(checkinPeriod time.Duration, lastCheckin *time.Time, missedCheckins *int) bool {
+	if !s.isRunning() {
+		return false
+	}
+	now := s.clock.Now().UTC()
+	if lastCheckin.IsZero() {
+		// never checked-in
+		*missedCheckins++
+	} else if now.Sub(*lastCheckin) > checkinPeriod {
+		// missed check-in during required period
+		*missedCheckins++
+	} else if now.Sub(*lastCheckin) <= checkinPeriod {
+		*missedCheckins = 0
+	}
+	s.missedCheckinsState(*missedCheckins, "")
+	return *missedCheckins > 0
+}
+
+// missedCheckinsState sets the state of the component from its missed check-ins, status is the output of the
+// service status command added to the message.
+func (s *serviceRuntime) missedCheckinsState(missedCheckins int, status string) {
+	if missedCheckins == 0 {
+		s.compState(client.UnitStateHealthy, missedCheckins, "")
+	} else if missedCheckins > 0 && missedCheckins < maxCheckinMisses {
+		s.compState(client.UnitStateDegraded, missedCheckins, status)
+	} else if missedCheckins >= maxCheckinMisses {
+		// something is wrong; the service should be checking in
+		msg := withServiceStatus(fmt.Sprintf("Failed: %s service missed %d check-ins", s.name(), maxCheckinMisses), status)
+		s.forceCompState(client.UnitStateFailed, msg)
 	}
 }
 
@@ -407,7 +528,9 @@ func (s *serviceRuntime) sendObserved() {
 	s.ch <- s.state.Copy()
 }
 
-func (s *serviceRuntime) compState(state client.UnitState, missedCheckins int) {
+// compState sets the state of the component, status is the output of the service status command
+// added to the degraded message.
+func (s *serviceRuntime) compState(state client.UnitState, missedCheckins int, status string) {
 	name := s.name()
 	msg := stateUnknownMessage
 	if state == client.UnitStateHealthy {
@@ -418,6 +541,7 @@ func (s *serviceRuntime) compState(state client.UnitState, missedCheckins int) {
 		} else {
 			msg = fmt.Sprintf("Degraded: %s missed %d check-ins", name, missedCheckins)
 		}
+		msg = withServiceStatus(msg, status)
 	}
 	if s.state.compState(state, msg) {
 		s.sendObserved()
@@ -435,7 +559,23 @@ func (s *serviceRuntime) check(ctx context.Context) error {
 		return ErrOperationSpecUndefined
 	}
 	s.log.Debugf("check if the %s is installed", s.comp.InputSpec.BinaryName)
-	return s.executeServiceCommandImpl(ctx, s.log, s.comp.InputSpec.BinaryPath, s.comp.InputSpec.Spec.Service.Operations.Check, false)
+	return s.executeServiceCommandImpl(ctx, s.log, s.comp.InputSpec.BinaryPath, s.comp.InputSpec.Spec.Service.Operations.Check, s.operationOutputPath("check"), false, nil)
+}
+
+// serviceStatus executes the service status command and returns its parsed output, it returns an empty
+// string when the service doesn't define a status command or the command has no output.
+func (s *serviceRuntime) serviceStatus(ctx context.Context) string {
+	spec := s.comp.InputSpec.Spec.Service.Operations.Status
+	if spec == nil {
+		return ""
+	}
+	s.log.Debugf("get the status of %s service", s.comp.InputSpec.BinaryName)
+	output, err := s.executeServiceStatusCommandImpl(ctx, s.log, s.comp.InputSpec.BinaryPath, spec)
+	if err != nil {
+		// the service can report why it is unhealthy through the output of a failed command
+		s.log.Warnf("failed to get the status of %s service: %v", s.name(), err)
+	}
+	return parseServiceStatus(output)
 }
 
 // install executes the service install command
@@ -445,7 +585,26 @@ func (s *serviceRuntime) install(ctx context.Context) error {
 		return ErrOperationSpecUndefined
 	}
 	s.log.Debugf("install %s service", s.comp.InputSpec.BinaryName)
-	return s.executeServiceCommandImpl(ctx, s.log, s.comp.InputSpec.BinaryPath, s.comp.InputSpec.Spec.Service.Operations.Install, true)
+	return s.executeServiceCommandImpl(ctx, s.log, s.comp.InputSpec.BinaryPath, s.comp.InputSpec.Spec.Service.Operations.Install, s.operationOutputPath("install"), true, s.installRetriesExhausted)
+}
+
+// operationOutputPath returns the path of the file the output of the service operation is recorded to.
+func (s *serviceRuntime) operationOutputPath(operation string) string {
+	return ServiceOperationsOutputPath(s.comp.InputSpec.BinaryName, operation)
+}
+
+// installRetriesExhausted is called by the retrier, from its own goroutine, once the install command
+// retries are exhausted.
+func (s *serviceRuntime) installRetriesExhausted(err error) {
+	// only the last failure matters
+	select {
+	case <-s.installFailedCh:
+	default:
+	}
+	select {
+	case s.installFailedCh <- err:
+	default:
+	}
 }
 
 // uninstall executes the service uninstall command
@@ -458,11 +617,18 @@ func UninstallService(ctx context.Context, log *logger.Logger, comp component.Co
 	return uninstallService(ctx, log, comp, executeServiceCommand)
 }
 
+// RunUninstallHook runs an uninstall hook of the input, its output is recorded next to the output of the
+// service operations.
+func RunUninstallHook(ctx context.Context, log *logger.Logger, spec component.InputRuntimeSpec, hook component.UninstallHookSpec) error {
+	log.Debugf("run uninstall hook %s of %s", hook.Name, spec.BinaryName)
+	return executeRecordedCommand(ctx, log, spec.BinaryPath, hook.Args, envSpecToEnv(hook.Env), hook.Timeout, ServiceOperationsOutputPath(spec.BinaryName, "uninstall-hook-"+hook.Name))
+}
+
 func uninstallService(ctx context.Context, log *logger.Logger, comp component.Component, executeServiceCommandImpl executeServiceCommandFunc) error {
 	if comp.InputSpec.Spec.Service.Operations.Uninstall == nil {
 		log.Errorf("missing uninstall spec for %s service", comp.InputSpec.BinaryName)
 		return ErrOperationSpecUndefined
 	}
 	log.Debugf("uninstall %s service", comp.InputSpec.BinaryName)
-	return executeServiceCommandImpl(ctx, log, comp.InputSpec.BinaryPath, comp.InputSpec.Spec.Service.Operations.Uninstall, true)
+	return executeServiceCommandImpl(ctx, log, comp.InputSpec.BinaryPath, comp.InputSpec.Spec.Service.Operations.Uninstall, ServiceOperationsOutputPath(comp.InputSpec.BinaryName, "uninstall"), true, nil)
 }
@@ -6,22 +6,28 @@ package runtime
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/cenkalti/backoff/v4"
 
 	"github.com/dolmen-go/contextio"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/core/process"
@@ -29,7 +35,27 @@ import (
 
 var serviceCmdRetrier = cmdRetrier{}
 
+const (
+	defaultServiceStatusTimeout = 10 * time.Second
+	// defaultServiceOperationTimeout is the timeout of the check, install and uninstall
+	// commands that don't define one
+	defaultServiceOperationTimeout = 10 * time.Minute
+	// outputDrainTimeout is how long the output is read once the process exited, the output is not
+	// closed as long as a child process started by the command holds it.
+	outputDrainTimeout = time.Second
+	// maxCommandOutputSize is the size of a command output file before it's rotated
+	maxCommandOutputSize = 1024 * 1024
+	// maxServiceStatusLen is the maximum length of the service status added to the state message
+	maxServiceStatusLen = 1024
+)
+
 func executeCommand(ctx context.Context, log *logger.Logger, binaryPath string, args []string, env []string, timeout time.Duration) error {
+	return executeCommandWithOutput(ctx, log, binaryPath, args, env, timeout, nil, nil)
+}
+
+// executeCommandWithOutput executes the command like executeCommand, the standard output of the command is written to
+// output and its standard error to errOutput when they are not nil.
+func executeCommandWithOutput(ctx context.Context, log *logger.Logger, binaryPath string, args []string, env []string, timeout time.Duration, output io.Writer, errOutput io.Writer) error {
 	log = log.With("context", "command output")
 	// Create context with timeout if the timeout is greater than 0
 	if timeout > 0 {
@@ -38,27 +64,47 @@ func executeCommand(ctx context.Context, log *logger.Logger, binaryPath string,
 		defer cn()
 	}
 
-	opts := []process.StartOption{
-		process.WithContext(ctx),
-		process.WithArgs(args),
-		process.WithEnv(env),
-	}
+	var cmdOpts []process.CmdOption
 
 	// Set the command working directory from binary
 	// This is needed because the endpoint installer was looking for it's resources in the current working directory
 	wdir := filepath.Dir(binaryPath)
 	if wdir != "." {
-		opts = append(opts,
-			process.WithCmdOptions(func(c *exec.Cmd) error {
-				c.Dir = wdir
-				return nil
-			}))
+		cmdOpts = append(cmdOpts, process.WithWorkDir(wdir))
+	}
+
+	var stdout io.ReadCloser
+	if output != nil {
+		cmdOpts = append(cmdOpts, func(c *exec.Cmd) (err error) {
+			stdout, err = c.StdoutPipe()
+			return err
+		})
+	}
+
+	opts := []process.StartOption{
+		process.WithContext(ctx),
+		process.WithArgs(args),
+		process.WithEnv(env),
+		process.WithCmdOptions(cmdOpts...),
+		process.WithKillTree(),
 	}
 
 	proc, err := process.Start(binaryPath, opts...)
 	if err != nil {
 		return fmt.Errorf("failed starting the command: %w", err)
 	}
+	defer func() {
+		_ = proc.ReleaseTree()
+	}()
+
+	// channel closed once the standard output is read
+	outch := make(chan struct{})
+	if stdout != nil {
+		go func() {
+			_, _ = io.Copy(output, contextio.NewReader(ctx, stdout))
+			close(outch)
+		}()
+	}
 
 	// channel for the last error message from the stderr output
 	errch := make(chan string, 1)
@@ -68,6 +114,9 @@ func executeCommand(ctx context.Context, log *logger.Logger, binaryPath string,
 		scanner := bufio.NewScanner(ctxStderr)
 		for scanner.Scan() {
 			line := scanner.Text()
+			if errOutput != nil {
+				_, _ = fmt.Fprintln(errOutput, line)
+			}
 			if len(line) > 0 {
 				txt := strings.TrimSpace(line)
 				if len(txt) > 0 {
@@ -81,6 +130,32 @@ func executeCommand(ctx context.Context, log *logger.Logger, binaryPath string,
 	}()
 
 	procState := <-proc.Wait()
+	if ctx.Err() != nil {
+		// The context only kills the command itself, installers often spawn children
+		// that would keep running and holding the outputs open.
+		if kErr := proc.KillTree(); kErr != nil {
+			log.Warnf("failed to kill the process tree of the command: %v", kErr)
+		}
+	}
+	if stdout != nil {
+		select {
+		case <-outch:
+		case <-time.After(outputDrainTimeout):
+			log.Warn("timed out reading the command output")
+		}
+		// closing the output unblocks the copy, output must not be written once this function returns
+		_ = stdout.Close()
+		<-outch
+	}
+
+	// the standard error is fully read before the caller gets the result
+	var errmsg string
+	select {
+	case errmsg = <-errch:
+	case <-time.After(outputDrainTimeout):
+		log.Warn("timed out reading the command error output")
+	}
+
 	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
 		err = ctx.Err() // Process was killed due to timeout
 	} else if !procState.Success() {
@@ -88,7 +163,6 @@ func executeCommand(ctx context.Context, log *logger.Logger, binaryPath string,
 	}
 
 	if err != nil {
-		errmsg := <-errch
 		errmsg = strings.TrimSpace(errmsg)
 		if errmsg != "" {
 			err = fmt.Errorf("%s: %w", errmsg, err)
@@ -98,37 +172,185 @@ func executeCommand(ctx context.Context, log *logger.Logger, binaryPath string,
 	return err
 }
 
-func executeServiceCommand(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, shouldRetry bool) error {
+// executeRecordedCommand executes the command like executeCommand and appends its output, along with the time it
+// was executed and its exit code, to the file at outputPath. The command is not recorded when outputPath is empty.
+func executeRecordedCommand(ctx context.Context, log *logger.Logger, binaryPath string, args []string, env []string, timeout time.Duration, outputPath string) error {
+	if outputPath == "" {
+		return executeCommand(ctx, log, binaryPath, args, env, timeout)
+	}
+
+	f, err := openCommandOutput(outputPath)
+	if err != nil {
+		// not being able to record the output must not prevent the command from being executed
+		log.Warnf("failed to open command output file %s: %v", outputPath, err)
+		return executeCommand(ctx, log, binaryPath, args, env, timeout)
+	}
+	defer f.Close()
+
+	// stdout and stderr are written concurrently, the writer is closed before the file so a child
+	// process still holding the output can't write to a closed file
+	w := &commandOutputWriter{w: f}
+	defer w.Close()
+
+	started := time.Now().UTC()
+	_, _ = fmt.Fprintf(w, "=== %s %s %s\n", started.Format(time.RFC3339Nano), binaryPath, strings.Join(args, " "))
+	err = executeCommandWithOutput(ctx, log, binaryPath, args, env, timeout, w, w)
+	_, _ = fmt.Fprintf(w, "=== %s %s (took %s)\n", time.Now().UTC().Format(time.RFC3339Nano), commandResult(err), time.Since(started))
+	return err
+}
+
+// openCommandOutput opens the command output file for appending, the previous output is kept in a single backup
+// file once the output file grows over maxCommandOutputSize.
+func openCommandOutput(outputPath string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0750); err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(outputPath); err == nil && fi.Size() > maxCommandOutputSize {
+		if err := os.Rename(outputPath, outputPath+".1"); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(outputPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+}
+
+// commandResult describes the result of a command.
+func commandResult(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "exit code 0"
+	case errors.As(err, &exitErr):
+		return fmt.Sprintf("exit code %d", exitErr.ExitCode())
+	default:
+		return fmt.Sprintf("failed: %v", err)
+	}
+}
+
+// commandOutputWriter serializes the writes of the standard output and error of a command, writes are
+// dropped once it's closed.
+type commandOutputWriter struct {
+	mx     sync.Mutex
+	w      io.Writer
+	closed bool
+}
+
+func (c *commandOutputWriter) Write(p []byte) (int, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.closed {
+		return len(p), nil
+	}
+	return c.w.Write(p)
+}
+
+func (c *commandOutputWriter) Close() error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.closed = true
+	return nil
+}
+
+// ServiceOperationsOutputPath returns the path of the file the output of the operation of the service binary is
+// persisted to.
+func ServiceOperationsOutputPath(binaryName string, operation string) string {
+	return filepath.Join(paths.ServiceOperations(), binaryName, operation+".log")
+}
+
+func executeServiceCommand(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, outputPath string, shouldRetry bool, onRetriesExhausted func(error)) error {
 	if spec == nil {
 		log.Warnf("spec is nil, nothing to execute, binaryPath: %s", binaryPath)
 		return nil
 	}
 
 	if !shouldRetry {
-		return executeCommand(ctx, log, binaryPath, spec.Args, envSpecToEnv(spec.Env), spec.Timeout)
+		return executeRecordedCommand(ctx, log, binaryPath, spec.Args, envSpecToEnv(spec.Env), operationTimeout(spec), outputPath)
 	}
 
 	executeServiceCommandWithRetries(
-		ctx, log, binaryPath, spec,
+		ctx, log, binaryPath, spec, outputPath, onRetriesExhausted,
 		context.Background(), 20*time.Second, 15*time.Minute,
 	)
 	return nil
 }
 
+// operationTimeout returns the timeout of the service operation, a hung command is killed
+// once its timeout expires.
+func operationTimeout(spec *component.ServiceOperationsCommandSpec) time.Duration {
+	if spec.Timeout == 0 {
+		return defaultServiceOperationTimeout
+	}
+	return spec.Timeout
+}
+
+// executeServiceStatusCommand executes the status command of the service and returns its standard output.
+// The output is returned even when the command fails, a service may report its failure through it.
+func executeServiceStatusCommand(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec) (string, error) {
+	timeout := spec.Timeout
+	if timeout == 0 {
+		timeout = defaultServiceStatusTimeout
+	}
+
+	var output bytes.Buffer
+	err := executeCommandWithOutput(ctx, log, binaryPath, spec.Args, envSpecToEnv(spec.Env), timeout, &output, nil)
+	return output.String(), err
+}
+
+// parseServiceStatus parses the output of the service status command to be added to the state message.
+// JSON output is compacted, any other output is reduced to a single line.
+func parseServiceStatus(output string) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return ""
+	}
+
+	var status string
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(output)); err == nil {
+		status = compacted.String()
+	} else {
+		status = strings.Join(strings.Fields(output), " ")
+	}
+
+	if len(status) > maxServiceStatusLen {
+		// cut on a rune boundary, the message must stay valid UTF-8
+		cut := maxServiceStatusLen
+		for cut > 0 && !utf8.RuneStart(status[cut]) {
+			cut--
+		}
+		status = status[:cut] + "..."
+	}
+	return status
+}
+
+// withServiceStatus adds the parsed output of the service status command to msg.
+func withServiceStatus(msg string, status string) string {
+	if status == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s; service status: %s", msg, status)
+}
+
+// executeServiceCommandWithRetries executes the command in the background, retrying it as configured in the spec.
+// The output of every execution is recorded to outputPath when it's not empty. onRetriesExhausted is called with the
+// last error of the command when the retries are exhausted, it can be nil.
 func executeServiceCommandWithRetries(
-	cmdCtx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec,
-	retryCtx context.Context, defaultRetryInitInterval time.Duration, retryMaxInterval time.Duration,
+	cmdCtx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, outputPath string, onRetriesExhausted func(error),
+	retryCtx context.Context, defaultRetryInitInterval time.Duration, defaultRetryMaxInterval time.Duration,
 ) {
+	retry := spec.Retry
+
 	// If no initial retry interval is specified, use default value
-	retryInitInterval := spec.Retry.InitInterval
-	if retryInitInterval == 0 {
-		retryInitInterval = defaultRetryInitInterval
+	if retry.InitInterval == 0 {
+		retry.InitInterval = defaultRetryInitInterval
+	}
+	if retry.MaxInterval == 0 {
+		retry.MaxInterval = defaultRetryMaxInterval
 	}
 
 	serviceCmdRetrier.Start(
 		cmdCtx, log,
-		binaryPath, spec.Args, envSpecToEnv(spec.Env), spec.Timeout,
-		retryCtx, retryInitInterval, retryMaxInterval,
+		binaryPath, spec.Args, envSpecToEnv(spec.Env), operationTimeout(spec), outputPath,
+		retryCtx, retry, onRetriesExhausted,
 	)
 }
 
@@ -138,15 +360,22 @@ type cmdRetryInfo struct {
 	cmdDone       <-chan struct{}
 }
 
+// cmdBreaker is an open circuit breaker, the command is not executed until the breaker expires.
+type cmdBreaker struct {
+	err   error
+	until time.Time
+}
+
 type cmdRetrier struct {
-	mu   sync.RWMutex
-	cmds map[string]cmdRetryInfo
+	mu       sync.RWMutex
+	cmds     map[string]cmdRetryInfo
+	breakers map[string]cmdBreaker
 }
 
 func (cr *cmdRetrier) Start(
 	cmdCtx context.Context, log *logger.Logger,
-	binaryPath string, args []string, env []string, timeout time.Duration,
-	retryCtx context.Context, retrySleepInitDuration time.Duration, retrySleepMaxDuration time.Duration,
+	binaryPath string, args []string, env []string, timeout time.Duration, outputPath string,
+	retryCtx context.Context, retry component.RetryConfig, onRetriesExhausted func(error),
 ) {
 	cmdKey := cr.cmdKey(binaryPath, args, env)
 
@@ -155,6 +384,16 @@ func (cr *cmdRetrier) Start(
 	// these retries as well as the command process.
 	cr.Stop(cmdKey, log)
 
+	// The retries of the command were exhausted recently, don't hammer it again
+	// until the circuit breaker expires.
+	if err := cr.openBreaker(cmdKey); err != nil {
+		log.Warnf("service command not executed, retries were exhausted recently: %s", err.Error())
+		if onRetriesExhausted != nil {
+			onRetriesExhausted(err)
+		}
+		return
+	}
+
 	// Track the command so we can cancel it and it's retries later.
 	cmdCtx, cmdCancelFn := context.WithCancel(cmdCtx)
 	retryCtx, retryCancelFn := context.WithCancel(retryCtx)
@@ -163,10 +402,16 @@ func (cr *cmdRetrier) Start(
 
 	// Execute command with retries and exponential backoff between attempts
 	expBackoff := backoff.NewExponentialBackOff()
-	expBackoff.InitialInterval = retrySleepInitDuration
-	expBackoff.MaxInterval = retrySleepMaxDuration
-
-	backoffCtx := backoff.WithContext(expBackoff, retryCtx)
+	expBackoff.InitialInterval = retry.InitInterval
+	expBackoff.MaxInterval = retry.MaxInterval
+	// Retries are bounded by the number of retries, not by their duration
+	expBackoff.MaxElapsedTime = 0
+
+	var b backoff.BackOff = expBackoff
+	if retry.MaxRetries > 0 {
+		b = backoff.WithMaxRetries(b, uint64(retry.MaxRetries))
+	}
+	backoffCtx := backoff.WithContext(b, retryCtx)
 
 	// Since we will be executing the command with infinite retries, we don't
 	// want to block.  So we execute the command with retries in its own
@@ -178,10 +423,9 @@ func (cr *cmdRetrier) Start(
 		// returns a non-nil error. We will block here until executeCommand
 		// returns a nil error, indicating that the command being executed has
 		// successfully completed execution.
-		//nolint: errcheck // No point checking the error inside the goroutine.
-		backoff.RetryNotify(
+		err := backoff.RetryNotify(
 			func() error {
-				err := executeCommand(cmdCtx, log, binaryPath, args, env, timeout)
+				err := executeRecordedCommand(cmdCtx, log, binaryPath, args, env, timeout, outputPath)
 				cmdDone <- struct{}{}
 				return err
 			},
@@ -199,6 +443,18 @@ func (cr *cmdRetrier) Start(
 		)
 
 		cr.untrack(cmdKey)
+
+		// The command is still failing and the retries were not cancelled, so they are exhausted.
+		if err != nil && retryCtx.Err() == nil && cmdCtx.Err() == nil {
+			err = fmt.Errorf("%w after %d retries: %s", ErrRetriesExhausted, retryAttempt, err.Error())
+			log.Errorf("service command execution failed: %s", err.Error())
+			if retry.CircuitBreakerTimeout > 0 {
+				cr.trip(cmdKey, err, retry.CircuitBreakerTimeout)
+			}
+			if onRetriesExhausted != nil {
+				onRetriesExhausted(err)
+			}
+		}
 	}()
 }
 
@@ -248,6 +504,40 @@ func (cr *cmdRetrier) untrack(cmdKey string) {
 	delete(cr.cmds, cmdKey)
 }
 
+// trip opens the circuit breaker of the command for timeout.
+func (cr *cmdRetrier) trip(cmdKey string, err error, timeout time.Duration) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	// Initialize map if needed
+	if cr.breakers == nil {
+		cr.breakers = map[string]cmdBreaker{}
+	}
+
+	cr.breakers[cmdKey] = cmdBreaker{
+		err:   err,
+		until: time.Now().Add(timeout),
+	}
+}
+
+// openBreaker returns the error the circuit breaker of the command was opened with, or nil
+// when the command can be executed.
+func (cr *cmdRetrier) openBreaker(cmdKey string) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	breaker, exists := cr.breakers[cmdKey]
+	if !exists {
+		return nil
+	}
+	if time.Now().After(breaker.until) {
+		// The breaker expired, give the command a new chance
+		delete(cr.breakers, cmdKey)
+		return nil
+	}
+	return fmt.Errorf("circuit breaker open until %s: %w", breaker.until.Format(time.RFC3339), breaker.err)
+}
+
 // cmdKey returns a unique, deterministic integer for the combination of the given
 // binaryPath, args, and env. This integer can be used to determine if the same command
 // is being executed again or not.
@@ -16,6 +16,7 @@ import (
 	"testing"
 	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap/zaptest/observer"
 
@@ -35,6 +36,7 @@ type progConfig struct {
 	ExitCode     int
 	SleepMS      int
 	SucceedAfter int64 // ms since unix epoch
+	Output       string
 }
 
 const testProgramTemplate = `
@@ -54,6 +56,9 @@ func main() {
 		}
 	}
 
+	if len({{printf "%q" .Output}}) > 0 {
+		fmt.Fprint(os.Stdout, {{printf "%q" .Output}})
+	}
 	if len("{{.ErrMessage}}") > 0 {
 		fmt.Fprintf(os.Stderr, "{{.ErrMessage}}")
 	}
@@ -126,15 +131,15 @@ func TestExecuteCommand(t *testing.T) {
 		},
 		{
 			name: "fail no error output",
-			cfg:  progConfig{"", 1, 0, 0},
+			cfg:  progConfig{"", 1, 0, 0, ""},
 		},
 		{
 			name: "fail with error output",
-			cfg:  progConfig{"something failed", 2, 0, 0},
+			cfg:  progConfig{"something failed", 2, 0, 0, ""},
 		},
 		{
 			name:    "fail with timeout",
-			cfg:     progConfig{"", 3, 5000, 0}, // executable runs for 5 seconds
+			cfg:     progConfig{"", 3, 5000, 0, ""}, // executable runs for 5 seconds
 			timeout: 100 * time.Millisecond,
 			wantErr: context.DeadlineExceeded,
 		},
@@ -197,6 +202,149 @@ func TestExecuteCommand(t *testing.T) {
 
 }
 
+func TestExecuteCommandKillsProcessTree(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the state of the child is read from /proc")
+	}
+	log := logp.NewLogger("test_service")
+
+	// the command starts a child holding its outputs open and waits for it
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	script := fmt.Sprintf("sleep 100 & echo $! > %s; wait", pidFile)
+	err := executeCommand(context.Background(), log, "/bin/sh", []string{"-c", script}, nil, 500*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	raw, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid := strings.TrimSpace(string(raw))
+	require.Eventually(t, func() bool {
+		// the child is gone or a zombie waiting for the init process to reap it
+		stat, err := os.ReadFile(filepath.Join("/proc", pid, "stat"))
+		if err != nil {
+			return os.IsNotExist(err)
+		}
+		fields := strings.Fields(string(stat))
+		return len(fields) > 2 && fields[2] == "Z"
+	}, 5*time.Second, 50*time.Millisecond, "the child %s of the command is still running", pid)
+}
+
+func TestOperationTimeout(t *testing.T) {
+	require.Equal(t, 10*time.Minute, operationTimeout(&component.ServiceOperationsCommandSpec{}), "a hung command without timeout is killed after 10 minutes")
+	require.Equal(t, time.Minute, operationTimeout(&component.ServiceOperationsCommandSpec{Timeout: time.Minute}))
+}
+
+func TestExecuteServiceStatusCommand(t *testing.T) {
+	log := logp.NewLogger("test_service")
+
+	tests := []struct {
+		name    string
+		cfg     progConfig
+		wantErr bool
+	}{
+		{
+			name: "success",
+			cfg:  progConfig{Output: `{"status": "healthy"}`},
+		},
+		{
+			name:    "fail with output",
+			cfg:     progConfig{ErrMessage: "service is unhealthy", ExitCode: 1, Output: `{"status": "failed"}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cn := context.WithCancel(context.Background())
+			defer cn()
+
+			exePath, err := prepareTestProg(ctx, log, t.TempDir(), tc.cfg)
+			require.NoError(t, err)
+
+			output, err := executeServiceStatusCommand(ctx, log, exePath, &component.ServiceOperationsCommandSpec{})
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.cfg.Output, output)
+		})
+	}
+}
+
+func TestExecuteRecordedCommand(t *testing.T) {
+	ctx := context.Background()
+	log := logp.NewLogger("test_service")
+
+	exeConfig := progConfig{
+		ErrMessage: "install failed",
+		ExitCode:   2,
+		Output:     "installing\n",
+	}
+	exePath, err := prepareTestProg(ctx, log, t.TempDir(), exeConfig)
+	require.NoError(t, err)
+
+	outputPath := filepath.Join(t.TempDir(), "prog", "install.log")
+	for i := 0; i < 2; i++ {
+		err = executeRecordedCommand(ctx, log, exePath, []string{"install"}, nil, 0, outputPath)
+		require.Error(t, err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+
+	// every execution is appended
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	require.Len(t, lines, 8)
+	for i := 0; i < 2; i++ {
+		execution := lines[i*4 : (i+1)*4]
+		require.True(t, strings.HasPrefix(execution[0], "=== "))
+		require.True(t, strings.HasSuffix(execution[0], exePath+" install"))
+		require.ElementsMatch(t, []string{"installing", exeConfig.ErrMessage}, execution[1:3])
+		require.True(t, strings.HasPrefix(execution[3], "=== "))
+		require.Contains(t, execution[3], "exit code 2")
+	}
+}
+
+func TestParseServiceStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "json",
+			output: "{\n  \"status\": \"failed\",\n  \"reason\": \"kernel module not loaded\"\n}\n",
+			want:   `{"status":"failed","reason":"kernel module not loaded"}`,
+		},
+		{
+			name:   "text",
+			output: "service failed\n\tkernel module not loaded\n",
+			want:   "service failed kernel module not loaded",
+		},
+		{
+			name:   "truncated",
+			output: strings.Repeat("a", maxServiceStatusLen+10),
+			want:   strings.Repeat("a", maxServiceStatusLen) + "...",
+		},
+		{
+			name:   "truncated on a rune boundary",
+			output: "a" + strings.Repeat("é", maxServiceStatusLen),
+			want:   "a" + strings.Repeat("é", (maxServiceStatusLen-1)/2) + "...",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			status := parseServiceStatus(tc.output)
+			require.Equal(t, tc.want, status)
+			require.True(t, utf8.ValidString(status), "status is not valid UTF-8")
+		})
+	}
+}
+
 func TestExecuteServiceCommand(t *testing.T) {
 	// No spec
 	t.Run("no_spec", func(t *testing.T) {
@@ -206,7 +354,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 		exePath, err := prepareTestProg(ctx, log, t.TempDir(), progConfig{})
 		require.NoError(t, err)
 
-		err = executeServiceCommand(ctx, log, exePath, nil, false)
+		err = executeServiceCommand(ctx, log, exePath, nil, "", false, nil)
 		require.NoError(t, err)
 
 		warnLogs := obs.FilterLevelExact(zapcore.WarnLevel)
@@ -226,7 +374,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 		exePath, err := prepareTestProg(ctx, log, t.TempDir(), exeConfig)
 		require.NoError(t, err)
 
-		err = executeServiceCommand(ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, false)
+		err = executeServiceCommand(ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, "", false, nil)
 		require.EqualError(t, err, fmt.Sprintf("%s: exit status %d", exeConfig.ErrMessage, exeConfig.ExitCode))
 
 		require.Equal(t, 1, obs.Len())
@@ -243,7 +391,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 		exePath, err := prepareTestProg(ctx, log, t.TempDir(), progConfig{})
 		require.NoError(t, err)
 
-		err = executeServiceCommand(ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, false)
+		err = executeServiceCommand(ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, "", false, nil)
 		require.NoError(t, err)
 
 		require.Equal(t, 0, obs.Len())
@@ -257,7 +405,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 		exePath, err := prepareTestProg(ctx, log, t.TempDir(), progConfig{})
 		require.NoError(t, err)
 
-		err = executeServiceCommand(ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, true)
+		err = executeServiceCommand(ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, "", true, nil)
 		require.NoError(t, err)
 
 		// Remove debug-level logs as those are only being emitted from
@@ -290,7 +438,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 		retryMaxInterval := 200 * time.Millisecond
 
 		executeServiceCommandWithRetries(
-			cmdCtx, log, exePath, &component.ServiceOperationsCommandSpec{},
+			cmdCtx, log, exePath, &component.ServiceOperationsCommandSpec{}, "", nil,
 			retryCtx, defaultRetryInitInterval, retryMaxInterval,
 		)
 
@@ -330,7 +478,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 			},
 		}
 		executeServiceCommandWithRetries(
-			cmdCtx, log, exePath, spec,
+			cmdCtx, log, exePath, spec, "", nil,
 			retryCtx, defaultRetryInitInterval, retryMaxInterval,
 		)
 
@@ -368,7 +516,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 			},
 		}
 		executeServiceCommandWithRetries(
-			cmdCtx, log, exePath, spec,
+			cmdCtx, log, exePath, spec, "", nil,
 			retryCtx, defaultRetryInitInterval, retryMaxInterval,
 		)
 
@@ -388,6 +536,61 @@ func TestExecuteServiceCommand(t *testing.T) {
 		checkRetryLogs(t, obs, exeConfig)
 	})
 
+	// Execution fails until the retries are exhausted, then the circuit breaker
+	// prevents the command from being executed again.
+	t.Run("retries_exhausted", func(t *testing.T) {
+		cmdCtx := context.Background()
+		log, obs := logger.NewTesting(t.Name())
+
+		exeConfig := progConfig{
+			ErrMessage: "foo bar",
+			ExitCode:   111,
+		}
+		exePath, err := prepareTestProg(cmdCtx, log, t.TempDir(), exeConfig)
+		require.NoError(t, err)
+
+		spec := &component.ServiceOperationsCommandSpec{
+			Retry: component.RetryConfig{
+				InitInterval:          50 * time.Millisecond,
+				MaxRetries:            2,
+				CircuitBreakerTimeout: time.Hour,
+			},
+		}
+		exhaustedCh := make(chan error, 1)
+		onRetriesExhausted := func(err error) {
+			exhaustedCh <- err
+		}
+
+		executeServiceCommandWithRetries(
+			cmdCtx, log, exePath, spec, "", onRetriesExhausted,
+			context.Background(), 50*time.Millisecond, 200*time.Millisecond,
+		)
+
+		select {
+		case err := <-exhaustedCh:
+			require.ErrorIs(t, err, ErrRetriesExhausted)
+			require.Contains(t, err.Error(), "after 2 retries: foo bar: exit status 111")
+		case <-time.After(5 * time.Second):
+			t.Fatal("retries were not exhausted")
+		}
+		require.Equal(t, 3, obs.FilterMessage(exeConfig.ErrMessage).Len())
+
+		// The circuit breaker is open, the command fails right away without being executed
+		executeServiceCommandWithRetries(
+			cmdCtx, log, exePath, spec, "", onRetriesExhausted,
+			context.Background(), 50*time.Millisecond, 200*time.Millisecond,
+		)
+
+		select {
+		case err := <-exhaustedCh:
+			require.ErrorIs(t, err, ErrRetriesExhausted)
+			require.Contains(t, err.Error(), "circuit breaker open")
+		case <-time.After(5 * time.Second):
+			t.Fatal("circuit breaker is not open")
+		}
+		require.Equal(t, 3, obs.FilterMessage(exeConfig.ErrMessage).Len())
+	})
+
 	// Ensure two calls to executeServiceCommandWithRetries cancels
 	// the previous command execution.
 	t.Run("previous_execution_cancels", func(t *testing.T) {
@@ -408,7 +611,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 		retry1Ctx := context.Background()
 
 		executeServiceCommandWithRetries(
-			cmd1Ctx, log, exePath, &component.ServiceOperationsCommandSpec{},
+			cmd1Ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, "", nil,
 			retry1Ctx, defaultRetryInitInterval, retryMaxInterval,
 		)
 
@@ -431,7 +634,7 @@ func TestExecuteServiceCommand(t *testing.T) {
 		defer cancel2()
 
 		executeServiceCommandWithRetries(
-			cmd2Ctx, log, exePath, &component.ServiceOperationsCommandSpec{},
+			cmd2Ctx, log, exePath, &component.ServiceOperationsCommandSpec{}, "", nil,
 			retry2Ctx, defaultRetryInitInterval, retryMaxInterval,
 		)
 
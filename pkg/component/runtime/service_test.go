@@ -0,0 +1,240 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/internal/pkg/core/clock"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestServiceRuntimeProcessesCheckinsDuringOperations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	comp := component.Component{
+		ID: "endpoint-default",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType:  "endpoint",
+			BinaryName: "endpoint-security",
+			BinaryPath: "/opt/endpoint-security",
+			Spec: component.InputSpec{
+				Name: "endpoint",
+				Service: &component.ServiceSpec{
+					Operations: component.ServiceOperationsSpec{
+						Check:     &component.ServiceOperationsCommandSpec{},
+						Install:   &component.ServiceOperationsCommandSpec{},
+						Uninstall: &component.ServiceOperationsCommandSpec{},
+					},
+				},
+			},
+		},
+	}
+
+	s, err := newServiceRuntime(comp, testutils.NewErrorLogger(t))
+	require.NoError(t, err)
+
+	checkStarted := make(chan struct{})
+	checkRelease := make(chan struct{})
+	s.executeServiceCommandImpl = func(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, outputPath string, shouldRetry bool, onRetriesExhausted func(error)) error {
+		if spec == comp.InputSpec.Spec.Service.Operations.Check {
+			// hung check command
+			close(checkStarted)
+			<-checkRelease
+		}
+		return nil
+	}
+
+	comm := newMockCommunicator()
+	go func() {
+		_ = s.Run(ctx, comm)
+	}()
+
+	awaitState := func(expected client.UnitState) {
+		t.Helper()
+		select {
+		case state := <-s.Watch():
+			require.Equal(t, expected, state.State, state.Message)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s state", expected)
+		}
+	}
+
+	require.NoError(t, s.Start())
+	awaitState(client.UnitStateStarting)
+	<-checkStarted
+
+	// the stop is deferred until the check is done, the check-in is processed right away
+	require.NoError(t, s.Stop())
+	comm.ch <- &proto.CheckinObserved{}
+	awaitState(client.UnitStateHealthy)
+
+	close(checkRelease)
+	awaitState(client.UnitStateStopped)
+}
+
+func TestServiceRuntimeMissedCheckins(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	comp := component.Component{
+		ID: "endpoint-default",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType:  "endpoint",
+			BinaryName: "endpoint-security",
+			BinaryPath: "/opt/endpoint-security",
+			Spec: component.InputSpec{
+				Name: "endpoint",
+				Service: &component.ServiceSpec{
+					Operations: component.ServiceOperationsSpec{
+						Check:     &component.ServiceOperationsCommandSpec{},
+						Install:   &component.ServiceOperationsCommandSpec{},
+						Uninstall: &component.ServiceOperationsCommandSpec{},
+					},
+				},
+			},
+		},
+	}
+
+	s, err := newServiceRuntime(comp, testutils.NewErrorLogger(t))
+	require.NoError(t, err)
+	clk := clock.NewMock(time.Now())
+	s.clock = clk
+	s.executeServiceCommandImpl = func(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, outputPath string, shouldRetry bool, onRetriesExhausted func(error)) error {
+		return nil
+	}
+
+	comm := newMockCommunicator()
+	go func() {
+		_ = s.Run(ctx, comm)
+	}()
+
+	awaitState := func(expected client.UnitState) string {
+		t.Helper()
+		select {
+		case state := <-s.Watch():
+			require.Equal(t, expected, state.State, state.Message)
+			return state.Message
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s state", expected)
+		}
+		return ""
+	}
+
+	require.NoError(t, s.Start())
+	awaitState(client.UnitStateStarting)
+	// the check-in timer starts once the service is installed
+	clk.BlockUntil(1)
+	comm.ch <- &proto.CheckinObserved{}
+	awaitState(client.UnitStateHealthy)
+
+	// checked in within the period
+	period := s.checkinPeriod()
+	clk.Advance(period)
+	clk.BlockUntil(1)
+
+	// each period without check-in is a missed check-in
+	for i := 1; i < maxCheckinMisses; i++ {
+		clk.Advance(period)
+		awaitState(client.UnitStateDegraded)
+		clk.BlockUntil(1)
+	}
+	clk.Advance(period)
+	msg := awaitState(client.UnitStateFailed)
+	require.Contains(t, msg, fmt.Sprintf("missed %d check-ins", maxCheckinMisses))
+}
+
+func TestServiceRuntimeStatusCommandInWorker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	comp := component.Component{
+		ID: "endpoint-default",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType:  "endpoint",
+			BinaryName: "endpoint-security",
+			BinaryPath: "/opt/endpoint-security",
+			Spec: component.InputSpec{
+				Name: "endpoint",
+				Service: &component.ServiceSpec{
+					Operations: component.ServiceOperationsSpec{
+						Check:     &component.ServiceOperationsCommandSpec{},
+						Install:   &component.ServiceOperationsCommandSpec{},
+						Uninstall: &component.ServiceOperationsCommandSpec{},
+						Status:    &component.ServiceOperationsCommandSpec{},
+					},
+				},
+			},
+		},
+	}
+
+	s, err := newServiceRuntime(comp, testutils.NewErrorLogger(t))
+	require.NoError(t, err)
+	clk := clock.NewMock(time.Now())
+	s.clock = clk
+	s.executeServiceCommandImpl = func(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec, outputPath string, shouldRetry bool, onRetriesExhausted func(error)) error {
+		return nil
+	}
+	statusStarted := make(chan struct{})
+	statusRelease := make(chan struct{})
+	s.executeServiceStatusCommandImpl = func(ctx context.Context, log *logger.Logger, binaryPath string, spec *component.ServiceOperationsCommandSpec) (string, error) {
+		// hung status command
+		close(statusStarted)
+		<-statusRelease
+		return `{"policy": "failed"}`, nil
+	}
+
+	comm := newMockCommunicator()
+	go func() {
+		_ = s.Run(ctx, comm)
+	}()
+
+	awaitState := func(expected client.UnitState) string {
+		t.Helper()
+		select {
+		case state := <-s.Watch():
+			require.Equal(t, expected, state.State, state.Message)
+			return state.Message
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s state", expected)
+		}
+		return ""
+	}
+
+	require.NoError(t, s.Start())
+	awaitState(client.UnitStateStarting)
+	clk.BlockUntil(1)
+	comm.ch <- &proto.CheckinObserved{}
+	awaitState(client.UnitStateHealthy)
+
+	period := s.checkinPeriod()
+	clk.Advance(period)
+	clk.BlockUntil(1)
+	clk.Advance(period)
+	msg := awaitState(client.UnitStateDegraded)
+	require.NotContains(t, msg, "service status", "the degraded state is reported before the status command is done")
+	<-statusStarted
+
+	// the check-ins are processed while the status command runs
+	select {
+	case comm.ch <- &proto.CheckinObserved{}:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the check-in is blocked by the status command")
+	}
+
+	close(statusRelease)
+	msg = awaitState(client.UnitStateDegraded)
+	require.Contains(t, msg, `service status: {"policy":"failed"}`)
+}
@@ -28,14 +28,67 @@ type ComponentUnitState struct {
 	Message string                 `yaml:"message"`
 	Payload map[string]interface{} `yaml:"payload,omitempty"`
 
+	// ExpectedConfigIdx is the index of the latest configuration sent to the unit.
+	ExpectedConfigIdx uint64 `yaml:"expected_config_idx,omitempty"`
+	// ObservedConfigIdx is the index of the configuration the unit reported as applied.
+	ObservedConfigIdx uint64 `yaml:"observed_config_idx,omitempty"`
+	// ExpectedPolicyRevision is the policy revision of the latest configuration sent to the unit.
+	ExpectedPolicyRevision int64 `yaml:"expected_policy_revision,omitempty"`
+	// ObservedPolicyRevision is the policy revision of the configuration the unit reported as applied.
+	ObservedPolicyRevision int64 `yaml:"observed_policy_revision,omitempty"`
+	// ConfigDriftKeys are the keys of the latest configuration sent to the unit that differ from
+	// the configuration the unit last applied, empty once the unit applied its latest configuration.
+	ConfigDriftKeys []string `yaml:"config_drift_keys,omitempty"`
+	// Events are the numbers of events of the data plane of the unit, collected by the agent from
+	// the component. Only set on the output unit of the components exposing them.
+	Events *UnitEvents `yaml:"events,omitempty"`
+
 	// internal
 	unitState      client.UnitState
 	unitMessage    string
 	unitPayload    map[string]interface{}
 	configStateIdx uint64
+	appliedConfig  *proto.UnitExpectedConfig
 	err            error
 }
 
+// UnitEvents are the numbers of events of the data plane of a unit.
+type UnitEvents struct {
+	// Published is the number of events acknowledged by the output.
+	Published uint64 `yaml:"published" json:"published"`
+	// Dropped is the number of events dropped by the pipeline or by the output.
+	Dropped uint64 `yaml:"dropped" json:"dropped"`
+	// Failed is the number of events that failed in the pipeline or in the output.
+	Failed uint64 `yaml:"failed" json:"failed"`
+	// Queued is the number of events waiting to be acknowledged by the output.
+	Queued uint64 `yaml:"queued" json:"queued"`
+	// Problem describes why the unit doesn't ship its events, empty while it does.
+	Problem string `yaml:"problem,omitempty" json:"problem,omitempty"`
+}
+
+// ReportedPayload returns the payload of the unit reported to the clients of the agent and to
+// Fleet, the events of the unit are added under the events key.
+func (s ComponentUnitState) ReportedPayload() map[string]interface{} {
+	if s.Events == nil {
+		return s.Payload
+	}
+	payload := make(map[string]interface{}, len(s.Payload)+1)
+	for k, v := range s.Payload {
+		payload[k] = v
+	}
+	events := map[string]interface{}{
+		"published": s.Events.Published,
+		"dropped":   s.Events.Dropped,
+		"failed":    s.Events.Failed,
+		"queued":    s.Events.Queued,
+	}
+	if s.Events.Problem != "" {
+		events["problem"] = s.Events.Problem
+	}
+	payload["events"] = events
+	return payload
+}
+
 // ComponentUnitKey is a composite key to identify a unit by its type and ID.
 type ComponentUnitKey struct {
 	UnitType client.UnitType
@@ -66,6 +119,9 @@ type ComponentState struct {
 
 	VersionInfo ComponentVersionInfo `yaml:"version_info"`
 
+	// Sandbox describes the sandbox applied to the running process, empty when it runs without one.
+	Sandbox string `yaml:"sandbox,omitempty"`
+
 	// internal
 	expectedUnits map[ComponentUnitKey]expectedUnitState
 
@@ -78,6 +134,7 @@ type expectedUnitState struct {
 	state          client.UnitState
 	configStateIdx uint64
 	config         *proto.UnitExpectedConfig
+	policyRevision int64
 	err            error
 	logLevel       client.UnitLogLevel
 }
@@ -141,6 +198,7 @@ func (s *ComponentState) syncExpected(comp *component.Component) bool {
 			if !gproto.Equal(existing.config, unit.Config) {
 				existing.config = unit.Config
 				existing.configStateIdx++
+				existing.policyRevision = policyRevision(unit.Config)
 				changed = true
 			}
 		} else {
@@ -148,6 +206,7 @@ func (s *ComponentState) syncExpected(comp *component.Component) bool {
 			existing.logLevel = unit.LogLevel
 			existing.config = unit.Config
 			existing.configStateIdx = 1
+			existing.policyRevision = policyRevision(unit.Config)
 			changed = true
 		}
 
@@ -242,6 +301,10 @@ func (s *ComponentState) syncUnits(comp *component.Component) bool {
 		changed = true
 	}
 
+	if s.syncConfigIdx() {
+		changed = true
+	}
+
 	return changed
 }
 
@@ -346,9 +409,69 @@ func (s *ComponentState) syncCheckin(checkin *proto.CheckinObserved) bool {
 		changed = true
 	}
 
+	if s.syncConfigIdx() {
+		changed = true
+	}
+
 	return changed
 }
 
+// syncConfigIdx updates the expected and observed configuration indexes and policy revisions
+// of the units, so it is visible when a unit didn't apply its latest configuration yet.
+func (s *ComponentState) syncConfigIdx() bool {
+	changed := false
+	for key, unit := range s.Units {
+		expected, ok := s.expectedUnits[key]
+		if !ok {
+			continue
+		}
+		observedRevision := unit.ObservedPolicyRevision
+		applied := unit.configStateIdx != 0 && unit.configStateIdx == expected.configStateIdx
+		if applied {
+			// the revision is only known once the unit applied the latest configuration,
+			// until then the revision of the previously applied configuration is kept
+			observedRevision = expected.policyRevision
+		}
+		if unit.ExpectedConfigIdx != expected.configStateIdx ||
+			unit.ObservedConfigIdx != unit.configStateIdx ||
+			unit.ExpectedPolicyRevision != expected.policyRevision ||
+			unit.ObservedPolicyRevision != observedRevision {
+			unit.ExpectedConfigIdx = expected.configStateIdx
+			unit.ObservedConfigIdx = unit.configStateIdx
+			unit.ExpectedPolicyRevision = expected.policyRevision
+			unit.ObservedPolicyRevision = observedRevision
+			if applied {
+				unit.appliedConfig = expected.config
+				unit.ConfigDriftKeys = nil
+			} else {
+				// the keys are only computed when the indexes change, not on every check-in
+				unit.ConfigDriftKeys = configDriftKeys(unit.appliedConfig, expected.config)
+			}
+			changed = true
+
+			// unit is a copy and must be set back into the map
+			s.Units[key] = unit
+		}
+	}
+	return changed
+}
+
+// observeApplied marks the latest configuration of the expected units as applied, it is used by the
+// runtimes of the components that don't check in.
+func (s *ComponentState) observeApplied() bool {
+	for key, unit := range s.Units {
+		expected, ok := s.expectedUnits[key]
+		if !ok || unit.configStateIdx == expected.configStateIdx {
+			continue
+		}
+		unit.configStateIdx = expected.configStateIdx
+
+		// unit is a copy and must be set back into the map
+		s.Units[key] = unit
+	}
+	return s.syncConfigIdx()
+}
+
 func (s *ComponentState) unsettled() bool {
 	if len(s.expectedUnits) != len(s.Units) {
 		// mismatch on unit count
@@ -483,6 +606,12 @@ func (s *ComponentState) compState(state client.UnitState, msg string) bool {
 	return false
 }
 
+// policyRevision returns the policy revision injected in the unit configuration, zero when not present.
+func policyRevision(cfg *proto.UnitExpectedConfig) int64 {
+	policy := cfg.GetSource().GetFields()["policy"].GetStructValue()
+	return int64(policy.GetFields()["revision"].GetNumberValue())
+}
+
 func diffPayload(existing map[string]interface{}, new map[string]interface{}) bool {
 	if existing == nil && new != nil {
 		return true
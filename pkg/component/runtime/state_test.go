@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestComponentStateConfigRevision(t *testing.T) {
+	unitConfig := func(revision int) *proto.UnitExpectedConfig {
+		return component.MustExpectedConfig(map[string]interface{}{
+			"type": "filestream",
+			"policy": map[string]interface{}{
+				"revision": revision,
+			},
+		})
+	}
+	comp := component.Component{
+		ID: "filestream-default",
+		Units: []component.Unit{
+			{
+				ID:     "filestream-default-filestream-0",
+				Type:   client.UnitTypeInput,
+				Config: unitConfig(5),
+			},
+		},
+	}
+	key := ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "filestream-default-filestream-0"}
+
+	state := newComponentState(&comp)
+	unit := state.Units[key]
+	assert.Equal(t, uint64(1), unit.ExpectedConfigIdx)
+	assert.Equal(t, uint64(0), unit.ObservedConfigIdx)
+	assert.Equal(t, int64(5), unit.ExpectedPolicyRevision)
+	assert.Equal(t, int64(0), unit.ObservedPolicyRevision)
+	assert.Equal(t, []string{"policy.revision", "type"}, unit.ConfigDriftKeys)
+
+	checkin := &proto.CheckinObserved{
+		Units: []*proto.UnitObserved{
+			{
+				Id:             key.UnitID,
+				Type:           proto.UnitType_INPUT,
+				State:          proto.State_HEALTHY,
+				ConfigStateIdx: 1,
+			},
+		},
+	}
+	require.True(t, state.syncCheckin(checkin))
+	unit = state.Units[key]
+	assert.Equal(t, uint64(1), unit.ObservedConfigIdx)
+	assert.Equal(t, int64(5), unit.ObservedPolicyRevision)
+	assert.Empty(t, unit.ConfigDriftKeys)
+
+	// policy changed, the unit didn't apply it yet
+	comp.Units[0].Config = unitConfig(6)
+	require.True(t, state.syncExpected(&comp))
+	require.True(t, state.syncUnits(&comp))
+	unit = state.Units[key]
+	assert.Equal(t, uint64(2), unit.ExpectedConfigIdx)
+	assert.Equal(t, uint64(1), unit.ObservedConfigIdx)
+	assert.Equal(t, int64(6), unit.ExpectedPolicyRevision)
+	assert.Equal(t, int64(5), unit.ObservedPolicyRevision)
+	assert.Equal(t, []string{"policy.revision"}, unit.ConfigDriftKeys)
+
+	checkin.Units[0].ConfigStateIdx = 2
+	require.True(t, state.syncCheckin(checkin))
+	unit = state.Units[key]
+	assert.Equal(t, uint64(2), unit.ObservedConfigIdx)
+	assert.Equal(t, int64(6), unit.ObservedPolicyRevision)
+	assert.Empty(t, unit.ConfigDriftKeys)
+}
@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+)
+
+// NewClientFromReader creates the client that connects to Elastic Agent with the connection
+// information read from reader.
+//
+// Unlike client.NewV2FromReader it also connects when Elastic Agent listens on a named pipe.
+func NewClientFromReader(reader io.Reader, info client.VersionInfo) (client.V2, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	connInfo := &proto.ConnInfo{}
+	err = protobuf.Unmarshal(data, connInfo)
+	if err != nil {
+		return nil, err
+	}
+	if connInfo.Services == nil {
+		return nil, client.ErrV2Unavailable
+	}
+	cert, err := tls.X509KeyPair(connInfo.PeerCert, connInfo.PeerKey)
+	if err != nil {
+		return nil, err
+	}
+	caCertPool := x509.NewCertPool()
+	caCertPool.AppendCertsFromPEM(connInfo.CaCert)
+	trans := credentials.NewTLS(&tls.Config{
+		ServerName:   connInfo.ServerName,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      caCertPool,
+		MinVersion:   tls.VersionTLS12,
+	})
+	target, opts := dialOptions(connInfo.Addr)
+	opts = append(opts, grpc.WithTransportCredentials(trans))
+	return client.NewV2(target, connInfo.Token, info, opts...), nil
+}
@@ -0,0 +1,218 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+)
+
+const (
+	configuringMsg = "Configuring"
+	healthyMsg     = "Healthy"
+	stoppingMsg    = "Stopping"
+	stoppedMsg     = "Stopped"
+)
+
+// Handler runs a single unit of a component.
+//
+// The methods are called one at a time from the run loop of the component, they must not block
+// and run the work of the unit on their own goroutines.
+type Handler interface {
+	// Configure applies the configuration of the unit. It is called when the unit is started and
+	// every time its configuration changes. The unit is reported healthy once it returns, unless
+	// the handler reported another state.
+	Configure(unit *Unit) error
+	// Stop stops the unit. It is called when the unit is expected to stop or it is removed.
+	Stop(unit *Unit) error
+}
+
+// HandlerFactory creates the Handler of a new unit.
+type HandlerFactory func(unit *Unit) (Handler, error)
+
+// Option configures a Component.
+type Option func(c *Component)
+
+// WithMeta adds meta information about the component reported to Elastic Agent.
+func WithMeta(meta map[string]string) Option {
+	return func(c *Component) {
+		c.info.Meta = meta
+	}
+}
+
+// WithErrorHandler sets the function called with the communication errors with Elastic Agent. The
+// client keeps retrying on errors, by default they are ignored.
+func WithErrorHandler(fn func(error)) Option {
+	return func(c *Component) {
+		c.onError = fn
+	}
+}
+
+// Component is an external component run by Elastic Agent.
+type Component struct {
+	info    client.VersionInfo
+	factory HandlerFactory
+	onError func(error)
+
+	units map[unitKey]*runningUnit
+}
+
+type unitKey struct {
+	unitType client.UnitType
+	unitID   string
+}
+
+type runningUnit struct {
+	unit    *Unit
+	handler Handler
+	running bool
+}
+
+// New creates a component reported to Elastic Agent with the name and version, its units are run
+// by the handlers the factory creates.
+func New(name string, version string, factory HandlerFactory, opts ...Option) *Component {
+	c := &Component{
+		info: client.VersionInfo{
+			Name:    name,
+			Version: version,
+		},
+		factory: factory,
+		onError: func(error) {},
+		units:   make(map[unitKey]*runningUnit),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run connects to Elastic Agent with the connection information it writes to stdin when it starts
+// the component, and runs the units until the context is cancelled or the component is signaled to
+// stop.
+func (c *Component) Run(ctx context.Context) error {
+	agentClient, err := NewClientFromReader(os.Stdin, c.info)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	return c.RunWithClient(ctx, agentClient)
+}
+
+// RunWithClient runs the units the client receives until the context is cancelled, the running
+// units are stopped before it returns.
+func (c *Component) RunWithClient(ctx context.Context, agentClient client.V2) error {
+	err := agentClient.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start client: %w", err)
+	}
+	defer stopClient(agentClient)
+
+	for {
+		select {
+		case <-ctx.Done():
+			for key, r := range c.units {
+				c.stop(r)
+				delete(c.units, key)
+			}
+			return nil
+		case change := <-agentClient.UnitChanges():
+			c.handle(change)
+		case err := <-agentClient.Errors():
+			if err != nil {
+				c.onError(err)
+			}
+		}
+	}
+}
+
+func (c *Component) handle(change client.UnitChanged) {
+	if change.Unit == nil {
+		return
+	}
+	key := unitKey{unitType: change.Unit.Type(), unitID: change.Unit.ID()}
+	switch change.Type {
+	case client.UnitChangedAdded, client.UnitChangedModified:
+		r, ok := c.units[key]
+		if !ok {
+			// a unit whose handler failed to be created is retried on its next change
+			unit := &Unit{unit: change.Unit}
+			handler, err := c.factory(unit)
+			if err != nil {
+				unit.Failed(fmt.Sprintf("Failed to create unit: %s", err))
+				return
+			}
+			r = &runningUnit{unit: unit, handler: handler}
+			c.units[key] = r
+		} else if change.Triggers&(client.TriggeredConfigChange|client.TriggeredStateChange) == 0 {
+			// only the log level or the features changed
+			return
+		}
+		c.apply(r)
+	case client.UnitChangedRemoved:
+		r, ok := c.units[key]
+		if !ok {
+			return
+		}
+		c.stop(r)
+		delete(c.units, key)
+	}
+}
+
+// apply moves the unit to its expected state.
+func (c *Component) apply(r *runningUnit) {
+	if r.unit.unit.Expected().State == client.UnitStateStopped {
+		c.stop(r)
+		return
+	}
+
+	r.unit.setState(client.UnitStateConfiguring, configuringMsg)
+	err := r.handler.Configure(r.unit)
+	if err != nil {
+		r.unit.Failed(fmt.Sprintf("Failed to configure: %s", err))
+		return
+	}
+	r.running = true
+	if state, _, _ := r.unit.unit.State(); state == client.UnitStateConfiguring {
+		r.unit.Healthy(healthyMsg)
+	}
+}
+
+// stop stops the unit when it's running and reports it stopped.
+func (c *Component) stop(r *runningUnit) {
+	if r.running {
+		r.unit.setState(client.UnitStateStopping, stoppingMsg)
+		err := r.handler.Stop(r.unit)
+		if err != nil {
+			r.unit.Failed(fmt.Sprintf("Failed to stop: %s", err))
+			return
+		}
+		r.running = false
+	}
+	r.unit.setState(client.UnitStateStopped, stoppedMsg)
+}
+
+// stopClient stops the client, the errors of the connections it closes are drained so it doesn't
+// block waiting for them to be read.
+func stopClient(agentClient client.V2) {
+	stopped := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stopped:
+				return
+			case <-agentClient.Errors():
+			}
+		}
+	}()
+	agentClient.Stop()
+	close(stopped)
+}
@@ -0,0 +1,169 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/client/mock"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+type testConfig struct {
+	Name     string `config:"name" validate:"required"`
+	Password Secret `config:"password"`
+}
+
+type testHandler struct {
+	mx         sync.Mutex
+	configured []testConfig
+	stopped    int
+	failWith   error
+}
+
+func (h *testHandler) Configure(unit *Unit) error {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if h.failWith != nil {
+		return h.failWith
+	}
+	var cfg testConfig
+	if err := unit.DecodeConfig(&cfg); err != nil {
+		return err
+	}
+	h.configured = append(h.configured, cfg)
+	return nil
+}
+
+func (h *testHandler) Stop(*Unit) error {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	h.stopped++
+	return nil
+}
+
+func TestComponent(t *testing.T) {
+	unitConfig := func(name string) *proto.UnitExpectedConfig {
+		return component.MustExpectedConfig(map[string]interface{}{
+			"type":     "custom",
+			"name":     name,
+			"password": "changeme",
+		})
+	}
+
+	var mx sync.Mutex
+	expected := &proto.UnitExpected{
+		Id:             "custom-default-custom-0",
+		Type:           proto.UnitType_INPUT,
+		State:          proto.State_HEALTHY,
+		ConfigStateIdx: 1,
+		Config:         unitConfig("first"),
+	}
+	var observed *proto.UnitObserved
+	// the client only checks in when the state of its units changes, or every 25s, so the
+	// response is held while the unit is idle until the expected unit changes
+	changed := make(chan struct{}, 1)
+	setExpected := func(state proto.State, configIdx uint64, config *proto.UnitExpectedConfig) {
+		mx.Lock()
+		defer mx.Unlock()
+		expected = &proto.UnitExpected{Id: expected.Id, Type: expected.Type, State: state, ConfigStateIdx: configIdx, Config: config}
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	srv := mock.StubServerV2{
+		CheckinV2Impl: func(o *proto.CheckinObserved) *proto.CheckinExpected {
+			mx.Lock()
+			for _, unit := range o.Units {
+				if unit.Id == expected.Id {
+					observed = unit
+				}
+			}
+			idle := observed != nil && observed.ConfigStateIdx == expected.ConfigStateIdx &&
+				observed.State != proto.State_CONFIGURING && observed.State != proto.State_STOPPING
+			mx.Unlock()
+			if idle {
+				select {
+				case <-changed:
+				case <-time.After(time.Second):
+				}
+			}
+			mx.Lock()
+			defer mx.Unlock()
+			return &proto.CheckinExpected{Units: []*proto.UnitExpected{expected}}
+		},
+		ActionImpl: func(response *proto.ActionResponse) error {
+			return nil
+		},
+		ActionsChan: make(chan *mock.PerformAction, 100),
+	}
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	waitObserved := func(state proto.State, configIdx uint64) {
+		t.Helper()
+		assert.Eventually(t, func() bool {
+			mx.Lock()
+			defer mx.Unlock()
+			return observed != nil && observed.State == state && observed.ConfigStateIdx == configIdx
+		}, 10*time.Second, 10*time.Millisecond)
+	}
+
+	handler := &testHandler{}
+	c := New("custom", "1.0.0", func(unit *Unit) (Handler, error) {
+		return handler, nil
+	})
+	agentClient := client.NewV2(fmt.Sprintf(":%d", srv.Port), mock.NewID(), client.VersionInfo{}, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- c.RunWithClient(ctx, agentClient)
+	}()
+
+	waitObserved(proto.State_HEALTHY, 1)
+
+	// configuration change
+	setExpected(proto.State_HEALTHY, 2, unitConfig("second"))
+	waitObserved(proto.State_HEALTHY, 2)
+
+	// failure to configure
+	handler.mx.Lock()
+	handler.failWith = errors.New("invalid")
+	handler.mx.Unlock()
+	setExpected(proto.State_HEALTHY, 3, unitConfig("third"))
+	waitObserved(proto.State_FAILED, 3)
+	mx.Lock()
+	assert.Equal(t, "Failed to configure: invalid", observed.Message)
+	mx.Unlock()
+
+	// stopped
+	setExpected(proto.State_STOPPED, 3, unitConfig("third"))
+	waitObserved(proto.State_STOPPED, 3)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	handler.mx.Lock()
+	defer handler.mx.Unlock()
+	require.Len(t, handler.configured, 2)
+	assert.Equal(t, "first", handler.configured[0].Name)
+	assert.Equal(t, "second", handler.configured[1].Name)
+	assert.Equal(t, "changeme", handler.configured[1].Password.Value())
+	assert.Equal(t, 1, handler.stopped)
+}
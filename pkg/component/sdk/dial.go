@@ -0,0 +1,14 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+
+package sdk
+
+import "google.golang.org/grpc"
+
+// dialOptions returns the target and the options to dial addr, GRPC dials unix:// addresses itself.
+func dialOptions(addr string) (string, []grpc.DialOption) {
+	return addr, nil
+}
@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package sdk
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/elastic/elastic-agent-libs/api/npipe"
+)
+
+// dialOptions returns the target and the options to dial addr, a npipe:// address is dialed over
+// the named pipe.
+func dialOptions(addr string) (string, []grpc.DialOption) {
+	if !strings.HasPrefix(addr, "npipe://") {
+		return addr, nil
+	}
+	pipe := npipe.TransformString(addr)
+	return pipe, []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return npipe.DialContext(pipe)(ctx, "", "")
+	})}
+}
@@ -0,0 +1,35 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package sdk is the supported way to write an external component that runs under Elastic Agent.
+//
+// It wraps the elastic-agent-client with the conventions the components shipped with Elastic Agent
+// follow: each unit is run by its own Handler, the unit state is reported as the unit is configured
+// and stopped, the unit configuration is decoded with the same `config` struct tags Elastic Agent
+// uses, and Secret values are kept out of logs and diagnostics.
+//
+// The exported API of this package follows semantic versioning with Elastic Agent, breaking changes
+// only happen in a major release.
+//
+// A component is a binary placed next to its specification file in the components directory of
+// Elastic Agent. The specification file can be written with WriteSpec:
+//
+//	func main() {
+//		if len(os.Args) > 1 && os.Args[1] == "spec" {
+//			_ = sdk.WriteSpec(os.Stdout, component.InputSpec{
+//				Name:        "my-input",
+//				Description: "My custom input",
+//				Platforms:   []string{"linux/amd64", "linux/arm64"},
+//				Outputs:     []string{"elasticsearch"},
+//				Command:     &component.CommandSpec{},
+//			})
+//			return
+//		}
+//		c := sdk.New("my-input", "1.0.0", newHandler)
+//		if err := c.Run(context.Background()); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+package sdk
@@ -0,0 +1,47 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import "encoding/json"
+
+const redacted = "[REDACTED]"
+
+// Secret is a configuration value that is redacted when it's formatted or serialized, so it's
+// kept out of logs and diagnostics. Use Value to get the actual value.
+type Secret string
+
+// Value returns the actual value of the secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String returns the redacted secret.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString returns the redacted secret.
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// MarshalJSON marshals the redacted secret.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalYAML marshals the redacted secret.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// Unpack unpacks the secret from the configuration of the unit.
+func (s *Secret) Unpack(value string) error {
+	*s = Secret(value)
+	return nil
+}
@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecret(t *testing.T) {
+	cfg := struct {
+		Username string `json:"username"`
+		Password Secret `json:"password"`
+	}{
+		Username: "elastic",
+		Password: "changeme",
+	}
+
+	assert.Equal(t, "changeme", cfg.Password.Value())
+	assert.NotContains(t, fmt.Sprintf("%s %v %+v %#v", cfg.Password, cfg, cfg, cfg), "changeme")
+
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"username":"elastic","password":"[REDACTED]"}`, string(data))
+
+	assert.Equal(t, "", Secret("").String())
+}
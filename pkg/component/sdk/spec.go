@@ -0,0 +1,44 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// WriteSpec writes the specification file of a component that implements the inputs. The
+// specification is validated the same way Elastic Agent validates it when it loads it.
+func WriteSpec(w io.Writer, inputs ...component.InputSpec) error {
+	spec := component.Spec{
+		Version: 2,
+		Inputs:  inputs,
+	}
+	// converted with the `config` struct tags, so durations are written in a form Elastic Agent reads back
+	cfg, err := config.NewConfigFrom(spec)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	err = cfg.Unpack(&fields)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	_, err = component.LoadSpec(data)
+	if err != nil {
+		return fmt.Errorf("invalid component specification: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
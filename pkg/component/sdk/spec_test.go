@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestWriteSpec(t *testing.T) {
+	input := component.InputSpec{
+		Name:        "custom",
+		Description: "Custom input",
+		Platforms:   []string{"linux/amd64", "windows/amd64"},
+		Outputs:     []string{"elasticsearch"},
+		Command: &component.CommandSpec{
+			Args: []string{"run"},
+			Timeouts: component.CommandTimeoutSpec{
+				Checkin: time.Minute,
+				Restart: 10 * time.Second,
+				Stop:    30 * time.Second,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSpec(&buf, input))
+
+	spec, err := component.LoadSpec(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, spec.Inputs, 1)
+	assert.Equal(t, 2, spec.Version)
+	assert.Equal(t, input.Name, spec.Inputs[0].Name)
+	assert.Equal(t, input.Platforms, spec.Inputs[0].Platforms)
+	require.NotNil(t, spec.Inputs[0].Command)
+	assert.Equal(t, input.Command.Args, spec.Inputs[0].Command.Args)
+	assert.Equal(t, time.Minute, spec.Inputs[0].Command.Timeouts.Checkin)
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := input
+		invalid.Platforms = []string{"unknown/amd64"}
+		buf.Reset()
+		assert.Error(t, WriteSpec(&buf, invalid))
+		assert.Zero(t, buf.Len())
+	})
+}
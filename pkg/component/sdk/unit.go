@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sdk
+
+import (
+	"errors"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+// Unit is a unit of a component, either an input or the output the inputs send their events to.
+type Unit struct {
+	unit *client.Unit
+}
+
+// ID returns the ID of the unit.
+func (u *Unit) ID() string {
+	return u.unit.ID()
+}
+
+// Type returns the type of the unit.
+func (u *Unit) Type() client.UnitType {
+	return u.unit.Type()
+}
+
+// Config returns the current expected configuration of the unit.
+func (u *Unit) Config() *proto.UnitExpectedConfig {
+	return u.unit.Expected().Config
+}
+
+// LogLevel returns the current expected log level of the unit.
+func (u *Unit) LogLevel() client.UnitLogLevel {
+	return u.unit.Expected().LogLevel
+}
+
+// DecodeConfig decodes the current expected configuration of the unit into to, with the `config`
+// struct tags, the defaults and the validation Elastic Agent uses for its own configuration.
+func (u *Unit) DecodeConfig(to interface{}) error {
+	source := u.Config().GetSource()
+	if source == nil {
+		return errors.New("unit has no configuration")
+	}
+	cfg, err := config.NewConfigFrom(source.AsMap())
+	if err != nil {
+		return err
+	}
+	return cfg.Unpack(to)
+}
+
+// Healthy reports the unit as healthy.
+func (u *Unit) Healthy(message string) {
+	u.setState(client.UnitStateHealthy, message)
+}
+
+// Degraded reports the unit as degraded, it still runs but not as expected.
+func (u *Unit) Degraded(message string) {
+	u.setState(client.UnitStateDegraded, message)
+}
+
+// Failed reports the unit as failed.
+func (u *Unit) Failed(message string) {
+	u.setState(client.UnitStateFailed, message)
+}
+
+// Client returns the unit of the elastic-agent-client, to access the features this package
+// doesn't wrap.
+func (u *Unit) Client() *client.Unit {
+	return u.unit
+}
+
+func (u *Unit) setState(state client.UnitState, message string) {
+	// only fails when the unit was removed, then the state is not reported anymore
+	_ = u.unit.UpdateState(state, message, nil)
+}
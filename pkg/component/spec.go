@@ -7,6 +7,7 @@ package component
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"time"
 )
 
@@ -80,6 +81,114 @@ type CommandSpec struct {
 	Log                     CommandLogSpec     `config:"log,omitempty" yaml:"log,omitempty"`
 	RestartMonitoringPeriod time.Duration      `config:"restart_monitoring_period,omitempty" yaml:"restart_monitoring_period,omitempty"`
 	MaxRestartsPerPeriod    int                `config:"maximum_restarts_per_period,omitempty" yaml:"maximum_restarts_per_period,omitempty"`
+	// Instrumentation is set when the command reads the APM instrumentation from its output unit.
+	Instrumentation bool `config:"instrumentation,omitempty" yaml:"instrumentation,omitempty"`
+	// Requires are the resources checked before the command is started.
+	Requires CommandRequiresSpec `config:"requires,omitempty" yaml:"requires,omitempty"`
+	// Sandbox is applied to the command when the sandboxing of the components is enabled.
+	Sandbox CommandSandboxSpec `config:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+}
+
+// CommandRequiresSpec is the specification of the resources a subprocess needs, they are checked
+// before it is started so a conflict is reported instead of a crash of the subprocess.
+type CommandRequiresSpec struct {
+	// Ports are the TCP ports the subprocess listens on.
+	Ports []int `config:"ports,omitempty" yaml:"ports,omitempty"`
+	// LockFiles are the files the subprocess locks, relative to its data path.
+	LockFiles []string `config:"lock_files,omitempty" yaml:"lock_files,omitempty"`
+	// KernelModules are the Linux kernel modules the subprocess needs, ignored on other platforms.
+	KernelModules []string `config:"kernel_modules,omitempty" yaml:"kernel_modules,omitempty"`
+}
+
+// Validate ensures correctness of the required resources.
+func (r *CommandRequiresSpec) Validate() error {
+	for i, port := range r.Ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("port %d at ports.%d is not between 1 and 65535", port, i)
+		}
+	}
+	for i, lockFile := range r.LockFiles {
+		if lockFile == "" || filepath.IsAbs(lockFile) {
+			return fmt.Errorf("lock file '%s' at lock_files.%d must be a path relative to the data path", lockFile, i)
+		}
+	}
+	return nil
+}
+
+// Actions of a seccomp rule.
+const (
+	SeccompActionAllow       = "allow"
+	SeccompActionErrno       = "errno"
+	SeccompActionKillProcess = "kill_process"
+	SeccompActionLog         = "log"
+)
+
+// CommandSandboxSpec is the specification of the sandbox of a subprocess, it is only applied when
+// the sandboxing of the components is enabled with agent.sandbox.enabled. Each field applies to a
+// single platform and is ignored on the others.
+type CommandSandboxSpec struct {
+	// UserNamespace runs the subprocess in a new Linux user namespace.
+	UserNamespace bool `config:"user_namespace,omitempty" yaml:"user_namespace,omitempty"`
+	// Seccomp is the Linux seccomp profile of the subprocess.
+	Seccomp *CommandSeccompSpec `config:"seccomp,omitempty" yaml:"seccomp,omitempty"`
+	// Profile is the macOS sandbox-exec profile of the subprocess, relative to the directory of its binary.
+	Profile string `config:"profile,omitempty" yaml:"profile,omitempty"`
+	// RestrictedToken runs the Windows subprocess with a token without the privileges of the Elastic Agent.
+	RestrictedToken bool `config:"restricted_token,omitempty" yaml:"restricted_token,omitempty"`
+}
+
+// Validate ensures correctness of the sandbox.
+func (s *CommandSandboxSpec) Validate() error {
+	if s.Profile != "" && filepath.IsAbs(s.Profile) {
+		return fmt.Errorf("profile '%s' must be a path relative to the directory of the binary", s.Profile)
+	}
+	return nil
+}
+
+// CommandSeccompSpec is a seccomp profile, a system call takes the action of the first rule
+// naming it and the default action when no rule names it.
+//
+// The rules can only name the system calls giving access to the host the components should not
+// need, the profile denies them. The default action must be allow, any other would also block
+// the execution of the component and the system calls of its runtime.
+type CommandSeccompSpec struct {
+	DefaultAction string                   `config:"default_action,omitempty" yaml:"default_action,omitempty"`
+	Syscalls      []CommandSeccompRuleSpec `config:"syscalls" yaml:"syscalls"`
+}
+
+// InitDefaults initializes the defaults for the seccomp profile.
+func (s *CommandSeccompSpec) InitDefaults() {
+	s.DefaultAction = SeccompActionAllow
+}
+
+// Validate ensures correctness of the seccomp profile.
+func (s *CommandSeccompSpec) Validate() error {
+	if s.DefaultAction != SeccompActionAllow {
+		return fmt.Errorf("default_action '%s' must be '%s', the rules only deny system calls", s.DefaultAction, SeccompActionAllow)
+	}
+	for i, rule := range s.Syscalls {
+		if len(rule.Names) == 0 {
+			return fmt.Errorf("syscalls.%d must name at least one system call", i)
+		}
+		if !validSeccompAction(rule.Action) {
+			return fmt.Errorf("unknown action '%s' at syscalls.%d", rule.Action, i)
+		}
+	}
+	return nil
+}
+
+// CommandSeccompRuleSpec is a rule of a seccomp profile.
+type CommandSeccompRuleSpec struct {
+	Names  []string `config:"names" yaml:"names"`
+	Action string   `config:"action" yaml:"action"`
+}
+
+func validSeccompAction(action string) bool {
+	switch action {
+	case SeccompActionAllow, SeccompActionErrno, SeccompActionKillProcess, SeccompActionLog:
+		return true
+	}
+	return false
 }
 
 // CommandEnvSpec is the specification that defines environment variables that will be set to execute the subprocess.
@@ -137,6 +246,22 @@ type ServiceSpec struct {
 	Timeouts   ServiceTimeoutSpec    `config:"timeouts,omitempty" yaml:"timeouts,omitempty"`
 }
 
+// BuiltinSpec is the specification for an input that runs in-process in Elastic Agent, its
+// implementation is registered with Elastic Agent instead of being a binary in the components directory.
+type BuiltinSpec struct {
+	Timeouts CommandTimeoutSpec `config:"timeouts" yaml:"timeouts"`
+}
+
+// OTelSpec is the specification for an OpenTelemetry Collector run as a subprocess, it runs the
+// pipelines of the receivers, processors and exporters sections of the policy. The collector does not
+// communicate with Elastic Agent, its health is checked with the health_check extension.
+type OTelSpec struct {
+	Args     []string           `config:"args,omitempty" yaml:"args,omitempty"`
+	Env      []CommandEnvSpec   `config:"env,omitempty" yaml:"env,omitempty"`
+	Timeouts CommandTimeoutSpec `config:"timeouts,omitempty" yaml:"timeouts,omitempty"`
+	Log      CommandLogSpec     `config:"log,omitempty" yaml:"log,omitempty"`
+}
+
 // ServiceLogSpec is the specification for the log path that the service logs to.
 type ServiceLogSpec struct {
 	Path string `config:"path,omitempty" yaml:"path,omitempty"`
@@ -147,9 +272,10 @@ type ServiceOperationsSpec struct {
 	Check     *ServiceOperationsCommandSpec `config:"check,omitempty" yaml:"check,omitempty"`
 	Install   *ServiceOperationsCommandSpec `config:"install" yaml:"install" validate:"required"`
 	Uninstall *ServiceOperationsCommandSpec `config:"uninstall" yaml:"uninstall" validate:"required"`
+	Status    *ServiceOperationsCommandSpec `config:"status,omitempty" yaml:"status,omitempty"`
 }
 
-// ServiceOperationsCommandSpec is the specification for execution of binaries to perform the check, install, and uninstall.
+// ServiceOperationsCommandSpec is the specification for execution of binaries to perform the check, install, uninstall and status.
 type ServiceOperationsCommandSpec struct {
 	Args    []string         `config:"args,omitempty" yaml:"args,omitempty"`
 	Env     []CommandEnvSpec `config:"env,omitempty" yaml:"env,omitempty"`
@@ -157,6 +283,75 @@ type ServiceOperationsCommandSpec struct {
 	Retry   RetryConfig
 }
 
+// RetryConfig is the configuration for retrying a failed service operation.
 type RetryConfig struct {
 	InitInterval time.Duration `config:"init_interval,omitempty" yaml:"init_interval,omitempty"`
+	MaxInterval  time.Duration `config:"max_interval,omitempty" yaml:"max_interval,omitempty"`
+	// MaxRetries is the number of retries before giving up, 0 retries indefinitely.
+	MaxRetries int `config:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+	// CircuitBreakerTimeout is how long the operation is not executed again once the retries
+	// are exhausted, 0 disables the circuit breaker.
+	CircuitBreakerTimeout time.Duration `config:"circuit_breaker_timeout,omitempty" yaml:"circuit_breaker_timeout,omitempty"`
+}
+
+// UninstallHookSpec is the specification of a command run when Elastic Agent is uninstalled, to remove
+// the artifacts the component leaves on the host like kernel modules, drivers, firewall rules or scheduled
+// tasks. The binary of the component is run with args, whether the component is running or not, so the
+// hook must succeed when there is nothing to remove.
+type UninstallHookSpec struct {
+	Name string           `config:"name" yaml:"name" validate:"required"`
+	Args []string         `config:"args" yaml:"args" validate:"required"`
+	Env  []CommandEnvSpec `config:"env,omitempty" yaml:"env,omitempty"`
+	// Timeout is how long the hook runs before it is killed.
+	Timeout time.Duration `config:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// InitDefaults initializes the defaults for the uninstall hook.
+func (s *UninstallHookSpec) InitDefaults() {
+	s.Timeout = time.Minute
+}
+
+// DiagnosticProviderSpec is the specification of a diagnostic provider of a component, its output
+// is added to the diagnostics bundle of the Elastic Agent next to the diagnostics of the units of
+// the component. A provider either runs the binary of the component with args or reads the file at
+// path.
+type DiagnosticProviderSpec struct {
+	Name        string `config:"name" yaml:"name" validate:"required"`
+	Filename    string `config:"filename" yaml:"filename" validate:"required"`
+	Description string `config:"description,omitempty" yaml:"description,omitempty"`
+	ContentType string `config:"content_type,omitempty" yaml:"content_type,omitempty"`
+	// Args are the arguments the binary of the component is run with, its standard output is the
+	// output of the provider.
+	Args []string         `config:"args,omitempty" yaml:"args,omitempty"`
+	Env  []CommandEnvSpec `config:"env,omitempty" yaml:"env,omitempty"`
+	// Path is the file read by the provider, relative to the data path of the component or absolute.
+	Path string `config:"path,omitempty" yaml:"path,omitempty"`
+	// Timeout is how long the provider runs before its output is cut.
+	Timeout time.Duration `config:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// MaxSize is the size of the output of the provider, in bytes, beyond which it is truncated.
+	MaxSize int64 `config:"max_size,omitempty" yaml:"max_size,omitempty"`
+}
+
+// InitDefaults initializes the defaults for the diagnostic provider.
+func (s *DiagnosticProviderSpec) InitDefaults() {
+	s.ContentType = "text/plain"
+	s.Timeout = 30 * time.Second
+	s.MaxSize = 10 * 1024 * 1024
+}
+
+// Validate ensures correctness of the diagnostic provider.
+func (s *DiagnosticProviderSpec) Validate() error {
+	if (len(s.Args) == 0) == (s.Path == "") {
+		return fmt.Errorf("diagnostic provider '%s' must define either args or path", s.Name)
+	}
+	if s.Filename != filepath.Base(s.Filename) || s.Filename == "." || s.Filename == ".." {
+		return fmt.Errorf("diagnostic provider '%s' filename '%s' must be a file name without a directory", s.Name, s.Filename)
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("diagnostic provider '%s' timeout must be positive", s.Name)
+	}
+	if s.MaxSize <= 0 {
+		return fmt.Errorf("diagnostic provider '%s' max_size must be positive", s.Name)
+	}
+	return nil
 }
@@ -0,0 +1,266 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package component
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintSeverity is the severity of an issue found in a component specification.
+type LintSeverity int
+
+const (
+	// LintWarning is a mistake that doesn't prevent the Elastic Agent from loading the specification.
+	LintWarning LintSeverity = iota
+	// LintError is a mistake that prevents the Elastic Agent from loading or running the specification.
+	LintError
+)
+
+// String returns the string representation of the severity.
+func (s LintSeverity) String() string {
+	if s == LintError {
+		return "error"
+	}
+	return "warning"
+}
+
+// LintIssue is an issue found in a component specification.
+type LintIssue struct {
+	Severity LintSeverity
+	// Path is the path of the setting with the issue, empty when it's about the whole specification.
+	Path    string
+	Message string
+}
+
+// String returns the string representation of the issue.
+func (i LintIssue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Path, i.Message)
+}
+
+// LintIssues are the issues found in a component specification.
+type LintIssues []LintIssue
+
+// HasErrors returns true when one of the issues is an error.
+func (l LintIssues) HasErrors() bool {
+	for _, i := range l {
+		if i.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintSpec checks the component specification for the mistakes the Elastic Agent reports when it
+// loads it, and for the ones it only hits when it runs the component.
+func LintSpec(data []byte) LintIssues {
+	spec, err := LoadSpec(data)
+	if err != nil {
+		return LintIssues{{Severity: LintError, Message: err.Error()}}
+	}
+
+	var issues LintIssues
+	cports := make(map[int]string)
+	shippers := make(map[string]bool)
+	for _, shipper := range spec.Shippers {
+		shippers[shipper.Name] = true
+	}
+	var inputNames []string
+	inputPlatforms := make(map[string][]string)
+	for i, input := range spec.Inputs {
+		path := fmt.Sprintf("inputs.%d", i)
+		issues = append(issues, lintPlatforms(path, input.Platforms)...)
+		if _, ok := inputPlatforms[input.Name]; !ok {
+			inputNames = append(inputNames, input.Name)
+		}
+		inputPlatforms[input.Name] = append(inputPlatforms[input.Name], input.Platforms...)
+		for j, shipper := range input.Shippers {
+			if !shippers[shipper] {
+				issues = append(issues, LintIssue{
+					Severity: LintWarning,
+					Path:     fmt.Sprintf("%s.shippers.%d", path, j),
+					Message:  fmt.Sprintf("shipper '%s' is not defined in this specification, it must be defined by another one", shipper),
+				})
+			}
+		}
+		if input.Command != nil {
+			issues = append(issues, lintArgs(path+".command.args", input.Command.Args)...)
+			if input.Service != nil {
+				issues = append(issues, LintIssue{
+					Severity: LintWarning,
+					Path:     path + ".service",
+					Message:  "input defines both command and service, the service is ignored",
+				})
+			}
+			continue
+		}
+		if input.Builtin != nil {
+			issues = append(issues, LintIssue{
+				Severity: LintWarning,
+				Path:     path + ".builtin",
+				Message:  "builtin inputs are registered by Elastic Agent, the input fails to run unless Elastic Agent implements it",
+			})
+			continue
+		}
+		if input.OTel != nil {
+			issues = append(issues, lintArgs(path+".otel.args", input.OTel.Args)...)
+			if input.Name != OTelInputType {
+				issues = append(issues, LintIssue{
+					Severity: LintWarning,
+					Path:     path + ".otel",
+					Message:  fmt.Sprintf("the OpenTelemetry Collector pipelines of the policy only run with the input '%s'", OTelInputType),
+				})
+			}
+			continue
+		}
+
+		service := input.Service
+		if service.CPort < 1 || service.CPort > 65535 {
+			issues = append(issues, LintIssue{
+				Severity: LintError,
+				Path:     path + ".service.cport",
+				Message:  fmt.Sprintf("port %d is out of the range 1-65535", service.CPort),
+			})
+		} else if other, ok := cports[service.CPort]; ok && other != input.Name {
+			issues = append(issues, LintIssue{
+				Severity: LintError,
+				Path:     path + ".service.cport",
+				Message:  fmt.Sprintf("port %d is already used by input '%s'", service.CPort, other),
+			})
+		} else {
+			cports[service.CPort] = input.Name
+		}
+		if service.Operations.Check == nil {
+			issues = append(issues, LintIssue{
+				Severity: LintWarning,
+				Path:     path + ".service.operations.check",
+				Message:  "service doesn't define a check operation, it's installed every time it's started",
+			})
+		}
+		operations := map[string]*ServiceOperationsCommandSpec{
+			"check":     service.Operations.Check,
+			"install":   service.Operations.Install,
+			"uninstall": service.Operations.Uninstall,
+			"status":    service.Operations.Status,
+		}
+		for _, name := range []string{"check", "install", "uninstall", "status"} {
+			if operations[name] == nil {
+				continue
+			}
+			opPath := fmt.Sprintf("%s.service.operations.%s", path, name)
+			issues = append(issues, lintArgs(opPath+".args", operations[name].Args)...)
+			if name != "status" && len(operations[name].Args) == 0 {
+				issues = append(issues, LintIssue{
+					Severity: LintWarning,
+					Path:     opPath + ".args",
+					Message:  fmt.Sprintf("%s operation runs the binary without arguments", name),
+				})
+			}
+		}
+	}
+	var shipperNames []string
+	shipperPlatforms := make(map[string][]string)
+	for i, shipper := range spec.Shippers {
+		path := fmt.Sprintf("shippers.%d", i)
+		issues = append(issues, lintPlatforms(path, shipper.Platforms)...)
+		if _, ok := shipperPlatforms[shipper.Name]; !ok {
+			shipperNames = append(shipperNames, shipper.Name)
+		}
+		shipperPlatforms[shipper.Name] = append(shipperPlatforms[shipper.Name], shipper.Platforms...)
+		if shipper.Command != nil {
+			issues = append(issues, lintArgs(path+".command.args", shipper.Command.Args)...)
+		}
+	}
+	issues = append(issues, lintPlatformMatrix("inputs", inputNames, inputPlatforms)...)
+	issues = append(issues, lintPlatformMatrix("shippers", shipperNames, shipperPlatforms)...)
+	return issues
+}
+
+// lintPlatforms checks the platforms are known.
+func lintPlatforms(path string, platforms []string) LintIssues {
+	var issues LintIssues
+	for i, platform := range platforms {
+		if !GlobalPlatforms.Exists(platform) {
+			issues = append(issues, LintIssue{
+				Severity: LintError,
+				Path:     fmt.Sprintf("%s.platforms.%d", path, i),
+				Message:  fmt.Sprintf("unknown platform '%s'", platform),
+			})
+		}
+	}
+	return issues
+}
+
+// lintPlatformMatrix checks that every architecture of an operating system a type supports is
+// supported. A type can be defined more than once, each definition for different platforms. The
+// embedded devices are opt-in, a type doesn't have to support them.
+func lintPlatformMatrix(kind string, names []string, platforms map[string][]string) LintIssues {
+	var issues LintIssues
+	for _, name := range names {
+		defined := make(map[string]bool)
+		for _, platform := range platforms[name] {
+			defined[platform] = true
+		}
+		for _, p := range GlobalPlatforms {
+			if defined[p.String()] || p.Edge() {
+				continue
+			}
+			for _, other := range platforms[name] {
+				if strings.HasPrefix(other, p.OS+"/") {
+					issues = append(issues, LintIssue{
+						Severity: LintWarning,
+						Path:     kind,
+						Message:  fmt.Sprintf("%s '%s' supports platform '%s' but not '%s'", strings.TrimSuffix(kind, "s"), name, other, p.String()),
+					})
+					break
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// lintArgs checks that the ${VAR} or ${VAR:default} templates in the arguments are well-formed.
+func lintArgs(path string, args []string) LintIssues {
+	var issues LintIssues
+	for i, arg := range args {
+		rest := arg
+		for {
+			start := strings.Index(rest, "${")
+			if start == -1 {
+				break
+			}
+			end := strings.Index(rest[start:], "}")
+			if end == -1 {
+				issues = append(issues, LintIssue{
+					Severity: LintError,
+					Path:     fmt.Sprintf("%s.%d", path, i),
+					Message:  fmt.Sprintf("template in '%s' is not closed", arg),
+				})
+				break
+			}
+			name := strings.SplitN(rest[start+2:start+end], ":", 2)[0]
+			if strings.TrimSpace(name) == "" {
+				issues = append(issues, LintIssue{
+					Severity: LintError,
+					Path:     fmt.Sprintf("%s.%d", path, i),
+					Message:  fmt.Sprintf("template in '%s' has no variable name", arg),
+				})
+			}
+			rest = rest[start+end+1:]
+		}
+		if strings.Contains(arg, "{{") {
+			issues = append(issues, LintIssue{
+				Severity: LintWarning,
+				Path:     fmt.Sprintf("%s.%d", path, i),
+				Message:  fmt.Sprintf("'%s' uses {{ }} which is passed as is, templates are written ${VAR}", arg),
+			})
+		}
+	}
+	return issues
+}
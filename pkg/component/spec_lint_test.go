@@ -0,0 +1,189 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package component
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintSpec(t *testing.T) {
+	scenarios := []struct {
+		Name   string
+		Spec   string
+		Issues []string
+	}{
+		{
+			Name:   "Load error",
+			Spec:   "version: 1",
+			Issues: []string{"error: only version 2 is allowed accessing config"},
+		},
+		{
+			Name: "Valid",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+      - linux/arm64
+    outputs:
+      - elasticsearch
+    command:
+      args:
+        - "-E"
+        - "gc_percent=${TESTING_GOGC:100}"
+`,
+		},
+		{
+			Name: "Platform matrix",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+      - darwin/amd64
+    outputs:
+      - elasticsearch
+    command: {}
+  - name: testing
+    description: Testing Input
+    platforms:
+      - darwin/arm64
+    outputs:
+      - elasticsearch
+    command: {}
+`,
+			Issues: []string{"warning: inputs: input 'testing' supports platform 'linux/amd64' but not 'linux/arm64'"},
+		},
+		{
+			Name: "Args templates",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - windows/amd64
+    outputs:
+      - elasticsearch
+    command:
+      args:
+        - "${TESTING_GOGC:100"
+        - "${:100}"
+        - "{{.Name}}"
+`,
+			Issues: []string{
+				"error: inputs.0.command.args.0: template in '${TESTING_GOGC:100' is not closed",
+				"error: inputs.0.command.args.1: template in '${:100}' has no variable name",
+				"warning: inputs.0.command.args.2: '{{.Name}}' uses {{ }} which is passed as is, templates are written ${VAR}",
+			},
+		},
+		{
+			Name: "Service",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - windows/amd64
+    outputs:
+      - elasticsearch
+    shippers:
+      - shipper
+    service:
+      cport: 70000
+      operations:
+        install:
+          args: ["install"]
+        uninstall:
+          timeout: 10s
+  - name: other
+    description: Other Input
+    platforms:
+      - windows/amd64
+    outputs:
+      - elasticsearch
+    service:
+      cport: 6788
+      operations:
+        check:
+          args: ["verify"]
+        install:
+          args: ["install"]
+        uninstall:
+          args: ["uninstall"]
+  - name: another
+    description: Another Input
+    platforms:
+      - windows/amd64
+    outputs:
+      - elasticsearch
+    service:
+      cport: 6788
+      operations:
+        check:
+          args: ["verify"]
+        install:
+          args: ["install"]
+        uninstall:
+          args: ["uninstall"]
+`,
+			Issues: []string{
+				"warning: inputs.0.shippers.0: shipper 'shipper' is not defined in this specification, it must be defined by another one",
+				"error: inputs.0.service.cport: port 70000 is out of the range 1-65535",
+				"warning: inputs.0.service.operations.check: service doesn't define a check operation, it's installed every time it's started",
+				"warning: inputs.0.service.operations.uninstall.args: uninstall operation runs the binary without arguments",
+				"error: inputs.2.service.cport: port 6788 is already used by input 'other'",
+			},
+		},
+		{
+			Name: "Shipper unknown platform",
+			Spec: `
+version: 2
+shippers:
+  - name: shipper
+    description: Shipper
+    platforms:
+      - plan9/amd64
+    outputs:
+      - elasticsearch
+    command: {}
+`,
+			Issues: []string{"error: shippers.0.platforms.0: unknown platform 'plan9/amd64'"},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			issues := LintSpec([]byte(scenario.Spec))
+			var actual []string
+			for _, issue := range issues {
+				actual = append(actual, issue.String())
+			}
+			assert.Equal(t, scenario.Issues, actual)
+		})
+	}
+}
+
+func TestLintSpec_Specs(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "specs", "*.spec.yml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		issues := LintSpec(data)
+		assert.False(t, issues.HasErrors(), "%s: %v", file, issues)
+	}
+}
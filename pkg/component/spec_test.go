@@ -6,6 +6,7 @@ package component
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,7 +40,7 @@ inputs:
     outputs:
       - shipper
 `,
-			Err: "input 'testing' must define either command or service accessing 'inputs.0'",
+			Err: "input 'testing' must define either command, service, builtin or otel accessing 'inputs.0'",
 		},
 		{
 			Name: "Duplicate Platform",
@@ -72,6 +73,100 @@ inputs:
 `,
 			Err: "input 'testing' defines an unknown platform 'unknown/amd64' accessing 'inputs.0'",
 		},
+		{
+			Name: "Required Port Out Of Range",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+    outputs:
+      - shipper
+    command:
+      requires:
+        ports:
+          - 70000
+`,
+			Err: "port 70000 at ports.0 is not between 1 and 65535 accessing 'inputs.0.command.requires'",
+		},
+		{
+			Name: "Required Lock File Absolute",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+    outputs:
+      - shipper
+    command:
+      requires:
+        lock_files:
+          - /var/lib/testing.lock
+`,
+			Err: "lock file '/var/lib/testing.lock' at lock_files.0 must be a path relative to the data path accessing 'inputs.0.command.requires'",
+		},
+		{
+			Name: "Seccomp Unknown Action",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+    outputs:
+      - shipper
+    command:
+      sandbox:
+        seccomp:
+          syscalls:
+            - names: [ptrace]
+              action: deny
+`,
+			Err: "unknown action 'deny' at syscalls.0 accessing 'inputs.0.command.sandbox.seccomp'",
+		},
+		{
+			Name: "Seccomp Default Action Not Allow",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+    outputs:
+      - shipper
+    command:
+      sandbox:
+        seccomp:
+          default_action: errno
+          syscalls:
+            - names: [ptrace]
+              action: errno
+`,
+			Err: "default_action 'errno' must be 'allow', the rules only deny system calls accessing 'inputs.0.command.sandbox.seccomp'",
+		},
+		{
+			Name: "Sandbox Profile Absolute",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - darwin/amd64
+    outputs:
+      - shipper
+    command:
+      sandbox:
+        profile: /etc/testing.sb
+`,
+			Err: "profile '/etc/testing.sb' must be a path relative to the directory of the binary accessing 'inputs.0.command.sandbox'",
+		},
 		{
 			Name: "Duplicate Output",
 			Spec: `
@@ -110,6 +205,44 @@ inputs:
 `,
 			Err: "input 'testing' at inputs.1 defines the same platform as a previous definition accessing config",
 		},
+		{
+			Name: "Duplicate Uninstall Hook",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+    outputs:
+      - shipper
+    command: {}
+    uninstall_hooks:
+      - name: kernel-module
+        args: ["cleanup", "kernel-module"]
+      - name: kernel-module
+        args: ["cleanup", "module"]
+`,
+			Err: "input 'testing' defines the uninstall hook 'kernel-module' more than once accessing 'inputs.0'",
+		},
+		{
+			Name: "Builtin Uninstall Hook",
+			Spec: `
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+    outputs:
+      - shipper
+    builtin: {}
+    uninstall_hooks:
+      - name: kernel-module
+        args: ["cleanup", "kernel-module"]
+`,
+			Err: "input 'testing' defined 'uninstall_hooks.0' but a builtin input has no binary to run accessing 'inputs.0'",
+		},
 		{
 			Name: "Valid",
 			Spec: `
@@ -154,3 +287,44 @@ inputs:
 		})
 	}
 }
+
+func TestDiagnosticProviderSpecValidate(t *testing.T) {
+	spec := DiagnosticProviderSpec{Name: "registry", Filename: "registry.json"}
+	spec.InitDefaults()
+	assert.Error(t, spec.Validate(), "provider without args or path should fail")
+
+	spec.Path = "registry/filebeat/log.json"
+	assert.NoError(t, spec.Validate())
+
+	spec.Args = []string{"summary"}
+	assert.Error(t, spec.Validate(), "provider with args and path should fail")
+
+	spec.Args = nil
+	spec.Filename = "../registry.json"
+	assert.Error(t, spec.Validate(), "filename with a directory should fail")
+}
+
+func TestUninstallHookSpecDefaults(t *testing.T) {
+	spec, err := LoadSpec([]byte(`
+version: 2
+inputs:
+  - name: testing
+    description: Testing Input
+    platforms:
+      - linux/amd64
+    outputs:
+      - shipper
+    command: {}
+    uninstall_hooks:
+      - name: kernel-module
+        args: ["cleanup", "kernel-module"]
+      - name: firewall
+        args: ["cleanup", "firewall"]
+        timeout: 5m
+`))
+	require.NoError(t, err)
+	hooks := spec.Inputs[0].UninstallHooks
+	require.Len(t, hooks, 2)
+	assert.Equal(t, time.Minute, hooks[0].Timeout)
+	assert.Equal(t, 5*time.Minute, hooks[1].Timeout)
+}
@@ -13,6 +13,8 @@ import (
 	"sync"
 	"time"
 
+	"google.golang.org/protobuf/types/known/timestamppb"
+
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 
@@ -78,6 +80,18 @@ type ComponentUnitState struct {
 	State    State                  `json:"state" yaml:"state"`
 	Message  string                 `json:"message" yaml:"message"`
 	Payload  map[string]interface{} `json:"payload,omitempty" yaml:"payload,omitempty"`
+
+	// ExpectedConfigIdx and ExpectedPolicyRevision identify the latest configuration sent to the unit,
+	// ObservedConfigIdx and ObservedPolicyRevision the configuration the unit reported as applied.
+	ExpectedConfigIdx      uint64 `json:"expected_config_idx,omitempty" yaml:"expected_config_idx,omitempty"`
+	ObservedConfigIdx      uint64 `json:"observed_config_idx,omitempty" yaml:"observed_config_idx,omitempty"`
+	ExpectedPolicyRevision int64  `json:"expected_policy_revision,omitempty" yaml:"expected_policy_revision,omitempty"`
+	ObservedPolicyRevision int64  `json:"observed_policy_revision,omitempty" yaml:"observed_policy_revision,omitempty"`
+}
+
+// ConfigApplied returns true when the unit applied the latest configuration sent to it.
+func (u ComponentUnitState) ConfigApplied() bool {
+	return u.ExpectedConfigIdx == u.ObservedConfigIdx
 }
 
 // ComponentState is a state of a component managed by the Elastic Agent.
@@ -88,6 +102,7 @@ type ComponentState struct {
 	Message     string               `json:"message" yaml:"message"`
 	Units       []ComponentUnitState `json:"units" yaml:"units"`
 	VersionInfo ComponentVersionInfo `json:"version_info" yaml:"version_info"`
+	Sandbox     string               `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
 }
 
 // AgentStateInfo is the overall information about the Elastic Agent.
@@ -97,6 +112,8 @@ type AgentStateInfo struct {
 	Commit    string `json:"commit" yaml:"commit"`
 	BuildTime string `json:"build_time" yaml:"build_time"`
 	Snapshot  bool   `json:"snapshot" yaml:"snapshot"`
+	// Features reports whether each feature flag is enabled.
+	Features map[string]bool `json:"features,omitempty" yaml:"features,omitempty"`
 }
 
 // AgentState is the current state of the Elastic Agent.
@@ -107,6 +124,8 @@ type AgentState struct {
 	Components   []ComponentState `json:"components" yaml:"components"`
 	FleetState   State            `yaml:"fleet_state"`
 	FleetMessage string           `yaml:"fleet_message"`
+	// FleetCircuitBreaker is the state of the circuit breaker of the check-ins with Fleet.
+	FleetCircuitBreaker string `json:"fleet_circuit_breaker,omitempty" yaml:"fleet_circuit_breaker,omitempty"`
 }
 
 // DiagnosticFileResult is a diagnostic file result.
@@ -135,6 +154,26 @@ type DiagnosticUnitResult struct {
 	Results     []DiagnosticFileResult
 }
 
+// BackfillRequest describes what the log inputs re-read.
+type BackfillRequest struct {
+	// Inputs are the IDs of the inputs to backfill, every log input when empty.
+	Inputs []string
+	// Paths are the files to re-read, every file of the inputs when empty.
+	Paths []string
+	// From and To bound the time range to re-read, unbounded when zero.
+	From time.Time
+	To   time.Time
+}
+
+// BackfillUnitResult is the result of the backfill of a unit.
+type BackfillUnitResult struct {
+	ComponentID string
+	UnitID      string
+	Err         error
+	// Response is the JSON encoded response of the unit.
+	Response string
+}
+
 // Client communicates to Elastic Agent through the control protocol.
 type Client interface {
 	// Connect connects to the running Elastic Agent.
@@ -149,6 +188,13 @@ type Client interface {
 	StateWatch(ctx context.Context) (ClientStateWatch, error)
 	// Restart triggers restarting the current running daemon.
 	Restart(ctx context.Context) error
+	// RotateCredentials restarts the running components with new connection credentials.
+	RotateCredentials(ctx context.Context) error
+	// Reload reloads the local configuration of the running daemon in standalone mode.
+	Reload(ctx context.Context) error
+	// Backfill instructs the log inputs to re-read a time range or a set of files, progress is
+	// called with the result of each unit once it is done.
+	Backfill(ctx context.Context, req BackfillRequest, progress func(BackfillUnitResult)) error
 	// Upgrade triggers upgrade of the current running daemon.
 	Upgrade(ctx context.Context, version string, sourceURI string, skipVerify bool, pgpBytes ...string) (string, error)
 	// DiagnosticAgent gathers diagnostics information for the running Elastic Agent.
@@ -276,6 +322,68 @@ func (c *client) Restart(ctx context.Context) error {
 	return nil
 }
 
+// RotateCredentials restarts the running components with new connection credentials.
+func (c *client) RotateCredentials(ctx context.Context) error {
+	res, err := c.client.RotateCredentials(ctx, &cproto.Empty{})
+	if err != nil {
+		return err
+	}
+	if res.Status == cproto.ActionStatus_FAILURE {
+		return fmt.Errorf(res.Error)
+	}
+	return nil
+}
+
+// Reload reloads the local configuration of the running daemon in standalone mode.
+func (c *client) Reload(ctx context.Context) error {
+	res, err := c.client.Reload(ctx, &cproto.Empty{})
+	if err != nil {
+		return err
+	}
+	if res.Status == cproto.ActionStatus_FAILURE {
+		return fmt.Errorf(res.Error)
+	}
+	return nil
+}
+
+// Backfill instructs the log inputs to re-read a time range or a set of files, progress is called
+// with the result of each unit once it is done.
+func (c *client) Backfill(ctx context.Context, req BackfillRequest, progress func(BackfillUnitResult)) error {
+	r := &cproto.BackfillRequest{
+		Inputs: req.Inputs,
+		Paths:  req.Paths,
+	}
+	if !req.From.IsZero() {
+		r.From = timestamppb.New(req.From)
+	}
+	if !req.To.IsZero() {
+		r.To = timestamppb.New(req.To)
+	}
+	stream, err := c.client.Backfill(ctx, r)
+	if err != nil {
+		return err
+	}
+	for {
+		u, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var unitErr error
+		if u.Error != "" {
+			unitErr = errors.New(u.Error)
+		}
+		progress(BackfillUnitResult{
+			ComponentID: u.ComponentId,
+			UnitID:      u.UnitId,
+			Err:         unitErr,
+			Response:    u.Response,
+		})
+	}
+}
+
 // Upgrade triggers upgrade of the current running daemon.
 func (c *client) Upgrade(ctx context.Context, version string, sourceURI string, skipVerify bool, pgpBytes ...string) (string, error) {
 	res, err := c.client.Upgrade(ctx, &cproto.UpgradeRequest{
@@ -397,12 +505,15 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 			Commit:    res.Info.Commit,
 			BuildTime: res.Info.BuildTime,
 			Snapshot:  res.Info.Snapshot,
+			Features:  res.Info.Features,
 		},
 		State:        res.State,
 		Message:      res.Message,
 		FleetState:   res.FleetState,
 		FleetMessage: res.FleetMessage,
 
+		FleetCircuitBreaker: res.FleetCircuitBreaker,
+
 		Components: make([]ComponentState, 0, len(res.Components)),
 	}
 	for _, comp := range res.Components {
@@ -421,6 +532,11 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 				State:    unit.State,
 				Message:  unit.Message,
 				Payload:  payload,
+
+				ExpectedConfigIdx:      unit.ExpectedConfigIdx,
+				ObservedConfigIdx:      unit.ObservedConfigIdx,
+				ExpectedPolicyRevision: unit.ExpectedPolicyRevision,
+				ObservedPolicyRevision: unit.ObservedPolicyRevision,
 			})
 		}
 		cs := ComponentState{
@@ -429,6 +545,7 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 			State:   comp.State,
 			Message: comp.Message,
 			Units:   units,
+			Sandbox: comp.Sandbox,
 		}
 		if comp.VersionInfo != nil {
 			cs.VersionInfo = ComponentVersionInfo{
@@ -51,6 +51,14 @@ type ElasticAgentControlClient interface {
 	// on any Elastic Agent that is not in TESTING_MODE will result in an error being
 	// returned and nothing occurring.
 	Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*Empty, error)
+	// RotateCredentials restarts the running components so they connect back with new credentials.
+	RotateCredentials(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RotateCredentialsResponse, error)
+	// Reload reloads the local configuration of an Elastic Agent in standalone mode, only the
+	// components whose configuration changed are updated.
+	Reload(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ReloadResponse, error)
+	// Backfill instructs the log inputs to re-read a time range or a set of files, the result of
+	// each unit is streamed once it is done.
+	Backfill(ctx context.Context, in *BackfillRequest, opts ...grpc.CallOption) (ElasticAgentControl_BackfillClient, error)
 }
 
 type elasticAgentControlClient struct {
@@ -179,6 +187,56 @@ func (c *elasticAgentControlClient) Configure(ctx context.Context, in *Configure
 	return out, nil
 }
 
+func (c *elasticAgentControlClient) RotateCredentials(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RotateCredentialsResponse, error) {
+	out := new(RotateCredentialsResponse)
+	err := c.cc.Invoke(ctx, "/cproto.ElasticAgentControl/RotateCredentials", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) Reload(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ReloadResponse, error) {
+	out := new(ReloadResponse)
+	err := c.cc.Invoke(ctx, "/cproto.ElasticAgentControl/Reload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) Backfill(ctx context.Context, in *BackfillRequest, opts ...grpc.CallOption) (ElasticAgentControl_BackfillClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ElasticAgentControl_ServiceDesc.Streams[2], "/cproto.ElasticAgentControl/Backfill", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &elasticAgentControlBackfillClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ElasticAgentControl_BackfillClient interface {
+	Recv() (*BackfillUnitResult, error)
+	grpc.ClientStream
+}
+
+type elasticAgentControlBackfillClient struct {
+	grpc.ClientStream
+}
+
+func (x *elasticAgentControlBackfillClient) Recv() (*BackfillUnitResult, error) {
+	m := new(BackfillUnitResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ElasticAgentControlServer is the server API for ElasticAgentControl service.
 // All implementations must embed UnimplementedElasticAgentControlServer
 // for forward compatibility
@@ -207,6 +265,14 @@ type ElasticAgentControlServer interface {
 	// on any Elastic Agent that is not in TESTING_MODE will result in an error being
 	// returned and nothing occurring.
 	Configure(context.Context, *ConfigureRequest) (*Empty, error)
+	// RotateCredentials restarts the running components so they connect back with new credentials.
+	RotateCredentials(context.Context, *Empty) (*RotateCredentialsResponse, error)
+	// Reload reloads the local configuration of an Elastic Agent in standalone mode, only the
+	// components whose configuration changed are updated.
+	Reload(context.Context, *Empty) (*ReloadResponse, error)
+	// Backfill instructs the log inputs to re-read a time range or a set of files, the result of
+	// each unit is streamed once it is done.
+	Backfill(*BackfillRequest, ElasticAgentControl_BackfillServer) error
 	mustEmbedUnimplementedElasticAgentControlServer()
 }
 
@@ -238,6 +304,15 @@ func (UnimplementedElasticAgentControlServer) DiagnosticUnits(*DiagnosticUnitsRe
 func (UnimplementedElasticAgentControlServer) Configure(context.Context, *ConfigureRequest) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Configure not implemented")
 }
+func (UnimplementedElasticAgentControlServer) RotateCredentials(context.Context, *Empty) (*RotateCredentialsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateCredentials not implemented")
+}
+func (UnimplementedElasticAgentControlServer) Reload(context.Context, *Empty) (*ReloadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
+}
+func (UnimplementedElasticAgentControlServer) Backfill(*BackfillRequest, ElasticAgentControl_BackfillServer) error {
+	return status.Errorf(codes.Unimplemented, "method Backfill not implemented")
+}
 func (UnimplementedElasticAgentControlServer) mustEmbedUnimplementedElasticAgentControlServer() {}
 
 // UnsafeElasticAgentControlServer may be embedded to opt out of forward compatibility for this service.
@@ -401,6 +476,63 @@ func _ElasticAgentControl_Configure_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ElasticAgentControl_RotateCredentials_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).RotateCredentials(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cproto.ElasticAgentControl/RotateCredentials",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).RotateCredentials(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_Reload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).Reload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cproto.ElasticAgentControl/Reload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).Reload(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_Backfill_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BackfillRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ElasticAgentControlServer).Backfill(m, &elasticAgentControlBackfillServer{stream})
+}
+
+type ElasticAgentControl_BackfillServer interface {
+	Send(*BackfillUnitResult) error
+	grpc.ServerStream
+}
+
+type elasticAgentControlBackfillServer struct {
+	grpc.ServerStream
+}
+
+func (x *elasticAgentControlBackfillServer) Send(m *BackfillUnitResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ElasticAgentControl_ServiceDesc is the grpc.ServiceDesc for ElasticAgentControl service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -432,6 +564,14 @@ var ElasticAgentControl_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Configure",
 			Handler:    _ElasticAgentControl_Configure_Handler,
 		},
+		{
+			MethodName: "RotateCredentials",
+			Handler:    _ElasticAgentControl_RotateCredentials_Handler,
+		},
+		{
+			MethodName: "Reload",
+			Handler:    _ElasticAgentControl_Reload_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -444,6 +584,11 @@ var ElasticAgentControl_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ElasticAgentControl_DiagnosticUnits_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Backfill",
+			Handler:       _ElasticAgentControl_Backfill_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "control_v2.proto",
 }
@@ -23,6 +23,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/backfill"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
@@ -31,6 +32,7 @@ import (
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/features"
 )
 
 // TestModeConfigSetter is used only for testing mode.
@@ -166,6 +168,36 @@ func (s *Server) Restart(_ context.Context, _ *cproto.Empty) (*cproto.RestartRes
 	}, nil
 }
 
+// RotateCredentials restarts the running components with new connection credentials.
+func (s *Server) RotateCredentials(ctx context.Context, _ *cproto.Empty) (*cproto.RotateCredentialsResponse, error) {
+	err := s.coord.RotateCredentials(ctx)
+	if err != nil {
+		//nolint:nilerr // ignore the error, return a failure response
+		return &cproto.RotateCredentialsResponse{
+			Status: cproto.ActionStatus_FAILURE,
+			Error:  err.Error(),
+		}, nil
+	}
+	return &cproto.RotateCredentialsResponse{
+		Status: cproto.ActionStatus_SUCCESS,
+	}, nil
+}
+
+// Reload reloads the local configuration of an Elastic Agent in standalone mode.
+func (s *Server) Reload(ctx context.Context, _ *cproto.Empty) (*cproto.ReloadResponse, error) {
+	err := s.coord.Reload(ctx)
+	if err != nil {
+		//nolint:nilerr // ignore the error, return a failure response
+		return &cproto.ReloadResponse{
+			Status: cproto.ActionStatus_FAILURE,
+			Error:  err.Error(),
+		}, nil
+	}
+	return &cproto.ReloadResponse{
+		Status: cproto.ActionStatus_SUCCESS,
+	}, nil
+}
+
 // Upgrade performs the upgrade operation.
 func (s *Server) Upgrade(ctx context.Context, request *cproto.UpgradeRequest) (*cproto.UpgradeResponse, error) {
 	err := s.coord.Upgrade(ctx, request.Version, request.SourceURI, nil, request.SkipVerify, request.PgpBytes...)
@@ -254,6 +286,49 @@ func (s *Server) DiagnosticUnits(req *cproto.DiagnosticUnitsRequest, srv cproto.
 	return nil
 }
 
+// Backfill has the log inputs re-read a time range or a set of files, the result of each unit is
+// sent once it is done.
+func (s *Server) Backfill(req *cproto.BackfillRequest, srv cproto.ElasticAgentControl_BackfillServer) error {
+	r := backfill.Request{
+		Inputs: req.Inputs,
+		Paths:  req.Paths,
+	}
+	if req.From != nil {
+		r.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		r.To = req.To.AsTime()
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	targets, err := backfill.Targets(s.coord.State().Components, r.Inputs)
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	backfill.Run(srv.Context(), s.logger, s.coord.PerformAction, targets, r, func(_, _ int, result backfill.Result) {
+		if sendErr != nil {
+			return
+		}
+		res := &cproto.BackfillUnitResult{
+			ComponentId: result.ComponentID,
+			UnitId:      result.UnitID,
+		}
+		if result.Err != nil {
+			res.Error = result.Err.Error()
+		}
+		if len(result.Response) > 0 {
+			if data, err := json.Marshal(result.Response); err == nil {
+				res.Response = string(data)
+			}
+		}
+		sendErr = srv.Send(res)
+	})
+	return sendErr
+}
+
 // Configure configures the running Elastic Agent configuration.
 //
 // Only available in testing mode.
@@ -275,8 +350,8 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 		units := make([]*cproto.ComponentUnitState, 0, len(comp.State.Units))
 		for key, unit := range comp.State.Units {
 			payload := []byte("")
-			if unit.Payload != nil {
-				payload, err = json.Marshal(unit.Payload)
+			if reported := unit.ReportedPayload(); reported != nil {
+				payload, err = json.Marshal(reported)
 				if err != nil {
 					return nil, fmt.Errorf("failed to marshal componend %s unit %s payload: %w", comp.Component.ID, key.UnitID, err)
 				}
@@ -287,6 +362,11 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 				State:    cproto.State(unit.State),
 				Message:  unit.Message,
 				Payload:  string(payload),
+
+				ExpectedConfigIdx:      unit.ExpectedConfigIdx,
+				ObservedConfigIdx:      unit.ObservedConfigIdx,
+				ExpectedPolicyRevision: unit.ExpectedPolicyRevision,
+				ObservedPolicyRevision: unit.ObservedPolicyRevision,
 			})
 		}
 		components = append(components, &cproto.ComponentState{
@@ -300,6 +380,7 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 				Version: comp.State.VersionInfo.Version,
 				Meta:    comp.State.VersionInfo.Meta,
 			},
+			Sandbox: comp.State.Sandbox,
 		})
 	}
 	return &cproto.StateResponse{
@@ -309,11 +390,14 @@ func stateToProto(state *coordinator.State, agentInfo *info.AgentInfo) (*cproto.
 			Commit:    release.Commit(),
 			BuildTime: release.BuildTime().Format(control.TimeFormat()),
 			Snapshot:  release.Snapshot(),
+			Features:  features.All(),
 		},
 		State:        state.State,
 		Message:      state.Message,
 		FleetState:   state.FleetState,
 		FleetMessage: state.FleetMessage,
 		Components:   components,
+
+		FleetCircuitBreaker: state.FleetCircuitBreaker,
 	}, nil
 }
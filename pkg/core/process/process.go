@@ -18,6 +18,8 @@ type Info struct {
 	Process *os.Process
 	Stdin   io.WriteCloser
 	Stderr  io.ReadCloser
+
+	tree *processTree
 }
 
 // CmdOption is an option func to change the underlying command
@@ -29,6 +31,7 @@ type StartConfig struct {
 	uid, gid  int
 	args, env []string
 	cmdOpts   []CmdOption
+	killTree  bool
 }
 
 // StartOption start options function
@@ -46,7 +49,7 @@ func Start(path string, opts ...StartOption) (proc *Info, err error) {
 		opt(&c)
 	}
 
-	return startContext(c.ctx, path, c.uid, c.gid, c.args, c.env, c.cmdOpts...)
+	return startContext(c.ctx, path, c.uid, c.gid, c.args, c.env, c.killTree, c.cmdOpts...)
 }
 
 // WithContext sets an optional context
@@ -91,6 +94,14 @@ func WithCmdOptions(cmdOpts ...CmdOption) StartOption {
 	}
 }
 
+// WithKillTree starts the process so it can be killed along with all its children with KillTree.
+// ReleaseTree must be called once the process exited.
+func WithKillTree() StartOption {
+	return func(cfg *StartConfig) {
+		cfg.killTree = true
+	}
+}
+
 // WithWorkDir sets the cmd working directory
 func WithWorkDir(wd string) CmdOption {
 	return func(c *exec.Cmd) error {
@@ -104,6 +115,24 @@ func (i *Info) Kill() error {
 	return killCmd(i.Process)
 }
 
+// KillTree kills the process and all its children. Only the process itself is killed when it was
+// not started with WithKillTree.
+func (i *Info) KillTree() error {
+	if i.tree == nil {
+		return killCmd(i.Process)
+	}
+	return i.tree.kill()
+}
+
+// ReleaseTree releases the resources used to kill the process tree, the children of the process
+// cannot be killed with KillTree anymore.
+func (i *Info) ReleaseTree() error {
+	if i.tree == nil {
+		return nil
+	}
+	return i.tree.release()
+}
+
 // Stop stops the process cleanly.
 func (i *Info) Stop() error {
 	return terminateCmd(i.Process)
@@ -136,7 +165,7 @@ func (i *Info) Wait() <-chan *os.ProcessState {
 }
 
 // startContext starts a new process with context. The context is optional and can be nil.
-func startContext(ctx context.Context, path string, uid, gid int, args []string, env []string, opts ...CmdOption) (*Info, error) {
+func startContext(ctx context.Context, path string, uid, gid int, args []string, env []string, killTree bool, opts ...CmdOption) (*Info, error) {
 	cmd, err := getCmd(ctx, path, env, uid, gid, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create command for %q: %w", path, err)
@@ -146,6 +175,9 @@ func startContext(ctx context.Context, path string, uid, gid int, args []string,
 			return nil, fmt.Errorf("failed to set option command for %q: %w", path, err)
 		}
 	}
+	if killTree {
+		setupTree(cmd)
+	}
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stdin for %q: %w", path, err)
@@ -173,10 +205,19 @@ func startContext(ctx context.Context, path string, uid, gid int, args []string,
 		return nil, fmt.Errorf("failed job assignment %q: %w", path, err)
 	}
 
+	var tree *processTree
+	if killTree {
+		if tree, err = newTree(cmd.Process); err != nil {
+			_ = killCmd(cmd.Process)
+			return nil, fmt.Errorf("failed to track process tree of %q: %w", path, err)
+		}
+	}
+
 	return &Info{
 		PID:     cmd.Process.Pid,
 		Process: cmd.Process,
 		Stdin:   stdin,
 		Stderr:  stderr,
+		tree:    tree,
 	}, err
 }
@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// processTree is the process group of the process, the process is the leader of its own group
+// so the group contains the process and all its children.
+type processTree struct {
+	pgid int
+}
+
+// setupTree starts the process in its own process group.
+func setupTree(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func newTree(proc *os.Process) (*processTree, error) {
+	return &processTree{pgid: proc.Pid}, nil
+}
+
+// kill kills every process in the process group.
+func (t *processTree) kill() error {
+	err := syscall.Kill(-t.pgid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		// all the processes of the group already exited
+		return nil
+	}
+	return err
+}
+
+func (t *processTree) release() error {
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build windows
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// processTree is a job object the process is assigned to, the children of the process are
+// assigned to the same job object when they are created.
+//
+// Unlike JobObject the processes are not killed when the job object handle is closed, so the
+// children that must outlive the process are left running once the tree is released.
+type processTree struct {
+	mx  sync.Mutex
+	job windows.Handle
+}
+
+func setupTree(_ *exec.Cmd) {}
+
+func newTree(proc *os.Process) (*processTree, error) {
+	h, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// processes can be in multiple nested jobs, the process is already assigned to JobObject
+	if err := Job(h).Assign(proc); err != nil {
+		_ = windows.CloseHandle(h)
+		return nil, err
+	}
+	return &processTree{job: h}, nil
+}
+
+// kill terminates every process of the job object.
+func (t *processTree) kill() error {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.job == 0 {
+		return nil
+	}
+	return windows.TerminateJobObject(t.job, 1)
+}
+
+func (t *processTree) release() error {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.job == 0 {
+		return nil
+	}
+	err := windows.CloseHandle(t.job)
+	t.job = 0
+	return err
+}
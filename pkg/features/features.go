@@ -5,8 +5,8 @@
 package features
 
 import (
-	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
@@ -15,98 +15,202 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// FQDNFlag is the name of the feature flag reporting the FQDN instead of the hostname as host.name.
+const FQDNFlag = "fqdn"
+
 var (
 	current = Flags{}
+
+	registeredMu sync.RWMutex
+	registered   = map[string]Flag{
+		FQDNFlag: {
+			Name:        FQDNFlag,
+			Description: "Use the FQDN instead of the hostname for host.name.",
+		},
+	}
 )
 
 type BoolValueOnChangeCallback func(new, old bool)
 
+// Flag describes a feature flag, it is enabled by the policy with `agent.features.<name>.enabled`.
+// Flags allow rolling out new behaviors per policy, they fall back to their default when the
+// policy does not set them.
+type Flag struct {
+	Name        string
+	Description string
+	Default     bool
+}
+
+// Register registers a feature flag, so it is reported with its default value even when the
+// policy does not set it. Registering a flag twice is an error.
+func Register(flag Flag) error {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	if _, ok := registered[flag.Name]; ok {
+		return fmt.Errorf("feature flag %q is already registered", flag.Name)
+	}
+	registered[flag.Name] = flag
+	return nil
+}
+
+// Registered returns the registered feature flags sorted by name.
+func Registered() []Flag {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+
+	flags := make([]Flag, 0, len(registered))
+	for _, flag := range registered {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
+
 type Flags struct {
 	mu     sync.RWMutex
 	source *structpb.Struct
 
-	fqdn          bool
-	fqdnCallbacks map[string]BoolValueOnChangeCallback
+	// enabled holds the flags set by the policy, the other ones take the default of their registration.
+	enabled   map[string]bool
+	callbacks map[string]map[string]BoolValueOnChangeCallback
+}
+
+type flagCfg struct {
+	Enabled bool `json:"enabled" yaml:"enabled" config:"enabled"`
 }
 
 type cfg struct {
 	Agent struct {
-		Features struct {
-			FQDN struct {
-				Enabled bool `json:"enabled" yaml:"enabled" config:"enabled"`
-			} `json:"fqdn" yaml:"fqdn" config:"fqdn"`
-		} `json:"features" yaml:"features" config:"features"`
+		Features map[string]flagCfg `json:"features" yaml:"features" config:"features"`
 	} `json:"agent" yaml:"agent" config:"agent"`
 }
 
-func (f *Flags) FQDN() bool {
+// Enabled reports if the feature flag is enabled, either by the policy or by default.
+func (f *Flags) Enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.enabledLocked(name)
+}
+
+func (f *Flags) enabledLocked(name string) bool {
+	if enabled, ok := f.enabled[name]; ok {
+		return enabled
+	}
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	return registered[name].Default
+}
+
+// All returns whether each feature flag is enabled, the registered flags and the ones set by the policy.
+func (f *Flags) All() map[string]bool {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
-	return f.fqdn
+	all := make(map[string]bool)
+	for _, flag := range Registered() {
+		all[flag.Name] = f.enabledLocked(flag.Name)
+	}
+	for name, enabled := range f.enabled {
+		all[name] = enabled
+	}
+	return all
+}
+
+func (f *Flags) FQDN() bool {
+	return f.Enabled(FQDNFlag)
 }
 
 func (f *Flags) AsProto() *proto.Features {
+	f.mu.RLock()
+	source := f.source
+	f.mu.RUnlock()
+
 	return &proto.Features{
 		Fqdn: &proto.FQDNFeature{
 			Enabled: f.FQDN(),
 		},
-		Source: f.source,
+		Source: source,
 	}
 }
 
-// AddFQDNOnChangeCallback takes a callback function that will be called with the new and old values
-// of `flags.fqdnEnabled` whenever it changes. It also takes a string ID - this is useful
-// in calling `RemoveFQDNOnChangeCallback` to de-register the callback.
-func AddFQDNOnChangeCallback(cb BoolValueOnChangeCallback, id string) error {
+// AddOnChangeCallback takes a callback function that will be called with the new and old values
+// of the named feature flag whenever the feature flags are applied. It also takes a string ID - this
+// is useful in calling `RemoveOnChangeCallback` to de-register the callback.
+func AddOnChangeCallback(name string, cb BoolValueOnChangeCallback, id string) error {
 	current.mu.Lock()
 	defer current.mu.Unlock()
 
 	// Initialize callbacks map if necessary.
-	if current.fqdnCallbacks == nil {
-		current.fqdnCallbacks = map[string]BoolValueOnChangeCallback{}
+	if current.callbacks == nil {
+		current.callbacks = map[string]map[string]BoolValueOnChangeCallback{}
+	}
+	if current.callbacks[name] == nil {
+		current.callbacks[name] = map[string]BoolValueOnChangeCallback{}
 	}
 
-	current.fqdnCallbacks[id] = cb
+	current.callbacks[name][id] = cb
 	return nil
 }
 
+// RemoveOnChangeCallback removes the callback function of the named feature flag associated with
+// the given ID, so that function will be no longer be called when the feature flag changes.
+func RemoveOnChangeCallback(name string, id string) {
+	current.mu.Lock()
+	defer current.mu.Unlock()
+
+	delete(current.callbacks[name], id)
+}
+
+// AddFQDNOnChangeCallback takes a callback function that will be called with the new and old values
+// of `flags.fqdnEnabled` whenever it changes. It also takes a string ID - this is useful
+// in calling `RemoveFQDNOnChangeCallback` to de-register the callback.
+func AddFQDNOnChangeCallback(cb BoolValueOnChangeCallback, id string) error {
+	return AddOnChangeCallback(FQDNFlag, cb, id)
+}
+
 // RemoveFQDNOnChangeCallback removes the callback function associated with the given ID (originally
 // returned by `AddFQDNOnChangeCallback` so that function will be no longer be called when
 // `flags.fqdnEnabled` changes.
 func RemoveFQDNOnChangeCallback(id string) {
-	current.mu.Lock()
-	defer current.mu.Unlock()
-
-	delete(current.fqdnCallbacks, id)
+	RemoveOnChangeCallback(FQDNFlag, id)
 }
 
-// setFQDN sets the value of the FQDN flag in Flags.
-func (f *Flags) setFQDN(newValue bool) {
+// set sets the feature flags and the source of the given Flags, calling the callbacks of the flags
+// with their new and old values.
+func (f *Flags) set(parsed *Flags) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	oldValue := f.fqdn
-	f.fqdn = newValue
-	for _, cb := range f.fqdnCallbacks {
-		cb(newValue, oldValue)
+	old := make(map[string]bool, len(f.callbacks))
+	for name := range f.callbacks {
+		old[name] = f.enabledLocked(name)
 	}
-}
-
-// setSource sets the source from he given cfg.
-func (f *Flags) setSource(c cfg) error {
-	// Use JSON marshalling-unmarshalling to convert cfg to mapstr
-	data, err := json.Marshal(c)
-	if err != nil {
-		return fmt.Errorf("could not convert feature flags configuration to JSON: %w", err)
+	f.enabled = parsed.enabled
+	f.source = parsed.source
+	for name, callbacks := range f.callbacks {
+		newValue := f.enabledLocked(name)
+		for _, cb := range callbacks {
+			cb(newValue, old[name])
+		}
 	}
+}
 
-	var s map[string]interface{}
-	if err := json.Unmarshal(data, &s); err != nil {
-		return fmt.Errorf("could not convert feature flags JSON to mapstr: %w", err)
+// setSource sets the source from the enabled feature flags.
+func (f *Flags) setSource() error {
+	features := make(map[string]interface{})
+	for name, enabled := range f.All() {
+		features[name] = map[string]interface{}{"enabled": enabled}
 	}
 
-	source, err := structpb.NewStruct(s)
+	source, err := structpb.NewStruct(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"features": features,
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("unable to create source from feature flags configuration: %w", err)
 	}
@@ -149,8 +253,11 @@ func Parse(policy any) (*Flags, error) {
 	}
 
 	flags := new(Flags)
-	flags.setFQDN(parsedFlags.Agent.Features.FQDN.Enabled)
-	if err := flags.setSource(parsedFlags); err != nil {
+	flags.enabled = make(map[string]bool, len(parsedFlags.Agent.Features))
+	for name, flag := range parsedFlags.Agent.Features {
+		flags.enabled[name] = flag.Enabled
+	}
+	if err := flags.setSource(); err != nil {
 		return nil, fmt.Errorf("error creating feature flags source: %w", err)
 	}
 
@@ -170,10 +277,20 @@ func Apply(c *config.Config) error {
 		return fmt.Errorf("could not apply feature flag config: %w", err)
 	}
 
-	current.setFQDN(parsed.FQDN())
+	current.set(parsed)
 	return err
 }
 
+// Enabled reports if the named feature flag is enabled by the applied policy or by default.
+func Enabled(name string) bool {
+	return current.Enabled(name)
+}
+
+// All returns whether each feature flag of the applied policy is enabled.
+func All() map[string]bool {
+	return current.All()
+}
+
 // FQDN reports if FQDN should be used instead of hostname for host.name.
 func FQDN() bool {
 	return current.FQDN()
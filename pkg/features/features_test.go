@@ -7,8 +7,10 @@ package features
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/elastic-agent-libs/monitoring"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 )
 
@@ -98,21 +100,95 @@ func TestFQDNCallbacks(t *testing.T) {
 	defer func() {
 		// Cleanup in case we don't get to the end of
 		// this test successfully.
-		if _, exists := current.fqdnCallbacks["cb1"]; exists {
+		if _, exists := current.callbacks[FQDNFlag]["cb1"]; exists {
 			RemoveFQDNOnChangeCallback("cb1")
 		}
-		if _, exists := current.fqdnCallbacks["cb2"]; exists {
+		if _, exists := current.callbacks[FQDNFlag]["cb2"]; exists {
 			RemoveFQDNOnChangeCallback("cb2")
 		}
 	}()
 
-	require.Len(t, current.fqdnCallbacks, 2)
-	current.setFQDN(false)
+	require.Len(t, current.callbacks[FQDNFlag], 2)
+	current.set(&Flags{enabled: map[string]bool{FQDNFlag: false}})
 	require.True(t, cb1Called)
 	require.True(t, cb2Called)
 
 	RemoveFQDNOnChangeCallback("cb1")
-	require.Len(t, current.fqdnCallbacks, 1)
+	require.Len(t, current.callbacks[FQDNFlag], 1)
 	RemoveFQDNOnChangeCallback("cb2")
-	require.Len(t, current.fqdnCallbacks, 0)
+	require.Len(t, current.callbacks[FQDNFlag], 0)
+}
+
+func TestFlags(t *testing.T) {
+	require.NoError(t, Register(Flag{Name: "test_default_on", Description: "Enabled by default.", Default: true}))
+	defer func() {
+		registeredMu.Lock()
+		delete(registered, "test_default_on")
+		registeredMu.Unlock()
+	}()
+	require.ErrorContains(t, Register(Flag{Name: FQDNFlag}), `feature flag "fqdn" is already registered`)
+
+	c, err := config.NewConfigFrom(`
+agent:
+  features:
+    fqdn:
+      enabled: true
+    new_downloader:
+      enabled: true`)
+	require.NoError(t, err)
+
+	var changes [][2]bool
+	require.NoError(t, AddOnChangeCallback("test_default_on", func(new, old bool) {
+		changes = append(changes, [2]bool{new, old})
+	}, "cb"))
+	defer RemoveOnChangeCallback("test_default_on", "cb")
+
+	require.NoError(t, Apply(c))
+	assert.True(t, Enabled("new_downloader"))
+	assert.True(t, Enabled("test_default_on"))
+	assert.False(t, Enabled("unknown"))
+	assert.Equal(t, map[string]bool{
+		FQDNFlag:          true,
+		"new_downloader":  true,
+		"test_default_on": true,
+	}, All())
+
+	c, err = config.NewConfigFrom(`
+agent:
+  features:
+    test_default_on:
+      enabled: false`)
+	require.NoError(t, err)
+	flags, err := Parse(c)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"agent": map[string]interface{}{
+			"features": map[string]interface{}{
+				FQDNFlag:          map[string]interface{}{"enabled": false},
+				"test_default_on": map[string]interface{}{"enabled": false},
+			},
+		},
+	}, flags.AsProto().Source.AsMap())
+
+	require.NoError(t, Apply(c))
+	assert.False(t, Enabled("test_default_on"))
+	assert.False(t, Enabled("new_downloader"))
+	assert.Equal(t, [][2]bool{{true, true}, {false, true}}, changes)
+}
+
+func TestRegisterMetrics(t *testing.T) {
+	c, err := config.NewConfigFrom(`
+agent:
+  features:
+    new_downloader:
+      enabled: true`)
+	require.NoError(t, err)
+	require.NoError(t, Apply(c))
+
+	RegisterMetrics()
+	snapshot := monitoring.CollectStructSnapshot(monitoring.GetNamespace("stats").GetRegistry(), monitoring.Full, false)
+	assert.Equal(t, map[string]interface{}{
+		FQDNFlag:         map[string]interface{}{"enabled": false},
+		"new_downloader": map[string]interface{}{"enabled": true},
+	}, snapshot[metricsName])
 }
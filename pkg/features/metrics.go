@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package features
+
+import (
+	"sort"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// metricsName is the name of the feature flags in the stats namespace of the monitoring endpoint.
+const metricsName = "features"
+
+// RegisterMetrics adds the applied feature flags to the stats of the monitoring endpoint, so the
+// telemetry of the agent reports which ones are enabled.
+func RegisterMetrics() {
+	reg := monitoring.GetNamespace("stats").GetRegistry()
+	reg.Remove(metricsName)
+	reg.Add(metricsName, monitoring.FuncVar(func(_ monitoring.Mode, vs monitoring.Visitor) {
+		vs.OnRegistryStart()
+		defer vs.OnRegistryFinished()
+
+		all := All()
+		names := make([]string, 0, len(all))
+		for name := range all {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			monitoring.ReportNamespace(vs, name, func() {
+				monitoring.ReportBool(vs, "enabled", all[name])
+			})
+		}
+	}), monitoring.Reported)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/otiai10/copy"
 	"gopkg.in/yaml.v2"
 
+	"github.com/elastic/elastic-agent/internal/pkg/fault"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -39,6 +40,7 @@ type Fixture struct {
 	logOutput       bool
 	allowErrs       bool
 	connectTimout   time.Duration
+	cmdOpts         []process.CmdOption
 
 	workDir string
 
@@ -96,6 +98,14 @@ func WithConnectTimeout(timeout time.Duration) FixtureOpt {
 	}
 }
 
+// WithCmdOptions adds options to the command running the Elastic Agent, for example to run it in
+// another network namespace.
+func WithCmdOptions(opts ...process.CmdOption) FixtureOpt {
+	return func(f *Fixture) {
+		f.cmdOpts = append(f.cmdOpts, opts...)
+	}
+}
+
 // NewFixture creates a new fixture to setup and manage Elastic Agent.
 func NewFixture(t *testing.T, version string, opts ...FixtureOpt) (*Fixture, error) {
 	// we store the caller so the fixture can find the cache directory for the artifacts that
@@ -183,12 +193,61 @@ func (f *Fixture) Configure(ctx context.Context, yamlConfig []byte) error {
 	return os.WriteFile(cfgFilePath, yamlConfig, 0600)
 }
 
+// InjectFaults replaces the faults injected in the Elastic Agent of the fixture, no faults clears them. The
+// Elastic Agent must be built with the faultinject build tag (FAULT_INJECTION=true mage build).
+func (f *Fixture) InjectFaults(ctx context.Context, faults ...fault.Fault) error {
+	err := f.ensurePrepared(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := fault.Marshal(faults)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(f.workDir, "faults.yml"), data, 0600)
+}
+
 // WorkDir returns the installed fixture's work dir AKA base dir AKA top dir. This
 // must be called after `Install` is called.
 func (f *Fixture) WorkDir() string {
 	return f.workDir
 }
 
+// Version returns the version of the Elastic Agent of the fixture.
+func (f *Fixture) Version() string {
+	return f.version
+}
+
+// SrcPackage returns the path of the fetched package of the Elastic Agent, for example to use its
+// directory as the source of an upgrade.
+func (f *Fixture) SrcPackage(ctx context.Context) (string, error) {
+	return f.fetch(ctx)
+}
+
+// CollectDiagnostics runs the diagnostics command of the Elastic Agent and writes the archive in dir,
+// the build/diagnostics directory of the project when empty. It returns the path of the archive.
+func (f *Fixture) CollectDiagnostics(ctx context.Context, dir string) (string, error) {
+	if dir == "" {
+		root, err := findProjectRoot(f.caller)
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(root, "build", "diagnostics")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed creating directory %s: %w", dir, err)
+	}
+
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(f.t.Name())
+	archive := filepath.Join(dir, fmt.Sprintf("diagnostics-%s-%s.zip", name, time.Now().UTC().Format("2006-01-02T15-04-05Z")))
+	out, err := f.Exec(ctx, []string{"diagnostics", "-f", archive})
+	if err != nil {
+		return "", fmt.Errorf("error running diagnostics command: %w. Output: %q", err, out)
+	}
+	return archive, nil
+}
+
 func ExtractArtifact(l Logger, artifactFile, outputDir string) error {
 	filename := filepath.Base(artifactFile)
 	_, ext, err := splitFileType(filename)
@@ -257,7 +316,7 @@ func (f *Fixture) Run(ctx context.Context, states ...State) error {
 		f.binaryPath(),
 		process.WithContext(ctx),
 		process.WithArgs([]string{"run", "-e", "--disable-encrypted-store", "--testing-mode"}),
-		process.WithCmdOptions(attachOutErr(stdOut, stdErr)))
+		process.WithCmdOptions(append([]process.CmdOption{attachOutErr(stdOut, stdErr)}, f.cmdOpts...)...))
 	if err != nil {
 		return fmt.Errorf("failed to spawn elastic-agent: %w", err)
 	}
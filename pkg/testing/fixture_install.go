@@ -31,6 +31,7 @@ type CmdOpts interface {
 type EnrollOpts struct {
 	URL             string // --url
 	EnrollmentToken string // --enrollment-token
+	Insecure        bool   // --insecure
 }
 
 func (e EnrollOpts) toCmdArgs() []string {
@@ -41,6 +42,9 @@ func (e EnrollOpts) toCmdArgs() []string {
 	if e.EnrollmentToken != "" {
 		args = append(args, "--enrollment-token", e.EnrollmentToken)
 	}
+	if e.Insecure {
+		args = append(args, "--insecure")
+	}
 	return args
 }
 
@@ -20,6 +20,11 @@ type Config struct {
 	RepoDir           string
 	ESS               *ESSConfig
 	GCE               *GCEConfig
+	AWS               *AWSConfig
+
+	// Provisioner selects how the instances are provisioned, through OGC on
+	// the cloud providers by default or through Vagrant on the local host.
+	Provisioner string
 
 	// Matrix enables matrix testing. This explodes each test to
 	// run on all supported platforms the runner supports.
@@ -61,16 +66,41 @@ func (c *Config) Validate() error {
 	if err != nil {
 		return fmt.Errorf("error validating ESS: %w", err)
 	}
-	if c.GCE == nil {
-		// in the future we could adjust to work on different providers
-		// making this selectable (at the moment we just do GCE)
-		return errors.New("config requires GCE to be set")
+	switch c.Provisioner {
+	case "", OGCProvisioner:
+		if c.GCE == nil && c.AWS == nil {
+			return errors.New("config requires GCE or AWS to be set")
+		}
+	case VagrantProvisioner:
+		// the instances run on the local host, no cloud provider is required
+	default:
+		return fmt.Errorf("unknown provisioner %q", c.Provisioner)
+	}
+	if c.GCE != nil {
+		err = c.GCE.Validate()
+		if err != nil {
+			return fmt.Errorf("error validating GCE: %w", err)
+		}
+	}
+	if c.AWS != nil {
+		err = c.AWS.Validate()
+		if err != nil {
+			return fmt.Errorf("error validating AWS: %w", err)
+		}
 	}
-	err = c.GCE.Validate()
-	if err != nil {
-		return fmt.Errorf("error validating GCE: %w", err)
+	return nil
+}
+
+// providers returns the cloud providers the instances can be provisioned on.
+func (c *Config) providers() []string {
+	var providers []string
+	if c.GCE != nil {
+		providers = append(providers, Google)
+	}
+	if c.AWS != nil {
+		providers = append(providers, Amazon)
 	}
-	return err
+	return providers
 }
 
 // ESSConfig is the configuration for communicating with ESS.
@@ -90,6 +120,27 @@ func (ess *ESSConfig) Validate() error {
 	return nil
 }
 
+// AWSConfig is the configuration for communicating with Amazon Web Services.
+type AWSConfig struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string
+}
+
+// Validate returns an error if the information is invalid.
+func (aws *AWSConfig) Validate() error {
+	if aws.AccessKeyID == "" {
+		return errors.New("field AccessKeyID must be set")
+	}
+	if aws.SecretAccessKey == "" {
+		return errors.New("field SecretAccessKey must be set")
+	}
+	if aws.Region == "" {
+		return errors.New("field Region must be set")
+	}
+	return nil
+}
+
 // GCEConfig is the configuration for communicating with Google Compute Engine.
 type GCEConfig struct {
 	ServiceTokenPath string
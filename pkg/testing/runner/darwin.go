@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/elastic/elastic-agent/pkg/testing/define"
+)
+
+// mageVersion is the version of mage installed on the hosts without make, it matches the
+// MAGE_VERSION of the Makefile.
+const mageVersion = "v1.13.0"
+
+// DarwinRunner is a handler for running tests on macOS
+type DarwinRunner struct{}
+
+// Prepare the test
+func (DarwinRunner) Prepare(ctx context.Context, sshClient *ssh.Client, logger Logger, arch string, goVersion string, repoArchive string, buildPath string) error {
+	// prepare golang, unzip and curl are part of the base system
+	logger.Logf("Install golang %s (%s)", goVersion, arch)
+	downloadURL := fmt.Sprintf("https://go.dev/dl/go%s.darwin-%s.tar.gz", goVersion, arch)
+	filename := path.Base(downloadURL)
+	stdOut, errOut, err := sshRunCommand(ctx, sshClient, "curl", []string{"-Ls", downloadURL, "--output", filename}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download go from %s with curl: %w (stdout: %s, stderr: %s)", downloadURL, err, stdOut, errOut)
+	}
+	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "sudo", []string{"tar", "-C", "/usr/local", "-xzf", filename}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to extract go to /usr/local with tar: %w (stdout: %s, stderr: %s)", err, stdOut, errOut)
+	}
+	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "sudo", []string{"mkdir", "-p", "/usr/local/bin"}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create /usr/local/bin: %w (stdout: %s, stderr: %s)", err, stdOut, errOut)
+	}
+	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "sudo", []string{"ln", "-sf", "/usr/local/go/bin/go", "/usr/local/bin/go"}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to symlink /usr/local/go/bin/go to /usr/local/bin/go: %w (stdout: %s, stderr: %s)", err, stdOut, errOut)
+	}
+
+	// copy the archive and extract it on the host
+	err = copyRepoArchive(ctx, sshClient, logger, repoArchive)
+	if err != nil {
+		return err
+	}
+
+	// install mage and prepare for testing, make is only present with the command line tools of Xcode
+	logger.Logf("Installing mage and running prepareOnRemote")
+	envs := `GOPATH="$HOME/go" PATH="$HOME/go/bin:/usr/local/bin:$PATH"`
+	installMage := strings.NewReader(fmt.Sprintf(`cd agent && %s go install github.com/magefile/mage@%s && %s mage integration:prepareOnRemote`, envs, mageVersion, envs))
+	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "bash", nil, installMage)
+	if err != nil {
+		return fmt.Errorf("failed to to install mage and perform prepareOnRemote: %w (stdout: %s, stderr: %s)", err, stdOut, errOut)
+	}
+
+	// place the build for the agent on the host
+	return copyBuild(ctx, sshClient, logger, buildPath)
+}
+
+// Run the test
+func (DarwinRunner) Run(ctx context.Context, verbose bool, sshClient *ssh.Client, logger Logger, agentVersion string, prefix string, batch define.Batch, env map[string]string) (OSRunnerResult, error) {
+	// the tests are run with bash and mage installed in $HOME/go/bin the same way as on Debian
+	env = extendEnv(env, "PATH", "$HOME/go/bin:/usr/local/bin:$PATH")
+	return DebianRunner{}.Run(ctx, verbose, sshClient, logger, agentVersion, prefix, batch, env)
+}
+
+// extendEnv returns a copy of env with the variable set.
+func extendEnv(env map[string]string, key string, value string) map[string]string {
+	extended := make(map[string]string, len(env)+1)
+	for k, v := range env {
+		extended[k] = v
+	}
+	extended[key] = value
+	return extended
+}
@@ -85,15 +85,9 @@ func (DebianRunner) Prepare(ctx context.Context, sshClient *ssh.Client, logger L
 	}
 
 	// copy the archive and extract it on the host
-	logger.Logf("Copying repo")
-	destRepoName := filepath.Base(repoArchive)
-	err = sshSCP(sshClient, repoArchive, destRepoName)
-	if err != nil {
-		return fmt.Errorf("failed to SCP repo archive %s: %w", repoArchive, err)
-	}
-	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "unzip", []string{destRepoName, "-d", "agent"}, nil)
+	err = copyRepoArchive(ctx, sshClient, logger, repoArchive)
 	if err != nil {
-		return fmt.Errorf("failed to unzip %s to agent directory: %w (stdout: %s, stderr: %s)", destRepoName, err, stdOut, errOut)
+		return err
 	}
 
 	// install mage and prepare for testing
@@ -106,22 +100,7 @@ func (DebianRunner) Prepare(ctx context.Context, sshClient *ssh.Client, logger L
 	}
 
 	// place the build for the agent on the host
-	logger.Logf("Copying agent build %s", filepath.Base(buildPath))
-	err = sshSCP(sshClient, buildPath, filepath.Base(buildPath))
-	if err != nil {
-		return fmt.Errorf("failed to SCP build %s: %w", filepath.Base(buildPath), err)
-	}
-	insideAgentDir := filepath.Join("agent", buildPath)
-	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "mkdir", []string{"-p", filepath.Dir(insideAgentDir)}, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create %s directory: %w (stdout: %s, stderr: %s)", filepath.Dir(insideAgentDir), err, stdOut, errOut)
-	}
-	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "mv", []string{filepath.Base(buildPath), insideAgentDir}, nil)
-	if err != nil {
-		return fmt.Errorf("failed to move %s to %s: %w (stdout: %s, stderr: %s)", filepath.Base(buildPath), insideAgentDir, err, stdOut, errOut)
-	}
-
-	return nil
+	return copyBuild(ctx, sshClient, logger, buildPath)
 }
 
 // Run the test
@@ -149,7 +128,7 @@ func (DebianRunner) Run(ctx context.Context, verbose bool, sshClient *ssh.Client
 		vars = extendVars(vars, env)
 
 		script := fmt.Sprintf(`cd agent && %s ~/go/bin/mage %s integration:testOnRemote`, vars, logArg)
-		results, err := runTests(ctx, logger, "non-sudo", prefix, script, sshClient, batch.Tests)
+		results, err := runTests(ctx, logger, "non-sudo", prefix, "bash", script, sshClient, batch.Tests, getRunnerPackageResult)
 		if err != nil {
 			return OSRunnerResult{}, fmt.Errorf("error running non-sudo tests: %w", err)
 		}
@@ -162,7 +141,7 @@ func (DebianRunner) Run(ctx context.Context, verbose bool, sshClient *ssh.Client
 		vars = extendVars(vars, env)
 		script := fmt.Sprintf(`cd agent && sudo %s ~/go/bin/mage %s integration:testOnRemote`, vars, logArg)
 
-		results, err := runTests(ctx, logger, "sudo", prefix, script, sshClient, batch.SudoTests)
+		results, err := runTests(ctx, logger, "sudo", prefix, "bash", script, sshClient, batch.SudoTests, getRunnerPackageResult)
 		if err != nil {
 			return OSRunnerResult{}, fmt.Errorf("error running sudo tests: %w", err)
 		}
@@ -172,7 +151,42 @@ func (DebianRunner) Run(ctx context.Context, verbose bool, sshClient *ssh.Client
 	return result, nil
 }
 
-func runTests(ctx context.Context, logger Logger, name string, prefix string, script string, sshClient *ssh.Client, tests []define.BatchPackageTests) ([]OSRunnerPackageResult, error) {
+// copyRepoArchive copies the archive of the repo to the host and extracts it in the agent directory.
+func copyRepoArchive(ctx context.Context, sshClient *ssh.Client, logger Logger, repoArchive string) error {
+	logger.Logf("Copying repo")
+	destRepoName := filepath.Base(repoArchive)
+	err := sshSCP(sshClient, repoArchive, destRepoName)
+	if err != nil {
+		return fmt.Errorf("failed to SCP repo archive %s: %w", repoArchive, err)
+	}
+	stdOut, errOut, err := sshRunCommand(ctx, sshClient, "unzip", []string{destRepoName, "-d", "agent"}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to unzip %s to agent directory: %w (stdout: %s, stderr: %s)", destRepoName, err, stdOut, errOut)
+	}
+	return nil
+}
+
+// copyBuild places the build for the agent on the host, at the same path in the agent directory.
+func copyBuild(ctx context.Context, sshClient *ssh.Client, logger Logger, buildPath string) error {
+	logger.Logf("Copying agent build %s", filepath.Base(buildPath))
+	err := sshSCP(sshClient, buildPath, filepath.Base(buildPath))
+	if err != nil {
+		return fmt.Errorf("failed to SCP build %s: %w", filepath.Base(buildPath), err)
+	}
+	insideAgentDir := filepath.Join("agent", buildPath)
+	stdOut, errOut, err := sshRunCommand(ctx, sshClient, "mkdir", []string{"-p", filepath.Dir(insideAgentDir)}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create %s directory: %w (stdout: %s, stderr: %s)", filepath.Dir(insideAgentDir), err, stdOut, errOut)
+	}
+	stdOut, errOut, err = sshRunCommand(ctx, sshClient, "mv", []string{filepath.Base(buildPath), insideAgentDir}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w (stdout: %s, stderr: %s)", filepath.Base(buildPath), insideAgentDir, err, stdOut, errOut)
+	}
+	return nil
+}
+
+// runTests runs the script with the shell of the host and fetches the results of each package with getResult.
+func runTests(ctx context.Context, logger Logger, name string, prefix string, shell string, script string, sshClient *ssh.Client, tests []define.BatchPackageTests, getResult packageResultGetter) ([]OSRunnerPackageResult, error) {
 	execTest := strings.NewReader(script)
 
 	session, err := sshClient.NewSession()
@@ -186,7 +200,7 @@ func runTests(ctx context.Context, logger Logger, name string, prefix string, sc
 
 	// allowed to fail because tests might fail
 	logger.Logf("Running %s tests...", name)
-	err = session.Run("bash")
+	err = session.Run(shell)
 	if err != nil {
 		logger.Logf("%s tests failed: %s", name, err)
 	}
@@ -196,7 +210,7 @@ func runTests(ctx context.Context, logger Logger, name string, prefix string, sc
 	var result []OSRunnerPackageResult
 	// fetch the contents for each package
 	for _, pkg := range tests {
-		resultPkg, err := getRunnerPackageResult(ctx, sshClient, pkg, prefix)
+		resultPkg, err := getResult(ctx, sshClient, pkg, prefix)
 		if err != nil {
 			return nil, err
 		}
@@ -205,6 +219,9 @@ func runTests(ctx context.Context, logger Logger, name string, prefix string, sc
 	return result, nil
 }
 
+// packageResultGetter fetches the results of the tests of a package from the host.
+type packageResultGetter func(ctx context.Context, c *ssh.Client, pkg define.BatchPackageTests, prefix string) (OSRunnerPackageResult, error)
+
 func getRunnerPackageResult(ctx context.Context, c *ssh.Client, pkg define.BatchPackageTests, prefix string) (OSRunnerPackageResult, error) {
 	var err error
 	var resultPkg OSRunnerPackageResult
@@ -4,6 +4,20 @@
 
 package runner
 
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/utils/strings/slices"
+
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
 // OGCLayout definition for `ogc layout import`.
 type OGCLayout struct {
 	Name          string            `yaml:"name"`
@@ -32,3 +46,248 @@ type OGCMachine struct {
 	Layout        OGCLayout `yaml:"layout"`
 	Create        string    `yaml:"created"`
 }
+
+// ogcProvisioner provisions the instances on the cloud providers through OGC.
+type ogcProvisioner struct {
+	cfg    Config
+	logger Logger
+}
+
+// newOGCProvisioner creates the provisioner of the instances through OGC.
+func newOGCProvisioner(cfg Config, logger Logger) *ogcProvisioner {
+	return &ogcProvisioner{
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Name returns the name of the provisioner.
+func (p *ogcProvisioner) Name() string {
+	return OGCProvisioner
+}
+
+// Supports returns true when the cloud provider of the layout is configured.
+func (p *ogcProvisioner) Supports(layout LayoutOS) bool {
+	return slices.Contains(p.cfg.providers(), layout.Provider)
+}
+
+// Prepare pulls the latest container for OGC.
+func (p *ogcProvisioner) Prepare(ctx context.Context, _ string) error {
+	return p.ogcPull(ctx)
+}
+
+// Provision imports the layouts of the batches into OGC and brings up all the instances.
+func (p *ogcProvisioner) Provision(ctx context.Context, batches []LayoutBatch) ([]Instance, error) {
+	// import the calculated layouts
+	importCtx, importCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer importCancel()
+	err := p.ogcImport(importCtx, batches)
+	if err != nil {
+		return nil, err
+	}
+
+	// bring up all the instances
+	upCtx, upCancel := context.WithTimeout(ctx, 30*time.Minute)
+	defer upCancel()
+	upOutput, err := p.ogcUp(upCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch the machines
+	machines, err := p.ogcMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(machines) == 0 {
+		// print the output so its clear what went wrong
+		// without this it's unclear where OGC went wrong it
+		// doesn't do a great job of reporting a clean error
+		fmt.Fprintf(os.Stdout, "%s\n", upOutput)
+		return nil, fmt.Errorf("ogc didn't create any machines")
+	}
+	instances := make([]Instance, 0, len(machines))
+	for _, m := range machines {
+		instances = append(instances, Instance{
+			ID:         m.InstanceID,
+			Name:       m.InstanceName,
+			IP:         m.PublicIP,
+			Port:       22,
+			Username:   m.Layout.Username,
+			RemotePath: m.Layout.RemotePath,
+			BatchID:    m.Layout.Name,
+		})
+	}
+	return instances, nil
+}
+
+// Clean brings down all the instances.
+func (p *ogcProvisioner) Clean(ctx context.Context) error {
+	return p.ogcDown(ctx)
+}
+
+// ogcPull pulls the latest ogc version.
+func (p *ogcProvisioner) ogcPull(ctx context.Context) error {
+	args := []string{
+		"pull",
+		"docker.io/gorambo/ogc:blake", // switch back to :latest when ready
+	}
+	p.logger.Logf("Pulling latest ogc image")
+	proc, err := process.Start("docker", process.WithContext(ctx), process.WithArgs(args))
+	if err != nil {
+		return fmt.Errorf("failed to run docker pull: %w", err)
+	}
+	ps := <-proc.Wait()
+	if ps.ExitCode() != 0 {
+		return fmt.Errorf("failed to run ogc import: docker run exited with code: %d", ps.ExitCode())
+	}
+	return nil
+}
+
+// ogcImport imports all the required batches into OGC.
+func (p *ogcProvisioner) ogcImport(ctx context.Context, batches []LayoutBatch) error {
+	var layouts []OGCLayout
+	for _, lb := range batches {
+		if !lb.Skip {
+			layouts = append(layouts, lb.toOGC())
+		}
+	}
+	layoutData, err := yaml.Marshal(struct {
+		Layouts []OGCLayout `yaml:"layouts"`
+	}{
+		Layouts: layouts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal layouts YAML: %w", err)
+	}
+	p.logger.Logf("Import layouts into ogc")
+	proc, err := p.ogcRun(ctx, []string{"layout", "import"}, true)
+	if err != nil {
+		return fmt.Errorf("failed to run ogc import: %w", err)
+	}
+	_, err = proc.Stdin.Write(layoutData)
+	if err != nil {
+		_ = proc.Stdin.Close()
+		_ = proc.Kill()
+		<-proc.Wait()
+		return fmt.Errorf("failed to write layouts to stdin: %w", err)
+	}
+	_ = proc.Stdin.Close()
+	ps := <-proc.Wait()
+	if ps.ExitCode() != 0 {
+		return fmt.Errorf("failed to run ogc import: docker run exited with code: %d", ps.ExitCode())
+	}
+	return nil
+}
+
+// ogcUp brings up all the instances.
+func (p *ogcProvisioner) ogcUp(ctx context.Context) ([]byte, error) {
+	p.logger.Logf("Bring up instances through ogc")
+	var output bytes.Buffer
+	proc, err := p.ogcRun(ctx, []string{"up", LayoutIntegrationTag}, false, process.WithCmdOptions(attachOut(&output), attachErr(&output)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ogc up: %w", err)
+	}
+	ps := <-proc.Wait()
+	if ps.ExitCode() != 0 {
+		// print the output so its clear what went wrong
+		fmt.Fprintf(os.Stdout, "%s\n", output.Bytes())
+		return nil, fmt.Errorf("failed to run ogc up: docker run exited with code: %d", ps.ExitCode())
+	}
+	return output.Bytes(), nil
+}
+
+// ogcDown brings down all the instances.
+func (p *ogcProvisioner) ogcDown(ctx context.Context) error {
+	p.logger.Logf("Bring down instances through ogc")
+	var output bytes.Buffer
+	proc, err := p.ogcRun(ctx, []string{"down", LayoutIntegrationTag}, false, process.WithCmdOptions(attachOut(&output), attachErr(&output)))
+	if err != nil {
+		return fmt.Errorf("failed to run ogc down: %w", err)
+	}
+	ps := <-proc.Wait()
+	if ps.ExitCode() != 0 {
+		// print the output so its clear what went wrong
+		fmt.Fprintf(os.Stdout, "%s\n", output.Bytes())
+		return fmt.Errorf("failed to run ogc down: docker run exited with code: %d", ps.ExitCode())
+	}
+	return nil
+}
+
+// ogcMachines lists all the instances.
+func (p *ogcProvisioner) ogcMachines(ctx context.Context) ([]OGCMachine, error) {
+	var out bytes.Buffer
+	proc, err := p.ogcRun(ctx, []string{"ls", "--as-yaml"}, false, process.WithCmdOptions(attachOut(&out)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ogc ls: %w", err)
+	}
+	ps := <-proc.Wait()
+	if ps.ExitCode() != 0 {
+		return nil, fmt.Errorf("failed to run ogc ls: docker run exited with code: %d", ps.ExitCode())
+	}
+	var machines []OGCMachine
+	err = yaml.Unmarshal(out.Bytes(), &machines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ogc ls output: %w", err)
+	}
+	return machines, nil
+}
+
+func (p *ogcProvisioner) ogcRun(ctx context.Context, args []string, interactive bool, processOpts ...process.StartOption) (*process.Info, error) {
+	wd, err := getWorkDir()
+	if err != nil {
+		return nil, err
+	}
+	runArgs := []string{"run"}
+	if interactive {
+		runArgs = append(runArgs, "-i")
+	}
+	runArgs = append(runArgs, "--rm")
+	if p.cfg.GCE != nil {
+		tokenName := filepath.Base(p.cfg.GCE.ServiceTokenPath)
+		clientEmail, err := p.cfg.GCE.ClientEmail()
+		if err != nil {
+			return nil, err
+		}
+		projectID, err := p.cfg.GCE.ProjectID()
+		if err != nil {
+			return nil, err
+		}
+		runArgs = append(runArgs,
+			"-e",
+			fmt.Sprintf("GOOGLE_APPLICATION_SERVICE_ACCOUNT=%s", clientEmail),
+			"-e",
+			fmt.Sprintf("GOOGLE_APPLICATION_CREDENTIALS=/root/%s", tokenName),
+			"-e",
+			fmt.Sprintf("GOOGLE_PROJECT=%s", projectID),
+			"-e",
+			fmt.Sprintf("GOOGLE_DATACENTER=%s", p.cfg.GCE.Datacenter),
+			"-v",
+			fmt.Sprintf("%s:/root/%s", p.cfg.GCE.ServiceTokenPath, tokenName),
+		)
+	}
+	if p.cfg.AWS != nil {
+		runArgs = append(runArgs,
+			"-e",
+			fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", p.cfg.AWS.AccessKeyID),
+			"-e",
+			fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", p.cfg.AWS.SecretAccessKey),
+			"-e",
+			fmt.Sprintf("AWS_REGION=%s", p.cfg.AWS.Region),
+		)
+	}
+	runArgs = append(runArgs,
+		"-v",
+		fmt.Sprintf("%s:%s", wd, wd),
+		"-w",
+		wd,
+		"docker.io/gorambo/ogc:blake", // switch back to :latest when ready
+		"--",
+		"ogc",
+		"-v",
+	)
+	runArgs = append(runArgs, args...)
+	opts := []process.StartOption{process.WithContext(ctx), process.WithArgs(runArgs)}
+	opts = append(opts, processOpts...)
+	return process.Start("docker", opts...)
+}
@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runner
+
+import (
+	"context"
+)
+
+const (
+	// OGCProvisioner provisions the instances on the cloud providers through OGC.
+	OGCProvisioner = "ogc"
+
+	// VagrantProvisioner provisions the instances as virtual machines on the local host through Vagrant.
+	VagrantProvisioner = "vagrant"
+)
+
+// Instance is a short-lived machine provisioned to run a batch of tests.
+type Instance struct {
+	// ID is the unique ID of the instance for the provisioner.
+	ID string
+	// Name is the name of the instance.
+	Name string
+	// IP is the IP address used to connect to the instance over SSH.
+	IP string
+	// Port is the port used to connect to the instance over SSH.
+	Port int
+	// Username is the user used to connect to the instance over SSH.
+	Username string
+	// RemotePath is the directory on the instance where the tests are run.
+	RemotePath string
+	// BatchID is the ID of the layout batch the instance runs.
+	BatchID string
+}
+
+// InstanceProvisioner provisions the instances running the batches of tests and destroys them.
+type InstanceProvisioner interface {
+	// Name returns the name of the provisioner.
+	Name() string
+	// Supports returns true when the provisioner can provision an instance for the layout.
+	Supports(layout LayoutOS) bool
+	// Prepare prepares the provisioner, the SSH keys in cacheDir are authorized on the instances.
+	Prepare(ctx context.Context, cacheDir string) error
+	// Provision brings up an instance for each batch.
+	Provision(ctx context.Context, batches []LayoutBatch) ([]Instance, error)
+	// Clean destroys all the instances brought up by the provisioner, including the ones of previous runs.
+	Clean(ctx context.Context) error
+}
@@ -12,6 +12,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -20,7 +21,6 @@ import (
 	"github.com/rs/xid"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/sync/errgroup"
-	"gopkg.in/yaml.v2"
 	"k8s.io/utils/strings/slices"
 
 	"github.com/elastic/elastic-agent/pkg/core/process"
@@ -81,6 +81,7 @@ type Result struct {
 type Runner struct {
 	cfg            Config
 	logger         Logger
+	provisioner    InstanceProvisioner
 	batches        []LayoutBatch
 	batchToCloud   map[string]*essCloudResponse
 	batchToCloudMx sync.RWMutex
@@ -92,9 +93,19 @@ func NewRunner(cfg Config, batches ...define.Batch) (*Runner, error) {
 	if err != nil {
 		return nil, err
 	}
+	logger := &runnerLogger{
+		writer:    os.Stdout,
+		timestamp: cfg.Timestamp,
+	}
+	var provisioner InstanceProvisioner
+	if cfg.Provisioner == VagrantProvisioner {
+		provisioner = newVagrantProvisioner(logger)
+	} else {
+		provisioner = newOGCProvisioner(cfg, logger)
+	}
 	var layoutBatches []LayoutBatch
 	for _, b := range batches {
-		lbs, err := createBatches(b, cfg.Matrix)
+		lbs, err := createBatches(b, cfg.Matrix, provisioner)
 		if err != nil {
 			return nil, err
 		}
@@ -106,13 +117,10 @@ func NewRunner(cfg Config, batches ...define.Batch) (*Runner, error) {
 			return nil, err
 		}
 	}
-	logger := &runnerLogger{
-		writer:    os.Stdout,
-		timestamp: cfg.Timestamp,
-	}
 	return &Runner{
 		cfg:          cfg,
 		logger:       logger,
+		provisioner:  provisioner,
 		batches:      layoutBatches,
 		batchToCloud: make(map[string]*essCloudResponse),
 	}, nil
@@ -149,39 +157,25 @@ func (r *Runner) Run(ctx context.Context) (Result, error) {
 		r.cleanupCloud()
 	}()
 
-	// import the calculated layouts
-	importCtx, importCancel := context.WithTimeout(ctx, 30*time.Second)
-	defer importCancel()
-	err = r.ogcImport(importCtx)
-	if err != nil {
-		return Result{}, err
-	}
-
 	// bring up all the instances
-	upCtx, upCancel := context.WithTimeout(ctx, 30*time.Minute)
-	defer upCancel()
-	upOutput, err := r.ogcUp(upCtx)
-	if err != nil {
-		return Result{}, err
+	var batches []LayoutBatch
+	for _, lb := range r.batches {
+		if !lb.Skip {
+			batches = append(batches, lb)
+		}
 	}
+	r.logger.Logf("Provisioning the instances through %s", r.provisioner.Name())
+	instances, err := r.provisioner.Provision(ctx, batches)
 	defer func() {
-		// always clean
+		// always clean, even the instances of a partial provisioning
 		_ = r.Clean()
 	}()
-
-	// fetch the machines and run the batches on the machine
-	machines, err := r.ogcMachines(ctx)
 	if err != nil {
 		return Result{}, err
 	}
-	if len(machines) == 0 {
-		// print the output so its clear what went wrong
-		// without this it's unclear where OGC went wrong it
-		// doesn't do a great job of reporting a clean error
-		fmt.Fprintf(os.Stdout, "%s\n", upOutput)
-		return Result{}, fmt.Errorf("ogc didn't create any machines")
-	}
-	results, err := r.runMachines(ctx, sshAuth, repoArchive, machines)
+
+	// run the batches on the instances
+	results, err := r.runInstances(ctx, sshAuth, repoArchive, instances)
 	if err != nil {
 		return Result{}, err
 	}
@@ -194,20 +188,20 @@ func (r *Runner) Run(ctx context.Context) (Result, error) {
 func (r *Runner) Clean() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
-	return r.ogcDown(ctx)
+	return r.provisioner.Clean(ctx)
 }
 
-// runMachines runs the batch on each machine in parallel.
-func (r *Runner) runMachines(ctx context.Context, sshAuth ssh.AuthMethod, repoArchive string, machines []OGCMachine) (map[string]OSRunnerResult, error) {
+// runInstances runs the batch on each instance in parallel.
+func (r *Runner) runInstances(ctx context.Context, sshAuth ssh.AuthMethod, repoArchive string, instances []Instance) (map[string]OSRunnerResult, error) {
 	g, ctx := errgroup.WithContext(ctx)
 	results := make(map[string]OSRunnerResult)
 	var resultsMx sync.Mutex
-	for _, m := range machines {
-		func(m OGCMachine) {
+	for _, instance := range instances {
+		func(instance Instance) {
 			g.Go(func() error {
-				batch, ok := findLayoutBatchByID(m.Layout.Name, r.batches)
+				batch, ok := findLayoutBatchByID(instance.BatchID, r.batches)
 				if !ok {
-					return fmt.Errorf("unable to find layout batch with ID: %s", m.Layout.Name)
+					return fmt.Errorf("unable to find layout batch with ID: %s", instance.BatchID)
 				}
 				loggerPrefix := fmt.Sprintf(
 					"%s/%s/%s/%s[%s]",
@@ -218,9 +212,9 @@ func (r *Runner) runMachines(ctx context.Context, sshAuth ssh.AuthMethod, repoAr
 					batch.ID[len(batch.ID)-5:len(batch.ID)-1],
 				)
 				logger := &batchLogger{wrapped: r.logger, prefix: loggerPrefix}
-				result, err := r.runMachine(ctx, sshAuth, logger, repoArchive, batch, m)
+				result, err := r.runInstance(ctx, sshAuth, logger, repoArchive, batch, instance)
 				if err != nil {
-					logger.Logf("Failed for instance %s: %s\n", m.PublicIP, err)
+					logger.Logf("Failed for instance %s: %s\n", instance.IP, err)
 					return err
 				}
 				resultsMx.Lock()
@@ -228,7 +222,7 @@ func (r *Runner) runMachines(ctx context.Context, sshAuth ssh.AuthMethod, repoAr
 				resultsMx.Unlock()
 				return nil
 			})
-		}(m)
+		}(instance)
 	}
 	err := g.Wait()
 	if err != nil {
@@ -237,20 +231,20 @@ func (r *Runner) runMachines(ctx context.Context, sshAuth ssh.AuthMethod, repoAr
 	return results, nil
 }
 
-// runMachine runs the batch on the machine.
-func (r *Runner) runMachine(ctx context.Context, sshAuth ssh.AuthMethod, logger Logger, repoArchive string, batch LayoutBatch, machine OGCMachine) (OSRunnerResult, error) {
+// runInstance runs the batch on the instance.
+func (r *Runner) runInstance(ctx context.Context, sshAuth ssh.AuthMethod, logger Logger, repoArchive string, batch LayoutBatch, instance Instance) (OSRunnerResult, error) {
 	sshPrivateKeyPath, err := filepath.Abs(filepath.Join(".ogc-cache", "id_rsa"))
 	if err != nil {
 		return OSRunnerResult{}, fmt.Errorf("failed to determine OGC SSH private key path: %w", err)
 	}
 
-	logger.Logf("Starting SSH; connect with `ssh -i %s %s@%s`", sshPrivateKeyPath, machine.Layout.Username, machine.PublicIP)
+	logger.Logf("Starting SSH; connect with `ssh -i %s -p %d %s@%s`", sshPrivateKeyPath, instance.Port, instance.Username, instance.IP)
 	connectCtx, connectCancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer connectCancel()
-	client, err := sshConnect(connectCtx, machine.PublicIP, machine.Layout.Username, sshAuth)
+	client, err := sshConnect(connectCtx, instance.IP, instance.Port, instance.Username, sshAuth)
 	if err != nil {
-		logger.Logf("Failed to connect to instance %s: %s", machine.PublicIP, err)
-		return OSRunnerResult{}, fmt.Errorf("failed to connect to instance %s: %w", machine.InstanceName, err)
+		logger.Logf("Failed to connect to instance %s: %s", instance.IP, err)
+		return OSRunnerResult{}, fmt.Errorf("failed to connect to instance %s: %w", instance.Name, err)
 	}
 	defer client.Close()
 	logger.Logf("Connected over SSH")
@@ -260,7 +254,7 @@ func (r *Runner) runMachine(ctx context.Context, sshAuth ssh.AuthMethod, logger
 	err = batch.LayoutOS.Runner.Prepare(ctx, client, logger, batch.LayoutOS.OS.Arch, r.cfg.GOVersion, repoArchive, r.getBuildPath(batch))
 	if err != nil {
 		logger.Logf("Failed to prepare instance: %s", err)
-		return OSRunnerResult{}, fmt.Errorf("failed to prepare instance %s: %w", machine.InstanceName, err)
+		return OSRunnerResult{}, fmt.Errorf("failed to prepare instance %s: %w", instance.Name, err)
 	}
 
 	// ensure that we have all the requirements for the stack if required
@@ -293,7 +287,7 @@ func (r *Runner) runMachine(ctx context.Context, sshAuth ssh.AuthMethod, logger
 	result, err := batch.LayoutOS.Runner.Run(ctx, r.cfg.VerboseMode, client, logger, r.cfg.AgentVersion, prefix, batch.Batch, env)
 	if err != nil {
 		logger.Logf("Failed to execute tests on instance: %s", err)
-		return OSRunnerResult{}, fmt.Errorf("failed to execute tests on instance %s: %w", machine.InstanceName, err)
+		return OSRunnerResult{}, fmt.Errorf("failed to execute tests on instance %s: %w", instance.Name, err)
 	}
 	return result, nil
 }
@@ -339,9 +333,9 @@ func (r *Runner) getBuildPath(b LayoutBatch) string {
 
 // prepare prepares for the runner to run.
 //
-// Creates the SSH keys to use, creates the archive of the repo and pulls the latest container for OGC.
+// Creates the SSH keys to use, creates the archive of the repo and prepares the provisioner.
 func (r *Runner) prepare(ctx context.Context) (ssh.AuthMethod, string, error) {
-	wd, err := r.getWorkDir()
+	wd, err := getWorkDir()
 	if err != nil {
 		return nil, "", err
 	}
@@ -357,17 +351,14 @@ func (r *Runner) prepare(ctx context.Context) (ssh.AuthMethod, string, error) {
 		return nil, "", err
 	}
 
-	var auth ssh.AuthMethod
+	// the provisioner authorizes the SSH keys on the instances
+	auth, err := r.createSSHKey(cacheDir)
+	if err != nil {
+		return nil, "", err
+	}
+
 	var repoArchive string
 	g, gCtx := errgroup.WithContext(ctx)
-	g.Go(func() error {
-		a, err := r.createSSHKey(cacheDir)
-		if err != nil {
-			return err
-		}
-		auth = a
-		return nil
-	})
 	g.Go(func() error {
 		repo, err := r.createRepoArchive(ctx, r.cfg.RepoDir, cacheDir)
 		if err != nil {
@@ -377,7 +368,7 @@ func (r *Runner) prepare(ctx context.Context) (ssh.AuthMethod, string, error) {
 		return nil
 	})
 	g.Go(func() error {
-		return r.ogcPull(gCtx)
+		return r.provisioner.Prepare(gCtx, cacheDir)
 	})
 	err = g.Wait()
 	if err != nil {
@@ -450,24 +441,6 @@ func (r *Runner) createRepoArchive(ctx context.Context, repoDir string, dir stri
 	return zipPath, nil
 }
 
-// ogcPull pulls the latest ogc version.
-func (r *Runner) ogcPull(ctx context.Context) error {
-	args := []string{
-		"pull",
-		"docker.io/gorambo/ogc:blake", // switch back to :latest when ready
-	}
-	r.logger.Logf("Pulling latest ogc image")
-	proc, err := process.Start("docker", process.WithContext(ctx), process.WithArgs(args))
-	if err != nil {
-		return fmt.Errorf("failed to run docker pull: %w", err)
-	}
-	ps := <-proc.Wait()
-	if ps.ExitCode() != 0 {
-		return fmt.Errorf("failed to run ogc import: docker run exited with code: %d", ps.ExitCode())
-	}
-	return nil
-}
-
 // setupCloud creates the clouds required for the tests to run
 func (r *Runner) setupCloud(ctx context.Context) error {
 	var versions []string
@@ -484,19 +457,18 @@ func (r *Runner) setupCloud(ctx context.Context) error {
 			batchToVersion[lb.ID] = lb.Batch.Stack.Version
 		}
 	}
-	// use GCE email to add uniqueness to deployment names
-	email, err := r.cfg.GCE.ClientEmail()
+	// use GCE email or the local user to add uniqueness to deployment names
+	owner, err := r.deploymentOwner()
 	if err != nil {
 		return err
 	}
-	emailParts := strings.Split(email, "@")
 	essClient := ess.NewClient(ess.Config{
 		ApiKey: r.cfg.ESS.APIKey,
 	})
 	r.batchToCloudMx.Lock()
 	defer r.batchToCloudMx.Unlock()
 	for _, version := range versions {
-		name := fmt.Sprintf("at-%s-%s", strings.Replace(emailParts[0], ".", "-", -1), strings.Replace(version, ".", "", -1))
+		name := fmt.Sprintf("at-%s-%s", owner, strings.Replace(version, ".", "", -1))
 		r.logger.Logf("Creating ESS cloud %s (%s)", version, name)
 		resp, err := essClient.CreateDeployment(ctx, ess.CreateDeploymentRequest{
 			Name:    name,
@@ -602,141 +574,26 @@ func (r *Runner) getCloudForBatchID(id string) (chan *ess.CreateDeploymentRespon
 	return subCh, nil
 }
 
-// ogcImport imports all the required batches into OGC.
-func (r *Runner) ogcImport(ctx context.Context) error {
-	var layouts []OGCLayout
-	for _, lb := range r.batches {
-		if !lb.Skip {
-			layouts = append(layouts, lb.toOGC())
+// deploymentOwner returns the name of the owner of the ESS deployments.
+func (r *Runner) deploymentOwner() (string, error) {
+	var name string
+	if r.cfg.GCE != nil {
+		email, err := r.cfg.GCE.ClientEmail()
+		if err != nil {
+			return "", err
 		}
+		name = strings.Split(email, "@")[0]
+	} else {
+		u, err := user.Current()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the current user: %w", err)
+		}
+		name = u.Username
 	}
-	layoutData, err := yaml.Marshal(struct {
-		Layouts []OGCLayout `yaml:"layouts"`
-	}{
-		Layouts: layouts,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to marshal layouts YAML: %w", err)
-	}
-	r.logger.Logf("Import layouts into ogc")
-	proc, err := r.ogcRun(ctx, []string{"layout", "import"}, true)
-	if err != nil {
-		return fmt.Errorf("failed to run ogc import: %w", err)
-	}
-	_, err = proc.Stdin.Write(layoutData)
-	if err != nil {
-		_ = proc.Stdin.Close()
-		_ = proc.Kill()
-		<-proc.Wait()
-		return fmt.Errorf("failed to write layouts to stdin: %w", err)
-	}
-	_ = proc.Stdin.Close()
-	ps := <-proc.Wait()
-	if ps.ExitCode() != 0 {
-		return fmt.Errorf("failed to run ogc import: docker run exited with code: %d", ps.ExitCode())
-	}
-	return nil
-}
-
-// ogcUp brings up all the instances.
-func (r *Runner) ogcUp(ctx context.Context) ([]byte, error) {
-	r.logger.Logf("Bring up instances through ogc")
-	var output bytes.Buffer
-	proc, err := r.ogcRun(ctx, []string{"up", LayoutIntegrationTag}, false, process.WithCmdOptions(attachOut(&output), attachErr(&output)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to run ogc up: %w", err)
-	}
-	ps := <-proc.Wait()
-	if ps.ExitCode() != 0 {
-		// print the output so its clear what went wrong
-		fmt.Fprintf(os.Stdout, "%s\n", output.Bytes())
-		return nil, fmt.Errorf("failed to run ogc up: docker run exited with code: %d", ps.ExitCode())
-	}
-	return output.Bytes(), nil
-}
-
-// ogcDown brings down all the instances.
-func (r *Runner) ogcDown(ctx context.Context) error {
-	r.logger.Logf("Bring down instances through ogc")
-	var output bytes.Buffer
-	proc, err := r.ogcRun(ctx, []string{"down", LayoutIntegrationTag}, false, process.WithCmdOptions(attachOut(&output), attachErr(&output)))
-	if err != nil {
-		return fmt.Errorf("failed to run ogc down: %w", err)
-	}
-	ps := <-proc.Wait()
-	if ps.ExitCode() != 0 {
-		// print the output so its clear what went wrong
-		fmt.Fprintf(os.Stdout, "%s\n", output.Bytes())
-		return fmt.Errorf("failed to run ogc down: docker run exited with code: %d", ps.ExitCode())
-	}
-	return nil
-}
-
-// ogcMachines lists all the instances.
-func (r *Runner) ogcMachines(ctx context.Context) ([]OGCMachine, error) {
-	var out bytes.Buffer
-	proc, err := r.ogcRun(ctx, []string{"ls", "--as-yaml"}, false, process.WithCmdOptions(attachOut(&out)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to run ogc ls: %w", err)
-	}
-	ps := <-proc.Wait()
-	if ps.ExitCode() != 0 {
-		return nil, fmt.Errorf("failed to run ogc ls: docker run exited with code: %d", ps.ExitCode())
-	}
-	var machines []OGCMachine
-	err = yaml.Unmarshal(out.Bytes(), &machines)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse ogc ls output: %w", err)
-	}
-	return machines, nil
+	return strings.ToLower(strings.NewReplacer(".", "-", "\\", "-", " ", "-").Replace(name)), nil
 }
 
-func (r *Runner) ogcRun(ctx context.Context, args []string, interactive bool, processOpts ...process.StartOption) (*process.Info, error) {
-	wd, err := r.getWorkDir()
-	if err != nil {
-		return nil, err
-	}
-	tokenName := filepath.Base(r.cfg.GCE.ServiceTokenPath)
-	clientEmail, err := r.cfg.GCE.ClientEmail()
-	if err != nil {
-		return nil, err
-	}
-	projectID, err := r.cfg.GCE.ProjectID()
-	if err != nil {
-		return nil, err
-	}
-	runArgs := []string{"run"}
-	if interactive {
-		runArgs = append(runArgs, "-i")
-	}
-	runArgs = append(runArgs,
-		"--rm",
-		"-e",
-		fmt.Sprintf("GOOGLE_APPLICATION_SERVICE_ACCOUNT=%s", clientEmail),
-		"-e",
-		fmt.Sprintf("GOOGLE_APPLICATION_CREDENTIALS=/root/%s", tokenName),
-		"-e",
-		fmt.Sprintf("GOOGLE_PROJECT=%s", projectID),
-		"-e",
-		fmt.Sprintf("GOOGLE_DATACENTER=%s", r.cfg.GCE.Datacenter),
-		"-v",
-		fmt.Sprintf("%s:/root/%s", r.cfg.GCE.ServiceTokenPath, tokenName),
-		"-v",
-		fmt.Sprintf("%s:%s", wd, wd),
-		"-w",
-		wd,
-		"docker.io/gorambo/ogc:blake", // switch back to :latest when ready
-		"--",
-		"ogc",
-		"-v",
-	)
-	runArgs = append(runArgs, args...)
-	opts := []process.StartOption{process.WithContext(ctx), process.WithArgs(runArgs)}
-	opts = append(opts, processOpts...)
-	return process.Start("docker", opts...)
-}
-
-func (r *Runner) getWorkDir() (string, error) {
+func getWorkDir() (string, error) {
 	wd, err := os.Getwd()
 	if err != nil {
 		return "", fmt.Errorf("failed to get work directory: %w", err)
@@ -857,9 +714,9 @@ func findLayoutBatchByID(id string, batches []LayoutBatch) (LayoutBatch, bool) {
 	return LayoutBatch{}, false
 }
 
-func createBatches(batch define.Batch, matrix bool) ([]LayoutBatch, error) {
+func createBatches(batch define.Batch, matrix bool, provisioner InstanceProvisioner) ([]LayoutBatch, error) {
 	var batches []LayoutBatch
-	specifics, err := getSupported(batch.OS)
+	specifics, err := getSupported(batch.OS, provisioner)
 	if errors.Is(err, ErrOSNotSupported) {
 		var s LayoutOS
 		s.OS.Type = batch.OS.Type
@@ -17,6 +17,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -57,7 +58,7 @@ func newSSHPublicKey(pk *rsa.PublicKey) ([]byte, error) {
 }
 
 // sshConnect keeps trying to make the SSH connection up until the context is cancelled
-func sshConnect(ctx context.Context, ip string, username string, sshAuth ssh.AuthMethod) (*ssh.Client, error) {
+func sshConnect(ctx context.Context, ip string, port int, username string, sshAuth ssh.AuthMethod) (*ssh.Client, error) {
 	var lastErr error
 	for {
 		if ctx.Err() != nil {
@@ -72,7 +73,7 @@ func sshConnect(ctx context.Context, ip string, username string, sshAuth ssh.Aut
 			Auth:            []ssh.AuthMethod{sshAuth},
 			Timeout:         30 * time.Second,
 		}
-		client, err := ssh.Dial("tcp", net.JoinHostPort(ip, "22"), config)
+		client, err := ssh.Dial("tcp", net.JoinHostPort(ip, strconv.Itoa(port)), config)
 		if err == nil {
 			return client, nil
 		}
@@ -15,8 +15,17 @@ const (
 	// Google is for the Google Cloud Platform (GCP)
 	Google = "google"
 
+	// Amazon is for Amazon Web Services (AWS)
+	Amazon = "aws"
+
 	// Ubuntu is a Linux distro.
 	Ubuntu = "ubuntu"
+
+	// WindowsServer is the Windows distro for servers.
+	WindowsServer = "windows-server"
+
+	// MacOS is the distro of Darwin.
+	MacOS = "macos"
 )
 
 var (
@@ -34,6 +43,10 @@ type LayoutOS struct {
 	Username     string
 	RemotePath   string
 	Runner       OSRunner
+
+	// VagrantBox is the box of the virtual machine provisioned through Vagrant, the layout
+	// can not be provisioned through Vagrant when it is empty.
+	VagrantBox string
 }
 
 // Supported defines the set of supported OS's the runner currently supports.
@@ -55,6 +68,7 @@ var supported = []LayoutOS{
 		Username:     "ubuntu",
 		RemotePath:   "/home/ubuntu/agent",
 		Runner:       DebianRunner{},
+		VagrantBox:   "ubuntu/jammy64",
 	},
 	{
 		OS: define.OS{
@@ -69,6 +83,7 @@ var supported = []LayoutOS{
 		Username:     "ubuntu",
 		RemotePath:   "/home/ubuntu/agent",
 		Runner:       DebianRunner{},
+		VagrantBox:   "ubuntu/focal64",
 	},
 	{
 		OS: define.OS{
@@ -83,6 +98,7 @@ var supported = []LayoutOS{
 		Username:     "ubuntu",
 		RemotePath:   "/home/ubuntu/agent",
 		Runner:       DebianRunner{},
+		VagrantBox:   "bento/ubuntu-22.04-arm64",
 	},
 	{
 		OS: define.OS{
@@ -98,13 +114,85 @@ var supported = []LayoutOS{
 		RemotePath:   "/home/ubuntu/agent",
 		Runner:       DebianRunner{},
 	},
+	{
+		OS: define.OS{
+			Type:    define.Linux,
+			Arch:    define.ARM64,
+			Distro:  Ubuntu,
+			Version: "22.04",
+		},
+		Provider:     Amazon,
+		InstanceSize: "t4g.large", // 2 arm64 cpus
+		RunsOn:       "ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-arm64-server-*",
+		Username:     "ubuntu",
+		RemotePath:   "/home/ubuntu/agent",
+		Runner:       DebianRunner{},
+	},
+	{
+		OS: define.OS{
+			Type:    define.Windows,
+			Arch:    define.AMD64,
+			Distro:  WindowsServer,
+			Version: "2022",
+		},
+		Provider:     Google,
+		InstanceSize: "e2-standard-4", // 4 amd64 cpus
+		RunsOn:       "windows-2022",
+		Username:     "windows",
+		RemotePath:   "C:\\Users\\windows\\agent",
+		Runner:       WindowsRunner{},
+		VagrantBox:   "gusztavvargadr/windows-server-2022-standard",
+	},
+	{
+		OS: define.OS{
+			Type:    define.Windows,
+			Arch:    define.AMD64,
+			Distro:  WindowsServer,
+			Version: "2022",
+		},
+		Provider:     Amazon,
+		InstanceSize: "t3.xlarge", // 4 amd64 cpus
+		RunsOn:       "Windows_Server-2022-English-Full-Base-*",
+		Username:     "Administrator",
+		RemotePath:   "C:\\Users\\Administrator\\agent",
+		Runner:       WindowsRunner{},
+	},
+	{
+		OS: define.OS{
+			Type:    define.Darwin,
+			Arch:    define.ARM64,
+			Distro:  MacOS,
+			Version: "13",
+		},
+		Provider:     Amazon,
+		InstanceSize: "mac2.metal", // dedicated Apple silicon host
+		RunsOn:       "amzn-ec2-macos-13.*-arm64",
+		Username:     "ec2-user",
+		RemotePath:   "/Users/ec2-user/agent",
+		Runner:       DarwinRunner{},
+	},
+	{
+		OS: define.OS{
+			Type:    define.Darwin,
+			Arch:    define.AMD64,
+			Distro:  MacOS,
+			Version: "13",
+		},
+		Provider:     Amazon,
+		InstanceSize: "mac1.metal", // dedicated Intel host
+		RunsOn:       "amzn-ec2-macos-13.*",
+		Username:     "ec2-user",
+		RemotePath:   "/Users/ec2-user/agent",
+		Runner:       DarwinRunner{},
+	},
 }
 
-// getSupported returns all the supported layout based on the provided OS profile.
-func getSupported(os define.OS) ([]LayoutOS, error) {
+// getSupported returns all the supported layout based on the provided OS profile that the provisioner
+// can provision.
+func getSupported(os define.OS, provisioner InstanceProvisioner) ([]LayoutOS, error) {
 	var match []LayoutOS
 	for _, s := range supported {
-		if osMatch(s.OS, os) {
+		if osMatch(s.OS, os) && provisioner.Supports(s) {
 			match = append(match, s)
 		}
 	}
@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/testing/define"
+)
+
+func TestGetSupported(t *testing.T) {
+	gce := newOGCProvisioner(Config{GCE: &GCEConfig{}}, nil)
+	aws := newOGCProvisioner(Config{AWS: &AWSConfig{}}, nil)
+	vagrant := newVagrantProvisioner(nil)
+
+	windows := define.OS{Type: define.Windows, Arch: define.AMD64}
+	darwin := define.OS{Type: define.Darwin, Arch: define.ARM64}
+
+	layouts, err := getSupported(windows, gce)
+	require.NoError(t, err)
+	require.Len(t, layouts, 1)
+	assert.Equal(t, Google, layouts[0].Provider)
+
+	layouts, err = getSupported(windows, aws)
+	require.NoError(t, err)
+	require.Len(t, layouts, 1)
+	assert.Equal(t, Amazon, layouts[0].Provider)
+
+	layouts, err = getSupported(darwin, aws)
+	require.NoError(t, err)
+	require.Len(t, layouts, 1)
+	assert.IsType(t, DarwinRunner{}, layouts[0].Runner)
+
+	_, err = getSupported(darwin, gce)
+	assert.ErrorIs(t, err, ErrOSNotSupported)
+	_, err = getSupported(darwin, vagrant)
+	assert.ErrorIs(t, err, ErrOSNotSupported)
+
+	layouts, err = getSupported(define.OS{Type: define.Linux, Arch: define.AMD64}, vagrant)
+	require.NoError(t, err)
+	for _, layout := range layouts {
+		assert.NotEmpty(t, layout.VagrantBox)
+	}
+}
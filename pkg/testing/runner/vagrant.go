@@ -0,0 +1,233 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/testing/define"
+)
+
+const vagrantUsername = "vagrant"
+
+// vagrantfileTemplate is the Vagrantfile of the virtual machine of a batch. The SSH server of the virtual
+// machine is forwarded to a port of the local host and authorizes the SSH key of the runner.
+var vagrantfileTemplate = template.Must(template.New("Vagrantfile").Parse(`# generated by the integration testing framework
+Vagrant.configure("2") do |config|
+  config.vm.box = "{{ .Box }}"
+  config.vm.boot_timeout = 1200
+  config.vm.network "forwarded_port", guest: 22, host: {{ .Port }}, host_ip: "127.0.0.1", id: "agent-ssh"
+{{- if .Windows }}
+  config.vm.communicator = "winrm"
+  config.vm.provision "shell", inline: <<-SHELL
+    Add-WindowsCapability -Online -Name OpenSSH.Server~~~~0.0.1.0
+    Set-Service -Name sshd -StartupType Automatic
+    Start-Service sshd
+    Set-Content -Path "$env:ProgramData\\ssh\\administrators_authorized_keys" -Value "{{ .PublicKey }}"
+    icacls.exe "$env:ProgramData\\ssh\\administrators_authorized_keys" /inheritance:r /grant "Administrators:F" /grant "SYSTEM:F"
+  SHELL
+{{- else }}
+  config.vm.provision "shell", privileged: false, inline: <<-SHELL
+    mkdir -p ~/.ssh
+    echo "{{ .PublicKey }}" >> ~/.ssh/authorized_keys
+  SHELL
+{{- end }}
+  config.vm.provider "virtualbox" do |vb|
+    vb.cpus = 2
+    vb.memory = 4096
+  end
+end
+`))
+
+// vagrantProvisioner provisions the instances as virtual machines on the local host through Vagrant, it
+// is the fallback for the contributors without access to the cloud providers.
+type vagrantProvisioner struct {
+	logger    Logger
+	publicKey string
+}
+
+// newVagrantProvisioner creates the provisioner of the instances through Vagrant.
+func newVagrantProvisioner(logger Logger) *vagrantProvisioner {
+	return &vagrantProvisioner{
+		logger: logger,
+	}
+}
+
+// Name returns the name of the provisioner.
+func (p *vagrantProvisioner) Name() string {
+	return VagrantProvisioner
+}
+
+// Supports returns true when the layout has a Vagrant box.
+func (p *vagrantProvisioner) Supports(layout LayoutOS) bool {
+	return layout.VagrantBox != ""
+}
+
+// Prepare ensures that Vagrant is installed and reads the SSH public key to authorize on the virtual machines.
+func (p *vagrantProvisioner) Prepare(_ context.Context, cacheDir string) error {
+	if _, err := exec.LookPath("vagrant"); err != nil {
+		return fmt.Errorf("vagrant is required to provision the instances on the local host: %w", err)
+	}
+	publicKey, err := os.ReadFile(filepath.Join(cacheDir, "id_rsa.pub"))
+	if err != nil {
+		return fmt.Errorf("failed to read ssh public key: %w", err)
+	}
+	p.publicKey = strings.TrimSpace(string(publicKey))
+	return nil
+}
+
+// Provision brings up a virtual machine for each batch, one after the other to not overload the local host.
+func (p *vagrantProvisioner) Provision(ctx context.Context, batches []LayoutBatch) ([]Instance, error) {
+	dir, err := vagrantDir()
+	if err != nil {
+		return nil, err
+	}
+	var instances []Instance
+	for _, batch := range batches {
+		port, err := freePort()
+		if err != nil {
+			return instances, err
+		}
+		machineDir := filepath.Join(dir, batch.ID)
+		vagrantfile, err := renderVagrantfile(batch.LayoutOS, port, p.publicKey)
+		if err != nil {
+			return instances, err
+		}
+		err = os.MkdirAll(machineDir, 0755)
+		if err != nil {
+			return instances, fmt.Errorf("failed to create %q: %w", machineDir, err)
+		}
+		err = os.WriteFile(filepath.Join(machineDir, "Vagrantfile"), vagrantfile, 0644)
+		if err != nil {
+			return instances, fmt.Errorf("failed to write Vagrantfile: %w", err)
+		}
+
+		p.logger.Logf("Bring up %s virtual machine through vagrant", batch.LayoutOS.VagrantBox)
+		upCtx, upCancel := context.WithTimeout(ctx, 30*time.Minute)
+		err = vagrantRun(upCtx, machineDir, "up")
+		upCancel()
+		if err != nil {
+			return instances, err
+		}
+
+		remotePath := fmt.Sprintf("/home/%s/agent", vagrantUsername)
+		if batch.LayoutOS.OS.Type == define.Windows {
+			remotePath = fmt.Sprintf("C:\\Users\\%s\\agent", vagrantUsername)
+		}
+		instances = append(instances, Instance{
+			ID:         batch.ID,
+			Name:       batch.ID,
+			IP:         "127.0.0.1",
+			Port:       port,
+			Username:   vagrantUsername,
+			RemotePath: remotePath,
+			BatchID:    batch.ID,
+		})
+	}
+	return instances, nil
+}
+
+// Clean destroys all the virtual machines.
+func (p *vagrantProvisioner) Clean(ctx context.Context) error {
+	dir, err := vagrantDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var errs error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		machineDir := filepath.Join(dir, entry.Name())
+		p.logger.Logf("Destroy virtual machine %s through vagrant", entry.Name())
+		err = vagrantRun(ctx, machineDir, "destroy", "-f")
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		_ = os.RemoveAll(machineDir)
+	}
+	return errs
+}
+
+// renderVagrantfile renders the Vagrantfile of the virtual machine of the layout.
+func renderVagrantfile(layout LayoutOS, port int, publicKey string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := vagrantfileTemplate.Execute(&buf, struct {
+		Box       string
+		Port      int
+		PublicKey string
+		Windows   bool
+	}{
+		Box:       layout.VagrantBox,
+		Port:      port,
+		PublicKey: publicKey,
+		Windows:   layout.OS.Type == define.Windows,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render Vagrantfile: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// vagrantDir returns the directory of the Vagrant virtual machines, one sub-directory per batch.
+func vagrantDir() (string, error) {
+	wd, err := getWorkDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wd, ".ogc-cache", "vagrant"), nil
+}
+
+// vagrantRun runs the vagrant command for the virtual machine of the directory.
+func vagrantRun(ctx context.Context, dir string, args ...string) error {
+	var output bytes.Buffer
+	proc, err := process.Start("vagrant",
+		process.WithContext(ctx),
+		process.WithArgs(args),
+		process.WithCmdOptions(attachOut(&output), attachErr(&output), func(c *exec.Cmd) error {
+			c.Dir = dir
+			return nil
+		}))
+	if err != nil {
+		return fmt.Errorf("failed to run vagrant %s: %w", args[0], err)
+	}
+	ps := <-proc.Wait()
+	if ps.ExitCode() != 0 {
+		// print the output so its clear what went wrong
+		fmt.Fprintf(os.Stdout, "%s\n", output.Bytes())
+		return fmt.Errorf("failed to run vagrant %s: exited with code: %d", args[0], ps.ExitCode())
+	}
+	return nil
+}
+
+// freePort returns a free TCP port of the local host.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,36 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/testing/define"
+)
+
+func TestRenderVagrantfile(t *testing.T) {
+	const publicKey = "ssh-rsa AAAAB3NzaC1yc2E agent-testing"
+
+	vagrantfile, err := renderVagrantfile(LayoutOS{
+		OS:         define.OS{Type: define.Linux, Arch: define.AMD64},
+		VagrantBox: "ubuntu/jammy64",
+	}, 52022, publicKey)
+	require.NoError(t, err)
+	assert.Contains(t, string(vagrantfile), `config.vm.box = "ubuntu/jammy64"`)
+	assert.Contains(t, string(vagrantfile), `guest: 22, host: 52022, host_ip: "127.0.0.1"`)
+	assert.Contains(t, string(vagrantfile), `echo "`+publicKey+`" >> ~/.ssh/authorized_keys`)
+	assert.NotContains(t, string(vagrantfile), "winrm")
+
+	vagrantfile, err = renderVagrantfile(LayoutOS{
+		OS:         define.OS{Type: define.Windows, Arch: define.AMD64},
+		VagrantBox: "gusztavvargadr/windows-server-2022-standard",
+	}, 52023, publicKey)
+	require.NoError(t, err)
+	assert.Contains(t, string(vagrantfile), `config.vm.communicator = "winrm"`)
+	assert.Contains(t, string(vagrantfile), `administrators_authorized_keys" -Value "`+publicKey+`"`)
+}
@@ -0,0 +1,153 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/elastic/elastic-agent/pkg/testing/define"
+)
+
+// windowsShell runs the PowerShell script read from stdin, whatever the default shell of the OpenSSH server is.
+const windowsShell = "powershell -NoProfile -NonInteractive -Command -"
+
+// windowsEnv sets the environment of the scripts to use the installed golang and mage.
+const windowsEnv = `$ProgressPreference = 'SilentlyContinue'; $ErrorActionPreference = 'Stop'; $env:GOPATH = "$HOME\go"; $env:PATH = "C:\go\bin;$HOME\go\bin;$env:PATH"`
+
+// WindowsRunner is a handler for running tests on Windows
+type WindowsRunner struct{}
+
+// Prepare the test
+func (WindowsRunner) Prepare(ctx context.Context, sshClient *ssh.Client, logger Logger, arch string, goVersion string, repoArchive string, buildPath string) error {
+	// prepare golang
+	logger.Logf("Install golang %s (%s)", goVersion, arch)
+	downloadURL := fmt.Sprintf("https://go.dev/dl/go%s.windows-%s.zip", goVersion, arch)
+	filename := path.Base(downloadURL)
+	stdOut, errOut, err := windowsRunScript(ctx, sshClient, fmt.Sprintf(`Invoke-WebRequest -Uri %s -OutFile %s; Expand-Archive -Path %s -DestinationPath C:\ -Force`, downloadURL, filename, filename))
+	if err != nil {
+		return fmt.Errorf("failed to install go from %s: %w (stdout: %s, stderr: %s)", downloadURL, err, stdOut, errOut)
+	}
+
+	// copy the archive and extract it on the host
+	logger.Logf("Copying repo")
+	destRepoName := filepath.Base(repoArchive)
+	err = sshSCP(sshClient, repoArchive, destRepoName)
+	if err != nil {
+		return fmt.Errorf("failed to SCP repo archive %s: %w", repoArchive, err)
+	}
+	stdOut, errOut, err = windowsRunScript(ctx, sshClient, fmt.Sprintf(`Expand-Archive -Path %s -DestinationPath agent -Force`, destRepoName))
+	if err != nil {
+		return fmt.Errorf("failed to extract %s to agent directory: %w (stdout: %s, stderr: %s)", destRepoName, err, stdOut, errOut)
+	}
+
+	// install mage and prepare for testing, make is not available
+	logger.Logf("Installing mage and running prepareOnRemote")
+	stdOut, errOut, err = windowsRunScript(ctx, sshClient, fmt.Sprintf(`cd agent; go install github.com/magefile/mage@%s; mage integration:prepareOnRemote`, mageVersion))
+	if err != nil {
+		return fmt.Errorf("failed to to install mage and perform prepareOnRemote: %w (stdout: %s, stderr: %s)", err, stdOut, errOut)
+	}
+
+	// place the build for the agent on the host
+	logger.Logf("Copying agent build %s", filepath.Base(buildPath))
+	err = sshSCP(sshClient, buildPath, filepath.Base(buildPath))
+	if err != nil {
+		return fmt.Errorf("failed to SCP build %s: %w", filepath.Base(buildPath), err)
+	}
+	insideAgentDir := path.Join("agent", filepath.ToSlash(buildPath))
+	stdOut, errOut, err = windowsRunScript(ctx, sshClient, fmt.Sprintf(`New-Item -ItemType Directory -Force -Path %s | Out-Null; Move-Item -Force -Path %s -Destination %s`, path.Dir(insideAgentDir), filepath.Base(buildPath), insideAgentDir))
+	if err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w (stdout: %s, stderr: %s)", filepath.Base(buildPath), insideAgentDir, err, stdOut, errOut)
+	}
+
+	return nil
+}
+
+// Run the test
+func (WindowsRunner) Run(ctx context.Context, verbose bool, sshClient *ssh.Client, logger Logger, agentVersion string, prefix string, batch define.Batch, env map[string]string) (OSRunnerResult, error) {
+	logArg := ""
+	if verbose {
+		logArg = "-v"
+	}
+
+	// the user connected over SSH is an administrator, the tests requiring sudo run the same way
+	var result OSRunnerResult
+	run := func(name string, prefix string, pkgs []define.BatchPackageTests) ([]OSRunnerPackageResult, error) {
+		var tests []string
+		for _, pkg := range pkgs {
+			for _, test := range pkg.Tests {
+				tests = append(tests, fmt.Sprintf("%s:%s", pkg.Name, test))
+			}
+		}
+		if len(tests) == 0 {
+			return nil, nil
+		}
+		vars := map[string]string{
+			"AGENT_VERSION":      agentVersion,
+			"TEST_DEFINE_PREFIX": prefix,
+			"TEST_DEFINE_TESTS":  strings.Join(tests, ","),
+		}
+		for k, v := range env {
+			vars[k] = v
+		}
+		var script strings.Builder
+		script.WriteString(windowsEnv)
+		for k, v := range vars {
+			fmt.Fprintf(&script, "; $env:%s = '%s'", k, strings.ReplaceAll(v, "'", "''"))
+		}
+		fmt.Fprintf(&script, "; cd agent; mage %s integration:testOnRemote\n", logArg)
+		return runTests(ctx, logger, name, prefix, windowsShell, script.String(), sshClient, pkgs, getWindowsRunnerPackageResult)
+	}
+
+	var err error
+	result.Packages, err = run("non-sudo", prefix, batch.Tests)
+	if err != nil {
+		return OSRunnerResult{}, fmt.Errorf("error running non-sudo tests: %w", err)
+	}
+	result.SudoPackages, err = run("sudo", fmt.Sprintf("%s-sudo", prefix), batch.SudoTests)
+	if err != nil {
+		return OSRunnerResult{}, fmt.Errorf("error running sudo tests: %w", err)
+	}
+	return result, nil
+}
+
+func getWindowsRunnerPackageResult(ctx context.Context, c *ssh.Client, pkg define.BatchPackageTests, prefix string) (OSRunnerPackageResult, error) {
+	var err error
+	var resultPkg OSRunnerPackageResult
+	resultPkg.Name = pkg.Name
+	outputPath := fmt.Sprintf("$HOME\\agent\\build\\TEST-go-remote-%s.%s", prefix, filepath.Base(pkg.Name))
+	resultPkg.Output, err = windowsGetFileContents(ctx, c, outputPath+".out")
+	if err != nil {
+		return OSRunnerPackageResult{}, fmt.Errorf("failed to fetched test output at %s.out", outputPath)
+	}
+	resultPkg.JSONOutput, err = windowsGetFileContents(ctx, c, outputPath+".out.json")
+	if err != nil {
+		return OSRunnerPackageResult{}, fmt.Errorf("failed to fetched test output at %s.out.json", outputPath)
+	}
+	resultPkg.XMLOutput, err = windowsGetFileContents(ctx, c, outputPath+".xml")
+	if err != nil {
+		return OSRunnerPackageResult{}, fmt.Errorf("failed to fetched test output at %s.xml", outputPath)
+	}
+	return resultPkg, nil
+}
+
+// windowsRunScript runs the PowerShell script on the host.
+func windowsRunScript(ctx context.Context, c *ssh.Client, script string) ([]byte, []byte, error) {
+	return sshRunCommand(ctx, c, windowsShell, nil, strings.NewReader(windowsEnv+"; "+script+"\n"))
+}
+
+// windowsGetFileContents returns the file content, copied as is to not be re-encoded by PowerShell.
+func windowsGetFileContents(ctx context.Context, c *ssh.Client, filename string) ([]byte, error) {
+	stdOut, errOut, err := windowsRunScript(ctx, c, fmt.Sprintf(`$f = [IO.File]::OpenRead("%s"); $o = [Console]::OpenStandardOutput(); $f.CopyTo($o); $o.Flush(); $f.Close()`, filename))
+	if err != nil {
+		return nil, fmt.Errorf("%w (stderr: %s)", err, errOut)
+	}
+	return stdOut, nil
+}
@@ -12,6 +12,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
 )
 
 const (
@@ -187,6 +189,37 @@ func (aac ArtifactAPIClient) GetBuildDetails(ctx context.Context, version string
 	return checkResponseAndUnmarshal[BuildDetails](resp)
 }
 
+// GetPreviousGAVersion returns the most recent released version, without prerelease, that is lower than
+// the version passed as parameter, for example 8.8.0 for 8.9.0-SNAPSHOT.
+func (aac ArtifactAPIClient) GetPreviousGAVersion(ctx context.Context, version string) (string, error) {
+	current, err := agtversion.ParseVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("parsing version %q: %w", version, err)
+	}
+	list, err := aac.GetVersions(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var previous *agtversion.ParsedSemVer
+	for _, v := range list.Versions {
+		pv, err := agtversion.ParseVersion(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid version retrieved from artifact API: %q: %w", v, err)
+		}
+		if pv.Prerelease() != "" || !pv.Less(*current) {
+			continue
+		}
+		if previous == nil || previous.Less(*pv) {
+			previous = pv
+		}
+	}
+	if previous == nil {
+		return "", fmt.Errorf("no released version lower than %s found", version)
+	}
+	return previous.String(), nil
+}
+
 func (aac *ArtifactAPIClient) composeURL(relativePath string) (string, error) {
 	joinedURL, err := url.JoinPath(aac.url, relativePath)
 	if err != nil {
@@ -224,3 +224,27 @@ func TestDefaultArtifactAPIClient(t *testing.T) {
 	assert.NotEmpty(t, buildDetails.Build.Projects)
 	assert.Contains(t, buildDetails.Build.Projects, "elastic-agent")
 }
+
+func TestArtifactAPIClientGetPreviousGAVersion(t *testing.T) {
+	cannedRespHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(cannedVersions))
+	})
+
+	testSrv := httptest.NewServer(cannedRespHandler)
+	defer testSrv.Close()
+
+	aac := NewArtifactAPIClient(WithUrl(testSrv.URL))
+	for current, expected := range map[string]string{
+		"8.9.0-SNAPSHOT": "8.8.0",
+		"8.8.0":          "8.7.1",
+		"8.7.1-SNAPSHOT": "8.7.0",
+		"8.0.0":          "7.17.10",
+	} {
+		previous, err := aac.GetPreviousGAVersion(context.Background(), current)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, previous, "previous GA version of %s", current)
+	}
+
+	_, err := aac.GetPreviousGAVersion(context.Background(), "7.0.0")
+	assert.Error(t, err)
+}
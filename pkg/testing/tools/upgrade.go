@@ -0,0 +1,223 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	atesting "github.com/elastic/elastic-agent/pkg/testing"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+// FastWatcherCfg configures the upgrade watcher to complete in about a minute, it is the default
+// configuration of the Elastic Agent installed by PerformUpgrade.
+const FastWatcherCfg = `
+agent.upgrade.watcher:
+  grace_period: 1m
+  error_check.interval: 15s
+  crash_check.interval: 15s
+`
+
+const (
+	upgradeHealthyTimeout = 5 * time.Minute
+	upgradeWatcherTimeout = 3 * time.Minute
+	upgradePollInterval   = 5 * time.Second
+)
+
+// UpgradeOpt is an option for PerformUpgrade.
+type UpgradeOpt func(opts *upgradeOpts)
+
+type upgradeOpts struct {
+	config          []byte
+	fleetURL        string
+	enrollmentToken string
+	rollback        func(ctx context.Context, f *atesting.Fixture) error
+	diagnosticsDir  string
+}
+
+// WithFleet enrolls the Elastic Agent into the Fleet Server at url, the mock Fleet Server of
+// testing/fleetservertest or a real one. The enrollment is insecure when url is not HTTPS.
+func WithFleet(url string, enrollmentToken string) UpgradeOpt {
+	return func(opts *upgradeOpts) {
+		opts.fleetURL = url
+		opts.enrollmentToken = enrollmentToken
+	}
+}
+
+// WithConfig replaces the configuration of the Elastic Agent, FastWatcherCfg by default.
+func WithConfig(cfg []byte) UpgradeOpt {
+	return func(opts *upgradeOpts) {
+		opts.config = cfg
+	}
+}
+
+// WithRollback calls trigger once the Elastic Agent is upgraded, the trigger must make the upgraded
+// Elastic Agent fail so the upgrade watcher rolls it back to the version it was upgraded from.
+func WithRollback(trigger func(ctx context.Context, f *atesting.Fixture) error) UpgradeOpt {
+	return func(opts *upgradeOpts) {
+		opts.rollback = trigger
+	}
+}
+
+// WithDiagnosticsDir sets the directory the diagnostics are archived in, the build/diagnostics
+// directory of the project by default.
+func WithDiagnosticsDir(dir string) UpgradeOpt {
+	return func(opts *upgradeOpts) {
+		opts.diagnosticsDir = dir
+	}
+}
+
+// PerformUpgrade installs the Elastic Agent of startFixture, upgrades it to the version of endFixture
+// using its local package and waits for the upgrade watcher to complete, or to roll back the upgrade
+// when WithRollback is used. The diagnostics of the Elastic Agent are archived once done, whether the
+// upgrade succeeded or not.
+func PerformUpgrade(ctx context.Context, t *testing.T, startFixture *atesting.Fixture, endFixture *atesting.Fixture, opts ...UpgradeOpt) error {
+	o := upgradeOpts{config: []byte(FastWatcherCfg)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	startVersion, err := agtversion.ParseVersion(startFixture.Version())
+	if err != nil {
+		return fmt.Errorf("failed to parse version of the start fixture: %w", err)
+	}
+	endVersion, err := agtversion.ParseVersion(endFixture.Version())
+	if err != nil {
+		return fmt.Errorf("failed to parse version of the end fixture: %w", err)
+	}
+	endPackage, err := endFixture.SrcPackage(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the package of the end fixture: %w", err)
+	}
+
+	if err := startFixture.Prepare(ctx); err != nil {
+		return fmt.Errorf("failed to prepare the start fixture: %w", err)
+	}
+	if err := startFixture.Configure(ctx, o.config); err != nil {
+		return fmt.Errorf("failed to configure the start fixture: %w", err)
+	}
+
+	t.Logf("Installing Elastic Agent %s...", startVersion)
+	installOpts := atesting.InstallOpts{
+		NonInteractive: true,
+		Force:          true,
+	}
+	if o.fleetURL != "" {
+		installOpts.EnrollOpts = atesting.EnrollOpts{
+			URL:             o.fleetURL,
+			EnrollmentToken: o.enrollmentToken,
+			Insecure:        !strings.HasPrefix(o.fleetURL, "https://"),
+		}
+	}
+	output, err := startFixture.Install(ctx, &installOpts)
+	if err != nil {
+		return fmt.Errorf("failed to install the start fixture: %w. Output: %q", err, output)
+	}
+	defer func() {
+		archive, err := startFixture.CollectDiagnostics(ctx, o.diagnosticsDir)
+		if err != nil {
+			t.Logf("Failed to collect diagnostics: %s", err)
+			return
+		}
+		t.Logf("Diagnostics archived at %s", archive)
+	}()
+
+	if err := waitHealthyVersion(ctx, t, startFixture, startVersion); err != nil {
+		return err
+	}
+
+	t.Logf("Upgrading Elastic Agent from %s to %s...", startVersion, endVersion)
+	sourceURI := "file://" + filepath.Dir(endPackage)
+	output, err = startFixture.Exec(ctx, []string{"upgrade", endVersion.String(), "--source-uri", sourceURI, "--skip-verify"})
+	if err != nil {
+		return fmt.Errorf("failed to upgrade to %s: %w. Output: %q", endVersion, err, output)
+	}
+	if err := waitHealthyVersion(ctx, t, startFixture, endVersion); err != nil {
+		return err
+	}
+
+	if o.rollback != nil {
+		t.Log("Triggering the rollback of the upgrade...")
+		if err := o.rollback(ctx, startFixture); err != nil {
+			return fmt.Errorf("failed to trigger the rollback: %w", err)
+		}
+		return waitHealthyVersion(ctx, t, startFixture, startVersion)
+	}
+	return waitUpgradeWatcher(ctx, t, startFixture)
+}
+
+// waitHealthyVersion waits until the running Elastic Agent is healthy and at the version, the control
+// client reconnects on each check as the Elastic Agent restarts during an upgrade.
+func waitHealthyVersion(ctx context.Context, t *testing.T, f *atesting.Fixture, version *agtversion.ParsedSemVer) error {
+	t.Logf("Waiting for Elastic Agent %s to be healthy...", version)
+	var lastErr error
+	check := func() (bool, error) {
+		c := f.Client()
+		if err := c.Connect(ctx); err != nil {
+			return false, fmt.Errorf("failed to connect to the agent: %w", err)
+		}
+		defer c.Disconnect()
+		state, err := c.State(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get the agent state: %w", err)
+		}
+		if state.Info.Version != version.CoreVersion() || state.Info.Snapshot != version.IsSnapshot() {
+			return false, fmt.Errorf("agent runs version %s (snapshot: %t)", state.Info.Version, state.Info.Snapshot)
+		}
+		if state.State != cproto.State_HEALTHY {
+			return false, fmt.Errorf("agent is %s: %s", state.State, state.Message)
+		}
+		return true, nil
+	}
+
+	deadline := time.Now().Add(upgradeHealthyTimeout)
+	for time.Now().Before(deadline) {
+		ok, err := check()
+		if ok {
+			return nil
+		}
+		lastErr = err
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(upgradePollInterval):
+		}
+	}
+	return fmt.Errorf("agent %s never became healthy: %w", version, lastErr)
+}
+
+// waitUpgradeWatcher waits until the upgrade watcher completes, it removes the upgrade marker when
+// done. On Windows the marker is left and removed on the next start of the Elastic Agent.
+func waitUpgradeWatcher(ctx context.Context, t *testing.T, f *atesting.Fixture) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	t.Log("Waiting for the upgrade watcher to complete...")
+	marker := filepath.Join(f.WorkDir(), "data", ".update-marker")
+	started := time.Now()
+	deadline := started.Add(upgradeWatcherTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(marker); errors.Is(err, fs.ErrNotExist) {
+			t.Logf("Upgrade watcher completed in %s", time.Since(started))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(upgradePollInterval):
+		}
+	}
+	return fmt.Errorf("upgrade watcher never removed the upgrade marker %s", marker)
+}
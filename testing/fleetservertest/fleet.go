@@ -0,0 +1,405 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetservertest
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PathDownloads is the path prefix the artifacts are served from, use <server URL>/downloads/ as the
+	// agent.download.sourceURI of the agent.
+	PathDownloads = "/downloads/"
+
+	defaultAgentID     = "agent-id"
+	defaultAPIKey      = "api-key"
+	defaultAPIKeyID    = "api-key-id"
+	defaultPolicyID    = "policy-id"
+	defaultPollTimeout = 5 * time.Second
+)
+
+// Builder builds a Fleet, a stateful mock of Fleet Server that enrolls one agent, delivers the actions
+// injected by the test on the check-ins of the agent, records its acks and serves artifacts.
+type Builder struct {
+	agentID     string
+	apiKey      string
+	apiKeyID    string
+	policyID    string
+	policy      map[string]interface{}
+	pollTimeout time.Duration
+	artifacts   map[string][]byte
+}
+
+// NewBuilder returns a Builder with default values.
+func NewBuilder() *Builder {
+	return &Builder{
+		agentID:     defaultAgentID,
+		apiKey:      defaultAPIKey,
+		apiKeyID:    defaultAPIKeyID,
+		policyID:    defaultPolicyID,
+		pollTimeout: defaultPollTimeout,
+		artifacts:   make(map[string][]byte),
+	}
+}
+
+// AgentID sets the ID given to the agent when it enrolls.
+func (b *Builder) AgentID(id string) *Builder {
+	b.agentID = id
+	return b
+}
+
+// APIKey sets the API key given to the agent when it enrolls.
+func (b *Builder) APIKey(id string, key string) *Builder {
+	b.apiKeyID = id
+	b.apiKey = key
+	return b
+}
+
+// Policy sets the policy the agent receives in a POLICY_CHANGE action on its first check-in.
+func (b *Builder) Policy(id string, policy map[string]interface{}) *Builder {
+	b.policyID = id
+	b.policy = policy
+	return b
+}
+
+// PollTimeout sets how long a check-in waits for actions before returning none, the long polling of
+// Fleet Server.
+func (b *Builder) PollTimeout(timeout time.Duration) *Builder {
+	b.pollTimeout = timeout
+	return b
+}
+
+// Artifact serves the artifact at PathDownloads+name with its .sha512 hash file, for example
+// beats/elastic-agent/elastic-agent-8.9.0-linux-x86_64.tar.gz.
+func (b *Builder) Artifact(name string, data []byte) *Builder {
+	name = strings.TrimPrefix(name, "/")
+	sum := sha512.Sum512(data)
+	b.artifacts[name] = data
+	b.artifacts[name+".sha512"] = []byte(hex.EncodeToString(sum[:]) + "  " + path.Base(name) + "\n")
+	return b
+}
+
+// Start starts the Fleet, it must be closed once done.
+func (b *Builder) Start() *Fleet {
+	f := &Fleet{
+		agentID:     b.agentID,
+		apiKey:      b.apiKey,
+		apiKeyID:    b.apiKeyID,
+		policyID:    b.policyID,
+		pollTimeout: b.pollTimeout,
+		artifacts:   make(map[string][]byte, len(b.artifacts)),
+		notify:      make(chan struct{}),
+	}
+	for name, data := range b.artifacts {
+		f.artifacts[name] = data
+	}
+	if b.policy != nil {
+		f.queuePolicyChange(b.policy)
+	}
+
+	router := NewRouter(Handlers{api: API{
+		AckFn:     f.ack,
+		CheckinFn: f.checkin,
+		EnrollFn:  f.enroll,
+		StatusFn:  NewStatusHandlerHealth(),
+	}})
+	router.PathPrefix(PathDownloads).Handler(http.HandlerFunc(f.download))
+	f.Server = httptest.NewServer(router)
+	return f
+}
+
+// Fleet is a stateful mock of Fleet Server started by a Builder.
+type Fleet struct {
+	*httptest.Server
+
+	agentID     string
+	apiKey      string
+	apiKeyID    string
+	policyID    string
+	pollTimeout time.Duration
+	artifacts   map[string][]byte
+
+	mx             sync.Mutex
+	enrolled       bool
+	replaceToken   string
+	policyRevision int
+	upgradeSeq     int
+	ackToken       int
+	pending        []Action
+	checkins       []CheckinRequest
+	acks           []Event
+	// notify is closed and replaced each time the state changes, waking up the waiting check-ins and tests
+	notify chan struct{}
+}
+
+// AgentID returns the ID of the agent.
+func (f *Fleet) AgentID() string {
+	return f.agentID
+}
+
+// APIKey returns the API key of the agent.
+func (f *Fleet) APIKey() string {
+	return f.apiKey
+}
+
+// Enrolled returns true once the agent has enrolled.
+func (f *Fleet) Enrolled() bool {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return f.enrolled
+}
+
+// AddAction queues the action, it's delivered on the next check-in of the agent. The agent ID and the
+// creation time are set when empty.
+func (f *Fleet) AddAction(action Action) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.queueAction(action)
+}
+
+// AddPolicyChange queues a POLICY_CHANGE action with the policy and returns the action ID.
+func (f *Fleet) AddPolicyChange(policy map[string]interface{}) string {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return f.queuePolicyChange(policy)
+}
+
+// AddUpgrade queues an UPGRADE action to the version and returns the action ID, the artifact is
+// downloaded from sourceURI, the Fleet downloads when empty.
+func (f *Fleet) AddUpgrade(version string, sourceURI string) string {
+	if sourceURI == "" {
+		sourceURI = f.URL + PathDownloads
+	}
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.upgradeSeq++
+	id := fmt.Sprintf("upgrade:%s:%d", version, f.upgradeSeq)
+	var data interface{} = map[string]interface{}{
+		"version":    version,
+		"source_uri": sourceURI,
+	}
+	f.queueAction(Action{Id: id, Type: "UPGRADE", Data: &data})
+	return id
+}
+
+// Checkins returns the check-in requests received from the agent.
+func (f *Fleet) Checkins() []CheckinRequest {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return append([]CheckinRequest(nil), f.checkins...)
+}
+
+// Acks returns the ack events received from the agent.
+func (f *Fleet) Acks() []Event {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return append([]Event(nil), f.acks...)
+}
+
+// WaitForCheckin waits until the agent checks in with the status.
+func (f *Fleet) WaitForCheckin(ctx context.Context, status string) (CheckinRequest, error) {
+	var found CheckinRequest
+	err := f.wait(ctx, func() bool {
+		for _, checkin := range f.checkins {
+			if checkin.Status == status {
+				found = checkin
+				return true
+			}
+		}
+		return false
+	})
+	return found, err
+}
+
+// WaitForAck waits until the agent acks the action.
+func (f *Fleet) WaitForAck(ctx context.Context, actionID string) (Event, error) {
+	var found Event
+	err := f.wait(ctx, func() bool {
+		for _, ack := range f.acks {
+			if ack.ActionId == actionID {
+				found = ack
+				return true
+			}
+		}
+		return false
+	})
+	return found, err
+}
+
+// wait waits until the condition, called with the lock held, is true.
+func (f *Fleet) wait(ctx context.Context, condition func() bool) error {
+	for {
+		f.mx.Lock()
+		done := condition()
+		notify := f.notify
+		f.mx.Unlock()
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// changed wakes up the waiting check-ins and tests, called with the lock held.
+func (f *Fleet) changed() {
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+func (f *Fleet) queueAction(action Action) {
+	if action.AgentId == "" {
+		action.AgentId = f.agentID
+	}
+	if action.CreatedAt == "" {
+		action.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	f.pending = append(f.pending, action)
+	f.changed()
+}
+
+func (f *Fleet) queuePolicyChange(policy map[string]interface{}) string {
+	f.policyRevision++
+	withID := make(map[string]interface{}, len(policy)+2)
+	for k, v := range policy {
+		withID[k] = v
+	}
+	withID["id"] = f.policyID
+	withID["revision"] = f.policyRevision
+
+	id := fmt.Sprintf("policy:%s:%d:1", f.policyID, f.policyRevision)
+	var data interface{} = map[string]interface{}{"policy": withID}
+	f.queueAction(Action{Id: id, Type: "POLICY_CHANGE", Data: &data})
+	return id
+}
+
+func (f *Fleet) enroll(
+	_ context.Context,
+	_ string,
+	_ string,
+	enrollRequest EnrollRequest) (*EnrollResponse, *HTTPError) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	// the agent is replaced only when enrolling again with its ID and replace token, any other
+	// enrollment gets the ID set by the builder
+	if f.enrolled && enrollRequest.Id == f.agentID && enrollRequest.ReplaceToken != f.replaceToken {
+		return nil, &HTTPError{
+			StatusCode: http.StatusConflict,
+			Message:    fmt.Sprintf("agent %s is enrolled with another replace token", f.agentID),
+		}
+	}
+	f.enrolled = true
+	f.replaceToken = enrollRequest.ReplaceToken
+	f.changed()
+
+	return &EnrollResponse{
+		Action: "created",
+		Item: EnrollResponseItem{
+			Id:             f.agentID,
+			Active:         true,
+			PolicyId:       f.policyID,
+			Type:           enrollRequest.Type,
+			EnrolledAt:     time.Now().UTC().Format(time.RFC3339),
+			LocalMetadata:  enrollRequest.Metadata.Local,
+			AccessApiKeyId: f.apiKeyID,
+			AccessApiKey:   f.apiKey,
+			Status:         "online",
+			Tags:           enrollRequest.Metadata.Tags,
+		},
+	}, nil
+}
+
+func (f *Fleet) checkin(
+	ctx context.Context,
+	id string,
+	_ string,
+	_ string,
+	checkinRequest CheckinRequest) (*CheckinResponse, *HTTPError) {
+	if id != f.agentID {
+		return nil, &HTTPError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("agent %s not found", id)}
+	}
+
+	f.mx.Lock()
+	f.checkins = append(f.checkins, checkinRequest)
+	f.changed()
+	f.mx.Unlock()
+
+	// like Fleet Server, the check-in waits for actions until the poll timeout
+	timeout := f.pollTimeout
+	if d, err := time.ParseDuration(checkinRequest.PollTimeout); err == nil && d < timeout {
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_ = f.wait(ctx, func() bool {
+		return len(f.pending) > 0
+	})
+
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	actions := f.pending
+	f.pending = nil
+	if len(actions) > 0 {
+		f.ackToken++
+	}
+	return &CheckinResponse{
+		AckToken: strconv.Itoa(f.ackToken),
+		Action:   "checkin",
+		Actions:  actions,
+	}, nil
+}
+
+func (f *Fleet) ack(
+	_ context.Context,
+	id string,
+	ackRequest AckRequest) (*AckResponse, *HTTPError) {
+	if id != f.agentID {
+		return nil, &HTTPError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("agent %s not found", id)}
+	}
+
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	resp := &AckResponse{Action: "acks"}
+	for _, event := range ackRequest.Events {
+		f.acks = append(f.acks, event)
+		resp.Items = append(resp.Items, AckResponseItem{Status: http.StatusOK, Message: http.StatusText(http.StatusOK)})
+	}
+	f.changed()
+	return resp, nil
+}
+
+func (f *Fleet) download(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, PathDownloads)
+	data, ok := f.artifacts[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// PolicyFromJSON converts a policy in JSON to the map expected by the Builder and Fleet.
+func PolicyFromJSON(policyJSON string) (map[string]interface{}, error) {
+	var policy map[string]interface{}
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy: %w", err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,161 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package fleetservertest
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestFleet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	policy, err := PolicyFromJSON(`{"outputs":{"default":{"type":"elasticsearch","hosts":["localhost:9200"]}}}`)
+	require.NoError(t, err)
+	f := NewBuilder().
+		AgentID("test-agent").
+		Policy("test-policy", policy).
+		PollTimeout(100*time.Millisecond).
+		Artifact("beats/elastic-agent/elastic-agent-8.9.0-linux-x86_64.tar.gz", []byte("artifact")).
+		Start()
+	defer f.Close()
+
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+	cfg := remote.DefaultClientConfig()
+	cfg.Hosts = []string{f.URL}
+
+	// enroll
+	client, err := fleetclient.NewWithConfig(log, cfg)
+	require.NoError(t, err)
+	enrollResp, err := fleetapi.NewEnrollCmd(client).Execute(ctx, &fleetapi.EnrollRequest{
+		EnrollAPIKey: "enrollment-key",
+		Type:         fleetapi.PermanentEnroll,
+		Metadata:     fleetapi.Metadata{Tags: []string{"test"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "test-agent", enrollResp.Item.ID)
+	assert.Equal(t, f.APIKey(), enrollResp.Item.AccessAPIKey)
+	assert.True(t, f.Enrolled())
+
+	// the first check-in gets the policy
+	client, err = fleetclient.NewAuthWithConfig(log, enrollResp.Item.AccessAPIKey, cfg)
+	require.NoError(t, err)
+	info := testAgentInfo("test-agent")
+	checkinResp, _, err := fleetapi.NewCheckinCmd(info, client).Execute(ctx, &fleetapi.CheckinRequest{Status: "online"})
+	require.NoError(t, err)
+	require.Len(t, checkinResp.Actions, 1)
+	policyChange, ok := checkinResp.Actions[0].(*fleetapi.ActionPolicyChange)
+	require.True(t, ok, "expected a policy change not a %T", checkinResp.Actions[0])
+	assert.Equal(t, "test-policy", policyChange.Policy["id"])
+	_, err = f.WaitForCheckin(ctx, "online")
+	require.NoError(t, err)
+
+	// no actions once the poll timeout is reached
+	checkinResp, _, err = fleetapi.NewCheckinCmd(info, client).Execute(ctx, &fleetapi.CheckinRequest{Status: "online", AckToken: checkinResp.AckToken})
+	require.NoError(t, err)
+	assert.Empty(t, checkinResp.Actions)
+
+	// injected actions are delivered to the waiting check-in
+	upgradeIDCh := make(chan string, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		upgradeIDCh <- f.AddUpgrade("8.9.0", "")
+	}()
+	f.pollTimeout = 10 * time.Second
+	checkinResp, _, err = fleetapi.NewCheckinCmd(info, client).Execute(ctx, &fleetapi.CheckinRequest{Status: "online"})
+	require.NoError(t, err)
+	upgradeID := <-upgradeIDCh
+	require.Len(t, checkinResp.Actions, 1)
+	upgrade, ok := checkinResp.Actions[0].(*fleetapi.ActionUpgrade)
+	require.True(t, ok, "expected an upgrade not a %T", checkinResp.Actions[0])
+	assert.Equal(t, upgradeID, upgrade.ActionID)
+	assert.Equal(t, "8.9.0", upgrade.Version)
+
+	// acks are recorded
+	_, err = fleetapi.NewAckCmd(info, client).Execute(ctx, &fleetapi.AckRequest{
+		Events: []fleetapi.AckEvent{{
+			EventType: "ACTION_RESULT",
+			SubType:   "ACKNOWLEDGED",
+			ActionID:  upgradeID,
+			AgentID:   "test-agent",
+		}},
+	})
+	require.NoError(t, err)
+	ack, err := f.WaitForAck(ctx, upgradeID)
+	require.NoError(t, err)
+	assert.Equal(t, "ACKNOWLEDGED", ack.Subtype)
+
+	// artifacts are served with their hash
+	artifactURL := upgrade.SourceURI + "beats/elastic-agent/elastic-agent-8.9.0-linux-x86_64.tar.gz"
+	assert.Equal(t, "artifact", get(t, ctx, artifactURL))
+	sum := sha512.Sum512([]byte("artifact"))
+	assert.Equal(t, hex.EncodeToString(sum[:])+"  elastic-agent-8.9.0-linux-x86_64.tar.gz\n", get(t, ctx, artifactURL+".sha512"))
+}
+
+func get(t *testing.T, ctx context.Context, url string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestFleetReenroll(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	f := NewBuilder().AgentID("test-agent").Start()
+	defer f.Close()
+
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+	cfg := remote.DefaultClientConfig()
+	cfg.Hosts = []string{f.URL}
+	client, err := fleetclient.NewWithConfig(log, cfg)
+	require.NoError(t, err)
+	enroll := func(id string, replaceToken string) (*fleetapi.EnrollResponse, error) {
+		return fleetapi.NewEnrollCmd(client).Execute(ctx, &fleetapi.EnrollRequest{
+			EnrollAPIKey: "enrollment-key",
+			Type:         fleetapi.PermanentEnroll,
+			ID:           id,
+			ReplaceToken: replaceToken,
+		})
+	}
+
+	_, err = enroll("", "replace-token")
+	require.NoError(t, err)
+
+	_, err = enroll("test-agent", "another-token")
+	assert.ErrorIs(t, err, fleetapi.ErrConflict)
+
+	resp, err := enroll("test-agent", "replace-token")
+	require.NoError(t, err)
+	assert.Equal(t, "test-agent", resp.Item.ID)
+}
+
+type testAgentInfo string
+
+func (a testAgentInfo) AgentID() string {
+	return string(a)
+}
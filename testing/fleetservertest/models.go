@@ -4,6 +4,8 @@
 
 package fleetservertest
 
+import "encoding/json"
+
 // =============================================================================
 // ================================== Ack ======================================
 // =============================================================================
@@ -67,7 +69,7 @@ type Event struct {
 	Message string `json:"message"`
 
 	// An embedded JSON object that contains additional information for the fleet-server to process. Defined as a json.RawMessage in both the fleet-server and the elastic-agent.  Is currently used by UPGRADE actions to signal retries. If the error attribute is non empty payload is checked for `retry: bool` and `retry_attempt: int`. If retry is true, fleet-serve will mark the agent as retrying, if it's false the upgrade will be marked as failed.
-	Payload string `json:"payload,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
 
 	// The time at which the action was started. Used only when acknowledging input actions.
 	StartedAt string `json:"started_at"`
@@ -76,13 +78,13 @@ type Event struct {
 	CompletedAt string `json:"completed_at"`
 
 	// The action data for the input action being acknowledged.
-	ActionData string `json:"action_data,omitempty"`
+	ActionData json.RawMessage `json:"action_data,omitempty"`
 
 	// The action response for the input action being acknowledged.
-	ActionResponse string `json:"action_response,omitempty"`
+	ActionResponse json.RawMessage `json:"action_response,omitempty"`
 
 	// An embedded JSON object that has the data about the ack.  Used by REQUEST_DIAGNOSTICS actions. Contains a `upload_id` attribute used to communicate the successfully uploaded diagnostics ID.
-	Data string `json:"data,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
 
 	// An error message. If this is non-empty an error has occurred when executing the action. For some actions (such as UPGRADE actions) it may result in the action being marked as failed.
 	Error string `json:"error,omitempty"`
@@ -104,10 +106,10 @@ type CheckinRequest struct {
 	AckToken string `json:"ack_token,omitempty"`
 
 	// An embedded JSON object that holds meta-data values. Defined in fleet-server as a `json.RawMessage`, defined as an object in the elastic-agent. elastic-agent will populate the object with information from the binary and host/system environment. fleet-server will update the agent record if a checkin response contains different data from the record.
-	LocalMetadata string `json:"local_metadata,omitempty"`
+	LocalMetadata json.RawMessage `json:"local_metadata,omitempty"`
 
 	// An embedded JSON object that holds component information that the agent is running. Defined in fleet-server as a `json.RawMessage`, defined as an object in the elastic-agent. fleet-server will update the components in an agent record if they differ from this object.
-	Components string `json:"components,omitempty"`
+	Components json.RawMessage `json:"components,omitempty"`
 
 	// An optional timeout value that informs fleet-server of when a client will time out on it's checkin request. If not specified fleet-server will use the timeout values specified in the config (defaults to 5m polling and a 10m write timeout). The value, if specified is expected to be a string that is parsable by [time.ParseDuration](https://pkg.go.dev/time#ParseDuration). If specified fleet-server will set its poll timeout to `max(1m, poll_timeout-2m)` and its write timeout to `max(2m, poll_timout-1m)`.
 	PollTimeout string `json:"poll_timeout,omitempty"`
@@ -183,6 +185,12 @@ type EnrollRequest struct {
 	// The shared ID of the agent. To support pre-existing installs. NOT YET IMPLEMENTED.
 	SharedId string `json:"shared_id"`
 
+	// The ID of an existing agent to replace.
+	Id string `json:"id,omitempty"`
+
+	// The token stored with the agent, it must match to replace the agent.
+	ReplaceToken string `json:"replace_token,omitempty"`
+
 	Metadata EnrollMetadata `json:"metadata"`
 }
 
@@ -214,10 +222,10 @@ type EnrollResponseItem struct {
 	EnrolledAt string `json:"enrolled_at"`
 
 	// A copy of the user provided metadata from the enrollment request. Currently will be empty.
-	UserProvidedMetadata string `json:"user_provided_metadata"`
+	UserProvidedMetadata json.RawMessage `json:"user_provided_metadata"`
 
 	// A copy of the (updated) local metadata provided in the enrollment request.
-	LocalMetadata string `json:"local_metadata"`
+	LocalMetadata json.RawMessage `json:"local_metadata"`
 
 	// Defined in fleet-server and elastic-agent as `[]interface{}` but never used.
 	Actions []map[string]interface{} `json:"actions"`
@@ -239,10 +247,10 @@ type EnrollResponseItem struct {
 type EnrollMetadata struct {
 
 	// An embedded JSON object that holds user-provided meta-data values. Defined in fleet-server as a `json.RawMessage`. fleet-server does not use these values on enrollment of an agent. Defined in the elastic-agent as a `map[string]interface{}` with no way to specify any values.
-	UserProvided string `json:"user_provided"`
+	UserProvided json.RawMessage `json:"user_provided"`
 
 	// An embedded JSON object that holds meta-data values. Defined in fleet-server as a `json.RawMessage`, defined as an object in the elastic-agent. elastic-agent will populate the object with information from the binary and host/system environment. If not empty fleet-server will update the value of `local[\"elastic\"][\"agent\"][\"id\"]` to the agent ID (assuming the keys exist). The (possibly updated) value is sent by fleet-server when creating the record for a new agent.
-	Local string `json:"local"`
+	Local json.RawMessage `json:"local"`
 
 	// User provided tags for the agent. fleet-server will pass the tags to the agent record on enrollment.
 	Tags []string `json:"tags"`
@@ -40,6 +40,7 @@ var diagnosticsFiles = []string{
 	"mutex.pprof.gz",
 	"pre-config.yaml",
 	"local-config.yaml",
+	"sbom.cdx.json",
 	"state.yaml",
 	"threadcreate.pprof.gz",
 	"variables.yaml",
@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	atesting "github.com/elastic/elastic-agent/pkg/testing"
+	"github.com/elastic/elastic-agent/pkg/testing/define"
+)
+
+const ipv6Namespace = "elastic-agent-ipv6"
+
+var ipv6Config = `
+agent.monitoring.http:
+  enabled: true
+  host: "::1"
+  port: 6791
+outputs:
+  default:
+    type: fake-action-output
+    shipper.enabled: true
+inputs:
+  - id: fake
+    type: fake
+    state: 2
+    message: Healthy
+`
+
+// TestIPv6OnlyNamespace runs Elastic Agent in a network namespace without IPv4 address, the
+// components connect to its gRPC server on the IPv6 loopback.
+func TestIPv6OnlyNamespace(t *testing.T) {
+	define.Require(t, define.Requirements{
+		OS: []define.OS{
+			{Type: define.Linux},
+		},
+		Local: false,
+		Sudo:  true,
+	})
+
+	ipNetns(t, "add", ipv6Namespace)
+	t.Cleanup(func() {
+		_ = exec.Command("ip", "netns", "delete", ipv6Namespace).Run()
+	})
+	ipNetns(t, "exec", ipv6Namespace, "ip", "link", "set", "lo", "up")
+	ipNetns(t, "exec", ipv6Namespace, "ip", "addr", "del", "127.0.0.1/8", "dev", "lo")
+
+	f, err := define.NewFixture(t, define.Version(), atesting.WithCmdOptions(inNetns(ipv6Namespace)))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = f.Prepare(ctx, fakeComponent, fakeShipper)
+	require.NoError(t, err)
+	// the monitoring endpoint is started from the configuration file
+	err = f.Configure(ctx, []byte(ipv6Config))
+	require.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	err = f.Run(ctx, atesting.State{
+		Configure:  ipv6Config,
+		AgentState: atesting.NewClientState(client.Healthy),
+		Components: map[string]atesting.ComponentState{
+			"fake-default": {
+				State: atesting.NewClientState(client.Healthy),
+			},
+			"fake-shipper-default": {
+				State: atesting.NewClientState(client.Healthy),
+			},
+		},
+		After: func() error {
+			// the monitoring endpoint listens on the IPv6 loopback of the namespace
+			out, err := exec.Command("ip", "netns", "exec", ipv6Namespace, "curl", "-sf", "http://[::1]:6791/stats").CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("failed to query the monitoring endpoint: %w: %s", err, out)
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+}
+
+func ipNetns(t *testing.T, args ...string) {
+	t.Helper()
+	out, err := exec.Command("ip", append([]string{"netns"}, args...)...).CombinedOutput()
+	require.NoErrorf(t, err, "ip netns %s: %s", strings.Join(args, " "), out)
+}
+
+// inNetns runs the command in the network namespace.
+func inNetns(namespace string) process.CmdOption {
+	return func(c *exec.Cmd) error {
+		ip, err := exec.LookPath("ip")
+		if err != nil {
+			return err
+		}
+		c.Args = append([]string{ip, "netns", "exec", namespace, c.Path}, c.Args[1:]...)
+		c.Path = ip
+		return nil
+	}
+}
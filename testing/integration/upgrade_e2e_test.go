@@ -0,0 +1,115 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	atesting "github.com/elastic/elastic-agent/pkg/testing"
+	"github.com/elastic/elastic-agent/pkg/testing/define"
+	"github.com/elastic/elastic-agent/pkg/testing/tools"
+	"github.com/elastic/elastic-agent/pkg/version"
+	"github.com/elastic/elastic-agent/testing/fleetservertest"
+)
+
+// TestUpgradeEndToEnd upgrades the previous released version of the Elastic Agent to the local build,
+// run it with `mage integration:upgrade`.
+func TestUpgradeEndToEnd(t *testing.T) {
+	define.Require(t, define.Requirements{
+		Local:   false, // requires Agent installation
+		Isolate: true,
+		Sudo:    true, // requires Agent installation
+		OS: []define.OS{
+			{Type: define.Linux}, // the rollback is triggered through systemd
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	startVersion, err := tools.NewArtifactAPIClient().GetPreviousGAVersion(ctx, define.Version())
+	require.NoError(t, err, "failed to find the previous released version")
+	t.Logf("Testing Elastic Agent upgrade from %s to %s...", startVersion, define.Version())
+
+	newFixtures := func(t *testing.T) (*atesting.Fixture, *atesting.Fixture) {
+		startFixture, err := atesting.NewFixture(t, startVersion, atesting.WithFetcher(atesting.ArtifactFetcher()))
+		require.NoError(t, err)
+		endFixture, err := define.NewFixture(t, define.Version())
+		require.NoError(t, err)
+		return startFixture, endFixture
+	}
+
+	t.Run("standalone", func(t *testing.T) {
+		startFixture, endFixture := newFixtures(t)
+		err := tools.PerformUpgrade(ctx, t, startFixture, endFixture)
+		require.NoError(t, err)
+	})
+
+	t.Run("fleet", func(t *testing.T) {
+		policy, err := fleetservertest.PolicyFromJSON(`{
+			"outputs": {"default": {"type": "elasticsearch", "hosts": ["127.0.0.1:9200"]}},
+			"agent": {"monitoring": {"enabled": false}},
+			"inputs": []
+		}`)
+		require.NoError(t, err)
+		fleet := fleetservertest.NewBuilder().Policy("upgrade-policy", policy).Start()
+		defer fleet.Close()
+
+		startFixture, endFixture := newFixtures(t)
+		err = tools.PerformUpgrade(ctx, t, startFixture, endFixture, tools.WithFleet(fleet.URL, "enrollment-token"))
+		require.NoError(t, err)
+
+		endVersion, err := version.ParseVersion(define.Version())
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			checkins := fleet.Checkins()
+			return len(checkins) > 0 && checkinAgentVersion(checkins[len(checkins)-1]) == endVersion.CoreVersion()
+		}, 2*time.Minute, 5*time.Second, "upgraded agent never checked in with version %s", endVersion.CoreVersion())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		startFixture, endFixture := newFixtures(t)
+		err := tools.PerformUpgrade(ctx, t, startFixture, endFixture, tools.WithRollback(crashAgentService))
+		require.NoError(t, err)
+	})
+}
+
+// crashAgentService kills the Elastic Agent service more times than the upgrade watcher allows
+// within its crash check interval.
+func crashAgentService(ctx context.Context, _ *atesting.Fixture) error {
+	for i := 0; i < 4; i++ {
+		output, err := exec.CommandContext(ctx, "systemctl", "kill", "--signal=SIGKILL", "elastic-agent").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to kill the elastic-agent service: %w. Output: %q", err, output)
+		}
+		time.Sleep(3 * time.Second)
+	}
+	return nil
+}
+
+// checkinAgentVersion returns the version of the Elastic Agent reported in the local metadata of the check-in.
+func checkinAgentVersion(checkin fleetservertest.CheckinRequest) string {
+	var metadata struct {
+		Elastic struct {
+			Agent struct {
+				Version string `json:"version"`
+			} `json:"agent"`
+		} `json:"elastic"`
+	}
+	if err := json.Unmarshal(checkin.LocalMetadata, &metadata); err != nil {
+		return ""
+	}
+	return metadata.Elastic.Agent.Version
+}